@@ -42,10 +42,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 
-	"github.com/gomodule/redigo/redis"
 	"github.com/gregjones/httpcache"
 	"github.com/gregjones/httpcache/diskcache"
-	rediscache "github.com/gregjones/httpcache/redis"
 	"github.com/peterbourgon/diskv"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
@@ -506,31 +504,60 @@ type CachePartitionCreator func(partitionKey string, expiresAt *time.Time) httpc
 // NewFromCache creates a GitHub cache RoundTripper that is backed by the
 // specified httpcache.Cache implementation.
 func NewFromCache(roundTripper http.RoundTripper, cache CachePartitionCreator, maxConcurrency int, throttlingTimes RequestThrottlingTimes) http.RoundTripper {
+	return newFromCache(roundTripper, cache, maxConcurrency, throttlingTimes, nil)
+}
+
+// newFromCache creates a GitHub cache RoundTripper that is backed by the
+// specified httpcache.Cache implementation. If coalescer is set, the requests
+// that weren't coalesced within the process are passed through it.
+func newFromCache(roundTripper http.RoundTripper, cache CachePartitionCreator, maxConcurrency int, throttlingTimes RequestThrottlingTimes, coalescer func(partitionKey string, requestExecutor http.RoundTripper) http.RoundTripper) http.RoundTripper {
 	hasher := ghmetrics.NewCachingHasher()
 	return newPartitioningRoundTripper(func(partitionKey string, expiresAt *time.Time) http.RoundTripper {
 		cacheTransport := httpcache.NewTransport(cache(partitionKey, expiresAt))
-		cacheTransport.Transport = newThrottlingTransport(maxConcurrency, upstreamTransport{roundTripper: roundTripper, hasher: hasher}, hasher, throttlingTimes)
+		// Partitions are per token, and so is the secondary rate limit. Requests
+		// that are held back don't take up the concurrency of the others.
+		cacheTransport.Transport = newSecondaryRateLimitTransport(
+			newThrottlingTransport(maxConcurrency, upstreamTransport{roundTripper: roundTripper, hasher: hasher}, hasher, throttlingTimes),
+			hasher,
+		)
+		var requestExecutor http.RoundTripper = cacheTransport
+		if coalescer != nil {
+			requestExecutor = coalescer(partitionKey, cacheTransport)
+		}
 		return &requestCoalescer{
 			cache:           make(map[string]*firstRequest),
-			requestExecutor: cacheTransport,
+			requestExecutor: requestExecutor,
 			hasher:          hasher,
 		}
 	})
 }
 
 // NewRedisCache creates a GitHub cache RoundTripper that is backed by a Redis
-// cache.
-// Important note: The redis implementation does not support partitioning the cache
-// which means that requests to the same path from different tokens will invalidate
-// each other.
+// cache. All replicas of ghproxy that use the same Redis share the cache, and
+// concurrent requests for the same resource are coalesced across them.
+// It supports a partitioned cache, the responses of partitions for tokens
+// that expire are dropped by Redis once the token expired, the others after
+// redisDefaultTTL.
 func NewRedisCache(roundTripper http.RoundTripper, redisAddress string, maxConcurrency int, throttlingTimes RequestThrottlingTimes) http.RoundTripper {
-	conn, err := redis.Dial("tcp", redisAddress)
-	if err != nil {
+	pool := newRedisPool(redisAddress, maxConcurrency)
+	conn := pool.Get()
+	if _, err := conn.Do("PING"); err != nil {
 		logrus.WithError(err).Fatal("Error connecting to Redis")
 	}
-	redisCache := rediscache.NewWithClient(conn)
-	return NewFromCache(roundTripper,
-		func(_ string, _ *time.Time) httpcache.Cache { return redisCache },
+	conn.Close()
+	return newFromCache(roundTripper,
+		func(partitionKey string, expiresAt *time.Time) httpcache.Cache {
+			return &redisCache{getConn: pool.Get, prefix: redisKeyPrefix + partitionKey + ":", expiresAt: expiresAt}
+		},
 		maxConcurrency,
-		throttlingTimes)
+		throttlingTimes,
+		func(partitionKey string, requestExecutor http.RoundTripper) http.RoundTripper {
+			return &redisCoalescer{
+				getConn:         pool.Get,
+				prefix:          redisKeyPrefix + partitionKey + ":",
+				requestExecutor: requestExecutor,
+				lockTTL:         redisLockTTL,
+				pollInterval:    redisLockPollInterval,
+			}
+		})
 }
@@ -0,0 +1,204 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ghcache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// redisKeyPrefix is the prefix of all keys ghproxy stores in Redis.
+	redisKeyPrefix = "ghcache:"
+	// redisDefaultTTL is how long responses of tokens that don't expire are
+	// kept, so that Redis doesn't fill up with responses nobody revalidates.
+	redisDefaultTTL = 24 * time.Hour
+
+	// redisLockTTL is how long a replica may take to fetch a response before
+	// the other replicas stop waiting for it.
+	redisLockTTL = 30 * time.Second
+	// redisLockPollInterval is how often waiting replicas check whether the
+	// response was fetched.
+	redisLockPollInterval = 100 * time.Millisecond
+)
+
+// releaseLockScript deletes a lock only if it is still held by the replica
+// that releases it, it may have expired and been taken by another replica.
+var releaseLockScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// redisCache is a httpcache.Cache that stores the responses of one cache
+// partition in Redis, so that all replicas of ghproxy share them.
+type redisCache struct {
+	getConn func() redis.Conn
+	prefix  string
+	// expiresAt is when the token of the partition expires. Responses are
+	// dropped by Redis then, nobody can read them anymore. Without it, they
+	// are dropped after redisDefaultTTL.
+	expiresAt *time.Time
+}
+
+func (c *redisCache) Get(key string) ([]byte, bool) {
+	conn := c.getConn()
+	defer conn.Close()
+	item, err := redis.Bytes(conn.Do("GET", c.prefix+key))
+	if err != nil {
+		if err != redis.ErrNil {
+			logrus.WithError(err).WithField("cache-key", key).Warn("Failed to get response from Redis.")
+		}
+		return nil, false
+	}
+	return item, true
+}
+
+func (c *redisCache) Set(key string, response []byte) {
+	conn := c.getConn()
+	defer conn.Close()
+	ttl := int(redisDefaultTTL.Seconds())
+	if c.expiresAt != nil {
+		ttl = int(time.Until(*c.expiresAt).Seconds())
+		if ttl <= 0 {
+			return
+		}
+	}
+	if _, err := conn.Do("SET", c.prefix+key, response, "EX", ttl); err != nil {
+		logrus.WithError(err).WithField("cache-key", key).Warn("Failed to store response in Redis.")
+	}
+}
+
+func (c *redisCache) Delete(key string) {
+	conn := c.getConn()
+	defer conn.Close()
+	if _, err := conn.Do("DEL", c.prefix+key); err != nil {
+		logrus.WithError(err).WithField("cache-key", key).Warn("Failed to delete response from Redis.")
+	}
+}
+
+// redisCoalescer coalesces GET requests for the same resource across the
+// replicas of ghproxy. The replica that takes the lock of a resource in
+// Redis fetches it and stores the response in the shared cache, the others
+// wait until it is done and only revalidate that response, which is free.
+// Requests are coalesced within a replica by the requestCoalescer before.
+type redisCoalescer struct {
+	getConn         func() redis.Conn
+	prefix          string
+	requestExecutor http.RoundTripper
+	lockTTL         time.Duration
+	pollInterval    time.Duration
+}
+
+func (c *redisCoalescer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return c.requestExecutor.RoundTrip(req)
+	}
+
+	lockKey := c.prefix + "lock:" + req.URL.String()
+	owner, err := c.lock(lockKey)
+	if err != nil {
+		// Requests are not coalesced across replicas while Redis is unavailable.
+		logrus.WithError(err).WithField("cache-key", req.URL.String()).Warn("Failed to take lock in Redis.")
+		return c.requestExecutor.RoundTrip(req)
+	}
+	if owner != "" {
+		defer c.unlock(lockKey, owner)
+		return c.requestExecutor.RoundTrip(req)
+	}
+
+	c.waitForUnlock(req, lockKey)
+	return c.requestExecutor.RoundTrip(req)
+}
+
+// lock takes the lock and returns the value that identifies this holder, or
+// "" if another replica holds the lock.
+func (c *redisCoalescer) lock(key string) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	owner := hex.EncodeToString(raw)
+
+	conn := c.getConn()
+	defer conn.Close()
+	_, err := redis.String(conn.Do("SET", key, owner, "NX", "PX", c.lockTTL.Milliseconds()))
+	if err == redis.ErrNil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return owner, nil
+}
+
+func (c *redisCoalescer) unlock(key, owner string) {
+	conn := c.getConn()
+	defer conn.Close()
+	if _, err := releaseLockScript.Do(conn, key, owner); err != nil {
+		logrus.WithError(err).WithField("lock", key).Warn("Failed to release lock in Redis.")
+	}
+}
+
+// waitForUnlock waits until the lock was released or expired, or the request
+// was cancelled.
+func (c *redisCoalescer) waitForUnlock(req *http.Request, key string) {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		conn := c.getConn()
+		exists, err := redis.Bool(conn.Do("EXISTS", key))
+		conn.Close()
+		if err != nil || !exists {
+			return
+		}
+	}
+}
+
+// newRedisPool returns a pool of connections to Redis.
+func newRedisPool(redisAddress string, maxConcurrency int) *redis.Pool {
+	return &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", redisAddress)
+		},
+		// Every in-flight request needs a connection for the cache and one
+		// for its lock, and waiting requests poll. Connections are only held
+		// for a command, so requests wait for one rather than opening more.
+		MaxIdle:     2 * maxConcurrency,
+		MaxActive:   2 * maxConcurrency,
+		Wait:        true,
+		IdleTimeout: 5 * time.Minute,
+		TestOnBorrow: func(conn redis.Conn, lastUsed time.Time) error {
+			if time.Since(lastUsed) < time.Minute {
+				return nil
+			}
+			_, err := conn.Do("PING")
+			return err
+		},
+	}
+}
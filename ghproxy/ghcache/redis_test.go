@@ -0,0 +1,232 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ghcache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// fakeRedis is a Redis server that knows the commands ghcache uses.
+type fakeRedis struct {
+	lock sync.Mutex
+	data map[string][]byte
+	// ttls are the TTLs in seconds or milliseconds that keys were set with.
+	ttls map[string]interface{}
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{data: map[string][]byte{}, ttls: map[string]interface{}{}}
+}
+
+func (f *fakeRedis) conn() redis.Conn {
+	return &fakeRedisConn{redis: f}
+}
+
+type fakeRedisConn struct {
+	redis *fakeRedis
+}
+
+func (c *fakeRedisConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	f := c.redis
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	switch cmd {
+	case "GET":
+		if value, ok := f.data[args[0].(string)]; ok {
+			return value, nil
+		}
+		return nil, nil
+	case "SET":
+		key := args[0].(string)
+		value := args[1]
+		for i := 2; i < len(args); i++ {
+			switch args[i] {
+			case "NX":
+				if _, exists := f.data[key]; exists {
+					return nil, nil
+				}
+			case "EX", "PX":
+				f.ttls[key] = args[i+1]
+				i++
+			}
+		}
+		switch v := value.(type) {
+		case []byte:
+			f.data[key] = v
+		case string:
+			f.data[key] = []byte(v)
+		}
+		return "OK", nil
+	case "DEL":
+		delete(f.data, args[0].(string))
+		return int64(1), nil
+	case "EXISTS":
+		if _, exists := f.data[args[0].(string)]; exists {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case "EVALSHA", "EVAL":
+		// The only script releases a lock: sha, number of keys, key, owner.
+		key, owner := args[2].(string), args[3].(string)
+		if string(f.data[key]) == owner {
+			delete(f.data, key)
+			return int64(1), nil
+		}
+		return int64(0), nil
+	}
+	return nil, fmt.Errorf("unsupported command %s", cmd)
+}
+
+func (c *fakeRedisConn) Close() error                          { return nil }
+func (c *fakeRedisConn) Err() error                            { return nil }
+func (c *fakeRedisConn) Send(_ string, _ ...interface{}) error { return nil }
+func (c *fakeRedisConn) Flush() error                          { return nil }
+func (c *fakeRedisConn) Receive() (interface{}, error)         { return nil, nil }
+
+func TestNewRedisPool(t *testing.T) {
+	pool := newRedisPool("localhost:6379", 10)
+	if pool.MaxActive != 20 || !pool.Wait {
+		t.Errorf("expected the connections to be bounded by the concurrency, got MaxActive %d and Wait %t", pool.MaxActive, pool.Wait)
+	}
+}
+
+func TestRedisCache(t *testing.T) {
+	server := newFakeRedis()
+	expiresAt := time.Now().Add(time.Hour)
+	partition := &redisCache{getConn: server.conn, prefix: "ghcache:partition:", expiresAt: &expiresAt}
+	otherPartition := &redisCache{getConn: server.conn, prefix: "ghcache:other-partition:"}
+
+	partition.Set("key", []byte("response"))
+	if response, ok := partition.Get("key"); !ok || string(response) != "response" {
+		t.Errorf("expected the response to be cached, got %q", string(response))
+	}
+	if _, ok := otherPartition.Get("key"); ok {
+		t.Error("expected partitions not to share responses")
+	}
+	otherPartition.Set("key", []byte("other response"))
+	if ttl, ok := server.ttls["ghcache:other-partition:key"].(int); !ok || ttl != int(redisDefaultTTL.Seconds()) {
+		t.Errorf("expected the response of a token that doesn't expire to expire after the default TTL, TTL was %v", server.ttls["ghcache:other-partition:key"])
+	}
+	if ttl, ok := server.ttls["ghcache:partition:key"].(int); !ok || ttl < 3590 || ttl > 3600 {
+		t.Errorf("expected the response to expire with the token, TTL was %v", server.ttls["ghcache:partition:key"])
+	}
+
+	partition.Delete("key")
+	if _, ok := partition.Get("key"); ok {
+		t.Error("expected the response to be deleted")
+	}
+
+	expiredAt := time.Now().Add(-time.Minute)
+	expired := &redisCache{getConn: server.conn, prefix: "ghcache:expired:", expiresAt: &expiredAt}
+	expired.Set("key", []byte("response"))
+	if _, ok := expired.Get("key"); ok {
+		t.Error("expected responses of expired tokens not to be cached")
+	}
+}
+
+type countingExecutor struct {
+	lock  sync.Mutex
+	count int
+}
+
+func (e *countingExecutor) RoundTrip(_ *http.Request) (*http.Response, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.count++
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+}
+
+func TestRedisCoalescer(t *testing.T) {
+	const lockKey = "ghcache:partition:lock:https://api.github.com/repos/org/repo"
+	testCases := []struct {
+		name        string
+		method      string
+		lockedFor   time.Duration
+		expectWait  bool
+		expectCalls int
+	}{
+		{
+			name:        "free resource is fetched right away",
+			method:      http.MethodGet,
+			expectCalls: 1,
+		},
+		{
+			name:        "resource another replica fetches is fetched once it is done",
+			method:      http.MethodGet,
+			lockedFor:   200 * time.Millisecond,
+			expectWait:  true,
+			expectCalls: 1,
+		},
+		{
+			name:        "write requests are not coalesced",
+			method:      http.MethodPost,
+			lockedFor:   time.Hour,
+			expectCalls: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newFakeRedis()
+			executor := &countingExecutor{}
+			coalescer := &redisCoalescer{
+				getConn:         server.conn,
+				prefix:          "ghcache:partition:",
+				requestExecutor: executor,
+				lockTTL:         time.Minute,
+				pollInterval:    10 * time.Millisecond,
+			}
+			if tc.lockedFor > 0 {
+				server.data[lockKey] = []byte("other-replica")
+				timer := time.AfterFunc(tc.lockedFor, func() {
+					server.lock.Lock()
+					delete(server.data, lockKey)
+					server.lock.Unlock()
+				})
+				defer timer.Stop()
+			}
+
+			req, err := http.NewRequest(tc.method, "https://api.github.com/repos/org/repo", nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			start := time.Now()
+			if _, err := coalescer.RoundTrip(req); err != nil {
+				t.Fatalf("Didn't expect error: %v", err)
+			}
+			if waited := time.Since(start) >= tc.lockedFor; tc.expectWait && !waited {
+				t.Error("expected the request to wait for the other replica")
+			}
+			if executor.count != tc.expectCalls {
+				t.Errorf("expected %d requests, got %d", tc.expectCalls, executor.count)
+			}
+			server.lock.Lock()
+			_, locked := server.data[lockKey]
+			server.lock.Unlock()
+			if locked && tc.method == http.MethodGet {
+				t.Error("expected the lock to be released")
+			}
+		})
+	}
+}
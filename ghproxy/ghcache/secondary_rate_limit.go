@@ -0,0 +1,214 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ghcache
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"k8s.io/test-infra/ghproxy/ghmetrics"
+)
+
+const (
+	// secondaryRateLimitRetries is how often a write request that hit the
+	// secondary rate limit is retried before the response is passed on.
+	secondaryRateLimitRetries = 3
+	// secondaryRateLimitDefaultWait is how long requests are held back if
+	// GitHub doesn't say how long to wait, as GitHub recommends.
+	secondaryRateLimitDefaultWait = time.Minute
+	// secondaryRateLimitMaxWait is how long a request without a deadline is
+	// held back at most. Requests with a deadline are held back as long as
+	// it allows.
+	secondaryRateLimitMaxWait = time.Minute
+	// secondaryRateLimitSpreadPeriod is how long after the limit was lifted
+	// write requests are still spread out.
+	secondaryRateLimitSpreadPeriod = 5 * time.Minute
+	// secondaryRateLimitWriteSpacing is the time between two write requests
+	// while they are spread out, before jitter. GitHub recommends to wait at
+	// least a second between writes.
+	secondaryRateLimitWriteSpacing = time.Second
+	// requestAllowance is how much of the deadline of a request is left for
+	// sending it after it was held back.
+	requestAllowance = 5 * time.Second
+
+	secondaryRateLimitRetried  = "retried"
+	secondaryRateLimitPassedOn = "passed_on"
+)
+
+// secondaryRateLimitTransport shapes the write requests of a token around the
+// secondary rate limit of GitHub, which GitHub imposes on tokens that make
+// many concurrent or write requests, independent of the remaining API tokens:
+// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#secondary-rate-limits
+// Once a response says that the limit was hit, write requests are held back
+// until GitHub accepts them again, the ones that hit the limit are retried,
+// and afterwards writes are spread out with jitter so that they don't hit
+// the limit again right away. Requests that can't wait that long reach
+// GitHub, and the response is passed on to the client.
+// Only write requests are shaped, reads pass through unchanged.
+type secondaryRateLimitTransport struct {
+	roundTripper http.RoundTripper
+	hasher       ghmetrics.Hasher
+	writeSpacing time.Duration
+
+	lock sync.Mutex
+	// blockedUntil is when GitHub accepts write requests of the token again.
+	blockedUntil time.Time
+	// spreadUntil is until when write requests are spread out.
+	spreadUntil time.Time
+	// nextWrite is when the next write request may be sent while writes are
+	// spread out.
+	nextWrite time.Time
+}
+
+func newSecondaryRateLimitTransport(roundTripper http.RoundTripper, hasher ghmetrics.Hasher) *secondaryRateLimitTransport {
+	return &secondaryRateLimitTransport{roundTripper: roundTripper, hasher: hasher, writeSpacing: secondaryRateLimitWriteSpacing}
+}
+
+func (s *secondaryRateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return s.roundTripper.RoundTrip(req)
+	}
+
+	// The body must be sent again if the request is retried.
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	tokenBudgetName := req.Header.Get(TokenBudgetIdentifierHeader)
+	if tokenBudgetName == "" {
+		tokenBudgetName = s.hasher.Hash(req)
+	}
+	log := logrus.WithFields(logrus.Fields{"token-budget": tokenBudgetName, "path": req.URL.Path})
+
+	for retry := 0; ; retry++ {
+		if delay := s.reserveWrite(); delay > 0 && canWait(req.Context(), delay) {
+			ghmetrics.CollectSecondaryRateLimitWaitDurationMetrics(tokenBudgetName, delay)
+			select {
+			case <-time.After(delay):
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+		}
+
+		if req.Body != nil {
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		resp, err := s.roundTripper.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		retryAfter, limited := secondaryRateLimitHit(resp)
+		if !limited {
+			return resp, nil
+		}
+		s.block(retryAfter)
+		if retry == secondaryRateLimitRetries || !canWait(req.Context(), retryAfter) {
+			log.WithField("retries", retry).Warn("Write request hit the secondary rate limit, passing the response on.")
+			ghmetrics.CollectSecondaryRateLimitMetrics(tokenBudgetName, req.URL.Path, secondaryRateLimitPassedOn)
+			return resp, nil
+		}
+		log.WithField("retry-after", retryAfter.String()).Debug("Write request hit the secondary rate limit, retrying.")
+		ghmetrics.CollectSecondaryRateLimitMetrics(tokenBudgetName, req.URL.Path, secondaryRateLimitRetried)
+		resp.Body.Close()
+	}
+}
+
+// reserveWrite reserves a time to send a write request at and returns how
+// long to wait for it.
+func (s *secondaryRateLimitTransport) reserveWrite() time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	if now.After(s.spreadUntil) {
+		return 0
+	}
+	slot := now
+	if s.blockedUntil.After(slot) {
+		slot = s.blockedUntil
+	}
+	if s.nextWrite.After(slot) {
+		slot = s.nextWrite
+	}
+	s.nextWrite = slot.Add(wait.Jitter(s.writeSpacing, 1.0))
+	return slot.Sub(now)
+}
+
+// block holds back write requests for the duration and spreads them out
+// afterwards.
+func (s *secondaryRateLimitTransport) block(duration time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	// Jitter keeps the held back requests from reaching GitHub all at once.
+	blockedUntil := time.Now().Add(wait.Jitter(duration, 0.1))
+	if blockedUntil.After(s.blockedUntil) {
+		s.blockedUntil = blockedUntil
+	}
+	s.spreadUntil = s.blockedUntil.Add(secondaryRateLimitSpreadPeriod)
+}
+
+// canWait returns whether the request can be held back for the duration and
+// still be sent before its deadline.
+func canWait(ctx context.Context, duration time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok {
+		return time.Until(deadline) > duration+requestAllowance
+	}
+	return duration <= secondaryRateLimitMaxWait
+}
+
+// secondaryRateLimitHit returns whether the response says that the secondary
+// rate limit was hit, and how long GitHub asks to wait in that case.
+func secondaryRateLimitHit(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	// The primary rate limit is exhausted, waiting won't help.
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return 0, false
+	}
+	if raw := resp.Header.Get("Retry-After"); raw != "" {
+		if seconds, err := strconv.Atoi(raw); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+
+	// Without Retry-After, only the message tells the secondary rate limit
+	// apart from missing permissions.
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return 0, false
+	}
+	message := strings.ToLower(string(body))
+	if strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse detection") {
+		return secondaryRateLimitDefaultWait, true
+	}
+	return 0, false
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ghcache
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/test-infra/ghproxy/ghmetrics"
+)
+
+type fakeResponse struct {
+	status int
+	header http.Header
+	body   string
+}
+
+// fakeGitHub answers requests with its responses in order, repeating the last
+// one, and records the bodies of the requests.
+type fakeGitHub struct {
+	responses []fakeResponse
+	bodies    []string
+}
+
+func (f *fakeGitHub) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = ioutil.ReadAll(req.Body)
+	}
+	f.bodies = append(f.bodies, string(body))
+	response := f.responses[len(f.responses)-1]
+	if len(f.bodies) <= len(f.responses) {
+		response = f.responses[len(f.bodies)-1]
+	}
+	header := response.header
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: response.status, Header: header, Body: ioutil.NopCloser(strings.NewReader(response.body))}, nil
+}
+
+func TestSecondaryRateLimitTransport(t *testing.T) {
+	secondaryLimit := fakeResponse{status: http.StatusForbidden, header: http.Header{"Retry-After": []string{"0"}}}
+	created := fakeResponse{status: http.StatusCreated}
+	testCases := []struct {
+		name             string
+		method           string
+		timeout          time.Duration
+		responses        []fakeResponse
+		expectedStatus   int
+		expectedRequests int
+	}{
+		{
+			name:             "write that hit the secondary rate limit is retried",
+			method:           http.MethodPost,
+			responses:        []fakeResponse{secondaryLimit, created},
+			expectedStatus:   http.StatusCreated,
+			expectedRequests: 2,
+		},
+		{
+			name:             "response is passed on once the retries are exhausted",
+			method:           http.MethodPost,
+			responses:        []fakeResponse{secondaryLimit},
+			expectedStatus:   http.StatusForbidden,
+			expectedRequests: secondaryRateLimitRetries + 1,
+		},
+		{
+			name:             "read is not retried",
+			method:           http.MethodGet,
+			responses:        []fakeResponse{secondaryLimit, {status: http.StatusOK}},
+			expectedStatus:   http.StatusForbidden,
+			expectedRequests: 1,
+		},
+		{
+			name:   "exhausted primary rate limit is not retried",
+			method: http.MethodPost,
+			responses: []fakeResponse{
+				{status: http.StatusForbidden, header: http.Header{"Retry-After": []string{"0"}, "X-Ratelimit-Remaining": []string{"0"}}},
+				created,
+			},
+			expectedStatus:   http.StatusForbidden,
+			expectedRequests: 1,
+		},
+		{
+			name:             "missing permissions are not retried",
+			method:           http.MethodPost,
+			responses:        []fakeResponse{{status: http.StatusForbidden, body: `{"message": "Resource not accessible by integration"}`}, created},
+			expectedStatus:   http.StatusForbidden,
+			expectedRequests: 1,
+		},
+		{
+			name:    "response is passed on if the request can't wait for the limit to be lifted",
+			method:  http.MethodPost,
+			timeout: 10 * time.Second,
+			responses: []fakeResponse{
+				{status: http.StatusForbidden, body: `{"message": "You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`},
+				created,
+			},
+			expectedStatus:   http.StatusForbidden,
+			expectedRequests: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			github := &fakeGitHub{responses: tc.responses}
+			transport := newSecondaryRateLimitTransport(github, ghmetrics.NewCachingHasher())
+			transport.writeSpacing = 0
+
+			ctx := context.Background()
+			if tc.timeout != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+			req, err := http.NewRequestWithContext(ctx, tc.method, "https://api.github.com/repos/org/repo/issues/1/comments", bytes.NewBufferString(`{"body": "hi"}`))
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("Didn't expect error: %v", err)
+			}
+			if resp.StatusCode != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d", tc.expectedStatus, resp.StatusCode)
+			}
+			if len(github.bodies) != tc.expectedRequests {
+				t.Fatalf("expected %d requests, got %d", tc.expectedRequests, len(github.bodies))
+			}
+			if tc.method != http.MethodGet {
+				for i, body := range github.bodies {
+					if body != `{"body": "hi"}` {
+						t.Errorf("expected request %d to have the body of the original request, got %q", i, body)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSecondaryRateLimitTransportSpreadsWrites(t *testing.T) {
+	transport := newSecondaryRateLimitTransport(nil, nil)
+	if delay := transport.reserveWrite(); delay != 0 {
+		t.Errorf("expected writes not to be held back before the limit was hit, got %v", delay)
+	}
+
+	transport.block(time.Minute)
+	first := transport.reserveWrite()
+	if first < 50*time.Second || first > 70*time.Second {
+		t.Errorf("expected the first write to be held back until the limit is lifted, got %v", first)
+	}
+	second := transport.reserveWrite()
+	if spacing := second - first; spacing < secondaryRateLimitWriteSpacing || spacing > 2*secondaryRateLimitWriteSpacing {
+		t.Errorf("expected the second write to be sent between one and two write spacings after the first, got %v", spacing)
+	}
+}
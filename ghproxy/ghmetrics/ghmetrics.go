@@ -101,6 +101,29 @@ var cacheEntryAge = prometheus.NewHistogramVec(
 	[]string{"token_hash", "path", "user_agent"},
 )
 
+// secondaryRateLimitCounter provides the 'github_secondary_rate_limits' counter
+// that keeps track of the responses that hit the secondary rate limit of
+// GitHub by whether the request was retried or the response passed on.
+var secondaryRateLimitCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "github_secondary_rate_limits",
+		Help: "How many responses hit the secondary rate limit of GitHub by API path and outcome.",
+	},
+	[]string{"token_hash", "path", "outcome"},
+)
+
+// secondaryRateLimitWaitDuration provides the 'github_secondary_rate_limit_wait_duration_seconds'
+// histogram that keeps track of how long write requests were held back
+// because of the secondary rate limit.
+var secondaryRateLimitWaitDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "github_secondary_rate_limit_wait_duration_seconds",
+		Help:    "How long write requests were held back because of the secondary rate limit of GitHub in seconds.",
+		Buckets: []float64{0.5, 1, 2.5, 5, 10, 30, 60},
+	},
+	[]string{"token_hash"},
+)
+
 var muxTokenUsage sync.Mutex
 var lastGitHubResponse time.Time
 
@@ -112,6 +135,8 @@ func init() {
 	prometheus.MustRegister(cacheCounter)
 	prometheus.MustRegister(timeoutDuration)
 	prometheus.MustRegister(cacheEntryAge)
+	prometheus.MustRegister(secondaryRateLimitCounter)
+	prometheus.MustRegister(secondaryRateLimitWaitDuration)
 }
 
 // CollectGitHubTokenMetrics publishes the rate limits of the github api to
@@ -194,3 +219,15 @@ func CollectRequestTimeoutMetrics(tokenHash, path, userAgent string, reqStartTim
 func CollectGitHubRequestWaitDurationMetrics(tokenHash, requestType, api string, duration time.Duration) {
 	ghRequestWaitDurationHistVec.With(prometheus.Labels{"token_hash": tokenHash, "request_type": requestType, "api": api}).Observe(duration.Seconds())
 }
+
+// CollectSecondaryRateLimitMetrics publishes a response that hit the secondary
+// rate limit to 'github_secondary_rate_limits' on prometheus.
+func CollectSecondaryRateLimitMetrics(tokenHash, path, outcome string) {
+	secondaryRateLimitCounter.With(prometheus.Labels{"token_hash": tokenHash, "path": simplifier.Simplify(path), "outcome": outcome}).Inc()
+}
+
+// CollectSecondaryRateLimitWaitDurationMetrics publishes how long a write
+// request was held back because of the secondary rate limit on prometheus.
+func CollectSecondaryRateLimitWaitDurationMetrics(tokenHash string, duration time.Duration) {
+	secondaryRateLimitWaitDuration.With(prometheus.Labels{"token_hash": tokenHash}).Observe(duration.Seconds())
+}
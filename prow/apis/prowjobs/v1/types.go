@@ -28,6 +28,7 @@ import (
 	pipelinev1alpha1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	prowgithub "k8s.io/test-infra/prow/github"
 )
@@ -94,6 +95,10 @@ const (
 
 	// ProwJobFile is the JSON file that stores the prowjob information.
 	ProwJobFile = "prowjob.json"
+
+	// ArtifactManifestFile is the JSON file that lists the build logs and
+	// artifacts uploaded by the sidecar with their sizes and checksums.
+	ArtifactManifestFile = "artifacts-manifest.json"
 )
 
 // +genclient
@@ -134,6 +139,9 @@ type ProwJobSpec struct {
 	// to run the job, only applicable for that
 	// specific agent
 	Cluster string `json:"cluster,omitempty"`
+	// ClusterSelector makes plank pick the cluster when it starts the
+	// job, if Cluster is unset. Only applicable for the kubernetes agent.
+	ClusterSelector *ClusterSelector `json:"cluster_selector,omitempty"`
 	// Namespace defines where to create pods/resources.
 	Namespace string `json:"namespace,omitempty"`
 	// Job is the name of the job
@@ -163,6 +171,13 @@ type ProwJobSpec struct {
 	// If this field is unspecified or false, a new pod will be created to replace
 	// the evicted one.
 	ErrorOnEviction bool `json:"error_on_eviction,omitempty"`
+	// RetryPolicy makes plank re-create the pod of the ProwJob when it fails,
+	// instead of completing the ProwJob. Only applies to the kubernetes agent.
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty"`
+	// RunAfter are the names of the jobs this job depends on. Plank starts
+	// the job once the latest ProwJob of each of them for the same refs
+	// succeeded. Only applies to the kubernetes agent.
+	RunAfter []string `json:"run_after,omitempty"`
 
 	// PodSpec provides the basis for running the test under
 	// a Kubernetes agent
@@ -219,6 +234,9 @@ type RerunAuthConfig struct {
 	GitHubUsers []string `json:"github_users,omitempty"`
 	// GitHubOrgs contains names of GitHub organizations whose members can rerun the job
 	GitHubOrgs []string `json:"github_orgs,omitempty"`
+	// Groups contains names of groups whose members can rerun the job, e.g. OIDC groups
+	// asserted by an authenticating proxy in front of Deck or the groups of Deck's API tokens
+	Groups []string `json:"groups,omitempty"`
 }
 
 // IsSpecifiedUser returns true if AllowAnyone is set to true or if the given user is
@@ -269,13 +287,28 @@ func (rac *RerunAuthConfig) IsAuthorized(org, user string, cli prowgithub.RerunC
 	return false, nil
 }
 
+// IsAuthorizedGroup returns true if any of the given groups is permitted to rerun the job.
+func (rac *RerunAuthConfig) IsAuthorizedGroup(groups []string) bool {
+	if rac == nil {
+		return false
+	}
+	for _, permitted := range rac.Groups {
+		for _, group := range groups {
+			if permitted == group {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Validate validates the RerunAuthConfig fields.
 func (rac *RerunAuthConfig) Validate() error {
 	if rac == nil {
 		return nil
 	}
 
-	hasAllowList := len(rac.GitHubUsers) > 0 || len(rac.GitHubTeamIDs) > 0 || len(rac.GitHubTeamSlugs) > 0 || len(rac.GitHubOrgs) > 0
+	hasAllowList := len(rac.GitHubUsers) > 0 || len(rac.GitHubTeamIDs) > 0 || len(rac.GitHubTeamSlugs) > 0 || len(rac.GitHubOrgs) > 0 || len(rac.Groups) > 0
 
 	// If an allowlist is specified, the user probably does not intend for anyone to be able to rerun any job.
 	if rac.AllowAnyone && hasAllowList {
@@ -294,6 +327,98 @@ func (rac *RerunAuthConfig) IsAllowAnyone() bool {
 	return rac.AllowAnyone
 }
 
+// ClusterSelector holds the requirements for the build cluster of a ProwJob.
+// Of the build clusters that meet them, the one with the most capacity left
+// is picked.
+type ClusterSelector struct {
+	// Clusters are the candidate build clusters, all the build clusters if
+	// empty.
+	Clusters []string `json:"clusters,omitempty"`
+	// NodeLabels are the labels of the nodes that can run the ProwJob, like
+	// the GPU or the architecture. The pod of the ProwJob is restricted to
+	// these nodes.
+	NodeLabels map[string]string `json:"node_labels,omitempty"`
+}
+
+// RetryCondition is a kind of failure of the pod of a ProwJob.
+type RetryCondition string
+
+const (
+	// InfraErrorCondition means the job didn't get to run its test: the pod
+	// failed without container statuses, an init container failed or a
+	// container got OOMKilled.
+	InfraErrorCondition RetryCondition = "infra_error"
+	// TestFailureCondition means the test of the job failed.
+	TestFailureCondition RetryCondition = "test_failure"
+)
+
+// DefaultRetryBackoff is the backoff before the first retry of a ProwJob
+// when its retry policy doesn't set one.
+const DefaultRetryBackoff = 30 * time.Second
+
+// RetryPolicy holds the configuration for retrying the failed pods of a
+// ProwJob.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of pods run for the ProwJob,
+	// including the first one.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// Backoff is the time waited before the first retry, it doubles with
+	// every retry. Defaults to 30s.
+	Backoff *Duration `json:"backoff,omitempty"`
+	// RetryOn are the failures retried, infra_error and/or test_failure.
+	// Defaults to infra_error.
+	RetryOn []RetryCondition `json:"retry_on,omitempty"`
+}
+
+// Validate validates the RetryPolicy fields.
+func (rp *RetryPolicy) Validate() error {
+	if rp == nil {
+		return nil
+	}
+	if rp.MaxAttempts < 1 {
+		return fmt.Errorf("retry policy max_attempts must be at least 1 (found %d)", rp.MaxAttempts)
+	}
+	if rp.Backoff != nil && rp.Backoff.Duration <= 0 {
+		return fmt.Errorf("retry policy backoff must be positive (found %s)", rp.Backoff.Duration)
+	}
+	for _, condition := range rp.RetryOn {
+		if condition != InfraErrorCondition && condition != TestFailureCondition {
+			return fmt.Errorf("retry policy retry_on must be one of %q or %q (found %q)", InfraErrorCondition, TestFailureCondition, condition)
+		}
+	}
+	return nil
+}
+
+// ShouldRetry returns true if a ProwJob that already made the given number
+// of failed attempts should be retried after a failure of the condition.
+func (rp *RetryPolicy) ShouldRetry(condition RetryCondition, attempts int) bool {
+	if rp == nil || attempts >= rp.MaxAttempts {
+		return false
+	}
+	if len(rp.RetryOn) == 0 {
+		return condition == InfraErrorCondition
+	}
+	for _, retryOn := range rp.RetryOn {
+		if retryOn == condition {
+			return true
+		}
+	}
+	return false
+}
+
+// BackoffFor returns the time to wait before retrying a ProwJob that made
+// the given number of failed attempts.
+func (rp *RetryPolicy) BackoffFor(attempts int) time.Duration {
+	backoff := DefaultRetryBackoff
+	if rp != nil && rp.Backoff != nil {
+		backoff = rp.Backoff.Duration
+	}
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+	}
+	return backoff
+}
+
 type ReporterConfig struct {
 	Slack *SlackReporterConfig `json:"slack,omitempty"`
 }
@@ -443,6 +568,15 @@ type DecorationConfig struct {
 	// GitHubAppPrivateKeySecret is a Kubernetes secret that contains the GitHub App private key,
 	// which is going to be used for fetching a private repository.
 	GitHubAppPrivateKeySecret *GitHubAppPrivateKeySecret `json:"github_app_private_key_secret,omitempty"`
+	// SubmoduleCredentialSecrets maps hosts that submodules are fetched
+	// from over HTTPS to Kubernetes secrets that contain an OAuth token,
+	// which is going to be used for fetching them from that host.
+	SubmoduleCredentialSecrets map[string]OauthTokenSecret `json:"submodule_credential_secrets,omitempty"`
+	// GitMirrorClaim is the name of a PersistentVolumeClaim that holds bare
+	// mirrors of repositories, laid out as <org>/<repo>.git. It is mounted
+	// read-only into clonerefs, which borrows objects from the mirrors so
+	// that only what they lack is fetched.
+	GitMirrorClaim string `json:"git_mirror_claim,omitempty"`
 
 	// CensorSecrets enables censoring output logs and artifacts.
 	CensorSecrets *bool `json:"censor_secrets,omitempty"`
@@ -453,6 +587,73 @@ type DecorationConfig struct {
 	// UploadIgnoresInterrupts causes sidecar to ignore interrupts for the upload process in
 	// hope that the test process exits cleanly before starting an upload.
 	UploadIgnoresInterrupts *bool `json:"upload_ignores_interrupts,omitempty"`
+
+	// StreamingOptions make sidecar upload the build logs and selected
+	// artifacts while the job runs.
+	StreamingOptions *StreamingOptions `json:"streaming_options,omitempty"`
+
+	// ArtifactRetention assigns retention classes to the artifacts of the job.
+	// The class of every artifact is recorded in the artifact manifest and set
+	// as the retention-class metadata of its object in blob storage, so that
+	// lifecycle policies can act on it.
+	ArtifactRetention []ArtifactRetentionClass `json:"artifact_retention,omitempty"`
+
+	// Steps replace the command of the test container with a list of
+	// commands that entrypoint runs in order, recording their exit codes and
+	// durations in steps.json in the artifacts. The job must have a single
+	// test container that does not set a command or args.
+	Steps []Step `json:"steps,omitempty"`
+
+	// Caches are mounted into the test containers and shared by the jobs that
+	// run for the same repo and branch, e.g. the Go module cache.
+	Caches []Cache `json:"caches,omitempty"`
+
+	// CloneDepth, SparseCheckout and PartialClone are the defaults for the
+	// fields of the same name of the primary refs of the job, the refs the
+	// default decoration config is picked for, if they are unset there.
+	CloneDepth     *int     `json:"clone_depth,omitempty"`
+	SparseCheckout []string `json:"sparse_checkout,omitempty"`
+	PartialClone   *bool    `json:"partial_clone,omitempty"`
+}
+
+// Cache is a named build cache of the test containers of a decorated job,
+// e.g. the Go module, Bazel or npm cache. The cache lives in a volume of the
+// build cluster, in a directory keyed by its name and the repo and branch of
+// the job, so that jobs building the same code share it.
+type Cache struct {
+	// Name identifies the cache. It must be a DNS label of at most 57
+	// characters.
+	Name string `json:"name"`
+	// MountPath is where the cache is mounted in the test containers.
+	MountPath string `json:"mount_path"`
+	// Env is the name of an environment variable set to MountPath in the
+	// test containers, e.g. GOMODCACHE, for tools that read the location of
+	// their cache from the environment.
+	Env string `json:"env,omitempty"`
+	// PersistentVolumeClaim is the name of the PVC in the namespace of the
+	// test pods that holds the cache. As it is used by concurrent jobs, it
+	// must support the ReadWriteMany access mode.
+	PersistentVolumeClaim string `json:"persistent_volume_claim,omitempty"`
+	// CSI is the volume of a cache CSI driver that holds the cache.
+	// Mutually exclusive with PersistentVolumeClaim.
+	CSI *corev1.CSIVolumeSource `json:"csi,omitempty"`
+}
+
+// Validate ensures the cache has a name, a mount path and a volume.
+func (c *Cache) Validate() error {
+	if errs := validation.IsDNS1123Label(c.Name); len(errs) > 0 {
+		return fmt.Errorf("name %q is not a DNS label: %s", c.Name, strings.Join(errs, ", "))
+	}
+	if len(c.Name) > 57 {
+		return fmt.Errorf("name %q is longer than 57 characters", c.Name)
+	}
+	if !strings.HasPrefix(c.MountPath, "/") {
+		return fmt.Errorf("mount_path %q of cache %s is not absolute", c.MountPath, c.Name)
+	}
+	if (c.PersistentVolumeClaim == "") == (c.CSI == nil) {
+		return fmt.Errorf("cache %s must specify exactly one of persistent_volume_claim and csi", c.Name)
+	}
+	return nil
 }
 
 type CensoringOptions struct {
@@ -516,6 +717,52 @@ func (g *CensoringOptions) ApplyDefault(def *CensoringOptions) *CensoringOptions
 	return &merged
 }
 
+// StreamingOptions configure uploading the build logs and artifacts of a job
+// while it runs, so that they can be viewed before it finishes and are kept
+// if its pod is lost. Only the bytes written since the last upload are
+// appended to the objects in GCS, other blob stores do not support appending
+// to objects, so every upload replaces the object with everything written so
+// far.
+type StreamingOptions struct {
+	// Interval is how often the build logs and artifacts that changed
+	// are uploaded. If unset, defaults to 30s.
+	Interval *Duration `json:"interval,omitempty"`
+	// Artifacts are the artifacts which should be uploaded as well. Entries
+	// in this list are relative to $ARTIFACTS and are parsed with the go-zglob
+	// library, allowing for globbed matches.
+	Artifacts []string `json:"artifacts,omitempty"`
+}
+
+// ArtifactRetentionClass assigns a retention class to the artifacts matching
+// its globs. An artifact gets the first class that matches it.
+type ArtifactRetentionClass struct {
+	// Class is the name of the retention class, e.g. "short-lived".
+	Class string `json:"class"`
+	// Artifacts are the artifacts of the class. Entries in this list are
+	// relative to $ARTIFACTS and are parsed with the go-zglob library,
+	// allowing for globbed matches.
+	Artifacts []string `json:"artifacts"`
+}
+
+// Step is a command entrypoint runs as part of the steps of a job. Steps run
+// one after another; adjacent steps of the same parallel group run
+// concurrently. Once a step fails that does not allow failure, the steps
+// after it are skipped.
+type Step struct {
+	// Name identifies the step in the build log and in steps.json.
+	Name string `json:"name"`
+	// Command is the command line of the step, it is not run in a shell.
+	Command []string `json:"command"`
+	// Timeout is how long the step may run. The timeout of the job applies
+	// to all steps regardless.
+	Timeout *Duration `json:"timeout,omitempty"`
+	// AllowFailure keeps the job from failing if the step fails.
+	AllowFailure bool `json:"allow_failure,omitempty"`
+	// ParallelGroup runs the step concurrently with the adjacent steps of
+	// the same group.
+	ParallelGroup string `json:"parallel_group,omitempty"`
+}
+
 // Resources holds resource requests and limits for
 // containers used to decorate a PodSpec
 type Resources struct {
@@ -647,6 +894,12 @@ func (d *DecorationConfig) ApplyDefault(def *DecorationConfig) *DecorationConfig
 	if merged.GitHubAppPrivateKeySecret == nil {
 		merged.GitHubAppPrivateKeySecret = def.GitHubAppPrivateKeySecret
 	}
+	if len(merged.SubmoduleCredentialSecrets) == 0 {
+		merged.SubmoduleCredentialSecrets = def.SubmoduleCredentialSecrets
+	}
+	if merged.GitMirrorClaim == "" {
+		merged.GitMirrorClaim = def.GitMirrorClaim
+	}
 	if merged.CensorSecrets == nil {
 		merged.CensorSecrets = def.CensorSecrets
 	}
@@ -654,6 +907,27 @@ func (d *DecorationConfig) ApplyDefault(def *DecorationConfig) *DecorationConfig
 	if merged.UploadIgnoresInterrupts == nil {
 		merged.UploadIgnoresInterrupts = def.UploadIgnoresInterrupts
 	}
+	if merged.StreamingOptions == nil {
+		merged.StreamingOptions = def.StreamingOptions
+	}
+	if len(merged.ArtifactRetention) == 0 {
+		merged.ArtifactRetention = def.ArtifactRetention
+	}
+	if len(merged.Steps) == 0 {
+		merged.Steps = def.Steps
+	}
+	if len(merged.Caches) == 0 {
+		merged.Caches = def.Caches
+	}
+	if merged.CloneDepth == nil {
+		merged.CloneDepth = def.CloneDepth
+	}
+	if len(merged.SparseCheckout) == 0 {
+		merged.SparseCheckout = def.SparseCheckout
+	}
+	if merged.PartialClone == nil {
+		merged.PartialClone = def.PartialClone
+	}
 
 	return &merged
 }
@@ -693,6 +967,54 @@ func (d *DecorationConfig) Validate() error {
 	if d.OauthTokenSecret != nil && len(d.SSHKeySecrets) > 0 {
 		return errors.New("both OAuth token and SSH key secrets are specified")
 	}
+	for host, secret := range d.SubmoduleCredentialSecrets {
+		if host == "" || strings.Contains(host, "/") {
+			return fmt.Errorf("submodule credential host %q is not a host name", host)
+		}
+		if secret.Name == "" || secret.Key == "" {
+			return fmt.Errorf("submodule credential secret for host %s must specify a name and a key", host)
+		}
+	}
+	if d.StreamingOptions != nil && d.StreamingOptions.Interval != nil && d.StreamingOptions.Interval.Duration <= 0 {
+		return fmt.Errorf("streaming interval %s is not positive", d.StreamingOptions.Interval.Duration)
+	}
+	for _, retention := range d.ArtifactRetention {
+		if retention.Class == "" {
+			return errors.New("artifact retention class must specify a class")
+		}
+		if len(retention.Artifacts) == 0 {
+			return fmt.Errorf("artifact retention class %s must specify artifacts", retention.Class)
+		}
+	}
+	if d.CloneDepth != nil && *d.CloneDepth < 0 {
+		return fmt.Errorf("clone depth %d is negative", *d.CloneDepth)
+	}
+	steps := map[string]bool{}
+	for _, step := range d.Steps {
+		if step.Name == "" {
+			return errors.New("step must specify a name")
+		}
+		if steps[step.Name] {
+			return fmt.Errorf("step %s is specified more than once", step.Name)
+		}
+		steps[step.Name] = true
+		if len(step.Command) == 0 {
+			return fmt.Errorf("step %s must specify a command", step.Name)
+		}
+		if step.Timeout != nil && step.Timeout.Duration <= 0 {
+			return fmt.Errorf("timeout %s of step %s is not positive", step.Timeout.Duration, step.Name)
+		}
+	}
+	names := map[string]bool{}
+	for i := range d.Caches {
+		if err := d.Caches[i].Validate(); err != nil {
+			return fmt.Errorf("cache configuration is invalid: %w", err)
+		}
+		if names[d.Caches[i].Name] {
+			return fmt.Errorf("cache %s is specified more than once", d.Caches[i].Name)
+		}
+		names[d.Caches[i].Name] = true
+	}
 	return nil
 }
 
@@ -889,6 +1211,10 @@ type ProwJobStatus struct {
 	PendingTime *metav1.Time `json:"pendingTime,omitempty"`
 	// CompletionTime is the timestamp for when the job goes to a final state
 	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// TimeoutTime is the timestamp for when the job ran out of time and its
+	// pod was stopped. The job is aborted once its pod terminated, so that
+	// the pod utilities upload the artifacts of the job.
+	TimeoutTime *metav1.Time `json:"timeoutTime,omitempty"`
 	// +kubebuilder:validation:Enum=triggered;pending;success;failure;aborted;error
 	// +kubebuilder:validation:Required
 	State       ProwJobState `json:"state,omitempty"`
@@ -917,6 +1243,36 @@ type ProwJobStatus struct {
 	// PrevReportStates stores the previous reported prowjob state per reporter
 	// So crier won't make duplicated report attempt
 	PrevReportStates map[string]ProwJobState `json:"prev_report_states,omitempty"`
+
+	// Attempts are the failed pods of the ProwJob that were retried per its
+	// retry policy, oldest first.
+	Attempts []ProwJobAttempt `json:"attempts,omitempty"`
+
+	// Prerequisites are the ProwJobs of the jobs in RunAfter that succeeded
+	// before the job was started.
+	Prerequisites []ProwJobPrerequisite `json:"prerequisites,omitempty"`
+}
+
+// ProwJobAttempt describes a failed pod of a ProwJob that was retried.
+type ProwJobAttempt struct {
+	PodName string `json:"pod_name,omitempty"`
+	BuildID string `json:"build_id,omitempty"`
+	// CompletionTime is the timestamp for when the failure was observed.
+	CompletionTime metav1.Time `json:"completionTime,omitempty"`
+	// RetryTime is the timestamp after which the next pod is started.
+	RetryTime metav1.Time    `json:"retryTime,omitempty"`
+	Condition RetryCondition `json:"condition,omitempty"`
+}
+
+// ProwJobPrerequisite describes the ProwJob a job waited for before it started.
+type ProwJobPrerequisite struct {
+	// Job is the name of the prerequisite job.
+	Job string `json:"job"`
+	// ProwJob is the name of the ProwJob of the prerequisite job.
+	ProwJob string `json:"prowjob"`
+	// ArtifactsURL is where the prerequisite uploaded its artifacts to,
+	// e.g. gs://bucket/pr-logs/pull/org_repo/1/job/1234/artifacts.
+	ArtifactsURL string `json:"artifacts_url,omitempty"`
 }
 
 // Complete returns true if the prow job has finished
@@ -1002,6 +1358,18 @@ type Refs struct {
 	// Multiheaded repos may need to not make this call.
 	// The git fetch <remote> <BaseRef> call occurs regardless.
 	SkipFetchHead bool `json:"skip_fetch_head,omitempty"`
+	// SparseCheckout are the directories checked out, in addition
+	// to the files at the root of the repository. All files are
+	// checked out if it is empty.
+	SparseCheckout []string `json:"sparse_checkout,omitempty"`
+	// PartialClone makes a blobless clone: the contents of files
+	// are only fetched when they are checked out, or lazily from
+	// the clone URI when they are needed later on.
+	PartialClone bool `json:"partial_clone,omitempty"`
+	// FetchLFS fetches the Git LFS objects of the repository
+	// after it is checked out. It requires git-lfs in the
+	// clonerefs image.
+	FetchLFS bool `json:"fetch_lfs,omitempty"`
 }
 
 func (r Refs) String() string {
@@ -18,12 +18,14 @@ package v1
 
 import (
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	fuzz "github.com/google/gofuzz"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func pStr(str string) *string {
@@ -284,6 +286,56 @@ func TestDecorationDefaultingDoesntOverwrite(t *testing.T) {
 				return def
 			},
 		},
+		{
+			name: "caches provided",
+			provided: &DecorationConfig{
+				Caches: []Cache{{Name: "npm", MountPath: "/caches/npm", PersistentVolumeClaim: "caches"}},
+			},
+			expected: func(orig, def *DecorationConfig) *DecorationConfig {
+				def.Caches = orig.Caches
+				return def
+			},
+		},
+		{
+			name: "streaming options provided",
+			provided: &DecorationConfig{
+				StreamingOptions: &StreamingOptions{Artifacts: []string{"progress/**"}},
+			},
+			expected: func(orig, def *DecorationConfig) *DecorationConfig {
+				def.StreamingOptions = orig.StreamingOptions
+				return def
+			},
+		},
+		{
+			name: "steps provided",
+			provided: &DecorationConfig{
+				Steps: []Step{{Name: "build", Command: []string{"make"}}},
+			},
+			expected: func(orig, def *DecorationConfig) *DecorationConfig {
+				def.Steps = orig.Steps
+				return def
+			},
+		},
+		{
+			name: "artifact retention provided",
+			provided: &DecorationConfig{
+				ArtifactRetention: []ArtifactRetentionClass{{Class: "short-lived", Artifacts: []string{"**/*.tar"}}},
+			},
+			expected: func(orig, def *DecorationConfig) *DecorationConfig {
+				def.ArtifactRetention = orig.ArtifactRetention
+				return def
+			},
+		},
+		{
+			name: "submodule credential secrets provided",
+			provided: &DecorationConfig{
+				SubmoduleCredentialSecrets: map[string]OauthTokenSecret{"gitlab.com": {Name: "gitlab", Key: "token"}},
+			},
+			expected: func(orig, def *DecorationConfig) *DecorationConfig {
+				def.SubmoduleCredentialSecrets = orig.SubmoduleCredentialSecrets
+				return def
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -311,6 +363,11 @@ func TestDecorationDefaultingDoesntOverwrite(t *testing.T) {
 				SSHKeySecrets:        []string{"first", "second"},
 				SSHHostFingerprints:  []string{"primero", "segundo"},
 				SkipCloning:          &truth,
+				Caches:               []Cache{{Name: "go-mod", MountPath: "/caches/go-mod", Env: "GOMODCACHE", PersistentVolumeClaim: "caches"}},
+				SubmoduleCredentialSecrets: map[string]OauthTokenSecret{
+					"github.com": {Name: "github", Key: "token"},
+				},
+				StreamingOptions: &StreamingOptions{Interval: &Duration{Duration: time.Minute}},
 			}
 
 			expected := tc.expected(tc.provided, defaults)
@@ -544,6 +601,42 @@ func TestRerunAuthConfigIsAuthorized(t *testing.T) {
 	}
 }
 
+func TestRerunAuthConfigIsAuthorizedGroup(t *testing.T) {
+	var testCases = []struct {
+		name       string
+		groups     []string
+		config     *RerunAuthConfig
+		authorized bool
+	}{
+		{
+			name:       "authorized - group in Groups",
+			groups:     []string{"devs", "release-managers"},
+			config:     &RerunAuthConfig{Groups: []string{"release-managers"}},
+			authorized: true,
+		},
+		{
+			name:       "unauthorized - no group in Groups",
+			groups:     []string{"devs"},
+			config:     &RerunAuthConfig{Groups: []string{"release-managers"}},
+			authorized: false,
+		},
+		{
+			name:       "unauthorized - RerunAuthConfig is nil",
+			groups:     []string{"devs"},
+			config:     nil,
+			authorized: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tc.config.IsAuthorizedGroup(tc.groups); actual != tc.authorized {
+				t.Errorf("Expected %v, got %v", tc.authorized, actual)
+			}
+		})
+	}
+}
+
 func TestRerunAuthConfigIsAllowAnyone(t *testing.T) {
 	var testCases = []struct {
 		name     string
@@ -582,6 +675,157 @@ func TestRerunAuthConfigIsAllowAnyone(t *testing.T) {
 	}
 }
 
+func TestCacheValidate(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		cache       Cache
+		errExpected bool
+	}{
+		{
+			name:  "cache in a PVC",
+			cache: Cache{Name: "go-mod", MountPath: "/caches/go-mod", Env: "GOMODCACHE", PersistentVolumeClaim: "caches"},
+		},
+		{
+			name:  "cache in a CSI volume",
+			cache: Cache{Name: "bazel", MountPath: "/caches/bazel", CSI: &corev1.CSIVolumeSource{Driver: "cache.csi.example.com"}},
+		},
+		{
+			name:        "name is not a DNS label",
+			cache:       Cache{Name: "Go_Mod", MountPath: "/caches/go-mod", PersistentVolumeClaim: "caches"},
+			errExpected: true,
+		},
+		{
+			name:        "name is too long",
+			cache:       Cache{Name: strings.Repeat("a", 58), MountPath: "/caches/a", PersistentVolumeClaim: "caches"},
+			errExpected: true,
+		},
+		{
+			name:        "relative mount path",
+			cache:       Cache{Name: "npm", MountPath: "caches/npm", PersistentVolumeClaim: "caches"},
+			errExpected: true,
+		},
+		{
+			name:        "no volume",
+			cache:       Cache{Name: "npm", MountPath: "/caches/npm"},
+			errExpected: true,
+		},
+		{
+			name:        "PVC and CSI volume",
+			cache:       Cache{Name: "npm", MountPath: "/caches/npm", PersistentVolumeClaim: "caches", CSI: &corev1.CSIVolumeSource{Driver: "cache.csi.example.com"}},
+			errExpected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.cache.Validate(); (err != nil) != tc.errExpected {
+				t.Errorf("Expected error %v, got %v", tc.errExpected, err)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		policy      *RetryPolicy
+		errExpected bool
+	}{
+		{
+			name: "no policy",
+		},
+		{
+			name:   "valid policy",
+			policy: &RetryPolicy{MaxAttempts: 3, Backoff: &Duration{Duration: time.Minute}, RetryOn: []RetryCondition{InfraErrorCondition, TestFailureCondition}},
+		},
+		{
+			name:        "no attempts",
+			policy:      &RetryPolicy{},
+			errExpected: true,
+		},
+		{
+			name:        "negative backoff",
+			policy:      &RetryPolicy{MaxAttempts: 3, Backoff: &Duration{Duration: -time.Minute}},
+			errExpected: true,
+		},
+		{
+			name:        "unknown condition",
+			policy:      &RetryPolicy{MaxAttempts: 3, RetryOn: []RetryCondition{"timeout"}},
+			errExpected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.policy.Validate(); (err != nil) != tc.errExpected {
+				t.Errorf("Expected error %v, got %v", tc.errExpected, err)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		policy    *RetryPolicy
+		condition RetryCondition
+		attempts  int
+		expected  bool
+	}{
+		{
+			name:      "no policy",
+			condition: InfraErrorCondition,
+			attempts:  1,
+		},
+		{
+			name:      "infra errors are retried by default",
+			policy:    &RetryPolicy{MaxAttempts: 2},
+			condition: InfraErrorCondition,
+			attempts:  1,
+			expected:  true,
+		},
+		{
+			name:      "test failures are not retried by default",
+			policy:    &RetryPolicy{MaxAttempts: 2},
+			condition: TestFailureCondition,
+			attempts:  1,
+		},
+		{
+			name:      "test failures are retried when configured",
+			policy:    &RetryPolicy{MaxAttempts: 2, RetryOn: []RetryCondition{TestFailureCondition}},
+			condition: TestFailureCondition,
+			attempts:  1,
+			expected:  true,
+		},
+		{
+			name:      "no retry after the last attempt",
+			policy:    &RetryPolicy{MaxAttempts: 2},
+			condition: InfraErrorCondition,
+			attempts:  2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tc.policy.ShouldRetry(tc.condition, tc.attempts); actual != tc.expected {
+				t.Errorf("Expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoffFor(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 4, Backoff: &Duration{Duration: time.Minute}}
+	for attempts, expected := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 3: 4 * time.Minute} {
+		if actual := policy.BackoffFor(attempts); actual != expected {
+			t.Errorf("Expected backoff %s after %d attempts, got %s", expected, attempts, actual)
+		}
+	}
+	if actual := (&RetryPolicy{MaxAttempts: 2}).BackoffFor(1); actual != DefaultRetryBackoff {
+		t.Errorf("Expected default backoff %s, got %s", DefaultRetryBackoff, actual)
+	}
+}
+
 func TestParsePath(t *testing.T) {
 	type args struct {
 		bucket string
@@ -73,6 +73,15 @@ type Options struct {
 	GitHubAppID             string   `json:"github_app_id,omitempty"`
 	GitHubAppPrivateKeyFile string   `json:"github_app_private_key_file,omitempty"`
 
+	// SubmoduleCredentialFiles maps hosts that submodules are fetched
+	// from over HTTPS to files that contain an OAuth token for that host.
+	SubmoduleCredentialFiles map[string]string `json:"submodule_credential_files,omitempty"`
+
+	// GitMirrorDir is a directory that holds bare mirrors of repositories,
+	// laid out as <org>/<repo>.git. Objects are borrowed from the mirrors
+	// while cloning so that only what they lack is fetched.
+	GitMirrorDir string `json:"git_mirror_dir,omitempty"`
+
 	// used to hold flag values
 	refs      gitRefs
 	clonePath orgRepoFormat
@@ -124,6 +133,12 @@ func (o *Options) Validate() error {
 		return errors.New("no GitHub App ID specified")
 	}
 
+	for host, file := range o.SubmoduleCredentialFiles {
+		if host == "" || file == "" {
+			return fmt.Errorf("invalid submodule credential file %q for host %q", file, host)
+		}
+	}
+
 	return nil
 }
 
@@ -182,6 +197,7 @@ func (o *Options) AddFlags(fs *flag.FlagSet) {
 	fs.Var(&o.cloneURI, "uri-prefix", "Format string for the URI prefix to clone from")
 	fs.IntVar(&o.MaxParallelWorkers, "max-workers", 0, "Maximum number of parallel workers, unset for unlimited.")
 	fs.StringVar(&o.CookiePath, "cookiefile", "", "Path to git http.cookiefile")
+	fs.StringVar(&o.GitMirrorDir, "git-mirror-dir", "", "Directory with bare mirrors of repositories, laid out as <org>/<repo>.git, to borrow objects from")
 	fs.BoolVar(&o.Fail, "fail", false, "Exit with failure if any of the refs can't be fetched.")
 }
 
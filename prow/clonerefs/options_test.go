@@ -211,6 +211,36 @@ func TestOptions_Validate(t *testing.T) {
 			},
 			expectedErr: true,
 		},
+		{
+			name: "submodule credential files",
+			input: Options{
+				SrcRoot: "test",
+				Log:     "thing",
+				GitRefs: []prowapi.Refs{
+					{
+						Repo: "repo",
+						Org:  "org",
+					},
+				},
+				SubmoduleCredentialFiles: map[string]string{"gitlab.com": "/tmp/gitlab-token"},
+			},
+			expectedErr: false,
+		},
+		{
+			name: "submodule credential file without a host",
+			input: Options{
+				SrcRoot: "test",
+				Log:     "thing",
+				GitRefs: []prowapi.Refs{
+					{
+						Repo: "repo",
+						Org:  "org",
+					},
+				},
+				SubmoduleCredentialFiles: map[string]string{"": "/tmp/gitlab-token"},
+			},
+			expectedErr: true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -25,6 +25,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -117,6 +118,23 @@ func (o *Options) createRecords() []clone.Record {
 		}
 	}
 
+	if needsSubmodules(o.GitRefs...) {
+		var hosts []string
+		for host := range o.SubmoduleCredentialFiles {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		for _, host := range hosts {
+			cmd, err := configureSubmoduleCredential(host, o.SubmoduleCredentialFiles[host])
+			rec.Commands = append(rec.Commands, cmd)
+			if err != nil {
+				logrus.WithError(err).WithField("host", host).Error("Failed to configure submodule credential")
+				rec.Failed = true
+				return []clone.Record{rec}
+			}
+		}
+	}
+
 	var numWorkers int
 	if o.MaxParallelWorkers != 0 {
 		numWorkers = o.MaxParallelWorkers
@@ -133,7 +151,7 @@ func (o *Options) createRecords() []clone.Record {
 		go func() {
 			defer wg.Done()
 			for ref := range input {
-				output <- cloneFunc(ref, o.SrcRoot, o.GitUserName, o.GitUserEmail, o.CookiePath, env, userGenerator, tokenGenerator)
+				output <- cloneFunc(ref, o.SrcRoot, o.GitUserName, o.GitUserEmail, o.CookiePath, o.GitMirrorDir, env, userGenerator, tokenGenerator)
 			}
 		}()
 	}
@@ -180,16 +198,20 @@ func (o Options) Run() error {
 }
 
 func needsGlobalCookiePath(cookieFile string, refs ...prowapi.Refs) string {
-	if cookieFile == "" || len(refs) == 0 {
+	if cookieFile == "" || !needsSubmodules(refs...) {
 		return ""
 	}
+	return cookieFile
+}
 
+// needsSubmodules returns true if the submodules of any of the refs are cloned.
+func needsSubmodules(refs ...prowapi.Refs) bool {
 	for _, r := range refs {
 		if !r.SkipSubmodules {
-			return cookieFile
+			return true
 		}
 	}
-	return ""
+	return false
 }
 
 // configureGlobalCookiefile ensures git authenticates submodules correctly.
@@ -207,6 +229,34 @@ func configureGlobalCookiefile(cookiePath string) (clone.Command, error) {
 	return cmd, err
 }
 
+// configureSubmoduleCredential makes git authenticate with the token in the
+// file when it fetches submodules from the host over HTTPS.
+//
+// Like the cookiefile, this is a global setting that we configure once and
+// before running parallel clones.
+func configureSubmoduleCredential(host, tokenFile string) (clone.Command, error) {
+	cmd := clone.Command{
+		Command: fmt.Sprintf("git config --global %s %s", submoduleCredentialConfig(host, "CENSORED"), fmt.Sprintf("https://%s/", host)),
+	}
+	if err := secret.Add(tokenFile); err != nil {
+		cmd.Error = err.Error()
+		return cmd, fmt.Errorf("read token file %s: %w", tokenFile, err)
+	}
+	token := strings.TrimSpace(string(secret.GetSecret(tokenFile)))
+	out, err := exec.Command("git", "config", "--global", submoduleCredentialConfig(host, token), fmt.Sprintf("https://%s/", host)).CombinedOutput()
+	cmd.Output = string(secret.Censor(out))
+	if err != nil {
+		cmd.Error = err.Error()
+	}
+	return cmd, err
+}
+
+// submoduleCredentialConfig returns the git config key that rewrites URLs of
+// the host to ones that authenticate with the token.
+func submoduleCredentialConfig(host, token string) string {
+	return fmt.Sprintf("url.https://x-access-token:%s@%s/.insteadOf", token, host)
+}
+
 func addHostFingerprints(fingerprints []string) (string, []clone.Command, error) {
 	// let's try to create the tmp dir if it doesn't exist
 	var cmds []clone.Command
@@ -105,6 +105,9 @@ type requirements struct {
 	Repo    string
 	Branch  string
 	Request *github.BranchProtectionRequest
+	// Ruleset changes the ruleset that protects the branch, when the policy
+	// uses rulesets. Request is always nil then.
+	Ruleset *rulesetRequirements
 }
 
 // Errors holds a list of errors, including a method to concurrently append.
@@ -172,6 +175,11 @@ type client interface {
 	GetRepos(org string, user bool) ([]github.Repo, error)
 	ListCollaborators(org, repo string) ([]github.User, error)
 	ListRepoTeams(org, repo string) ([]github.Team, error)
+	ListRepoRulesets(org, repo string) ([]github.Ruleset, error)
+	GetRepoRuleset(org, repo string, id int) (*github.Ruleset, error)
+	CreateRepoRuleset(org, repo string, ruleset github.Ruleset) (*github.Ruleset, error)
+	UpdateRepoRuleset(org, repo string, id int, ruleset github.Ruleset) error
+	DeleteRepoRuleset(org, repo string, id int) error
 }
 
 type protector struct {
@@ -187,6 +195,16 @@ type protector struct {
 
 func (p *protector) configureBranches() {
 	for u := range p.updates {
+		if u.Ruleset != nil {
+			if err := p.configureRuleset(u); err != nil {
+				p.errors.add(fmt.Errorf("configure %s/%s=%s ruleset failed: %w", u.Org, u.Repo, u.Branch, err))
+				continue
+			}
+			if !u.Ruleset.RemoveBranchProtection {
+				continue
+			}
+		}
+
 		if u.Request == nil {
 			if err := p.client.RemoveBranchProtection(u.Org, u.Repo, u.Branch); err != nil {
 				p.errors.add(fmt.Errorf("remove %s/%s=%s protection failed: %w", u.Org, u.Repo, u.Branch, err))
@@ -442,6 +460,9 @@ func (p *protector) UpdateBranch(orgName, repo string, branchName string, branch
 	if bp == nil || bp.Protect == nil {
 		return nil
 	}
+	if bp.Rulesets != nil && *bp.Rulesets {
+		return p.updateRuleset(orgName, repo, branchName, *bp)
+	}
 	if !protected && !*bp.Protect {
 		logrus.Infof("%s/%s=%s: already unprotected", orgName, repo, branchName)
 		return nil
@@ -112,6 +112,7 @@ type fakeClient struct {
 	branchProtections map[string]github.BranchProtection
 	collaborators     []github.User
 	teams             []github.Team
+	rulesets          map[string][]github.Ruleset
 }
 
 func (c fakeClient) GetRepo(org string, repo string) (github.FullRepo, error) {
@@ -196,6 +197,60 @@ func (c *fakeClient) ListRepoTeams(org, repo string) ([]github.Team, error) {
 	return c.teams, nil
 }
 
+func (c *fakeClient) ListRepoRulesets(org, repo string) ([]github.Ruleset, error) {
+	var rulesets []github.Ruleset
+	for _, ruleset := range c.rulesets[org+"/"+repo] {
+		// Listed rulesets don't include their rules.
+		rulesets = append(rulesets, github.Ruleset{ID: ruleset.ID, Name: ruleset.Name})
+	}
+	return rulesets, nil
+}
+
+func (c *fakeClient) GetRepoRuleset(org, repo string, id int) (*github.Ruleset, error) {
+	for _, ruleset := range c.rulesets[org+"/"+repo] {
+		if ruleset.ID == id {
+			return &ruleset, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown ruleset: %d", id)
+}
+
+func (c *fakeClient) CreateRepoRuleset(org, repo string, ruleset github.Ruleset) (*github.Ruleset, error) {
+	if repo == "error" {
+		return nil, errors.New("failed to create ruleset")
+	}
+	if c.rulesets == nil {
+		c.rulesets = map[string][]github.Ruleset{}
+	}
+	ctx := org + "/" + repo
+	ruleset.ID = len(c.rulesets[ctx]) + 1
+	c.rulesets[ctx] = append(c.rulesets[ctx], ruleset)
+	return &ruleset, nil
+}
+
+func (c *fakeClient) UpdateRepoRuleset(org, repo string, id int, ruleset github.Ruleset) error {
+	ctx := org + "/" + repo
+	for i := range c.rulesets[ctx] {
+		if c.rulesets[ctx][i].ID == id {
+			ruleset.ID = id
+			c.rulesets[ctx][i] = ruleset
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown ruleset: %d", id)
+}
+
+func (c *fakeClient) DeleteRepoRuleset(org, repo string, id int) error {
+	ctx := org + "/" + repo
+	for i := range c.rulesets[ctx] {
+		if c.rulesets[ctx][i].ID == id {
+			c.rulesets[ctx] = append(c.rulesets[ctx][:i], c.rulesets[ctx][i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown ruleset: %d", id)
+}
+
 func TestConfigureBranches(t *testing.T) {
 	yes := true
 
@@ -0,0 +1,254 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	branchprotection "k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+)
+
+// rulesetRequirements change the ruleset that protects a branch.
+type rulesetRequirements struct {
+	// ID is the ruleset to update or delete, 0 creates it.
+	ID int
+	// Ruleset is what the ruleset should be, nil deletes it.
+	Ruleset *github.Ruleset
+	// RemoveBranchProtection removes the branch protection the ruleset replaces.
+	RemoveBranchProtection bool
+}
+
+// rulesetName is the name of the ruleset that protects a branch.
+func rulesetName(branch string) string {
+	return "branchprotector: " + branch
+}
+
+// makeRuleset renders a branch protection policy into the ruleset that
+// protects the branch.
+//
+// Rulesets can't restrict who can push to a branch or dismiss reviews, so
+// policies with restrictions are an error.
+func makeRuleset(branch string, policy branchprotection.Policy) (*github.Ruleset, error) {
+	if policy.Restrictions != nil {
+		return nil, errors.New("rulesets can't enforce restrictions")
+	}
+	if policy.RequiredPullRequestReviews != nil && policy.RequiredPullRequestReviews.DismissalRestrictions != nil {
+		return nil, errors.New("rulesets can't enforce dismissal_restrictions")
+	}
+
+	ruleset := github.Ruleset{
+		Name:         rulesetName(branch),
+		Target:       github.RulesetTargetBranch,
+		Enforcement:  github.RulesetEnforcementActive,
+		BypassActors: []github.RulesetBypassActor{},
+		Conditions: &github.RulesetConditions{
+			RefName: github.RulesetRefNameCondition{
+				Include: []string{"refs/heads/" + branch},
+				Exclude: []string{},
+			},
+		},
+		Rules: []github.RulesetRule{},
+	}
+	if !*makeAdmins(policy.Admins) {
+		ruleset.BypassActors = append(ruleset.BypassActors, github.RulesetBypassActor{
+			ActorID:    github.RulesetAdminRoleID,
+			ActorType:  "RepositoryRole",
+			BypassMode: "always",
+		})
+	}
+	if reviews := makeReviews(policy.RequiredPullRequestReviews); reviews != nil {
+		no := false
+		ruleset.Rules = append(ruleset.Rules, github.RulesetRule{
+			Type: github.RulesetRulePullRequest,
+			Parameters: &github.RulesetRuleParameters{
+				RequiredApprovingReviewCount:   &reviews.RequiredApprovingReviewCount,
+				DismissStaleReviewsOnPush:      &reviews.DismissStaleReviews,
+				RequireCodeOwnerReview:         &reviews.RequireCodeOwnerReviews,
+				RequireLastPushApproval:        &no,
+				RequiredReviewThreadResolution: &no,
+			},
+		})
+	}
+	if checks := makeChecks(policy.RequiredStatusChecks); checks != nil {
+		statusChecks := []github.RulesetStatusCheck{}
+		for _, context := range checks.Contexts {
+			statusChecks = append(statusChecks, github.RulesetStatusCheck{Context: context})
+		}
+		ruleset.Rules = append(ruleset.Rules, github.RulesetRule{
+			Type: github.RulesetRuleRequiredStatusChecks,
+			Parameters: &github.RulesetRuleParameters{
+				RequiredStatusChecks:             statusChecks,
+				StrictRequiredStatusChecksPolicy: &checks.Strict,
+			},
+		})
+	}
+	if makeBool(policy.RequiredLinearHistory) {
+		ruleset.Rules = append(ruleset.Rules, github.RulesetRule{Type: github.RulesetRuleRequiredLinearHistory})
+	}
+	if !makeBool(policy.AllowForcePushes) {
+		ruleset.Rules = append(ruleset.Rules, github.RulesetRule{Type: github.RulesetRuleNonFastForward})
+	}
+	if !makeBool(policy.AllowDeletions) {
+		ruleset.Rules = append(ruleset.Rules, github.RulesetRule{Type: github.RulesetRuleDeletion})
+	}
+	return &ruleset, nil
+}
+
+// normalizeRuleset returns the parts of a ruleset that branchprotector
+// manages, in a canonical order.
+func normalizeRuleset(ruleset github.Ruleset) github.Ruleset {
+	normalized := github.Ruleset{
+		Name:        ruleset.Name,
+		Target:      ruleset.Target,
+		Enforcement: ruleset.Enforcement,
+	}
+	normalized.BypassActors = append(normalized.BypassActors, ruleset.BypassActors...)
+	sort.Slice(normalized.BypassActors, func(i, j int) bool {
+		a, b := normalized.BypassActors[i], normalized.BypassActors[j]
+		if a.ActorType != b.ActorType {
+			return a.ActorType < b.ActorType
+		}
+		return a.ActorID < b.ActorID
+	})
+	if ruleset.Conditions != nil {
+		normalized.Conditions = &github.RulesetConditions{
+			RefName: github.RulesetRefNameCondition{
+				Include: sets.NewString(ruleset.Conditions.RefName.Include...).List(),
+				Exclude: sets.NewString(ruleset.Conditions.RefName.Exclude...).List(),
+			},
+		}
+	}
+	for _, rule := range ruleset.Rules {
+		if rule.Parameters != nil {
+			parameters := *rule.Parameters
+			parameters.RequiredStatusChecks = nil
+			for _, context := range sets.NewString(statusCheckContexts(rule.Parameters.RequiredStatusChecks)...).List() {
+				parameters.RequiredStatusChecks = append(parameters.RequiredStatusChecks, github.RulesetStatusCheck{Context: context})
+			}
+			rule.Parameters = &parameters
+		}
+		normalized.Rules = append(normalized.Rules, rule)
+	}
+	sort.Slice(normalized.Rules, func(i, j int) bool {
+		return normalized.Rules[i].Type < normalized.Rules[j].Type
+	})
+	return normalized
+}
+
+func statusCheckContexts(checks []github.RulesetStatusCheck) []string {
+	var contexts []string
+	for _, check := range checks {
+		contexts = append(contexts, check.Context)
+	}
+	return contexts
+}
+
+func equalRulesets(state, request *github.Ruleset) bool {
+	switch {
+	case state == nil && request == nil:
+		return true
+	case state != nil && request != nil:
+		return reflect.DeepEqual(normalizeRuleset(*state), normalizeRuleset(*request))
+	default:
+		return false
+	}
+}
+
+// currentRuleset returns the ruleset that protects the branch, or nil if
+// there is none.
+func (p *protector) currentRuleset(orgName, repo, branchName string) (*github.Ruleset, error) {
+	rulesets, err := p.client.ListRepoRulesets(orgName, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, ruleset := range rulesets {
+		if ruleset.Name == rulesetName(branchName) {
+			// Listed rulesets don't include their rules.
+			return p.client.GetRepoRuleset(orgName, repo, ruleset.ID)
+		}
+	}
+	return nil, nil
+}
+
+// updateRuleset protects the branch with a ruleset instead of branch
+// protection.
+func (p *protector) updateRuleset(orgName, repo, branchName string, bp branchprotection.Policy) error {
+	var ruleset *github.Ruleset
+	if *bp.Protect {
+		var err error
+		if ruleset, err = makeRuleset(branchName, bp); err != nil {
+			return fmt.Errorf("invalid ruleset: %s/%s=%s: %w", orgName, repo, branchName, err)
+		}
+	}
+
+	currentRuleset, err := p.currentRuleset(orgName, repo, branchName)
+	if err != nil {
+		return fmt.Errorf("get current ruleset: %w", err)
+	}
+	currentBP, err := p.client.GetBranchProtection(orgName, repo, url.QueryEscape(branchName))
+	if err != nil {
+		return fmt.Errorf("get current branch protection: %w", err)
+	}
+
+	switch {
+	case !equalRulesets(currentRuleset, ruleset):
+		update := rulesetRequirements{
+			Ruleset:                ruleset,
+			RemoveBranchProtection: currentBP != nil,
+		}
+		if currentRuleset != nil {
+			update.ID = currentRuleset.ID
+		}
+		p.updates <- requirements{
+			Org:     orgName,
+			Repo:    repo,
+			Branch:  branchName,
+			Ruleset: &update,
+		}
+	case currentBP != nil:
+		// Only remove the branch protection the ruleset replaces.
+		p.updates <- requirements{
+			Org:    orgName,
+			Repo:   repo,
+			Branch: branchName,
+		}
+	default:
+		logrus.Debugf("%s/%s=%s: current ruleset matches policy, skipping", orgName, repo, branchName)
+	}
+	return nil
+}
+
+// configureRuleset creates, updates or deletes the ruleset of a branch.
+func (p *protector) configureRuleset(u requirements) error {
+	switch r := u.Ruleset; {
+	case r.Ruleset == nil:
+		return p.client.DeleteRepoRuleset(u.Org, u.Repo, r.ID)
+	case r.ID == 0:
+		_, err := p.client.CreateRepoRuleset(u.Org, u.Repo, *r.Ruleset)
+		return err
+	default:
+		return p.client.UpdateRepoRuleset(u.Org, u.Repo, r.ID, *r.Ruleset)
+	}
+}
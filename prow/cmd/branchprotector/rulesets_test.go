@@ -0,0 +1,287 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+)
+
+func masterRuleset(bypass []github.RulesetBypassActor, rules ...github.RulesetRule) *github.Ruleset {
+	return &github.Ruleset{
+		Name:         "branchprotector: master",
+		Target:       github.RulesetTargetBranch,
+		Enforcement:  github.RulesetEnforcementActive,
+		BypassActors: bypass,
+		Conditions: &github.RulesetConditions{
+			RefName: github.RulesetRefNameCondition{
+				Include: []string{"refs/heads/master"},
+				Exclude: []string{},
+			},
+		},
+		Rules: rules,
+	}
+}
+
+var adminBypass = []github.RulesetBypassActor{{ActorID: github.RulesetAdminRoleID, ActorType: "RepositoryRole", BypassMode: "always"}}
+
+func TestMakeRuleset(t *testing.T) {
+	yes := true
+	no := false
+	two := 2
+	cases := []struct {
+		name     string
+		policy   config.Policy
+		expected *github.Ruleset
+		err      bool
+	}{
+		{
+			name:   "defaults let admins bypass and forbid force pushes and deletions",
+			policy: config.Policy{Protect: &yes},
+			expected: masterRuleset(adminBypass,
+				github.RulesetRule{Type: github.RulesetRuleNonFastForward},
+				github.RulesetRule{Type: github.RulesetRuleDeletion},
+			),
+		},
+		{
+			name: "full policy",
+			policy: config.Policy{
+				Protect: &yes,
+				Admins:  &yes,
+				RequiredPullRequestReviews: &config.ReviewPolicy{
+					Approvals:    &two,
+					DismissStale: &yes,
+				},
+				RequiredStatusChecks: &config.ContextPolicy{
+					Contexts: []string{"unit", "lint"},
+					Strict:   &yes,
+				},
+				RequiredLinearHistory: &yes,
+				AllowForcePushes:      &yes,
+				AllowDeletions:        &yes,
+			},
+			expected: masterRuleset([]github.RulesetBypassActor{},
+				github.RulesetRule{Type: github.RulesetRulePullRequest, Parameters: &github.RulesetRuleParameters{
+					RequiredApprovingReviewCount:   &two,
+					DismissStaleReviewsOnPush:      &yes,
+					RequireCodeOwnerReview:         &no,
+					RequireLastPushApproval:        &no,
+					RequiredReviewThreadResolution: &no,
+				}},
+				github.RulesetRule{Type: github.RulesetRuleRequiredStatusChecks, Parameters: &github.RulesetRuleParameters{
+					RequiredStatusChecks:             []github.RulesetStatusCheck{{Context: "lint"}, {Context: "unit"}},
+					StrictRequiredStatusChecksPolicy: &yes,
+				}},
+				github.RulesetRule{Type: github.RulesetRuleRequiredLinearHistory},
+			),
+		},
+		{
+			name: "restrictions are an error",
+			policy: config.Policy{
+				Protect:      &yes,
+				Restrictions: &config.Restrictions{Teams: []string{"pushers"}},
+			},
+			err: true,
+		},
+		{
+			name: "dismissal restrictions are an error",
+			policy: config.Policy{
+				Protect: &yes,
+				RequiredPullRequestReviews: &config.ReviewPolicy{
+					Approvals:             &two,
+					DismissalRestrictions: &config.Restrictions{Users: []string{"someone"}},
+				},
+			},
+			err: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := makeRuleset("master", tc.policy)
+			switch {
+			case err != nil:
+				if !tc.err {
+					t.Errorf("unexpected error: %v", err)
+				}
+			case tc.err:
+				t.Error("failed to receive an error")
+			default:
+				if diff := cmp.Diff(tc.expected, actual); diff != "" {
+					t.Errorf("actual ruleset differs from expected (-expected +actual):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateRuleset(t *testing.T) {
+	yes := true
+	no := false
+	want := masterRuleset(adminBypass,
+		github.RulesetRule{Type: github.RulesetRuleNonFastForward},
+		github.RulesetRule{Type: github.RulesetRuleDeletion},
+	)
+	existing := *masterRuleset(adminBypass,
+		// GitHub may return the rules in any order.
+		github.RulesetRule{Type: github.RulesetRuleDeletion},
+		github.RulesetRule{Type: github.RulesetRuleNonFastForward},
+	)
+	existing.ID = 3
+	outdated := *masterRuleset(adminBypass, github.RulesetRule{Type: github.RulesetRuleDeletion})
+	outdated.ID = 3
+
+	cases := []struct {
+		name              string
+		protect           bool
+		rulesets          []github.Ruleset
+		branchProtections map[string]github.BranchProtection
+		expected          []requirements
+	}{
+		{
+			name:              "ruleset is created and replaces branch protection",
+			protect:           true,
+			branchProtections: map[string]github.BranchProtection{"org/repo=master": {}},
+			expected: []requirements{{
+				Org:     "org",
+				Repo:    "repo",
+				Branch:  "master",
+				Ruleset: &rulesetRequirements{Ruleset: want, RemoveBranchProtection: true},
+			}},
+		},
+		{
+			name:     "matching ruleset is unchanged",
+			protect:  true,
+			rulesets: []github.Ruleset{existing},
+		},
+		{
+			name:     "outdated ruleset is updated",
+			protect:  true,
+			rulesets: []github.Ruleset{outdated},
+			expected: []requirements{{
+				Org:     "org",
+				Repo:    "repo",
+				Branch:  "master",
+				Ruleset: &rulesetRequirements{ID: 3, Ruleset: want},
+			}},
+		},
+		{
+			name:              "branch protection left next to a matching ruleset is removed",
+			protect:           true,
+			rulesets:          []github.Ruleset{existing},
+			branchProtections: map[string]github.BranchProtection{"org/repo=master": {}},
+			expected:          []requirements{{Org: "org", Repo: "repo", Branch: "master"}},
+		},
+		{
+			name:     "ruleset of an unprotected branch is deleted",
+			rulesets: []github.Ruleset{existing},
+			expected: []requirements{{
+				Org:     "org",
+				Repo:    "repo",
+				Branch:  "master",
+				Ruleset: &rulesetRequirements{ID: 3},
+			}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakeClient{
+				rulesets:          map[string][]github.Ruleset{"org/repo": tc.rulesets},
+				branchProtections: tc.branchProtections,
+			}
+			p := protector{
+				client:  &fc,
+				updates: make(chan requirements, 1),
+			}
+			protect := &no
+			if tc.protect {
+				protect = &yes
+			}
+			if err := p.updateRuleset("org", "repo", "master", config.Policy{Protect: protect}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			close(p.updates)
+			var actual []requirements
+			for u := range p.updates {
+				actual = append(actual, u)
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("actual updates differ from expected (-expected +actual):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfigureRulesets(t *testing.T) {
+	ruleset := masterRuleset(adminBypass, github.RulesetRule{Type: github.RulesetRuleDeletion})
+	created := *ruleset
+	created.ID = 1
+
+	cases := []struct {
+		name     string
+		updates  []requirements
+		rulesets map[string][]github.Ruleset
+		deletes  map[string]bool
+		errors   int
+	}{
+		{
+			name: "create ruleset and remove branch protection",
+			updates: []requirements{
+				{Org: "one", Repo: "1", Branch: "master", Ruleset: &rulesetRequirements{Ruleset: ruleset, RemoveBranchProtection: true}},
+			},
+			rulesets: map[string][]github.Ruleset{"one/1": {created}},
+			deletes:  map[string]bool{"one/1=master": true},
+		},
+		{
+			name: "branch protection is kept when the ruleset fails",
+			updates: []requirements{
+				{Org: "one", Repo: "error", Branch: "master", Ruleset: &rulesetRequirements{Ruleset: ruleset, RemoveBranchProtection: true}},
+			},
+			errors: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		fc := fakeClient{}
+		p := protector{
+			client:  &fc,
+			updates: make(chan requirements),
+			done:    make(chan []error),
+		}
+		go p.configureBranches()
+		for _, u := range tc.updates {
+			p.updates <- u
+		}
+		close(p.updates)
+		errs := <-p.done
+		if len(errs) != tc.errors {
+			t.Errorf("%s: %d errors != expected %d: %v", tc.name, len(errs), tc.errors, errs)
+		}
+		if !reflect.DeepEqual(fc.rulesets, tc.rulesets) {
+			t.Errorf("%s: rulesets %v != expected %v", tc.name, fc.rulesets, tc.rulesets)
+		}
+		if !reflect.DeepEqual(fc.deleted, tc.deletes) {
+			t.Errorf("%s: deletes %v != expected %v", tc.name, fc.deleted, tc.deletes)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// The dead config warnings report config that never takes effect. They are
+// all enabled by --dead-config.
+const (
+	unusedPresetsWarning           = "unused-presets"
+	unreachableRunIfChangedWarning = "unreachable-run-if-changed"
+	archivedRepoPluginsWarning     = "archived-repo-plugins"
+	nonexistentTideBranchesWarning = "nonexistent-tide-branches"
+)
+
+var deadConfigWarnings = []string{
+	unusedPresetsWarning,
+	unreachableRunIfChangedWarning,
+	archivedRepoPluginsWarning,
+	nonexistentTideBranchesWarning,
+}
+
+// validateUnusedPresets reports the presets whose labels no job has, which
+// are never applied. Presets without labels apply to all the jobs. The jobs
+// of in-repo config can use any preset but aren't known here, so nothing is
+// reported once in-repo config is enabled for any repo.
+func validateUnusedPresets(cfg *config.Config) error {
+	for _, enabled := range cfg.InRepoConfig.Enabled {
+		if enabled != nil && *enabled {
+			return nil
+		}
+	}
+
+	var jobLabels []map[string]string
+	for _, presubmits := range cfg.PresubmitsStatic {
+		for _, job := range presubmits {
+			jobLabels = append(jobLabels, job.Labels)
+		}
+	}
+	for _, postsubmits := range cfg.PostsubmitsStatic {
+		for _, job := range postsubmits {
+			jobLabels = append(jobLabels, job.Labels)
+		}
+	}
+	for _, job := range cfg.Periodics {
+		jobLabels = append(jobLabels, job.Labels)
+	}
+
+	var errs []error
+	for _, preset := range cfg.Presets {
+		if len(preset.Labels) == 0 {
+			continue
+		}
+		used := false
+		for _, labels := range jobLabels {
+			if hasLabels(labels, preset.Labels) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			errs = append(errs, fmt.Errorf("no job has the labels %v of a preset, it is never applied", preset.Labels))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func hasLabels(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// validateUnreachableRunIfChanged reports the jobs whose run_if_changed
+// can't match any path and those whose skip_if_only_changed matches any
+// path: neither is ever triggered by a change.
+func validateUnreachableRunIfChanged(cfg *config.Config) error {
+	var errs []error
+	check := func(repo, name string, matcher config.RegexpChangeMatcher) {
+		if matcher.RunIfChanged != "" && neverMatchesPath(matcher.RunIfChanged) {
+			errs = append(errs, fmt.Errorf("job %s/%s: run_if_changed %q can't match any file, the job never runs for a change", repo, name, matcher.RunIfChanged))
+		}
+		if matcher.SkipIfOnlyChanged != "" && matchesAnyPath(matcher.SkipIfOnlyChanged) {
+			errs = append(errs, fmt.Errorf("job %s/%s: skip_if_only_changed %q matches any file, the job never runs for a change", repo, name, matcher.SkipIfOnlyChanged))
+		}
+	}
+	for repo, presubmits := range cfg.PresubmitsStatic {
+		for _, job := range presubmits {
+			check(repo, job.Name, job.RegexpChangeMatcher)
+		}
+	}
+	for repo, postsubmits := range cfg.PostsubmitsStatic {
+		for _, job := range postsubmits {
+			check(repo, job.Name, job.RegexpChangeMatcher)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// matchesAnyPath returns whether the regex matches any path, like `.*` or
+// `^|docs`. Paths aren't empty, so a regex matches any of them when it matches
+// the empty string at their start or at their end. This under-approximates
+// it, so that matches it isn't sure of aren't reported.
+func matchesAnyPath(expr string) bool {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return false
+	}
+	re = re.Simplify()
+	return matchesEmptyAt(re, atStart) || matchesEmptyAt(re, atEnd)
+}
+
+// The positions in a path, as a set, for neverMatchesPath.
+const (
+	atStart = 1 << iota
+	inMiddle
+	atEnd
+)
+
+// neverMatchesPath returns whether the regex can't match any path, like
+// `docs$/` or `[^\x00-\x{10FFFF}]`. The regex is evaluated over the positions
+// in a path it can be at, which over-approximates it: when no position is
+// left, the regex can't match. Invalid regexes are reported by the config
+// validation.
+func neverMatchesPath(expr string) bool {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return false
+	}
+	// The match of a regex starts anywhere in the path.
+	return positionsAfter(re.Simplify(), atStart|inMiddle|atEnd) == 0
+}
+
+// positionsAfter returns the positions the regex can be at once it matched
+// from the positions. Paths have no newlines, so lines are the whole path.
+func positionsAfter(re *syntax.Regexp, positions int) int {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return 0
+	case syntax.OpLiteral:
+		for range re.Rune {
+			positions = consume(positions)
+		}
+		return positions
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return 0
+		}
+		return consume(positions)
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		return consume(positions)
+	case syntax.OpBeginLine, syntax.OpBeginText:
+		return positions & atStart
+	case syntax.OpEndLine, syntax.OpEndText:
+		return positions & atEnd
+	case syntax.OpCapture:
+		return positionsAfter(re.Sub[0], positions)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			positions = positionsAfter(sub, positions)
+		}
+		return positions
+	case syntax.OpAlternate:
+		var after int
+		for _, sub := range re.Sub {
+			after |= positionsAfter(sub, positions)
+		}
+		return after
+	case syntax.OpQuest:
+		return positions | positionsAfter(re.Sub[0], positions)
+	case syntax.OpStar:
+		return repeated(re.Sub[0], positions)
+	case syntax.OpPlus:
+		return repeated(re.Sub[0], positionsAfter(re.Sub[0], positions))
+	case syntax.OpRepeat:
+		for i := 0; i < re.Min; i++ {
+			positions = positionsAfter(re.Sub[0], positions)
+		}
+		if re.Max == re.Min {
+			return positions
+		}
+		return repeated(re.Sub[0], positions)
+	default:
+		// Empty matches and word boundaries, which aren't worth the details.
+		return positions
+	}
+}
+
+// matchesEmptyAt returns whether the regex surely matches the empty string at
+// the position of a path that isn't empty.
+func matchesEmptyAt(re *syntax.Regexp, position int) bool {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpQuest, syntax.OpStar:
+		return true
+	case syntax.OpLiteral:
+		return len(re.Rune) == 0
+	case syntax.OpBeginLine, syntax.OpBeginText:
+		return position == atStart
+	case syntax.OpEndLine, syntax.OpEndText:
+		return position == atEnd
+	case syntax.OpCapture, syntax.OpPlus:
+		return matchesEmptyAt(re.Sub[0], position)
+	case syntax.OpRepeat:
+		return re.Min == 0 || matchesEmptyAt(re.Sub[0], position)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !matchesEmptyAt(sub, position) {
+				return false
+			}
+		}
+		return true
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if matchesEmptyAt(sub, position) {
+				return true
+			}
+		}
+		return false
+	default:
+		// Characters, and word boundaries, which depend on the path.
+		return false
+	}
+}
+
+// consume returns the positions after a character from the positions.
+func consume(positions int) int {
+	if positions&(atStart|inMiddle) == 0 {
+		return 0
+	}
+	return inMiddle | atEnd
+}
+
+// repeated returns the positions after the regex repeated any number of
+// times from the positions.
+func repeated(re *syntax.Regexp, positions int) int {
+	for {
+		after := positions | positionsAfter(re, positions)
+		if after == positions {
+			return positions
+		}
+		positions = after
+	}
+}
+
+type deadConfigClient interface {
+	GetRepo(owner, name string) (github.FullRepo, error)
+	GetBranches(org, repo string, onlyProtected bool) ([]github.Branch, error)
+}
+
+// validateArchivedRepoPlugins reports the repos plugins or external plugins
+// are enabled for that are archived, so never get events.
+func validateArchivedRepoPlugins(client deadConfigClient, pcfg *plugins.Configuration) error {
+	repos := sets.NewString()
+	for orgRepo := range pcfg.Plugins {
+		if strings.Contains(orgRepo, "/") {
+			repos.Insert(orgRepo)
+		}
+	}
+	for orgRepo := range pcfg.ExternalPlugins {
+		if strings.Contains(orgRepo, "/") {
+			repos.Insert(orgRepo)
+		}
+	}
+
+	var errs []error
+	for _, orgRepo := range repos.List() {
+		org, repo := splitOrgRepo(orgRepo)
+		fullRepo, err := client.GetRepo(org, repo)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get repo %s: %w", orgRepo, err))
+			continue
+		}
+		if fullRepo.Archived {
+			errs = append(errs, fmt.Errorf("plugins are enabled for %s, which is archived", orgRepo))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// validateNonexistentTideBranches reports the branches tide queries include
+// that don't exist in the repos of the queries. The repos of the orgs of the
+// queries aren't checked, most of them may lack the branches.
+func validateNonexistentTideBranches(client deadConfigClient, cfg *config.Config) error {
+	branches := map[string]sets.String{}
+	var errs []error
+	for i, query := range cfg.Tide.Queries {
+		if len(query.IncludedBranches) == 0 {
+			continue
+		}
+		for _, orgRepo := range query.Repos {
+			if _, ok := branches[orgRepo]; !ok {
+				org, repo := splitOrgRepo(orgRepo)
+				repoBranches, err := client.GetBranches(org, repo, false)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to get the branches of %s: %w", orgRepo, err))
+					continue
+				}
+				branches[orgRepo] = sets.NewString()
+				for _, branch := range repoBranches {
+					branches[orgRepo].Insert(branch.Name)
+				}
+			}
+			for _, branch := range query.IncludedBranches {
+				if !branches[orgRepo].Has(branch) {
+					errs = append(errs, fmt.Errorf("tide query %d includes branch %s, which doesn't exist in %s", i, branch, orgRepo))
+				}
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func splitOrgRepo(orgRepo string) (string, string) {
+	parts := strings.SplitN(orgRepo, "/", 2)
+	return parts[0], parts[1]
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	utilpointer "k8s.io/utils/pointer"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func errMsg(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestValidateUnusedPresets(t *testing.T) {
+	testCases := []struct {
+		name         string
+		presets      []config.Preset
+		inRepoConfig map[string]*bool
+		expectedErr  string
+	}{
+		{
+			name: "presets matching jobs",
+			presets: []config.Preset{
+				{Labels: map[string]string{"preset-a": "true"}},
+				{Labels: map[string]string{"preset-b": "true", "preset-c": "true"}},
+				{Labels: map[string]string{"preset-d": "true"}},
+			},
+		},
+		{
+			name:    "presets without labels apply to all the jobs",
+			presets: []config.Preset{{}},
+		},
+		{
+			name: "preset no job has all the labels of",
+			presets: []config.Preset{
+				{Labels: map[string]string{"preset-a": "true", "preset-b": "true"}},
+			},
+			expectedErr: "no job has the labels map[preset-a:true preset-b:true] of a preset, it is never applied",
+		},
+		{
+			name: "preset with another value",
+			presets: []config.Preset{
+				{Labels: map[string]string{"preset-a": "false"}},
+			},
+			expectedErr: "no job has the labels map[preset-a:false] of a preset, it is never applied",
+		},
+		{
+			name: "preset possibly used by in-repo jobs",
+			presets: []config.Preset{
+				{Labels: map[string]string{"preset-e": "true"}},
+			},
+			inRepoConfig: map[string]*bool{"org": utilpointer.BoolPtr(true)},
+		},
+		{
+			name: "in-repo config disabled",
+			presets: []config.Preset{
+				{Labels: map[string]string{"preset-e": "true"}},
+			},
+			inRepoConfig: map[string]*bool{"org": utilpointer.BoolPtr(false)},
+			expectedErr:  "no job has the labels map[preset-e:true] of a preset, it is never applied",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{ProwConfig: config.ProwConfig{
+				InRepoConfig: config.InRepoConfig{Enabled: tc.inRepoConfig},
+			}, JobConfig: config.JobConfig{
+				Presets: tc.presets,
+				PresubmitsStatic: map[string][]config.Presubmit{
+					"org/repo": {{JobBase: config.JobBase{Name: "unit", Labels: map[string]string{"preset-a": "true"}}}},
+				},
+				PostsubmitsStatic: map[string][]config.Postsubmit{
+					"org/repo": {{JobBase: config.JobBase{Name: "build", Labels: map[string]string{"preset-b": "true", "preset-c": "true"}}}},
+				},
+				Periodics: []config.Periodic{{JobBase: config.JobBase{Name: "nightly", Labels: map[string]string{"preset-d": "true"}}}},
+			}}
+			if actual := errMsg(validateUnusedPresets(cfg)); actual != tc.expectedErr {
+				t.Errorf("expected error %q, got %q", tc.expectedErr, actual)
+			}
+		})
+	}
+}
+
+func TestValidateUnreachableRunIfChanged(t *testing.T) {
+	testCases := []struct {
+		name        string
+		matcher     config.RegexpChangeMatcher
+		expectedErr string
+	}{
+		{
+			name:    "reachable run_if_changed",
+			matcher: config.RegexpChangeMatcher{RunIfChanged: `^docs/.*\.md$`},
+		},
+		{
+			name:    "reachable multiline run_if_changed",
+			matcher: config.RegexpChangeMatcher{RunIfChanged: `(?m)^pkg/|^cmd/`},
+		},
+		{
+			name:        "run_if_changed matching after the end of the path",
+			matcher:     config.RegexpChangeMatcher{RunIfChanged: `docs$/`},
+			expectedErr: `job org/repo/unit: run_if_changed "docs$/" can't match any file, the job never runs for a change`,
+		},
+		{
+			name:        "run_if_changed matching before the start of the path",
+			matcher:     config.RegexpChangeMatcher{RunIfChanged: `(vendor/)+^pkg`},
+			expectedErr: `job org/repo/unit: run_if_changed "(vendor/)+^pkg" can't match any file, the job never runs for a change`,
+		},
+		{
+			name:        "run_if_changed matching an empty path",
+			matcher:     config.RegexpChangeMatcher{RunIfChanged: `^$`},
+			expectedErr: `job org/repo/unit: run_if_changed "^$" can't match any file, the job never runs for a change`,
+		},
+		{
+			name:    "skip_if_only_changed matching some paths",
+			matcher: config.RegexpChangeMatcher{SkipIfOnlyChanged: `^docs/|\.md$`},
+		},
+		{
+			name:    "skip_if_only_changed matching many but not all paths",
+			matcher: config.RegexpChangeMatcher{SkipIfOnlyChanged: `^(a|README\.md|docs/.*|pkg/.*|\.github/.*|vendor/.*)$`},
+		},
+		{
+			name:        "skip_if_only_changed matching the start of any path",
+			matcher:     config.RegexpChangeMatcher{SkipIfOnlyChanged: `^(docs/)?`},
+			expectedErr: `job org/repo/unit: skip_if_only_changed "^(docs/)?" matches any file, the job never runs for a change`,
+		},
+		{
+			name:        "skip_if_only_changed matching any path",
+			matcher:     config.RegexpChangeMatcher{SkipIfOnlyChanged: `docs|.*`},
+			expectedErr: `job org/repo/unit: skip_if_only_changed "docs|.*" matches any file, the job never runs for a change`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{JobConfig: config.JobConfig{
+				PresubmitsStatic: map[string][]config.Presubmit{
+					"org/repo": {{JobBase: config.JobBase{Name: "unit"}, RegexpChangeMatcher: tc.matcher}},
+				},
+			}}
+			if actual := errMsg(validateUnreachableRunIfChanged(cfg)); actual != tc.expectedErr {
+				t.Errorf("expected error %q, got %q", tc.expectedErr, actual)
+			}
+		})
+	}
+}
+
+type fakeDeadConfigClient struct {
+	archived map[string]bool
+	branches map[string][]string
+}
+
+func (c *fakeDeadConfigClient) GetRepo(owner, name string) (github.FullRepo, error) {
+	archived, ok := c.archived[owner+"/"+name]
+	if !ok {
+		return github.FullRepo{}, errors.New("not found")
+	}
+	return github.FullRepo{Repo: github.Repo{Archived: archived}}, nil
+}
+
+func (c *fakeDeadConfigClient) GetBranches(org, repo string, onlyProtected bool) ([]github.Branch, error) {
+	names, ok := c.branches[org+"/"+repo]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	var branches []github.Branch
+	for _, name := range names {
+		branches = append(branches, github.Branch{Name: name})
+	}
+	return branches, nil
+}
+
+func TestValidateArchivedRepoPlugins(t *testing.T) {
+	client := &fakeDeadConfigClient{archived: map[string]bool{"org/repo": false, "org/old": true}}
+	testCases := []struct {
+		name        string
+		pcfg        *plugins.Configuration
+		expectedErr string
+	}{
+		{
+			name: "plugins for orgs and repos that are not archived",
+			pcfg: &plugins.Configuration{
+				Plugins:         plugins.Plugins{"org": {Plugins: []string{"lgtm"}}, "org/repo": {Plugins: []string{"hold"}}},
+				ExternalPlugins: map[string][]plugins.ExternalPlugin{"org/repo": {{Name: "needs-rebase"}}},
+			},
+		},
+		{
+			name:        "plugins for an archived repo",
+			pcfg:        &plugins.Configuration{Plugins: plugins.Plugins{"org/old": {Plugins: []string{"lgtm"}}}},
+			expectedErr: "plugins are enabled for org/old, which is archived",
+		},
+		{
+			name:        "external plugins for an archived repo",
+			pcfg:        &plugins.Configuration{ExternalPlugins: map[string][]plugins.ExternalPlugin{"org/old": {{Name: "needs-rebase"}}}},
+			expectedErr: "plugins are enabled for org/old, which is archived",
+		},
+		{
+			name:        "plugins for a repo that can't be fetched",
+			pcfg:        &plugins.Configuration{Plugins: plugins.Plugins{"org/gone": {Plugins: []string{"lgtm"}}}},
+			expectedErr: "failed to get repo org/gone: not found",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := errMsg(validateArchivedRepoPlugins(client, tc.pcfg)); actual != tc.expectedErr {
+				t.Errorf("expected error %q, got %q", tc.expectedErr, actual)
+			}
+		})
+	}
+}
+
+func TestValidateNonexistentTideBranches(t *testing.T) {
+	client := &fakeDeadConfigClient{branches: map[string][]string{"org/repo": {"main", "release-1.0"}}}
+	testCases := []struct {
+		name        string
+		queries     config.TideQueries
+		expectedErr string
+	}{
+		{
+			name: "existing branches",
+			queries: config.TideQueries{
+				{Repos: []string{"org/repo"}, IncludedBranches: []string{"main", "release-1.0"}},
+				{Orgs: []string{"other"}, IncludedBranches: []string{"master"}},
+			},
+		},
+		{
+			name:    "queries without included branches",
+			queries: config.TideQueries{{Repos: []string{"org/gone"}}},
+		},
+		{
+			name:        "nonexistent branch",
+			queries:     config.TideQueries{{Repos: []string{"org/repo"}, IncludedBranches: []string{"main", "master"}}},
+			expectedErr: "tide query 0 includes branch master, which doesn't exist in org/repo",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{ProwConfig: config.ProwConfig{Tide: config.Tide{Queries: tc.queries}}}
+			if actual := errMsg(validateNonexistentTideBranches(client, cfg)); actual != tc.expectedErr {
+				t.Errorf("expected error %q, got %q", tc.expectedErr, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// behaviorField is a field deciding when a job runs or how it reports.
+type behaviorField struct {
+	name  string
+	value string
+}
+
+// jobBehavior is what a diff reports of a job.
+type jobBehavior struct {
+	// fields are the fields deciding when the job runs and how it reports,
+	// empty when unset.
+	fields []behaviorField
+	// context is the status context the job reports, if any.
+	context string
+	// raw is the whole job, to tell about changes of the other fields.
+	raw string
+}
+
+// reportConfigDiff reports in markdown the jobs, plugins and Tide queries whose behavior
+// the configs change from the configs of the base checkout, to be commented
+// on the PRs changing them.
+func reportConfigDiff(o options) (string, error) {
+	head, headPlugins, err := loadConfigs(o)
+	if err != nil {
+		return "", err
+	}
+	baseOptions := o
+	baseOptions.root = o.diffBase
+	base, basePlugins, err := loadConfigs(baseOptions)
+	if err != nil {
+		return "", fmt.Errorf("base: %w", err)
+	}
+	return diffConfigs(base, head, basePlugins, headPlugins), nil
+}
+
+func diffConfigs(base, head *config.Config, basePlugins, headPlugins *plugins.Configuration) string {
+	if basePlugins == nil {
+		basePlugins = &plugins.Configuration{}
+	}
+	if headPlugins == nil {
+		headPlugins = &plugins.Configuration{}
+	}
+	sections := []struct {
+		title   string
+		changes []string
+	}{
+		{title: "Presubmits", changes: diffJobs(presubmitBehaviors(base.PresubmitsStatic), presubmitBehaviors(head.PresubmitsStatic))},
+		{title: "Postsubmits", changes: diffJobs(postsubmitBehaviors(base.PostsubmitsStatic), postsubmitBehaviors(head.PostsubmitsStatic))},
+		{title: "Periodics", changes: diffJobs(periodicBehaviors(base.Periodics), periodicBehaviors(head.Periodics))},
+		{title: "Plugins", changes: diffPlugins(basePlugins, headPlugins)},
+		{title: "Tide", changes: diffTide(base.Tide.Queries, head.Tide.Queries)},
+	}
+
+	var report strings.Builder
+	report.WriteString("## Config changes\n")
+	changed := false
+	for _, section := range sections {
+		if len(section.changes) == 0 {
+			continue
+		}
+		changed = true
+		fmt.Fprintf(&report, "\n### %s\n\n", section.title)
+		for _, change := range section.changes {
+			fmt.Fprintf(&report, "* %s\n", change)
+		}
+	}
+	if !changed {
+		report.WriteString("\nNo job, plugin or Tide query changes behavior.\n")
+	}
+	return report.String()
+}
+
+func presubmitBehaviors(presubmits map[string][]config.Presubmit) map[string]jobBehavior {
+	behaviors := map[string]jobBehavior{}
+	for repo, jobs := range presubmits {
+		for _, job := range jobs {
+			behaviors[fmt.Sprintf("`%s` `%s`", repo, job.Name)] = newJobBehavior(job, reportedContext(job.Reporter), []behaviorField{
+				{name: "always_run", value: boolValue(job.AlwaysRun)},
+				{name: "run_if_changed", value: job.RunIfChanged},
+				{name: "skip_if_only_changed", value: job.SkipIfOnlyChanged},
+				{name: "trigger", value: job.Trigger},
+				{name: "branches", value: strings.Join(job.Branches, ", ")},
+				{name: "skip_branches", value: strings.Join(job.SkipBranches, ", ")},
+				{name: "optional", value: boolValue(job.Optional)},
+				{name: "context", value: reportedContext(job.Reporter)},
+			})
+		}
+	}
+	return behaviors
+}
+
+func postsubmitBehaviors(postsubmits map[string][]config.Postsubmit) map[string]jobBehavior {
+	behaviors := map[string]jobBehavior{}
+	for repo, jobs := range postsubmits {
+		for _, job := range jobs {
+			var alwaysRun string
+			if job.AlwaysRun != nil {
+				alwaysRun = strconv.FormatBool(*job.AlwaysRun)
+			}
+			behaviors[fmt.Sprintf("`%s` `%s`", repo, job.Name)] = newJobBehavior(job, reportedContext(job.Reporter), []behaviorField{
+				{name: "always_run", value: alwaysRun},
+				{name: "run_if_changed", value: job.RunIfChanged},
+				{name: "skip_if_only_changed", value: job.SkipIfOnlyChanged},
+				{name: "branches", value: strings.Join(job.Branches, ", ")},
+				{name: "skip_branches", value: strings.Join(job.SkipBranches, ", ")},
+				{name: "context", value: reportedContext(job.Reporter)},
+			})
+		}
+	}
+	return behaviors
+}
+
+func periodicBehaviors(periodics []config.Periodic) map[string]jobBehavior {
+	behaviors := map[string]jobBehavior{}
+	for _, job := range periodics {
+		behaviors[fmt.Sprintf("`%s`", job.Name)] = newJobBehavior(job, "", []behaviorField{
+			{name: "interval", value: job.Interval},
+			{name: "cron", value: job.Cron},
+		})
+	}
+	return behaviors
+}
+
+func newJobBehavior(job interface{}, context string, fields []behaviorField) jobBehavior {
+	// Jobs marshal to JSON, the error can't happen.
+	raw, _ := json.Marshal(job)
+	return jobBehavior{fields: fields, context: context, raw: string(raw)}
+}
+
+func reportedContext(reporter config.Reporter) string {
+	if reporter.SkipReport {
+		return ""
+	}
+	return reporter.Context
+}
+
+func boolValue(b bool) string {
+	if b {
+		return "true"
+	}
+	return ""
+}
+
+func code(value string) string {
+	if value == "" {
+		return "unset"
+	}
+	return "`" + value + "`"
+}
+
+// diffJobs describes the jobs that are added, removed or changed, by job.
+func diffJobs(base, head map[string]jobBehavior) []string {
+	names := sets.NewString()
+	for name := range base {
+		names.Insert(name)
+	}
+	for name := range head {
+		names.Insert(name)
+	}
+
+	var changes []string
+	for _, name := range names.List() {
+		before, inBase := base[name]
+		after, inHead := head[name]
+		switch {
+		case !inBase:
+			var set []string
+			for _, field := range after.fields {
+				if field.value != "" {
+					set = append(set, fmt.Sprintf("`%s` %s", field.name, code(field.value)))
+				}
+			}
+			change := name + ": added"
+			if len(set) > 0 {
+				change += ", " + strings.Join(set, ", ")
+			}
+			changes = append(changes, change)
+		case !inHead:
+			change := name + ": removed"
+			if before.context != "" {
+				change += fmt.Sprintf(", context %s is no longer reported", code(before.context))
+			}
+			changes = append(changes, change)
+		case before.raw != after.raw:
+			var fieldChanges []string
+			for i, field := range after.fields {
+				if old := before.fields[i].value; old != field.value {
+					fieldChanges = append(fieldChanges, fmt.Sprintf("`%s` %s → %s", field.name, code(old), code(field.value)))
+				}
+			}
+			if before.context != "" && before.context != after.context {
+				fieldChanges = append(fieldChanges, fmt.Sprintf("context %s is no longer reported", code(before.context)))
+			}
+			if len(fieldChanges) == 0 {
+				fieldChanges = append(fieldChanges, "other fields changed")
+			}
+			changes = append(changes, fmt.Sprintf("%s: %s", name, strings.Join(fieldChanges, ", ")))
+		}
+	}
+	return changes
+}
+
+// diffPlugins describes the plugins and external plugins that are enabled or
+// disabled, by org or repo.
+func diffPlugins(base, head *plugins.Configuration) []string {
+	orgRepos := sets.NewString()
+	for orgRepo := range base.Plugins {
+		orgRepos.Insert(orgRepo)
+	}
+	for orgRepo := range head.Plugins {
+		orgRepos.Insert(orgRepo)
+	}
+	for orgRepo := range base.ExternalPlugins {
+		orgRepos.Insert(orgRepo)
+	}
+	for orgRepo := range head.ExternalPlugins {
+		orgRepos.Insert(orgRepo)
+	}
+
+	var changes []string
+	for _, orgRepo := range orgRepos.List() {
+		var orgRepoChanges []string
+		describe := func(verb string, items sets.String) {
+			if items.Len() == 0 {
+				return
+			}
+			var quoted []string
+			for _, item := range items.List() {
+				quoted = append(quoted, code(item))
+			}
+			orgRepoChanges = append(orgRepoChanges, verb+" "+strings.Join(quoted, ", "))
+		}
+
+		before, after := sets.NewString(base.Plugins[orgRepo].Plugins...), sets.NewString(head.Plugins[orgRepo].Plugins...)
+		describe("enables", after.Difference(before))
+		describe("disables", before.Difference(after))
+		beforeExcluded, afterExcluded := sets.NewString(base.Plugins[orgRepo].ExcludedRepos...), sets.NewString(head.Plugins[orgRepo].ExcludedRepos...)
+		describe("excludes", afterExcluded.Difference(beforeExcluded))
+		describe("no longer excludes", beforeExcluded.Difference(afterExcluded))
+
+		beforeExternal, afterExternal := externalPluginEvents(base.ExternalPlugins[orgRepo]), externalPluginEvents(head.ExternalPlugins[orgRepo])
+		describe("enables the external plugins", sets.StringKeySet(afterExternal).Difference(sets.StringKeySet(beforeExternal)))
+		describe("disables the external plugins", sets.StringKeySet(beforeExternal).Difference(sets.StringKeySet(afterExternal)))
+		for _, name := range sets.StringKeySet(beforeExternal).Intersection(sets.StringKeySet(afterExternal)).List() {
+			if beforeExternal[name] != afterExternal[name] {
+				orgRepoChanges = append(orgRepoChanges, fmt.Sprintf("the external plugin %s gets the events %s instead of %s", code(name), code(afterExternal[name]), code(beforeExternal[name])))
+			}
+		}
+
+		if len(orgRepoChanges) > 0 {
+			changes = append(changes, fmt.Sprintf("%s: %s", code(orgRepo), strings.Join(orgRepoChanges, ", ")))
+		}
+	}
+	return changes
+}
+
+// externalPluginEvents returns the events of the external plugins, by name.
+func externalPluginEvents(externalPlugins []plugins.ExternalPlugin) map[string]string {
+	events := map[string]string{}
+	for _, p := range externalPlugins {
+		pluginEvents := append([]string{}, p.Events...)
+		sort.Strings(pluginEvents)
+		events[p.Name] = strings.Join(pluginEvents, ", ")
+	}
+	return events
+}
+
+// diffTide describes the orgs and repos whose PRs Tide merges under other
+// requirements.
+func diffTide(base, head config.TideQueries) []string {
+	before, after := tideRequirements(base), tideRequirements(head)
+	orgRepos := sets.StringKeySet(before).Union(sets.StringKeySet(after))
+
+	var changes []string
+	for _, orgRepo := range orgRepos.List() {
+		switch old, current := before[orgRepo], after[orgRepo]; {
+		case old == current:
+		case old == "":
+			changes = append(changes, fmt.Sprintf("%s: merges the PRs %s", code(orgRepo), current))
+		case current == "":
+			changes = append(changes, fmt.Sprintf("%s: no longer merges PRs", code(orgRepo)))
+		default:
+			changes = append(changes, fmt.Sprintf("%s: merges the PRs %s instead of %s", code(orgRepo), current, old))
+		}
+	}
+	return changes
+}
+
+// tideRequirements describes the requirements of the queries, by org and
+// repo.
+func tideRequirements(queries config.TideQueries) map[string]string {
+	requirements := map[string][]string{}
+	for _, query := range queries {
+		requirement := tideRequirement(query)
+		for _, orgRepo := range append(append([]string{}, query.Orgs...), query.Repos...) {
+			requirements[orgRepo] = append(requirements[orgRepo], requirement)
+		}
+	}
+	described := map[string]string{}
+	for orgRepo, orgRepoRequirements := range requirements {
+		described[orgRepo] = strings.Join(sets.NewString(orgRepoRequirements...).List(), " or ")
+	}
+	return described
+}
+
+func tideRequirement(query config.TideQuery) string {
+	var parts []string
+	list := func(prefix string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		var quoted []string
+		for _, item := range items {
+			quoted = append(quoted, code(item))
+		}
+		parts = append(parts, prefix+" "+strings.Join(quoted, ", "))
+	}
+	list("with the labels", query.Labels)
+	list("without the labels", query.MissingLabels)
+	list("on the branches", query.IncludedBranches)
+	list("not on the branches", query.ExcludedBranches)
+	if query.Milestone != "" {
+		parts = append(parts, "in the milestone "+code(query.Milestone))
+	}
+	if query.Author != "" {
+		parts = append(parts, "by "+code(query.Author))
+	}
+	if query.ReviewApprovedRequired {
+		parts = append(parts, "approved by a review")
+	}
+	if len(parts) == 0 {
+		return "(all)"
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	base := &config.Config{
+		JobConfig: config.JobConfig{
+			PresubmitsStatic: map[string][]config.Presubmit{
+				"org/repo": {
+					{JobBase: config.JobBase{Name: "unit"}, AlwaysRun: true, Reporter: config.Reporter{Context: "unit"}},
+					{JobBase: config.JobBase{Name: "lint"}, RegexpChangeMatcher: config.RegexpChangeMatcher{RunIfChanged: `\.go$`}, Reporter: config.Reporter{Context: "lint"}},
+					{JobBase: config.JobBase{Name: "e2e", Spec: &v1.PodSpec{Containers: []v1.Container{{Image: "e2e:v1"}}}}, Reporter: config.Reporter{Context: "e2e"}},
+					{JobBase: config.JobBase{Name: "old"}, Reporter: config.Reporter{Context: "old"}},
+				},
+			},
+			Periodics: []config.Periodic{{JobBase: config.JobBase{Name: "nightly"}, Interval: "24h"}},
+		},
+		ProwConfig: config.ProwConfig{Tide: config.Tide{Queries: config.TideQueries{
+			{Repos: []string{"org/repo"}, Labels: []string{"lgtm", "approved"}},
+		}}},
+	}
+	head := &config.Config{
+		JobConfig: config.JobConfig{
+			PresubmitsStatic: map[string][]config.Presubmit{
+				"org/repo": {
+					{JobBase: config.JobBase{Name: "unit"}, AlwaysRun: true, Reporter: config.Reporter{Context: "unit"}},
+					{JobBase: config.JobBase{Name: "lint"}, RegexpChangeMatcher: config.RegexpChangeMatcher{RunIfChanged: `\.(go|sh)$`}, Reporter: config.Reporter{Context: "lint", SkipReport: true}},
+					{JobBase: config.JobBase{Name: "e2e", Spec: &v1.PodSpec{Containers: []v1.Container{{Image: "e2e:v2"}}}}, Reporter: config.Reporter{Context: "e2e"}},
+					{JobBase: config.JobBase{Name: "verify"}, AlwaysRun: true, Reporter: config.Reporter{Context: "verify"}},
+				},
+			},
+			Periodics: []config.Periodic{{JobBase: config.JobBase{Name: "nightly"}, Cron: "0 0 * * *"}},
+		},
+		ProwConfig: config.ProwConfig{Tide: config.Tide{Queries: config.TideQueries{
+			{Repos: []string{"org/repo"}, Labels: []string{"lgtm", "approved"}, MissingLabels: []string{"hold"}},
+			{Orgs: []string{"other"}},
+		}}},
+	}
+	basePlugins := &plugins.Configuration{
+		Plugins: plugins.Plugins{
+			"org":      {Plugins: []string{"lgtm", "hold"}},
+			"org/repo": {Plugins: []string{"approve"}},
+		},
+		ExternalPlugins: map[string][]plugins.ExternalPlugin{
+			"org": {{Name: "needs-rebase", Events: []string{"pull_request"}}},
+		},
+	}
+	headPlugins := &plugins.Configuration{
+		Plugins: plugins.Plugins{
+			"org":      {Plugins: []string{"lgtm", "wip"}, ExcludedRepos: []string{"archived"}},
+			"org/repo": {Plugins: []string{"approve"}},
+		},
+		ExternalPlugins: map[string][]plugins.ExternalPlugin{
+			"org": {{Name: "needs-rebase", Events: []string{"pull_request", "issue_comment"}}, {Name: "cherrypicker"}},
+		},
+	}
+
+	expected := "## Config changes\n" +
+		"\n### Presubmits\n\n" +
+		"* `org/repo` `e2e`: other fields changed\n" +
+		"* `org/repo` `lint`: `run_if_changed` `\\.go$` → `\\.(go|sh)$`, `context` `lint` → unset, context `lint` is no longer reported\n" +
+		"* `org/repo` `old`: removed, context `old` is no longer reported\n" +
+		"* `org/repo` `verify`: added, `always_run` `true`, `context` `verify`\n" +
+		"\n### Periodics\n\n" +
+		"* `nightly`: `interval` `24h` → unset, `cron` unset → `0 0 * * *`\n" +
+		"\n### Plugins\n\n" +
+		"* `org`: enables `wip`, disables `hold`, excludes `archived`, enables the external plugins `cherrypicker`, the external plugin `needs-rebase` gets the events `issue_comment, pull_request` instead of `pull_request`\n" +
+		"\n### Tide\n\n" +
+		"* `org/repo`: merges the PRs (with the labels `lgtm`, `approved` without the labels `hold`) instead of (with the labels `lgtm`, `approved`)\n" +
+		"* `other`: merges the PRs (all)\n"
+	if diff := cmp.Diff(expected, diffConfigs(base, head, basePlugins, headPlugins)); diff != "" {
+		t.Errorf("report differs from expected (-expected +got):\n%s", diff)
+	}
+
+	if actual, expected := diffConfigs(base, base, nil, nil), "## Config changes\n\nNo job, plugin or Tide query changes behavior.\n"; actual != expected {
+		t.Errorf("expected report %q for unchanged configs, got %q", expected, actual)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
@@ -80,9 +81,34 @@ type options struct {
 	strict                 bool
 	expensive              bool
 	includeDefaultWarnings bool
+	deadConfig             bool
 
 	github  flagutil.GitHubOptions
 	storage flagutil.StorageClientOptions
+
+	// diffBase is the checkout of the base of the configs, see diff.
+	diffBase string
+	// serveAddress is the address of the validation service, see serve.
+	serveAddress string
+	// root is the directory the paths of the configs are relative to, the
+	// working directory if empty.
+	root string
+}
+
+// path returns the path of a config.
+func (o *options) path(p string) string {
+	if o.root == "" || p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(o.root, p)
+}
+
+func (o *options) paths(ps []string) []string {
+	var joined []string
+	for _, p := range ps {
+		joined = append(joined, o.path(p))
+	}
+	return joined
 }
 
 func reportWarning(strict bool, errs utilerrors.Aggregate) {
@@ -169,7 +195,9 @@ func getAllWarnings() []string {
 }
 
 func (o *options) DefaultAndValidate() error {
-	allWarnings := getAllWarnings()
+	// The dead config warnings aren't enabled by --expensive-checks, but can
+	// be enabled one by one.
+	allWarnings := append(getAllWarnings(), deadConfigWarnings...)
 	for _, validate := range []interface{ Validate(bool) error }{&o.config, &o.pluginsConfig, &o.storage} {
 		if err := validate.Validate(false); err != nil {
 			return err
@@ -212,6 +240,9 @@ func (o *options) gatherOptions(flag *flag.FlagSet, args []string) error {
 	flag.BoolVar(&o.expensive, "expensive-checks", false, "If set, additional expensive warnings will be enabled")
 	flag.BoolVar(&o.strict, "strict", false, "If set, consider all warnings as errors.")
 	flag.BoolVar(&o.includeDefaultWarnings, "include-default-warnings", false, "If set force inclusion of default warning set. Normally this is inferred based on a lack of '--warnings' flags.")
+	flag.BoolVar(&o.deadConfig, "dead-config", false, "If set, additionally report the config that never takes effect, like unused presets and plugins enabled for archived repos. Requires GitHub access.")
+	flag.StringVar(&o.diffBase, "diff-base", "", "If set, the directory of a checkout of the base of the configs, like the target branch of a PR changing them: instead of validating the configs, report in markdown the jobs and plugins whose behavior they change from the base. The config paths must be relative to the working directory and the base checkout.")
+	flag.StringVar(&o.serveAddress, "serve-address", "", "If set, like :8888, serve the validation of the configs posted to /validate instead of validating the configs once. The config paths are then relative to the root of the posted files.")
 	o.github.AddCustomizedFlags(flag, throttlerDefaults)
 	o.github.AllowAnonymous = true
 	o.config.AddFlags(flag)
@@ -234,6 +265,20 @@ func main() {
 		logrus.Fatalf("Error parsing options - %v", err)
 	}
 
+	if o.serveAddress != "" {
+		serve(o)
+		return
+	}
+
+	if o.diffBase != "" {
+		report, err := reportConfigDiff(o)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to diff the configs")
+		}
+		fmt.Print(report)
+		return
+	}
+
 	if err := validate(o); err != nil {
 		switch e := err.(type) {
 		case utilerrors.Aggregate:
@@ -247,6 +292,27 @@ func main() {
 	}
 }
 
+// loadConfigs loads the Prow config and the plugin config, which is nil
+// without --plugin-config. The configs are loaded without an agent, which
+// would keep reloading them, since the validation service validates many
+// configs.
+func loadConfigs(o options) (*config.Config, *plugins.Configuration, error) {
+	cfg, err := config.Load(o.path(o.config.ConfigPath), o.path(o.config.JobConfigPath), o.paths(o.config.SupplementalProwConfigDirs.Strings()), o.config.SupplementalProwConfigsFileNameSuffix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading prow config: %w", err)
+	}
+
+	var pcfg *plugins.Configuration
+	if o.pluginsConfig.PluginConfigPath != "" {
+		pluginAgent := &plugins.ConfigAgent{}
+		if err := pluginAgent.Load(o.path(o.pluginsConfig.PluginConfigPath), o.paths(o.pluginsConfig.SupplementalPluginsConfigDirs.Strings()), o.pluginsConfig.SupplementalPluginsConfigsFileNameSuffix, o.pluginsConfig.CheckUnknownPlugins, o.pluginsConfig.SkipResolveConfigUpdater); err != nil {
+			return nil, nil, fmt.Errorf("error loading Prow plugin config: %w", err)
+		}
+		pcfg = pluginAgent.Config()
+	}
+	return cfg, pcfg, nil
+}
+
 func validate(o options) error {
 	// use all warnings by default
 	if len(o.warnings.Strings()) == 0 || o.includeDefaultWarnings {
@@ -259,12 +325,14 @@ func validate(o options) error {
 	if o.github.AppID != "" && o.github.AppPrivateKeyPath != "" {
 		o.warnings.Add(validateGitHubAppInstallationWarning)
 	}
+	if o.deadConfig {
+		o.warnings = flagutil.NewStrings(append(o.warnings.Strings(), deadConfigWarnings...)...)
+	}
 
-	configAgent, err := o.config.ConfigAgent()
+	cfg, pcfg, err := loadConfigs(o)
 	if err != nil {
-		return fmt.Errorf("error loading prow config: %w", err)
+		return err
 	}
-	cfg := configAgent.Config()
 
 	if o.prowYAMLRepoName != "" {
 		if err := validateInRepoConfig(cfg, o.prowYAMLPath, o.prowYAMLRepoName, o.warningEnabled(unknownFieldsAllWarning)); err != nil {
@@ -272,15 +340,6 @@ func validate(o options) error {
 		}
 	}
 
-	var pcfg *plugins.Configuration
-	if o.pluginsConfig.PluginConfigPath != "" {
-		pluginAgent, err := o.pluginsConfig.PluginAgent()
-		if err != nil {
-			return fmt.Errorf("error loading Prow plugin config: %w", err)
-		}
-		pcfg = pluginAgent.Config()
-	}
-
 	// the following checks are useful in finding user errors but their
 	// presence won't lead to strictly incorrect behavior, so we can
 	// detect them here but don't necessarily want to stop config re-load
@@ -361,24 +420,24 @@ func validate(o options) error {
 	unknownAllEnabled := o.warningEnabled(unknownFieldsAllWarning)
 	unknownEnabled := o.warningEnabled(unknownFieldsWarning)
 	if unknownAllEnabled {
-		if _, err := config.LoadStrict(o.config.ConfigPath, o.config.JobConfigPath, nil, ""); err != nil {
+		if _, err := config.LoadStrict(o.path(o.config.ConfigPath), o.path(o.config.JobConfigPath), nil, ""); err != nil {
 			errs = append(errs, err)
 		}
 	} else if unknownEnabled {
-		cfgBytes, err := ioutil.ReadFile(o.config.ConfigPath)
+		cfgBytes, err := ioutil.ReadFile(o.path(o.config.ConfigPath))
 		if err != nil {
 			return fmt.Errorf("error reading Prow config for validation: %w", err)
 		}
-		if err := validateUnknownFields(&config.Config{}, cfgBytes, o.config.ConfigPath); err != nil {
+		if err := validateUnknownFields(&config.Config{}, cfgBytes, o.path(o.config.ConfigPath)); err != nil {
 			errs = append(errs, err)
 		}
 	}
 	if pcfg != nil && (unknownEnabled || unknownAllEnabled) {
-		pcfgBytes, err := ioutil.ReadFile(o.pluginsConfig.PluginConfigPath)
+		pcfgBytes, err := ioutil.ReadFile(o.path(o.pluginsConfig.PluginConfigPath))
 		if err != nil {
 			return fmt.Errorf("error reading Prow plugin config for validation: %w", err)
 		}
-		if err := validateUnknownFields(&plugins.Configuration{}, pcfgBytes, o.pluginsConfig.PluginConfigPath); err != nil {
+		if err := validateUnknownFields(&plugins.Configuration{}, pcfgBytes, o.path(o.pluginsConfig.PluginConfigPath)); err != nil {
 			errs = append(errs, err)
 		}
 	}
@@ -403,7 +462,11 @@ func validate(o options) error {
 	}
 
 	if o.warningEnabled(validateSupplementalProwConfigOrgRepoHirarchy) {
-		if err := validateAdditionalProwConfigIsInOrgRepoDirectoryStructure(os.DirFS("./"), o.config.SupplementalProwConfigDirs.Strings(), o.pluginsConfig.SupplementalPluginsConfigDirs.Strings(), o.config.SupplementalProwConfigsFileNameSuffix, o.pluginsConfig.SupplementalPluginsConfigsFileNameSuffix); err != nil {
+		root := o.root
+		if root == "" {
+			root = "./"
+		}
+		if err := validateAdditionalProwConfigIsInOrgRepoDirectoryStructure(os.DirFS(root), o.config.SupplementalProwConfigDirs.Strings(), o.pluginsConfig.SupplementalPluginsConfigDirs.Strings(), o.config.SupplementalProwConfigsFileNameSuffix, o.pluginsConfig.SupplementalPluginsConfigsFileNameSuffix); err != nil {
 			errs = append(errs, err)
 		}
 	}
@@ -431,6 +494,36 @@ func validate(o options) error {
 		}
 	}
 
+	if o.warningEnabled(unusedPresetsWarning) {
+		if err := validateUnusedPresets(cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if o.warningEnabled(unreachableRunIfChangedWarning) {
+		if err := validateUnreachableRunIfChanged(cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if (pcfg != nil && o.warningEnabled(archivedRepoPluginsWarning)) || o.warningEnabled(nonexistentTideBranchesWarning) {
+		githubClient, err := o.github.GitHubClient(false)
+		if err != nil {
+			return fmt.Errorf("error loading GitHub client: %w", err)
+		}
+
+		if pcfg != nil && o.warningEnabled(archivedRepoPluginsWarning) {
+			if err := validateArchivedRepoPlugins(githubClient, pcfg); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if o.warningEnabled(nonexistentTideBranchesWarning) {
+			if err := validateNonexistentTideBranches(githubClient, cfg); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
 	return utilerrors.NewAggregate(errs)
 }
 func policyIsStrict(p config.Policy) bool {
@@ -533,7 +626,19 @@ func validateURLs(c config.ProwConfig) error {
 }
 
 func validateUnknownFields(cfg interface{}, cfgBytes []byte, filePath string) error {
-	err := yaml.Unmarshal(cfgBytes, &cfg, yaml.DisallowUnknownFields)
+	// Templates are not fields of the configs, they are resolved when the
+	// configs are loaded.
+	var err error
+	switch cfg.(type) {
+	case *config.Config:
+		cfgBytes, err = config.StripJobTemplates(cfgBytes)
+	case *plugins.Configuration:
+		cfgBytes, err = config.StripTemplates(cfgBytes, plugins.TemplatesKey)
+	}
+	if err != nil {
+		return fmt.Errorf("bad config in %s: %w", filePath, err)
+	}
+	err = yaml.Unmarshal(cfgBytes, &cfg, yaml.DisallowUnknownFields)
 	if err != nil {
 		return fmt.Errorf("unknown fields or bad config in %s: %w", filePath, err)
 	}
@@ -1174,14 +1279,25 @@ func validateJobCluster(job config.JobBase, statuses map[string]plank.ClusterSta
 	if job.Cluster != "" && job.Cluster != kube.DefaultClusterAlias && agentsNotSupportingCluster.Has(job.Agent) {
 		return fmt.Errorf("%s: cannot set cluster field if agent is %s", job.Name, job.Agent)
 	}
-	if statuses != nil {
-		status, ok := statuses[job.Cluster]
-		if !ok {
-			return fmt.Errorf("job configuration for %q specifies unknown 'cluster' value %q", job.Name, job.Cluster)
-		}
-		if status != plank.ClusterStatusReachable {
-			logrus.Warnf("Job configuration for %q specifies cluster %q which cannot be reached from Plank. Status: %q", job.Name, job.Cluster, status)
+	if statuses == nil {
+		return nil
+	}
+	if job.ClusterSelector != nil {
+		// The candidate clusters of the selector may not be reachable, plank
+		// doesn't pick them then.
+		for _, cluster := range job.ClusterSelector.Clusters {
+			if _, ok := statuses[cluster]; !ok {
+				return fmt.Errorf("job configuration for %q specifies unknown cluster %q in 'cluster_selector'", job.Name, cluster)
+			}
 		}
+		return nil
+	}
+	status, ok := statuses[job.Cluster]
+	if !ok {
+		return fmt.Errorf("job configuration for %q specifies unknown 'cluster' value %q", job.Name, job.Cluster)
+	}
+	if status != plank.ClusterStatusReachable {
+		logrus.Warnf("Job configuration for %q specifies cluster %q which cannot be reached from Plank. Status: %q", job.Name, job.Cluster, status)
 	}
 	return nil
 }
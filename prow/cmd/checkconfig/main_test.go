@@ -527,6 +527,59 @@ size:
   xs: 1`),
 			expectedErr: "size",
 		},
+		{
+			name:     "job templates",
+			filename: "templates.yaml",
+			cfg:      &config.Config{},
+			configBytes: []byte(`job_templates:
+  unit:
+    decorate: true
+job_overlays:
+  kube/kube: [unit]
+presubmits:
+  kube/kube:
+  - name: test-presubmit
+    inherits: [unit]
+    always_run: true
+    spec:
+      containers:
+      - image: alpine
+        command: ["/bin/printenv"]`),
+			expectedErr: "",
+		},
+		{
+			name:     "job templates and unknown job field",
+			filename: "templates.yaml",
+			cfg:      &config.Config{},
+			configBytes: []byte(`job_templates:
+  unit:
+    decorate: true
+presubmits:
+  kube/kube:
+  - name: test-presubmit
+    inherits: [unit]
+    never_run: false
+    spec:
+      containers:
+      - image: alpine
+        command: ["/bin/printenv"]`),
+			expectedErr: "never_run",
+		},
+		{
+			name:     "plugin templates",
+			filename: "templates.yaml",
+			cfg:      &plugins.Configuration{},
+			configBytes: []byte(`plugin_templates:
+  small:
+    s: 5
+plugins:
+  kube/kube:
+  - size
+size:
+  inherits: [small]
+  m: 20`),
+			expectedErr: "",
+		},
 		{
 			name:     "pointer to a slice",
 			filename: "pointer.yaml",
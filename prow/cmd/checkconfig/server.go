@@ -0,0 +1,211 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"k8s.io/test-infra/prow/interrupts"
+)
+
+// maxValidationRequestBytes bounds the size of the files of a validation
+// request.
+const maxValidationRequestBytes = 32 << 20
+
+var (
+	lineRe         = regexp.MustCompile(`\bline (\d+)`)
+	unknownFieldRe = regexp.MustCompile(`unknown field "([^"]+)"`)
+	structFieldRe  = regexp.MustCompile(`Go struct field \w+\.([\w.]+) of type`)
+)
+
+// validationRequest is the body of a request to the validation service.
+type validationRequest struct {
+	// Files are the contents of the files of the config repo, by path
+	// relative to its root.
+	Files map[string]string `json:"files"`
+	// ProwYAMLRepoName is the repo whose .prow.yaml or .prow directory, among
+	// the files, is validated, if set.
+	ProwYAMLRepoName string `json:"prow_yaml_repo_name,omitempty"`
+}
+
+// validationError is a problem found by the validation.
+type validationError struct {
+	// File is the path of the file of the problem, relative to the root of
+	// the files of the request, if known.
+	File string `json:"file,omitempty"`
+	// Line is the line of the problem in the file, if known.
+	Line int `json:"line,omitempty"`
+	// Field is the field of the problem, if known.
+	Field string `json:"field,omitempty"`
+	// Message describes the problem.
+	Message string `json:"message"`
+	// Severity is "error" for the problems failing the validation and
+	// "warning" for the others.
+	Severity string `json:"severity"`
+}
+
+// validationResponse is the body of the response of the validation service.
+type validationResponse struct {
+	Valid  bool              `json:"valid"`
+	Errors []validationError `json:"errors,omitempty"`
+}
+
+// validationServer validates the configs posted to it with the options of
+// checkconfig, their paths being relative to the root of the posted files.
+type validationServer struct {
+	o options
+	// lock serializes the validations, which mutate the options.
+	lock sync.Mutex
+}
+
+// serve serves the validation service until checkconfig is interrupted.
+func serve(o options) {
+	defer interrupts.WaitForGracefulShutdown()
+
+	mux := http.NewServeMux()
+	mux.Handle("/validate", &validationServer{o: o})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "OK") })
+	server := &http.Server{Addr: o.serveAddress, Handler: mux}
+	logrus.WithField("address", o.serveAddress).Info("Serving the config validation.")
+	interrupts.ListenAndServe(server, 5*time.Second)
+}
+
+func (s *validationServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "405 Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req validationRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxValidationRequestBytes)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	root, err := ioutil.TempDir("", "checkconfig")
+	if err != nil {
+		logrus.WithError(err).Error("Failed to create the directory of the files.")
+		http.Error(w, "failed to store the files", http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(root)
+	if err := writeFiles(root, req.Files); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	response := s.validate(root, req.ProwYAMLRepoName)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logrus.WithError(err).Error("Failed to write the validation response.")
+	}
+}
+
+// writeFiles writes the files, by path relative to the root, under the root.
+func writeFiles(root string, files map[string]string) error {
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if filepath.IsAbs(filepath.FromSlash(name)) || !strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return fmt.Errorf("file %q is outside of the config repo", name)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate validates the files under the root.
+func (s *validationServer) validate(root, prowYAMLRepoName string) validationResponse {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	o := s.o
+	o.root = root
+	// Requests that don't change any job config don't need to post any.
+	if jobConfigPath := o.path(o.config.JobConfigPath); jobConfigPath != "" {
+		if _, err := os.Stat(jobConfigPath); os.IsNotExist(err) {
+			if err := os.MkdirAll(jobConfigPath, 0755); err != nil {
+				return newValidationResponse(root, err, o.strict)
+			}
+		}
+	}
+	o.prowYAMLRepoName, o.prowYAMLPath = prowYAMLRepoName, ""
+	if prowYAMLRepoName != "" {
+		o.prowYAMLPath = filepath.Join(root, ".prow.yaml")
+	}
+	return newValidationResponse(root, validate(o), o.strict)
+}
+
+// newValidationResponse structures the error of the validation of the files
+// under the root. Aggregated errors are warnings, unless strict.
+func newValidationResponse(root string, err error, strict bool) validationResponse {
+	response := validationResponse{Valid: true}
+	if err == nil {
+		return response
+	}
+	severity, errs := "error", []error{err}
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		errs = utilerrors.Flatten(agg).Errors()
+		if !strict {
+			severity = "warning"
+		}
+	}
+	for _, err := range errs {
+		response.Errors = append(response.Errors, newValidationError(root, err.Error(), severity))
+	}
+	response.Valid = severity != "error"
+	return response
+}
+
+// newValidationError extracts the file, line and field of the problem from
+// the message of the error, whose paths are made relative to the root.
+func newValidationError(root, message, severity string) validationError {
+	prefix := root + string(filepath.Separator)
+	e := validationError{Message: strings.ReplaceAll(message, prefix, ""), Severity: severity}
+	if idx := strings.Index(message, prefix); idx >= 0 {
+		file := message[idx+len(prefix):]
+		if end := strings.IndexAny(file, " :\"'"); end >= 0 {
+			file = file[:end]
+		}
+		e.File = filepath.ToSlash(file)
+	}
+	if m := lineRe.FindStringSubmatch(message); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+	}
+	if m := unknownFieldRe.FindStringSubmatch(message); m != nil {
+		e.Field = m[1]
+	} else if m := structFieldRe.FindStringSubmatch(message); m != nil {
+		e.Field = m[1]
+	}
+	return e
+}
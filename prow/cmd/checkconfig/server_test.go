@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/flagutil"
+	configflagutil "k8s.io/test-infra/prow/flagutil/config"
+)
+
+func TestNewValidationError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		message  string
+		expected validationError
+	}{
+		{
+			name:    "yaml syntax error",
+			message: "error loading prow config: error unmarshaling /tmp/root/config.yaml: error converting YAML to JSON: yaml: line 3: did not find expected key",
+			expected: validationError{
+				File:    "config.yaml",
+				Line:    3,
+				Message: "error loading prow config: error unmarshaling config.yaml: error converting YAML to JSON: yaml: line 3: did not find expected key",
+			},
+		},
+		{
+			name:    "unknown field",
+			message: `unknown fields or bad config in /tmp/root/prow/config.yaml: error unmarshaling JSON: while decoding JSON: json: unknown field "tide_"`,
+			expected: validationError{
+				File:    "prow/config.yaml",
+				Field:   "tide_",
+				Message: `unknown fields or bad config in prow/config.yaml: error unmarshaling JSON: while decoding JSON: json: unknown field "tide_"`,
+			},
+		},
+		{
+			name:    "wrong type",
+			message: "error unmarshaling /tmp/root/jobs/org/repo.yaml: error unmarshaling JSON: while decoding JSON: json: cannot unmarshal string into Go struct field Presubmit.presubmits.always_run of type bool",
+			expected: validationError{
+				File:    "jobs/org/repo.yaml",
+				Field:   "presubmits.always_run",
+				Message: "error unmarshaling jobs/org/repo.yaml: error unmarshaling JSON: while decoding JSON: json: cannot unmarshal string into Go struct field Presubmit.presubmits.always_run of type bool",
+			},
+		},
+		{
+			name:     "no location",
+			message:  "job org/repo/unit is not decorated",
+			expected: validationError{Message: "job org/repo/unit is not decorated"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expected.Severity = "error"
+			if diff := cmp.Diff(tc.expected, newValidationError("/tmp/root", tc.message, "error")); diff != "" {
+				t.Errorf("validation error differs from expected (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidationServer(t *testing.T) {
+	server := &validationServer{o: options{
+		config:   configflagutil.ConfigOptions{ConfigPath: "prow/config.yaml", JobConfigPath: "jobs"},
+		warnings: flagutil.NewStrings(unknownFieldsWarning),
+	}}
+	testCases := []struct {
+		name     string
+		strict   bool
+		files    map[string]string
+		method   string
+		expected *validationResponse
+		// expectLine is whether the errors have a line, which is only checked
+		// to be set.
+		expectLine bool
+		// expectedStatus is the status of the response, 200 if unset.
+		expectedStatus int
+	}{
+		{
+			name: "valid config",
+			files: map[string]string{
+				"prow/config.yaml": "tide:\n  sync_period: 1m\n",
+				"jobs/org/repo.yaml": "presubmits:\n  org/repo:\n  - name: unit\n    spec:\n      containers:\n" +
+					"      - image: alpine\n        command: [\"true\"]\n",
+			},
+			expected: &validationResponse{Valid: true},
+		},
+		{
+			name: "invalid job config",
+			files: map[string]string{
+				"prow/config.yaml":   "",
+				"jobs/org/repo.yaml": "presubmits:\n  org/repo:\n  - name: unit\n    always_run: [\n",
+			},
+			expected:   &validationResponse{Errors: []validationError{{File: "jobs/org/repo.yaml", Severity: "error"}}},
+			expectLine: true,
+		},
+		{
+			name:  "unknown field is a warning",
+			files: map[string]string{"prow/config.yaml": "tide:\n  sync_periods: 1m\n"},
+			expected: &validationResponse{
+				Valid:  true,
+				Errors: []validationError{{File: "prow/config.yaml", Field: "sync_periods", Severity: "warning"}},
+			},
+		},
+		{
+			name:   "unknown field is an error when strict",
+			strict: true,
+			files:  map[string]string{"prow/config.yaml": "tide:\n  sync_periods: 1m\n"},
+			expected: &validationResponse{
+				Errors: []validationError{{File: "prow/config.yaml", Field: "sync_periods", Severity: "error"}},
+			},
+		},
+		{
+			name:           "file outside of the repo",
+			files:          map[string]string{"../config.yaml": ""},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "not a post",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server.o.strict = tc.strict
+			body, err := json.Marshal(validationRequest{Files: tc.files})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.method == "" {
+				tc.method = http.MethodPost
+			}
+			if tc.expectedStatus == 0 {
+				tc.expectedStatus = http.StatusOK
+			}
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, httptest.NewRequest(tc.method, "/validate", bytes.NewReader(body)))
+			if recorder.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+			if tc.expected == nil {
+				return
+			}
+			var actual validationResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &actual); err != nil {
+				t.Fatalf("failed to decode the response: %v", err)
+			}
+			// The messages come from the config loading, they are only
+			// checked not to leak the directory of the files.
+			for i := range actual.Errors {
+				if strings.Contains(actual.Errors[i].Message, "checkconfig") {
+					t.Errorf("expected the message to have relative paths, got %q", actual.Errors[i].Message)
+				}
+				actual.Errors[i].Message = ""
+				if tc.expectLine != (actual.Errors[i].Line > 0) {
+					t.Errorf("expected a line: %t, got %d", tc.expectLine, actual.Errors[i].Line)
+				}
+				actual.Errors[i].Line = 0
+			}
+			if diff := cmp.Diff(*tc.expected, actual); diff != "" {
+				t.Errorf("response differs from expected (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
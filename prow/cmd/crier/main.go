@@ -27,20 +27,24 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/bugzilla"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/config/secret"
 	"k8s.io/test-infra/prow/crier"
+	emailreporter "k8s.io/test-infra/prow/crier/reporters/email"
 	gcsreporter "k8s.io/test-infra/prow/crier/reporters/gcs"
 	k8sgcsreporter "k8s.io/test-infra/prow/crier/reporters/gcs/kubernetes"
 	gerritreporter "k8s.io/test-infra/prow/crier/reporters/gerrit"
 	githubreporter "k8s.io/test-infra/prow/crier/reporters/github"
 	pubsubreporter "k8s.io/test-infra/prow/crier/reporters/pubsub"
 	slackreporter "k8s.io/test-infra/prow/crier/reporters/slack"
+	trackerreporter "k8s.io/test-infra/prow/crier/reporters/tracker"
 	prowflagutil "k8s.io/test-infra/prow/flagutil"
 	configflagutil "k8s.io/test-infra/prow/flagutil/config"
 	gerritclient "k8s.io/test-infra/prow/gerrit/client"
 	"k8s.io/test-infra/prow/interrupts"
 	"k8s.io/test-infra/prow/io"
+	"k8s.io/test-infra/prow/jira"
 	"k8s.io/test-infra/prow/logrusutil"
 	"k8s.io/test-infra/prow/metrics"
 	slackclient "k8s.io/test-infra/prow/slack"
@@ -59,6 +63,8 @@ type options struct {
 	pubsubWorkers         int
 	githubWorkers         int
 	slackWorkers          int
+	emailWorkers          int
+	trackerWorkers        int
 	gcsWorkers            int
 	k8sGCSWorkers         int
 	blobStorageWorkers    int
@@ -67,6 +73,14 @@ type options struct {
 	slackTokenFile            string
 	additionalSlackTokenFiles slackclient.HostsFlag
 
+	smtpServer         string
+	smtpUsername       string
+	smtpPasswordFile   string
+	sendGridAPIKeyFile string
+
+	bugzilla prowflagutil.BugzillaOptions
+	jira     prowflagutil.JiraOptions
+
 	storage prowflagutil.StorageClientOptions
 
 	instrumentationOptions prowflagutil.InstrumentationOptions
@@ -78,7 +92,7 @@ type options struct {
 }
 
 func (o *options) validate() error {
-	if o.gerritWorkers+o.pubsubWorkers+o.githubWorkers+o.slackWorkers+o.gcsWorkers+o.k8sGCSWorkers+o.blobStorageWorkers+o.k8sBlobStorageWorkers <= 0 {
+	if o.gerritWorkers+o.pubsubWorkers+o.githubWorkers+o.slackWorkers+o.emailWorkers+o.trackerWorkers+o.gcsWorkers+o.k8sGCSWorkers+o.blobStorageWorkers+o.k8sBlobStorageWorkers <= 0 {
 		return errors.New("crier need to have at least one report worker to start")
 	}
 
@@ -108,6 +122,24 @@ func (o *options) validate() error {
 		}
 	}
 
+	if o.emailWorkers > 0 {
+		if (o.smtpServer == "") == (o.sendGridAPIKeyFile == "") {
+			return errors.New("exactly one of --smtp-server or --sendgrid-api-key-file must be set")
+		}
+		if (o.smtpUsername == "") != (o.smtpPasswordFile == "") {
+			return errors.New("--smtp-username and --smtp-password-file must be set together")
+		}
+	}
+
+	if o.trackerWorkers > 0 {
+		if err := o.bugzilla.Validate(o.dryrun); err != nil {
+			return err
+		}
+		if err := o.jira.Validate(o.dryrun); err != nil {
+			return err
+		}
+	}
+
 	if o.gcsWorkers > 0 {
 		logrus.Warn("--gcs-workers is deprecated and will be removed in August 2020. Use --blob-storage-workers instead.")
 		// return an error when the old and new flags are both set
@@ -147,6 +179,12 @@ func (o *options) parseArgs(fs *flag.FlagSet, args []string) error {
 	fs.IntVar(&o.githubWorkers, "github-workers", 0, "Number of github report workers (0 means disabled)")
 	fs.IntVar(&o.slackWorkers, "slack-workers", 0, "Number of Slack report workers (0 means disabled)")
 	fs.Var(&o.additionalSlackTokenFiles, "additional-slack-token-files", "Map of additional slack token files. example: --additional-slack-token-files=foo=/etc/foo-slack-tokens/token, repeat flag for each host")
+	fs.IntVar(&o.emailWorkers, "email-workers", 0, "Number of email report workers (0 means disabled)")
+	fs.StringVar(&o.smtpServer, "smtp-server", "", "Address of the SMTP server the email reporter sends emails through, as host:port")
+	fs.StringVar(&o.smtpUsername, "smtp-username", "", "Username for the SMTP server")
+	fs.StringVar(&o.smtpPasswordFile, "smtp-password-file", "", "Path to a file containing the password for the SMTP server")
+	fs.StringVar(&o.sendGridAPIKeyFile, "sendgrid-api-key-file", "", "Path to a file containing the SendGrid API key the email reporter sends emails with, instead of SMTP")
+	fs.IntVar(&o.trackerWorkers, "tracker-workers", 0, "Number of Bugzilla and Jira issue report workers (0 means disabled)")
 	fs.IntVar(&o.gcsWorkers, "gcs-workers", 0, "Number of GCS report workers (0 means disabled)")
 	fs.IntVar(&o.k8sGCSWorkers, "kubernetes-gcs-workers", 0, "Number of Kubernetes-specific GCS report workers (0 means disabled)")
 	fs.IntVar(&o.blobStorageWorkers, "blob-storage-workers", 0, "Number of blob storage report workers (0 means disabled)")
@@ -156,12 +194,14 @@ func (o *options) parseArgs(fs *flag.FlagSet, args []string) error {
 	fs.StringVar(&o.reportAgent, "report-agent", "", "Only report specified agent - empty means report to all agents (effective for github and Slack only)")
 
 	// TODO(krzyzacy): implement dryrun for gerrit/pubsub
-	fs.BoolVar(&o.dryrun, "dry-run", false, "Run in dry-run mode, not doing actual report (effective for github and Slack only)")
+	fs.BoolVar(&o.dryrun, "dry-run", false, "Run in dry-run mode, not doing actual report (effective for github, Slack, email and tracker only)")
 
 	o.config.AddFlags(fs)
 	o.github.AddFlags(fs)
 	o.client.AddFlags(fs)
 	o.storage.AddFlags(fs)
+	o.bugzilla.AddFlags(fs)
+	o.jira.AddFlags(fs)
 	o.instrumentationOptions.AddFlags(fs)
 	o.githubEnablement.AddFlags(fs)
 
@@ -244,6 +284,73 @@ func main() {
 		}
 	}
 
+	if o.emailWorkers > 0 {
+		var sender emailreporter.Sender
+		if o.sendGridAPIKeyFile != "" {
+			if err := secret.Add(o.sendGridAPIKeyFile); err != nil {
+				logrus.WithError(err).Fatal("could not read SendGrid API key")
+			}
+			sender = &emailreporter.SendGridSender{APIKey: secret.GetTokenGenerator(o.sendGridAPIKeyFile)}
+		} else {
+			smtpSender := &emailreporter.SMTPSender{Server: o.smtpServer, Username: o.smtpUsername}
+			if o.smtpPasswordFile != "" {
+				if err := secret.Add(o.smtpPasswordFile); err != nil {
+					logrus.WithError(err).Fatal("could not read SMTP password")
+				}
+				smtpSender.Password = secret.GetTokenGenerator(o.smtpPasswordFile)
+			}
+			sender = smtpSender
+		}
+
+		hasReporter = true
+		emailReporter := emailreporter.NewReporter(cfg, sender, mgr.GetAPIReader(), o.dryrun)
+		if err := mgr.Add(emailReporter); err != nil {
+			logrus.WithError(err).Fatal("failed to add the email digests to the manager")
+		}
+		if err := crier.New(mgr, emailReporter, o.emailWorkers, o.githubEnablement.EnablementChecker()); err != nil {
+			logrus.WithError(err).Fatal("failed to construct email reporter controller")
+		}
+	}
+
+	if o.trackerWorkers > 0 {
+		// Clients are only created for the trackers the rules use when
+		// crier starts.
+		var usesBugzilla, usesJira bool
+		for _, rule := range cfg().TrackerReporter.Rules {
+			usesBugzilla = usesBugzilla || rule.Bugzilla != nil
+			usesJira = usesJira || rule.Jira != nil
+		}
+		var bugzillaClient bugzilla.Client
+		if usesBugzilla {
+			if o.bugzilla.ApiKeyPath != "" {
+				if err := secret.Add(o.bugzilla.ApiKeyPath); err != nil {
+					logrus.WithError(err).Fatal("could not read Bugzilla API key")
+				}
+			}
+			bugzillaClient, err = o.bugzilla.BugzillaClient()
+			if err != nil {
+				logrus.WithError(err).Fatal("Error getting Bugzilla client.")
+			}
+		}
+		var jiraClient jira.Client
+		if usesJira {
+			jiraClient, err = o.jira.Client()
+			if err != nil {
+				logrus.WithError(err).Fatal("Error getting Jira client.")
+			}
+		}
+		opener, err := io.NewOpener(context.Background(), o.storage.GCSCredentialsFile, o.storage.S3CredentialsFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Error creating opener")
+		}
+
+		hasReporter = true
+		trackerReporter := trackerreporter.NewReporter(cfg, bugzillaClient, jiraClient, opener, o.dryrun)
+		if err := crier.New(mgr, trackerReporter, o.trackerWorkers, o.githubEnablement.EnablementChecker()); err != nil {
+			logrus.WithError(err).Fatal("failed to construct tracker reporter controller")
+		}
+	}
+
 	if o.gerritWorkers > 0 {
 		gerritReporter, err := gerritreporter.NewReporter(cfg, o.cookiefilePath, o.gerritProjects, mgr.GetClient())
 		if err != nil {
@@ -153,6 +153,83 @@ func TestOptions(t *testing.T) {
 				instrumentationOptions: prowflagutil.DefaultInstrumentationOptions(),
 			},
 		},
+		//Email Reporter
+		{
+			name: "email workers with SMTP, sets workers",
+			args: []string{"--email-workers=2", "--smtp-server=smtp.example.com:587", "--smtp-username=prow", "--smtp-password-file=/etc/smtp/password", "--config-path=foo"},
+			expected: &options{
+				emailWorkers:     2,
+				smtpServer:       "smtp.example.com:587",
+				smtpUsername:     "prow",
+				smtpPasswordFile: "/etc/smtp/password",
+				config: configflagutil.ConfigOptions{
+					ConfigPathFlagName:                    "config-path",
+					JobConfigPathFlagName:                 "job-config-path",
+					ConfigPath:                            "foo",
+					SupplementalProwConfigsFileNameSuffix: "_prowconfig.yaml",
+				},
+				github:                 defaultGitHubOptions,
+				gerritProjects:         defaultGerritProjects,
+				k8sReportFraction:      1.0,
+				instrumentationOptions: prowflagutil.DefaultInstrumentationOptions(),
+			},
+		},
+		{
+			name: "email workers with SendGrid, sets workers",
+			args: []string{"--email-workers=1", "--sendgrid-api-key-file=/etc/sendgrid/key", "--config-path=foo"},
+			expected: &options{
+				emailWorkers:       1,
+				sendGridAPIKeyFile: "/etc/sendgrid/key",
+				config: configflagutil.ConfigOptions{
+					ConfigPathFlagName:                    "config-path",
+					JobConfigPathFlagName:                 "job-config-path",
+					ConfigPath:                            "foo",
+					SupplementalProwConfigsFileNameSuffix: "_prowconfig.yaml",
+				},
+				github:                 defaultGitHubOptions,
+				gerritProjects:         defaultGerritProjects,
+				k8sReportFraction:      1.0,
+				instrumentationOptions: prowflagutil.DefaultInstrumentationOptions(),
+			},
+		},
+		{
+			name: "email workers without SMTP server or SendGrid, rejects",
+			args: []string{"--email-workers=1", "--config-path=foo"},
+		},
+		{
+			name: "email workers with SMTP server and SendGrid, rejects",
+			args: []string{"--email-workers=1", "--smtp-server=smtp.example.com:587", "--sendgrid-api-key-file=/etc/sendgrid/key", "--config-path=foo"},
+		},
+		{
+			name: "email workers with SMTP username but no password, rejects",
+			args: []string{"--email-workers=1", "--smtp-server=smtp.example.com:587", "--smtp-username=prow", "--config-path=foo"},
+		},
+		//Tracker Reporter
+		{
+			name: "tracker workers, sets workers",
+			args: []string{"--tracker-workers=2", "--config-path=foo"},
+			expected: &options{
+				trackerWorkers: 2,
+				config: configflagutil.ConfigOptions{
+					ConfigPathFlagName:                    "config-path",
+					JobConfigPathFlagName:                 "job-config-path",
+					ConfigPath:                            "foo",
+					SupplementalProwConfigsFileNameSuffix: "_prowconfig.yaml",
+				},
+				github:                 defaultGitHubOptions,
+				gerritProjects:         defaultGerritProjects,
+				k8sReportFraction:      1.0,
+				instrumentationOptions: prowflagutil.DefaultInstrumentationOptions(),
+			},
+		},
+		{
+			name: "tracker workers with invalid Jira endpoint, rejects",
+			args: []string{"--tracker-workers=2", "--jira-endpoint=not a url", "--config-path=foo"},
+		},
+		{
+			name: "tracker workers with Jira username but no password, rejects",
+			args: []string{"--tracker-workers=2", "--jira-endpoint=https://jira.example.com", "--jira-username=prow", "--config-path=foo"},
+		},
 		{
 			name: "k8s-gcs enables k8s-gcs",
 			args: []string{"--kubernetes-blob-storage-workers=3", "--config-path=foo"},
@@ -0,0 +1,388 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+	cron "gopkg.in/robfig/cron.v2"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/deck/jobs"
+)
+
+const (
+	atomFeedFormat = "atom"
+	rssFeedFormat  = "rss"
+
+	// maxFeedEntries is the number of failures in the feeds.
+	maxFeedEntries = 50
+
+	defaultCalendarDays = 7
+	maxCalendarDays     = 31
+	// maxCalendarEvents is the number of runs of a job in the calendar, which
+	// bounds the calendars of frequent jobs.
+	maxCalendarEvents = 100
+	// defaultRunDuration is the duration of the runs in the calendar of the
+	// jobs which haven't completed a run yet.
+	defaultRunDuration = 30 * time.Minute
+
+	icsTimeFormat = "20060102T150405Z"
+)
+
+// matchesJobs returns whether the job matches the selector, a comma-separated
+// list of globs. The empty selector matches all jobs.
+func matchesJobs(job, selector string) bool {
+	if selector == "" {
+		return true
+	}
+	for _, pat := range strings.Split(selector, ",") {
+		if match, _ := filepath.Match(pat, job); match {
+			return true
+		}
+	}
+	return false
+}
+
+// recentFailures returns the most recent failed runs of the periodics matching
+// the selector, the most recent first.
+func recentFailures(pjs []prowapi.ProwJob, selector string) []prowapi.ProwJob {
+	var failures []prowapi.ProwJob
+	for _, pj := range pjs {
+		if pj.Spec.Type != prowapi.PeriodicJob || pj.Status.CompletionTime == nil {
+			continue
+		}
+		if pj.Status.State != prowapi.FailureState && pj.Status.State != prowapi.ErrorState {
+			continue
+		}
+		if matchesJobs(pj.Spec.Job, selector) {
+			failures = append(failures, pj)
+		}
+	}
+	sort.SliceStable(failures, func(i, j int) bool {
+		return failures[j].Status.CompletionTime.Before(failures[i].Status.CompletionTime)
+	})
+	if len(failures) > maxFeedEntries {
+		failures = failures[:maxFeedEntries]
+	}
+	return failures
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	Description string  `xml:"description,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+func failureTitle(pj prowapi.ProwJob) string {
+	return fmt.Sprintf("%s %s", pj.Spec.Job, pj.Status.State)
+}
+
+// failureID identifies the failure in the feed. The names of ProwJobs are
+// unique, their URLs may not be set.
+func failureID(pj prowapi.ProwJob) string {
+	return "urn:prowjob:" + pj.Name
+}
+
+// renderFailureFeed renders the failures as an Atom or RSS feed linking to
+// the URL of the feed.
+func renderFailureFeed(failures []prowapi.ProwJob, format, feedURL string, now time.Time) ([]byte, error) {
+	title := "Prow periodic failures"
+	var feed interface{}
+	switch format {
+	case atomFeedFormat:
+		updated := now
+		if len(failures) > 0 {
+			updated = failures[0].Status.CompletionTime.Time
+		}
+		atom := atomFeed{
+			Title:   title,
+			ID:      feedURL,
+			Updated: updated.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: feedURL, Rel: "self"},
+			Author:  atomAuthor{Name: "Prow"},
+		}
+		for _, pj := range failures {
+			atom.Entries = append(atom.Entries, atomEntry{
+				Title:   failureTitle(pj),
+				ID:      failureID(pj),
+				Updated: pj.Status.CompletionTime.UTC().Format(time.RFC3339),
+				Link:    atomLink{Href: pj.Status.URL},
+				Summary: pj.Status.Description,
+			})
+		}
+		feed = atom
+	case rssFeedFormat:
+		rss := rssFeed{
+			Version: "2.0",
+			Channel: rssChannel{Title: title, Link: feedURL, Description: title},
+		}
+		for _, pj := range failures {
+			rss.Channel.Items = append(rss.Channel.Items, rssItem{
+				Title:       failureTitle(pj),
+				Link:        pj.Status.URL,
+				Description: pj.Status.Description,
+				GUID:        rssGUID{Value: failureID(pj)},
+				PubDate:     pj.Status.CompletionTime.UTC().Format(time.RFC1123Z),
+			})
+		}
+		feed = rss
+	default:
+		return nil, fmt.Errorf("unknown feed format %q", format)
+	}
+	b, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+// requestURL returns the absolute URL of the request, which is behind a load
+// balancer terminating TLS unless it says otherwise.
+func requestURL(r *http.Request) string {
+	scheme := "https"
+	if r.TLS == nil && r.Header.Get("x-forwarded-proto") == "http" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.RequestURI())
+}
+
+// handleFailureFeed serves the recent failures of the periodics matching the
+// `jobs` query parameter, a comma-separated list of globs, as an Atom or RSS
+// feed, e.g.:
+// - /feeds/failures.atom?jobs=ci-kubernetes-e2e-*
+// - /feeds/failures.rss?jobs=ci-kubernetes-build,ci-kubernetes-node-*
+func handleFailureFeed(ja *jobs.JobAgent, format string, log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		failures := recentFailures(scopedProwJobs(r, ja), r.URL.Query().Get("jobs"))
+		b, err := renderFailureFeed(failures, format, requestURL(r), time.Now())
+		if err != nil {
+			log.WithError(err).Error("Error rendering failure feed.")
+			http.Error(w, "failed to render the feed", http.StatusInternalServerError)
+			return
+		}
+		if format == atomFeedFormat {
+			w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		}
+		if _, err := w.Write(b); err != nil {
+			log.WithError(err).Debug("Error writing failure feed.")
+		}
+	}
+}
+
+// upcomingRun is a scheduled run of a periodic.
+type upcomingRun struct {
+	Job      string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// upcomingRuns returns the runs of the periodics matching the selector which
+// are scheduled before the end, sorted by start. Cron periodics run on their
+// schedule, interval periodics an interval after their last run, or right away
+// if they are overdue. The runs last as long as the last completed run.
+func upcomingRuns(periodics []config.Periodic, pjs []prowapi.ProwJob, selector string, now, end time.Time) []upcomingRun {
+	lastStart := map[string]time.Time{}
+	lastCompletion := map[string]time.Time{}
+	durations := map[string]time.Duration{}
+	for _, pj := range pjs {
+		if pj.Spec.Type != prowapi.PeriodicJob {
+			continue
+		}
+		job := pj.Spec.Job
+		if start := pj.Status.StartTime.Time; start.After(lastStart[job]) {
+			lastStart[job] = start
+		}
+		if c := pj.Status.CompletionTime; c != nil && c.Time.After(lastCompletion[job]) {
+			lastCompletion[job] = c.Time
+			durations[job] = c.Time.Sub(pj.Status.StartTime.Time)
+		}
+	}
+
+	var runs []upcomingRun
+	for _, p := range periodics {
+		if !matchesJobs(p.Name, selector) {
+			continue
+		}
+		var start time.Time
+		var next func(time.Time) time.Time
+		if p.Cron != "" {
+			// Like horologium, which schedules the cron periodics in UTC.
+			schedule, err := cron.Parse("TZ=UTC " + p.Cron)
+			if err != nil {
+				continue
+			}
+			start, next = schedule.Next(now), schedule.Next
+		} else if interval := p.GetInterval(); interval > 0 {
+			start = lastStart[p.Name].Add(interval)
+			if start.Before(now) {
+				start = now
+			}
+			next = func(t time.Time) time.Time { return t.Add(interval) }
+		} else {
+			continue
+		}
+		duration, ok := durations[p.Name]
+		if !ok || duration <= 0 {
+			duration = defaultRunDuration
+		}
+		for i := 0; i < maxCalendarEvents && !start.IsZero() && start.Before(end); i++ {
+			runs = append(runs, upcomingRun{Job: p.Name, Start: start, Duration: duration})
+			start = next(start)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		if runs[i].Start.Equal(runs[j].Start) {
+			return runs[i].Job < runs[j].Job
+		}
+		return runs[i].Start.Before(runs[j].Start)
+	})
+	return runs
+}
+
+// escapeICSText escapes the text values of an ICS calendar, see RFC 5545 3.3.11.
+func escapeICSText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
+
+// foldICSLine folds the content line to lines of at most 75 octets, the
+// continuation lines starting with a space, see RFC 5545 3.1.
+func foldICSLine(line string) string {
+	const maxLength = 75
+	var b strings.Builder
+	length := 0
+	for _, r := range line {
+		size := utf8.RuneLen(r)
+		if length+size > maxLength {
+			b.WriteString("\r\n ")
+			length = 1
+		}
+		b.WriteRune(r)
+		length += size
+	}
+	return b.String()
+}
+
+// renderCalendar renders the runs as an ICS calendar. The UIDs of the events
+// are stable so that subscribed calendars update rather than duplicate them.
+func renderCalendar(runs []upcomingRun, host string, now time.Time) []byte {
+	var b bytes.Buffer
+	write := func(line string) {
+		b.WriteString(foldICSLine(line))
+		b.WriteString("\r\n")
+	}
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:-//Prow//Deck//EN")
+	write("X-WR-CALNAME:Prow periodics")
+	for _, run := range runs {
+		write("BEGIN:VEVENT")
+		write("UID:" + escapeICSText(fmt.Sprintf("%s-%d@%s", run.Job, run.Start.Unix(), host)))
+		write("DTSTAMP:" + now.UTC().Format(icsTimeFormat))
+		write("DTSTART:" + run.Start.UTC().Format(icsTimeFormat))
+		write("DTEND:" + run.Start.Add(run.Duration).UTC().Format(icsTimeFormat))
+		write("SUMMARY:" + escapeICSText(run.Job))
+		write("END:VEVENT")
+	}
+	write("END:VCALENDAR")
+	return b.Bytes()
+}
+
+// handlePeriodicCalendar serves the upcoming runs of the periodics matching
+// the `jobs` query parameter, a comma-separated list of globs, as an ICS
+// calendar covering the number of days of the `days` query parameter, e.g.:
+// - /feeds/periodics.ics?jobs=ci-kubernetes-*&days=14
+func handlePeriodicCalendar(cfg config.Getter, ja *jobs.JobAgent, log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		days := defaultCalendarDays
+		if param := r.URL.Query().Get("days"); param != "" {
+			var err error
+			if days, err = strconv.Atoi(param); err != nil || days <= 0 || days > maxCalendarDays {
+				http.Error(w, fmt.Sprintf("invalid days %q, must be between 1 and %d", param, maxCalendarDays), http.StatusBadRequest)
+				return
+			}
+		}
+		now := time.Now()
+		runs := upcomingRuns(scopedPeriodics(r.Context(), cfg().AllPeriodics()), scopedProwJobs(r, ja), r.URL.Query().Get("jobs"), now, now.AddDate(0, 0, days))
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		if _, err := w.Write(renderCalendar(runs, r.Host, now)); err != nil {
+			log.WithError(err).Debug("Error writing periodic calendar.")
+		}
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+var feedNow = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func periodicRun(name, job string, state prowapi.ProwJobState, start time.Time, duration time.Duration) prowapi.ProwJob {
+	pj := prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       prowapi.ProwJobSpec{Job: job, Type: prowapi.PeriodicJob},
+		Status: prowapi.ProwJobStatus{
+			State:     state,
+			StartTime: metav1.NewTime(start),
+			URL:       "https://prow.example.com/view/" + name,
+		},
+	}
+	if duration > 0 {
+		completed := metav1.NewTime(start.Add(duration))
+		pj.Status.CompletionTime = &completed
+	}
+	return pj
+}
+
+func TestRecentFailures(t *testing.T) {
+	presubmit := periodicRun("presubmit", "pull-unit", prowapi.FailureState, feedNow.Add(-time.Hour), time.Minute)
+	presubmit.Spec.Type = prowapi.PresubmitJob
+	pjs := []prowapi.ProwJob{
+		periodicRun("old", "ci-e2e", prowapi.FailureState, feedNow.Add(-3*time.Hour), time.Minute),
+		periodicRun("passed", "ci-e2e", prowapi.SuccessState, feedNow.Add(-2*time.Hour), time.Minute),
+		periodicRun("errored", "ci-build", prowapi.ErrorState, feedNow.Add(-time.Hour), time.Minute),
+		periodicRun("running", "ci-e2e", prowapi.PendingState, feedNow, 0),
+		periodicRun("other", "ci-other", prowapi.FailureState, feedNow.Add(-time.Hour), time.Minute),
+		presubmit,
+	}
+
+	var names []string
+	for _, pj := range recentFailures(pjs, "ci-e2e,ci-build") {
+		names = append(names, pj.Name)
+	}
+	if diff := cmp.Diff([]string{"errored", "old"}, names); diff != "" {
+		t.Errorf("failures differ from expected: %s", diff)
+	}
+}
+
+func TestRenderFailureFeed(t *testing.T) {
+	failures := []prowapi.ProwJob{periodicRun("failed", "ci-e2e", prowapi.FailureState, feedNow.Add(-time.Hour), time.Minute)}
+	failures[0].Status.Description = "Job failed."
+
+	b, err := renderFailureFeed(failures, atomFeedFormat, "https://prow.example.com/feeds/failures.atom", feedNow)
+	if err != nil {
+		t.Fatalf("failed to render atom feed: %v", err)
+	}
+	var atom atomFeed
+	if err := xml.Unmarshal(b, &atom); err != nil {
+		t.Fatalf("failed to unmarshal atom feed: %v", err)
+	}
+	expectedEntries := []atomEntry{{
+		Title:   "ci-e2e failure",
+		ID:      "urn:prowjob:failed",
+		Updated: "2021-06-01T11:01:00Z",
+		Link:    atomLink{Href: "https://prow.example.com/view/failed"},
+		Summary: "Job failed.",
+	}}
+	if diff := cmp.Diff(expectedEntries, atom.Entries); diff != "" {
+		t.Errorf("atom entries differ from expected: %s", diff)
+	}
+
+	b, err = renderFailureFeed(failures, rssFeedFormat, "https://prow.example.com/feeds/failures.rss", feedNow)
+	if err != nil {
+		t.Fatalf("failed to render rss feed: %v", err)
+	}
+	var rss rssFeed
+	if err := xml.Unmarshal(b, &rss); err != nil {
+		t.Fatalf("failed to unmarshal rss feed: %v", err)
+	}
+	expectedItems := []rssItem{{
+		Title:       "ci-e2e failure",
+		Link:        "https://prow.example.com/view/failed",
+		Description: "Job failed.",
+		GUID:        rssGUID{Value: "urn:prowjob:failed"},
+		PubDate:     "Tue, 01 Jun 2021 11:01:00 +0000",
+	}}
+	if diff := cmp.Diff(expectedItems, rss.Channel.Items); diff != "" {
+		t.Errorf("rss items differ from expected: %s", diff)
+	}
+
+	if _, err := renderFailureFeed(failures, "json", "", feedNow); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestUpcomingRuns(t *testing.T) {
+	hourly := config.Periodic{JobBase: config.JobBase{Name: "ci-hourly"}, Cron: "0 * * * *"}
+	interval := config.Periodic{JobBase: config.JobBase{Name: "ci-interval"}, Interval: "2h"}
+	interval.SetInterval(2 * time.Hour)
+	overdue := config.Periodic{JobBase: config.JobBase{Name: "ci-overdue"}, Interval: "1h"}
+	overdue.SetInterval(time.Hour)
+	other := config.Periodic{JobBase: config.JobBase{Name: "other"}, Cron: "0 * * * *"}
+	pjs := []prowapi.ProwJob{
+		periodicRun("a", "ci-interval", prowapi.SuccessState, feedNow.Add(-time.Hour), 10*time.Minute),
+		periodicRun("b", "ci-interval", prowapi.SuccessState, feedNow.Add(-3*time.Hour), 20*time.Minute),
+		periodicRun("c", "ci-overdue", prowapi.FailureState, feedNow.Add(-2*time.Hour), time.Minute),
+	}
+
+	runs := upcomingRuns([]config.Periodic{hourly, interval, overdue, other}, pjs, "ci-*", feedNow, feedNow.Add(150*time.Minute))
+	expected := []upcomingRun{
+		{Job: "ci-overdue", Start: feedNow, Duration: time.Minute},
+		{Job: "ci-hourly", Start: feedNow.Add(time.Hour), Duration: defaultRunDuration},
+		{Job: "ci-interval", Start: feedNow.Add(time.Hour), Duration: 10 * time.Minute},
+		{Job: "ci-overdue", Start: feedNow.Add(time.Hour), Duration: time.Minute},
+		{Job: "ci-hourly", Start: feedNow.Add(2 * time.Hour), Duration: defaultRunDuration},
+		{Job: "ci-overdue", Start: feedNow.Add(2 * time.Hour), Duration: time.Minute},
+	}
+	if diff := cmp.Diff(expected, runs); diff != "" {
+		t.Errorf("runs differ from expected: %s", diff)
+	}
+}
+
+func TestRenderCalendar(t *testing.T) {
+	runs := []upcomingRun{{Job: "ci-e2e,gce", Start: feedNow, Duration: time.Hour}}
+	expected := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Prow//Deck//EN",
+		"X-WR-CALNAME:Prow periodics",
+		"BEGIN:VEVENT",
+		`UID:ci-e2e\,gce-1622548800@prow.example.com`,
+		"DTSTAMP:20210601T120000Z",
+		"DTSTART:20210601T120000Z",
+		"DTEND:20210601T130000Z",
+		`SUMMARY:ci-e2e\,gce`,
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	if diff := cmp.Diff(expected, string(renderCalendar(runs, "prow.example.com", feedNow))); diff != "" {
+		t.Errorf("calendar differs from expected: %s", diff)
+	}
+}
+
+func TestFoldICSLine(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("a", 67) + strings.Repeat("é", 5)
+	folded := foldICSLine(line)
+	lines := strings.Split(folded, "\r\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", folded)
+	}
+	for _, l := range lines {
+		if len(l) > 75 {
+			t.Errorf("line %q is longer than 75 octets", l)
+		}
+	}
+	if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != line {
+		t.Errorf("expected the folded line to unfold to %q, got %q", line, unfolded)
+	}
+}
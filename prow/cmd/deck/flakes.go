@@ -0,0 +1,176 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/deck/jobs"
+)
+
+const defaultFlakeWindow = 24 * time.Hour
+
+// jobFlakiness are the results of a job in a repo. A revision of the repo,
+// i.e. a base commit and PR heads, is flaky when the job both passed and
+// failed on it.
+type jobFlakiness struct {
+	Job  string
+	Type prowapi.ProwJobType
+	Repo string `json:",omitempty"`
+
+	Runs      int
+	Passes    int
+	Failures  int
+	Revisions int
+	Flakes    int
+
+	PassRate  float64
+	FlakeRate float64
+}
+
+type flakeReport struct {
+	Window string
+	Jobs   []jobFlakiness
+}
+
+type flakeKey struct {
+	job      string
+	jobType  prowapi.ProwJobType
+	repo     string
+	revision string
+}
+
+// revisionKey identifies the code a job ran on.
+func revisionKey(refs *prowapi.Refs) string {
+	parts := []string{refs.BaseSHA}
+	for _, pull := range refs.Pulls {
+		parts = append(parts, fmt.Sprintf("%d:%s", pull.Number, pull.SHA))
+	}
+	return strings.Join(parts, ",")
+}
+
+// computeFlakiness computes the flakiness of the jobs from the runs that
+// completed after since. Only successes and failures count, e.g. aborted runs
+// are ignored. The jobs are sorted by descending flake rate.
+func computeFlakiness(pjs []prowapi.ProwJob, since time.Time) []jobFlakiness {
+	type revisionResults struct{ passed, failed bool }
+	results := map[flakeKey]*revisionResults{}
+	stats := map[flakeKey]*jobFlakiness{}
+	for _, pj := range pjs {
+		if pj.Status.CompletionTime == nil || pj.Status.CompletionTime.Time.Before(since) {
+			continue
+		}
+		state := pj.Status.State
+		if state != prowapi.SuccessState && state != prowapi.FailureState {
+			continue
+		}
+
+		key := flakeKey{job: pj.Spec.Job, jobType: pj.Spec.Type}
+		if refs := pj.Spec.Refs; refs != nil {
+			key.repo = refs.Org + "/" + refs.Repo
+			key.revision = revisionKey(refs)
+		}
+		jobKey := key
+		jobKey.revision = ""
+		s, ok := stats[jobKey]
+		if !ok {
+			s = &jobFlakiness{Job: key.job, Type: key.jobType, Repo: key.repo}
+			stats[jobKey] = s
+		}
+		r, ok := results[key]
+		if !ok {
+			r = &revisionResults{}
+			results[key] = r
+			s.Revisions++
+		}
+
+		s.Runs++
+		wasFlaky := r.passed && r.failed
+		if state == prowapi.SuccessState {
+			s.Passes++
+			r.passed = true
+		} else {
+			s.Failures++
+			r.failed = true
+		}
+		// Jobs without refs, e.g. most periodics, run on no known revision.
+		if key.revision != "" && !wasFlaky && r.passed && r.failed {
+			s.Flakes++
+		}
+	}
+
+	flakiness := make([]jobFlakiness, 0, len(stats))
+	for _, s := range stats {
+		s.PassRate = float64(s.Passes) / float64(s.Runs)
+		s.FlakeRate = float64(s.Flakes) / float64(s.Revisions)
+		flakiness = append(flakiness, *s)
+	}
+	sort.Slice(flakiness, func(i, j int) bool {
+		a, b := flakiness[i], flakiness[j]
+		if a.FlakeRate != b.FlakeRate {
+			return a.FlakeRate > b.FlakeRate
+		}
+		if a.Flakes != b.Flakes {
+			return a.Flakes > b.Flakes
+		}
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		return a.Job < b.Job
+	})
+	return flakiness
+}
+
+// handleFlakes serves the flakiness of the jobs over the window parameter,
+// optionally only of the jobs of the repo and type parameters. The window is
+// bounded by how long ProwJobs are kept.
+func handleFlakes(ja *jobs.JobAgent, log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		window := defaultFlakeWindow
+		if param := r.URL.Query().Get("window"); param != "" {
+			var err error
+			if window, err = time.ParseDuration(param); err != nil || window <= 0 {
+				http.Error(w, fmt.Sprintf("invalid window %q, must be a positive duration", param), http.StatusBadRequest)
+				return
+			}
+		}
+		repo := r.URL.Query().Get("repo")
+		jobType := prowapi.ProwJobType(r.URL.Query().Get("type"))
+
+		report := flakeReport{Window: window.String(), Jobs: []jobFlakiness{}}
+		for _, f := range computeFlakiness(scopedProwJobs(r, ja), time.Now().Add(-window)) {
+			if (repo == "" || f.Repo == repo) && (jobType == "" || f.Type == jobType) {
+				report.Jobs = append(report.Jobs, f)
+			}
+		}
+		b, err := json.Marshal(report)
+		if err != nil {
+			log.WithError(err).Error("Error marshaling flake report.")
+			b = []byte("{}")
+		}
+		writeJSONResponse(w, r, b)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+func TestComputeFlakiness(t *testing.T) {
+	now := time.Now()
+	run := func(job string, jobType prowapi.ProwJobType, pr int, sha string, state prowapi.ProwJobState, age time.Duration) prowapi.ProwJob {
+		completed := metav1.NewTime(now.Add(-age))
+		pj := prowapi.ProwJob{
+			Spec:   prowapi.ProwJobSpec{Job: job, Type: jobType},
+			Status: prowapi.ProwJobStatus{State: state, CompletionTime: &completed},
+		}
+		if pr != 0 {
+			pj.Spec.Refs = &prowapi.Refs{Org: "org", Repo: "repo", BaseSHA: "base", Pulls: []prowapi.Pull{{Number: pr, SHA: sha}}}
+		}
+		return pj
+	}
+	pending := run("unit", prowapi.PresubmitJob, 3, "c", prowapi.PendingState, 0)
+	pending.Status.CompletionTime = nil
+
+	pjs := []prowapi.ProwJob{
+		// PR 1 flaked on its first head and passed on its second.
+		run("unit", prowapi.PresubmitJob, 1, "a", prowapi.FailureState, time.Hour),
+		run("unit", prowapi.PresubmitJob, 1, "a", prowapi.FailureState, time.Hour),
+		run("unit", prowapi.PresubmitJob, 1, "a", prowapi.SuccessState, time.Hour),
+		run("unit", prowapi.PresubmitJob, 1, "b", prowapi.SuccessState, time.Hour),
+		// PR 2 consistently fails.
+		run("unit", prowapi.PresubmitJob, 2, "c", prowapi.FailureState, time.Hour),
+		run("unit", prowapi.PresubmitJob, 2, "c", prowapi.FailureState, time.Hour),
+		// Runs that do not count.
+		run("unit", prowapi.PresubmitJob, 2, "c", prowapi.SuccessState, 48*time.Hour),
+		run("unit", prowapi.PresubmitJob, 2, "c", prowapi.AbortedState, time.Hour),
+		pending,
+		// Periodics run on no known revision, so they never flake.
+		run("nightly", prowapi.PeriodicJob, 0, "", prowapi.FailureState, time.Hour),
+		run("nightly", prowapi.PeriodicJob, 0, "", prowapi.SuccessState, time.Hour),
+	}
+
+	expected := []jobFlakiness{
+		{Job: "unit", Type: prowapi.PresubmitJob, Repo: "org/repo", Runs: 6, Passes: 2, Failures: 4, Revisions: 3, Flakes: 1, PassRate: 2.0 / 6, FlakeRate: 1.0 / 3},
+		{Job: "nightly", Type: prowapi.PeriodicJob, Runs: 2, Passes: 1, Failures: 1, Revisions: 1, PassRate: 0.5},
+	}
+	if diff := cmp.Diff(expected, computeFlakiness(pjs, now.Add(-24*time.Hour))); diff != "" {
+		t.Errorf("flakiness differs from expected: %s", diff)
+	}
+}
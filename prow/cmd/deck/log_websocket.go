@@ -0,0 +1,196 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	stdio "io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/NYTimes/gziphandler"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// logWriteTimeout is how long writing a message of the log may take
+	// before the client is considered gone.
+	logWriteTimeout = 10 * time.Second
+	// logPingInterval is how often idle log websockets are pinged, so that
+	// proxies don't close them while the job doesn't log anything.
+	logPingInterval = 30 * time.Second
+	// maxLogReconnects is how often in a row Deck reconnects to the pod of a
+	// running job without receiving any of its log before it gives up and
+	// lets the client reconnect later.
+	maxLogReconnects = 5
+	// maxCloseReasonLength is the maximum length of the reason of a close
+	// message, which has to fit into a control frame.
+	maxCloseReasonLength = 123
+)
+
+// logReconnectBackoff is how long Deck waits before it reconnects to the pod
+// of a running job the first time, it doubles with every reconnect.
+var logReconnectBackoff = time.Second
+
+var logUpgrader = websocket.Upgrader{
+	// The log is served to any origin, like the responses of /log.
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// gzipUnlessWebsocket compresses the responses of the handler, except for the
+// websocket upgrades, whose connections are hijacked.
+func gzipUnlessWebsocket(handler http.Handler) http.Handler {
+	gzipped := gziphandler.GzipHandler(handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		gzipped.ServeHTTP(w, r)
+	})
+}
+
+// streamLogOverWebsocket sends the log of the job from the offset on as binary
+// messages over a websocket. While the job is running, Deck reconnects to its
+// pod whenever the stream of the log breaks, resuming at the offset it has
+// sent. Once the whole log was sent, the websocket is closed normally. Any other
+// close tells the client to reconnect at the offset it has received.
+func streamLogOverWebsocket(w http.ResponseWriter, r *http.Request, lc logClient, job, id, container string, offset int64, logger *logrus.Entry) {
+	// The log is streamed until the client is gone, which is noticed by
+	// reading the connection as the server doesn't after it was hijacked.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	// Open the log before upgrading, so that missing logs are reported with
+	// an HTTP status.
+	jobLog, following, err := lc.StreamJobLog(ctx, job, id, container)
+	if err != nil {
+		logNotFound(w, err, logger)
+		return
+	}
+	conn, err := logUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an error.
+		jobLog.Close()
+		logger.WithError(err).Debug("Failed to upgrade to a websocket.")
+		return
+	}
+	defer conn.Close()
+
+	// The client doesn't send anything, but the connection has to be read
+	// to process its control messages and to notice that it is gone.
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		ticker := time.NewTicker(logPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(logWriteTimeout)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	backoff := logReconnectBackoff
+	for reconnects := 0; ; {
+		sent, err := sendLog(conn, jobLog, offset)
+		jobLog.Close()
+		offset += sent
+		if err != nil && ctx.Err() == nil {
+			logger.WithError(err).Info("Error streaming log.")
+		}
+		if !following {
+			if err == nil {
+				closeLogWebsocket(conn, websocket.CloseNormalClosure, "")
+			}
+			return
+		}
+
+		if sent > 0 {
+			reconnects, backoff = 0, logReconnectBackoff
+		}
+		if reconnects++; reconnects > maxLogReconnects {
+			closeLogWebsocket(conn, websocket.CloseTryAgainLater, "The log of the pod can't be streamed, try again later.")
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		if jobLog, following, err = lc.StreamJobLog(ctx, job, id, container); err != nil {
+			if ctx.Err() == nil {
+				logger.WithError(err).Info("Error reconnecting to the log.")
+				closeLogWebsocket(conn, websocket.CloseInternalServerErr, fmt.Sprintf("Log not found: %v", err))
+			}
+			return
+		}
+	}
+}
+
+// sendLog sends the log from the offset on, returning how much of it it sent.
+func sendLog(conn *websocket.Conn, jobLog stdio.Reader, offset int64) (int64, error) {
+	if _, err := stdio.CopyN(ioutil.Discard, jobLog, offset); err != nil {
+		if err == stdio.EOF {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("skip to the offset of the log: %w", err)
+	}
+	var sent int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := jobLog.Read(buf)
+		if n > 0 {
+			if err := conn.SetWriteDeadline(time.Now().Add(logWriteTimeout)); err != nil {
+				return sent, err
+			}
+			if err := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
+				return sent, fmt.Errorf("write log: %w", err)
+			}
+			sent += int64(n)
+		}
+		if err == stdio.EOF {
+			return sent, nil
+		}
+		if err != nil {
+			return sent, fmt.Errorf("read log: %w", err)
+		}
+	}
+}
+
+func closeLogWebsocket(conn *websocket.Conn, code int, reason string) {
+	if len(reason) > maxCloseReasonLength {
+		reason = reason[:maxCloseReasonLength]
+	}
+	// The client may be gone already, so errors are ignored.
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(logWriteTimeout))
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+// fakeLogStream is the log of a pod as it is streamed once.
+type fakeLogStream struct {
+	log       string
+	following bool
+}
+
+// streamingLogClient returns its streams one after the other, and the last
+// one from then on.
+type streamingLogClient struct {
+	lock    sync.Mutex
+	streams []fakeLogStream
+}
+
+func (c *streamingLogClient) GetProwJob(job, id string) (prowapi.ProwJob, error) {
+	return prowapi.ProwJob{Spec: prowapi.ProwJobSpec{Job: job}}, nil
+}
+
+func (c *streamingLogClient) GetJobLog(job, id, container string) ([]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *streamingLogClient) StreamJobLog(ctx context.Context, job, id, container string) (io.ReadCloser, bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.streams) == 0 {
+		return nil, false, errors.New("pod not found")
+	}
+	stream := c.streams[0]
+	if len(c.streams) > 1 {
+		c.streams = c.streams[1:]
+	}
+	return ioutil.NopCloser(strings.NewReader(stream.log)), stream.following, nil
+}
+
+func TestStreamLogOverWebsocket(t *testing.T) {
+	logReconnectBackoff = time.Millisecond
+	testCases := []struct {
+		name          string
+		streams       []fakeLogStream
+		offset        string
+		expectedLog   string
+		expectedClose int
+		expectedCode  int
+	}{
+		{
+			name:          "log of a finished job is sent and closed normally",
+			streams:       []fakeLogStream{{log: "hello\nworld\n"}},
+			expectedLog:   "hello\nworld\n",
+			expectedClose: websocket.CloseNormalClosure,
+		},
+		{
+			name:          "log is sent from the offset",
+			streams:       []fakeLogStream{{log: "hello\nworld\n"}},
+			offset:        "6",
+			expectedLog:   "world\n",
+			expectedClose: websocket.CloseNormalClosure,
+		},
+		{
+			name: "stream of a running job is resumed when it breaks",
+			streams: []fakeLogStream{
+				{log: "hello\n", following: true},
+				{log: "hello\nwor", following: true},
+				{log: "hello\nworld\n"},
+			},
+			expectedLog:   "hello\nworld\n",
+			expectedClose: websocket.CloseNormalClosure,
+		},
+		{
+			name:          "client is told to try again later when the log of a running job can't be streamed",
+			streams:       []fakeLogStream{{log: "hello\n", following: true}},
+			expectedLog:   "hello\n",
+			expectedClose: websocket.CloseTryAgainLater,
+		},
+		{
+			name:         "missing log is reported before upgrading",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lc := &streamingLogClient{streams: tc.streams}
+			server := httptest.NewServer(gzipUnlessWebsocket(handleLog(lc, logrus.WithField("handler", "/log"))))
+			defer server.Close()
+
+			url := "ws" + strings.TrimPrefix(server.URL, "http") + "/log?job=job&id=123&follow=true"
+			if tc.offset != "" {
+				url += "&offset=" + tc.offset
+			}
+			conn, response, err := websocket.DefaultDialer.Dial(url, nil)
+			if tc.expectedCode != 0 {
+				if err == nil {
+					conn.Close()
+					t.Fatalf("expected the upgrade to fail with %d", tc.expectedCode)
+				}
+				if response == nil || response.StatusCode != tc.expectedCode {
+					t.Fatalf("expected the upgrade to fail with %d, got %v (error: %v)", tc.expectedCode, response, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to dial: %v", err)
+			}
+			defer conn.Close()
+
+			var log bytes.Buffer
+			for {
+				messageType, message, err := conn.ReadMessage()
+				if err != nil {
+					if !websocket.IsCloseError(err, tc.expectedClose) {
+						t.Errorf("expected close %d, got %v", tc.expectedClose, err)
+					}
+					break
+				}
+				if messageType != websocket.BinaryMessage {
+					t.Errorf("expected binary messages, got type %d", messageType)
+				}
+				log.Write(message)
+			}
+			if log.String() != tc.expectedLog {
+				t.Errorf("expected log %q, got %q", tc.expectedLog, log.String())
+			}
+		})
+	}
+}
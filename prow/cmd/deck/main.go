@@ -26,18 +26,21 @@ import (
 	"flag"
 	"fmt"
 	"html/template"
+	stdio "io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/NYTimes/gziphandler"
 	"github.com/gorilla/csrf"
 	"github.com/gorilla/sessions"
+	"github.com/gorilla/websocket"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
@@ -81,13 +84,17 @@ import (
 
 	"k8s.io/test-infra/prow/spyglass/lenses"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/buildlog"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/clusterdump"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/coverage"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/html"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/junit"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/junitdiff"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/links"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/metadata"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/podinfo"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/restcoverage"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/search"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/steps"
 )
 
 // Omittable ProwJob fields.
@@ -134,6 +141,13 @@ type options struct {
 	timeoutListingProwJobs int
 	dryRun                 bool
 	tenantIDs              flagutil.Strings
+	savedViewsPath         string
+	rerunTokensPath        string
+	rerunProxyUserHeader   string
+	rerunProxyGroupsHeader string
+	enableTenantScoping    bool
+	tenantHostHeader       string
+	serveOwners            bool
 }
 
 func (o *options) Validate() error {
@@ -164,6 +178,12 @@ func (o *options) Validate() error {
 	if (o.hiddenOnly && o.showHidden) || (o.tenantIDs.Strings() != nil && (o.hiddenOnly || o.showHidden)) {
 		return errors.New("'--hidden-only', '--tenant-id', and '--show-hidden' are mutually exclusive, 'hidden-only' shows only hidden job, '--tenant-id' shows all jobs with matching ID and 'show-hidden' shows both hidden and non-hidden jobs")
 	}
+	if o.enableTenantScoping && (o.tenantIDs.Strings() != nil || o.hiddenOnly || o.showHidden) {
+		return errors.New("'--enable-tenant-scoping' scopes the jobs by the tenant of each request and can't be used with '--hidden-only', '--tenant-id' or '--show-hidden'")
+	}
+	if o.serveOwners && o.pluginsConfig.PluginConfigPath == "" {
+		return errors.New("'--serve-owners' requires '--plugin-config' to load OWNERS like hook does")
+	}
 	return nil
 }
 
@@ -190,6 +210,13 @@ func gatherOptions(fs *flag.FlagSet, args ...string) options {
 	fs.IntVar(&o.timeoutListingProwJobs, "timeout-listing-prowjobs", 30, "Timeout for listing prowjobs in seconds.")
 	fs.BoolVar(&o.dryRun, "dry-run", false, "Whether or not to make mutating API calls to GitHub.")
 	fs.Var(&o.tenantIDs, "tenant-id", "The tenantID(s) used by the ProwJobs that should be displayed by this instance of Deck. This flag can be repeated.")
+	fs.StringVar(&o.savedViewsPath, "saved-views-path", "", "Path to the GCS, S3 or local file persisting the saved views of the job list, e.g. gs://bucket/deck/saved-views.json. If empty, views can not be saved. Saving views requires GitHub OAuth.")
+	fs.StringVar(&o.rerunTokensPath, "rerun-tokens-path", "", "Path to the file containing the API tokens for programmatic reruns, a list of name, token and groups. If empty, reruns can not be requested with API tokens.")
+	fs.StringVar(&o.rerunProxyUserHeader, "rerun-proxy-user-header", "", "Header an authenticating proxy in front of Deck sets to the user, e.g. X-Forwarded-User. Reruns of users with the header are authorized by their groups. Only set it if all requests go through the proxy.")
+	fs.StringVar(&o.rerunProxyGroupsHeader, "rerun-proxy-groups-header", "", "Header an authenticating proxy in front of Deck sets to the comma-separated groups of the user, e.g. X-Forwarded-Groups.")
+	fs.BoolVar(&o.enableTenantScoping, "enable-tenant-scoping", false, "Scope the jobs, Tide pools and plugin help to the tenant of each request, identified by the hosts and GitHub orgs of deck.tenants. Experimental.")
+	fs.StringVar(&o.tenantHostHeader, "tenant-host-header", "", "Header an authenticating proxy in front of Deck sets to the host of the request, e.g. X-Forwarded-Host. The hosts of deck.tenants are only matched against it, as clients choose the Host header. Only set it if all requests go through the proxy and the proxy overwrites the header.")
+	fs.BoolVar(&o.serveOwners, "serve-owners", false, "Serve /api/owners, which explains the effective approvers and reviewers of a file. Requires GitHub credentials. Only repos with jobs or Tide queries in the Prow config are served, scoped to the tenant of the request with --enable-tenant-scoping.")
 	o.config.AddFlags(fs)
 	o.instrumentation.AddFlags(fs)
 	o.kubernetes.AddFlags(fs)
@@ -213,7 +240,7 @@ var (
 	traceHandler        = metrics.TraceHandler(simplifier, httpRequestDuration, httpResponseSize)
 )
 
-type authCfgGetter func(*prowapi.Refs) *prowapi.RerunAuthConfig
+type authCfgGetter func(prowapi.ProwJobType, *prowapi.Refs) *prowapi.RerunAuthConfig
 
 func init() {
 	prometheus.MustRegister(httpRequestDuration)
@@ -222,11 +249,19 @@ func init() {
 
 var simplifier = simplifypath.NewSimplifier(l("", // shadow element mimicing the root
 	l(""),
+	l("api",
+		l("flakes"),
+		l("owners")),
 	l("badge.svg"),
 	l("command-help"),
 	l("config"),
 	l("data.js"),
 	l("favicon.ico"),
+	l("feeds",
+		l("failures.atom"),
+		l("failures.rss"),
+		l("periodics.ics")),
+	l("flakes"),
 	l("github-login",
 		l("redirect")),
 	l("github-link"),
@@ -239,9 +274,12 @@ var simplifier = simplifypath.NewSimplifier(l("", // shadow element mimicing the
 	l("pr"),
 	l("pr-data.js"),
 	l("pr-history"),
+	l("pr-timeline"),
+	l("pr-timeline.js"),
 	l("prowjob"),
 	l("prowjobs.js"),
 	l("rerun"),
+	l("saved-views"),
 	l("spyglass",
 		l("static",
 			simplifypath.VGreedy("path")),
@@ -316,7 +354,20 @@ func main() {
 	mux.Handle("/plugin-help", http.RedirectHandler("/command-help", http.StatusMovedPermanently))
 	mux.Handle("/tide", gziphandler.GzipHandler(handleSimpleTemplate(o, cfg, "tide.html", nil)))
 	mux.Handle("/tide-history", gziphandler.GzipHandler(handleSimpleTemplate(o, cfg, "tide-history.html", nil)))
+	mux.Handle("/pr-timeline", gziphandler.GzipHandler(handlePRTimelinePage(o, cfg)))
 	mux.Handle("/plugins", gziphandler.GzipHandler(handleSimpleTemplate(o, cfg, "plugins.html", nil)))
+	mux.Handle("/flakes", gziphandler.GzipHandler(handleSimpleTemplate(o, cfg, "flakes.html", nil)))
+
+	var savedViews *savedViewStore
+	if o.savedViewsPath != "" {
+		opener, err := io.NewOpener(context.Background(), o.storage.GCSCredentialsFile, o.storage.S3CredentialsFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Error creating opener for saved views.")
+		}
+		if savedViews, err = newSavedViewStore(opener, o.savedViewsPath); err != nil {
+			logrus.WithError(err).Fatal("Error loading saved views.")
+		}
+	}
 
 	runLocal := o.pregeneratedData != ""
 
@@ -324,6 +375,7 @@ func main() {
 	var pjListingClient jobs.PJListingClient
 	var githubClient deckGitHubClient
 	var gitClient git.ClientFactory
+	var ownersClient ownersLoader
 	var podLogClients map[string]jobs.PodLogClient
 	if runLocal {
 		localDataHandler := staticHandlerFromDir(o.pregeneratedData)
@@ -388,15 +440,19 @@ func main() {
 		// When inrepoconfig is enabled, both the GitHubClient and the gitClient are used to resolve
 		// presubmits dynamically which we need for the PR history page.
 		if o.github.TokenPath != "" || o.github.AppID != "" {
-			githubClient, err = o.github.GitHubClient(o.dryRun)
+			ghc, err := o.github.GitHubClient(o.dryRun)
 			if err != nil {
 				logrus.WithError(err).Fatal("Error getting GitHub client.")
 			}
+			githubClient = ghc
 			g, err := o.github.GitClient(o.dryRun)
 			if err != nil {
 				logrus.WithError(err).Fatal("Error getting Git client.")
 			}
 			gitClient = git.ClientFactoryFrom(g)
+			if o.serveOwners {
+				ownersClient = newOwnersClient(cfg, pluginAgent, gitClient, ghc)
+			}
 		} else {
 			if len(cfg().InRepoConfig.Enabled) > 0 {
 				logrus.Info(" --github-token-path not configured. InRepoConfigEnabled, but current configuration won't display full PR history")
@@ -414,8 +470,8 @@ func main() {
 		}
 	}
 
-	authCfgGetter := func(refs *prowapi.Refs) *prowapi.RerunAuthConfig {
-		rac := cfg().Deck.RerunAuthConfigs.GetRerunAuthConfig(refs)
+	authCfgGetter := func(jobType prowapi.ProwJobType, refs *prowapi.Refs) *prowapi.RerunAuthConfig {
+		rac := cfg().Deck.GetRerunAuthConfig(jobType, refs)
 		return &rac
 	}
 
@@ -424,16 +480,29 @@ func main() {
 			fallbackHandler(w, r)
 			return
 		}
+		if id := r.URL.Query().Get("saved-view"); id != "" && savedViews != nil {
+			handleSavedViewRedirect(savedViews, w, r, id)
+			return
+		}
 		indexHandler := handleSimpleTemplate(o, cfg, "index.html", struct {
-			SpyglassEnabled bool
-			ReRunCreatesJob bool
+			SpyglassEnabled   bool
+			ReRunCreatesJob   bool
+			SavedViewsEnabled bool
 		}{
-			SpyglassEnabled: o.spyglass,
-			ReRunCreatesJob: o.rerunCreatesJob})
+			SpyglassEnabled:   o.spyglass,
+			ReRunCreatesJob:   o.rerunCreatesJob,
+			SavedViewsEnabled: savedViews != nil})
 		indexHandler(w, r)
 	})
 
-	ja := jobs.NewJobAgent(context.Background(), pjListingClient, o.hiddenOnly, o.showHidden, o.tenantIDs.Strings(), podLogClients, cfg)
+	var ja *jobs.JobAgent
+	var tenants *tenantResolver
+	if o.enableTenantScoping {
+		ja = jobs.NewTenantScopedJobAgent(context.Background(), pjListingClient, podLogClients, cfg)
+		tenants = newTenantResolver(cfg, o.tenantHostHeader)
+	} else {
+		ja = jobs.NewJobAgent(context.Background(), pjListingClient, o.hiddenOnly, o.showHidden, o.tenantIDs.Strings(), podLogClients, cfg)
+	}
 	ja.Start()
 
 	// setup prod only handlers. These handlers can work with runlocal as long
@@ -441,7 +510,14 @@ func main() {
 	mux.Handle("/data.js", gziphandler.GzipHandler(handleData(ja, logrus.WithField("handler", "/data.js"))))
 	mux.Handle("/prowjobs.js", gziphandler.GzipHandler(handleProwJobs(ja, logrus.WithField("handler", "/prowjobs.js"))))
 	mux.Handle("/badge.svg", gziphandler.GzipHandler(handleBadge(ja)))
-	mux.Handle("/log", gziphandler.GzipHandler(handleLog(ja, logrus.WithField("handler", "/log"))))
+	mux.Handle("/log", gzipUnlessWebsocket(handleLog(ja, logrus.WithField("handler", "/log"))))
+	mux.Handle("/api/flakes", gziphandler.GzipHandler(handleFlakes(ja, logrus.WithField("handler", "/api/flakes"))))
+	mux.Handle("/feeds/failures.atom", gziphandler.GzipHandler(handleFailureFeed(ja, atomFeedFormat, logrus.WithField("handler", "/feeds/failures.atom"))))
+	mux.Handle("/feeds/failures.rss", gziphandler.GzipHandler(handleFailureFeed(ja, rssFeedFormat, logrus.WithField("handler", "/feeds/failures.rss"))))
+	mux.Handle("/feeds/periodics.ics", gziphandler.GzipHandler(handlePeriodicCalendar(cfg, ja, logrus.WithField("handler", "/feeds/periodics.ics"))))
+	if ownersClient != nil {
+		mux.Handle("/api/owners", gziphandler.GzipHandler(handleOwners(cfg, ownersClient, logrus.WithField("handler", "/api/owners"))))
+	}
 
 	if o.spyglass {
 		initSpyglass(cfg, o, mux, ja, githubClient, gitClient)
@@ -450,7 +526,12 @@ func main() {
 	if runLocal {
 		mux = localOnlyMain(cfg, o, mux)
 	} else {
-		mux = prodOnlyMain(cfg, pluginAgent, authCfgGetter, githubClient, o, mux)
+		mux = prodOnlyMain(cfg, pluginAgent, authCfgGetter, githubClient, ja, savedViews, tenants, o, mux)
+	}
+
+	var handler http.Handler = mux
+	if tenants != nil {
+		handler = tenants.scope(mux)
 	}
 
 	// signal to the world that we're ready
@@ -488,18 +569,18 @@ func main() {
 	// if we allow direct reruns, we must protect against CSRF in all post requests using the cookie secret as a token
 	// for more information about CSRF, see https://github.com/kubernetes/test-infra/blob/master/prow/cmd/deck/csrf.md
 	empty := prowapi.Refs{}
-	if o.rerunCreatesJob && csrfToken == nil && !authCfgGetter(&empty).IsAllowAnyone() {
+	if o.rerunCreatesJob && csrfToken == nil && !authCfgGetter("", &empty).IsAllowAnyone() {
 		logrus.Fatal("Rerun creates job cannot be enabled without CSRF protection, which requires --cookie-secret to be exactly 32 bytes")
 		return
 	}
 
 	if csrfToken != nil {
 		CSRF := csrf.Protect(csrfToken, csrf.Path("/"), csrf.Secure(!o.allowInsecure))
-		logrus.WithError(http.ListenAndServe(":8080", CSRF(traceHandler(mux)))).Fatal("ListenAndServe returned.")
+		logrus.WithError(http.ListenAndServe(":8080", skipCSRFForBearerTokens(CSRF(traceHandler(handler))))).Fatal("ListenAndServe returned.")
 		return
 	}
 	// setup done, actually start the server
-	server := &http.Server{Addr: ":8080", Handler: traceHandler(mux)}
+	server := &http.Server{Addr: ":8080", Handler: traceHandler(handler)}
 	interrupts.ListenAndServe(server, 5*time.Second)
 }
 
@@ -524,6 +605,10 @@ func (c *podLogClient) GetLogs(name, container string) ([]byte, error) {
 	return ioutil.ReadAll(reader)
 }
 
+func (c *podLogClient) StreamLogs(ctx context.Context, name, container string) (stdio.ReadCloser, error) {
+	return c.client.GetLogs(name, &coreapi.PodLogOptions{Container: container, Follow: true}).Stream(ctx)
+}
+
 type pjListingClientWrapper struct {
 	reader ctrlruntimeclient.Reader
 }
@@ -546,7 +631,7 @@ func (fjc *fakePjListingClientWrapper) List(ctx context.Context, pjl *prowapi.Pr
 }
 
 // prodOnlyMain contains logic only used when running deployed, not locally
-func prodOnlyMain(cfg config.Getter, pluginAgent *plugins.ConfigAgent, authCfgGetter authCfgGetter, githubClient deckGitHubClient, o options, mux *http.ServeMux) *http.ServeMux {
+func prodOnlyMain(cfg config.Getter, pluginAgent *plugins.ConfigAgent, authCfgGetter authCfgGetter, githubClient deckGitHubClient, ja *jobs.JobAgent, savedViews *savedViewStore, tenants *tenantResolver, o options, mux *http.ServeMux) *http.ServeMux {
 	prowJobClient, err := o.kubernetes.ProwJobClient(cfg().ProwJobNamespace, false)
 	if err != nil {
 		logrus.WithError(err).Fatal("Error getting ProwJob client for infrastructure cluster.")
@@ -557,12 +642,13 @@ func prodOnlyMain(cfg config.Getter, pluginAgent *plugins.ConfigAgent, authCfgGe
 
 	if o.hookURL != "" {
 		mux.Handle("/plugin-help.js",
-			gziphandler.GzipHandler(handlePluginHelp(newHelpAgent(o.hookURL), logrus.WithField("handler", "/plugin-help.js"))))
+			gziphandler.GzipHandler(handlePluginHelp(newHelpAgent(o.hookURL), cfg, logrus.WithField("handler", "/plugin-help.js"))))
 	}
 
 	// tide could potentially be mocked by static data
+	var ta *tideAgent
 	if o.tideURL != "" {
-		ta := &tideAgent{
+		ta = &tideAgent{
 			log:  logrus.WithField("agent", "tide"),
 			path: o.tideURL,
 			updatePeriod: func() time.Duration {
@@ -574,6 +660,7 @@ func prodOnlyMain(cfg config.Getter, pluginAgent *plugins.ConfigAgent, authCfgGe
 			hiddenOnly: o.hiddenOnly,
 			showHidden: o.showHidden,
 			tenantIDs:  sets.NewString(o.tenantIDs.Strings()...),
+			allTenants: o.enableTenantScoping,
 			cfg:        cfg,
 		}
 		ta.start()
@@ -581,6 +668,10 @@ func prodOnlyMain(cfg config.Getter, pluginAgent *plugins.ConfigAgent, authCfgGe
 		mux.Handle("/tide-history.js", gziphandler.GzipHandler(handleTideHistory(ta, logrus.WithField("handler", "/tide-history.js"))))
 	}
 
+	// The timeline leaves out the plugin actions without a GitHub client and
+	// the Tide actions without Tide.
+	mux.Handle("/pr-timeline.js", gziphandler.GzipHandler(handlePRTimeline(o, cfg, ja, ta, githubClient, logrus.WithField("handler", "/pr-timeline.js"))))
+
 	secure := !o.allowInsecure
 
 	// Handles link to github
@@ -650,9 +741,32 @@ func prodOnlyMain(cfg config.Getter, pluginAgent *plugins.ConfigAgent, authCfgGe
 		mux.Handle("/github-login", goa.HandleLogin(oauthClient, secure))
 		// Handles redirect from GitHub OAuth server.
 		mux.Handle("/github-login/redirect", goa.HandleRedirect(oauthClient, githuboauth.NewAuthenticatedUserIdentifier(&o.github), secure))
+
+		if tenants != nil && githubClient != nil {
+			tenants.login = func(r *http.Request) (string, error) {
+				return goa.GetLogin(r, githuboauth.NewAuthenticatedUserIdentifier(&o.github))
+			}
+			tenants.members = githubClient
+		}
 	}
 
-	mux.Handle("/rerun", gziphandler.GzipHandler(handleRerun(prowJobClient, o.rerunCreatesJob, authCfgGetter, goa, githuboauth.NewAuthenticatedUserIdentifier(&o.github), githubClient, pluginAgent, logrus.WithField("handler", "/rerun"))))
+	if savedViews != nil {
+		var login func(*http.Request) (string, error)
+		if goa != nil {
+			login = func(r *http.Request) (string, error) {
+				return goa.GetLogin(r, githuboauth.NewAuthenticatedUserIdentifier(&o.github))
+			}
+		}
+		mux.Handle("/saved-views", gziphandler.GzipHandler(handleSavedViews(savedViews, login, logrus.WithField("handler", "/saved-views"))))
+	}
+
+	rerunAuth := &rerunAuthenticator{proxyUserHeader: o.rerunProxyUserHeader, proxyGroupsHeader: o.rerunProxyGroupsHeader}
+	if o.rerunTokensPath != "" {
+		if rerunAuth.tokens, err = loadRerunTokens(o.rerunTokensPath); err != nil {
+			logrus.WithError(err).Fatal("Error loading rerun tokens.")
+		}
+	}
+	mux.Handle("/rerun", gziphandler.GzipHandler(handleRerun(prowJobClient, o.rerunCreatesJob, authCfgGetter, rerunAuth, goa, githuboauth.NewAuthenticatedUserIdentifier(&o.github), githubClient, pluginAgent, logrus.WithField("handler", "/rerun"))))
 
 	// optionally inject http->https redirect handler when behind loadbalancer
 	if o.redirectHTTPTo != "" {
@@ -690,7 +804,7 @@ func initSpyglass(cfg config.Getter, o options, mux *http.ServeMux, ja *jobs.Job
 	sg.Start()
 
 	mux.Handle("/spyglass/static/", http.StripPrefix("/spyglass/static", staticHandlerFromDir(o.spyglassFilesLocation)))
-	mux.Handle("/spyglass/lens/", gziphandler.GzipHandler(http.StripPrefix("/spyglass/lens/", handleArtifactView(o, sg, cfg))))
+	mux.Handle("/spyglass/lens/", gziphandler.GzipHandler(http.StripPrefix("/spyglass/lens/", handleArtifactView(o, sg, cfg, common.NewGRPCLensClient()))))
 	mux.Handle("/view/", gziphandler.GzipHandler(handleRequestJobViews(sg, cfg, o, logrus.WithField("handler", "/view"))))
 	mux.Handle("/job-history/", gziphandler.GzipHandler(handleJobHistory(o, cfg, opener, logrus.WithField("handler", "/job-history"))))
 	mux.Handle("/pr-history/", gziphandler.GzipHandler(handlePRHistory(o, cfg, opener, gitHubClient, gitClient, logrus.WithField("handler", "/pr-history"))))
@@ -783,7 +897,7 @@ func handleNotCached(next http.Handler) http.HandlerFunc {
 func handleProwJobs(ja *jobs.JobAgent, log *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
-		jobs := ja.ProwJobs()
+		jobs := scopedProwJobs(r, ja)
 		omit := r.URL.Query().Get("omit")
 
 		if set := sets.NewString(strings.Split(omit, ",")...); set.Len() > 0 {
@@ -830,7 +944,7 @@ func handleProwJobs(ja *jobs.JobAgent, log *logrus.Entry) http.HandlerFunc {
 func handleData(ja *jobs.JobAgent, log *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
-		jobs := ja.Jobs()
+		jobs := scopedJobs(r, ja)
 		jd, err := json.Marshal(jobs)
 		if err != nil {
 			log.WithError(err).Error("Error marshaling jobs.")
@@ -860,7 +974,7 @@ func handleBadge(ja *jobs.JobAgent) http.HandlerFunc {
 		}
 		w.Header().Set("Content-Type", "image/svg+xml")
 
-		allJobs := ja.ProwJobs()
+		allJobs := scopedProwJobs(r, ja)
 		_, _, svg := renderBadge(pickLatestJobs(allJobs, wantJobs))
 		w.Write(svg)
 	}
@@ -889,6 +1003,10 @@ func handleBadge(ja *jobs.JobAgent) http.HandlerFunc {
 func handleJobHistory(o options, cfg config.Getter, opener io.Opener, log *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
+		if _, _, root, _, err := parseJobHistURL(r.URL); err == nil && !jobRunVisible(r.Context(), cfg, nil, path.Base(root), "") {
+			http.Error(w, fmt.Sprintf("Job %s not found", path.Base(root)), http.StatusNotFound)
+			return
+		}
 		tmpl, err := getJobHistory(r.Context(), r.URL, cfg, opener)
 		if err != nil {
 			msg := fmt.Sprintf("failed to get job history: %v", err)
@@ -911,6 +1029,10 @@ func handleJobHistory(o options, cfg config.Getter, opener io.Opener, log *logru
 func handlePRHistory(o options, cfg config.Getter, opener io.Opener, gitHubClient deckGitHubClient, gitClient git.ClientFactory, log *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
+		if org, repo, _, err := parsePullURL(r.URL); err == nil && !tenantVisible(r.Context(), orgRepoTenantID(cfg(), org+"/"+repo)) {
+			http.Error(w, fmt.Sprintf("Repo %s/%s not found", org, repo), http.StatusNotFound)
+			return
+		}
 		tmpl, err := getPRHistory(r.Context(), r.URL, cfg(), opener, gitHubClient, gitClient, o.github.Host)
 		if err != nil {
 			msg := fmt.Sprintf("failed to get PR history: %v", err)
@@ -967,6 +1089,9 @@ func renderSpyglass(ctx context.Context, sg *spyglass.Spyglass, cfg config.Gette
 		return "", fmt.Errorf("error when resolving real path %s: %w", src, err)
 	}
 	src = realPath
+	if !storagePathVisible(ctx, cfg, sg.JobAgent, src) {
+		return "", httpError{error: fmt.Errorf("job run %s not found", src), statusCode: http.StatusNotFound}
+	}
 	artifactNames, err := sg.ListArtifacts(ctx, src)
 	if err != nil {
 		return "", fmt.Errorf("error listing artifacts: %w", err)
@@ -1142,7 +1267,7 @@ lensesLoop:
 	}
 	t := template.New("spyglass.html")
 
-	if _, err := prepareBaseTemplate(o, cfg, csrfToken, t); err != nil {
+	if _, err := prepareBaseTemplate(ctx, o, cfg, csrfToken, t); err != nil {
 		return "", fmt.Errorf("error preparing base template: %w", err)
 	}
 	t, err = t.ParseFiles(path.Join(o.templateFilesLocation, "spyglass.html"))
@@ -1183,7 +1308,7 @@ func prHistLinkFromTemplate(prHistLinkTemplate, org, repo string, number int) (s
 // Query params:
 // - name: required, specifies the name of the viewer to load
 // - src: required, specifies the job source from which to fetch artifacts
-func handleArtifactView(o options, sg *spyglass.Spyglass, cfg config.Getter) http.HandlerFunc {
+func handleArtifactView(o options, sg *spyglass.Spyglass, cfg config.Getter, grpcLenses *common.GRPCLensClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
 		pathSegments := strings.Split(r.URL.Path, "/")
@@ -1219,12 +1344,26 @@ func handleArtifactView(o options, sg *spyglass.Spyglass, cfg config.Getter) htt
 			http.Error(w, fmt.Sprintf("Failed to process request: %v", err), httpStatusForError(err))
 			return
 		}
+		if !storagePathVisible(r.Context(), cfg, sg.JobAgent, request.Source) {
+			http.Error(w, fmt.Sprintf("Job run %s not found", request.Source), http.StatusNotFound)
+			return
+		}
 
-		handleRemoteLens(*lens, w, r, resource, request)
+		handleRemoteLens(*lens, grpcLenses, w, r, resource, request)
 	}
 }
 
-func handleRemoteLens(lens config.LensFileConfig, w http.ResponseWriter, r *http.Request, resource string, request spyglass.LensRequest) {
+// lensResourceRoot returns the URL the browser loads the static resources of the
+// lens from. Deck serves them unless the lens has a static root, which allows
+// lenses running as separate services to ship their own resources.
+func lensResourceRoot(lens config.LensFileConfig) string {
+	if lens.RemoteConfig != nil && lens.RemoteConfig.StaticRoot != "" {
+		return strings.TrimSuffix(lens.RemoteConfig.StaticRoot, "/") + "/"
+	}
+	return "/spyglass/static/" + lens.Lens.Name + "/"
+}
+
+func handleRemoteLens(lens config.LensFileConfig, grpcLenses *common.GRPCLensClient, w http.ResponseWriter, r *http.Request, resource string, request spyglass.LensRequest) {
 	var requestType spyglassapi.RequestAction
 	switch resource {
 	case "iframe":
@@ -1252,11 +1391,28 @@ func handleRemoteLens(lens config.LensFileConfig, w http.ResponseWriter, r *http
 		Action:         requestType,
 		Data:           data,
 		Config:         lens.Lens.Config,
-		ResourceRoot:   "/spyglass/static/" + lens.Lens.Name + "/",
+		ResourceRoot:   lensResourceRoot(lens),
 		Artifacts:      request.Artifacts,
 		ArtifactSource: request.Source,
 		LensIndex:      request.Index,
+		LensName:       lens.Lens.Name,
+	}
+
+	if common.IsGRPCEndpoint(lens.RemoteConfig.ParsedEndpoint) {
+		response, err := grpcLenses.Render(r.Context(), lens.RemoteConfig.ParsedEndpoint, &lensRequest)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to call lens backend: %v", err), common.HTTPStatusForGRPCError(err))
+			return
+		}
+		if response.ContentType != "" {
+			w.Header().Set("Content-Type", response.ContentType)
+		}
+		if _, err := w.Write([]byte(response.Body)); err != nil {
+			logrus.WithError(err).Error("Failed to write lens response.")
+		}
+		return
 	}
+
 	serializedRequest, err := json.Marshal(lensRequest)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to marshal request to lens backend: %v", err), http.StatusInternalServerError)
@@ -1275,7 +1431,7 @@ func handleRemoteLens(lens config.LensFileConfig, w http.ResponseWriter, r *http
 func handleTidePools(cfg config.Getter, ta *tideAgent, log *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
-		queryConfigs := ta.filterQueries(cfg().Tide.Queries)
+		queryConfigs := scopedTideQueries(r.Context(), cfg(), ta.filterQueries(cfg().Tide.Queries))
 		queries := make([]string, 0, len(queryConfigs))
 		for _, qc := range queryConfigs {
 			queries = append(queries, qc.Query())
@@ -1288,7 +1444,7 @@ func handleTidePools(cfg config.Getter, ta *tideAgent, log *logrus.Entry) http.H
 		payload := tidePools{
 			Queries:     queries,
 			TideQueries: queryConfigs,
-			Pools:       pools,
+			Pools:       scopedTidePools(r.Context(), cfg(), pools),
 		}
 		pd, err := json.Marshal(payload)
 		if err != nil {
@@ -1308,7 +1464,7 @@ func handleTideHistory(ta *tideAgent, log *logrus.Entry) http.HandlerFunc {
 		ta.Unlock()
 
 		payload := tideHistory{
-			History: history,
+			History: scopedTideHistory(r.Context(), ta.cfg(), history),
 		}
 		pd, err := json.Marshal(payload)
 		if err != nil {
@@ -1319,7 +1475,7 @@ func handleTideHistory(ta *tideAgent, log *logrus.Entry) http.HandlerFunc {
 	}
 }
 
-func handlePluginHelp(ha *helpAgent, log *logrus.Entry) http.HandlerFunc {
+func handlePluginHelp(ha *helpAgent, cfg config.Getter, log *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
 		help, err := ha.getHelp()
@@ -1327,7 +1483,7 @@ func handlePluginHelp(ha *helpAgent, log *logrus.Entry) http.HandlerFunc {
 			log.WithError(err).Error("Getting plugin help from hook.")
 			help = &pluginhelp.Help{}
 		}
-		b, err := json.Marshal(*help)
+		b, err := json.Marshal(scopedPluginHelp(r.Context(), cfg(), *help))
 		if err != nil {
 			log.WithError(err).Error("Marshaling plugin help.")
 			b = []byte("[]")
@@ -1337,14 +1493,24 @@ func handlePluginHelp(ha *helpAgent, log *logrus.Entry) http.HandlerFunc {
 }
 
 type logClient interface {
+	prowJobGetter
 	GetJobLog(job, id, container string) ([]byte, error)
+	StreamJobLog(ctx context.Context, job, id, container string) (stdio.ReadCloser, bool, error)
 }
 
+// followingLogHeader is set on the responses following the log of a running
+// job, whose stream ends when the pod of the job terminates or the connection is
+// lost. Clients can reconnect at the offset they received until it is missing.
+// Clients that follow the log over a websocket are reconnected to the pod by
+// Deck instead, see streamLogOverWebsocket.
+const followingLogHeader = "X-Prow-Following-Log"
+
 // TODO(spxtr): Cache, rate limit.
 func handleLog(lc logClient, log *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
 		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Expose-Headers", followingLogHeader)
 		job := r.URL.Query().Get("job")
 		id := r.URL.Query().Get("id")
 		container := r.URL.Query().Get("container")
@@ -1356,31 +1522,102 @@ func handleLog(lc logClient, log *logrus.Entry) http.HandlerFunc {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if pj, err := lc.GetProwJob(job, id); err == nil && !prowJobVisible(r.Context(), pj) {
+			logNotFound(w, fmt.Errorf("ProwJob of job %s with build ID %s not found", job, id), logger)
+			return
+		}
+		follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+		var offset int64
+		if o := r.URL.Query().Get("offset"); o != "" {
+			var err error
+			if offset, err = strconv.ParseInt(o, 10, 64); err != nil || offset < 0 {
+				http.Error(w, fmt.Sprintf("invalid offset %q", o), http.StatusBadRequest)
+				return
+			}
+		}
+		if follow {
+			if websocket.IsWebSocketUpgrade(r) {
+				streamLogOverWebsocket(w, r, lc, job, id, container, offset, logger)
+				return
+			}
+			streamLog(w, r, lc, job, id, container, offset, logger)
+			return
+		}
 		jobLog, err := lc.GetJobLog(job, id, container)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Log not found: %v", err), http.StatusNotFound)
-			logger := logger.WithError(err)
-			msg := "Log not found."
-			if strings.Contains(err.Error(), "PodInitializing") || strings.Contains(err.Error(), "not found") ||
-				strings.Contains(err.Error(), "terminated") {
-				// PodInitializing is really common and not something
-				// that has any actionable items for administrators
-				// monitoring logs, so we should log it as information.
-				// Similarly, if a user asks us to proxy through logs
-				// for a Pod or ProwJob that doesn't exit, it's not
-				// something an administrator wants to see in logs.
-				logger.Info(msg)
-			} else {
-				logger.Warning(msg)
-			}
+			logNotFound(w, err, logger)
 			return
 		}
-		if _, err = w.Write(jobLog); err != nil {
+		if offset > int64(len(jobLog)) {
+			offset = int64(len(jobLog))
+		}
+		if _, err = w.Write(jobLog[offset:]); err != nil {
 			logger.WithError(err).Warning("Error writing log.")
 		}
 	}
 }
 
+// streamLog writes the log of the job from the offset on, flushing it as it is
+// read so that the logs of running jobs can be followed.
+func streamLog(w http.ResponseWriter, r *http.Request, lc logClient, job, id, container string, offset int64, logger *logrus.Entry) {
+	jobLog, following, err := lc.StreamJobLog(r.Context(), job, id, container)
+	if err != nil {
+		logNotFound(w, err, logger)
+		return
+	}
+	defer jobLog.Close()
+	if _, err := stdio.CopyN(ioutil.Discard, jobLog, offset); err != nil && err != stdio.EOF {
+		logger.WithError(err).Warning("Error skipping to the offset of the log.")
+		http.Error(w, fmt.Sprintf("Error reading log: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if following {
+		w.Header().Set(followingLogHeader, "true")
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := jobLog.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				logger.WithError(err).Debug("Error writing log, the client is probably gone.")
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == stdio.EOF {
+			return
+		}
+		if err != nil {
+			// The client reconnects at its offset.
+			logger.WithError(err).Info("Error streaming log.")
+			return
+		}
+	}
+}
+
+func logNotFound(w http.ResponseWriter, err error, logger *logrus.Entry) {
+	http.Error(w, fmt.Sprintf("Log not found: %v", err), http.StatusNotFound)
+	logger = logger.WithError(err)
+	msg := "Log not found."
+	if strings.Contains(err.Error(), "PodInitializing") || strings.Contains(err.Error(), "not found") ||
+		strings.Contains(err.Error(), "terminated") {
+		// PodInitializing is really common and not something
+		// that has any actionable items for administrators
+		// monitoring logs, so we should log it as information.
+		// Similarly, if a user asks us to proxy through logs
+		// for a Pod or ProwJob that doesn't exit, it's not
+		// something an administrator wants to see in logs.
+		logger.Info(msg)
+	} else {
+		logger.Warning(msg)
+	}
+}
+
 func validateLogRequest(r *http.Request) error {
 	job := r.URL.Query().Get("job")
 	id := r.URL.Query().Get("id")
@@ -1412,6 +1649,10 @@ func handleProwJob(prowJobClient prowv1.ProwJobInterface, log *logrus.Entry) htt
 			}
 			return
 		}
+		if !prowJobVisible(r.Context(), *pj) {
+			http.Error(w, fmt.Sprintf("ProwJob not found: %v", kerrors.NewNotFound(prowapi.Resource("prowjobs"), name)), http.StatusNotFound)
+			return
+		}
 		pj.ManagedFields = nil
 		handleSerialize(w, "prowjob", pj, l)
 	}
@@ -1467,7 +1708,7 @@ func canTriggerJob(user string, pj prowapi.ProwJob, cfg *prowapi.RerunAuthConfig
 // handleRerun triggers a rerun of the given job if that features is enabled, it receives a
 // POST request, and the user has the necessary permissions. Otherwise, it writes the config
 // for a new job but does not trigger it.
-func handleRerun(prowJobClient prowv1.ProwJobInterface, createProwJob bool, cfg authCfgGetter, goa *githuboauth.Agent, ghc githuboauth.AuthenticatedUserIdentifier, cli deckGitHubClient, pluginAgent *plugins.ConfigAgent, log *logrus.Entry) http.HandlerFunc {
+func handleRerun(prowJobClient prowv1.ProwJobInterface, createProwJob bool, cfg authCfgGetter, auth *rerunAuthenticator, goa *githuboauth.Agent, ghc githuboauth.AuthenticatedUserIdentifier, cli deckGitHubClient, pluginAgent *plugins.ConfigAgent, log *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		name := r.URL.Query().Get("prowjob")
 		l := log.WithField("prowjob", name)
@@ -1484,6 +1725,10 @@ func handleRerun(prowJobClient prowv1.ProwJobInterface, createProwJob bool, cfg
 			}
 			return
 		}
+		if !prowJobVisible(r.Context(), *pj) {
+			http.Error(w, fmt.Sprintf("ProwJob not found: %v", kerrors.NewNotFound(prowapi.Resource("prowjobs"), name)), http.StatusNotFound)
+			return
+		}
 		newPJ := pjutil.NewProwJob(pj.Spec, pj.ObjectMeta.Labels, pj.ObjectMeta.Annotations)
 		l = l.WithField("job", newPJ.Spec.Job)
 		switch r.Method {
@@ -1494,13 +1739,25 @@ func handleRerun(prowJobClient prowv1.ProwJobInterface, createProwJob bool, cfg
 				http.Error(w, "Direct rerun feature is not enabled. Enable with the '--rerun-creates-job' flag.", http.StatusMethodNotAllowed)
 				return
 			}
-			authConfig := cfg(pj.Spec.Refs)
+			authConfig := cfg(pj.Spec.Type, pj.Spec.Refs)
+			identity, err := auth.identify(r)
+			if err != nil {
+				l.WithError(err).Info("Error authenticating rerun request.")
+				http.Error(w, fmt.Sprintf("Error authenticating request: %v", err), http.StatusUnauthorized)
+				return
+			}
 			var allowed bool
-			if pj.Spec.RerunAuthConfig.IsAllowAnyone() || authConfig.IsAllowAnyone() {
+			switch {
+			case identity != nil:
+				// Requests authenticated with tokens or by a proxy are authorized by their groups.
+				allowed = pj.Spec.RerunAuthConfig.IsAllowAnyone() || authConfig.IsAllowAnyone() ||
+					authConfig.IsAuthorizedGroup(identity.Groups) || pj.Spec.RerunAuthConfig.IsAuthorizedGroup(identity.Groups)
+			case pj.Spec.RerunAuthConfig.IsAllowAnyone() || authConfig.IsAllowAnyone():
 				// Skip getting the users login via GH oauth if anyone is allowed to rerun
 				// jobs so that GH oauth doesn't need to be set up for private Prows.
+				identity = &rerunIdentity{Method: "anonymous"}
 				allowed = true
-			} else {
+			default:
 				if goa == nil {
 					msg := "GitHub oauth must be configured to rerun jobs unless 'allow_anyone: true' is specified."
 					http.Error(w, msg, http.StatusInternalServerError)
@@ -1513,6 +1770,7 @@ func handleRerun(prowJobClient prowv1.ProwJobInterface, createProwJob bool, cfg
 					http.Error(w, "Error retrieving GitHub login", http.StatusUnauthorized)
 					return
 				}
+				identity = &rerunIdentity{Method: "github", Name: login}
 				l = l.WithField("user", login)
 				allowed, err = canTriggerJob(login, newPJ, authConfig, cli, pluginAgent.Config, l)
 				if err != nil {
@@ -1522,7 +1780,8 @@ func handleRerun(prowJobClient prowv1.ProwJobInterface, createProwJob bool, cfg
 				}
 			}
 
-			l = l.WithField("allowed", allowed)
+			// The attempts are the audit log of the reruns.
+			l = l.WithFields(logrus.Fields{"audit": "rerun", "requester": identity.String(), "groups": identity.Groups, "allowed": allowed})
 			l.Info("Attempted rerun")
 			if !allowed {
 				if _, err = w.Write([]byte("You don't have permission to rerun that job")); err != nil {
@@ -1530,6 +1789,10 @@ func handleRerun(prowJobClient prowv1.ProwJobInterface, createProwJob bool, cfg
 				}
 				return
 			}
+			if newPJ.Annotations == nil {
+				newPJ.Annotations = map[string]string{}
+			}
+			newPJ.Annotations[kube.RerunByAnnotation] = identity.String()
 			created, err := prowJobClient.Create(context.TODO(), &newPJ, metav1.CreateOptions{})
 			if err != nil {
 				l.WithError(err).Error("Error creating job")
@@ -1589,9 +1852,8 @@ func handlePluginConfig(pluginAgent *plugins.ConfigAgent, log *logrus.Entry) htt
 
 func handleFavicon(staticFilesLocation string, cfg config.Getter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		config := cfg()
-		if config.Deck.Branding != nil && config.Deck.Branding.Favicon != "" {
-			http.ServeFile(w, r, staticFilesLocation+"/"+config.Deck.Branding.Favicon)
+		if favicon := requestBranding(r.Context(), cfg).Favicon; favicon != "" {
+			http.ServeFile(w, r, staticFilesLocation+"/"+favicon)
 		} else {
 			http.ServeFile(w, r, staticFilesLocation+"/favicon.ico")
 		}
@@ -1619,6 +1881,8 @@ type deckGitHubClient interface {
 	GetPullRequest(org, repo string, number int) (*prowgithub.PullRequest, error)
 	GetRef(org, repo, ref string) (string, error)
 	BotUserChecker() (func(candidate string) bool, error)
+	ListIssueEvents(org, repo string, num int) ([]prowgithub.ListedIssueEvent, error)
+	ListIssueComments(org, repo string, number int) ([]prowgithub.IssueComment, error)
 }
 
 func spglassConfigDefaulting(c *config.Config) error {
@@ -56,6 +56,7 @@ import (
 	pluginsflagutil "k8s.io/test-infra/prow/flagutil/plugins"
 	"k8s.io/test-infra/prow/github/fakegithub"
 	"k8s.io/test-infra/prow/githuboauth"
+	"k8s.io/test-infra/prow/kube"
 	"k8s.io/test-infra/prow/pluginhelp"
 	"k8s.io/test-infra/prow/plugins"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/buildlog"
@@ -173,6 +174,13 @@ func TestOptions_Validate(t *testing.T) {
 
 type flc int
 
+func (f flc) GetProwJob(job, id string) (prowapi.ProwJob, error) {
+	if job == "job" && id == "123" {
+		return prowapi.ProwJob{Spec: prowapi.ProwJobSpec{Job: job}}, nil
+	}
+	return prowapi.ProwJob{}, errors.New("muahaha")
+}
+
 func (f flc) GetJobLog(job, id, container string) ([]byte, error) {
 	if job == "job" && id == "123" {
 		return []byte("hello"), nil
@@ -180,6 +188,14 @@ func (f flc) GetJobLog(job, id, container string) ([]byte, error) {
 	return nil, errors.New("muahaha")
 }
 
+func (f flc) StreamJobLog(ctx context.Context, job, id, container string) (io.ReadCloser, bool, error) {
+	log, err := f.GetJobLog(job, id, container)
+	if err != nil {
+		return nil, false, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(log)), true, nil
+}
+
 func TestHandleLog(t *testing.T) {
 	var testcases = []struct {
 		name string
@@ -211,6 +227,21 @@ func TestHandleLog(t *testing.T) {
 			path: "?job=ohno&id=123",
 			code: http.StatusNotFound,
 		},
+		{
+			name: "following, found",
+			path: "?job=job&id=123&follow=true",
+			code: http.StatusOK,
+		},
+		{
+			name: "following, not found",
+			path: "?job=ohno&id=123&follow=true",
+			code: http.StatusNotFound,
+		},
+		{
+			name: "invalid offset",
+			path: "?job=job&id=123&offset=-1",
+			code: http.StatusBadRequest,
+		},
 	}
 	handler := handleLog(flc(0), logrus.WithField("handler", "/log"))
 	for _, tc := range testcases {
@@ -239,17 +270,20 @@ func TestHandleLog(t *testing.T) {
 				var buf bytes.Buffer
 				for {
 					line, err := reader.ReadBytes('\n')
+					buf.Write(line)
 					if err == io.EOF {
 						break
 					}
 					if err != nil {
 						t.Fatalf("Expecting reply with content but got error: %v", err)
 					}
-					buf.Write(line)
 				}
 				if !bytes.Contains(buf.Bytes(), []byte("hello")) {
 					t.Errorf("Unexpected body: got %s.", buf.String())
 				}
+				if rr.Header().Get(followingLogHeader) != "true" {
+					t.Errorf("Expected the %s header to be set.", followingLogHeader)
+				}
 			} else {
 				resp := rr.Result()
 				defer resp.Body.Close()
@@ -408,6 +442,13 @@ func TestProwJob(t *testing.T) {
 	if res.Status.State != prowapi.PendingState {
 		t.Errorf("Wrong state, expected \"%v\", got \"%v\"", prowapi.PendingState, res.Status.State)
 	}
+
+	// The ProwJob belongs to the default tenant, so another tenant can't see it.
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req.WithContext(scopedContext("a")))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected the ProwJob of another tenant not to be found, got code %d", rr.Code)
+	}
 }
 
 type fakeAuthenticatedUserIdentifier struct {
@@ -427,7 +468,10 @@ func TestRerun(t *testing.T) {
 		authorized          []string
 		allowAnyone         bool
 		rerunCreatesJob     bool
+		groups              []string
+		headers             map[string]string
 		shouldCreateProwJob bool
+		expectedRerunBy     string
 		httpCode            int
 		httpMethod          string
 	}{
@@ -508,6 +552,48 @@ func TestRerun(t *testing.T) {
 			allowAnyone:         false,
 			rerunCreatesJob:     true,
 			shouldCreateProwJob: true,
+			expectedRerunBy:     "github:org-member",
+			httpCode:            http.StatusOK,
+			httpMethod:          http.MethodPost,
+		},
+		{
+			name:                "API token in permitted group",
+			login:               "random-dude",
+			groups:              []string{"release-managers"},
+			headers:             map[string]string{"Authorization": "Bearer release-secret"},
+			rerunCreatesJob:     true,
+			shouldCreateProwJob: true,
+			expectedRerunBy:     "token:release-bot",
+			httpCode:            http.StatusOK,
+			httpMethod:          http.MethodPost,
+		},
+		{
+			name:                "API token not in permitted group",
+			login:               "authorized",
+			groups:              []string{"release-managers"},
+			headers:             map[string]string{"Authorization": "Bearer test-secret"},
+			rerunCreatesJob:     true,
+			shouldCreateProwJob: false,
+			httpCode:            http.StatusOK,
+			httpMethod:          http.MethodPost,
+		},
+		{
+			name:                "Unknown API token",
+			login:               "authorized",
+			headers:             map[string]string{"Authorization": "Bearer guessed"},
+			rerunCreatesJob:     true,
+			shouldCreateProwJob: false,
+			httpCode:            http.StatusUnauthorized,
+			httpMethod:          http.MethodPost,
+		},
+		{
+			name:                "Proxy user in permitted group",
+			login:               "random-dude",
+			groups:              []string{"release-managers"},
+			headers:             map[string]string{"X-Forwarded-User": "alice", "X-Forwarded-Groups": "devs, release-managers"},
+			rerunCreatesJob:     true,
+			shouldCreateProwJob: true,
+			expectedRerunBy:     "proxy:alice",
 			httpCode:            http.StatusOK,
 			httpMethod:          http.MethodPost,
 		},
@@ -543,12 +629,21 @@ func TestRerun(t *testing.T) {
 					State: prowapi.PendingState,
 				},
 			})
-			authCfgGetter := func(refs *prowapi.Refs) *prowapi.RerunAuthConfig {
+			authCfgGetter := func(jobType prowapi.ProwJobType, refs *prowapi.Refs) *prowapi.RerunAuthConfig {
 				return &prowapi.RerunAuthConfig{
 					AllowAnyone: tc.allowAnyone,
 					GitHubUsers: tc.authorized,
+					Groups:      tc.groups,
 				}
 			}
+			rerunAuth := &rerunAuthenticator{
+				tokens: []rerunToken{
+					{Name: "release-bot", Token: "release-secret", Groups: []string{"release-managers"}},
+					{Name: "test-bot", Token: "test-secret", Groups: []string{"testers"}},
+				},
+				proxyUserHeader:   "X-Forwarded-User",
+				proxyGroupsHeader: "X-Forwarded-Groups",
+			}
 
 			req, err := http.NewRequest(tc.httpMethod, "/rerun?prowjob=wowsuch", nil)
 			if err != nil {
@@ -561,6 +656,9 @@ func TestRerun(t *testing.T) {
 				Expires: time.Now().Add(time.Hour * 24 * 30),
 				Secure:  true,
 			})
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
 			mockCookieStore := sessions.NewCookieStore([]byte("secret-key"))
 			session, err := sessions.GetRegistry(req).Get(mockCookieStore, "access-token-session")
 			if err != nil {
@@ -577,7 +675,7 @@ func TestRerun(t *testing.T) {
 			rc := fakegithub.NewFakeClient()
 			rc.OrgMembers = map[string][]string{"org": {"org-member"}}
 			pca := plugins.NewFakeConfigAgent()
-			handler := handleRerun(fakeProwJobClient.ProwV1().ProwJobs("prowjobs"), tc.rerunCreatesJob, authCfgGetter, goa, ghc, rc, &pca, logrus.WithField("handler", "/rerun"))
+			handler := handleRerun(fakeProwJobClient.ProwV1().ProwJobs("prowjobs"), tc.rerunCreatesJob, authCfgGetter, rerunAuth, goa, ghc, rc, &pca, logrus.WithField("handler", "/rerun"))
 			handler.ServeHTTP(rr, req)
 			if rr.Code != tc.httpCode {
 				t.Fatalf("Bad error code: %d", rr.Code)
@@ -591,6 +689,21 @@ func TestRerun(t *testing.T) {
 				if numPJs := len(pjs.Items); numPJs != 2 {
 					t.Errorf("expected to get two prowjobs, got %d", numPJs)
 				}
+				if tc.expectedRerunBy != "" {
+					for _, pj := range pjs.Items {
+						if pj.Name != "wowsuch" && pj.Annotations[kube.RerunByAnnotation] != tc.expectedRerunBy {
+							t.Errorf("expected the rerun to be annotated with %q, got %q", tc.expectedRerunBy, pj.Annotations[kube.RerunByAnnotation])
+						}
+					}
+				}
+			} else if tc.rerunCreatesJob && tc.httpMethod == http.MethodPost {
+				pjs, err := fakeProwJobClient.ProwV1().ProwJobs("prowjobs").List(context.Background(), metav1.ListOptions{})
+				if err != nil {
+					t.Fatalf("failed to list prowjobs: %v", err)
+				}
+				if numPJs := len(pjs.Items); numPJs != 1 {
+					t.Errorf("expected to get one prowjob, got %d", numPJs)
+				}
 
 			} else if !tc.rerunCreatesJob && tc.httpCode == http.StatusOK {
 				resp := rr.Result()
@@ -762,7 +875,7 @@ func TestHelp(t *testing.T) {
 	ha := &helpAgent{
 		path: s.URL,
 	}
-	handler := handlePluginHelp(ha, logrus.WithField("handler", "/plugin-help.js"))
+	handler := handlePluginHelp(ha, func() *config.Config { return &config.Config{} }, logrus.WithField("handler", "/plugin-help.js"))
 	handleAndCheck := func() {
 		req, err := http.NewRequest(http.MethodGet, "/plugin-help.js", nil)
 		if err != nil {
@@ -1164,6 +1277,32 @@ func TestSpyglassConfigDefaulting(t *testing.T) {
 	}
 }
 
+func TestLensResourceRoot(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lens     config.LensFileConfig
+		expected string
+	}{
+		{
+			name:     "served by Deck",
+			lens:     config.LensFileConfig{Lens: config.LensConfig{Name: "junit"}, RemoteConfig: &config.LensRemoteConfig{Endpoint: "http://127.0.0.1:1234/dynamic/junit"}},
+			expected: "/spyglass/static/junit/",
+		},
+		{
+			name:     "static root of remote lens",
+			lens:     config.LensFileConfig{Lens: config.LensConfig{Name: "custom"}, RemoteConfig: &config.LensRemoteConfig{Endpoint: "http://custom-lens/lens", StaticRoot: "https://custom-lens.example.com/static"}},
+			expected: "https://custom-lens.example.com/static/",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := lensResourceRoot(tc.lens); actual != tc.expected {
+				t.Errorf("expected resource root %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
 func TestHandleGitHubLink(t *testing.T) {
 	ghoptions := flagutil.GitHubOptions{Host: "github.mycompany.com"}
 	org, repo := "org", "repo"
@@ -0,0 +1,179 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/git/v2"
+	prowgithub "k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+	"k8s.io/test-infra/prow/plugins/ownersconfig"
+	"k8s.io/test-infra/prow/repoowners"
+)
+
+// ownersLoader loads the OWNERS of a branch of a repo.
+type ownersLoader interface {
+	LoadRepoOwners(org, repo, base string) (repoowners.RepoOwner, error)
+}
+
+// ownersExplainer is implemented by the owners that ownersLoader returns.
+type ownersExplainer interface {
+	Explain(path string) repoowners.OwnersExplanation
+}
+
+// newOwnersClient returns a client that loads OWNERS the same way hook does.
+func newOwnersClient(cfg config.Getter, pluginAgent *plugins.ConfigAgent, gitClient git.ClientFactory, githubClient prowgithub.Client) *repoowners.Client {
+	client := repoowners.NewClient(
+		gitClient,
+		githubClient,
+		func(org, repo string) bool {
+			return pluginAgent.Config().MDYAMLEnabled(org, repo)
+		},
+		func(org, repo string) bool {
+			return pluginAgent.Config().SkipCollaborators(org, repo)
+		},
+		func() *config.OwnersDirDenylist {
+			if l := cfg().OwnersDirDenylist; l != nil {
+				return l
+			}
+			if l := cfg().OwnersDirBlacklist; l != nil {
+				return l
+			}
+			return &config.OwnersDirDenylist{}
+		},
+		func(org, repo string) ownersconfig.Filenames {
+			return pluginAgent.Config().OwnersFilenames(org, repo)
+		},
+	)
+	client.UseDefaultOwners(func(org, repo string) *ownersconfig.DefaultOwners {
+		return pluginAgent.Config().DefaultOwnersFor(org, repo)
+	})
+	return client
+}
+
+// handleOwners serves the effective approvers and reviewers of a file in a
+// branch, and which OWNERS files they come from. The response is JSON, or a
+// tree for people to read if format=text is set. Only repos in the Prow
+// config that the tenant of the request may see are served.
+func handleOwners(cfg config.Getter, owners ownersLoader, log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		query := r.URL.Query()
+		org, repo, ref, path := query.Get("org"), query.Get("repo"), query.Get("ref"), query.Get("path")
+		if org == "" || repo == "" || ref == "" {
+			http.Error(w, "org, repo and ref must be set", http.StatusBadRequest)
+			return
+		}
+		orgRepo := org + "/" + repo
+		if !cfg().AllRepos.Has(orgRepo) || !tenantVisible(r.Context(), orgRepoTenantID(cfg(), orgRepo)) {
+			http.Error(w, fmt.Sprintf("Repo %s not found", orgRepo), http.StatusNotFound)
+			return
+		}
+		path = strings.Trim(path, "/")
+		l := log.WithFields(logrus.Fields{"org": org, "repo": repo, "ref": ref, "path": path})
+
+		repoOwners, err := owners.LoadRepoOwners(org, repo, strings.TrimPrefix(ref, "refs/heads/"))
+		if err != nil {
+			l.WithError(err).Warn("Failed to load OWNERS.")
+			http.Error(w, fmt.Sprintf("failed to load OWNERS of %s/%s@%s: %v", org, repo, ref, err), http.StatusInternalServerError)
+			return
+		}
+		explainer, ok := repoOwners.(ownersExplainer)
+		if !ok {
+			http.Error(w, "OWNERS of this repo can't be explained", http.StatusInternalServerError)
+			return
+		}
+		explanation := explainer.Explain(path)
+
+		if query.Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, renderOwnersExplanation(explanation))
+			return
+		}
+		b, err := json.Marshal(explanation)
+		if err != nil {
+			l.WithError(err).Error("Error marshaling OWNERS explanation.")
+			b = []byte("{}")
+		}
+		writeJSONResponse(w, r, b)
+	}
+}
+
+// renderOwnersExplanation renders the explanation as the chain of OWNERS
+// files from the path to the root of the repo.
+func renderOwnersExplanation(explanation repoowners.OwnersExplanation) string {
+	var out bytes.Buffer
+	path := explanation.Path
+	if path == "" {
+		path = "/"
+	}
+	fmt.Fprintf(&out, "%s\n", path)
+	for _, effective := range []struct {
+		label  string
+		logins []string
+	}{
+		{"approvers:         ", explanation.Approvers},
+		{"leaf approvers:    ", explanation.LeafApprovers},
+		{"reviewers:         ", explanation.Reviewers},
+		{"leaf reviewers:    ", explanation.LeafReviewers},
+		{"required reviewers:", explanation.RequiredReviewers},
+	} {
+		fmt.Fprintf(&out, "  %s\n", strings.TrimSpace(effective.label+" "+strings.Join(effective.logins, ", ")))
+	}
+	if explanation.CodeOwners {
+		out.WriteString("owners come from CODEOWNERS because the repo has no OWNERS files\n")
+	}
+	if explanation.DefaultOwners {
+		out.WriteString("owners are the default owners of the org because the repo has no OWNERS files\n")
+	}
+
+	lastFile := ""
+	for i, contribution := range explanation.Contributions {
+		if i == 0 || contribution.File != lastFile {
+			file := contribution.File
+			if file == "" {
+				file = "default owners"
+			}
+			fmt.Fprintf(&out, "%s\n", file)
+			lastFile = contribution.File
+		}
+		reason := "inherited"
+		if contribution.Leaf {
+			reason = "leaf"
+		}
+		line := fmt.Sprintf("%s (%s)", contribution.Role, reason)
+		if contribution.Filter != "" {
+			line += fmt.Sprintf(" for %q", contribution.Filter)
+		}
+		fmt.Fprintf(&out, "  %s\n", strings.TrimSpace(line+": "+strings.Join(contribution.Logins, ", ")))
+		if len(contribution.NotCollaborators) > 0 {
+			fmt.Fprintf(&out, "    dropped, not collaborators: %s\n", strings.Join(contribution.NotCollaborators, ", "))
+		}
+	}
+	if explanation.NoParentOwners != "" {
+		fmt.Fprintf(&out, "no_parent_owners in %s stops inheriting from parent directories\n", explanation.NoParentOwners)
+	}
+	return out.String()
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/repoowners"
+)
+
+type fakeRepoOwners struct {
+	repoowners.RepoOwner
+	explained string
+}
+
+func (f *fakeRepoOwners) Explain(path string) repoowners.OwnersExplanation {
+	f.explained = path
+	return repoowners.OwnersExplanation{
+		Path:          path,
+		Approvers:     []string{"alice", "bob"},
+		LeafApprovers: []string{"bob"},
+		Contributions: []repoowners.OwnersContribution{
+			{File: "pkg/OWNERS", Role: repoowners.RoleApprovers, Leaf: true, Logins: []string{"bob"}, NotCollaborators: []string{"carl"}},
+			{File: "OWNERS", Role: repoowners.RoleApprovers, Logins: []string{"alice"}},
+		},
+	}
+}
+
+type fakeOwnersLoader struct {
+	owners *fakeRepoOwners
+	base   string
+}
+
+func (f *fakeOwnersLoader) LoadRepoOwners(org, repo, base string) (repoowners.RepoOwner, error) {
+	f.base = base
+	return f.owners, nil
+}
+
+func TestHandleOwners(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		ctx            context.Context
+		expectedStatus int
+		expectedBase   string
+		expectedPath   string
+		expectedBody   string
+	}{
+		{
+			name:           "repo is required",
+			query:          "org=org&ref=main&path=pkg/file.go",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "repo not in the config is not found",
+			query:          "org=org&repo=other&ref=main&path=pkg/file.go",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "repo of another tenant is not found",
+			query:          "org=org&repo=repo&ref=main&path=pkg/file.go",
+			ctx:            scopedContext("other-tenant"),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "repo of the tenant is explained",
+			query:          "org=org&repo=repo&ref=main&path=pkg/file.go",
+			ctx:            scopedContext(config.DefaultTenantID),
+			expectedStatus: http.StatusOK,
+			expectedBase:   "main",
+			expectedPath:   "pkg/file.go",
+		},
+		{
+			name:           "owners of a file are explained",
+			query:          "org=org&repo=repo&ref=refs/heads/main&path=/pkg/file.go",
+			expectedStatus: http.StatusOK,
+			expectedBase:   "main",
+			expectedPath:   "pkg/file.go",
+		},
+		{
+			name:           "explanation is rendered as text",
+			query:          "org=org&repo=repo&ref=main&path=pkg/file.go&format=text",
+			expectedStatus: http.StatusOK,
+			expectedBase:   "main",
+			expectedPath:   "pkg/file.go",
+			expectedBody: `pkg/file.go
+  approvers:          alice, bob
+  leaf approvers:     bob
+  reviewers:
+  leaf reviewers:
+  required reviewers:
+pkg/OWNERS
+  approvers (leaf): bob
+    dropped, not collaborators: carl
+OWNERS
+  approvers (inherited): alice
+`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			loader := &fakeOwnersLoader{owners: &fakeRepoOwners{}}
+			req := httptest.NewRequest(http.MethodGet, "/api/owners?"+tc.query, nil)
+			if tc.ctx != nil {
+				req = req.WithContext(tc.ctx)
+			}
+			rr := httptest.NewRecorder()
+			cfg := &config.Config{JobConfig: config.JobConfig{AllRepos: sets.NewString("org/repo")}}
+			handleOwners(func() *config.Config { return cfg }, loader, logrus.WithField("handler", "/api/owners")).ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			if loader.base != tc.expectedBase {
+				t.Errorf("expected OWNERS of %q to be loaded, got %q", tc.expectedBase, loader.base)
+			}
+			if loader.owners.explained != tc.expectedPath {
+				t.Errorf("expected %q to be explained, got %q", tc.expectedPath, loader.owners.explained)
+			}
+			if tc.expectedBody != "" {
+				if diff := cmp.Diff(tc.expectedBody, rr.Body.String()); diff != "" {
+					t.Errorf("unexpected body (-want +got):\n%s", diff)
+				}
+				return
+			}
+			var explanation repoowners.OwnersExplanation
+			if err := json.Unmarshal(rr.Body.Bytes(), &explanation); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if explanation.Path != tc.expectedPath || len(explanation.Contributions) != 2 {
+				t.Errorf("unexpected explanation: %+v", explanation)
+			}
+		})
+	}
+}
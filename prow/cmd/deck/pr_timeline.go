@@ -0,0 +1,310 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/deck/jobs"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/tide/history"
+)
+
+// The sources of the events of a PR timeline.
+const (
+	timelineSourceProwJob = "prowjob"
+	timelineSourceLabel   = "label"
+	timelineSourceComment = "comment"
+	timelineSourceTide    = "tide"
+)
+
+// maxTimelineCommentLength is the length comments are truncated to in the
+// timeline.
+const maxTimelineCommentLength = 200
+
+type prTimeline struct {
+	Org    string
+	Repo   string
+	Number int
+	Link   string
+	Events []timelineEvent
+}
+
+type timelineEvent struct {
+	Time    time.Time
+	Source  string
+	Summary string
+	Actor   string `json:",omitempty"`
+	State   string `json:",omitempty"`
+	Link    string `json:",omitempty"`
+}
+
+type timelineGitHubClient interface {
+	ListIssueEvents(org, repo string, num int) ([]github.ListedIssueEvent, error)
+	ListIssueComments(org, repo string, number int) ([]github.IssueComment, error)
+	BotUserChecker() (func(candidate string) bool, error)
+}
+
+// parseTimelinePR parses the PR of a timeline request, which has the format
+// org/repo#number.
+func parseTimelinePR(pr string) (org, repo string, number int, err error) {
+	orgRepo, num := pr, ""
+	if i := strings.LastIndex(pr, "#"); i != -1 {
+		orgRepo, num = pr[:i], pr[i+1:]
+	}
+	parts := strings.Split(orgRepo, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", 0, fmt.Errorf("invalid PR %q, expected org/repo#number", pr)
+	}
+	number, err = strconv.Atoi(num)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("invalid PR number %q: %w", num, err)
+	}
+	return parts[0], parts[1], number, nil
+}
+
+// prTimelineVisible returns whether Deck may show the timeline of the PRs of a
+// repo, following the same rules as for ProwJobs and Tide pools.
+func prTimelineVisible(c *config.Config, o options, orgRepo string) bool {
+	if tenantIDs := o.tenantIDs.Strings(); len(tenantIDs) > 0 {
+		return sets.NewString(tenantIDs...).Has(c.GetProwJobDefault(orgRepo, "*").TenantID)
+	}
+	hidden := matches(orgRepo, c.Deck.HiddenRepos)
+	if o.hiddenOnly {
+		return hidden
+	}
+	return !hidden || o.showHidden
+}
+
+func prowJobTimelineEvents(pjs []prowapi.ProwJob, org, repo string, number int) []timelineEvent {
+	var events []timelineEvent
+	for _, pj := range pjs {
+		refs := pj.Spec.Refs
+		if refs == nil || refs.Org != org || refs.Repo != repo {
+			continue
+		}
+		var found bool
+		for _, pull := range refs.Pulls {
+			if pull.Number == number {
+				found = true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+
+		kind := "job"
+		if pj.Spec.Type == prowapi.BatchJob {
+			kind = "batch job"
+		}
+		events = append(events, timelineEvent{
+			Time:    pj.Status.StartTime.Time,
+			Source:  timelineSourceProwJob,
+			Summary: fmt.Sprintf("Started %s %s", kind, pj.Spec.Job),
+			State:   string(prowapi.TriggeredState),
+			Link:    pj.Status.URL,
+		})
+		if pj.Status.CompletionTime != nil {
+			events = append(events, timelineEvent{
+				Time:    pj.Status.CompletionTime.Time,
+				Source:  timelineSourceProwJob,
+				Summary: fmt.Sprintf("Finished %s %s", kind, pj.Spec.Job),
+				State:   string(pj.Status.State),
+				Link:    pj.Status.URL,
+			})
+		}
+	}
+	return events
+}
+
+// gitHubTimelineEvents returns the labels and comments added to the PR by the
+// bot, that is by the plugins.
+func gitHubTimelineEvents(ghc timelineGitHubClient, org, repo string, number int) ([]timelineEvent, error) {
+	isBot, err := ghc.BotUserChecker()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bot user: %w", err)
+	}
+	issueEvents, err := ghc.ListIssueEvents(org, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issue events: %w", err)
+	}
+	comments, err := ghc.ListIssueComments(org, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+
+	var events []timelineEvent
+	for _, e := range issueEvents {
+		if !isBot(e.Actor.Login) {
+			continue
+		}
+		var summary string
+		switch e.Event {
+		case github.IssueActionLabeled:
+			summary = "Added label " + e.Label.Name
+		case github.IssueActionUnlabeled:
+			summary = "Removed label " + e.Label.Name
+		default:
+			continue
+		}
+		events = append(events, timelineEvent{
+			Time:    e.CreatedAt,
+			Source:  timelineSourceLabel,
+			Summary: summary,
+			Actor:   e.Actor.Login,
+		})
+	}
+	for _, c := range comments {
+		if !isBot(c.User.Login) {
+			continue
+		}
+		summary := c.Body
+		if len(summary) > maxTimelineCommentLength {
+			summary = summary[:maxTimelineCommentLength] + "..."
+		}
+		events = append(events, timelineEvent{
+			Time:    c.CreatedAt,
+			Source:  timelineSourceComment,
+			Summary: summary,
+			Actor:   c.User.Login,
+			Link:    c.HTMLURL,
+		})
+	}
+	return events, nil
+}
+
+// tideTimelineEvents returns the actions Tide took on the PR, e.g. triggering
+// its jobs or merging it.
+func tideTimelineEvents(hist map[string][]history.Record, org, repo string, number int) []timelineEvent {
+	var events []timelineEvent
+	for poolKey, records := range hist {
+		if !strings.HasPrefix(poolKey, org+"/"+repo+":") {
+			continue
+		}
+		for _, rec := range records {
+			var found bool
+			for _, pull := range rec.Target {
+				if pull.Number == number {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+
+			summary := fmt.Sprintf("%s in pool %s", rec.Action, poolKey)
+			if len(rec.Target) > 1 {
+				summary = fmt.Sprintf("%s with %d PRs in pool %s", rec.Action, len(rec.Target), poolKey)
+			}
+			state := string(prowapi.SuccessState)
+			if rec.Err != "" {
+				summary += ": " + rec.Err
+				state = string(prowapi.FailureState)
+			}
+			events = append(events, timelineEvent{
+				Time:    rec.Time,
+				Source:  timelineSourceTide,
+				Summary: summary,
+				State:   state,
+			})
+		}
+	}
+	return events
+}
+
+// getPRTimeline merges the ProwJobs, the plugin actions and the Tide actions of
+// a PR into a chronological timeline. The GitHub client and the Tide history
+// are optional.
+func getPRTimeline(pjs []prowapi.ProwJob, ghc timelineGitHubClient, hist map[string][]history.Record, githubHost, org, repo string, number int) (prTimeline, error) {
+	timeline := prTimeline{
+		Org:    org,
+		Repo:   repo,
+		Number: number,
+		Link:   githubPRLink(githubHost, org, repo, number),
+	}
+	timeline.Events = append(timeline.Events, prowJobTimelineEvents(pjs, org, repo, number)...)
+	if ghc != nil {
+		events, err := gitHubTimelineEvents(ghc, org, repo, number)
+		if err != nil {
+			return timeline, err
+		}
+		timeline.Events = append(timeline.Events, events...)
+	}
+	timeline.Events = append(timeline.Events, tideTimelineEvents(hist, org, repo, number)...)
+	sort.SliceStable(timeline.Events, func(i, j int) bool {
+		return timeline.Events[i].Time.Before(timeline.Events[j].Time)
+	})
+	return timeline, nil
+}
+
+func handlePRTimeline(o options, cfg config.Getter, ja *jobs.JobAgent, ta *tideAgent, ghc deckGitHubClient, log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		pr := r.URL.Query().Get("pr")
+		org, repo, number, err := parseTimelinePR(pr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !prTimelineVisible(cfg(), o, org+"/"+repo) || !tenantVisible(r.Context(), orgRepoTenantID(cfg(), org+"/"+repo)) {
+			http.Error(w, fmt.Sprintf("PR %s not found", pr), http.StatusNotFound)
+			return
+		}
+
+		var hist map[string][]history.Record
+		if ta != nil {
+			ta.Lock()
+			hist = ta.history
+			ta.Unlock()
+		}
+		var gc timelineGitHubClient
+		if ghc != nil {
+			gc = ghc
+		}
+		timeline, err := getPRTimeline(scopedProwJobs(r, ja), gc, hist, o.github.Host, org, repo, number)
+		if err != nil {
+			log.WithError(err).WithField("pr", pr).Warning("Failed to get PR timeline.")
+			http.Error(w, fmt.Sprintf("failed to get PR timeline: %v", err), http.StatusInternalServerError)
+			return
+		}
+		pd, err := json.Marshal(timeline)
+		if err != nil {
+			log.WithError(err).Error("Error marshaling payload.")
+			pd = []byte("{}")
+		}
+		writeJSONResponse(w, r, pd)
+	}
+}
+
+func handlePRTimelinePage(o options, cfg config.Getter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleSimpleTemplate(o, cfg, "pr-timeline.html", r.URL.Query().Get("pr"))(w, r)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/tide/history"
+)
+
+func TestParseTimelinePR(t *testing.T) {
+	testCases := []struct {
+		pr          string
+		org         string
+		repo        string
+		number      int
+		expectedErr bool
+	}{
+		{pr: "kubernetes/test-infra#123", org: "kubernetes", repo: "test-infra", number: 123},
+		{pr: "kubernetes/test-infra", expectedErr: true},
+		{pr: "test-infra#123", expectedErr: true},
+		{pr: "kubernetes/test-infra#abc", expectedErr: true},
+		{pr: "", expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.pr, func(t *testing.T) {
+			org, repo, number, err := parseTimelinePR(tc.pr)
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("expected error: %t, got: %v", tc.expectedErr, err)
+			}
+			if org != tc.org || repo != tc.repo || number != tc.number {
+				t.Errorf("expected %s/%s#%d, got %s/%s#%d", tc.org, tc.repo, tc.number, org, repo, number)
+			}
+		})
+	}
+}
+
+func TestGetPRTimeline(t *testing.T) {
+	start := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	pullRefs := func(numbers ...int) *prowapi.Refs {
+		refs := &prowapi.Refs{Org: "org", Repo: "repo"}
+		for _, n := range numbers {
+			refs.Pulls = append(refs.Pulls, prowapi.Pull{Number: n})
+		}
+		return refs
+	}
+	completed := metav1.NewTime(at(10))
+	pjs := []prowapi.ProwJob{
+		{
+			Spec:   prowapi.ProwJobSpec{Type: prowapi.PresubmitJob, Job: "unit", Refs: pullRefs(1)},
+			Status: prowapi.ProwJobStatus{StartTime: metav1.NewTime(at(1)), CompletionTime: &completed, State: prowapi.SuccessState, URL: "unit-url"},
+		},
+		{
+			Spec:   prowapi.ProwJobSpec{Type: prowapi.BatchJob, Job: "e2e", Refs: pullRefs(1, 2)},
+			Status: prowapi.ProwJobStatus{StartTime: metav1.NewTime(at(20)), State: prowapi.PendingState},
+		},
+		{
+			Spec:   prowapi.ProwJobSpec{Type: prowapi.PresubmitJob, Job: "unit", Refs: pullRefs(2)},
+			Status: prowapi.ProwJobStatus{StartTime: metav1.NewTime(at(2)), State: prowapi.PendingState},
+		},
+	}
+
+	ghc := fakegithub.NewFakeClient()
+	ghc.IssueEvents = map[int][]github.ListedIssueEvent{
+		1: {
+			{Event: github.IssueActionLabeled, Actor: github.User{Login: "k8s-ci-robot"}, Label: github.Label{Name: "lgtm"}, CreatedAt: at(5)},
+			{Event: github.IssueActionLabeled, Actor: github.User{Login: "someone"}, Label: github.Label{Name: "kind/bug"}, CreatedAt: at(6)},
+			{Event: github.IssueActionUnlabeled, Actor: github.User{Login: "k8s-ci-robot"}, Label: github.Label{Name: "do-not-merge/hold"}, CreatedAt: at(7)},
+		},
+	}
+	ghc.IssueComments = map[int][]github.IssueComment{
+		1: {
+			{Body: "/lgtm", User: github.User{Login: "someone"}, CreatedAt: at(4)},
+			{Body: "LGTM label has been added.", User: github.User{Login: "k8s-ci-robot"}, HTMLURL: "comment-url", CreatedAt: at(5)},
+		},
+	}
+
+	hist := map[string][]history.Record{
+		"org/repo:master": {
+			{Time: at(30), Action: "MERGE_BATCH", Target: []prowapi.Pull{{Number: 1}, {Number: 2}}},
+			{Time: at(15), Action: "TRIGGER_BATCH", Target: []prowapi.Pull{{Number: 1}, {Number: 2}}, Err: "failed"},
+			{Time: at(14), Action: "TRIGGER", Target: []prowapi.Pull{{Number: 2}}},
+		},
+		"org/other:master": {
+			{Time: at(16), Action: "MERGE", Target: []prowapi.Pull{{Number: 1}}},
+		},
+	}
+
+	expected := prTimeline{
+		Org:    "org",
+		Repo:   "repo",
+		Number: 1,
+		Link:   "https://github.com/org/repo/pull/1",
+		Events: []timelineEvent{
+			{Time: at(1), Source: timelineSourceProwJob, Summary: "Started job unit", State: "triggered", Link: "unit-url"},
+			{Time: at(5), Source: timelineSourceLabel, Summary: "Added label lgtm", Actor: "k8s-ci-robot"},
+			{Time: at(5), Source: timelineSourceComment, Summary: "LGTM label has been added.", Actor: "k8s-ci-robot", Link: "comment-url"},
+			{Time: at(7), Source: timelineSourceLabel, Summary: "Removed label do-not-merge/hold", Actor: "k8s-ci-robot"},
+			{Time: at(10), Source: timelineSourceProwJob, Summary: "Finished job unit", State: "success", Link: "unit-url"},
+			{Time: at(15), Source: timelineSourceTide, Summary: "TRIGGER_BATCH with 2 PRs in pool org/repo:master: failed", State: "failure"},
+			{Time: at(20), Source: timelineSourceProwJob, Summary: "Started batch job e2e", State: "triggered"},
+			{Time: at(30), Source: timelineSourceTide, Summary: "MERGE_BATCH with 2 PRs in pool org/repo:master", State: "success"},
+		},
+	}
+	timeline, err := getPRTimeline(pjs, ghc, hist, "github.com", "org", "repo", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, timeline); diff != "" {
+		t.Errorf("timeline differs from expected: %s", diff)
+	}
+
+	// Without GitHub client and Tide only the ProwJobs are left.
+	timeline, err = getPRTimeline(pjs, nil, nil, "github.com", "org", "repo", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := len(timeline.Events); n != 2 {
+		t.Errorf("expected 2 events, got %d: %v", n, timeline.Events)
+	}
+}
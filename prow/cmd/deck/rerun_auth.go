@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/csrf"
+	"sigs.k8s.io/yaml"
+)
+
+// rerunToken is an API token allowing programmatic reruns, e.g. by bots. The
+// requests authenticate with an `Authorization: Bearer <token>` header and are
+// authorized by the groups of the token.
+type rerunToken struct {
+	Name   string   `json:"name"`
+	Token  string   `json:"token"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+func loadRerunTokens(path string) ([]rerunToken, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rerun tokens: %w", err)
+	}
+	var tokens []rerunToken
+	if err := yaml.Unmarshal(b, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rerun tokens: %w", err)
+	}
+	names := map[string]bool{}
+	for _, token := range tokens {
+		if token.Name == "" || token.Token == "" {
+			return nil, errors.New("rerun tokens must have a name and a token")
+		}
+		if names[token.Name] {
+			return nil, fmt.Errorf("rerun token name %q is not unique", token.Name)
+		}
+		names[token.Name] = true
+	}
+	return tokens, nil
+}
+
+// rerunIdentity is who requested a rerun.
+type rerunIdentity struct {
+	// Method is how the requester was authenticated, i.e. github, token,
+	// proxy or anonymous if anyone may rerun the job.
+	Method string
+	Name   string
+	Groups []string
+}
+
+func (i rerunIdentity) String() string {
+	if i.Name == "" {
+		return i.Method
+	}
+	return i.Method + ":" + i.Name
+}
+
+// rerunAuthenticator authenticates the rerun requests which don't use the
+// GitHub login of the user.
+type rerunAuthenticator struct {
+	tokens []rerunToken
+	// The headers an authenticating proxy in front of Deck, e.g. oauth2-proxy,
+	// sets to the user and their comma-separated groups.
+	proxyUserHeader   string
+	proxyGroupsHeader string
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(header, prefix), true
+}
+
+// identify returns the identity of the requester if the request has a bearer
+// token or was authenticated by a proxy, and nil otherwise. Requests with an
+// unknown token are rejected rather than authenticated otherwise.
+func (a *rerunAuthenticator) identify(r *http.Request) (*rerunIdentity, error) {
+	if a == nil {
+		return nil, nil
+	}
+	if token, ok := bearerToken(r); ok {
+		for _, known := range a.tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(known.Token)) == 1 {
+				return &rerunIdentity{Method: "token", Name: known.Name, Groups: known.Groups}, nil
+			}
+		}
+		return nil, errors.New("unknown API token")
+	}
+	if a.proxyUserHeader == "" || r.Header.Get(a.proxyUserHeader) == "" {
+		return nil, nil
+	}
+	identity := &rerunIdentity{Method: "proxy", Name: r.Header.Get(a.proxyUserHeader)}
+	if a.proxyGroupsHeader != "" {
+		for _, group := range strings.Split(r.Header.Get(a.proxyGroupsHeader), ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				identity.Groups = append(identity.Groups, group)
+			}
+		}
+	}
+	return identity, nil
+}
+
+// skipCSRFForBearerTokens exempts the requests authenticated with bearer
+// tokens from the CSRF protection, as browsers never send them on their own.
+// The handlers must not fall back to the cookies of requests with tokens.
+func skipCSRFForBearerTokens(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := bearerToken(r); ok {
+			r = csrf.UnsafeSkipCheck(r)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadRerunTokens(t *testing.T) {
+	testCases := []struct {
+		name        string
+		content     string
+		expected    []rerunToken
+		expectedErr bool
+	}{
+		{
+			name: "valid tokens",
+			content: `- name: release-bot
+  token: secret
+  groups:
+  - release-managers
+- name: test-bot
+  token: other-secret
+`,
+			expected: []rerunToken{
+				{Name: "release-bot", Token: "secret", Groups: []string{"release-managers"}},
+				{Name: "test-bot", Token: "other-secret"},
+			},
+		},
+		{
+			name:        "token without name",
+			content:     "- token: secret\n",
+			expectedErr: true,
+		},
+		{
+			name:        "duplicate names",
+			content:     "- name: bot\n  token: a\n- name: bot\n  token: b\n",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tokens.yaml")
+			if err := ioutil.WriteFile(path, []byte(tc.content), 0600); err != nil {
+				t.Fatalf("failed to write tokens: %v", err)
+			}
+			tokens, err := loadRerunTokens(path)
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("expected error: %t, got: %v", tc.expectedErr, err)
+			}
+			if diff := cmp.Diff(tc.expected, tokens); diff != "" {
+				t.Errorf("tokens differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestRerunAuthenticatorIdentify(t *testing.T) {
+	auth := &rerunAuthenticator{
+		tokens:            []rerunToken{{Name: "bot", Token: "secret", Groups: []string{"bots"}}},
+		proxyUserHeader:   "X-Forwarded-User",
+		proxyGroupsHeader: "X-Forwarded-Groups",
+	}
+	testCases := []struct {
+		name        string
+		headers     map[string]string
+		expected    *rerunIdentity
+		expectedErr bool
+	}{
+		{
+			name: "no headers",
+		},
+		{
+			name:     "token",
+			headers:  map[string]string{"Authorization": "Bearer secret"},
+			expected: &rerunIdentity{Method: "token", Name: "bot", Groups: []string{"bots"}},
+		},
+		{
+			name:        "unknown token",
+			headers:     map[string]string{"Authorization": "Bearer nope", "X-Forwarded-User": "alice"},
+			expectedErr: true,
+		},
+		{
+			name:     "proxy",
+			headers:  map[string]string{"X-Forwarded-User": "alice", "X-Forwarded-Groups": "devs, ,ops"},
+			expected: &rerunIdentity{Method: "proxy", Name: "alice", Groups: []string{"devs", "ops"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, "/rerun", nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			identity, err := auth.identify(r)
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("expected error: %t, got: %v", tc.expectedErr, err)
+			}
+			if diff := cmp.Diff(tc.expected, identity); diff != "" {
+				t.Errorf("identity differs from expected: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/io"
+)
+
+const (
+	// maxSavedViewsPerUser is how many views each user can save.
+	maxSavedViewsPerUser = 50
+	// maxSavedViewSize is how many bytes the name and the filters of a view
+	// can have in total.
+	maxSavedViewSize = 4 * 1024
+)
+
+// savedViewFilters are the job list filters a saved view can set.
+var savedViewFilters = sets.NewString("type", "repo", "pull", "author", "job", "state", "cluster", "since")
+
+var errSavedViewNotFound = errors.New("saved view not found")
+
+// savedView is a named set of job list filters. Its ID only depends on its
+// owner and name, so that its URL stays the same when its filters change.
+type savedView struct {
+	ID      string
+	Name    string
+	Owner   string `json:",omitempty"`
+	Filters map[string]string
+}
+
+func savedViewID(owner, name string) string {
+	sum := sha256.Sum256([]byte(owner + "/" + name))
+	return hex.EncodeToString(sum[:8])
+}
+
+// query returns the query string selecting the filters of the view in the job
+// list.
+func (v savedView) query() string {
+	values := url.Values{}
+	for k, val := range v.Filters {
+		values.Set(k, val)
+	}
+	return values.Encode()
+}
+
+type savedViewOpener interface {
+	Reader(ctx context.Context, path string) (io.ReadCloser, error)
+	Writer(ctx context.Context, path string, opts ...io.WriterOptions) (io.WriteCloser, error)
+}
+
+// savedViewStore keeps the saved views in memory and persists all of them in a
+// single GCS, S3 or local file, which is overwritten on every change.
+type savedViewStore struct {
+	opener savedViewOpener
+	path   string
+
+	sync.Mutex
+	views map[string]savedView
+}
+
+func newSavedViewStore(opener savedViewOpener, path string) (*savedViewStore, error) {
+	s := &savedViewStore{
+		opener: opener,
+		path:   path,
+		views:  map[string]savedView{},
+	}
+	reader, err := opener.Reader(context.Background(), path)
+	if io.IsNotExist(err) { // No view was saved yet.
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer io.LogClose(reader)
+	raw, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	var views []savedView
+	if err := json.Unmarshal(raw, &views); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+	for _, v := range views {
+		s.views[v.ID] = v
+	}
+	return s, nil
+}
+
+func (s *savedViewStore) get(id string) (savedView, bool) {
+	s.Lock()
+	defer s.Unlock()
+	v, ok := s.views[id]
+	return v, ok
+}
+
+// list returns the views of an owner sorted by name.
+func (s *savedViewStore) list(owner string) []savedView {
+	s.Lock()
+	defer s.Unlock()
+	views := []savedView{}
+	for _, v := range s.views {
+		if v.Owner == owner {
+			views = append(views, v)
+		}
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views
+}
+
+// save creates the view of the owner with the name or replaces its filters.
+func (s *savedViewStore) save(owner, name string, filters map[string]string) (savedView, error) {
+	if name == "" {
+		return savedView{}, errors.New("the name of a view must not be empty")
+	}
+	size := len(name)
+	for k, val := range filters {
+		if !savedViewFilters.Has(k) {
+			return savedView{}, fmt.Errorf("unknown filter %q, must be one of %v", k, savedViewFilters.List())
+		}
+		size += len(k) + len(val)
+	}
+	if size > maxSavedViewSize {
+		return savedView{}, fmt.Errorf("the name and filters of a view must not exceed %d bytes", maxSavedViewSize)
+	}
+	v := savedView{ID: savedViewID(owner, name), Name: name, Owner: owner, Filters: filters}
+
+	s.Lock()
+	defer s.Unlock()
+	prev, existed := s.views[v.ID]
+	if !existed {
+		var count int
+		for _, other := range s.views {
+			if other.Owner == owner {
+				count++
+			}
+		}
+		if count >= maxSavedViewsPerUser {
+			return savedView{}, fmt.Errorf("no more than %d views can be saved, delete one first", maxSavedViewsPerUser)
+		}
+	}
+	s.views[v.ID] = v
+	if err := s.write(); err != nil {
+		if existed {
+			s.views[v.ID] = prev
+		} else {
+			delete(s.views, v.ID)
+		}
+		return savedView{}, err
+	}
+	return v, nil
+}
+
+// delete deletes the view if it belongs to the owner.
+func (s *savedViewStore) delete(owner, id string) error {
+	s.Lock()
+	defer s.Unlock()
+	v, ok := s.views[id]
+	if !ok || v.Owner != owner {
+		return errSavedViewNotFound
+	}
+	delete(s.views, id)
+	if err := s.write(); err != nil {
+		s.views[id] = v
+		return err
+	}
+	return nil
+}
+
+// write persists the views, the store must be locked.
+func (s *savedViewStore) write() error {
+	views := make([]savedView, 0, len(s.views))
+	for _, v := range s.views {
+		views = append(views, v)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].ID < views[j].ID })
+	b, err := json.Marshal(views)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	writer, err := s.opener.Writer(ctx, s.path)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	if _, err := writer.Write(b); err != nil {
+		io.LogClose(writer)
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+	return nil
+}
+
+type savedViewRequest struct {
+	Name    string
+	Filters map[string]string
+}
+
+// handleSavedViews serves the saved views of the job list. GET returns the
+// view with the id parameter or lists the views of the user, POST saves a view
+// and DELETE deletes the view with the id parameter. Views belong to the GitHub
+// login of the user, so they can only be saved and deleted when GitHub OAuth
+// is configured and login is set.
+func handleSavedViews(store *savedViewStore, login func(*http.Request) (string, error), log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		var owner string
+		if r.Method != http.MethodGet && login == nil {
+			http.Error(w, "Saving views requires GitHub OAuth", http.StatusForbidden)
+			return
+		}
+		if login != nil {
+			user, err := login(r)
+			if err != nil && r.Method != http.MethodGet {
+				http.Error(w, "Error retrieving GitHub login", http.StatusUnauthorized)
+				return
+			}
+			owner = user
+		}
+
+		id := r.URL.Query().Get("id")
+		switch r.Method {
+		case http.MethodGet:
+			if id == "" {
+				writeSavedViews(w, r, store.list(owner), log)
+				return
+			}
+			v, ok := store.get(id)
+			if !ok {
+				http.Error(w, errSavedViewNotFound.Error(), http.StatusNotFound)
+				return
+			}
+			writeSavedViews(w, r, v, log)
+		case http.MethodPost:
+			var req savedViewRequest
+			// The request is allowed some room for its JSON encoding.
+			body := http.MaxBytesReader(w, r.Body, 2*maxSavedViewSize)
+			if err := json.NewDecoder(body).Decode(&req); err != nil {
+				http.Error(w, fmt.Sprintf("invalid view: %v", err), http.StatusBadRequest)
+				return
+			}
+			v, err := store.save(owner, req.Name, req.Filters)
+			if err != nil {
+				log.WithError(err).WithField("user", owner).Info("Failed to save view.")
+				http.Error(w, fmt.Sprintf("failed to save view: %v", err), http.StatusBadRequest)
+				return
+			}
+			writeSavedViews(w, r, v, log)
+		case http.MethodDelete:
+			if err := store.delete(owner, id); err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, errSavedViewNotFound) {
+					status = http.StatusNotFound
+				} else {
+					log.WithError(err).WithField("user", owner).Error("Failed to delete view.")
+				}
+				http.Error(w, fmt.Sprintf("failed to delete view: %v", err), status)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, fmt.Sprintf("bad verb %v", r.Method), http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func writeSavedViews(w http.ResponseWriter, r *http.Request, data interface{}, log *logrus.Entry) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		log.WithError(err).Error("Error marshaling saved views.")
+		http.Error(w, "failed to marshal saved views", http.StatusInternalServerError)
+		return
+	}
+	writeJSONResponse(w, r, b)
+}
+
+// handleSavedViewRedirect redirects the stable URL of a saved view to the job
+// list with the filters of the view.
+func handleSavedViewRedirect(store *savedViewStore, w http.ResponseWriter, r *http.Request, id string) {
+	v, ok := store.get(id)
+	if !ok {
+		http.Error(w, errSavedViewNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	http.Redirect(w, r, "/?"+v.query(), http.StatusFound)
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/io"
+)
+
+type fakeSavedViewOpener struct {
+	content []byte
+	err     error
+}
+
+func (o *fakeSavedViewOpener) Reader(ctx context.Context, path string) (io.ReadCloser, error) {
+	if o.content == nil {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(bytes.NewReader(o.content)), nil
+}
+
+func (o *fakeSavedViewOpener) Writer(ctx context.Context, path string, opts ...io.WriterOptions) (io.WriteCloser, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	return &fakeSavedViewWriter{opener: o}, nil
+}
+
+type fakeSavedViewWriter struct {
+	opener *fakeSavedViewOpener
+	buf    bytes.Buffer
+}
+
+func (w *fakeSavedViewWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *fakeSavedViewWriter) Close() error {
+	w.opener.content = w.buf.Bytes()
+	return nil
+}
+
+func TestSavedViewStore(t *testing.T) {
+	opener := &fakeSavedViewOpener{}
+	store, err := newSavedViewStore(opener, "gs://bucket/views.json")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	flaky, err := store.save("alice", "flaky", map[string]string{"repo": "org/repo", "state": "failure"})
+	if err != nil {
+		t.Fatalf("failed to save view: %v", err)
+	}
+	if _, err := store.save("bob", "mine", map[string]string{"job": "unit"}); err != nil {
+		t.Fatalf("failed to save view: %v", err)
+	}
+	if _, err := store.save("alice", "bad", map[string]string{"color": "blue"}); err == nil {
+		t.Error("expected an error saving a view with an unknown filter")
+	}
+	if _, err := store.save("alice", "", nil); err == nil {
+		t.Error("expected an error saving a view without name")
+	}
+
+	// Updating the filters of a view keeps its ID.
+	updated, err := store.save("alice", "flaky", map[string]string{"repo": "org/repo", "since": "24h"})
+	if err != nil {
+		t.Fatalf("failed to save view: %v", err)
+	}
+	if updated.ID != flaky.ID {
+		t.Errorf("expected ID %s to be kept, got %s", flaky.ID, updated.ID)
+	}
+	if expected := "repo=org%2Frepo&since=24h"; updated.query() != expected {
+		t.Errorf("expected query %q, got %q", expected, updated.query())
+	}
+
+	// The views are loaded again from the file.
+	reloaded, err := newSavedViewStore(opener, "gs://bucket/views.json")
+	if err != nil {
+		t.Fatalf("failed to reload store: %v", err)
+	}
+	if diff := cmp.Diff([]savedView{updated}, reloaded.list("alice")); diff != "" {
+		t.Errorf("views differ from expected: %s", diff)
+	}
+
+	if err := reloaded.delete("bob", updated.ID); !errors.Is(err, errSavedViewNotFound) {
+		t.Errorf("expected bob to not find the view of alice, got %v", err)
+	}
+	if err := reloaded.delete("alice", updated.ID); err != nil {
+		t.Errorf("failed to delete view: %v", err)
+	}
+	if views := reloaded.list("alice"); len(views) != 0 {
+		t.Errorf("expected no views left, got %v", views)
+	}
+
+	// A failed write leaves the views unchanged.
+	opener.err = errors.New("injected error")
+	if _, err := reloaded.save("alice", "new", nil); err == nil {
+		t.Error("expected an error when the views can't be written")
+	}
+	if views := reloaded.list("alice"); len(views) != 0 {
+		t.Errorf("expected no views after a failed write, got %v", views)
+	}
+}
+
+func TestHandleSavedViews(t *testing.T) {
+	store, err := newSavedViewStore(&fakeSavedViewOpener{}, "views.json")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	handler := handleSavedViews(store, func(*http.Request) (string, error) { return "alice", nil }, logrus.WithField("handler", "/saved-views"))
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodPost, "/saved-views", strings.NewReader(`{"Name": "unit", "Filters": {"job": "unit"}}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var saved savedView
+	if err := json.Unmarshal(rr.Body.Bytes(), &saved); err != nil {
+		t.Fatalf("failed to unmarshal view: %v", err)
+	}
+
+	rr = httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodGet, "/saved-views", nil))
+	var views []savedView
+	if err := json.Unmarshal(rr.Body.Bytes(), &views); err != nil {
+		t.Fatalf("failed to unmarshal views: %v", err)
+	}
+	if diff := cmp.Diff([]savedView{saved}, views); diff != "" {
+		t.Errorf("views differ from expected: %s", diff)
+	}
+
+	rr = httptest.NewRecorder()
+	handleSavedViewRedirect(store, rr, httptest.NewRequest(http.MethodGet, "/?saved-view="+saved.ID, nil), saved.ID)
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != "/?job=unit" {
+		t.Errorf("expected a redirect to /?job=unit, got %d to %q", rr.Code, rr.Header().Get("Location"))
+	}
+
+	rr = httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodPost, "/saved-views", strings.NewReader(`{"Name": "bad", "Filters": {"color": "blue"}}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an unknown filter, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodDelete, "/saved-views?id="+saved.ID, nil))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodGet, "/saved-views?id="+saved.ID, nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for a deleted view, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestHandleSavedViewsRequiresLogin(t *testing.T) {
+	store, err := newSavedViewStore(&fakeSavedViewOpener{}, "views.json")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	testCases := []struct {
+		name         string
+		login        func(*http.Request) (string, error)
+		expectedCode int
+	}{
+		{
+			name:         "views can't be saved without OAuth",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "views can't be saved by users who are not logged in",
+			login:        func(*http.Request) (string, error) { return "", errors.New("not logged in") },
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "views are saved by users who are logged in",
+			login:        func(*http.Request) (string, error) { return "alice", nil },
+			expectedCode: http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handleSavedViews(store, tc.login, logrus.WithField("handler", "/saved-views"))(rr, httptest.NewRequest(http.MethodPost, "/saved-views", strings.NewReader(`{"Name": "unit", "Filters": {"job": "unit"}}`)))
+			if rr.Code != tc.expectedCode {
+				t.Errorf("expected status %d, got %d: %s", tc.expectedCode, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestSavedViewStoreLimits(t *testing.T) {
+	store, err := newSavedViewStore(&fakeSavedViewOpener{}, "views.json")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	if _, err := store.save("alice", "big", map[string]string{"job": strings.Repeat("a", maxSavedViewSize)}); err == nil {
+		t.Error("expected an error for a view over the size limit")
+	}
+	for i := 0; i < maxSavedViewsPerUser; i++ {
+		if _, err := store.save("alice", fmt.Sprintf("view-%d", i), map[string]string{"job": "unit"}); err != nil {
+			t.Fatalf("failed to save view %d: %v", i, err)
+		}
+	}
+	if _, err := store.save("alice", "one-too-many", nil); err == nil {
+		t.Error("expected an error for a view over the count limit")
+	}
+	if _, err := store.save("alice", "view-0", map[string]string{"job": "e2e"}); err != nil {
+		t.Errorf("expected an existing view to be updated at the count limit, got %v", err)
+	}
+	if _, err := store.save("bob", "mine", nil); err != nil {
+		t.Errorf("expected the limit to only apply to alice, got %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+import {ProwJobType} from "./prow";
+
+// FlakeReport mirrors the flakeReport struct defined in prow/cmd/deck/flakes.go.
+export interface FlakeReport {
+  Window: string;
+  Jobs: JobFlakiness[];
+}
+
+export interface JobFlakiness {
+  Job: string;
+  Type: ProwJobType;
+  Repo?: string;
+  Runs: number;
+  Passes: number;
+  Failures: number;
+  Revisions: number;
+  Flakes: number;
+  PassRate: number;
+  FlakeRate: number;
+}
@@ -0,0 +1,21 @@
+import {ProwJobState} from "./prow";
+
+// PRTimeline mirrors the prTimeline struct defined in prow/cmd/deck/pr_timeline.go.
+export interface PRTimeline {
+  Org: string;
+  Repo: string;
+  Number: number;
+  Link: string;
+  Events: TimelineEvent[] | null;
+}
+
+export type TimelineSource = "prowjob" | "label" | "comment" | "tide";
+
+export interface TimelineEvent {
+  Time: string;
+  Source: TimelineSource;
+  Summary: string;
+  Actor?: string;
+  State?: ProwJobState;
+  Link?: string;
+}
@@ -0,0 +1,7 @@
+// SavedView mirrors the savedView struct defined in prow/cmd/deck/saved_views.go.
+export interface SavedView {
+  ID: string;
+  Name: string;
+  Owner?: string;
+  Filters: {[key: string]: string};
+}
@@ -31,7 +31,7 @@ export interface PullRequest extends BasePullRequest {
   };
 }
 
-export type Action = "WAIT" | "TRIGGER" | "TRIGGER_BATCH" | "MERGE" | "MERGE_BATCH" | "BLOCKED";
+export type Action = "WAIT" | "TRIGGER" | "TRIGGER_BATCH" | "MERGE" | "MERGE_BATCH" | "BLOCKED" | "TRIGGER_QUEUE" | "FROZEN" | "UPDATE_BRANCH";
 
 export interface Blocker {
   Number: number;
@@ -39,6 +39,22 @@ export interface Blocker {
   URL: string;
 }
 
+export interface PullRequestBlockers {
+  Number: number;
+  Title: string;
+
+  Unmergeable: string;
+  MissingLabels?: string[];
+  UnwantedLabels?: string[];
+  MissingMilestone: string;
+  ReviewRequired: boolean;
+  FailedContexts?: string[];
+  PendingContexts?: string[];
+  MissingContexts?: string[];
+  MergeFreeze: string;
+  BlockingIssues?: number[];
+}
+
 export interface TidePool {
   Org: string;
   Repo: string;
@@ -50,6 +66,13 @@ export interface TidePool {
 
   BatchPending: PullRequest[];
 
+  QueuedPRs?: PullRequest[];
+  EvictedPRs?: PullRequest[];
+
+  MergeFreeze?: string;
+
+  BlockedPRs?: PullRequestBlockers[];
+
   Action: Action;
   Target: PullRequest[];
   Blockers: Blocker[];
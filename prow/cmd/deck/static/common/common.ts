@@ -271,7 +271,7 @@ export function createRerunProwJobIcon(modal: HTMLElement, rerunElement: HTMLEle
   return i;
 }
 
-function copyToClipboardWithToast(text: string): void {
+export function copyToClipboardWithToast(text: string): void {
   copyToClipboard(text);
 
   const toast = document.getElementById("toast") as SnackbarElement<HTMLDivElement>;
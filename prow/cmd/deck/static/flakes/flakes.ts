@@ -0,0 +1,133 @@
+import {FlakeReport, JobFlakiness} from "../api/flakes";
+import {cell} from "../common/common";
+import {getParameterByName} from "../common/urls";
+
+// The number of the flakiest jobs highlighted per repo.
+const topFlakesPerRepo = 3;
+
+type SortKey = "Job" | "Repo" | "Runs" | "PassRate" | "Flakes" | "FlakeRate";
+
+let report: FlakeReport = {Window: "", Jobs: []};
+let sortKey: SortKey = "FlakeRate";
+let sortAscending = false;
+
+window.onload = (): void => {
+  for (const id of ["window", "type"]) {
+    const sel = document.getElementById(id) as HTMLSelectElement;
+    const param = getParameterByName(id);
+    if (param !== undefined) {
+      sel.value = param;
+    }
+    sel.onchange = () => {
+      load();
+    };
+  }
+  (document.getElementById("repo") as HTMLSelectElement).onchange = () => {
+    redraw();
+  };
+  document.querySelectorAll<HTMLElement>("#flakes th[data-sort]").forEach((th) => {
+    th.onclick = () => {
+      const key = th.dataset.sort as SortKey;
+      sortAscending = key === sortKey ? !sortAscending : key === "Job" || key === "Repo";
+      sortKey = key;
+      redraw();
+    };
+  });
+  load();
+};
+
+async function load(): Promise<void> {
+  const windowSel = (document.getElementById("window") as HTMLSelectElement).value;
+  const typeSel = (document.getElementById("type") as HTMLSelectElement).value;
+  const params = [`window=${encodeURIComponent(windowSel)}`];
+  if (typeSel) {
+    params.push(`type=${encodeURIComponent(typeSel)}`);
+  }
+  if (window.history && window.history.replaceState !== undefined) {
+    history.replaceState(null, "", "/flakes?" + params.join("&"));
+  }
+  const result = await fetch("/api/flakes?" + params.join("&"));
+  if (!result.ok) {
+    report = {Window: windowSel, Jobs: []};
+  } else {
+    report = await result.json() as FlakeReport;
+  }
+  redrawRepos();
+  redraw();
+}
+
+function redrawRepos(): void {
+  const sel = document.getElementById("repo") as HTMLSelectElement;
+  const selected = sel.value || getParameterByName("repo") || "";
+  while (sel.length > 1) {
+    sel.removeChild(sel.lastChild!);
+  }
+  const repos = new Set(report.Jobs.map((j) => j.Repo || ""));
+  for (const repo of Array.from(repos).filter((r) => r).sort()) {
+    const o = document.createElement("option");
+    o.value = repo;
+    o.text = repo;
+    o.selected = repo === selected;
+    sel.appendChild(o);
+  }
+}
+
+// topFlakes returns the flakiest jobs of each repo.
+function topFlakes(jobs: JobFlakiness[]): Set<JobFlakiness> {
+  const byRepo = new Map<string, JobFlakiness[]>();
+  for (const job of jobs) {
+    if (job.Flakes === 0) {
+      continue;
+    }
+    const repo = job.Repo || "";
+    byRepo.set(repo, [...(byRepo.get(repo) || []), job]);
+  }
+  const top = new Set<JobFlakiness>();
+  byRepo.forEach((repoJobs) => {
+    repoJobs
+      .sort((a, b) => b.FlakeRate - a.FlakeRate || b.Flakes - a.Flakes)
+      .slice(0, topFlakesPerRepo)
+      .forEach((j) => top.add(j));
+  });
+  return top;
+}
+
+function compare(a: JobFlakiness, b: JobFlakiness): number {
+  let order: number;
+  if (sortKey === "Job" || sortKey === "Repo") {
+    order = (a[sortKey] || "").localeCompare(b[sortKey] || "");
+  } else {
+    order = a[sortKey] - b[sortKey];
+  }
+  return sortAscending ? order : -order;
+}
+
+function percent(rate: number): string {
+  return `${(rate * 100).toFixed(1)}%`;
+}
+
+function redraw(): void {
+  const repoSel = (document.getElementById("repo") as HTMLSelectElement).value;
+  const jobs = report.Jobs.filter((j) => !repoSel || j.Repo === repoSel);
+  const top = topFlakes(jobs);
+  jobs.sort(compare);
+
+  const tbody = document.getElementById("flakes")!.getElementsByTagName("tbody")[0];
+  while (tbody.firstChild) {
+    tbody.removeChild(tbody.firstChild);
+  }
+  for (const job of jobs) {
+    const r = document.createElement("tr");
+    if (top.has(job)) {
+      r.classList.add("top-flake");
+    }
+    r.appendChild(cell.text(job.Job));
+    r.appendChild(cell.text(job.Repo || ""));
+    r.appendChild(cell.text(String(job.Runs)));
+    r.appendChild(cell.text(percent(job.PassRate)));
+    r.appendChild(cell.text(`${job.Flakes}/${job.Revisions}`));
+    r.appendChild(cell.text(percent(job.FlakeRate)));
+    tbody.appendChild(r);
+  }
+  document.getElementById("job-count")!.textContent = `${jobs.length} jobs over ${report.Window}`;
+}
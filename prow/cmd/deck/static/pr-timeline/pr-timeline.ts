@@ -0,0 +1,73 @@
+import moment from "moment";
+import {PRTimeline, TimelineEvent, TimelineSource} from "../api/pr-timeline";
+import {cell} from "../common/common";
+
+declare const prTimeline: PRTimeline;
+
+window.onload = (): void => {
+  const sources = document.getElementById("source")! as HTMLSelectElement;
+  sources.onchange = () => {
+    redraw();
+  };
+  redraw();
+};
+
+function redraw(): void {
+  const title = document.getElementById("timeline-title")!;
+  const events = document.getElementById("events")!.getElementsByTagName("tbody")[0];
+  while (events.firstChild) {
+    events.removeChild(events.firstChild);
+  }
+  if (typeof prTimeline === 'undefined') {
+    title.textContent = "No timeline found, the pr parameter must look like org/repo#123.";
+    return;
+  }
+
+  const pr = `${prTimeline.Org}/${prTimeline.Repo}#${prTimeline.Number}`;
+  while (title.firstChild) {
+    title.removeChild(title.firstChild);
+  }
+  const a = document.createElement("a");
+  a.href = prTimeline.Link;
+  a.textContent = pr;
+  title.appendChild(a);
+
+  const sourceSel = (document.getElementById("source") as HTMLSelectElement).value;
+  // Show the newest events first.
+  const filtered = (prTimeline.Events || [])
+    .filter((e) => sourceSel === "" || e.Source === sourceSel)
+    .reverse();
+  for (const e of filtered) {
+    events.appendChild(eventRow(e));
+  }
+  document.getElementById("event-count")!.textContent = `Showing ${filtered.length} events`;
+}
+
+function eventRow(e: TimelineEvent): HTMLTableRowElement {
+  const r = document.createElement("tr");
+  r.appendChild(cell.state(e.State || ""));
+  r.appendChild(cell.time(nextID(), moment(e.Time)));
+  r.appendChild(cell.text(sourceName(e.Source)));
+  r.appendChild(e.Link ? cell.link(e.Summary, e.Link) : cell.text(e.Summary));
+  r.appendChild(cell.text(e.Actor || ""));
+  return r;
+}
+
+function sourceName(source: TimelineSource): string {
+  switch (source) {
+    case "prowjob":
+      return "ProwJob";
+    case "label":
+      return "Label";
+    case "comment":
+      return "Comment";
+    case "tide":
+      return "Tide";
+  }
+}
+
+let idCounter = 0;
+function nextID(): string {
+  idCounter++;
+  return "timelineID-" + String(idCounter);
+}
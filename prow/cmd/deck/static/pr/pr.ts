@@ -213,6 +213,23 @@ function createMergeBlockingIssueAlert(tidePool: TidePool, blockers: Blocker[]):
     return alert;
 }
 
+/**
+ * Creates an alert for merge freezes on tide.
+ */
+function createMergeFreezeAlert(tidePool: TidePool): HTMLElement {
+    const alert = document.createElement("div");
+    alert.classList.add("alert");
+    alert.textContent = `Currently Prow is not merging PRs to ${tidePool.Org}/${tidePool.Repo} on branch ${tidePool.Branch}: ${tidePool.MergeFreeze}.`;
+    const closeButton = document.createElement("span");
+    closeButton.textContent = "×";
+    closeButton.classList.add("closebutton");
+    closeButton.addEventListener("click", () => {
+        alert.classList.add("hidden");
+    });
+    alert.appendChild(closeButton);
+    return alert;
+}
+
 /**
  * Displays any status alerts, e.g: tide pool blocking issues.
  */
@@ -224,6 +241,9 @@ function showAlerts(): void {
         if (blockers.length > 0) {
             alertContainer.appendChild(createMergeBlockingIssueAlert(pool, blockers));
         }
+        if (pool.MergeFreeze) {
+            alertContainer.appendChild(createMergeFreezeAlert(pool));
+        }
     }
 }
 
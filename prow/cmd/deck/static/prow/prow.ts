@@ -4,11 +4,13 @@ import {cell, createRerunProwJobIcon, formatDuration, icon} from "../common/comm
 import {getParameterByName} from "../common/urls";
 import {FuzzySearch} from './fuzzy-search';
 import {JobHistogram, JobSample} from './histogram';
+import {initSavedViews} from './saved-views';
 
 declare const allBuilds: ProwJobList;
 declare const spyglass: boolean;
 declare const rerunCreatesJob: boolean;
 declare const csrfToken: string;
+declare const savedViewsEnabled: boolean;
 
 function genShortRefKey(baseRef: string, pulls: Pull[] = []) {
     return [baseRef, ...pulls.map((p) => p.number)].filter((n) => n).join(",");
@@ -268,6 +270,7 @@ window.onload = (): void => {
             "job-list",
             Object.keys(optsPopped.jobs).sort());
         redrawOptions(fzPopped, optsPopped);
+        selectSince();
         redraw(fzPopped, false);
     });
     // set dropdown based on options from query string
@@ -278,9 +281,35 @@ window.onload = (): void => {
         "job-list",
         Object.keys(opts.jobs).sort());
     redrawOptions(fz, opts);
+    selectSince();
     redraw(fz);
+    if (savedViewsEnabled) {
+        initSavedViews();
+    }
 };
 
+// selectSince selects the time range of the query string.
+function selectSince(): void {
+    const sel = document.getElementById("since") as HTMLSelectElement;
+    const param = getParameterByName("since");
+    sel.selectedIndex = 0;
+    for (let i = 1; i < sel.options.length; i++) {
+        if (sel.options[i].value === param) {
+            sel.selectedIndex = i;
+        }
+    }
+}
+
+// sinceSeconds returns the length of a time range like 6h in seconds.
+function sinceSeconds(since: string): number {
+    const match = /^(\d+)([mhd])$/.exec(since);
+    if (!match) {
+        return Infinity;
+    }
+    const unit: {[key: string]: number} = {d: 86400, h: 3600, m: 60};
+    return Number(match[1]) * unit[match[2]];
+}
+
 function displayFuzzySearchResult(el: HTMLElement, inputContainer: ClientRect | DOMRect): void {
     el.classList.add("active-fuzzy-search");
     el.style.top = inputContainer.height - 1 + "px";
@@ -475,6 +504,11 @@ function redraw(fz: FuzzySearch, pushState: boolean = true): void {
     const jobSel = getSelectionFuzzySearch("job", "job-input");
     const stateSel = getSelection("state");
     const clusterSel = getSelection("cluster");
+    const sinceElem = document.getElementById("since") as HTMLSelectElement;
+    const sinceSel = sinceElem.selectedIndex === 0 ? "" : sinceElem.value;
+    if (sinceSel !== "") {
+        args.push(`since=${encodeURIComponent(sinceSel)}`);
+    }
 
     if (pushState && window.history && window.history.pushState !== undefined) {
         if (args.length > 0) {
@@ -539,6 +573,9 @@ function redraw(fz: FuzzySearch, pushState: boolean = true): void {
         if (!equalSelected(clusterSel, cluster)) {
             continue;
         }
+        if (sinceSel && now - Date.parse(startTime) / 1000 > sinceSeconds(sinceSel)) {
+            continue;
+        }
         if (!jobSel.test(job)) {
             continue;
         }
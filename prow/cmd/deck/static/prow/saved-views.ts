@@ -0,0 +1,122 @@
+import {SavedView} from "../api/saved-views";
+import {copyToClipboardWithToast} from "../common/common";
+import {parseQuery, relativeURL} from "../common/urls";
+
+declare const csrfToken: string;
+
+// The job list filters a saved view can set, see savedViewFilters in
+// prow/cmd/deck/saved_views.go.
+const viewFilters = ["type", "repo", "pull", "author", "job", "state", "cluster", "since"];
+
+function savedViewURL(view: SavedView): string {
+    return `${window.location.origin}/?saved-view=${encodeURIComponent(view.ID)}`;
+}
+
+function currentFilters(): {[key: string]: string} {
+    const filters: {[key: string]: string} = {};
+    const query = parseQuery(window.location.search.substring(1));
+    for (const name of viewFilters) {
+        const value = query[name];
+        if (value) {
+            filters[name] = value;
+        }
+    }
+    return filters;
+}
+
+function handleUnauthorized(result: Response): boolean {
+    if (result.status !== 401) {
+        return false;
+    }
+    window.location.href = window.location.origin + `/github-login?dest=${relativeURL()}`;
+    return true;
+}
+
+async function loadSavedViews(): Promise<SavedView[]> {
+    const result = await fetch("/saved-views");
+    if (!result.ok) {
+        return [];
+    }
+    return await result.json() as SavedView[];
+}
+
+function redrawSavedViews(views: SavedView[], selectedID: string = ""): void {
+    const sel = document.getElementById("saved-view") as HTMLSelectElement;
+    while (sel.length > 1) {
+        sel.removeChild(sel.lastChild!);
+    }
+    for (const view of views) {
+        const o = document.createElement("option");
+        o.value = view.ID;
+        o.text = view.Name;
+        o.selected = view.ID === selectedID;
+        sel.appendChild(o);
+    }
+}
+
+// initSavedViews shows the saved views of the user, which select their filters
+// in the job list, and lets the user save the current filters as a view.
+export async function initSavedViews(): Promise<void> {
+    document.getElementById("saved-views-box")!.classList.remove("hidden");
+    let views = await loadSavedViews();
+    redrawSavedViews(views);
+
+    const sel = document.getElementById("saved-view") as HTMLSelectElement;
+    const selected = (): SavedView | undefined => views.find((v) => v.ID === sel.value);
+    sel.onchange = () => {
+        const view = selected();
+        if (view) {
+            window.location.href = savedViewURL(view);
+        }
+    };
+
+    document.getElementById("saved-view-save")!.onclick = async () => {
+        const current = selected();
+        const name = prompt("Name of the view", current ? current.Name : "");
+        if (!name) {
+            return;
+        }
+        const result = await fetch("/saved-views", {
+            body: JSON.stringify({Name: name, Filters: currentFilters()}),
+            headers: {
+                "Content-type": "application/json",
+                "X-CSRF-Token": csrfToken,
+            },
+            method: "post",
+        });
+        if (handleUnauthorized(result)) {
+            return;
+        }
+        if (!result.ok) {
+            alert(await result.text());
+            return;
+        }
+        const view = await result.json() as SavedView;
+        views = await loadSavedViews();
+        redrawSavedViews(views, view.ID);
+        copyToClipboardWithToast(savedViewURL(view));
+    };
+
+    document.getElementById("saved-view-share")!.onclick = () => {
+        const view = selected();
+        if (view) {
+            copyToClipboardWithToast(savedViewURL(view));
+        }
+    };
+
+    document.getElementById("saved-view-delete")!.onclick = async () => {
+        const view = selected();
+        if (!view || !confirm(`Delete the view ${view.Name}?`)) {
+            return;
+        }
+        const result = await fetch(`/saved-views?id=${encodeURIComponent(view.ID)}`, {
+            headers: {"X-CSRF-Token": csrfToken},
+            method: "delete",
+        });
+        if (handleUnauthorized(result)) {
+            return;
+        }
+        views = await loadSavedViews();
+        redrawSavedViews(views);
+    };
+}
@@ -1,4 +1,4 @@
-import {PullRequest, TideData, TidePool} from '../api/tide';
+import {PullRequest, PullRequestBlockers, TideData, TidePool} from '../api/tide';
 import {tidehistory, tooltip} from '../common/common';
 
 declare const tideData: TideData;
@@ -223,6 +223,7 @@ function redrawPools(): void {
         r.appendChild(createPRCell(pool, pool.SuccessPRs));
         r.appendChild(createPRCell(pool, pool.PendingPRs));
         r.appendChild(createPRCell(pool, pool.MissingPRs));
+        r.appendChild(createBlockedCell(pool));
 
         pools.appendChild(r);
     }
@@ -261,6 +262,10 @@ function createActionCell(pool: TidePool): HTMLTableDataCellElement {
     } else if (targeted) {
         addPRsToElem(c, pool, pool.Target);
     }
+    if (pool.MergeFreeze) {
+        c.classList.add("blocked");
+        c.appendChild(document.createTextNode(` (${pool.MergeFreeze})`));
+    }
     return c;
 }
 
@@ -270,6 +275,66 @@ function createPRCell(pool: TidePool, prs: PullRequest[]): HTMLTableDataCellElem
     return c;
 }
 
+function createBlockedCell(pool: TidePool): HTMLTableDataCellElement {
+    const c = document.createElement("td");
+    if (!pool.BlockedPRs) {
+        return c;
+    }
+    for (let i = 0; i < pool.BlockedPRs.length; i++) {
+        const b = pool.BlockedPRs[i];
+        const a = document.createElement("a");
+        a.href = `/github-link?dest=${pool.Org}/${pool.Repo}/pull/${b.Number}`;
+        a.appendChild(document.createTextNode("#" + b.Number));
+        a.id = `blocked-${pool.Org}-${pool.Repo}-${b.Number}-${nextID()}`;
+        a.appendChild(tooltip.forElem(a.id, document.createTextNode(blockerReasons(b).join(" "))));
+        c.appendChild(a);
+        // Add a space after each PR number except the last.
+        if (i + 1 < pool.BlockedPRs.length) {
+            c.appendChild(document.createTextNode(" "));
+        }
+    }
+    return c;
+}
+
+// blockerReasons describes what keeps a PR from merging.
+function blockerReasons(b: PullRequestBlockers): string[] {
+    const reasons: string[] = [];
+    if (b.Title) {
+        reasons.push(`${b.Title}:`);
+    }
+    if (b.Unmergeable) {
+        reasons.push(b.Unmergeable);
+    }
+    if (b.MissingLabels) {
+        reasons.push(`Needs labels ${b.MissingLabels.join(", ")}.`);
+    }
+    if (b.UnwantedLabels) {
+        reasons.push(`Should not have labels ${b.UnwantedLabels.join(", ")}.`);
+    }
+    if (b.MissingMilestone) {
+        reasons.push(`Must be in milestone ${b.MissingMilestone}.`);
+    }
+    if (b.ReviewRequired) {
+        reasons.push("Needs an approving review.");
+    }
+    if (b.FailedContexts) {
+        reasons.push(`Failed: ${b.FailedContexts.join(", ")}.`);
+    }
+    if (b.PendingContexts) {
+        reasons.push(`Pending: ${b.PendingContexts.join(", ")}.`);
+    }
+    if (b.MissingContexts) {
+        reasons.push(`Not reported: ${b.MissingContexts.join(", ")}.`);
+    }
+    if (b.MergeFreeze) {
+        reasons.push(`Frozen: ${b.MergeFreeze}.`);
+    }
+    if (b.BlockingIssues) {
+        reasons.push(`Blocked by issues ${b.BlockingIssues.map((n) => "#" + n).join(", ")}.`);
+    }
+    return reasons;
+}
+
 function createBatchCell(pool: TidePool): HTMLTableDataCellElement {
     const td = document.createElement('td');
     if (pool.BatchPending) {
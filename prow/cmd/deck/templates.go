@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"html/template"
 	"net/http"
 	"path"
@@ -39,12 +40,9 @@ func makeBaseTemplateSettings(mobileFriendly bool, darkMode bool, pageName strin
 	return baseTemplateSettings{mobileFriendly, darkMode, pageName, arguments}
 }
 
-func getConcreteBrandingFunction(cfg config.Getter) func() config.Branding {
+func getConcreteBrandingFunction(ctx context.Context, cfg config.Getter) func() config.Branding {
 	return func() config.Branding {
-		if branding := cfg().Deck.Branding; branding != nil {
-			return *branding
-		}
-		return config.Branding{}
+		return requestBranding(ctx, cfg)
 	}
 }
 
@@ -62,10 +60,10 @@ func getConcreteSectionFunction(o options) func() baseTemplateSections {
 	}
 }
 
-func prepareBaseTemplate(o options, cfg config.Getter, csrfToken string, t *template.Template) (*template.Template, error) {
+func prepareBaseTemplate(ctx context.Context, o options, cfg config.Getter, csrfToken string, t *template.Template) (*template.Template, error) {
 	return t.Funcs(map[string]interface{}{
 		"settings":         makeBaseTemplateSettings,
-		"branding":         getConcreteBrandingFunction(cfg),
+		"branding":         getConcreteBrandingFunction(ctx, cfg),
 		"sections":         getConcreteSectionFunction(o),
 		"mobileFriendly":   func() bool { return true },
 		"mobileUnfriendly": func() bool { return false },
@@ -80,7 +78,7 @@ func prepareBaseTemplate(o options, cfg config.Getter, csrfToken string, t *temp
 func handleSimpleTemplate(o options, cfg config.Getter, templateName string, param interface{}) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		t := template.New(templateName) // the name matters, and must match the filename.
-		if _, err := prepareBaseTemplate(o, cfg, csrf.Token(r), t); err != nil {
+		if _, err := prepareBaseTemplate(r.Context(), o, cfg, csrf.Token(r), t); err != nil {
 			logrus.WithError(err).Error("error preparing base template")
 			http.Error(w, "error preparing base template", http.StatusInternalServerError)
 			return
@@ -0,0 +1,358 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/deck/jobs"
+	"k8s.io/test-infra/prow/pluginhelp"
+	"k8s.io/test-infra/prow/spyglass/lenses/common"
+	"k8s.io/test-infra/prow/tide"
+	"k8s.io/test-infra/prow/tide/history"
+)
+
+// tenantMembershipTTL is how long the tenants of a GitHub user are cached.
+const tenantMembershipTTL = 10 * time.Minute
+
+type tenantMembershipClient interface {
+	IsMember(org, user string) (bool, error)
+}
+
+type cachedTenants struct {
+	ids    sets.String
+	expiry time.Time
+}
+
+// tenantResolver identifies the tenants whose jobs the requests may see when
+// Deck scopes by tenant. That's the tenant served at the host of the request if
+// any, otherwise the default tenant and the tenants of the GitHub orgs of the
+// logged in user.
+//
+// The Host header is chosen by the client, so the host of the request is only
+// taken from the header set by the authenticating proxy in front of Deck.
+type tenantResolver struct {
+	cfg config.Getter
+	// hostHeader is the header the authenticating proxy sets to the host of
+	// the request. Hosts don't identify tenants without it.
+	hostHeader string
+	// login returns the GitHub login of the user of the request, it is nil
+	// without GitHub OAuth.
+	login   func(r *http.Request) (string, error)
+	members tenantMembershipClient
+
+	mut   sync.Mutex
+	cache map[string]cachedTenants
+}
+
+func newTenantResolver(cfg config.Getter, hostHeader string) *tenantResolver {
+	return &tenantResolver{cfg: cfg, hostHeader: hostHeader, cache: map[string]cachedTenants{}}
+}
+
+func (tr *tenantResolver) tenantIDs(r *http.Request) sets.String {
+	deck := tr.cfg().Deck
+	if tr.hostHeader != "" {
+		host := r.Header.Get(tr.hostHeader)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if tenant := deck.TenantForHost(host); host != "" && tenant != nil {
+			return sets.NewString(tenant.ID)
+		}
+	}
+	ids := sets.NewString(config.DefaultTenantID)
+	if tr.login == nil || tr.members == nil {
+		return ids
+	}
+	login, err := tr.login(r)
+	if err != nil || login == "" {
+		return ids
+	}
+
+	tr.mut.Lock()
+	defer tr.mut.Unlock()
+	if cached, ok := tr.cache[login]; ok && time.Now().Before(cached.expiry) {
+		return cached.ids
+	}
+	for _, tenant := range deck.Tenants {
+		for _, org := range tenant.Orgs {
+			member, err := tr.members.IsMember(org, login)
+			if err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{"org": org, "user": login}).Warn("Failed to check the membership of a tenant org.")
+				continue
+			}
+			if member {
+				ids.Insert(tenant.ID)
+				break
+			}
+		}
+	}
+	tr.cache[login] = cachedTenants{ids: ids, expiry: time.Now().Add(tenantMembershipTTL)}
+	return ids
+}
+
+type tenantIDsKey struct{}
+
+// scope passes the tenants of the requests to the handlers.
+func (tr *tenantResolver) scope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantIDsKey{}, tr.tenantIDs(r))))
+	})
+}
+
+// tenantVisible returns whether the request may see an object of the tenants,
+// e.g. the tenant of a ProwJob or the tenants of the jobs of a Tide pool. Objects
+// without tenant belong to the default tenant. Everything is visible when Deck
+// doesn't scope by tenant.
+func tenantVisible(ctx context.Context, ids ...string) bool {
+	scope, ok := ctx.Value(tenantIDsKey{}).(sets.String)
+	if !ok {
+		return true
+	}
+	owners := sets.NewString()
+	for _, id := range ids {
+		if id == "" {
+			id = config.DefaultTenantID
+		}
+		owners.Insert(id)
+	}
+	if owners.Len() == 0 {
+		owners.Insert(config.DefaultTenantID)
+	}
+	return scope.HasAll(owners.UnsortedList()...)
+}
+
+// prowJobVisible returns whether the request may see the ProwJob.
+func prowJobVisible(ctx context.Context, pj prowapi.ProwJob) bool {
+	return tenantVisible(ctx, prowJobTenantID(pj))
+}
+
+// prowJobGetter finds the ProwJob of a run of a job.
+type prowJobGetter interface {
+	GetProwJob(job, id string) (prowapi.ProwJob, error)
+}
+
+// jobRunVisible returns whether the request may see the run of the job. Runs
+// whose ProwJob is gone belong to the tenant of the job in the config, and runs
+// of jobs that aren't in the config to the default tenant.
+func jobRunVisible(ctx context.Context, cfg config.Getter, pjs prowJobGetter, job, id string) bool {
+	if _, ok := ctx.Value(tenantIDsKey{}).(sets.String); !ok {
+		return true
+	}
+	if pjs != nil && id != "" {
+		if pj, err := pjs.GetProwJob(job, id); err == nil {
+			return prowJobVisible(ctx, pj)
+		}
+	}
+	return tenantVisible(ctx, configuredJobTenantID(cfg(), job))
+}
+
+// configuredJobTenantID returns the tenant of the job in the config.
+func configuredJobTenantID(cfg *config.Config, job string) string {
+	for _, p := range cfg.AllPeriodics() {
+		if p.Name == job && p.ProwJobDefault != nil {
+			return p.ProwJobDefault.TenantID
+		}
+	}
+	for _, p := range cfg.AllStaticPresubmits(nil) {
+		if p.Name == job && p.ProwJobDefault != nil {
+			return p.ProwJobDefault.TenantID
+		}
+	}
+	for _, p := range cfg.AllStaticPostsubmits(nil) {
+		if p.Name == job && p.ProwJobDefault != nil {
+			return p.ProwJobDefault.TenantID
+		}
+	}
+	return ""
+}
+
+// storagePathVisible returns whether the request may see the artifacts of the
+// job run stored at the path, e.g. gs/bucket/logs/job/1234.
+func storagePathVisible(ctx context.Context, cfg config.Getter, pjs prowJobGetter, path string) bool {
+	if _, ok := ctx.Value(tenantIDsKey{}).(sets.String); !ok {
+		return true
+	}
+	job, id, err := common.KeyToJob(path)
+	if err != nil {
+		return tenantVisible(ctx)
+	}
+	return jobRunVisible(ctx, cfg, pjs, job, id)
+}
+
+// requestBranding returns the branding of the tenant served at the host of the
+// request, which defaults to the branding of Deck.
+func requestBranding(ctx context.Context, cfg config.Getter) config.Branding {
+	var branding *config.Branding
+	if scope, ok := ctx.Value(tenantIDsKey{}).(sets.String); ok && scope.Len() == 1 && !scope.Has(config.DefaultTenantID) {
+		branding = cfg().Deck.GetBranding(scope.List()[0])
+	} else {
+		branding = cfg().Deck.Branding
+	}
+	if branding != nil {
+		return *branding
+	}
+	return config.Branding{}
+}
+
+func prowJobTenantID(pj prowapi.ProwJob) string {
+	if pj.Spec.ProwJobDefault == nil {
+		return ""
+	}
+	return pj.Spec.ProwJobDefault.TenantID
+}
+
+// scopedProwJobs returns the ProwJobs the request may see.
+func scopedProwJobs(r *http.Request, ja *jobs.JobAgent) []prowapi.ProwJob {
+	pjs := ja.ProwJobs()
+	if _, ok := r.Context().Value(tenantIDsKey{}).(sets.String); !ok {
+		return pjs
+	}
+	filtered := make([]prowapi.ProwJob, 0, len(pjs))
+	for _, pj := range pjs {
+		if tenantVisible(r.Context(), prowJobTenantID(pj)) {
+			filtered = append(filtered, pj)
+		}
+	}
+	return filtered
+}
+
+// scopedJobs returns the jobs the request may see.
+func scopedJobs(r *http.Request, ja *jobs.JobAgent) []jobs.Job {
+	all := ja.Jobs()
+	if _, ok := r.Context().Value(tenantIDsKey{}).(sets.String); !ok {
+		return all
+	}
+	visible := sets.NewString()
+	for _, pj := range scopedProwJobs(r, ja) {
+		visible.Insert(pj.Name)
+	}
+	filtered := make([]jobs.Job, 0, len(all))
+	for _, job := range all {
+		if visible.Has(job.ProwJob) {
+			filtered = append(filtered, job)
+		}
+	}
+	return filtered
+}
+
+// scopedPeriodics returns the periodics the request may see.
+func scopedPeriodics(ctx context.Context, periodics []config.Periodic) []config.Periodic {
+	if _, ok := ctx.Value(tenantIDsKey{}).(sets.String); !ok {
+		return periodics
+	}
+	var filtered []config.Periodic
+	for _, p := range periodics {
+		var tenantID string
+		if p.ProwJobDefault != nil {
+			tenantID = p.ProwJobDefault.TenantID
+		}
+		if tenantVisible(ctx, tenantID) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// orgRepoTenantID returns the tenant of the jobs of an org or repo.
+func orgRepoTenantID(cfg *config.Config, orgRepo string) string {
+	return cfg.GetProwJobDefault(orgRepo, "*").TenantID
+}
+
+// scopedTidePools returns the Tide pools the request may see.
+func scopedTidePools(ctx context.Context, cfg *config.Config, pools []tide.Pool) []tide.Pool {
+	if _, ok := ctx.Value(tenantIDsKey{}).(sets.String); !ok {
+		return pools
+	}
+	filtered := make([]tide.Pool, 0, len(pools))
+	for _, pool := range pools {
+		ids := append([]string{orgRepoTenantID(cfg, pool.Org+"/"+pool.Repo)}, pool.TenantIDs...)
+		if tenantVisible(ctx, ids...) {
+			filtered = append(filtered, pool)
+		}
+	}
+	return filtered
+}
+
+// scopedTideHistory returns the Tide history of the pools the request may see.
+func scopedTideHistory(ctx context.Context, cfg *config.Config, hist map[string][]history.Record) map[string][]history.Record {
+	if _, ok := ctx.Value(tenantIDsKey{}).(sets.String); !ok {
+		return hist
+	}
+	filtered := make(map[string][]history.Record, len(hist))
+	for pool, records := range hist {
+		ids := append([]string{orgRepoTenantID(cfg, strings.Split(pool, ":")[0])}, recordIDs(records).List()...)
+		if tenantVisible(ctx, ids...) {
+			filtered[pool] = records
+		}
+	}
+	return filtered
+}
+
+// scopedTideQueries returns the Tide queries the request may see.
+func scopedTideQueries(ctx context.Context, cfg *config.Config, queries []config.TideQuery) []config.TideQuery {
+	if _, ok := ctx.Value(tenantIDsKey{}).(sets.String); !ok {
+		return queries
+	}
+	filtered := make([]config.TideQuery, 0, len(queries))
+	for _, query := range queries {
+		if tenantVisible(ctx, query.TenantIDs(*cfg)...) {
+			filtered = append(filtered, query)
+		}
+	}
+	return filtered
+}
+
+// scopedPluginHelp returns the plugin help of the orgs and repos the request
+// may see.
+func scopedPluginHelp(ctx context.Context, cfg *config.Config, help pluginhelp.Help) pluginhelp.Help {
+	if _, ok := ctx.Value(tenantIDsKey{}).(sets.String); !ok {
+		return help
+	}
+	visible := func(orgRepo string) bool {
+		return orgRepo == "" || tenantVisible(ctx, orgRepoTenantID(cfg, orgRepo))
+	}
+	scoped := help
+	scoped.AllRepos = nil
+	for _, repo := range help.AllRepos {
+		if visible(repo) {
+			scoped.AllRepos = append(scoped.AllRepos, repo)
+		}
+	}
+	filterRepoPlugins := func(repoPlugins map[string][]string) map[string][]string {
+		filtered := make(map[string][]string, len(repoPlugins))
+		for orgRepo, plugins := range repoPlugins {
+			if visible(orgRepo) {
+				filtered[orgRepo] = plugins
+			}
+		}
+		return filtered
+	}
+	scoped.RepoPlugins = filterRepoPlugins(help.RepoPlugins)
+	scoped.RepoExternalPlugins = filterRepoPlugins(help.RepoExternalPlugins)
+	return scoped
+}
@@ -0,0 +1,340 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/pluginhelp"
+	"k8s.io/test-infra/prow/tide"
+)
+
+type fakeTenantMembers struct {
+	members map[string]sets.String
+	checks  int
+}
+
+func (f *fakeTenantMembers) IsMember(org, user string) (bool, error) {
+	f.checks++
+	if org == "broken" {
+		return false, errors.New("injected error")
+	}
+	return f.members[org].Has(user), nil
+}
+
+func tenantConfig() *config.Config {
+	return &config.Config{
+		ProwConfig: config.ProwConfig{
+			Deck: config.Deck{
+				Branding: &config.Branding{Logo: "prow.png"},
+				Tenants: []config.DeckTenant{
+					{ID: "a", Hosts: []string{"a.example.com"}, Orgs: []string{"org-a"}, Branding: &config.Branding{Logo: "a.png"}},
+					{ID: "b", Orgs: []string{"broken", "org-b"}},
+				},
+			},
+			ProwJobDefaultEntries: []*config.ProwJobDefaultEntry{
+				{OrgRepo: "org-a", Config: &prowapi.ProwJobDefault{TenantID: "a"}},
+				{OrgRepo: "org-b/repo", Config: &prowapi.ProwJobDefault{TenantID: "b"}},
+			},
+		},
+	}
+}
+
+func TestTenantIDs(t *testing.T) {
+	members := &fakeTenantMembers{members: map[string]sets.String{
+		"org-a": sets.NewString("alice"),
+		"org-b": sets.NewString("alice", "bob"),
+	}}
+	tr := newTenantResolver(tenantConfig, "X-Forwarded-Host")
+	tr.members = members
+	tr.login = func(r *http.Request) (string, error) {
+		if login := r.Header.Get("login"); login != "" {
+			return login, nil
+		}
+		return "", errors.New("not logged in")
+	}
+
+	testCases := []struct {
+		name      string
+		host      string
+		proxyHost string
+		login     string
+		expected  sets.String
+	}{
+		{
+			name:      "host of a tenant",
+			proxyHost: "a.example.com:8080",
+			login:     "bob",
+			expected:  sets.NewString("a"),
+		},
+		{
+			name:     "host of a tenant not set by the proxy",
+			host:     "a.example.com",
+			expected: sets.NewString(config.DefaultTenantID),
+		},
+		{
+			name:     "anonymous user",
+			host:     "prow.example.com",
+			expected: sets.NewString(config.DefaultTenantID),
+		},
+		{
+			name:     "member of the orgs of tenants",
+			host:     "prow.example.com",
+			login:    "alice",
+			expected: sets.NewString(config.DefaultTenantID, "a", "b"),
+		},
+		{
+			name:     "member of no tenant org",
+			host:     "prow.example.com",
+			login:    "carol",
+			expected: sets.NewString(config.DefaultTenantID),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/prowjobs.js", nil)
+			r.Host = tc.host
+			if tc.proxyHost != "" {
+				r.Header.Set("X-Forwarded-Host", tc.proxyHost)
+			}
+			if tc.login != "" {
+				r.Header.Set("login", tc.login)
+			}
+			if diff := cmp.Diff(tc.expected, tr.tenantIDs(r)); diff != "" {
+				t.Errorf("tenants differ from expected: %s", diff)
+			}
+		})
+	}
+
+	checks := members.checks
+	r := httptest.NewRequest(http.MethodGet, "/prowjobs.js", nil)
+	r.Header.Set("login", "alice")
+	tr.tenantIDs(r)
+	if members.checks != checks {
+		t.Errorf("expected the tenants of alice to be cached, got %d more membership checks", members.checks-checks)
+	}
+}
+
+func scopedContext(ids ...string) context.Context {
+	return context.WithValue(context.Background(), tenantIDsKey{}, sets.NewString(ids...))
+}
+
+func TestTenantVisible(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		ids      []string
+		expected bool
+	}{
+		{
+			name:     "not scoped",
+			ctx:      context.Background(),
+			ids:      []string{"a"},
+			expected: true,
+		},
+		{
+			name:     "default tenant sees objects without tenant",
+			ctx:      scopedContext(config.DefaultTenantID),
+			ids:      []string{""},
+			expected: true,
+		},
+		{
+			name:     "default tenant sees objects without tenant ids",
+			ctx:      scopedContext(config.DefaultTenantID),
+			expected: true,
+		},
+		{
+			name: "default tenant does not see the objects of a tenant",
+			ctx:  scopedContext(config.DefaultTenantID),
+			ids:  []string{"a"},
+		},
+		{
+			name:     "tenant sees its objects",
+			ctx:      scopedContext("a"),
+			ids:      []string{"a"},
+			expected: true,
+		},
+		{
+			name: "tenant does not see the objects of the default tenant",
+			ctx:  scopedContext("a"),
+			ids:  []string{config.DefaultTenantID},
+		},
+		{
+			name: "objects shared with other tenants are not visible",
+			ctx:  scopedContext(config.DefaultTenantID, "a"),
+			ids:  []string{"a", "b"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tenantVisible(tc.ctx, tc.ids...); actual != tc.expected {
+				t.Errorf("expected visible: %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRequestBranding(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+	}{
+		{
+			name:     "not scoped",
+			ctx:      context.Background(),
+			expected: "prow.png",
+		},
+		{
+			name:     "tenant with branding",
+			ctx:      scopedContext("a"),
+			expected: "a.png",
+		},
+		{
+			name:     "tenant without branding",
+			ctx:      scopedContext("b"),
+			expected: "prow.png",
+		},
+		{
+			name:     "default tenant and the tenants of a user",
+			ctx:      scopedContext(config.DefaultTenantID, "a"),
+			expected: "prow.png",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := requestBranding(tc.ctx, tenantConfig).Logo; actual != tc.expected {
+				t.Errorf("expected logo %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestScopedTidePools(t *testing.T) {
+	pools := []tide.Pool{
+		{Org: "org", Repo: "repo"},
+		{Org: "org-a", Repo: "repo"},
+		{Org: "org", Repo: "shared", TenantIDs: []string{"b"}},
+	}
+	var repos []string
+	for _, pool := range scopedTidePools(scopedContext("a"), tenantConfig(), pools) {
+		repos = append(repos, pool.Org+"/"+pool.Repo)
+	}
+	if diff := cmp.Diff([]string{"org-a/repo"}, repos); diff != "" {
+		t.Errorf("pools differ from expected: %s", diff)
+	}
+}
+
+func TestScopedPluginHelp(t *testing.T) {
+	help := pluginhelp.Help{
+		AllRepos:            []string{"org/repo", "org-a/repo", "org-b/repo"},
+		RepoPlugins:         map[string][]string{"": {"lgtm", "size"}, "org": {"lgtm"}, "org-a": {"size"}},
+		RepoExternalPlugins: map[string][]string{"org-b/repo": {"needs-rebase"}},
+	}
+	expected := pluginhelp.Help{
+		AllRepos:            []string{"org/repo", "org-b/repo"},
+		RepoPlugins:         map[string][]string{"": {"lgtm", "size"}, "org": {"lgtm"}},
+		RepoExternalPlugins: map[string][]string{"org-b/repo": {"needs-rebase"}},
+	}
+	if diff := cmp.Diff(expected, scopedPluginHelp(scopedContext(config.DefaultTenantID, "b"), tenantConfig(), help)); diff != "" {
+		t.Errorf("plugin help differs from expected: %s", diff)
+	}
+	if diff := cmp.Diff(help, scopedPluginHelp(context.Background(), tenantConfig(), help)); diff != "" {
+		t.Errorf("expected the plugin help not to be scoped: %s", diff)
+	}
+}
+
+type fakeProwJobGetter map[string]prowapi.ProwJob
+
+func (f fakeProwJobGetter) GetProwJob(job, id string) (prowapi.ProwJob, error) {
+	if pj, ok := f[job+"/"+id]; ok {
+		return pj, nil
+	}
+	return prowapi.ProwJob{}, errors.New("not found")
+}
+
+func TestJobRunVisible(t *testing.T) {
+	cfg := tenantConfig()
+	cfg.Periodics = []config.Periodic{{JobBase: config.JobBase{Name: "periodic-a", ProwJobDefault: &prowapi.ProwJobDefault{TenantID: "a"}}}}
+	pjs := fakeProwJobGetter{
+		"job-a/1": {Spec: prowapi.ProwJobSpec{Job: "job-a", ProwJobDefault: &prowapi.ProwJobDefault{TenantID: "a"}}},
+		"job/1":   {Spec: prowapi.ProwJobSpec{Job: "job"}},
+	}
+
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		path     string
+		expected bool
+	}{
+		{
+			name:     "unscoped",
+			ctx:      context.Background(),
+			path:     "gs/bucket/logs/job-a/1",
+			expected: true,
+		},
+		{
+			name:     "ProwJob of the tenant",
+			ctx:      scopedContext("a"),
+			path:     "gs/bucket/logs/job-a/1",
+			expected: true,
+		},
+		{
+			name: "ProwJob of another tenant",
+			ctx:  scopedContext(config.DefaultTenantID),
+			path: "gs/bucket/logs/job-a/1",
+		},
+		{
+			name: "ProwJob of the default tenant",
+			ctx:  scopedContext("a"),
+			path: "prowjob/job/1",
+		},
+		{
+			name:     "run without ProwJob of a configured job of the tenant",
+			ctx:      scopedContext("a"),
+			path:     "gs/bucket/logs/periodic-a/2",
+			expected: true,
+		},
+		{
+			name: "run without ProwJob of a configured job of another tenant",
+			ctx:  scopedContext(config.DefaultTenantID),
+			path: "gs/bucket/logs/periodic-a/2",
+		},
+		{
+			name:     "run of an unknown job",
+			ctx:      scopedContext(config.DefaultTenantID),
+			path:     "gs/bucket/logs/unknown/2",
+			expected: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := storagePathVisible(tc.ctx, func() *config.Config { return cfg }, pjs, tc.path); actual != tc.expected {
+				t.Errorf("expected visible to be %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -54,7 +54,10 @@ type tideAgent struct {
 	showHidden  bool
 
 	tenantIDs sets.String
-	cfg       func() *config.Config
+	// allTenants keeps the pools of all the tenants, which Deck scopes by the
+	// tenant of each request.
+	allTenants bool
+	cfg        func() *config.Config
 
 	sync.Mutex
 	pools   []tide.Pool
@@ -216,7 +219,9 @@ func (ta *tideAgent) filter(orgRepoID string, curIDs sets.String, needsHide bool
 	if orgRepoID != "" && orgRepoID != config.DefaultTenantID {
 		curIDs.Insert(orgRepoID)
 	}
-	if len(ta.tenantIDs) > 0 {
+	if ta.allTenants {
+		return !noTenantIDOrDefaultTenantID(curIDs.List()) || !needsHide
+	} else if len(ta.tenantIDs) > 0 {
 		if ta.matchingIDs(curIDs.List()) {
 			// Deck has tenantIDs and they match with the History
 			return true
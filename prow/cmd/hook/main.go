@@ -17,6 +17,8 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"net/http"
 	"os"
@@ -25,6 +27,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"k8s.io/test-infra/prow/pjutil/pprof"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"k8s.io/test-infra/pkg/flagutil"
 	"k8s.io/test-infra/prow/bugzilla"
@@ -34,7 +37,9 @@ import (
 	configflagutil "k8s.io/test-infra/prow/flagutil/config"
 	pluginsflagutil "k8s.io/test-infra/prow/flagutil/plugins"
 	"k8s.io/test-infra/prow/git/v2"
+	"k8s.io/test-infra/prow/gitea"
 	"k8s.io/test-infra/prow/githubeventserver"
+	"k8s.io/test-infra/prow/gitlab"
 	"k8s.io/test-infra/prow/hook"
 	"k8s.io/test-infra/prow/interrupts"
 	jiraclient "k8s.io/test-infra/prow/jira"
@@ -53,7 +58,9 @@ import (
 )
 
 const (
-	defaultWebhookPath = "/hook"
+	defaultWebhookPath       = "/hook"
+	defaultGitLabWebhookPath = "/hook/gitlab"
+	defaultGiteaWebhookPath  = "/hook/gitea"
 )
 
 type options struct {
@@ -65,8 +72,11 @@ type options struct {
 
 	dryRun                 bool
 	gracePeriod            time.Duration
+	periodicInterval       time.Duration
 	kubernetes             prowflagutil.KubernetesOptions
 	github                 prowflagutil.GitHubOptions
+	gitlab                 prowflagutil.GitLabOptions
+	gitea                  prowflagutil.GiteaOptions
 	githubEnablement       prowflagutil.GitHubEnablementOptions
 	bugzilla               prowflagutil.BugzillaOptions
 	instrumentationOptions prowflagutil.InstrumentationOptions
@@ -74,14 +84,28 @@ type options struct {
 
 	webhookSecretFile string
 	slackTokenFile    string
+
+	gitlabWebhookPath       string
+	gitlabWebhookSecretFile string
+	giteaWebhookPath        string
+	giteaWebhookSecretFile  string
+
+	ownersCacheRedisAddress string
+	ownersCacheTTL          time.Duration
 }
 
 func (o *options) Validate() error {
-	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github, &o.bugzilla, &o.jira, &o.githubEnablement, &o.config, &o.pluginsConfig} {
+	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github, &o.gitlab, &o.gitea, &o.bugzilla, &o.jira, &o.githubEnablement, &o.config, &o.pluginsConfig} {
 		if err := group.Validate(o.dryRun); err != nil {
 			return err
 		}
 	}
+	if o.gitlab.Enabled() && o.gitlabWebhookSecretFile == "" {
+		return errors.New("--gitlab-endpoint requires --gitlab-webhook-secret-file")
+	}
+	if o.gitea.Enabled() && o.giteaWebhookSecretFile == "" {
+		return errors.New("--gitea-endpoint requires --gitea-webhook-secret-file")
+	}
 
 	return nil
 }
@@ -93,13 +117,20 @@ func gatherOptions(fs *flag.FlagSet, args ...string) options {
 
 	fs.BoolVar(&o.dryRun, "dry-run", true, "Dry run for testing. Uses API tokens but does not mutate.")
 	fs.DurationVar(&o.gracePeriod, "grace-period", 180*time.Second, "On shutdown, try to handle remaining events for the specified duration. ")
+	fs.DurationVar(&o.periodicInterval, "periodic-interval", time.Hour, "Interval at which the periodic handlers of enabled plugins are run by the leader replica.")
 	o.pluginsConfig.PluginConfigPathDefault = "/etc/plugins/plugins.yaml"
-	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github, &o.bugzilla, &o.instrumentationOptions, &o.jira, &o.githubEnablement, &o.config, &o.pluginsConfig} {
+	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github, &o.gitlab, &o.gitea, &o.bugzilla, &o.instrumentationOptions, &o.jira, &o.githubEnablement, &o.config, &o.pluginsConfig} {
 		group.AddFlags(fs)
 	}
 
 	fs.StringVar(&o.webhookSecretFile, "hmac-secret-file", "/etc/webhook/hmac", "Path to the file containing the GitHub HMAC secret.")
 	fs.StringVar(&o.slackTokenFile, "slack-token-file", "", "Path to the file containing the Slack token to use.")
+	fs.StringVar(&o.gitlabWebhookPath, "gitlab-webhook-path", defaultGitLabWebhookPath, "The path of GitLab webhook events, served when --gitlab-endpoint is set.")
+	fs.StringVar(&o.gitlabWebhookSecretFile, "gitlab-webhook-secret-file", "", "Path to the file containing the secret token of the GitLab webhooks.")
+	fs.StringVar(&o.giteaWebhookPath, "gitea-webhook-path", defaultGiteaWebhookPath, "The path of Gitea and Forgejo webhook events, served when --gitea-endpoint is set.")
+	fs.StringVar(&o.giteaWebhookSecretFile, "gitea-webhook-secret-file", "", "Path to the file containing the secret of the Gitea and Forgejo webhooks.")
+	fs.StringVar(&o.ownersCacheRedisAddress, "owners-cache-redis-address", "", "Address of a Redis server in which all replicas share the OWNERS they load. Enable the owners-cache plugin to load them when branches are pushed to.")
+	fs.DurationVar(&o.ownersCacheTTL, "owners-cache-ttl", 7*24*time.Hour, "How long OWNERS are kept in the Redis server of --owners-cache-redis-address.")
 	fs.Parse(args)
 	return o
 }
@@ -128,6 +159,13 @@ func main() {
 	}
 	tokens = append(tokens, o.webhookSecretFile)
 
+	if o.gitlab.Enabled() {
+		tokens = append(tokens, o.gitlabWebhookSecretFile)
+	}
+	if o.gitea.Enabled() {
+		tokens = append(tokens, o.giteaWebhookSecretFile)
+	}
+
 	// This is necessary since slack token is optional.
 	if o.slackTokenFile != "" {
 		tokens = append(tokens, o.slackTokenFile)
@@ -230,6 +268,12 @@ func main() {
 		return pluginAgent.Config().OwnersFilenames(org, repo)
 	}
 	ownersClient := repoowners.NewClient(git.ClientFactoryFrom(gitClient), githubClient, mdYAMLEnabled, skipCollaborators, ownersDirDenylist, resolver)
+	ownersClient.UseDefaultOwners(func(org, repo string) *ownersconfig.DefaultOwners {
+		return pluginAgent.Config().DefaultOwnersFor(org, repo)
+	})
+	if o.ownersCacheRedisAddress != "" {
+		ownersClient.UsePersistentCache(repoowners.NewRedisCache(o.ownersCacheRedisAddress, o.ownersCacheTTL))
+	}
 
 	clientAgent := &plugins.ClientAgent{
 		GitHubClient:              githubClient,
@@ -243,6 +287,21 @@ func main() {
 		JiraClient:                jiraClient,
 	}
 
+	var gitlabClient gitlab.Client
+	if o.gitlab.Enabled() {
+		gitlabClient, err = o.gitlab.Client(o.dryRun)
+		if err != nil {
+			logrus.WithError(err).Fatal("Error getting GitLab client.")
+		}
+	}
+	var giteaClient gitea.Client
+	if o.gitea.Enabled() {
+		giteaClient, err = o.gitea.Client()
+		if err != nil {
+			logrus.WithError(err).Fatal("Error getting Gitea client.")
+		}
+	}
+
 	promMetrics := githubeventserver.NewMetrics()
 
 	defer interrupts.WaitForGracefulShutdown()
@@ -258,6 +317,15 @@ func main() {
 		Metrics:        promMetrics,
 		RepoEnabled:    o.githubEnablement.EnablementChecker(),
 		TokenGenerator: secret.GetTokenGenerator(o.webhookSecretFile),
+		GitLabClient:   gitlabClient,
+		GiteaClient:    giteaClient,
+		DryRun:         o.dryRun,
+	}
+	if o.gitlab.Enabled() {
+		server.GitLabTokenGenerator = secret.GetTokenGenerator(o.gitlabWebhookSecretFile)
+	}
+	if o.gitea.Enabled() {
+		server.GiteaTokenGenerator = secret.GetTokenGenerator(o.giteaWebhookSecretFile)
 	}
 	interrupts.OnInterrupt(func() {
 		server.GracefulShutdown()
@@ -266,6 +334,44 @@ func main() {
 		}
 	})
 
+	// Periodic handlers act on every PR they match, so only the replica
+	// holding the leader lock runs them.
+	infrastructureClusterConfig, err := o.kubernetes.InfrastructureClusterConfig(o.dryRun)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting config for infrastructure cluster.")
+	}
+	periodicManager, err := manager.New(infrastructureClusterConfig, manager.Options{
+		MetricsBindAddress:            "0",
+		Namespace:                     configAgent.Config().ProwJobNamespace,
+		LeaderElection:                true,
+		LeaderElectionNamespace:       configAgent.Config().ProwJobNamespace,
+		LeaderElectionID:              "prow-hook-periodic-leaderlock",
+		LeaderElectionReleaseOnCancel: true,
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("Error creating manager for periodic handlers.")
+	}
+	if err := periodicManager.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		for {
+			server.HandlePeriodic()
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(o.periodicInterval):
+			}
+		}
+	})); err != nil {
+		logrus.WithError(err).Fatal("Error adding periodic handlers to manager.")
+	}
+	interrupts.Run(func(ctx context.Context) {
+		if err := periodicManager.Start(ctx); err != nil {
+			logrus.WithError(err).Error("Periodic handler manager failed.")
+		}
+	})
+	interrupts.Tick(server.CheckExternalPlugins, func() time.Duration {
+		return pluginAgent.Config().ExternalPluginHealth.IntervalDuration
+	})
+
 	health := pjutil.NewHealthOnPort(o.instrumentationOptions.HealthPort)
 
 	hookMux := http.NewServeMux()
@@ -275,6 +381,13 @@ func main() {
 
 	// For /hook, handle a webhook normally.
 	hookMux.Handle(o.webhookPath, server)
+	// GitLab and Gitea webhooks are adapted to the events of GitHub for the plugins.
+	if o.gitlab.Enabled() {
+		hookMux.HandleFunc(o.gitlabWebhookPath, server.ServeGitLab)
+	}
+	if o.gitea.Enabled() {
+		hookMux.HandleFunc(o.giteaWebhookPath, server.ServeGitea)
+	}
 	// Serve plugin help information from /plugin-help.
 	hookMux.Handle("/plugin-help", pluginhelp.NewHelpAgent(pluginAgent, githubClient))
 
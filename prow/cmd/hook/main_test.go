@@ -86,12 +86,30 @@ func Test_gatherOptions(t *testing.T) {
 				o.webhookPath = "/random/hook"
 			},
 		},
+		{
+			name: "--gitlab-endpoint requires --gitlab-webhook-secret-file",
+			args: map[string]string{
+				"--gitlab-endpoint":   "https://gitlab.example.com",
+				"--gitlab-token-path": "/etc/gitlab/token",
+			},
+			err: true,
+		},
+		{
+			name: "--gitea-endpoint requires --gitea-webhook-secret-file",
+			args: map[string]string{
+				"--gitea-endpoint":   "https://gitea.example.com",
+				"--gitea-token-path": "/etc/gitea/token",
+			},
+			err: true,
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			expected := &options{
-				webhookPath: "/hook",
-				port:        8888,
+				webhookPath:       "/hook",
+				gitlabWebhookPath: "/hook/gitlab",
+				giteaWebhookPath:  "/hook/gitea",
+				port:              8888,
 				config: configflagutil.ConfigOptions{
 					ConfigPath:                            "yo",
 					ConfigPathFlagName:                    "config-path",
@@ -105,8 +123,10 @@ func Test_gatherOptions(t *testing.T) {
 				},
 				dryRun:                 true,
 				gracePeriod:            180 * time.Second,
+				periodicInterval:       time.Hour,
 				webhookSecretFile:      "/etc/webhook/hmac",
 				instrumentationOptions: flagutil.DefaultInstrumentationOptions(),
+				ownersCacheTTL:         7 * 24 * time.Hour,
 			}
 			expectedfs := flag.NewFlagSet("fake-flags", flag.PanicOnError)
 			expected.github.AddFlags(expectedfs)
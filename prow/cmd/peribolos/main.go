@@ -20,8 +20,11 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path"
+	"sort"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -34,6 +37,8 @@ import (
 	"k8s.io/test-infra/prow/flagutil"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/logrusutil"
+	"k8s.io/test-infra/prow/plugins/ownersconfig"
+	"k8s.io/test-infra/prow/repoowners"
 )
 
 const (
@@ -61,6 +66,7 @@ type options struct {
 	ignoreSecretTeams bool
 	allowRepoArchival bool
 	allowRepoPublish  bool
+	diffOnly          bool
 	github            flagutil.GitHubOptions
 
 	// TODO(petr-muller): Remove after August 2021, replaced by github.ThrottleHourlyTokens
@@ -99,6 +105,7 @@ func (o *options) parseArgs(flags *flag.FlagSet, args []string) error {
 	flags.BoolVar(&o.fixRepos, "fix-repos", false, "Create/update repositories if set")
 	flags.BoolVar(&o.allowRepoArchival, "allow-repo-archival", false, "If set, archiving repos is allowed while updating repos")
 	flags.BoolVar(&o.allowRepoPublish, "allow-repo-publish", false, "If set, making private repos public is allowed while updating repos")
+	flags.BoolVar(&o.diffOnly, "diff-only", false, "Print the changes --fix-repos and --fix-team-members would make instead of making them")
 	flags.StringVar(&o.logLevel, "log-level", logrus.InfoLevel.String(), fmt.Sprintf("Logging level, one of %v", logrus.AllLevels))
 	o.github.AddCustomizedFlags(flags, flagutil.ThrottlerDefaults(defaultTokens, defaultBurst))
 	if err := flags.Parse(args); err != nil {
@@ -153,6 +160,14 @@ func (o *options) parseArgs(flags *flag.FlagSet, args []string) error {
 		return fmt.Errorf("--fix-team-repos requires --fix-teams")
 	}
 
+	if o.diffOnly && !o.fixRepos && !o.fixTeamMembers {
+		return fmt.Errorf("--diff-only requires --fix-repos or --fix-team-members")
+	}
+
+	if o.diffOnly && o.confirm {
+		return fmt.Errorf("--diff-only cannot be used with --confirm")
+	}
+
 	level, err := logrus.ParseLevel(o.logLevel)
 	if err != nil {
 		return fmt.Errorf("--log-level invalid: %w", err)
@@ -383,6 +398,7 @@ func dumpOrgConfig(client dumpClient, orgName string, ignoreSecretTeams bool) (*
 			AllowRebaseMerge: &full.AllowRebaseMerge,
 			Archived:         &full.Archived,
 			DefaultBranch:    &full.DefaultBranch,
+			Topics:           full.Topics,
 		})
 	}
 
@@ -855,8 +871,9 @@ func configureOrg(opt options, client github.Client, orgName string, orgConfig o
 		return fmt.Errorf("failed to configure %s teams: %w", orgName, err)
 	}
 
+	orgMembers := normalize(sets.NewString(orgConfig.Admins...).Union(sets.NewString(orgConfig.Members...)))
 	for name, team := range orgConfig.Teams {
-		err := configureTeamAndMembers(opt, client, githubTeams, name, orgName, team, nil)
+		err := configureTeamAndMembers(opt, client, githubTeams, name, orgName, orgMembers, team, nil)
 		if err != nil {
 			return fmt.Errorf("failed to configure %s teams: %w", orgName, err)
 		}
@@ -877,8 +894,18 @@ type repoClient interface {
 	GetRepos(orgName string, isUser bool) ([]github.Repo, error)
 	CreateRepo(owner string, isUser bool, repo github.RepoCreateRequest) (*github.FullRepo, error)
 	UpdateRepo(owner, name string, repo github.RepoUpdateRequest) (*github.FullRepo, error)
+	ReplaceRepoTopics(org, repo string, topics []string) error
+	GetVulnerabilityAlerts(org, repo string) (bool, error)
+	SetVulnerabilityAlerts(org, repo string, enabled bool) error
+	GetRepoPages(org, repo string) (*github.RepoPages, error)
+	CreateRepoPages(org, repo string, pages github.RepoPages) error
+	UpdateRepoPages(org, repo string, pages github.RepoPages) error
+	DeleteRepoPages(org, repo string) error
 }
 
+// diffOutput is where --diff-only prints the changes to repositories and teams.
+var diffOutput io.Writer = os.Stdout
+
 func newRepoCreateRequest(name string, definition org.Repo) github.RepoCreateRequest {
 	repoCreate := github.RepoCreateRequest{
 		RepoRequest: github.RepoRequest{
@@ -984,6 +1011,172 @@ func sanitizeRepoDelta(opt options, delta *github.RepoUpdateRequest) []error {
 	return errs
 }
 
+// describeRepoDelta describes the changes a github.RepoUpdateRequest makes
+// to the current repo, one line per setting.
+func describeRepoDelta(current github.FullRepo, delta github.RepoUpdateRequest) []string {
+	var changes []string
+	describeString := func(setting, have string, want *string) {
+		if want != nil {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", setting, have, *want))
+		}
+	}
+	describeBool := func(setting string, have bool, want *bool) {
+		if want != nil {
+			changes = append(changes, fmt.Sprintf("%s: %t -> %t", setting, have, *want))
+		}
+	}
+	describeString("name", current.Name, delta.Name)
+	describeString("description", current.Description, delta.Description)
+	describeString("homepage", current.Homepage, delta.Homepage)
+	describeBool("private", current.Private, delta.Private)
+	describeBool("has_issues", current.HasIssues, delta.HasIssues)
+	describeBool("has_projects", current.HasProjects, delta.HasProjects)
+	describeBool("has_wiki", current.HasWiki, delta.HasWiki)
+	describeBool("allow_squash_merge", current.AllowSquashMerge, delta.AllowSquashMerge)
+	describeBool("allow_merge_commit", current.AllowMergeCommit, delta.AllowMergeCommit)
+	describeBool("allow_rebase_merge", current.AllowRebaseMerge, delta.AllowRebaseMerge)
+	describeString("default_branch", current.DefaultBranch, delta.DefaultBranch)
+	describeBool("archived", current.Archived, delta.Archived)
+	return changes
+}
+
+// repoSettingChange is a change to a repo setting that GitHub does not
+// manage through the repo edit API.
+type repoSettingChange struct {
+	description string
+	apply       func() error
+}
+
+// newRepoSettingChanges returns the changes needed to bring the topics,
+// vulnerability alerts and Pages site of the current repo into the target
+// state.
+func newRepoSettingChanges(client repoClient, orgName string, current github.FullRepo, repo org.Repo) ([]repoSettingChange, error) {
+	var changes []repoSettingChange
+	name := current.Name
+
+	if repo.Topics != nil && !sets.NewString(repo.Topics...).Equal(sets.NewString(current.Topics...)) {
+		changes = append(changes, repoSettingChange{
+			description: fmt.Sprintf("topics: %s -> %s", describeTopics(current.Topics), describeTopics(repo.Topics)),
+			apply: func() error {
+				return client.ReplaceRepoTopics(orgName, name, repo.Topics)
+			},
+		})
+	}
+
+	if repo.VulnerabilityAlerts != nil {
+		enabled, err := client.GetVulnerabilityAlerts(orgName, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get vulnerability alerts: %w", err)
+		}
+		if want := *repo.VulnerabilityAlerts; want != enabled {
+			changes = append(changes, repoSettingChange{
+				description: fmt.Sprintf("vulnerability_alerts: %t -> %t", enabled, want),
+				apply: func() error {
+					return client.SetVulnerabilityAlerts(orgName, name, want)
+				},
+			})
+		}
+	}
+
+	if repo.Pages != nil {
+		have, err := client.GetRepoPages(orgName, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pages: %w", err)
+		}
+		if change := newRepoPagesChange(client, orgName, name, have, *repo.Pages); change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	return changes, nil
+}
+
+// newRepoPagesChange returns the change needed to bring the Pages site of
+// the repo into the target state, or nil if it is already there.
+func newRepoPagesChange(client repoClient, orgName, name string, have *github.RepoPages, want org.RepoPages) *repoSettingChange {
+	if want.Branch == "" {
+		if have == nil {
+			return nil
+		}
+		return &repoSettingChange{
+			description: fmt.Sprintf("pages: %s -> disabled", describePages(have)),
+			apply: func() error {
+				return client.DeleteRepoPages(orgName, name)
+			},
+		}
+	}
+
+	pages := github.RepoPages{
+		CNAME:  want.CNAME,
+		Source: github.RepoPagesSource{Branch: want.Branch, Path: want.Path},
+	}
+	if pages.Source.Path == "" {
+		pages.Source.Path = "/"
+	}
+	if want.CNAME != nil && *want.CNAME == "" {
+		// GitHub removes the custom domain when it is null.
+		pages.CNAME = nil
+	}
+	if have == nil {
+		return &repoSettingChange{
+			description: fmt.Sprintf("pages: disabled -> %s", describePages(&pages)),
+			apply: func() error {
+				if err := client.CreateRepoPages(orgName, name, pages); err != nil {
+					return err
+				}
+				if pages.CNAME == nil {
+					return nil
+				}
+				// The custom domain can only be set once the site exists.
+				return client.UpdateRepoPages(orgName, name, pages)
+			},
+		}
+	}
+	if want.CNAME == nil {
+		// Keep the current custom domain.
+		pages.CNAME = have.CNAME
+	}
+	if have.Source == pages.Source && describeCNAME(have.CNAME) == describeCNAME(pages.CNAME) {
+		return nil
+	}
+	return &repoSettingChange{
+		description: fmt.Sprintf("pages: %s -> %s", describePages(have), describePages(&pages)),
+		apply: func() error {
+			return client.UpdateRepoPages(orgName, name, pages)
+		},
+	}
+}
+
+func describeTopics(topics []string) string {
+	return "[" + strings.Join(sets.NewString(topics...).List(), ", ") + "]"
+}
+
+func describeCNAME(cname *string) string {
+	if cname == nil {
+		return ""
+	}
+	return *cname
+}
+
+func describePages(pages *github.RepoPages) string {
+	description := fmt.Sprintf("branch=%s path=%s", pages.Source.Branch, pages.Source.Path)
+	if cname := describeCNAME(pages.CNAME); cname != "" {
+		description += fmt.Sprintf(" cname=%s", cname)
+	}
+	return description
+}
+
+// printDiff prints the changes to a repo or team for --diff-only.
+func printDiff(target string, changes []string) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(diffOutput, "%s:\n", target)
+	for _, change := range changes {
+		fmt.Fprintf(diffOutput, "  %s\n", change)
+	}
+}
+
 func configureRepos(opt options, client repoClient, orgName string, orgConfig org.Config) error {
 	if err := validateRepos(orgConfig.Repos); err != nil {
 		return err
@@ -1001,7 +1194,15 @@ func configureRepos(opt options, client repoClient, orgName string, orgConfig or
 
 	var allErrors []error
 
-	for wantName, wantRepo := range orgConfig.Repos {
+	// Sort the repos so that --diff-only prints them in a stable order.
+	wantNames := make([]string, 0, len(orgConfig.Repos))
+	for wantName := range orgConfig.Repos {
+		wantNames = append(wantNames, wantName)
+	}
+	sort.Strings(wantNames)
+
+	for _, wantName := range wantNames {
+		wantRepo := orgConfig.Repos[wantName]
 		repoLogger := logrus.WithField("repo", wantName)
 		pastErrors := len(allErrors)
 		var existing *github.FullRepo = nil
@@ -1032,6 +1233,10 @@ func configureRepos(opt options, client repoClient, orgName string, orgConfig or
 				allErrors = append(allErrors, fmt.Errorf("nonexistent repo configured as archived: %s", wantName))
 				continue
 			}
+			if opt.diffOnly {
+				printDiff(orgName+"/"+wantName, []string{"created"})
+				continue
+			}
 			repoLogger.Info("repo does not exist, creating")
 			created, err := client.CreateRepo(orgName, false, newRepoCreateRequest(wantName, wantRepo))
 			if err != nil {
@@ -1057,11 +1262,48 @@ func configureRepos(opt options, client repoClient, orgName string, orgConfig or
 				}
 				allErrors = append(allErrors, deltaErrors...)
 			}
+			changes, err := newRepoSettingChanges(client, orgName, *existing, wantRepo)
+			if err != nil {
+				repoLogger.WithError(err).Error("failed to get repository settings")
+				allErrors = append(allErrors, err)
+			}
+			if opt.diffOnly {
+				diff := describeRepoDelta(*existing, delta)
+				for _, change := range changes {
+					diff = append(diff, change.description)
+				}
+				printDiff(orgName+"/"+wantName, diff)
+				continue
+			}
+			applySettings := func() {
+				for _, change := range changes {
+					repoLogger.Infof("updating repository setting: %s", change.description)
+					if err := change.apply(); err != nil {
+						repoLogger.WithError(err).Error("failed to update repository setting")
+						allErrors = append(allErrors, err)
+					}
+				}
+			}
+			// Archived repos are read-only, so settings are changed before a
+			// repo is archived and after it is unarchived.
+			if !existing.Archived {
+				applySettings()
+			}
+			updated := true
 			if delta.Defined() {
 				repoLogger.Info("repo exists and differs from desired state, updating")
 				if _, err := client.UpdateRepo(orgName, existing.Name, delta); err != nil {
 					repoLogger.WithError(err).Error("failed to update repository")
 					allErrors = append(allErrors, err)
+					updated = false
+				}
+			}
+			if existing.Archived {
+				if unarchived := delta.Archived != nil && !*delta.Archived; unarchived && updated {
+					applySettings()
+				} else if len(changes) > 0 {
+					repoLogger.Error("repo is archived, not updating its settings")
+					allErrors = append(allErrors, fmt.Errorf("cannot update the settings of archived repo: %s", wantName))
 				}
 			}
 		}
@@ -1070,7 +1312,7 @@ func configureRepos(opt options, client repoClient, orgName string, orgConfig or
 	return utilerrors.NewAggregate(allErrors)
 }
 
-func configureTeamAndMembers(opt options, client github.Client, githubTeams map[string]github.Team, name, orgName string, team org.Team, parent *int) error {
+func configureTeamAndMembers(opt options, client github.Client, githubTeams map[string]github.Team, name, orgName string, orgMembers sets.String, team org.Team, parent *int) error {
 	gt, ok := githubTeams[name]
 	if !ok { // configureTeams is buggy if this is the case
 		return fmt.Errorf("%s not found in id list", name)
@@ -1085,12 +1327,29 @@ func configureTeamAndMembers(opt options, client github.Client, githubTeams map[
 	// Configure team members
 	if !opt.fixTeamMembers {
 		logrus.Infof("Skipping %s member configuration", name)
-	} else if err = configureTeamMembers(client, orgName, gt, team); err != nil {
-		return fmt.Errorf("failed to update %s members: %w", name, err)
+	} else {
+		if team.Owners != nil {
+			if team, err = withOwnersMembers(client, team, orgMembers); err != nil {
+				return fmt.Errorf("failed to get %s members from OWNERS: %w", name, err)
+			}
+		}
+		var membersClient teamMembersClient = client
+		var diff *teamDiffClient
+		if opt.diffOnly {
+			diff = &teamDiffClient{teamMembersClient: client}
+			membersClient = diff
+		}
+		if err = configureTeamMembers(membersClient, orgName, gt, team); err != nil {
+			return fmt.Errorf("failed to update %s members: %w", name, err)
+		}
+		if diff != nil {
+			sort.Strings(diff.changes)
+			printDiff(fmt.Sprintf("@%s/%s", orgName, gt.Slug), diff.changes)
+		}
 	}
 
 	for childName, childTeam := range team.Children {
-		err = configureTeamAndMembers(opt, client, githubTeams, childName, orgName, childTeam, &gt.ID)
+		err = configureTeamAndMembers(opt, client, githubTeams, childName, orgName, orgMembers, childTeam, &gt.ID)
 		if err != nil {
 			return fmt.Errorf("failed to update %s child teams: %w", name, err)
 		}
@@ -1223,6 +1482,145 @@ func configureTeamRepos(client teamRepoClient, githubTeams map[string]github.Tea
 	return utilerrors.NewAggregate(updateErrors)
 }
 
+// ownersClient reads the OWNERS files of repos.
+type ownersClient interface {
+	GetFile(org, repo, filepath, commit string) ([]byte, error)
+}
+
+// withOwnersMembers adds the users that join the team from OWNERS files to
+// its members, or to its maintainers if so configured. Users that are
+// listed as members or maintainers keep their role. Like the users listed in
+// the config, the users from OWNERS files must be org members: the others are
+// skipped with a warning, since peribolos does not invite people to the org
+// on behalf of OWNERS files.
+func withOwnersMembers(client ownersClient, team org.Team, orgMembers sets.String) (org.Team, error) {
+	users, err := ownersMembers(client, *team.Owners)
+	if err != nil {
+		return team, err
+	}
+	if outside := users.Difference(orgMembers); outside.Len() > 0 {
+		logrus.Warnf("Skipping users from OWNERS files who are not org members: %s", strings.Join(outside.List(), ", "))
+		users = users.Intersection(orgMembers)
+	}
+	listed := normalize(sets.NewString(team.Members...).Union(sets.NewString(team.Maintainers...)))
+	derived := users.Difference(listed).List()
+	if team.Owners.Maintainers {
+		team.Maintainers = append(append([]string{}, team.Maintainers...), derived...)
+	} else {
+		team.Members = append(append([]string{}, team.Members...), derived...)
+	}
+	return team, nil
+}
+
+// ownersMembers aggregates the members of the aliases and the approvers of
+// the directories declared in owners across its repos. Repos without the
+// OWNERS file of a directory are skipped, but every directory must have one
+// in at least one repo.
+func ownersMembers(client ownersClient, owners org.TeamOwners) (sets.String, error) {
+	users := sets.NewString()
+	found := sets.NewString()
+	for _, fullName := range owners.Repos {
+		parts := strings.SplitN(fullName, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid repo %q, expected org/repo", fullName)
+		}
+		orgName, repo := parts[0], parts[1]
+
+		aliases, err := ownersAliases(client, orgName, repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, alias := range owners.Aliases {
+			expanded := aliases.ExpandAlias(alias)
+			if len(expanded) == 0 {
+				logrus.Warnf("Alias %s is not defined in %s or has no members", alias, fullName)
+			}
+			users = users.Union(expanded)
+		}
+
+		for _, dir := range owners.Paths {
+			approvers, err := ownersApprovers(client, orgName, repo, dir)
+			if err != nil {
+				var notFound *github.FileNotFound
+				if errors.As(err, &notFound) {
+					logrus.Debugf("%s has no OWNERS file in %s", fullName, dir)
+					continue
+				}
+				return nil, err
+			}
+			found.Insert(dir)
+			users = users.Union(aliases.ExpandAliases(approvers))
+		}
+	}
+	if missing := sets.NewString(owners.Paths...).Difference(found); missing.Len() > 0 {
+		return nil, fmt.Errorf("no OWNERS file in %s of any of %s", strings.Join(missing.List(), ", "), strings.Join(owners.Repos, ", "))
+	}
+	return normalize(users).Difference(normalize(sets.NewString(owners.Exclude...))), nil
+}
+
+// ownersAliases returns the aliases of a repo, which may have none.
+func ownersAliases(client ownersClient, orgName, repo string) (repoowners.RepoAliases, error) {
+	b, err := client.GetFile(orgName, repo, ownersconfig.DefaultOwnersAliasesFile, "")
+	if err != nil {
+		if _, notFound := err.(*github.FileNotFound); notFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get %s of %s/%s: %w", ownersconfig.DefaultOwnersAliasesFile, orgName, repo, err)
+	}
+	aliases, err := repoowners.ParseAliasesConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s of %s/%s: %w", ownersconfig.DefaultOwnersAliasesFile, orgName, repo, err)
+	}
+	return aliases, nil
+}
+
+// ownersApprovers returns all approvers in the OWNERS file of a directory,
+// including those of its filters and rules.
+func ownersApprovers(client ownersClient, orgName, repo, dir string) (sets.String, error) {
+	file := path.Join(dir, ownersconfig.DefaultOwnersFile)
+	b, err := client.GetFile(orgName, repo, file, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s of %s/%s: %w", file, orgName, repo, err)
+	}
+	simple, err := repoowners.LoadSimpleConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s of %s/%s: %w", file, orgName, repo, err)
+	}
+	full, err := repoowners.LoadFullConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s of %s/%s: %w", file, orgName, repo, err)
+	}
+	approvers := sets.NewString(simple.Approvers...)
+	for _, filter := range full.Filters {
+		approvers.Insert(filter.Approvers...)
+	}
+	for _, rule := range full.Rules {
+		approvers.Insert(rule.Approvers...)
+	}
+	return approvers, nil
+}
+
+// teamDiffClient records the changes to the memberships of a team for
+// --diff-only instead of making them.
+type teamDiffClient struct {
+	teamMembersClient
+	changes []string
+}
+
+func (c *teamDiffClient) UpdateTeamMembershipBySlug(org, teamSlug, user string, maintainer bool) (*github.TeamMembership, error) {
+	role := github.RoleMember
+	if maintainer {
+		role = github.RoleMaintainer
+	}
+	c.changes = append(c.changes, fmt.Sprintf("+ %s (%s)", user, role))
+	return &github.TeamMembership{Membership: github.Membership{Role: role, State: github.StateActive}}, nil
+}
+
+func (c *teamDiffClient) RemoveTeamMembershipBySlug(org, teamSlug, user string) error {
+	c.changes = append(c.changes, fmt.Sprintf("- %s", user))
+	return nil
+}
+
 // teamMembersClient can list/remove/update people to a team.
 type teamMembersClient interface {
 	ListTeamMembersBySlug(org, teamSlug, role string) ([]github.TeamMember, error)
@@ -17,9 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"errors"
 	"flag"
 	"fmt"
+	"os"
 	"reflect"
 	"sort"
 	"testing"
@@ -117,6 +119,29 @@ func TestOptions(t *testing.T) {
 			name: "reject --fix-team-members without --fix-teams",
 			args: []string{"--config-path=foo", "--fix-team-members"},
 		},
+		{
+			name: "reject --diff-only without --fix-repos or --fix-team-members",
+			args: []string{"--config-path=foo", "--diff-only"},
+		},
+		{
+			name: "reject --diff-only and --confirm",
+			args: []string{"--config-path=foo", "--fix-repos", "--diff-only", "--confirm"},
+		},
+		{
+			name: "allow --diff-only with --fix-repos",
+			args: []string{"--config-path=foo", "--fix-repos", "--diff-only"},
+			expected: &options{
+				config:        "foo",
+				minAdmins:     defaultMinAdmins,
+				requireSelf:   true,
+				maximumDelta:  defaultDelta,
+				tokensPerHour: defaultTokens,
+				tokenBurst:    defaultBurst,
+				fixRepos:      true,
+				diffOnly:      true,
+				logLevel:      "info",
+			},
+		},
 		{
 			name: "allow legacy disabled throttle",
 			args: []string{"--config-path=foo", "--tokens=0"},
@@ -1385,6 +1410,168 @@ func TestConfigureTeamMembers(t *testing.T) {
 	}
 }
 
+type fakeOwnersClient map[string]string
+
+func (c fakeOwnersClient) GetFile(org, repo, filepath, commit string) ([]byte, error) {
+	content, ok := c[org+"/"+repo+"/"+filepath]
+	if !ok {
+		return nil, &github.FileNotFound{}
+	}
+	return []byte(content), nil
+}
+
+func TestWithOwnersMembers(t *testing.T) {
+	files := fakeOwnersClient{
+		"org/repo/OWNERS_ALIASES": `aliases:
+  sig-foo-leads:
+  - Alice
+  - bob
+  sig-foo-approvers:
+  - carol
+`,
+		"org/repo/sig-foo/OWNERS": `approvers:
+- sig-foo-approvers
+- dave
+`,
+		"org/repo/sig-baz/OWNERS": `approvers:
+- carol
+- grace
+`,
+		"org/other/pkg/OWNERS": `version: 2
+rules:
+- paths:
+  - api/
+  approvers:
+  - erin
+`,
+		"org/other/docs/OWNERS": `filters:
+  ".*\\.md$":
+    approvers:
+    - frank
+`,
+	}
+	orgMembers := sets.NewString("alice", "bob", "carol", "dave", "erin", "frank", "zed")
+
+	cases := []struct {
+		name      string
+		team      org.Team
+		expected  org.Team
+		expectErr bool
+	}{
+		{
+			name: "members of aliases join the team",
+			team: org.Team{Owners: &org.TeamOwners{Repos: []string{"org/repo"}, Aliases: []string{"sig-foo-leads"}}},
+			expected: org.Team{
+				Members: []string{"alice", "bob"},
+			},
+		},
+		{
+			name: "approvers of paths join the team with their aliases expanded",
+			team: org.Team{Owners: &org.TeamOwners{Repos: []string{"org/repo"}, Paths: []string{"sig-foo"}}},
+			expected: org.Team{
+				Members: []string{"carol", "dave"},
+			},
+		},
+		{
+			name: "approvers of filters and rules are aggregated across repos",
+			team: org.Team{Owners: &org.TeamOwners{Repos: []string{"org/repo", "org/other"}, Paths: []string{"pkg", "docs"}}},
+			expected: org.Team{
+				Members: []string{"erin", "frank"},
+			},
+		},
+		{
+			name: "excluded users don't join the team",
+			team: org.Team{Owners: &org.TeamOwners{Repos: []string{"org/repo"}, Aliases: []string{"sig-foo-leads"}, Paths: []string{"sig-foo"}, Exclude: []string{"Bob", "dave"}}},
+			expected: org.Team{
+				Members: []string{"alice", "carol"},
+			},
+		},
+		{
+			name: "listed users keep their role",
+			team: org.Team{
+				Members:     []string{"zed"},
+				Maintainers: []string{"alice"},
+				Owners:      &org.TeamOwners{Repos: []string{"org/repo"}, Aliases: []string{"sig-foo-leads"}},
+			},
+			expected: org.Team{
+				Members:     []string{"zed", "bob"},
+				Maintainers: []string{"alice"},
+			},
+		},
+		{
+			name: "users can join as maintainers",
+			team: org.Team{
+				Members: []string{"bob"},
+				Owners:  &org.TeamOwners{Repos: []string{"org/repo"}, Aliases: []string{"sig-foo-leads"}, Maintainers: true},
+			},
+			expected: org.Team{
+				Members:     []string{"bob"},
+				Maintainers: []string{"alice"},
+			},
+		},
+		{
+			name: "users who are not org members don't join the team",
+			team: org.Team{Owners: &org.TeamOwners{Repos: []string{"org/repo"}, Paths: []string{"sig-baz"}}},
+			expected: org.Team{
+				Members: []string{"carol"},
+			},
+		},
+		{
+			name:      "OWNERS file missing from every repo is an error",
+			team:      org.Team{Owners: &org.TeamOwners{Repos: []string{"org/repo"}, Paths: []string{"sig-bar"}}},
+			expectErr: true,
+		},
+		{
+			name:      "repo without org is an error",
+			team:      org.Team{Owners: &org.TeamOwners{Repos: []string{"repo"}}},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			team, err := withOwnersMembers(files, tc.team, orgMembers)
+			switch {
+			case err != nil:
+				if !tc.expectErr {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			case tc.expectErr:
+				t.Error("Failed to receive error")
+			default:
+				tc.expected.Owners = tc.team.Owners
+				if diff := cmp.Diff(tc.expected, team); diff != "" {
+					t.Errorf("Unexpected team (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestTeamDiffClient(t *testing.T) {
+	fc := &fakeClient{
+		admins:     sets.NewString("alice"),
+		members:    sets.NewString("bob", "carol"),
+		invitees:   sets.String{},
+		removed:    sets.String{},
+		newAdmins:  sets.String{},
+		newMembers: sets.String{},
+	}
+	diff := &teamDiffClient{teamMembersClient: fc}
+	team := org.Team{Members: []string{"alice", "bob", "dave"}, Maintainers: []string{"erin"}}
+	if err := configureTeamMembers(diff, "org", github.Team{Slug: configuredTeamSlug}, team); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sort.Strings(diff.changes)
+	expected := []string{"+ alice (member)", "+ dave (member)", "+ erin (maintainer)", "- carol"}
+	if d := cmp.Diff(expected, diff.changes); d != "" {
+		t.Errorf("Unexpected changes (-want +got):\n%s", d)
+	}
+	if len(fc.removed) != 0 || len(fc.newMembers) != 0 || len(fc.newAdmins) != 0 {
+		t.Errorf("Team memberships were changed: removed %v, added members %v and maintainers %v", fc.removed.List(), fc.newMembers.List(), fc.newAdmins.List())
+	}
+}
+
 func cmpLists(a, b []string) error {
 	if a == nil {
 		a = []string{}
@@ -2545,8 +2732,10 @@ func TestConfigureTeamRepos(t *testing.T) {
 }
 
 type fakeRepoClient struct {
-	t     *testing.T
-	repos map[string]github.FullRepo
+	t      *testing.T
+	repos  map[string]github.FullRepo
+	alerts map[string]bool
+	pages  map[string]github.RepoPages
 }
 
 func (f fakeRepoClient) GetRepo(owner, name string) (github.FullRepo, error) {
@@ -2639,10 +2828,68 @@ func (f fakeRepoClient) UpdateRepo(owner, name string, want github.RepoUpdateReq
 	return &have, nil
 }
 
+func (f fakeRepoClient) ReplaceRepoTopics(org, name string, topics []string) error {
+	repo, ok := f.repos[name]
+	if !ok {
+		return fmt.Errorf("repo not found")
+	}
+	if repo.Archived {
+		return fmt.Errorf("Repository was archived so is read-only.")
+	}
+	repo.Topics = topics
+	f.repos[name] = repo
+	return nil
+}
+
+func (f fakeRepoClient) GetVulnerabilityAlerts(org, name string) (bool, error) {
+	if name == "fail" {
+		return false, fmt.Errorf("injected GetVulnerabilityAlerts failure")
+	}
+	return f.alerts[name], nil
+}
+
+func (f fakeRepoClient) SetVulnerabilityAlerts(org, name string, enabled bool) error {
+	f.alerts[name] = enabled
+	return nil
+}
+
+func (f fakeRepoClient) GetRepoPages(org, name string) (*github.RepoPages, error) {
+	pages, ok := f.pages[name]
+	if !ok {
+		return nil, nil
+	}
+	return &pages, nil
+}
+
+func (f fakeRepoClient) CreateRepoPages(org, name string, pages github.RepoPages) error {
+	if _, ok := f.pages[name]; ok {
+		f.t.Errorf("CreateRepoPages() called on repo that already has pages")
+		return fmt.Errorf("CreateRepoPages() called on repo that already has pages")
+	}
+	f.pages[name] = github.RepoPages{Source: pages.Source}
+	return nil
+}
+
+func (f fakeRepoClient) UpdateRepoPages(org, name string, pages github.RepoPages) error {
+	if _, ok := f.pages[name]; !ok {
+		f.t.Errorf("UpdateRepoPages() called on repo without pages")
+		return fmt.Errorf("UpdateRepoPages() called on repo without pages")
+	}
+	f.pages[name] = pages
+	return nil
+}
+
+func (f fakeRepoClient) DeleteRepoPages(org, name string) error {
+	delete(f.pages, name)
+	return nil
+}
+
 func makeFakeRepoClient(t *testing.T, repos ...github.FullRepo) fakeRepoClient {
 	fc := fakeRepoClient{
-		repos: make(map[string]github.FullRepo, len(repos)),
-		t:     t,
+		repos:  make(map[string]github.FullRepo, len(repos)),
+		alerts: map[string]bool{},
+		pages:  map[string]github.RepoPages{},
+		t:      t,
 	}
 	for _, repo := range repos {
 		fc.repos[repo.Name] = repo
@@ -2989,6 +3236,189 @@ func TestConfigureRepos(t *testing.T) {
 	}
 }
 
+func TestConfigureRepoSettings(t *testing.T) {
+	orgName := "test-org"
+	yes := true
+	cname := "docs.example.com"
+	noCNAME := ""
+	docs := github.RepoPages{Source: github.RepoPagesSource{Branch: "main", Path: "/docs"}}
+
+	testCases := []struct {
+		description string
+		repo        org.Repo
+		archived    bool
+		topics      []string
+		alerts      bool
+		pages       *github.RepoPages
+
+		expectError  bool
+		expectTopics []string
+		expectAlerts bool
+		expectPages  *github.RepoPages
+	}{
+		{
+			description:  "unset settings are not changed",
+			topics:       []string{"kubernetes"},
+			alerts:       true,
+			pages:        &docs,
+			expectTopics: []string{"kubernetes"},
+			expectAlerts: true,
+			expectPages:  &docs,
+		},
+		{
+			description:  "topics are replaced",
+			repo:         org.Repo{Topics: []string{"prow", "kubernetes"}},
+			topics:       []string{"kubernetes", "ci"},
+			expectTopics: []string{"prow", "kubernetes"},
+		},
+		{
+			description:  "empty topics remove all topics",
+			repo:         org.Repo{Topics: []string{}},
+			topics:       []string{"kubernetes"},
+			expectTopics: []string{},
+		},
+		{
+			description:  "vulnerability alerts are enabled",
+			repo:         org.Repo{VulnerabilityAlerts: &yes},
+			expectAlerts: true,
+		},
+		{
+			description: "pages are created with the default path",
+			repo:        org.Repo{Pages: &org.RepoPages{Branch: "gh-pages"}},
+			expectPages: &github.RepoPages{Source: github.RepoPagesSource{Branch: "gh-pages", Path: "/"}},
+		},
+		{
+			description: "pages are created with a custom domain",
+			repo:        org.Repo{Pages: &org.RepoPages{Branch: "gh-pages", CNAME: &cname}},
+			expectPages: &github.RepoPages{CNAME: &cname, Source: github.RepoPagesSource{Branch: "gh-pages", Path: "/"}},
+		},
+		{
+			description: "pages are updated and keep their custom domain",
+			repo:        org.Repo{Pages: &org.RepoPages{Branch: "main"}},
+			pages:       &github.RepoPages{CNAME: &cname, Source: github.RepoPagesSource{Branch: "gh-pages", Path: "/"}},
+			expectPages: &github.RepoPages{CNAME: &cname, Source: github.RepoPagesSource{Branch: "main", Path: "/"}},
+		},
+		{
+			description: "custom domain of pages is removed",
+			repo:        org.Repo{Pages: &org.RepoPages{Branch: "main", Path: "/docs", CNAME: &noCNAME}},
+			pages:       &github.RepoPages{CNAME: &cname, Source: docs.Source},
+			expectPages: &github.RepoPages{Source: docs.Source},
+		},
+		{
+			description: "pages are disabled",
+			repo:        org.Repo{Pages: &org.RepoPages{}},
+			pages:       &docs,
+		},
+		{
+			description:  "topics are replaced before the repo is archived",
+			repo:         org.Repo{Archived: &yes, Topics: []string{"prow"}},
+			topics:       []string{"kubernetes"},
+			expectTopics: []string{"prow"},
+		},
+		{
+			description:  "topics of an archived repo are not replaced",
+			repo:         org.Repo{Topics: []string{"prow"}},
+			archived:     true,
+			topics:       []string{"kubernetes"},
+			expectError:  true,
+			expectTopics: []string{"kubernetes"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			fc := makeFakeRepoClient(t, github.FullRepo{Repo: github.Repo{Name: "repo", Archived: tc.archived}, Topics: tc.topics})
+			fc.alerts["repo"] = tc.alerts
+			if tc.pages != nil {
+				fc.pages["repo"] = *tc.pages
+			}
+			err := configureRepos(options{allowRepoArchival: true}, fc, orgName, org.Config{Repos: map[string]org.Repo{"repo": tc.repo}})
+			if err != nil && !tc.expectError {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err == nil && tc.expectError {
+				t.Error("expected error, got none")
+			}
+
+			if diff := cmp.Diff(tc.expectTopics, fc.repos["repo"].Topics); diff != "" {
+				t.Errorf("unexpected topics (-want +got):\n%s", diff)
+			}
+			if fc.alerts["repo"] != tc.expectAlerts {
+				t.Errorf("expected vulnerability alerts enabled to be %t, got %t", tc.expectAlerts, fc.alerts["repo"])
+			}
+			var pages *github.RepoPages
+			if p, ok := fc.pages["repo"]; ok {
+				pages = &p
+			}
+			if diff := cmp.Diff(tc.expectPages, pages); diff != "" {
+				t.Errorf("unexpected pages (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfigureReposDiffOnly(t *testing.T) {
+	orgName := "test-org"
+	yes := true
+	no := false
+	description := "A better description"
+	defaultBranch := "main"
+
+	existing := []github.FullRepo{
+		{Repo: github.Repo{Name: "old", Description: "An old description", DefaultBranch: "master"}, AllowMergeCommit: true, Topics: []string{"kubernetes"}},
+		{Repo: github.Repo{Name: "same", Description: description}},
+	}
+	fc := makeFakeRepoClient(t, existing...)
+	fc.pages["old"] = github.RepoPages{Source: github.RepoPagesSource{Branch: "gh-pages", Path: "/"}}
+
+	out := &bytes.Buffer{}
+	diffOutput = out
+	defer func() { diffOutput = os.Stdout }()
+
+	orgConfig := org.Config{
+		Repos: map[string]org.Repo{
+			"new": {Description: &description},
+			"old": {
+				Description:         &description,
+				DefaultBranch:       &defaultBranch,
+				AllowMergeCommit:    &no,
+				Topics:              []string{"prow", "kubernetes"},
+				VulnerabilityAlerts: &yes,
+				Pages:               &org.RepoPages{Branch: "main", Path: "/docs"},
+			},
+			"same": {Description: &description},
+		},
+	}
+	if err := configureRepos(options{diffOnly: true}, fc, orgName, orgConfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `test-org/new:
+  created
+test-org/old:
+  description: "An old description" -> "A better description"
+  allow_merge_commit: true -> false
+  default_branch: "master" -> "main"
+  topics: [kubernetes] -> [kubernetes, prow]
+  vulnerability_alerts: false -> true
+  pages: branch=gh-pages path=/ -> branch=main path=/docs
+`
+	if diff := cmp.Diff(expected, out.String()); diff != "" {
+		t.Errorf("unexpected diff output (-want +got):\n%s", diff)
+	}
+
+	after := makeFakeRepoClient(t, existing...)
+	after.pages["old"] = fc.pages["old"]
+	if diff := cmp.Diff(after.repos, fc.repos); diff != "" {
+		t.Errorf("--diff-only changed repos (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(after.alerts, fc.alerts); diff != "" {
+		t.Errorf("--diff-only changed vulnerability alerts (-want +got):\n%s", diff)
+	}
+	if fc.pages["old"].Source.Branch != "gh-pages" {
+		t.Errorf("--diff-only changed pages: %+v", fc.pages["old"])
+	}
+}
+
 func TestValidateRepos(t *testing.T) {
 	description := "cool repo"
 	testCases := []struct {
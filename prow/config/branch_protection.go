@@ -48,6 +48,9 @@ type Policy struct {
 	AllowForcePushes *bool `json:"allow_force_pushes,omitempty"`
 	// AllowDeletions allows deletion of the protected branch by anyone with write access to the repository.
 	AllowDeletions *bool `json:"allow_deletions,omitempty"`
+	// Rulesets protects the branch with a repository ruleset instead of branch protection,
+	// and removes the branch protection it replaces.
+	Rulesets *bool `json:"rulesets,omitempty"`
 	// Exclude specifies a set of regular expressions which identify branches
 	// that should be excluded from the protection policy, mutually exclusive with Include
 	Exclude []string `json:"exclude,omitempty"`
@@ -74,6 +77,8 @@ type ContextPolicy struct {
 	Contexts []string `json:"contexts,omitempty"`
 	// Strict overrides whether new commits in the base branch require updating the PR if set
 	Strict *bool `json:"strict,omitempty"`
+	// Tiers appends the contexts of the presubmits in these job tiers
+	Tiers []string `json:"tiers,omitempty"`
 }
 
 // ReviewPolicy specifies github approval/review criteria.
@@ -136,6 +141,7 @@ func mergeContextPolicy(parent, child *ContextPolicy) *ContextPolicy {
 	return &ContextPolicy{
 		Contexts: unionStrings(parent.Contexts, child.Contexts),
 		Strict:   selectBool(parent.Strict, child.Strict),
+		Tiers:    unionStrings(parent.Tiers, child.Tiers),
 	}
 }
 
@@ -177,6 +183,7 @@ func (p Policy) Apply(child Policy) Policy {
 		RequiredLinearHistory:      selectBool(p.RequiredLinearHistory, child.RequiredLinearHistory),
 		AllowForcePushes:           selectBool(p.AllowForcePushes, child.AllowForcePushes),
 		AllowDeletions:             selectBool(p.AllowDeletions, child.AllowDeletions),
+		Rulesets:                   selectBool(p.Rulesets, child.Rulesets),
 		Restrictions:               mergeRestrictions(p.Restrictions, child.Restrictions),
 		RequiredPullRequestReviews: mergeReviewPolicy(p.RequiredPullRequestReviews, child.RequiredPullRequestReviews),
 		Exclude:                    unionStrings(p.Exclude, child.Exclude),
@@ -202,6 +209,32 @@ type BranchProtection struct {
 	// ProtectReposWithOptionalJobs will make the Branchprotector manage required status
 	// contexts on repositories that only have optional jobs (default: false)
 	ProtectReposWithOptionalJobs *bool `json:"protect_repos_with_optional_jobs,omitempty"`
+	// JobTiers select presubmits by name, so that policies can require their contexts
+	// in required_status_checks.tiers instead of listing them by hand.
+	JobTiers map[string]JobTier `json:"job_tiers,omitempty"`
+}
+
+// JobTier selects the presubmits of a branch whose contexts are required.
+// Only the presubmits whose context GitHub can require are in a tier: like in
+// BranchRequirements, optional and skip_report presubmits are left out, and so
+// are the ones that run conditionally or when triggered, since their status may
+// never be reported on a PR.
+type JobTier struct {
+	// Labels selects the presubmits that have all of these labels.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Matches returns whether the presubmit is in the tier.
+func (t JobTier) Matches(job Presubmit) bool {
+	if !job.ContextRequired() || job.TriggersConditionally() {
+		return false
+	}
+	for key, value := range t.Labels {
+		if actual, ok := job.Labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
 }
 
 func isPolicySet(p Policy) bool {
@@ -267,6 +300,16 @@ func (bp *BranchProtection) merge(additional *BranchProtection) error {
 	} else if additional.ProtectReposWithOptionalJobs != nil {
 		bp.ProtectReposWithOptionalJobs = additional.ProtectReposWithOptionalJobs
 	}
+	for name, tier := range additional.JobTiers {
+		if _, ok := bp.JobTiers[name]; ok {
+			errs = append(errs, fmt.Errorf("both branchprotection configs define job tier %s", name))
+			continue
+		}
+		if bp.JobTiers == nil {
+			bp.JobTiers = map[string]JobTier{}
+		}
+		bp.JobTiers[name] = tier
+	}
 	for org := range additional.Orgs {
 		if bp.Orgs == nil {
 			bp.Orgs = map[string]Org{}
@@ -443,6 +486,16 @@ func (c *Config) GetPolicy(org, repo, branch string, b Branch, presubmits []Pres
 		policy = policy.Apply(ps)
 	}
 
+	if policy.RequiredStatusChecks != nil && len(policy.RequiredStatusChecks.Tiers) > 0 {
+		contexts, err := c.tierContexts(branch, policy.RequiredStatusChecks.Tiers, presubmits)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s=%s: %w", org, repo, branch, err)
+		}
+		checks := *policy.RequiredStatusChecks
+		checks.Contexts = unionStrings(checks.Contexts, contexts)
+		policy.RequiredStatusChecks = &checks
+	}
+
 	if policy.Protect != nil && !*policy.Protect {
 		// Ensure that protection is false => no protection settings
 		var old *bool
@@ -459,6 +512,23 @@ func (c *Config) GetPolicy(org, repo, branch string, b Branch, presubmits []Pres
 	return &policy, nil
 }
 
+// tierContexts returns the contexts of the presubmits of the branch in the job tiers.
+func (c *Config) tierContexts(branch string, tiers []string, presubmits []Presubmit) ([]string, error) {
+	contexts := sets.NewString()
+	for _, name := range tiers {
+		tier, ok := c.BranchProtection.JobTiers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown job tier %q", name)
+		}
+		for _, job := range presubmits {
+			if job.CouldRun(branch) && tier.Matches(job) {
+				contexts.Insert(job.Context)
+			}
+		}
+	}
+	return contexts.List(), nil
+}
+
 func (c *Config) shouldManageRequiredStatusCheck(requiredContexts, requiredIfPresentContexts, optionalContexts []string) bool {
 	if len(requiredContexts) > 0 {
 		return true
@@ -874,6 +874,133 @@ func TestConfig_GetBranchProtection(t *testing.T) {
 			},
 			expected: nil,
 		},
+		{
+			name: "job tiers require the contexts of their presubmits",
+			config: Config{
+				ProwConfig: ProwConfig{
+					BranchProtection: BranchProtection{
+						JobTiers: map[string]JobTier{
+							"release": {Labels: map[string]string{"tier": "release"}},
+							"unused":  {Labels: map[string]string{"tier": "unused"}},
+						},
+						Orgs: map[string]Org{
+							"org": {
+								Policy: Policy{
+									Protect: yes,
+									RequiredStatusChecks: &ContextPolicy{
+										Tiers: []string{"release"},
+									},
+								},
+							},
+						},
+					},
+				},
+				JobConfig: JobConfig{
+					PresubmitsStatic: map[string][]Presubmit{
+						"org/repo": {
+							{
+								JobBase:   JobBase{Name: "required"},
+								Reporter:  Reporter{Context: "required"},
+								AlwaysRun: true,
+							},
+							{
+								JobBase:   JobBase{Name: "release", Labels: map[string]string{"tier": "release"}},
+								Reporter:  Reporter{Context: "release"},
+								AlwaysRun: true,
+							},
+							{
+								JobBase:   JobBase{Name: "unused", Labels: map[string]string{"tier": "unused"}},
+								Reporter:  Reporter{Context: "unused"},
+								AlwaysRun: true,
+							},
+						},
+					},
+				},
+			},
+			expected: &Policy{
+				Protect: yes,
+				RequiredStatusChecks: &ContextPolicy{
+					Contexts: []string{"release", "required"},
+					Tiers:    []string{"release"},
+				},
+			},
+		},
+		{
+			name: "job tiers leave out presubmits whose context may be missing",
+			config: Config{
+				ProwConfig: ProwConfig{
+					BranchProtection: BranchProtection{
+						JobTiers: map[string]JobTier{
+							"release": {Labels: map[string]string{"tier": "release"}},
+						},
+						Orgs: map[string]Org{
+							"org": {
+								Policy: Policy{
+									Protect: yes,
+									RequiredStatusChecks: &ContextPolicy{
+										Contexts: []string{"cla"},
+										Tiers:    []string{"release"},
+									},
+								},
+							},
+						},
+					},
+				},
+				JobConfig: JobConfig{
+					PresubmitsStatic: map[string][]Presubmit{
+						"org/repo": {
+							{
+								JobBase:   JobBase{Name: "optional", Labels: map[string]string{"tier": "release"}},
+								Reporter:  Reporter{Context: "optional"},
+								AlwaysRun: true,
+								Optional:  true,
+							},
+							{
+								JobBase:   JobBase{Name: "skip-report", Labels: map[string]string{"tier": "release"}},
+								Reporter:  Reporter{Context: "skip-report", SkipReport: true},
+								AlwaysRun: true,
+							},
+							{
+								JobBase:             JobBase{Name: "conditional", Labels: map[string]string{"tier": "release"}},
+								Reporter:            Reporter{Context: "conditional"},
+								RegexpChangeMatcher: RegexpChangeMatcher{RunIfChanged: "^docs/"},
+							},
+							{
+								JobBase:  JobBase{Name: "manual", Labels: map[string]string{"tier": "release"}},
+								Reporter: Reporter{Context: "manual"},
+							},
+						},
+					},
+				},
+			},
+			expected: &Policy{
+				Protect: yes,
+				RequiredStatusChecks: &ContextPolicy{
+					Contexts: []string{"cla"},
+					Tiers:    []string{"release"},
+				},
+			},
+		},
+		{
+			name: "unknown job tier is an error",
+			config: Config{
+				ProwConfig: ProwConfig{
+					BranchProtection: BranchProtection{
+						Orgs: map[string]Org{
+							"org": {
+								Policy: Policy{
+									Protect: yes,
+									RequiredStatusChecks: &ContextPolicy{
+										Tiers: []string{"missing"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			err: true,
+		},
 	}
 
 	for _, tc := range testCases {
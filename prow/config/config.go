@@ -137,6 +137,8 @@ type ProwConfig struct {
 	GitHubReporter       GitHubReporter       `json:"github_reporter"`
 	Horologium           Horologium           `json:"horologium"`
 	SlackReporterConfigs SlackReporterConfigs `json:"slack_reporter_configs,omitempty"`
+	EmailReporter        EmailReporter        `json:"email_reporter,omitempty"`
+	TrackerReporter      TrackerReporter      `json:"tracker_reporter,omitempty"`
 	InRepoConfig         InRepoConfig         `json:"in_repo_config"`
 
 	// TODO: Move this out of the main config.
@@ -214,6 +216,11 @@ type InRepoConfig struct {
 	// a given repo. All clusters that are allowed for the specific repo, its org or
 	// globally can be used.
 	AllowedClusters map[string][]string `json:"allowed_clusters,omitempty"`
+	// AllowedIncludes lists the repos, as 'org/repo' or 'org' for all repos of an org,
+	// whose files the inrepoconfig of a repo may include. All repos that are allowed for
+	// the specific repo, its org or globally, using '*', 'org' or 'org/repo' as key, can
+	// be included. Includes of other repos are rejected.
+	AllowedIncludes map[string][]string `json:"allowed_includes,omitempty"`
 }
 
 func trimRepoPrefix(repo string) string {
@@ -280,6 +287,30 @@ func (c *Config) InRepoConfigAllowsCluster(clusterName, repoIdentifier string) b
 	return false
 }
 
+// InRepoConfigAllowsInclude determines if the inrepoconfig of a given repository may
+// include the files of another repository.
+// Assumes that config will not include http:// or https://
+func (c *Config) InRepoConfigAllowsInclude(includedRepo, repoIdentifier string) bool {
+	includedOrg, _, _ := SplitRepoName(includedRepo)
+	allows := func(key string) bool {
+		for _, allowed := range c.InRepoConfig.AllowedIncludes[key] {
+			if allowed == includedRepo || (includedOrg != "" && allowed == includedOrg) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if allows(trimRepoPrefix(repoIdentifier)) {
+		return true
+	}
+	// Errors if failed to split. We are ignoring this and just checking if org != "" instead
+	if org, _, _ := SplitRepoName(repoIdentifier); org != "" && allows(org) {
+		return true
+	}
+	return allows("*")
+}
+
 // RefGetter is used to retrieve a Git Reference. Its purpose is
 // to be able to defer calling out to GitHub in the context of
 // inrepoconfig to make sure its only done when we actually need
@@ -585,7 +616,10 @@ type Plank struct {
 	// collection on pending pods. Defaults to 10 minutes.
 	PodPendingTimeout *metav1.Duration `json:"pod_pending_timeout,omitempty"`
 	// PodRunningTimeout is after how long the controller will abort a prowjob pod
-	// stuck in running state. Defaults to two days.
+	// stuck in running state. Defaults to two days. Jobs with a decoration timeout
+	// are aborted once their timeout and grace period passed, if that is sooner.
+	// The pod is stopped gracefully and the job is only aborted once the pod
+	// terminated, so that its artifacts are uploaded.
 	PodRunningTimeout *metav1.Duration `json:"pod_running_timeout,omitempty"`
 	// PodUnscheduledTimeout is after how long the controller will abort a prowjob
 	// stuck in an unscheduled state. Defaults to 5 minutes.
@@ -740,6 +774,14 @@ func (p *Plank) mergeDefaultDecorationConfig(repo, cluster string, jobDC *prowap
 	return merged
 }
 
+// MergeDefaultDecorationConfig merges the default decoration config entries
+// matching the repo and cluster into the decoration config of a job. The
+// decoration config of jobs with a cluster selector is left as configured when
+// the config is loaded, plank merges the defaults once it has picked the cluster.
+func (p *Plank) MergeDefaultDecorationConfig(repo, cluster string, jobDC *prowapi.DecorationConfig) *prowapi.DecorationConfig {
+	return p.mergeDefaultDecorationConfig(repo, cluster, jobDC)
+}
+
 // GetProwJobDefault finds the resolved prowJobDefault config for a given repo and
 // cluster
 func (c *Config) GetProwJobDefault(repo, cluster string) *prowapi.ProwJobDefault {
@@ -978,11 +1020,15 @@ type LensFileConfig struct {
 
 // LensRemoteConfig is the configuration for a remote lens.
 type LensRemoteConfig struct {
-	// The endpoint for the lense
+	// The endpoint for the lense. http:// and https:// endpoints are called
+	// with HTTP and JSON, grpc:// and grpcs:// endpoints are called over
+	// gRPC, the latter with TLS.
 	Endpoint string `json:"endpoint"`
 	// The parsed endpoint
 	ParsedEndpoint *url.URL `json:"-"`
-	// The endpoint for static resources
+	// The endpoint for static resources. When set, the browser loads the
+	// resources of the lens, e.g. its scripts and stylesheets, from it instead
+	// of from Deck, so that lenses running as separate services can ship them.
 	StaticRoot string `json:"static_root"`
 	// The human-readable title for the lens
 	Title string `json:"title"`
@@ -1062,12 +1108,21 @@ type Deck struct {
 	ExternalAgentLogs []ExternalAgentLog `json:"external_agent_logs,omitempty"`
 	// Branding of the frontend
 	Branding *Branding `json:"branding,omitempty"`
+	// Tenants lets a single Deck serve several tenants when it is started with
+	// --enable-tenant-scoping. The ProwJobs, Tide pools and plugin help are then
+	// scoped to the tenant of each request, identified by the host of the request
+	// or the GitHub orgs of the logged in user.
+	Tenants []DeckTenant `json:"tenants,omitempty"`
 	// GoogleAnalytics, if specified, include a Google Analytics tracking code on each page.
 	GoogleAnalytics string `json:"google_analytics,omitempty"`
 	// RerunAuthConfigs is a map of configs that specify who is able to trigger job reruns. The field
 	// accepts a key of: `org/repo`, `org` or `*` (wildcard) to define what GitHub org (or repo) a particular
 	// config applies to and a value of: `RerunAuthConfig` struct to define the users/groups authorized to rerun jobs.
 	RerunAuthConfigs RerunAuthConfigs `json:"rerun_auth_configs,omitempty"`
+	// RerunAuthConfigsByJobType overrides RerunAuthConfigs for the jobs of a type, e.g. to only
+	// let release managers rerun postsubmits. The field accepts a job type as key and a map like
+	// RerunAuthConfigs as value, which is used if it has a config for the org or repo of the job.
+	RerunAuthConfigsByJobType map[prowapi.ProwJobType]RerunAuthConfigs `json:"rerun_auth_configs_by_job_type,omitempty"`
 	// SkipStoragePathValidation skips validation that restricts artifact requests to specific buckets.
 	// By default, buckets listed in the GCSConfiguration are automatically allowed.
 	// Additional locations can be allowed via `AdditionalAllowedBuckets` fields.
@@ -1096,6 +1151,34 @@ func (d *Deck) Validate() error {
 			}
 		}
 	}
+	tenantIDs, tenantHosts := sets.NewString(), sets.NewString()
+	for i, tenant := range d.Tenants {
+		if tenant.ID == "" || tenant.ID == DefaultTenantID {
+			return fmt.Errorf("tenants[%d]: id must be set and not be %q", i, DefaultTenantID)
+		}
+		if tenantIDs.Has(tenant.ID) {
+			return fmt.Errorf("tenants[%d]: duplicate id %q", i, tenant.ID)
+		}
+		tenantIDs.Insert(tenant.ID)
+		for _, host := range tenant.Hosts {
+			if tenantHosts.Has(host) {
+				return fmt.Errorf("tenants[%d]: host %q belongs to several tenants", i, host)
+			}
+			tenantHosts.Insert(host)
+		}
+	}
+	for jobType, configs := range d.RerunAuthConfigsByJobType {
+		switch jobType {
+		case prowapi.PresubmitJob, prowapi.PostsubmitJob, prowapi.PeriodicJob, prowapi.BatchJob:
+		default:
+			return fmt.Errorf("rerun_auth_configs_by_job_type: invalid job type %q", jobType)
+		}
+		for k, config := range configs {
+			if err := config.Validate(); err != nil {
+				return fmt.Errorf("rerun_auth_configs_by_job_type[%s][%s]: %w", jobType, k, err)
+			}
+		}
+	}
 
 	return nil
 }
@@ -1215,25 +1298,78 @@ type Branding struct {
 	HeaderColor string `json:"header_color,omitempty"`
 }
 
+// DeckTenant is a tenant served by Deck.
+type DeckTenant struct {
+	// ID is the tenant_id of the ProwJobs of the tenant.
+	ID string `json:"id"`
+	// Hosts are the hostnames Deck serves the tenant at, e.g. prow.tenant.example.com.
+	// They are matched against the header Deck's --tenant-host-header names, which
+	// the authenticating proxy in front of Deck sets.
+	Hosts []string `json:"hosts,omitempty"`
+	// Orgs are the GitHub orgs whose members are users of the tenant once they
+	// logged in with GitHub OAuth.
+	Orgs []string `json:"orgs,omitempty"`
+	// Branding overrides the branding of Deck for the tenant.
+	Branding *Branding `json:"branding,omitempty"`
+}
+
+// TenantForHost returns the tenant served at the host, if any.
+func (d *Deck) TenantForHost(host string) *DeckTenant {
+	for i, tenant := range d.Tenants {
+		for _, h := range tenant.Hosts {
+			if h == host {
+				return &d.Tenants[i]
+			}
+		}
+	}
+	return nil
+}
+
+// GetBranding returns the branding of the tenant, which defaults to the
+// branding of Deck.
+func (d *Deck) GetBranding(tenantID string) *Branding {
+	for _, tenant := range d.Tenants {
+		if tenant.ID == tenantID && tenant.Branding != nil {
+			return tenant.Branding
+		}
+	}
+	return d.Branding
+}
+
 // RerunAuthConfigs represents the configs for rerun authorization in Deck.
 // Use `org/repo`, `org` or `*` as key and a `RerunAuthConfig` struct as value.
 type RerunAuthConfigs map[string]prowapi.RerunAuthConfig
 
 // GetRerunAuthConfig returns the appropriate RerunAuthConfig based on the provided Refs.
 func (rac RerunAuthConfigs) GetRerunAuthConfig(refs *prowapi.Refs) prowapi.RerunAuthConfig {
-	if refs == nil || refs.Org == "" {
-		return rac["*"]
-	}
+	rerun, _ := rac.lookup(refs)
+	return rerun
+}
 
-	if rerun, exists := rac[fmt.Sprintf("%s/%s", refs.Org, refs.Repo)]; exists {
-		return rerun
+// lookup returns the RerunAuthConfig of the repo or org of the Refs or the wildcard, and
+// whether there is any of them.
+func (rac RerunAuthConfigs) lookup(refs *prowapi.Refs) (prowapi.RerunAuthConfig, bool) {
+	if refs != nil && refs.Org != "" {
+		if rerun, exists := rac[fmt.Sprintf("%s/%s", refs.Org, refs.Repo)]; exists {
+			return rerun, true
+		}
+
+		if rerun, exists := rac[refs.Org]; exists {
+			return rerun, true
+		}
 	}
 
-	if rerun, exists := rac[refs.Org]; exists {
+	rerun, exists := rac["*"]
+	return rerun, exists
+}
+
+// GetRerunAuthConfig returns the appropriate RerunAuthConfig for a job of the given type based on
+// the provided Refs, preferring the configs for the type over the ones for all jobs.
+func (d *Deck) GetRerunAuthConfig(jobType prowapi.ProwJobType, refs *prowapi.Refs) prowapi.RerunAuthConfig {
+	if rerun, exists := d.RerunAuthConfigsByJobType[jobType].lookup(refs); exists {
 		return rerun
 	}
-
-	return rac["*"]
+	return d.RerunAuthConfigs.GetRerunAuthConfig(refs)
 }
 
 const (
@@ -1288,6 +1424,81 @@ type ManagedWebhooks struct {
 type SlackReporter struct {
 	JobTypesToReport            []prowapi.ProwJobType `json:"job_types_to_report,omitempty"`
 	prowapi.SlackReporterConfig `json:",inline"`
+	// Routes send the reports of jobs to other channels than the channel of the
+	// reporter. The first route that matches a job is used. A channel set on the
+	// job takes precedence over the routes.
+	Routes []SlackReporterRoute `json:"routes,omitempty"`
+	// MessagesPerMinute limits how many messages are posted to each channel per
+	// minute. Reports over the limit are delayed. Defaults to no limit.
+	MessagesPerMinute int `json:"messages_per_minute,omitempty"`
+	// ThreadRetries posts the reports of reruns of a job for the same commits,
+	// e.g. of a presubmit for the same head of a pull request, as replies in the
+	// thread of the first report. Threads are tracked by crier in memory.
+	ThreadRetries bool `json:"thread_retries,omitempty"`
+}
+
+// SlackReporterRoute routes the reports of the jobs it matches to a channel.
+// A route without any filters matches all jobs.
+type SlackReporterRoute struct {
+	// Repos limits the route to the jobs of these orgs or repos, given as
+	// org or org/repo.
+	Repos []string `json:"repos,omitempty"`
+	// Jobs is a regex the name of the job must match.
+	Jobs string `json:"jobs,omitempty"`
+	// JobStates limits the route to jobs in these states.
+	JobStates []prowapi.ProwJobState `json:"job_states,omitempty"`
+	// Host is the Slack host to post to. Defaults to the host of the reporter.
+	Host string `json:"host,omitempty"`
+	// Channel is the channel to post to.
+	Channel string `json:"channel"`
+	// ReportTemplate replaces the report template of the reporter for the jobs
+	// of the route.
+	ReportTemplate string `json:"report_template,omitempty"`
+}
+
+// Matches returns whether the route applies to the job.
+func (r *SlackReporterRoute) Matches(pj *prowapi.ProwJob) bool {
+	if len(r.Repos) > 0 {
+		refs := pj.Spec.Refs
+		if refs == nil && len(pj.Spec.ExtraRefs) > 0 {
+			refs = &pj.Spec.ExtraRefs[0]
+		}
+		if refs == nil {
+			return false
+		}
+		repos := sets.NewString(r.Repos...)
+		if !repos.Has(refs.Org) && !repos.Has(fmt.Sprintf("%s/%s", refs.Org, refs.Repo)) {
+			return false
+		}
+	}
+	if r.Jobs != "" {
+		if matched, err := regexp.MatchString(r.Jobs, pj.Spec.Job); err != nil || !matched {
+			return false
+		}
+	}
+	if len(r.JobStates) > 0 {
+		var stateMatches bool
+		for _, state := range r.JobStates {
+			if state == pj.Status.State {
+				stateMatches = true
+				break
+			}
+		}
+		if !stateMatches {
+			return false
+		}
+	}
+	return true
+}
+
+// RouteFor returns the first route that matches the job, or nil if none does.
+func (cfg *SlackReporter) RouteFor(pj *prowapi.ProwJob) *SlackReporterRoute {
+	for i := range cfg.Routes {
+		if cfg.Routes[i].Matches(pj) {
+			return &cfg.Routes[i]
+		}
+	}
+	return nil
 }
 
 // SlackReporterConfigs represents the config for the Slack reporter(s).
@@ -1321,14 +1532,258 @@ func (cfg *SlackReporter) DefaultAndValidate() error {
 	}
 
 	// Validate ReportTemplate
-	tmpl, err := template.New("").Parse(cfg.ReportTemplate)
+	if err := validateSlackReportTemplate(cfg.ReportTemplate); err != nil {
+		return err
+	}
+
+	if cfg.MessagesPerMinute < 0 {
+		return errors.New("messages_per_minute must not be negative")
+	}
+	for i, route := range cfg.Routes {
+		if route.Channel == "" {
+			return fmt.Errorf("routes[%d]: channel must be set", i)
+		}
+		if _, err := regexp.Compile(route.Jobs); err != nil {
+			return fmt.Errorf("routes[%d]: invalid jobs regex: %w", i, err)
+		}
+		if route.ReportTemplate != "" {
+			if err := validateSlackReportTemplate(route.ReportTemplate); err != nil {
+				return fmt.Errorf("routes[%d]: %w", i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateSlackReportTemplate(reportTemplate string) error {
+	tmpl, err := template.New("").Parse(reportTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse template: %w", err)
 	}
 	if err := tmpl.Execute(&bytes.Buffer{}, &prowapi.ProwJob{}); err != nil {
 		return fmt.Errorf("failed to execute report_template: %w", err)
 	}
+	return nil
+}
+
+// EmailReporter is the config of the email reporter of crier, which sends
+// digests of the results of periodic jobs by email.
+type EmailReporter struct {
+	// From is the address the emails are sent from.
+	From string `json:"from,omitempty"`
+	// Digests are the digests the reporter sends.
+	Digests []EmailDigest `json:"digests,omitempty"`
+}
+
+// EmailDigest sends the failures of periodic jobs and the changes of their
+// state from passing to failing and back to a list of recipients.
+type EmailDigest struct {
+	// Name identifies the digest. It is available to the templates as .Name.
+	Name string `json:"name"`
+	// Jobs is a regex the names of the periodic jobs of the digest must match.
+	Jobs string `json:"jobs"`
+	// To are the addresses the digest is sent to.
+	To []string `json:"to"`
+	// Interval is how often the failures of the jobs since the last digest
+	// are sent, if there are any. Digests are sent at multiples of it, like
+	// at every full hour for 1h.
+	Interval *prowapi.Duration `json:"interval,omitempty"`
+	// OnTransition sends an email as soon as a job starts failing or passes
+	// again.
+	OnTransition bool `json:"on_transition,omitempty"`
+	// SubjectTemplate is the Go template of the subject of the emails. It is
+	// executed on the .Name of the digest, its .Failures, a list of ProwJobs,
+	// and its .Transitions, a list of the ProwJob of each .Job whose state
+	// changed and its .PreviousState.
+	SubjectTemplate string `json:"subject_template,omitempty"`
+	// BodyTemplate is the Go template of the plain text body of the emails.
+	// It is executed on the same data as the SubjectTemplate.
+	BodyTemplate string `json:"body_template,omitempty"`
+}
+
+const (
+	// DefaultEmailSubjectTemplate is the default subject of email digests.
+	DefaultEmailSubjectTemplate = `[{{.Name}}] {{len .Failures}} failed runs, {{len .Transitions}} state changes of periodic jobs`
+	// DefaultEmailBodyTemplate is the default body of email digests.
+	DefaultEmailBodyTemplate = `{{range .Transitions}}{{.Job.Spec.Job}} changed from {{.PreviousState}} to {{.Job.Status.State}}: {{.Job.Status.URL}}
+{{end}}{{range .Failures}}{{.Spec.Job}} ended with state {{.Status.State}}: {{.Status.URL}}
+{{end}}`
+)
+
+// Matches returns whether the job belongs to the digest.
+func (d *EmailDigest) Matches(pj *prowapi.ProwJob) bool {
+	if pj.Spec.Type != prowapi.PeriodicJob {
+		return false
+	}
+	matched, err := regexp.MatchString(d.Jobs, pj.Spec.Job)
+	return err == nil && matched
+}
 
+// DefaultAndValidate defaults the templates of the digests and validates the config.
+func (cfg *EmailReporter) DefaultAndValidate() error {
+	if len(cfg.Digests) > 0 && cfg.From == "" {
+		return errors.New("from must be set")
+	}
+	names := sets.NewString()
+	for i := range cfg.Digests {
+		digest := &cfg.Digests[i]
+		if digest.Name == "" {
+			return fmt.Errorf("digests[%d]: name must be set", i)
+		}
+		if names.Has(digest.Name) {
+			return fmt.Errorf("digests[%d]: name %q is not unique", i, digest.Name)
+		}
+		names.Insert(digest.Name)
+		if digest.Jobs == "" {
+			return fmt.Errorf("digest %s: jobs must be set", digest.Name)
+		}
+		if _, err := regexp.Compile(digest.Jobs); err != nil {
+			return fmt.Errorf("digest %s: invalid jobs regex: %w", digest.Name, err)
+		}
+		if len(digest.To) == 0 {
+			return fmt.Errorf("digest %s: to must be set", digest.Name)
+		}
+		if digest.Interval == nil && !digest.OnTransition {
+			return fmt.Errorf("digest %s: one of interval or on_transition must be set", digest.Name)
+		}
+		if digest.Interval != nil && digest.Interval.Duration <= 0 {
+			return fmt.Errorf("digest %s: interval must be positive", digest.Name)
+		}
+		if digest.SubjectTemplate == "" {
+			digest.SubjectTemplate = DefaultEmailSubjectTemplate
+		}
+		if digest.BodyTemplate == "" {
+			digest.BodyTemplate = DefaultEmailBodyTemplate
+		}
+		if _, err := template.New("").Parse(digest.SubjectTemplate); err != nil {
+			return fmt.Errorf("digest %s: failed to parse subject_template: %w", digest.Name, err)
+		}
+		if _, err := template.New("").Parse(digest.BodyTemplate); err != nil {
+			return fmt.Errorf("digest %s: failed to parse body_template: %w", digest.Name, err)
+		}
+	}
+	return nil
+}
+
+// TrackerReporter is the config of the tracker reporter of crier, which
+// files issues in Bugzilla or Jira for jobs that keep failing.
+type TrackerReporter struct {
+	// Rules select the jobs to file issues for. The first rule that matches
+	// a job applies.
+	Rules []TrackerRule `json:"rules,omitempty"`
+}
+
+// TrackerRule files an issue for a job once it failed a number of times in a
+// row, and comments on or closes the issue once the job passes again.
+type TrackerRule struct {
+	// Jobs is a regex the names of the jobs of the rule must match. Presubmits
+	// are never matched, their failures depend on the pull request.
+	Jobs string `json:"jobs"`
+	// ConsecutiveFailures is how many runs of a job must fail in a row
+	// before an issue is filed. Defaults to 3.
+	ConsecutiveFailures int `json:"consecutive_failures,omitempty"`
+	// CloseOnRecovery closes the issue once the job passes again. Otherwise
+	// the issue is only commented on.
+	CloseOnRecovery bool `json:"close_on_recovery,omitempty"`
+	// Bugzilla files the issues in Bugzilla. Exactly one of Bugzilla or Jira
+	// must be set.
+	Bugzilla *BugzillaTracker `json:"bugzilla,omitempty"`
+	// Jira files the issues in Jira.
+	Jira *JiraTracker `json:"jira,omitempty"`
+}
+
+// BugzillaTracker is where issues are filed in Bugzilla.
+type BugzillaTracker struct {
+	Product   string `json:"product"`
+	Component string `json:"component"`
+	// Version is the version bugs are filed against. Defaults to "unspecified".
+	Version string `json:"version,omitempty"`
+	// ClosedStatus is the status bugs are closed with. Defaults to "RESOLVED".
+	ClosedStatus string `json:"closed_status,omitempty"`
+	// ClosedResolution is the resolution bugs are closed with. Defaults to "FIXED".
+	ClosedResolution string `json:"closed_resolution,omitempty"`
+}
+
+// JiraTracker is where issues are filed in Jira.
+type JiraTracker struct {
+	// Project is the key of the project.
+	Project string `json:"project"`
+	// IssueType is the type of the issues. Defaults to "Bug".
+	IssueType string `json:"issue_type,omitempty"`
+	// CloseTransition is the name of the transition issues are closed with.
+	// Defaults to "Done".
+	CloseTransition string `json:"close_transition,omitempty"`
+}
+
+// DefaultConsecutiveFailures is how many runs of a job must fail in a row
+// before the tracker reporter files an issue by default.
+const DefaultConsecutiveFailures = 3
+
+// Matches returns whether the rule applies to the job.
+func (r *TrackerRule) Matches(pj *prowapi.ProwJob) bool {
+	if pj.Spec.Type == prowapi.PresubmitJob {
+		return false
+	}
+	matched, err := regexp.MatchString(r.Jobs, pj.Spec.Job)
+	return err == nil && matched
+}
+
+// RuleFor returns the first rule that matches the job, or nil if none does.
+func (cfg *TrackerReporter) RuleFor(pj *prowapi.ProwJob) *TrackerRule {
+	for i := range cfg.Rules {
+		if cfg.Rules[i].Matches(pj) {
+			return &cfg.Rules[i]
+		}
+	}
+	return nil
+}
+
+// DefaultAndValidate defaults the rules and validates the config.
+func (cfg *TrackerReporter) DefaultAndValidate() error {
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		if rule.Jobs == "" {
+			return fmt.Errorf("rules[%d]: jobs must be set", i)
+		}
+		if _, err := regexp.Compile(rule.Jobs); err != nil {
+			return fmt.Errorf("rules[%d]: invalid jobs regex: %w", i, err)
+		}
+		if rule.ConsecutiveFailures == 0 {
+			rule.ConsecutiveFailures = DefaultConsecutiveFailures
+		}
+		if rule.ConsecutiveFailures < 0 {
+			return fmt.Errorf("rules[%d]: consecutive_failures must be positive", i)
+		}
+		if (rule.Bugzilla == nil) == (rule.Jira == nil) {
+			return fmt.Errorf("rules[%d]: exactly one of bugzilla or jira must be set", i)
+		}
+		if rule.Bugzilla != nil {
+			if rule.Bugzilla.Product == "" || rule.Bugzilla.Component == "" {
+				return fmt.Errorf("rules[%d]: bugzilla product and component must be set", i)
+			}
+			if rule.Bugzilla.Version == "" {
+				rule.Bugzilla.Version = "unspecified"
+			}
+			if rule.Bugzilla.ClosedStatus == "" {
+				rule.Bugzilla.ClosedStatus = "RESOLVED"
+			}
+			if rule.Bugzilla.ClosedResolution == "" {
+				rule.Bugzilla.ClosedResolution = "FIXED"
+			}
+		}
+		if rule.Jira != nil {
+			if rule.Jira.Project == "" {
+				return fmt.Errorf("rules[%d]: jira project must be set", i)
+			}
+			if rule.Jira.IssueType == "" {
+				rule.Jira.IssueType = "Bug"
+			}
+			if rule.Jira.CloseTransition == "" {
+				rule.Jira.CloseTransition = "Done"
+			}
+		}
+	}
 	return nil
 }
 
@@ -1399,6 +1854,8 @@ func ReadJobConfig(jobConfig string, yamlOpts ...yaml.JSONOpt) (JobConfig, error
 	jobConfigCount := 0
 	allStart := time.Now()
 	jc := JobConfig{}
+	var paths []string
+	var contents [][]byte
 	err = filepath.Walk(jobConfig, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			logrus.WithError(err).Errorf("walking path %q.", path)
@@ -1433,21 +1890,58 @@ func ReadJobConfig(jobConfig string, yamlOpts ...yaml.JSONOpt) (JobConfig, error
 		}
 		uniqueBasenames.Insert(base)
 
+		b, err := ReadFileMaybeGZIP(path)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", path, err)
+		}
+		paths = append(paths, path)
+		contents = append(contents, b)
+		return nil
+	})
+	if err != nil {
+		return JobConfig{}, err
+	}
+
+	// The jobs of a file may inherit the templates of any file, they are
+	// collected before the files are unmarshaled.
+	resolver := newJobTemplateResolver()
+	raws := make([]map[string]interface{}, len(contents))
+	for i, b := range contents {
+		if !usesJobTemplates(b) {
+			continue
+		}
+		if raws[i], err = decodeJobConfig(b); err != nil {
+			return JobConfig{}, fmt.Errorf("error unmarshaling %s: %w", paths[i], err)
+		}
+		if err := resolver.collect(paths[i], raws[i]); err != nil {
+			return JobConfig{}, fmt.Errorf("error reading the job templates of %s: %w", paths[i], err)
+		}
+	}
+
+	// Overlays apply to the jobs of the files that don't use templates too.
+	if len(resolver.overlays) > 0 {
+		for i, b := range contents {
+			if raws[i] != nil {
+				continue
+			}
+			if raws[i], err = decodeJobConfig(b); err != nil {
+				return JobConfig{}, fmt.Errorf("error unmarshaling %s: %w", paths[i], err)
+			}
+		}
+	}
+
+	for i, path := range paths {
 		fileStart := time.Now()
 		var subConfig JobConfig
-		if err := yamlToConfig(path, &subConfig, yamlOpts...); err != nil {
-			return err
+		if err := bytesToConfig(path, contents[i], raws[i], resolver, &subConfig, yamlOpts...); err != nil {
+			return JobConfig{}, err
 		}
 		jc, err = mergeJobConfigs(jc, subConfig)
-		if err == nil {
-			logrus.WithField("jobConfig", path).WithField("duration", time.Since(fileStart)).Traceln("config loaded")
-			jobConfigCount++
+		if err != nil {
+			return JobConfig{}, err
 		}
-		return err
-	})
-
-	if err != nil {
-		return JobConfig{}, err
+		logrus.WithField("jobConfig", path).WithField("duration", time.Since(fileStart)).Traceln("config loaded")
+		jobConfigCount++
 	}
 	logrus.WithField("count", jobConfigCount).WithField("duration", time.Since(allStart)).Traceln("jobConfigs loaded successfully")
 
@@ -1594,12 +2088,43 @@ func loadConfig(prowConfig, jobConfig string, additionalProwConfigDirs []string,
 	return &nc, nil
 }
 
-// yamlToConfig converts a yaml file into a Config object.
+// yamlToConfig converts a yaml file into a Config object. The jobs of the
+// file may inherit the job templates it declares.
 func yamlToConfig(path string, nc interface{}, opts ...yaml.JSONOpt) error {
 	b, err := ReadFileMaybeGZIP(path)
 	if err != nil {
 		return fmt.Errorf("error reading %s: %w", path, err)
 	}
+	var raw map[string]interface{}
+	var resolver *jobTemplateResolver
+	switch nc.(type) {
+	case *JobConfig, *Config:
+		if usesJobTemplates(b) {
+			if raw, err = decodeJobConfig(b); err != nil {
+				return fmt.Errorf("error unmarshaling %s: %w", path, err)
+			}
+			resolver = newJobTemplateResolver()
+			if err := resolver.collect(path, raw); err != nil {
+				return fmt.Errorf("error reading the job templates of %s: %w", path, err)
+			}
+		}
+	}
+	return bytesToConfig(path, b, raw, resolver, nc, opts...)
+}
+
+// bytesToConfig converts the yaml content of a file into a Config object. If
+// the file uses job templates, raw is its decoded content without the job
+// templates, collected by the resolver.
+func bytesToConfig(path string, b []byte, raw map[string]interface{}, resolver *jobTemplateResolver, nc interface{}, opts ...yaml.JSONOpt) error {
+	if raw != nil {
+		if err := resolver.resolve(raw); err != nil {
+			return fmt.Errorf("error resolving the job templates of %s: %w", path, err)
+		}
+		var err error
+		if b, err = json.Marshal(raw); err != nil {
+			return fmt.Errorf("error marshaling %s: %w", path, err)
+		}
+	}
 	if err := yaml.Unmarshal(b, nc, opts...); err != nil {
 		return fmt.Errorf("error unmarshaling %s: %w", path, err)
 	}
@@ -1735,11 +2260,11 @@ func shouldDecorate(c *JobConfig, util *UtilityConfig) bool {
 }
 
 func setPresubmitProwJobDefaults(c *Config, ps *Presubmit, repo string) {
-	ps.ProwJobDefault = c.mergeProwJobDefault(repo, ps.Cluster, ps.ProwJobDefault)
+	ps.ProwJobDefault = c.mergeProwJobDefault(repo, c.defaultsCluster(ps.JobBase), ps.ProwJobDefault)
 }
 
 func setPostsubmitProwJobDefaults(c *Config, ps *Postsubmit, repo string) {
-	ps.ProwJobDefault = c.mergeProwJobDefault(repo, ps.Cluster, ps.ProwJobDefault)
+	ps.ProwJobDefault = c.mergeProwJobDefault(repo, c.defaultsCluster(ps.JobBase), ps.ProwJobDefault)
 }
 
 func setPeriodicProwJobDefaults(c *Config, ps *Periodic) {
@@ -1748,17 +2273,17 @@ func setPeriodicProwJobDefaults(c *Config, ps *Periodic) {
 		repo = fmt.Sprintf("%s/%s", ps.UtilityConfig.ExtraRefs[0].Org, ps.UtilityConfig.ExtraRefs[0].Repo)
 	}
 
-	ps.ProwJobDefault = c.mergeProwJobDefault(repo, ps.Cluster, ps.ProwJobDefault)
+	ps.ProwJobDefault = c.mergeProwJobDefault(repo, c.defaultsCluster(ps.JobBase), ps.ProwJobDefault)
 }
 func setPresubmitDecorationDefaults(c *Config, ps *Presubmit, repo string) {
 	if shouldDecorate(&c.JobConfig, &ps.JobBase.UtilityConfig) {
-		ps.DecorationConfig = c.Plank.mergeDefaultDecorationConfig(repo, ps.Cluster, ps.DecorationConfig)
+		ps.DecorationConfig = c.jobDecorationConfig(ps.JobBase, repo)
 	}
 }
 
 func setPostsubmitDecorationDefaults(c *Config, ps *Postsubmit, repo string) {
 	if shouldDecorate(&c.JobConfig, &ps.JobBase.UtilityConfig) {
-		ps.DecorationConfig = c.Plank.mergeDefaultDecorationConfig(repo, ps.Cluster, ps.DecorationConfig)
+		ps.DecorationConfig = c.jobDecorationConfig(ps.JobBase, repo)
 	}
 }
 
@@ -1769,8 +2294,88 @@ func setPeriodicDecorationDefaults(c *Config, ps *Periodic) {
 			repo = fmt.Sprintf("%s/%s", ps.UtilityConfig.ExtraRefs[0].Org, ps.UtilityConfig.ExtraRefs[0].Repo)
 		}
 
-		ps.DecorationConfig = c.Plank.mergeDefaultDecorationConfig(repo, ps.Cluster, ps.DecorationConfig)
+		ps.DecorationConfig = c.jobDecorationConfig(ps.JobBase, repo)
+	}
+}
+
+// jobDecorationConfig returns the decoration config of a decorated job with
+// the default decoration config entries merged in. Jobs with a cluster selector
+// keep their own decoration config, which plank merges the defaults of the
+// cluster it picks into, see Plank.MergeDefaultDecorationConfig.
+func (c *Config) jobDecorationConfig(jb JobBase, repo string) *prowapi.DecorationConfig {
+	if jb.ClusterSelector == nil {
+		return c.Plank.mergeDefaultDecorationConfig(repo, jb.Cluster, jb.DecorationConfig)
+	}
+	if jb.DecorationConfig == nil {
+		// A decoration config marks the job as decorated.
+		return &prowapi.DecorationConfig{}
+	}
+	return jb.DecorationConfig
+}
+
+// defaultsCluster returns the cluster to merge the ProwJob defaults of a job
+// for. The ProwJob defaults of jobs with a cluster selector are set when the
+// job is triggered, before plank picks the cluster, so they must be the same
+// in all the clusters the job may run in, see validateClusterSelectorDefaults.
+func (c *Config) defaultsCluster(jb JobBase) string {
+	if jb.ClusterSelector == nil {
+		return jb.Cluster
+	}
+	return c.selectableClusters(jb.ClusterSelector)[0]
+}
+
+// selectableClusters returns the clusters that a job with the cluster selector
+// may be scheduled to, as far as the cluster-specific defaults can tell them
+// apart: the candidate clusters of the selector, or if it has none, the
+// clusters named in the default entries plus an empty cluster, which stands
+// for all the others.
+func (c *Config) selectableClusters(selector *prowapi.ClusterSelector) []string {
+	if len(selector.Clusters) > 0 {
+		return selector.Clusters
+	}
+	clusters := sets.NewString("")
+	for _, entry := range c.Plank.DefaultDecorationConfigs {
+		if entry.Cluster != "*" {
+			clusters.Insert(entry.Cluster)
+		}
+	}
+	for _, entry := range c.ProwJobDefaultEntries {
+		if entry.Cluster != "*" {
+			clusters.Insert(entry.Cluster)
+		}
+	}
+	return clusters.List()
+}
+
+// validateClusterSelectorDefaults validates the defaults of a job with a
+// cluster selector in each of the clusters it may be scheduled to: its
+// decoration config must be valid with the defaults of any of them merged in,
+// and its ProwJob defaults, which hold the tenant of the job, must be the same
+// in all of them. The job must not have been defaulted yet.
+func (c *Config) validateClusterSelectorDefaults(jb JobBase, repo string) error {
+	if jb.ClusterSelector == nil {
+		return nil
+	}
+	var errs []error
+	var prowJobDefault *prowapi.ProwJobDefault
+	for _, cluster := range c.selectableClusters(jb.ClusterSelector) {
+		if jb.Spec != nil && shouldDecorate(&c.JobConfig, &jb.UtilityConfig) {
+			dc := c.Plank.mergeDefaultDecorationConfig(repo, cluster, jb.DecorationConfig)
+			for _, container := range jb.Spec.Containers {
+				if err := validateDecoration(container, dc); err != nil {
+					errs = append(errs, fmt.Errorf("job %s in cluster %q: %w", jb.Name, cluster, err))
+				}
+			}
+		}
+		merged := c.mergeProwJobDefault(repo, cluster, jb.ProwJobDefault)
+		if prowJobDefault == nil {
+			prowJobDefault = merged
+		} else if *merged != *prowJobDefault {
+			errs = append(errs, fmt.Errorf("the prowjob defaults of job %s differ between the clusters its cluster_selector allows, but they are set before plank picks the cluster", jb.Name))
+			break
+		}
 	}
+	return utilerrors.NewAggregate(errs)
 }
 
 // defaultPresubmits defaults the presubmits for one repo
@@ -1778,6 +2383,9 @@ func defaultPresubmits(presubmits []Presubmit, additionalPresets []Preset, c *Co
 	c.defaultPresubmitFields(presubmits)
 	var errs []error
 	for idx, ps := range presubmits {
+		if err := c.validateClusterSelectorDefaults(ps.JobBase, repo); err != nil {
+			errs = append(errs, err)
+		}
 		setPresubmitDecorationDefaults(c, &presubmits[idx], repo)
 		setPresubmitProwJobDefaults(c, &presubmits[idx], repo)
 		if err := resolvePresets(ps.Name, ps.Labels, ps.Spec, append(c.Presets, additionalPresets...)); err != nil {
@@ -1796,6 +2404,9 @@ func defaultPostsubmits(postsubmits []Postsubmit, additionalPresets []Preset, c
 	c.defaultPostsubmitFields(postsubmits)
 	var errs []error
 	for idx, ps := range postsubmits {
+		if err := c.validateClusterSelectorDefaults(ps.JobBase, repo); err != nil {
+			errs = append(errs, err)
+		}
 		setPostsubmitDecorationDefaults(c, &postsubmits[idx], repo)
 		setPostsubmitProwJobDefaults(c, &postsubmits[idx], repo)
 		if err := resolvePresets(ps.Name, ps.Labels, ps.Spec, append(c.Presets, additionalPresets...)); err != nil {
@@ -1811,6 +2422,13 @@ func defaultPostsubmits(postsubmits []Postsubmit, additionalPresets []Preset, c
 // DefaultPeriodic defaults (mutates) a single Periodic
 func (c *Config) DefaultPeriodic(periodic *Periodic) error {
 	c.defaultPeriodicFields(periodic)
+	var repo string
+	if len(periodic.UtilityConfig.ExtraRefs) > 0 {
+		repo = fmt.Sprintf("%s/%s", periodic.UtilityConfig.ExtraRefs[0].Org, periodic.UtilityConfig.ExtraRefs[0].Repo)
+	}
+	if err := c.validateClusterSelectorDefaults(periodic.JobBase, repo); err != nil {
+		return err
+	}
 	setPeriodicDecorationDefaults(c, periodic)
 	setPeriodicProwJobDefaults(c, periodic)
 	return resolvePresets(periodic.Name, periodic.Labels, periodic.Spec, c.Presets)
@@ -1886,6 +2504,14 @@ func (c *Config) validateComponentConfig() error {
 		}
 	}
 
+	if err := c.EmailReporter.DefaultAndValidate(); err != nil {
+		return fmt.Errorf("failed to validate email_reporter config: %w", err)
+	}
+
+	if err := c.TrackerReporter.DefaultAndValidate(); err != nil {
+		return fmt.Errorf("failed to validate tracker_reporter config: %w", err)
+	}
+
 	if err := c.Deck.Validate(); err != nil {
 		return err
 	}
@@ -1924,9 +2550,20 @@ func validateJobBase(v JobBase, jobType prowapi.ProwJobType, podNamespace string
 	if err := v.RerunAuthConfig.Validate(); err != nil {
 		return err
 	}
+	if err := v.RetryPolicy.Validate(); err != nil {
+		return err
+	}
 	if err := v.UtilityConfig.Validate(); err != nil {
 		return err
 	}
+	if v.DecorationConfig != nil && len(v.DecorationConfig.Steps) > 0 && len(v.Spec.Containers) > 1 {
+		return errors.New("decorated jobs with steps must have a single container")
+	}
+	if v.ClusterSelector != nil {
+		// The defaults of the clusters are not merged in yet, see
+		// validateClusterSelectorDefaults.
+		return nil
+	}
 	for i := range v.Spec.Containers {
 		if err := validateDecoration(v.Spec.Containers[i], v.DecorationConfig); err != nil {
 			return err
@@ -1966,6 +2603,17 @@ func validatePresubmits(presubmits []Presubmit, podNamespace string) error {
 		validPresubmits[ps.Name] = append(validPresubmits[ps.Name], ps)
 	}
 
+	jobs := map[string][]runAfterJob{}
+	for _, ps := range presubmits {
+		jobs[ps.Name] = append(jobs[ps.Name], runAfterJob{
+			base:          ps.JobBase,
+			brancher:      ps.Brancher,
+			unconditional: ps.AlwaysRun && !ps.RegexpChangeMatcher.CouldRun(),
+			runAfter:      ps.RunAfter,
+		})
+	}
+	errs = append(errs, validateRunAfter("presubmit", jobs)...)
+
 	return utilerrors.NewAggregate(errs)
 }
 
@@ -2025,9 +2673,120 @@ func validatePostsubmits(postsubmits []Postsubmit, podNamespace string) error {
 		validPostsubmits[ps.Name] = append(validPostsubmits[ps.Name], ps)
 	}
 
+	jobs := map[string][]runAfterJob{}
+	for _, ps := range postsubmits {
+		jobs[ps.Name] = append(jobs[ps.Name], runAfterJob{
+			base:          ps.JobBase,
+			brancher:      ps.Brancher,
+			unconditional: (ps.AlwaysRun == nil || *ps.AlwaysRun) && !ps.RegexpChangeMatcher.CouldRun(),
+			runAfter:      ps.RunAfter,
+		})
+	}
+	errs = append(errs, validateRunAfter("postsubmit", jobs)...)
+
 	return utilerrors.NewAggregate(errs)
 }
 
+// runAfterJob is what validateRunAfter needs to know about a job.
+type runAfterJob struct {
+	base     JobBase
+	brancher Brancher
+	// unconditional is true if the job is triggered for every change of the
+	// branches it runs on.
+	unconditional bool
+	runAfter      []string
+}
+
+// validateRunAfter validates the run_after dependencies between the jobs of
+// one repo: the prerequisites must be jobs of the same type that are
+// triggered whenever the jobs depending on them are, and the jobs must not
+// depend on themselves, directly or through their prerequisites.
+func validateRunAfter(jobType string, jobs map[string][]runAfterJob) []error {
+	var errs []error
+	names := sets.NewString()
+	runAfter := map[string][]string{}
+	for name, variants := range jobs {
+		for _, job := range variants {
+			runAfter[name] = append(runAfter[name], job.runAfter...)
+		}
+		if len(runAfter[name]) > 0 {
+			names.Insert(name)
+		}
+	}
+	for _, name := range names.List() {
+		for _, job := range jobs[name] {
+			if agent := job.base.Agent; agent == string(prowapi.JenkinsAgent) || agent == string(prowapi.TektonAgent) {
+				errs = append(errs, fmt.Errorf("invalid %s job %s: run_after only applies to agent: %s (found %q)", jobType, name, prowapi.KubernetesAgent, agent))
+			}
+			for _, prerequisite := range job.runAfter {
+				if _, ok := jobs[prerequisite]; !ok {
+					errs = append(errs, fmt.Errorf("invalid %s job %s: run_after job %s is not a %s of the repo", jobType, name, prerequisite, jobType))
+				} else if !triggeredWith(jobs[prerequisite], job.brancher) {
+					// The job would wait for a prerequisite that is never
+					// triggered and error out.
+					errs = append(errs, fmt.Errorf("invalid %s job %s: run_after job %s is not guaranteed to run: it must run on all the branches of %s and must not be triggered conditionally", jobType, name, prerequisite, name))
+				}
+			}
+		}
+	}
+
+	// Look for cycles with a depth-first search, a job that is reached again
+	// while its prerequisites are still visited is part of a cycle.
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			for i := range path {
+				if path[i] == name {
+					return append(append([]string{}, path[i:]...), name)
+				}
+			}
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, prerequisite := range runAfter[name] {
+			if cycle := visit(prerequisite); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, name := range names.List() {
+		if cycle := visit(name); cycle != nil {
+			errs = append(errs, fmt.Errorf("run_after of %s jobs forms a cycle: %s", jobType, strings.Join(cycle, " -> ")))
+			break
+		}
+	}
+	return errs
+}
+
+// triggeredWith returns true if one of the variants of a job is triggered
+// for every change a job running on the branches of the brancher is
+// triggered for.
+func triggeredWith(variants []runAfterJob, brancher Brancher) bool {
+	for _, job := range variants {
+		if !job.unconditional {
+			continue
+		}
+		if job.brancher.RunsAgainstAllBranch() ||
+			(sets.NewString(job.brancher.Branches...).Equal(sets.NewString(brancher.Branches...)) &&
+				sets.NewString(job.brancher.SkipBranches...).Equal(sets.NewString(brancher.SkipBranches...))) {
+			return true
+		}
+	}
+	return false
+}
+
 // validatePeriodics validates a set of periodics
 func validatePeriodics(periodics []Periodic, podNamespace string) error {
 
@@ -2273,6 +3032,19 @@ func parseProwConfig(c *Config) error {
 		return fmt.Errorf("tide has invalid max_goroutines (%d), it needs to be a positive number", c.Tide.MaxGoroutines)
 	}
 
+	if c.Tide.MaxBranchUpdates == 0 {
+		c.Tide.MaxBranchUpdates = 3
+	}
+	if c.Tide.MaxBranchUpdates < 0 {
+		return fmt.Errorf("tide has invalid max_branch_updates (%d), it needs to be a positive number", c.Tide.MaxBranchUpdates)
+	}
+
+	for orgOrRepo, gracePeriod := range c.Tide.PendingContextGracePeriods {
+		if gracePeriod.Duration < 0 {
+			return fmt.Errorf("tide has invalid pending_context_grace_periods for %q (%s), it must not be negative", orgOrRepo, gracePeriod.Duration)
+		}
+	}
+
 	if len(c.Tide.TargetURLs) > 0 && c.Tide.TargetURL != "" {
 		return fmt.Errorf("tide.target_url and tide.target_urls are mutually exclusive")
 	}
@@ -2311,6 +3083,16 @@ func parseProwConfig(c *Config) error {
 		}
 	}
 
+	for name, methods := range c.Tide.MergeMethodOverrides {
+		for _, method := range methods {
+			if method != github.MergeMerge &&
+				method != github.MergeRebase &&
+				method != github.MergeSquash {
+				return fmt.Errorf("merge method override %q for %s is not a valid type", method, name)
+			}
+		}
+	}
+
 	for name, templates := range c.Tide.MergeTemplate {
 		if templates.TitleTemplate != "" {
 			titleTemplate, err := template.New("CommitTitle").Parse(templates.TitleTemplate)
@@ -2341,6 +3123,12 @@ func parseProwConfig(c *Config) error {
 		}
 	}
 
+	for i := range c.Tide.MergeCalendars {
+		if err := c.Tide.MergeCalendars[i].validate(); err != nil {
+			return fmt.Errorf("tide merge calendar (index %d) is invalid: %w", i, err)
+		}
+	}
+
 	if c.ProwJobNamespace == "" {
 		c.ProwJobNamespace = "default"
 	}
@@ -2436,6 +3224,12 @@ func validateAgent(v JobBase, podNamespace string) error {
 		return fmt.Errorf("decoration requires agent: %s (found %q)", k, agent)
 	case v.ErrorOnEviction && agent != k:
 		return fmt.Errorf("error_on_eviction only applies to agent: %s (found %q)", k, agent)
+	case v.RetryPolicy != nil && agent != k:
+		return fmt.Errorf("retry_policy only applies to agent: %s (found %q)", k, agent)
+	case v.ClusterSelector != nil && agent != k:
+		return fmt.Errorf("cluster_selector only applies to agent: %s (found %q)", k, agent)
+	case v.ClusterSelector != nil && v.Cluster != "":
+		return fmt.Errorf("cluster and cluster_selector are mutually exclusive (found cluster %q)", v.Cluster)
 	case v.Namespace == nil || *v.Namespace == "":
 		return fmt.Errorf("failed to default namespace")
 	case *v.Namespace != podNamespace && agent != p:
@@ -2455,6 +3249,12 @@ func validateDecoration(container v1.Container, config *prowapi.DecorationConfig
 	}
 	var args []string
 	args = append(append(args, container.Command...), container.Args...)
+	if len(config.Steps) > 0 {
+		if len(args) > 0 {
+			return errors.New("decorated job containers must not specify command or args if the job has steps")
+		}
+		return nil
+	}
 	if len(args) == 0 || args[0] == "" {
 		return errors.New("decorated job containers must specify command and/or args")
 	}
@@ -2725,7 +3525,7 @@ func (c *ProwConfig) defaultJobBase(base *JobBase) {
 		s := c.PodNamespace
 		base.Namespace = &s
 	}
-	if base.Cluster == "" {
+	if base.Cluster == "" && base.ClusterSelector == nil {
 		base.Cluster = kube.DefaultClusterAlias
 	}
 }
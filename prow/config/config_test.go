@@ -35,6 +35,7 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 	fuzz "github.com/google/gofuzz"
 	pipelinev1alpha1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	"gopkg.in/robfig/cron.v2"
 	v1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -1147,6 +1148,37 @@ func TestValidateAgent(t *testing.T) {
 			},
 			pass: true,
 		},
+		{
+			name: "retry_policy allowed for kubernetes agent",
+			base: func(j *JobBase) {
+				j.RetryPolicy = &prowapi.RetryPolicy{MaxAttempts: 3}
+			},
+			pass: true,
+		},
+		{
+			name: "cluster_selector allowed for kubernetes agent",
+			base: func(j *JobBase) {
+				j.Cluster = ""
+				j.ClusterSelector = &prowapi.ClusterSelector{NodeLabels: map[string]string{"gpu": "true"}}
+			},
+			pass: true,
+		},
+		{
+			name: "reject cluster_selector with cluster",
+			base: func(j *JobBase) {
+				j.Cluster = "default"
+				j.ClusterSelector = &prowapi.ClusterSelector{NodeLabels: map[string]string{"gpu": "true"}}
+			},
+		},
+		{
+			name: "reject retry_policy for jenkins agent",
+			base: func(j *JobBase) {
+				j.Agent = jenk
+				j.Spec = nil
+				j.DecorationConfig = nil
+				j.RetryPolicy = &prowapi.RetryPolicy{MaxAttempts: 3}
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -1543,6 +1575,10 @@ func TestValidateDecoration(t *testing.T) {
 			DefaultRepo:  "very-repo",
 		},
 	}
+	withSteps := func(cfg prowapi.DecorationConfig) *prowapi.DecorationConfig {
+		cfg.Steps = []prowapi.Step{{Name: "test", Command: []string{"hello", "world"}}}
+		return &cfg
+	}
 	cases := []struct {
 		name      string
 		container v1.Container
@@ -1580,6 +1616,18 @@ func TestValidateDecoration(t *testing.T) {
 			name:   "reject container that has no cmd, no args",
 			config: &defCfg,
 		},
+		{
+			name:   "happy case with steps",
+			config: withSteps(defCfg),
+			pass:   true,
+		},
+		{
+			name:   "reject container that has cmd and steps",
+			config: withSteps(defCfg),
+			container: v1.Container{
+				Command: []string{"hello", "world"},
+			},
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -1910,6 +1958,40 @@ func TestValidateDeck(t *testing.T) {
 			deck:        Deck{SkipStoragePathValidation: &boolTrue, AdditionalAllowedBuckets: []string{"hello", "world"}},
 			expectedErr: "skip_storage_path_validation is enabled",
 		},
+		{
+			name: "RerunAuthConfigsByJobType with valid job type => no error",
+			deck: Deck{RerunAuthConfigsByJobType: map[prowapi.ProwJobType]RerunAuthConfigs{
+				prowapi.PostsubmitJob: {"*": prowapi.RerunAuthConfig{Groups: []string{"release-managers"}}},
+			}},
+			expectedErr: "",
+		},
+		{
+			name: "RerunAuthConfigsByJobType with invalid job type => error",
+			deck: Deck{RerunAuthConfigsByJobType: map[prowapi.ProwJobType]RerunAuthConfigs{
+				"nightly": {"*": prowapi.RerunAuthConfig{Groups: []string{"release-managers"}}},
+			}},
+			expectedErr: `invalid job type "nightly"`,
+		},
+		{
+			name:        "Tenants with distinct ids and hosts => no error",
+			deck:        Deck{Tenants: []DeckTenant{{ID: "a", Hosts: []string{"a.example.com"}}, {ID: "b", Orgs: []string{"b"}}}},
+			expectedErr: "",
+		},
+		{
+			name:        "Tenant without id => error",
+			deck:        Deck{Tenants: []DeckTenant{{Hosts: []string{"a.example.com"}}}},
+			expectedErr: "id must be set",
+		},
+		{
+			name:        "Tenants with the same id => error",
+			deck:        Deck{Tenants: []DeckTenant{{ID: "a"}, {ID: "a"}}},
+			expectedErr: `duplicate id "a"`,
+		},
+		{
+			name:        "Tenants with the same host => error",
+			deck:        Deck{Tenants: []DeckTenant{{ID: "a", Hosts: []string{"example.com"}}, {ID: "b", Hosts: []string{"example.com"}}}},
+			expectedErr: `host "example.com" belongs to several tenants`,
+		},
 	}
 
 	for _, tc := range cases {
@@ -3723,6 +3805,78 @@ func TestRerunAuthConfigsGetRerunAuthConfig(t *testing.T) {
 	}
 }
 
+func TestDeckGetRerunAuthConfig(t *testing.T) {
+	deck := Deck{
+		RerunAuthConfigs: RerunAuthConfigs{
+			"*":     prowapi.RerunAuthConfig{GitHubUsers: []string{"clarketm"}},
+			"istio": prowapi.RerunAuthConfig{GitHubUsers: []string{"scoobydoo"}},
+		},
+		RerunAuthConfigsByJobType: map[prowapi.ProwJobType]RerunAuthConfigs{
+			prowapi.PostsubmitJob: {"istio/istio": prowapi.RerunAuthConfig{Groups: []string{"release-managers"}}},
+			prowapi.PeriodicJob:   {"*": prowapi.RerunAuthConfig{Groups: []string{"oncall"}}},
+		},
+	}
+	var testCases = []struct {
+		name     string
+		jobType  prowapi.ProwJobType
+		refs     *prowapi.Refs
+		expected prowapi.RerunAuthConfig
+	}{
+		{
+			name:     "job type without configs uses the configs for all jobs",
+			jobType:  prowapi.PresubmitJob,
+			refs:     &prowapi.Refs{Org: "istio", Repo: "istio"},
+			expected: prowapi.RerunAuthConfig{GitHubUsers: []string{"scoobydoo"}},
+		},
+		{
+			name:     "config of the job type for the repo takes precedence",
+			jobType:  prowapi.PostsubmitJob,
+			refs:     &prowapi.Refs{Org: "istio", Repo: "istio"},
+			expected: prowapi.RerunAuthConfig{Groups: []string{"release-managers"}},
+		},
+		{
+			name:     "job type without config for the repo uses the configs for all jobs",
+			jobType:  prowapi.PostsubmitJob,
+			refs:     &prowapi.Refs{Org: "istio", Repo: "test-infra"},
+			expected: prowapi.RerunAuthConfig{GitHubUsers: []string{"scoobydoo"}},
+		},
+		{
+			name:     "wildcard of the job type",
+			jobType:  prowapi.PeriodicJob,
+			expected: prowapi.RerunAuthConfig{Groups: []string{"oncall"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := deck.GetRerunAuthConfig(tc.jobType, tc.refs); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDeckTenants(t *testing.T) {
+	deck := Deck{
+		Branding: &Branding{Logo: "prow.png"},
+		Tenants: []DeckTenant{
+			{ID: "a", Hosts: []string{"a.example.com"}, Branding: &Branding{Logo: "a.png"}},
+			{ID: "b", Hosts: []string{"b.example.com"}},
+		},
+	}
+	if tenant := deck.TenantForHost("a.example.com"); tenant == nil || tenant.ID != "a" {
+		t.Errorf("expected tenant a for a.example.com, got %v", tenant)
+	}
+	if tenant := deck.TenantForHost("prow.example.com"); tenant != nil {
+		t.Errorf("expected no tenant for prow.example.com, got %v", tenant)
+	}
+	for tenantID, expected := range map[string]string{"a": "a.png", "b": "prow.png", "": "prow.png"} {
+		if actual := deck.GetBranding(tenantID).Logo; actual != expected {
+			t.Errorf("expected logo %q for tenant %q, got %q", expected, tenantID, actual)
+		}
+	}
+}
+
 func TestMergeCommitTemplateLoading(t *testing.T) {
 	var testCases = []struct {
 		name        string
@@ -4231,6 +4385,87 @@ func TestSlackReporterValidation(t *testing.T) {
 			},
 			successExpected: false,
 		},
+		{
+			name: "Valid routes - no error",
+			config: func() Config {
+				slackCfg := map[string]SlackReporter{
+					"*": {
+						SlackReporterConfig: prowjobv1.SlackReporterConfig{
+							Channel: "my-channel",
+						},
+						Routes: []SlackReporterRoute{
+							{Repos: []string{"org/repo"}, Jobs: "^pull-.*-e2e$", Channel: "e2e"},
+							{JobStates: []prowapi.ProwJobState{prowapi.FailureState}, Channel: "failures", ReportTemplate: "{{.Spec.Job}} failed"},
+						},
+						MessagesPerMinute: 20,
+						ThreadRetries:     true,
+					},
+				}
+				return Config{
+					ProwConfig: ProwConfig{
+						SlackReporterConfigs: slackCfg,
+					},
+				}
+			},
+			successExpected: true,
+		},
+		{
+			name: "Route without channel - error",
+			config: func() Config {
+				slackCfg := map[string]SlackReporter{
+					"*": {
+						SlackReporterConfig: prowjobv1.SlackReporterConfig{
+							Channel: "my-channel",
+						},
+						Routes: []SlackReporterRoute{{Repos: []string{"org"}}},
+					},
+				}
+				return Config{
+					ProwConfig: ProwConfig{
+						SlackReporterConfigs: slackCfg,
+					},
+				}
+			},
+			successExpected: false,
+		},
+		{
+			name: "Route with invalid jobs regex - error",
+			config: func() Config {
+				slackCfg := map[string]SlackReporter{
+					"*": {
+						SlackReporterConfig: prowjobv1.SlackReporterConfig{
+							Channel: "my-channel",
+						},
+						Routes: []SlackReporterRoute{{Jobs: "pull-(", Channel: "e2e"}},
+					},
+				}
+				return Config{
+					ProwConfig: ProwConfig{
+						SlackReporterConfigs: slackCfg,
+					},
+				}
+			},
+			successExpected: false,
+		},
+		{
+			name: "Negative messages per minute - error",
+			config: func() Config {
+				slackCfg := map[string]SlackReporter{
+					"*": {
+						SlackReporterConfig: prowjobv1.SlackReporterConfig{
+							Channel: "my-channel",
+						},
+						MessagesPerMinute: -1,
+					},
+				}
+				return Config{
+					ProwConfig: ProwConfig{
+						SlackReporterConfigs: slackCfg,
+					},
+				}
+			},
+			successExpected: false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -4252,6 +4487,163 @@ func TestSlackReporterValidation(t *testing.T) {
 		})
 	}
 }
+func TestEmailReporterValidation(t *testing.T) {
+	testCases := []struct {
+		name            string
+		config          EmailReporter
+		successExpected bool
+	}{
+		{
+			name:            "Empty config - no error",
+			successExpected: true,
+		},
+		{
+			name: "Valid digests - no error",
+			config: EmailReporter{
+				From: "prow@example.com",
+				Digests: []EmailDigest{
+					{Name: "e2e", Jobs: "^ci-e2e-", To: []string{"team@example.com"}, Interval: &prowapi.Duration{Duration: time.Hour}},
+					{Name: "transitions", Jobs: ".*", To: []string{"team@example.com"}, OnTransition: true, SubjectTemplate: "{{.Name}}"},
+				},
+			},
+			successExpected: true,
+		},
+		{
+			name: "No from - error",
+			config: EmailReporter{
+				Digests: []EmailDigest{{Name: "e2e", Jobs: "^ci-e2e-", To: []string{"team@example.com"}, OnTransition: true}},
+			},
+		},
+		{
+			name: "Duplicate name - error",
+			config: EmailReporter{
+				From: "prow@example.com",
+				Digests: []EmailDigest{
+					{Name: "e2e", Jobs: "^ci-e2e-", To: []string{"team@example.com"}, OnTransition: true},
+					{Name: "e2e", Jobs: "^ci-e2e-", To: []string{"other-team@example.com"}, OnTransition: true},
+				},
+			},
+		},
+		{
+			name: "Invalid jobs regex - error",
+			config: EmailReporter{
+				From:    "prow@example.com",
+				Digests: []EmailDigest{{Name: "e2e", Jobs: "ci-(", To: []string{"team@example.com"}, OnTransition: true}},
+			},
+		},
+		{
+			name: "No recipients - error",
+			config: EmailReporter{
+				From:    "prow@example.com",
+				Digests: []EmailDigest{{Name: "e2e", Jobs: "^ci-e2e-", OnTransition: true}},
+			},
+		},
+		{
+			name: "Neither interval nor on_transition - error",
+			config: EmailReporter{
+				From:    "prow@example.com",
+				Digests: []EmailDigest{{Name: "e2e", Jobs: "^ci-e2e-", To: []string{"team@example.com"}}},
+			},
+		},
+		{
+			name: "Invalid body template - error",
+			config: EmailReporter{
+				From:    "prow@example.com",
+				Digests: []EmailDigest{{Name: "e2e", Jobs: "^ci-e2e-", To: []string{"team@example.com"}, OnTransition: true, BodyTemplate: "{{ if .Name}}"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Config{ProwConfig: ProwConfig{EmailReporter: tc.config}}
+			if err := cfg.validateComponentConfig(); (err == nil) != tc.successExpected {
+				t.Errorf("Expected success=%t but got err=%v", tc.successExpected, err)
+			}
+			if tc.successExpected {
+				for _, digest := range cfg.EmailReporter.Digests {
+					if digest.SubjectTemplate == "" || digest.BodyTemplate == "" {
+						t.Errorf("expected the templates of digest %s to be defaulted", digest.Name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTrackerReporterValidation(t *testing.T) {
+	testCases := []struct {
+		name            string
+		config          TrackerReporter
+		successExpected bool
+	}{
+		{
+			name:            "Empty config - no error",
+			successExpected: true,
+		},
+		{
+			name: "Valid rules - no error",
+			config: TrackerReporter{
+				Rules: []TrackerRule{
+					{Jobs: "^ci-e2e-", Bugzilla: &BugzillaTracker{Product: "Prow", Component: "CI"}},
+					{Jobs: ".*", ConsecutiveFailures: 5, CloseOnRecovery: true, Jira: &JiraTracker{Project: "CI"}},
+				},
+			},
+			successExpected: true,
+		},
+		{
+			name:   "Invalid jobs regex - error",
+			config: TrackerReporter{Rules: []TrackerRule{{Jobs: "ci-(", Jira: &JiraTracker{Project: "CI"}}}},
+		},
+		{
+			name:   "Negative consecutive failures - error",
+			config: TrackerReporter{Rules: []TrackerRule{{Jobs: ".*", ConsecutiveFailures: -1, Jira: &JiraTracker{Project: "CI"}}}},
+		},
+		{
+			name:   "No tracker - error",
+			config: TrackerReporter{Rules: []TrackerRule{{Jobs: ".*"}}},
+		},
+		{
+			name: "Both trackers - error",
+			config: TrackerReporter{Rules: []TrackerRule{{
+				Jobs:     ".*",
+				Bugzilla: &BugzillaTracker{Product: "Prow", Component: "CI"},
+				Jira:     &JiraTracker{Project: "CI"},
+			}}},
+		},
+		{
+			name:   "Bugzilla without component - error",
+			config: TrackerReporter{Rules: []TrackerRule{{Jobs: ".*", Bugzilla: &BugzillaTracker{Product: "Prow"}}}},
+		},
+		{
+			name:   "Jira without project - error",
+			config: TrackerReporter{Rules: []TrackerRule{{Jobs: ".*", Jira: &JiraTracker{}}}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Config{ProwConfig: ProwConfig{TrackerReporter: tc.config}}
+			if err := cfg.validateComponentConfig(); (err == nil) != tc.successExpected {
+				t.Errorf("Expected success=%t but got err=%v", tc.successExpected, err)
+			}
+			if tc.successExpected {
+				for _, rule := range cfg.TrackerReporter.Rules {
+					if rule.ConsecutiveFailures == 0 {
+						t.Errorf("expected consecutive_failures of rule %s to be defaulted", rule.Jobs)
+					}
+					if rule.Bugzilla != nil && (rule.Bugzilla.Version == "" || rule.Bugzilla.ClosedStatus == "" || rule.Bugzilla.ClosedResolution == "") {
+						t.Errorf("expected the bugzilla fields of rule %s to be defaulted", rule.Jobs)
+					}
+					if rule.Jira != nil && (rule.Jira.IssueType == "" || rule.Jira.CloseTransition == "") {
+						t.Errorf("expected the jira fields of rule %s to be defaulted", rule.Jobs)
+					}
+				}
+			}
+		})
+	}
+}
+
 func TestManagedHmacEntityValidation(t *testing.T) {
 	testCases := []struct {
 		name       string
@@ -7167,6 +7559,81 @@ func TestInRepoConfigAllowsCluster(t *testing.T) {
 	}
 }
 
+func TestInRepoConfigAllowsInclude(t *testing.T) {
+	testCases := []struct {
+		name            string
+		repoIdentifier  string
+		includedRepo    string
+		allowedIncludes map[string][]string
+
+		expectedResult bool
+	}{
+		{
+			name:           "Nothing configured, nothing allowed",
+			repoIdentifier: "foo/repo",
+			includedRepo:   "foo/templates",
+			expectedResult: false,
+		},
+		{
+			name:            "Allowed on repolevel",
+			repoIdentifier:  "foo/repo",
+			includedRepo:    "foo/templates",
+			allowedIncludes: map[string][]string{"foo/repo": {"foo/templates"}},
+			expectedResult:  true,
+		},
+		{
+			name:            "Allowed for different repo",
+			repoIdentifier:  "foo/repo",
+			includedRepo:    "foo/templates",
+			allowedIncludes: map[string][]string{"foo/other": {"foo/templates"}},
+			expectedResult:  false,
+		},
+		{
+			name:            "Allowed on orglevel",
+			repoIdentifier:  "foo/repo",
+			includedRepo:    "foo/templates",
+			allowedIncludes: map[string][]string{"foo": {"foo/templates"}},
+			expectedResult:  true,
+		},
+		{
+			name:            "Allowed globally",
+			repoIdentifier:  "foo/repo",
+			includedRepo:    "foo/templates",
+			allowedIncludes: map[string][]string{"*": {"foo/templates"}},
+			expectedResult:  true,
+		},
+		{
+			name:            "All repos of the included org allowed",
+			repoIdentifier:  "foo/repo",
+			includedRepo:    "shared/templates",
+			allowedIncludes: map[string][]string{"foo/repo": {"shared"}},
+			expectedResult:  true,
+		},
+		{
+			name:            "Other repo of the included org not allowed",
+			repoIdentifier:  "foo/repo",
+			includedRepo:    "shared/private",
+			allowedIncludes: map[string][]string{"foo/repo": {"shared/templates"}},
+			expectedResult:  false,
+		},
+	}
+
+	for idx := range testCases {
+		tc := testCases[idx]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &Config{
+				ProwConfig: ProwConfig{InRepoConfig: InRepoConfig{AllowedIncludes: tc.allowedIncludes}},
+			}
+
+			if actual := cfg.InRepoConfigAllowsInclude(tc.includedRepo, tc.repoIdentifier); actual != tc.expectedResult {
+				t.Errorf("expected result %t, got result %t", tc.expectedResult, actual)
+			}
+		})
+	}
+}
+
 func TestMergeDefaultDecorationConfigThreadSafety(t *testing.T) {
 	const repo = "org/repo"
 	const cluster = "default"
@@ -7368,6 +7835,68 @@ func TestValidatePresubmits(t *testing.T) {
 			}},
 			expectedError: "job a declares run_if_changed and skip_if_only_changed, which are mutually exclusive",
 		},
+		{
+			name: "Run after other presubmits",
+			presubmits: []Presubmit{
+				{JobBase: JobBase{Name: "a"}, AlwaysRun: true, Reporter: Reporter{Context: "a"}},
+				{JobBase: JobBase{Name: "b"}, AlwaysRun: true, Reporter: Reporter{Context: "b"}, RunAfter: []string{"a"}},
+				{JobBase: JobBase{Name: "c"}, AlwaysRun: true, Reporter: Reporter{Context: "c"}, RunAfter: []string{"a", "b"}},
+			},
+		},
+		{
+			name: "Run after unknown presubmit",
+			presubmits: []Presubmit{
+				{JobBase: JobBase{Name: "a"}, AlwaysRun: true, Reporter: Reporter{Context: "a"}, RunAfter: []string{"b"}},
+			},
+			expectedError: "invalid presubmit job a: run_after job b is not a presubmit of the repo",
+		},
+		{
+			name: "Run after requires the kubernetes agent",
+			presubmits: []Presubmit{
+				{JobBase: JobBase{Name: "a"}, AlwaysRun: true, Reporter: Reporter{Context: "a"}},
+				{JobBase: JobBase{Name: "b", Agent: string(prowapi.TektonAgent), Namespace: utilpointer.StringPtr("tekton"), PipelineRunSpec: &pipelinev1alpha1.PipelineRunSpec{}}, AlwaysRun: true, Reporter: Reporter{Context: "b"}, RunAfter: []string{"a"}},
+			},
+			expectedError: `invalid presubmit job b: run_after only applies to agent: kubernetes (found "tekton-pipeline")`,
+		},
+		{
+			name: "Run after cycle",
+			presubmits: []Presubmit{
+				{JobBase: JobBase{Name: "a"}, AlwaysRun: true, Reporter: Reporter{Context: "a"}, RunAfter: []string{"c"}},
+				{JobBase: JobBase{Name: "b"}, AlwaysRun: true, Reporter: Reporter{Context: "b"}, RunAfter: []string{"a"}},
+				{JobBase: JobBase{Name: "c"}, AlwaysRun: true, Reporter: Reporter{Context: "c"}, RunAfter: []string{"b"}},
+			},
+			expectedError: "run_after of presubmit jobs forms a cycle: a -> c -> b -> a",
+		},
+		{
+			name: "Run after itself",
+			presubmits: []Presubmit{
+				{JobBase: JobBase{Name: "a"}, AlwaysRun: true, Reporter: Reporter{Context: "a"}, RunAfter: []string{"a"}},
+			},
+			expectedError: "run_after of presubmit jobs forms a cycle: a -> a",
+		},
+		{
+			name: "Run after conditional presubmit",
+			presubmits: []Presubmit{
+				{JobBase: JobBase{Name: "a"}, RegexpChangeMatcher: RegexpChangeMatcher{RunIfChanged: `\.go$`}, Reporter: Reporter{Context: "a"}},
+				{JobBase: JobBase{Name: "b"}, AlwaysRun: true, Reporter: Reporter{Context: "b"}, RunAfter: []string{"a"}},
+			},
+			expectedError: "invalid presubmit job b: run_after job a is not guaranteed to run: it must run on all the branches of b and must not be triggered conditionally",
+		},
+		{
+			name: "Run after presubmit of other branches",
+			presubmits: []Presubmit{
+				{JobBase: JobBase{Name: "a"}, AlwaysRun: true, Brancher: Brancher{Branches: []string{"main"}}, Reporter: Reporter{Context: "a"}},
+				{JobBase: JobBase{Name: "b"}, AlwaysRun: true, Reporter: Reporter{Context: "b"}, RunAfter: []string{"a"}},
+			},
+			expectedError: "invalid presubmit job b: run_after job a is not guaranteed to run: it must run on all the branches of b and must not be triggered conditionally",
+		},
+		{
+			name: "Run after presubmit of the same branches",
+			presubmits: []Presubmit{
+				{JobBase: JobBase{Name: "a"}, AlwaysRun: true, Brancher: Brancher{Branches: []string{"main"}}, Reporter: Reporter{Context: "a"}},
+				{JobBase: JobBase{Name: "b"}, AlwaysRun: true, Brancher: Brancher{Branches: []string{"main"}}, Reporter: Reporter{Context: "b"}, RunAfter: []string{"a"}},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -7455,6 +7984,28 @@ func TestValidatePostsubmits(t *testing.T) {
 			}},
 			expectedError: "job a declares run_if_changed and skip_if_only_changed, which are mutually exclusive",
 		},
+		{
+			name: "Run after unknown postsubmit",
+			postsubmits: []Postsubmit{
+				{JobBase: JobBase{Name: "a"}, Reporter: Reporter{Context: "a"}, RunAfter: []string{"b"}},
+			},
+			expectedError: "invalid postsubmit job a: run_after job b is not a postsubmit of the repo",
+		},
+		{
+			name: "Run after postsubmit",
+			postsubmits: []Postsubmit{
+				{JobBase: JobBase{Name: "a"}, Reporter: Reporter{Context: "a"}},
+				{JobBase: JobBase{Name: "b"}, Reporter: Reporter{Context: "b"}, RunAfter: []string{"a"}},
+			},
+		},
+		{
+			name: "Run after postsubmit that does not always run",
+			postsubmits: []Postsubmit{
+				{JobBase: JobBase{Name: "a"}, AlwaysRun: utilpointer.BoolPtr(false), Reporter: Reporter{Context: "a"}},
+				{JobBase: JobBase{Name: "b"}, Reporter: Reporter{Context: "b"}, RunAfter: []string{"a"}},
+			},
+			expectedError: "invalid postsubmit job b: run_after job a is not guaranteed to run: it must run on all the branches of b and must not be triggered conditionally",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -7607,6 +8158,7 @@ deck:
     size_limit: 100000000
   tide_update_period: 10s
 default_job_timeout: 24h0m0s
+email_reporter: {}
 gerrit:
   ratelimit: 5
   tick_interval: 1m0s
@@ -7643,9 +8195,12 @@ sinker:
 status_error_link: https://github.com/kubernetes/test-infra/issues
 tide:
   context_options: {}
+  gitlab: {}
+  max_branch_updates: 3
   max_goroutines: 20
   status_update_period: 1m0s
   sync_period: 1m0s
+tracker_reporter: {}
 `,
 		},
 		{
@@ -7685,6 +8240,7 @@ deck:
     size_limit: 100000000
   tide_update_period: 10s
 default_job_timeout: 24h0m0s
+email_reporter: {}
 gerrit:
   ratelimit: 5
   tick_interval: 1m0s
@@ -7721,11 +8277,14 @@ sinker:
 status_error_link: https://github.com/kubernetes/test-infra/issues
 tide:
   context_options: {}
+  gitlab: {}
+  max_branch_updates: 3
   max_goroutines: 20
   merge_method:
     foo/bar: squash
   status_update_period: 1m0s
   sync_period: 1m0s
+tracker_reporter: {}
 `,
 		},
 		{
@@ -7756,6 +8315,7 @@ deck:
     size_limit: 100000000
   tide_update_period: 10s
 default_job_timeout: 24h0m0s
+email_reporter: {}
 gerrit:
   ratelimit: 5
   tick_interval: 1m0s
@@ -7792,6 +8352,8 @@ sinker:
 status_error_link: https://github.com/kubernetes/test-infra/issues
 tide:
   context_options: {}
+  gitlab: {}
+  max_branch_updates: 3
   max_goroutines: 20
   queries:
   - labels:
@@ -7802,6 +8364,7 @@ tide:
     - another/repo
   status_update_period: 1m0s
   sync_period: 1m0s
+tracker_reporter: {}
 `},
 	}
 
@@ -8052,6 +8615,7 @@ func TestHasConfigFor(t *testing.T) {
 				*t = *template.New("whatever")
 			},
 			func(*labels.Selector, fuzz.Continue) {},
+			func(*cron.Schedule, fuzz.Continue) {},
 		)
 
 	for _, tc := range testCases {
@@ -8235,6 +8799,7 @@ func TestProwConfigMergingProperties(t *testing.T) {
 				*t = *template.New("whatever")
 			},
 			func(*labels.Selector, fuzz.Continue) {},
+			func(*cron.Schedule, fuzz.Continue) {},
 			func(p *Policy, c fuzz.Continue) {
 				// Make sure we always have a good sample of non-nil but empty Policies so
 				// we check that they get copied over. Today, the meaning of an empty and
@@ -8488,3 +9053,121 @@ func TestSplitRepoName(t *testing.T) {
 		})
 	}
 }
+
+func TestClusterSelectorDefaults(t *testing.T) {
+	decorationConfig := func(bucket string) *prowapi.DecorationConfig {
+		return &prowapi.DecorationConfig{
+			UtilityImages: &prowapi.UtilityImages{
+				CloneRefs:  "clonerefs:default",
+				InitUpload: "initupload:default",
+				Entrypoint: "entrypoint:default",
+				Sidecar:    "sidecar:default",
+			},
+			GCSConfiguration: &prowapi.GCSConfiguration{Bucket: bucket, PathStrategy: prowapi.PathStrategyExplicit},
+		}
+	}
+	testCases := []struct {
+		name              string
+		selector          *prowapi.ClusterSelector
+		decorationEntries []*DefaultDecorationConfigEntry
+		prowJobEntries    []*ProwJobDefaultEntry
+		expectedBuckets   map[string]string
+		expectedTenantID  string
+		expectErr         bool
+	}{
+		{
+			name:     "the decoration defaults of the picked cluster apply",
+			selector: &prowapi.ClusterSelector{Clusters: []string{"default", "gpu"}},
+			decorationEntries: []*DefaultDecorationConfigEntry{
+				{Config: decorationConfig("default-bucket")},
+				{Cluster: "gpu", Config: &prowapi.DecorationConfig{GCSConfiguration: &prowapi.GCSConfiguration{Bucket: "gpu-bucket", PathStrategy: prowapi.PathStrategyExplicit}}},
+			},
+			expectedBuckets:  map[string]string{"default": "default-bucket", "gpu": "gpu-bucket"},
+			expectedTenantID: DefaultTenantID,
+		},
+		{
+			name:     "the prowjob defaults of the clusters apply if they are the same",
+			selector: &prowapi.ClusterSelector{Clusters: []string{"gpu", "tpu"}},
+			decorationEntries: []*DefaultDecorationConfigEntry{
+				{Config: decorationConfig("default-bucket")},
+			},
+			prowJobEntries: []*ProwJobDefaultEntry{
+				{Cluster: "gpu", Config: &prowapi.ProwJobDefault{TenantID: "accelerators"}},
+				{Cluster: "tpu", Config: &prowapi.ProwJobDefault{TenantID: "accelerators"}},
+			},
+			expectedBuckets:  map[string]string{"gpu": "default-bucket", "tpu": "default-bucket"},
+			expectedTenantID: "accelerators",
+		},
+		{
+			name:     "the prowjob defaults of the clusters must be the same",
+			selector: &prowapi.ClusterSelector{Clusters: []string{"default", "gpu"}},
+			decorationEntries: []*DefaultDecorationConfigEntry{
+				{Config: decorationConfig("default-bucket")},
+			},
+			prowJobEntries: []*ProwJobDefaultEntry{
+				{Cluster: "gpu", Config: &prowapi.ProwJobDefault{TenantID: "accelerators"}},
+			},
+			expectErr: true,
+		},
+		{
+			name:     "the clusters of the entries are candidates of a selector without clusters",
+			selector: &prowapi.ClusterSelector{},
+			decorationEntries: []*DefaultDecorationConfigEntry{
+				{Config: decorationConfig("default-bucket")},
+			},
+			prowJobEntries: []*ProwJobDefaultEntry{
+				{Cluster: "gpu", Config: &prowapi.ProwJobDefault{TenantID: "accelerators"}},
+			},
+			expectErr: true,
+		},
+		{
+			name:     "the decoration config must be valid in all the clusters",
+			selector: &prowapi.ClusterSelector{Clusters: []string{"default", "gpu"}},
+			decorationEntries: []*DefaultDecorationConfigEntry{
+				{Cluster: "default", Config: decorationConfig("default-bucket")},
+			},
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decorate := true
+			c := &Config{
+				ProwConfig: ProwConfig{
+					Plank:                 Plank{DefaultDecorationConfigs: tc.decorationEntries},
+					ProwJobDefaultEntries: tc.prowJobEntries,
+					PodNamespace:          "test-pods",
+				},
+			}
+			periodic := Periodic{
+				JobBase: JobBase{
+					Name:            "periodic",
+					ClusterSelector: tc.selector,
+					UtilityConfig:   UtilityConfig{Decorate: &decorate},
+					Spec:            &v1.PodSpec{Containers: []v1.Container{{Image: "alpine", Command: []string{"true"}}}},
+				},
+			}
+			err := c.DefaultPeriodic(&periodic)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(&prowapi.DecorationConfig{}, periodic.DecorationConfig); diff != "" {
+				t.Errorf("expected the decoration config to be left for plank to default, differs: %s", diff)
+			}
+			for cluster, bucket := range tc.expectedBuckets {
+				if actual := c.Plank.MergeDefaultDecorationConfig("", cluster, periodic.DecorationConfig).GCSConfiguration.Bucket; actual != bucket {
+					t.Errorf("expected bucket %q in cluster %q, got %q", bucket, cluster, actual)
+				}
+			}
+			if periodic.ProwJobDefault.TenantID != tc.expectedTenantID {
+				t.Errorf("expected tenant %q, got %q", tc.expectedTenantID, periodic.ProwJobDefault.TenantID)
+			}
+		})
+	}
+}
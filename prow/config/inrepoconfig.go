@@ -17,6 +17,7 @@ limitations under the License.
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -24,6 +25,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	gitignore "github.com/denormal/go-gitignore"
@@ -48,11 +50,53 @@ type ProwYAML struct {
 	Presubmits  []Presubmit  `json:"presubmits"`
 	Postsubmits []Postsubmit `json:"postsubmits"`
 
+	// Include includes the presets, presubmits and postsubmits of other files
+	// of the repo or of other repos, like the job templates shared by an org.
+	Include ProwYAMLIncludes `json:"include,omitempty"`
+
 	// ProwIgnored is a well known, unparsed field where non-Prow fields can
 	// be defined without conflicting with unknown field validation.
 	ProwIgnored *json.RawMessage `json:"prow_ignored,omitempty"`
 }
 
+// ProwYAMLInclude is a file included by a ProwYAML.
+type ProwYAMLInclude struct {
+	// Repo is the org/repo of the file, the repo of the ProwYAML if empty.
+	Repo string `json:"repo,omitempty"`
+	// Path is the path of the file in its repo.
+	Path string `json:"path"`
+	// Ref pins the file of another repo to a git ref, like a tag or a commit.
+	// It is required for other repos, the files of the repo of the ProwYAML
+	// are included as of the tested commit.
+	Ref string `json:"ref,omitempty"`
+}
+
+// ProwYAMLIncludes are the files included by a ProwYAML, written as a list or
+// as a single include.
+type ProwYAMLIncludes []ProwYAMLInclude
+
+func (i *ProwYAMLIncludes) UnmarshalJSON(b []byte) error {
+	decode := func(v interface{}) error {
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(v)
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		var include ProwYAMLInclude
+		if err := decode(&include); err != nil {
+			return err
+		}
+		*i = ProwYAMLIncludes{include}
+		return nil
+	}
+	var includes []ProwYAMLInclude
+	if err := decode(&includes); err != nil {
+		return err
+	}
+	*i = includes
+	return nil
+}
+
 // ProwYAMLGetter is used to retrieve a ProwYAML. Tests should provide
 // their own implementation and set that on the Config.
 type ProwYAMLGetter func(c *Config, gc git.ClientFactory, identifier, baseSHA string, headSHAs ...string) (*ProwYAML, error)
@@ -115,7 +159,14 @@ func prowYAMLGetter(
 		return nil, fmt.Errorf("failed to merge: %w", err)
 	}
 
-	return ReadProwYAML(log, repo.Directory(), false)
+	prowYAML, err := ReadProwYAML(log, repo.Directory(), false)
+	if err != nil {
+		return nil, err
+	}
+	if err := includeRemoteFiles(c, gc, identifier, prowYAML); err != nil {
+		return nil, err
+	}
+	return prowYAML, nil
 }
 
 func ensureHeadCommits(repo git.RepoClient, headSHAs ...string) error {
@@ -138,14 +189,6 @@ func ReadProwYAML(log *logrus.Entry, dir string, strict bool) (*ProwYAML, error)
 	prowYAMLDirPath := path.Join(dir, inRepoConfigDirName)
 	log.Debugf("Attempting to read config files under %q.", prowYAMLDirPath)
 	if fileInfo, err := os.Stat(prowYAMLDirPath); !os.IsNotExist(err) && err == nil && fileInfo.IsDir() {
-		mergeProwYAML := func(a, b *ProwYAML) *ProwYAML {
-			c := &ProwYAML{}
-			c.Presets = append(a.Presets, b.Presets...)
-			c.Presubmits = append(a.Presubmits, b.Presubmits...)
-			c.Postsubmits = append(a.Postsubmits, b.Postsubmits...)
-
-			return c
-		}
 		prowIgnore, err := gitignore.NewRepositoryWithFile(dir, ProwIgnoreFileName)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create `%s` parser: %w", ProwIgnoreFileName, err)
@@ -196,9 +239,114 @@ func ReadProwYAML(log *logrus.Entry, dir string, strict bool) (*ProwYAML, error)
 			}
 		}
 	}
+	if err := includeLocalFiles(log, dir, prowYAML, opts); err != nil {
+		return nil, err
+	}
 	return prowYAML, nil
 }
 
+func mergeProwYAML(a, b *ProwYAML) *ProwYAML {
+	c := &ProwYAML{}
+	c.Presets = append(a.Presets, b.Presets...)
+	c.Presubmits = append(a.Presubmits, b.Presubmits...)
+	c.Postsubmits = append(a.Postsubmits, b.Postsubmits...)
+	c.Include = append(a.Include, b.Include...)
+
+	return c
+}
+
+// includeLocalFiles merges the files the ProwYAML includes from its repo into
+// it. The includes of other repos are validated and kept, they are included
+// with a git client by includeRemoteFiles.
+func includeLocalFiles(log *logrus.Entry, dir string, prowYAML *ProwYAML, opts []yaml.JSONOpt) error {
+	var remote ProwYAMLIncludes
+	included := &ProwYAML{}
+	for _, include := range prowYAML.Include {
+		if include.Path == "" {
+			return errors.New("includes must have a path")
+		}
+		if include.Repo != "" {
+			if include.Ref == "" {
+				return fmt.Errorf("the include of %s from %s must pin a ref", include.Path, include.Repo)
+			}
+			remote = append(remote, include)
+			continue
+		}
+		log.Debugf("Including YAML file %q", include.Path)
+		file, err := readIncludedFile(dir, include.Path, opts)
+		if err != nil {
+			return err
+		}
+		included = mergeProwYAML(included, file)
+	}
+	*prowYAML = *mergeProwYAML(prowYAML, included)
+	prowYAML.Include = remote
+	return nil
+}
+
+// includeRemoteFiles merges the files the ProwYAML of the repo includes from
+// other repos into it. Only the repos the config allows for the repo can be
+// included, so that repos can't read the files of private repos or inject jobs
+// into each other.
+func includeRemoteFiles(c *Config, gc git.ClientFactory, identifier string, prowYAML *ProwYAML) error {
+	included := &ProwYAML{}
+	for _, include := range prowYAML.Include {
+		if include.Repo == identifier {
+			return fmt.Errorf("the include of %s from %s must omit the repo, the files of the repo are included as of the tested commit", include.Path, include.Repo)
+		}
+		orgRepo := NewOrgRepo(include.Repo)
+		if orgRepo.Repo == "" {
+			return fmt.Errorf("the include of %s must be from an org/repo, not %q", include.Path, include.Repo)
+		}
+		if !c.InRepoConfigAllowsInclude(include.Repo, identifier) {
+			return fmt.Errorf("the include of %s from %s is not allowed, the repos %s can include from are listed in in_repo_config.allowed_includes", include.Path, include.Repo, identifier)
+		}
+		file, err := func() (*ProwYAML, error) {
+			repo, err := gc.ClientFor(orgRepo.Org, orgRepo.Repo)
+			if err != nil {
+				return nil, fmt.Errorf("failed to clone repo: %w", err)
+			}
+			defer func() {
+				if err := repo.Clean(); err != nil {
+					logrus.WithError(err).WithField("repo", include.Repo).Error("Failed to clean up repo.")
+				}
+			}()
+			if err := repo.Checkout(include.Ref); err != nil {
+				return nil, fmt.Errorf("failed to checkout: %w", err)
+			}
+			return readIncludedFile(repo.Directory(), include.Path, nil)
+		}()
+		if err != nil {
+			return fmt.Errorf("failed to include %s from %s@%s: %w", include.Path, include.Repo, include.Ref, err)
+		}
+		included = mergeProwYAML(included, file)
+	}
+	*prowYAML = *mergeProwYAML(prowYAML, included)
+	prowYAML.Include = nil
+	return nil
+}
+
+// readIncludedFile reads a file included by a ProwYAML, which must not include
+// other files.
+func readIncludedFile(dir, p string, opts []yaml.JSONOpt) (*ProwYAML, error) {
+	filePath := filepath.Join(dir, filepath.FromSlash(p))
+	if !strings.HasPrefix(filePath, filepath.Clean(dir)+string(filepath.Separator)) {
+		return nil, fmt.Errorf("included file %q is outside of the repo", p)
+	}
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read included file %q: %w", p, err)
+	}
+	included := &ProwYAML{}
+	if err := yaml.Unmarshal(b, included, opts...); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal included file %q: %w", p, err)
+	}
+	if len(included.Include) > 0 {
+		return nil, fmt.Errorf("included file %q must not include other files", p)
+	}
+	return included, nil
+}
+
 // prowYAMLGetterWithDefaults is like prowYAMLGetter, but additionally sets
 // defaults by calling DefaultAndValidateProwYAML.
 func prowYAMLGetterWithDefaults(
@@ -237,14 +385,10 @@ func DefaultAndValidateProwYAML(c *Config, p *ProwYAML, identifier string) error
 
 	var errs []error
 	for _, pre := range p.Presubmits {
-		if !c.InRepoConfigAllowsCluster(pre.Cluster, identifier) {
-			errs = append(errs, fmt.Errorf("cluster %q is not allowed for repository %q", pre.Cluster, identifier))
-		}
+		errs = append(errs, c.inRepoConfigClusterErrors(pre.JobBase, identifier)...)
 	}
 	for _, post := range p.Postsubmits {
-		if !c.InRepoConfigAllowsCluster(post.Cluster, identifier) {
-			errs = append(errs, fmt.Errorf("cluster %q is not allowed for repository %q", post.Cluster, identifier))
-		}
+		errs = append(errs, c.inRepoConfigClusterErrors(post.JobBase, identifier)...)
 	}
 
 	if len(errs) == 0 {
@@ -255,6 +399,27 @@ func DefaultAndValidateProwYAML(c *Config, p *ProwYAML, identifier string) error
 	return utilerrors.NewAggregate(errs)
 }
 
+// inRepoConfigClusterErrors returns an error for each cluster the job may run
+// in that is not allowed for the repository. Jobs with a cluster selector
+// must list their candidate clusters, so that they aren't scheduled to other
+// clusters.
+func (c *Config) inRepoConfigClusterErrors(jb JobBase, identifier string) []error {
+	clusters := []string{jb.Cluster}
+	if jb.ClusterSelector != nil {
+		if len(jb.ClusterSelector.Clusters) == 0 {
+			return []error{fmt.Errorf("cluster_selector of job %q must list the clusters allowed for repository %q", jb.Name, identifier)}
+		}
+		clusters = jb.ClusterSelector.Clusters
+	}
+	var errs []error
+	for _, cluster := range clusters {
+		if !c.InRepoConfigAllowsCluster(cluster, identifier) {
+			errs = append(errs, fmt.Errorf("cluster %q is not allowed for repository %q", cluster, identifier))
+		}
+	}
+	return errs
+}
+
 // InRepoConfigGitCache is a wrapper around a git.ClientFactory that allows for
 // threadsafe reuse of git.RepoClients when one already exists for the specified repo.
 type InRepoConfigGitCache struct {
@@ -0,0 +1,169 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/git/v2"
+	pkgio "k8s.io/test-infra/prow/io"
+)
+
+// InRepoConfigStore persists the ProwYAMLs of the repos by the SHAs they are
+// read at, so that the replicas of the components share them and don't get
+// them from git again when they start. The ProwYAMLs are stored before
+// defaulting, which depends on the Config.
+type InRepoConfigStore interface {
+	// Get returns the ProwYAML stored for the key, nil if there is none.
+	Get(ctx context.Context, key CacheKey) (*ProwYAML, error)
+	// Set stores the ProwYAML for the key.
+	Set(ctx context.Context, key CacheKey, prowYAML *ProwYAML) error
+}
+
+// blobInRepoConfigStore stores the ProwYAMLs as JSON files in a bucket or a
+// directory. The files are not deleted, they only expire after the TTL, so
+// buckets should have a lifecycle rule that deletes them after the TTL.
+type blobInRepoConfigStore struct {
+	opener pkgio.Opener
+	prefix string
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// storedProwYAML is the JSON file of a ProwYAML in a blobInRepoConfigStore.
+type storedProwYAML struct {
+	// Stored is when the ProwYAML was stored, files without it are expired.
+	Stored   time.Time `json:"stored"`
+	ProwYAML *ProwYAML `json:"prowYAML"`
+}
+
+// NewBlobInRepoConfigStore returns a store of the ProwYAMLs under the prefix,
+// like gs://bucket/inrepoconfig, s3://bucket/inrepoconfig or a directory.
+// The ProwYAMLs expire after the TTL, expired ones are got from git and stored
+// again.
+func NewBlobInRepoConfigStore(opener pkgio.Opener, prefix string, ttl time.Duration) InRepoConfigStore {
+	return &blobInRepoConfigStore{opener: opener, prefix: strings.TrimSuffix(prefix, "/"), ttl: ttl, now: time.Now}
+}
+
+// path returns the path of the ProwYAML of the key, which is under the
+// org/repo so that the ProwYAMLs of a repo can be removed together.
+func (s *blobInRepoConfigStore) path(key CacheKey) (string, error) {
+	var parts CacheKeyParts
+	if err := json.Unmarshal([]byte(key), &parts); err != nil {
+		return "", fmt.Errorf("invalid cache key %q: %w", key, err)
+	}
+	return fmt.Sprintf("%s/%s/%x.json", s.prefix, parts.Identifier, sha256.Sum256([]byte(key))), nil
+}
+
+func (s *blobInRepoConfigStore) Get(ctx context.Context, key CacheKey) (*ProwYAML, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := s.opener.Reader(ctx, path)
+	if err != nil {
+		if pkgio.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer pkgio.LogClose(reader)
+	var stored storedProwYAML
+	if err := json.NewDecoder(reader).Decode(&stored); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	if stored.ProwYAML == nil || s.now().Sub(stored.Stored) > s.ttl {
+		return nil, nil
+	}
+	return stored.ProwYAML, nil
+}
+
+func (s *blobInRepoConfigStore) Set(ctx context.Context, key CacheKey, prowYAML *ProwYAML) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	writer, err := s.opener.Writer(ctx, path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for writing: %w", path, err)
+	}
+	if err := json.NewEncoder(writer).Encode(storedProwYAML{Stored: s.now(), ProwYAML: prowYAML}); err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// WithInRepoConfigStore makes the Config get the ProwYAMLs from the store
+// before getting them from git, and store the ones it gets from git. It is
+// meant as an additional of loading the Config, like in Agent.Start, and the
+// store is best effort: its errors are logged and the ProwYAMLs are got from
+// git.
+func WithInRepoConfigStore(store InRepoConfigStore) func(*Config) error {
+	return func(c *Config) error {
+		getter := storedProwYAMLGetter(store, c.ProwYAMLGetter)
+		c.ProwYAMLGetter = getter
+		c.ProwYAMLGetterWithDefaults = func(c *Config, gc git.ClientFactory, identifier, baseSHA string, headSHAs ...string) (*ProwYAML, error) {
+			prowYAML, err := getter(c, gc, identifier, baseSHA, headSHAs...)
+			if err != nil {
+				return nil, err
+			}
+			if err := DefaultAndValidateProwYAML(c, prowYAML, identifier); err != nil {
+				return nil, err
+			}
+			return prowYAML, nil
+		}
+		return nil
+	}
+}
+
+func storedProwYAMLGetter(store InRepoConfigStore, getter ProwYAMLGetter) ProwYAMLGetter {
+	return func(c *Config, gc git.ClientFactory, identifier, baseSHA string, headSHAs ...string) (*ProwYAML, error) {
+		log := logrus.WithFields(logrus.Fields{"repo": identifier, "base-sha": baseSHA, "head-shas": headSHAs})
+		key, err := MakeCacheKey(identifier, baseSHA, headSHAs)
+		if err != nil {
+			return nil, err
+		}
+		ctx := context.Background()
+		stored, err := store.Get(ctx, key)
+		if err != nil {
+			log.WithError(err).Warn("Failed to get the stored ProwYAML.")
+		}
+		if stored != nil {
+			return stored, nil
+		}
+
+		prowYAML, err := getter(c, gc, identifier, baseSHA, headSHAs...)
+		if err != nil {
+			return nil, err
+		}
+		if err := store.Set(ctx, key, prowYAML); err != nil {
+			log.WithError(err).Warn("Failed to store the ProwYAML.")
+		}
+		return prowYAML, nil
+	}
+}
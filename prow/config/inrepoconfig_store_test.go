@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"k8s.io/test-infra/prow/git/v2"
+	pkgio "k8s.io/test-infra/prow/io"
+	"k8s.io/test-infra/prow/kube"
+)
+
+func TestBlobInRepoConfigStore(t *testing.T) {
+	ctx := context.Background()
+	opener, err := pkgio.NewOpener(ctx, "", "")
+	if err != nil {
+		t.Fatalf("failed to create opener: %v", err)
+	}
+	now := time.Now()
+	store := NewBlobInRepoConfigStore(opener, t.TempDir()+"/", time.Hour)
+	store.(*blobInRepoConfigStore).now = func() time.Time { return now }
+
+	key, err := MakeCacheKey("org/repo", "base", []string{"head"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := store.Get(ctx, key); err != nil || stored != nil {
+		t.Fatalf("expected no stored ProwYAML, got %v, %v", stored, err)
+	}
+
+	prowYAML := &ProwYAML{Presubmits: []Presubmit{{JobBase: JobBase{Name: "unit"}, AlwaysRun: true}}}
+	if err := store.Set(ctx, key, prowYAML); err != nil {
+		t.Fatalf("failed to store: %v", err)
+	}
+	stored, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if n := len(stored.Presubmits); n != 1 || stored.Presubmits[0].Name != "unit" || !stored.Presubmits[0].AlwaysRun {
+		t.Errorf("expected the stored presubmit, got %v", stored.Presubmits)
+	}
+
+	otherKey, err := MakeCacheKey("org/repo", "base", []string{"other-head"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := store.Get(ctx, otherKey); err != nil || stored != nil {
+		t.Errorf("expected no ProwYAML stored for other SHAs, got %v, %v", stored, err)
+	}
+
+	now = now.Add(time.Hour + time.Second)
+	if stored, err := store.Get(ctx, key); err != nil || stored != nil {
+		t.Errorf("expected the stored ProwYAML to be expired after the TTL, got %v, %v", stored, err)
+	}
+	if err := store.Set(ctx, key, prowYAML); err != nil {
+		t.Fatalf("failed to store again: %v", err)
+	}
+	if stored, err := store.Get(ctx, key); err != nil || stored == nil {
+		t.Errorf("expected the ProwYAML stored again, got %v, %v", stored, err)
+	}
+}
+
+type fakeInRepoConfigStore struct {
+	prowYAMLs map[CacheKey]*ProwYAML
+	err       error
+}
+
+func (s *fakeInRepoConfigStore) Get(_ context.Context, key CacheKey) (*ProwYAML, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.prowYAMLs[key].DeepCopy(), nil
+}
+
+func (s *fakeInRepoConfigStore) Set(_ context.Context, key CacheKey, prowYAML *ProwYAML) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.prowYAMLs[key] = prowYAML.DeepCopy()
+	return nil
+}
+
+func TestWithInRepoConfigStore(t *testing.T) {
+	var gets int
+	getter := func(_ *Config, _ git.ClientFactory, identifier, baseSHA string, headSHAs ...string) (*ProwYAML, error) {
+		gets++
+		return &ProwYAML{Presubmits: []Presubmit{{JobBase: JobBase{Name: "unit", Spec: &v1.PodSpec{Containers: []v1.Container{{}}}}}}}, nil
+	}
+	testCases := []struct {
+		name         string
+		store        *fakeInRepoConfigStore
+		expectedGets int
+	}{
+		{
+			name:         "ProwYAMLs are got from git once",
+			store:        &fakeInRepoConfigStore{prowYAMLs: map[CacheKey]*ProwYAML{}},
+			expectedGets: 1,
+		},
+		{
+			name:         "ProwYAMLs are got from git when the store fails",
+			store:        &fakeInRepoConfigStore{prowYAMLs: map[CacheKey]*ProwYAML{}, err: errors.New("unavailable")},
+			expectedGets: 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gets = 0
+			c := &Config{
+				ProwConfig: ProwConfig{
+					InRepoConfig: InRepoConfig{AllowedClusters: map[string][]string{"*": {kube.DefaultClusterAlias}}},
+					PodNamespace: "my-ns",
+				},
+				JobConfig: JobConfig{
+					ProwYAMLGetter:             getter,
+					ProwYAMLGetterWithDefaults: getter,
+				},
+			}
+			if err := WithInRepoConfigStore(tc.store)(c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i := 0; i < 2; i++ {
+				prowYAML, err := c.ProwYAMLGetterWithDefaults(c, nil, "org/repo", "base", "head")
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if n := len(prowYAML.Presubmits); n != 1 || prowYAML.Presubmits[0].Context != "unit" {
+					t.Fatalf("expected a defaulted presubmit, got %v", prowYAML.Presubmits)
+				}
+			}
+			if gets != tc.expectedGets {
+				t.Errorf("expected %d gets from git, got %d", tc.expectedGets, gets)
+			}
+		})
+	}
+}
@@ -49,6 +49,8 @@ func testDefaultProwYAMLGetter(clients localgit.Clients, t *testing.T) {
 		dontPassGitClient bool
 		validate          func(*ProwYAML, error) error
 		repo              string
+		// templatesContent is the content of the org/ci-templates repo.
+		templatesContent map[string][]byte
 	}{
 		// presubmits
 		{
@@ -170,6 +172,38 @@ func testDefaultProwYAMLGetter(clients localgit.Clients, t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name: "Not allowed cluster of a cluster selector is rejected (presubmits)",
+			baseContent: map[string][]byte{
+				".prow.yaml": []byte(`presubmits: [{"name": "hans", "cluster_selector": {"clusters": ["default", "privileged"]}, "spec": {"containers": [{}]}}]`),
+			},
+			validate: func(_ *ProwYAML, err error) error {
+				if err == nil {
+					return errors.New("error is nil")
+				}
+				expectedErrMsg := "cluster \"privileged\" is not allowed for repository \"org/repo\""
+				if err.Error() != expectedErrMsg {
+					return fmt.Errorf("expected error message to be %q, was %q", expectedErrMsg, err.Error())
+				}
+				return nil
+			},
+		},
+		{
+			name: "Cluster selector without clusters is rejected (presubmits)",
+			baseContent: map[string][]byte{
+				".prow.yaml": []byte(`presubmits: [{"name": "hans", "cluster_selector": {"node_labels": {"gpu": "true"}}, "spec": {"containers": [{}]}}]`),
+			},
+			validate: func(_ *ProwYAML, err error) error {
+				if err == nil {
+					return errors.New("error is nil")
+				}
+				expectedErrMsg := "cluster_selector of job \"hans\" must list the clusters allowed for repository \"org/repo\""
+				if err.Error() != expectedErrMsg {
+					return fmt.Errorf("expected error message to be %q, was %q", expectedErrMsg, err.Error())
+				}
+				return nil
+			},
+		},
 		// postsubmits
 		{
 			name: "Basic happy path (postsubmits)",
@@ -544,6 +578,107 @@ postsubmits: [{"name": "oli", "spec": {"containers": [{}]}}]`),
 			},
 			repo: "repo/name",
 		},
+		{
+			name: "Files of the repo are included",
+			baseContent: map[string][]byte{
+				".prow.yaml":   []byte(`{"include": [{"path": "ci/jobs.yaml"}], "presubmits": [{"name": "hans", "spec": {"containers": [{}]}}]}`),
+				"ci/jobs.yaml": []byte(`presubmits: [{"name": "peter", "spec": {"containers": [{}]}}]`),
+			},
+			validate: func(p *ProwYAML, err error) error {
+				if err != nil {
+					return fmt.Errorf("unexpected error: %w", err)
+				}
+				if n := len(p.Presubmits); n != 2 || p.Presubmits[0].Name != "hans" || p.Presubmits[1].Name != "peter" {
+					return fmt.Errorf(`expected the presubmits "hans" and "peter", got %v`, p.Presubmits)
+				}
+				if len(p.Include) != 0 {
+					return fmt.Errorf("expected the includes to be resolved, got %v", p.Include)
+				}
+				return nil
+			},
+		},
+		{
+			name: "Files of other repos are included",
+			baseContent: map[string][]byte{
+				".prow.yaml": []byte(fmt.Sprintf(`include: {"repo": "org/ci-templates", "path": "go.yaml", "ref": %q}`, defaultBranch)),
+			},
+			templatesContent: map[string][]byte{
+				"go.yaml": []byte(`presubmits: [{"name": "hans", "spec": {"containers": [{}]}}]`),
+			},
+			validate: func(p *ProwYAML, err error) error {
+				if err != nil {
+					return fmt.Errorf("unexpected error: %w", err)
+				}
+				if n := len(p.Presubmits); n != 1 || p.Presubmits[0].Name != "hans" {
+					return fmt.Errorf(`expected exactly one presubmit with name "hans", got %v`, p.Presubmits)
+				}
+				return nil
+			},
+		},
+		{
+			name: "Files of repos that are not allowed are not included",
+			baseContent: map[string][]byte{
+				".prow.yaml": []byte(fmt.Sprintf(`include: {"repo": "org/ci-templates", "path": "go.yaml", "ref": %q}`, defaultBranch)),
+			},
+			templatesContent: map[string][]byte{
+				"go.yaml": []byte(`presubmits: [{"name": "hans", "spec": {"containers": [{}]}}]`),
+			},
+			config: &Config{
+				ProwConfig: ProwConfig{
+					InRepoConfig: InRepoConfig{
+						AllowedClusters: map[string][]string{"*": {kube.DefaultClusterAlias}},
+						AllowedIncludes: map[string][]string{"org/other": {"org/ci-templates"}},
+					},
+				},
+			},
+			validate: func(_ *ProwYAML, err error) error {
+				expectedErrMsg := "the include of go.yaml from org/ci-templates is not allowed, the repos org/repo can include from are listed in in_repo_config.allowed_includes"
+				if err == nil || err.Error() != expectedErrMsg {
+					return fmt.Errorf("expected error message to be %q, was %v", expectedErrMsg, err)
+				}
+				return nil
+			},
+		},
+		{
+			name: "Files of other repos must be pinned",
+			baseContent: map[string][]byte{
+				".prow.yaml": []byte(`include: [{"repo": "org/ci-templates", "path": "go.yaml"}]`),
+			},
+			validate: func(_ *ProwYAML, err error) error {
+				expectedErrMsg := "the include of go.yaml from org/ci-templates must pin a ref"
+				if err == nil || err.Error() != expectedErrMsg {
+					return fmt.Errorf("expected error message to be %q, was %v", expectedErrMsg, err)
+				}
+				return nil
+			},
+		},
+		{
+			name: "Included files can't include other files",
+			baseContent: map[string][]byte{
+				".prow.yaml":   []byte(`include: [{"path": "ci/jobs.yaml"}]`),
+				"ci/jobs.yaml": []byte(`include: [{"path": "ci/more.yaml"}]`),
+			},
+			validate: func(_ *ProwYAML, err error) error {
+				expectedErrMsg := `included file "ci/jobs.yaml" must not include other files`
+				if err == nil || err.Error() != expectedErrMsg {
+					return fmt.Errorf("expected error message to be %q, was %v", expectedErrMsg, err)
+				}
+				return nil
+			},
+		},
+		{
+			name: "Included files must be in the repo",
+			baseContent: map[string][]byte{
+				".prow.yaml": []byte(`include: [{"path": "../other/.prow.yaml"}]`),
+			},
+			validate: func(_ *ProwYAML, err error) error {
+				expectedErrMsg := `included file "../other/.prow.yaml" is outside of the repo`
+				if err == nil || err.Error() != expectedErrMsg {
+					return fmt.Errorf("expected error message to be %q, was %v", expectedErrMsg, err)
+				}
+				return nil
+			},
+		},
 	}
 
 	for idx := range testCases {
@@ -572,6 +707,14 @@ postsubmits: [{"name": "oli", "spec": {"containers": [{}]}}]`),
 			if err := lg.MakeFakeRepo(org, repo); err != nil {
 				t.Fatalf("Making fake repo: %v", err)
 			}
+			if tc.templatesContent != nil {
+				if err := lg.MakeFakeRepo(org, "ci-templates"); err != nil {
+					t.Fatalf("Making templates repo: %v", err)
+				}
+				if err := lg.AddCommit(org, "ci-templates", tc.templatesContent); err != nil {
+					t.Fatalf("failed to commit templatesContent: %v", err)
+				}
+			}
 			if tc.baseContent != nil {
 				if err := lg.AddCommit(org, repo, tc.baseContent); err != nil {
 					t.Fatalf("failed to commit baseContent: %v", err)
@@ -600,6 +743,7 @@ postsubmits: [{"name": "oli", "spec": {"containers": [{}]}}]`),
 					ProwConfig: ProwConfig{
 						InRepoConfig: InRepoConfig{
 							AllowedClusters: map[string][]string{"*": {kube.DefaultClusterAlias}},
+							AllowedIncludes: map[string][]string{org: {org + "/ci-templates"}},
 						},
 					},
 				}
@@ -99,6 +99,9 @@ type JobBase struct {
 	// Cluster is the alias of the cluster to run this job in.
 	// (Default: kube.DefaultClusterAlias)
 	Cluster string `json:"cluster,omitempty"`
+	// ClusterSelector makes plank pick the cluster to run this job in when
+	// it starts it, instead of using Cluster.
+	ClusterSelector *prowapi.ClusterSelector `json:"cluster_selector,omitempty"`
 	// Namespace is the namespace in which pods schedule.
 	//   nil: results in config.PodNamespace (aka pod default)
 	//   empty: results in config.ProwJobNamespace (aka same as prowjob)
@@ -108,6 +111,9 @@ type JobBase struct {
 	// If this field is unspecified or false, a new pod will be created to replace
 	// the evicted one.
 	ErrorOnEviction bool `json:"error_on_eviction,omitempty"`
+	// RetryPolicy makes plank re-create the pod of the job when it fails,
+	// instead of completing the job. Only applies to agent: kubernetes.
+	RetryPolicy *prowapi.RetryPolicy `json:"retry_policy,omitempty"`
 	// SourcePath contains the path where this job is defined
 	SourcePath string `json:"-"`
 	// Spec is the Kubernetes pod spec used if Agent is kubernetes.
@@ -155,6 +161,11 @@ type Presubmit struct {
 	// (Default: `/test <job name>`)
 	RerunCommand string `json:"rerun_command,omitempty"`
 
+	// RunAfter are the names of presubmits of the same repo this job depends
+	// on. The job only starts once they succeeded for the same pull request
+	// commit, and is aborted if one of them does not.
+	RunAfter []string `json:"run_after,omitempty"`
+
 	Brancher
 
 	RegexpChangeMatcher
@@ -207,6 +218,11 @@ type Postsubmit struct {
 	// if this field is not provided, which is the opposite of what we want.
 	AlwaysRun *bool `json:"always_run,omitempty"`
 
+	// RunAfter are the names of postsubmits of the same repo this job depends
+	// on. The job only starts once they succeeded for the same commit, and is
+	// aborted if one of them does not.
+	RunAfter []string `json:"run_after,omitempty"`
+
 	RegexpChangeMatcher
 
 	Brancher
@@ -500,6 +516,18 @@ type UtilityConfig struct {
 	// SkipFetchHead tells prow to avoid a git fetch <remote> call.
 	// The git fetch <remote> <BaseRef> call occurs regardless.
 	SkipFetchHead bool `json:"skip_fetch_head,omitempty"`
+	// SparseCheckout are the directories checked out, in addition
+	// to the files at the root of the repository. All files are
+	// checked out if it is empty.
+	SparseCheckout []string `json:"sparse_checkout,omitempty"`
+	// PartialClone makes a blobless clone: the contents of files
+	// are only fetched when they are checked out, or lazily from
+	// the clone URI when they are needed later on.
+	PartialClone bool `json:"partial_clone,omitempty"`
+	// FetchLFS fetches the Git LFS objects of the repository
+	// after it is checked out. It requires git-lfs in the
+	// clonerefs image.
+	FetchLFS bool `json:"fetch_lfs,omitempty"`
 
 	// ExtraRefs are auxiliary repositories that
 	// need to be cloned, determined from config
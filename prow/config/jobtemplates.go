@@ -0,0 +1,460 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// jobTemplatesKey declares named partial jobs, by name, in job config
+	// files. Templates may inherit other templates.
+	jobTemplatesKey = "job_templates"
+	// jobOverlaysKey declares the templates inherited by all the presubmits
+	// and postsubmits of an org, an org/repo or an org/repo:branch, by org,
+	// org/repo or org/repo:branch.
+	jobOverlaysKey = "job_overlays"
+	// inheritsKey lists the templates a job or a template inherits.
+	inheritsKey = "inherits"
+)
+
+// jobTemplateResolver resolves the inheritance of the jobs of job config
+// files. A job inherits, in order, the overlays of its org, the overlays of
+// its org/repo, the overlays of its org/repo:branch if it only runs on that
+// branch and the templates it lists under inherits, and overrides them: maps
+// are merged recursively, other values, lists included, are replaced and null
+// removes an inherited value.
+type jobTemplateResolver struct {
+	// kind names the templates in errors.
+	kind      string
+	templates map[string]map[string]interface{}
+	overlays  map[string][]string
+	// sources are the files declaring the templates and overlays, for errors.
+	sources map[string]string
+
+	resolved  map[string]map[string]interface{}
+	resolving map[string]bool
+}
+
+func newJobTemplateResolver() *jobTemplateResolver {
+	return &jobTemplateResolver{
+		kind:      "job template",
+		templates: map[string]map[string]interface{}{},
+		overlays:  map[string][]string{},
+		sources:   map[string]string{},
+		resolved:  map[string]map[string]interface{}{},
+		resolving: map[string]bool{},
+	}
+}
+
+// usesJobTemplates returns whether the job config may declare or use
+// templates, to spare decoding the job configs that don't.
+func usesJobTemplates(b []byte) bool {
+	for _, key := range []string{jobTemplatesKey, jobOverlaysKey, inheritsKey} {
+		if bytes.Contains(b, []byte(key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeJobConfig decodes a yaml job config into generic JSON values.
+func decodeJobConfig(b []byte) (map[string]interface{}, error) {
+	j, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	// Numbers are kept as written, large integers would be marshaled back
+	// in exponent notation as floats.
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.UseNumber()
+	var raw map[string]interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+	return raw, nil
+}
+
+// collect removes the templates and overlays declared by the decoded job
+// config of the file and adds them to the resolver.
+func (r *jobTemplateResolver) collect(path string, raw map[string]interface{}) error {
+	if err := r.collectTemplates(path, raw, jobTemplatesKey); err != nil {
+		return err
+	}
+	if overlays, ok := raw[jobOverlaysKey]; ok {
+		delete(raw, jobOverlaysKey)
+		overlayMap, ok := overlays.(map[string]interface{})
+		if !ok && overlays != nil {
+			return fmt.Errorf("%s must map orgs, org/repos and org/repo:branches to template names", jobOverlaysKey)
+		}
+		for orgRepo, names := range overlayMap {
+			if source, ok := r.sources["overlay "+orgRepo]; ok {
+				return fmt.Errorf("job overlays of %q are already declared in %s", orgRepo, source)
+			}
+			templateNames, err := templateNames(names)
+			if err != nil {
+				return fmt.Errorf("job overlays of %q: %w", orgRepo, err)
+			}
+			r.overlays[orgRepo] = templateNames
+			r.sources["overlay "+orgRepo] = path
+		}
+	}
+	return nil
+}
+
+// collectTemplates removes the templates declared under the key by the decoded
+// config of the file and adds them to the resolver.
+func (r *jobTemplateResolver) collectTemplates(path string, raw map[string]interface{}, key string) error {
+	templates, ok := raw[key]
+	if !ok {
+		return nil
+	}
+	delete(raw, key)
+	templateMap, ok := templates.(map[string]interface{})
+	if !ok && templates != nil {
+		return fmt.Errorf("%s must map template names to settings", key)
+	}
+	for name, template := range templateMap {
+		if source, ok := r.sources["template "+name]; ok {
+			return fmt.Errorf("%s %q is already declared in %s", r.kind, name, source)
+		}
+		t, ok := template.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s %q must be a map of settings", r.kind, name)
+		}
+		r.templates[name] = t
+		r.sources["template "+name] = path
+	}
+	return nil
+}
+
+func templateNames(value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok && value != nil {
+		return nil, fmt.Errorf("%s must be a list of template names", inheritsKey)
+	}
+	var names []string
+	for _, item := range list {
+		name, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of template names", inheritsKey)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// template returns the template of the name with its inheritance resolved.
+func (r *jobTemplateResolver) template(name string) (map[string]interface{}, error) {
+	if resolved, ok := r.resolved[name]; ok {
+		return resolved, nil
+	}
+	template, ok := r.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown %s %q", r.kind, name)
+	}
+	if r.resolving[name] {
+		return nil, fmt.Errorf("%s %q inherits itself", r.kind, name)
+	}
+	r.resolving[name] = true
+	defer delete(r.resolving, name)
+
+	resolved, err := r.inherit(template, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s %q: %w", r.kind, name, err)
+	}
+	r.resolved[name] = resolved
+	return resolved, nil
+}
+
+// inherit returns the job merged over the templates it inherits, after the
+// overlays.
+func (r *jobTemplateResolver) inherit(job map[string]interface{}, overlays []string) (map[string]interface{}, error) {
+	inherits, err := templateNames(job[inheritsKey])
+	if err != nil {
+		return nil, err
+	}
+	own := make(map[string]interface{}, len(job))
+	for k, v := range job {
+		if k != inheritsKey {
+			own[k] = v
+		}
+	}
+
+	merged := map[string]interface{}{}
+	for _, name := range append(append([]string{}, overlays...), inherits...) {
+		template, err := r.template(name)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeJSON(merged, template).(map[string]interface{})
+	}
+	return mergeJSON(merged, own).(map[string]interface{}), nil
+}
+
+// overlaysFor returns the templates inherited by the jobs of the repo, the
+// overlays of its org first.
+func (r *jobTemplateResolver) overlaysFor(repo string) []string {
+	var overlays []string
+	if idx := strings.LastIndex(repo, "/"); idx > 0 {
+		overlays = append(overlays, r.overlays[repo[:idx]]...)
+	}
+	return append(overlays, r.overlays[repo]...)
+}
+
+// hasBranchOverlays returns whether overlays are declared for a branch of the
+// repo.
+func (r *jobTemplateResolver) hasBranchOverlays(repo string) bool {
+	for orgRepo := range r.overlays {
+		if strings.HasPrefix(orgRepo, repo+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// soleBranch returns the branch of a decoded job that only runs on one branch.
+func soleBranch(job map[string]interface{}) (string, bool) {
+	branches, ok := job["branches"].([]interface{})
+	if !ok || len(branches) != 1 {
+		return "", false
+	}
+	branch, ok := branches[0].(string)
+	return branch, ok
+}
+
+// resolve resolves the inheritance of the jobs of the decoded job config.
+func (r *jobTemplateResolver) resolve(raw map[string]interface{}) error {
+	for _, key := range []string{"presubmits", "postsubmits"} {
+		repos, ok := raw[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for repo, jobs := range repos {
+			jobList, ok := jobs.([]interface{})
+			if !ok {
+				continue
+			}
+			for i, job := range jobList {
+				if err := r.resolveJob(jobList, i, job, repo); err != nil {
+					return fmt.Errorf("%s of %s: %w", key, repo, err)
+				}
+			}
+		}
+	}
+	if jobList, ok := raw["periodics"].([]interface{}); ok {
+		for i, job := range jobList {
+			if err := r.resolveJob(jobList, i, job, ""); err != nil {
+				return fmt.Errorf("periodics: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveJob resolves the inheritance of a job of the repo, periodics have no
+// repo.
+func (r *jobTemplateResolver) resolveJob(jobs []interface{}, i int, job interface{}, repo string) error {
+	jobMap, ok := job.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var overlays []string
+	var branchOverlays bool
+	if repo != "" {
+		overlays = r.overlaysFor(repo)
+		branchOverlays = r.hasBranchOverlays(repo)
+	}
+	if _, ok := jobMap[inheritsKey]; !ok && len(overlays) == 0 && !branchOverlays {
+		return nil
+	}
+	resolved, err := r.inherit(jobMap, overlays)
+	if err != nil {
+		return fmt.Errorf("job %v: %w", jobMap["name"], err)
+	}
+	// The branches of a job may be inherited, so the overlays of its branch
+	// are only known once it is resolved.
+	if branch, ok := soleBranch(resolved); ok && len(r.overlays[repo+":"+branch]) > 0 {
+		overlays = append(overlays, r.overlays[repo+":"+branch]...)
+		if resolved, err = r.inherit(jobMap, overlays); err != nil {
+			return fmt.Errorf("job %v: %w", jobMap["name"], err)
+		}
+	}
+	jobs[i] = resolved
+	return nil
+}
+
+// resolveAll resolves the inheritance of every map of the decoded value that
+// lists templates under inherits. The value is not modified.
+func (r *jobTemplateResolver) resolveAll(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, ok := v[inheritsKey]; ok {
+			inherited, err := r.inherit(v, nil)
+			if err != nil {
+				return nil, err
+			}
+			v = inherited
+		}
+		resolved := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolvedItem, err := r.resolveAll(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			resolved[key] = resolvedItem
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			resolvedItem, err := r.resolveAll(item)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			resolved[i] = resolvedItem
+		}
+		return resolved, nil
+	}
+	return value, nil
+}
+
+// TemplateResolver resolves the templates of config files other than job
+// configs, like plugins.yaml. Templates are declared by name under the
+// templates key of the resolver, and any map of settings may list the
+// templates it inherits under inherits and override them the way jobs do.
+type TemplateResolver struct {
+	templatesKey string
+	resolver     *jobTemplateResolver
+}
+
+// NewTemplateResolver returns a TemplateResolver for the templates declared
+// under templatesKey, named kind in errors.
+func NewTemplateResolver(templatesKey, kind string) *TemplateResolver {
+	resolver := newJobTemplateResolver()
+	resolver.kind = kind
+	return &TemplateResolver{templatesKey: templatesKey, resolver: resolver}
+}
+
+// Collect decodes the yaml config of the file and adds the templates it
+// declares to the resolver. It returns the decoded config without the
+// templates, or nil if the config neither declares nor inherits templates.
+func (t *TemplateResolver) Collect(path string, b []byte) (map[string]interface{}, error) {
+	if !bytes.Contains(b, []byte(t.templatesKey)) && !bytes.Contains(b, []byte(inheritsKey)) {
+		return nil, nil
+	}
+	raw, err := decodeJobConfig(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.resolver.collectTemplates(path, raw, t.templatesKey); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// Unmarshal unmarshals the yaml config into target. If Collect decoded the
+// config into raw, the templates its settings inherit are resolved first.
+func (t *TemplateResolver) Unmarshal(b []byte, raw map[string]interface{}, target interface{}, opts ...yaml.JSONOpt) error {
+	if raw != nil {
+		resolved, err := t.resolver.resolveAll(raw)
+		if err != nil {
+			return err
+		}
+		if b, err = json.Marshal(resolved); err != nil {
+			return err
+		}
+	}
+	return yaml.Unmarshal(b, target, opts...)
+}
+
+// StripJobTemplates returns the yaml job config without the job templates and
+// overlays it declares and the inherits of its jobs, see StripTemplates.
+func StripJobTemplates(b []byte) ([]byte, error) {
+	return StripTemplates(b, jobTemplatesKey, jobOverlaysKey)
+}
+
+// StripTemplates returns the yaml config without the templates declared under
+// the keys and the inherits of its settings, for the checks of the fields of
+// configs that don't resolve templates. Configs that neither declare nor
+// inherit templates are returned as they are.
+func StripTemplates(b []byte, keys ...string) ([]byte, error) {
+	uses := bytes.Contains(b, []byte(inheritsKey))
+	for _, key := range keys {
+		uses = uses || bytes.Contains(b, []byte(key))
+	}
+	if !uses {
+		return b, nil
+	}
+	raw, err := decodeJobConfig(b)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		delete(raw, key)
+	}
+	var strip func(value interface{})
+	strip = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			delete(v, inheritsKey)
+			for _, item := range v {
+				strip(item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				strip(item)
+			}
+		}
+	}
+	strip(raw)
+	return json.Marshal(raw)
+}
+
+// mergeJSON merges the overlay decoded JSON value over the base one: maps are
+// merged recursively, null removes a value of the base and other values
+// replace the base. The values are not modified.
+func mergeJSON(base, overlay interface{}) interface{} {
+	overlayMap, ok := overlay.(map[string]interface{})
+	if !ok {
+		return overlay
+	}
+	baseMap, ok := base.(map[string]interface{})
+	if !ok {
+		baseMap = map[string]interface{}{}
+	}
+	merged := make(map[string]interface{}, len(baseMap)+len(overlayMap))
+	for k, v := range baseMap {
+		merged[k] = v
+	}
+	for k, v := range overlayMap {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = mergeJSON(merged[k], v)
+	}
+	return merged
+}
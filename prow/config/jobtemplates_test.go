@@ -0,0 +1,276 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/yaml"
+)
+
+const orgTemplates = `job_templates:
+  org-defaults:
+    decorate: true
+    max_concurrency: 1000000
+    labels:
+      preset-service-account: "true"
+      preset-dind: "true"
+    spec:
+      containers:
+      - image: org-image:latest
+  unit:
+    inherits: [org-defaults]
+    always_run: true
+    spec:
+      containers:
+      - image: unit-image:latest
+        command: ["make", "test"]
+job_overlays:
+  org: [org-defaults]
+`
+
+func TestJobTemplates(t *testing.T) {
+	testCases := []struct {
+		name  string
+		files map[string]string
+
+		expectedPresubmits  map[string][]string
+		expectedPostsubmits map[string][]string
+		expectedPeriodics   []string
+		expectedErr         string
+	}{
+		{
+			name: "jobs inherit templates and overlays of other files",
+			files: map[string]string{
+				"org.yaml": orgTemplates,
+				"repo.yaml": `presubmits:
+  org/repo:
+  - name: unit
+    inherits: [unit]
+    labels:
+      preset-dind: null
+  - name: lint
+    spec:
+      containers:
+      - image: lint-image:latest
+postsubmits:
+  other/repo:
+  - name: build
+    spec:
+      containers:
+      - image: build-image:latest
+periodics:
+- name: nightly
+  interval: 24h
+  inherits: [unit]
+  always_run: null
+`,
+			},
+			expectedPresubmits: map[string][]string{
+				"org/repo": {
+					"unit decorate=true always_run=true max_concurrency=1000000 labels=map[preset-service-account:true] image=unit-image:latest",
+					"lint decorate=true always_run=false max_concurrency=1000000 labels=map[preset-dind:true preset-service-account:true] image=lint-image:latest",
+				},
+			},
+			expectedPostsubmits: map[string][]string{
+				"other/repo": {"build decorate=false always_run=false max_concurrency=0 labels=map[] image=build-image:latest"},
+			},
+			expectedPeriodics: []string{
+				"nightly decorate=true always_run=false max_concurrency=1000000 labels=map[preset-dind:true preset-service-account:true] image=unit-image:latest",
+			},
+		},
+		{
+			name: "repo overlays apply after org overlays",
+			files: map[string]string{
+				"org.yaml": orgTemplates + `  org/repo: [repo-defaults]
+`,
+				"repo.yaml": `job_templates:
+  repo-defaults:
+    max_concurrency: 1
+presubmits:
+  org/repo:
+  - name: unit
+`,
+			},
+			expectedPresubmits: map[string][]string{
+				"org/repo": {"unit decorate=true always_run=false max_concurrency=1 labels=map[preset-dind:true preset-service-account:true] image=org-image:latest"},
+			},
+		},
+		{
+			name: "branch overlays apply to the jobs of the branch",
+			files: map[string]string{
+				"org.yaml": orgTemplates + `  org/repo:release-1.0: [release]
+`,
+				"repo.yaml": `job_templates:
+  release:
+    max_concurrency: 1
+  release-branch:
+    branches: [release-1.0]
+presubmits:
+  org/repo:
+  - name: unit
+  - name: release-unit
+    branches: [release-1.0]
+  - name: release-lint
+    inherits: [release-branch]
+  - name: all-branches-unit
+    branches: [master, release-1.0]
+`,
+			},
+			expectedPresubmits: map[string][]string{
+				"org/repo": {
+					"unit decorate=true always_run=false max_concurrency=1000000 labels=map[preset-dind:true preset-service-account:true] image=org-image:latest",
+					"release-unit decorate=true always_run=false max_concurrency=1 labels=map[preset-dind:true preset-service-account:true] image=org-image:latest",
+					"release-lint decorate=true always_run=false max_concurrency=1 labels=map[preset-dind:true preset-service-account:true] image=org-image:latest",
+					"all-branches-unit decorate=true always_run=false max_concurrency=1000000 labels=map[preset-dind:true preset-service-account:true] image=org-image:latest",
+				},
+			},
+		},
+		{
+			name: "unknown template",
+			files: map[string]string{
+				"repo.yaml": `presubmits:
+  org/repo:
+  - name: unit
+    inherits: [unit]
+`,
+			},
+			expectedErr: `unknown job template "unit"`,
+		},
+		{
+			name: "templates inheriting each other",
+			files: map[string]string{
+				"repo.yaml": `job_templates:
+  a:
+    inherits: [b]
+  b:
+    inherits: [a]
+periodics:
+- name: nightly
+  inherits: [a]
+`,
+			},
+			expectedErr: "inherits itself",
+		},
+		{
+			name: "duplicate template",
+			files: map[string]string{
+				"a.yaml": orgTemplates,
+				"b.yaml": `job_templates:
+  unit: {}
+`,
+			},
+			expectedErr: `job template "unit" is already declared`,
+		},
+	}
+
+	describe := func(name string, base JobBase, alwaysRun bool) string {
+		var images []string
+		if base.Spec != nil {
+			for _, c := range base.Spec.Containers {
+				images = append(images, c.Image)
+			}
+		}
+		labels := map[string]string{}
+		for k, v := range base.Labels {
+			labels[k] = v
+		}
+		decorate := base.Decorate != nil && *base.Decorate
+		return fmt.Sprintf("%s decorate=%t always_run=%t max_concurrency=%d labels=%v image=%s", name, decorate, alwaysRun, base.MaxConcurrency, labels, strings.Join(images, ","))
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+					t.Fatal(err)
+				}
+			}
+			jc, err := ReadJobConfig(dir, yaml.DisallowUnknownFields)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			presubmits := map[string][]string{}
+			for repo, jobs := range jc.PresubmitsStatic {
+				for _, job := range jobs {
+					presubmits[repo] = append(presubmits[repo], describe(job.Name, job.JobBase, job.AlwaysRun))
+				}
+			}
+			postsubmits := map[string][]string{}
+			for repo, jobs := range jc.PostsubmitsStatic {
+				for _, job := range jobs {
+					postsubmits[repo] = append(postsubmits[repo], describe(job.Name, job.JobBase, job.AlwaysRun != nil && *job.AlwaysRun))
+				}
+			}
+			var periodics []string
+			for _, job := range jc.Periodics {
+				periodics = append(periodics, describe(job.Name, job.JobBase, false))
+			}
+			if tc.expectedPresubmits == nil {
+				tc.expectedPresubmits = map[string][]string{}
+			}
+			if tc.expectedPostsubmits == nil {
+				tc.expectedPostsubmits = map[string][]string{}
+			}
+			if diff := cmp.Diff(tc.expectedPresubmits, presubmits); diff != "" {
+				t.Errorf("presubmits differ from expected (-expected +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedPostsubmits, postsubmits); diff != "" {
+				t.Errorf("postsubmits differ from expected (-expected +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedPeriodics, periodics); diff != "" {
+				t.Errorf("periodics differ from expected (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMergeJSON(t *testing.T) {
+	base := map[string]interface{}{
+		"decorate": true,
+		"labels":   map[string]interface{}{"a": "1", "b": "2"},
+		"args":     []interface{}{"x", "y"},
+	}
+	overlay := map[string]interface{}{
+		"decorate": nil,
+		"labels":   map[string]interface{}{"b": "3", "c": "4"},
+		"args":     []interface{}{"z"},
+	}
+	expected := map[string]interface{}{
+		"labels": map[string]interface{}{"a": "1", "b": "3", "c": "4"},
+		"args":   []interface{}{"z"},
+	}
+	if diff := cmp.Diff(expected, mergeJSON(base, overlay)); diff != "" {
+		t.Errorf("merged value differs from expected (-expected +got):\n%s", diff)
+	}
+	if _, ok := base["decorate"]; !ok {
+		t.Error("expected the base not to be modified")
+	}
+}
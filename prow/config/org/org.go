@@ -69,11 +69,33 @@ type Repo struct {
 	DefaultBranch *string `json:"default_branch,omitempty"`
 	Archived      *bool   `json:"archived,omitempty"`
 
+	// Topics replace all topics of the repo when set. An empty list
+	// removes all topics.
+	Topics []string `json:"topics,omitempty"`
+	// VulnerabilityAlerts enables or disables vulnerability alerts.
+	VulnerabilityAlerts *bool `json:"vulnerability_alerts,omitempty"`
+	// Pages configures the GitHub Pages site of the repo.
+	Pages *RepoPages `json:"pages,omitempty"`
+
 	Previously []string `json:"previously,omitempty"`
 
 	OnCreate *RepoCreateOptions `json:"on_create,omitempty"`
 }
 
+// RepoPages declares the GitHub Pages site of a repository
+//
+// See https://docs.github.com/en/rest/reference/repos#pages
+type RepoPages struct {
+	// Branch is the branch the site is built from. The site is disabled
+	// when it is empty.
+	Branch string `json:"branch,omitempty"`
+	// Path is the directory the site is built from, either / or /docs.
+	// Defaults to /.
+	Path string `json:"path,omitempty"`
+	// CNAME is the custom domain of the site. An empty string removes it.
+	CNAME *string `json:"cname,omitempty"`
+}
+
 // Config declares org metadata as well as its people and teams.
 type Config struct {
 	Metadata
@@ -106,6 +128,29 @@ type Team struct {
 	// https://developer.github.com/v3/teams/#list-team-repos
 	// https://developer.github.com/v3/teams/#add-or-update-team-repository
 	Repos map[string]github.RepoPermissionLevel `json:"repos,omitempty"`
+
+	// Owners adds the approvers in OWNERS files to the team, so that the
+	// team and the OWNERS files don't drift apart.
+	Owners *TeamOwners `json:"owners,omitempty"`
+}
+
+// TeamOwners declares the OWNERS files that the members of a team are
+// derived from.
+type TeamOwners struct {
+	// Repos are the repos, as org/repo, whose OWNERS files are aggregated.
+	Repos []string `json:"repos"`
+	// Aliases are the OWNERS_ALIASES aliases whose members join the team.
+	Aliases []string `json:"aliases,omitempty"`
+	// Paths are the directories whose OWNERS approvers join the team. Each
+	// must have an OWNERS file in at least one of the repos. Approvers who
+	// are not org members are skipped.
+	Paths []string `json:"paths,omitempty"`
+	// Exclude are the users that never join the team from OWNERS files,
+	// e.g. bots or approvers who are not meant to have the team's access.
+	Exclude []string `json:"exclude,omitempty"`
+	// Maintainers makes the users from OWNERS files maintainers of the team
+	// instead of members.
+	Maintainers bool `json:"maintainers,omitempty"`
 }
 
 // Privacy is secret or closed.
@@ -22,8 +22,10 @@ import (
 	"strings"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"gopkg.in/robfig/cron.v2"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -74,11 +76,151 @@ type TideMergeCommitTemplate struct {
 	Body  *template.Template `json:"-"`
 }
 
+// TideMergeCalendar restricts when Tide merges the PRs of some branches.
+type TideMergeCalendar struct {
+	// Orgs and Repos are the orgs and org/repos the calendar applies to.
+	Orgs  []string `json:"orgs,omitempty"`
+	Repos []string `json:"repos,omitempty"`
+	// Branches limits the calendar to some branches. Defaults to all branches.
+	Branches []string `json:"branches,omitempty"`
+	// Windows are the recurring periods during which merging is allowed.
+	// If none are set, merging is allowed at any time outside of freezes.
+	Windows []TideMergeWindow `json:"windows,omitempty"`
+	// Freezes are the ad-hoc periods during which merging is not allowed.
+	Freezes []TideMergeFreeze `json:"freezes,omitempty"`
+	// OverrideLabel is an optional label allowing PRs to be merged regardless
+	// of the calendar in an emergency.
+	OverrideLabel string `json:"override_label,omitempty"`
+}
+
+// TideMergeWindow is a recurring period during which merging is allowed.
+type TideMergeWindow struct {
+	// Cron is the cron expression of the start of the window, e.g. "0 9 * * 1-5".
+	// It is evaluated in UTC unless a time zone is given, e.g. "TZ=Europe/Berlin 0 9 * * 1-5".
+	Cron string `json:"cron,omitempty"`
+	// Duration is how long the window stays open, e.g. "8h".
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	Schedule cron.Schedule `json:"-"`
+}
+
+// TideMergeFreeze is an ad-hoc period during which merging is not allowed.
+type TideMergeFreeze struct {
+	Start metav1.Time `json:"start,omitempty"`
+	End   metav1.Time `json:"end,omitempty"`
+	// Reason is shown in the status of the frozen PRs, e.g. "v1.2 release".
+	Reason string `json:"reason,omitempty"`
+}
+
+// IsOpen returns whether the window is open at the given time.
+func (w *TideMergeWindow) IsOpen(now time.Time) bool {
+	if w.Schedule == nil || w.Duration == nil {
+		return false
+	}
+	start := w.Schedule.Next(now.Add(-w.Duration.Duration))
+	return !start.IsZero() && !start.After(now)
+}
+
+// Frozen returns whether the calendar does not allow merging at the given
+// time, and why.
+func (c *TideMergeCalendar) Frozen(now time.Time) (bool, string) {
+	for _, freeze := range c.Freezes {
+		if now.Before(freeze.Start.Time) || !now.Before(freeze.End.Time) {
+			continue
+		}
+		if freeze.Reason != "" {
+			return true, fmt.Sprintf("merge freeze (%s)", freeze.Reason)
+		}
+		return true, "merge freeze"
+	}
+	if len(c.Windows) == 0 {
+		return false, ""
+	}
+	for i := range c.Windows {
+		if c.Windows[i].IsOpen(now) {
+			return false, ""
+		}
+	}
+	return true, "outside of merge windows"
+}
+
+func (c *TideMergeCalendar) validate() error {
+	if len(c.Orgs) == 0 && len(c.Repos) == 0 {
+		return errors.New("no orgs or repos are set")
+	}
+	for i := range c.Windows {
+		w := &c.Windows[i]
+		if w.Duration == nil || w.Duration.Duration <= 0 {
+			return fmt.Errorf("window %q has no positive duration", w.Cron)
+		}
+		spec := w.Cron
+		if !strings.HasPrefix(spec, "TZ=") {
+			spec = "TZ=UTC " + spec
+		}
+		schedule, err := cron.Parse(spec)
+		if err != nil {
+			return fmt.Errorf("invalid cron string %q: %w", w.Cron, err)
+		}
+		w.Schedule = schedule
+	}
+	for _, freeze := range c.Freezes {
+		if !freeze.End.After(freeze.Start.Time) {
+			return fmt.Errorf("freeze %q does not end after it starts", freeze.Reason)
+		}
+	}
+	return nil
+}
+
+// TideGitLab configures the orgs Tide merges on GitLab. An org is a GitLab
+// group and its repos are the projects of the group. Queries, merge methods and
+// the other Tide settings apply to these orgs like to GitHub orgs.
+type TideGitLab struct {
+	// Orgs are the GitLab groups whose merge requests Tide merges.
+	Orgs []string `json:"orgs,omitempty"`
+	// MergeTrainsMap configures on org or org/repo level if Tide adds merge
+	// requests to the merge train of their target branch instead of merging them.
+	// Use '*' as key to set this globally. Defaults to false.
+	MergeTrainsMap map[string]bool `json:"merge_trains,omitempty"`
+}
+
+// IsGitLabOrg returns whether the org is hosted on GitLab.
+func (g *TideGitLab) IsGitLabOrg(org string) bool {
+	for _, o := range g.Orgs {
+		if o == org {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeTrain returns whether merge requests of the repo are merged through
+// merge trains.
+func (g *TideGitLab) MergeTrain(repo OrgRepo) bool {
+	if val, set := g.MergeTrainsMap[repo.String()]; set {
+		return val
+	}
+	if val, set := g.MergeTrainsMap[repo.Org]; set {
+		return val
+	}
+	return g.MergeTrainsMap["*"]
+}
+
 // TidePriority contains a list of labels used to prioritize PRs in the merge pool
 type TidePriority struct {
+	// Name is the name of the priority class in metrics. Defaults to
+	// the labels joined by commas.
+	Name   string   `json:"name,omitempty"`
 	Labels []string `json:"labels,omitempty"`
 }
 
+// ClassName returns the name of the priority class in metrics.
+func (p TidePriority) ClassName() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return strings.Join(p.Labels, ",")
+}
+
 // Tide is config for the tide pool.
 type Tide struct {
 	// SyncPeriod specifies how often Tide will sync jobs with GitHub. Defaults to 1m.
@@ -139,6 +281,12 @@ type Tide struct {
 	// Leave this blank to disable this feature.
 	MergeLabel string `json:"merge_label,omitempty"`
 
+	// MergeMethodOverrides is a key/value pair of an org or org/repo as the key
+	// and the merge methods that the SquashLabel, RebaseLabel and MergeLabel may
+	// select for a PR as the value. PRs requesting any other method through a
+	// label are not merged. Repos without an entry allow all merge methods.
+	MergeMethodOverrides map[string][]github.PullRequestMergeType `json:"merge_method_overrides,omitempty"`
+
 	// MaxGoroutines is the maximum number of goroutines spawned inside the
 	// controller to handle org/repo:branch pools. Defaults to 20. Needs to be a
 	// positive number.
@@ -159,7 +307,8 @@ type Tide struct {
 
 	// Priority is an ordered list of sets of labels that would be prioritized before other PRs
 	// PRs should match all labels contained in a set to be prioritized. The first entry has
-	// the highest priority.
+	// the highest priority. Prioritized PRs are picked first for serial merges, batches and
+	// merge queues.
 	Priority []TidePriority `json:"priority,omitempty"`
 
 	// PrioritizeExistingBatches configures on org or org/repo level if Tide should continue
@@ -169,6 +318,49 @@ type Tide struct {
 	// Use '*' as key to set this globally. Defaults to true.
 	PrioritizeExistingBatchesMap map[string]bool `json:"prioritize_existing_batches,omitempty"`
 
+	// MergeQueueMap configures on org or org/repo level if Tide should merge PRs
+	// through a merge queue. PRs enter a FIFO queue per branch and every PR is
+	// speculatively tested on top of the PRs ahead of it in the queue. PRs failing
+	// their speculative tests are evicted without invalidating the rest of the queue.
+	// The depth of the queue is limited by the batch size limit.
+	// Use '*' as key to set this globally. Defaults to false.
+	MergeQueueMap map[string]bool `json:"merge_queue,omitempty"`
+
+	// MergeCalendars restrict when Tide merges the PRs of some branches. PRs are
+	// only merged if all the calendars applying to their branch allow it.
+	MergeCalendars []TideMergeCalendar `json:"merge_calendars,omitempty"`
+
+	// RequireUpToDateMap configures on org or org/repo level if Tide should update
+	// the branch of PRs that are behind their base branch before merging them,
+	// instead of merging stale heads. Updating the branch retests the PR. Batch
+	// merges are disabled for these repos, and their merge queues only test
+	// their head.
+	// Use '*' as key to set this globally. Defaults to false.
+	RequireUpToDateMap map[string]bool `json:"require_up_to_date,omitempty"`
+
+	// MaxBranchUpdates is the maximum number of times Tide updates the branch of
+	// a PR while it is in the pool. PRs behind their base branch whose branch was
+	// updated that many times are not merged. Defaults to 3.
+	MaxBranchUpdates int `json:"max_branch_updates,omitempty"`
+
+	// PendingContextGracePeriods configures on org or org/repo level for how long
+	// Tide assumes that pending required contexts will pass. PRs whose pending
+	// contexts were created within the grace period stay in the pool and can be
+	// tested in batches, but are only merged once their contexts passed.
+	// Use '*' as key to set this globally. Defaults to no grace period.
+	PendingContextGracePeriods map[string]metav1.Duration `json:"pending_context_grace_periods,omitempty"`
+
+	// AutoMergeMap configures on org or org/repo level if Tide should enable the
+	// auto-merge of GitHub on PRs instead of merging them itself, for branches
+	// whose protection requires GitHub to merge PRs. Tide still tests and batches
+	// the PRs. Does not apply to GitLab orgs.
+	// Use '*' as key to set this globally. Defaults to false.
+	AutoMergeMap map[string]bool `json:"auto_merge,omitempty"`
+
+	// GitLab configures the orgs whose merge requests Tide merges on GitLab
+	// instead of GitHub.
+	GitLab TideGitLab `json:"gitlab,omitempty"`
+
 	// DisplayAllQueriesInStatus controls if Tide should mention all queries in the status it
 	// creates. The default is to only mention the one to which we are closest (Calculated
 	// by total number of requirements - fulfilled number of requirements).
@@ -214,6 +406,68 @@ func (t *Tide) PrioritizeExistingBatches(repo OrgRepo) bool {
 	return true
 }
 
+// MergeQueue returns whether PRs of a repo are merged through a merge queue.
+func (t *Tide) MergeQueue(repo OrgRepo) bool {
+	if val, set := t.MergeQueueMap[repo.String()]; set {
+		return val
+	}
+	if val, set := t.MergeQueueMap[repo.Org]; set {
+		return val
+	}
+	return t.MergeQueueMap["*"]
+}
+
+// RequireUpToDate returns whether Tide updates the branch of PRs behind their
+// base branch before merging them in the repo.
+func (t *Tide) RequireUpToDate(repo OrgRepo) bool {
+	if val, set := t.RequireUpToDateMap[repo.String()]; set {
+		return val
+	}
+	if val, set := t.RequireUpToDateMap[repo.Org]; set {
+		return val
+	}
+	return t.RequireUpToDateMap["*"]
+}
+
+// AutoMerge returns whether Tide enables the auto-merge of GitHub on the PRs
+// of a repo instead of merging them itself.
+func (t *Tide) AutoMerge(repo OrgRepo) bool {
+	if val, set := t.AutoMergeMap[repo.String()]; set {
+		return val
+	}
+	if val, set := t.AutoMergeMap[repo.Org]; set {
+		return val
+	}
+	return t.AutoMergeMap["*"]
+}
+
+// PendingContextGracePeriod returns for how long Tide assumes that the pending
+// contexts of the PRs of a repo will pass.
+func (t *Tide) PendingContextGracePeriod(repo OrgRepo) time.Duration {
+	if val, set := t.PendingContextGracePeriods[repo.String()]; set {
+		return val.Duration
+	}
+	if val, set := t.PendingContextGracePeriods[repo.Org]; set {
+		return val.Duration
+	}
+	return t.PendingContextGracePeriods["*"].Duration
+}
+
+// MergeCalendarsFor returns the merge calendars applying to a branch.
+func (t *Tide) MergeCalendarsFor(repo OrgRepo, branch string) []TideMergeCalendar {
+	var calendars []TideMergeCalendar
+	for _, c := range t.MergeCalendars {
+		if !sets.NewString(c.Orgs...).Has(repo.Org) && !sets.NewString(c.Repos...).Has(repo.String()) {
+			continue
+		}
+		if len(c.Branches) > 0 && !sets.NewString(c.Branches...).Has(branch) {
+			continue
+		}
+		calendars = append(calendars, c)
+	}
+	return calendars
+}
+
 func (t *Tide) BatchSizeLimit(repo OrgRepo) int {
 	if limit, ok := t.BatchSizeLimitMap[repo.String()]; ok {
 		return limit
@@ -239,6 +493,24 @@ func (t *Tide) MergeMethod(repo OrgRepo) github.PullRequestMergeType {
 	return v
 }
 
+// MergeMethodOverrideAllowed returns whether a label may select the merge
+// method for a PR of the repo.
+func (t *Tide) MergeMethodOverrideAllowed(repo OrgRepo, method github.PullRequestMergeType) bool {
+	allowed, ok := t.MergeMethodOverrides[repo.String()]
+	if !ok {
+		allowed, ok = t.MergeMethodOverrides[repo.Org]
+	}
+	if !ok {
+		return true
+	}
+	for _, m := range allowed {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
 // MergeCommitTemplate returns a struct with Go template string(s) or nil
 func (t *Tide) MergeCommitTemplate(repo OrgRepo) TideMergeCommitTemplate {
 	v, ok := t.MergeTemplate[repo.String()]
@@ -21,7 +21,9 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/diff"
 	"k8s.io/apimachinery/pkg/util/sets"
 	utilpointer "k8s.io/utils/pointer"
@@ -216,6 +218,35 @@ func TestMergeMethod(t *testing.T) {
 		}
 	}
 }
+
+func TestMergeMethodOverrideAllowed(t *testing.T) {
+	ti := &Tide{
+		MergeMethodOverrides: map[string][]github.PullRequestMergeType{
+			"kubernetes":      {github.MergeSquash, github.MergeRebase},
+			"kubernetes/kops": {github.MergeMerge},
+		},
+	}
+
+	var testcases = []struct {
+		repo     OrgRepo
+		method   github.PullRequestMergeType
+		expected bool
+	}{
+		{OrgRepo{Org: "kubernetes-helm", Repo: "monocular"}, github.MergeRebase, true},
+		{OrgRepo{Org: "kubernetes", Repo: "kubernetes"}, github.MergeRebase, true},
+		{OrgRepo{Org: "kubernetes", Repo: "kubernetes"}, github.MergeMerge, false},
+		{OrgRepo{Org: "kubernetes", Repo: "kops"}, github.MergeMerge, true},
+		{OrgRepo{Org: "kubernetes", Repo: "kops"}, github.MergeSquash, false},
+	}
+
+	for _, test := range testcases {
+		actual := ti.MergeMethodOverrideAllowed(test.repo, test.method)
+		if actual != test.expected {
+			t.Errorf("Expected override with %q to be allowed=%t but got %t for %s", test.method, test.expected, actual, test.repo)
+		}
+	}
+}
+
 func TestMergeTemplate(t *testing.T) {
 	ti := &Tide{
 		MergeTemplate: map[string]TideMergeCommitTemplate{
@@ -1089,3 +1120,205 @@ func fakeProwYAMLGetterFactory(presubmits []Presubmit, postsubmits []Postsubmit)
 		}, nil
 	}
 }
+
+func TestMergeCalendarsFor(t *testing.T) {
+	ti := &Tide{
+		MergeCalendars: []TideMergeCalendar{
+			{Orgs: []string{"org"}, OverrideLabel: "org"},
+			{Repos: []string{"org/repo"}, Branches: []string{"release"}, OverrideLabel: "release"},
+		},
+	}
+	testCases := []struct {
+		repo     OrgRepo
+		branch   string
+		expected []string
+	}{
+		{repo: OrgRepo{Org: "org", Repo: "repo"}, branch: "master", expected: []string{"org"}},
+		{repo: OrgRepo{Org: "org", Repo: "repo"}, branch: "release", expected: []string{"org", "release"}},
+		{repo: OrgRepo{Org: "org", Repo: "other"}, branch: "release", expected: []string{"org"}},
+		{repo: OrgRepo{Org: "other", Repo: "repo"}, branch: "release"},
+	}
+	for _, tc := range testCases {
+		var got []string
+		for _, c := range ti.MergeCalendarsFor(tc.repo, tc.branch) {
+			got = append(got, c.OverrideLabel)
+		}
+		if !reflect.DeepEqual(tc.expected, got) {
+			t.Errorf("expected calendars %v for %s:%s, got %v", tc.expected, tc.repo, tc.branch, got)
+		}
+	}
+}
+
+func TestTideMergeCalendarFrozen(t *testing.T) {
+	// A Monday.
+	monday := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	calendar := TideMergeCalendar{
+		Orgs: []string{"org"},
+		Windows: []TideMergeWindow{
+			{Cron: "0 9 * * 1-5", Duration: &metav1.Duration{Duration: 8 * time.Hour}},
+		},
+		Freezes: []TideMergeFreeze{
+			{
+				Start:  metav1.NewTime(monday.Add(24 * time.Hour)),
+				End:    metav1.NewTime(monday.Add(48 * time.Hour)),
+				Reason: "v1.2 release",
+			},
+		},
+	}
+	if err := calendar.validate(); err != nil {
+		t.Fatalf("unexpected error validating the calendar: %v", err)
+	}
+
+	testCases := []struct {
+		name           string
+		now            time.Time
+		expectedFrozen bool
+		expectedReason string
+	}{
+		{
+			name: "inside a window",
+			now:  monday.Add(10 * time.Hour),
+		},
+		{
+			name: "at the start of a window",
+			now:  monday.Add(9 * time.Hour),
+		},
+		{
+			name:           "before a window",
+			now:            monday.Add(8 * time.Hour),
+			expectedFrozen: true,
+			expectedReason: "outside of merge windows",
+		},
+		{
+			name:           "at the end of a window",
+			now:            monday.Add(17 * time.Hour),
+			expectedFrozen: true,
+			expectedReason: "outside of merge windows",
+		},
+		{
+			name:           "on the weekend",
+			now:            monday.Add(-12 * time.Hour),
+			expectedFrozen: true,
+			expectedReason: "outside of merge windows",
+		},
+		{
+			name:           "inside a window during a freeze",
+			now:            monday.Add(34 * time.Hour),
+			expectedFrozen: true,
+			expectedReason: "merge freeze (v1.2 release)",
+		},
+		{
+			name: "inside a window after a freeze",
+			now:  monday.Add(58 * time.Hour),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			frozen, reason := calendar.Frozen(tc.now)
+			if frozen != tc.expectedFrozen || reason != tc.expectedReason {
+				t.Errorf("expected (%t, %q), got (%t, %q)", tc.expectedFrozen, tc.expectedReason, frozen, reason)
+			}
+		})
+	}
+}
+
+func TestTideMergeCalendarValidate(t *testing.T) {
+	hour := &metav1.Duration{Duration: time.Hour}
+	now := time.Now()
+	testCases := []struct {
+		name     string
+		calendar TideMergeCalendar
+		expected string
+	}{
+		{
+			name:     "valid calendar",
+			calendar: TideMergeCalendar{Repos: []string{"org/repo"}, Windows: []TideMergeWindow{{Cron: "TZ=Europe/Berlin 0 9 * * *", Duration: hour}}},
+		},
+		{
+			name:     "no orgs or repos",
+			calendar: TideMergeCalendar{Windows: []TideMergeWindow{{Cron: "0 9 * * *", Duration: hour}}},
+			expected: "no orgs or repos are set",
+		},
+		{
+			name:     "invalid cron",
+			calendar: TideMergeCalendar{Orgs: []string{"org"}, Windows: []TideMergeWindow{{Cron: "every day", Duration: hour}}},
+			expected: `invalid cron string "every day"`,
+		},
+		{
+			name:     "missing duration",
+			calendar: TideMergeCalendar{Orgs: []string{"org"}, Windows: []TideMergeWindow{{Cron: "0 9 * * *"}}},
+			expected: `window "0 9 * * *" has no positive duration`,
+		},
+		{
+			name:     "freeze ending before it starts",
+			calendar: TideMergeCalendar{Orgs: []string{"org"}, Freezes: []TideMergeFreeze{{Start: metav1.NewTime(now), End: metav1.NewTime(now.Add(-time.Hour)), Reason: "oops"}}},
+			expected: `freeze "oops" does not end after it starts`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.calendar.validate()
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tc.expected) {
+				t.Errorf("expected error starting with %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestTidePendingContextGracePeriod(t *testing.T) {
+	tide := &Tide{
+		PendingContextGracePeriods: map[string]metav1.Duration{
+			"*":        {Duration: time.Minute},
+			"org":      {Duration: 10 * time.Minute},
+			"org/slow": {Duration: time.Hour},
+		},
+	}
+	testCases := []struct {
+		repo     OrgRepo
+		expected time.Duration
+	}{
+		{repo: OrgRepo{Org: "org", Repo: "slow"}, expected: time.Hour},
+		{repo: OrgRepo{Org: "org", Repo: "repo"}, expected: 10 * time.Minute},
+		{repo: OrgRepo{Org: "other", Repo: "repo"}, expected: time.Minute},
+	}
+	for _, tc := range testCases {
+		if got := tide.PendingContextGracePeriod(tc.repo); got != tc.expected {
+			t.Errorf("expected grace period %s for %s, got %s", tc.expected, tc.repo, got)
+		}
+	}
+	if got := (&Tide{}).PendingContextGracePeriod(OrgRepo{Org: "org", Repo: "repo"}); got != 0 {
+		t.Errorf("expected no grace period by default, got %s", got)
+	}
+}
+
+func TestTideAutoMerge(t *testing.T) {
+	tide := &Tide{
+		AutoMergeMap: map[string]bool{
+			"*":        true,
+			"org":      false,
+			"org/repo": true,
+		},
+	}
+	testCases := []struct {
+		repo     OrgRepo
+		expected bool
+	}{
+		{repo: OrgRepo{Org: "org", Repo: "repo"}, expected: true},
+		{repo: OrgRepo{Org: "org", Repo: "other"}, expected: false},
+		{repo: OrgRepo{Org: "other", Repo: "repo"}, expected: true},
+	}
+	for _, tc := range testCases {
+		if got := tide.AutoMerge(tc.repo); got != tc.expected {
+			t.Errorf("expected auto-merge %t for %s, got %t", tc.expected, tc.repo, got)
+		}
+	}
+	if (&Tide{}).AutoMerge(OrgRepo{Org: "org", Repo: "repo"}) {
+		t.Error("expected auto-merge to be disabled by default")
+	}
+}
@@ -83,11 +83,21 @@ func (in *JobBase) DeepCopyInto(out *JobBase) {
 			(*out)[key] = val
 		}
 	}
+	if in.ClusterSelector != nil {
+		in, out := &in.ClusterSelector, &out.ClusterSelector
+		*out = new(prowjobsv1.ClusterSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Namespace != nil {
 		in, out := &in.Namespace, &out.Namespace
 		*out = new(string)
 		**out = **in
 	}
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(prowjobsv1.RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Spec != nil {
 		in, out := &in.Spec, &out.Spec
 		*out = new(v1.PodSpec)
@@ -143,6 +153,11 @@ func (in *Postsubmit) DeepCopyInto(out *Postsubmit) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.RunAfter != nil {
+		in, out := &in.RunAfter, &out.RunAfter
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	in.RegexpChangeMatcher.DeepCopyInto(&out.RegexpChangeMatcher)
 	in.Brancher.DeepCopyInto(&out.Brancher)
 	out.Reporter = in.Reporter
@@ -212,6 +227,11 @@ func (in *Preset) DeepCopy() *Preset {
 func (in *Presubmit) DeepCopyInto(out *Presubmit) {
 	*out = *in
 	in.JobBase.DeepCopyInto(&out.JobBase)
+	if in.RunAfter != nil {
+		in, out := &in.RunAfter, &out.RunAfter
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	in.Brancher.DeepCopyInto(&out.Brancher)
 	in.RegexpChangeMatcher.DeepCopyInto(&out.RegexpChangeMatcher)
 	out.Reporter = in.Reporter
@@ -261,6 +281,11 @@ func (in *ProwYAML) DeepCopyInto(out *ProwYAML) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make(ProwYAMLIncludes, len(*in))
+		copy(*out, *in)
+	}
 	if in.ProwIgnored != nil {
 		in, out := &in.ProwIgnored, &out.ProwIgnored
 		*out = new(json.RawMessage)
@@ -311,6 +336,11 @@ func (in *UtilityConfig) DeepCopyInto(out *UtilityConfig) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.SparseCheckout != nil {
+		in, out := &in.SparseCheckout, &out.SparseCheckout
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.ExtraRefs != nil {
 		in, out := &in.ExtraRefs, &out.ExtraRefs
 		*out = make([]prowjobsv1.Refs, len(*in))
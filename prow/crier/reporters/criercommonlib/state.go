@@ -0,0 +1,33 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package criercommonlib contains shared lib used by reporters
+package criercommonlib
+
+import (
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+// Passing returns whether a job in the state passed.
+func Passing(state prowv1.ProwJobState) bool {
+	return state == prowv1.SuccessState
+}
+
+// Failing returns whether a job in the state failed, including jobs that
+// errored out.
+func Failing(state prowv1.ProwJobState) bool {
+	return state == prowv1.FailureState || state == prowv1.ErrorState
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package email contains a crier reporter that sends digests of the results
+// of periodic jobs by email.
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/crier/reporters/criercommonlib"
+)
+
+const (
+	reporterName = "emailreporter"
+
+	// tickInterval is how often the reporter checks whether digests are due.
+	tickInterval = time.Minute
+)
+
+// Digest is what the templates of a digest are executed on.
+type Digest struct {
+	// Name is the name of the digest.
+	Name string
+	// Failures are the runs of the jobs that failed since the last digest.
+	Failures []prowapi.ProwJob
+	// Transitions are the jobs that started failing or passed again.
+	Transitions []Transition
+}
+
+// Transition is a change of the state of a job from passing to failing or back.
+type Transition struct {
+	// Job is the run that changed the state.
+	Job prowapi.ProwJob
+	// PreviousState is the state of the run before it.
+	PreviousState prowapi.ProwJobState
+}
+
+// jobState is the state of the last completed run of a job.
+type jobState struct {
+	state      prowapi.ProwJobState
+	completion time.Time
+}
+
+// Client is a reporter client fed to crier controller. It is also a
+// manager.Runnable that sends the digests that are due.
+type Client struct {
+	config   config.Getter
+	sender   Sender
+	pjLister ctrlruntimeclient.Reader
+	dryRun   bool
+
+	lock sync.Mutex
+	// states are the states of the last completed runs by job name.
+	states map[string]jobState
+	// pending are the failures that were not sent yet by digest name.
+	pending map[string][]prowapi.ProwJob
+	// lastSent is the start of the interval of the digests by digest name.
+	// Intervals are aligned on multiples of their duration, so that they are
+	// the same across restarts.
+	lastSent map[string]time.Time
+	// sentTransitions are the digests the transitions of runs were sent to
+	// by run name, while the transitions couldn't be sent to all of them.
+	sentTransitions map[string]sets.String
+}
+
+// NewReporter creates a new email reporter that sends emails with the sender.
+// The ProwJobs listed with the lister on start restore the states of the jobs
+// and the failures to send.
+func NewReporter(cfg config.Getter, sender Sender, pjLister ctrlruntimeclient.Reader, dryRun bool) *Client {
+	return &Client{
+		config:          cfg,
+		sender:          sender,
+		pjLister:        pjLister,
+		dryRun:          dryRun,
+		states:          map[string]jobState{},
+		pending:         map[string][]prowapi.ProwJob{},
+		lastSent:        map[string]time.Time{},
+		sentTransitions: map[string]sets.String{},
+	}
+}
+
+// GetName returns the name of the reporter
+func (c *Client) GetName() string {
+	return reporterName
+}
+
+// ShouldReport returns whether the job is a completed periodic job of one of
+// the digests.
+func (c *Client) ShouldReport(_ context.Context, _ *logrus.Entry, pj *prowapi.ProwJob) bool {
+	if !criercommonlib.Passing(pj.Status.State) && !criercommonlib.Failing(pj.Status.State) {
+		return false
+	}
+	cfg := c.config().EmailReporter
+	for i := range cfg.Digests {
+		if cfg.Digests[i].Matches(pj) {
+			return true
+		}
+	}
+	return false
+}
+
+// Report records the failures of the job for the digests and sends an email
+// to the digests that report transitions if the state of the job changed.
+func (c *Client) Report(_ context.Context, log *logrus.Entry, pj *prowapi.ProwJob) ([]*prowapi.ProwJob, *reconcile.Result, error) {
+	cfg := c.config().EmailReporter
+	var completion time.Time
+	if pj.Status.CompletionTime != nil {
+		completion = pj.Status.CompletionTime.Time
+	}
+
+	c.lock.Lock()
+	previous, known := c.states[pj.Spec.Job]
+	c.lock.Unlock()
+	// Runs that complete out of order don't change the state of the job.
+	latest := !known || !completion.Before(previous.completion)
+	transition := known && latest && criercommonlib.Passing(previous.state) != criercommonlib.Passing(pj.Status.State)
+
+	var digests []config.EmailDigest
+	for _, digest := range cfg.Digests {
+		if digest.Matches(pj) {
+			digests = append(digests, digest)
+		}
+	}
+
+	if transition {
+		// Reports that failed are retried, the transition isn't sent again
+		// to the digests it was sent to.
+		c.lock.Lock()
+		sent := c.sentTransitions[pj.Name]
+		c.lock.Unlock()
+		var errs []error
+		for _, digest := range digests {
+			if !digest.OnTransition || sent.Has(digest.Name) {
+				continue
+			}
+			data := Digest{Name: digest.Name, Transitions: []Transition{{Job: *pj, PreviousState: previous.state}}}
+			if err := c.send(log, cfg.From, digest, data); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			sent = sent.Union(sets.NewString(digest.Name))
+		}
+		c.lock.Lock()
+		if len(errs) > 0 {
+			c.sentTransitions[pj.Name] = sent
+		} else {
+			delete(c.sentTransitions, pj.Name)
+		}
+		c.lock.Unlock()
+		if len(errs) > 0 {
+			return nil, nil, utilerrors.NewAggregate(errs)
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if latest {
+		c.states[pj.Spec.Job] = jobState{state: pj.Status.State, completion: completion}
+	}
+	if criercommonlib.Failing(pj.Status.State) {
+		for _, digest := range digests {
+			if digest.Interval != nil {
+				c.addPending(digest.Name, pj)
+			}
+		}
+	}
+	return []*prowapi.ProwJob{pj}, nil, nil
+}
+
+// addPending adds the failure to the failures to send in the digest, unless
+// it is there already. The lock must be held.
+func (c *Client) addPending(digest string, pj *prowapi.ProwJob) {
+	for _, pending := range c.pending[digest] {
+		if pending.Name == pj.Name {
+			return
+		}
+	}
+	c.pending[digest] = append(c.pending[digest], *pj)
+}
+
+// Start restores the states of the jobs and the failures to send from the
+// ProwJobs, then sends the digests that are due until the context is done.
+func (c *Client) Start(ctx context.Context) error {
+	if err := c.restore(ctx, time.Now()); err != nil {
+		return fmt.Errorf("failed to restore the states of the jobs: %w", err)
+	}
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			c.sendDigests(now)
+		}
+	}
+}
+
+// restore restores the states of the jobs and the failures of the current
+// intervals of the digests from the ProwJobs this reporter reported already,
+// which crier doesn't report again. The failures of earlier intervals were
+// sent in the digests at their end.
+func (c *Client) restore(ctx context.Context, now time.Time) error {
+	var pjs prowapi.ProwJobList
+	if err := c.pjLister.List(ctx, &pjs, ctrlruntimeclient.InNamespace(c.config().ProwJobNamespace)); err != nil {
+		return err
+	}
+	cfg := c.config().EmailReporter
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, digest := range cfg.Digests {
+		if digest.Interval != nil {
+			if _, ok := c.lastSent[digest.Name]; !ok {
+				c.lastSent[digest.Name] = now.Truncate(digest.Interval.Duration)
+			}
+		}
+	}
+	for i := range pjs.Items {
+		pj := &pjs.Items[i]
+		if _, reported := pj.Status.PrevReportStates[reporterName]; !reported || pj.Status.CompletionTime == nil {
+			continue
+		}
+		completion := pj.Status.CompletionTime.Time
+		if previous, known := c.states[pj.Spec.Job]; !known || !completion.Before(previous.completion) {
+			c.states[pj.Spec.Job] = jobState{state: pj.Status.State, completion: completion}
+		}
+		if !criercommonlib.Failing(pj.Status.State) {
+			continue
+		}
+		for _, digest := range cfg.Digests {
+			if digest.Interval != nil && digest.Matches(pj) && !completion.Before(c.lastSent[digest.Name]) {
+				c.addPending(digest.Name, pj)
+			}
+		}
+	}
+	return nil
+}
+
+// sendDigests sends the failures of the digests whose interval ended.
+func (c *Client) sendDigests(now time.Time) {
+	cfg := c.config().EmailReporter
+	for _, digest := range cfg.Digests {
+		if digest.Interval == nil {
+			continue
+		}
+		log := logrus.WithFields(logrus.Fields{"reporter": reporterName, "digest": digest.Name})
+		interval := now.Truncate(digest.Interval.Duration)
+
+		c.lock.Lock()
+		lastSent, sent := c.lastSent[digest.Name]
+		if !sent {
+			// Digests added since the start are first sent at the end of
+			// the current interval.
+			c.lastSent[digest.Name] = interval
+		}
+		failures := c.pending[digest.Name]
+		c.lock.Unlock()
+		if !sent || !interval.After(lastSent) {
+			continue
+		}
+
+		if len(failures) > 0 {
+			if err := c.send(log, cfg.From, digest, Digest{Name: digest.Name, Failures: failures}); err != nil {
+				log.WithError(err).Error("Failed to send digest, retrying on the next tick.")
+				continue
+			}
+		}
+
+		c.lock.Lock()
+		// Failures may have been recorded while the digest was sent.
+		c.pending[digest.Name] = c.pending[digest.Name][len(failures):]
+		c.lastSent[digest.Name] = interval
+		c.lock.Unlock()
+	}
+}
+
+// send executes the templates of the digest on the data and sends the email.
+func (c *Client) send(log *logrus.Entry, from string, digest config.EmailDigest, data Digest) error {
+	subject, err := execute(digest.SubjectTemplate, data)
+	if err != nil {
+		return fmt.Errorf("failed to execute subject template of digest %s: %w", digest.Name, err)
+	}
+	body, err := execute(digest.BodyTemplate, data)
+	if err != nil {
+		return fmt.Errorf("failed to execute body template of digest %s: %w", digest.Name, err)
+	}
+	if c.dryRun {
+		log.WithFields(logrus.Fields{"to": digest.To, "subject": subject, "body": body}).Debug("Skipping sending email because dry-run is enabled")
+		return nil
+	}
+	if err := c.sender.Send(from, digest.To, subject, body); err != nil {
+		return fmt.Errorf("failed to send email of digest %s: %w", digest.Name, err)
+	}
+	log.WithField("to", digest.To).Info("Sent email.")
+	return nil
+}
+
+func execute(text string, data Digest) (string, error) {
+	tmpl, err := template.New("").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+type email struct {
+	to      []string
+	subject string
+	body    string
+}
+
+type fakeSender struct {
+	sent []email
+	// failing are the recipients sending emails to fails.
+	failing map[string]bool
+}
+
+func (f *fakeSender) Send(_ string, to []string, subject, body string) error {
+	if f.failing[to[0]] {
+		return errors.New("injected error")
+	}
+	f.sent = append(f.sent, email{to: to, subject: subject, body: body})
+	return nil
+}
+
+func testConfig(digests ...config.EmailDigest) config.Getter {
+	cfg := &config.Config{ProwConfig: config.ProwConfig{EmailReporter: config.EmailReporter{From: "prow@example.com", Digests: digests}}}
+	if err := cfg.EmailReporter.DefaultAndValidate(); err != nil {
+		panic(err)
+	}
+	return func() *config.Config { return cfg }
+}
+
+func periodic(name string, state prowapi.ProwJobState, completion time.Time) *prowapi.ProwJob {
+	return &prowapi.ProwJob{
+		Spec: prowapi.ProwJobSpec{Type: prowapi.PeriodicJob, Job: name},
+		Status: prowapi.ProwJobStatus{
+			State:          state,
+			CompletionTime: &metav1.Time{Time: completion},
+			URL:            "https://prow.example.com/view/" + name,
+		},
+	}
+}
+
+func TestShouldReport(t *testing.T) {
+	c := NewReporter(testConfig(config.EmailDigest{Name: "e2e", Jobs: "^ci-e2e-", To: []string{"team@example.com"}, OnTransition: true}), &fakeSender{}, fakectrlruntimeclient.NewFakeClient(), false)
+	now := time.Now()
+	testCases := []struct {
+		name     string
+		pj       *prowapi.ProwJob
+		expected bool
+	}{
+		{
+			name:     "failed periodic of the digest",
+			pj:       periodic("ci-e2e-gce", prowapi.FailureState, now),
+			expected: true,
+		},
+		{
+			name:     "passed periodic of the digest",
+			pj:       periodic("ci-e2e-gce", prowapi.SuccessState, now),
+			expected: true,
+		},
+		{
+			name: "pending periodic of the digest",
+			pj:   periodic("ci-e2e-gce", prowapi.PendingState, now),
+		},
+		{
+			name: "aborted periodic of the digest",
+			pj:   periodic("ci-e2e-gce", prowapi.AbortedState, now),
+		},
+		{
+			name: "periodic of no digest",
+			pj:   periodic("ci-unit", prowapi.FailureState, now),
+		},
+		{
+			name: "postsubmit matching the digest",
+			pj: &prowapi.ProwJob{
+				Spec:   prowapi.ProwJobSpec{Type: prowapi.PostsubmitJob, Job: "ci-e2e-gce"},
+				Status: prowapi.ProwJobStatus{State: prowapi.FailureState},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := c.ShouldReport(context.Background(), logrus.NewEntry(logrus.StandardLogger()), tc.pj); actual != tc.expected {
+				t.Errorf("expected ShouldReport to be %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReportTransitions(t *testing.T) {
+	sender := &fakeSender{}
+	c := NewReporter(testConfig(config.EmailDigest{
+		Name:            "e2e",
+		Jobs:            "^ci-e2e-",
+		To:              []string{"team@example.com"},
+		OnTransition:    true,
+		SubjectTemplate: "{{range .Transitions}}{{.Job.Spec.Job}} is {{.Job.Status.State}}{{end}}",
+	}), sender, fakectrlruntimeclient.NewFakeClient(), false)
+	start := time.Now()
+	log := logrus.NewEntry(logrus.StandardLogger())
+
+	for i, pj := range []*prowapi.ProwJob{
+		periodic("ci-e2e-gce", prowapi.SuccessState, start),
+		periodic("ci-e2e-gce", prowapi.FailureState, start.Add(2*time.Hour)),
+		// Completed before the failure, so it doesn't change the state.
+		periodic("ci-e2e-gce", prowapi.SuccessState, start.Add(time.Hour)),
+		periodic("ci-e2e-gce", prowapi.ErrorState, start.Add(3*time.Hour)),
+		periodic("ci-e2e-gce", prowapi.SuccessState, start.Add(4*time.Hour)),
+	} {
+		if _, _, err := c.Report(context.Background(), log, pj); err != nil {
+			t.Fatalf("report %d failed: %v", i, err)
+		}
+	}
+
+	var subjects []string
+	for _, sent := range sender.sent {
+		subjects = append(subjects, sent.subject)
+	}
+	expected := []string{"ci-e2e-gce is failure", "ci-e2e-gce is success"}
+	if diff := cmp.Diff(expected, subjects); diff != "" {
+		t.Errorf("sent emails differ from expected: %s", diff)
+	}
+}
+
+func TestSendDigests(t *testing.T) {
+	sender := &fakeSender{}
+	c := NewReporter(testConfig(
+		config.EmailDigest{
+			Name:         "e2e",
+			Jobs:         "^ci-e2e-",
+			To:           []string{"team@example.com"},
+			Interval:     &prowapi.Duration{Duration: time.Hour},
+			BodyTemplate: "{{range .Failures}}{{.Spec.Job}} {{.Status.State}}\n{{end}}",
+		},
+		config.EmailDigest{
+			Name:         "transitions",
+			Jobs:         ".*",
+			To:           []string{"other-team@example.com"},
+			OnTransition: true,
+		},
+	), sender, fakectrlruntimeclient.NewFakeClient(), false)
+	// Digests are sent at the end of intervals aligned on their duration.
+	start := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	log := logrus.NewEntry(logrus.StandardLogger())
+
+	c.sendDigests(start)
+	for _, pj := range []*prowapi.ProwJob{
+		periodic("ci-e2e-gce", prowapi.FailureState, start),
+		periodic("ci-e2e-aws", prowapi.SuccessState, start),
+		periodic("ci-e2e-aws", prowapi.ErrorState, start),
+	} {
+		if _, _, err := c.Report(context.Background(), log, pj); err != nil {
+			t.Fatalf("report failed: %v", err)
+		}
+	}
+	// The transition of ci-e2e-aws is sent right away.
+	if len(sender.sent) != 1 || sender.sent[0].to[0] != "other-team@example.com" {
+		t.Fatalf("expected the transition to be sent to other-team@example.com, got %v", sender.sent)
+	}
+
+	c.sendDigests(start.Add(30 * time.Minute))
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected no digest before the interval passed, got %v", sender.sent)
+	}
+
+	c.sendDigests(start.Add(time.Hour))
+	expected := email{to: []string{"team@example.com"}, subject: "[e2e] 2 failed runs, 0 state changes of periodic jobs", body: "ci-e2e-gce failure\nci-e2e-aws error\n"}
+	if len(sender.sent) != 2 {
+		t.Fatalf("expected the digest to be sent, got %v", sender.sent)
+	}
+	if diff := cmp.Diff(expected, sender.sent[1], cmp.AllowUnexported(email{})); diff != "" {
+		t.Errorf("digest differs from expected: %s", diff)
+	}
+
+	c.sendDigests(start.Add(2 * time.Hour))
+	if len(sender.sent) != 2 {
+		t.Errorf("expected no digest without new failures, got %v", sender.sent)
+	}
+}
+
+func TestReportRetriesOnlyFailedTransitions(t *testing.T) {
+	sender := &fakeSender{failing: map[string]bool{"other-team@example.com": true}}
+	c := NewReporter(testConfig(
+		config.EmailDigest{Name: "team", Jobs: ".*", To: []string{"team@example.com"}, OnTransition: true},
+		config.EmailDigest{Name: "other-team", Jobs: ".*", To: []string{"other-team@example.com"}, OnTransition: true},
+	), sender, fakectrlruntimeclient.NewFakeClient(), false)
+	start := time.Now()
+	log := logrus.NewEntry(logrus.StandardLogger())
+
+	if _, _, err := c.Report(context.Background(), log, periodic("ci-e2e-gce", prowapi.SuccessState, start)); err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	failure := periodic("ci-e2e-gce", prowapi.FailureState, start.Add(time.Hour))
+	failure.Name = "failure"
+	if _, _, err := c.Report(context.Background(), log, failure); err == nil {
+		t.Fatal("expected the report to fail")
+	}
+	delete(sender.failing, "other-team@example.com")
+	if _, _, err := c.Report(context.Background(), log, failure); err != nil {
+		t.Fatalf("retried report failed: %v", err)
+	}
+
+	var recipients []string
+	for _, sent := range sender.sent {
+		recipients = append(recipients, sent.to[0])
+	}
+	if diff := cmp.Diff([]string{"team@example.com", "other-team@example.com"}, recipients); diff != "" {
+		t.Errorf("recipients differ from expected: %s", diff)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 10, 30, 0, 0, time.UTC)
+	reported := func(name, job string, state prowapi.ProwJobState, completion time.Time) *prowapi.ProwJob {
+		pj := periodic(job, state, completion)
+		pj.Name = name
+		pj.Namespace = "prowjobs"
+		pj.Status.PrevReportStates = map[string]prowapi.ProwJobState{reporterName: state}
+		return pj
+	}
+	notReported := periodic("ci-e2e-aws", prowapi.FailureState, now.Add(-time.Minute))
+	notReported.Name = "not-reported"
+	notReported.Namespace = "prowjobs"
+	pjLister := fakectrlruntimeclient.NewFakeClient(
+		// Sent in the digest at the end of the previous interval.
+		reported("sent", "ci-e2e-gce", prowapi.FailureState, now.Add(-time.Hour)),
+		reported("pending", "ci-e2e-gce", prowapi.FailureState, now.Add(-10*time.Minute)),
+		reported("passed", "ci-e2e-gce", prowapi.SuccessState, now.Add(-5*time.Minute)),
+		// Reported by crier once the reporter started.
+		notReported,
+	)
+
+	cfg := testConfig(config.EmailDigest{
+		Name:         "e2e",
+		Jobs:         "^ci-e2e-",
+		To:           []string{"team@example.com"},
+		Interval:     &prowapi.Duration{Duration: time.Hour},
+		BodyTemplate: "{{range .Failures}}{{.Name}}\n{{end}}",
+	})
+	cfg().ProwJobNamespace = "prowjobs"
+	sender := &fakeSender{}
+	c := NewReporter(cfg, sender, pjLister, false)
+	if err := c.restore(context.Background(), now); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+
+	if expected, actual := (jobState{state: prowapi.SuccessState, completion: now.Add(-5 * time.Minute)}), c.states["ci-e2e-gce"]; expected != actual {
+		t.Errorf("expected the state of ci-e2e-gce to be %v, got %v", expected, actual)
+	}
+	if _, known := c.states["ci-e2e-aws"]; known {
+		t.Error("expected the state of ci-e2e-aws not to be restored from a run that wasn't reported")
+	}
+
+	c.sendDigests(now.Add(30 * time.Minute))
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected the digest to be sent at the end of the interval, got %v", sender.sent)
+	}
+	if expected := "pending\n"; sender.sent[0].body != expected {
+		t.Errorf("expected the digest body %q, got %q", expected, sender.sent[0].body)
+	}
+}
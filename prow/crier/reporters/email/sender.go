@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package email
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+const sendGridURL = "https://api.sendgrid.com/v3/mail/send"
+
+// Sender sends plain text emails.
+type Sender interface {
+	Send(from string, to []string, subject, body string) error
+}
+
+// SMTPSender sends emails through an SMTP server.
+type SMTPSender struct {
+	// Server is the address of the server as host:port.
+	Server   string
+	Username string
+	// Password returns the password of the user, it is only called if
+	// Username is set.
+	Password func() []byte
+}
+
+// Send sends a plain text email.
+func (s *SMTPSender) Send(from string, to []string, subject, body string) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Server)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP server %q: %w", s.Server, err)
+		}
+		auth = smtp.PlainAuth("", s.Username, string(s.Password()), host)
+	}
+	return smtp.SendMail(s.Server, auth, from, to, message(from, to, subject, body))
+}
+
+// message formats a plain text email.
+func message(from string, to []string, subject, body string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", strings.ReplaceAll(subject, "\n", " "))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// SendGridSender sends emails through the API of SendGrid.
+type SendGridSender struct {
+	// APIKey returns the API key of SendGrid.
+	APIKey func() []byte
+	// URL is the endpoint of the API, defaults to the one of SendGrid.
+	URL    string
+	Client *http.Client
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridMessage struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+// Send sends a plain text email.
+func (s *SendGridSender) Send(from string, to []string, subject, body string) error {
+	msg := sendGridMessage{
+		From:    sendGridAddress{Email: from},
+		Subject: subject,
+		Content: []sendGridContent{{Type: "text/plain", Value: body}},
+	}
+	var recipients []sendGridAddress
+	for _, address := range to {
+		recipients = append(recipients, sendGridAddress{Email: address})
+	}
+	msg.Personalizations = []sendGridPersonalization{{To: recipients}}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	url := s.URL
+	if url == "" {
+		url = sendGridURL
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+string(s.APIKey()))
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("SendGrid returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package email
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMessage(t *testing.T) {
+	actual := string(message("prow@example.com", []string{"a@example.com", "b@example.com"}, "Failures\nof jobs", "line 1\nline 2\n"))
+	expected := "From: prow@example.com\r\n" +
+		"To: a@example.com, b@example.com\r\n" +
+		"Subject: Failures of jobs\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=\"utf-8\"\r\n" +
+		"\r\n" +
+		"line 1\r\nline 2\r\n"
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("message differs from expected: %s", diff)
+	}
+}
+
+func TestSendGridSender(t *testing.T) {
+	testCases := []struct {
+		name      string
+		status    int
+		expectErr bool
+	}{
+		{
+			name:   "message is accepted",
+			status: http.StatusAccepted,
+		},
+		{
+			name:      "message is rejected",
+			status:    http.StatusUnauthorized,
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var received sendGridMessage
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if auth := r.Header.Get("Authorization"); auth != "Bearer key" {
+					t.Errorf("expected the API key to be sent, got Authorization %q", auth)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("failed to decode message: %v", err)
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			s := &SendGridSender{APIKey: func() []byte { return []byte("key") }, URL: server.URL}
+			err := s.Send("prow@example.com", []string{"team@example.com"}, "subject", "body")
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %t, got %v", tc.expectErr, err)
+			}
+			expected := sendGridMessage{
+				Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: "team@example.com"}}}},
+				From:             sendGridAddress{Email: "prow@example.com"},
+				Subject:          "subject",
+				Content:          []sendGridContent{{Type: "text/plain", Value: "body"}},
+			}
+			if diff := cmp.Diff(expected, received); diff != "" {
+				t.Errorf("message differs from expected: %s", diff)
+			}
+		})
+	}
+}
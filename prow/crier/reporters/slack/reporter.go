@@ -21,9 +21,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"text/template"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
@@ -35,16 +39,33 @@ import (
 const (
 	reporterName    = "slackreporter"
 	DefaultHostName = "*"
+
+	// threadTTL is how long reruns of a job are posted to the thread of its
+	// first report.
+	threadTTL = 24 * time.Hour
 )
 
 type slackClient interface {
 	WriteMessage(text, channel string) error
+	WriteThreadMessage(text, channel, threadTS string) (string, error)
 }
 
 type slackReporter struct {
 	clients map[string]slackClient
 	config  func(*prowapi.Refs) config.SlackReporter
 	dryRun  bool
+
+	lock sync.Mutex
+	// limiters limit the messages per host and channel.
+	limiters map[string]*rate.Limiter
+	// threads holds the threads of the reports of jobs by threadKey.
+	threads map[string]slackThread
+}
+
+// slackThread is the thread of the first report of a job for some commits.
+type slackThread struct {
+	ts      string
+	created time.Time
 }
 
 func hostAndChannel(cfg *v1.SlackReporterConfig) (string, string) {
@@ -69,42 +90,150 @@ func (sr *slackReporter) getConfig(pj *v1.ProwJob) (*config.SlackReporter, *v1.S
 }
 
 func (sr *slackReporter) Report(_ context.Context, log *logrus.Entry, pj *v1.ProwJob) ([]*v1.ProwJob, *reconcile.Result, error) {
-	return []*v1.ProwJob{pj}, nil, sr.report(log, pj)
+	delay, err := sr.report(log, pj)
+	if delay > 0 {
+		log.WithField("delay", delay).Debug("Delaying report because of the rate limit")
+		return nil, &reconcile.Result{RequeueAfter: delay}, nil
+	}
+	return []*v1.ProwJob{pj}, nil, err
 }
 
-func (sr *slackReporter) report(log *logrus.Entry, pj *v1.ProwJob) error {
+// report posts the report of the job. It returns how long the report has to be
+// delayed if the channel is over its rate limit.
+func (sr *slackReporter) report(log *logrus.Entry, pj *v1.ProwJob) (time.Duration, error) {
 	globalSlackConfig, jobSlackConfig := sr.getConfig(pj)
+	jobHasChannel := jobSlackConfig != nil && jobSlackConfig.Channel != ""
 	if globalSlackConfig != nil {
 		jobSlackConfig = jobSlackConfig.ApplyDefault(&globalSlackConfig.SlackReporterConfig)
 	}
 	if jobSlackConfig == nil {
-		return errors.New("resolved slack config is empty") // Shouldn't happen at all, just in case
+		return 0, errors.New("resolved slack config is empty") // Shouldn't happen at all, just in case
 	}
 	host, channel := hostAndChannel(jobSlackConfig)
+	reportTemplate := jobSlackConfig.ReportTemplate
+	// A channel set on the job takes precedence over the routes.
+	if route := globalSlackConfig.RouteFor(pj); route != nil && !jobHasChannel {
+		channel = route.Channel
+		if route.Host != "" {
+			host = route.Host
+		}
+		if route.ReportTemplate != "" {
+			reportTemplate = route.ReportTemplate
+		}
+	}
 
 	client, ok := sr.clients[host]
 	if !ok {
-		return fmt.Errorf("host '%s' not supported", host)
+		return 0, fmt.Errorf("host '%s' not supported", host)
 	}
 	b := &bytes.Buffer{}
-	tmpl, err := template.New("").Parse(jobSlackConfig.ReportTemplate)
+	tmpl, err := template.New("").Parse(reportTemplate)
 	if err != nil {
 		log.WithError(err).Error("failed to parse template")
-		return fmt.Errorf("failed to parse template: %w", err)
+		return 0, fmt.Errorf("failed to parse template: %w", err)
 	}
 	if err := tmpl.Execute(b, pj); err != nil {
 		log.WithError(err).Error("failed to execute report template")
-		return fmt.Errorf("failed to execute report template: %w", err)
+		return 0, fmt.Errorf("failed to execute report template: %w", err)
 	}
 	if sr.dryRun {
 		log.WithField("messagetext", b.String()).Debug("Skipping reporting because dry-run is enabled")
-		return nil
+		return 0, nil
 	}
-	if err := client.WriteMessage(b.String(), channel); err != nil {
+	if delay := sr.reserve(host, channel, globalSlackConfig.MessagesPerMinute); delay > 0 {
+		return delay, nil
+	}
+	if !globalSlackConfig.ThreadRetries {
+		if err := client.WriteMessage(b.String(), channel); err != nil {
+			log.WithError(err).Error("failed to write Slack message")
+			return 0, fmt.Errorf("failed to write Slack message: %w", err)
+		}
+		return 0, nil
+	}
+
+	key := threadKey(host, channel, pj)
+	threadTS := sr.thread(key)
+	ts, err := client.WriteThreadMessage(b.String(), channel, threadTS)
+	if err != nil {
 		log.WithError(err).Error("failed to write Slack message")
-		return fmt.Errorf("failed to write Slack message: %w", err)
+		return 0, fmt.Errorf("failed to write Slack message: %w", err)
+	}
+	if threadTS == "" && ts != "" {
+		sr.recordThread(key, ts)
+	}
+	return 0, nil
+}
+
+// reserve reserves a message for the channel if it is within the rate limit,
+// otherwise it returns how long to wait for the next message.
+func (sr *slackReporter) reserve(host, channel string, messagesPerMinute int) time.Duration {
+	if messagesPerMinute <= 0 {
+		return 0
+	}
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	if sr.limiters == nil {
+		sr.limiters = map[string]*rate.Limiter{}
+	}
+	limit := rate.Limit(float64(messagesPerMinute) / time.Minute.Seconds())
+	key := host + "/" + channel
+	limiter, ok := sr.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(limit, messagesPerMinute)
+		sr.limiters[key] = limiter
+	} else if limiter.Limit() != limit {
+		limiter.SetLimit(limit)
+		limiter.SetBurst(messagesPerMinute)
+	}
+	reservation := limiter.Reserve()
+	if delay := reservation.Delay(); delay > 0 {
+		reservation.Cancel()
+		return delay
+	}
+	return 0
+}
+
+// threadKey identifies the reports of the runs of a job for the same commits.
+func threadKey(host, channel string, pj *v1.ProwJob) string {
+	parts := []string{host, channel, pj.Spec.Job}
+	refs := pj.Spec.Refs
+	if refs == nil && len(pj.Spec.ExtraRefs) > 0 {
+		refs = &pj.Spec.ExtraRefs[0]
+	}
+	if refs != nil {
+		parts = append(parts, refs.Org, refs.Repo, refs.BaseSHA)
+		for _, pull := range refs.Pulls {
+			parts = append(parts, fmt.Sprintf("%d:%s", pull.Number, pull.SHA))
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// thread returns the timestamp of the thread for the key, or an empty
+// string if there is none.
+func (sr *slackReporter) thread(key string) string {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	thread, ok := sr.threads[key]
+	if !ok || time.Since(thread.created) > threadTTL {
+		return ""
+	}
+	return thread.ts
+}
+
+// recordThread records the thread for the key and forgets expired threads.
+func (sr *slackReporter) recordThread(key, ts string) {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	if sr.threads == nil {
+		sr.threads = map[string]slackThread{}
+	}
+	for k, thread := range sr.threads {
+		if time.Since(thread.created) > threadTTL {
+			delete(sr.threads, k)
+		}
 	}
-	return nil
+	sr.threads[key] = slackThread{ts: ts, created: time.Now()}
 }
 
 func (sr *slackReporter) GetName() string {
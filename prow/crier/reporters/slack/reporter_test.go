@@ -18,8 +18,10 @@ package slack
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/sirupsen/logrus"
 
 	v1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
@@ -622,6 +624,9 @@ func TestShouldReportDefaultsToExtraRefs(t *testing.T) {
 
 type fakeSlackClient struct {
 	messages map[string]string
+	// threadMessages holds the texts posted to each thread.
+	threadMessages map[string][]string
+	posted         int
 }
 
 func (fsc *fakeSlackClient) WriteMessage(text, channel string) error {
@@ -629,9 +634,25 @@ func (fsc *fakeSlackClient) WriteMessage(text, channel string) error {
 		fsc.messages = map[string]string{}
 	}
 	fsc.messages[channel] = text
+	fsc.posted++
 	return nil
 }
 
+func (fsc *fakeSlackClient) WriteThreadMessage(text, channel, threadTS string) (string, error) {
+	if err := fsc.WriteMessage(text, channel); err != nil {
+		return "", err
+	}
+	ts := fmt.Sprintf("%d.000", fsc.posted)
+	if threadTS == "" {
+		threadTS = ts
+	}
+	if fsc.threadMessages == nil {
+		fsc.threadMessages = map[string][]string{}
+	}
+	fsc.threadMessages[threadTS] = append(fsc.threadMessages[threadTS], text)
+	return ts, nil
+}
+
 var _ slackClient = &fakeSlackClient{}
 
 func TestReportDefaultsToExtraRefs(t *testing.T) {
@@ -669,3 +690,163 @@ func TestReportDefaultsToExtraRefs(t *testing.T) {
 		t.Errorf("expected the channel 'emergency' to contain message 'there you go' but wasn't the case, all messages: %v", fsc.messages)
 	}
 }
+
+func TestReportUsesRoutes(t *testing.T) {
+	reporterConfig := config.SlackReporter{
+		JobTypesToReport: []v1.ProwJobType{v1.PresubmitJob},
+		SlackReporterConfig: v1.SlackReporterConfig{
+			JobStatesToReport: []v1.ProwJobState{v1.SuccessState, v1.FailureState},
+			Channel:           "default",
+			ReportTemplate:    "{{.Spec.Job}} {{.Status.State}}",
+		},
+		Routes: []config.SlackReporterRoute{
+			{
+				Repos:     []string{"org/repo"},
+				Jobs:      "^pull-.*-e2e$",
+				JobStates: []v1.ProwJobState{v1.FailureState},
+				Channel:   "e2e-failures",
+			},
+			{
+				Repos:          []string{"other-org"},
+				Channel:        "other-org",
+				ReportTemplate: "other {{.Spec.Job}}",
+			},
+		},
+	}
+	testCases := []struct {
+		name         string
+		pj           *v1.ProwJob
+		wantChannel  string
+		wantMessages string
+	}{
+		{
+			name: "job matching the first route",
+			pj: &v1.ProwJob{
+				Spec:   v1.ProwJobSpec{Type: v1.PresubmitJob, Job: "pull-repo-e2e", Refs: &v1.Refs{Org: "org", Repo: "repo"}},
+				Status: v1.ProwJobStatus{State: v1.FailureState},
+			},
+			wantChannel:  "e2e-failures",
+			wantMessages: "pull-repo-e2e failure",
+		},
+		{
+			name: "job in another state than the route",
+			pj: &v1.ProwJob{
+				Spec:   v1.ProwJobSpec{Type: v1.PresubmitJob, Job: "pull-repo-e2e", Refs: &v1.Refs{Org: "org", Repo: "repo"}},
+				Status: v1.ProwJobStatus{State: v1.SuccessState},
+			},
+			wantChannel:  "default",
+			wantMessages: "pull-repo-e2e success",
+		},
+		{
+			name: "job matching the org of the second route uses its template",
+			pj: &v1.ProwJob{
+				Spec:   v1.ProwJobSpec{Type: v1.PresubmitJob, Job: "pull-repo-e2e", Refs: &v1.Refs{Org: "other-org", Repo: "repo"}},
+				Status: v1.ProwJobStatus{State: v1.FailureState},
+			},
+			wantChannel:  "other-org",
+			wantMessages: "other pull-repo-e2e",
+		},
+		{
+			name: "channel of the job takes precedence over the routes",
+			pj: &v1.ProwJob{
+				Spec: v1.ProwJobSpec{
+					Type:           v1.PresubmitJob,
+					Job:            "pull-repo-e2e",
+					Refs:           &v1.Refs{Org: "org", Repo: "repo"},
+					ReporterConfig: &v1.ReporterConfig{Slack: &v1.SlackReporterConfig{Channel: "job-channel"}},
+				},
+				Status: v1.ProwJobStatus{State: v1.FailureState},
+			},
+			wantChannel:  "job-channel",
+			wantMessages: "pull-repo-e2e failure",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fsc := &fakeSlackClient{}
+			sr := slackReporter{
+				config:  func(*v1.Refs) config.SlackReporter { return reporterConfig },
+				clients: map[string]slackClient{DefaultHostName: fsc},
+			}
+			if _, _, err := sr.Report(context.Background(), logrus.NewEntry(logrus.StandardLogger()), tc.pj); err != nil {
+				t.Fatalf("reporting failed: %v", err)
+			}
+			if diff := cmp.Diff(map[string]string{tc.wantChannel: tc.wantMessages}, fsc.messages); diff != "" {
+				t.Errorf("messages differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestReportRateLimit(t *testing.T) {
+	fsc := &fakeSlackClient{}
+	sr := slackReporter{
+		config: func(*v1.Refs) config.SlackReporter {
+			return config.SlackReporter{
+				SlackReporterConfig: v1.SlackReporterConfig{Channel: "channel", ReportTemplate: "{{.Spec.Job}}"},
+				MessagesPerMinute:   2,
+			}
+		},
+		clients: map[string]slackClient{DefaultHostName: fsc},
+	}
+	job := &v1.ProwJob{Spec: v1.ProwJobSpec{Type: v1.PeriodicJob, Job: "periodic"}}
+	log := logrus.NewEntry(logrus.StandardLogger())
+
+	for i := 0; i < 2; i++ {
+		pjs, result, err := sr.Report(context.Background(), log, job)
+		if err != nil {
+			t.Fatalf("reporting failed: %v", err)
+		}
+		if result != nil || len(pjs) != 1 {
+			t.Fatalf("expected report %d to be posted, got result %v", i, result)
+		}
+	}
+	pjs, result, err := sr.Report(context.Background(), log, job)
+	if err != nil {
+		t.Fatalf("reporting failed: %v", err)
+	}
+	if result == nil || result.RequeueAfter <= 0 || len(pjs) != 0 {
+		t.Errorf("expected report over the rate limit to be requeued, got result %v", result)
+	}
+	if fsc.posted != 2 {
+		t.Errorf("expected 2 messages to be posted, got %d", fsc.posted)
+	}
+}
+
+func TestReportThreadsRetries(t *testing.T) {
+	fsc := &fakeSlackClient{}
+	sr := slackReporter{
+		config: func(*v1.Refs) config.SlackReporter {
+			return config.SlackReporter{
+				SlackReporterConfig: v1.SlackReporterConfig{Channel: "channel", ReportTemplate: "{{.Spec.Job}} {{.Status.BuildID}}"},
+				ThreadRetries:       true,
+			}
+		},
+		clients: map[string]slackClient{DefaultHostName: fsc},
+	}
+	job := func(buildID, sha string) *v1.ProwJob {
+		return &v1.ProwJob{
+			Spec: v1.ProwJobSpec{
+				Type: v1.PresubmitJob,
+				Job:  "pull-unit",
+				Refs: &v1.Refs{Org: "org", Repo: "repo", BaseSHA: "base", Pulls: []v1.Pull{{Number: 1, SHA: sha}}},
+			},
+			Status: v1.ProwJobStatus{State: v1.FailureState, BuildID: buildID},
+		}
+	}
+	log := logrus.NewEntry(logrus.StandardLogger())
+	for _, pj := range []*v1.ProwJob{job("1", "head"), job("2", "head"), job("3", "new-head"), job("4", "head")} {
+		if _, _, err := sr.Report(context.Background(), log, pj); err != nil {
+			t.Fatalf("reporting failed: %v", err)
+		}
+	}
+
+	expected := map[string][]string{
+		"1.000": {"pull-unit 1", "pull-unit 2", "pull-unit 4"},
+		"3.000": {"pull-unit 3"},
+	}
+	if diff := cmp.Diff(expected, fsc.threadMessages); diff != "" {
+		t.Errorf("threads differ from expected: %s", diff)
+	}
+}
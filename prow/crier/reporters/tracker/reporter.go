@@ -0,0 +1,307 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracker contains a crier reporter that files issues in Bugzilla or
+// Jira for jobs that keep failing.
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	stdio "io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/testgrid/metadata/junit"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/bugzilla"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/crier/reporters/criercommonlib"
+	"k8s.io/test-infra/prow/crier/reporters/gcs/util"
+	"k8s.io/test-infra/prow/io"
+	"k8s.io/test-infra/prow/jira"
+)
+
+const (
+	reporterName = "trackerreporter"
+
+	// maxFailedTests is how many failed tests are listed in an issue.
+	maxFailedTests = 20
+)
+
+// junitRegex matches the names of the junit artifacts of a job.
+var junitRegex = regexp.MustCompile(`^junit.*\.xml$`)
+
+// issueTracker files and updates issues in a bug tracker.
+type issueTracker interface {
+	// find returns the ID of the open issue with the summary, or "" if
+	// there is none.
+	find(summary string) (string, error)
+	// file files an issue and returns its ID.
+	file(summary, description string) (string, error)
+	comment(id, text string) error
+	close(id, text string) error
+}
+
+// jobState is what is known about the last completed runs of a job.
+type jobState struct {
+	// failures is how many runs failed in a row.
+	failures   int
+	completion time.Time
+	// issue is the ID of the open issue of the job, if one was filed.
+	issue string
+}
+
+// Client is a reporter client fed to crier controller.
+type Client struct {
+	config   config.Getter
+	bugzilla bugzilla.Client
+	jira     jira.Client
+	opener   io.Opener
+	dryRun   bool
+
+	lock sync.Mutex
+	// states are the states of the jobs by job name.
+	states map[string]jobState
+}
+
+// NewReporter creates a new tracker reporter. Either of the clients may be
+// nil if no rule files issues in that tracker. The opener is used to list
+// the failed tests of jobs, it may be nil as well.
+func NewReporter(cfg config.Getter, bugzillaClient bugzilla.Client, jiraClient jira.Client, opener io.Opener, dryRun bool) *Client {
+	return &Client{
+		config:   cfg,
+		bugzilla: bugzillaClient,
+		jira:     jiraClient,
+		opener:   opener,
+		dryRun:   dryRun,
+		states:   map[string]jobState{},
+	}
+}
+
+// GetName returns the name of the reporter
+func (c *Client) GetName() string {
+	return reporterName
+}
+
+// ShouldReport returns whether the job passed or failed and matches a rule.
+func (c *Client) ShouldReport(_ context.Context, _ *logrus.Entry, pj *prowapi.ProwJob) bool {
+	if !criercommonlib.Passing(pj.Status.State) && !criercommonlib.Failing(pj.Status.State) {
+		return false
+	}
+	cfg := c.config().TrackerReporter
+	return cfg.RuleFor(pj) != nil
+}
+
+// Report counts the consecutive failures of the job. It files an issue, or
+// comments on the open one, once the job failed often enough, and comments
+// on or closes the issue once the job passes again.
+func (c *Client) Report(ctx context.Context, log *logrus.Entry, pj *prowapi.ProwJob) ([]*prowapi.ProwJob, *reconcile.Result, error) {
+	cfg := c.config().TrackerReporter
+	rule := cfg.RuleFor(pj)
+	if rule == nil {
+		// The config changed since ShouldReport.
+		return []*prowapi.ProwJob{pj}, nil, nil
+	}
+	tracker, err := c.trackerFor(log, rule)
+	if err != nil {
+		return nil, nil, err
+	}
+	var completion time.Time
+	if pj.Status.CompletionTime != nil {
+		completion = pj.Status.CompletionTime.Time
+	}
+
+	c.lock.Lock()
+	state, known := c.states[pj.Spec.Job]
+	c.lock.Unlock()
+	if known && completion.Before(state.completion) {
+		// Runs that complete out of order don't change the state of the job.
+		return []*prowapi.ProwJob{pj}, nil, nil
+	}
+
+	summary := Summary(pj.Spec.Job)
+	if criercommonlib.Passing(pj.Status.State) {
+		issue := state.issue
+		if !known {
+			// The issue may have been filed before crier restarted.
+			if issue, err = tracker.find(summary); err != nil {
+				return nil, nil, fmt.Errorf("failed to find the issue of job %s: %w", pj.Spec.Job, err)
+			}
+		}
+		if issue != "" {
+			text := fmt.Sprintf("Job %s passed again: %s", pj.Spec.Job, pj.Status.URL)
+			if rule.CloseOnRecovery {
+				err = tracker.close(issue, text)
+			} else {
+				err = tracker.comment(issue, text)
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to update issue %s of job %s: %w", issue, pj.Spec.Job, err)
+			}
+			log.WithField("issue", issue).Info("Updated the issue of a job that passed again.")
+		}
+		state = jobState{completion: completion}
+	} else {
+		state.failures++
+		state.completion = completion
+		if state.failures >= rule.ConsecutiveFailures && state.issue == "" {
+			description := c.description(ctx, log, pj, state.failures)
+			issue, err := tracker.find(summary)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to find the issue of job %s: %w", pj.Spec.Job, err)
+			}
+			if issue == "" {
+				if issue, err = tracker.file(summary, description); err != nil {
+					return nil, nil, fmt.Errorf("failed to file an issue for job %s: %w", pj.Spec.Job, err)
+				}
+				log.WithField("issue", issue).Info("Filed an issue for a failing job.")
+			} else if err := tracker.comment(issue, description); err != nil {
+				return nil, nil, fmt.Errorf("failed to comment on issue %s of job %s: %w", issue, pj.Spec.Job, err)
+			}
+			state.issue = issue
+		}
+	}
+
+	c.lock.Lock()
+	c.states[pj.Spec.Job] = state
+	c.lock.Unlock()
+	return []*prowapi.ProwJob{pj}, nil, nil
+}
+
+// Summary is the summary of the issues of a job. Open issues with the same
+// summary are reused instead of filing new ones.
+func Summary(job string) string {
+	return fmt.Sprintf("Job %s is failing repeatedly", job)
+}
+
+// trackerFor returns the tracker the rule files issues in.
+func (c *Client) trackerFor(log *logrus.Entry, rule *config.TrackerRule) (issueTracker, error) {
+	var tracker issueTracker
+	switch {
+	case rule.Bugzilla != nil:
+		if c.bugzilla == nil {
+			return nil, fmt.Errorf("rule for jobs %q files issues in Bugzilla, but no Bugzilla client is configured", rule.Jobs)
+		}
+		tracker = &bugzillaTracker{client: c.bugzilla, config: *rule.Bugzilla}
+	case rule.Jira != nil:
+		if c.jira == nil {
+			return nil, fmt.Errorf("rule for jobs %q files issues in Jira, but no Jira client is configured", rule.Jobs)
+		}
+		tracker = &jiraTracker{client: c.jira, config: *rule.Jira}
+	default:
+		return nil, fmt.Errorf("rule for jobs %q has no tracker", rule.Jobs)
+	}
+	if c.dryRun {
+		tracker = &dryRunTracker{issueTracker: tracker, log: log}
+	}
+	return tracker, nil
+}
+
+// description describes the failures of the job, with a link to the run and
+// the tests that failed in it.
+func (c *Client) description(ctx context.Context, log *logrus.Entry, pj *prowapi.ProwJob, failures int) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Job %s failed %d times in a row.\n\n", pj.Spec.Job, failures)
+	fmt.Fprintf(&b, "Latest failed run: %s\n", pj.Status.URL)
+	tests, err := c.failedTests(ctx, pj)
+	if err != nil {
+		log.WithError(err).Warn("Failed to list the failed tests of the job.")
+	}
+	if len(tests) > 0 {
+		b.WriteString("\nFailed tests:\n")
+		for i, test := range tests {
+			if i == maxFailedTests {
+				fmt.Fprintf(&b, "... and %d more\n", len(tests)-maxFailedTests)
+				break
+			}
+			fmt.Fprintf(&b, "- %s\n", test)
+		}
+	}
+	return b.String()
+}
+
+// failedTests returns the names of the tests that failed in the junit
+// artifacts of the job.
+func (c *Client) failedTests(ctx context.Context, pj *prowapi.ProwJob) ([]string, error) {
+	if c.opener == nil {
+		return nil, nil
+	}
+	bucket, dir, err := util.GetJobDestination(c.config, pj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job destination: %w", err)
+	}
+	pp, err := prowapi.ParsePath(bucket)
+	if err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("%s://%s/", pp.StorageProvider(), pp.Bucket())
+	it, err := c.opener.Iterator(ctx, prefix+path.Join(dir, "artifacts")+"/", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	var tests []string
+	for {
+		attr, err := it.Next(ctx)
+		if errors.Is(err, stdio.EOF) {
+			break
+		}
+		if err != nil {
+			return tests, fmt.Errorf("failed to list artifacts: %w", err)
+		}
+		if attr.IsDir || !junitRegex.MatchString(attr.ObjName) {
+			continue
+		}
+		reader, err := c.opener.Reader(ctx, prefix+attr.Name)
+		if err != nil {
+			return tests, fmt.Errorf("failed to open %s: %w", attr.Name, err)
+		}
+		contents, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return tests, fmt.Errorf("failed to read %s: %w", attr.Name, err)
+		}
+		suites, err := junit.Parse(contents)
+		if err != nil {
+			// Not every file named junit*.xml is a junit file.
+			continue
+		}
+		var record func(suite junit.Suite)
+		record = func(suite junit.Suite) {
+			for _, subSuite := range suite.Suites {
+				record(subSuite)
+			}
+			for _, test := range suite.Results {
+				if test.Failure != nil || test.Errored != nil {
+					tests = append(tests, test.Name)
+				}
+			}
+		}
+		for _, suite := range suites.Suites {
+			record(suite)
+		}
+	}
+	return tests, nil
+}
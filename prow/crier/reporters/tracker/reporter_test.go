@@ -0,0 +1,337 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"context"
+	stdio "io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	jiraapi "github.com/andygrunwald/go-jira"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/bugzilla"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/io"
+	"k8s.io/test-infra/prow/jira"
+)
+
+func testConfig(rules ...config.TrackerRule) config.Getter {
+	cfg := &config.Config{ProwConfig: config.ProwConfig{TrackerReporter: config.TrackerReporter{Rules: rules}}}
+	if err := cfg.TrackerReporter.DefaultAndValidate(); err != nil {
+		panic(err)
+	}
+	return func() *config.Config { return cfg }
+}
+
+func run(name string, jobType prowapi.ProwJobType, state prowapi.ProwJobState, completion time.Time) *prowapi.ProwJob {
+	return &prowapi.ProwJob{
+		Spec: prowapi.ProwJobSpec{Type: jobType, Job: name},
+		Status: prowapi.ProwJobStatus{
+			State:          state,
+			CompletionTime: &metav1.Time{Time: completion},
+			URL:            "https://prow.example.com/view/" + name,
+		},
+	}
+}
+
+func newFakeBugzilla() *bugzilla.Fake {
+	return &bugzilla.Fake{Bugs: map[int]bugzilla.Bug{}, BugComments: map[int][]bugzilla.Comment{}}
+}
+
+func TestShouldReport(t *testing.T) {
+	c := NewReporter(testConfig(config.TrackerRule{Jobs: "^ci-e2e-", Jira: &config.JiraTracker{Project: "CI"}}), nil, nil, nil, false)
+	now := time.Now()
+	testCases := []struct {
+		name     string
+		pj       *prowapi.ProwJob
+		expected bool
+	}{
+		{
+			name:     "failed periodic of a rule",
+			pj:       run("ci-e2e-gce", prowapi.PeriodicJob, prowapi.FailureState, now),
+			expected: true,
+		},
+		{
+			name:     "passed postsubmit of a rule",
+			pj:       run("ci-e2e-gce", prowapi.PostsubmitJob, prowapi.SuccessState, now),
+			expected: true,
+		},
+		{
+			name: "aborted periodic of a rule",
+			pj:   run("ci-e2e-gce", prowapi.PeriodicJob, prowapi.AbortedState, now),
+		},
+		{
+			name: "failed presubmit matching a rule",
+			pj:   run("ci-e2e-gce", prowapi.PresubmitJob, prowapi.FailureState, now),
+		},
+		{
+			name: "periodic of no rule",
+			pj:   run("ci-unit", prowapi.PeriodicJob, prowapi.FailureState, now),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := c.ShouldReport(context.Background(), logrus.NewEntry(logrus.StandardLogger()), tc.pj); actual != tc.expected {
+				t.Errorf("expected ShouldReport to be %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReportBugzilla(t *testing.T) {
+	testCases := []struct {
+		name             string
+		closeOnRecovery  bool
+		existing         *bugzilla.Bug
+		states           []prowapi.ProwJobState
+		expectedBug      *bugzilla.Bug
+		expectedComments []string
+	}{
+		{
+			name:   "fewer failures than the threshold files no bug",
+			states: []prowapi.ProwJobState{prowapi.FailureState, prowapi.SuccessState, prowapi.FailureState, prowapi.ErrorState},
+		},
+		{
+			name:        "consecutive failures file a bug",
+			states:      []prowapi.ProwJobState{prowapi.FailureState, prowapi.ErrorState, prowapi.FailureState, prowapi.FailureState},
+			expectedBug: &bugzilla.Bug{Product: "Prow", Component: []string{"CI"}, Version: []string{"unspecified"}, Summary: Summary("ci-e2e-gce")},
+			expectedComments: []string{
+				"Job ci-e2e-gce failed 3 times in a row.\n\nLatest failed run: https://prow.example.com/view/ci-e2e-gce\n",
+			},
+		},
+		{
+			name:        "recovery comments on the bug",
+			states:      []prowapi.ProwJobState{prowapi.FailureState, prowapi.FailureState, prowapi.FailureState, prowapi.SuccessState},
+			expectedBug: &bugzilla.Bug{Product: "Prow", Component: []string{"CI"}, Version: []string{"unspecified"}, Summary: Summary("ci-e2e-gce")},
+			expectedComments: []string{
+				"Job ci-e2e-gce failed 3 times in a row.\n\nLatest failed run: https://prow.example.com/view/ci-e2e-gce\n",
+				"Job ci-e2e-gce passed again: https://prow.example.com/view/ci-e2e-gce",
+			},
+		},
+		{
+			name:            "recovery closes the bug",
+			closeOnRecovery: true,
+			states:          []prowapi.ProwJobState{prowapi.FailureState, prowapi.FailureState, prowapi.FailureState, prowapi.SuccessState},
+			expectedBug:     &bugzilla.Bug{Product: "Prow", Component: []string{"CI"}, Version: []string{"unspecified"}, Summary: Summary("ci-e2e-gce"), Status: "RESOLVED", Resolution: "FIXED"},
+			expectedComments: []string{
+				"Job ci-e2e-gce failed 3 times in a row.\n\nLatest failed run: https://prow.example.com/view/ci-e2e-gce\n",
+				"Job ci-e2e-gce passed again: https://prow.example.com/view/ci-e2e-gce",
+			},
+		},
+		{
+			name:        "open bug is commented on instead of filing a new one",
+			existing:    &bugzilla.Bug{ID: 1, Summary: Summary("ci-e2e-gce")},
+			states:      []prowapi.ProwJobState{prowapi.FailureState, prowapi.FailureState, prowapi.FailureState, prowapi.FailureState},
+			expectedBug: &bugzilla.Bug{ID: 1, Summary: Summary("ci-e2e-gce")},
+			expectedComments: []string{
+				"Job ci-e2e-gce failed 3 times in a row.\n\nLatest failed run: https://prow.example.com/view/ci-e2e-gce\n",
+			},
+		},
+		{
+			name:        "open bug is updated when the first run passes",
+			existing:    &bugzilla.Bug{ID: 1, Summary: Summary("ci-e2e-gce")},
+			states:      []prowapi.ProwJobState{prowapi.SuccessState, prowapi.SuccessState},
+			expectedBug: &bugzilla.Bug{ID: 1, Summary: Summary("ci-e2e-gce")},
+			expectedComments: []string{
+				"Job ci-e2e-gce passed again: https://prow.example.com/view/ci-e2e-gce",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newFakeBugzilla()
+			if tc.existing != nil {
+				client.Bugs[tc.existing.ID] = *tc.existing
+				client.SearchedBugs = []*bugzilla.Bug{tc.existing}
+			}
+			c := NewReporter(testConfig(config.TrackerRule{
+				Jobs:            "^ci-e2e-",
+				CloseOnRecovery: tc.closeOnRecovery,
+				Bugzilla:        &config.BugzillaTracker{Product: "Prow", Component: "CI"},
+			}), client, nil, nil, false)
+			start := time.Now()
+			for i, state := range tc.states {
+				pj := run("ci-e2e-gce", prowapi.PeriodicJob, state, start.Add(time.Duration(i)*time.Hour))
+				if _, _, err := c.Report(context.Background(), logrus.NewEntry(logrus.StandardLogger()), pj); err != nil {
+					t.Fatalf("report %d failed: %v", i, err)
+				}
+			}
+
+			if len(client.Bugs) > 1 {
+				t.Fatalf("expected at most one bug, got %v", client.Bugs)
+			}
+			var bug *bugzilla.Bug
+			for _, actual := range client.Bugs {
+				actual := actual
+				bug = &actual
+			}
+			if diff := cmp.Diff(tc.expectedBug, bug); diff != "" {
+				t.Errorf("bug differs from expected: %s", diff)
+			}
+			var comments []string
+			if bug != nil {
+				for _, comment := range client.BugComments[bug.ID] {
+					comments = append(comments, comment.Text)
+				}
+			}
+			if diff := cmp.Diff(tc.expectedComments, comments); diff != "" {
+				t.Errorf("comments differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+type fakeJira struct {
+	jira.Client
+	issues      map[string]*jiraapi.Issue
+	comments    map[string][]string
+	transitions []string
+}
+
+func (f *fakeJira) SearchIssues(jql string) ([]jiraapi.Issue, error) {
+	var issues []jiraapi.Issue
+	for _, issue := range f.issues {
+		issues = append(issues, *issue)
+	}
+	return issues, nil
+}
+
+func (f *fakeJira) CreateIssue(issue *jiraapi.Issue) (*jiraapi.Issue, error) {
+	issue.Key = issue.Fields.Project.Key + "-1"
+	f.issues[issue.Key] = issue
+	return issue, nil
+}
+
+func (f *fakeJira) AddComment(id string, comment *jiraapi.Comment) (*jiraapi.Comment, error) {
+	f.comments[id] = append(f.comments[id], comment.Body)
+	return comment, nil
+}
+
+func (f *fakeJira) GetTransitions(id string) ([]jiraapi.Transition, error) {
+	return []jiraapi.Transition{{ID: "11", Name: "In Progress"}, {ID: "31", Name: "Done"}}, nil
+}
+
+func (f *fakeJira) DoTransition(id, transitionID string) error {
+	f.transitions = append(f.transitions, id+":"+transitionID)
+	return nil
+}
+
+func TestReportJira(t *testing.T) {
+	client := &fakeJira{issues: map[string]*jiraapi.Issue{}, comments: map[string][]string{}}
+	c := NewReporter(testConfig(config.TrackerRule{
+		Jobs:                "^ci-e2e-",
+		ConsecutiveFailures: 2,
+		CloseOnRecovery:     true,
+		Jira:                &config.JiraTracker{Project: "CI"},
+	}), nil, client, nil, false)
+	start := time.Now()
+	for i, state := range []prowapi.ProwJobState{prowapi.FailureState, prowapi.FailureState, prowapi.FailureState, prowapi.SuccessState} {
+		pj := run("ci-e2e-gce", prowapi.PeriodicJob, state, start.Add(time.Duration(i)*time.Hour))
+		if _, _, err := c.Report(context.Background(), logrus.NewEntry(logrus.StandardLogger()), pj); err != nil {
+			t.Fatalf("report %d failed: %v", i, err)
+		}
+	}
+
+	issue, ok := client.issues["CI-1"]
+	if !ok {
+		t.Fatalf("expected issue CI-1 to be filed, got %v", client.issues)
+	}
+	if issue.Fields.Summary != Summary("ci-e2e-gce") || issue.Fields.Type.Name != "Bug" {
+		t.Errorf("unexpected issue fields: %+v", issue.Fields)
+	}
+	if diff := cmp.Diff([]string{"Job ci-e2e-gce passed again: https://prow.example.com/view/ci-e2e-gce"}, client.comments["CI-1"]); diff != "" {
+		t.Errorf("comments differ from expected: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"CI-1:31"}, client.transitions); diff != "" {
+		t.Errorf("transitions differ from expected: %s", diff)
+	}
+}
+
+func TestReportWithoutClient(t *testing.T) {
+	c := NewReporter(testConfig(config.TrackerRule{Jobs: ".*", Jira: &config.JiraTracker{Project: "CI"}}), newFakeBugzilla(), nil, nil, false)
+	pj := run("ci-e2e-gce", prowapi.PeriodicJob, prowapi.FailureState, time.Now())
+	if _, _, err := c.Report(context.Background(), logrus.NewEntry(logrus.StandardLogger()), pj); err == nil {
+		t.Error("expected an error for a rule whose tracker has no client")
+	}
+}
+
+type fakeIterator struct {
+	attrs []io.ObjectAttributes
+}
+
+func (f *fakeIterator) Next(_ context.Context) (io.ObjectAttributes, error) {
+	if len(f.attrs) == 0 {
+		return io.ObjectAttributes{}, stdio.EOF
+	}
+	attr := f.attrs[0]
+	f.attrs = f.attrs[1:]
+	return attr, nil
+}
+
+type fakeOpener struct {
+	io.Opener
+	// files are the contents of the files in gs://logs by name.
+	files map[string]string
+}
+
+func (f fakeOpener) Iterator(_ context.Context, prefix, _ string) (io.ObjectIterator, error) {
+	var names []string
+	for name := range f.files {
+		if strings.HasPrefix("gs://logs/"+name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	it := &fakeIterator{}
+	for _, name := range names {
+		it.attrs = append(it.attrs, io.ObjectAttributes{Name: name, ObjName: name[strings.LastIndex(name, "/")+1:]})
+	}
+	return it, nil
+}
+
+func (f fakeOpener) Reader(_ context.Context, path string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(f.files[strings.TrimPrefix(path, "gs://logs/")])), nil
+}
+
+func TestFailedTests(t *testing.T) {
+	opener := fakeOpener{files: map[string]string{
+		"logs/ci-e2e-gce/1/artifacts/junit_01.xml":     `<testsuite><testcase name="TestA"/><testcase name="TestB"><failure>boom</failure></testcase></testsuite>`,
+		"logs/ci-e2e-gce/1/artifacts/e2e/junit_02.xml": `<testsuites><testsuite><testcase name="TestC"><error>boom</error></testcase></testsuite></testsuites>`,
+		"logs/ci-e2e-gce/1/artifacts/build-log.txt":    `<testsuite><testcase name="TestD"><failure>boom</failure></testcase></testsuite>`,
+		"logs/ci-e2e-gce/2/artifacts/junit_01.xml":     `<testsuite><testcase name="TestE"><failure>boom</failure></testcase></testsuite>`,
+	}}
+	c := NewReporter(testConfig(), nil, nil, opener, false)
+	pj := run("ci-e2e-gce", prowapi.PeriodicJob, prowapi.FailureState, time.Now())
+	pj.Status.BuildID = "1"
+	pj.Spec.DecorationConfig = &prowapi.DecorationConfig{GCSConfiguration: &prowapi.GCSConfiguration{Bucket: "logs", PathStrategy: prowapi.PathStrategyExplicit}}
+
+	tests, err := c.failedTests(context.Background(), pj)
+	if err != nil {
+		t.Fatalf("failed to list failed tests: %v", err)
+	}
+	if diff := cmp.Diff([]string{"TestC", "TestB"}, tests); diff != "" {
+		t.Errorf("failed tests differ from expected: %s", diff)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"fmt"
+	"strconv"
+
+	jiraapi "github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/bugzilla"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/jira"
+)
+
+// bugzillaTracker files issues as bugs in a component of Bugzilla.
+type bugzillaTracker struct {
+	client bugzilla.Client
+	config config.BugzillaTracker
+}
+
+func (t *bugzillaTracker) find(summary string) (string, error) {
+	bugs, err := t.client.SearchBugs(map[string]string{
+		"product":   t.config.Product,
+		"component": t.config.Component,
+		"summary":   summary,
+		// Bugzilla calls the resolution of open bugs "---".
+		"resolution": "---",
+	})
+	if err != nil {
+		return "", err
+	}
+	// The search matches substrings of the summary.
+	for _, bug := range bugs {
+		if bug.Summary == summary {
+			return strconv.Itoa(bug.ID), nil
+		}
+	}
+	return "", nil
+}
+
+func (t *bugzillaTracker) file(summary, description string) (string, error) {
+	id, err := t.client.CreateBug(&bugzilla.BugCreate{
+		Product:     t.config.Product,
+		Component:   []string{t.config.Component},
+		Version:     []string{t.config.Version},
+		Summary:     summary,
+		Description: description,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(id), nil
+}
+
+func (t *bugzillaTracker) comment(id, text string) error {
+	bugID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid bug ID %q: %w", id, err)
+	}
+	_, err = t.client.CreateComment(&bugzilla.CommentCreate{ID: bugID, Comment: text})
+	return err
+}
+
+func (t *bugzillaTracker) close(id, text string) error {
+	if err := t.comment(id, text); err != nil {
+		return err
+	}
+	bugID, _ := strconv.Atoi(id)
+	return t.client.UpdateBug(bugID, bugzilla.BugUpdate{Status: t.config.ClosedStatus, Resolution: t.config.ClosedResolution})
+}
+
+// jiraTracker files issues in a project of Jira.
+type jiraTracker struct {
+	client jira.Client
+	config config.JiraTracker
+}
+
+func (t *jiraTracker) find(summary string) (string, error) {
+	issues, err := t.client.SearchIssues(fmt.Sprintf("project = %q AND statusCategory != Done AND summary ~ %q", t.config.Project, summary))
+	if err != nil {
+		return "", err
+	}
+	// The search matches the words of the summary.
+	for _, issue := range issues {
+		if issue.Fields != nil && issue.Fields.Summary == summary {
+			return issue.Key, nil
+		}
+	}
+	return "", nil
+}
+
+func (t *jiraTracker) file(summary, description string) (string, error) {
+	issue, err := t.client.CreateIssue(&jiraapi.Issue{Fields: &jiraapi.IssueFields{
+		Project:     jiraapi.Project{Key: t.config.Project},
+		Type:        jiraapi.IssueType{Name: t.config.IssueType},
+		Summary:     summary,
+		Description: description,
+	}})
+	if err != nil {
+		return "", err
+	}
+	return issue.Key, nil
+}
+
+func (t *jiraTracker) comment(id, text string) error {
+	_, err := t.client.AddComment(id, &jiraapi.Comment{Body: text})
+	return err
+}
+
+func (t *jiraTracker) close(id, text string) error {
+	if err := t.comment(id, text); err != nil {
+		return err
+	}
+	transitions, err := t.client.GetTransitions(id)
+	if err != nil {
+		return err
+	}
+	for _, transition := range transitions {
+		if transition.Name == t.config.CloseTransition {
+			return t.client.DoTransition(id, transition.ID)
+		}
+	}
+	return fmt.Errorf("issue %s has no transition %q", id, t.config.CloseTransition)
+}
+
+// dryRunTracker only logs the changes it would make to issues.
+type dryRunTracker struct {
+	issueTracker
+	log *logrus.Entry
+}
+
+func (t *dryRunTracker) file(summary, description string) (string, error) {
+	t.log.WithFields(logrus.Fields{"summary": summary, "description": description}).Debug("Skipping filing issue because dry-run is enabled")
+	return "dry-run", nil
+}
+
+func (t *dryRunTracker) comment(id, text string) error {
+	t.log.WithFields(logrus.Fields{"issue": id, "text": text}).Debug("Skipping commenting on issue because dry-run is enabled")
+	return nil
+}
+
+func (t *dryRunTracker) close(id, text string) error {
+	t.log.WithFields(logrus.Fields{"issue": id, "text": text}).Debug("Skipping closing issue because dry-run is enabled")
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"sort"
@@ -81,6 +82,12 @@ type PodLogClient interface {
 	GetLogs(name, container string) ([]byte, error)
 }
 
+// PodLogStreamer is implemented by the PodLogClients which can follow the logs
+// of running pods.
+type PodLogStreamer interface {
+	StreamLogs(ctx context.Context, name, container string) (io.ReadCloser, error)
+}
+
 // PJListingClient is an interface to list ProwJobs
 type PJListingClient interface {
 	List(context.Context, *prowapi.ProwJobList, ...ctrlruntimeclient.ListOption) error
@@ -103,6 +110,15 @@ func NewJobAgent(ctx context.Context, pjLister PJListingClient, hiddenOnly, show
 	}
 }
 
+// NewTenantScopedJobAgent is a JobAgent constructor for a Deck scoping the jobs
+// by the tenant of each request. The agent lists the jobs which are not hidden
+// of the default tenant and all the jobs of the other tenants.
+func NewTenantScopedJobAgent(ctx context.Context, pjLister PJListingClient, plClients map[string]PodLogClient, cfg config.Getter) *JobAgent {
+	ja := NewJobAgent(ctx, pjLister, false, false, nil, plClients, cfg)
+	ja.kc.(*filteringProwJobLister).allTenants = true
+	return ja
+}
+
 type filteringProwJobLister struct {
 	ctx         context.Context
 	client      PJListingClient
@@ -111,6 +127,7 @@ type filteringProwJobLister struct {
 	hiddenOnly  bool
 	showHidden  bool
 	tenantIDs   []string
+	allTenants  bool
 }
 
 func (c *filteringProwJobLister) TenantIDMatch(pj prowapi.ProwJob) bool {
@@ -142,7 +159,11 @@ func (c *filteringProwJobLister) ListProwJobs(selector string) ([]prowapi.ProwJo
 
 	var filtered []prowapi.ProwJob
 	for _, item := range prowJobList.Items {
-		if len(c.tenantIDs) != 0 {
+		if c.allTenants {
+			if !tenantIDMissingOrDefault(item) || !(item.Spec.Hidden || c.pjHasHiddenRefs(item)) {
+				filtered = append(filtered, item)
+			}
+		} else if len(c.tenantIDs) != 0 {
 			if c.TenantIDMatch(item) {
 				// Deck has tenantID and it matches Prowjob
 				filtered = append(filtered, item)
@@ -271,6 +292,35 @@ func (ja *JobAgent) GetJobLog(job, id string, container string) ([]byte, error)
 	return nil, fmt.Errorf("cannot get logs for prowjob %q with agent %q: the agent is missing from the prow config file", j.ObjectMeta.Name, j.Spec.Agent)
 }
 
+// StreamJobLog returns the log of the job, following it until the pod of the
+// job terminates if the job is running. The whole log is returned at once if the
+// job isn't running or the client of its build cluster can't follow logs, in
+// which case following is false.
+func (ja *JobAgent) StreamJobLog(ctx context.Context, job, id string, container string) (log io.ReadCloser, following bool, err error) {
+	j, err := ja.GetProwJob(job, id)
+	if err != nil {
+		return nil, false, fmt.Errorf("error getting prowjob: %w", err)
+	}
+	if j.Spec.Agent == prowapi.KubernetesAgent && j.Status.State == prowapi.PendingState {
+		client, ok := ja.pkcs[j.ClusterAlias()]
+		if !ok {
+			return nil, false, fmt.Errorf("cannot get logs for prowjob %q with agent %q: unknown cluster alias %q", j.ObjectMeta.Name, j.Spec.Agent, j.ClusterAlias())
+		}
+		if streamer, ok := client.(PodLogStreamer); ok {
+			log, err := streamer.StreamLogs(ctx, j.Status.PodName, container)
+			if err != nil {
+				return nil, false, err
+			}
+			return log, true, nil
+		}
+	}
+	b, err := ja.GetJobLog(job, id, container)
+	if err != nil {
+		return nil, false, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), false, nil
+}
+
 func (ja *JobAgent) tryUpdate() {
 	if err := ja.update(); err != nil {
 		logrus.WithError(err).Warning("Error updating job list.")
@@ -17,9 +17,12 @@ limitations under the License.
 package jobs
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"testing"
 	"time"
 
@@ -55,6 +58,18 @@ func (f fpkc) GetLogs(name, container string) ([]byte, error) {
 	return nil, fmt.Errorf("pod not found: %s", name)
 }
 
+type fpks struct {
+	fpkc
+}
+
+func (f fpks) StreamLogs(ctx context.Context, name, container string) (io.ReadCloser, error) {
+	b, err := f.GetLogs(name, container)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(append(b, []byte(".streamed")...))), nil
+}
+
 func TestGetLog(t *testing.T) {
 	kc := fkc{
 		prowapi.ProwJob{
@@ -106,6 +121,88 @@ func TestGetLog(t *testing.T) {
 	}
 }
 
+func TestStreamJobLog(t *testing.T) {
+	kc := fkc{
+		prowapi.ProwJob{
+			Spec: prowapi.ProwJobSpec{
+				Agent: prowapi.KubernetesAgent,
+				Job:   "running",
+			},
+			Status: prowapi.ProwJobStatus{
+				State:   prowapi.PendingState,
+				PodName: "wowowow",
+				BuildID: "123",
+			},
+		},
+		prowapi.ProwJob{
+			Spec: prowapi.ProwJobSpec{
+				Agent: prowapi.KubernetesAgent,
+				Job:   "finished",
+			},
+			Status: prowapi.ProwJobStatus{
+				State:   prowapi.SuccessState,
+				PodName: "wowowow",
+				BuildID: "123",
+			},
+		},
+		prowapi.ProwJob{
+			Spec: prowapi.ProwJobSpec{
+				Agent:   prowapi.KubernetesAgent,
+				Job:     "not-streamable",
+				Cluster: "trusted",
+			},
+			Status: prowapi.ProwJobStatus{
+				State:   prowapi.PendingState,
+				PodName: "powowow",
+				BuildID: "123",
+			},
+		},
+	}
+	ja := &JobAgent{
+		kc:   kc,
+		pkcs: map[string]PodLogClient{kube.DefaultClusterAlias: fpks{fpkc("clusterA")}, "trusted": fpkc("clusterB")},
+	}
+	if err := ja.update(); err != nil {
+		t.Fatalf("Updating: %v", err)
+	}
+
+	testCases := []struct {
+		job               string
+		expectedLog       string
+		expectedFollowing bool
+	}{
+		{
+			job:               "running",
+			expectedLog:       "clusterA.test.streamed",
+			expectedFollowing: true,
+		},
+		{
+			job:         "finished",
+			expectedLog: "clusterA.test",
+		},
+		{
+			job:         "not-streamable",
+			expectedLog: "clusterB.test",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.job, func(t *testing.T) {
+			log, following, err := ja.StreamJobLog(context.Background(), tc.job, "123", kube.TestContainerName)
+			if err != nil {
+				t.Fatalf("Failed to stream log: %v", err)
+			}
+			defer log.Close()
+			b, err := ioutil.ReadAll(log)
+			if err != nil {
+				t.Fatalf("Failed to read log: %v", err)
+			}
+			if string(b) != tc.expectedLog || following != tc.expectedFollowing {
+				t.Errorf("Expected log %q (following: %t), got %q (following: %t)", tc.expectedLog, tc.expectedFollowing, string(b), following)
+			}
+		})
+	}
+}
+
 func TestProwJobs(t *testing.T) {
 	kc := fkc{
 		prowapi.ProwJob{
@@ -272,6 +369,7 @@ func TestListProwJobs(t *testing.T) {
 		expected    sets.String
 		expectedErr bool
 		tenantIDs   []string
+		allTenants  bool
 	}{
 		{
 			name:        "list error results in filter error",
@@ -711,6 +809,34 @@ func TestListProwJobs(t *testing.T) {
 			},
 			expected: sets.NewString("empty tenant id", "No ProwJobDefault"),
 		},
+		{
+			name:        "all tenants lists the jobs of the tenants and the default jobs which are not hidden",
+			allTenants:  true,
+			hiddenRepos: sets.NewString("org"),
+			prowJobs: []func(*prowapi.ProwJob) runtime.Object{
+				func(in *prowapi.ProwJob) runtime.Object {
+					in.Name = "default"
+					return in
+				},
+				func(in *prowapi.ProwJob) runtime.Object {
+					in.Name = "hidden"
+					in.Spec.Hidden = true
+					return in
+				},
+				func(in *prowapi.ProwJob) runtime.Object {
+					in.Name = "hidden repo"
+					in.Spec.Refs = &prowapi.Refs{Org: "org", Repo: "repo"}
+					return in
+				},
+				func(in *prowapi.ProwJob) runtime.Object {
+					in.Name = "tenant"
+					in.Spec.Hidden = true
+					in.Spec.ProwJobDefault = &prowapi.ProwJobDefault{TenantID: "tenant"}
+					return in
+				},
+			},
+			expected: sets.NewString("default", "tenant"),
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -730,6 +856,7 @@ func TestListProwJobs(t *testing.T) {
 			hiddenOnly: testCase.hiddenOnly,
 			showHidden: testCase.showHidden,
 			tenantIDs:  testCase.tenantIDs,
+			allTenants: testCase.allTenants,
 			cfg:        func() *config.Config { return &config.Config{} },
 		}
 
@@ -22,6 +22,7 @@ import (
 	"flag"
 	"time"
 
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/pod-utils/wrapper"
 )
 
@@ -62,6 +63,10 @@ type Options struct {
 	// Primarily useful in case a subsequent entrypoint will read this entrypoint's marker
 	AlwaysZero bool `json:"always_zero,omitempty"`
 
+	// Steps are run instead of Args if set. Their exit codes and durations
+	// are recorded in steps.json in the ArtifactDir.
+	Steps []prowapi.Step `json:"steps,omitempty"`
+
 	CopyModeOnly bool   `json:"copy_mode_only,omitempty"`
 	CopyDst      string `json:"copy_dst,omitempty"`
 
@@ -71,9 +76,12 @@ type Options struct {
 // Validate ensures that the set of options are
 // self-consistent and valid
 func (o *Options) Validate() error {
-	if len(o.Args) == 0 {
+	if len(o.Args) == 0 && len(o.Steps) == 0 {
 		return errors.New("no process to wrap specified")
 	}
+	if len(o.Args) > 0 && len(o.Steps) > 0 {
+		return errors.New("args and steps are mutually exclusive")
+	}
 
 	return o.Options.Validate()
 }
@@ -19,6 +19,7 @@ package entrypoint
 import (
 	"testing"
 
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/pod-utils/wrapper"
 )
 
@@ -49,6 +50,29 @@ func TestOptions_Validate(t *testing.T) {
 			},
 			expectedErr: true,
 		},
+		{
+			name: "steps instead of args",
+			input: Options{
+				Steps: []prowapi.Step{{Name: "test", Command: []string{"/usr/bin/true"}}},
+				Options: &wrapper.Options{
+					ProcessLog: "output.txt",
+					MarkerFile: "marker.txt",
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name: "both steps and args",
+			input: Options{
+				Steps: []prowapi.Step{{Name: "test", Command: []string{"/usr/bin/true"}}},
+				Options: &wrapper.Options{
+					Args:       []string{"/usr/bin/true"},
+					ProcessLog: "output.txt",
+					MarkerFile: "marker.txt",
+				},
+			},
+			expectedErr: true,
+		},
 	}
 
 	for _, testCase := range testCases {
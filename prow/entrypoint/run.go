@@ -134,24 +134,56 @@ func (o Options) ExecuteProcess() (int, error) {
 		}
 	}
 
-	executable := o.Args[0]
+	abort := watchInterrupt(interrupt)
+	if len(o.Steps) > 0 {
+		return o.executeSteps(output, abort)
+	}
+	timeout := optionOrDefault(o.Timeout, DefaultTimeout)
+	gracePeriod := optionOrDefault(o.GracePeriod, DefaultGracePeriod)
+	return executeCommand(o.Args, output, timeout, gracePeriod, abort)
+}
+
+// abortion is closed once entrypoint is asked to terminate, which is
+// forwarded to all commands that are running.
+type abortion struct {
+	done chan struct{}
+	// signal is the signal entrypoint received, it is set before done is
+	// closed.
+	signal os.Signal
+}
+
+// watchInterrupt returns the abortion that happens when entrypoint receives
+// an interrupt.
+func watchInterrupt(interrupt <-chan os.Signal) *abortion {
+	abort := &abortion{done: make(chan struct{})}
+	go func() {
+		s := <-interrupt
+		logrus.Errorf("Entrypoint received interrupt: %v", s)
+		abort.signal = s
+		close(abort.done)
+	}()
+	return abort
+}
+
+// executeCommand runs the command, writing its output to output, and
+// returns its exit code or the code entrypoint chose if it did not finish.
+func executeCommand(args []string, output io.Writer, timeout, gracePeriod time.Duration, abort *abortion) (int, error) {
+	executable := args[0]
 	var arguments []string
-	if len(o.Args) > 1 {
-		arguments = o.Args[1:]
+	if len(args) > 1 {
+		arguments = args[1:]
 	}
 	command := exec.Command(executable, arguments...)
 	command.Stderr = output
 	command.Stdout = output
 	if err := command.Start(); err != nil {
 		errs := []error{fmt.Errorf("could not start the process: %w", err)}
-		if _, err := processLogFile.Write([]byte(errs[0].Error())); err != nil {
+		if _, err := output.Write([]byte(errs[0].Error())); err != nil {
 			errs = append(errs, err)
 		}
 		return InternalErrorCode, utilerrors.NewAggregate(errs)
 	}
 
-	timeout := optionOrDefault(o.Timeout, DefaultTimeout)
-	gracePeriod := optionOrDefault(o.GracePeriod, DefaultGracePeriod)
 	var commandErr error
 	cancelled, aborted := false, false
 	done := make(chan error)
@@ -165,11 +197,10 @@ func (o Options) ExecuteProcess() (int, error) {
 		logrus.Errorf("Process did not finish before %s timeout", timeout)
 		cancelled = true
 		gracefullyTerminate(command, done, gracePeriod, nil)
-	case s := <-interrupt:
-		logrus.Errorf("Entrypoint received interrupt: %v", s)
+	case <-abort.done:
 		cancelled = true
 		aborted = true
-		gracefullyTerminate(command, done, gracePeriod, &s)
+		gracefullyTerminate(command, done, gracePeriod, &abort.signal)
 	}
 
 	var returnCode int
@@ -0,0 +1,177 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package entrypoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+// StepsFile is the JSON file in the artifact directory that records the
+// steps entrypoint ran.
+const StepsFile = "steps.json"
+
+// Steps holds steps.json data
+type Steps struct {
+	Steps []StepResult `json:"steps"`
+}
+
+// StepResult records how a step ran.
+type StepResult struct {
+	Name          string `json:"name"`
+	ParallelGroup string `json:"parallel_group,omitempty"`
+	AllowFailure  bool   `json:"allow_failure,omitempty"`
+	// Skipped is true if the step did not run because a step before it
+	// failed, or the job timed out or was aborted before it.
+	Skipped bool `json:"skipped,omitempty"`
+	// ExitCode is the exit code of the step, or the code entrypoint chose
+	// if the step did not finish.
+	ExitCode int `json:"exit_code"`
+	// Error describes why the step failed.
+	Error           string     `json:"error,omitempty"`
+	StartTime       *time.Time `json:"start_time,omitempty"`
+	DurationSeconds float64    `json:"duration_seconds,omitempty"`
+}
+
+// executeSteps runs the steps, writing their output to output, and records
+// them in steps.json in the artifact directory. It returns the exit code of
+// the first step that failed and does not allow failure, the steps after it
+// are skipped.
+func (o Options) executeSteps(output io.Writer, abort *abortion) (int, error) {
+	timeout := optionOrDefault(o.Timeout, DefaultTimeout)
+	gracePeriod := optionOrDefault(o.GracePeriod, DefaultGracePeriod)
+	deadline := time.Now().Add(timeout)
+
+	var results []StepResult
+	var returnCode int
+	var returnErr error
+	checkInterrupted := func() {
+		if returnCode != 0 {
+			return
+		}
+		select {
+		case <-abort.done:
+			returnCode, returnErr = AbortedErrorCode, errAborted
+		default:
+			if !time.Now().Before(deadline) {
+				logrus.Errorf("Process did not finish before %s timeout", timeout)
+				returnCode, returnErr = InternalErrorCode, errTimedOut
+			}
+		}
+	}
+
+	for _, group := range groupSteps(o.Steps) {
+		checkInterrupted()
+		groupResults := make([]StepResult, len(group))
+		if returnCode != 0 {
+			for i, step := range group {
+				groupResults[i] = StepResult{Name: step.Name, ParallelGroup: step.ParallelGroup, AllowFailure: step.AllowFailure, Skipped: true}
+			}
+			results = append(results, groupResults...)
+			continue
+		}
+
+		var wg sync.WaitGroup
+		for i := range group {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				groupResults[i] = executeStep(group[i], output, deadline, gracePeriod, abort)
+			}(i)
+		}
+		wg.Wait()
+		for _, result := range groupResults {
+			if returnCode == 0 && result.ExitCode != 0 && !result.AllowFailure {
+				returnCode, returnErr = result.ExitCode, fmt.Errorf("step %s failed: %s", result.Name, result.Error)
+			}
+		}
+		results = append(results, groupResults...)
+	}
+	checkInterrupted()
+
+	if err := o.writeSteps(results); err != nil {
+		logrus.WithError(err).Error("Failed to record the steps.")
+	}
+	return returnCode, returnErr
+}
+
+// executeStep runs the step until it finishes, its timeout passes or the
+// deadline of the job passes, whichever comes first.
+func executeStep(step prowapi.Step, output io.Writer, deadline time.Time, gracePeriod time.Duration, abort *abortion) StepResult {
+	timeout := time.Until(deadline)
+	if stepTimeout := step.Timeout.Get(); stepTimeout > 0 && stepTimeout < timeout {
+		timeout = stepTimeout
+	}
+
+	logrus.Infof("Running step %s", step.Name)
+	start := time.Now()
+	code, err := executeCommand(step.Command, output, timeout, gracePeriod, abort)
+	result := StepResult{
+		Name:            step.Name,
+		ParallelGroup:   step.ParallelGroup,
+		AllowFailure:    step.AllowFailure,
+		ExitCode:        code,
+		StartTime:       &start,
+		DurationSeconds: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		logrus.WithError(err).Errorf("Step %s failed with exit code %d", step.Name, code)
+	} else {
+		logrus.Infof("Step %s passed", step.Name)
+	}
+	return result
+}
+
+// groupSteps splits the steps into the groups that run one after another,
+// every group holds adjacent steps of the same parallel group or a single
+// step.
+func groupSteps(steps []prowapi.Step) [][]prowapi.Step {
+	var groups [][]prowapi.Step
+	for i, step := range steps {
+		if i > 0 && step.ParallelGroup != "" && step.ParallelGroup == steps[i-1].ParallelGroup {
+			groups[len(groups)-1] = append(groups[len(groups)-1], step)
+			continue
+		}
+		groups = append(groups, []prowapi.Step{step})
+	}
+	return groups
+}
+
+// writeSteps writes steps.json to the artifact directory, if there is one.
+func (o Options) writeSteps(results []StepResult) error {
+	if o.ArtifactDir == "" {
+		return nil
+	}
+	data, err := json.Marshal(Steps{Steps: results})
+	if err != nil {
+		return fmt.Errorf("could not marshal steps: %w", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(o.ArtifactDir, StepsFile), data, 0644); err != nil {
+		return fmt.Errorf("could not write %s: %w", StepsFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package entrypoint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/wrapper"
+)
+
+func TestOptions_RunSteps(t *testing.T) {
+	var testCases = []struct {
+		name           string
+		steps          []prowapi.Step
+		expectedSteps  []StepResult
+		expectedMarker string
+		expectedCode   int
+	}{
+		{
+			name: "steps run in order",
+			steps: []prowapi.Step{
+				{Name: "build", Command: []string{"sh", "-c", "exit 0"}},
+				{Name: "test", Command: []string{"sh", "-c", "exit 0"}},
+			},
+			expectedSteps: []StepResult{
+				{Name: "build"},
+				{Name: "test"},
+			},
+			expectedMarker: "0",
+		},
+		{
+			name: "failing step skips the steps after it",
+			steps: []prowapi.Step{
+				{Name: "build", Command: []string{"sh", "-c", "exit 3"}},
+				{Name: "test", Command: []string{"sh", "-c", "exit 0"}},
+			},
+			expectedSteps: []StepResult{
+				{Name: "build", ExitCode: 3, Error: "wrapped process failed: exit status 3"},
+				{Name: "test", Skipped: true},
+			},
+			expectedMarker: "3",
+			expectedCode:   3,
+		},
+		{
+			name: "step that allows failure does not fail the job",
+			steps: []prowapi.Step{
+				{Name: "lint", Command: []string{"sh", "-c", "exit 3"}, AllowFailure: true},
+				{Name: "test", Command: []string{"sh", "-c", "exit 0"}},
+			},
+			expectedSteps: []StepResult{
+				{Name: "lint", AllowFailure: true, ExitCode: 3, Error: "wrapped process failed: exit status 3"},
+				{Name: "test"},
+			},
+			expectedMarker: "0",
+		},
+		{
+			name: "parallel group runs all of its steps",
+			steps: []prowapi.Step{
+				{Name: "unit", Command: []string{"sh", "-c", "exit 5"}, ParallelGroup: "test"},
+				{Name: "integration", Command: []string{"sh", "-c", "exit 0"}, ParallelGroup: "test"},
+				{Name: "publish", Command: []string{"sh", "-c", "exit 0"}},
+			},
+			expectedSteps: []StepResult{
+				{Name: "unit", ParallelGroup: "test", ExitCode: 5, Error: "wrapped process failed: exit status 5"},
+				{Name: "integration", ParallelGroup: "test"},
+				{Name: "publish", Skipped: true},
+			},
+			expectedMarker: "5",
+			expectedCode:   5,
+		},
+		{
+			name: "step times out",
+			steps: []prowapi.Step{
+				{Name: "test", Command: []string{"sleep", "10"}, Timeout: &prowapi.Duration{Duration: time.Second}},
+			},
+			expectedSteps: []StepResult{
+				{Name: "test", ExitCode: InternalErrorCode, Error: errTimedOut.Error()},
+			},
+			expectedMarker: strconv.Itoa(InternalErrorCode),
+			expectedCode:   InternalErrorCode,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			tmpDir, err := ioutil.TempDir("", "steps")
+			if err != nil {
+				t.Fatalf("error creating temp dir: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			options := Options{
+				GracePeriod: time.Second,
+				ArtifactDir: path.Join(tmpDir, "artifacts"),
+				Steps:       testCase.steps,
+				Options: &wrapper.Options{
+					ProcessLog: path.Join(tmpDir, "process-log.txt"),
+					MarkerFile: path.Join(tmpDir, "marker-file.txt"),
+				},
+			}
+			if code := options.Run(); code != testCase.expectedCode {
+				t.Errorf("expected exit code %d != actual %d", testCase.expectedCode, code)
+			}
+			compareFileContents(testCase.name, options.MarkerFile, testCase.expectedMarker, t)
+
+			data, err := ioutil.ReadFile(path.Join(options.ArtifactDir, StepsFile))
+			if err != nil {
+				t.Fatalf("could not read steps: %v", err)
+			}
+			var steps Steps
+			if err := json.Unmarshal(data, &steps); err != nil {
+				t.Fatalf("could not unmarshal steps: %v", err)
+			}
+			for i := range steps.Steps {
+				if !steps.Steps[i].Skipped && steps.Steps[i].StartTime == nil {
+					t.Errorf("expected step %s to record its start time", steps.Steps[i].Name)
+				}
+				steps.Steps[i].StartTime = nil
+				steps.Steps[i].DurationSeconds = 0
+			}
+			if diff := cmp.Diff(testCase.expectedSteps, steps.Steps); diff != "" {
+				t.Errorf("steps differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestGroupSteps(t *testing.T) {
+	steps := []prowapi.Step{
+		{Name: "a", ParallelGroup: "x"},
+		{Name: "b", ParallelGroup: "x"},
+		{Name: "c"},
+		{Name: "d"},
+		{Name: "e", ParallelGroup: "x"},
+	}
+	expected := [][]prowapi.Step{
+		{steps[0], steps[1]},
+		{steps[2]},
+		{steps[3]},
+		{steps[4]},
+	}
+	if diff := cmp.Diff(expected, groupSteps(steps)); diff != "" {
+		t.Errorf("groups differ from expected: %s", diff)
+	}
+}
@@ -35,6 +35,7 @@ import (
 	cherrypicker "k8s.io/test-infra/prow/external-plugins/cherrypicker/lib"
 	"k8s.io/test-infra/prow/git/v2"
 	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/labels"
 	"k8s.io/test-infra/prow/pluginhelp"
 	"k8s.io/test-infra/prow/plugins"
 )
@@ -51,6 +52,7 @@ type githubClient interface {
 	CreateComment(org, repo string, number int, comment string) error
 	CreateFork(org, repo string) (string, error)
 	CreatePullRequest(org, repo, title, body, head, base string, canModify bool) (int, error)
+	CreateDraftPullRequest(org, repo, title, body, head, base string, canModify bool) (int, error)
 	CreateIssue(org, repo, title, body string, milestone int, labels, assignees []string) (int, error)
 	EnsureFork(forkingUser, org, repo string) (string, error)
 	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
@@ -66,15 +68,15 @@ type githubClient interface {
 // HelpProvider construct the pluginhelp.PluginHelp for this plugin.
 func HelpProvider(_ []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
 	pluginHelp := &pluginhelp.PluginHelp{
-		Description: `The cherrypick plugin is used for cherrypicking PRs across branches. For every successful cherrypick invocation a new PR is opened against the target branch and assigned to the requestor. If the parent PR contains a release note, it is copied to the cherrypick PR.`,
+		Description: `The cherrypick plugin is used for cherrypicking PRs across branches. For every successful cherrypick invocation a new PR is opened against the target branch and assigned to the requestor. If the parent PR contains a release note, it is copied to the cherrypick PR. When running in conflict-assist mode, a cherrypick that does not apply cleanly still opens a draft PR containing the conflict markers, labeled '` + labels.CpConflict + `'.`,
 	}
 	pluginHelp.AddCommand(pluginhelp.Command{
-		Usage:       "/cherrypick [branch]",
-		Description: "Cherrypick a PR to a different branch. This command works both in merged PRs (the cherrypick PR is opened immediately) and open PRs (the cherrypick PR opens as soon as the original PR merges).",
+		Usage:       "/cherrypick [branch ...]",
+		Description: "Cherrypick a PR to one or more different branches. This command works both in merged PRs (the cherrypick PRs are opened immediately) and open PRs (the cherrypick PRs open as soon as the original PR merges). When several branches are given, the outcome for each of them is reported in a single comment.",
 		Featured:    true,
 		// depends on how the cherrypick server runs; needs auth by default (--allow-all=false)
 		WhoCanUse: "Members of the trusted organization for the repo.",
-		Examples:  []string{"/cherrypick release-3.9", "/cherry-pick release-1.15"},
+		Examples:  []string{"/cherrypick release-3.9", "/cherry-pick release-1.15", "/cherrypick release-1.28 release-1.29 release-1.30"},
 	})
 	return pluginHelp, nil
 }
@@ -100,6 +102,8 @@ type Server struct {
 	allowAll bool
 	// Create an issue on cherrypick conflict.
 	issueOnConflict bool
+	// Open a draft PR with the conflict markers on cherrypick conflict.
+	conflictAssist bool
 	// Set a custom label prefix.
 	labelPrefix string
 
@@ -186,7 +190,10 @@ func (s *Server) handleIssueComment(l *logrus.Entry, ic github.IssueCommentEvent
 	if len(cherryPickMatches) == 0 || len(cherryPickMatches[0]) != 2 {
 		return nil
 	}
-	targetBranch := strings.TrimSpace(cherryPickMatches[0][1])
+	targetBranches := parseTargetBranches(cherryPickMatches[0][1])
+	if len(targetBranches) == 0 {
+		return nil
+	}
 
 	if ic.Issue.State != "closed" {
 		if !s.allowAll {
@@ -201,7 +208,10 @@ func (s *Server) handleIssueComment(l *logrus.Entry, ic github.IssueCommentEvent
 				return s.ghc.CreateComment(org, repo, num, plugins.FormatICResponse(ic.Comment, resp))
 			}
 		}
-		resp := fmt.Sprintf("once the present PR merges, I will cherry-pick it on top of %s in a new PR and assign it to you.", targetBranch)
+		resp := fmt.Sprintf("once the present PR merges, I will cherry-pick it on top of %s in a new PR and assign it to you.", targetBranches[0])
+		if len(targetBranches) > 1 {
+			resp = fmt.Sprintf("once the present PR merges, I will cherry-pick it on top of %s in new PRs and assign them to you.", strings.Join(targetBranches, ", "))
+		}
 		l.Info(resp)
 		return s.ghc.CreateComment(org, repo, num, plugins.FormatICResponse(ic.Comment, resp))
 	}
@@ -221,13 +231,6 @@ func (s *Server) handleIssueComment(l *logrus.Entry, ic github.IssueCommentEvent
 		return s.ghc.CreateComment(org, repo, num, plugins.FormatICResponse(ic.Comment, resp))
 	}
 
-	// TODO: Use an allowlist for allowed base and target branches.
-	if baseBranch == targetBranch {
-		resp := fmt.Sprintf("base branch (%s) needs to differ from target branch (%s)", baseBranch, targetBranch)
-		l.Info(resp)
-		return s.ghc.CreateComment(org, repo, num, plugins.FormatICResponse(ic.Comment, resp))
-	}
-
 	if !s.allowAll {
 		// Only org members should be able to do cherry-picks.
 		ok, err := s.ghc.IsMember(org, commentAuthor)
@@ -241,12 +244,27 @@ func (s *Server) handleIssueComment(l *logrus.Entry, ic github.IssueCommentEvent
 		}
 	}
 
-	*l = *l.WithFields(logrus.Fields{
-		"requestor":     ic.Comment.User.Login,
-		"target_branch": targetBranch,
-	})
-	l.Debug("Cherrypick request.")
-	return s.handle(l, ic.Comment.User.Login, &ic.Comment, org, repo, targetBranch, title, body, num)
+	*l = *l.WithField("requestor", ic.Comment.User.Login)
+	summary := &cherryPickSummary{}
+	var errs []error
+	for _, targetBranch := range targetBranches {
+		// TODO: Use an allowlist for allowed base and target branches.
+		if baseBranch == targetBranch {
+			resp := fmt.Sprintf("base branch (%s) needs to differ from target branch (%s)", baseBranch, targetBranch)
+			l.Info(resp)
+			summary.add(targetBranch, resp)
+			continue
+		}
+		l := l.WithField("target_branch", targetBranch)
+		l.Debug("Cherrypick request.")
+		if err := s.handle(l, ic.Comment.User.Login, summary.responderFor(targetBranch), org, repo, targetBranch, title, body, num); err != nil {
+			errs = append(errs, fmt.Errorf("failed to create cherrypick: %w", err))
+		}
+	}
+	if err := s.postSummary(l, org, repo, num, &ic.Comment, summary); err != nil {
+		errs = append(errs, err)
+	}
+	return utilerrors.NewAggregate(errs)
 }
 
 func (s *Server) handlePullRequest(l *logrus.Entry, pre github.PullRequestEvent) error {
@@ -287,11 +305,12 @@ func (s *Server) handlePullRequest(l *logrus.Entry, pre github.PullRequestEvent)
 		c := comments[i]
 		cherryPickMatches := cherryPickRe.FindAllStringSubmatch(c.Body, -1)
 		for _, match := range cherryPickMatches {
-			targetBranch := strings.TrimSpace(match[1])
-			if requestorToComments[c.User.Login] == nil {
-				requestorToComments[c.User.Login] = make(map[string]*github.IssueComment)
+			for _, targetBranch := range parseTargetBranches(match[1]) {
+				if requestorToComments[c.User.Login] == nil {
+					requestorToComments[c.User.Login] = make(map[string]*github.IssueComment)
+				}
+				requestorToComments[c.User.Login][targetBranch] = &c
 			}
-			requestorToComments[c.User.Login][targetBranch] = &c
 		}
 	}
 
@@ -345,8 +364,10 @@ func (s *Server) handlePullRequest(l *logrus.Entry, pre github.PullRequestEvent)
 	}
 
 	// Handle multiple comments serially. Make sure to filter out
-	// comments targeting the same branch.
+	// comments targeting the same branch. The responses are collected
+	// per comment so that each request is answered with a single comment.
 	handledBranches := make(map[string]bool)
+	summaries := make(map[*github.IssueComment]*cherryPickSummary)
 	var errs []error
 	for requestor, branches := range requestorToComments {
 		for targetBranch, ic := range branches {
@@ -354,12 +375,13 @@ func (s *Server) handlePullRequest(l *logrus.Entry, pre github.PullRequestEvent)
 				// Branch already handled. Skip.
 				continue
 			}
+			if summaries[ic] == nil {
+				summaries[ic] = &cherryPickSummary{}
+			}
 			if targetBranch == baseBranch {
 				resp := fmt.Sprintf("base branch (%s) needs to differ from target branch (%s)", baseBranch, targetBranch)
 				l.Info(resp)
-				if err := s.createComment(l, org, repo, num, ic, resp); err != nil {
-					l.WithError(err).WithField("response", resp).Error("Failed to create comment.")
-				}
+				summaries[ic].add(targetBranch, resp)
 				continue
 			}
 			handledBranches[targetBranch] = true
@@ -368,18 +390,95 @@ func (s *Server) handlePullRequest(l *logrus.Entry, pre github.PullRequestEvent)
 				"target_branch": targetBranch,
 			})
 			l.Debug("Cherrypick request.")
-			err := s.handle(l, requestor, ic, org, repo, targetBranch, title, body, num)
+			err := s.handle(l, requestor, summaries[ic].responderFor(targetBranch), org, repo, targetBranch, title, body, num)
 			if err != nil {
 				errs = append(errs, fmt.Errorf("failed to create cherrypick: %w", err))
 			}
 		}
 	}
+	for ic, summary := range summaries {
+		if err := s.postSummary(l, org, repo, num, ic, summary); err != nil {
+			l.WithError(err).Error("Failed to create comment.")
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// parseTargetBranches returns the distinct branches listed in the
+// argument of a cherrypick command, in the order they were given.
+func parseTargetBranches(arg string) []string {
+	var branches []string
+	seen := make(map[string]bool)
+	for _, branch := range strings.Fields(arg) {
+		if !seen[branch] {
+			seen[branch] = true
+			branches = append(branches, branch)
+		}
+	}
+	return branches
+}
+
+// responder reports a response to a cherry-pick request.
+type responder func(resp string) error
+
+// cherryPickSummary collects the responses of the cherry-picks to each
+// target branch of a single request.
+type cherryPickSummary struct {
+	branches  []string
+	responses map[string][]string
+}
+
+// add records a response for the branch.
+func (cs *cherryPickSummary) add(branch, resp string) {
+	if cs.responses == nil {
+		cs.responses = make(map[string][]string)
+	}
+	if _, ok := cs.responses[branch]; !ok {
+		cs.branches = append(cs.branches, branch)
+	}
+	cs.responses[branch] = append(cs.responses[branch], resp)
+}
+
+// responderFor returns a responder recording the responses for the branch.
+func (cs *cherryPickSummary) responderFor(branch string) responder {
+	return func(resp string) error {
+		cs.add(branch, resp)
+		return nil
+	}
+}
+
+// String formats the responses of all branches as a single comment.
+func (cs *cherryPickSummary) String() string {
+	lines := []string{"cherry-pick results:"}
+	for _, branch := range cs.branches {
+		lines = append(lines, fmt.Sprintf("**%s**: %s", branch, strings.Join(cs.responses[branch], "\n\n")))
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+// postSummary comments the responses collected in the summary. The responses
+// of a single branch are posted as they are, while the responses for several
+// branches are reported in one summary comment.
+func (s *Server) postSummary(l *logrus.Entry, org, repo string, num int, comment *github.IssueComment, summary *cherryPickSummary) error {
+	if len(summary.branches) > 1 {
+		return s.createComment(l, org, repo, num, comment, summary.String())
+	}
+	var errs []error
+	for _, branch := range summary.branches {
+		for _, resp := range summary.responses[branch] {
+			if err := s.createComment(l, org, repo, num, comment, resp); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
 	return utilerrors.NewAggregate(errs)
 }
 
 var cherryPickBranchFmt = "cherry-pick-%d-to-%s"
 
-func (s *Server) handle(logger *logrus.Entry, requestor string, comment *github.IssueComment, org, repo, targetBranch, title, body string, num int) error {
+const conflictNote = "The cherry-pick did not apply cleanly, the conflicting files were committed with their conflict markers. Resolve the conflicts by pushing to this branch, then mark the PR as ready for review and remove the `" + labels.CpConflict + "` label."
+
+func (s *Server) handle(logger *logrus.Entry, requestor string, respond responder, org, repo, targetBranch, title, body string, num int) error {
 	var lock *sync.Mutex
 	func() {
 		s.mapLock.Lock()
@@ -399,7 +498,7 @@ func (s *Server) handle(logger *logrus.Entry, requestor string, comment *github.
 	if err != nil {
 		logger.WithError(err).Warn("failed to ensure fork exists")
 		resp := fmt.Sprintf("cannot fork %s/%s: %v", org, repo, err)
-		return s.createComment(logger, org, repo, num, comment, resp)
+		return respond(resp)
 	}
 
 	// Clone the repo, checkout the target branch.
@@ -416,7 +515,7 @@ func (s *Server) handle(logger *logrus.Entry, requestor string, comment *github.
 	if err := r.Checkout(targetBranch); err != nil {
 		logger.WithError(err).Warn("failed to checkout target branch")
 		resp := fmt.Sprintf("cannot checkout `%s`: %v", targetBranch, err)
-		return s.createComment(logger, org, repo, num, comment, resp)
+		return respond(resp)
 	}
 	logger.WithField("duration", time.Since(startClone)).Info("Cloned and checked out target branch.")
 
@@ -451,7 +550,7 @@ func (s *Server) handle(logger *logrus.Entry, requestor string, comment *github.
 			if pr.Head.Ref == fmt.Sprintf("%s:%s", s.botUser.Login, newBranch) {
 				logger.WithField("preexisting_cherrypick", pr.HTMLURL).Info("PR already has cherrypick")
 				resp := fmt.Sprintf("Looks like #%d has already been cherry picked in %s", num, pr.HTMLURL)
-				return s.createComment(logger, org, repo, num, comment, resp)
+				return respond(resp)
 			}
 		}
 	}
@@ -464,18 +563,25 @@ func (s *Server) handle(logger *logrus.Entry, requestor string, comment *github.
 	// Title for GitHub issue/PR.
 	title = fmt.Sprintf("[%s] %s", targetBranch, title)
 
-	// Apply the patch.
-	if err := r.Am(localPath); err != nil {
+	// Apply the patch. In conflict-assist mode, conflicts are committed
+	// with their markers so they can be resolved in the cherry-pick PR.
+	var conflicts bool
+	if s.conflictAssist {
+		conflicts, err = r.AmWithConflicts(localPath)
+	} else {
+		err = r.Am(localPath)
+	}
+	if err != nil {
 		errs := []error{fmt.Errorf("failed to `git am`: %w", err)}
 		logger.WithError(err).Warn("failed to apply PR on top of target branch")
 		resp := fmt.Sprintf("#%d failed to apply on top of branch %q:\n```\n%v\n```", num, targetBranch, err)
-		if err := s.createComment(logger, org, repo, num, comment, resp); err != nil {
+		if err := respond(resp); err != nil {
 			errs = append(errs, fmt.Errorf("failed to create comment: %w", err))
 		}
 
 		if s.issueOnConflict {
 			resp = fmt.Sprintf("Manual cherrypick required.\n\n%v", resp)
-			if err := s.createIssue(logger, org, repo, title, resp, num, comment, nil, []string{requestor}); err != nil {
+			if err := s.createIssue(logger, org, repo, title, resp, respond, nil, []string{requestor}); err != nil {
 				errs = append(errs, fmt.Errorf("failed to create issue: %w", err))
 			}
 		}
@@ -491,7 +597,7 @@ func (s *Server) handle(logger *logrus.Entry, requestor string, comment *github.
 	if err := push(forkName, newBranch, true); err != nil {
 		logger.WithError(err).Warn("failed to push chery-picked changes to GitHub")
 		resp := fmt.Sprintf("failed to push cherry-picked changes in GitHub: %v", err)
-		return utilerrors.NewAggregate([]error{err, s.createComment(logger, org, repo, num, comment, resp)})
+		return utilerrors.NewAggregate([]error{err, respond(resp)})
 	}
 
 	// Open a PR in GitHub.
@@ -502,19 +608,29 @@ func (s *Server) handle(logger *logrus.Entry, requestor string, comment *github.
 		cherryPickBody = cherrypicker.CreateCherrypickBody(num, "", releaseNoteFromParentPR(body))
 	}
 	head := fmt.Sprintf("%s:%s", s.botUser.Login, newBranch)
-	createdNum, err := s.ghc.CreatePullRequest(org, repo, title, cherryPickBody, head, targetBranch, true)
+	createPullRequest := s.ghc.CreatePullRequest
+	prLabels := s.labels
+	if conflicts {
+		cherryPickBody = fmt.Sprintf("%s\n\n%s", cherryPickBody, conflictNote)
+		createPullRequest = s.ghc.CreateDraftPullRequest
+		prLabels = append([]string{labels.CpConflict}, prLabels...)
+	}
+	createdNum, err := createPullRequest(org, repo, title, cherryPickBody, head, targetBranch, true)
 	if err != nil {
 		logger.WithError(err).Warn("failed to create new pull request")
 		resp := fmt.Sprintf("new pull request could not be created: %v", err)
-		return utilerrors.NewAggregate([]error{err, s.createComment(logger, org, repo, num, comment, resp)})
+		return utilerrors.NewAggregate([]error{err, respond(resp)})
 	}
 	*logger = *logger.WithField("new_pull_request_number", createdNum)
 	resp := fmt.Sprintf("new pull request created: #%d", createdNum)
+	if conflicts {
+		resp = fmt.Sprintf("#%d does not apply cleanly on top of branch %q, new draft pull request created with the conflicts to resolve: #%d", num, targetBranch, createdNum)
+	}
 	logger.Info("new pull request created")
-	if err := s.createComment(logger, org, repo, num, comment, resp); err != nil {
+	if err := respond(resp); err != nil {
 		return fmt.Errorf("failed to create comment: %w", err)
 	}
-	for _, label := range s.labels {
+	for _, label := range prLabels {
 		if err := s.ghc.AddLabel(org, repo, createdNum, label); err != nil {
 			return fmt.Errorf("failed to add label %s: %w", label, err)
 		}
@@ -546,13 +662,13 @@ func (s *Server) createComment(l *logrus.Entry, org, repo string, num int, comme
 }
 
 // createIssue creates an issue on GitHub.
-func (s *Server) createIssue(l *logrus.Entry, org, repo, title, body string, num int, comment *github.IssueComment, labels, assignees []string) error {
+func (s *Server) createIssue(l *logrus.Entry, org, repo, title, body string, respond responder, labels, assignees []string) error {
 	issueNum, err := s.ghc.CreateIssue(org, repo, title, body, 0, labels, assignees)
 	if err != nil {
-		return s.createComment(l, org, repo, num, comment, fmt.Sprintf("new issue could not be created for failed cherrypick: %v", err))
+		return respond(fmt.Sprintf("new issue could not be created for failed cherrypick: %v", err))
 	}
 
-	return s.createComment(l, org, repo, num, comment, fmt.Sprintf("new issue created for failed cherrypick: #%d", issueNum))
+	return respond(fmt.Sprintf("new issue created for failed cherrypick: #%d", issueNum))
 }
 
 // ensureForkExists ensures a fork of org/repo exists for the bot.
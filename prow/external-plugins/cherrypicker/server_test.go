@@ -17,9 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 
@@ -27,6 +29,7 @@ import (
 
 	"k8s.io/test-infra/prow/git/localgit"
 	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/labels"
 )
 
 var commentFormat = "%s/%s#%d %s"
@@ -149,6 +152,14 @@ func (f *fghc) CreateIssue(org, repo, title, body string, milestone int, labels,
 }
 
 func (f *fghc) CreatePullRequest(org, repo, title, body, head, base string, canModify bool) (int, error) {
+	return f.createPullRequest(title, body, head, base, false)
+}
+
+func (f *fghc) CreateDraftPullRequest(org, repo, title, body, head, base string, canModify bool) (int, error) {
+	return f.createPullRequest(title, body, head, base, true)
+}
+
+func (f *fghc) createPullRequest(title, body, head, base string, draft bool) (int, error) {
 	f.Lock()
 	defer f.Unlock()
 	num := len(f.prs) + 1
@@ -158,6 +169,7 @@ func (f *fghc) CreatePullRequest(org, repo, title, body, head, base string, canM
 		Number: num,
 		Head:   github.PullRequestBranch{Ref: head},
 		Base:   github.PullRequestBranch{Ref: base},
+		Draft:  draft,
 	})
 	return num, nil
 }
@@ -323,6 +335,227 @@ func testCherryPickIC(clients localgit.Clients, t *testing.T) {
 	}
 }
 
+func TestCherryPickICMultipleBranches(t *testing.T) {
+	t.Parallel()
+	testCherryPickICMultipleBranches(localgit.New, t)
+}
+
+func TestCherryPickICMultipleBranchesV2(t *testing.T) {
+	t.Parallel()
+	testCherryPickICMultipleBranches(localgit.NewV2, t)
+}
+
+func testCherryPickICMultipleBranches(clients localgit.Clients, t *testing.T) {
+	lg, c, err := clients()
+	if err != nil {
+		t.Fatalf("Making localgit: %v", err)
+	}
+	defer func() {
+		if err := lg.Clean(); err != nil {
+			t.Errorf("Cleaning up localgit: %v", err)
+		}
+		if err := c.Clean(); err != nil {
+			t.Errorf("Cleaning up client: %v", err)
+		}
+	}()
+	if err := lg.MakeFakeRepo("foo", "bar"); err != nil {
+		t.Fatalf("Making fake repo: %v", err)
+	}
+	if err := lg.AddCommit("foo", "bar", initialFiles); err != nil {
+		t.Fatalf("Adding initial commit: %v", err)
+	}
+	expectedBranches := []string{"release-1.28", "release-1.29"}
+	for _, branch := range expectedBranches {
+		if err := lg.CheckoutNewBranch("foo", "bar", branch); err != nil {
+			t.Fatalf("Checking out pull branch: %v", err)
+		}
+	}
+
+	ghc := &fghc{
+		pr: &github.PullRequest{
+			Base: github.PullRequestBranch{
+				Ref: "master",
+			},
+			Merged: true,
+			Title:  "This is a fix for X",
+			Body:   body,
+		},
+		isMember: true,
+		patch:    patch,
+	}
+	ic := github.IssueCommentEvent{
+		Action: github.IssueCommentActionCreated,
+		Repo: github.Repo{
+			Owner: github.User{
+				Login: "foo",
+			},
+			Name:     "bar",
+			FullName: "foo/bar",
+		},
+		Issue: github.Issue{
+			Number:      2,
+			State:       "closed",
+			PullRequest: &struct{}{},
+		},
+		Comment: github.IssueComment{
+			User: github.User{
+				Login: "wiseguy",
+			},
+			Body: "/cherrypick release-1.28 master release-1.29 release-1.28",
+		},
+	}
+
+	botUser := &github.UserData{Login: "ci-robot", Email: "ci-robot@users.noreply.github.com"}
+	s := &Server{
+		botUser:        botUser,
+		gc:             c,
+		push:           func(forkName, newBranch string, force bool) error { return nil },
+		ghc:            ghc,
+		tokenGenerator: func() []byte { return []byte("sha=abcdefg") },
+		log:            logrus.StandardLogger().WithField("client", "cherrypicker"),
+		repos:          []github.Repo{{Fork: true, FullName: "ci-robot/bar"}},
+
+		prowAssignments: true,
+	}
+
+	if err := s.handleIssueComment(logrus.NewEntry(logrus.StandardLogger()), ic); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ghc.prs) != len(expectedBranches) {
+		t.Fatalf("Expected %d PRs, got %d", len(expectedBranches), len(ghc.prs))
+	}
+	for i, branch := range expectedBranches {
+		expectedTitle := fmt.Sprintf("[%s] This is a fix for X", branch)
+		expectedBody := "This is an automated cherry-pick of #2\n\n/assign wiseguy\n\n```release-note\nUpdate the magic number from 42 to 49\n```"
+		expectedHead := fmt.Sprintf(botUser.Login+":"+cherryPickBranchFmt, 2, branch)
+		expected := fmt.Sprintf(expectedFmt, expectedTitle, expectedBody, expectedHead, branch, []string{})
+		if got := prToString(ghc.prs[i]); got != expected {
+			t.Errorf("Expected (%d):\n%s\nGot (%d):\n%+v\n", len(expected), expected, len(got), got)
+		}
+	}
+
+	if len(ghc.comments) != 1 {
+		t.Fatalf("Expected a single summary comment, got %d: %v", len(ghc.comments), ghc.comments)
+	}
+	expectedSummary := "cherry-pick results:\n\n" +
+		"**release-1.28**: new pull request created: #1\n\n" +
+		"**master**: base branch (master) needs to differ from target branch (master)\n\n" +
+		"**release-1.29**: new pull request created: #2"
+	if !strings.Contains(ghc.comments[0], expectedSummary) {
+		t.Errorf("Expected comment to contain:\n%s\nGot:\n%s", expectedSummary, ghc.comments[0])
+	}
+}
+
+func TestCherryPickICConflictAssist(t *testing.T) {
+	t.Parallel()
+	testCherryPickICConflictAssist(localgit.New, t)
+}
+
+func TestCherryPickICConflictAssistV2(t *testing.T) {
+	t.Parallel()
+	testCherryPickICConflictAssist(localgit.NewV2, t)
+}
+
+func testCherryPickICConflictAssist(clients localgit.Clients, t *testing.T) {
+	lg, c, err := clients()
+	if err != nil {
+		t.Fatalf("Making localgit: %v", err)
+	}
+	defer func() {
+		if err := lg.Clean(); err != nil {
+			t.Errorf("Cleaning up localgit: %v", err)
+		}
+		if err := c.Clean(); err != nil {
+			t.Errorf("Cleaning up client: %v", err)
+		}
+	}()
+	if err := lg.MakeFakeRepo("foo", "bar"); err != nil {
+		t.Fatalf("Making fake repo: %v", err)
+	}
+	if err := lg.AddCommit("foo", "bar", initialFiles); err != nil {
+		t.Fatalf("Adding initial commit: %v", err)
+	}
+	if err := lg.CheckoutNewBranch("foo", "bar", "stage"); err != nil {
+		t.Fatalf("Checking out pull branch: %v", err)
+	}
+	conflictingFiles := map[string][]byte{
+		"bar.go": bytes.Replace(initialFiles["bar.go"], []byte("42 + wow"), []byte("43 + wow"), 1),
+	}
+	if err := lg.AddCommit("foo", "bar", conflictingFiles); err != nil {
+		t.Fatalf("Adding conflicting commit: %v", err)
+	}
+
+	ghc := &fghc{
+		pr: &github.PullRequest{
+			Base: github.PullRequestBranch{
+				Ref: "master",
+			},
+			Merged: true,
+			Title:  "This is a fix for X",
+			Body:   body,
+		},
+		isMember: true,
+		patch:    patch,
+	}
+	ic := github.IssueCommentEvent{
+		Action: github.IssueCommentActionCreated,
+		Repo: github.Repo{
+			Owner: github.User{
+				Login: "foo",
+			},
+			Name:     "bar",
+			FullName: "foo/bar",
+		},
+		Issue: github.Issue{
+			Number:      2,
+			State:       "closed",
+			PullRequest: &struct{}{},
+		},
+		Comment: github.IssueComment{
+			User: github.User{
+				Login: "wiseguy",
+			},
+			Body: "/cherrypick stage",
+		},
+	}
+
+	botUser := &github.UserData{Login: "ci-robot", Email: "ci-robot@users.noreply.github.com"}
+	s := &Server{
+		botUser:        botUser,
+		gc:             c,
+		push:           func(forkName, newBranch string, force bool) error { return nil },
+		ghc:            ghc,
+		tokenGenerator: func() []byte { return []byte("sha=abcdefg") },
+		log:            logrus.StandardLogger().WithField("client", "cherrypicker"),
+		repos:          []github.Repo{{Fork: true, FullName: "ci-robot/bar"}},
+
+		labels:          []string{"cla: yes"},
+		prowAssignments: true,
+		conflictAssist:  true,
+	}
+
+	if err := s.handleIssueComment(logrus.NewEntry(logrus.StandardLogger()), ic); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ghc.prs) != 1 {
+		t.Fatalf("Expected 1 PR, got %d", len(ghc.prs))
+	}
+	expectedTitle := "[stage] This is a fix for X"
+	expectedBody := "This is an automated cherry-pick of #2\n\n/assign wiseguy\n\n```release-note\nUpdate the magic number from 42 to 49\n```\n\n" + conflictNote
+	expectedHead := fmt.Sprintf(botUser.Login+":"+cherryPickBranchFmt, 2, "stage")
+	expected := fmt.Sprintf(expectedFmt, expectedTitle, expectedBody, expectedHead, "stage", []string{labels.CpConflict, "cla: yes"})
+	if got := prToString(ghc.prs[0]); got != expected {
+		t.Errorf("Expected (%d):\n%s\nGot (%d):\n%+v\n", len(expected), expected, len(got), got)
+	}
+	if !ghc.prs[0].Draft {
+		t.Error("Expected the cherry-pick PR to be a draft")
+	}
+	expectedComment := `#2 does not apply cleanly on top of branch "stage", new draft pull request created with the conflicts to resolve: #1`
+	if len(ghc.comments) != 1 || !strings.Contains(ghc.comments[0], expectedComment) {
+		t.Errorf("Expected a comment containing %q, got %v", expectedComment, ghc.comments)
+	}
+}
+
 func TestCherryPickPR(t *testing.T) {
 	t.Parallel()
 	testCherryPickPR(localgit.New, t)
@@ -744,7 +977,11 @@ func TestCherryPickCreateIssue(t *testing.T) {
 			ghc: ghc,
 		}
 
-		if err := s.createIssue(logrus.WithField("test", t.Name()), tc.org, tc.repo, tc.title, tc.body, tc.prNum, nil, tc.labels, tc.assignees); err != nil {
+		l := logrus.WithField("test", t.Name())
+		respond := func(resp string) error {
+			return s.createComment(l, tc.org, tc.repo, tc.prNum, nil, resp)
+		}
+		if err := s.createIssue(l, tc.org, tc.repo, tc.title, tc.body, respond, tc.labels, tc.assignees); err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
@@ -795,6 +1032,34 @@ func TestCherryPickCreateIssue(t *testing.T) {
 	}
 }
 
+func TestCherryPickICWithoutBranches(t *testing.T) {
+	t.Parallel()
+	for _, state := range []string{"open", "closed"} {
+		t.Run(state, func(t *testing.T) {
+			ghc := &fghc{isMember: true}
+			s := &Server{
+				ghc: ghc,
+				log: logrus.StandardLogger().WithField("client", "cherrypicker"),
+			}
+			ic := github.IssueCommentEvent{
+				Action: github.IssueCommentActionCreated,
+				Repo:   github.Repo{Owner: github.User{Login: "foo"}, Name: "bar", FullName: "foo/bar"},
+				Issue:  github.Issue{Number: 2, State: state, PullRequest: &struct{}{}},
+				Comment: github.IssueComment{
+					User: github.User{Login: "wiseguy"},
+					Body: "/cherrypick  \t ",
+				},
+			}
+			if err := s.handleIssueComment(logrus.NewEntry(logrus.StandardLogger()), ic); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(ghc.comments) != 0 || len(ghc.prs) != 0 {
+				t.Errorf("expected no comments nor PRs, got comments %v and PRs %v", ghc.comments, ghc.prs)
+			}
+		})
+	}
+}
+
 func TestHandleLocks(t *testing.T) {
 	t.Parallel()
 	s := &Server{
@@ -809,13 +1074,13 @@ func TestHandleLocks(t *testing.T) {
 
 	go func() {
 		defer close(routine1Done)
-		if err := s.handle(l, "", &github.IssueComment{}, "org", "repo", "targetBranch", "title", "body", 0); err != nil {
+		if err := s.handle(l, "", (&cherryPickSummary{}).responderFor("targetBranch"), "org", "repo", "targetBranch", "title", "body", 0); err != nil {
 			t.Errorf("routine failed: %v", err)
 		}
 	}()
 	go func() {
 		defer close(routine2Done)
-		if err := s.handle(l, "", &github.IssueComment{}, "org", "repo", "targetBranch", "title", "body", 0); err != nil {
+		if err := s.handle(l, "", (&cherryPickSummary{}).responderFor("targetBranch"), "org", "repo", "targetBranch", "title", "body", 0); err != nil {
 			t.Errorf("routine failed: %v", err)
 		}
 	}()
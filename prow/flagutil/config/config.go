@@ -17,11 +17,16 @@ limitations under the License.
 package flagutil
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"strings"
+	"time"
 
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/flagutil"
+	"k8s.io/test-infra/prow/io"
 )
 
 const (
@@ -40,6 +45,11 @@ type ConfigOptions struct {
 	JobConfigPathFlagName                 string
 	SupplementalProwConfigDirs            flagutil.Strings
 	SupplementalProwConfigsFileNameSuffix string
+	// InRepoConfigStore is where the inrepoconfig of the repos is shared with
+	// the other replicas and components, see config.WithInRepoConfigStore.
+	InRepoConfigStore                string
+	InRepoConfigStoreCredentialsFile string
+	InRepoConfigStoreTTL             time.Duration
 }
 
 func (o *ConfigOptions) AddFlags(fs *flag.FlagSet) {
@@ -54,12 +64,18 @@ func (o *ConfigOptions) AddFlags(fs *flag.FlagSet) {
 	fs.Var(&o.SupplementalProwConfigDirs, "supplemental-prow-config-dir", "An additional directory from which to load prow configs. Can be used for config sharding but only supports a subset of the config. The flag can be passed multiple times.")
 	fs.StringVar(&o.SupplementalProwConfigsFileNameSuffix, "supplemental-prow-configs-filename", "_prowconfig.yaml", "Suffix for additional prow configs. Only files with this name will be considered. Deprecated and mutually exclusive with --supplemental-prow-configs-filename-suffix")
 	fs.StringVar(&o.SupplementalProwConfigsFileNameSuffix, "supplemental-prow-configs-filename-suffix", "_prowconfig.yaml", "Suffix for additional prow configs. Only files with this name will be considered")
+	fs.StringVar(&o.InRepoConfigStore, "in-repo-config-store", "", "Bucket path, like gs://bucket/inrepoconfig, s3://bucket/inrepoconfig or azblob://container/inrepoconfig, or directory to share the inrepoconfig of the repos by SHA with the other replicas and components, which then don't get it from git again.")
+	fs.StringVar(&o.InRepoConfigStoreCredentialsFile, "in-repo-config-store-credentials-file", "", "File with the GCS, S3 or Azure Blob storage credentials of --in-repo-config-store.")
+	fs.DurationVar(&o.InRepoConfigStoreTTL, "in-repo-config-store-ttl", 24*time.Hour, "How long the inrepoconfig shared in --in-repo-config-store is used before it is got from git again. Lifecycle rules of the bucket should delete it after this.")
 }
 
 func (o *ConfigOptions) Validate(_ bool) error {
 	if o.ConfigPath == "" {
 		return fmt.Errorf("--%s is mandatory", o.ConfigPathFlagName)
 	}
+	if o.InRepoConfigStore != "" && o.InRepoConfigStoreTTL <= 0 {
+		return errors.New("--in-repo-config-store-ttl must be positive")
+	}
 	return nil
 }
 
@@ -83,5 +99,18 @@ func (o *ConfigOptions) ConfigAgent(reuse ...*config.Agent) (*config.Agent, erro
 }
 
 func (o *ConfigOptions) ConfigAgentWithAdditionals(ca *config.Agent, additionals []func(*config.Config) error) (*config.Agent, error) {
+	if o.InRepoConfigStore != "" {
+		var gcsCredentialsFile, s3CredentialsFile string
+		if strings.HasPrefix(o.InRepoConfigStore, "s3://") || strings.HasPrefix(o.InRepoConfigStore, "azblob://") {
+			s3CredentialsFile = o.InRepoConfigStoreCredentialsFile
+		} else {
+			gcsCredentialsFile = o.InRepoConfigStoreCredentialsFile
+		}
+		opener, err := io.NewOpener(context.Background(), gcsCredentialsFile, s3CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the opener of --in-repo-config-store: %w", err)
+		}
+		additionals = append(additionals, config.WithInRepoConfigStore(config.NewBlobInRepoConfigStore(opener, o.InRepoConfigStore, o.InRepoConfigStoreTTL)))
+	}
 	return ca, ca.Start(o.ConfigPath, o.JobConfigPath, o.SupplementalProwConfigDirs.Strings(), o.SupplementalProwConfigsFileNameSuffix, additionals...)
 }
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flagutil
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+
+	"k8s.io/test-infra/prow/config/secret"
+	"k8s.io/test-infra/prow/gitea"
+)
+
+type GiteaOptions struct {
+	endpoint  string
+	tokenPath string
+}
+
+func (o *GiteaOptions) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.endpoint, "gitea-endpoint", "", "The Gitea or Forgejo endpoint to use, for example https://codeberg.org")
+	fs.StringVar(&o.tokenPath, "gitea-token-path", "", "Location to a file containing the Gitea access token")
+}
+
+func (o *GiteaOptions) Validate(_ bool) error {
+	if o.endpoint == "" {
+		if o.tokenPath != "" {
+			return errors.New("--gitea-token-path requires --gitea-endpoint")
+		}
+		return nil
+	}
+
+	if _, err := url.ParseRequestURI(o.endpoint); err != nil {
+		return fmt.Errorf("--gitea-endpoint %q is invalid: %w", o.endpoint, err)
+	}
+
+	if o.tokenPath == "" {
+		return errors.New("--gitea-endpoint requires --gitea-token-path")
+	}
+
+	return nil
+}
+
+// Enabled returns whether a Gitea endpoint was configured.
+func (o *GiteaOptions) Enabled() bool {
+	return o.endpoint != ""
+}
+
+func (o *GiteaOptions) Client() (gitea.Client, error) {
+	if o.endpoint == "" {
+		return nil, errors.New("empty --gitea-endpoint, can not create a client")
+	}
+
+	if err := secret.Add(o.tokenPath); err != nil {
+		return nil, fmt.Errorf("failed to get --gitea-token-path: %w", err)
+	}
+
+	return gitea.NewClient(secret.GetTokenGenerator(o.tokenPath), o.endpoint), nil
+}
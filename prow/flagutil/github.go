@@ -48,6 +48,9 @@ type GitHubOptions struct {
 	AllowDirectAccess bool
 	AppID             string
 	AppPrivateKeyPath string
+	// GitMirrorDir is a persistent directory that holds the git mirrors that
+	// git clients clone from, it may be shared with other components.
+	GitMirrorDir string
 
 	ThrottleHourlyTokens int
 	ThrottleAllowBurst   int
@@ -65,6 +68,10 @@ type GitHubOptions struct {
 	max404Retries  int
 	initialDelay   time.Duration
 	maxSleepTime   time.Duration
+	graphQLReads   bool
+	// conditionalCacheSizeMB is the size of the conditional request cache
+	// in MiB.
+	conditionalCacheSizeMB int
 }
 
 type throttlerSettings struct {
@@ -135,6 +142,7 @@ func (o *GitHubOptions) addFlags(fs *flag.FlagSet, paramFuncs ...FlagParameter)
 	fs.StringVar(&o.TokenPath, "github-token-path", defaults.TokenPath, "Path to the file containing the GitHub OAuth secret.")
 	fs.StringVar(&o.AppID, "github-app-id", defaults.AppID, "ID of the GitHub app. If set, requires --github-app-private-key-path to be set and --github-token-path to be unset.")
 	fs.StringVar(&o.AppPrivateKeyPath, "github-app-private-key-path", defaults.AppPrivateKeyPath, "Path to the private key of the github app. If set, requires --github-app-id to bet set and --github-token-path to be unset")
+	fs.StringVar(&o.GitMirrorDir, "git-mirror-dir", "", "Persistent directory for the mirrors of repos that git clients clone from, e.g. on a volume that other components mount too. Defaults to a temporary directory per process.")
 
 	if !params.disableThrottlerOptions {
 		fs.IntVar(&o.ThrottleHourlyTokens, "github-hourly-tokens", defaults.ThrottleHourlyTokens, "If set to a value larger than zero, enable client-side throttling to limit hourly token consumption. If set, --github-allowed-burst must be positive too.")
@@ -147,6 +155,8 @@ func (o *GitHubOptions) addFlags(fs *flag.FlagSet, paramFuncs ...FlagParameter)
 	fs.IntVar(&o.max404Retries, "github-client.max-404-retries", github.DefaultMax404Retries, "Maximum number of retries that will be used for a 404-ing request to the GitHub API.")
 	fs.DurationVar(&o.maxSleepTime, "github-client.backoff-timeout", github.DefaultMaxSleepTime, "Largest allowable Retry-After time for requests to the GitHub API.")
 	fs.DurationVar(&o.initialDelay, "github-client.initial-delay", github.DefaultInitialDelay, "Initial delay before retries begin for requests to the GitHub API.")
+	fs.BoolVar(&o.graphQLReads, "github-client.graphql-reads", false, "Read issue labels, pull request reviews and team members through the GraphQL API to spare the REST rate limit, falling back to REST when a query fails.")
+	fs.IntVar(&o.conditionalCacheSizeMB, "github-client.conditional-cache-size-mb", 0, "Size in MiB of the in-memory cache of GET responses that are revalidated with conditional requests, which don't count against the rate limit. Zero disables the cache.")
 }
 
 func (o *GitHubOptions) parseOrgThrottlers() error {
@@ -306,15 +316,17 @@ func (o *GitHubOptions) githubClient(dryRun bool) (github.Client, error) {
 // baseClientOptions populates client options that are derived from flags without processing
 func (o *GitHubOptions) baseClientOptions() github.ClientOptions {
 	return github.ClientOptions{
-		Censor:          secret.Censor,
-		AppID:           o.AppID,
-		GraphqlEndpoint: o.graphqlEndpoint,
-		Bases:           o.endpoint.Strings(),
-		MaxRequestTime:  o.maxRequestTime,
-		InitialDelay:    o.initialDelay,
-		MaxSleepTime:    o.maxSleepTime,
-		MaxRetries:      o.maxRetries,
-		Max404Retries:   o.max404Retries,
+		Censor:                secret.Censor,
+		AppID:                 o.AppID,
+		GraphqlEndpoint:       o.graphqlEndpoint,
+		Bases:                 o.endpoint.Strings(),
+		MaxRequestTime:        o.maxRequestTime,
+		InitialDelay:          o.initialDelay,
+		MaxSleepTime:          o.maxSleepTime,
+		MaxRetries:            o.maxRetries,
+		Max404Retries:         o.max404Retries,
+		GraphQLReads:          o.graphQLReads,
+		ConditionalCacheBytes: o.conditionalCacheSizeMB * 1024 * 1024,
 	}
 }
 
@@ -333,7 +345,11 @@ func (o *GitHubOptions) GitHubClientWithAccessToken(token string) github.Client
 
 // GitClient returns a Git client.
 func (o *GitHubOptions) GitClient(dryRun bool) (client *git.Client, err error) {
-	client, err = git.NewClientWithHost(o.Host)
+	if o.GitMirrorDir != "" {
+		client, err = git.NewClientWithMirrorDir(o.Host, o.GitMirrorDir)
+	} else {
+		client, err = git.NewClientWithHost(o.Host)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flagutil
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+
+	"k8s.io/test-infra/prow/config/secret"
+	"k8s.io/test-infra/prow/gitlab"
+)
+
+type GitLabOptions struct {
+	endpoint  string
+	tokenPath string
+}
+
+func (o *GitLabOptions) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.endpoint, "gitlab-endpoint", "", "The GitLab endpoint to use, for example https://gitlab.com")
+	fs.StringVar(&o.tokenPath, "gitlab-token-path", "", "Location to a file containing the GitLab personal access token")
+}
+
+func (o *GitLabOptions) Validate(_ bool) error {
+	if o.endpoint == "" {
+		if o.tokenPath != "" {
+			return errors.New("--gitlab-token-path requires --gitlab-endpoint")
+		}
+		return nil
+	}
+
+	if _, err := url.ParseRequestURI(o.endpoint); err != nil {
+		return fmt.Errorf("--gitlab-endpoint %q is invalid: %w", o.endpoint, err)
+	}
+
+	if o.tokenPath == "" {
+		return errors.New("--gitlab-endpoint requires --gitlab-token-path")
+	}
+
+	return nil
+}
+
+// Enabled returns whether a GitLab endpoint was configured.
+func (o *GitLabOptions) Enabled() bool {
+	return o.endpoint != ""
+}
+
+// Client returns a GitLab client, which only logs the requests that would
+// mutate GitLab in dry run.
+func (o *GitLabOptions) Client(dryRun bool) (gitlab.Client, error) {
+	if o.endpoint == "" {
+		return nil, errors.New("empty --gitlab-endpoint, can not create a client")
+	}
+
+	if err := secret.Add(o.tokenPath); err != nil {
+		return nil, fmt.Errorf("failed to get --gitlab-token-path: %w", err)
+	}
+
+	return gitlab.NewClient(secret.GetTokenGenerator(o.tokenPath), o.endpoint, dryRun), nil
+}
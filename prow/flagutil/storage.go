@@ -30,9 +30,9 @@ type StorageClientOptions struct {
 	// If set, this file is used to read/write to gs:// paths
 	// If not, credential auto-discovery is used
 	GCSCredentialsFile string `json:"gcs_credentials_file,omitempty"`
-	// S3CredentialsFile is used for reading/writing to s3 block storage.
+	// S3CredentialsFile is used for reading/writing to s3 and Azure block storage.
 	// It's optional, if you want to write to local paths or S3 credentials auto-discovery is used.
-	// If set, this file is used to read/write to s3:// paths
+	// If set, this file is used to read/write to s3:// and azblob:// paths
 	// If not, go cloud credential auto-discovery is used
 	// For more details see the prow/io/providers pkg.
 	S3CredentialsFile string `json:"s3_credentials_file,omitempty"`
@@ -41,7 +41,7 @@ type StorageClientOptions struct {
 // AddFlags injects status client options into the given FlagSet.
 func (o *StorageClientOptions) AddFlags(fs *flag.FlagSet) {
 	fs.StringVar(&o.GCSCredentialsFile, "gcs-credentials-file", "", "File where GCS credentials are stored")
-	fs.StringVar(&o.S3CredentialsFile, "s3-credentials-file", "", "File where s3 credentials are stored. For the exact format see https://github.com/kubernetes/test-infra/blob/master/prow/io/providers/providers.go")
+	fs.StringVar(&o.S3CredentialsFile, "s3-credentials-file", "", "File where s3 and Azure Blob storage credentials are stored. For the exact format see https://github.com/kubernetes/test-infra/blob/master/prow/io/providers/providers.go")
 }
 
 func (o *StorageClientOptions) HasGCSCredentials() bool {
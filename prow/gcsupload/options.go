@@ -46,6 +46,10 @@ type Options struct {
 	// SubDir is appended to the GCS path.
 	SubDir string `json:"sub_dir,omitempty"`
 
+	// ArtifactRetention assigns retention classes to the files in the
+	// directories of Items, which are set as metadata of their objects.
+	ArtifactRetention []prowapi.ArtifactRetentionClass `json:"artifact_retention,omitempty"`
+
 	*prowapi.GCSConfiguration
 
 	prowflagutil.StorageClientOptions
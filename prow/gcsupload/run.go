@@ -18,6 +18,7 @@ package gcsupload
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"mime"
 	"net/url"
@@ -63,6 +64,56 @@ func (o Options) Run(ctx context.Context, spec *downwardapi.JobSpec, extra map[s
 	return err
 }
 
+// UploadFiles uploads only the given files to the directory of the job in
+// blob storage. Unlike Run, it neither uploads the items of the options nor
+// updates the alias and latest build markers of the job, so it can be called
+// repeatedly while the job runs.
+func (o Options) UploadFiles(ctx context.Context, spec *downwardapi.JobSpec, files map[string]gcs.UploadFunc) error {
+	_, blobStoragePath, _ := PathsForJob(o.GCSConfiguration, spec, o.SubDir)
+	if o.LocalOutputDir != "" {
+		blobStoragePath = ""
+	}
+	uploadTargets := make(map[string]gcs.UploadFunc, len(files))
+	for destination, upload := range files {
+		uploadTargets[path.Join(blobStoragePath, destination)] = upload
+	}
+	return completeUpload(ctx, o, uploadTargets)
+}
+
+// AppendFiles appends the data of the given files to the files in the
+// directory of the job in blob storage, like UploadFiles. It returns
+// gcs.ErrAppendUnsupported for buckets that do not support appending.
+func (o Options) AppendFiles(ctx context.Context, spec *downwardapi.JobSpec, files map[string]gcs.UploadFunc) error {
+	_, blobStoragePath, _ := PathsForJob(o.GCSConfiguration, spec, o.SubDir)
+	if o.LocalOutputDir != "" {
+		blobStoragePath = ""
+	}
+	uploadTargets := make(map[string]gcs.UploadFunc, len(files))
+	for destination, upload := range files {
+		uploadTargets[path.Join(blobStoragePath, destination)] = upload
+	}
+
+	if o.DryRun {
+		for destination := range uploadTargets {
+			logrus.WithField("dest", destination).Info("Would append")
+		}
+		return nil
+	}
+	if o.LocalOutputDir != "" {
+		if err := gcs.LocalAppend(ctx, o.LocalOutputDir, uploadTargets); err != nil {
+			return fmt.Errorf("failed to append to files in %q: %w", o.LocalOutputDir, err)
+		}
+		return nil
+	}
+	if err := gcs.Append(ctx, o.Bucket, o.StorageClientOptions.GCSCredentialsFile, uploadTargets); err != nil {
+		if errors.Is(err, gcs.ErrAppendUnsupported) {
+			return err
+		}
+		return fmt.Errorf("failed to append to blob storage: %w", err)
+	}
+	return nil
+}
+
 func completeUpload(ctx context.Context, o Options, uploadTargets map[string]gcs.UploadFunc) error {
 	if o.DryRun {
 		for destination := range uploadTargets {
@@ -131,7 +182,7 @@ func (o Options) assembleTargets(spec *downwardapi.JobSpec, extra map[string]gcs
 			continue
 		}
 		if info.IsDir() {
-			gatherArtifacts(item, blobStoragePath, info.Name(), uploadTargets)
+			gatherArtifacts(item, blobStoragePath, info.Name(), o.ArtifactRetention, uploadTargets)
 		} else {
 			metadataFromFileName, writerOptions := gcs.WriterOptionsFromFileName(info.Name())
 			destination := path.Join(blobStoragePath, metadataFromFileName)
@@ -190,7 +241,7 @@ func builderForStrategy(strategy, defaultOrg, defaultRepo string) gcs.RepoPathBu
 	return builder
 }
 
-func gatherArtifacts(artifactDir, blobStoragePath, subDir string, uploadTargets map[string]gcs.UploadFunc) {
+func gatherArtifacts(artifactDir, blobStoragePath, subDir string, retention []prowapi.ArtifactRetentionClass, uploadTargets map[string]gcs.UploadFunc) {
 	logrus.Printf("Gathering artifacts from artifact directory: %s", artifactDir)
 	filepath.Walk(artifactDir, func(fspath string, info os.FileInfo, err error) error {
 		if info == nil || info.IsDir() {
@@ -209,6 +260,9 @@ func gatherArtifacts(artifactDir, blobStoragePath, subDir string, uploadTargets
 				logrus.Warnf("Encountered duplicate upload of %s, skipping...", destination)
 				return nil
 			}
+			if class := gcs.RetentionClass(retention, relPath); class != "" {
+				writerOptions.Metadata = map[string]string{gcs.RetentionClassMetadataKey: class}
+			}
 			logrus.Printf("Found %s in artifact directory. Uploading as %s\n", fspath, destination)
 			uploadTargets[destination] = gcs.FileUploadWithOptions(fspath, writerOptions)
 		} else {
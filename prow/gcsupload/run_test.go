@@ -17,6 +17,7 @@ limitations under the License.
 package gcsupload
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"path"
@@ -255,6 +256,46 @@ func TestOptions_AssembleTargets(t *testing.T) {
 	}
 }
 
+func TestOptions_UploadFiles(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "upload-files")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := os.Mkdir(path.Join(tmpDir, "artifacts"), 0755); err != nil {
+		t.Fatalf("could not create test directory: %v", err)
+	}
+	if err := ioutil.WriteFile(path.Join(tmpDir, "artifacts", "junit.xml"), []byte("junit"), 0644); err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+	outputDir := path.Join(tmpDir, "output")
+
+	options := Options{
+		Items: []string{path.Join(tmpDir, "artifacts")},
+		GCSConfiguration: &prowapi.GCSConfiguration{
+			PathStrategy:   prowapi.PathStrategyExplicit,
+			LocalOutputDir: outputDir,
+		},
+	}
+	spec := &downwardapi.JobSpec{Job: "job", Type: prowapi.PeriodicJob, BuildID: "build"}
+	if err := options.UploadFiles(context.Background(), spec, map[string]gcs.UploadFunc{
+		"build-log.txt": gcs.DataUpload(strings.NewReader("partial log")),
+	}); err != nil {
+		t.Fatalf("UploadFiles() failed: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(path.Join(outputDir, "build-log.txt"))
+	if err != nil {
+		t.Fatalf("could not read uploaded file: %v", err)
+	}
+	if string(data) != "partial log" {
+		t.Errorf("expected the uploaded file to contain %q, got %q", "partial log", string(data))
+	}
+	if _, err := os.Stat(path.Join(outputDir, "artifacts", "junit.xml")); !os.IsNotExist(err) {
+		t.Errorf("expected items not to be uploaded, got error %v", err)
+	}
+}
+
 func TestBuilderForStrategy(t *testing.T) {
 	type info struct {
 		org, repo string
@@ -28,6 +28,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -52,6 +53,9 @@ type Client struct {
 
 	// dir is the location of the git cache.
 	dir string
+	// shared is set if dir holds mirrors that other processes use too, see
+	// NewClientWithMirrorDir.
+	shared bool
 	// git is the path to the git binary.
 	git string
 	// base is the base path for git clone calls. For users it will be set to
@@ -71,7 +75,11 @@ type Client struct {
 }
 
 // Clean removes the local repo cache. The Client is unusable after calling.
+// Shared mirrors are left in place.
 func (c *Client) Clean() error {
+	if c.shared {
+		return nil
+	}
 	return os.RemoveAll(c.dir)
 }
 
@@ -102,6 +110,56 @@ func NewClientWithHost(host string) (*Client, error) {
 	}, nil
 }
 
+// NewClientWithMirrorDir creates a client with specified host that keeps the
+// mirrors of repos in dir instead of a temporary directory. The directory is
+// meant to be persistent and may be shared with other processes, e.g. on a
+// persistent volume that several components mount, so that they clone from
+// the same mirrors and only fetch what changed from the host. Components that
+// only read, like clonerefs, can borrow objects from the mirrors, which are
+// laid out as dir/org/repo.git.
+// Processes lock a mirror while they update it. Mirrors don't store
+// credentials and are never garbage collected, so that objects don't vanish
+// from under the repos that borrow them.
+func NewClientWithMirrorDir(host, dir string) (*Client, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create mirror dir: %w", err)
+	}
+	c, err := NewClientWithHost(host)
+	if err != nil {
+		return nil, err
+	}
+	// The temporary directory isn't needed anymore.
+	if err := os.RemoveAll(c.dir); err != nil {
+		return nil, err
+	}
+	c.dir = dir
+	c.shared = true
+	return c, nil
+}
+
+// MirrorPath returns the path of the mirror of org/repo in the mirror dir of
+// a client created with NewClientWithMirrorDir.
+func MirrorPath(dir, org, repo string) string {
+	return filepath.Join(dir, org, repo) + ".git"
+}
+
+// lockMirror locks the mirror at path against other processes and returns a
+// function that unlocks it.
+func lockMirror(path string) (func(), error) {
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock mirror: %w", err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
 // SetRemote sets the remote for the client. This is not thread-safe, and is
 // useful for testing. The client will clone from remote/org/repo, and Repo
 // objects spun out of the client will also hit that path.
@@ -167,8 +225,18 @@ func (c *Client) Clone(organization, repository string) (*Repo, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get token: %w", err)
 	}
-	cache := filepath.Join(c.dir, orgRepo) + ".git"
+	cache := MirrorPath(c.dir, organization, repository)
 	remote := remoteFromBase(c.base, user, pass, c.host, organization, repository)
+	if c.shared {
+		if err := os.MkdirAll(filepath.Dir(cache), os.ModePerm); err != nil && !os.IsExist(err) {
+			return nil, err
+		}
+		unlock, err := lockMirror(cache)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
 	if _, err := os.Stat(cache); os.IsNotExist(err) {
 		// Cache miss, clone it now.
 		c.logger.WithField("repo", orgRepo).Info("Cloning for the first time.")
@@ -178,8 +246,24 @@ func (c *Client) Clone(organization, repository string) (*Repo, error) {
 		if b, err := retryCmd(c.logger, "", c.git, "clone", "--mirror", remote, cache); err != nil {
 			return nil, fmt.Errorf("git cache clone error: %v. output: %s", err, string(b))
 		}
+		if c.shared {
+			// Other processes read the mirror, it must not hold our token.
+			if b, err := retryCmd(c.logger, cache, c.git, "remote", "set-url", "origin", remoteFromBase(c.base, "", "", c.host, organization, repository)); err != nil {
+				return nil, fmt.Errorf("updating remote url failed: %w. output: %s", err, string(b))
+			}
+			if b, err := retryCmd(c.logger, cache, c.git, "config", "gc.auto", "0"); err != nil {
+				return nil, fmt.Errorf("disabling git gc failed: %w. output: %s", err, string(b))
+			}
+		}
 	} else if err != nil {
 		return nil, err
+	} else if c.shared {
+		// Cache hit. Fetch from the remote with the credentials without storing
+		// them in the mirror.
+		c.logger.WithField("repo", orgRepo).Info("Fetching.")
+		if b, err := retryCmd(c.logger, cache, c.git, "fetch", "--prune", remote, "+refs/*:refs/*"); err != nil {
+			return nil, fmt.Errorf("git fetch error: %v. output: %s", err, string(b))
+		}
 	} else {
 		// Cache hit. Do a git fetch to keep updated.
 		// Update remote url, if we use apps auth the token changes every hour
@@ -463,15 +547,40 @@ func (r *Repo) Am(path string) error {
 	if b, abortErr := r.gitCommand("am", "--abort").CombinedOutput(); abortErr != nil {
 		r.logger.WithField("out", string(b)).WithError(abortErr).Warning("Aborting patch apply failed.")
 	}
+	return errors.New(amErrorMessage(output))
+}
+
+// amErrorMessage strips the hint about the location of the failed patch
+// from the output of `git am`.
+func amErrorMessage(output string) string {
 	applyMsg := "The copy of the patch that failed is found in: .git/rebase-apply/patch"
-	msg := ""
 	if strings.Contains(output, applyMsg) {
 		i := strings.Index(output, applyMsg)
-		msg = string(output[:i])
-	} else {
-		msg = string(output)
+		return output[:i]
+	}
+	return output
+}
+
+// Revert reverts the commitlike in a new commit. Merge commits are reverted
+// relative to their first parent.
+func (r *Repo) Revert(commitlike string) error {
+	r.logger.WithField("commitlike", commitlike).Info("Revert.")
+	b, err := r.gitCommand("rev-list", "--parents", "-n", "1", commitlike).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error listing parents of %s: %v. output: %s", commitlike, err, string(b))
 	}
-	return errors.New(msg)
+	args := []string{"revert", "--no-edit"}
+	if len(strings.Fields(string(b))) > 2 {
+		args = append(args, "-m", "1")
+	}
+	args = append(args, commitlike)
+	if b, err := r.gitCommand(args...).CombinedOutput(); err != nil {
+		if b, abortErr := r.gitCommand("revert", "--abort").CombinedOutput(); abortErr != nil {
+			r.logger.WithField("out", string(b)).WithError(abortErr).Warning("Aborting revert failed.")
+		}
+		return fmt.Errorf("error reverting %s: %v. output: %s", commitlike, err, string(b))
+	}
+	return nil
 }
 
 // Push pushes over https to the provided owner/repo#branch using a password
@@ -22,6 +22,7 @@ package git_test
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -29,6 +30,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"k8s.io/test-infra/prow/git"
 	"k8s.io/test-infra/prow/git/localgit"
 	"k8s.io/test-infra/prow/github"
 )
@@ -574,3 +576,72 @@ func testShowRef(clients localgit.Clients, t *testing.T) {
 		t.Errorf("expeted result to be %s, was %s", reference, res)
 	}
 }
+
+func TestCloneWithMirrorDir(t *testing.T) {
+	lg, c, err := localgit.New()
+	if err != nil {
+		t.Fatalf("Making local git repo: %v", err)
+	}
+	defer func() {
+		if err := lg.Clean(); err != nil {
+			t.Errorf("Error cleaning LocalGit: %v", err)
+		}
+		if err := c.Clean(); err != nil {
+			t.Errorf("Error cleaning Client: %v", err)
+		}
+	}()
+	if err := lg.MakeFakeRepo("foo", "bar"); err != nil {
+		t.Fatalf("Making fake repo: %v", err)
+	}
+	mirrorDir, err := ioutil.TempDir("", "mirrors")
+	if err != nil {
+		t.Fatalf("Making mirror dir: %v", err)
+	}
+	defer os.RemoveAll(mirrorDir)
+
+	// Two components share the mirrors.
+	var clients []*git.Client
+	for i := 0; i < 2; i++ {
+		client, err := git.NewClientWithMirrorDir("", mirrorDir)
+		if err != nil {
+			t.Fatalf("Creating client: %v", err)
+		}
+		client.SetRemote(lg.Dir)
+		clients = append(clients, client)
+	}
+
+	r1, err := clients[0].Clone("foo", "bar")
+	if err != nil {
+		t.Fatalf("Cloning the first time: %v", err)
+	}
+	defer r1.Clean()
+	if err := clients[0].Clean(); err != nil {
+		t.Fatalf("Cleaning client: %v", err)
+	}
+	mirror := git.MirrorPath(mirrorDir, "foo", "bar")
+	if _, err := os.Stat(filepath.Join(mirror, "HEAD")); err != nil {
+		t.Fatalf("Expected the mirror to outlive the client: %v", err)
+	}
+
+	if err := lg.AddCommit("foo", "bar", map[string][]byte{"second": {}}); err != nil {
+		t.Fatalf("Adding second commit: %v", err)
+	}
+	r2, err := clients[1].Clone("foo", "bar")
+	if err != nil {
+		t.Fatalf("Cloning from the shared mirror: %v", err)
+	}
+	defer r2.Clean()
+	log := exec.Command("git", "log", "--oneline")
+	log.Dir = r2.Directory()
+	if b, err := log.CombinedOutput(); err != nil {
+		t.Fatalf("git log: %v, %s", err, string(b))
+	} else if len(bytes.Split(bytes.TrimSpace(b), []byte("\n"))) != 2 {
+		t.Errorf("Wrong number of commits in git log output. Expected 2, got %s", string(b))
+	}
+
+	gc := exec.Command("git", "config", "gc.auto")
+	gc.Dir = mirror
+	if b, err := gc.CombinedOutput(); err != nil || string(bytes.TrimSpace(b)) != "0" {
+		t.Errorf("Expected git gc to be disabled in the mirror, got %q: %v", string(b), err)
+	}
+}
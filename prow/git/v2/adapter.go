@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/sirupsen/logrus"
+
 	"k8s.io/test-infra/prow/git"
 	"k8s.io/test-infra/prow/github"
 )
@@ -66,6 +68,18 @@ func (a *repoClientAdapter) MergeWithStrategy(commitlike, mergeStrategy string,
 	return a.Repo.MergeWithStrategy(commitlike, github.PullRequestMergeType(mergeStrategy))
 }
 
+// AmWithConflicts applies the patch with the implementation of the v2 client,
+// which only needs the directory of the repo.
+func (a *repoClientAdapter) AmWithConflicts(path string) (bool, error) {
+	logger := logrus.WithField("dir", a.Repo.Directory())
+	executor, err := NewCensoringExecutor(a.Repo.Directory(), func(content []byte) []byte { return content }, logger)
+	if err != nil {
+		return false, err
+	}
+	i := &interactor{executor: executor, dir: a.Repo.Directory(), logger: logger}
+	return i.AmWithConflicts(path)
+}
+
 func (a *repoClientAdapter) Clone(from string) error {
 	return errors.New("no Clone implementation exists in the v1 repo client")
 }
@@ -21,7 +21,9 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -54,6 +56,10 @@ type Interactor interface {
 	MergeAndCheckout(baseSHA string, mergeStrategy string, headSHAs ...string) error
 	// Am calls `git am`
 	Am(path string) error
+	// AmWithConflicts calls `git am`, committing conflicts with their markers instead of aborting
+	AmWithConflicts(path string) (bool, error)
+	// Revert calls `git revert`
+	Revert(commitlike string) error
 	// Fetch calls `git fetch arg...`
 	Fetch(arg ...string) error
 	// FetchRef fetches the refspec
@@ -288,7 +294,93 @@ func (i *interactor) Am(path string) error {
 	if abortOut, abortErr := i.executor.Run("am", "--abort"); err != nil {
 		i.logger.WithError(abortErr).Warningf("Aborting patch apply failed with output: %s", string(abortOut))
 	}
-	return errors.New(string(bytes.TrimPrefix(out, []byte("The copy of the patch that failed is found in: .git/rebase-apply/patch"))))
+	return errors.New(amErrorMessage(out))
+}
+
+// AmWithConflicts tries to apply the patch at the provided path like Am, but
+// when a patch does not apply cleanly the conflicting files are committed with
+// their conflict markers instead of aborting. It returns true if conflicts were
+// committed. If a patch fails for another reason, or the patches still fail
+// once all of them were committed, the apply is aborted and an error is
+// returned.
+func (i *interactor) AmWithConflicts(path string) (bool, error) {
+	i.logger.Infof("Applying patch at %s, keeping conflicts", path)
+	out, err := i.executor.Run("am", "--3way", path)
+	if err == nil {
+		return false, nil
+	}
+	i.logger.WithError(err).Infof("Patch apply failed with output: %s", string(out))
+	// Every patch conflicts at most once, which bounds the continuations.
+	patches := countPatches(path)
+	for attempt := 0; attempt < patches; attempt++ {
+		if addOut, addErr := i.executor.Run("add", "--all"); addErr != nil {
+			i.abortAm()
+			return false, fmt.Errorf("error staging conflicts: %w %v", addErr, string(addOut))
+		}
+		// Without staged changes the patch did not fail because of conflicts.
+		if _, diffErr := i.executor.Run("diff", "--cached", "--quiet"); diffErr == nil {
+			i.abortAm()
+			return false, errors.New(amErrorMessage(out))
+		}
+		out, err = i.executor.Run("am", "--continue")
+		if err == nil {
+			return true, nil
+		}
+		i.logger.WithError(err).Infof("Patch apply failed with output: %s", string(out))
+	}
+	i.abortAm()
+	return false, fmt.Errorf("patches still fail after committing the conflicts of all %d of them: %s", patches, amErrorMessage(out))
+}
+
+// patchHeader starts every patch of a mailbox written by `git format-patch`.
+var patchHeader = regexp.MustCompile(`(?m)^From [0-9a-f]{40} `)
+
+// countPatches returns the number of patches in the mailbox at the path, at
+// least one.
+func countPatches(path string) int {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 1
+	}
+	if count := len(patchHeader.FindAllIndex(content, -1)); count > 1 {
+		return count
+	}
+	return 1
+}
+
+// amErrorMessage strips the hint about the location of the failed patch
+// from the output of `git am`.
+func amErrorMessage(out []byte) string {
+	return string(bytes.TrimPrefix(out, []byte("The copy of the patch that failed is found in: .git/rebase-apply/patch")))
+}
+
+// Revert reverts the commitlike in a new commit. Merge commits are reverted
+// relative to their first parent. If the revert does not complete, it is
+// aborted and an error is returned.
+func (i *interactor) Revert(commitlike string) error {
+	i.logger.Infof("Reverting %q", commitlike)
+	out, err := i.executor.Run("rev-list", "--parents", "-n", "1", commitlike)
+	if err != nil {
+		return fmt.Errorf("error listing parents of %q: %w %v", commitlike, err, string(out))
+	}
+	args := []string{"revert", "--no-edit"}
+	if len(strings.Fields(string(out))) > 2 {
+		args = append(args, "-m", "1")
+	}
+	args = append(args, commitlike)
+	if out, err := i.executor.Run(args...); err != nil {
+		if abortOut, abortErr := i.executor.Run("revert", "--abort"); abortErr != nil {
+			i.logger.WithError(abortErr).Warningf("Aborting revert failed with output: %s", string(abortOut))
+		}
+		return fmt.Errorf("error reverting %q: %w %v", commitlike, err, string(out))
+	}
+	return nil
+}
+
+func (i *interactor) abortAm() {
+	if abortOut, abortErr := i.executor.Run("am", "--abort"); abortErr != nil {
+		i.logger.WithError(abortErr).Warningf("Aborting patch apply failed with output: %s", string(abortOut))
+	}
 }
 
 // RemoteUpdate fetches all updates from the remote.
@@ -19,6 +19,9 @@ package git
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -1027,6 +1030,268 @@ func TestInteractor_Am(t *testing.T) {
 	}
 }
 
+func TestInteractor_AmWithConflicts(t *testing.T) {
+	var testCases = []struct {
+		name              string
+		path              string
+		responses         map[string]execResponse
+		expectedCalls     [][]string
+		expectedConflicts bool
+		expectedErr       bool
+	}{
+		{
+			name: "happy case",
+			path: "my/changes.patch",
+			responses: map[string]execResponse{
+				"am --3way my/changes.patch": {
+					out: []byte(`ok`),
+				},
+			},
+			expectedCalls: [][]string{
+				{"am", "--3way", "my/changes.patch"},
+			},
+		},
+		{
+			name: "conflicts are committed",
+			path: "my/changes.patch",
+			responses: map[string]execResponse{
+				"am --3way my/changes.patch": {
+					err: errors.New("oops"),
+				},
+				"add --all": {
+					out: []byte(`ok`),
+				},
+				"diff --cached --quiet": {
+					err: errors.New("exit status 1"),
+				},
+				"am --continue": {
+					out: []byte(`ok`),
+				},
+			},
+			expectedCalls: [][]string{
+				{"am", "--3way", "my/changes.patch"},
+				{"add", "--all"},
+				{"diff", "--cached", "--quiet"},
+				{"am", "--continue"},
+			},
+			expectedConflicts: true,
+		},
+		{
+			name: "failure without conflicts is aborted",
+			path: "my/changes.patch",
+			responses: map[string]execResponse{
+				"am --3way my/changes.patch": {
+					err: errors.New("oops"),
+				},
+				"add --all": {
+					out: []byte(`ok`),
+				},
+				"diff --cached --quiet": {
+					out: []byte(``),
+				},
+				"am --abort": {
+					out: []byte(`ok`),
+				},
+			},
+			expectedCalls: [][]string{
+				{"am", "--3way", "my/changes.patch"},
+				{"add", "--all"},
+				{"diff", "--cached", "--quiet"},
+				{"am", "--abort"},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "staging fails",
+			path: "my/changes.patch",
+			responses: map[string]execResponse{
+				"am --3way my/changes.patch": {
+					err: errors.New("oops"),
+				},
+				"add --all": {
+					err: errors.New("oops"),
+				},
+				"am --abort": {
+					out: []byte(`ok`),
+				},
+			},
+			expectedCalls: [][]string{
+				{"am", "--3way", "my/changes.patch"},
+				{"add", "--all"},
+				{"am", "--abort"},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			e := fakeExecutor{
+				records:   [][]string{},
+				responses: testCase.responses,
+			}
+			i := interactor{
+				executor: &e,
+				logger:   logrus.WithField("test", testCase.name),
+			}
+			conflicts, actualErr := i.AmWithConflicts(testCase.path)
+			if testCase.expectedErr && actualErr == nil {
+				t.Errorf("%s: expected an error but got none", testCase.name)
+			}
+			if !testCase.expectedErr && actualErr != nil {
+				t.Errorf("%s: expected no error but got one: %v", testCase.name, actualErr)
+			}
+			if conflicts != testCase.expectedConflicts {
+				t.Errorf("%s: expected conflicts %v, got %v", testCase.name, testCase.expectedConflicts, conflicts)
+			}
+			if actual, expected := e.records, testCase.expectedCalls; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("%s: got incorrect git calls: %v", testCase.name, diff.ObjectReflectDiff(actual, expected))
+			}
+		})
+	}
+}
+
+func TestInteractor_AmWithConflictsIsBounded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "am")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "changes.patch")
+	patches := "From 0123456789abcdef0123456789abcdef01234567 Mon Sep 17 00:00:00 2001\nSubject: first\n\n" +
+		"From 89abcdef0123456789abcdef0123456789abcdef Mon Sep 17 00:00:00 2001\nSubject: second\n"
+	if err := ioutil.WriteFile(path, []byte(patches), 0644); err != nil {
+		t.Fatalf("could not write patches: %v", err)
+	}
+
+	e := fakeExecutor{
+		records: [][]string{},
+		responses: map[string]execResponse{
+			"am --3way " + path:     {err: errors.New("oops")},
+			"add --all":             {out: []byte(`ok`)},
+			"diff --cached --quiet": {err: errors.New("exit status 1")},
+			"am --continue":         {err: errors.New("oops")},
+			"am --abort":            {out: []byte(`ok`)},
+		},
+	}
+	i := interactor{executor: &e, logger: logrus.WithField("test", t.Name())}
+	if _, err := i.AmWithConflicts(path); err == nil {
+		t.Error("expected an error but got none")
+	}
+	expectedCalls := [][]string{
+		{"am", "--3way", path},
+		{"add", "--all"},
+		{"diff", "--cached", "--quiet"},
+		{"am", "--continue"},
+		{"add", "--all"},
+		{"diff", "--cached", "--quiet"},
+		{"am", "--continue"},
+		{"am", "--abort"},
+	}
+	if actual := e.records; !reflect.DeepEqual(actual, expectedCalls) {
+		t.Errorf("got incorrect git calls: %v", diff.ObjectReflectDiff(actual, expectedCalls))
+	}
+}
+func TestInteractor_Revert(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		commitlike    string
+		responses     map[string]execResponse
+		expectedCalls [][]string
+		expectedErr   bool
+	}{
+		{
+			name:       "happy case",
+			commitlike: "shasum",
+			responses: map[string]execResponse{
+				"rev-list --parents -n 1 shasum": {
+					out: []byte("shasum parent\n"),
+				},
+				"revert --no-edit shasum": {
+					out: []byte(`ok`),
+				},
+			},
+			expectedCalls: [][]string{
+				{"rev-list", "--parents", "-n", "1", "shasum"},
+				{"revert", "--no-edit", "shasum"},
+			},
+		},
+		{
+			name:       "merge commit is reverted relative to its first parent",
+			commitlike: "shasum",
+			responses: map[string]execResponse{
+				"rev-list --parents -n 1 shasum": {
+					out: []byte("shasum parent1 parent2\n"),
+				},
+				"revert --no-edit -m 1 shasum": {
+					out: []byte(`ok`),
+				},
+			},
+			expectedCalls: [][]string{
+				{"rev-list", "--parents", "-n", "1", "shasum"},
+				{"revert", "--no-edit", "-m", "1", "shasum"},
+			},
+		},
+		{
+			name:       "listing parents fails",
+			commitlike: "shasum",
+			responses: map[string]execResponse{
+				"rev-list --parents -n 1 shasum": {
+					err: errors.New("oops"),
+				},
+			},
+			expectedCalls: [][]string{
+				{"rev-list", "--parents", "-n", "1", "shasum"},
+			},
+			expectedErr: true,
+		},
+		{
+			name:       "revert fails and is aborted",
+			commitlike: "shasum",
+			responses: map[string]execResponse{
+				"rev-list --parents -n 1 shasum": {
+					out: []byte("shasum parent\n"),
+				},
+				"revert --no-edit shasum": {
+					err: errors.New("oops"),
+				},
+				"revert --abort": {
+					out: []byte(`ok`),
+				},
+			},
+			expectedCalls: [][]string{
+				{"rev-list", "--parents", "-n", "1", "shasum"},
+				{"revert", "--no-edit", "shasum"},
+				{"revert", "--abort"},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			e := fakeExecutor{
+				records:   [][]string{},
+				responses: testCase.responses,
+			}
+			i := interactor{
+				executor: &e,
+				logger:   logrus.WithField("test", testCase.name),
+			}
+			actualErr := i.Revert(testCase.commitlike)
+			if testCase.expectedErr && actualErr == nil {
+				t.Errorf("%s: expected an error but got none", testCase.name)
+			}
+			if !testCase.expectedErr && actualErr != nil {
+				t.Errorf("%s: expected no error but got one: %v", testCase.name, actualErr)
+			}
+			if actual, expected := e.records, testCase.expectedCalls; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("%s: got incorrect git calls: %v", testCase.name, diff.ObjectReflectDiff(actual, expected))
+			}
+		})
+	}
+}
+
 func TestInteractor_RemoteUpdate(t *testing.T) {
 	var testCases = []struct {
 		name          string
@@ -0,0 +1,375 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitea is a minimal client for the REST API of Gitea and Forgejo,
+// covering what the comment-command plugins need to act on their pull requests
+// and issues. It also validates Gitea and Forgejo webhooks, whose payloads are
+// close enough to the ones of GitHub to be decoded as GitHub events.
+package gitea
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/version"
+)
+
+// pageLimit is the number of items requested per page.
+const pageLimit = 50
+
+// Client interacts with the Gitea API. It serves the calls of the plugins the
+// way the GitHub client does: orgs are the owners of repos, numbers are the
+// indexes of pull requests and issues.
+type Client interface {
+	github.Provider
+}
+
+type client struct {
+	logger   *logrus.Entry
+	endpoint string
+	getToken func() []byte
+	client   *http.Client
+}
+
+// NewClient returns a client for the Gitea or Forgejo instance at the endpoint,
+// for example https://codeberg.org. The token is sent as an access token.
+func NewClient(getToken func() []byte, endpoint string) Client {
+	retryingClient := retryablehttp.NewClient()
+	retryingClient.Logger = nil
+	return &client{
+		logger:   logrus.WithField("client", "gitea"),
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		getToken: getToken,
+		client:   retryingClient.StandardClient(),
+	}
+}
+
+func repoPath(org, repo string) string {
+	return "/repos/" + url.PathEscape(org) + "/" + url.PathEscape(repo)
+}
+
+// request sends a request to the API with a JSON body, unless it is nil, and
+// decodes the response into target, unless it is nil.
+func (c *client) request(method, path string, values url.Values, body, target interface{}) error {
+	logger := c.logger.WithFields(logrus.Fields{"method": method, "path": path})
+	u := c.endpoint + "/api/v1" + path
+	if len(values) > 0 {
+		u += "?" + values.Encode()
+	}
+	var reqBody io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reqBody = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if token := strings.TrimSpace(string(c.getToken())); token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.WithError(err).Warn("could not close response body")
+		}
+	}()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	logger.WithField("response", resp.StatusCode).Debug("Got response from Gitea.")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var msg struct {
+			Message string `json:"message"`
+		}
+		message := string(raw)
+		if err := json.Unmarshal(raw, &msg); err == nil && msg.Message != "" {
+			message = msg.Message
+		}
+		return &RequestError{StatusCode: resp.StatusCode, Message: message}
+	}
+	if target != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, target); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+	return nil
+}
+
+// paginate requests the pages of a list until one isn't full. newPage returns
+// the target a page is decoded into, and the function collecting the decoded
+// page and returning its length.
+func (c *client) paginate(path string, newPage func() (interface{}, func() int)) error {
+	values := url.Values{}
+	values.Set("limit", strconv.Itoa(pageLimit))
+	for page := 1; ; page++ {
+		values.Set("page", strconv.Itoa(page))
+		target, length := newPage()
+		if err := c.request(http.MethodGet, path, values, nil, target); err != nil {
+			return err
+		}
+		if length() < pageLimit {
+			return nil
+		}
+	}
+}
+
+// RequestError is returned when Gitea answers a request with an error.
+type RequestError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("code %d: %s", e.StatusCode, e.Message)
+}
+
+func isNotFound(err error) bool {
+	reqErr, ok := err.(*RequestError)
+	return ok && reqErr.StatusCode == http.StatusNotFound
+}
+
+// BotUserChecker returns a function checking whether a login is the one of the
+// user the client authenticates as.
+func (c *client) BotUserChecker() (func(candidate string) bool, error) {
+	var user github.User
+	if err := c.request(http.MethodGet, "/user", nil, nil, &user); err != nil {
+		return nil, fmt.Errorf("fetching the current user from Gitea: %w", err)
+	}
+	return func(candidate string) bool {
+		return github.NormLogin(candidate) == github.NormLogin(user.Login)
+	}, nil
+}
+
+// IsCollaborator returns whether the user is a collaborator of the repo.
+func (c *client) IsCollaborator(org, repo, user string) (bool, error) {
+	err := c.request(http.MethodGet, repoPath(org, repo)+"/collaborators/"+url.PathEscape(github.NormLogin(user)), nil, nil, nil)
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+type commentOptions struct {
+	Body string `json:"body"`
+}
+
+// CreateComment comments on a pull request or an issue.
+func (c *client) CreateComment(org, repo string, number int, comment string) error {
+	return c.request(http.MethodPost, fmt.Sprintf("%s/issues/%d/comments", repoPath(org, repo), number), nil, commentOptions{Body: comment}, nil)
+}
+
+// EditComment changes the body of a comment.
+func (c *client) EditComment(org, repo string, id int, comment string) error {
+	return c.request(http.MethodPatch, fmt.Sprintf("%s/issues/comments/%d", repoPath(org, repo), id), nil, commentOptions{Body: comment}, nil)
+}
+
+// DeleteComment deletes a comment.
+func (c *client) DeleteComment(org, repo string, id int) error {
+	return c.request(http.MethodDelete, fmt.Sprintf("%s/issues/comments/%d", repoPath(org, repo), id), nil, nil, nil)
+}
+
+// ListIssueComments lists the comments of a pull request or an issue.
+func (c *client) ListIssueComments(org, repo string, number int) ([]github.IssueComment, error) {
+	var comments []github.IssueComment
+	err := c.request(http.MethodGet, fmt.Sprintf("%s/issues/%d/comments", repoPath(org, repo), number), nil, nil, &comments)
+	return comments, err
+}
+
+type label struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (c *client) listLabels(path string) ([]label, error) {
+	var labels []label
+	err := c.paginate(path, func() (interface{}, func() int) {
+		var page []label
+		return &page, func() int {
+			labels = append(labels, page...)
+			return len(page)
+		}
+	})
+	return labels, err
+}
+
+// labelID returns the ID of a label of the repo or of its org. Gitea only
+// takes label IDs.
+func (c *client) labelID(org, repo, name string) (int, error) {
+	labels, err := c.listLabels(repoPath(org, repo) + "/labels")
+	if err != nil {
+		return 0, err
+	}
+	orgLabels, err := c.listLabels("/orgs/" + url.PathEscape(org) + "/labels")
+	// Repos owned by users have no org labels.
+	if err != nil && !isNotFound(err) {
+		return 0, err
+	}
+	for _, l := range append(labels, orgLabels...) {
+		if strings.EqualFold(l.Name, name) {
+			return l.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("label %q not found in %s/%s", name, org, repo)
+}
+
+// AddLabel adds an existing label to a pull request or an issue.
+func (c *client) AddLabel(org, repo string, number int, name string) error {
+	id, err := c.labelID(org, repo, name)
+	if err != nil {
+		return err
+	}
+	body := struct {
+		Labels []int `json:"labels"`
+	}{Labels: []int{id}}
+	return c.request(http.MethodPost, fmt.Sprintf("%s/issues/%d/labels", repoPath(org, repo), number), nil, body, nil)
+}
+
+// RemoveLabel removes a label from a pull request or an issue.
+func (c *client) RemoveLabel(org, repo string, number int, name string) error {
+	id, err := c.labelID(org, repo, name)
+	if err != nil {
+		return err
+	}
+	return c.request(http.MethodDelete, fmt.Sprintf("%s/issues/%d/labels/%d", repoPath(org, repo), number, id), nil, nil, nil)
+}
+
+// GetIssueLabels returns the labels of a pull request or an issue.
+func (c *client) GetIssueLabels(org, repo string, number int) ([]github.Label, error) {
+	var labels []github.Label
+	err := c.request(http.MethodGet, fmt.Sprintf("%s/issues/%d/labels", repoPath(org, repo), number), nil, nil, &labels)
+	return labels, err
+}
+
+// updateAssignees adds or removes the logins from the assignees of a pull
+// request or an issue. Gitea only takes the complete list of assignees.
+func (c *client) updateAssignees(org, repo string, number int, logins []string, add bool) error {
+	path := fmt.Sprintf("%s/issues/%d", repoPath(org, repo), number)
+	var issue github.Issue
+	if err := c.request(http.MethodGet, path, nil, nil, &issue); err != nil {
+		return err
+	}
+	changed := map[string]bool{}
+	for _, login := range logins {
+		changed[github.NormLogin(login)] = true
+	}
+	assignees := []string{}
+	for _, assignee := range issue.Assignees {
+		if add || !changed[github.NormLogin(assignee.Login)] {
+			assignees = append(assignees, assignee.Login)
+		}
+		delete(changed, github.NormLogin(assignee.Login))
+	}
+	if add {
+		for _, login := range logins {
+			if changed[github.NormLogin(login)] {
+				assignees = append(assignees, github.NormLogin(login))
+			}
+		}
+	}
+	body := struct {
+		Assignees []string `json:"assignees"`
+	}{Assignees: assignees}
+	return c.request(http.MethodPatch, path, nil, body, nil)
+}
+
+// AssignIssue assigns the logins to a pull request or an issue.
+func (c *client) AssignIssue(org, repo string, number int, logins []string) error {
+	return c.updateAssignees(org, repo, number, logins, true)
+}
+
+// UnassignIssue unassigns the logins from a pull request or an issue.
+func (c *client) UnassignIssue(org, repo string, number int, logins []string) error {
+	return c.updateAssignees(org, repo, number, logins, false)
+}
+
+type reviewersOptions struct {
+	Reviewers []string `json:"reviewers"`
+}
+
+func normLogins(logins []string) []string {
+	var normed []string
+	for _, login := range logins {
+		normed = append(normed, github.NormLogin(login))
+	}
+	return normed
+}
+
+// RequestReview requests the review of the logins on a pull request.
+func (c *client) RequestReview(org, repo string, number int, logins []string) error {
+	return c.request(http.MethodPost, fmt.Sprintf("%s/pulls/%d/requested_reviewers", repoPath(org, repo), number), nil, reviewersOptions{Reviewers: normLogins(logins)}, nil)
+}
+
+// UnrequestReview withdraws the review requests of the logins on a pull request.
+func (c *client) UnrequestReview(org, repo string, number int, logins []string) error {
+	return c.request(http.MethodDelete, fmt.Sprintf("%s/pulls/%d/requested_reviewers", repoPath(org, repo), number), nil, reviewersOptions{Reviewers: normLogins(logins)}, nil)
+}
+
+// GetPullRequest returns a pull request.
+func (c *client) GetPullRequest(org, repo string, number int) (*github.PullRequest, error) {
+	var pr github.PullRequest
+	err := c.request(http.MethodGet, fmt.Sprintf("%s/pulls/%d", repoPath(org, repo), number), nil, nil, &pr)
+	return &pr, err
+}
+
+// fileStatuses maps the statuses of the files changed by pull requests to the
+// ones of GitHub.
+var fileStatuses = map[string]string{
+	"deleted": github.PullRequestFileRemoved,
+	"changed": string(github.PullRequestFileModified),
+}
+
+// GetPullRequestChanges returns the files changed by a pull request.
+func (c *client) GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error) {
+	var changes []github.PullRequestChange
+	err := c.paginate(fmt.Sprintf("%s/pulls/%d/files", repoPath(org, repo), number), func() (interface{}, func() int) {
+		var page []github.PullRequestChange
+		return &page, func() int {
+			changes = append(changes, page...)
+			return len(page)
+		}
+	})
+	for i := range changes {
+		if status, ok := fileStatuses[changes[i].Status]; ok {
+			changes[i].Status = status
+		}
+	}
+	return changes, err
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitea
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIsCollaborator(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token abc" {
+			t.Errorf("unexpected authorization header %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/api/v1/repos/org/repo/collaborators/alice":
+			w.WriteHeader(http.StatusNoContent)
+		case "/api/v1/repos/org/repo/collaborators/bob":
+			http.Error(w, `{"message": "not found"}`, http.StatusNotFound)
+		default:
+			http.Error(w, `{"message": "boom"}`, http.StatusInternalServerError)
+		}
+	}))
+	defer s.Close()
+	c := NewClient(func() []byte { return []byte("abc\n") }, s.URL+"/")
+
+	for user, expected := range map[string]bool{"alice": true, "@Bob": false} {
+		if actual, err := c.IsCollaborator("org", "repo", user); err != nil || actual != expected {
+			t.Errorf("expected %s to be a collaborator: %t, got %t (%v)", user, expected, actual, err)
+		}
+	}
+	if _, err := c.IsCollaborator("org", "repo", "carol"); err == nil {
+		t.Error("expected an error for a server error")
+	}
+}
+
+func TestAddLabel(t *testing.T) {
+	var added []int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/repos/org/repo/labels":
+			w.Write([]byte(`[{"id": 1, "name": "bug"}]`))
+		case "/api/v1/orgs/org/labels":
+			w.Write([]byte(`[{"id": 2, "name": "lgtm"}]`))
+		case "/api/v1/repos/org/repo/issues/3/labels":
+			if r.Method != http.MethodPost {
+				t.Errorf("expected a POST, got %s", r.Method)
+			}
+			raw, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+			var body struct {
+				Labels []int `json:"labels"`
+			}
+			if err := json.Unmarshal(raw, &body); err != nil {
+				t.Fatalf("failed to unmarshal body: %v", err)
+			}
+			added = append(added, body.Labels...)
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+	c := NewClient(func() []byte { return nil }, s.URL)
+
+	if err := c.AddLabel("org", "repo", 3, "LGTM"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.AddLabel("org", "repo", 3, "missing"); err == nil {
+		t.Error("expected an error for a missing label")
+	}
+	if diff := cmp.Diff([]int{2}, added); diff != "" {
+		t.Errorf("added labels differ from expected: %s", diff)
+	}
+}
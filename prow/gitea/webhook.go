@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitea
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+// Actions of pull request events that differ from the ones of GitHub.
+const (
+	PullRequestActionSynchronized = "synchronized"
+	PullRequestActionLabelUpdated = "label_updated"
+	PullRequestActionLabelCleared = "label_cleared"
+	PullRequestActionReviewed     = "reviewed"
+)
+
+// header returns the value of a header Forgejo sends with the X-Forgejo-
+// prefix, falling back to the X-Gitea- prefix Gitea and older Forgejo
+// versions use.
+func header(r *http.Request, name string) string {
+	if v := r.Header.Get("X-Forgejo-" + name); v != "" {
+		return v
+	}
+	return r.Header.Get("X-Gitea-" + name)
+}
+
+// ValidateWebhook ensures that the provided request conforms to the format of a
+// Gitea or Forgejo webhook and the payload can be validated with the provided
+// secret. It returns the event type, the event guid, the payload of the
+// request, whether the webhook is valid or not, and finally the resultant HTTP
+// status code. The event types are the ones of GitHub, like pull_request or
+// issue_comment.
+func ValidateWebhook(w http.ResponseWriter, r *http.Request, tokenGenerator func() []byte) (string, string, []byte, bool, int) {
+	defer r.Body.Close()
+
+	if r.Method != http.MethodPost {
+		responseHTTPError(w, http.StatusMethodNotAllowed, "405 Method not allowed")
+		return "", "", nil, false, http.StatusMethodNotAllowed
+	}
+	eventType := header(r, "Event")
+	if eventType == "" {
+		responseHTTPError(w, http.StatusBadRequest, "400 Bad Request: Missing X-Gitea-Event Header")
+		return "", "", nil, false, http.StatusBadRequest
+	}
+	eventGUID := header(r, "Delivery")
+	if eventGUID == "" {
+		responseHTTPError(w, http.StatusBadRequest, "400 Bad Request: Missing X-Gitea-Delivery Header")
+		return "", "", nil, false, http.StatusBadRequest
+	}
+	sig := header(r, "Signature")
+	if sig == "" {
+		responseHTTPError(w, http.StatusForbidden, "403 Forbidden: Missing X-Gitea-Signature")
+		return "", "", nil, false, http.StatusForbidden
+	}
+	if contentType := r.Header.Get("content-type"); contentType != "application/json" {
+		responseHTTPError(w, http.StatusBadRequest, "400 Bad Request: Hook only accepts content-type: application/json - please reconfigure this hook on Gitea")
+		return "", "", nil, false, http.StatusBadRequest
+	}
+	payload, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responseHTTPError(w, http.StatusInternalServerError, "500 Internal Server Error: Failed to read request body")
+		return "", "", nil, false, http.StatusInternalServerError
+	}
+	if !ValidatePayload(payload, sig, tokenGenerator) {
+		responseHTTPError(w, http.StatusForbidden, "403 Forbidden: Invalid X-Gitea-Signature")
+		return "", "", nil, false, http.StatusForbidden
+	}
+
+	return eventType, eventGUID, payload, true, http.StatusOK
+}
+
+// ValidatePayload ensures that the request payload signature matches the
+// secret. Gitea signs payloads with HMAC-SHA256 and sends the hex digest.
+func ValidatePayload(payload []byte, sig string, tokenGenerator func() []byte) bool {
+	expected, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(strings.TrimSpace(string(tokenGenerator()))))
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// PayloadSignature returns the signature of a payload, as Gitea sends it in
+// the X-Gitea-Signature header.
+func PayloadSignature(payload []byte, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func responseHTTPError(w http.ResponseWriter, statusCode int, response string) {
+	logrus.WithFields(logrus.Fields{
+		"response":    response,
+		"status-code": statusCode,
+	}).Debug(response)
+	http.Error(w, response, statusCode)
+}
+
+// PullRequestAction returns the action of the GitHub pull request event a Gitea
+// pull request event corresponds to, or "" if there is none. Gitea doesn't say
+// which labels changed and reviews have their own events on GitHub.
+func PullRequestAction(action string) github.PullRequestEventAction {
+	switch action {
+	case PullRequestActionSynchronized:
+		return github.PullRequestActionSynchronize
+	case PullRequestActionLabelUpdated, PullRequestActionLabelCleared, PullRequestActionReviewed:
+		return ""
+	}
+	return github.PullRequestEventAction(action)
+}
+
+// IssueAction returns the action of the GitHub issue event a Gitea issue event
+// corresponds to, or "" if there is none.
+func IssueAction(action string) github.IssueEventAction {
+	switch action {
+	case PullRequestActionLabelUpdated, PullRequestActionLabelCleared:
+		return ""
+	}
+	return github.IssueEventAction(action)
+}
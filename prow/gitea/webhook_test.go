@@ -0,0 +1,122 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitea
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+func TestValidateWebhook(t *testing.T) {
+	payload := []byte(`{"action": "opened"}`)
+	tokenGenerator := func() []byte { return []byte("secret\n") }
+	testCases := []struct {
+		name     string
+		headers  map[string]string
+		expected int
+	}{
+		{
+			name: "valid Gitea webhook",
+			headers: map[string]string{
+				"X-Gitea-Event":     "pull_request",
+				"X-Gitea-Delivery":  "guid",
+				"X-Gitea-Signature": PayloadSignature(payload, []byte("secret")),
+			},
+			expected: http.StatusOK,
+		},
+		{
+			name: "valid Forgejo webhook",
+			headers: map[string]string{
+				"X-Forgejo-Event":     "pull_request",
+				"X-Forgejo-Delivery":  "guid",
+				"X-Forgejo-Signature": PayloadSignature(payload, []byte("secret")),
+			},
+			expected: http.StatusOK,
+		},
+		{
+			name: "missing event",
+			headers: map[string]string{
+				"X-Gitea-Delivery":  "guid",
+				"X-Gitea-Signature": PayloadSignature(payload, []byte("secret")),
+			},
+			expected: http.StatusBadRequest,
+		},
+		{
+			name: "missing signature",
+			headers: map[string]string{
+				"X-Gitea-Event":    "pull_request",
+				"X-Gitea-Delivery": "guid",
+			},
+			expected: http.StatusForbidden,
+		},
+		{
+			name: "wrong secret",
+			headers: map[string]string{
+				"X-Gitea-Event":     "pull_request",
+				"X-Gitea-Delivery":  "guid",
+				"X-Gitea-Signature": PayloadSignature(payload, []byte("other")),
+			},
+			expected: http.StatusForbidden,
+		},
+		{
+			name: "signature not in hex",
+			headers: map[string]string{
+				"X-Gitea-Event":     "pull_request",
+				"X-Gitea-Delivery":  "guid",
+				"X-Gitea-Signature": "sha256=zz",
+			},
+			expected: http.StatusForbidden,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/hook/gitea", bytes.NewReader(payload))
+			r.Header.Set("content-type", "application/json")
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			eventType, guid, body, ok, code := ValidateWebhook(w, r, tokenGenerator)
+			if code != tc.expected {
+				t.Fatalf("expected status %d, got %d", tc.expected, code)
+			}
+			if ok != (tc.expected == http.StatusOK) {
+				t.Fatalf("expected the webhook to be valid: %t, got %t", tc.expected == http.StatusOK, ok)
+			}
+			if ok && (eventType != "pull_request" || guid != "guid" || !bytes.Equal(body, payload)) {
+				t.Errorf("unexpected event %q, guid %q or payload %q", eventType, guid, body)
+			}
+		})
+	}
+}
+
+func TestPullRequestAction(t *testing.T) {
+	for action, expected := range map[string]github.PullRequestEventAction{
+		"opened":        github.PullRequestActionOpened,
+		"synchronized":  github.PullRequestActionSynchronize,
+		"label_updated": "",
+		"reviewed":      "",
+	} {
+		if actual := PullRequestAction(action); actual != expected {
+			t.Errorf("%s: expected action %q, got %q", action, expected, actual)
+		}
+	}
+}
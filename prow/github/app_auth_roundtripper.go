@@ -34,6 +34,11 @@ import (
 
 const (
 	githubOrgHeaderKey = "X-PROW-GITHUB-ORG"
+
+	// installationTokenRefreshMargin is how long before they expire installation
+	// tokens are replaced. Tokens are handed out for git operations too, so they
+	// must stay valid for as long as a fetch or push may take.
+	installationTokenRefreshMargin = 10 * time.Minute
 )
 
 type appGitHubClient interface {
@@ -48,11 +53,13 @@ type appsRoundTripper struct {
 	appSlugLock      sync.Mutex
 	privateKey       func() *rsa.PrivateKey
 	installationLock sync.RWMutex
-	installations    map[string]AppInstallation
-	tokenLock        sync.RWMutex
-	tokens           map[int64]*AppInstallationToken
-	upstream         http.RoundTripper
-	githubClient     appGitHubClient
+	// installations are the installations of the app by the lowercased login
+	// of the org or user they were installed for.
+	installations map[string]AppInstallation
+	tokenLock     sync.RWMutex
+	tokens        map[int64]*AppInstallationToken
+	upstream      http.RoundTripper
+	githubClient  appGitHubClient
 }
 
 // appsAuthError is returned by the appsRoundTripper if any issues were encountered
@@ -121,8 +128,23 @@ func extractOrgFromContext(ctx context.Context) string {
 	return org
 }
 
+// extractOrgFromPath returns the owner of the repo or the org that the path
+// of a REST request points to, or "" if it points to neither.
+func extractOrgFromPath(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) < 2 || (parts[0] != "repos" && parts[0] != "orgs") {
+		return ""
+	}
+	return parts[1]
+}
+
 func (arr *appsRoundTripper) addAppInstallationAuth(r *http.Request) *appsAuthError {
 	org := extractOrgFromContext(r.Context())
+	if org == "" {
+		// Requests for a repo or an org are authorized by its installation even
+		// if the caller didn't pass the org along.
+		org = extractOrgFromPath(r.URL.Path)
+	}
 	if org == "" {
 		return &appsAuthError{fmt.Errorf("BUG apps auth requested but empty org, please report this to the test-infra repo. Stack: %s", string(debug.Stack()))}
 	}
@@ -153,6 +175,14 @@ func (arr *appsRoundTripper) installationTokenFor(org string) (string, time.Time
 	}
 
 	token, expiresAt, err := arr.getTokenForInstallation(installationID)
+	if IsNotFound(err) {
+		// The app was uninstalled, or reinstalled with a new installation id.
+		arr.forgetInstallation(org, installationID)
+		if installationID, err = arr.installationIDFor(org); err != nil {
+			return "", time.Time{}, fmt.Errorf("failed to get installation id for org %s: %w", org, err)
+		}
+		token, expiresAt, err = arr.getTokenForInstallation(installationID)
+	}
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("failed to get an installation token for org %s: %w", org, err)
 	}
@@ -166,6 +196,9 @@ func (arr *appsRoundTripper) installationTokenFor(org string) (string, time.Time
 // property.
 // Ref: https://docs.github.com/en/free-pro-team@latest/rest/reference/apps#list-installations-for-the-authenticated-app
 func (arr *appsRoundTripper) installationIDFor(org string) (int64, error) {
+	// Logins are case-insensitive, but orgs in the config may be spelled
+	// differently than GitHub returns them.
+	org = strings.ToLower(org)
 	arr.installationLock.RLock()
 	id, found := arr.installations[org]
 	arr.installationLock.RUnlock()
@@ -189,7 +222,7 @@ func (arr *appsRoundTripper) installationIDFor(org string) (int64, error) {
 
 	installationsMap := make(map[string]AppInstallation, len(installations))
 	for _, installation := range installations {
-		installationsMap[installation.Account.Login] = installation
+		installationsMap[strings.ToLower(installation.Account.Login)] = installation
 	}
 
 	if equal := reflect.DeepEqual(arr.installations, installationsMap); equal {
@@ -205,12 +238,34 @@ func (arr *appsRoundTripper) installationIDFor(org string) (int64, error) {
 	return id.ID, nil
 }
 
+// forgetInstallation removes the installation of the org and its token from
+// the cache, so that the installations are listed again on the next request
+// for the org.
+func (arr *appsRoundTripper) forgetInstallation(org string, installation int64) {
+	org = strings.ToLower(org)
+	arr.installationLock.Lock()
+	if id, found := arr.installations[org]; found && id.ID == installation {
+		delete(arr.installations, org)
+	}
+	arr.installationLock.Unlock()
+
+	arr.tokenLock.Lock()
+	delete(arr.tokens, installation)
+	arr.tokenLock.Unlock()
+}
+
+// tokenIsFresh returns whether the token is valid for longer than the
+// refresh margin.
+func tokenIsFresh(token *AppInstallationToken) bool {
+	return token.ExpiresAt.Add(-installationTokenRefreshMargin).After(TimeNow())
+}
+
 func (arr *appsRoundTripper) getTokenForInstallation(installation int64) (string, time.Time, error) {
 	arr.tokenLock.RLock()
 	token, found := arr.tokens[installation]
 	arr.tokenLock.RUnlock()
 
-	if found && token.ExpiresAt.Add(-time.Minute).After(time.Now()) {
+	if found && tokenIsFresh(token) {
 		return token.Token, token.ExpiresAt, nil
 	}
 
@@ -219,7 +274,7 @@ func (arr *appsRoundTripper) getTokenForInstallation(installation int64) (string
 
 	// Check again in case a concurrent routine got a token while we waited for the lock
 	token, found = arr.tokens[installation]
-	if found && token.ExpiresAt.Add(-time.Minute).After(time.Now()) {
+	if found && tokenIsFresh(token) {
 		return token.Token, token.ExpiresAt, nil
 	}
 
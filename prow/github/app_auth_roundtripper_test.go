@@ -422,6 +422,142 @@ func TestAppsRoundTripperThreadSafety(t *testing.T) {
 	<-req2Done
 }
 
+type fakeAppGitHubClient struct {
+	installations []AppInstallation
+	// tokens are the tokens minted for installations, installations without
+	// one are not found.
+	tokens       map[int64]*AppInstallationToken
+	listed       int
+	tokensMinted int
+}
+
+func (f *fakeAppGitHubClient) ListAppInstallations() ([]AppInstallation, error) {
+	f.listed++
+	return f.installations, nil
+}
+
+func (f *fakeAppGitHubClient) getAppInstallationToken(installationID int64) (*AppInstallationToken, error) {
+	token, found := f.tokens[installationID]
+	if !found {
+		return nil, requestError{StatusCode: http.StatusNotFound, ErrorString: "status code 404 not one of [201], body: "}
+	}
+	f.tokensMinted++
+	return token, nil
+}
+
+func (f *fakeAppGitHubClient) GetApp() (*App, error) {
+	return &App{Slug: "ci-app"}, nil
+}
+
+func TestInstallationTokenFor(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name                string
+		org                 string
+		cachedInstallations map[string]AppInstallation
+		cachedTokens        map[int64]*AppInstallationToken
+		installations       []AppInstallation
+		tokens              map[int64]*AppInstallationToken
+		expectedToken       string
+		expectedErr         bool
+		expectedListed      int
+		expectedMinted      int
+	}{
+		{
+			name:                "cached token is used",
+			org:                 "org",
+			cachedInstallations: map[string]AppInstallation{"org": {ID: 1}},
+			cachedTokens:        map[int64]*AppInstallationToken{1: {Token: "cached", ExpiresAt: now.Add(time.Hour)}},
+			expectedToken:       "cached",
+		},
+		{
+			name:                "token that expires within the refresh margin is replaced",
+			org:                 "org",
+			cachedInstallations: map[string]AppInstallation{"org": {ID: 1}},
+			cachedTokens:        map[int64]*AppInstallationToken{1: {Token: "cached", ExpiresAt: now.Add(5 * time.Minute)}},
+			tokens:              map[int64]*AppInstallationToken{1: {Token: "fresh", ExpiresAt: now.Add(time.Hour)}},
+			expectedToken:       "fresh",
+			expectedMinted:      1,
+		},
+		{
+			name:           "installation is found regardless of the case of the org",
+			org:            "Org",
+			installations:  []AppInstallation{{ID: 1, Account: User{Login: "ORG"}}},
+			tokens:         map[int64]*AppInstallationToken{1: {Token: "fresh", ExpiresAt: now.Add(time.Hour)}},
+			expectedToken:  "fresh",
+			expectedListed: 1,
+			expectedMinted: 1,
+		},
+		{
+			name:                "reinstalled app is found again",
+			org:                 "org",
+			cachedInstallations: map[string]AppInstallation{"org": {ID: 1}},
+			cachedTokens:        map[int64]*AppInstallationToken{1: {Token: "cached", ExpiresAt: now.Add(time.Minute)}},
+			installations:       []AppInstallation{{ID: 2, Account: User{Login: "org"}}},
+			tokens:              map[int64]*AppInstallationToken{2: {Token: "fresh", ExpiresAt: now.Add(time.Hour)}},
+			expectedToken:       "fresh",
+			expectedListed:      1,
+			expectedMinted:      1,
+		},
+		{
+			name:                "uninstalled app is an error",
+			org:                 "org",
+			cachedInstallations: map[string]AppInstallation{"org": {ID: 1}, "other-org": {ID: 3}},
+			installations:       []AppInstallation{{ID: 3, Account: User{Login: "other-org"}}},
+			expectedErr:         true,
+			expectedListed:      1,
+		},
+	}
+
+	oldTimeNow := TimeNow
+	defer func() { TimeNow = oldTimeNow }()
+	TimeNow = func() time.Time { return now }
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ghClient := &fakeAppGitHubClient{installations: tc.installations, tokens: tc.tokens}
+			arr := &appsRoundTripper{
+				installations: tc.cachedInstallations,
+				tokens:        tc.cachedTokens,
+				githubClient:  ghClient,
+			}
+			token, _, err := arr.installationTokenFor(tc.org)
+			if err != nil && !tc.expectedErr {
+				t.Fatalf("Didn't expect error: %v", err)
+			}
+			if err == nil && tc.expectedErr {
+				t.Fatal("Expected an error, got none")
+			}
+			if token != tc.expectedToken {
+				t.Errorf("expected token %q, got %q", tc.expectedToken, token)
+			}
+			if ghClient.listed != tc.expectedListed {
+				t.Errorf("expected installations to be listed %d times, were listed %d times", tc.expectedListed, ghClient.listed)
+			}
+			if ghClient.tokensMinted != tc.expectedMinted {
+				t.Errorf("expected %d tokens to be minted, %d were", tc.expectedMinted, ghClient.tokensMinted)
+			}
+		})
+	}
+}
+
+func TestExtractOrgFromPath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/repos/org/repo/issues/1", expected: "org"},
+		{path: "/orgs/org/members", expected: "org"},
+		{path: "/users/someone", expected: ""},
+		{path: "/repos", expected: ""},
+	}
+	for _, tc := range testCases {
+		if actual := extractOrgFromPath(tc.path); actual != tc.expected {
+			t.Errorf("expected org of path %s to be %q, got %q", tc.path, tc.expected, actual)
+		}
+	}
+}
+
 func serializeOrDie(in interface{}) io.ReadCloser {
 	rawData, err := json.Marshal(in)
 	if err != nil {
@@ -128,6 +128,7 @@ type PullRequestClient interface {
 	EditPullRequest(org, repo string, number int, pr *PullRequest) (*PullRequest, error)
 	GetPullRequestPatch(org, repo string, number int) ([]byte, error)
 	CreatePullRequest(org, repo, title, body, head, base string, canModify bool) (int, error)
+	CreateDraftPullRequest(org, repo, title, body, head, base string, canModify bool) (int, error)
 	UpdatePullRequest(org, repo string, number int, title, body *string, open *bool, branch *string, canModify *bool) error
 	GetPullRequestChanges(org, repo string, number int) ([]PullRequestChange, error)
 	ListPullRequestComments(org, repo string, number int) ([]ReviewComment, error)
@@ -150,8 +151,11 @@ type CommitClient interface {
 	CreateStatusWithContext(ctx context.Context, org, repo, SHA string, s Status) error
 	ListStatuses(org, repo, ref string) ([]Status, error)
 	GetSingleCommit(org, repo, SHA string) (RepositoryCommit, error)
+	CompareCommits(org, repo, base, head string) (*CommitComparison, error)
 	GetCombinedStatus(org, repo, ref string) (*CombinedStatus, error)
 	ListCheckRuns(org, repo, ref string) (*CheckRunList, error)
+	CreateCheckRun(org, repo string, checkRun CheckRun) error
+	UpdateCheckRun(org, repo string, checkRunID int64, checkRun CheckRun) error
 	GetRef(org, repo, ref string) (string, error)
 	DeleteRef(org, repo, ref string) error
 	ListFileCommits(org, repo, path string) ([]RepositoryCommit, error)
@@ -165,6 +169,11 @@ type RepositoryClient interface {
 	GetBranchProtection(org, repo, branch string) (*BranchProtection, error)
 	RemoveBranchProtection(org, repo, branch string) error
 	UpdateBranchProtection(org, repo, branch string, config BranchProtectionRequest) error
+	ListRepoRulesets(org, repo string) ([]Ruleset, error)
+	GetRepoRuleset(org, repo string, id int) (*Ruleset, error)
+	CreateRepoRuleset(org, repo string, ruleset Ruleset) (*Ruleset, error)
+	UpdateRepoRuleset(org, repo string, id int, ruleset Ruleset) error
+	DeleteRepoRuleset(org, repo string, id int) error
 	AddRepoLabel(org, repo, label, description, color string) error
 	UpdateRepoLabel(org, repo, label, newName, description, color string) error
 	DeleteRepoLabel(org, repo, label string) error
@@ -185,6 +194,13 @@ type RepositoryClient interface {
 	ListRepoTeams(org, repo string) ([]Team, error)
 	CreateRepo(owner string, isUser bool, repo RepoCreateRequest) (*FullRepo, error)
 	UpdateRepo(owner, name string, repo RepoUpdateRequest) (*FullRepo, error)
+	ReplaceRepoTopics(org, repo string, topics []string) error
+	GetVulnerabilityAlerts(org, repo string) (bool, error)
+	SetVulnerabilityAlerts(org, repo string, enabled bool) error
+	GetRepoPages(org, repo string) (*RepoPages, error)
+	CreateRepoPages(org, repo string, pages RepoPages) error
+	UpdateRepoPages(org, repo string, pages RepoPages) error
+	DeleteRepoPages(org, repo string) error
 }
 
 // TeamClient interface for team related API actions
@@ -223,6 +239,7 @@ type UserClient interface {
 	BotUserChecker() (func(candidate string) bool, error)
 	BotUserCheckerWithContext(ctx context.Context) (func(candidate string) bool, error)
 	Email() (string, error)
+	UserExists(login string) (bool, error)
 }
 
 // ProjectClient interface for project related API actions
@@ -280,6 +297,7 @@ type Client interface {
 	WithFields(fields logrus.Fields) Client
 	ForPlugin(plugin string) Client
 	ForSubcomponent(subcomponent string) Client
+	ForDryRun() Client
 	Used() bool
 	TriggerGitHubWorkflow(org, repo string, id int) error
 }
@@ -294,6 +312,9 @@ type client struct {
 	identifier string
 	gqlc       gqlClient
 	used       bool
+	// dryRun makes this client, but not others sharing its delegate,
+	// log mutating requests instead of sending them.
+	dryRun bool
 	*delegate
 }
 
@@ -311,6 +332,7 @@ type delegate struct {
 	dry          bool
 	fake         bool
 	usesAppsAuth bool
+	graphQLReads bool
 	throttle     throttler
 	getToken     func() []byte
 	censor       func([]byte) []byte
@@ -342,10 +364,23 @@ func (c *client) ForSubcomponent(subcomponent string) Client {
 	return c.forKeyValue("subcomponent", subcomponent)
 }
 
+// ForDryRun clones the client, keeping the underlying delegate the same but
+// logging mutating requests instead of sending them to GitHub.
+func (c *client) ForDryRun() Client {
+	return &client{
+		logger:     c.logger.WithField("dry-run", true),
+		identifier: c.identifier,
+		gqlc:       c.gqlc,
+		dryRun:     true,
+		delegate:   c.delegate,
+	}
+}
+
 func (c *client) forKeyValue(key, value string) Client {
 	newClient := &client{
 		identifier: value,
 		logger:     c.logger.WithField(key, value),
+		dryRun:     c.dryRun,
 		delegate:   c.delegate,
 	}
 	newClient.gqlc = c.gqlc.forUserAgent(newClient.userAgent())
@@ -366,10 +401,17 @@ func (c *client) WithFields(fields logrus.Fields) Client {
 		logger:     c.logger.WithFields(fields),
 		identifier: c.identifier,
 		gqlc:       c.gqlc,
+		dryRun:     c.dryRun,
 		delegate:   c.delegate,
 	}
 }
 
+// isDry returns whether mutating requests should be skipped, either because
+// the whole client is in dry-run mode or because this clone is.
+func (c *client) isDry() bool {
+	return c.dry || c.dryRun
+}
+
 var (
 	teamRe = regexp.MustCompile(`^(.*)/(.*)$`)
 )
@@ -639,6 +681,14 @@ type ClientOptions struct {
 	MaxRetries, Max404Retries                  int
 
 	DryRun bool
+	// GraphQLReads reads issue labels, pull request reviews and team members
+	// through the GraphQL API instead of REST, falling back to REST when a
+	// query fails.
+	GraphQLReads bool
+	// ConditionalCacheBytes is how many bytes of response bodies the client
+	// keeps to revalidate GET requests with conditional requests, which don't
+	// count against the rate limit. Zero disables the cache.
+	ConditionalCacheBytes int
 	// BaseRoundTripper is the last RoundTripper to be called. Used for testing, gets defaulted to http.DefaultTransport
 	BaseRoundTripper http.RoundTripper
 }
@@ -734,6 +784,7 @@ func NewClientFromOptions(fields logrus.Fields, options ClientOptions) (TokenGen
 			censor:        options.Censor,
 			dry:           options.DryRun,
 			usesAppsAuth:  options.AppID != "",
+			graphQLReads:  options.GraphQLReads,
 			maxRetries:    options.MaxRetries,
 			max404Retries: options.Max404Retries,
 			initialDelay:  options.InitialDelay,
@@ -776,6 +827,9 @@ func NewClientFromOptions(fields logrus.Fields, options ClientOptions) (TokenGen
 			return user.Login, nil
 		}
 	}
+	if options.ConditionalCacheBytes > 0 {
+		httpClient.Transport = newConditionalCache(httpClient.Transport, options.ConditionalCacheBytes)
+	}
 
 	return tokenGenerator, userGenerator, c
 }
@@ -1006,7 +1060,10 @@ func (c *client) requestRaw(r *request) (int, []byte, error) {
 }
 
 func (c *client) requestRawWithContext(ctx context.Context, r *request) (int, []byte, error) {
-	if c.fake || (c.dry && r.method != http.MethodGet) {
+	if c.fake || (c.isDry() && r.method != http.MethodGet) {
+		if c.dryRun && !c.fake && c.logger != nil {
+			c.logger.WithFields(logrus.Fields{"method": r.method, "path": r.path, "body": r.requestBody}).Info("Dry run: not sending request to GitHub.")
+		}
 		return r.exitCodes[0], nil, nil
 	}
 	resp, err := c.requestRetryWithContext(ctx, r.method, r.path, r.accept, r.org, r.requestBody)
@@ -1348,6 +1405,22 @@ func (c *client) Email() (string, error) {
 	return c.userData.Email, nil
 }
 
+// UserExists returns whether a user or an organization with the login exists.
+//
+// See https://docs.github.com/en/rest/users/users#get-a-user
+func (c *client) UserExists(login string) (bool, error) {
+	c.log("UserExists", login)
+	code, err := c.request(&request{
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("/users/%s", login),
+		exitCodes: []int{200, 404},
+	}, nil)
+	if err != nil {
+		return false, err
+	}
+	return code == 200, nil
+}
+
 // IsMember returns whether or not the user is a member of the org.
 //
 // See https://developer.github.com/v3/orgs/members/#check-membership
@@ -1417,7 +1490,7 @@ func (c *client) ListRepoHooks(org, repo string) ([]Hook, error) {
 }
 
 func (c *client) editHook(org string, repo *string, id int, req HookRequest) error {
-	if c.dry {
+	if c.isDry() {
 		return nil
 	}
 	var path string
@@ -1452,7 +1525,7 @@ func (c *client) EditOrgHook(org string, id int, req HookRequest) error {
 }
 
 func (c *client) createHook(org string, repo *string, req HookRequest) (int, error) {
-	if c.dry {
+	if c.isDry() {
 		return -1, nil
 	}
 	var path string
@@ -1490,7 +1563,7 @@ func (c *client) CreateRepoHook(org, repo string, req HookRequest) (int, error)
 }
 
 func (c *client) deleteHook(org, path string) error {
-	if c.dry {
+	if c.isDry() {
 		return nil
 	}
 
@@ -1542,7 +1615,7 @@ func (c *client) GetOrg(name string) (*Organization, error) {
 // https://developer.github.com/v3/orgs/#edit-an-organization
 func (c *client) EditOrg(name string, config Organization) (*Organization, error) {
 	c.log("EditOrg", name, config)
-	if c.dry {
+	if c.isDry() {
 		return &config, nil
 	}
 	var retOrg Organization
@@ -1765,7 +1838,7 @@ func (c *client) UpdateOrgMembership(org, user string, admin bool) (*OrgMembersh
 	} else {
 		om.Role = RoleMember
 	}
-	if c.dry {
+	if c.isDry() {
 		return &om, nil
 	}
 
@@ -2201,7 +2274,7 @@ func (c *client) EditPullRequest(org, repo string, number int, pr *PullRequest)
 	durationLogger := c.log("EditPullRequest", org, repo, number)
 	defer durationLogger()
 
-	if c.dry {
+	if c.isDry() {
 		return pr, nil
 	}
 	edit := struct {
@@ -2254,7 +2327,7 @@ func (c *client) EditIssue(org, repo string, number int, issue *Issue) (*Issue,
 	durationLogger := c.log("EditIssue", org, repo, number)
 	defer durationLogger()
 
-	if c.dry {
+	if c.isDry() {
 		return issue, nil
 	}
 	edit := struct {
@@ -2305,6 +2378,21 @@ func (c *client) CreatePullRequest(org, repo, title, body, head, base string, ca
 	durationLogger := c.log("CreatePullRequest", org, repo, title)
 	defer durationLogger()
 
+	return c.createPullRequest(org, repo, title, body, head, base, canModify, false)
+}
+
+// CreateDraftPullRequest creates a new draft pull request and returns its
+// number if the creation is successful, otherwise any error that is encountered.
+//
+// See https://developer.github.com/v3/pulls/#create-a-pull-request
+func (c *client) CreateDraftPullRequest(org, repo, title, body, head, base string, canModify bool) (int, error) {
+	durationLogger := c.log("CreateDraftPullRequest", org, repo, title)
+	defer durationLogger()
+
+	return c.createPullRequest(org, repo, title, body, head, base, canModify, true)
+}
+
+func (c *client) createPullRequest(org, repo, title, body, head, base string, canModify, draft bool) (int, error) {
 	data := struct {
 		Title string `json:"title"`
 		Body  string `json:"body"`
@@ -2313,6 +2401,7 @@ func (c *client) CreatePullRequest(org, repo, title, body, head, base string, ca
 		// MaintainerCanModify allows maintainers of the repo to modify this
 		// pull request, eg. push changes to it before merging.
 		MaintainerCanModify bool `json:"maintainer_can_modify"`
+		Draft               bool `json:"draft,omitempty"`
 	}{
 		Title: title,
 		Body:  body,
@@ -2320,6 +2409,7 @@ func (c *client) CreatePullRequest(org, repo, title, body, head, base string, ca
 		Base:  base,
 
 		MaintainerCanModify: canModify,
+		Draft:               draft,
 	}
 	var resp struct {
 		Num int `json:"number"`
@@ -2453,6 +2543,13 @@ func (c *client) ListReviews(org, repo string, number int) ([]Review, error) {
 	if c.fake {
 		return nil, nil
 	}
+	if c.graphQLReads {
+		reviews, err := c.graphQLReviews(org, repo, number)
+		if err == nil {
+			return reviews, nil
+		}
+		c.fallBackToREST("ListReviews", err)
+	}
 	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews", org, repo, number)
 	var reviews []Review
 	err := c.readPaginatedResults(
@@ -2543,7 +2640,7 @@ func (c *client) CreateRepo(owner string, isUser bool, repo RepoCreateRequest) (
 	}
 	if c.fake {
 		return nil, nil
-	} else if c.dry {
+	} else if c.isDry() {
 		return repo.ToRepo(), nil
 	}
 
@@ -2570,7 +2667,7 @@ func (c *client) UpdateRepo(owner, name string, repo RepoUpdateRequest) (*FullRe
 
 	if c.fake {
 		return nil, nil
-	} else if c.dry {
+	} else if c.isDry() {
 		return repo.ToRepo(), nil
 	}
 
@@ -2586,6 +2683,143 @@ func (c *client) UpdateRepo(owner, name string, repo RepoUpdateRequest) (*FullRe
 	return &retRepo, err
 }
 
+// ReplaceRepoTopics replaces all topics of a repository.
+//
+// See https://docs.github.com/en/rest/reference/repos#replace-all-repository-topics
+func (c *client) ReplaceRepoTopics(org, repo string, topics []string) error {
+	durationLogger := c.log("ReplaceRepoTopics", org, repo, topics)
+	defer durationLogger()
+
+	if topics == nil {
+		// GitHub requires the list, even when it removes all topics.
+		topics = []string{}
+	}
+	_, err := c.request(&request{
+		method:      http.MethodPut,
+		path:        fmt.Sprintf("/repos/%s/%s/topics", org, repo),
+		org:         org,
+		requestBody: map[string][]string{"names": topics},
+		exitCodes:   []int{200},
+	}, nil)
+	return err
+}
+
+// GetVulnerabilityAlerts returns whether vulnerability alerts are enabled for a repository.
+//
+// See https://docs.github.com/en/rest/reference/repos#check-if-vulnerability-alerts-are-enabled-for-a-repository
+func (c *client) GetVulnerabilityAlerts(org, repo string) (bool, error) {
+	durationLogger := c.log("GetVulnerabilityAlerts", org, repo)
+	defer durationLogger()
+
+	code, err := c.request(&request{
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("/repos/%s/%s/vulnerability-alerts", org, repo),
+		org:       org,
+		exitCodes: []int{204, 404},
+	}, nil)
+	if err != nil {
+		return false, err
+	}
+	return code == 204, nil
+}
+
+// SetVulnerabilityAlerts enables or disables vulnerability alerts for a repository.
+//
+// See https://docs.github.com/en/rest/reference/repos#enable-vulnerability-alerts
+func (c *client) SetVulnerabilityAlerts(org, repo string, enabled bool) error {
+	durationLogger := c.log("SetVulnerabilityAlerts", org, repo, enabled)
+	defer durationLogger()
+
+	method := http.MethodDelete
+	if enabled {
+		method = http.MethodPut
+	}
+	_, err := c.request(&request{
+		method:    method,
+		path:      fmt.Sprintf("/repos/%s/%s/vulnerability-alerts", org, repo),
+		org:       org,
+		exitCodes: []int{204},
+	}, nil)
+	return err
+}
+
+// GetRepoPages returns the GitHub Pages site of a repository, or nil when
+// Pages are disabled.
+//
+// See https://docs.github.com/en/rest/reference/repos#get-a-github-pages-site
+func (c *client) GetRepoPages(org, repo string) (*RepoPages, error) {
+	durationLogger := c.log("GetRepoPages", org, repo)
+	defer durationLogger()
+
+	code, body, err := c.requestRaw(&request{
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("/repos/%s/%s/pages", org, repo),
+		org:       org,
+		exitCodes: []int{200, 404},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if code == 404 {
+		return nil, nil
+	}
+	var pages RepoPages
+	if err := json.Unmarshal(body, &pages); err != nil {
+		return nil, err
+	}
+	return &pages, nil
+}
+
+// CreateRepoPages enables the GitHub Pages site of a repository.
+//
+// See https://docs.github.com/en/rest/reference/repos#create-a-github-pages-site
+func (c *client) CreateRepoPages(org, repo string, pages RepoPages) error {
+	durationLogger := c.log("CreateRepoPages", org, repo, pages)
+	defer durationLogger()
+
+	_, err := c.request(&request{
+		method:      http.MethodPost,
+		path:        fmt.Sprintf("/repos/%s/%s/pages", org, repo),
+		org:         org,
+		requestBody: map[string]RepoPagesSource{"source": pages.Source},
+		exitCodes:   []int{201},
+	}, nil)
+	return err
+}
+
+// UpdateRepoPages updates the GitHub Pages site of a repository.
+//
+// See https://docs.github.com/en/rest/reference/repos#update-information-about-a-github-pages-site
+func (c *client) UpdateRepoPages(org, repo string, pages RepoPages) error {
+	durationLogger := c.log("UpdateRepoPages", org, repo, pages)
+	defer durationLogger()
+
+	_, err := c.request(&request{
+		method:      http.MethodPut,
+		path:        fmt.Sprintf("/repos/%s/%s/pages", org, repo),
+		org:         org,
+		requestBody: &pages,
+		exitCodes:   []int{204},
+	}, nil)
+	return err
+}
+
+// DeleteRepoPages disables the GitHub Pages site of a repository.
+//
+// See https://docs.github.com/en/rest/reference/repos#delete-a-github-pages-site
+func (c *client) DeleteRepoPages(org, repo string) error {
+	durationLogger := c.log("DeleteRepoPages", org, repo)
+	defer durationLogger()
+
+	_, err := c.request(&request{
+		method:    http.MethodDelete,
+		path:      fmt.Sprintf("/repos/%s/%s/pages", org, repo),
+		org:       org,
+		exitCodes: []int{204},
+	}, nil)
+	return err
+}
+
 // GetRepos returns all repos in an org.
 //
 // This call uses multiple API tokens when results are paginated.
@@ -2641,6 +2875,27 @@ func (c *client) GetSingleCommit(org, repo, SHA string) (RepositoryCommit, error
 	return commit, err
 }
 
+// CompareCommits compares the head commit with the base commit, telling how
+// many commits of each are missing from the other.
+//
+// See https://docs.github.com/en/rest/commits/commits#compare-two-commits
+func (c *client) CompareCommits(org, repo, base, head string) (*CommitComparison, error) {
+	durationLogger := c.log("CompareCommits", org, repo, base, head)
+	defer durationLogger()
+
+	var comparison CommitComparison
+	_, err := c.request(&request{
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("/repos/%s/%s/compare/%s...%s", org, repo, base, head),
+		org:       org,
+		exitCodes: []int{200},
+	}, &comparison)
+	if err != nil {
+		return nil, err
+	}
+	return &comparison, nil
+}
+
 // GetBranches returns all branches in the repo.
 //
 // If onlyProtected is true it will only return repos with protection enabled,
@@ -2757,6 +3012,109 @@ func (c *client) UpdateBranchProtection(org, repo, branch string, config BranchP
 	return err
 }
 
+// ListRepoRulesets returns the rulesets of a repository. They don't include
+// their rules, use GetRepoRuleset for those.
+//
+// See https://docs.github.com/en/rest/repos/rules#get-all-repository-rulesets
+func (c *client) ListRepoRulesets(org, repo string) ([]Ruleset, error) {
+	durationLogger := c.log("ListRepoRulesets", org, repo)
+	defer durationLogger()
+
+	if c.fake {
+		return nil, nil
+	}
+	var rulesets []Ruleset
+	err := c.readPaginatedResults(
+		fmt.Sprintf("/repos/%s/%s/rulesets", org, repo),
+		acceptNone,
+		org,
+		func() interface{} {
+			return &[]Ruleset{}
+		},
+		func(obj interface{}) {
+			rulesets = append(rulesets, *(obj.(*[]Ruleset))...)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return rulesets, nil
+}
+
+// GetRepoRuleset returns a ruleset of a repository with its rules.
+//
+// See https://docs.github.com/en/rest/repos/rules#get-a-repository-ruleset
+func (c *client) GetRepoRuleset(org, repo string, id int) (*Ruleset, error) {
+	durationLogger := c.log("GetRepoRuleset", org, repo, id)
+	defer durationLogger()
+
+	var ruleset Ruleset
+	_, err := c.request(&request{
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("/repos/%s/%s/rulesets/%d", org, repo, id),
+		org:       org,
+		exitCodes: []int{200},
+	}, &ruleset)
+	if err != nil {
+		return nil, err
+	}
+	return &ruleset, nil
+}
+
+// CreateRepoRuleset creates a ruleset in a repository.
+//
+// See https://docs.github.com/en/rest/repos/rules#create-a-repository-ruleset
+func (c *client) CreateRepoRuleset(org, repo string, ruleset Ruleset) (*Ruleset, error) {
+	durationLogger := c.log("CreateRepoRuleset", org, repo, ruleset)
+	defer durationLogger()
+
+	var created Ruleset
+	_, err := c.request(&request{
+		method:      http.MethodPost,
+		path:        fmt.Sprintf("/repos/%s/%s/rulesets", org, repo),
+		org:         org,
+		requestBody: &ruleset,
+		exitCodes:   []int{201},
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateRepoRuleset replaces a ruleset of a repository.
+//
+// See https://docs.github.com/en/rest/repos/rules#update-a-repository-ruleset
+func (c *client) UpdateRepoRuleset(org, repo string, id int, ruleset Ruleset) error {
+	durationLogger := c.log("UpdateRepoRuleset", org, repo, id, ruleset)
+	defer durationLogger()
+
+	_, err := c.request(&request{
+		method:      http.MethodPut,
+		path:        fmt.Sprintf("/repos/%s/%s/rulesets/%d", org, repo, id),
+		org:         org,
+		requestBody: &ruleset,
+		exitCodes:   []int{200},
+	}, nil)
+	return err
+}
+
+// DeleteRepoRuleset deletes a ruleset of a repository.
+//
+// See https://docs.github.com/en/rest/repos/rules#delete-a-repository-ruleset
+func (c *client) DeleteRepoRuleset(org, repo string, id int) error {
+	durationLogger := c.log("DeleteRepoRuleset", org, repo, id)
+	defer durationLogger()
+
+	_, err := c.request(&request{
+		method:    http.MethodDelete,
+		path:      fmt.Sprintf("/repos/%s/%s/rulesets/%d", org, repo, id),
+		org:       org,
+		exitCodes: []int{204},
+	}, nil)
+	return err
+}
+
 // AddRepoLabel adds a defined label given org/repo
 //
 // See https://developer.github.com/v3/issues/labels/#create-a-label
@@ -2875,6 +3233,13 @@ func (c *client) GetIssueLabels(org, repo string, number int) ([]Label, error) {
 	durationLogger := c.log("GetIssueLabels", org, repo, number)
 	defer durationLogger()
 
+	if c.graphQLReads && !c.fake {
+		labels, err := c.graphQLIssueLabels(org, repo, number)
+		if err == nil {
+			return labels, nil
+		}
+		c.fallBackToREST("GetIssueLabels", err)
+	}
 	return c.getLabels(fmt.Sprintf("/repos/%s/%s/issues/%d/labels", org, repo, number), org)
 }
 
@@ -3555,6 +3920,12 @@ func (c *client) QueryWithGitHubAppsSupport(ctx context.Context, q interface{},
 
 // MutateWithGitHubAppsSupport runs a GraphQL mutation using shurcooL/githubql's client.
 func (c *client) MutateWithGitHubAppsSupport(ctx context.Context, m interface{}, input githubql.Input, vars map[string]interface{}, org string) error {
+	if c.dryRun {
+		if c.logger != nil {
+			c.logger.WithField("input", input).Info("Dry run: not sending GraphQL mutation to GitHub.")
+		}
+		return nil
+	}
 	return c.gqlc.MutateWithGitHubAppsSupport(ctx, m, input, vars, org)
 }
 
@@ -3570,7 +3941,7 @@ func (c *client) CreateTeam(org string, team Team) (*Team, error) {
 	}
 	if c.fake {
 		return nil, nil
-	} else if c.dry {
+	} else if c.isDry() {
 		return &team, nil
 	}
 	path := fmt.Sprintf("/orgs/%s/teams", org)
@@ -3599,7 +3970,7 @@ func (c *client) EditTeam(org string, t Team) (*Team, error) {
 	if t.Slug == "" {
 		return nil, errors.New("team.Slug must be populated")
 	}
-	if c.dry {
+	if c.isDry() {
 		return &t, nil
 	}
 	t.ID = 0
@@ -3720,7 +4091,7 @@ func (c *client) UpdateTeamMembership(org string, id int, user string, maintaine
 		tm.Role = RoleMember
 	}
 
-	if c.dry {
+	if c.isDry() {
 		return &tm, nil
 	}
 
@@ -3758,7 +4129,7 @@ func (c *client) UpdateTeamMembershipBySlug(org, teamSlug, user string, maintain
 		tm.Role = RoleMember
 	}
 
-	if c.dry {
+	if c.isDry() {
 		return &tm, nil
 	}
 
@@ -3871,6 +4242,13 @@ func (c *client) ListTeamMembersBySlug(org, teamSlug, role string) ([]TeamMember
 	if c.fake {
 		return nil, nil
 	}
+	if c.graphQLReads {
+		members, err := c.graphQLTeamMembers(org, teamSlug, role)
+		if err == nil {
+			return members, nil
+		}
+		c.fallBackToREST("ListTeamMembersBySlug", err)
+	}
 	path := fmt.Sprintf("/orgs/%s/teams/%s/members", org, teamSlug)
 	var teamMembers []TeamMember
 	err := c.readPaginatedResultsWithValues(
@@ -3997,7 +4375,7 @@ func (c *client) UpdateTeamRepo(id int, org, repo string, permission TeamPermiss
 	durationLogger := c.log("UpdateTeamRepo", id, org, repo, permission)
 	defer durationLogger()
 
-	if c.fake || c.dry {
+	if c.fake || c.isDry() {
 		return nil
 	}
 
@@ -4029,7 +4407,7 @@ func (c *client) UpdateTeamRepoBySlug(org, teamSlug, repo string, permission Tea
 	durationLogger := c.log("UpdateTeamRepoBySlug", org, teamSlug, repo, permission)
 	defer durationLogger()
 
-	if c.fake || c.dry {
+	if c.fake || c.isDry() {
 		return nil
 	}
 
@@ -4059,7 +4437,7 @@ func (c *client) RemoveTeamRepo(id int, org, repo string) error {
 	durationLogger := c.log("RemoveTeamRepo", id, org, repo)
 	defer durationLogger()
 
-	if c.fake || c.dry {
+	if c.fake || c.isDry() {
 		return nil
 	}
 
@@ -4084,7 +4462,7 @@ func (c *client) RemoveTeamRepoBySlug(org, teamSlug, repo string) error {
 	durationLogger := c.log("RemoveTeamRepoBySlug", org, teamSlug, repo)
 	defer durationLogger()
 
-	if c.fake || c.dry {
+	if c.fake || c.isDry() {
 		return nil
 	}
 
@@ -4748,7 +5126,7 @@ func (c *client) CreateProjectCard(org string, columnID int, projectCard Project
 	if (projectCard.ContentType != "Issue") && (projectCard.ContentType != "PullRequest") {
 		return nil, errors.New("projectCard.ContentType must be either Issue or PullRequest")
 	}
-	if c.dry {
+	if c.isDry() {
 		return &projectCard, nil
 	}
 	path := fmt.Sprintf("/projects/columns/%d/cards", columnID)
@@ -4925,6 +5303,42 @@ func (c *client) ListCheckRuns(org, repo, ref string) (*CheckRunList, error) {
 	return &checkRunList, nil
 }
 
+// CreateCheckRun creates a new check run for the head SHA of the check run
+//
+// See https://docs.github.com/en/rest/reference/checks#create-a-check-run
+func (c *client) CreateCheckRun(org, repo string, checkRun CheckRun) error {
+	durationLogger := c.log("CreateCheckRun", org, repo, checkRun)
+	defer durationLogger()
+
+	_, err := c.request(&request{
+		accept:      "application/vnd.github.antiope-preview+json",
+		method:      http.MethodPost,
+		path:        fmt.Sprintf("/repos/%s/%s/check-runs", org, repo),
+		org:         org,
+		requestBody: &checkRun,
+		exitCodes:   []int{201},
+	}, nil)
+	return err
+}
+
+// UpdateCheckRun updates an existing check run
+//
+// See https://docs.github.com/en/rest/reference/checks#update-a-check-run
+func (c *client) UpdateCheckRun(org, repo string, checkRunID int64, checkRun CheckRun) error {
+	durationLogger := c.log("UpdateCheckRun", org, repo, checkRunID, checkRun)
+	defer durationLogger()
+
+	_, err := c.request(&request{
+		accept:      "application/vnd.github.antiope-preview+json",
+		method:      http.MethodPatch,
+		path:        fmt.Sprintf("/repos/%s/%s/check-runs/%d", org, repo, checkRunID),
+		org:         org,
+		requestBody: &checkRun,
+		exitCodes:   []int{200},
+	}, nil)
+	return err
+}
+
 // ListAppInstallations lists the installations for the current app. Will not work with
 // a Personal Access Token.
 //
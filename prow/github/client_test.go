@@ -67,6 +67,7 @@ func getClient(url string) *client {
 	logger.SetLevel(logrus.DebugLevel)
 	return &client{
 		logger: logrus.NewEntry(logger),
+		gqlc:   &graphQLGitHubAppsAuthClientWrapper{},
 		delegate: &delegate{
 			time:     &testTime{},
 			throttle: throttler{throttlerDelegate: &throttlerDelegate{}},
@@ -286,6 +287,27 @@ func TestCreateComment(t *testing.T) {
 	}
 }
 
+func TestCreateCommentForDryRun(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Dry-run client sent a %s request to %s", r.Method, r.URL.Path)
+		}
+		http.Error(w, "404 Not Found", http.StatusNotFound)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	dryRunClient := c.ForDryRun().ForPlugin("lgtm")
+	if err := dryRunClient.CreateComment("k8s", "kuber", 5, "hello"); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+	if err := dryRunClient.AddLabel("k8s", "kuber", 5, "lgtm"); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+	if c.isDry() {
+		t.Error("Expected the original client not to be in dry-run mode")
+	}
+}
+
 func TestCreateCommentCensored(t *testing.T) {
 	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -2862,6 +2884,147 @@ func TestUpdateRepo(t *testing.T) {
 	}
 }
 
+func TestReplaceRepoTopics(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/org/repo/topics" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		var body map[string][]string
+		if err := json.Unmarshal(b, &body); err != nil {
+			t.Errorf("Could not unmarshal request: %v", err)
+		} else if names, ok := body["names"]; !ok || len(names) != 0 {
+			t.Errorf("Expected an empty list of names, got %s", string(b))
+		}
+		fmt.Fprint(w, `{"names": []}`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.ReplaceRepoTopics("org", "repo", nil); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+}
+
+func TestGetVulnerabilityAlerts(t *testing.T) {
+	for _, tc := range []struct {
+		statusCode int
+		expected   bool
+	}{
+		{statusCode: http.StatusNoContent, expected: true},
+		{statusCode: http.StatusNotFound, expected: false},
+	} {
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				t.Errorf("Bad method: %s", r.Method)
+			}
+			if r.URL.Path != "/repos/org/repo/vulnerability-alerts" {
+				t.Errorf("Bad request path: %s", r.URL.Path)
+			}
+			w.WriteHeader(tc.statusCode)
+		}))
+		c := getClient(ts.URL)
+		enabled, err := c.GetVulnerabilityAlerts("org", "repo")
+		if err != nil {
+			t.Errorf("Didn't expect error: %v", err)
+		} else if enabled != tc.expected {
+			t.Errorf("Expected vulnerability alerts enabled to be %t for status %d, got %t", tc.expected, tc.statusCode, enabled)
+		}
+		ts.Close()
+	}
+}
+
+func TestGetRepoPages(t *testing.T) {
+	ts := simpleTestServer(t, "/repos/org/repo/pages", RepoPages{Source: RepoPagesSource{Branch: "gh-pages", Path: "/"}}, http.StatusOK)
+	defer ts.Close()
+	c := getClient(ts.URL)
+	pages, err := c.GetRepoPages("org", "repo")
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if expected := (&RepoPages{Source: RepoPagesSource{Branch: "gh-pages", Path: "/"}}); !reflect.DeepEqual(pages, expected) {
+		t.Errorf("Pages differ from expected:\n%s", diff.ObjectReflectDiff(expected, pages))
+	}
+
+	disabled := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+	}))
+	defer disabled.Close()
+	c = getClient(disabled.URL)
+	if pages, err := c.GetRepoPages("org", "repo"); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if pages != nil {
+		t.Errorf("Expected no pages, got %+v", pages)
+	}
+}
+
+func TestCreateRepoRuleset(t *testing.T) {
+	ruleset := Ruleset{
+		Name:        "branchprotector: master",
+		Target:      RulesetTargetBranch,
+		Enforcement: RulesetEnforcementActive,
+		Conditions:  &RulesetConditions{RefName: RulesetRefNameCondition{Include: []string{"refs/heads/master"}, Exclude: []string{}}},
+		Rules:       []RulesetRule{{Type: RulesetRuleDeletion}},
+	}
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/org/repo/rulesets" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		var got Ruleset
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("Could not unmarshal request: %v", err)
+		}
+		if !reflect.DeepEqual(got, ruleset) {
+			t.Errorf("Ruleset differs from expected:\n%s", diff.ObjectReflectDiff(ruleset, got))
+		}
+		got.ID = 42
+		b, err = json.Marshal(got)
+		if err != nil {
+			t.Fatalf("Didn't expect error: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, string(b))
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	created, err := c.CreateRepoRuleset("org", "repo", ruleset)
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if created.ID != 42 {
+		t.Errorf("Expected ruleset 42, got %d", created.ID)
+	}
+}
+
+func TestDeleteRepoRuleset(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/org/repo/rulesets/42" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		http.Error(w, "204 No Content", http.StatusNoContent)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.DeleteRepoRuleset("org", "repo", 42); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
 type fakeHttpClient struct {
 	received []*http.Request
 }
@@ -0,0 +1,198 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	conditionalCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "github_client_conditional_cache_requests",
+		Help: "Number of GET requests made through the conditional request cache of the GitHub client by outcome: hit (revalidated, served from the cache), miss or uncacheable.",
+	}, []string{"outcome"})
+	conditionalCacheBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "github_client_conditional_cache_bytes",
+		Help: "Size of the bodies held by the conditional request caches of GitHub clients.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(conditionalCacheRequests)
+	prometheus.MustRegister(conditionalCacheBytes)
+}
+
+const (
+	conditionalCacheHit         = "hit"
+	conditionalCacheMiss        = "miss"
+	conditionalCacheUncacheable = "uncacheable"
+)
+
+// conditionalCacheEntry is a cached response to a GET request.
+type conditionalCacheEntry struct {
+	key          string
+	etag         string
+	lastModified string
+	header       http.Header
+	body         []byte
+}
+
+// conditionalCache is a RoundTripper that remembers the responses to GET
+// requests that carry an ETag or Last-Modified header and revalidates them
+// with a conditional request the next time. GitHub doesn't count responses
+// with status 304 against the rate limit, so re-reading unchanged resources
+// like the comments of an issue is free. It complements ghproxy for
+// components that don't use it, or whose responses ghproxy evicted.
+// The least recently used responses are evicted once the bodies exceed
+// maxBytes.
+type conditionalCache struct {
+	upstream http.RoundTripper
+	maxBytes int
+
+	lock    sync.Mutex
+	size    int
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+func newConditionalCache(upstream http.RoundTripper, maxBytes int) *conditionalCache {
+	return &conditionalCache{
+		upstream: upstream,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+// conditionalCacheKey identifies the response to a request. Responses differ
+// by the credentials they are read with, so those are part of the key.
+func conditionalCacheKey(r *http.Request) string {
+	auth := sha256.Sum256([]byte(r.Header.Get("Authorization")))
+	return fmt.Sprintf("%s\x00%s\x00%x\x00%s", r.URL.String(), r.Header.Get("Accept"), auth, extractOrgFromContext(r.Context()))
+}
+
+func (c *conditionalCache) RoundTrip(r *http.Request) (*http.Response, error) {
+	// Requests that are conditional already are the caller's business.
+	if r.Method != http.MethodGet || r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+		return c.upstream.RoundTrip(r)
+	}
+
+	key := conditionalCacheKey(r)
+	entry := c.get(key)
+	if entry != nil {
+		// The request must not be modified by a RoundTripper.
+		r = r.Clone(r.Context())
+		if entry.etag != "" {
+			r.Header.Set("If-None-Match", entry.etag)
+		}
+		if entry.lastModified != "" {
+			r.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+
+	resp, err := c.upstream.RoundTrip(r)
+	if err != nil {
+		return resp, err
+	}
+
+	if entry != nil && resp.StatusCode == http.StatusNotModified {
+		conditionalCacheRequests.WithLabelValues(conditionalCacheHit).Inc()
+		resp.Body.Close()
+		// Rate limit headers of the 304 are current, keep them.
+		header := entry.header.Clone()
+		for name, values := range resp.Header {
+			header[name] = values
+		}
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewReader(entry.body)),
+			ContentLength: int64(len(entry.body)),
+			Request:       resp.Request,
+		}, nil
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		conditionalCacheRequests.WithLabelValues(conditionalCacheUncacheable).Inc()
+		return resp, nil
+	}
+	conditionalCacheRequests.WithLabelValues(conditionalCacheMiss).Inc()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	c.put(&conditionalCacheEntry{
+		key:          key,
+		etag:         etag,
+		lastModified: lastModified,
+		header:       resp.Header.Clone(),
+		body:         body,
+	})
+	return resp, nil
+}
+
+func (c *conditionalCache) get(key string) *conditionalCacheEntry {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	element, found := c.entries[key]
+	if !found {
+		return nil
+	}
+	c.lru.MoveToFront(element)
+	return element.Value.(*conditionalCacheEntry)
+}
+
+func (c *conditionalCache) put(entry *conditionalCacheEntry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if element, found := c.entries[entry.key]; found {
+		c.remove(element)
+	}
+	if len(entry.body) > c.maxBytes {
+		return
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.size += len(entry.body)
+	conditionalCacheBytes.Add(float64(len(entry.body)))
+	for c.size > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove removes the element from the cache. The lock must be held.
+func (c *conditionalCache) remove(element *list.Element) {
+	entry := c.lru.Remove(element).(*conditionalCacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= len(entry.body)
+	conditionalCacheBytes.Sub(float64(len(entry.body)))
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// etagServer answers with the body of a path and its ETag, or with 304 if
+// the request carries the ETag already.
+type etagServer struct {
+	bodies   map[string]string
+	requests []*http.Request
+}
+
+func (s *etagServer) RoundTrip(r *http.Request) (*http.Response, error) {
+	s.requests = append(s.requests, r)
+	body := s.bodies[r.URL.Path]
+	etag := `"` + body + `"`
+	header := http.Header{"X-Ratelimit-Remaining": []string{"4999"}}
+	if r.Header.Get("If-None-Match") == etag {
+		return &http.Response{StatusCode: http.StatusNotModified, Header: header, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	header.Set("ETag", etag)
+	header.Set("X-Ratelimit-Remaining", "4998")
+	return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+}
+
+func getThroughCache(t *testing.T, rt http.RoundTripper, path, auth string) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com"+path, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", auth)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	return resp, string(body)
+}
+
+func TestConditionalCache(t *testing.T) {
+	server := &etagServer{bodies: map[string]string{
+		"/repos/org/repo/issues/1/comments": "[1]",
+		"/repos/org/repo/issues/2/comments": "[2]",
+	}}
+	cache := newConditionalCache(server, 6)
+
+	resp, body := getThroughCache(t, cache, "/repos/org/repo/issues/1/comments", "token a")
+	if resp.StatusCode != http.StatusOK || body != "[1]" {
+		t.Fatalf("expected the first response to be passed on, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = getThroughCache(t, cache, "/repos/org/repo/issues/1/comments", "token a")
+	if resp.StatusCode != http.StatusOK || body != "[1]" {
+		t.Errorf("expected the revalidated response to be served from the cache, got %d: %s", resp.StatusCode, body)
+	}
+	if val := server.requests[1].Header.Get("If-None-Match"); val != `"[1]"` {
+		t.Errorf("expected the second request to be conditional, If-None-Match was %q", val)
+	}
+	if val := resp.Header.Get("X-Ratelimit-Remaining"); val != "4999" {
+		t.Errorf("expected the rate limit headers of the 304 to be kept, got %q", val)
+	}
+
+	getThroughCache(t, cache, "/repos/org/repo/issues/1/comments", "token b")
+	if val := server.requests[2].Header.Get("If-None-Match"); val != "" {
+		t.Errorf("expected responses not to be shared between credentials, If-None-Match was %q", val)
+	}
+
+	// Issue 1 was read with both tokens, so this evicts the response read with token a.
+	getThroughCache(t, cache, "/repos/org/repo/issues/2/comments", "token a")
+	getThroughCache(t, cache, "/repos/org/repo/issues/1/comments", "token a")
+	if val := server.requests[4].Header.Get("If-None-Match"); val != "" {
+		t.Errorf("expected the least recently used response to be evicted, If-None-Match was %q", val)
+	}
+	if cache.size > 6 {
+		t.Errorf("expected the cache to hold at most 6 bytes, holds %d", cache.size)
+	}
+}
+
+func TestConditionalCacheSkipsUncacheableRequests(t *testing.T) {
+	server := &etagServer{bodies: map[string]string{"/repos/org/repo/pulls/1/files": "[]"}}
+	cache := newConditionalCache(server, 1024)
+
+	req, err := http.NewRequest(http.MethodPost, "https://api.github.com/repos/org/repo/pulls/1/files", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if _, err := cache.RoundTrip(req); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("expected the response to a POST not to be cached, got %d entries", len(cache.entries))
+	}
+
+	req, err = http.NewRequest(http.MethodGet, "https://api.github.com/repos/org/repo/pulls/1/files", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("If-None-Match", `"other"`)
+	if _, err := cache.RoundTrip(req); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("expected the response to a conditional request of the caller not to be cached, got %d entries", len(cache.entries))
+	}
+}
@@ -47,6 +47,7 @@ type FakeClient struct {
 	IssueID                    int
 	OrgMembers                 map[string][]string
 	Collaborators              []string
+	NonexistentUsers           []string
 	IssueComments              map[int][]github.IssueComment
 	IssueCommentID             int
 	PullRequests               map[int]*github.PullRequest
@@ -58,8 +59,12 @@ type FakeClient struct {
 	Reviews                    map[int][]github.Review
 	CombinedStatuses           map[string]*github.CombinedStatus
 	CreatedStatuses            map[string][]github.Status
+	CheckRuns                  map[string][]github.CheckRun
+	CheckRunID                 int64
 	IssueEvents                map[int][]github.ListedIssueEvent
 	Commits                    map[string]github.RepositoryCommit
+	// base...head:comparison
+	CommitComparisons map[string]*github.CommitComparison
 
 	// All Labels That Exist In The Repo
 	RepoLabelsExisting []string
@@ -215,6 +220,18 @@ func (f *FakeClient) IsMember(org, user string) (bool, error) {
 	return false, nil
 }
 
+// UserExists returns true unless the user is one of the NonexistentUsers.
+func (f *FakeClient) UserExists(login string) (bool, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	for _, u := range f.NonexistentUsers {
+		if u == login {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 func (f *FakeClient) WasLabelAddedByHuman(_, _ string, _ int, _ string) (bool, error) {
 	f.lock.RLock()
 	defer f.lock.RUnlock()
@@ -286,12 +303,22 @@ func (f *FakeClient) CreateCommentWithContext(_ context.Context, owner, repo str
 	return nil
 }
 
-// EditComment edits a comment. Its a stub that does nothing.
+// EditComment edits a comment.
 func (f *FakeClient) EditComment(org, repo string, ID int, comment string) error {
 	return f.EditCommentWithContext(context.Background(), org, repo, ID, comment)
 }
 
 func (f *FakeClient) EditCommentWithContext(_ context.Context, org, repo string, ID int, comment string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, ics := range f.IssueComments {
+		for i := range ics {
+			if ics[i].ID == ID {
+				ics[i].Body = comment
+				return nil
+			}
+		}
+	}
 	return nil
 }
 
@@ -471,6 +498,17 @@ func (f *FakeClient) GetSingleCommit(org, repo, SHA string) (github.RepositoryCo
 	return f.Commits[SHA], nil
 }
 
+// CompareCommits returns the comparison of the commits, they are identical
+// unless a comparison is configured.
+func (f *FakeClient) CompareCommits(org, repo, base, head string) (*github.CommitComparison, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	if comparison, ok := f.CommitComparisons[base+"..."+head]; ok {
+		return comparison, nil
+	}
+	return &github.CommitComparison{Status: "identical"}, nil
+}
+
 // CreateStatus adds a status context to a commit.
 func (f *FakeClient) CreateStatus(owner, repo, SHA string, s github.Status) error {
 	return f.CreateStatusWithContext(context.Background(), owner, repo, SHA, s)
@@ -503,6 +541,43 @@ func (f *FakeClient) CreateStatusWithContext(_ context.Context, owner, repo, SHA
 	return nil
 }
 
+// ListCheckRuns lists the check runs created for a ref.
+func (f *FakeClient) ListCheckRuns(org, repo, ref string) (*github.CheckRunList, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return &github.CheckRunList{Total: len(f.CheckRuns[ref]), CheckRuns: f.CheckRuns[ref]}, nil
+}
+
+// CreateCheckRun adds a check run to the head SHA of the check run.
+func (f *FakeClient) CreateCheckRun(org, repo string, checkRun github.CheckRun) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.CheckRuns == nil {
+		f.CheckRuns = make(map[string][]github.CheckRun)
+	}
+	f.CheckRunID++
+	checkRun.ID = f.CheckRunID
+	f.CheckRuns[checkRun.HeadSHA] = append(f.CheckRuns[checkRun.HeadSHA], checkRun)
+	return nil
+}
+
+// UpdateCheckRun replaces an existing check run.
+func (f *FakeClient) UpdateCheckRun(org, repo string, checkRunID int64, checkRun github.CheckRun) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for sha, checkRuns := range f.CheckRuns {
+		for i := range checkRuns {
+			if checkRuns[i].ID == checkRunID {
+				checkRun.ID = checkRunID
+				checkRun.HeadSHA = sha
+				checkRuns[i] = checkRun
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("could not find check run %d", checkRunID)
+}
+
 // ListStatuses returns individual status contexts on a commit.
 func (f *FakeClient) ListStatuses(org, repo, ref string) ([]github.Status, error) {
 	f.lock.RLock()
@@ -611,8 +686,9 @@ func (f *FakeClient) FindIssues(query, sort string, asc bool) ([]github.Issue, e
 	}
 	for _, pr := range f.PullRequests {
 		issues = append(issues, github.Issue{
-			User:   pr.User,
-			Number: pr.Number,
+			User:    pr.User,
+			Number:  pr.Number,
+			HTMLURL: pr.HTMLURL,
 		})
 	}
 	return issues, nil
@@ -985,6 +1061,15 @@ func (f *FakeClient) GetTeamBySlug(slug string, org string) (*github.Team, error
 }
 
 func (f *FakeClient) CreatePullRequest(org, repo, title, body, head, base string, canModify bool) (int, error) {
+	return f.createPullRequest(org, repo, base, false)
+}
+
+// CreateDraftPullRequest creates a pull request marked as draft.
+func (f *FakeClient) CreateDraftPullRequest(org, repo, title, body, head, base string, canModify bool) (int, error) {
+	return f.createPullRequest(org, repo, base, true)
+}
+
+func (f *FakeClient) createPullRequest(org, repo, base string, draft bool) (int, error) {
 	f.lock.Lock()
 	defer f.lock.Unlock()
 	if f.PullRequests == nil {
@@ -1003,6 +1088,7 @@ func (f *FakeClient) CreatePullRequest(org, repo, title, body, head, base string
 				Ref:  base,
 				Repo: github.Repo{Owner: github.User{Login: org}, Name: repo},
 			},
+			Draft: draft,
 		}
 		f.Issues[i] = &github.Issue{Number: i}
 		return i, nil
@@ -0,0 +1,213 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	githubql "github.com/shurcooL/githubv4"
+)
+
+// This file holds the GraphQL implementations of read calls that plugins
+// make for most events. They are used instead of the REST calls if the
+// client was created with ClientOptions.GraphQLReads, so that large orgs
+// spend their GraphQL rate limit on them instead of exhausting the REST one.
+// A GraphQL query returns up to 100 items per page, like the REST calls, but
+// it can't return fields that only REST knows, so those are left empty:
+//  - Label.URL
+//  - Review.User fields other than Login
+// Calls fall back to REST whenever their query fails.
+
+type graphQLPageInfo struct {
+	HasNextPage githubql.Boolean
+	EndCursor   githubql.String
+}
+
+type graphQLLabels struct {
+	Nodes []struct {
+		Name        githubql.String
+		Description githubql.String
+		Color       githubql.String
+	}
+	PageInfo graphQLPageInfo
+}
+
+type issueLabelsQuery struct {
+	Repository struct {
+		IssueOrPullRequest struct {
+			Issue struct {
+				Labels graphQLLabels `graphql:"labels(first: 100, after: $cursor)"`
+			} `graphql:"... on Issue"`
+			PullRequest struct {
+				Labels graphQLLabels `graphql:"labels(first: 100, after: $cursor)"`
+			} `graphql:"... on PullRequest"`
+		} `graphql:"issueOrPullRequest(number: $number)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+type reviewsQuery struct {
+	Repository struct {
+		PullRequest struct {
+			Reviews struct {
+				Nodes []struct {
+					DatabaseID githubql.Int    `graphql:"databaseId"`
+					ID         githubql.String `graphql:"id"`
+					Author     struct {
+						Login githubql.String
+					}
+					Body        githubql.String
+					State       githubql.PullRequestReviewState
+					URL         githubql.URI `graphql:"url"`
+					SubmittedAt *githubql.DateTime
+				}
+				PageInfo graphQLPageInfo
+			} `graphql:"reviews(first: 100, after: $cursor)"`
+		} `graphql:"pullRequest(number: $number)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+type teamMembersQuery struct {
+	Organization struct {
+		Team *struct {
+			Members struct {
+				Nodes []struct {
+					Login githubql.String
+				}
+				PageInfo graphQLPageInfo
+			} `graphql:"members(first: 100, after: $cursor, role: $role)"`
+		} `graphql:"team(slug: $slug)"`
+	} `graphql:"organization(login: $org)"`
+}
+
+// fallBackToREST logs that a GraphQL query failed and its REST call is made
+// instead.
+func (c *client) fallBackToREST(methodName string, err error) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.WithError(err).WithField("methodName", methodName).Warn("GraphQL query failed, falling back to REST.")
+}
+
+// graphQLIssueLabels returns the labels of org/repo#number.
+func (c *client) graphQLIssueLabels(org, repo string, number int) ([]Label, error) {
+	vars := map[string]interface{}{
+		"owner":  githubql.String(org),
+		"name":   githubql.String(repo),
+		"number": githubql.Int(number),
+		"cursor": (*githubql.String)(nil),
+	}
+	var labels []Label
+	for {
+		var q issueLabelsQuery
+		if err := c.QueryWithGitHubAppsSupport(context.Background(), &q, vars, org); err != nil {
+			return nil, err
+		}
+		// Only the fragment of the type of the number is filled.
+		page := q.Repository.IssueOrPullRequest.Issue.Labels
+		if len(page.Nodes) == 0 && !page.PageInfo.HasNextPage {
+			page = q.Repository.IssueOrPullRequest.PullRequest.Labels
+		}
+		for _, node := range page.Nodes {
+			labels = append(labels, Label{Name: string(node.Name), Description: string(node.Description), Color: string(node.Color)})
+		}
+		if !page.PageInfo.HasNextPage {
+			return labels, nil
+		}
+		vars["cursor"] = githubql.NewString(page.PageInfo.EndCursor)
+	}
+}
+
+// graphQLReviews returns the reviews of the pull request org/repo#number.
+func (c *client) graphQLReviews(org, repo string, number int) ([]Review, error) {
+	vars := map[string]interface{}{
+		"owner":  githubql.String(org),
+		"name":   githubql.String(repo),
+		"number": githubql.Int(number),
+		"cursor": (*githubql.String)(nil),
+	}
+	var reviews []Review
+	for {
+		var q reviewsQuery
+		if err := c.QueryWithGitHubAppsSupport(context.Background(), &q, vars, org); err != nil {
+			return nil, err
+		}
+		page := q.Repository.PullRequest.Reviews
+		for _, node := range page.Nodes {
+			review := Review{
+				ID:     int(node.DatabaseID),
+				NodeID: string(node.ID),
+				User:   User{Login: string(node.Author.Login)},
+				Body:   string(node.Body),
+				State:  ReviewState(node.State),
+			}
+			if node.URL.URL != nil {
+				review.HTMLURL = node.URL.String()
+			}
+			if node.SubmittedAt != nil {
+				review.SubmittedAt = node.SubmittedAt.Time
+			}
+			reviews = append(reviews, review)
+		}
+		if !page.PageInfo.HasNextPage {
+			return reviews, nil
+		}
+		vars["cursor"] = githubql.NewString(page.PageInfo.EndCursor)
+	}
+}
+
+// graphQLTeamMembers returns the members of the team with the slug in org
+// that have the role.
+func (c *client) graphQLTeamMembers(org, teamSlug, role string) ([]TeamMember, error) {
+	var teamRole *githubql.TeamMemberRole
+	switch role {
+	case RoleAll:
+	case RoleMaintainer:
+		maintainer := githubql.TeamMemberRoleMaintainer
+		teamRole = &maintainer
+	case RoleMember:
+		member := githubql.TeamMemberRoleMember
+		teamRole = &member
+	default:
+		return nil, fmt.Errorf("unsupported role %q", role)
+	}
+	vars := map[string]interface{}{
+		"org":    githubql.String(org),
+		"slug":   githubql.String(teamSlug),
+		"role":   teamRole,
+		"cursor": (*githubql.String)(nil),
+	}
+	var members []TeamMember
+	for {
+		var q teamMembersQuery
+		if err := c.QueryWithGitHubAppsSupport(context.Background(), &q, vars, org); err != nil {
+			return nil, err
+		}
+		if q.Organization.Team == nil {
+			return nil, errors.New("team not found")
+		}
+		page := q.Organization.Team.Members
+		for _, node := range page.Nodes {
+			members = append(members, TeamMember{Login: string(node.Login)})
+		}
+		if !page.PageInfo.HasNextPage {
+			return members, nil
+		}
+		vars["cursor"] = githubql.NewString(page.PageInfo.EndCursor)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shurcooL/githubv4"
+)
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// getGraphQLClient returns a client that reads through the GraphQL API of
+// the server.
+func getGraphQLClient(ts *httptest.Server) *client {
+	c := getClient(ts.URL)
+	c.gqlc = &graphQLGitHubAppsAuthClientWrapper{Client: githubv4.NewEnterpriseClient(ts.URL+"/graphql", ts.Client())}
+	c.graphQLReads = true
+	return c
+}
+
+// graphQLServer serves the pages of a GraphQL query, the next one of which
+// is selected by the cursor variable, and the REST paths.
+func graphQLServer(t *testing.T, pages map[string]string, check func(graphQLRequest), rest map[string]interface{}) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" {
+			response, ok := rest[r.URL.Path]
+			if !ok {
+				t.Errorf("Bad request path: %s", r.URL.Path)
+				return
+			}
+			b, err := json.Marshal(response)
+			if err != nil {
+				t.Fatalf("Didn't expect error: %v", err)
+			}
+			fmt.Fprint(w, string(b))
+			return
+		}
+		var req graphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode GraphQL request: %v", err)
+		}
+		if check != nil {
+			check(req)
+		}
+		cursor, _ := req.Variables["cursor"].(string)
+		page, ok := pages[cursor]
+		if !ok {
+			http.Error(w, "no such page", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, `{"data": %s}`, page)
+	}))
+}
+
+func TestGetIssueLabelsGraphQL(t *testing.T) {
+	ts := graphQLServer(t, map[string]string{
+		"":   `{"repository": {"issueOrPullRequest": {"labels": {"nodes": [{"name": "lgtm", "description": "Looks good", "color": "15dd18"}], "pageInfo": {"hasNextPage": true, "endCursor": "c1"}}}}}`,
+		"c1": `{"repository": {"issueOrPullRequest": {"labels": {"nodes": [{"name": "approved", "description": "", "color": "0ffa16"}], "pageInfo": {"hasNextPage": false, "endCursor": "c2"}}}}}`,
+	}, func(req graphQLRequest) {
+		if req.Variables["owner"] != "k8s" || req.Variables["name"] != "kuber" || req.Variables["number"] != float64(5) {
+			t.Errorf("unexpected variables: %v", req.Variables)
+		}
+	}, nil)
+	defer ts.Close()
+
+	labels, err := getGraphQLClient(ts).GetIssueLabels("k8s", "kuber", 5)
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	expected := []Label{{Name: "lgtm", Description: "Looks good", Color: "15dd18"}, {Name: "approved", Color: "0ffa16"}}
+	if diff := cmp.Diff(expected, labels); diff != "" {
+		t.Errorf("labels differ from expected: %s", diff)
+	}
+}
+
+func TestListReviewsGraphQL(t *testing.T) {
+	ts := graphQLServer(t, map[string]string{
+		"": `{"repository": {"pullRequest": {"reviews": {"nodes": [{"databaseId": 1, "id": "R_1", "author": {"login": "alice"}, "body": "LGTM", "state": "APPROVED", "url": "https://github.com/k8s/kuber/pull/15#pullrequestreview-1", "submittedAt": "2021-06-01T10:00:00Z"}], "pageInfo": {"hasNextPage": false, "endCursor": "c1"}}}}}`,
+	}, nil, nil)
+	defer ts.Close()
+
+	reviews, err := getGraphQLClient(ts).ListReviews("k8s", "kuber", 15)
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	expected := []Review{{
+		ID:          1,
+		NodeID:      "R_1",
+		User:        User{Login: "alice"},
+		Body:        "LGTM",
+		State:       ReviewStateApproved,
+		HTMLURL:     "https://github.com/k8s/kuber/pull/15#pullrequestreview-1",
+		SubmittedAt: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+	}}
+	if diff := cmp.Diff(expected, reviews); diff != "" {
+		t.Errorf("reviews differ from expected: %s", diff)
+	}
+}
+
+func TestListTeamMembersBySlugGraphQL(t *testing.T) {
+	ts := graphQLServer(t, map[string]string{
+		"": `{"organization": {"team": {"members": {"nodes": [{"login": "alice"}, {"login": "bob"}], "pageInfo": {"hasNextPage": false, "endCursor": "c1"}}}}}`,
+	}, func(req graphQLRequest) {
+		if req.Variables["role"] != "MAINTAINER" {
+			t.Errorf("expected the role to be MAINTAINER, got %v", req.Variables["role"])
+		}
+	}, nil)
+	defer ts.Close()
+
+	members, err := getGraphQLClient(ts).ListTeamMembersBySlug("orgName", "team-name", RoleMaintainer)
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if diff := cmp.Diff([]TeamMember{{Login: "alice"}, {Login: "bob"}}, members); diff != "" {
+		t.Errorf("members differ from expected: %s", diff)
+	}
+}
+
+func TestGraphQLFallsBackToREST(t *testing.T) {
+	testCases := []struct {
+		name  string
+		pages map[string]string
+	}{
+		{
+			name: "query fails",
+		},
+		{
+			name:  "team is not found",
+			pages: map[string]string{"": `{"organization": {"team": null}}`},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := graphQLServer(t, tc.pages, nil, map[string]interface{}{
+				"/orgs/orgName/teams/team-name/members": []TeamMember{{Login: "foo"}},
+			})
+			defer ts.Close()
+
+			members, err := getGraphQLClient(ts).ListTeamMembersBySlug("orgName", "team-name", RoleAll)
+			if err != nil {
+				t.Fatalf("Didn't expect error: %v", err)
+			}
+			if diff := cmp.Diff([]TeamMember{{Login: "foo"}}, members); diff != "" {
+				t.Errorf("members differ from expected: %s", diff)
+			}
+		})
+	}
+}
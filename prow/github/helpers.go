@@ -19,6 +19,7 @@ package github
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -69,6 +70,20 @@ func ImageTooBig(url string) (bool, error) {
 	return false, nil
 }
 
+// OrgRepoFromHTMLURL returns the org and repo of an issue or PR from its
+// HTML URL, e.g. https://github.com/org/repo/pull/1.
+func OrgRepoFromHTMLURL(htmlURL string) (string, string, error) {
+	u, err := url.Parse(htmlURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse %q: %w", htmlURL, err)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 {
+		return "", "", fmt.Errorf("cannot determine org and repo from %q", htmlURL)
+	}
+	return parts[0], parts[1], nil
+}
+
 // LevelFromPermissions adapts a repo permissions struct to the
 // appropriate permission level used elsewhere.
 func LevelFromPermissions(permissions RepoPermissions) RepoPermissionLevel {
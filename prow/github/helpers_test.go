@@ -151,3 +151,26 @@ func TestPermissionsFromTeamPermission(t *testing.T) {
 		}
 	}
 }
+
+func TestOrgRepoFromHTMLURL(t *testing.T) {
+	testCases := []struct {
+		htmlURL     string
+		org, repo   string
+		expectedErr bool
+	}{
+		{htmlURL: "https://github.com/org/repo/pull/1", org: "org", repo: "repo"},
+		{htmlURL: "https://github.example.com/org/repo/issues/2", org: "org", repo: "repo"},
+		{htmlURL: "https://github.com/org", expectedErr: true},
+		{htmlURL: "://github.com/org/repo/pull/1", expectedErr: true},
+	}
+	for _, tc := range testCases {
+		org, repo, err := OrgRepoFromHTMLURL(tc.htmlURL)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%s: expected error %t, got %v", tc.htmlURL, tc.expectedErr, err)
+			continue
+		}
+		if org != tc.org || repo != tc.repo {
+			t.Errorf("%s: expected %s/%s, got %s/%s", tc.htmlURL, tc.org, tc.repo, org, repo)
+		}
+	}
+}
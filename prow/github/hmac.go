@@ -86,6 +86,30 @@ func PayloadSignature(payload []byte, key []byte) string {
 	return "sha1=" + hex.EncodeToString(sum)
 }
 
+// ResignPayload returns the signature of the payload, a changed version of the
+// original payload, made with the hmac that the original payload is signed with,
+// so that the changed payload can be passed on to the receivers of the webhook.
+func ResignPayload(original, payload []byte, sig string, tokenGenerator func() []byte) (string, error) {
+	var event GenericEvent
+	if err := json.Unmarshal(original, &event); err != nil {
+		return "", fmt.Errorf("couldn't unmarshal the github event payload: %w", err)
+	}
+	orgRepo := event.Repo.FullName
+	if orgRepo == "" {
+		orgRepo = event.Org.Login
+	}
+	hmacs, err := extractHMACs(orgRepo, tokenGenerator)
+	if err != nil {
+		return "", err
+	}
+	for _, key := range hmacs {
+		if hmac.Equal([]byte(PayloadSignature(original, key)), []byte(sig)) {
+			return PayloadSignature(payload, key), nil
+		}
+	}
+	return "", fmt.Errorf("no hmac of %s matches the signature of the payload", orgRepo)
+}
+
 // extractHMACs returns all *valid* HMAC tokens for given repository/organization.
 // It considers only the tokens at the most specific level configured for the given repo.
 // For example : if a token for repo is present and it doesn't match the repo, we will
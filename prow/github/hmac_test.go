@@ -101,3 +101,22 @@ func TestValidatePayload(t *testing.T) {
 		}
 	}
 }
+
+func TestResignPayload(t *testing.T) {
+	original := []byte(`{"repository": {"full_name": "org2/repo"}}`)
+	payload := []byte(`{"repository": {"full_name": "org2/repo"}, "changed": true}`)
+	sig, err := ResignPayload(original, payload, "sha1=0b5ea8bf5683e4bf89cf900271e1c8a021b4b0b3", defaultTokenGenerator)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !ValidatePayload(payload, sig, defaultTokenGenerator) {
+		t.Errorf("Expected the changed payload to be valid with signature %s", sig)
+	}
+	if expected := PayloadSignature(payload, []byte("key2")); sig != expected {
+		t.Errorf("Expected the changed payload to be signed with the key of the original, %s, but got %s", expected, sig)
+	}
+
+	if _, err := ResignPayload(original, payload, "sha1=db5c76f4264d0ad96cf21baec394964b4b8ce580", defaultTokenGenerator); err == nil {
+		t.Error("Expected an error for a signature that matches no hmac of the repo")
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Provider serves the calls of the comment-command plugins from a forge other
+// than GitHub, like GitLab or Gitea. Orgs, repos, numbers and logins are those
+// of the events the forge translated for the plugins, and comments and labels
+// those of its merge requests, pull requests or issues.
+type Provider interface {
+	BotUserChecker() (func(candidate string) bool, error)
+	IsCollaborator(org, repo, user string) (bool, error)
+
+	CreateComment(org, repo string, number int, comment string) error
+	EditComment(org, repo string, id int, comment string) error
+	DeleteComment(org, repo string, id int) error
+	ListIssueComments(org, repo string, number int) ([]IssueComment, error)
+
+	AddLabel(org, repo string, number int, label string) error
+	RemoveLabel(org, repo string, number int, label string) error
+	GetIssueLabels(org, repo string, number int) ([]Label, error)
+
+	AssignIssue(org, repo string, number int, logins []string) error
+	UnassignIssue(org, repo string, number int, logins []string) error
+	RequestReview(org, repo string, number int, logins []string) error
+	UnrequestReview(org, repo string, number int, logins []string) error
+
+	GetPullRequest(org, repo string, number int) (*PullRequest, error)
+	GetPullRequestChanges(org, repo string, number int) ([]PullRequestChange, error)
+}
+
+// providerClient is a Client backed by a Provider. The calls the provider
+// doesn't serve fail with ErrUnsupported.
+type providerClient struct {
+	unsupportedClient
+
+	provider Provider
+	logger   *logrus.Entry
+	dryRun   bool
+}
+
+// NewProviderClient returns a Client serving the calls of the Provider from the
+// forge of the provider. In dry run, and for its clones for dry run, the client
+// logs the changes it would make instead of making them.
+func NewProviderClient(forge string, provider Provider, dryRun bool) Client {
+	c := &providerClient{
+		unsupportedClient: unsupportedClient{forge: forge},
+		provider:          provider,
+		logger:            logrus.WithField("client", forge),
+	}
+	if dryRun {
+		return c.ForDryRun()
+	}
+	return c
+}
+
+func (c *providerClient) WithFields(fields logrus.Fields) Client {
+	scoped := *c
+	scoped.logger = c.logger.WithFields(fields)
+	return &scoped
+}
+
+func (c *providerClient) ForPlugin(plugin string) Client {
+	scoped := *c
+	scoped.logger = c.logger.WithField("plugin", plugin)
+	return &scoped
+}
+
+func (c *providerClient) ForSubcomponent(subcomponent string) Client {
+	scoped := *c
+	scoped.logger = c.logger.WithField("subcomponent", subcomponent)
+	return &scoped
+}
+
+func (c *providerClient) ForDryRun() Client {
+	scoped := *c
+	scoped.logger = c.logger.WithField("dry-run", true)
+	scoped.dryRun = true
+	return &scoped
+}
+
+func (c *providerClient) Used() bool {
+	return true
+}
+
+// skip returns whether a change must be skipped in dry run.
+func (c *providerClient) skip(change string, args ...interface{}) bool {
+	if c.dryRun {
+		c.logger.Infof("Not going to "+change+" in dry run.", args...)
+	}
+	return c.dryRun
+}
+
+func (c *providerClient) BotUserChecker() (func(candidate string) bool, error) {
+	return c.provider.BotUserChecker()
+}
+
+func (c *providerClient) BotUserCheckerWithContext(_ context.Context) (func(candidate string) bool, error) {
+	return c.BotUserChecker()
+}
+
+func (c *providerClient) IsCollaborator(org, repo, user string) (bool, error) {
+	return c.provider.IsCollaborator(org, repo, user)
+}
+
+func (c *providerClient) CreateComment(org, repo string, number int, comment string) error {
+	if c.skip("comment on %s/%s#%d", org, repo, number) {
+		return nil
+	}
+	return c.provider.CreateComment(org, repo, number, comment)
+}
+
+func (c *providerClient) CreateCommentWithContext(_ context.Context, org, repo string, number int, comment string) error {
+	return c.CreateComment(org, repo, number, comment)
+}
+
+func (c *providerClient) EditComment(org, repo string, id int, comment string) error {
+	if c.skip("edit comment %d of %s/%s", id, org, repo) {
+		return nil
+	}
+	return c.provider.EditComment(org, repo, id, comment)
+}
+
+func (c *providerClient) EditCommentWithContext(_ context.Context, org, repo string, id int, comment string) error {
+	return c.EditComment(org, repo, id, comment)
+}
+
+func (c *providerClient) DeleteComment(org, repo string, id int) error {
+	if c.skip("delete comment %d of %s/%s", id, org, repo) {
+		return nil
+	}
+	return c.provider.DeleteComment(org, repo, id)
+}
+
+func (c *providerClient) DeleteCommentWithContext(_ context.Context, org, repo string, id int) error {
+	return c.DeleteComment(org, repo, id)
+}
+
+func (c *providerClient) ListIssueComments(org, repo string, number int) ([]IssueComment, error) {
+	return c.provider.ListIssueComments(org, repo, number)
+}
+
+func (c *providerClient) ListIssueCommentsWithContext(_ context.Context, org, repo string, number int) ([]IssueComment, error) {
+	return c.ListIssueComments(org, repo, number)
+}
+
+func (c *providerClient) DeleteStaleComments(org, repo string, number int, comments []IssueComment, isStale func(IssueComment) bool) error {
+	var err error
+	if comments == nil {
+		comments, err = c.ListIssueComments(org, repo, number)
+		if err != nil {
+			return fmt.Errorf("failed to list comments while deleting stale comments. err: %w", err)
+		}
+	}
+	for _, comment := range comments {
+		if isStale(comment) {
+			if err := c.DeleteComment(org, repo, comment.ID); err != nil {
+				return fmt.Errorf("failed to delete stale comment with ID '%d'", comment.ID)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *providerClient) DeleteStaleCommentsWithContext(_ context.Context, org, repo string, number int, comments []IssueComment, isStale func(IssueComment) bool) error {
+	return c.DeleteStaleComments(org, repo, number, comments, isStale)
+}
+
+func (c *providerClient) AddLabel(org, repo string, number int, label string) error {
+	if c.skip("add label %q to %s/%s#%d", label, org, repo, number) {
+		return nil
+	}
+	return c.provider.AddLabel(org, repo, number, label)
+}
+
+func (c *providerClient) AddLabelWithContext(_ context.Context, org, repo string, number int, label string) error {
+	return c.AddLabel(org, repo, number, label)
+}
+
+func (c *providerClient) RemoveLabel(org, repo string, number int, label string) error {
+	if c.skip("remove label %q from %s/%s#%d", label, org, repo, number) {
+		return nil
+	}
+	return c.provider.RemoveLabel(org, repo, number, label)
+}
+
+func (c *providerClient) RemoveLabelWithContext(_ context.Context, org, repo string, number int, label string) error {
+	return c.RemoveLabel(org, repo, number, label)
+}
+
+func (c *providerClient) GetIssueLabels(org, repo string, number int) ([]Label, error) {
+	return c.provider.GetIssueLabels(org, repo, number)
+}
+
+func (c *providerClient) AssignIssue(org, repo string, number int, logins []string) error {
+	if c.skip("assign %v to %s/%s#%d", logins, org, repo, number) {
+		return nil
+	}
+	return c.provider.AssignIssue(org, repo, number, logins)
+}
+
+func (c *providerClient) UnassignIssue(org, repo string, number int, logins []string) error {
+	if c.skip("unassign %v from %s/%s#%d", logins, org, repo, number) {
+		return nil
+	}
+	return c.provider.UnassignIssue(org, repo, number, logins)
+}
+
+func (c *providerClient) RequestReview(org, repo string, number int, logins []string) error {
+	if c.skip("request the review of %v on %s/%s#%d", logins, org, repo, number) {
+		return nil
+	}
+	return c.provider.RequestReview(org, repo, number, logins)
+}
+
+func (c *providerClient) UnrequestReview(org, repo string, number int, logins []string) error {
+	if c.skip("unrequest the review of %v on %s/%s#%d", logins, org, repo, number) {
+		return nil
+	}
+	return c.provider.UnrequestReview(org, repo, number, logins)
+}
+
+func (c *providerClient) GetPullRequest(org, repo string, number int) (*PullRequest, error) {
+	return c.provider.GetPullRequest(org, repo, number)
+}
+
+func (c *providerClient) GetPullRequestChanges(org, repo string, number int) ([]PullRequestChange, error) {
+	return c.provider.GetPullRequestChanges(org, repo, number)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fakeProvider struct {
+	Provider
+
+	comments []string
+	labels   []string
+}
+
+func (f *fakeProvider) CreateComment(org, repo string, number int, comment string) error {
+	f.comments = append(f.comments, comment)
+	return nil
+}
+
+func (f *fakeProvider) AddLabel(org, repo string, number int, label string) error {
+	f.labels = append(f.labels, label)
+	return nil
+}
+
+func (f *fakeProvider) ListIssueComments(org, repo string, number int) ([]IssueComment, error) {
+	return []IssueComment{{ID: 1, Body: "stale"}, {ID: 2, Body: "fresh"}}, nil
+}
+
+func (f *fakeProvider) DeleteComment(org, repo string, id int) error {
+	f.comments = append(f.comments, "deleted")
+	return nil
+}
+
+func TestProviderClient(t *testing.T) {
+	provider := &fakeProvider{}
+	c := NewProviderClient("fake", provider, false).ForPlugin("plugin")
+
+	if err := c.CreateComment("org", "repo", 1, "hello"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.ForDryRun().CreateComment("org", "repo", 1, "dry"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.ForDryRun().AddLabel("org", "repo", 1, "lgtm"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.AddLabel("org", "repo", 1, "hold"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.DeleteStaleComments("org", "repo", 1, nil, func(ic IssueComment) bool { return ic.Body == "stale" }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := NewProviderClient("fake", provider, true).CreateComment("org", "repo", 1, "dry"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Merge("org", "repo", 1, MergeDetails{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected merging to be unsupported, got %v", err)
+	}
+
+	if expected := []string{"hello", "deleted"}; !reflect.DeepEqual(provider.comments, expected) {
+		t.Errorf("expected comments %v, got %v", expected, provider.comments)
+	}
+	if expected := []string{"hold"}; !reflect.DeepEqual(provider.labels, expected) {
+		t.Errorf("expected labels %v, got %v", expected, provider.labels)
+	}
+}
@@ -0,0 +1,585 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	githubql "github.com/shurcooL/githubv4"
+)
+
+// ErrUnsupported is returned by the calls of a Client backed by a Provider that
+// the forge of the provider doesn't serve.
+var ErrUnsupported = errors.New("not supported")
+
+// unsupportedClient implements the calls of Client that a Provider doesn't
+// serve, they all fail with ErrUnsupported.
+type unsupportedClient struct {
+	forge string
+}
+
+func (c unsupportedClient) unsupported() error {
+	return fmt.Errorf("%s: %w", c.forge, ErrUnsupported)
+}
+
+func (c unsupportedClient) AcceptUserOrgInvitation(org string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) AcceptUserRepoInvitation(invitationID int) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) AddLabels(org, repo string, number int, labels ...string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) AddLabelsWithContext(ctx context.Context, org, repo string, number int, labels ...string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) AddRepoLabel(org, repo, label, description, color string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) BotUser() (*UserData, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ClearMilestone(org, repo string, num int) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) CloseIssue(org, repo string, number int) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) ClosePR(org, repo string, number int) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) CreateCheckRun(org, repo string, checkRun CheckRun) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) CreateCommentReaction(org, repo string, id int, reaction string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) CreateDraftPullRequest(org, repo, title, body, head, base string, canModify bool) (int, error) {
+	return 0, c.unsupported()
+}
+
+func (c unsupportedClient) CreateFork(owner, repo string) (string, error) {
+	return "", c.unsupported()
+}
+
+func (c unsupportedClient) CreateIssue(org, repo, title, body string, milestone int, labels, assignees []string) (int, error) {
+	return 0, c.unsupported()
+}
+
+func (c unsupportedClient) CreateIssueReaction(org, repo string, id int, reaction string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) CreateOrgHook(org string, req HookRequest) (int, error) {
+	return 0, c.unsupported()
+}
+
+func (c unsupportedClient) CreateProjectCard(org string, columnID int, projectCard ProjectCard) (*ProjectCard, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) CreatePullRequest(org, repo, title, body, head, base string, canModify bool) (int, error) {
+	return 0, c.unsupported()
+}
+
+func (c unsupportedClient) CreatePullRequestReviewComment(org, repo string, number int, rc ReviewComment) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) CreateRepo(owner string, isUser bool, repo RepoCreateRequest) (*FullRepo, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) CreateRepoHook(org, repo string, req HookRequest) (int, error) {
+	return 0, c.unsupported()
+}
+
+func (c unsupportedClient) CreateRepoPages(org, repo string, pages RepoPages) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) CreateRepoRuleset(org, repo string, ruleset Ruleset) (*Ruleset, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) CreateReview(org, repo string, number int, r DraftReview) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) CreateStatus(org, repo, SHA string, s Status) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) CreateStatusWithContext(ctx context.Context, org, repo, SHA string, s Status) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) CreateTeam(org string, team Team) (*Team, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) DeleteOrgHook(org string, id int, req HookRequest) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) DeleteProjectCard(org string, projectCardID int) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) DeleteRef(org, repo, ref string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) DeleteRepoHook(org, repo string, id int, req HookRequest) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) DeleteRepoLabel(org, repo, label string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) DeleteRepoPages(org, repo string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) DeleteRepoRuleset(org, repo string, id int) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) DeleteTeam(org string, id int) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) DeleteTeamBySlug(org, teamSlug string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) EditIssue(org, repo string, number int, issue *Issue) (*Issue, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) EditOrg(name string, config Organization) (*Organization, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) EditOrgHook(org string, id int, req HookRequest) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) EditPullRequest(org, repo string, number int, pr *PullRequest) (*PullRequest, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) EditRepoHook(org, repo string, id int, req HookRequest) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) EditTeam(org string, t Team) (*Team, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) Email() (string, error) {
+	return "", c.unsupported()
+}
+
+func (c unsupportedClient) EnsureFork(forkingUser, org, repo string) (string, error) {
+	return "", c.unsupported()
+}
+
+func (c unsupportedClient) FindIssues(query, sort string, asc bool) ([]Issue, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetApp() (*App, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetAppWithContext(ctx context.Context) (*App, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetBranchProtection(org, repo, branch string) (*BranchProtection, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetBranches(org, repo string, onlyProtected bool) ([]Branch, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetColumnProjectCard(org string, columnID int, issueURL string) (*ProjectCard, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetColumnProjectCards(org string, columnID int) ([]ProjectCard, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetCombinedStatus(org, repo, ref string) (*CombinedStatus, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetDirectory(org, repo, dirpath, commit string) ([]DirectoryContent, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetFailedActionRunsByHeadBranch(org, repo, branchName, headSHA string) ([]WorkflowRun, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetFile(org, repo, filepath, commit string) ([]byte, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetIssue(org, repo string, number int) (*Issue, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetOrg(name string) (*Organization, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetOrgProjects(org string) ([]Project, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetProjectColumns(org string, projectID int) ([]ProjectColumn, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetPullRequestPatch(org, repo string, number int) ([]byte, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetPullRequests(org, repo string) ([]PullRequest, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetRef(org, repo, ref string) (string, error) {
+	return "", c.unsupported()
+}
+
+func (c unsupportedClient) GetRepo(owner, name string) (FullRepo, error) {
+	return FullRepo{}, c.unsupported()
+}
+
+func (c unsupportedClient) GetRepoLabels(org, repo string) ([]Label, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetRepoPages(org, repo string) (*RepoPages, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetRepoProjects(owner, repo string) ([]Project, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetRepoRuleset(org, repo string, id int) (*Ruleset, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetRepos(org string, isUser bool) ([]Repo, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetSingleCommit(org, repo, SHA string) (RepositoryCommit, error) {
+	return RepositoryCommit{}, c.unsupported()
+}
+
+func (c unsupportedClient) CompareCommits(org, repo, base, head string) (*CommitComparison, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetTeamBySlug(slug string, org string) (*Team, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) GetUserPermission(org, repo, user string) (string, error) {
+	return "", c.unsupported()
+}
+
+func (c unsupportedClient) GetVulnerabilityAlerts(org, repo string) (bool, error) {
+	return false, c.unsupported()
+}
+
+func (c unsupportedClient) HasPermission(org, repo, user string, roles ...string) (bool, error) {
+	return false, c.unsupported()
+}
+
+func (c unsupportedClient) IsMember(org, user string) (bool, error) {
+	return false, c.unsupported()
+}
+
+func (c unsupportedClient) UserExists(login string) (bool, error) {
+	return false, c.unsupported()
+}
+
+func (c unsupportedClient) IsMergeable(org, repo string, number int, SHA string) (bool, error) {
+	return false, c.unsupported()
+}
+
+func (c unsupportedClient) ListAppInstallations() ([]AppInstallation, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListCheckRuns(org, repo, ref string) (*CheckRunList, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListCollaborators(org, repo string) ([]User, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListCurrentUserOrgInvitations() ([]UserOrgInvitation, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListCurrentUserRepoInvitations() ([]UserRepoInvitation, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListFileCommits(org, repo, path string) ([]RepositoryCommit, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListIssueEvents(org, repo string, num int) ([]ListedIssueEvent, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListMilestones(org, repo string) ([]Milestone, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListOpenIssues(org, repo string) ([]Issue, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListOrgHooks(org string) ([]Hook, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListOrgInvitations(org string) ([]OrgInvitation, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListOrgMembers(org, role string) ([]TeamMember, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListPRCommits(org, repo string, number int) ([]RepositoryCommit, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListPullRequestComments(org, repo string, number int) ([]ReviewComment, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListRepoHooks(org, repo string) ([]Hook, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListRepoRulesets(org, repo string) ([]Ruleset, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListRepoTeams(org, repo string) ([]Team, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListReviews(org, repo string, number int) ([]Review, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListStatuses(org, repo, ref string) ([]Status, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListTeamInvitations(org string, id int) ([]OrgInvitation, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListTeamInvitationsBySlug(org, teamSlug string) ([]OrgInvitation, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListTeamMembers(org string, id int, role string) ([]TeamMember, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListTeamMembersBySlug(org, teamSlug, role string) ([]TeamMember, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListTeamRepos(org string, id int) ([]Repo, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListTeamReposBySlug(org, teamSlug string) ([]Repo, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) ListTeams(org string) ([]Team, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) Merge(org, repo string, pr int, details MergeDetails) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) MoveProjectCard(org string, projectCardID int, newColumnID int) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) MutateWithGitHubAppsSupport(ctx context.Context, m interface{}, input githubql.Input, vars map[string]interface{}, org string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) QueryWithGitHubAppsSupport(ctx context.Context, q interface{}, vars map[string]interface{}, org string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) RemoveBranchProtection(org, repo, branch string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) RemoveOrgMembership(org, user string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) RemoveTeamMembership(org string, id int, user string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) RemoveTeamMembershipBySlug(org, teamSlug, user string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) RemoveTeamRepo(id int, org, repo string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) RemoveTeamRepoBySlug(org, teamSlug, repo string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) ReopenIssue(org, repo string, number int) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) ReopenPR(org, repo string, number int) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) ReplaceRepoTopics(org, repo string, topics []string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) SetMax404Retries(int) {}
+
+func (c unsupportedClient) SetMilestone(org, repo string, issueNum, milestoneNum int) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) SetVulnerabilityAlerts(org, repo string, enabled bool) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) TeamBySlugHasMember(org string, teamSlug string, memberLogin string) (bool, error) {
+	return false, c.unsupported()
+}
+
+func (c unsupportedClient) TeamHasMember(org string, teamID int, memberLogin string) (bool, error) {
+	return false, c.unsupported()
+}
+
+func (c unsupportedClient) Throttle(hourlyTokens, burst int, org ...string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) TriggerGitHubWorkflow(org, repo string, id int) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) UpdateBranchProtection(org, repo, branch string, config BranchProtectionRequest) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) UpdateCheckRun(org, repo string, checkRunID int64, checkRun CheckRun) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) UpdateOrgMembership(org, user string, admin bool) (*OrgMembership, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) UpdatePullRequest(org, repo string, number int, title, body *string, open *bool, branch *string, canModify *bool) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) UpdatePullRequestBranch(org, repo string, number int, expectedHeadSha *string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) UpdateRepo(owner, name string, repo RepoUpdateRequest) (*FullRepo, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) UpdateRepoLabel(org, repo, label, newName, description, color string) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) UpdateRepoPages(org, repo string, pages RepoPages) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) UpdateRepoRuleset(org, repo string, id int, ruleset Ruleset) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) UpdateTeamMembership(org string, id int, user string, maintainer bool) (*TeamMembership, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) UpdateTeamMembershipBySlug(org, teamSlug, user string, maintainer bool) (*TeamMembership, error) {
+	return nil, c.unsupported()
+}
+
+func (c unsupportedClient) UpdateTeamRepo(id int, org, repo string, permission TeamPermission) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) UpdateTeamRepoBySlug(org, teamSlug, repo string, permission TeamPermission) error {
+	return c.unsupported()
+}
+
+func (c unsupportedClient) WasLabelAddedByHuman(org, repo string, number int, label string) (bool, error) {
+	return false, c.unsupported()
+}
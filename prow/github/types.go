@@ -282,6 +282,10 @@ type PullRequest struct {
 	// background job was started to compute it. When the job is complete, the response
 	// will include a non-null value for the mergeable attribute.
 	Mergable *bool `json:"mergeable,omitempty"`
+	// MergeableState is the state of the PR with regard to the protection of its
+	// base branch, e.g. "clean" if it can be merged right away or "blocked" if it
+	// can't be merged yet.
+	MergeableState string `json:"mergeable_state,omitempty"`
 	// If the PR doesn't have any milestone, `milestone` is null and is unmarshaled to nil.
 	Milestone         *Milestone `json:"milestone,omitempty"`
 	Commits           int        `json:"commits"`
@@ -378,6 +382,23 @@ type FullRepo struct {
 	AllowSquashMerge bool `json:"allow_squash_merge,omitempty"`
 	AllowMergeCommit bool `json:"allow_merge_commit,omitempty"`
 	AllowRebaseMerge bool `json:"allow_rebase_merge,omitempty"`
+
+	Topics []string `json:"topics,omitempty"`
+}
+
+// RepoPages is the GitHub Pages site of a repository.
+// See https://docs.github.com/en/rest/reference/repos#pages
+type RepoPages struct {
+	// CNAME is the custom domain of the site. Updating the site with
+	// a nil CNAME removes the custom domain.
+	CNAME  *string         `json:"cname"`
+	Source RepoPagesSource `json:"source"`
+}
+
+// RepoPagesSource is the branch and directory a Pages site is built from.
+type RepoPagesSource struct {
+	Branch string `json:"branch"`
+	Path   string `json:"path"`
 }
 
 // RepoRequest contains metadata used in requests to create or update a Repo.
@@ -646,6 +667,87 @@ type RestrictionsRequest struct {
 	Teams *[]string `json:"teams,omitempty"`
 }
 
+// Ruleset rule types, targets and enforcements.
+const (
+	RulesetRulePullRequest           = "pull_request"
+	RulesetRuleRequiredStatusChecks  = "required_status_checks"
+	RulesetRuleRequiredLinearHistory = "required_linear_history"
+	RulesetRuleNonFastForward        = "non_fast_forward"
+	RulesetRuleDeletion              = "deletion"
+
+	RulesetTargetBranch      = "branch"
+	RulesetEnforcementActive = "active"
+)
+
+// RulesetAdminRoleID is the actor ID of the repository admin role in
+// ruleset bypass actors.
+const RulesetAdminRoleID = 5
+
+// Ruleset is a repository ruleset.
+// See https://docs.github.com/en/rest/repos/rules
+type Ruleset struct {
+	ID           int                  `json:"id,omitempty"`
+	Name         string               `json:"name"`
+	Target       string               `json:"target"`
+	Enforcement  string               `json:"enforcement"`
+	BypassActors []RulesetBypassActor `json:"bypass_actors"`
+	Conditions   *RulesetConditions   `json:"conditions,omitempty"`
+	Rules        []RulesetRule        `json:"rules"`
+}
+
+func (r Ruleset) String() string {
+	bytes, err := json.Marshal(&r)
+	if err != nil {
+		return fmt.Sprintf("%#v", r)
+	}
+	return string(bytes)
+}
+
+// RulesetBypassActor is an actor that can bypass a ruleset.
+type RulesetBypassActor struct {
+	ActorID    int    `json:"actor_id"`
+	ActorType  string `json:"actor_type"`
+	BypassMode string `json:"bypass_mode"`
+}
+
+// RulesetConditions selects the refs a ruleset applies to.
+type RulesetConditions struct {
+	RefName RulesetRefNameCondition `json:"ref_name"`
+}
+
+// RulesetRefNameCondition includes and excludes refs by name.
+type RulesetRefNameCondition struct {
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+}
+
+// RulesetRule is a rule of a ruleset. Only pull_request and
+// required_status_checks rules have parameters.
+type RulesetRule struct {
+	Type       string                 `json:"type"`
+	Parameters *RulesetRuleParameters `json:"parameters,omitempty"`
+}
+
+// RulesetRuleParameters are the parameters of pull_request and
+// required_status_checks rules.
+type RulesetRuleParameters struct {
+	// pull_request
+	RequiredApprovingReviewCount   *int  `json:"required_approving_review_count,omitempty"`
+	DismissStaleReviewsOnPush      *bool `json:"dismiss_stale_reviews_on_push,omitempty"`
+	RequireCodeOwnerReview         *bool `json:"require_code_owner_review,omitempty"`
+	RequireLastPushApproval        *bool `json:"require_last_push_approval,omitempty"`
+	RequiredReviewThreadResolution *bool `json:"required_review_thread_resolution,omitempty"`
+
+	// required_status_checks
+	RequiredStatusChecks             []RulesetStatusCheck `json:"required_status_checks,omitempty"`
+	StrictRequiredStatusChecksPolicy *bool                `json:"strict_required_status_checks_policy,omitempty"`
+}
+
+// RulesetStatusCheck is a status check required by a ruleset.
+type RulesetStatusCheck struct {
+	Context string `json:"context"`
+}
+
 // HookConfig holds the endpoint and its secret.
 type HookConfig struct {
 	URL         string  `json:"url"`
@@ -1222,6 +1324,18 @@ type Milestone struct {
 	Number int    `json:"number"`
 }
 
+// CommitComparison is the comparison of a head commit with a base commit.
+// See https://docs.github.com/en/rest/commits/commits#compare-two-commits
+type CommitComparison struct {
+	// Status is one of diverged, ahead, behind or identical.
+	Status string `json:"status"`
+	// AheadBy is the number of commits of the head missing from the base.
+	AheadBy int `json:"ahead_by"`
+	// BehindBy is the number of commits of the base missing from the head.
+	BehindBy        int              `json:"behind_by"`
+	MergeBaseCommit RepositoryCommit `json:"merge_base_commit"`
+}
+
 // RepositoryCommit represents a commit in a repo.
 // Note that it's wrapping a GitCommit, so author/committer information is in two places,
 // but contain different details about them: in RepositoryCommit "github details", in GitCommit - "git details".
@@ -41,6 +41,27 @@ var (
 		Name: "prow_plugin_handle_errors",
 		Help: "Prow errors handling an event by plugin, event type and action",
 	}, []string{"event_type", "action", "plugin"})
+	pluginQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prow_plugin_queue_depth",
+		Help: "How many events are waiting for a plugin to handle them because of its concurrency limits.",
+	}, []string{"plugin"})
+	pluginQueueWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "prow_plugin_queue_wait_seconds",
+		Help:    "How long events waited for a plugin to handle them because of its concurrency limits.",
+		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40, 80, 160, 320, 640},
+	}, []string{"plugin"})
+	externalPluginHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prow_external_plugin_healthy",
+		Help: "Whether an external plugin passes its health checks, events are not forwarded to it when it doesn't.",
+	}, []string{"plugin", "endpoint"})
+	externalPluginHealthCheckFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_external_plugin_health_check_failures",
+		Help: "A counter of the failed health checks of the external plugins.",
+	}, []string{"plugin", "endpoint"})
+	externalPluginSkippedEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_external_plugin_skipped_events",
+		Help: "A counter of the events not forwarded to the external plugins because they were unhealthy.",
+	}, []string{"plugin", "endpoint"})
 )
 
 func init() {
@@ -48,6 +69,11 @@ func init() {
 	prometheus.MustRegister(responseCounter)
 	prometheus.MustRegister(pluginHandleDuration)
 	prometheus.MustRegister(pluginHandleErrors)
+	prometheus.MustRegister(pluginQueueDepth)
+	prometheus.MustRegister(pluginQueueWait)
+	prometheus.MustRegister(externalPluginHealthy)
+	prometheus.MustRegister(externalPluginHealthCheckFailures)
+	prometheus.MustRegister(externalPluginSkippedEvents)
 }
 
 // Metrics is a set of metrics gathered by hook.
@@ -56,6 +82,12 @@ type Metrics struct {
 	ResponseCounter      *prometheus.CounterVec
 	PluginHandleDuration *prometheus.HistogramVec
 	PluginHandleErrors   *prometheus.CounterVec
+	PluginQueueDepth     *prometheus.GaugeVec
+	PluginQueueWait      *prometheus.HistogramVec
+
+	ExternalPluginHealthy             *prometheus.GaugeVec
+	ExternalPluginHealthCheckFailures *prometheus.CounterVec
+	ExternalPluginSkippedEvents       *prometheus.CounterVec
 	*plugins.Metrics
 }
 
@@ -73,6 +105,12 @@ func NewMetrics() *Metrics {
 		ResponseCounter:      responseCounter,
 		PluginHandleDuration: pluginHandleDuration,
 		PluginHandleErrors:   pluginHandleErrors,
-		Metrics:              plugins.NewMetrics(),
+		PluginQueueDepth:     pluginQueueDepth,
+		PluginQueueWait:      pluginQueueWait,
+
+		ExternalPluginHealthy:             externalPluginHealthy,
+		ExternalPluginHealthCheckFailures: externalPluginHealthCheckFailures,
+		ExternalPluginSkippedEvents:       externalPluginSkippedEvents,
+		Metrics:                           plugins.NewMetrics(),
 	}
 }
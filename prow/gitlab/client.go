@@ -0,0 +1,470 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitlab is a minimal client for the GitLab REST API, covering what
+// Tide needs to merge GitLab merge requests and what the comment-command
+// plugins need to act on them. It also validates and parses GitLab webhooks.
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/version"
+)
+
+// Client interacts with the GitLab REST API. Projects are identified by their
+// full path, for example group/project, and merge requests by their IID.
+type Client interface {
+	ListGroupMergeRequests(group string, opts ListMergeRequestsOptions) ([]MergeRequest, error)
+	ListProjectMergeRequests(project string, opts ListMergeRequestsOptions) ([]MergeRequest, error)
+	GetMergeRequestApprovals(project string, iid int) (*MergeRequestApprovals, error)
+	GetMergeRequestChanges(project string, iid int) ([]MergeRequestChange, error)
+	AcceptMergeRequest(project string, iid int, opts AcceptMergeRequestOptions) error
+	AddToMergeTrain(project string, iid int, sha string) error
+	RebaseMergeRequest(project string, iid int) error
+	ListCommitStatuses(project, sha string) ([]CommitStatus, error)
+	SetCommitStatus(project, sha string, status CommitStatus) error
+	GetBranch(project, branch string) (*Branch, error)
+	Compare(project, from, to string) (*Comparison, error)
+	GetProject(project string) (*Project, error)
+
+	GetMergeRequest(project string, iid int) (*MergeRequest, error)
+	GetIssue(project string, iid int) (*Issue, error)
+	UpdateNoteable(n Noteable, opts UpdateNoteableOptions) error
+	ListNotes(n Noteable) ([]Note, error)
+	CreateNote(n Noteable, body string) error
+	UpdateNote(n Noteable, id int, body string) error
+	DeleteNote(n Noteable, id int) error
+	GetCurrentUser() (*User, error)
+	GetUser(id int) (*User, error)
+	FindUser(username string) (*User, error)
+	GetProjectMember(project, username string) (*ProjectMember, error)
+}
+
+type client struct {
+	logger   *logrus.Entry
+	endpoint string
+	getToken func() []byte
+	client   *http.Client
+	dryRun   bool
+}
+
+// NewClient returns a client for the GitLab instance at the endpoint, for
+// example https://gitlab.com. The token is sent as a private token. In dry run
+// the client only logs the requests that would mutate GitLab.
+func NewClient(getToken func() []byte, endpoint string, dryRun bool) Client {
+	retryingClient := retryablehttp.NewClient()
+	retryingClient.Logger = nil
+	return &client{
+		logger:   logrus.WithField("client", "gitlab"),
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		getToken: getToken,
+		client:   retryingClient.StandardClient(),
+		dryRun:   dryRun,
+	}
+}
+
+func projectPath(project string) string {
+	return "/projects/" + url.PathEscape(project)
+}
+
+func mergeRequestPath(project string, iid int) string {
+	return fmt.Sprintf("%s/merge_requests/%d", projectPath(project), iid)
+}
+
+// request sends a request to the API and decodes the response into target,
+// unless it is nil. Values are sent in the query string of GET requests and
+// form-encoded otherwise. It returns the next page of paginated responses.
+func (c *client) request(method, path string, values url.Values, target interface{}) (int, error) {
+	logger := c.logger.WithFields(logrus.Fields{"method": method, "path": path})
+	if c.dryRun && method != http.MethodGet {
+		logger.WithField("body", values.Encode()).Info("Dry run: not sending request to GitLab.")
+		return 0, nil
+	}
+	u := c.endpoint + "/api/v4" + path
+	var body *strings.Reader
+	if method == http.MethodGet {
+		if len(values) > 0 {
+			u += "?" + values.Encode()
+		}
+		body = strings.NewReader("")
+	} else {
+		body = strings.NewReader(values.Encode())
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return 0, err
+	}
+	if method != http.MethodGet {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if token := c.getToken(); len(token) > 0 {
+		req.Header.Set("PRIVATE-TOKEN", string(token))
+	}
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.WithError(err).Warn("could not close response body")
+		}
+	}()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+	logger.WithField("response", resp.StatusCode).Debug("Got response from GitLab.")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var msg struct {
+			Message interface{} `json:"message"`
+			Error   string      `json:"error"`
+		}
+		message := string(raw)
+		if err := json.Unmarshal(raw, &msg); err == nil {
+			if msg.Message != nil {
+				message = fmt.Sprint(msg.Message)
+			} else if msg.Error != "" {
+				message = msg.Error
+			}
+		}
+		return 0, &RequestError{StatusCode: resp.StatusCode, Message: message}
+	}
+	if target != nil {
+		if err := json.Unmarshal(raw, target); err != nil {
+			return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+	nextPage, _ := strconv.Atoi(resp.Header.Get("X-Next-Page"))
+	return nextPage, nil
+}
+
+func noteablePath(n Noteable) string {
+	return fmt.Sprintf("%s/%s/%d", projectPath(n.Project), n.Kind, n.IID)
+}
+
+func (o ListMergeRequestsOptions) values() url.Values {
+	values := url.Values{}
+	values.Set("state", "opened")
+	values.Set("scope", "all")
+	values.Set("per_page", "100")
+	if len(o.Labels) > 0 {
+		values.Set("labels", strings.Join(o.Labels, ","))
+	}
+	if len(o.NotLabels) > 0 {
+		values.Set("not[labels]", strings.Join(o.NotLabels, ","))
+	}
+	if o.TargetBranch != "" {
+		values.Set("target_branch", o.TargetBranch)
+	}
+	if o.Milestone != "" {
+		values.Set("milestone", o.Milestone)
+	}
+	if o.AuthorUsername != "" {
+		values.Set("author_username", o.AuthorUsername)
+	}
+	return values
+}
+
+func (c *client) listMergeRequests(path string, opts ListMergeRequestsOptions) ([]MergeRequest, error) {
+	values := opts.values()
+	var mrs []MergeRequest
+	for page := 1; page > 0; {
+		values.Set("page", strconv.Itoa(page))
+		var mrPage []MergeRequest
+		next, err := c.request(http.MethodGet, path, values, &mrPage)
+		if err != nil {
+			return mrs, err
+		}
+		mrs = append(mrs, mrPage...)
+		page = next
+	}
+	return mrs, nil
+}
+
+// ListGroupMergeRequests lists the open merge requests of the projects of a group.
+func (c *client) ListGroupMergeRequests(group string, opts ListMergeRequestsOptions) ([]MergeRequest, error) {
+	return c.listMergeRequests("/groups/"+url.PathEscape(group)+"/merge_requests", opts)
+}
+
+// ListProjectMergeRequests lists the open merge requests of a project.
+func (c *client) ListProjectMergeRequests(project string, opts ListMergeRequestsOptions) ([]MergeRequest, error) {
+	return c.listMergeRequests(projectPath(project)+"/merge_requests", opts)
+}
+
+// GetMergeRequestApprovals returns whether a merge request is approved.
+func (c *client) GetMergeRequestApprovals(project string, iid int) (*MergeRequestApprovals, error) {
+	var approvals MergeRequestApprovals
+	_, err := c.request(http.MethodGet, mergeRequestPath(project, iid)+"/approvals", nil, &approvals)
+	return &approvals, err
+}
+
+// GetMergeRequestChanges returns the files changed by a merge request.
+func (c *client) GetMergeRequestChanges(project string, iid int) ([]MergeRequestChange, error) {
+	var changes struct {
+		Changes []MergeRequestChange `json:"changes"`
+	}
+	_, err := c.request(http.MethodGet, mergeRequestPath(project, iid)+"/changes", nil, &changes)
+	return changes.Changes, err
+}
+
+// AcceptMergeRequest merges a merge request.
+func (c *client) AcceptMergeRequest(project string, iid int, opts AcceptMergeRequestOptions) error {
+	values := url.Values{}
+	if opts.SHA != "" {
+		values.Set("sha", opts.SHA)
+	}
+	if opts.Squash {
+		values.Set("squash", "true")
+	}
+	if opts.MergeCommitMessage != "" {
+		values.Set("merge_commit_message", opts.MergeCommitMessage)
+	}
+	if opts.SquashCommitMessage != "" {
+		values.Set("squash_commit_message", opts.SquashCommitMessage)
+	}
+	_, err := c.request(http.MethodPut, mergeRequestPath(project, iid)+"/merge", values, nil)
+	return err
+}
+
+// AddToMergeTrain adds a merge request to the merge train of its target branch.
+func (c *client) AddToMergeTrain(project string, iid int, sha string) error {
+	values := url.Values{}
+	if sha != "" {
+		values.Set("sha", sha)
+	}
+	_, err := c.request(http.MethodPost, fmt.Sprintf("%s/merge_trains/merge_requests/%d", projectPath(project), iid), values, nil)
+	return err
+}
+
+// RebaseMergeRequest rebases the source branch of a merge request on its
+// target branch.
+func (c *client) RebaseMergeRequest(project string, iid int) error {
+	_, err := c.request(http.MethodPut, mergeRequestPath(project, iid)+"/rebase", nil, nil)
+	return err
+}
+
+// ListCommitStatuses lists the latest status of every job and external
+// system for a commit.
+func (c *client) ListCommitStatuses(project, sha string) ([]CommitStatus, error) {
+	values := url.Values{}
+	values.Set("per_page", "100")
+	var statuses []CommitStatus
+	for page := 1; page > 0; {
+		values.Set("page", strconv.Itoa(page))
+		var statusPage []CommitStatus
+		next, err := c.request(http.MethodGet, fmt.Sprintf("%s/repository/commits/%s/statuses", projectPath(project), sha), values, &statusPage)
+		if err != nil {
+			return statuses, err
+		}
+		statuses = append(statuses, statusPage...)
+		page = next
+	}
+	return statuses, nil
+}
+
+// SetCommitStatus sets the status of a commit.
+func (c *client) SetCommitStatus(project, sha string, status CommitStatus) error {
+	values := url.Values{}
+	values.Set("state", status.Status)
+	values.Set("name", status.Name)
+	if status.Description != "" {
+		values.Set("description", status.Description)
+	}
+	if status.TargetURL != "" {
+		values.Set("target_url", status.TargetURL)
+	}
+	_, err := c.request(http.MethodPost, fmt.Sprintf("%s/statuses/%s", projectPath(project), sha), values, nil)
+	return err
+}
+
+// GetBranch returns a branch of a project.
+func (c *client) GetBranch(project, branch string) (*Branch, error) {
+	var b Branch
+	_, err := c.request(http.MethodGet, projectPath(project)+"/repository/branches/"+url.PathEscape(branch), nil, &b)
+	return &b, err
+}
+
+// Compare compares two commits of a project, listing the commits of to that
+// are missing from from.
+func (c *client) Compare(project, from, to string) (*Comparison, error) {
+	values := url.Values{}
+	values.Set("from", from)
+	values.Set("to", to)
+	var comparison Comparison
+	_, err := c.request(http.MethodGet, projectPath(project)+"/repository/compare", values, &comparison)
+	return &comparison, err
+}
+
+// GetProject returns a project.
+func (c *client) GetProject(project string) (*Project, error) {
+	var p Project
+	_, err := c.request(http.MethodGet, projectPath(project), nil, &p)
+	return &p, err
+}
+
+// GetMergeRequest returns a merge request.
+func (c *client) GetMergeRequest(project string, iid int) (*MergeRequest, error) {
+	var mr MergeRequest
+	_, err := c.request(http.MethodGet, mergeRequestPath(project, iid), nil, &mr)
+	return &mr, err
+}
+
+// GetIssue returns an issue.
+func (c *client) GetIssue(project string, iid int) (*Issue, error) {
+	var issue Issue
+	_, err := c.request(http.MethodGet, fmt.Sprintf("%s/issues/%d", projectPath(project), iid), nil, &issue)
+	return &issue, err
+}
+
+func joinIDs(ids []int) string {
+	s := make([]string, 0, len(ids))
+	for _, id := range ids {
+		s = append(s, strconv.Itoa(id))
+	}
+	return strings.Join(s, ",")
+}
+
+// UpdateNoteable changes the labels, assignees or reviewers of a merge request
+// or an issue.
+func (c *client) UpdateNoteable(n Noteable, opts UpdateNoteableOptions) error {
+	values := url.Values{}
+	if len(opts.AddLabels) > 0 {
+		values.Set("add_labels", strings.Join(opts.AddLabels, ","))
+	}
+	if len(opts.RemoveLabels) > 0 {
+		values.Set("remove_labels", strings.Join(opts.RemoveLabels, ","))
+	}
+	// An empty list of IDs is sent as 0, which unassigns everyone.
+	if opts.AssigneeIDs != nil {
+		values.Set("assignee_ids", joinIDs(*opts.AssigneeIDs))
+		if len(*opts.AssigneeIDs) == 0 {
+			values.Set("assignee_ids", "0")
+		}
+	}
+	if opts.ReviewerIDs != nil {
+		values.Set("reviewer_ids", joinIDs(*opts.ReviewerIDs))
+		if len(*opts.ReviewerIDs) == 0 {
+			values.Set("reviewer_ids", "0")
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	_, err := c.request(http.MethodPut, noteablePath(n), values, nil)
+	return err
+}
+
+// ListNotes lists the notes of a merge request or an issue, oldest first.
+func (c *client) ListNotes(n Noteable) ([]Note, error) {
+	values := url.Values{}
+	values.Set("per_page", "100")
+	values.Set("sort", "asc")
+	values.Set("order_by", "created_at")
+	var notes []Note
+	for page := 1; page > 0; {
+		values.Set("page", strconv.Itoa(page))
+		var notePage []Note
+		next, err := c.request(http.MethodGet, noteablePath(n)+"/notes", values, &notePage)
+		if err != nil {
+			return notes, err
+		}
+		notes = append(notes, notePage...)
+		page = next
+	}
+	return notes, nil
+}
+
+// CreateNote comments on a merge request or an issue.
+func (c *client) CreateNote(n Noteable, body string) error {
+	values := url.Values{}
+	values.Set("body", body)
+	_, err := c.request(http.MethodPost, noteablePath(n)+"/notes", values, nil)
+	return err
+}
+
+// UpdateNote changes the body of a note.
+func (c *client) UpdateNote(n Noteable, id int, body string) error {
+	values := url.Values{}
+	values.Set("body", body)
+	_, err := c.request(http.MethodPut, fmt.Sprintf("%s/notes/%d", noteablePath(n), id), values, nil)
+	return err
+}
+
+// DeleteNote deletes a note.
+func (c *client) DeleteNote(n Noteable, id int) error {
+	_, err := c.request(http.MethodDelete, fmt.Sprintf("%s/notes/%d", noteablePath(n), id), nil, nil)
+	return err
+}
+
+// GetCurrentUser returns the user the client authenticates as.
+func (c *client) GetCurrentUser() (*User, error) {
+	var u User
+	_, err := c.request(http.MethodGet, "/user", nil, &u)
+	return &u, err
+}
+
+// GetUser returns a user by ID.
+func (c *client) GetUser(id int) (*User, error) {
+	var u User
+	_, err := c.request(http.MethodGet, fmt.Sprintf("/users/%d", id), nil, &u)
+	return &u, err
+}
+
+// FindUser returns a user by username. It returns a RequestError with status
+// 404 if there is no such user.
+func (c *client) FindUser(username string) (*User, error) {
+	values := url.Values{}
+	values.Set("username", username)
+	var users []User
+	if _, err := c.request(http.MethodGet, "/users", values, &users); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, &RequestError{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("user %q not found", username)}
+	}
+	return &users[0], nil
+}
+
+// GetProjectMember returns a member of a project, including the members
+// inherited from its groups. It returns a RequestError with status 404 if the
+// user is no member.
+func (c *client) GetProjectMember(project, username string) (*ProjectMember, error) {
+	values := url.Values{}
+	values.Set("query", username)
+	values.Set("per_page", "100")
+	var members []ProjectMember
+	if _, err := c.request(http.MethodGet, projectPath(project)+"/members/all", values, &members); err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		if strings.EqualFold(member.Username, username) {
+			return &member, nil
+		}
+	}
+	return nil, &RequestError{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("%s is no member of %s", username, project)}
+}
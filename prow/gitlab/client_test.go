@@ -0,0 +1,175 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestListProjectMergeRequests(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "token" {
+			t.Errorf("missing private token")
+		}
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fproject/merge_requests" {
+			t.Errorf("unexpected path %q", r.URL.EscapedPath())
+		}
+		query := r.URL.Query()
+		if query.Get("state") != "opened" || query.Get("labels") != "lgtm,approved" || query.Get("not[labels]") != "hold" {
+			t.Errorf("unexpected query %q", r.URL.RawQuery)
+		}
+		switch query.Get("page") {
+		case "1":
+			w.Header().Set("X-Next-Page", "2")
+			fmt.Fprint(w, `[{"iid": 1, "references": {"full": "group/project!1"}}]`)
+		case "2":
+			fmt.Fprint(w, `[{"iid": 2, "references": {"full": "group/project!2"}}]`)
+		default:
+			t.Errorf("unexpected page %q", query.Get("page"))
+		}
+	}))
+	defer s.Close()
+
+	c := NewClient(func() []byte { return []byte("token") }, s.URL, false)
+	mrs, err := c.ListProjectMergeRequests("group/project", ListMergeRequestsOptions{
+		Labels:    []string{"lgtm", "approved"},
+		NotLabels: []string{"hold"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var iids []int
+	for _, mr := range mrs {
+		iids = append(iids, mr.IID)
+		if project, err := mr.Project(); err != nil || project != "group/project" {
+			t.Errorf("expected project group/project, got %q (%v)", project, err)
+		}
+	}
+	if diff := cmp.Diff([]int{1, 2}, iids); diff != "" {
+		t.Errorf("merge requests differ from expected: %s", diff)
+	}
+}
+
+func TestAcceptMergeRequest(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.EscapedPath() != "/api/v4/projects/group%2Fproject/merge_requests/3/merge" {
+			t.Errorf("unexpected request %s %q", r.Method, r.URL.EscapedPath())
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("failed to parse form: %v", err)
+		}
+		if r.PostForm.Get("sha") != "abc" || r.PostForm.Get("squash") != "true" {
+			t.Errorf("unexpected form %v", r.PostForm)
+		}
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"message": "SHA does not match HEAD of source branch"}`)
+	}))
+	defer s.Close()
+
+	c := NewClient(func() []byte { return nil }, s.URL, false)
+	err := c.AcceptMergeRequest("group/project", 3, AcceptMergeRequestOptions{SHA: "abc", Squash: true})
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("expected a request error, got %v", err)
+	}
+	if reqErr.StatusCode != http.StatusConflict || reqErr.Message != "SHA does not match HEAD of source branch" {
+		t.Errorf("unexpected error %v", reqErr)
+	}
+}
+
+func TestUpdateNoteable(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.EscapedPath() != "/api/v4/projects/group%2Fproject/issues/4" {
+			t.Errorf("unexpected request %s %q", r.Method, r.URL.EscapedPath())
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("failed to parse form: %v", err)
+		}
+		expected := "add_labels=lgtm&assignee_ids=0&remove_labels=hold"
+		if r.PostForm.Encode() != expected {
+			t.Errorf("expected form %q, got %q", expected, r.PostForm.Encode())
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer s.Close()
+
+	c := NewClient(func() []byte { return nil }, s.URL, false)
+	err := c.UpdateNoteable(Noteable{Project: "group/project", Kind: NoteableIssue, IID: 4}, UpdateNoteableOptions{
+		AddLabels:    []string{"lgtm"},
+		RemoveLabels: []string{"hold"},
+		AssigneeIDs:  &[]int{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGetProjectMember(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fproject/members/all" {
+			t.Errorf("unexpected path %q", r.URL.EscapedPath())
+		}
+		// The query matches usernames and names by prefix.
+		fmt.Fprint(w, `[{"id": 1, "username": "alice2", "access_level": 50}, {"id": 2, "username": "Alice", "access_level": 30}]`)
+	}))
+	defer s.Close()
+
+	c := NewClient(func() []byte { return nil }, s.URL, false)
+	member, err := c.GetProjectMember("group/project", "alice")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if member.ID != 2 || member.AccessLevel != AccessLevelDeveloper {
+		t.Errorf("unexpected member %+v", member)
+	}
+	_, err = c.GetProjectMember("group/project", "bob")
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) || reqErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected %s request %q in dry run", r.Method, r.URL.EscapedPath())
+		}
+		fmt.Fprint(w, `{"iid": 3, "sha": "cafe"}`)
+	}))
+	defer s.Close()
+
+	c := NewClient(func() []byte { return nil }, s.URL, true)
+	mr, err := c.GetMergeRequest("group/project", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mr.IID != 3 {
+		t.Errorf("expected merge request 3, got %d", mr.IID)
+	}
+	if err := c.AcceptMergeRequest("group/project", 3, AcceptMergeRequestOptions{SHA: "cafe"}); err != nil {
+		t.Errorf("unexpected error accepting the merge request: %v", err)
+	}
+	if err := c.CreateNote(Noteable{Project: "group/project", Kind: NoteableMergeRequest, IID: 3}, "/lgtm"); err != nil {
+		t.Errorf("unexpected error creating the note: %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Merge statuses of merge requests.
+const (
+	MergeStatusCanBeMerged    = "can_be_merged"
+	MergeStatusCannotBeMerged = "cannot_be_merged"
+)
+
+// States of commit statuses and pipelines.
+const (
+	StatusCreated  = "created"
+	StatusPending  = "pending"
+	StatusRunning  = "running"
+	StatusSuccess  = "success"
+	StatusFailed   = "failed"
+	StatusCanceled = "canceled"
+	StatusSkipped  = "skipped"
+	StatusManual   = "manual"
+)
+
+// User is a GitLab user.
+type User struct {
+	ID       int    `json:"id,omitempty"`
+	Username string `json:"username"`
+}
+
+// Milestone is a GitLab milestone.
+type Milestone struct {
+	Title string `json:"title"`
+}
+
+// References holds the references to a merge request.
+type References struct {
+	// Full is the reference of the merge request including its project,
+	// for example group/project!1.
+	Full string `json:"full"`
+}
+
+// MergeRequest is a GitLab merge request.
+// See https://docs.gitlab.com/ee/api/merge_requests.html
+type MergeRequest struct {
+	IID          int        `json:"iid"`
+	ProjectID    int        `json:"project_id"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	State        string     `json:"state"`
+	TargetBranch string     `json:"target_branch"`
+	SourceBranch string     `json:"source_branch"`
+	SHA          string     `json:"sha"`
+	Author       User       `json:"author"`
+	Assignees    []User     `json:"assignees"`
+	Reviewers    []User     `json:"reviewers"`
+	Labels       []string   `json:"labels"`
+	Milestone    *Milestone `json:"milestone"`
+	MergeStatus  string     `json:"merge_status"`
+	HasConflicts bool       `json:"has_conflicts"`
+	References   References `json:"references"`
+	WebURL       string     `json:"web_url"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Project returns the path of the project of the merge request, for example
+// group/project.
+func (mr MergeRequest) Project() (string, error) {
+	idx := strings.LastIndex(mr.References.Full, "!")
+	if idx < 1 {
+		return "", fmt.Errorf("merge request %d has invalid reference %q", mr.IID, mr.References.Full)
+	}
+	return mr.References.Full[:idx], nil
+}
+
+// ListMergeRequestsOptions filters the listed merge requests. Only open merge
+// requests are listed.
+type ListMergeRequestsOptions struct {
+	// Labels are the labels the merge requests must all have.
+	Labels []string
+	// NotLabels are the labels the merge requests must not have.
+	NotLabels      []string
+	TargetBranch   string
+	Milestone      string
+	AuthorUsername string
+}
+
+// MergeRequestApprovals is the approval state of a merge request.
+type MergeRequestApprovals struct {
+	Approved bool `json:"approved"`
+}
+
+// MergeRequestChange is a file changed by a merge request.
+type MergeRequestChange struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	NewFile     bool   `json:"new_file"`
+	RenamedFile bool   `json:"renamed_file"`
+	DeletedFile bool   `json:"deleted_file"`
+}
+
+// AcceptMergeRequestOptions configures how a merge request is merged.
+type AcceptMergeRequestOptions struct {
+	// SHA is the head the merge request must have to be merged.
+	SHA                 string
+	Squash              bool
+	MergeCommitMessage  string
+	SquashCommitMessage string
+}
+
+// CommitStatus is the status of a commit, set by pipeline jobs or external
+// systems.
+type CommitStatus struct {
+	ID          int    `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Description string `json:"description"`
+	TargetURL   string `json:"target_url"`
+}
+
+// Commit is a GitLab commit.
+type Commit struct {
+	ID string `json:"id"`
+}
+
+// Branch is a GitLab branch.
+type Branch struct {
+	Name   string `json:"name"`
+	Commit Commit `json:"commit"`
+}
+
+// Comparison is the comparison of two commits.
+type Comparison struct {
+	Commits []Commit `json:"commits"`
+}
+
+// Project is a GitLab project.
+type Project struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+	// MergeMethod is one of merge, rebase_merge and ff.
+	MergeMethod string `json:"merge_method"`
+	// SquashOption is one of never, always, default_on and default_off.
+	SquashOption string `json:"squash_option"`
+}
+
+// Issue is a GitLab issue.
+// See https://docs.gitlab.com/ee/api/issues.html
+type Issue struct {
+	IID         int      `json:"iid"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	State       string   `json:"state"`
+	Author      User     `json:"author"`
+	Assignees   []User   `json:"assignees"`
+	Labels      []string `json:"labels"`
+	WebURL      string   `json:"web_url"`
+}
+
+// Kinds of the objects notes are attached to, as they appear in API paths.
+const (
+	NoteableMergeRequest = "merge_requests"
+	NoteableIssue        = "issues"
+)
+
+// Noteable identifies a merge request or an issue of a project, which notes
+// can be attached to.
+type Noteable struct {
+	Project string
+	// Kind is NoteableMergeRequest or NoteableIssue.
+	Kind string
+	IID  int
+}
+
+// Note is a comment on a merge request or an issue.
+type Note struct {
+	ID     int    `json:"id"`
+	Body   string `json:"body"`
+	Author User   `json:"author"`
+	// System notes are created by GitLab for changes like added labels.
+	System    bool      `json:"system"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UpdateNoteableOptions are the changes made to a merge request or an issue.
+// Nil assignees or reviewers are left unchanged.
+type UpdateNoteableOptions struct {
+	AddLabels    []string
+	RemoveLabels []string
+	AssigneeIDs  *[]int
+	// ReviewerIDs only apply to merge requests.
+	ReviewerIDs *[]int
+}
+
+// Access levels of the members of projects.
+const (
+	AccessLevelGuest      = 10
+	AccessLevelReporter   = 20
+	AccessLevelDeveloper  = 30
+	AccessLevelMaintainer = 40
+	AccessLevelOwner      = 50
+)
+
+// ProjectMember is a member of a project, directly or through its groups.
+type ProjectMember struct {
+	User
+	AccessLevel int `json:"access_level"`
+}
+
+// RequestError is returned when GitLab answers a request with an error.
+type RequestError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("code %d: %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"crypto/subtle"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Types of the webhook events, as sent in the X-Gitlab-Event header.
+// See https://docs.gitlab.com/ee/user/project/integrations/webhooks.html
+const (
+	MergeRequestHook = "Merge Request Hook"
+	NoteHook         = "Note Hook"
+	PipelineHook     = "Pipeline Hook"
+)
+
+// Actions of merge request events.
+const (
+	MergeRequestActionOpen       = "open"
+	MergeRequestActionClose      = "close"
+	MergeRequestActionReopen     = "reopen"
+	MergeRequestActionUpdate     = "update"
+	MergeRequestActionMerge      = "merge"
+	MergeRequestActionApproved   = "approved"
+	MergeRequestActionUnapproved = "unapproved"
+)
+
+// Actions of note events. Older GitLab versions send no action, which means
+// the note was created.
+const (
+	NoteActionCreate = "create"
+	NoteActionUpdate = "update"
+)
+
+// Types of the objects notes are attached to, as sent in note events.
+const (
+	NoteableTypeMergeRequest = "MergeRequest"
+	NoteableTypeIssue        = "Issue"
+	NoteableTypeCommit       = "Commit"
+	NoteableTypeSnippet      = "Snippet"
+)
+
+// ValidateWebhook ensures that the provided request conforms to the format of a
+// GitLab webhook and carries the secret token. It returns the event type, the
+// event UUID, the payload of the request, whether the webhook is valid or not,
+// and finally the resultant HTTP status code.
+func ValidateWebhook(w http.ResponseWriter, r *http.Request, tokenGenerator func() []byte) (string, string, []byte, bool, int) {
+	defer r.Body.Close()
+
+	if r.Method != http.MethodPost {
+		responseHTTPError(w, http.StatusMethodNotAllowed, "405 Method not allowed")
+		return "", "", nil, false, http.StatusMethodNotAllowed
+	}
+	eventType := r.Header.Get("X-Gitlab-Event")
+	if eventType == "" {
+		responseHTTPError(w, http.StatusBadRequest, "400 Bad Request: Missing X-Gitlab-Event Header")
+		return "", "", nil, false, http.StatusBadRequest
+	}
+	token := r.Header.Get("X-Gitlab-Token")
+	if token == "" {
+		responseHTTPError(w, http.StatusForbidden, "403 Forbidden: Missing X-Gitlab-Token")
+		return "", "", nil, false, http.StatusForbidden
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(strings.TrimSpace(string(tokenGenerator())))) != 1 {
+		responseHTTPError(w, http.StatusForbidden, "403 Forbidden: Invalid X-Gitlab-Token")
+		return "", "", nil, false, http.StatusForbidden
+	}
+	if contentType := r.Header.Get("content-type"); contentType != "application/json" {
+		responseHTTPError(w, http.StatusBadRequest, "400 Bad Request: Hook only accepts content-type: application/json - please reconfigure this hook on GitLab")
+		return "", "", nil, false, http.StatusBadRequest
+	}
+	payload, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responseHTTPError(w, http.StatusInternalServerError, "500 Internal Server Error: Failed to read request body")
+		return "", "", nil, false, http.StatusInternalServerError
+	}
+	// Older GitLab versions don't identify the events.
+	eventUUID := r.Header.Get("X-Gitlab-Event-UUID")
+
+	return eventType, eventUUID, payload, true, http.StatusOK
+}
+
+func responseHTTPError(w http.ResponseWriter, statusCode int, response string) {
+	logrus.WithFields(logrus.Fields{
+		"response":    response,
+		"status-code": statusCode,
+	}).Debug(response)
+	http.Error(w, response, statusCode)
+}
+
+// EventProject is the project of a webhook event.
+type EventProject struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+}
+
+// EventUser is the user of a webhook event.
+type EventUser struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+}
+
+// EventLabel is a label of a webhook event.
+type EventLabel struct {
+	Title string `json:"title"`
+}
+
+// EventCommit is the commit of a webhook event.
+type EventCommit struct {
+	ID string `json:"id"`
+}
+
+// EventMergeRequest is a merge request of a webhook event.
+type EventMergeRequest struct {
+	ID           int          `json:"id"`
+	IID          int          `json:"iid"`
+	Title        string       `json:"title"`
+	Description  string       `json:"description"`
+	State        string       `json:"state"`
+	AuthorID     int          `json:"author_id"`
+	AssigneeIDs  []int        `json:"assignee_ids"`
+	SourceBranch string       `json:"source_branch"`
+	TargetBranch string       `json:"target_branch"`
+	LastCommit   EventCommit  `json:"last_commit"`
+	URL          string       `json:"url"`
+	Labels       []EventLabel `json:"labels"`
+	Draft        bool         `json:"work_in_progress"`
+	// Action and OldRev are only set in merge request events. OldRev is the
+	// previous head when an update pushed commits.
+	Action string `json:"action"`
+	OldRev string `json:"oldrev"`
+}
+
+// EventIssue is an issue of a webhook event.
+type EventIssue struct {
+	ID          int          `json:"id"`
+	IID         int          `json:"iid"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	State       string       `json:"state"`
+	AuthorID    int          `json:"author_id"`
+	AssigneeIDs []int        `json:"assignee_ids"`
+	URL         string       `json:"url"`
+	Labels      []EventLabel `json:"labels"`
+}
+
+// MergeRequestEvent is sent when a merge request is opened, updated, closed,
+// reopened, merged or approved.
+type MergeRequestEvent struct {
+	User             EventUser         `json:"user"`
+	Project          EventProject      `json:"project"`
+	ObjectAttributes EventMergeRequest `json:"object_attributes"`
+	Labels           []EventLabel      `json:"labels"`
+	Assignees        []EventUser       `json:"assignees"`
+	// Changes holds the previous and current values of the changed
+	// attributes, keyed by attribute.
+	Changes map[string]interface{} `json:"changes"`
+}
+
+// EventNote is a note of a webhook event.
+type EventNote struct {
+	ID           int    `json:"id"`
+	Note         string `json:"note"`
+	NoteableType string `json:"noteable_type"`
+	AuthorID     int    `json:"author_id"`
+	URL          string `json:"url"`
+	System       bool   `json:"system"`
+	Action       string `json:"action"`
+}
+
+// NoteEvent is sent when a note is added to or edited on a merge request, an
+// issue, a commit or a snippet.
+type NoteEvent struct {
+	User             EventUser    `json:"user"`
+	Project          EventProject `json:"project"`
+	ObjectAttributes EventNote    `json:"object_attributes"`
+	// MergeRequest is set for the notes of merge requests, Issue for the
+	// notes of issues.
+	MergeRequest *EventMergeRequest `json:"merge_request"`
+	Issue        *EventIssue        `json:"issue"`
+}
+
+// EventPipeline is a pipeline of a webhook event.
+type EventPipeline struct {
+	ID             int    `json:"id"`
+	Ref            string `json:"ref"`
+	SHA            string `json:"sha"`
+	Status         string `json:"status"`
+	DetailedStatus string `json:"detailed_status"`
+	URL            string `json:"url"`
+}
+
+// PipelineEvent is sent when the status of a pipeline changes.
+type PipelineEvent struct {
+	User             EventUser     `json:"user"`
+	Project          EventProject  `json:"project"`
+	ObjectAttributes EventPipeline `json:"object_attributes"`
+	// MergeRequest is set for merge request pipelines.
+	MergeRequest *EventMergeRequest `json:"merge_request"`
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateWebhook(t *testing.T) {
+	headers := func(changes map[string]string) http.Header {
+		h := http.Header{}
+		h.Set("X-Gitlab-Event", NoteHook)
+		h.Set("X-Gitlab-Event-UUID", "uuid")
+		h.Set("X-Gitlab-Token", "secret")
+		h.Set("content-type", "application/json")
+		for k, v := range changes {
+			if v == "" {
+				h.Del(k)
+			} else {
+				h.Set(k, v)
+			}
+		}
+		return h
+	}
+	testCases := []struct {
+		name     string
+		method   string
+		header   http.Header
+		expected int
+	}{
+		{
+			name:     "valid webhook",
+			method:   http.MethodPost,
+			header:   headers(nil),
+			expected: http.StatusOK,
+		},
+		{
+			name:     "valid webhook without UUID",
+			method:   http.MethodPost,
+			header:   headers(map[string]string{"X-Gitlab-Event-UUID": ""}),
+			expected: http.StatusOK,
+		},
+		{
+			name:     "not a POST",
+			method:   http.MethodGet,
+			header:   headers(nil),
+			expected: http.StatusMethodNotAllowed,
+		},
+		{
+			name:     "missing event",
+			method:   http.MethodPost,
+			header:   headers(map[string]string{"X-Gitlab-Event": ""}),
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "missing token",
+			method:   http.MethodPost,
+			header:   headers(map[string]string{"X-Gitlab-Token": ""}),
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "invalid token",
+			method:   http.MethodPost,
+			header:   headers(map[string]string{"X-Gitlab-Token": "guess"}),
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "not JSON",
+			method:   http.MethodPost,
+			header:   headers(map[string]string{"content-type": "application/x-www-form-urlencoded"}),
+			expected: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, "/hook/gitlab", strings.NewReader(`{}`))
+			r.Header = tc.header
+			w := httptest.NewRecorder()
+			eventType, _, payload, ok, resp := ValidateWebhook(w, r, func() []byte { return []byte("secret\n") })
+			if resp != tc.expected {
+				t.Fatalf("expected status %d, got %d", tc.expected, resp)
+			}
+			if ok != (tc.expected == http.StatusOK) {
+				t.Errorf("expected valid: %t, got %t", tc.expected == http.StatusOK, ok)
+			}
+			if ok && (eventType != NoteHook || string(payload) != `{}`) {
+				t.Errorf("unexpected event %q with payload %q", eventType, payload)
+			}
+		})
+	}
+}
+
+func TestNoteEvent(t *testing.T) {
+	payload := `{
+  "object_kind": "note",
+  "user": {"id": 1, "name": "Alice", "username": "alice"},
+  "project": {"id": 5, "name": "Project", "path_with_namespace": "group/sub/project", "web_url": "https://gitlab.example.com/group/sub/project"},
+  "object_attributes": {"id": 1244, "note": "/lgtm", "noteable_type": "MergeRequest", "author_id": 1, "url": "https://gitlab.example.com/group/sub/project/-/merge_requests/1#note_1244"},
+  "merge_request": {"id": 7, "iid": 1, "title": "Fix", "state": "opened", "author_id": 2, "assignee_ids": [3], "last_commit": {"id": "abc"}, "labels": [{"title": "lgtm"}]}
+}`
+	var ne NoteEvent
+	if err := json.Unmarshal([]byte(payload), &ne); err != nil {
+		t.Fatalf("failed to unmarshal note event: %v", err)
+	}
+	if ne.Project.PathWithNamespace != "group/sub/project" || ne.ObjectAttributes.Note != "/lgtm" || ne.ObjectAttributes.NoteableType != NoteableTypeMergeRequest {
+		t.Errorf("unexpected note event %+v", ne)
+	}
+	if ne.MergeRequest == nil || ne.MergeRequest.IID != 1 || ne.MergeRequest.LastCommit.ID != "abc" || ne.MergeRequest.Labels[0].Title != "lgtm" {
+		t.Errorf("unexpected merge request %+v", ne.MergeRequest)
+	}
+	if ne.Issue != nil {
+		t.Errorf("expected no issue, got %+v", ne.Issue)
+	}
+}
@@ -19,6 +19,7 @@ package hook
 import (
 	"fmt"
 	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -83,6 +84,8 @@ func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
 		s.wg.Add(1)
 		go func(p string, h plugins.ReviewEventHandler) {
 			defer s.wg.Done()
+			release := s.schedule(p, re.Repo.Owner.Login)
+			defer release()
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, re.Repo.Owner.Login, s.Metrics.Metrics, l, p)
 			agent.InitializeCommentPruner(
 				re.Repo.Owner.Login,
@@ -105,6 +108,7 @@ func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
 	}
 	s.handleGenericComment(
 		l,
+		s.ClientAgent,
 		&github.GenericCommentEvent{
 			GUID:         re.GUID,
 			NodeID:       re.Review.NodeID,
@@ -140,6 +144,8 @@ func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewComm
 		s.wg.Add(1)
 		go func(p string, h plugins.ReviewCommentEventHandler) {
 			defer s.wg.Done()
+			release := s.schedule(p, rce.Repo.Owner.Login)
+			defer release()
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, rce.Repo.Owner.Login, s.Metrics.Metrics, l, p)
 			agent.InitializeCommentPruner(
 				rce.Repo.Owner.Login,
@@ -162,6 +168,7 @@ func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewComm
 	}
 	s.handleGenericComment(
 		l,
+		s.ClientAgent,
 		&github.GenericCommentEvent{
 			GUID:         rce.GUID,
 			NodeID:       rce.Comment.NodeID,
@@ -183,7 +190,7 @@ func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewComm
 	)
 }
 
-func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEvent) {
+func (s *Server) handlePullRequestEvent(l *logrus.Entry, clients *plugins.ClientAgent, pr github.PullRequestEvent) {
 	defer s.wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  pr.Repo.Owner.Login,
@@ -197,7 +204,9 @@ func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEv
 		s.wg.Add(1)
 		go func(p string, h plugins.PullRequestHandler) {
 			defer s.wg.Done()
-			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, pr.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			release := s.schedule(p, pr.Repo.Owner.Login)
+			defer release()
+			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, clients, pr.Repo.Owner.Login, s.Metrics.Metrics, l, p)
 			agent.InitializeCommentPruner(
 				pr.Repo.Owner.Login,
 				pr.Repo.Name,
@@ -221,6 +230,7 @@ func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEv
 	}
 	s.handleGenericComment(
 		l,
+		clients,
 		&github.GenericCommentEvent{
 			ID:           pr.PullRequest.ID,
 			NodeID:       pr.PullRequest.NodeID,
@@ -255,6 +265,8 @@ func (s *Server) handlePushEvent(l *logrus.Entry, pe github.PushEvent) {
 		s.wg.Add(1)
 		go func(p string, h plugins.PushEventHandler) {
 			defer s.wg.Done()
+			release := s.schedule(p, pe.Repo.Owner.Login)
+			defer release()
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, pe.Repo.Owner.Login, s.Metrics.Metrics, l, p)
 			start := time.Now()
 			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": "none", "plugin": p}
@@ -267,7 +279,7 @@ func (s *Server) handlePushEvent(l *logrus.Entry, pe github.PushEvent) {
 	}
 }
 
-func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
+func (s *Server) handleIssueEvent(l *logrus.Entry, clients *plugins.ClientAgent, i github.IssueEvent) {
 	defer s.wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  i.Repo.Owner.Login,
@@ -281,7 +293,9 @@ func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
 		s.wg.Add(1)
 		go func(p string, h plugins.IssueHandler) {
 			defer s.wg.Done()
-			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, i.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			release := s.schedule(p, i.Repo.Owner.Login)
+			defer release()
+			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, clients, i.Repo.Owner.Login, s.Metrics.Metrics, l, p)
 			agent.InitializeCommentPruner(
 				i.Repo.Owner.Login,
 				i.Repo.Name,
@@ -305,6 +319,7 @@ func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
 	}
 	s.handleGenericComment(
 		l,
+		clients,
 		&github.GenericCommentEvent{
 			ID:           i.Issue.ID,
 			NodeID:       i.Issue.NodeID,
@@ -326,7 +341,7 @@ func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
 	)
 }
 
-func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueCommentEvent) {
+func (s *Server) handleIssueCommentEvent(l *logrus.Entry, clients *plugins.ClientAgent, ic github.IssueCommentEvent) {
 	defer s.wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  ic.Repo.Owner.Login,
@@ -336,11 +351,17 @@ func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueComment
 		"url":               ic.Comment.HTMLURL,
 	})
 	l.Infof("Issue comment %s.", ic.Action)
+	// The generic comment event is built from the comment as written, its
+	// aliases are expanded by handleGenericComment.
+	aliased := ic
+	aliased.Comment.Body = s.expandCommandAliases(ic.Repo.Owner.Login, ic.Repo.Name, ic.Comment.Body)
 	for p, h := range s.Plugins.IssueCommentHandlers(ic.Repo.Owner.Login, ic.Repo.Name) {
 		s.wg.Add(1)
 		go func(p string, h plugins.IssueCommentHandler) {
 			defer s.wg.Done()
-			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, ic.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			release := s.schedule(p, ic.Repo.Owner.Login)
+			defer release()
+			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, clients, ic.Repo.Owner.Login, s.Metrics.Metrics, l, p)
 			agent.InitializeCommentPruner(
 				ic.Repo.Owner.Login,
 				ic.Repo.Name,
@@ -348,7 +369,7 @@ func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueComment
 			)
 			start := time.Now()
 			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(ic.Action), "plugin": p}
-			if err := errorOnPanic(func() error { return h(agent, ic) }); err != nil {
+			if err := errorOnPanic(func() error { return h(agent, aliased) }); err != nil {
 				agent.Logger.WithError(err).Error("Error handling IssueCommentEvent.")
 				s.Metrics.PluginHandleErrors.With(labels).Inc()
 			}
@@ -362,6 +383,7 @@ func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueComment
 	}
 	s.handleGenericComment(
 		l,
+		clients,
 		&github.GenericCommentEvent{
 			ID:           ic.Issue.ID,
 			NodeID:       ic.Issue.NodeID,
@@ -384,7 +406,7 @@ func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueComment
 	)
 }
 
-func (s *Server) handleStatusEvent(l *logrus.Entry, se github.StatusEvent) {
+func (s *Server) handleStatusEvent(l *logrus.Entry, clients *plugins.ClientAgent, se github.StatusEvent) {
 	defer s.wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  se.Repo.Owner.Login,
@@ -399,7 +421,9 @@ func (s *Server) handleStatusEvent(l *logrus.Entry, se github.StatusEvent) {
 		s.wg.Add(1)
 		go func(p string, h plugins.StatusEventHandler) {
 			defer s.wg.Done()
-			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, se.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			release := s.schedule(p, se.Repo.Owner.Login)
+			defer release()
+			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, clients, se.Repo.Owner.Login, s.Metrics.Metrics, l, p)
 			start := time.Now()
 			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": "none", "plugin": p}
 			if err := errorOnPanic(func() error { return h(agent, se) }); err != nil {
@@ -411,6 +435,52 @@ func (s *Server) handleStatusEvent(l *logrus.Entry, se github.StatusEvent) {
 	}
 }
 
+// HandlePeriodic runs the periodic handlers of all enabled plugins. It is meant
+// to be called on a fixed interval and blocks until all handlers have returned.
+func (s *Server) HandlePeriodic() {
+	s.wg.Add(1)
+	defer s.wg.Done()
+	l := logrus.WithField(eventTypeField, "periodic")
+	var wg sync.WaitGroup
+	for p, h := range s.Plugins.PeriodicHandlers() {
+		s.wg.Add(1)
+		wg.Add(1)
+		go func(p string, h plugins.PeriodicHandler) {
+			defer s.wg.Done()
+			defer wg.Done()
+			release := s.schedule(p, "")
+			defer release()
+			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, "", s.Metrics.Metrics, l, p)
+			start := time.Now()
+			labels := prometheus.Labels{"event_type": "periodic", "action": "none", "plugin": p}
+			if err := errorOnPanic(func() error { return h(agent) }); err != nil {
+				agent.Logger.WithError(err).Error("Error running periodic handler.")
+				s.Metrics.PluginHandleErrors.With(labels).Inc()
+			}
+			s.Metrics.PluginHandleDuration.With(labels).Observe(time.Since(start).Seconds())
+		}(p, h)
+	}
+	wg.Wait()
+}
+
+// handleShutdown runs the shutdown handlers of all plugins, e.g. to start the
+// jobs that are still batched, and blocks until all handlers have returned.
+func (s *Server) handleShutdown() {
+	var wg sync.WaitGroup
+	for p, h := range s.Plugins.ShutdownHandlers() {
+		s.wg.Add(1)
+		wg.Add(1)
+		go func(p string, h plugins.ShutdownHandler) {
+			defer s.wg.Done()
+			defer wg.Done()
+			if err := errorOnPanic(func() error { h(); return nil }); err != nil {
+				logrus.WithField("plugin", p).WithError(err).Error("Error running shutdown handler.")
+			}
+		}(p, h)
+	}
+	wg.Wait()
+}
+
 // genericCommentAction normalizes the action string to a GenericCommentEventAction or returns ""
 // if the action is unrelated to the comment text. (For example a PR 'label' action.)
 func genericCommentAction(action string) github.GenericCommentEventAction {
@@ -426,12 +496,15 @@ func genericCommentAction(action string) github.GenericCommentEventAction {
 	return ""
 }
 
-func (s *Server) handleGenericComment(l *logrus.Entry, ce *github.GenericCommentEvent) {
+func (s *Server) handleGenericComment(l *logrus.Entry, clients *plugins.ClientAgent, ce *github.GenericCommentEvent) {
+	ce.Body = s.expandCommandAliases(ce.Repo.Owner.Login, ce.Repo.Name, ce.Body)
 	for p, h := range s.Plugins.GenericCommentHandlers(ce.Repo.Owner.Login, ce.Repo.Name) {
 		s.wg.Add(1)
 		go func(p string, h plugins.GenericCommentHandler) {
 			defer s.wg.Done()
-			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, ce.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			release := s.schedule(p, ce.Repo.Owner.Login)
+			defer release()
+			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, clients, ce.Repo.Owner.Login, s.Metrics.Metrics, l, p)
 			agent.InitializeCommentPruner(
 				ce.Repo.Owner.Login,
 				ce.Repo.Name,
@@ -446,6 +519,81 @@ func (s *Server) handleGenericComment(l *logrus.Entry, ce *github.GenericComment
 			s.Metrics.PluginHandleDuration.With(labels).Observe(time.Since(start).Seconds())
 		}(p, h)
 	}
+	s.handleCommands(l, clients, ce)
+	s.handleHelpCommand(l, clients, ce)
+}
+
+// expandCommandAliases rewrites the command aliases of the repo in the body
+// into the commands they invoke.
+func (s *Server) expandCommandAliases(org, repo, body string) string {
+	return plugins.ExpandCommandAliases(body, s.Plugins.Config().CommandAliasesFor(org, repo))
+}
+
+// handleCommands runs the handlers of the commands a new comment invokes and
+// answers their invalid invocations with their usage.
+func (s *Server) handleCommands(l *logrus.Entry, clients *plugins.ClientAgent, ce *github.GenericCommentEvent) {
+	if ce.Action != github.GenericCommentActionCreated {
+		return
+	}
+	org, repo := ce.Repo.Owner.Login, ce.Repo.Name
+	for p, commands := range s.Plugins.Commands(org, repo) {
+		var handled []plugins.Command
+		for _, c := range commands {
+			if c.Handler != nil {
+				handled = append(handled, c)
+			}
+		}
+		invocations, errs := plugins.ParseCommands(ce.Body, handled)
+		if len(invocations) == 0 && len(errs) == 0 {
+			continue
+		}
+		s.wg.Add(1)
+		go func(p string, invocations []plugins.CommandInvocation, errs []error) {
+			defer s.wg.Done()
+			release := s.schedule(p, org)
+			defer release()
+			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, clients, org, s.Metrics.Metrics, l, p)
+			start := time.Now()
+			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(ce.Action), "plugin": p}
+			for _, err := range errs {
+				if err := agent.GitHubClient.CreateComment(org, repo, ce.Number, plugins.FormatResponseRaw(ce.Body, ce.HTMLURL, ce.User.Login, err.Error())); err != nil {
+					agent.Logger.WithError(err).Error("Failed to answer an invalid command.")
+				}
+			}
+			for _, invocation := range invocations {
+				if err := errorOnPanic(func() error { return invocation.Command.Handler(agent, *ce, invocation) }); err != nil {
+					agent.Logger.WithError(err).WithField("command", invocation.Name).Error("Error handling command.")
+					s.Metrics.PluginHandleErrors.With(labels).Inc()
+				}
+			}
+			s.Metrics.PluginHandleDuration.With(labels).Observe(time.Since(start).Seconds())
+		}(p, invocations, errs)
+	}
+}
+
+// handleHelpCommand answers /help with the commands of the plugins enabled for
+// the repo. The help plugin handles /help on issues, where the commands are
+// listed with /help commands.
+func (s *Server) handleHelpCommand(l *logrus.Entry, clients *plugins.ClientAgent, ce *github.GenericCommentEvent) {
+	if ce.Action != github.GenericCommentActionCreated {
+		return
+	}
+	m := plugins.HelpCommandRe.FindStringSubmatch(ce.Body)
+	if m == nil || (!ce.IsPR && m[1] == "") {
+		return
+	}
+	org, repo := ce.Repo.Owner.Login, ce.Repo.Name
+	commands := s.Plugins.Commands(org, repo)
+	if len(commands) == 0 {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := clients.GitHubClient.CreateComment(org, repo, ce.Number, plugins.FormatResponseRaw(ce.Body, ce.HTMLURL, ce.User.Login, plugins.FormatCommandsHelp(commands))); err != nil {
+			l.WithError(err).Error("Failed to list the commands of the repo.")
+		}
+	}()
 }
 
 func intPtr(i int) *int {
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/gitea"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// ServeGitea validates an incoming Gitea or Forgejo webhook and dispatches it
+// to the plugins as the GitHub event it corresponds to.
+func (s *Server) ServeGitea(w http.ResponseWriter, r *http.Request) {
+	eventType, eventGUID, payload, ok, resp := gitea.ValidateWebhook(w, r, s.GiteaTokenGenerator)
+	if counter, err := s.Metrics.ResponseCounter.GetMetricWithLabelValues(strconv.Itoa(resp)); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"status-code": resp,
+		}).WithError(err).Error("Failed to get metric for reporting webhook status code")
+	} else {
+		counter.Inc()
+	}
+
+	if !ok {
+		return
+	}
+	fmt.Fprint(w, "Event received. Have a nice day.")
+
+	if err := s.demuxGiteaEvent(eventType, eventGUID, payload); err != nil {
+		logrus.WithError(err).Error("Error parsing Gitea event.")
+	}
+}
+
+// giteaClientAgent returns the clients of the plugins handling a Gitea event,
+// with a GitHub client acting on Gitea.
+func (s *Server) giteaClientAgent() *plugins.ClientAgent {
+	clients := *s.ClientAgent
+	clients.GitHubClient = github.NewProviderClient("gitea", s.GiteaClient, s.DryRun)
+	return &clients
+}
+
+func (s *Server) demuxGiteaEvent(eventType, eventGUID string, payload []byte) error {
+	l := logrus.WithFields(
+		logrus.Fields{
+			eventTypeField:   eventType,
+			github.EventGUID: eventGUID,
+		},
+	)
+	// We don't want to fail the webhook due to a metrics error.
+	if counter, err := s.Metrics.WebhookCounter.GetMetricWithLabelValues(eventType); err != nil {
+		l.WithError(err).Warn("Failed to get metric for eventType " + eventType)
+	} else {
+		counter.Inc()
+	}
+	switch eventType {
+	case "issues":
+		var i github.IssueEvent
+		if err := json.Unmarshal(payload, &i); err != nil {
+			return err
+		}
+		i.GUID = eventGUID
+		if i.Action = gitea.IssueAction(string(i.Action)); i.Action == "" {
+			return nil
+		}
+		if s.RepoEnabled(i.Repo.Owner.Login, i.Repo.Name) {
+			s.wg.Add(1)
+			go s.handleIssueEvent(l, s.giteaClientAgent(), i)
+		}
+	case "issue_comment":
+		var ic github.IssueCommentEvent
+		if err := json.Unmarshal(payload, &ic); err != nil {
+			return err
+		}
+		ic.GUID = eventGUID
+		if s.RepoEnabled(ic.Repo.Owner.Login, ic.Repo.Name) {
+			s.wg.Add(1)
+			go s.handleIssueCommentEvent(l, s.giteaClientAgent(), ic)
+		}
+	case "pull_request":
+		var pr github.PullRequestEvent
+		if err := json.Unmarshal(payload, &pr); err != nil {
+			return err
+		}
+		pr.GUID = eventGUID
+		if pr.Action = gitea.PullRequestAction(string(pr.Action)); pr.Action == "" {
+			return nil
+		}
+		if s.RepoEnabled(pr.Repo.Owner.Login, pr.Repo.Name) {
+			s.wg.Add(1)
+			go s.handlePullRequestEvent(l, s.giteaClientAgent(), pr)
+		}
+	default:
+		l.Debug("Ignoring unhandled Gitea event type.")
+	}
+	return nil
+}
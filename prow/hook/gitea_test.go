@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/bugzilla"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/gitea"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/githubeventserver"
+	"k8s.io/test-infra/prow/plugins"
+	"k8s.io/test-infra/prow/plugins/ownersconfig"
+	"k8s.io/test-infra/prow/repoowners"
+)
+
+type fakeGiteaClient struct {
+	gitea.Client
+
+	mut      sync.Mutex
+	comments []string
+}
+
+func (f *fakeGiteaClient) CreateComment(org, repo string, number int, comment string) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.comments = append(f.comments, comment)
+	return nil
+}
+
+// TestServeGitea sends a Forgejo comment webhook to hook and ensures that a
+// plugin handles it as a comment and comments back on the pull request.
+func TestServeGitea(t *testing.T) {
+	called := make(chan github.GenericCommentEvent, 1)
+	plugins.RegisterGenericCommentHandler(
+		"gitea-test",
+		func(pc plugins.Agent, ce github.GenericCommentEvent) error {
+			called <- ce
+			return pc.GitHubClient.CreateComment(ce.Repo.Owner.Login, ce.Repo.Name, ce.Number, "Hello")
+		},
+		nil,
+	)
+	pa := &plugins.ConfigAgent{}
+	pa.Set(&plugins.Configuration{Plugins: plugins.Plugins{"org": {Plugins: []string{"gitea-test"}}}})
+	gc := &fakeGiteaClient{}
+	server := &Server{
+		ClientAgent: &plugins.ClientAgent{
+			GitHubClient:   github.NewFakeClient(),
+			OwnersClient:   repoowners.NewClient(nil, nil, func(org, repo string) bool { return false }, func(org, repo string) bool { return false }, func() *config.OwnersDirDenylist { return &config.OwnersDirDenylist{} }, ownersconfig.FakeResolver),
+			BugzillaClient: &bugzilla.Fake{},
+		},
+		Plugins:             pa,
+		ConfigAgent:         &config.Agent{},
+		Metrics:             githubeventserver.NewMetrics(),
+		RepoEnabled:         func(org, repo string) bool { return true },
+		GiteaClient:         gc,
+		GiteaTokenGenerator: func() []byte { return []byte("secret") },
+	}
+	s := httptest.NewServer(http.HandlerFunc(server.ServeGitea))
+	defer s.Close()
+
+	payload := []byte(`{
+  "action": "created",
+  "issue": {"number": 3, "user": {"login": "bob"}, "pull_request": {}},
+  "comment": {"id": 10, "body": "/hello", "user": {"login": "alice"}},
+  "repository": {"name": "repo", "full_name": "org/repo", "owner": {"login": "org"}},
+  "sender": {"login": "alice"}
+}`)
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("X-Forgejo-Event", "issue_comment")
+	req.Header.Set("X-Forgejo-Delivery", "guid")
+	req.Header.Set("X-Forgejo-Signature", gitea.PayloadSignature(payload, []byte("secret")))
+	req.Header.Set("content-type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to send webhook: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	select {
+	case ce := <-called:
+		if ce.Repo.Owner.Login != "org" || ce.Repo.Name != "repo" || ce.Number != 3 || !ce.IsPR || ce.Body != "/hello" || ce.User.Login != "alice" {
+			t.Errorf("unexpected comment event %+v", ce)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Plugin not called after one second.")
+	}
+	server.GracefulShutdown()
+
+	if diff := cmp.Diff([]string{"Hello"}, gc.comments); diff != "" {
+		t.Errorf("comments differ from expected: %s", diff)
+	}
+}
@@ -0,0 +1,340 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/gitlab"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// gitlabPipelineContext is the context of the status events adapted from the
+// pipeline events of GitLab.
+const gitlabPipelineContext = "gitlab/pipeline"
+
+// ServeGitLab validates an incoming GitLab webhook and dispatches it to the
+// plugins as the GitHub event it corresponds to. GitLab projects appear to the
+// plugins as repos whose org is the namespace of the project, for example the
+// project group/subgroup/project is the repo project of the org group/subgroup.
+func (s *Server) ServeGitLab(w http.ResponseWriter, r *http.Request) {
+	eventType, eventGUID, payload, ok, resp := gitlab.ValidateWebhook(w, r, s.GitLabTokenGenerator)
+	if counter, err := s.Metrics.ResponseCounter.GetMetricWithLabelValues(strconv.Itoa(resp)); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"status-code": resp,
+		}).WithError(err).Error("Failed to get metric for reporting webhook status code")
+	} else {
+		counter.Inc()
+	}
+
+	if !ok {
+		return
+	}
+	fmt.Fprint(w, "Event received. Have a nice day.")
+
+	if err := s.demuxGitLabEvent(eventType, eventGUID, payload); err != nil {
+		logrus.WithError(err).Error("Error parsing GitLab event.")
+	}
+}
+
+func (s *Server) demuxGitLabEvent(eventType, eventGUID string, payload []byte) error {
+	l := logrus.WithFields(
+		logrus.Fields{
+			eventTypeField:   eventType,
+			github.EventGUID: eventGUID,
+		},
+	)
+	// We don't want to fail the webhook due to a metrics error.
+	if counter, err := s.Metrics.WebhookCounter.GetMetricWithLabelValues(eventType); err != nil {
+		l.WithError(err).Warn("Failed to get metric for eventType " + eventType)
+	} else {
+		counter.Inc()
+	}
+	switch eventType {
+	case gitlab.MergeRequestHook:
+		var mre gitlab.MergeRequestEvent
+		if err := json.Unmarshal(payload, &mre); err != nil {
+			return err
+		}
+		repo := gitlabRepo(mre.Project)
+		if s.RepoEnabled(repo.Owner.Login, repo.Name) {
+			s.wg.Add(1)
+			go s.handleGitLabMergeRequestEvent(l, eventGUID, repo, mre)
+		}
+	case gitlab.NoteHook:
+		var ne gitlab.NoteEvent
+		if err := json.Unmarshal(payload, &ne); err != nil {
+			return err
+		}
+		repo := gitlabRepo(ne.Project)
+		if s.RepoEnabled(repo.Owner.Login, repo.Name) {
+			s.wg.Add(1)
+			go s.handleGitLabNoteEvent(l, eventGUID, repo, ne)
+		}
+	case gitlab.PipelineHook:
+		var pe gitlab.PipelineEvent
+		if err := json.Unmarshal(payload, &pe); err != nil {
+			return err
+		}
+		repo := gitlabRepo(pe.Project)
+		if s.RepoEnabled(repo.Owner.Login, repo.Name) {
+			s.wg.Add(1)
+			go s.handleGitLabPipelineEvent(l, eventGUID, repo, pe)
+		}
+	default:
+		l.Debug("Ignoring unhandled GitLab event type.")
+	}
+	return nil
+}
+
+// gitlabRepo returns the repo a GitLab project appears as to the plugins.
+func gitlabRepo(project gitlab.EventProject) github.Repo {
+	owner, name := "", project.PathWithNamespace
+	if idx := strings.LastIndex(project.PathWithNamespace, "/"); idx >= 0 {
+		owner, name = project.PathWithNamespace[:idx], project.PathWithNamespace[idx+1:]
+	}
+	return github.Repo{
+		Owner:    github.User{Login: owner},
+		Name:     name,
+		FullName: project.PathWithNamespace,
+		HTMLURL:  project.WebURL,
+	}
+}
+
+// gitlabClientAgent returns the clients of the plugins handling an event of a
+// merge request or an issue, with a GitHub client acting on GitLab.
+func (s *Server) gitlabClientAgent(repo github.Repo, kind string, iid int) *plugins.ClientAgent {
+	clients := *s.ClientAgent
+	clients.GitHubClient = newGitLabGitHubClient(s.GitLabClient, gitlab.Noteable{Project: repo.FullName, Kind: kind, IID: iid}, s.DryRun)
+	return &clients
+}
+
+func gitlabEventUser(u gitlab.EventUser) github.User {
+	return github.User{ID: u.ID, Login: u.Username, Name: u.Name}
+}
+
+// gitlabUser returns the user with the ID. Events only carry the user who
+// triggered them, GitLab is asked for the others.
+func (s *Server) gitlabUser(l *logrus.Entry, id int, known ...gitlab.EventUser) github.User {
+	for _, u := range known {
+		if u.ID == id {
+			return gitlabEventUser(u)
+		}
+	}
+	u, err := s.GitLabClient.GetUser(id)
+	if err != nil {
+		l.WithError(err).WithField("user-id", id).Warn("Failed to get GitLab user.")
+		return github.User{ID: id}
+	}
+	return github.User{ID: u.ID, Login: u.Username}
+}
+
+func (s *Server) gitlabAssignees(l *logrus.Entry, ids []int, known []gitlab.EventUser) []github.User {
+	var assignees []github.User
+	for _, id := range ids {
+		assignees = append(assignees, s.gitlabUser(l, id, known...))
+	}
+	return assignees
+}
+
+func gitlabLabels(labels []gitlab.EventLabel) []github.Label {
+	var converted []github.Label
+	for _, label := range labels {
+		converted = append(converted, github.Label{Name: label.Title})
+	}
+	return converted
+}
+
+// gitlabState returns the state of a merge request or an issue as a GitHub state.
+func gitlabState(state string) string {
+	if state == "opened" {
+		return github.PullRequestStateOpen
+	}
+	return github.PullRequestStateClosed
+}
+
+func (s *Server) gitlabPullRequest(l *logrus.Entry, repo github.Repo, mr gitlab.EventMergeRequest, actor gitlab.EventUser, assignees []gitlab.EventUser) github.PullRequest {
+	return github.PullRequest{
+		ID:        mr.ID,
+		Number:    mr.IID,
+		HTMLURL:   mr.URL,
+		User:      s.gitlabUser(l, mr.AuthorID, actor),
+		Labels:    gitlabLabels(mr.Labels),
+		Base:      github.PullRequestBranch{Ref: mr.TargetBranch, Repo: repo},
+		Head:      github.PullRequestBranch{Ref: mr.SourceBranch, SHA: mr.LastCommit.ID, Repo: repo},
+		Title:     mr.Title,
+		Body:      mr.Description,
+		Assignees: s.gitlabAssignees(l, mr.AssigneeIDs, assignees),
+		State:     gitlabState(mr.State),
+		Draft:     mr.Draft,
+		Merged:    mr.State == "merged",
+	}
+}
+
+// gitlabPullRequestAction returns the action of the pull request event a merge
+// request event corresponds to, or "" if there is none.
+func gitlabPullRequestAction(mre gitlab.MergeRequestEvent) github.PullRequestEventAction {
+	switch mre.ObjectAttributes.Action {
+	case gitlab.MergeRequestActionOpen:
+		return github.PullRequestActionOpened
+	case gitlab.MergeRequestActionReopen:
+		return github.PullRequestActionReopened
+	case gitlab.MergeRequestActionClose, gitlab.MergeRequestActionMerge:
+		return github.PullRequestActionClosed
+	case gitlab.MergeRequestActionUpdate:
+		if mre.ObjectAttributes.OldRev != "" {
+			return github.PullRequestActionSynchronize
+		}
+		_, title := mre.Changes["title"]
+		_, description := mre.Changes["description"]
+		if title || description {
+			return github.PullRequestActionEdited
+		}
+	}
+	return ""
+}
+
+func (s *Server) handleGitLabMergeRequestEvent(l *logrus.Entry, guid string, repo github.Repo, mre gitlab.MergeRequestEvent) {
+	defer s.wg.Done()
+	mr := mre.ObjectAttributes
+	action := gitlabPullRequestAction(mre)
+	if action == "" {
+		l.Debugf("Ignoring merge request action %q.", mr.Action)
+		return
+	}
+	if len(mre.Labels) > 0 {
+		mr.Labels = mre.Labels
+	}
+	pr := github.PullRequestEvent{
+		Action:      action,
+		Number:      mr.IID,
+		PullRequest: s.gitlabPullRequest(l, repo, mr, mre.User, mre.Assignees),
+		Repo:        repo,
+		Sender:      gitlabEventUser(mre.User),
+		GUID:        guid,
+	}
+	s.wg.Add(1)
+	s.handlePullRequestEvent(l, s.gitlabClientAgent(repo, gitlab.NoteableMergeRequest, mr.IID), pr)
+}
+
+func (s *Server) handleGitLabNoteEvent(l *logrus.Entry, guid string, repo github.Repo, ne gitlab.NoteEvent) {
+	defer s.wg.Done()
+	note := ne.ObjectAttributes
+	if note.System {
+		return
+	}
+	var action github.GenericCommentEventAction
+	switch note.Action {
+	case "", gitlab.NoteActionCreate:
+		action = github.GenericCommentActionCreated
+	case gitlab.NoteActionUpdate:
+		action = github.GenericCommentActionEdited
+	default:
+		l.Errorf(failedCommentCoerceFmt, "note", note.Action)
+		return
+	}
+	ce := &github.GenericCommentEvent{
+		CommentID: intPtr(note.ID),
+		GUID:      guid,
+		Action:    action,
+		Body:      note.Note,
+		HTMLURL:   note.URL,
+		Repo:      repo,
+		User:      gitlabEventUser(ne.User),
+	}
+	var kind string
+	switch {
+	case note.NoteableType == gitlab.NoteableTypeMergeRequest && ne.MergeRequest != nil:
+		kind = gitlab.NoteableMergeRequest
+		mr := s.gitlabPullRequest(l, repo, *ne.MergeRequest, ne.User, nil)
+		ce.ID = mr.ID
+		ce.IsPR = true
+		ce.Number = mr.Number
+		ce.IssueAuthor = mr.User
+		ce.Assignees = mr.Assignees
+		ce.IssueState = mr.State
+		ce.IssueTitle = mr.Title
+		ce.IssueBody = mr.Body
+		ce.IssueHTMLURL = mr.HTMLURL
+	case note.NoteableType == gitlab.NoteableTypeIssue && ne.Issue != nil:
+		kind = gitlab.NoteableIssue
+		issue := ne.Issue
+		ce.ID = issue.ID
+		ce.Number = issue.IID
+		ce.IssueAuthor = s.gitlabUser(l, issue.AuthorID, ne.User)
+		ce.Assignees = s.gitlabAssignees(l, issue.AssigneeIDs, nil)
+		ce.IssueState = gitlabState(issue.State)
+		ce.IssueTitle = issue.Title
+		ce.IssueBody = issue.Description
+		ce.IssueHTMLURL = issue.URL
+	default:
+		l.Debugf("Ignoring note on %s.", note.NoteableType)
+		return
+	}
+	l = l.WithFields(logrus.Fields{
+		github.OrgLogField:  repo.Owner.Login,
+		github.RepoLogField: repo.Name,
+		github.PrLogField:   ce.Number,
+		"author":            ce.User.Login,
+		"url":               ce.HTMLURL,
+	})
+	l.Infof("Note %s.", action)
+	s.handleGenericComment(l, s.gitlabClientAgent(repo, kind, ce.Number), ce)
+}
+
+// gitlabPipelineState returns the state of a pipeline as the state of a GitHub
+// status.
+func gitlabPipelineState(status string) string {
+	switch status {
+	case gitlab.StatusSuccess:
+		return github.StatusSuccess
+	case gitlab.StatusFailed:
+		return github.StatusFailure
+	case gitlab.StatusCanceled, gitlab.StatusSkipped:
+		return github.StatusError
+	}
+	return github.StatusPending
+}
+
+func (s *Server) handleGitLabPipelineEvent(l *logrus.Entry, guid string, repo github.Repo, pe gitlab.PipelineEvent) {
+	defer s.wg.Done()
+	pipeline := pe.ObjectAttributes
+	se := github.StatusEvent{
+		SHA:         pipeline.SHA,
+		State:       gitlabPipelineState(pipeline.Status),
+		Description: fmt.Sprintf("Pipeline %s", pipeline.DetailedStatus),
+		TargetURL:   pipeline.URL,
+		ID:          pipeline.ID,
+		Context:     gitlabPipelineContext,
+		Sender:      gitlabEventUser(pe.User),
+		Repo:        repo,
+		GUID:        guid,
+	}
+	var iid int
+	if pe.MergeRequest != nil {
+		iid = pe.MergeRequest.IID
+	}
+	s.wg.Add(1)
+	s.handleStatusEvent(l, s.gitlabClientAgent(repo, gitlab.NoteableMergeRequest, iid), se)
+}
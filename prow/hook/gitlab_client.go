@@ -0,0 +1,275 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/gitlab"
+)
+
+// gitlabProvider serves the GitHub API calls of the comment-command plugins
+// from GitLab, for the merge request or issue of the event they handle. Issue
+// and PR numbers are the IIDs of the merge requests or issues of the project
+// org/repo, and comments are notes.
+type gitlabProvider struct {
+	glc gitlab.Client
+	// noteable is the merge request or issue of the event. Comments are only
+	// edited and deleted there, since GitHub identifies them by ID alone.
+	noteable gitlab.Noteable
+}
+
+func newGitLabGitHubClient(glc gitlab.Client, noteable gitlab.Noteable, dryRun bool) github.Client {
+	return github.NewProviderClient("gitlab", &gitlabProvider{glc: glc, noteable: noteable}, dryRun)
+}
+
+func (c *gitlabProvider) noteableOf(org, repo string, number int) gitlab.Noteable {
+	return gitlab.Noteable{Project: org + "/" + repo, Kind: c.noteable.Kind, IID: number}
+}
+
+func (c *gitlabProvider) BotUserChecker() (func(candidate string) bool, error) {
+	user, err := c.glc.GetCurrentUser()
+	if err != nil {
+		return nil, fmt.Errorf("fetching the current user from GitLab: %w", err)
+	}
+	return func(candidate string) bool {
+		return github.NormLogin(candidate) == github.NormLogin(user.Username)
+	}, nil
+}
+
+// IsCollaborator returns whether the user is a member of the project with at
+// least the developer role, who can push to the project.
+func (c *gitlabProvider) IsCollaborator(org, repo, user string) (bool, error) {
+	member, err := c.glc.GetProjectMember(org+"/"+repo, github.NormLogin(user))
+	var reqErr *gitlab.RequestError
+	if errors.As(err, &reqErr) && reqErr.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return member.AccessLevel >= gitlab.AccessLevelDeveloper, nil
+}
+
+func (c *gitlabProvider) CreateComment(org, repo string, number int, comment string) error {
+	return c.glc.CreateNote(c.noteableOf(org, repo, number), comment)
+}
+
+func (c *gitlabProvider) EditComment(org, repo string, id int, comment string) error {
+	return c.glc.UpdateNote(c.noteable, id, comment)
+}
+
+func (c *gitlabProvider) DeleteComment(org, repo string, id int) error {
+	return c.glc.DeleteNote(c.noteable, id)
+}
+
+// ListIssueComments lists the notes of a merge request or an issue, without
+// the system notes GitLab adds for changes like labels.
+func (c *gitlabProvider) ListIssueComments(org, repo string, number int) ([]github.IssueComment, error) {
+	notes, err := c.glc.ListNotes(c.noteableOf(org, repo, number))
+	if err != nil {
+		return nil, err
+	}
+	var comments []github.IssueComment
+	for _, note := range notes {
+		if note.System {
+			continue
+		}
+		comments = append(comments, github.IssueComment{
+			ID:        note.ID,
+			Body:      note.Body,
+			User:      github.User{ID: note.Author.ID, Login: note.Author.Username},
+			CreatedAt: note.CreatedAt,
+			UpdatedAt: note.UpdatedAt,
+		})
+	}
+	return comments, nil
+}
+
+func (c *gitlabProvider) AddLabel(org, repo string, number int, label string) error {
+	return c.glc.UpdateNoteable(c.noteableOf(org, repo, number), gitlab.UpdateNoteableOptions{AddLabels: []string{label}})
+}
+
+func (c *gitlabProvider) RemoveLabel(org, repo string, number int, label string) error {
+	return c.glc.UpdateNoteable(c.noteableOf(org, repo, number), gitlab.UpdateNoteableOptions{RemoveLabels: []string{label}})
+}
+
+// noteableUsers returns the labels, assignees and reviewers of a merge request
+// or an issue.
+func (c *gitlabProvider) noteableUsers(n gitlab.Noteable) ([]string, []gitlab.User, []gitlab.User, error) {
+	if n.Kind == gitlab.NoteableIssue {
+		issue, err := c.glc.GetIssue(n.Project, n.IID)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return issue.Labels, issue.Assignees, nil, nil
+	}
+	mr, err := c.glc.GetMergeRequest(n.Project, n.IID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return mr.Labels, mr.Assignees, mr.Reviewers, nil
+}
+
+func (c *gitlabProvider) GetIssueLabels(org, repo string, number int) ([]github.Label, error) {
+	names, _, _, err := c.noteableUsers(c.noteableOf(org, repo, number))
+	if err != nil {
+		return nil, err
+	}
+	var labels []github.Label
+	for _, name := range names {
+		labels = append(labels, github.Label{Name: name})
+	}
+	return labels, nil
+}
+
+// updateUsers adds or removes the users with the logins from the assignees or
+// reviewers of a merge request or an issue. GitLab only takes the complete list
+// of IDs.
+func (c *gitlabProvider) updateUsers(org, repo string, number int, logins []string, reviewers, add bool) error {
+	n := c.noteableOf(org, repo, number)
+	_, assignees, currentReviewers, err := c.noteableUsers(n)
+	if err != nil {
+		return err
+	}
+	current := assignees
+	if reviewers {
+		current = currentReviewers
+	}
+	ids := map[int]bool{}
+	for _, user := range current {
+		ids[user.ID] = true
+	}
+	var missing []string
+	for _, login := range logins {
+		user, err := c.glc.FindUser(github.NormLogin(login))
+		if err != nil {
+			var reqErr *gitlab.RequestError
+			if errors.As(err, &reqErr) && reqErr.StatusCode == http.StatusNotFound {
+				missing = append(missing, login)
+				continue
+			}
+			return err
+		}
+		ids[user.ID] = add
+	}
+	updated := []int{}
+	for id, keep := range ids {
+		if keep {
+			updated = append(updated, id)
+		}
+	}
+	sort.Ints(updated)
+	var opts gitlab.UpdateNoteableOptions
+	if reviewers {
+		opts.ReviewerIDs = &updated
+	} else {
+		opts.AssigneeIDs = &updated
+	}
+	if err := c.glc.UpdateNoteable(n, opts); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("could not find the following user(s) on GitLab: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (c *gitlabProvider) AssignIssue(org, repo string, number int, logins []string) error {
+	return c.updateUsers(org, repo, number, logins, false, true)
+}
+
+func (c *gitlabProvider) UnassignIssue(org, repo string, number int, logins []string) error {
+	return c.updateUsers(org, repo, number, logins, false, false)
+}
+
+func (c *gitlabProvider) RequestReview(org, repo string, number int, logins []string) error {
+	return c.updateUsers(org, repo, number, logins, true, true)
+}
+
+func (c *gitlabProvider) UnrequestReview(org, repo string, number int, logins []string) error {
+	return c.updateUsers(org, repo, number, logins, true, false)
+}
+
+func gitlabUsers(users []gitlab.User) []github.User {
+	var converted []github.User
+	for _, user := range users {
+		converted = append(converted, github.User{ID: user.ID, Login: user.Username})
+	}
+	return converted
+}
+
+func (c *gitlabProvider) GetPullRequest(org, repo string, number int) (*github.PullRequest, error) {
+	mr, err := c.glc.GetMergeRequest(org+"/"+repo, number)
+	if err != nil {
+		return nil, err
+	}
+	ghRepo := github.Repo{Owner: github.User{Login: org}, Name: repo, FullName: org + "/" + repo}
+	var labels []github.Label
+	for _, name := range mr.Labels {
+		labels = append(labels, github.Label{Name: name})
+	}
+	state := github.PullRequestStateClosed
+	if mr.State == "opened" {
+		state = github.PullRequestStateOpen
+	}
+	return &github.PullRequest{
+		Number:             mr.IID,
+		HTMLURL:            mr.WebURL,
+		User:               github.User{ID: mr.Author.ID, Login: mr.Author.Username},
+		Labels:             labels,
+		Base:               github.PullRequestBranch{Ref: mr.TargetBranch, Repo: ghRepo},
+		Head:               github.PullRequestBranch{Ref: mr.SourceBranch, SHA: mr.SHA, Repo: ghRepo},
+		Title:              mr.Title,
+		Body:               mr.Description,
+		RequestedReviewers: gitlabUsers(mr.Reviewers),
+		Assignees:          gitlabUsers(mr.Assignees),
+		State:              state,
+		Merged:             mr.State == "merged",
+		UpdatedAt:          mr.UpdatedAt,
+	}, nil
+}
+
+func (c *gitlabProvider) GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error) {
+	changes, err := c.glc.GetMergeRequestChanges(org+"/"+repo, number)
+	if err != nil {
+		return nil, err
+	}
+	var converted []github.PullRequestChange
+	for _, change := range changes {
+		status := string(github.PullRequestFileModified)
+		switch {
+		case change.NewFile:
+			status = github.PullRequestFileAdded
+		case change.DeletedFile:
+			status = github.PullRequestFileRemoved
+		case change.RenamedFile:
+			status = github.PullRequestFileRenamed
+		}
+		converted = append(converted, github.PullRequestChange{
+			Filename:         change.NewPath,
+			PreviousFilename: change.OldPath,
+			Status:           status,
+		})
+	}
+	return converted, nil
+}
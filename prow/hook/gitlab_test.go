@@ -0,0 +1,284 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/bugzilla"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/githubeventserver"
+	"k8s.io/test-infra/prow/gitlab"
+	"k8s.io/test-infra/prow/plugins"
+	"k8s.io/test-infra/prow/plugins/ownersconfig"
+	"k8s.io/test-infra/prow/repoowners"
+)
+
+type fakeGitLabClient struct {
+	gitlab.Client
+
+	mut     sync.Mutex
+	users   map[string]int
+	members map[string]int
+	mr      gitlab.MergeRequest
+	notes   map[gitlab.Noteable][]string
+	updates []gitlab.UpdateNoteableOptions
+}
+
+func (f *fakeGitLabClient) GetUser(id int) (*gitlab.User, error) {
+	for username, userID := range f.users {
+		if userID == id {
+			return &gitlab.User{ID: id, Username: username}, nil
+		}
+	}
+	return nil, &gitlab.RequestError{StatusCode: http.StatusNotFound}
+}
+
+func (f *fakeGitLabClient) FindUser(username string) (*gitlab.User, error) {
+	if id, ok := f.users[username]; ok {
+		return &gitlab.User{ID: id, Username: username}, nil
+	}
+	return nil, &gitlab.RequestError{StatusCode: http.StatusNotFound}
+}
+
+func (f *fakeGitLabClient) GetProjectMember(project, username string) (*gitlab.ProjectMember, error) {
+	if level, ok := f.members[username]; ok {
+		return &gitlab.ProjectMember{User: gitlab.User{Username: username}, AccessLevel: level}, nil
+	}
+	return nil, &gitlab.RequestError{StatusCode: http.StatusNotFound}
+}
+
+func (f *fakeGitLabClient) GetMergeRequest(project string, iid int) (*gitlab.MergeRequest, error) {
+	mr := f.mr
+	return &mr, nil
+}
+
+func (f *fakeGitLabClient) UpdateNoteable(n gitlab.Noteable, opts gitlab.UpdateNoteableOptions) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.updates = append(f.updates, opts)
+	return nil
+}
+
+func (f *fakeGitLabClient) CreateNote(n gitlab.Noteable, body string) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if f.notes == nil {
+		f.notes = map[gitlab.Noteable][]string{}
+	}
+	f.notes[n] = append(f.notes[n], body)
+	return nil
+}
+
+func TestGitLabRepo(t *testing.T) {
+	testCases := []struct {
+		path        string
+		owner, name string
+	}{
+		{path: "group/project", owner: "group", name: "project"},
+		{path: "group/sub/project", owner: "group/sub", name: "project"},
+	}
+	for _, tc := range testCases {
+		repo := gitlabRepo(gitlab.EventProject{PathWithNamespace: tc.path})
+		if repo.Owner.Login != tc.owner || repo.Name != tc.name || repo.FullName != tc.path {
+			t.Errorf("%s: expected repo %s of org %s, got %s of %s", tc.path, tc.name, tc.owner, repo.Name, repo.Owner.Login)
+		}
+	}
+}
+
+func TestGitLabPullRequestAction(t *testing.T) {
+	testCases := []struct {
+		name     string
+		event    gitlab.MergeRequestEvent
+		expected github.PullRequestEventAction
+	}{
+		{
+			name:     "opened",
+			event:    gitlab.MergeRequestEvent{ObjectAttributes: gitlab.EventMergeRequest{Action: gitlab.MergeRequestActionOpen}},
+			expected: github.PullRequestActionOpened,
+		},
+		{
+			name:     "merged",
+			event:    gitlab.MergeRequestEvent{ObjectAttributes: gitlab.EventMergeRequest{Action: gitlab.MergeRequestActionMerge}},
+			expected: github.PullRequestActionClosed,
+		},
+		{
+			name:     "pushed",
+			event:    gitlab.MergeRequestEvent{ObjectAttributes: gitlab.EventMergeRequest{Action: gitlab.MergeRequestActionUpdate, OldRev: "abc"}},
+			expected: github.PullRequestActionSynchronize,
+		},
+		{
+			name: "description changed",
+			event: gitlab.MergeRequestEvent{
+				ObjectAttributes: gitlab.EventMergeRequest{Action: gitlab.MergeRequestActionUpdate},
+				Changes:          map[string]interface{}{"description": map[string]interface{}{"previous": "", "current": "/hold"}},
+			},
+			expected: github.PullRequestActionEdited,
+		},
+		{
+			name: "labels changed",
+			event: gitlab.MergeRequestEvent{
+				ObjectAttributes: gitlab.EventMergeRequest{Action: gitlab.MergeRequestActionUpdate},
+				Changes:          map[string]interface{}{"labels": map[string]interface{}{}},
+			},
+		},
+		{
+			name:  "approved",
+			event: gitlab.MergeRequestEvent{ObjectAttributes: gitlab.EventMergeRequest{Action: gitlab.MergeRequestActionApproved}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := gitlabPullRequestAction(tc.event); actual != tc.expected {
+				t.Errorf("expected action %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGitLabGitHubClient(t *testing.T) {
+	glc := &fakeGitLabClient{
+		users:   map[string]int{"alice": 1, "bob": 2},
+		members: map[string]int{"alice": gitlab.AccessLevelDeveloper, "carol": gitlab.AccessLevelReporter},
+		mr:      gitlab.MergeRequest{Assignees: []gitlab.User{{ID: 3, Username: "dave"}}},
+	}
+	c := newGitLabGitHubClient(glc, gitlab.Noteable{Project: "group/project", Kind: gitlab.NoteableMergeRequest, IID: 1}, false)
+
+	for user, expected := range map[string]bool{"alice": true, "carol": false, "erin": false} {
+		if actual, err := c.IsCollaborator("group", "project", user); err != nil || actual != expected {
+			t.Errorf("expected %s to be a collaborator: %t, got %t (%v)", user, expected, actual, err)
+		}
+	}
+
+	if err := c.AssignIssue("group", "project", 1, []string{"alice", "@Bob", "frank"}); err == nil {
+		t.Error("expected an error for the unknown user")
+	}
+	if err := c.ForDryRun().AddLabel("group", "project", 1, "lgtm"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := c.RemoveLabel("group", "project", 1, "lgtm"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := []gitlab.UpdateNoteableOptions{
+		{AssigneeIDs: &[]int{1, 2, 3}},
+		{RemoveLabels: []string{"lgtm"}},
+	}
+	if diff := cmp.Diff(expected, glc.updates); diff != "" {
+		t.Errorf("updates differ from expected: %s", diff)
+	}
+}
+
+// TestServeGitLab sends a GitLab note webhook to hook and ensures that a plugin
+// handles it as a comment and comments back on the merge request, unless hook
+// runs in dry run.
+func TestServeGitLab(t *testing.T) {
+	called := make(chan github.GenericCommentEvent, 1)
+	plugins.RegisterGenericCommentHandler(
+		"gitlab-test",
+		func(pc plugins.Agent, ce github.GenericCommentEvent) error {
+			called <- ce
+			return pc.GitHubClient.CreateComment(ce.Repo.Owner.Login, ce.Repo.Name, ce.Number, "Hello")
+		},
+		nil,
+	)
+	noteable := gitlab.Noteable{Project: "group/sub/project", Kind: gitlab.NoteableMergeRequest, IID: 3}
+	testCases := []struct {
+		name          string
+		dryRun        bool
+		expectedNotes map[gitlab.Noteable][]string
+	}{
+		{
+			name:          "plugin comments on the merge request",
+			expectedNotes: map[gitlab.Noteable][]string{noteable: {"Hello"}},
+		},
+		{
+			name:   "plugin does not comment in dry run",
+			dryRun: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pa := &plugins.ConfigAgent{}
+			pa.Set(&plugins.Configuration{Plugins: plugins.Plugins{"group/sub": {Plugins: []string{"gitlab-test"}}}})
+			glc := &fakeGitLabClient{users: map[string]int{"alice": 1, "bob": 2}}
+			server := &Server{
+				ClientAgent: &plugins.ClientAgent{
+					GitHubClient:   github.NewFakeClient(),
+					OwnersClient:   repoowners.NewClient(nil, nil, func(org, repo string) bool { return false }, func(org, repo string) bool { return false }, func() *config.OwnersDirDenylist { return &config.OwnersDirDenylist{} }, ownersconfig.FakeResolver),
+					BugzillaClient: &bugzilla.Fake{},
+				},
+				Plugins:              pa,
+				ConfigAgent:          &config.Agent{},
+				Metrics:              githubeventserver.NewMetrics(),
+				RepoEnabled:          func(org, repo string) bool { return true },
+				GitLabClient:         glc,
+				GitLabTokenGenerator: func() []byte { return []byte("secret") },
+				DryRun:               tc.dryRun,
+			}
+			s := httptest.NewServer(http.HandlerFunc(server.ServeGitLab))
+			defer s.Close()
+
+			payload := `{
+  "object_kind": "note",
+  "user": {"id": 1, "username": "alice"},
+  "project": {"path_with_namespace": "group/sub/project"},
+  "object_attributes": {"id": 10, "note": "/hello", "noteable_type": "MergeRequest"},
+  "merge_request": {"id": 7, "iid": 3, "title": "Fix", "state": "opened", "author_id": 2}
+}`
+			req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewBufferString(payload))
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			req.Header.Set("X-Gitlab-Event", gitlab.NoteHook)
+			req.Header.Set("X-Gitlab-Token", "secret")
+			req.Header.Set("content-type", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("failed to send webhook: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", resp.StatusCode)
+			}
+
+			select {
+			case ce := <-called:
+				if ce.Repo.Owner.Login != "group/sub" || ce.Repo.Name != "project" || ce.Number != 3 || !ce.IsPR || ce.Body != "/hello" {
+					t.Errorf("unexpected comment event %+v", ce)
+				}
+				if ce.User.Login != "alice" || ce.IssueAuthor.Login != "bob" {
+					t.Errorf("expected a comment of alice on a merge request of bob, got %s on %s", ce.User.Login, ce.IssueAuthor.Login)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Plugin not called after one second.")
+			}
+			server.GracefulShutdown()
+
+			if diff := cmp.Diff(tc.expectedNotes, glc.notes); diff != "" {
+				t.Errorf("notes differ from expected: %s", diff)
+			}
+		})
+	}
+}
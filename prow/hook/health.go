@@ -0,0 +1,170 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// pluginHealth tracks the failed health checks in a row of the external
+// plugins, by endpoint.
+type pluginHealth struct {
+	mut sync.Mutex
+	// checked are the plugins checked, by endpoint.
+	checked  map[string]plugins.ExternalPlugin
+	failures map[string]int
+}
+
+// record records the result of a check of the plugin and returns the number
+// of checks it failed in a row before and after it.
+func (h *pluginHealth) record(p plugins.ExternalPlugin, err error) (int, int) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	if h.checked == nil {
+		h.checked, h.failures = map[string]plugins.ExternalPlugin{}, map[string]int{}
+	}
+	h.checked[p.Endpoint] = p
+	before := h.failures[p.Endpoint]
+	if err == nil {
+		delete(h.failures, p.Endpoint)
+		return before, 0
+	}
+	h.failures[p.Endpoint]++
+	return before, h.failures[p.Endpoint]
+}
+
+// healthy returns whether the endpoint passed its last check or failed less
+// than threshold checks in a row.
+func (h *pluginHealth) healthy(endpoint string, threshold int) bool {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	failures := h.failures[endpoint]
+	return failures == 0 || failures < threshold
+}
+
+// prune forgets the plugins whose endpoints are not configured anymore and
+// returns them.
+func (h *pluginHealth) prune(configured map[string]plugins.ExternalPlugin) []plugins.ExternalPlugin {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	var pruned []plugins.ExternalPlugin
+	for endpoint, p := range h.checked {
+		if _, ok := configured[endpoint]; !ok {
+			delete(h.checked, endpoint)
+			delete(h.failures, endpoint)
+			pruned = append(pruned, p)
+		}
+	}
+	return pruned
+}
+
+// externalPluginHealthy returns whether events may be forwarded to the
+// external plugin.
+func (s *Server) externalPluginHealthy(p plugins.ExternalPlugin) bool {
+	cfg := s.Plugins.Config().ExternalPluginHealth
+	return !cfg.Enabled || s.health.healthy(p.Endpoint, cfg.UnhealthyThreshold)
+}
+
+// CheckExternalPlugins checks the health of the configured external plugins
+// once. The plugins failing too many checks in a row are demoted until one of
+// their checks succeeds. It is meant to be called on the interval of the
+// checks and blocks until all the checks are done.
+func (s *Server) CheckExternalPlugins() {
+	s.wg.Add(1)
+	defer s.wg.Done()
+	cfg := s.Plugins.Config()
+	if !cfg.ExternalPluginHealth.Enabled {
+		return
+	}
+	// Plugins enabled in several orgs or repos share their endpoint.
+	endpoints := map[string]plugins.ExternalPlugin{}
+	for _, externalPlugins := range cfg.ExternalPlugins {
+		for _, p := range externalPlugins {
+			endpoints[p.Endpoint] = p
+		}
+	}
+	for _, p := range s.health.prune(endpoints) {
+		s.Metrics.ExternalPluginHealthy.DeleteLabelValues(p.Name, p.Endpoint)
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range endpoints {
+		wg.Add(1)
+		go func(p plugins.ExternalPlugin) {
+			defer wg.Done()
+			s.checkExternalPlugin(p, cfg.ExternalPluginHealth)
+		}(p)
+	}
+	wg.Wait()
+}
+
+func (s *Server) checkExternalPlugin(p plugins.ExternalPlugin, cfg plugins.ExternalPluginHealth) {
+	l := logrus.WithFields(logrus.Fields{"external-plugin": p.Name, "endpoint": p.Endpoint})
+	labels := prometheus.Labels{"plugin": p.Name, "endpoint": p.Endpoint}
+	err := s.probe(p, cfg.TimeoutDuration)
+	if err != nil {
+		l.WithError(err).Debug("External plugin failed its health check.")
+		s.Metrics.ExternalPluginHealthCheckFailures.With(labels).Inc()
+	}
+	before, after := s.health.record(p, err)
+	switch {
+	case before < cfg.UnhealthyThreshold && after >= cfg.UnhealthyThreshold:
+		l.WithError(err).Warnf("External plugin failed %d health checks in a row, not forwarding events to it until it recovers.", after)
+	case before >= cfg.UnhealthyThreshold && after == 0:
+		l.Info("External plugin recovered, forwarding events to it again.")
+	}
+	healthy := 0.0
+	if after < cfg.UnhealthyThreshold {
+		healthy = 1
+	}
+	s.Metrics.ExternalPluginHealthy.With(labels).Set(healthy)
+}
+
+// probe sends a health check request to the external plugin.
+func (s *Server) probe(p plugins.ExternalPlugin, timeout time.Duration) error {
+	endpoint := p.HealthEndpoint
+	if endpoint == "" {
+		endpoint = p.Endpoint
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "ProwHook")
+	resp, err := s.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Event endpoints answer GET requests with client errors, which are fine
+	// as long as they answer.
+	if resp.StatusCode >= 500 || (p.HealthEndpoint != "" && (resp.StatusCode < 200 || resp.StatusCode > 299)) {
+		return fmt.Errorf("response has status %q", resp.Status)
+	}
+	return nil
+}
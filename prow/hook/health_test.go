@@ -0,0 +1,151 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/githubeventserver"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestCheckExternalPlugins(t *testing.T) {
+	var failing, events int32
+	plugin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method == http.MethodGet {
+			// Event endpoints don't serve GET requests, which is fine.
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		atomic.AddInt32(&events, 1)
+	}))
+	defer plugin.Close()
+
+	external := plugins.ExternalPlugin{Name: "external", Endpoint: plugin.URL}
+	pa := &plugins.ConfigAgent{}
+	pa.Set(&plugins.Configuration{
+		ExternalPlugins: map[string][]plugins.ExternalPlugin{"org": {external}},
+		ExternalPluginHealth: plugins.ExternalPluginHealth{
+			Enabled:            true,
+			TimeoutDuration:    time.Second,
+			UnhealthyThreshold: 2,
+		},
+	})
+	s := &Server{Plugins: pa, Metrics: githubeventserver.NewMetrics()}
+	dispatch := func() {
+		s.wg.Add(1)
+		s.demuxExternal(logrus.NewEntry(logrus.New()), "org", []plugins.ExternalPlugin{external}, []byte("{}"), http.Header{})
+		s.GracefulShutdown()
+	}
+
+	s.CheckExternalPlugins()
+	dispatch()
+	if !s.externalPluginHealthy(external) || atomic.LoadInt32(&events) != 1 {
+		t.Fatalf("expected a healthy plugin to receive the event, got %d events", events)
+	}
+
+	atomic.StoreInt32(&failing, 1)
+	s.CheckExternalPlugins()
+	if !s.externalPluginHealthy(external) {
+		t.Fatal("expected the plugin to be healthy after a single failed check")
+	}
+	s.CheckExternalPlugins()
+	if s.externalPluginHealthy(external) {
+		t.Fatal("expected the plugin to be demoted after two failed checks")
+	}
+	dispatch()
+	if atomic.LoadInt32(&events) != 1 {
+		t.Errorf("expected no event to be forwarded to the demoted plugin, got %d events", events-1)
+	}
+
+	atomic.StoreInt32(&failing, 0)
+	s.CheckExternalPlugins()
+	if !s.externalPluginHealthy(external) {
+		t.Fatal("expected the plugin to recover after a successful check")
+	}
+	dispatch()
+	if atomic.LoadInt32(&events) != 2 {
+		t.Errorf("expected the recovered plugin to receive the event, got %d events", events)
+	}
+}
+
+func TestExternalPluginHealthChecksAreOffByDefault(t *testing.T) {
+	var checks int32
+	plugin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&checks, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer plugin.Close()
+
+	external := plugins.ExternalPlugin{Name: "external", Endpoint: plugin.URL}
+	pa := &plugins.ConfigAgent{}
+	pa.Set(&plugins.Configuration{
+		ExternalPlugins:      map[string][]plugins.ExternalPlugin{"org": {external}},
+		ExternalPluginHealth: plugins.ExternalPluginHealth{UnhealthyThreshold: 1},
+	})
+	s := &Server{Plugins: pa, Metrics: githubeventserver.NewMetrics()}
+	s.CheckExternalPlugins()
+	if n := atomic.LoadInt32(&checks); n != 0 {
+		t.Errorf("expected no health checks, got %d", n)
+	}
+	if !s.externalPluginHealthy(external) {
+		t.Error("expected the plugin to be healthy without health checks")
+	}
+}
+
+func TestProbe(t *testing.T) {
+	status := http.StatusOK
+	plugin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer plugin.Close()
+	s := &Server{}
+
+	testCases := []struct {
+		name      string
+		status    int
+		health    bool
+		expectErr bool
+	}{
+		{name: "event endpoint answering", status: http.StatusMethodNotAllowed},
+		{name: "event endpoint failing", status: http.StatusBadGateway, expectErr: true},
+		{name: "health endpoint ok", status: http.StatusOK, health: true},
+		{name: "health endpoint answering with a client error", status: http.StatusNotFound, health: true, expectErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status = tc.status
+			p := plugins.ExternalPlugin{Name: "external", Endpoint: plugin.URL}
+			if tc.health {
+				p.HealthEndpoint = plugin.URL + "/healthz"
+			}
+			if err := s.probe(p, time.Second); (err != nil) != tc.expectErr {
+				t.Errorf("expected an error: %t, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
@@ -19,9 +19,14 @@ package hook
 import (
 	"encoding/json"
 	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/sirupsen/logrus"
+
 	"k8s.io/test-infra/prow/bugzilla"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
@@ -183,3 +188,93 @@ func TestHook(t *testing.T) {
 		t.Error("Plugin not called after one second.")
 	}
 }
+
+type commentingProvider struct {
+	github.Provider
+
+	mut      sync.Mutex
+	comments map[int][]string
+}
+
+func (p *commentingProvider) CreateComment(org, repo string, number int, comment string) error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if p.comments == nil {
+		p.comments = map[int][]string{}
+	}
+	p.comments[number] = append(p.comments[number], comment)
+	return nil
+}
+
+// TestCommands ensures that hook calls the handlers of the commands invoked in
+// comments, answers their invalid invocations and lists them with /help.
+func TestCommands(t *testing.T) {
+	called := make(chan string, 10)
+	plugins.RegisterCommands("commands-test", plugins.Command{
+		Name:        "greet",
+		Args:        []plugins.CommandArg{{Name: "who", Values: []string{"world", "prow"}}},
+		Description: "Greets.",
+		Handler: func(pc plugins.Agent, e github.GenericCommentEvent, i plugins.CommandInvocation) error {
+			called <- i.Arg("who")
+			return nil
+		},
+	})
+	pa := &plugins.ConfigAgent{}
+	pa.Set(&plugins.Configuration{
+		Plugins:        plugins.Plugins{"org": {Plugins: []string{"commands-test"}}},
+		CommandAliases: map[string]map[string]string{"org/repo": {"hello": "greet world"}},
+	})
+	provider := &commentingProvider{}
+	s := &Server{
+		ClientAgent: &plugins.ClientAgent{
+			GitHubClient:   github.NewProviderClient("fake", provider, false),
+			OwnersClient:   repoowners.NewClient(nil, nil, func(org, repo string) bool { return false }, func(org, repo string) bool { return false }, func() *config.OwnersDirDenylist { return &config.OwnersDirDenylist{} }, ownersconfig.FakeResolver),
+			BugzillaClient: &bugzilla.Fake{},
+		},
+		Plugins:     pa,
+		ConfigAgent: &config.Agent{},
+		Metrics:     githubeventserver.NewMetrics(),
+	}
+	l := logrus.WithField(eventTypeField, "issue_comment")
+	event := func(number int, isPR bool, body string) *github.GenericCommentEvent {
+		return &github.GenericCommentEvent{
+			Action: github.GenericCommentActionCreated,
+			IsPR:   isPR,
+			Body:   body,
+			Number: number,
+			Repo:   github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+			User:   github.User{Login: "alice"},
+		}
+	}
+	s.handleGenericComment(l, s.ClientAgent, event(1, true, "/greet PROW\n/greet world"))
+	s.handleGenericComment(l, s.ClientAgent, event(2, true, "/greet everyone"))
+	s.handleGenericComment(l, s.ClientAgent, event(3, true, "/help"))
+	s.handleGenericComment(l, s.ClientAgent, event(4, false, "/help"))
+	s.handleGenericComment(l, s.ClientAgent, event(5, false, "/help commands"))
+	s.handleGenericComment(l, s.ClientAgent, event(6, true, "/hello"))
+	s.GracefulShutdown()
+	close(called)
+
+	var greeted []string
+	for who := range called {
+		greeted = append(greeted, who)
+	}
+	sort.Strings(greeted)
+	if strings.Join(greeted, ",") != "prow,world,world" {
+		t.Errorf("expected prow and world, twice through an alias, to be greeted, got %v", greeted)
+	}
+	if len(provider.comments[1]) != 0 {
+		t.Errorf("expected no answer to valid commands, got %v", provider.comments[1])
+	}
+	if comments := provider.comments[2]; len(comments) != 1 || !strings.Contains(comments[0], "Usage: /greet <world|prow>") {
+		t.Errorf("expected the usage of the command, got %v", comments)
+	}
+	for _, number := range []int{3, 5} {
+		if comments := provider.comments[number]; len(comments) != 1 || !strings.Contains(comments[0], "- `/greet <world|prow>`: Greets.") {
+			t.Errorf("expected the commands to be listed on #%d, got %v", number, comments)
+		}
+	}
+	if len(provider.comments[4]) != 0 {
+		t.Errorf("expected /help on an issue to be left to the help plugin, got %v", provider.comments[4])
+	}
+}
@@ -19,8 +19,10 @@ package pluginimports
 // We need to empty import all enabled plugins so that they will be linked into
 // any hook binary.
 import (
-	_ "k8s.io/test-infra/prow/plugins/approve" // Import all enabled plugins.
+	_ "k8s.io/test-infra/prow/plugins/api-review" // Import all enabled plugins.
+	_ "k8s.io/test-infra/prow/plugins/approve"
 	_ "k8s.io/test-infra/prow/plugins/assign"
+	_ "k8s.io/test-infra/prow/plugins/backport-tracker"
 	_ "k8s.io/test-infra/prow/plugins/blockade"
 	_ "k8s.io/test-infra/prow/plugins/blunderbuss"
 	_ "k8s.io/test-infra/prow/plugins/branchcleaner"
@@ -30,6 +32,8 @@ import (
 	_ "k8s.io/test-infra/prow/plugins/cherrypickunapproved"
 	_ "k8s.io/test-infra/prow/plugins/cla"
 	_ "k8s.io/test-infra/prow/plugins/dco"
+	_ "k8s.io/test-infra/prow/plugins/dco-cla"
+	_ "k8s.io/test-infra/prow/plugins/dedupe"
 	_ "k8s.io/test-infra/prow/plugins/dog"
 	_ "k8s.io/test-infra/prow/plugins/golint"
 	_ "k8s.io/test-infra/prow/plugins/goose"
@@ -47,19 +51,24 @@ import (
 	_ "k8s.io/test-infra/prow/plugins/milestoneapplier"
 	_ "k8s.io/test-infra/prow/plugins/milestonestatus"
 	_ "k8s.io/test-infra/prow/plugins/override"
+	_ "k8s.io/test-infra/prow/plugins/owners-cache"
 	_ "k8s.io/test-infra/prow/plugins/owners-label"
 	_ "k8s.io/test-infra/prow/plugins/pony"
 	_ "k8s.io/test-infra/prow/plugins/project"
 	_ "k8s.io/test-infra/prow/plugins/projectmanager"
 	_ "k8s.io/test-infra/prow/plugins/releasenote"
 	_ "k8s.io/test-infra/prow/plugins/require-matching-label"
+	_ "k8s.io/test-infra/prow/plugins/required-reviewers"
 	_ "k8s.io/test-infra/prow/plugins/retitle"
+	_ "k8s.io/test-infra/prow/plugins/revert"
+	_ "k8s.io/test-infra/prow/plugins/semantic-title"
 	_ "k8s.io/test-infra/prow/plugins/shrug"
 	_ "k8s.io/test-infra/prow/plugins/sigmention"
 	_ "k8s.io/test-infra/prow/plugins/size"
 	_ "k8s.io/test-infra/prow/plugins/skip"
 	_ "k8s.io/test-infra/prow/plugins/slackevents"
 	_ "k8s.io/test-infra/prow/plugins/stage"
+	_ "k8s.io/test-infra/prow/plugins/stale"
 	_ "k8s.io/test-infra/prow/plugins/testfreeze"
 	_ "k8s.io/test-infra/prow/plugins/transfer-issue"
 	_ "k8s.io/test-infra/prow/plugins/trick-or-treat"
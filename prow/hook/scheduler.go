@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// semaphore bounds the concurrent executions of a plugin, or of the plugins for
+// the events of an org.
+type semaphore struct {
+	limit int
+	slots chan struct{}
+}
+
+// scheduler bounds the concurrent executions of the plugins following their
+// concurrency config. The handlers beyond the limits wait for a slot, so the
+// events queue up for the busy plugins and orgs only.
+type scheduler struct {
+	mut     sync.Mutex
+	plugins map[string]*semaphore
+	orgs    map[string]*semaphore
+}
+
+// semaphoreFor returns the semaphore of the key, nil if its limit is 0. The
+// semaphore is replaced when its limit changed with the config, the handlers
+// holding slots of the previous one release them there.
+func semaphoreFor(semaphores map[string]*semaphore, key string, limit int) *semaphore {
+	if limit <= 0 {
+		delete(semaphores, key)
+		return nil
+	}
+	if sem, ok := semaphores[key]; ok && sem.limit == limit {
+		return sem
+	}
+	sem := &semaphore{limit: limit, slots: make(chan struct{}, limit)}
+	semaphores[key] = sem
+	return sem
+}
+
+// acquire blocks until the plugin may handle an event of the org and returns
+// the function releasing its slots. The slot of the plugin is always acquired
+// before the one of the org, so that handlers can't wait for each other.
+func (s *scheduler) acquire(cfg plugins.Concurrency, plugin, org string) func() {
+	s.mut.Lock()
+	if s.plugins == nil {
+		s.plugins, s.orgs = map[string]*semaphore{}, map[string]*semaphore{}
+	}
+	var acquired []*semaphore
+	if sem := semaphoreFor(s.plugins, plugin, cfg.PluginLimit(plugin)); sem != nil {
+		acquired = append(acquired, sem)
+	}
+	if org != "" {
+		if sem := semaphoreFor(s.orgs, org, cfg.PerOrg); sem != nil {
+			acquired = append(acquired, sem)
+		}
+	}
+	s.mut.Unlock()
+
+	for _, sem := range acquired {
+		sem.slots <- struct{}{}
+	}
+	return func() {
+		for _, sem := range acquired {
+			<-sem.slots
+		}
+	}
+}
+
+// schedule waits until the plugin may handle an event of the org and returns
+// the function to call once it handled it.
+func (s *Server) schedule(plugin, org string) func() {
+	labels := prometheus.Labels{"plugin": plugin}
+	depth := s.Metrics.PluginQueueDepth.With(labels)
+	depth.Inc()
+	start := time.Now()
+	release := s.scheduler.acquire(s.Plugins.Config().Concurrency, plugin, org)
+	depth.Dec()
+	s.Metrics.PluginQueueWait.With(labels).Observe(time.Since(start).Seconds())
+	return release
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/githubeventserver"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// acquired returns whether the scheduler lets the plugin handle an event of the
+// org within a short time, and the function releasing its slots.
+func acquired(s *scheduler, cfg plugins.Concurrency, plugin, org string) (bool, func()) {
+	done := make(chan func(), 1)
+	go func() { done <- s.acquire(cfg, plugin, org) }()
+	select {
+	case release := <-done:
+		return true, release
+	case <-time.After(100 * time.Millisecond):
+		return false, func() { (<-done)() }
+	}
+}
+
+func TestSchedulerAcquire(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  plugins.Concurrency
+		// first is the plugin and org handling an event when the second waits.
+		first, second [2]string
+		expected      bool
+	}{
+		{
+			name:     "no limits",
+			first:    [2]string{"owners-label", "org"},
+			second:   [2]string{"owners-label", "org"},
+			expected: true,
+		},
+		{
+			name:   "plugin at its limit",
+			cfg:    plugins.Concurrency{PerPlugin: map[string]int{"owners-label": 1}},
+			first:  [2]string{"owners-label", "org"},
+			second: [2]string{"owners-label", "other-org"},
+		},
+		{
+			name:     "other plugins are not bound by the limit of a plugin",
+			cfg:      plugins.Concurrency{PerPlugin: map[string]int{"owners-label": 1}},
+			first:    [2]string{"owners-label", "org"},
+			second:   [2]string{"lgtm", "org"},
+			expected: true,
+		},
+		{
+			name:   "default plugin limit",
+			cfg:    plugins.Concurrency{DefaultPerPlugin: 1},
+			first:  [2]string{"lgtm", "org"},
+			second: [2]string{"lgtm", "other-org"},
+		},
+		{
+			name:   "org at its limit",
+			cfg:    plugins.Concurrency{PerOrg: 1},
+			first:  [2]string{"owners-label", "org"},
+			second: [2]string{"lgtm", "org"},
+		},
+		{
+			name:     "other orgs are not bound by the limit of an org",
+			cfg:      plugins.Concurrency{PerOrg: 1},
+			first:    [2]string{"owners-label", "org"},
+			second:   [2]string{"owners-label", "other-org"},
+			expected: true,
+		},
+		{
+			name:     "periodic handlers are not bound by the limit of the orgs",
+			cfg:      plugins.Concurrency{PerOrg: 1},
+			first:    [2]string{"stale", ""},
+			second:   [2]string{"stale", ""},
+			expected: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &scheduler{}
+			ok, releaseFirst := acquired(s, tc.cfg, tc.first[0], tc.first[1])
+			if !ok {
+				t.Fatal("expected the first handler to run")
+			}
+			ok, releaseSecond := acquired(s, tc.cfg, tc.second[0], tc.second[1])
+			if ok != tc.expected {
+				t.Errorf("expected the second handler to run: %t, got %t", tc.expected, ok)
+			}
+			releaseFirst()
+			releaseSecond()
+		})
+	}
+}
+
+func TestSchedulerLimitChange(t *testing.T) {
+	s := &scheduler{}
+	cfg := plugins.Concurrency{DefaultPerPlugin: 1}
+	_, release := acquired(s, cfg, "lgtm", "org")
+	if ok, _ := acquired(s, plugins.Concurrency{DefaultPerPlugin: 2}, "lgtm", "org"); !ok {
+		t.Error("expected the handler to run with the raised limit")
+	}
+	release()
+	if ok, _ := acquired(s, cfg, "lgtm", "org"); !ok {
+		t.Error("expected the handler to run once the limit is lowered again")
+	}
+}
+
+func TestDemuxExternalIsScheduled(t *testing.T) {
+	var inFlight, maxInFlight int32
+	plugin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer plugin.Close()
+
+	external := plugins.ExternalPlugin{Name: "external", Endpoint: plugin.URL}
+	pa := &plugins.ConfigAgent{}
+	pa.Set(&plugins.Configuration{
+		ExternalPlugins: map[string][]plugins.ExternalPlugin{"org": {external}},
+		Concurrency:     plugins.Concurrency{PerPlugin: map[string]int{"external": 1}},
+	})
+	s := &Server{Plugins: pa, Metrics: githubeventserver.NewMetrics(), c: http.Client{}}
+	for i := 0; i < 3; i++ {
+		s.wg.Add(1)
+		go s.demuxExternal(logrus.NewEntry(logrus.New()), "org", []plugins.ExternalPlugin{external}, []byte("{}"), http.Header{})
+	}
+	s.wg.Wait()
+	if maxInFlight != 1 {
+		t.Errorf("expected the external plugin to receive one event at a time, got up to %d", maxInFlight)
+	}
+}
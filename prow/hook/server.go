@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strconv"
@@ -30,8 +31,10 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/gitea"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/githubeventserver"
+	"k8s.io/test-infra/prow/gitlab"
 	_ "k8s.io/test-infra/prow/hook/plugin-imports"
 	"k8s.io/test-infra/prow/plugins"
 )
@@ -46,11 +49,27 @@ type Server struct {
 	Metrics        *githubeventserver.Metrics
 	RepoEnabled    func(org, repo string) bool
 
+	// GitLabClient and GitLabTokenGenerator are only set when GitLab
+	// webhooks are served, see ServeGitLab.
+	GitLabClient         gitlab.Client
+	GitLabTokenGenerator func() []byte
+	// GiteaClient and GiteaTokenGenerator are only set when Gitea webhooks
+	// are served, see ServeGitea.
+	GiteaClient         gitea.Client
+	GiteaTokenGenerator func() []byte
+	// DryRun makes the plugins handling GitLab and Gitea events log the
+	// changes they would make on the forge instead of making them.
+	DryRun bool
+
 	// c is an http client used for dispatching events
 	// to external plugin services.
 	c http.Client
 	// Tracks running handlers for graceful shutdown
 	wg sync.WaitGroup
+	// Bounds the concurrent executions of the plugins
+	scheduler scheduler
+	// Tracks the health of the external plugins
+	health pluginHealth
 }
 
 // ServeHTTP validates an incoming webhook and puts it into the event channel.
@@ -98,7 +117,7 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		srcRepo = i.Repo.FullName
 		if s.RepoEnabled(i.Repo.Owner.Login, i.Repo.Name) {
 			s.wg.Add(1)
-			go s.handleIssueEvent(l, i)
+			go s.handleIssueEvent(l, s.ClientAgent, i)
 		}
 	case "issue_comment":
 		var ic github.IssueCommentEvent
@@ -109,7 +128,7 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		srcRepo = ic.Repo.FullName
 		if s.RepoEnabled(ic.Repo.Owner.Login, ic.Repo.Name) {
 			s.wg.Add(1)
-			go s.handleIssueCommentEvent(l, ic)
+			go s.handleIssueCommentEvent(l, s.ClientAgent, ic)
 		}
 	case "pull_request":
 		var pr github.PullRequestEvent
@@ -120,7 +139,7 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		srcRepo = pr.Repo.FullName
 		if s.RepoEnabled(pr.Repo.Owner.Login, pr.Repo.Name) {
 			s.wg.Add(1)
-			go s.handlePullRequestEvent(l, pr)
+			go s.handlePullRequestEvent(l, s.ClientAgent, pr)
 		}
 	case "pull_request_review":
 		var re github.ReviewEvent
@@ -164,7 +183,7 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		srcRepo = se.Repo.FullName
 		if s.RepoEnabled(se.Repo.Owner.Login, se.Repo.Name) {
 			s.wg.Add(1)
-			go s.handleStatusEvent(l, se)
+			go s.handleStatusEvent(l, s.ClientAgent, se)
 		}
 	default:
 		var ge github.GenericEvent
@@ -176,12 +195,78 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 	}
 	// Demux events only to external plugins that require this event.
 	if external := s.needDemux(eventType, srcRepo); len(external) > 0 {
+		payload, h = s.expandExternalCommandAliases(l, eventType, srcRepo, payload, h)
 		s.wg.Add(1)
-		go s.demuxExternal(l, external, payload, h)
+		go s.demuxExternal(l, strings.Split(srcRepo, "/")[0], external, payload, h)
 	}
 	return nil
 }
 
+// maxExternalPluginResponseSize is how many bytes of the responses of
+// external plugins are read.
+const maxExternalPluginResponseSize = 64 * 1024
+
+// commentFields are the fields of the payloads of events with the object
+// whose body is handled like a comment, see handleGenericComment.
+var commentFields = map[string]string{
+	"issues":                      "issue",
+	"issue_comment":               "comment",
+	"pull_request":                "pull_request",
+	"pull_request_review":         "review",
+	"pull_request_review_comment": "comment",
+}
+
+// expandExternalCommandAliases expands the command aliases of the repo in the
+// comment of the payload, so that external plugins get the same commands as
+// the plugins of hook. A changed payload is signed again with the hmac of
+// the repo. The payload is passed on unchanged when its aliases can't be
+// expanded.
+func (s *Server) expandExternalCommandAliases(l *logrus.Entry, eventType, orgRepo string, payload []byte, h http.Header) ([]byte, http.Header) {
+	field, ok := commentFields[eventType]
+	if !ok {
+		return payload, h
+	}
+	org, repo := orgRepo, ""
+	if i := strings.Index(orgRepo, "/"); i >= 0 {
+		org, repo = orgRepo[:i], orgRepo[i+1:]
+	}
+	aliases := s.Plugins.Config().CommandAliasesFor(org, repo)
+	if len(aliases) == 0 {
+		return payload, h
+	}
+
+	// Numbers are kept as they are, IDs may not fit into a float64.
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var event map[string]interface{}
+	if err := decoder.Decode(&event); err != nil {
+		l.WithError(err).Warn("Failed to decode the payload to expand command aliases for external plugins.")
+		return payload, h
+	}
+	object, _ := event[field].(map[string]interface{})
+	body, _ := object["body"].(string)
+	expanded := plugins.ExpandCommandAliases(body, aliases)
+	if expanded == body {
+		return payload, h
+	}
+	object["body"] = expanded
+	changed, err := json.Marshal(event)
+	if err != nil {
+		l.WithError(err).Warn("Failed to encode the payload with expanded command aliases for external plugins.")
+		return payload, h
+	}
+	sig, err := github.ResignPayload(payload, changed, h.Get("X-Hub-Signature"), s.TokenGenerator)
+	if err != nil {
+		l.WithError(err).Warn("Failed to sign the payload with expanded command aliases for external plugins.")
+		return payload, h
+	}
+	h = h.Clone()
+	h.Set("X-Hub-Signature", sig)
+	// The SHA-256 signature of GitHub doesn't match the changed payload.
+	h.Del("X-Hub-Signature-256")
+	return changed, h
+}
+
 // needDemux returns whether there are any external plugins that need to
 // get the present event.
 func (s *Server) needDemux(eventType, orgRepo string) []plugins.ExternalPlugin {
@@ -220,14 +305,23 @@ func (s *Server) needDemux(eventType, orgRepo string) []plugins.ExternalPlugin {
 	return matching
 }
 
-// demuxExternal dispatches the provided payload to the external plugins.
-func (s *Server) demuxExternal(l *logrus.Entry, externalPlugins []plugins.ExternalPlugin, payload []byte, h http.Header) {
+// demuxExternal dispatches the provided payload to the external plugins. The
+// dispatches are bounded like the executions of the plugins of hook, by the
+// name of the external plugin and by the org of the event.
+func (s *Server) demuxExternal(l *logrus.Entry, org string, externalPlugins []plugins.ExternalPlugin, payload []byte, h http.Header) {
 	defer s.wg.Done()
 	h.Set("User-Agent", "ProwHook")
 	for _, p := range externalPlugins {
+		if !s.externalPluginHealthy(p) {
+			l.WithField("external-plugin", p.Name).Debug("Not dispatching event to unhealthy external plugin.")
+			s.Metrics.ExternalPluginSkippedEvents.WithLabelValues(p.Name, p.Endpoint).Inc()
+			continue
+		}
 		s.wg.Add(1)
 		go func(p plugins.ExternalPlugin) {
 			defer s.wg.Done()
+			release := s.schedule(p.Name, org)
+			defer release()
 			if err := s.dispatch(p.Endpoint, payload, h); err != nil {
 				l.WithError(err).WithField("external-plugin", p.Name).Error("Error dispatching event to external plugin.")
 			} else {
@@ -250,7 +344,9 @@ func (s *Server) dispatch(endpoint string, payload []byte, h http.Header) error
 		return err
 	}
 	defer resp.Body.Close()
-	rb, err := ioutil.ReadAll(resp.Body)
+	// Only so much of the response is read, external plugins have no reason
+	// to send more than an error message.
+	rb, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxExternalPluginResponseSize))
 	if err != nil {
 		return err
 	}
@@ -264,6 +360,7 @@ func (s *Server) dispatch(endpoint string, payload []byte, h http.Header) error
 // receiving the shutdown signal.
 func (s *Server) GracefulShutdown() {
 	s.wg.Wait() // Handle remaining requests
+	s.handleShutdown()
 }
 
 func (s *Server) do(req *http.Request) (*http.Response, error) {
@@ -18,6 +18,7 @@ package hook
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -28,7 +29,9 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sirupsen/logrus"
 
+	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/githubeventserver"
 	"k8s.io/test-infra/prow/plugins"
 )
@@ -578,3 +581,102 @@ foo/bar:
 		})
 	}
 }
+
+func TestExpandExternalCommandAliases(t *testing.T) {
+	getSecret := func() []byte {
+		return []byte(`
+'*':
+  - value: abc
+    created_at: 2019-10-02T15:00:00Z
+`)
+	}
+	pa := &plugins.ConfigAgent{}
+	pa.Set(&plugins.Configuration{
+		CommandAliases: map[string]map[string]string{"kubernetes/test-infra": {"ship-it": "lgtm"}},
+	})
+	s := &Server{Plugins: pa, TokenGenerator: getSecret}
+
+	comment := func(repo, body string) []byte {
+		return []byte(`{"action": "created", "comment": {"id": 9007199254740993, "body": "` + body + `"}, "repository": {"full_name": "` + repo + `"}}`)
+	}
+	testcases := []struct {
+		name         string
+		eventType    string
+		orgRepo      string
+		payload      []byte
+		signature    string
+		expectedBody string
+	}{
+		{
+			name:         "aliases of a comment are expanded",
+			eventType:    "issue_comment",
+			orgRepo:      "kubernetes/test-infra",
+			payload:      comment("kubernetes/test-infra", "/ship-it"),
+			expectedBody: "/lgtm",
+		},
+		{
+			name:      "comments of repos without aliases are unchanged",
+			eventType: "issue_comment",
+			orgRepo:   "kubernetes/kubernetes",
+			payload:   comment("kubernetes/kubernetes", "/ship-it"),
+		},
+		{
+			name:      "events without comments are unchanged",
+			eventType: "push",
+			orgRepo:   "kubernetes/test-infra",
+			payload:   comment("kubernetes/test-infra", "/ship-it"),
+		},
+		{
+			name:      "payloads that can't be signed again are unchanged",
+			eventType: "issue_comment",
+			orgRepo:   "kubernetes/test-infra",
+			payload:   comment("kubernetes/test-infra", "/ship-it"),
+			signature: "sha1=invalid",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			signature := tc.signature
+			if signature == "" {
+				signature = github.PayloadSignature(tc.payload, []byte("abc"))
+			}
+			h := http.Header{}
+			h.Set("X-Hub-Signature", signature)
+			h.Set("X-Hub-Signature-256", "sha256=signature")
+
+			payload, header := s.expandExternalCommandAliases(logrus.NewEntry(logrus.New()), tc.eventType, tc.orgRepo, tc.payload, h)
+			if tc.expectedBody == "" {
+				if !bytes.Equal(payload, tc.payload) || !reflect.DeepEqual(header, h) {
+					t.Errorf("Expected the payload and header to be unchanged, got %s and %v", payload, header)
+				}
+				return
+			}
+
+			var event struct {
+				Comment struct {
+					ID   json.Number `json:"id"`
+					Body string      `json:"body"`
+				} `json:"comment"`
+			}
+			if err := json.Unmarshal(payload, &event); err != nil {
+				t.Fatalf("Failed to unmarshal the payload: %v", err)
+			}
+			if event.Comment.Body != tc.expectedBody {
+				t.Errorf("Expected body %q, got %q", tc.expectedBody, event.Comment.Body)
+			}
+			if event.Comment.ID != "9007199254740993" {
+				t.Errorf("Expected the ID to be kept, got %s", event.Comment.ID)
+			}
+			if !github.ValidatePayload(payload, header.Get("X-Hub-Signature"), getSecret) {
+				t.Error("Expected the changed payload to be signed again")
+			}
+			if header.Get("X-Hub-Signature-256") != "" {
+				t.Error("Expected the SHA-256 signature to be removed")
+			}
+			if h.Get("X-Hub-Signature") != signature {
+				t.Error("Expected the original header to be unchanged")
+			}
+		})
+	}
+}
@@ -89,7 +89,7 @@ type opener struct {
 	cachedBucketsMutex sync.Mutex
 }
 
-// NewOpener returns an opener that can read GCS, S3 and local paths.
+// NewOpener returns an opener that can read GCS, S3, Azure Blob storage and local paths.
 // credentialsFile may also be empty
 // For local paths it has to be empty
 // In all other cases gocloud auto-discovery is used to detect credentials, if credentialsFile is empty.
@@ -186,16 +186,17 @@ func (o *opener) openGCS(path string) (*storage.ObjectHandle, error) {
 
 // getBucket opens a bucket
 // The storageProvider is discovered based on the given path.
-// The buckets are cached per bucket name. So we don't open a bucket multiple times in the same process
+// The buckets are cached per storageProvider and bucket name. So we don't open a bucket multiple times in the same process
 func (o *opener) getBucket(ctx context.Context, path string) (*blob.Bucket, string, error) {
-	_, bucketName, relativePath, err := providers.ParseStoragePath(path)
+	storageProvider, bucketName, relativePath, err := providers.ParseStoragePath(path)
 	if err != nil {
 		return nil, "", fmt.Errorf("could not get bucket: %w", err)
 	}
 
 	o.cachedBucketsMutex.Lock()
 	defer o.cachedBucketsMutex.Unlock()
-	if bucket, ok := o.cachedBuckets[bucketName]; ok {
+	cacheKey := fmt.Sprintf("%s://%s", storageProvider, bucketName)
+	if bucket, ok := o.cachedBuckets[cacheKey]; ok {
 		return bucket, relativePath, nil
 	}
 
@@ -203,7 +204,7 @@ func (o *opener) getBucket(ctx context.Context, path string) (*blob.Bucket, stri
 	if err != nil {
 		return nil, "", err
 	}
-	o.cachedBuckets[bucketName] = bucket
+	o.cachedBuckets[cacheKey] = bucket
 	return bucket, relativePath, nil
 }
 
@@ -19,21 +19,25 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
 
+	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"gocloud.dev/blob"
+	"gocloud.dev/blob/azureblob"
 	_ "gocloud.dev/blob/memblob"
 	"gocloud.dev/blob/s3blob"
 )
 
 const (
-	S3 = "s3"
-	GS = "gs"
+	S3    = "s3"
+	GS    = "gs"
+	Azure = "azblob"
 )
 
 // GetBucket opens and returns a gocloud blob.Bucket based on credentials and a path.
@@ -43,7 +47,7 @@ const (
 // If no credentials are given, we just fall back to blob.OpenBucket which tries to auto discover credentials
 // e.g. via environment variables. For more details, see: https://gocloud.dev/howto/blob/
 //
-// If we specify credentials and an 3:// path is used, credentials must be given in one of the
+// If we specify credentials and an s3:// or azblob:// path is used, credentials must be given in one of the
 // following formats:
 // * AWS S3 (s3://):
 //    {
@@ -60,13 +64,48 @@ const (
 //      "access_key": "access_key",
 //      "secret_key": "secret_key"
 //    }
-func GetBucket(ctx context.Context, s3Credentials []byte, path string) (*blob.Bucket, error) {
+// * Azure Blob storage (azblob://<container>), storage_domain is only needed for clouds
+//   other than the Azure public cloud and sas_token may be given instead of storage_key:
+//    {
+//      "storage_account": "account",
+//      "storage_key": "key",
+//      "storage_domain": "blob.core.windows.net"
+//    }
+// Credentials for individual buckets can be given in "buckets", keyed by the bucket name that is
+// used in storage paths. They replace the top-level credentials for that bucket, e.g. to push to
+// a Ceph bucket and an Azure container from the same job:
+//    {
+//      "buckets": {
+//        "ceph-artifacts": {
+//          "region": "ceph",
+//          "endpoint": "https://rgw.ceph.example.com",
+//          "s3_force_path_style": true,
+//          "access_key": "access_key",
+//          "secret_key": "secret_key"
+//        },
+//        "prow-logs": {
+//          "storage_account": "account",
+//          "storage_key": "key"
+//        }
+//      }
+//    }
+//
+// Signed URLs for artifact links require an access_key and secret_key for S3, and a storage_key
+// for Azure Blob storage.
+func GetBucket(ctx context.Context, credentials []byte, path string) (*blob.Bucket, error) {
 	storageProvider, bucket, _, err := ParseStoragePath(path)
 	if err != nil {
 		return nil, err
 	}
-	if storageProvider == S3 && len(s3Credentials) > 0 {
-		return getS3Bucket(ctx, s3Credentials, bucket)
+	if (storageProvider == S3 || storageProvider == Azure) && len(credentials) > 0 {
+		creds, err := bucketCredentialsFor(credentials, bucket)
+		if err != nil {
+			return nil, err
+		}
+		if storageProvider == Azure {
+			return getAzureBucket(ctx, creds.azureCredentials, bucket)
+		}
+		return getS3Bucket(ctx, creds.s3Credentials, bucket)
 	}
 
 	bkt, err := blob.OpenBucket(ctx, fmt.Sprintf("%s://%s", storageProvider, bucket))
@@ -76,6 +115,33 @@ func GetBucket(ctx context.Context, s3Credentials []byte, path string) (*blob.Bu
 	return bkt, nil
 }
 
+// storageCredentials is the content of the credentials file, see GetBucket.
+type storageCredentials struct {
+	bucketCredentials
+	// Buckets holds the credentials of individual buckets, keyed by bucket name.
+	Buckets map[string]bucketCredentials `json:"buckets"`
+}
+
+// bucketCredentials are the credentials of a bucket, only the ones of the
+// storageProvider of the bucket are used.
+type bucketCredentials struct {
+	s3Credentials
+	azureCredentials
+}
+
+// bucketCredentialsFor returns the credentials of the bucket, or the top-level
+// credentials if there are none for the bucket.
+func bucketCredentialsFor(creds []byte, bucketName string) (bucketCredentials, error) {
+	storageCreds := &storageCredentials{}
+	if err := json.Unmarshal(creds, storageCreds); err != nil {
+		return bucketCredentials{}, fmt.Errorf("error getting storage credentials from JSON: %w", err)
+	}
+	if bucketCreds, ok := storageCreds.Buckets[bucketName]; ok {
+		return bucketCreds, nil
+	}
+	return storageCreds.bucketCredentials, nil
+}
+
 // s3Credentials are credentials used to access S3 or an S3-compatible storage service
 // Endpoint is an optional property. Default is the AWS S3 endpoint. If set, the specified
 // endpoint will be used instead.
@@ -90,12 +156,7 @@ type s3Credentials struct {
 
 // getS3Bucket opens a gocloud blob.Bucket based on given credentials in the format the
 // struct s3Credentials defines (see documentation of GetBucket for an example)
-func getS3Bucket(ctx context.Context, creds []byte, bucketName string) (*blob.Bucket, error) {
-	s3Creds := &s3Credentials{}
-	if err := json.Unmarshal(creds, s3Creds); err != nil {
-		return nil, fmt.Errorf("error getting S3 credentials from JSON: %w", err)
-	}
-
+func getS3Bucket(ctx context.Context, s3Creds s3Credentials, bucketName string) (*blob.Bucket, error) {
 	cfg := &aws.Config{}
 
 	//  Use the default credential chain if no credentials are specified
@@ -127,17 +188,60 @@ func getS3Bucket(ctx context.Context, creds []byte, bucketName string) (*blob.Bu
 	return bkt, nil
 }
 
+// azureCredentials are credentials used to access a container of an Azure Blob storage account.
+// Either StorageKey or SASToken must be set. StorageDomain is optional, it defaults to the
+// domain of the Azure public cloud.
+type azureCredentials struct {
+	StorageAccount string `json:"storage_account"`
+	StorageKey     string `json:"storage_key"`
+	SASToken       string `json:"sas_token"`
+	StorageDomain  string `json:"storage_domain"`
+}
+
+// getAzureBucket opens a gocloud blob.Bucket for the Azure Blob storage container based on
+// given credentials in the format the struct azureCredentials defines
+func getAzureBucket(ctx context.Context, azureCreds azureCredentials, containerName string) (*blob.Bucket, error) {
+	if azureCreds.StorageAccount == "" {
+		return nil, errors.New("storage_account is required for Azure Blob storage")
+	}
+	if azureCreds.StorageKey == "" && azureCreds.SASToken == "" {
+		return nil, errors.New("either storage_key or sas_token is required for Azure Blob storage")
+	}
+
+	accountName := azureblob.AccountName(azureCreds.StorageAccount)
+	opts := &azureblob.Options{
+		SASToken:      azureblob.SASToken(azureCreds.SASToken),
+		StorageDomain: azureblob.StorageDomain(azureCreds.StorageDomain),
+	}
+	var credential azblob.Credential = azblob.NewAnonymousCredential()
+	if azureCreds.StorageKey != "" {
+		sharedKeyCredential, err := azureblob.NewCredential(accountName, azureblob.AccountKey(azureCreds.StorageKey))
+		if err != nil {
+			return nil, fmt.Errorf("error creating Azure credential: %w", err)
+		}
+		credential = sharedKeyCredential
+		// The shared key is required to sign URLs.
+		opts.Credential = sharedKeyCredential
+	}
+
+	bkt, err := azureblob.OpenBucket(ctx, azureblob.NewPipeline(credential, azblob.PipelineOptions{}), accountName, containerName, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error opening Azure Blob storage container: %w", err)
+	}
+	return bkt, nil
+}
+
 // HasStorageProviderPrefix returns true if the given string starts with
 // any of the known storageProviders and a slash, e.g.
 // * gs/kubernetes-jenkins returns true
 // * kubernetes-jenkins returns false
 func HasStorageProviderPrefix(path string) bool {
-	return strings.HasPrefix(path, GS+"/") || strings.HasPrefix(path, S3+"/")
+	return strings.HasPrefix(path, GS+"/") || strings.HasPrefix(path, S3+"/") || strings.HasPrefix(path, Azure+"/")
 }
 
 // ParseStoragePath parses storagePath and returns the storageProvider, bucket and relativePath
 // For example gs://prow-artifacts/test.log results in (gs, prow-artifacts, test.log)
-// Currently detected storageProviders are GS, S3 and file.
+// Currently detected storageProviders are GS, S3, Azure and file.
 // Paths with a leading / instead of a storageProvider prefix are treated as file paths for backwards
 // compatibility reasons.
 // File paths are split into a directory and a file. Directory is returned as bucket, file is returned.
@@ -17,6 +17,7 @@ limitations under the License.
 package providers_test
 
 import (
+	"context"
 	"testing"
 
 	"k8s.io/test-infra/prow/io/providers"
@@ -38,6 +39,11 @@ func TestHasStorageProviderPrefix(t *testing.T) {
 			path: "gs/kubernetes-jenkins",
 			want: true,
 		},
+		{
+			name: "azblob prefix",
+			path: "azblob/prow-logs",
+			want: true,
+		},
 		{
 			name: "no prefix",
 			path: "kubernetes-jenkins",
@@ -102,6 +108,14 @@ func TestParseStoragePath(t *testing.T) {
 			wantRelativePath:    "",
 			wantErr:             false,
 		},
+		{
+			name:                "parse azblob path",
+			args:                args{storagePath: "azblob://prow-logs/pr-logs/bazel-build/test.log"},
+			wantStorageProvider: providers.Azure,
+			wantBucket:          "prow-logs",
+			wantRelativePath:    "pr-logs/bazel-build/test.log",
+			wantErr:             false,
+		},
 		{
 			name:    "parse gs to short path fails",
 			args:    args{storagePath: "gs://"},
@@ -133,3 +147,62 @@ func TestParseStoragePath(t *testing.T) {
 		})
 	}
 }
+
+func TestGetBucket(t *testing.T) {
+	tests := []struct {
+		name        string
+		credentials string
+		path        string
+		wantErr     bool
+	}{
+		{
+			name:        "s3 bucket with top-level credentials",
+			credentials: `{"region": "minio", "endpoint": "https://minio.example.com", "s3_force_path_style": true, "access_key": "key", "secret_key": "secret"}`,
+			path:        "s3://prow-logs/pr-logs",
+		},
+		{
+			name:        "azblob container with storage key",
+			credentials: `{"storage_account": "account", "storage_key": "a2V5"}`,
+			path:        "azblob://prow-logs/pr-logs",
+		},
+		{
+			name:        "azblob container with SAS token",
+			credentials: `{"storage_account": "account", "sas_token": "sv=2019-12-12&sig=signature"}`,
+			path:        "azblob://prow-logs/pr-logs",
+		},
+		{
+			name:        "azblob container without storage account fails",
+			credentials: `{"storage_key": "a2V5"}`,
+			path:        "azblob://prow-logs/pr-logs",
+			wantErr:     true,
+		},
+		{
+			name:        "azblob container with credentials of another bucket fails",
+			credentials: `{"buckets": {"other-logs": {"storage_account": "account", "storage_key": "a2V5"}}}`,
+			path:        "azblob://prow-logs/pr-logs",
+			wantErr:     true,
+		},
+		{
+			name:        "azblob container with bucket credentials",
+			credentials: `{"region": "us-east-1", "buckets": {"prow-logs": {"storage_account": "account", "storage_key": "a2V5"}}}`,
+			path:        "azblob://prow-logs/pr-logs",
+		},
+		{
+			name:        "invalid credentials fail",
+			credentials: `{"buckets": []}`,
+			path:        "s3://prow-logs/pr-logs",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, err := providers.GetBucket(context.Background(), []byte(tt.credentials), tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetBucket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if bucket != nil {
+				bucket.Close()
+			}
+		})
+	}
+}
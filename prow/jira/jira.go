@@ -37,6 +37,11 @@ type Client interface {
 	GetRemoteLinks(id string) ([]jira.RemoteLink, error)
 	AddRemoteLink(id string, link *jira.RemoteLink) error
 	ListProjects() (*jira.ProjectList, error)
+	GetTransitions(id string) ([]jira.Transition, error)
+	DoTransition(id, transitionID string) error
+	CreateIssue(issue *jira.Issue) (*jira.Issue, error)
+	SearchIssues(jql string) ([]jira.Issue, error)
+	AddComment(id string, comment *jira.Comment) (*jira.Comment, error)
 	JiraClient() *jira.Client
 	JiraURL() string
 }
@@ -190,6 +195,46 @@ func (jc *client) AddRemoteLink(id string, link *jira.RemoteLink) error {
 	return nil
 }
 
+func (jc *client) GetTransitions(id string) ([]jira.Transition, error) {
+	transitions, response, err := jc.upstream.Issue.GetTransitions(id)
+	if err != nil {
+		return nil, JiraError(response, err)
+	}
+	return transitions, nil
+}
+
+func (jc *client) DoTransition(id, transitionID string) error {
+	response, err := jc.upstream.Issue.DoTransition(id, transitionID)
+	if err != nil {
+		return JiraError(response, err)
+	}
+	return nil
+}
+
+func (jc *client) CreateIssue(issue *jira.Issue) (*jira.Issue, error) {
+	result, response, err := jc.upstream.Issue.Create(issue)
+	if err != nil {
+		return nil, JiraError(response, err)
+	}
+	return result, nil
+}
+
+func (jc *client) SearchIssues(jql string) ([]jira.Issue, error) {
+	issues, response, err := jc.upstream.Issue.Search(jql, &jira.SearchOptions{})
+	if err != nil {
+		return nil, JiraError(response, err)
+	}
+	return issues, nil
+}
+
+func (jc *client) AddComment(id string, comment *jira.Comment) (*jira.Comment, error) {
+	result, response, err := jc.upstream.Issue.AddComment(id, comment)
+	if err != nil {
+		return nil, JiraError(response, err)
+	}
+	return result, nil
+}
+
 func (jc *client) JiraURL() string {
 	return jc.url
 }
@@ -41,8 +41,10 @@ func pathSimplifier() simplifypath.Simplifier {
 			simplifypath.L("api",
 				simplifypath.L("2",
 					simplifypath.L("project"),
+					simplifypath.L("search"),
 					simplifypath.L("issue",
 						simplifypath.V("issueID",
+							simplifypath.L("comment"),
 							simplifypath.L("remotelink"),
 						),
 					),
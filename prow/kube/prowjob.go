@@ -66,4 +66,11 @@ const (
 	// IsOptionalLabel is added in resources created by prow and
 	// carries the Optional from a Presubmit job.
 	IsOptionalLabel = "prow.k8s.io/is-optional"
+	// RerunByAnnotation is added to the ProwJobs rerun from Deck and
+	// carries who requested the rerun, eg github:alice.
+	RerunByAnnotation = "prow.k8s.io/rerun-by"
+	// TriggeredByAnnotation is added to the ProwJobs started by trigger and
+	// carries the GitHub login of the user whose comment or PR update started
+	// them, eg alice.
+	TriggeredByAnnotation = "prow.k8s.io/triggered-by"
 )
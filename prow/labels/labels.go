@@ -18,7 +18,9 @@ package labels
 
 // labels for github plugins
 const (
+	APIReview                   = "api-review"
 	Approved                    = "approved"
+	BackportTracking            = "tracking/backport"
 	BlockedPaths                = "do-not-merge/blocked-paths"
 	Bug                         = "kind/bug"
 	BugzillaSeverityUrgent      = "bugzilla/severity-urgent"
@@ -29,6 +31,7 @@ const (
 	ClaNo                       = "cncf-cla: no"
 	ClaYes                      = "cncf-cla: yes"
 	CpApproved                  = "cherry-pick-approved"
+	CpConflict                  = "do-not-merge/cherry-pick-conflict"
 	CpUnapproved                = "do-not-merge/cherry-pick-not-approved"
 	DeprecationLabel            = "kind/deprecation"
 	GoodFirstIssue              = "good first issue"
@@ -36,14 +39,19 @@ const (
 	Hold                        = "do-not-merge/hold"
 	InvalidOwners               = "do-not-merge/invalid-owners-file"
 	InvalidBug                  = "bugzilla/invalid-bug"
+	InvalidIssue                = "invalid-issue"
+	InvalidTitle                = "do-not-merge/invalid-title"
 	LGTM                        = "lgtm"
 	LifecycleActive             = "lifecycle/active"
 	LifecycleFrozen             = "lifecycle/frozen"
 	LifecycleRotten             = "lifecycle/rotten"
 	LifecycleStale              = "lifecycle/stale"
 	MergeCommits                = "do-not-merge/contains-merge-commits"
+	NeedsAPIReview              = "do-not-merge/needs-api-review"
+	NeedsIssue                  = "needs-issue"
 	NeedsOkToTest               = "needs-ok-to-test"
 	NeedsRebase                 = "needs-rebase"
+	NeedsRequiredReviewer       = "do-not-merge/needs-required-reviewer"
 	OkToTest                    = "ok-to-test"
 	ReleaseNoteLabelNeeded      = "do-not-merge/release-note-label-needed"
 	ReleaseNote                 = "release-note"
@@ -51,6 +59,7 @@ const (
 	ReleaseNoteActionRequired   = "release-note-action-required"
 	Shrug                       = "¯\\_(ツ)_/¯"
 	TriageAccepted              = "triage/accepted"
+	TriageDuplicate             = "triage/duplicate"
 	WorkInProgress              = "do-not-merge/work-in-progress"
 	ValidBug                    = "bugzilla/valid-bug"
 )
@@ -76,6 +76,35 @@ func AvailablePresubmits(changes config.ChangedFilesProvider, org, repo, branch
 	return runWithTestAllNames, optionalJobTriggerCommands, requiredJobsTriggerCommands, nil
 }
 
+// ApplicablePresubmits returns the presubmits that can run against the branch,
+// grouped by how they are triggered:
+// 1. presubmits that always run.
+// 2. presubmits whose run_if_changed or skip_if_only_changed matches the changes.
+// 3. presubmits that only run when requested with their trigger, e.g. '/test job'.
+// Presubmits that run conditionally but don't match the changes are omitted.
+func ApplicablePresubmits(changes config.ChangedFilesProvider, branch string, presubmits []config.Presubmit) (alwaysRun, runIfChanged, optional []config.Presubmit, err error) {
+	for _, ps := range presubmits {
+		if !ps.CouldRun(branch) {
+			continue
+		}
+		switch {
+		case ps.AlwaysRun:
+			alwaysRun = append(alwaysRun, ps)
+		case ps.NeedsExplicitTrigger():
+			optional = append(optional, ps)
+		default:
+			_, shouldRun, err := ps.RegexpChangeMatcher.ShouldRun(changes)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("%s: should run: %w", ps.Name, err)
+			}
+			if shouldRun {
+				runIfChanged = append(runIfChanged, ps)
+			}
+		}
+	}
+	return alwaysRun, runIfChanged, optional, nil
+}
+
 // Filter digests a presubmit config to determine if:
 //  - the presubmit matched the filter
 //  - we know that the presubmit is forced to run
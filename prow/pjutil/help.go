@@ -19,13 +19,15 @@ package pjutil
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/config"
 )
 
 var (
-	TestHelpRe          = regexp.MustCompile(`(?m)^/test[ \t]*\?\s*$`)
+	TestHelpRe          = regexp.MustCompile(`(?m)^/test[ \t]*(?:\?|list)\s*$`)
 	EmptyTestRe         = regexp.MustCompile(`(?m)^/test\s*$`)
 	RetestWithTargetRe  = regexp.MustCompile(`(?m)^/retest[ \t]+\S+`)
 	TestWithAnyTargetRe = regexp.MustCompile(`(?m)^/test[ \t]+\S+`)
@@ -94,3 +96,35 @@ func HelpMessage(org, repo, branch, note string, testAllNames, optionalTestComma
 
 	return resp
 }
+
+// JobListMessage returns a message listing the presubmits that apply to a
+// change, grouped as returned by ApplicablePresubmits, with their trigger commands.
+func JobListMessage(org, repo, branch string, alwaysRun, runIfChanged, optional []config.Presubmit) string {
+	if len(alwaysRun)+len(runIfChanged)+len(optional) == 0 {
+		return fmt.Sprintf("No presubmit jobs available for %s/%s@%s", org, repo, branch)
+	}
+
+	listBuilder := func(presubmits []config.Presubmit) string {
+		var lines []string
+		for _, ps := range presubmits {
+			lines = append(lines, fmt.Sprintf("\n* `%s`: `%s`", ps.Name, ps.RerunCommand))
+		}
+		sort.Strings(lines)
+		return strings.Join(lines, "")
+	}
+
+	var resp string
+	if len(alwaysRun) > 0 {
+		resp += fmt.Sprintf("The following jobs always run:%s\n\n", listBuilder(alwaysRun))
+	}
+	if len(runIfChanged) > 0 {
+		resp += fmt.Sprintf("The following jobs run because of the files changed:%s\n\n", listBuilder(runIfChanged))
+	}
+	if len(optional) > 0 {
+		resp += fmt.Sprintf("The following optional jobs only run when requested:%s\n\n", listBuilder(optional))
+	}
+	if len(alwaysRun)+len(runIfChanged) > 0 {
+		resp += "Use `/test all` to run all jobs that always run or run because of the files changed.\n"
+	}
+	return resp
+}
@@ -143,6 +143,7 @@ func PresubmitSpec(p config.Presubmit, refs prowapi.Refs) prowapi.ProwJobSpec {
 	pjs.Context = p.Context
 	pjs.Report = !p.SkipReport
 	pjs.RerunCommand = p.RerunCommand
+	pjs.RunAfter = p.RunAfter
 	if p.JenkinsSpec != nil {
 		pjs.JenkinsSpec = &prowapi.JenkinsSpec{
 			GitHubBranchSourceJob: p.JenkinsSpec.GitHubBranchSourceJob,
@@ -159,6 +160,7 @@ func PostsubmitSpec(p config.Postsubmit, refs prowapi.Refs) prowapi.ProwJobSpec
 	pjs.Type = prowapi.PostsubmitJob
 	pjs.Context = p.Context
 	pjs.Report = !p.SkipReport
+	pjs.RunAfter = p.RunAfter
 	pjs.Refs = CompletePrimaryRefs(refs, p.JobBase)
 	if p.JenkinsSpec != nil {
 		pjs.JenkinsSpec = &prowapi.JenkinsSpec{
@@ -184,6 +186,7 @@ func BatchSpec(p config.Presubmit, refs prowapi.Refs) prowapi.ProwJobSpec {
 	pjs := specFromJobBase(p.JobBase)
 	pjs.Type = prowapi.BatchJob
 	pjs.Context = p.Context
+	pjs.RunAfter = p.RunAfter
 	pjs.Refs = CompletePrimaryRefs(refs, p.JobBase)
 
 	return pjs
@@ -198,9 +201,11 @@ func specFromJobBase(jb config.JobBase) prowapi.ProwJobSpec {
 		Job:             jb.Name,
 		Agent:           prowapi.ProwJobAgent(jb.Agent),
 		Cluster:         jb.Cluster,
+		ClusterSelector: jb.ClusterSelector,
 		Namespace:       namespace,
 		MaxConcurrency:  jb.MaxConcurrency,
 		ErrorOnEviction: jb.ErrorOnEviction,
+		RetryPolicy:     jb.RetryPolicy,
 
 		ExtraRefs:        jb.ExtraRefs,
 		DecorationConfig: jb.DecorationConfig,
@@ -231,6 +236,15 @@ func CompletePrimaryRefs(refs prowapi.Refs, jb config.JobBase) *prowapi.Refs {
 	if jb.SkipFetchHead {
 		refs.SkipFetchHead = jb.SkipFetchHead
 	}
+	if len(jb.SparseCheckout) > 0 {
+		refs.SparseCheckout = jb.SparseCheckout
+	}
+	if jb.PartialClone {
+		refs.PartialClone = jb.PartialClone
+	}
+	if jb.FetchLFS {
+		refs.FetchLFS = jb.FetchLFS
+	}
 	return &refs
 }
 
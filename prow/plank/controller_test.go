@@ -839,6 +839,8 @@ func TestSyncPendingJob(t *testing.T) {
 		ExpectedReport          bool
 		ExpectedURL             string
 		ExpectedBuildID         string
+		ExpectedAttempts        int
+		ExpectedTimedOut        bool
 	}
 	var testcases = []testCase{
 		{
@@ -1160,6 +1162,136 @@ func TestSyncPendingJob(t *testing.T) {
 			ExpectedNumPods:  1,
 			ExpectedURL:      "boop-42/error",
 		},
+		{
+			Name: "delete failed pod and record the attempt w/ retry_policy",
+			PJ: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "boop-42",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					RetryPolicy: &prowapi.RetryPolicy{MaxAttempts: 2, Backoff: &prowapi.Duration{Duration: 5 * time.Minute}, RetryOn: []prowapi.RetryCondition{prowapi.TestFailureCondition}},
+					PodSpec:     &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:   prowapi.PendingState,
+					PodName: "boop-42",
+					BuildID: "2",
+				},
+			},
+			Pods: []v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:       "boop-42",
+						Namespace:  "pods",
+						Finalizers: []string{"prow.x-k8s.io/gcsk8sreporter"},
+					},
+					Status: v1.PodStatus{
+						Phase:             v1.PodFailed,
+						ContainerStatuses: []v1.ContainerStatus{{State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}}},
+					},
+				},
+			},
+			expectedReconcileResult: &reconcile.Result{RequeueAfter: 5 * time.Minute},
+			ExpectedComplete:        false,
+			ExpectedState:           prowapi.PendingState,
+			ExpectedNumPods:         0,
+			ExpectedAttempts:        1,
+		},
+		{
+			Name: "complete PJ when retry_policy is out of attempts",
+			PJ: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "boop-42",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					RetryPolicy: &prowapi.RetryPolicy{MaxAttempts: 2, Backoff: &prowapi.Duration{Duration: 5 * time.Minute}, RetryOn: []prowapi.RetryCondition{prowapi.TestFailureCondition}},
+					PodSpec:     &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:    prowapi.PendingState,
+					PodName:  "boop-42",
+					BuildID:  "2",
+					Attempts: []prowapi.ProwJobAttempt{{PodName: "boop-42", BuildID: "1", Condition: prowapi.TestFailureCondition}},
+				},
+			},
+			Pods: []v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:       "boop-42",
+						Namespace:  "pods",
+						Finalizers: []string{"prow.x-k8s.io/gcsk8sreporter"},
+					},
+					Status: v1.PodStatus{
+						Phase:             v1.PodFailed,
+						ContainerStatuses: []v1.ContainerStatus{{State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}}},
+					},
+				},
+			},
+			ExpectedComplete: true,
+			ExpectedState:    prowapi.FailureState,
+			ExpectedNumPods:  1,
+			ExpectedAttempts: 1,
+		},
+		{
+			Name: "complete PJ when retry_policy doesn't retry test failures",
+			PJ: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "boop-42",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					RetryPolicy: &prowapi.RetryPolicy{MaxAttempts: 3},
+					PodSpec:     &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:   prowapi.PendingState,
+					PodName: "boop-42",
+					BuildID: "2",
+				},
+			},
+			Pods: []v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:       "boop-42",
+						Namespace:  "pods",
+						Finalizers: []string{"prow.x-k8s.io/gcsk8sreporter"},
+					},
+					Status: v1.PodStatus{
+						Phase:             v1.PodFailed,
+						ContainerStatuses: []v1.ContainerStatus{{State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}}},
+					},
+				},
+			},
+			ExpectedComplete: true,
+			ExpectedState:    prowapi.FailureState,
+			ExpectedNumPods:  1,
+		},
+		{
+			Name: "wait for the backoff of retry_policy before starting the next pod",
+			PJ: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "boop-42",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					RetryPolicy: &prowapi.RetryPolicy{MaxAttempts: 2, Backoff: &prowapi.Duration{Duration: 5 * time.Minute}, RetryOn: []prowapi.RetryCondition{prowapi.TestFailureCondition}},
+					PodSpec:     &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:    prowapi.PendingState,
+					PodName:  "boop-42",
+					BuildID:  "2",
+					Attempts: []prowapi.ProwJobAttempt{{PodName: "boop-42", BuildID: "2", RetryTime: metav1.NewTime(time.Now().Add(5 * time.Minute))}},
+				},
+			},
+			expectedReconcileResult: &reconcile.Result{RequeueAfter: 5 * time.Minute},
+			ExpectedComplete:        false,
+			ExpectedState:           prowapi.PendingState,
+			ExpectedNumPods:         0,
+			ExpectedAttempts:        1,
+		},
 		{
 			Name: "running pod",
 			PJ: prowapi.ProwJob{
@@ -1301,10 +1433,162 @@ func TestSyncPendingJob(t *testing.T) {
 					},
 				},
 			},
+			ExpectedState:    prowapi.PendingState,
+			ExpectedNumPods:  0,
+			ExpectedComplete: false,
+			ExpectedTimedOut: true,
+		},
+		{
+			Name: "running pod past the timeout and grace period of the job",
+			PJ: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "slow",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					DecorationConfig: &prowapi.DecorationConfig{
+						Timeout:     &prowapi.Duration{Duration: time.Hour},
+						GracePeriod: &prowapi.Duration{Duration: 15 * time.Minute},
+					},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:   prowapi.PendingState,
+					PodName: "slow",
+				},
+			},
+			Pods: []v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "slow",
+						Namespace: "pods",
+					},
+					Status: v1.PodStatus{
+						Phase:     v1.PodRunning,
+						StartTime: startTime(time.Now().Add(-2 * time.Hour)),
+					},
+				},
+			},
+			ExpectedState:    prowapi.PendingState,
+			ExpectedNumPods:  0,
+			ExpectedComplete: false,
+			ExpectedTimedOut: true,
+		},
+		{
+			Name: "running pod within the timeout and grace period of the job",
+			PJ: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "slow",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					DecorationConfig: &prowapi.DecorationConfig{
+						Timeout:     &prowapi.Duration{Duration: time.Hour},
+						GracePeriod: &prowapi.Duration{Duration: 15 * time.Minute},
+					},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:   prowapi.PendingState,
+					PodName: "slow",
+				},
+			},
+			Pods: []v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "slow",
+						Namespace: "pods",
+					},
+					Status: v1.PodStatus{
+						Phase:     v1.PodRunning,
+						StartTime: startTime(time.Now().Add(-time.Hour)),
+					},
+				},
+			},
+			expectedReconcileResult: &reconcile.Result{RequeueAfter: 15 * time.Minute},
+			ExpectedState:           prowapi.PendingState,
+			ExpectedNumPods:         1,
+			ExpectedComplete:        false,
+		},
+		{
+			Name: "wait for the stopped pod of a timed out job to terminate",
+			PJ: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "slow",
+					Namespace: "prowjobs",
+				},
+				Status: prowapi.ProwJobStatus{
+					State:       prowapi.PendingState,
+					PodName:     "slow",
+					TimeoutTime: &metav1.Time{Time: time.Now()},
+				},
+			},
+			Pods: []v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "slow",
+						Namespace:         "pods",
+						DeletionTimestamp: func() *metav1.Time { n := metav1.Now(); return &n }(),
+					},
+					Spec: v1.PodSpec{TerminationGracePeriodSeconds: func() *int64 { s := int64(240); return &s }()},
+					Status: v1.PodStatus{
+						Phase: v1.PodRunning,
+					},
+				},
+			},
+			expectedReconcileResult: &reconcile.Result{RequeueAfter: 5 * time.Minute},
+			ExpectedState:           prowapi.PendingState,
+			ExpectedNumPods:         1,
+			ExpectedComplete:        false,
+			ExpectedTimedOut:        true,
+		},
+		{
+			Name: "abort timed out job once its pod terminated",
+			PJ: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "slow",
+					Namespace: "prowjobs",
+				},
+				Status: prowapi.ProwJobStatus{
+					State:       prowapi.PendingState,
+					PodName:     "slow",
+					TimeoutTime: &metav1.Time{Time: time.Now()},
+				},
+			},
+			Pods: []v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "slow",
+						Namespace:         "pods",
+						DeletionTimestamp: func() *metav1.Time { n := metav1.Now(); return &n }(),
+					},
+					Status: v1.PodStatus{
+						Phase: v1.PodFailed,
+					},
+				},
+			},
+			ExpectedState:    prowapi.AbortedState,
+			ExpectedNumPods:  1,
+			ExpectedComplete: true,
+			ExpectedTimedOut: true,
+			ExpectedURL:      "slow/aborted",
+		},
+		{
+			Name: "abort timed out job once its pod is gone",
+			PJ: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "slow",
+					Namespace: "prowjobs",
+				},
+				Status: prowapi.ProwJobStatus{
+					State:       prowapi.PendingState,
+					PodName:     "slow",
+					TimeoutTime: &metav1.Time{Time: time.Now()},
+				},
+			},
 			ExpectedState:    prowapi.AbortedState,
 			ExpectedNumPods:  0,
 			ExpectedComplete: true,
-			ExpectedURL:      "endless/aborted",
+			ExpectedTimedOut: true,
+			ExpectedURL:      "slow/aborted",
 		},
 		{
 			Name: "stale unschedulable prow job",
@@ -1573,6 +1857,12 @@ func TestSyncPendingJob(t *testing.T) {
 			if tc.ExpectedBuildID != "" && actual.Status.BuildID != tc.ExpectedBuildID {
 				t.Errorf("expected BuildID %q, got %q", tc.ExpectedBuildID, actual.Status.BuildID)
 			}
+			if n := len(actual.Status.Attempts); n != tc.ExpectedAttempts {
+				t.Errorf("expected %d attempts, got %d", tc.ExpectedAttempts, n)
+			}
+			if timedOut := actual.Status.TimeoutTime != nil; timedOut != tc.ExpectedTimedOut {
+				t.Errorf("expected timed out: %t, got timed out: %t", tc.ExpectedTimedOut, timedOut)
+			}
 			actualPods := &v1.PodList{}
 			if err := buildClients[prowapi.DefaultClusterAlias].List(context.Background(), actualPods); err != nil {
 				t.Errorf("could not list pods from the client: %v", err)
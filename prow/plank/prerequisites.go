@@ -0,0 +1,133 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plank
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/crier/reporters/gcs/util"
+	"k8s.io/test-infra/prow/pjutil"
+)
+
+const (
+	// prerequisiteRequeue is how often a job that waits for its
+	// prerequisites checks them again.
+	prerequisiteRequeue = 30 * time.Second
+	// prerequisiteTriggerTimeout is how long a job waits for a ProwJob of
+	// each of its prerequisites to be created before it errors out.
+	prerequisiteTriggerTimeout = 10 * time.Minute
+)
+
+// needsPrerequisites returns true if the ProwJob runs after other jobs and
+// plank did not yet see all of them succeed.
+func needsPrerequisites(pj *prowv1.ProwJob) bool {
+	return len(pj.Spec.RunAfter) > 0 && len(pj.Status.Prerequisites) == 0
+}
+
+// syncPrerequisites checks the latest ProwJob of each job the ProwJob runs
+// after. If all of them succeeded, they are recorded in the status of the
+// ProwJob and a nil result is returned so that the caller starts the job.
+// Otherwise the job waits for them, or is completed if one of them did not
+// succeed.
+func (r *reconciler) syncPrerequisites(ctx context.Context, pj *prowv1.ProwJob) (*reconcile.Result, error) {
+	latest := map[string]*prowv1.ProwJob{}
+	for _, job := range pj.Spec.RunAfter {
+		pjs := &prowv1.ProwJobList{}
+		if err := r.pjClient.List(ctx, pjs, optRunAfterJobsNamed(pj.Spec.Type, job, pj.Spec.Refs)); err != nil {
+			return nil, fmt.Errorf("failed to list prowjobs: %w", err)
+		}
+		for i := range pjs.Items {
+			candidate := &pjs.Items[i]
+			if prev, ok := latest[job]; !ok || prev.CreationTimestamp.Before(&candidate.CreationTimestamp) {
+				latest[job] = candidate
+			}
+		}
+	}
+
+	var prerequisites []prowv1.ProwJobPrerequisite
+	var waiting []string
+	for _, job := range pj.Spec.RunAfter {
+		prerequisite, ok := latest[job]
+		switch {
+		case !ok && r.clock.Since(pj.CreationTimestamp.Time) >= prerequisiteTriggerTimeout:
+			return r.completeWithoutPrerequisite(ctx, pj, prowv1.ErrorState, fmt.Sprintf("Prerequisite job %s was not triggered.", job))
+		case !ok, !prerequisite.Complete():
+			waiting = append(waiting, job)
+		case prerequisite.Status.State != prowv1.SuccessState:
+			return r.completeWithoutPrerequisite(ctx, pj, prowv1.AbortedState, fmt.Sprintf("Prerequisite job %s did not succeed.", job))
+		default:
+			prerequisites = append(prerequisites, prowv1.ProwJobPrerequisite{
+				Job:          job,
+				ProwJob:      prerequisite.Name,
+				ArtifactsURL: r.artifactsURL(prerequisite),
+			})
+		}
+	}
+
+	if len(waiting) > 0 {
+		if description := fmt.Sprintf("Waiting for %s.", strings.Join(waiting, ", ")); pj.Status.Description != description {
+			prevPJ := pj.DeepCopy()
+			pj.Status.Description = description
+			if err := r.pjClient.Patch(ctx, pj.DeepCopy(), ctrlruntimeclient.MergeFrom(prevPJ)); err != nil {
+				return nil, fmt.Errorf("patch prowjob: %w", err)
+			}
+		}
+		return &reconcile.Result{RequeueAfter: prerequisiteRequeue}, nil
+	}
+	pj.Status.Prerequisites = prerequisites
+	return nil, nil
+}
+
+// completeWithoutPrerequisite completes a ProwJob that can not run because of
+// one of its prerequisites.
+func (r *reconciler) completeWithoutPrerequisite(ctx context.Context, pj *prowv1.ProwJob, state prowv1.ProwJobState, description string) (*reconcile.Result, error) {
+	prevPJ := pj.DeepCopy()
+	pj.SetComplete()
+	pj.Status.State = state
+	pj.Status.Description = description
+	r.log.WithFields(pjutil.ProwJobFields(pj)).
+		WithField("from", prevPJ.Status.State).
+		WithField("to", pj.Status.State).Info("Transitioning states.")
+	if err := r.pjClient.Patch(ctx, pj.DeepCopy(), ctrlruntimeclient.MergeFrom(prevPJ)); err != nil {
+		return nil, fmt.Errorf("patch prowjob: %w", err)
+	}
+	return nil, nil
+}
+
+// artifactsURL returns where the ProwJob uploaded its artifacts to, or an
+// empty string if that can not be determined.
+func (r *reconciler) artifactsURL(pj *prowv1.ProwJob) string {
+	bucket, dir, err := util.GetJobDestination(r.config, pj)
+	if err != nil {
+		r.log.WithFields(pjutil.ProwJobFields(pj)).WithError(err).Debug("Failed to get the job destination of the prerequisite.")
+		return ""
+	}
+	parsedBucket, err := prowv1.ParsePath(bucket)
+	if err != nil {
+		r.log.WithFields(pjutil.ProwJobFields(pj)).WithError(err).Debug("Failed to parse the bucket of the prerequisite.")
+		return ""
+	}
+	return fmt.Sprintf("%s://%s/%s", parsedBucket.StorageProvider(), parsedBucket.Bucket(), path.Join(dir, "artifacts"))
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plank
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+func TestSyncTriggeredJobWithPrerequisites(t *testing.T) {
+	totServ := httptest.NewServer(http.HandlerFunc(handleTot))
+	defer totServ.Close()
+	fakeClock := clock.NewFakeClock(time.Now().Truncate(time.Second))
+	refs := &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "main", BaseSHA: "abcdef"}
+	otherRefs := &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "main", BaseSHA: "fedcba"}
+
+	prerequisite := func(name string, created time.Duration, state prowapi.ProwJobState, refs *prowapi.Refs) *prowapi.ProwJob {
+		pj := &prowapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "prowjobs",
+				CreationTimestamp: metav1.NewTime(fakeClock.Now().Add(-created)),
+			},
+			Spec: prowapi.ProwJobSpec{
+				Job:   "build",
+				Type:  prowapi.PostsubmitJob,
+				Agent: prowapi.KubernetesAgent,
+				Refs:  refs,
+				DecorationConfig: &prowapi.DecorationConfig{
+					GCSConfiguration: &prowapi.GCSConfiguration{Bucket: "bucket", PathStrategy: prowapi.PathStrategyExplicit},
+				},
+			},
+			Status: prowapi.ProwJobStatus{State: state, BuildID: "1"},
+		}
+		if state != prowapi.PendingState {
+			pj.SetComplete()
+		}
+		return pj
+	}
+
+	testCases := []struct {
+		name                  string
+		created               time.Duration
+		prerequisites         []runtime.Object
+		expectedResult        bool
+		expectedState         prowapi.ProwJobState
+		expectedDescription   string
+		expectedPrerequisites []prowapi.ProwJobPrerequisite
+	}{
+		{
+			name:                "prerequisite is running",
+			prerequisites:       []runtime.Object{prerequisite("build-1", time.Minute, prowapi.PendingState, refs)},
+			expectedResult:      true,
+			expectedState:       prowapi.TriggeredState,
+			expectedDescription: "Waiting for build.",
+		},
+		{
+			name:                "prerequisite only ran for other refs",
+			prerequisites:       []runtime.Object{prerequisite("build-1", time.Minute, prowapi.SuccessState, otherRefs)},
+			expectedResult:      true,
+			expectedState:       prowapi.TriggeredState,
+			expectedDescription: "Waiting for build.",
+		},
+		{
+			name:                "prerequisite succeeded",
+			prerequisites:       []runtime.Object{prerequisite("build-1", time.Minute, prowapi.SuccessState, refs)},
+			expectedState:       prowapi.PendingState,
+			expectedDescription: "Job triggered.",
+			expectedPrerequisites: []prowapi.ProwJobPrerequisite{{
+				Job:          "build",
+				ProwJob:      "build-1",
+				ArtifactsURL: "gs://bucket/logs/build/1/artifacts",
+			}},
+		},
+		{
+			name:                "prerequisite failed",
+			prerequisites:       []runtime.Object{prerequisite("build-1", time.Minute, prowapi.FailureState, refs)},
+			expectedState:       prowapi.AbortedState,
+			expectedDescription: "Prerequisite job build did not succeed.",
+		},
+		{
+			name: "latest prerequisite failed",
+			prerequisites: []runtime.Object{
+				prerequisite("build-1", 2*time.Minute, prowapi.SuccessState, refs),
+				prerequisite("build-2", time.Minute, prowapi.FailureState, refs),
+			},
+			expectedState:       prowapi.AbortedState,
+			expectedDescription: "Prerequisite job build did not succeed.",
+		},
+		{
+			name:                "prerequisite is not triggered yet",
+			created:             time.Minute,
+			expectedResult:      true,
+			expectedState:       prowapi.TriggeredState,
+			expectedDescription: "Waiting for build.",
+		},
+		{
+			name:                "prerequisite is never triggered",
+			created:             prerequisiteTriggerTimeout,
+			expectedState:       prowapi.ErrorState,
+			expectedDescription: "Prerequisite job build was not triggered.",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pj := &prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "deploy-1",
+					Namespace:         "prowjobs",
+					CreationTimestamp: metav1.NewTime(fakeClock.Now().Add(-tc.created)),
+				},
+				Spec: prowapi.ProwJobSpec{
+					Job:      "deploy",
+					Type:     prowapi.PostsubmitJob,
+					Agent:    prowapi.KubernetesAgent,
+					Refs:     refs,
+					RunAfter: []string{"build"},
+					PodSpec:  &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{State: prowapi.TriggeredState},
+			}
+			pjClient := &indexingClient{
+				Client:     fakectrlruntimeclient.NewFakeClient(append(tc.prerequisites, pj.DeepCopy())...),
+				indexFuncs: map[string]ctrlruntimeclient.IndexerFunc{prowJobIndexName: prowJobIndexer("prowjobs")},
+			}
+			buildClient := fakectrlruntimeclient.NewFakeClient()
+			r := &reconciler{
+				pjClient:     pjClient,
+				buildClients: map[string]ctrlruntimeclient.Client{prowapi.DefaultClusterAlias: buildClient},
+				log:          logrus.NewEntry(logrus.StandardLogger()),
+				config:       newFakeConfigAgent(t, 0).Config,
+				totURL:       totServ.URL,
+				clock:        fakeClock,
+			}
+			result, err := r.syncTriggeredJob(context.Background(), pj)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if requeued := result != nil && result.RequeueAfter > 0; requeued != tc.expectedResult {
+				t.Errorf("expected requeue to be %t, got result %+v", tc.expectedResult, result)
+			}
+
+			actual := &prowapi.ProwJob{}
+			if err := pjClient.Get(context.Background(), types.NamespacedName{Namespace: "prowjobs", Name: "deploy-1"}, actual); err != nil {
+				t.Fatalf("failed to get prowjob: %v", err)
+			}
+			if actual.Status.State != tc.expectedState || actual.Status.Description != tc.expectedDescription {
+				t.Errorf("expected state %q with description %q, got %q with description %q", tc.expectedState, tc.expectedDescription, actual.Status.State, actual.Status.Description)
+			}
+			if diff := cmp.Diff(tc.expectedPrerequisites, actual.Status.Prerequisites); diff != "" {
+				t.Errorf("prerequisites differ from expected: %s", diff)
+			}
+
+			pods := &v1.PodList{}
+			if err := buildClient.List(context.Background(), pods); err != nil {
+				t.Fatalf("failed to list pods: %v", err)
+			}
+			if tc.expectedState != prowapi.PendingState {
+				if len(pods.Items) != 0 {
+					t.Errorf("expected no pods, got %d", len(pods.Items))
+				}
+				return
+			}
+			if len(pods.Items) != 1 {
+				t.Fatalf("expected a pod, got %d", len(pods.Items))
+			}
+			expected := v1.EnvVar{Name: "PREREQUISITE_ARTIFACTS", Value: `{"build":"gs://bucket/logs/build/1/artifacts"}`}
+			for _, env := range pods.Items[0].Spec.Containers[0].Env {
+				if env.Name == expected.Name {
+					if diff := cmp.Diff(expected, env); diff != "" {
+						t.Errorf("env differs from expected: %s", diff)
+					}
+					return
+				}
+			}
+			t.Errorf("expected env %s to be set", expected.Name)
+		})
+	}
+}
@@ -336,7 +336,23 @@ func (r *reconciler) syncPendingJob(ctx context.Context, pj *prowv1.ProwJob) (*r
 		return nil, err
 	}
 
-	if !podExists {
+	if pj.Status.TimeoutTime != nil {
+		// The pod of the job was stopped because the job ran out of time, wait
+		// for the pod utilities to upload the artifacts before aborting the job.
+		if requeueAfter := r.timedOutPodRequeue(pj, pod, podExists); requeueAfter > 0 {
+			return &reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
+		pj.SetComplete()
+		pj.Status.State = prowv1.AbortedState
+		pj.Status.Description = "Job timed out."
+	} else if !podExists {
+		// The pod of a failed attempt is deleted right away, wait for the backoff
+		// of its retry policy before starting the next one.
+		if attempt := lastAttempt(pj); attempt != nil {
+			if backoff := attempt.RetryTime.Sub(r.clock.Now()); backoff > 0 {
+				return &reconcile.Result{RequeueAfter: backoff}, nil
+			}
+		}
 		// Pod is missing. This can happen in case the previous pod was deleted manually or by
 		// a rescheduler. Start a new pod.
 		id, pn, err := r.startPod(ctx, pj)
@@ -366,16 +382,8 @@ func (r *reconciler) syncPendingJob(ctx context.Context, pj *prowv1.ProwJob) (*r
 				return nil, fmt.Errorf("unknown pod %s: unknown cluster alias %q", pod.Name, pj.ClusterAlias())
 			}
 
-			if finalizers := sets.NewString(pod.Finalizers...); finalizers.Has(kubernetesreporterapi.FinalizerName) {
-				// We want the end user to not see this, so we have to remove the finalizer, otherwise the pod hangs
-				oldPod := pod.DeepCopy()
-				pod.Finalizers = finalizers.Delete(kubernetesreporterapi.FinalizerName).UnsortedList()
-				if err := client.Patch(ctx, pod, ctrlruntimeclient.MergeFrom(oldPod)); err != nil {
-					return nil, fmt.Errorf("failed to patch pod trying to remove %s finalizer: %w", kubernetesreporterapi.FinalizerName, err)
-				}
-			}
 			r.log.WithField("name", pj.ObjectMeta.Name).Debug("Delete Pod.")
-			return nil, ctrlruntimeclient.IgnoreNotFound(client.Delete(ctx, pod))
+			return nil, deletePodWithoutFinalizer(ctx, client, pod)
 
 		case corev1.PodSucceeded:
 			pj.SetComplete()
@@ -406,16 +414,13 @@ func (r *reconciler) syncPendingJob(ctx context.Context, pj *prowv1.ProwJob) (*r
 				if !ok {
 					return nil, fmt.Errorf("evicted pod %s: unknown cluster alias %q", pod.Name, pj.ClusterAlias())
 				}
-				if finalizers := sets.NewString(pod.Finalizers...); finalizers.Has(kubernetesreporterapi.FinalizerName) {
-					// We want the end user to not see this, so we have to remove the finalizer, otherwise the pod hangs
-					oldPod := pod.DeepCopy()
-					pod.Finalizers = finalizers.Delete(kubernetesreporterapi.FinalizerName).UnsortedList()
-					if err := client.Patch(ctx, pod, ctrlruntimeclient.MergeFrom(oldPod)); err != nil {
-						return nil, fmt.Errorf("failed to patch pod trying to remove %s finalizer: %w", kubernetesreporterapi.FinalizerName, err)
-					}
-				}
 				r.log.WithField("name", pj.ObjectMeta.Name).Debug("Delete Pod.")
-				return nil, ctrlruntimeclient.IgnoreNotFound(client.Delete(ctx, pod))
+				return nil, deletePodWithoutFinalizer(ctx, client, pod)
+			}
+			// The failure may already be recorded as an attempt if the deletion of
+			// the pod didn't reach the cache yet.
+			if condition := podFailureCondition(pod); lastAttempt(pj) != nil || pj.Spec.RetryPolicy.ShouldRetry(condition, len(pj.Status.Attempts)+1) {
+				return r.retryJob(ctx, pj, prevPJ, pod, condition)
 			}
 			// Pod failed. Update ProwJob, talk to GitHub.
 			pj.SetComplete()
@@ -470,20 +475,36 @@ func (r *reconciler) syncPendingJob(ctx context.Context, pj *prowv1.ProwJob) (*r
 			if pod.DeletionTimestamp != nil {
 				break
 			}
-			maxPodRunning := r.config().Plank.PodRunningTimeout.Duration
-			if pod.Status.StartTime.IsZero() || time.Since(pod.Status.StartTime.Time) < maxPodRunning {
+			if pod.Status.StartTime.IsZero() {
 				// Pod is still running. Do nothing.
 				return nil, nil
 			}
+			timeout := runningTimeout(pj, r.config().Plank.PodRunningTimeout.Duration)
+			if running := time.Since(pod.Status.StartTime.Time); running < timeout {
+				// Pod is still running. Check on it again once it ran out of time.
+				return &reconcile.Result{RequeueAfter: timeout - running}, nil
+			}
 
-			// Pod is stuck in running state longer than maxPodRunning
-			// abort the job, and talk to GitHub
-			pj.SetComplete()
-			pj.Status.State = prowv1.AbortedState
-			pj.Status.Description = "Pod running timeout."
+			// Pod is running longer than the job may. Stop the pod gracefully, so
+			// that the pod utilities upload the artifacts, and abort the job once
+			// the pod terminated. The timeout is recorded first so that the
+			// deletion of the pod isn't taken as unexpected.
+			now := metav1.NewTime(r.clock.Now())
+			pj.Status.TimeoutTime = &now
+			pj.Status.Description = "Job timed out, waiting for its artifacts to be uploaded."
+			if err := r.pjClient.Patch(ctx, pj.DeepCopy(), ctrlruntimeclient.MergeFrom(prevPJ)); err != nil {
+				return nil, fmt.Errorf("patching prowjob: %w", err)
+			}
+			if err := r.waitForCachedProwJob(ctx, pj, "time out", func(cached *prowv1.ProwJob) bool {
+				return cached.Status.TimeoutTime != nil
+			}); err != nil {
+				return nil, err
+			}
+			r.log.WithFields(pjutil.ProwJobFields(pj)).WithField("timeout", timeout).Info("Job timed out, stopping its pod.")
 			if err := r.deletePod(ctx, pj); err != nil {
 				return nil, fmt.Errorf("failed to delete pod %s/%s in cluster %s: %w", pod.Namespace, pod.Name, pj.ClusterAlias(), err)
 			}
+			return nil, nil
 		default:
 			if pod.DeletionTimestamp == nil {
 				// other states, ignore
@@ -556,10 +577,153 @@ func (r *reconciler) syncPendingJob(ctx context.Context, pj *prowv1.ProwJob) (*r
 	return nil, nil
 }
 
+// runningTimeout returns after how long the pod of the job is stopped: once
+// the timeout and grace period of its decoration config passed, after which
+// the entrypoint should have stopped the test process, or the
+// pod_running_timeout of plank if it is shorter.
+func runningTimeout(pj *prowv1.ProwJob, podRunningTimeout time.Duration) time.Duration {
+	dc := pj.Spec.DecorationConfig
+	if dc == nil || dc.Timeout == nil {
+		return podRunningTimeout
+	}
+	timeout := dc.Timeout.Duration
+	if dc.GracePeriod != nil {
+		timeout += dc.GracePeriod.Duration
+	}
+	if timeout > podRunningTimeout {
+		return podRunningTimeout
+	}
+	return timeout
+}
+
+// timedOutPodRequeue returns after how long to check on the stopped pod of
+// a timed out job again, zero once the pod terminated or had its termination
+// grace period and some slack to do so.
+func (r *reconciler) timedOutPodRequeue(pj *prowv1.ProwJob, pod *corev1.Pod, podExists bool) time.Duration {
+	if !podExists || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return 0
+	}
+	gracePeriod := 30 * time.Second
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		gracePeriod = time.Duration(*pod.Spec.TerminationGracePeriodSeconds) * time.Second
+	}
+	deadline := pj.Status.TimeoutTime.Add(gracePeriod + time.Minute)
+	if remaining := deadline.Sub(r.clock.Now()); remaining > 0 {
+		return remaining
+	}
+	r.log.WithFields(pjutil.ProwJobFields(pj)).Warn("Pod of the timed out job didn't terminate within its grace period.")
+	return 0
+}
+
+// retryJob records the failed pod of the ProwJob as an attempt and deletes
+// it, the next pod is started once the backoff of the retry policy passed.
+// The attempt is recorded before the pod is deleted so that a failed deletion
+// doesn't lead to a retry that isn't accounted for.
+func (r *reconciler) retryJob(ctx context.Context, pj, prevPJ *prowv1.ProwJob, pod *corev1.Pod, condition prowv1.RetryCondition) (*reconcile.Result, error) {
+	client, ok := r.buildClients[pj.ClusterAlias()]
+	if !ok {
+		return nil, fmt.Errorf("failed pod %s: unknown cluster alias %q", pod.Name, pj.ClusterAlias())
+	}
+
+	// The pod may still be in the cache after its attempt was recorded.
+	attempt := lastAttempt(pj)
+	if attempt == nil {
+		now := metav1.NewTime(r.clock.Now())
+		backoff := pj.Spec.RetryPolicy.BackoffFor(len(pj.Status.Attempts) + 1)
+		pj.Status.Attempts = append(pj.Status.Attempts, prowv1.ProwJobAttempt{
+			PodName:        pj.Status.PodName,
+			BuildID:        pj.Status.BuildID,
+			CompletionTime: now,
+			RetryTime:      metav1.NewTime(now.Add(backoff)),
+			Condition:      condition,
+		})
+		attempt = &pj.Status.Attempts[len(pj.Status.Attempts)-1]
+		pj.Status.Description = fmt.Sprintf("Job failed with %s, retrying in %s (attempt %d of %d).", condition, backoff, len(pj.Status.Attempts)+1, pj.Spec.RetryPolicy.MaxAttempts)
+		r.log.WithFields(pjutil.ProwJobFields(pj)).WithField("condition", condition).Info("Pod failed, retrying the job.")
+		if err := r.pjClient.Patch(ctx, pj.DeepCopy(), ctrlruntimeclient.MergeFrom(prevPJ)); err != nil {
+			return nil, fmt.Errorf("patching prowjob: %w", err)
+		}
+		// The attempt must reach the cache before the pod is missing, otherwise
+		// the next pod might be started without waiting for the backoff.
+		attempts := len(pj.Status.Attempts)
+		if err := r.waitForCachedProwJob(ctx, pj, fmt.Sprintf("record attempt %d", attempts), func(cached *prowv1.ProwJob) bool {
+			return len(cached.Status.Attempts) == attempts
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	r.log.WithField("name", pj.ObjectMeta.Name).Debug("Delete Pod.")
+	if err := deletePodWithoutFinalizer(ctx, client, pod); err != nil {
+		return nil, err
+	}
+	return &reconcile.Result{RequeueAfter: attempt.RetryTime.Sub(r.clock.Now())}, nil
+}
+
+// waitForCachedProwJob blocks until the change of the ProwJob is in the cache,
+// otherwise a new reconciliation might react to the stale ProwJob.
+func (r *reconciler) waitForCachedProwJob(ctx context.Context, pj *prowv1.ProwJob, change string, changed func(*prowv1.ProwJob) bool) error {
+	nn := types.NamespacedName{Namespace: pj.Namespace, Name: pj.Name}
+	cached := &prowv1.ProwJob{}
+	if err := wait.Poll(100*time.Millisecond, 2*time.Second, func() (bool, error) {
+		if err := r.pjClient.Get(ctx, nn, cached); err != nil {
+			return false, fmt.Errorf("failed to get prowjob: %w", err)
+		}
+		return changed(cached), nil
+	}); err != nil {
+		return fmt.Errorf("failed to wait for cached prowjob %s to %s: %w", nn.String(), change, err)
+	}
+	return nil
+}
+
+// lastAttempt returns the attempt of the current pod of the ProwJob, if it
+// already failed and is retried.
+func lastAttempt(pj *prowv1.ProwJob) *prowv1.ProwJobAttempt {
+	if n := len(pj.Status.Attempts); n > 0 && pj.Status.Attempts[n-1].BuildID == pj.Status.BuildID {
+		return &pj.Status.Attempts[n-1]
+	}
+	return nil
+}
+
+// deletePodWithoutFinalizer deletes the pod after removing the finalizer of
+// the kubernetes reporter, so that the end user doesn't see the pod.
+func deletePodWithoutFinalizer(ctx context.Context, client ctrlruntimeclient.Client, pod *corev1.Pod) error {
+	if finalizers := sets.NewString(pod.Finalizers...); finalizers.Has(kubernetesreporterapi.FinalizerName) {
+		// We want the end user to not see this, so we have to remove the finalizer, otherwise the pod hangs
+		oldPod := pod.DeepCopy()
+		pod.Finalizers = finalizers.Delete(kubernetesreporterapi.FinalizerName).UnsortedList()
+		if err := client.Patch(ctx, pod, ctrlruntimeclient.MergeFrom(oldPod)); err != nil {
+			return fmt.Errorf("failed to patch pod trying to remove %s finalizer: %w", kubernetesreporterapi.FinalizerName, err)
+		}
+	}
+	return ctrlruntimeclient.IgnoreNotFound(client.Delete(ctx, pod))
+}
+
 // syncTriggeredJob syncs jobs that do not yet have an associated test workload running
 func (r *reconciler) syncTriggeredJob(ctx context.Context, pj *prowv1.ProwJob) (*reconcile.Result, error) {
+	// The prerequisites are recorded with the transition to pending below.
 	prevPJ := pj.DeepCopy()
 
+	if needsPrerequisites(pj) {
+		if result, err := r.syncPrerequisites(ctx, pj); result != nil || err != nil || pj.Complete() {
+			return result, err
+		}
+	}
+
+	if needsScheduling(pj) {
+		cluster, err := r.scheduleCluster(ctx, pj)
+		if err != nil {
+			return nil, fmt.Errorf("scheduleCluster: %w", err)
+		}
+		if cluster == "" {
+			r.log.WithFields(pjutil.ProwJobFields(pj)).Debug("No build cluster has capacity left for the job.")
+			return &reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+		}
+		if err := r.setCluster(ctx, pj, cluster); err != nil {
+			return nil, err
+		}
+	}
+
 	var id, pn string
 
 	pod, podExists, err := r.pod(ctx, pj)
@@ -717,6 +881,15 @@ func (r *reconciler) startPod(ctx context.Context, pj *prowv1.ProwJob) (string,
 		return "", "", err
 	}
 	pod.Namespace = r.config().PodNamespace
+	if pj.Spec.ClusterSelector != nil && len(pj.Spec.ClusterSelector.NodeLabels) > 0 {
+		// Run on the nodes the cluster was picked for.
+		if pod.Spec.NodeSelector == nil {
+			pod.Spec.NodeSelector = map[string]string{}
+		}
+		for key, value := range pj.Spec.ClusterSelector.NodeLabels {
+			pod.Spec.NodeSelector[key] = value
+		}
+	}
 	// Add prow version as a label for better debugging prowjobs.
 	pod.ObjectMeta.Labels[kube.PlankVersionLabel] = version.Version
 	podName := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
@@ -868,6 +1041,12 @@ func pendingTriggeredIndexKeyByName(jobName string) string {
 	return fmt.Sprintf("pending-triggered-named-%s", jobName)
 }
 
+// runAfterIndexKeyByJob is the indexKey for the ProwJobs of a job that
+// tested the refs, which the jobs running after it look up.
+func runAfterIndexKeyByJob(jobType prowv1.ProwJobType, jobName string, refs *prowv1.Refs) string {
+	return fmt.Sprintf("run-after-%s-%s-%s/%s@%s", jobType, jobName, refs.Org, refs.Repo, refs.String())
+}
+
 func prowJobIndexer(prowJobNamespace string) ctrlruntimeclient.IndexerFunc {
 	return func(o ctrlruntimeclient.Object) []string {
 		pj := o.(*prowv1.ProwJob)
@@ -875,22 +1054,27 @@ func prowJobIndexer(prowJobNamespace string) ctrlruntimeclient.IndexerFunc {
 			return nil
 		}
 
-		if pj.Status.State == prowv1.PendingState {
-			return []string{
+		var keys []string
+		switch pj.Status.State {
+		case prowv1.PendingState:
+			keys = []string{
 				prowJobIndexKeyAll,
 				prowJobIndexKeyPending,
 				pendingTriggeredIndexKeyByName(pj.Spec.Job),
 			}
-		}
-
-		if pj.Status.State == prowv1.TriggeredState {
-			return []string{
+		case prowv1.TriggeredState:
+			keys = []string{
 				prowJobIndexKeyAll,
 				pendingTriggeredIndexKeyByName(pj.Spec.Job),
 			}
+		default:
+			keys = []string{prowJobIndexKeyAll}
 		}
 
-		return []string{prowJobIndexKeyAll}
+		if pj.Spec.Refs != nil && (pj.Spec.Type == prowv1.PresubmitJob || pj.Spec.Type == prowv1.PostsubmitJob) {
+			keys = append(keys, runAfterIndexKeyByJob(pj.Spec.Type, pj.Spec.Job, pj.Spec.Refs))
+		}
+		return keys
 	}
 }
 
@@ -906,6 +1090,10 @@ func optPendingTriggeredJobsNamed(name string) ctrlruntimeclient.ListOption {
 	return ctrlruntimeclient.MatchingFields{prowJobIndexName: pendingTriggeredIndexKeyByName(name)}
 }
 
+func optRunAfterJobsNamed(jobType prowv1.ProwJobType, name string, refs *prowv1.Refs) ctrlruntimeclient.ListOption {
+	return ctrlruntimeclient.MatchingFields{prowJobIndexName: runAfterIndexKeyByJob(jobType, name, refs)}
+}
+
 func didPodSucceed(p *corev1.Pod) bool {
 	if p.Status.Phase != corev1.PodSucceeded {
 		return false
@@ -919,6 +1107,26 @@ func didPodSucceed(p *corev1.Pod) bool {
 	return true
 }
 
+// podFailureCondition tells whether the failed pod ran the test of the job,
+// which failed, or didn't get to: it has no container statuses, an init
+// container failed or a container got OOMKilled.
+func podFailureCondition(p *corev1.Pod) prowv1.RetryCondition {
+	if len(p.Status.ContainerStatuses) == 0 {
+		return prowv1.InfraErrorCondition
+	}
+	for _, container := range p.Status.InitContainerStatuses {
+		if container.State.Terminated != nil && container.State.Terminated.ExitCode != 0 {
+			return prowv1.InfraErrorCondition
+		}
+	}
+	for _, container := range p.Status.ContainerStatuses {
+		if container.State.Terminated != nil && container.State.Terminated.Reason == "OOMKilled" {
+			return prowv1.InfraErrorCondition
+		}
+	}
+	return prowv1.TestFailureCondition
+}
+
 func getPodBuildID(pod *corev1.Pod) string {
 	if buildID, ok := pod.ObjectMeta.Labels[kube.ProwBuildIDLabel]; ok && buildID != "" {
 		return buildID
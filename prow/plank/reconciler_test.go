@@ -332,6 +332,24 @@ func TestProwJobIndexer(t *testing.T) {
 			modify:   func(pj *prowv1.ProwJob) { pj.Spec.Job = "some-name" },
 			expected: []string{prowJobIndexKeyAll, prowJobIndexKeyPending, pendingTriggeredIndexKeyByName("some-name")},
 		},
+		{
+			name: "Postsubmit goes into runAfter index",
+			modify: func(pj *prowv1.ProwJob) {
+				pj.Status.State = prowv1.SuccessState
+				pj.Spec.Type = prowv1.PostsubmitJob
+				pj.Spec.Refs = &prowv1.Refs{Org: "org", Repo: "repo", BaseRef: "main", BaseSHA: "abc"}
+			},
+			expected: []string{prowJobIndexKeyAll, "run-after-postsubmit-my-pj-org/repo@main:abc"},
+		},
+		{
+			name: "Periodic does not go into runAfter index",
+			modify: func(pj *prowv1.ProwJob) {
+				pj.Status.State = prowv1.SuccessState
+				pj.Spec.Type = prowv1.PeriodicJob
+				pj.Spec.Refs = &prowv1.Refs{Org: "org", Repo: "repo", BaseRef: "main", BaseSHA: "abc"}
+			},
+			expected: []string{prowJobIndexKeyAll},
+		},
 	}
 
 	for _, tc := range testCases {
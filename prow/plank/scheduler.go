@@ -0,0 +1,198 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plank
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pjutil"
+)
+
+// needsScheduling returns true if the build cluster of the ProwJob is picked
+// by plank when it starts the job.
+func needsScheduling(pj *prowv1.ProwJob) bool {
+	return pj.Spec.ClusterSelector != nil && pj.Spec.Cluster == ""
+}
+
+// schedulingResources are the resources whose requests are compared with the
+// capacity of the nodes to pick the build cluster of a ProwJob.
+var schedulingResources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, "nvidia.com/gpu"}
+
+// scheduleCluster picks the build cluster of a ProwJob with a cluster
+// selector: of its candidate clusters, the one that can run the most pods like
+// the pod of the job. Only the ready nodes with the labels of the selector that
+// can fit the pod on their own count, and the ProwJobs pending in a cluster are
+// subtracted from the resources they can allocate and from the number of pods
+// they can run. It returns an empty cluster if no cluster can run the pod.
+func (r *reconciler) scheduleCluster(ctx context.Context, pj *prowv1.ProwJob) (string, error) {
+	selector := pj.Spec.ClusterSelector
+	candidates := selector.Clusters
+	if len(candidates) == 0 {
+		for cluster := range r.buildClients {
+			candidates = append(candidates, cluster)
+		}
+	}
+	sort.Strings(candidates)
+
+	pjs := &prowv1.ProwJobList{}
+	if err := r.pjClient.List(ctx, pjs, optPendingProwJobs()); err != nil {
+		return "", fmt.Errorf("failed to list pending prowjobs: %w", err)
+	}
+	pending := map[string][]corev1.ResourceList{}
+	for _, job := range pjs.Items {
+		pending[job.ClusterAlias()] = append(pending[job.ClusterAlias()], podRequests(job.Spec.PodSpec))
+	}
+
+	requests := podRequests(pj.Spec.PodSpec)
+	var picked string
+	var mostLeft int64
+	for _, cluster := range candidates {
+		log := r.log.WithFields(pjutil.ProwJobFields(pj)).WithField("cluster", cluster)
+		client, ok := r.buildClients[cluster]
+		if !ok {
+			log.Warn("Unknown cluster in the cluster selector, skipping it.")
+			continue
+		}
+		nodes := &corev1.NodeList{}
+		if err := client.List(ctx, nodes, ctrlruntimeclient.MatchingLabels(selector.NodeLabels)); err != nil {
+			log.WithError(err).Warn("Failed to list the nodes of the cluster, skipping it.")
+			continue
+		}
+		if left := podsLeft(nodes.Items, pending[cluster], requests); left > mostLeft {
+			picked, mostLeft = cluster, left
+		}
+	}
+	return picked, nil
+}
+
+// podRequests returns the scheduling resources requested by the containers
+// of the pod.
+func podRequests(spec *corev1.PodSpec) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	if spec == nil {
+		return requests
+	}
+	for _, container := range spec.Containers {
+		for _, name := range schedulingResources {
+			if quantity, ok := container.Resources.Requests[name]; ok {
+				total := requests[name]
+				total.Add(quantity)
+				requests[name] = total
+			}
+		}
+	}
+	return requests
+}
+
+// podsLeft returns the number of pods with the requests that the ready nodes
+// which can fit such a pod can still run, once the pending pods are running.
+func podsLeft(nodes []corev1.Node, pending []corev1.ResourceList, requests corev1.ResourceList) int64 {
+	allocatable := corev1.ResourceList{}
+	var pods int64
+	for _, node := range nodes {
+		if node.Spec.Unschedulable || !isNodeReady(node) || !fits(node.Status.Allocatable, requests) {
+			continue
+		}
+		pods += node.Status.Allocatable.Pods().Value()
+		for _, name := range schedulingResources {
+			total := allocatable[name]
+			total.Add(node.Status.Allocatable[name])
+			allocatable[name] = total
+		}
+	}
+	if pods == 0 {
+		return 0
+	}
+	pods -= int64(len(pending))
+	for _, podRequests := range pending {
+		for name, quantity := range podRequests {
+			total := allocatable[name]
+			total.Sub(quantity)
+			allocatable[name] = total
+		}
+	}
+
+	left := pods
+	for name, quantity := range requests {
+		if quantity.IsZero() {
+			continue
+		}
+		free := allocatable[name]
+		if n := free.MilliValue() / quantity.MilliValue(); n < left {
+			left = n
+		}
+	}
+	return left
+}
+
+// fits returns whether a node with the allocatable resources can fit a pod
+// with the requests.
+func fits(allocatable, requests corev1.ResourceList) bool {
+	for name, quantity := range requests {
+		if capacity, ok := allocatable[name]; !ok || capacity.Cmp(quantity) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func isNodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// setCluster records the picked build cluster in the ProwJob, along with the
+// decoration config of the job with the defaults of the cluster merged in, and
+// blocks until it is in the cache, otherwise a new reconciliation might pick
+// another cluster and start a second pod there.
+func (r *reconciler) setCluster(ctx context.Context, pj *prowv1.ProwJob, cluster string) error {
+	prevPJ := pj.DeepCopy()
+	pj.Spec.Cluster = cluster
+	if pj.Spec.DecorationConfig != nil {
+		pj.Spec.DecorationConfig = r.config().Plank.MergeDefaultDecorationConfig(defaultsRepo(pj), cluster, pj.Spec.DecorationConfig)
+	}
+	r.log.WithFields(pjutil.ProwJobFields(pj)).WithField("cluster", cluster).Info("Scheduled the job to a build cluster.")
+	if err := r.pjClient.Patch(ctx, pj.DeepCopy(), ctrlruntimeclient.MergeFrom(prevPJ)); err != nil {
+		return fmt.Errorf("patch prowjob: %w", err)
+	}
+	return r.waitForCachedProwJob(ctx, pj, "get scheduled to cluster "+cluster, func(cached *prowv1.ProwJob) bool {
+		return cached.Spec.Cluster == cluster
+	})
+}
+
+// defaultsRepo returns the repo whose defaults apply to the ProwJob, like
+// when the config of its job is loaded: the repo of its refs, or of its first
+// extra refs for periodics.
+func defaultsRepo(pj *prowv1.ProwJob) string {
+	if pj.Spec.Refs != nil {
+		return pj.Spec.Refs.Org + "/" + pj.Spec.Refs.Repo
+	}
+	if len(pj.Spec.ExtraRefs) > 0 {
+		return pj.Spec.ExtraRefs[0].Org + "/" + pj.Spec.ExtraRefs[0].Repo
+	}
+	return ""
+}
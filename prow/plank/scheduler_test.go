@@ -0,0 +1,291 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plank
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	utilpointer "k8s.io/utils/pointer"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+func node(name string, allocatable map[v1.ResourceName]string, ready bool, labels map[string]string) *v1.Node {
+	status := v1.ConditionTrue
+	if !ready {
+		status = v1.ConditionFalse
+	}
+	resources := v1.ResourceList{}
+	for name, quantity := range allocatable {
+		resources[name] = resource.MustParse(quantity)
+	}
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status: v1.NodeStatus{
+			Allocatable: resources,
+			Conditions:  []v1.NodeCondition{{Type: v1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func pendingProwJobs(cluster string, n int, requests v1.ResourceList) []runtime.Object {
+	var pjs []runtime.Object
+	for i := 0; i < n; i++ {
+		pjs = append(pjs, &prowapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", cluster, i), Namespace: "prowjobs"},
+			Spec: prowapi.ProwJobSpec{
+				Agent:   prowapi.KubernetesAgent,
+				Cluster: cluster,
+				PodSpec: &v1.PodSpec{Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: requests}}}},
+			},
+			Status: prowapi.ProwJobStatus{State: prowapi.PendingState},
+		})
+	}
+	return pjs
+}
+
+func schedulingBuildClients() map[string]ctrlruntimeclient.Client {
+	return map[string]ctrlruntimeclient.Client{
+		// 20 pods, the unready node doesn't count.
+		"a": fakectrlruntimeclient.NewFakeClient(
+			node("a-1", map[v1.ResourceName]string{v1.ResourcePods: "10", v1.ResourceCPU: "4"}, true, map[string]string{"arch": "amd64"}),
+			node("a-2", map[v1.ResourceName]string{v1.ResourcePods: "10", v1.ResourceCPU: "4"}, true, map[string]string{"arch": "amd64"}),
+			node("a-3", map[v1.ResourceName]string{v1.ResourcePods: "100", v1.ResourceCPU: "64"}, false, map[string]string{"arch": "amd64"}),
+		),
+		"b": fakectrlruntimeclient.NewFakeClient(
+			node("b-1", map[v1.ResourceName]string{v1.ResourcePods: "10", v1.ResourceCPU: "16"}, true, map[string]string{"arch": "amd64"}),
+		),
+		"gpu": fakectrlruntimeclient.NewFakeClient(
+			node("gpu-1", map[v1.ResourceName]string{v1.ResourcePods: "4", v1.ResourceCPU: "16", "nvidia.com/gpu": "4"}, true, map[string]string{"arch": "amd64", "gpu": "nvidia-tesla-t4"}),
+			node("gpu-2", map[v1.ResourceName]string{v1.ResourcePods: "4", v1.ResourceCPU: "16"}, true, map[string]string{"arch": "amd64"}),
+		),
+	}
+}
+
+func TestScheduleCluster(t *testing.T) {
+	testCases := []struct {
+		name            string
+		selector        *prowapi.ClusterSelector
+		requests        v1.ResourceList
+		pending         map[string]int
+		pendingRequests v1.ResourceList
+		expected        string
+	}{
+		{
+			name:     "cluster with the most capacity",
+			selector: &prowapi.ClusterSelector{},
+			expected: "a",
+		},
+		{
+			name:     "cluster with the most capacity left",
+			selector: &prowapi.ClusterSelector{},
+			pending:  map[string]int{"a": 15},
+			expected: "b",
+		},
+		{
+			name:     "cluster with nodes with the labels",
+			selector: &prowapi.ClusterSelector{NodeLabels: map[string]string{"gpu": "nvidia-tesla-t4"}},
+			expected: "gpu",
+		},
+		{
+			name:     "candidate cluster",
+			selector: &prowapi.ClusterSelector{Clusters: []string{"b", "gpu"}},
+			expected: "b",
+		},
+		{
+			name:     "unknown candidate clusters are skipped",
+			selector: &prowapi.ClusterSelector{Clusters: []string{"unknown", "gpu"}},
+			expected: "gpu",
+		},
+		{
+			name:     "no cluster with nodes with the labels",
+			selector: &prowapi.ClusterSelector{NodeLabels: map[string]string{"arch": "arm64"}},
+		},
+		{
+			name:     "no cluster with capacity left",
+			selector: &prowapi.ClusterSelector{NodeLabels: map[string]string{"gpu": "nvidia-tesla-t4"}},
+			pending:  map[string]int{"gpu": 4},
+		},
+		{
+			name:     "nodes that can't fit the pod don't count",
+			selector: &prowapi.ClusterSelector{Clusters: []string{"a", "b"}},
+			requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("8")},
+			expected: "b",
+		},
+		{
+			name:     "cluster with nodes with the requested GPUs",
+			selector: &prowapi.ClusterSelector{},
+			requests: v1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")},
+			expected: "gpu",
+		},
+		{
+			name:            "requests of pending jobs are subtracted",
+			selector:        &prowapi.ClusterSelector{},
+			requests:        v1.ResourceList{"nvidia.com/gpu": resource.MustParse("2")},
+			pending:         map[string]int{"gpu": 1},
+			pendingRequests: v1.ResourceList{"nvidia.com/gpu": resource.MustParse("3")},
+		},
+		{
+			name:            "cluster with the most resources left",
+			selector:        &prowapi.ClusterSelector{Clusters: []string{"b", "gpu"}},
+			requests:        v1.ResourceList{v1.ResourceCPU: resource.MustParse("4")},
+			pending:         map[string]int{"gpu": 2},
+			pendingRequests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("12")},
+			expected:        "b",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var pjs []runtime.Object
+			for cluster, n := range tc.pending {
+				pjs = append(pjs, pendingProwJobs(cluster, n, tc.pendingRequests)...)
+			}
+			r := &reconciler{
+				pjClient: &indexingClient{
+					Client:     fakectrlruntimeclient.NewFakeClient(pjs...),
+					indexFuncs: map[string]ctrlruntimeclient.IndexerFunc{prowJobIndexName: prowJobIndexer("prowjobs")},
+				},
+				buildClients: schedulingBuildClients(),
+				log:          logrus.NewEntry(logrus.StandardLogger()),
+			}
+			pj := &prowapi.ProwJob{Spec: prowapi.ProwJobSpec{
+				ClusterSelector: tc.selector,
+				PodSpec:         &v1.PodSpec{Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: tc.requests}}}},
+			}}
+			actual, err := r.scheduleCluster(context.Background(), pj)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected cluster %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSyncTriggeredJobSchedulesCluster(t *testing.T) {
+	totServ := httptest.NewServer(http.HandlerFunc(handleTot))
+	defer totServ.Close()
+	pj := &prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "blabla", Namespace: "prowjobs"},
+		Spec: prowapi.ProwJobSpec{
+			Job:             "boop",
+			Type:            prowapi.PeriodicJob,
+			Agent:           prowapi.KubernetesAgent,
+			ClusterSelector: &prowapi.ClusterSelector{NodeLabels: map[string]string{"gpu": "nvidia-tesla-t4"}},
+			PodSpec:         &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+		},
+		Status: prowapi.ProwJobStatus{State: prowapi.TriggeredState},
+	}
+	pjClient := &indexingClient{
+		Client:     fakectrlruntimeclient.NewFakeClient(pj.DeepCopy()),
+		indexFuncs: map[string]ctrlruntimeclient.IndexerFunc{prowJobIndexName: prowJobIndexer("prowjobs")},
+	}
+	buildClients := schedulingBuildClients()
+	r := &reconciler{
+		pjClient:     pjClient,
+		buildClients: buildClients,
+		log:          logrus.NewEntry(logrus.StandardLogger()),
+		config:       newFakeConfigAgent(t, 0).Config,
+		totURL:       totServ.URL,
+		clock:        clock.RealClock{},
+	}
+	if _, err := r.syncTriggeredJob(context.Background(), pj); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual := &prowapi.ProwJob{}
+	if err := pjClient.Get(context.Background(), types.NamespacedName{Namespace: "prowjobs", Name: "blabla"}, actual); err != nil {
+		t.Fatalf("failed to get prowjob: %v", err)
+	}
+	if actual.Spec.Cluster != "gpu" || actual.Status.State != prowapi.PendingState {
+		t.Errorf("expected the prowjob to be pending in cluster gpu, got %q in cluster %q", actual.Status.State, actual.Spec.Cluster)
+	}
+	pods := &v1.PodList{}
+	if err := buildClients["gpu"].List(context.Background(), pods); err != nil {
+		t.Fatalf("failed to list pods: %v", err)
+	}
+	if len(pods.Items) != 1 {
+		t.Fatalf("expected a pod in cluster gpu, got %d", len(pods.Items))
+	}
+	if diff := cmp.Diff(map[string]string{"gpu": "nvidia-tesla-t4"}, pods.Items[0].Spec.NodeSelector); diff != "" {
+		t.Errorf("pod node selector differs from expected: %s", diff)
+	}
+}
+
+func TestSetClusterMergesDecorationDefaults(t *testing.T) {
+	pj := &prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "blabla", Namespace: "prowjobs"},
+		Spec: prowapi.ProwJobSpec{
+			Job:              "boop",
+			Type:             prowapi.PresubmitJob,
+			Agent:            prowapi.KubernetesAgent,
+			Refs:             &prowapi.Refs{Org: "org", Repo: "repo"},
+			ClusterSelector:  &prowapi.ClusterSelector{Clusters: []string{"default", "gpu"}},
+			DecorationConfig: &prowapi.DecorationConfig{GCSCredentialsSecret: utilpointer.StringPtr("job-secret")},
+		},
+		Status: prowapi.ProwJobStatus{State: prowapi.TriggeredState},
+	}
+	pjClient := fakectrlruntimeclient.NewFakeClient(pj.DeepCopy())
+	cfg := &config.Config{ProwConfig: config.ProwConfig{Plank: config.Plank{
+		DefaultDecorationConfigs: []*config.DefaultDecorationConfigEntry{
+			{Config: &prowapi.DecorationConfig{
+				GCSConfiguration:     &prowapi.GCSConfiguration{Bucket: "default-bucket"},
+				GCSCredentialsSecret: utilpointer.StringPtr("default-secret"),
+			}},
+			{OrgRepo: "org/repo", Cluster: "gpu", Config: &prowapi.DecorationConfig{
+				GCSConfiguration: &prowapi.GCSConfiguration{Bucket: "gpu-bucket"},
+			}},
+		},
+	}}}
+	r := &reconciler{
+		pjClient: pjClient,
+		log:      logrus.NewEntry(logrus.StandardLogger()),
+		config:   func() *config.Config { return cfg },
+	}
+	if err := r.setCluster(context.Background(), pj, "gpu"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual := &prowapi.ProwJob{}
+	if err := pjClient.Get(context.Background(), types.NamespacedName{Namespace: "prowjobs", Name: "blabla"}, actual); err != nil {
+		t.Fatalf("failed to get prowjob: %v", err)
+	}
+	expected := &prowapi.DecorationConfig{
+		GCSConfiguration:     &prowapi.GCSConfiguration{Bucket: "gpu-bucket"},
+		GCSCredentialsSecret: utilpointer.StringPtr("job-secret"),
+	}
+	if diff := cmp.Diff(expected, actual.Spec.DecorationConfig); diff != "" {
+		t.Errorf("decoration config differs from expected: %s", diff)
+	}
+}
@@ -90,6 +90,9 @@ func (ha *HelpAgent) generateNormalPluginHelp(config *plugins.Configuration, rev
 			continue
 		}
 		help.Events = plugins.EventsForPlugin(name)
+		for _, command := range plugins.CommandsForPlugin(name) {
+			help.AddCommand(command.Help())
+		}
 		pluginHelp[name] = *help
 	}
 	return
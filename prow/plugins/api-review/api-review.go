@@ -0,0 +1,200 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package apireview implements the `api-review` plugin. It escalates PRs
+// changing API files to the API review team and blocks them from merging
+// until a member of the team approves.
+package apireview
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/gitattributes"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/pluginhelp"
+	"k8s.io/test-infra/prow/plugins"
+	requiredreviewers "k8s.io/test-infra/prow/plugins/required-reviewers"
+)
+
+// PluginName defines this plugin's registered name.
+const PluginName = "api-review"
+
+var handlePRActions = map[github.PullRequestEventAction]bool{
+	github.PullRequestActionOpened:         true,
+	github.PullRequestActionReopened:       true,
+	github.PullRequestActionSynchronize:    true,
+	github.PullRequestActionReadyForReview: true,
+}
+
+type githubClient interface {
+	AddLabel(org, repo string, number int, label string) error
+	RemoveLabel(org, repo string, number int, label string) error
+	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
+	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
+	ListReviews(org, repo string, number int) ([]github.Review, error)
+	RequestReview(org, repo string, number int, logins []string) error
+	TeamBySlugHasMember(org string, teamSlug string, memberLogin string) (bool, error)
+}
+
+func init() {
+	plugins.RegisterPullRequestHandler(PluginName, handlePullRequestEvent, helpProvider)
+	plugins.RegisterReviewEventHandler(PluginName, handleReviewEvent, helpProvider)
+}
+
+func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	configInfo := map[string]string{}
+	for _, repo := range enabledRepos {
+		opts := config.APIReviewFor(repo.Org, repo.Repo)
+		if opts == nil {
+			continue
+		}
+		configInfo[repo.String()] = fmt.Sprintf("Changes to %s need an approval from a member of team %s.", strings.Join(opts.Paths, ", "), opts.Team)
+	}
+	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
+		APIReview: []plugins.APIReview{
+			{
+				Repos: []string{"org/repo"},
+				Paths: []string{"api/**", "*.proto", "openapi/**"},
+				Team:  "api-reviewers",
+			},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", PluginName)
+	}
+	// Only the 'Description' and 'Config' fields are necessary because this plugin does not react
+	// to any commands.
+	return &pluginhelp.PluginHelp{
+		Description: fmt.Sprintf("The api-review plugin applies the '%s' label to PRs that change API files and requests a review from the API review team. Until a member of the team approves the PR with a GitHub review, the '%s' label blocks it from merging.", labels.APIReview, labels.NeedsAPIReview),
+		Config:      configInfo,
+		Snippet:     yamlSnippet,
+	}, nil
+}
+
+func handlePullRequestEvent(pc plugins.Agent, pre github.PullRequestEvent) error {
+	if !handlePRActions[pre.Action] {
+		return nil
+	}
+	cfg := pc.PluginConfig.APIReviewFor(pre.Repo.Owner.Login, pre.Repo.Name)
+	if cfg == nil {
+		return nil
+	}
+	return handle(pc.Logger, pc.GitHubClient, cfg, pre.Repo.Owner.Login, pre.Repo.Name, pre.Number)
+}
+
+func handleReviewEvent(pc plugins.Agent, re github.ReviewEvent) error {
+	if re.Action != github.ReviewActionSubmitted && re.Action != github.ReviewActionDismissed {
+		return nil
+	}
+	if re.PullRequest.State != "open" {
+		return nil
+	}
+	cfg := pc.PluginConfig.APIReviewFor(re.Repo.Owner.Login, re.Repo.Name)
+	if cfg == nil {
+		return nil
+	}
+	return handle(pc.Logger, pc.GitHubClient, cfg, re.Repo.Owner.Login, re.Repo.Name, re.PullRequest.Number)
+}
+
+func handle(log *logrus.Entry, ghc githubClient, cfg *plugins.APIReview, org, repo string, number int) error {
+	changes, err := ghc.GetPullRequestChanges(org, repo, number)
+	if err != nil {
+		return fmt.Errorf("failed to get PR changes: %w", err)
+	}
+	changesAPI := matchesAPIPaths(log, cfg.Paths, changes)
+
+	approved := false
+	if changesAPI {
+		reviews, err := ghc.ListReviews(org, repo, number)
+		if err != nil {
+			return fmt.Errorf("failed to list reviews: %w", err)
+		}
+		for _, approver := range requiredreviewers.CurrentApprovers(reviews).List() {
+			member, err := ghc.TeamBySlugHasMember(org, cfg.Team, approver)
+			if err != nil {
+				return fmt.Errorf("failed to check if %s is a member of team %s: %w", approver, cfg.Team, err)
+			}
+			if member {
+				approved = true
+				break
+			}
+		}
+	}
+
+	issueLabels, err := ghc.GetIssueLabels(org, repo, number)
+	if err != nil {
+		return fmt.Errorf("failed to get labels: %w", err)
+	}
+	hasAPIReview := github.HasLabel(labels.APIReview, issueLabels)
+	hasNeedsAPIReview := github.HasLabel(labels.NeedsAPIReview, issueLabels)
+
+	if !changesAPI {
+		if hasAPIReview {
+			if err := ghc.RemoveLabel(org, repo, number, labels.APIReview); err != nil {
+				return err
+			}
+		}
+		if hasNeedsAPIReview {
+			return ghc.RemoveLabel(org, repo, number, labels.NeedsAPIReview)
+		}
+		return nil
+	}
+
+	if !hasAPIReview {
+		// The team is requested only when the PR starts changing the API, so
+		// that removing the request is not undone by the next push.
+		log.Infof("Requesting an API review from team %s.", cfg.Team)
+		if err := ghc.AddLabel(org, repo, number, labels.APIReview); err != nil {
+			return err
+		}
+		if err := ghc.RequestReview(org, repo, number, []string{fmt.Sprintf("%s/%s", org, cfg.Team)}); err != nil {
+			log.WithError(err).Warnf("Failed to request a review from team %s.", cfg.Team)
+		}
+	}
+	if !approved && !hasNeedsAPIReview {
+		return ghc.AddLabel(org, repo, number, labels.NeedsAPIReview)
+	}
+	if approved && hasNeedsAPIReview {
+		return ghc.RemoveLabel(org, repo, number, labels.NeedsAPIReview)
+	}
+	return nil
+}
+
+// matchesAPIPaths returns whether one of the changed files is an API file.
+func matchesAPIPaths(log *logrus.Entry, paths []string, changes []github.PullRequestChange) bool {
+	var patterns []gitattributes.Pattern
+	for _, path := range paths {
+		pattern, err := gitattributes.ParsePattern(path)
+		if err != nil {
+			log.WithError(err).Warnf("Ignoring invalid path %q.", path)
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	for _, change := range changes {
+		for _, pattern := range patterns {
+			if pattern.Match(change.Filename) {
+				return true
+			}
+		}
+	}
+	return false
+}
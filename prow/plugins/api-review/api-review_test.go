@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apireview
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestHandle(t *testing.T) {
+	cfg := &plugins.APIReview{
+		Repos: []string{"org/repo"},
+		Paths: []string{"api/**", "*.proto"},
+		Team:  "api-reviewers",
+	}
+	now := time.Now()
+	review := func(user string, state github.ReviewState, ago time.Duration) github.Review {
+		return github.Review{User: github.User{Login: user}, State: state, SubmittedAt: now.Add(-ago)}
+	}
+	apiLabel := "org/repo#1:" + labels.APIReview
+	needsLabel := "org/repo#1:" + labels.NeedsAPIReview
+
+	testCases := []struct {
+		name              string
+		files             []string
+		reviews           []github.Review
+		existing          []string
+		expectedAdded     []string
+		expectedRemoved   []string
+		expectedRequested []string
+	}{
+		{
+			name:  "no API change",
+			files: []string{"docs/README.md"},
+		},
+		{
+			name:            "no API change anymore removes the labels",
+			files:           []string{"docs/README.md"},
+			existing:        []string{apiLabel, needsLabel},
+			expectedRemoved: []string{apiLabel, needsLabel},
+		},
+		{
+			name:              "API change requests a review from the team",
+			files:             []string{"api/v1/types.go"},
+			expectedAdded:     []string{apiLabel, needsLabel},
+			expectedRequested: []string{"org/api-reviewers"},
+		},
+		{
+			name:     "team is only requested once",
+			files:    []string{"types.proto"},
+			existing: []string{apiLabel, needsLabel},
+		},
+		{
+			name:          "approval by someone outside the team does not count",
+			files:         []string{"types.proto"},
+			reviews:       []github.Review{review("bob", github.ReviewStateApproved, time.Hour)},
+			existing:      []string{apiLabel},
+			expectedAdded: []string{needsLabel},
+		},
+		{
+			name:            "approval by a team member unblocks the PR",
+			files:           []string{"types.proto"},
+			reviews:         []github.Review{review("Carl", github.ReviewStateApproved, time.Hour)},
+			existing:        []string{apiLabel, needsLabel},
+			expectedRemoved: []string{needsLabel},
+		},
+		{
+			name:  "approval superseded by a request for changes blocks the PR",
+			files: []string{"types.proto"},
+			reviews: []github.Review{
+				review("carl", github.ReviewStateApproved, time.Hour),
+				review("carl", github.ReviewStateChangesRequested, time.Minute),
+			},
+			existing:      []string{apiLabel},
+			expectedAdded: []string{needsLabel},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			var changes []github.PullRequestChange
+			for _, file := range tc.files {
+				changes = append(changes, github.PullRequestChange{Filename: file})
+			}
+			fc.PullRequestChanges = map[int][]github.PullRequestChange{1: changes}
+			fc.Reviews = map[int][]github.Review{1: tc.reviews}
+			fc.Teams = map[string]map[string]fakegithub.TeamWithMembers{
+				"org": {"api-reviewers": {Members: sets.NewString("carl")}},
+			}
+			fc.IssueLabelsExisting = tc.existing
+
+			if err := handle(logrus.WithField("plugin", PluginName), fc, cfg, "org", "repo", 1); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expectedAdded, fc.IssueLabelsAdded); diff != "" {
+				t.Errorf("added labels differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedRemoved, fc.IssueLabelsRemoved); diff != "" {
+				t.Errorf("removed labels differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedRequested, fc.ReviewersRequested); diff != "" {
+				t.Errorf("requested reviewers differ from expected: %s", diff)
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package approve
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -28,6 +29,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/gitattributes"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/labels"
 	"k8s.io/test-infra/prow/pluginhelp"
@@ -42,9 +44,15 @@ const (
 
 	approveCommand       = "APPROVE"
 	cancelArgument       = "cancel"
+	filesArgument        = "files"
 	lgtmCommand          = "LGTM"
 	noIssueArgument      = "no-issue"
 	removeApproveCommand = "REMOVE-APPROVE"
+
+	checkRunName                     = "approve"
+	checkRunStatusCompleted          = "completed"
+	checkRunConclusionSuccess        = "success"
+	checkRunConclusionActionRequired = "action_required"
 )
 
 var (
@@ -69,6 +77,9 @@ type githubClient interface {
 	AddLabel(org, repo string, number int, label string) error
 	RemoveLabel(org, repo string, number int, label string) error
 	WasLabelAddedByHuman(org, repo string, num int, label string) (bool, error)
+	ListCheckRuns(org, repo, ref string) (*github.CheckRunList, error)
+	CreateCheckRun(org, repo string, checkRun github.CheckRun) error
+	UpdateCheckRun(org, repo string, checkRunID int64, checkRun github.CheckRun) error
 }
 
 type ownersClient interface {
@@ -76,10 +87,11 @@ type ownersClient interface {
 }
 
 type state struct {
-	org    string
-	repo   string
-	branch string
-	number int
+	org     string
+	repo    string
+	branch  string
+	number  int
+	headSHA string
 
 	body      string
 	author    string
@@ -111,6 +123,14 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 	for _, repo := range enabledRepos {
 		opts := config.ApproveFor(repo.Org, repo.Repo)
 		approveConfig[repo.String()] = fmt.Sprintf("Pull requests %s require an associated issue.<br>Pull request authors %s implicitly approve their own PRs.<br>The /lgtm [cancel] command(s) %s act as approval.<br>A GitHub approved or changes requested review %s act as approval or cancel respectively.", doNot(opts.IssueRequired), doNot(opts.HasSelfApproval()), willNot(opts.LgtmActsAsApprove), willNot(opts.ConsiderReviewState()))
+		for depth, required := range opts.ApprovalsRequiredByDepth {
+			if required > 1 {
+				approveConfig[repo.String()] += fmt.Sprintf("<br>Files owned by OWNERS files at depth %d need %d approvers.", depth, required)
+			}
+		}
+		if opts.ReportCheckRun {
+			approveConfig[repo.String()] += fmt.Sprintf("<br>The approval status is reported in the %q check run.", checkRunName)
+		}
 	}
 
 	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
@@ -138,11 +158,11 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 		Snippet: yamlSnippet,
 	}
 	pluginHelp.AddCommand(pluginhelp.Command{
-		Usage:       "/[remove-]approve [no-issue|cancel]",
-		Description: "Approves a pull request",
+		Usage:       "/[remove-]approve [no-issue|cancel|files <glob>...]",
+		Description: "Approves a pull request. With 'files', only approves the changed files matching one of the gitattributes-style globs, e.g. 'docs/**', and nothing if they are missing or invalid.",
 		Featured:    true,
 		WhoCanUse:   "Users listed as 'approvers' in appropriate OWNERS files.",
-		Examples:    []string{"/approve", "/approve no-issue", "/remove-approve"},
+		Examples:    []string{"/approve", "/approve no-issue", "/approve files docs/** *.md", "/remove-approve"},
 	})
 	return pluginHelp, nil
 }
@@ -179,6 +199,19 @@ func handleGenericComment(log *logrus.Entry, ghc githubClient, oc ownersClient,
 		return nil
 	}
 
+	for _, match := range commandRegex.FindAllStringSubmatch(ce.Body, -1) {
+		if strings.ToUpper(match[1]) != approveCommand {
+			continue
+		}
+		if _, isFiles, err := filesGlobs(match[2]); isFiles && err != nil {
+			resp := fmt.Sprintf("The `/approve files` command approves nothing: %v.", err)
+			log.WithError(err).Debug("Invalid /approve files command.")
+			if err := ghc.CreateComment(ce.Repo.Owner.Login, ce.Repo.Name, ce.Number, plugins.FormatResponseRaw(ce.Body, ce.HTMLURL, ce.User.Login, resp)); err != nil {
+				log.WithError(err).Error("Failed to reply to an invalid /approve files command.")
+			}
+		}
+	}
+
 	log.Debug("Resolving pull request...")
 	pr, err := ghc.GetPullRequest(ce.Repo.Owner.Login, ce.Repo.Name, ce.Number)
 	if err != nil {
@@ -202,6 +235,7 @@ func handleGenericComment(log *logrus.Entry, ghc githubClient, oc ownersClient,
 			repo:      ce.Repo.Name,
 			branch:    pr.Base.Ref,
 			number:    ce.Number,
+			headSHA:   pr.Head.SHA,
 			body:      ce.IssueBody,
 			author:    ce.IssueAuthor.Login,
 			assignees: ce.Assignees,
@@ -272,6 +306,7 @@ func handleReview(log *logrus.Entry, ghc githubClient, oc ownersClient, githubCo
 			repo:      re.Repo.Name,
 			branch:    re.PullRequest.Base.Ref,
 			number:    re.PullRequest.Number,
+			headSHA:   re.PullRequest.Head.SHA,
 			body:      re.PullRequest.Body,
 			author:    re.PullRequest.User.Login,
 			assignees: re.PullRequest.Assignees,
@@ -331,6 +366,7 @@ func handlePullRequest(log *logrus.Entry, ghc githubClient, oc ownersClient, git
 			repo:      pre.Repo.Name,
 			branch:    pre.PullRequest.Base.Ref,
 			number:    pre.Number,
+			headSHA:   pre.PullRequest.Head.SHA,
 			body:      pre.PullRequest.Body,
 			author:    pre.PullRequest.User.Login,
 			assignees: pre.PullRequest.Assignees,
@@ -420,14 +456,14 @@ func handle(log *logrus.Entry, ghc githubClient, repo approvers.Repo, githubConf
 	log.WithField("duration", time.Since(start).String()).Debug("Completed github functions in handle")
 
 	start = time.Now()
-	approversHandler := approvers.NewApprovers(
-		approvers.NewOwners(
-			log,
-			filenames,
-			repo,
-			int64(pr.number),
-		),
+	owners := approvers.NewOwners(
+		log,
+		filenames,
+		repo,
+		int64(pr.number),
 	)
+	owners.ApprovalsRequiredByDepth = opts.ApprovalsRequiredByDepth
+	approversHandler := approvers.NewApprovers(owners)
 	approversHandler.AssociatedIssue, err = findAssociatedIssue(pr.body, pr.org)
 	if err != nil {
 		log.WithError(err).Errorf("Failed to find associated issue from PR body: %v", err)
@@ -490,9 +526,53 @@ func handle(log *logrus.Entry, ghc githubClient, repo approvers.Repo, githubConf
 		}
 	}
 	log.WithField("duration", time.Since(start).String()).Debug("Completed adding/deleting approval labels in handle")
+
+	if opts.ReportCheckRun {
+		start = time.Now()
+		if err := reportCheckRun(ghc, githubConfig.LinkURL, pr, approversHandler); err != nil {
+			log.WithError(err).Errorf("Failed to report the %q check run on %s/%s#%d.", checkRunName, pr.org, pr.repo, pr.number)
+		}
+		log.WithField("duration", time.Since(start).String()).Debug("Completed reporting the check run in handle")
+	}
 	return nil
 }
 
+// reportCheckRun creates or updates the approve check run on the head commit
+// of the PR to reflect the current approval state.
+func reportCheckRun(ghc githubClient, linkURL *url.URL, pr *state, approversHandler approvers.Approvers) error {
+	title, summary := approvers.GetCheckRunOutput(approversHandler, linkURL, pr.org, pr.repo, pr.branch)
+	conclusion := checkRunConclusionSuccess
+	if !approversHandler.IsApproved() {
+		conclusion = checkRunConclusionActionRequired
+	}
+	checkRun := github.CheckRun{
+		Name:       checkRunName,
+		HeadSHA:    pr.headSHA,
+		DetailsURL: pr.htmlURL,
+		Status:     checkRunStatusCompleted,
+		Conclusion: conclusion,
+		Output: github.CheckRunOutput{
+			Title:   title,
+			Summary: summary,
+		},
+	}
+
+	checkRuns, err := ghc.ListCheckRuns(pr.org, pr.repo, pr.headSHA)
+	if err != nil {
+		return fmt.Errorf("failed to list check runs: %w", err)
+	}
+	for _, existing := range checkRuns.CheckRuns {
+		if existing.Name != checkRunName {
+			continue
+		}
+		if existing.Conclusion == checkRun.Conclusion && existing.Output.Title == title && existing.Output.Summary == summary {
+			return nil
+		}
+		return ghc.UpdateCheckRun(pr.org, pr.repo, existing.ID, checkRun)
+	}
+	return ghc.CreateCheckRun(pr.org, pr.repo, checkRun)
+}
+
 func humanAddedApproved(ghc githubClient, log *logrus.Entry, org, repo string, number int, hasLabel bool) func() bool {
 	findOut := func() bool {
 		if !hasLabel {
@@ -609,6 +689,20 @@ func addApprovers(approversHandler *approvers.Approvers, approveComments []*comm
 			if name != approveCommand && name != lgtmCommand {
 				continue
 			}
+			// "/approve files <glob>..." only approves the matching files,
+			// and nothing if its globs are missing or invalid.
+			if globs, isFiles, err := filesGlobs(match[2]); name == approveCommand && isFiles {
+				if err == nil {
+					approversHandler.AddPartialApprover(
+						c.Author,
+						c.HTMLURL,
+						false,
+						globs,
+					)
+				}
+				continue
+			}
+
 			args := strings.ToLower(strings.TrimSpace(match[2]))
 			if strings.Contains(args, cancelArgument) {
 				approversHandler.RemoveApprover(c.Author)
@@ -641,6 +735,25 @@ func addApprovers(approversHandler *approvers.Approvers, approveComments []*comm
 	}
 }
 
+// filesGlobs returns the globs of the arguments of an "/approve files
+// <glob>..." command, and whether they are the arguments of one. It fails
+// without globs or with invalid ones.
+func filesGlobs(args string) ([]string, bool, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != filesArgument {
+		return nil, false, nil
+	}
+	if len(fields) == 1 {
+		return nil, true, errors.New("it needs at least one glob, e.g. `/approve files docs/**`")
+	}
+	for _, glob := range fields[1:] {
+		if _, err := gitattributes.ParsePattern(glob); err != nil {
+			return nil, true, fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+	}
+	return fields[1:], true, nil
+}
+
 type comment struct {
 	Body        string
 	Author      string
@@ -34,6 +34,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/git/localgit"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
 	"k8s.io/test-infra/prow/labels"
@@ -1372,6 +1373,119 @@ Approvers can cancel approval by writing ` + "`/approve cancel`" + ` in a commen
 	}
 }
 
+func TestHandleReportsCheckRun(t *testing.T) {
+	const headSHA = "SHA"
+	fr := fakeRepo{
+		approvers: map[string]layeredsets.String{
+			"a": layeredsets.NewString("alice"),
+			"c": layeredsets.NewString("cblecker", "cjwagner"),
+		},
+		leafApprovers: map[string]sets.String{
+			"a": sets.NewString("alice"),
+			"c": sets.NewString("cblecker", "cjwagner"),
+		},
+		approverOwners: map[string]string{
+			"a/a.go": "a",
+			"c/c.go": "c",
+		},
+	}
+	tests := []struct {
+		name              string
+		comments          []github.IssueComment
+		existingCheckRuns []github.CheckRun
+		expectedCheckRuns []github.CheckRun
+	}{
+		{
+			name: "not approved PR gets an action_required check run",
+			expectedCheckRuns: []github.CheckRun{{
+				ID:         1,
+				Name:       checkRunName,
+				HeadSHA:    headSHA,
+				DetailsURL: "https://github.com/org/repo/pull/1",
+				Status:     "completed",
+				Conclusion: "action_required",
+				Output: github.CheckRunOutput{
+					Title:   "Not approved",
+					Summary: "Needs approval from an approver in each of these files:\n\n- **[a/OWNERS](https://github.com/org/repo/blob/master/a/OWNERS)** can be approved by: alice\n",
+				},
+			}},
+		},
+		{
+			name:     "approved PR gets a success check run",
+			comments: []github.IssueComment{newTestComment("alice", "/approve")},
+			expectedCheckRuns: []github.CheckRun{{
+				ID:         1,
+				Name:       checkRunName,
+				HeadSHA:    headSHA,
+				DetailsURL: "https://github.com/org/repo/pull/1",
+				Status:     "completed",
+				Conclusion: "success",
+				Output: github.CheckRunOutput{
+					Title:   "Approved",
+					Summary: "All OWNERS files have been approved.\n",
+				},
+			}},
+		},
+		{
+			name:     "existing check run is updated",
+			comments: []github.IssueComment{newTestComment("alice", "/approve")},
+			existingCheckRuns: []github.CheckRun{
+				{ID: 1, Name: "other", HeadSHA: headSHA, Conclusion: "failure"},
+				{ID: 2, Name: checkRunName, HeadSHA: headSHA, Conclusion: "action_required"},
+			},
+			expectedCheckRuns: []github.CheckRun{
+				{ID: 1, Name: "other", HeadSHA: headSHA, Conclusion: "failure"},
+				{
+					ID:         2,
+					Name:       checkRunName,
+					HeadSHA:    headSHA,
+					DetailsURL: "https://github.com/org/repo/pull/1",
+					Status:     "completed",
+					Conclusion: "success",
+					Output: github.CheckRunOutput{
+						Title:   "Approved",
+						Summary: "All OWNERS files have been approved.\n",
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fghc := newFakeGitHubClient(false, false, []string{"a/a.go", "c/c.go"}, test.comments, nil)
+			fghc.CheckRuns = map[string][]github.CheckRun{headSHA: test.existingCheckRuns}
+			fghc.CheckRunID = int64(len(test.existingCheckRuns))
+			if err := handle(
+				logrus.WithField("plugin", "approve"),
+				fghc,
+				fr,
+				config.GitHubOptions{
+					LinkURL: &url.URL{Scheme: "https", Host: "github.com"},
+				},
+				&plugins.Approve{
+					Repos:          []string{"org/repo"},
+					ReportCheckRun: true,
+				},
+				&state{
+					org:     "org",
+					repo:    "repo",
+					branch:  "master",
+					number:  prNumber,
+					headSHA: headSHA,
+					author:  "cjwagner",
+					htmlURL: "https://github.com/org/repo/pull/1",
+				},
+			); err != nil {
+				t.Fatalf("Unexpected error handling event: %v.", err)
+			}
+			if diff := cmp.Diff(test.expectedCheckRuns, fghc.CheckRuns[headSHA]); diff != "" {
+				t.Errorf("check runs differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
 // TODO: cache approvers 'GetFilesApprovers' and 'GetCCs' since these are called repeatedly and are
 // expensive.
 
@@ -1593,6 +1707,96 @@ func TestHandleGenericComment(t *testing.T) {
 	}
 }
 
+func TestHandleGenericCommentInvalidApproveFiles(t *testing.T) {
+	testCases := []struct {
+		name            string
+		body            string
+		expectedComment bool
+	}{
+		{
+			name: "approve files with globs",
+			body: "/approve files docs/** *.md",
+		},
+		{
+			name:            "approve files without globs",
+			body:            "/approve files",
+			expectedComment: true,
+		},
+		{
+			name:            "approve files with an invalid glob",
+			body:            "/approve files docs/",
+			expectedComment: true,
+		},
+	}
+
+	handleFunc = func(log *logrus.Entry, ghc githubClient, repo approvers.Repo, githubConfig config.GitHubOptions, opts *plugins.Approve, pr *state) error {
+		return nil
+	}
+	defer func() {
+		handleFunc = handle
+	}()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fghc := fakegithub.NewFakeClient()
+			fghc.PullRequests = map[int]*github.PullRequest{1: {Base: github.PullRequestBranch{Ref: "branch"}, Number: 1}}
+			ce := &github.GenericCommentEvent{
+				Action: github.GenericCommentActionCreated,
+				IsPR:   true,
+				Body:   tc.body,
+				Number: 1,
+				User:   github.User{Login: "approver"},
+				Repo:   github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+			}
+			if err := handleGenericComment(logrus.WithField("plugin", "approve"), fghc, fakeOwnersClient{}, config.GitHubOptions{}, &plugins.Configuration{}, ce); err != nil {
+				t.Fatalf("error calling handleGenericComment: %v", err)
+			}
+			if commented := len(fghc.IssueCommentsAdded) > 0; commented != tc.expectedComment {
+				t.Errorf("expected a comment: %t, got comments: %v", tc.expectedComment, fghc.IssueCommentsAdded)
+			}
+		})
+	}
+}
+
+func TestAddApproversFiles(t *testing.T) {
+	testCases := []struct {
+		name              string
+		body              string
+		expectedApprovers []string
+		expectedFiles     []string
+	}{
+		{
+			name:              "approve files with globs",
+			body:              "/approve files docs/** *.md",
+			expectedApprovers: []string{"approver"},
+			expectedFiles:     []string{"*.md", "docs/**"},
+		},
+		{
+			name: "approve files without globs",
+			body: "/approve files",
+		},
+		{
+			name: "approve files with an invalid glob",
+			body: "/approve files docs/** docs/",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ap := approvers.NewApprovers(approvers.NewOwners(logrus.WithField("plugin", "approve"), nil, nil, 0))
+			addApprovers(&ap, []*comment{{Author: "approver", Body: tc.body}}, "author", false)
+			if expected, actual := sets.NewString(tc.expectedApprovers...), ap.GetCurrentApproversSet(); !expected.Equal(actual) {
+				t.Errorf("expected approvers %v, got %v", expected.List(), actual.List())
+			}
+			for _, approval := range ap.ListApprovals() {
+				if diff := cmp.Diff(tc.expectedFiles, approval.Files); diff != "" {
+					t.Errorf("approved files differ from expected: %s", diff)
+				}
+			}
+		})
+	}
+}
+
 // GitHub webhooks send state as lowercase, so force it to lowercase here.
 func stateToLower(s github.ReviewState) github.ReviewState {
 	return github.ReviewState(strings.ToLower(string(s)))
@@ -1999,3 +2203,49 @@ func TestHelpProvider(t *testing.T) {
 		})
 	}
 }
+
+// TestCodeOwnersApproval ensures that the files of a repo without OWNERS files
+// are approved per the CODEOWNERS rule matching them rather than all together.
+func TestCodeOwnersApproval(t *testing.T) {
+	lg, gc, err := localgit.NewV2()
+	if err != nil {
+		t.Fatalf("Error making local git: %v", err)
+	}
+	defer func() {
+		if err := lg.Clean(); err != nil {
+			t.Errorf("Error cleaning LocalGit: %v", err)
+		}
+		if err := gc.Clean(); err != nil {
+			t.Errorf("Error cleaning Client: %v", err)
+		}
+	}()
+	lg.InitialBranch = "master"
+	if err := lg.MakeFakeRepo("org", "repo"); err != nil {
+		t.Fatalf("Error making fake repo: %v", err)
+	}
+	if err := lg.AddCommit("org", "repo", map[string][]byte{".github/CODEOWNERS": []byte("* @alice\n/docs/ @bob\n")}); err != nil {
+		t.Fatalf("Error adding commit: %v", err)
+	}
+	oc := repoowners.NewClient(gc, nil, func(org, repo string) bool { return false }, func(org, repo string) bool { return true }, func() *config.OwnersDirDenylist { return &config.OwnersDirDenylist{} }, ownersconfig.FakeResolver)
+	ro, err := oc.LoadRepoOwners("org", "repo", "master")
+	if err != nil {
+		t.Fatalf("Error loading RepoOwners: %v", err)
+	}
+
+	owners := approvers.NewOwners(logrus.WithField("plugin", "approve"), []string{"README.md", "docs/guide.md"}, ro, int64(0))
+	if expected, actual := sets.NewString("*", "/docs/"), owners.GetOwnersSet(); !expected.Equal(actual) {
+		t.Errorf("expected OWNERS set %v, got %v", expected.List(), actual.List())
+	}
+	ap := approvers.NewApprovers(owners)
+	ap.AddApprover("alice", "", false)
+	if ap.AreFilesApproved() {
+		t.Error("expected the files under /docs/ to still need approval after alice approved")
+	}
+	if expected, actual := sets.NewString("/docs/"), ap.UnapprovedFiles(); !expected.Equal(actual) {
+		t.Errorf("expected unapproved files %v, got %v", expected.List(), actual.List())
+	}
+	ap.AddApprover("bob", "", false)
+	if !ap.AreFilesApproved() {
+		t.Error("expected the files to be approved after alice and bob approved")
+	}
+}
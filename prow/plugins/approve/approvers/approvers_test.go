@@ -235,6 +235,191 @@ func TestGetFiles(t *testing.T) {
 	}
 }
 
+func TestApprovalsRequiredByDepth(t *testing.T) {
+	rootApprovers := sets.NewString("Alice", "Bob", "Carl")
+	bApprovers := sets.NewString("Bill", "Ben")
+	FakeRepoMap := map[string]sets.String{
+		"":  rootApprovers,
+		"b": bApprovers,
+	}
+	baseURL := &url.URL{Scheme: "https", Host: "github.com", Path: "org/repo"}
+	tests := []struct {
+		testName           string
+		filenames          []string
+		currentlyApproved  sets.String
+		expectedUnapproved sets.String
+		expectedFiles      []File
+	}{
+		{
+			testName:           "Root file with a single approval",
+			filenames:          []string{"kubernetes.go"},
+			currentlyApproved:  sets.NewString("Alice"),
+			expectedUnapproved: sets.NewString(""),
+			expectedFiles: []File{
+				QuorumFile{baseURL, "", ownersconfig.DefaultOwnersFile, sets.NewString("Alice"), 2, "master"},
+			},
+		},
+		{
+			testName:           "Root file with two approvals",
+			filenames:          []string{"kubernetes.go"},
+			currentlyApproved:  sets.NewString("Alice", "Bob"),
+			expectedUnapproved: sets.NewString(),
+			expectedFiles: []File{
+				QuorumFile{baseURL, "", ownersconfig.DefaultOwnersFile, sets.NewString("Alice", "Bob"), 2, "master"},
+			},
+		},
+		{
+			testName:           "Leaf directory only needs a single approval",
+			filenames:          []string{"b/test.go"},
+			currentlyApproved:  sets.NewString("Bill"),
+			expectedUnapproved: sets.NewString(),
+			expectedFiles: []File{
+				ApprovedFile{baseURL, "b", ownersconfig.DefaultOwnersFile, sets.NewString("Bill"), "master"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: TestSeed, ApprovalsRequiredByDepth: []int{2}, log: logrus.WithField("plugin", "some_plugin")})
+			for approver := range test.currentlyApproved {
+				testApprovers.AddApprover(approver, "REFERENCE", false)
+			}
+			if calculated := testApprovers.UnapprovedFiles(); !test.expectedUnapproved.Equal(calculated) {
+				t.Errorf("Expected unapproved files: %v. Found %v", test.expectedUnapproved, calculated)
+			}
+			calculated := testApprovers.GetFiles(baseURL, "master")
+			if diff := cmp.Diff(test.expectedFiles, calculated, cmpopts.EquateEmpty(), cmp.Exporter(func(_ reflect.Type) bool { return true })); diff != "" {
+				t.Errorf("expected files differ from actual: %s", diff)
+			}
+		})
+	}
+}
+
+func TestQuorumFileString(t *testing.T) {
+	baseURL := &url.URL{Scheme: "https", Host: "github.com", Path: "org/repo"}
+	tests := []struct {
+		name      string
+		approvers sets.String
+		expected  string
+	}{
+		{
+			name:      "no approvals",
+			approvers: sets.NewString(),
+			expected:  "- **[OWNERS](https://github.com/org/repo/blob/master/OWNERS)** (0/2 approvals)\n",
+		},
+		{
+			name:      "partially approved",
+			approvers: sets.NewString("Alice"),
+			expected:  "- **[OWNERS](https://github.com/org/repo/blob/master/OWNERS)** [Alice] (1/2 approvals)\n",
+		},
+		{
+			name:      "approved",
+			approvers: sets.NewString("Alice", "Bob"),
+			expected:  "- ~~[OWNERS](https://github.com/org/repo/blob/master/OWNERS)~~ [Alice,Bob] (2/2 approvals)\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := QuorumFile{baseURL, "", ownersconfig.DefaultOwnersFile, test.approvers, 2, "master"}
+			if actual := file.String(); actual != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPartialApprovals(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+	}
+	filenames := []string{"a/main.go", "a/docs/README.md", "b/main.go"}
+	tests := []struct {
+		testName                  string
+		fullApprovers             sets.String
+		partialApprovers          map[string][]string
+		expectedUnapproved        sets.String
+		expectedPartiallyApproved map[string]sets.String
+	}{
+		{
+			testName:                  "No approvals",
+			expectedUnapproved:        sets.NewString("a", "b"),
+			expectedPartiallyApproved: map[string]sets.String{},
+		},
+		{
+			testName:           "Partial approval covering some files of an OWNERS file",
+			partialApprovers:   map[string][]string{"Art": {"a/*.go"}},
+			expectedUnapproved: sets.NewString("a", "b"),
+			expectedPartiallyApproved: map[string]sets.String{
+				"a/main.go": sets.NewString("Art"),
+			},
+		},
+		{
+			testName:           "Partial approval covering all files of an OWNERS file",
+			partialApprovers:   map[string][]string{"Art": {"a/**"}},
+			expectedUnapproved: sets.NewString("b"),
+			expectedPartiallyApproved: map[string]sets.String{
+				"a/main.go":        sets.NewString("Art"),
+				"a/docs/README.md": sets.NewString("Art"),
+			},
+		},
+		{
+			testName:           "Partial approvals from different approvers",
+			partialApprovers:   map[string][]string{"Art": {"*.go", "*.md"}, "Bill": {"b/main.go"}},
+			expectedUnapproved: sets.NewString(),
+			expectedPartiallyApproved: map[string]sets.String{
+				"a/main.go":        sets.NewString("Art"),
+				"a/docs/README.md": sets.NewString("Art"),
+				"b/main.go":        sets.NewString("Bill"),
+			},
+		},
+		{
+			testName:           "Partial approvals covering all files of an OWNERS file together",
+			partialApprovers:   map[string][]string{"Art": {"*.go"}, "Alice": {"*.md"}},
+			expectedUnapproved: sets.NewString("b"),
+			expectedPartiallyApproved: map[string]sets.String{
+				"a/main.go":        sets.NewString("Art"),
+				"a/docs/README.md": sets.NewString("Alice"),
+			},
+		},
+		{
+			testName:           "Partial approval from a root approver",
+			partialApprovers:   map[string][]string{"Alice": {"b/**"}},
+			expectedUnapproved: sets.NewString("a"),
+			expectedPartiallyApproved: map[string]sets.String{
+				"b/main.go": sets.NewString("Alice"),
+			},
+		},
+		{
+			testName:                  "Partial approval does not narrow a full approval",
+			fullApprovers:             sets.NewString("Art"),
+			partialApprovers:          map[string][]string{"Art": {"a/*.go"}},
+			expectedUnapproved:        sets.NewString("b"),
+			expectedPartiallyApproved: map[string]sets.String{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			testApprovers := NewApprovers(Owners{filenames: filenames, repo: createFakeRepo(FakeRepoMap), seed: TestSeed, log: logrus.WithField("plugin", "some_plugin")})
+			for approver := range test.fullApprovers {
+				testApprovers.AddApprover(approver, "REFERENCE", false)
+			}
+			for approver, globs := range test.partialApprovers {
+				testApprovers.AddPartialApprover(approver, "REFERENCE", false, globs)
+			}
+			if calculated := testApprovers.UnapprovedFiles(); !test.expectedUnapproved.Equal(calculated) {
+				t.Errorf("Expected unapproved files: %v. Found %v", test.expectedUnapproved, calculated)
+			}
+			if diff := cmp.Diff(test.expectedPartiallyApproved, testApprovers.PartiallyApprovedFiles(), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("expected partially approved files differ from actual: %s", diff)
+			}
+		})
+	}
+}
+
 func TestGetCCs(t *testing.T) {
 	rootApprovers := sets.NewString("Alice", "Bob")
 	aApprovers := sets.NewString("Art", "Anne")
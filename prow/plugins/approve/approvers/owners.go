@@ -31,6 +31,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/util/sets"
 
+	"k8s.io/test-infra/prow/gitattributes"
 	"k8s.io/test-infra/prow/pkg/layeredsets"
 	"k8s.io/test-infra/prow/plugins/ownersconfig"
 )
@@ -62,6 +63,11 @@ type Owners struct {
 	repo      Repo
 	seed      int64
 
+	// ApprovalsRequiredByDepth is the number of distinct approvers needed for
+	// the files of an OWNERS file at each depth, starting with the root OWNERS
+	// file. OWNERS files deeper than the list need a single approval.
+	ApprovalsRequiredByDepth []int
+
 	log *logrus.Entry
 }
 
@@ -70,6 +76,19 @@ func NewOwners(log *logrus.Entry, filenames []string, r Repo, s int64) Owners {
 	return Owners{filenamesUnfiltered: filenames, filenames: filenames, repo: r, seed: s, log: log}
 }
 
+// approvalsRequired returns the number of distinct approvers needed for the
+// files covered by the given OWNERS file.
+func (o Owners) approvalsRequired(ownersFile string) int {
+	depth := 0
+	if ownersFile != "" {
+		depth = strings.Count(ownersFile, "/") + 1
+	}
+	if depth < len(o.ApprovalsRequiredByDepth) {
+		return o.ApprovalsRequiredByDepth[depth]
+	}
+	return 1
+}
+
 // GetApprovers returns a map from ownersFiles -> people that are approvers in them
 func (o Owners) GetApprovers() map[string]sets.String {
 	ownersToApprovers := map[string]sets.String{}
@@ -122,10 +141,13 @@ func (o Owners) GetReverseMap(approvers map[string]sets.String) map[string]sets.
 	return approverOwnersfiles
 }
 
-func findMostCoveringApprover(allApprovers []string, reverseMap map[string]sets.String, unapproved sets.String) string {
+func findMostCoveringApprover(allApprovers []string, reverseMap map[string]sets.String, unapproved, current sets.String) string {
 	maxCovered := 0
 	var bestPerson string
 	for _, approver := range allApprovers {
+		if current.Has(approver) {
+			continue
+		}
 		filesCanApprove := reverseMap[approver]
 		if filesCanApprove.Intersection(unapproved).Len() > maxCovered {
 			maxCovered = len(filesCanApprove)
@@ -169,7 +191,7 @@ func (o Owners) KeepCoveringApprovers(reverseMap map[string]sets.String, knownAp
 func (o Owners) GetSuggestedApprovers(reverseMap map[string]sets.String, potentialApprovers []string) sets.String {
 	ap := NewApprovers(o)
 	for !ap.RequirementsMet() {
-		newApprover := findMostCoveringApprover(potentialApprovers, reverseMap, ap.UnapprovedFiles())
+		newApprover := findMostCoveringApprover(potentialApprovers, reverseMap, ap.UnapprovedFiles(), ap.GetCurrentApproversSet())
 		if newApprover == "" {
 			o.log.Debugf("Couldn't find/suggest approvers for each files. Unapproved: %q", ap.UnapprovedFiles().List())
 			return ap.GetCurrentApproversSet()
@@ -201,6 +223,33 @@ func (o Owners) GetOwnersSet() sets.String {
 	return owners
 }
 
+// GetFilesForOwners returns a map from the OWNERS files in GetOwnersSet to the
+// changed files that need their approval.
+func (o Owners) GetFilesForOwners() map[string][]string {
+	owners := o.GetOwnersSet()
+	ownersToFiles := map[string][]string{}
+	for _, file := range o.filenames {
+		ownersFile := o.repo.FindApproverOwnersForFile(file)
+		if strings.Contains(filepath.Dir(filepath.Dir(file)), ownersFile) && o.repo.IsAutoApproveUnownedSubfolders(ownersFile) {
+			continue
+		}
+		// Subdirectories are merged into their closest parent in the set.
+		for dir := ownersFile; ; dir = filepath.Dir(dir) {
+			if dir == "." {
+				dir = ""
+			}
+			if owners.Has(dir) {
+				ownersToFiles[dir] = append(ownersToFiles[dir], file)
+				break
+			}
+			if dir == "" {
+				break
+			}
+		}
+	}
+	return ownersToFiles
+}
+
 // GetShuffledApprovers shuffles the potential approvers so that we don't
 // always suggest the same people.
 func (o Owners) GetShuffledApprovers() []string {
@@ -242,10 +291,37 @@ func (o Owners) removeSubdirs(dirs sets.String) {
 
 // Approval has the information about each approval on a PR
 type Approval struct {
-	Login     string // Login of the approver (can include uppercase)
-	How       string // How did the approver approved
-	Reference string // Where did the approver approved
-	NoIssue   bool   // Approval also accepts missing associated issue
+	Login     string   // Login of the approver (can include uppercase)
+	How       string   // How did the approver approved
+	Reference string   // Where did the approver approved
+	NoIssue   bool     // Approval also accepts missing associated issue
+	Files     []string // Glob patterns the approval is limited to, empty if it covers all files
+}
+
+// covers returns whether the approval covers every one of the files.
+func (a Approval) covers(files []string) bool {
+	if len(a.Files) == 0 {
+		return true
+	}
+	var patterns []gitattributes.Pattern
+	for _, glob := range a.Files {
+		if pattern, err := gitattributes.ParsePattern(glob); err == nil {
+			patterns = append(patterns, pattern)
+		}
+	}
+	for _, file := range files {
+		var matched bool
+		for _, pattern := range patterns {
+			if pattern.Match(file) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
 }
 
 // String creates a link for the approval. Use `Login` if you just want the name.
@@ -351,6 +427,27 @@ func (ap *Approvers) AddAuthorSelfApprover(login, reference string, noIssue bool
 	}
 }
 
+// AddPartialApprover adds an approver whose approval only covers the changed
+// files matching one of the glob patterns. Partial approvals by the same user
+// accumulate, and they do not narrow an earlier full approval.
+func (ap *Approvers) AddPartialApprover(login, reference string, noIssue bool, globs []string) {
+	if ap.shouldNotOverrideApproval(login, noIssue) {
+		return
+	}
+	existing, alreadyApproved := ap.approvers[strings.ToLower(login)]
+	if alreadyApproved && len(existing.Files) == 0 {
+		return
+	}
+	files := sets.NewString(existing.Files...).Insert(globs...).List()
+	ap.approvers[strings.ToLower(login)] = Approval{
+		Login:     login,
+		How:       "Approved " + strings.Join(files, " "),
+		Reference: reference,
+		NoIssue:   noIssue,
+		Files:     files,
+	}
+}
+
 // RemoveApprover removes an approver from the list.
 func (ap *Approvers) RemoveApprover(login string) {
 	delete(ap.approvers, strings.ToLower(login))
@@ -401,7 +498,9 @@ func (ap Approvers) GetNoIssueApproversSet() sets.String {
 func (ap Approvers) GetFilesApprovers() map[string]sets.String {
 	filesApprovers := map[string]sets.String{}
 	currentApprovers := ap.GetCurrentApproversSetCased()
+	ownersToFiles := ap.owners.GetFilesForOwners()
 	for ownersFilename, potentialApprovers := range ap.owners.GetApprovers() {
+		approvers := ap.coveringApprovers(ownersToFiles[ownersFilename], potentialApprovers, ap.owners.approvalsRequired(ownersFilename))
 		// The order of parameter matters here:
 		// - currentApprovers is the list of github handles that have approved
 		// - potentialApprovers is the list of handles in the OWNER
@@ -410,12 +509,69 @@ func (ap Approvers) GetFilesApprovers() map[string]sets.String {
 		// We want to keep the syntax of the github handle
 		// rather than the potential mis-cased username found in
 		// the OWNERS file, that's why it's the first parameter.
-		filesApprovers[ownersFilename] = CaseInsensitiveIntersection(currentApprovers, potentialApprovers)
+		filesApprovers[ownersFilename] = CaseInsensitiveIntersection(currentApprovers.Intersection(approvers), potentialApprovers)
 	}
 
 	return filesApprovers
 }
 
+// coveringApprovers returns the logins of the approvals of potentialApprovers
+// that count for the files of an OWNERS file. Partial approvals count once,
+// together with the other approvals, each of the files is approved by enough
+// approvers; until then only the approvals covering all files count.
+func (ap Approvers) coveringApprovers(files []string, potentialApprovers sets.String, required int) sets.String {
+	all := sets.NewString()
+	some := sets.NewString()
+	fileApprovers := map[string]sets.String{}
+	for _, file := range files {
+		fileApprovers[file] = sets.NewString()
+	}
+	for _, approval := range ap.approvers {
+		if !potentialApprovers.Has(strings.ToLower(approval.Login)) {
+			continue
+		}
+		if approval.covers(files) {
+			all.Insert(approval.Login)
+		}
+		for _, file := range files {
+			if approval.covers([]string{file}) {
+				some.Insert(approval.Login)
+				fileApprovers[file].Insert(approval.Login)
+			}
+		}
+	}
+	for _, approvers := range fileApprovers {
+		if approvers.Len() < required {
+			return all
+		}
+	}
+	return all.Union(some)
+}
+
+// PartiallyApprovedFiles returns a map from changed files to the users that
+// approved them with a partial approval, for the files that have one.
+func (ap Approvers) PartiallyApprovedFiles() map[string]sets.String {
+	partiallyApproved := map[string]sets.String{}
+	ownersApprovers := ap.owners.GetApprovers()
+	for ownersFilename, files := range ap.owners.GetFilesForOwners() {
+		for _, approval := range ap.approvers {
+			if len(approval.Files) == 0 || !ownersApprovers[ownersFilename].Has(strings.ToLower(approval.Login)) {
+				continue
+			}
+			for _, file := range files {
+				if !approval.covers([]string{file}) {
+					continue
+				}
+				if partiallyApproved[file] == nil {
+					partiallyApproved[file] = sets.NewString()
+				}
+				partiallyApproved[file].Insert(approval.Login)
+			}
+		}
+	}
+	return partiallyApproved
+}
+
 // NoIssueApprovers returns the list of people who have "no-issue"
 // approved the pull-request. They are included in the list if they can
 // approve one of the files.
@@ -442,7 +598,7 @@ func (ap Approvers) NoIssueApprovers() map[string]Approval {
 func (ap Approvers) UnapprovedFiles() sets.String {
 	unapproved := sets.NewString()
 	for fn, approvers := range ap.GetFilesApprovers() {
-		if len(approvers) == 0 {
+		if len(approvers) < ap.owners.approvalsRequired(fn) {
 			unapproved.Insert(fn)
 		}
 	}
@@ -454,7 +610,16 @@ func (ap Approvers) GetFiles(baseURL *url.URL, branch string) []File {
 	var allOwnersFiles []File
 	filesApprovers := ap.GetFilesApprovers()
 	for _, file := range ap.owners.GetOwnersSet().List() {
-		if len(filesApprovers[file]) == 0 {
+		if required := ap.owners.approvalsRequired(file); required > 1 {
+			allOwnersFiles = append(allOwnersFiles, QuorumFile{
+				baseURL:        baseURL,
+				filepath:       file,
+				ownersFilename: ap.owners.repo.Filenames().Owners,
+				approvers:      filesApprovers[file],
+				required:       required,
+				branch:         branch,
+			})
+		} else if len(filesApprovers[file]) == 0 {
 			allOwnersFiles = append(allOwnersFiles, UnapprovedFile{
 				baseURL:        baseURL,
 				filepath:       file,
@@ -585,6 +750,19 @@ type UnapprovedFile struct {
 	branch         string
 }
 
+// QuorumFile contains the information of a file that needs more than one
+// approval, whether or not it has got them all yet.
+type QuorumFile struct {
+	baseURL        *url.URL
+	filepath       string
+	ownersFilename string
+	// approvers is the set of users that approved this file change so far.
+	approvers sets.String
+	// required is the number of approvers needed for this file change.
+	required int
+	branch   string
+}
+
 func (a ApprovedFile) String() string {
 	fullOwnersPath := filepath.Join(a.filepath, a.ownersFilename)
 	if strings.HasSuffix(a.filepath, ".md") {
@@ -611,6 +789,27 @@ func (ua UnapprovedFile) String() string {
 	return fmt.Sprintf("- **[%s](%s)**\n", fullOwnersPath, link)
 }
 
+func (q QuorumFile) String() string {
+	fullOwnersPath := filepath.Join(q.filepath, q.ownersFilename)
+	if strings.HasSuffix(q.filepath, ".md") {
+		fullOwnersPath = q.filepath
+	}
+	link := fmt.Sprintf("%s/blob/%s/%v",
+		q.baseURL.String(),
+		q.branch,
+		fullOwnersPath,
+	)
+	progress := fmt.Sprintf("(%d/%d approvals)", q.approvers.Len(), q.required)
+	switch {
+	case q.approvers.Len() >= q.required:
+		return fmt.Sprintf("- ~~[%s](%s)~~ [%v] %s\n", fullOwnersPath, link, strings.Join(q.approvers.List(), ","), progress)
+	case q.approvers.Len() > 0:
+		return fmt.Sprintf("- **[%s](%s)** [%v] %s\n", fullOwnersPath, link, strings.Join(q.approvers.List(), ","), progress)
+	default:
+		return fmt.Sprintf("- **[%s](%s)** %s\n", fullOwnersPath, link, progress)
+	}
+}
+
 // GenerateTemplate takes a template, name and data, and generates
 // the corresponding string.
 func GenerateTemplate(templ, name string, data interface{}) (string, error) {
@@ -678,6 +877,12 @@ The pull request process is described [here]({{ .prProcessLink }})
 Needs approval from an approver in each of these files:
 
 {{range .ap.GetFiles .baseURL .branch}}{{.}}{{end}}
+{{- if .ap.PartiallyApprovedFiles}}
+Files approved individually with `+"`/approve files`"+`:
+
+{{range $file, $approvers := .ap.PartiallyApprovedFiles}}- `+"`{{$file}}`"+` [{{range $index, $approver := $approvers.List}}{{if $index}},{{end}}{{$approver}}{{end}}]
+{{end}}
+{{- end}}
 Approvers can indicate their approval by writing `+"`/approve`"+` in a comment
 Approvers can cancel approval by writing `+"`/approve cancel`"+` in a comment
 </details>`, "message", map[string]interface{}{"ap": ap, "baseURL": linkURL, "commandHelpLink": commandHelpLink, "prProcessLink": prProcessLink, "org": org, "repo": repo, "branch": branch})
@@ -696,6 +901,45 @@ Approvers can cancel approval by writing `+"`/approve cancel`"+` in a comment
 	return notification(ApprovalNotificationName, title, message)
 }
 
+// GetCheckRunOutput returns the title and summary of the check run that the
+// approve plugin publishes on PRs. The summary lists the OWNERS files that
+// still need approval along with the approvers that can provide it.
+func GetCheckRunOutput(ap Approvers, linkURL *url.URL, org, repo, branch string) (string, string) {
+	linkURL.Path = org + "/" + repo
+	title := "Approved"
+	if !ap.IsApproved() {
+		title = "Not approved"
+	}
+
+	var summary strings.Builder
+	switch {
+	case ap.AreFilesApproved():
+		summary.WriteString("All OWNERS files have been approved.\n")
+	case ap.ManuallyApproved():
+		summary.WriteString("Approval requirements bypassed by manually added approval.\n")
+	default:
+		ownersApprovers := ap.owners.GetApprovers()
+		currentApprovers := ap.GetCurrentApproversSet()
+		summary.WriteString("Needs approval from an approver in each of these files:\n\n")
+		for _, fn := range ap.UnapprovedFiles().List() {
+			file := UnapprovedFile{
+				baseURL:        linkURL,
+				filepath:       fn,
+				ownersFilename: ap.owners.repo.Filenames().Owners,
+				branch:         branch,
+			}
+			// Approvers that already approved a file needing several approvals
+			// cannot provide the missing ones.
+			candidates := ownersApprovers[fn].Difference(currentApprovers).List()
+			fmt.Fprintf(&summary, "%s can be approved by: %s\n", strings.TrimSuffix(file.String(), "\n"), strings.Join(candidates, ", "))
+		}
+	}
+	if ap.AreFilesApproved() && !ap.IsApproved() {
+		summary.WriteString("\n*No associated issue*. Update pull-request body to add a reference to an issue, or get approval with `/approve no-issue`.\n")
+	}
+	return title, summary.String()
+}
+
 func notification(name, arguments, context string) *string {
 	str := "[" + strings.ToUpper(name) + "]"
 
@@ -18,10 +18,13 @@ package assign
 
 import (
 	"fmt"
+	"math/rand"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
@@ -32,34 +35,74 @@ import (
 const pluginName = "assign"
 
 var (
-	assignRe = regexp.MustCompile(`(?mi)^/(un)?assign(( @?[-\w]+?)*)\s*$`)
-	// CCRegexp parses and validates /cc commands, also used by blunderbuss
+	// CCRegexp matches /cc commands, blunderbuss uses it to leave the PRs
+	// whose body requests reviews alone.
 	CCRegexp = regexp.MustCompile(`(?mi)^/(un)?cc(( +@?[-/\w]+?)*)\s*$`)
+	teamRe   = regexp.MustCompile(`^([-\w]+)/([-\w]+)$`)
+	loginRe  = regexp.MustCompile(`^@?[-/\w]+$`)
+
+	usersArg       = []plugins.CommandArg{{Name: "user|org/team", Optional: true, Variadic: true}}
+	assignCommands = []plugins.Command{
+		{
+			Name:        "assign",
+			Args:        usersArg,
+			Description: "Assigns assignee(s) to the PR",
+			Featured:    true,
+			WhoCanUse:   "Anyone can use the command, but the target user(s) must be an org member, a repo collaborator, or should have previously commented on the issue or PR.",
+			Examples:    []string{"/assign", "/assign @spongebob", "/assign spongebob patrick", "/assign @kubernetes/sig-testing-misc"},
+			Handler:     handleCommand,
+		},
+		{
+			Name:        "unassign",
+			Args:        usersArg,
+			Description: "Unassigns assignee(s) from the PR",
+			WhoCanUse:   "Anyone can use the command.",
+			Examples:    []string{"/unassign", "/unassign @spongebob"},
+			Handler:     handleCommand,
+		},
+		{
+			Name:        "cc",
+			Args:        usersArg,
+			Description: "Requests a review from the user(s).",
+			Featured:    true,
+			WhoCanUse:   "Anyone can use the command, but the target user(s) must be a member of the org that owns the repository.",
+			Examples:    []string{"/cc", "/cc @spongebob", "/cc spongebob patrick", "/cc @kubernetes/sig-testing-misc"},
+			Handler:     handleCommand,
+		},
+		{
+			Name:        "uncc",
+			Args:        usersArg,
+			Description: "Removes the requests of reviews from the user(s).",
+			WhoCanUse:   "Anyone can use the command.",
+			Examples:    []string{"/uncc", "/uncc @spongebob"},
+			Handler:     handleCommand,
+		},
+	}
 )
 
 func init() {
-	plugins.RegisterGenericCommentHandler(pluginName, handleGenericComment, helpProvider)
+	plugins.RegisterCommands(pluginName, assignCommands...)
+	plugins.RegisterHelpProvider(pluginName, helpProvider)
 }
 
 func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
-	// The Config field is omitted because this plugin is not configurable.
-	pluginHelp := &pluginhelp.PluginHelp{
-		Description: "The assign plugin assigns or requests reviews from users. Specific users can be assigned with the command '/assign @user1' or have reviews requested of them with the command '/cc @user1'. If no users are specified, the commands default to targeting the user who created the command. Assignments and requested reviews can be removed in the same way that they are added by prefixing the commands with 'un'.",
-	}
-	pluginHelp.AddCommand(pluginhelp.Command{
-		Usage:       "/[un]assign [[@]<username>...]",
-		Description: "Assigns assignee(s) to the PR",
-		Featured:    true,
-		WhoCanUse:   "Anyone can use the command, but the target user(s) must be an org member, a repo collaborator, or should have previously commented on the issue or PR.",
-		Examples:    []string{"/assign", "/unassign", "/assign @spongebob", "/assign spongebob patrick"},
-	})
-	pluginHelp.AddCommand(pluginhelp.Command{
-		Usage:       "/[un]cc [[@]<username>...]",
-		Description: "Requests a review from the user(s).",
-		Featured:    true,
-		WhoCanUse:   "Anyone can use the command, but the target user(s) must be a member of the org that owns the repository.",
-		Examples:    []string{"/cc", "/uncc", "/cc @spongebob", "/cc spongebob patrick"},
+	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
+		Assign: plugins.Assign{
+			MaxTeamReviewers:    5,
+			RoundRobinConfigMap: "assign-round-robin",
+		},
 	})
+	if err != nil {
+		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", pluginName)
+	}
+	pluginHelp := &pluginhelp.PluginHelp{
+		Description: "The assign plugin assigns or requests reviews from users. Specific users can be assigned with the command '/assign @user1' or have reviews requested of them with the command '/cc @user1'. If no users are specified, the commands default to targeting the user who created the command. Assignments and requested reviews can be removed in the same way that they are added by prefixing the commands with 'un'. Teams of the org owning the repository are expanded: '/cc @org/team' requests reviews from the members of the team, up to a configurable maximum, '/assign @org/team' assigns one of the members of the team in turn, and '/uncc @org/team' and '/unassign @org/team' remove all of the members of the team.",
+		Config: map[string]string{
+			"": fmt.Sprintf("Reviews are requested from up to %d members of a cc'ed team.", config.Assign.MaxTeamReviewers),
+		},
+		Snippet: yamlSnippet,
+	}
+	// The help of the commands is added by the plugins framework.
 	return pluginHelp, nil
 }
 
@@ -71,17 +114,27 @@ type githubClient interface {
 	UnrequestReview(org, repo string, number int, logins []string) error
 
 	CreateComment(owner, repo string, number int, comment string) error
+
+	ListTeamMembersBySlug(org, teamSlug, role string) ([]github.TeamMember, error)
 }
 
-func handleGenericComment(pc plugins.Agent, e github.GenericCommentEvent) error {
-	if e.Action != github.GenericCommentActionCreated {
-		return nil
-	}
-	err := handle(newAssignHandler(e, pc.GitHubClient, pc.Logger))
-	if e.IsPR {
-		err = combineErrors(err, handle(newReviewHandler(e, pc.GitHubClient, pc.Logger)))
+func handleCommand(pc plugins.Agent, e github.GenericCommentEvent, invocation plugins.CommandInvocation) error {
+	switch invocation.Command.Name {
+	case "cc", "uncc":
+		if !e.IsPR {
+			return nil
+		}
+		return handle(newReviewHandler(e, pc.GitHubClient, pc.Logger, pc.PluginConfig.Assign.MaxTeamReviewers), invocation)
+	default:
+		var rr *roundRobin
+		if pc.KubernetesClient != nil && pc.Config != nil {
+			rr = &roundRobin{
+				configMaps: pc.KubernetesClient.CoreV1().ConfigMaps(pc.Config.ProwJobNamespace),
+				name:       pc.PluginConfig.Assign.RoundRobinConfigMap,
+			}
+		}
+		return handle(newAssignHandler(e, pc.GitHubClient, pc.Logger, rr), invocation)
 	}
-	return err
 }
 
 func parseLogins(text string) []string {
@@ -96,54 +149,82 @@ func parseLogins(text string) []string {
 	return parts
 }
 
-func combineErrors(err1, err2 error) error {
-	if err1 != nil && err2 != nil {
-		return fmt.Errorf("two errors: 1) %v 2) %w", err1, err2)
-	} else if err1 != nil {
-		return err1
-	} else {
-		return err2
+// teamMembers returns the sorted logins of the members of the team if login
+// is a team of the org, e.g. "org/team". Teams that cannot be listed are
+// left to GitHub to resolve.
+func teamMembers(gc githubClient, log *logrus.Entry, org, login string) ([]string, bool) {
+	mat := teamRe.FindStringSubmatch(login)
+	if mat == nil || !strings.EqualFold(mat[1], org) {
+		return nil, false
+	}
+	members, err := gc.ListTeamMembersBySlug(org, mat[2], github.RoleAll)
+	if err != nil {
+		log.WithError(err).Warnf("Cannot list the members of team %s.", login)
+		return nil, false
+	}
+	var logins []string
+	for _, member := range members {
+		logins = append(logins, member.Login)
+	}
+	sort.Strings(logins)
+	return logins, len(logins) > 0
+}
+
+// teamsAndMembers replaces the teams among the logins with all of their
+// members, keeping the teams themselves if keepTeams is set.
+func teamsAndMembers(gc githubClient, log *logrus.Entry, org string, logins []string, keepTeams bool) []string {
+	expanded := sets.NewString()
+	for _, login := range logins {
+		members, ok := teamMembers(gc, log, org, login)
+		if !ok || keepTeams {
+			expanded.Insert(login)
+		}
+		expanded.Insert(members...)
 	}
+	return expanded.List()
 }
 
-// handle is the generic handler for the assign plugin. It uses the handler's regexp and affectedLogins
-// functions to identify the users to add and/or remove and then passes the appropriate users to the
-// handler's add and remove functions. If add fails to add some of the users, a response comment is
+// handle is the generic handler for the assign plugin. It handles an invocation of the handler's
+// command, or of the command prefixed with 'un', passing the users it names, or the commenter, to the
+// handler's add or remove function. If add fails to add some of the users, a response comment is
 // created where the body of the response is generated by the handler's addFailureResponse function.
-func handle(h *handler) error {
+func handle(h *handler, invocation plugins.CommandInvocation) error {
 	e := h.event
 	org := e.Repo.Owner.Login
 	repo := e.Repo.Name
-	matches := h.regexp.FindAllStringSubmatch(e.Body, -1)
-	if matches == nil {
+	name := invocation.Command.Name
+	if name != h.command && name != "un"+h.command {
 		return nil
 	}
-	users := make(map[string]bool)
-	for _, re := range matches {
-		add := re[1] != "un" // un<cmd> == !add
-		if re[2] == "" {
-			users[e.User.Login] = add
-		} else {
-			for _, login := range parseLogins(re[2]) {
-				users[login] = add
-			}
+	for _, word := range strings.Fields(invocation.Arg("user|org/team")) {
+		if !loginRe.MatchString(word) {
+			h.log.Debugf("Ignoring /%s with the invalid login %q.", name, word)
+			return nil
 		}
 	}
+	logins := sets.NewString(parseLogins(invocation.Arg("user|org/team"))...)
+	if logins.Len() == 0 {
+		logins.Insert(e.User.Login)
+	}
 	var toAdd, toRemove []string
-	for login, add := range users {
-		if add {
-			toAdd = append(toAdd, login)
-		} else {
-			toRemove = append(toRemove, login)
-		}
+	if name == h.command {
+		toAdd = logins.List()
+	} else {
+		toRemove = logins.List()
 	}
 
+	if len(toRemove) > 0 && h.expandTeamRemovals != nil {
+		toRemove = h.expandTeamRemovals(org, toRemove)
+	}
 	if len(toRemove) > 0 {
 		h.log.Printf("Removing %s from %s/%s#%d: %v", h.userType, org, repo, e.Number, toRemove)
 		if err := h.remove(org, repo, e.Number, toRemove); err != nil {
 			return err
 		}
 	}
+	if len(toAdd) > 0 && h.expandTeams != nil {
+		toAdd = h.expandTeams(org, toAdd)
+	}
 	if len(toAdd) > 0 {
 		h.log.Printf("Adding %s to %s/%s#%d: %v", h.userType, org, repo, e.Number, toAdd)
 		if err := h.add(org, repo, e.Number, toAdd); err != nil {
@@ -171,12 +252,15 @@ type handler struct {
 	remove func(org, repo string, number int, users []string) error
 	// add is the function that is called on the affected logins for a command with no 'un' prefix.
 	add func(org, repo string, number int, users []string) error
+	// expandTeams replaces the teams among the logins to add with some of their members.
+	expandTeams func(org string, logins []string) []string
+	// expandTeamRemovals replaces the teams among the logins to remove with their members.
+	expandTeamRemovals func(org string, logins []string) []string
 
 	// event is a pointer to the github.GenericCommentEvent struct that triggered the handler.
 	event *github.GenericCommentEvent
-	// regexp is the regular expression describing the command. It must have an optional 'un' prefix
-	// as the first subgroup and the arguments to the command as the second subgroup.
-	regexp *regexp.Regexp
+	// command is the name of the command adding users, the command prefixed with 'un' removes them.
+	command string
 	// gc is the githubClient to use for creating response comments in the event of a failure.
 	gc githubClient
 
@@ -186,36 +270,100 @@ type handler struct {
 	userType string
 }
 
-func newAssignHandler(e github.GenericCommentEvent, gc githubClient, log *logrus.Entry) *handler {
+func newAssignHandler(e github.GenericCommentEvent, gc githubClient, log *logrus.Entry, rr *roundRobin) *handler {
 	org := e.Repo.Owner.Login
 	addFailureResponse := func(mu github.MissingUsers) string {
 		return fmt.Sprintf("GitHub didn't allow me to assign the following users: %s.\n\nNote that only [%s members](https://github.com/orgs/%s/people), repo collaborators and people who have commented on this issue/PR can be assigned. Additionally, issues/PRs can only have 10 assignees at the same time.\nFor more information please see [the contributor guide](https://git.k8s.io/community/contributors/guide/first-contribution.md#issue-assignment-in-github)", strings.Join(mu.Users, ", "), org, org)
 	}
 
+	// Teams are assigned in turn to their members, ordered by login, so that
+	// consecutive issues are spread over the team. Without a round-robin to
+	// keep the turns, or when it fails, the member is picked by the number of
+	// the issue.
+	expandTeams := func(org string, logins []string) []string {
+		expanded := sets.NewString()
+		for _, login := range logins {
+			members, ok := teamMembers(gc, log, org, login)
+			if !ok {
+				expanded.Insert(login)
+				continue
+			}
+			member := members[e.Number%len(members)]
+			if rr != nil {
+				next, err := rr.next(login, members)
+				if err != nil {
+					log.WithError(err).Warnf("Cannot take the turn of team %s, falling back to the issue number.", login)
+				} else {
+					member = next
+				}
+			}
+			expanded.Insert(member)
+		}
+		return expanded.List()
+	}
+	// Unassigning a team unassigns all of its members.
+	expandTeamRemovals := func(org string, logins []string) []string {
+		return teamsAndMembers(gc, log, org, logins, false)
+	}
+
 	return &handler{
 		addFailureResponse: addFailureResponse,
 		remove:             gc.UnassignIssue,
 		add:                gc.AssignIssue,
+		expandTeams:        expandTeams,
+		expandTeamRemovals: expandTeamRemovals,
 		event:              &e,
-		regexp:             assignRe,
+		command:            "assign",
 		gc:                 gc,
 		log:                log,
 		userType:           "assignee(s)",
 	}
 }
 
-func newReviewHandler(e github.GenericCommentEvent, gc githubClient, log *logrus.Entry) *handler {
+func newReviewHandler(e github.GenericCommentEvent, gc githubClient, log *logrus.Entry, maxTeamReviewers int) *handler {
 	org := e.Repo.Owner.Login
 	addFailureResponse := func(mu github.MissingUsers) string {
 		return fmt.Sprintf("GitHub didn't allow me to request PR reviews from the following users: %s.\n\nNote that only [%s members](https://github.com/orgs/%s/people) and repo collaborators can review this PR, and authors cannot review their own PRs.", strings.Join(mu.Users, ", "), org, org)
 	}
 
+	// Teams are replaced by up to maxTeamReviewers of their members, picked
+	// at random, excluding the author who cannot review their own PR.
+	expandTeams := func(org string, logins []string) []string {
+		expanded := sets.NewString()
+		for _, login := range logins {
+			members, ok := teamMembers(gc, log, org, login)
+			if !ok {
+				expanded.Insert(login)
+				continue
+			}
+			candidates := sets.NewString(members...).Delete(e.IssueAuthor.Login).List()
+			if len(candidates) == 0 {
+				expanded.Insert(login)
+				continue
+			}
+			if maxTeamReviewers > 0 && len(candidates) > maxTeamReviewers {
+				rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+				candidates = candidates[:maxTeamReviewers]
+			}
+			expanded.Insert(candidates...)
+		}
+		return expanded.List()
+	}
+	// Unrequesting reviews from a team unrequests them from all of its
+	// members and from the team itself, which may have been requested
+	// directly.
+	expandTeamRemovals := func(org string, logins []string) []string {
+		return teamsAndMembers(gc, log, org, logins, true)
+	}
+
 	return &handler{
 		addFailureResponse: addFailureResponse,
 		remove:             gc.UnrequestReview,
 		add:                gc.RequestReview,
+		expandTeams:        expandTeams,
+		expandTeamRemovals: expandTeamRemovals,
 		event:              &e,
-		regexp:             CCRegexp,
+		command:            "cc",
 		gc:                 gc,
 		log:                log,
 		userType:           "reviewer(s)",
@@ -17,14 +17,33 @@ limitations under the License.
 package assign
 
 import (
+	"context"
+	"fmt"
 	"sort"
 	"testing"
 
 	"github.com/sirupsen/logrus"
+	coreapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
 
 	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
 )
 
+// handleComment handles the commands of the handler in the body of its event,
+// like hook does.
+func handleComment(h *handler) error {
+	invocations, _ := plugins.ParseCommands(h.event.Body, assignCommands)
+	for _, invocation := range invocations {
+		if err := handle(h, invocation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type fakeClient struct {
 	assigned   map[string]int
 	unassigned map[string]int
@@ -32,6 +51,7 @@ type fakeClient struct {
 	requested    map[string]int
 	unrequested  map[string]int
 	contributors map[string]bool
+	teams        map[string][]string
 
 	commented bool
 }
@@ -95,6 +115,17 @@ func (c *fakeClient) CreateComment(owner, repo string, number int, comment strin
 	return nil
 }
 
+func (c *fakeClient) ListTeamMembersBySlug(org, teamSlug, role string) ([]github.TeamMember, error) {
+	if org != "org" {
+		return nil, fmt.Errorf("bad org: %s", org)
+	}
+	var members []github.TeamMember
+	for _, login := range c.teams[teamSlug] {
+		members = append(members, github.TeamMember{Login: login})
+	}
+	return members, nil
+}
+
 func newFakeClient(contribs []string) *fakeClient {
 	c := &fakeClient{
 		contributors: make(map[string]bool),
@@ -102,6 +133,10 @@ func newFakeClient(contribs []string) *fakeClient {
 		unrequested:  make(map[string]int),
 		assigned:     make(map[string]int),
 		unassigned:   make(map[string]int),
+		teams: map[string][]string{
+			"sig-foo":  {"carol", "alice", "bob"},
+			"sig-many": {"alice", "bob", "carol", "dave", "erin", "frank"},
+		},
 	}
 	for _, user := range contribs {
 		c.contributors[user] = true
@@ -386,20 +421,62 @@ func TestAssignAndReview(t *testing.T) {
 			commenter:   "rando",
 			unrequested: []string{"kubernetes/sig-testing-misc"},
 		},
+		{
+			name:      "request review from team of the org",
+			body:      "/cc @org/sig-foo",
+			commenter: "rando",
+			requested: []string{"alice", "bob", "carol"},
+		},
+		{
+			name:      "request review from team and member of the org",
+			body:      "/cc @org/sig-foo @alice",
+			commenter: "rando",
+			requested: []string{"alice", "bob", "carol"},
+		},
+		{
+			name:      "request review from unknown team of the org",
+			body:      "/cc @org/sig-unknown",
+			commenter: "rando",
+			commented: true,
+		},
+		{
+			name:        "unrequest team review of the org",
+			body:        "/uncc @org/sig-foo",
+			commenter:   "rando",
+			unrequested: []string{"org/sig-foo", "alice", "bob", "carol"},
+		},
+		{
+			name:       "unassign team of the org",
+			body:       "/unassign @org/sig-foo",
+			commenter:  "rando",
+			unassigned: []string{"alice", "bob", "carol"},
+		},
+		{
+			name:      "assign team of the org",
+			body:      "/assign @org/sig-foo",
+			commenter: "rando",
+			assigned:  []string{"carol"},
+		},
+		{
+			name:      "assign team of another org",
+			body:      "/assign @kubernetes/sig-testing-misc",
+			commenter: "rando",
+			assigned:  []string{"kubernetes/sig-testing-misc"},
+		},
 	}
 	for _, tc := range testcases {
-		fc := newFakeClient([]string{"hello-world", "allow_underscore", "cjwagner", "merlin", "kubernetes/sig-testing-misc"})
+		fc := newFakeClient([]string{"hello-world", "allow_underscore", "cjwagner", "merlin", "kubernetes/sig-testing-misc", "alice", "bob", "carol"})
 		e := github.GenericCommentEvent{
 			Body:   tc.body,
 			User:   github.User{Login: tc.commenter},
 			Repo:   github.Repo{Name: "repo", Owner: github.User{Login: "org"}},
 			Number: 5,
 		}
-		if err := handle(newAssignHandler(e, fc, logrus.WithField("plugin", pluginName))); err != nil {
+		if err := handleComment(newAssignHandler(e, fc, logrus.WithField("plugin", pluginName), nil)); err != nil {
 			t.Errorf("For case %s, didn't expect error from handle: %v", tc.name, err)
 			continue
 		}
-		if err := handle(newReviewHandler(e, fc, logrus.WithField("plugin", pluginName))); err != nil {
+		if err := handleComment(newReviewHandler(e, fc, logrus.WithField("plugin", pluginName), 5)); err != nil {
 			t.Errorf("For case %s, didn't expect error from handle: %v", tc.name, err)
 			continue
 		}
@@ -451,3 +528,127 @@ func TestAssignAndReview(t *testing.T) {
 		}
 	}
 }
+
+func TestReviewTeamExpansion(t *testing.T) {
+	var testcases = []struct {
+		name             string
+		body             string
+		author           string
+		maxTeamReviewers int
+		expectedCount    int
+		excluded         string
+	}{
+		{
+			name:             "reviews are requested from at most the max number of members",
+			body:             "/cc @org/sig-many",
+			maxTeamReviewers: 2,
+			expectedCount:    2,
+		},
+		{
+			name:          "reviews are requested from all members without max",
+			body:          "/cc @org/sig-many",
+			expectedCount: 6,
+		},
+		{
+			name:          "the author is not requested",
+			body:          "/cc @org/sig-many",
+			author:        "dave",
+			expectedCount: 5,
+			excluded:      "dave",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := newFakeClient([]string{"alice", "bob", "carol", "dave", "erin", "frank"})
+			e := github.GenericCommentEvent{
+				Body:        tc.body,
+				User:        github.User{Login: "rando"},
+				IssueAuthor: github.User{Login: tc.author},
+				Repo:        github.Repo{Name: "repo", Owner: github.User{Login: "org"}},
+				Number:      5,
+			}
+			if err := handleComment(newReviewHandler(e, fc, logrus.WithField("plugin", pluginName), tc.maxTeamReviewers)); err != nil {
+				t.Fatalf("didn't expect error from handle: %v", err)
+			}
+			if len(fc.requested) != tc.expectedCount {
+				t.Errorf("expected %d requested reviewers, got %v", tc.expectedCount, fc.requested)
+			}
+			if _, ok := fc.requested[tc.excluded]; ok && tc.excluded != "" {
+				t.Errorf("expected %s not to be requested, got %v", tc.excluded, fc.requested)
+			}
+		})
+	}
+}
+
+func TestAssignTeamRoundRobin(t *testing.T) {
+	var testcases = []struct {
+		name              string
+		existing          []runtime.Object
+		assignments       int
+		expectedAssigned  []string
+		expectedPositions map[string]string
+	}{
+		{
+			name:              "the first member is assigned without a ConfigMap",
+			assignments:       1,
+			expectedAssigned:  []string{"alice"},
+			expectedPositions: map[string]string{"org.sig-foo": "1"},
+		},
+		{
+			name:              "members are assigned in turn",
+			assignments:       2,
+			expectedAssigned:  []string{"alice", "bob"},
+			expectedPositions: map[string]string{"org.sig-foo": "2"},
+		},
+		{
+			name: "the turn kept in the ConfigMap is taken and wraps around",
+			existing: []runtime.Object{&coreapi.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "assign-round-robin", Namespace: "prowjobs"},
+				Data:       map[string]string{"org.sig-foo": "2", "org.sig-bar": "7"},
+			}},
+			assignments:       2,
+			expectedAssigned:  []string{"alice", "carol"},
+			expectedPositions: map[string]string{"org.sig-foo": "1", "org.sig-bar": "7"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			configMaps := fake.NewSimpleClientset(tc.existing...).CoreV1().ConfigMaps("prowjobs")
+			rr := &roundRobin{configMaps: configMaps, name: "assign-round-robin"}
+			fc := newFakeClient(nil)
+			e := github.GenericCommentEvent{
+				Body:   "/assign @org/sig-foo",
+				User:   github.User{Login: "rando"},
+				Repo:   github.Repo{Name: "repo", Owner: github.User{Login: "org"}},
+				Number: 5,
+			}
+			for i := 0; i < tc.assignments; i++ {
+				if err := handleComment(newAssignHandler(e, fc, logrus.WithField("plugin", pluginName), rr)); err != nil {
+					t.Fatalf("didn't expect error from handle: %v", err)
+				}
+			}
+			if len(fc.assigned) != len(tc.expectedAssigned) {
+				t.Errorf("expected %v to be assigned, got %v", tc.expectedAssigned, fc.assigned)
+			}
+			for _, who := range tc.expectedAssigned {
+				if fc.assigned[who] != 1 {
+					t.Errorf("expected %v to be assigned once each, got %v", tc.expectedAssigned, fc.assigned)
+					break
+				}
+			}
+			cm, err := configMaps.Get(context.TODO(), "assign-round-robin", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get the round-robin ConfigMap: %v", err)
+			}
+			if len(cm.Data) != len(tc.expectedPositions) {
+				t.Errorf("expected positions %v, got %v", tc.expectedPositions, cm.Data)
+			}
+			for team, position := range tc.expectedPositions {
+				if cm.Data[team] != position {
+					t.Errorf("expected positions %v, got %v", tc.expectedPositions, cm.Data)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assign
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// roundRobin keeps the position of the member of each team that is assigned
+// next in a ConfigMap, so that assignments go around the team even across
+// restarts of hook.
+type roundRobin struct {
+	configMaps corev1.ConfigMapInterface
+	name       string
+}
+
+// roundRobinKey is the key of the position of the team in the ConfigMap,
+// which only allows alphanumerics, '-', '_' and '.' in its keys.
+func roundRobinKey(team string) string {
+	return strings.ToLower(strings.Replace(team, "/", ".", -1))
+}
+
+// next returns the member of the team to assign, out of its members ordered
+// by login, and moves the team on to the following member.
+func (r *roundRobin) next(team string, members []string) (string, error) {
+	key := roundRobinKey(team)
+	var member string
+	// The ConfigMap is created on the first assignment, two hooks creating it
+	// at the same time are handled like conflicting updates.
+	retriable := func(err error) bool {
+		return errors.IsConflict(err) || errors.IsAlreadyExists(err)
+	}
+	err := retry.OnError(retry.DefaultRetry, retriable, func() error {
+		cm, err := r.configMaps.Get(context.TODO(), r.name, metav1.GetOptions{})
+		exists := err == nil
+		if errors.IsNotFound(err) {
+			cm = &coreapi.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: r.name}}
+		} else if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		// Positions that cannot be parsed start over with the first member.
+		position, _ := strconv.Atoi(cm.Data[key])
+		if position < 0 {
+			position = 0
+		}
+		member = members[position%len(members)]
+		cm.Data[key] = strconv.Itoa((position + 1) % len(members))
+
+		if exists {
+			_, err = r.configMaps.Update(context.TODO(), cm, metav1.UpdateOptions{})
+		} else {
+			_, err = r.configMaps.Create(context.TODO(), cm, metav1.CreateOptions{})
+		}
+		return err
+	})
+	return member, err
+}
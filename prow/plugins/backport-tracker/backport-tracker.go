@@ -0,0 +1,289 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backporttracker implements the `backport-tracker` plugin. It opens
+// a tracking issue for every backport requested on a merged PR, pings the PR
+// author until the cherry-pick merges and then closes the tracking issue.
+package backporttracker
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/pluginhelp"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// PluginName defines this plugin's registered name.
+const PluginName = "backport-tracker"
+
+var (
+	// markerRe matches the hidden marker identifying the PR and the target
+	// branch of a tracking issue.
+	markerRe = regexp.MustCompile(`<!-- backport-tracker: ([^/\s]+)/([^#\s]+)#(\d+) (\S+) -->`)
+	// cherryPickOfRe matches the reference to the original PR in the body of
+	// a cherry-pick, e.g. "This is an automated cherry-pick of #123".
+	cherryPickOfRe = regexp.MustCompile(`(?i)cherry[- ]?pick of #(\d+)`)
+)
+
+type githubClient interface {
+	CreateComment(org, repo string, number int, comment string) error
+	CreateIssue(org, repo, title, body string, milestone int, labels, assignees []string) (int, error)
+	CloseIssue(org, repo string, number int) error
+	FindIssues(query, sort string, asc bool) ([]github.Issue, error)
+}
+
+// tracker identifies the backport of a PR to a branch.
+type tracker struct {
+	org    string
+	repo   string
+	number int
+	branch string
+}
+
+func (t tracker) marker() string {
+	return fmt.Sprintf("<!-- backport-tracker: %s/%s#%d %s -->", t.org, t.repo, t.number, t.branch)
+}
+
+func parseMarker(body string) (tracker, bool) {
+	match := markerRe.FindStringSubmatch(body)
+	if match == nil {
+		return tracker{}, false
+	}
+	number, err := strconv.Atoi(match[3])
+	if err != nil {
+		return tracker{}, false
+	}
+	return tracker{org: match[1], repo: match[2], number: number, branch: match[4]}, true
+}
+
+func init() {
+	plugins.RegisterPullRequestHandler(PluginName, handlePullRequest, helpProvider)
+	plugins.RegisterPeriodicHandler(PluginName, handlePeriodic, helpProvider)
+}
+
+func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	configInfo := map[string]string{}
+	for _, repo := range enabledRepos {
+		opts := config.BackportTrackerFor(repo.Org, repo.Repo)
+		if opts == nil {
+			continue
+		}
+		configInfo[repo.String()] = fmt.Sprintf("Merged PRs labeled '%s<branch>' get a tracking issue per branch. Authors are pinged after %s of inactivity.", opts.LabelPrefix, opts.PingIntervalDuration)
+	}
+	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
+		BackportTracker: []plugins.BackportTracker{
+			{
+				Repos:        []string{"org/repo"},
+				LabelPrefix:  "needs-backport/",
+				PingInterval: "168h",
+			},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", PluginName)
+	}
+	// Only the 'Description' and 'Config' fields are necessary because this plugin does not react
+	// to any commands.
+	return &pluginhelp.PluginHelp{
+		Description: fmt.Sprintf("The backport-tracker plugin opens a tracking issue labeled '%s' for every backport requested with a 'needs-backport/<branch>' label on a merged PR. The PR author is assigned to the issue and pinged periodically until the cherry-pick to the branch merges, at which point the issue is closed. Cherry-picks are recognized by the 'cherry-pick of #<number>' reference in their description, as added by the cherrypicker.", labels.BackportTracking),
+		Config:      configInfo,
+		Snippet:     yamlSnippet,
+	}, nil
+}
+
+func handlePullRequest(pc plugins.Agent, pre github.PullRequestEvent) error {
+	opts := pc.PluginConfig.BackportTrackerFor(pre.Repo.Owner.Login, pre.Repo.Name)
+	if opts == nil {
+		return nil
+	}
+	return handle(pc.Logger, pc.GitHubClient, opts, pre)
+}
+
+func handle(log *logrus.Entry, gc githubClient, opts *plugins.BackportTracker, pre github.PullRequestEvent) error {
+	pr := pre.PullRequest
+	if !pr.Merged {
+		return nil
+	}
+	org := pre.Repo.Owner.Login
+	repo := pre.Repo.Name
+
+	var branches []string
+	switch pre.Action {
+	case github.PullRequestActionClosed:
+		for _, label := range pr.Labels {
+			if strings.HasPrefix(label.Name, opts.LabelPrefix) {
+				branches = append(branches, strings.TrimPrefix(label.Name, opts.LabelPrefix))
+			}
+		}
+		if err := closeTracker(log, gc, org, repo, pr); err != nil {
+			return err
+		}
+	case github.PullRequestActionLabeled:
+		// The backport may be requested after the PR merged.
+		if strings.HasPrefix(pre.Label.Name, opts.LabelPrefix) {
+			branches = append(branches, strings.TrimPrefix(pre.Label.Name, opts.LabelPrefix))
+		}
+	}
+	if len(branches) == 0 {
+		return nil
+	}
+
+	existing, err := findTrackers(gc, org, repo, pr.Number, false)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, branch := range branches {
+		t := tracker{org: org, repo: repo, number: pr.Number, branch: branch}
+		if _, ok := existing[t]; ok || branch == "" {
+			continue
+		}
+		title := fmt.Sprintf("Backport #%d to %s", pr.Number, branch)
+		body := fmt.Sprintf("%s was merged with the `%s%s` label, but has not been cherry-picked to `%s` yet.\n\n"+
+			"@%s: please open a cherry-pick, e.g. by commenting `/cherrypick %s` on #%d. This issue will be closed once the cherry-pick merges.\n\n%s",
+			pr.HTMLURL, opts.LabelPrefix, branch, branch, pr.User.Login, branch, pr.Number, t.marker())
+		log.WithField("branch", branch).Info("Opening backport tracking issue.")
+		if _, err := gc.CreateIssue(org, repo, title, body, 0, []string{labels.BackportTracking}, []string{pr.User.Login}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to create the tracking issue for the backport of %s/%s#%d to %s: %w", org, repo, pr.Number, branch, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// closeTracker closes the tracking issue the merged PR is a cherry-pick for,
+// if there is one.
+func closeTracker(log *logrus.Entry, gc githubClient, org, repo string, pr github.PullRequest) error {
+	match := cherryPickOfRe.FindStringSubmatch(pr.Body)
+	if match == nil {
+		return nil
+	}
+	original, err := strconv.Atoi(match[1])
+	if err != nil {
+		return nil
+	}
+	trackers, err := findTrackers(gc, org, repo, original, true)
+	if err != nil {
+		return err
+	}
+	issue, ok := trackers[tracker{org: org, repo: repo, number: original, branch: pr.Base.Ref}]
+	if !ok {
+		return nil
+	}
+	log.WithField("tracker", issue.Number).Info("Closing backport tracking issue.")
+	if err := gc.CreateComment(org, repo, issue.Number, fmt.Sprintf("#%d was backported to `%s` in #%d.", original, pr.Base.Ref, pr.Number)); err != nil {
+		return err
+	}
+	return gc.CloseIssue(org, repo, issue.Number)
+}
+
+// findTrackers returns the tracking issues of the backports of a PR by target
+// branch.
+func findTrackers(gc githubClient, org, repo string, number int, onlyOpen bool) (map[tracker]github.Issue, error) {
+	query := fmt.Sprintf("is:issue repo:%s/%s label:%q in:title \"Backport #%d\"", org, repo, labels.BackportTracking, number)
+	if onlyOpen {
+		query = "is:open " + query
+	}
+	issues, err := gc.FindIssues(query, "", false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for the backport tracking issues of %s/%s#%d: %w", org, repo, number, err)
+	}
+	trackers := map[tracker]github.Issue{}
+	for _, issue := range issues {
+		if issue.IsPullRequest() || (onlyOpen && issue.State == "closed") {
+			continue
+		}
+		// The search is fuzzy, so only trust the marker.
+		if t, ok := parseMarker(issue.Body); ok && t.org == org && t.repo == repo && t.number == number {
+			trackers[t] = issue
+		}
+	}
+	return trackers, nil
+}
+
+func handlePeriodic(pc plugins.Agent) error {
+	return ping(pc.Logger, pc.GitHubClient, pc.PluginConfig, time.Now())
+}
+
+// ping reminds the assignees of the tracking issues that have been inactive
+// for longer than the ping interval that the backport is still missing.
+func ping(log *logrus.Entry, gc githubClient, config *plugins.Configuration, now time.Time) error {
+	issues := map[string]github.Issue{}
+	var errs []error
+	for _, bt := range config.BackportTracker {
+		for _, orgOrRepo := range bt.Repos {
+			found, err := gc.FindIssues(searchQuery(bt, orgOrRepo, now), "", false)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to search for backport tracking issues in %s: %w", orgOrRepo, err))
+				continue
+			}
+			for _, issue := range found {
+				issues[issue.HTMLURL] = issue
+			}
+		}
+	}
+
+	for htmlURL, issue := range issues {
+		org, repo, err := github.OrgRepoFromHTMLURL(htmlURL)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		opts := config.BackportTrackerFor(org, repo)
+		if !config.PluginEnabledFor(PluginName, org, repo) || opts == nil || now.Sub(issue.UpdatedAt) < opts.PingIntervalDuration {
+			continue
+		}
+		t, ok := parseMarker(issue.Body)
+		if !ok || issue.State == "closed" {
+			continue
+		}
+		var mentions []string
+		for _, assignee := range issue.Assignees {
+			mentions = append(mentions, "@"+assignee.Login)
+		}
+		if len(mentions) == 0 {
+			mentions = append(mentions, "@"+issue.User.Login)
+		}
+		log.WithFields(logrus.Fields{github.OrgLogField: org, github.RepoLogField: repo, github.PrLogField: issue.Number}).Info("Pinging about missing backport.")
+		comment := fmt.Sprintf("%s: #%d has still not been cherry-picked to `%s`. Please open a cherry-pick, e.g. by commenting `/cherrypick %s` on #%d.", strings.Join(mentions, " "), t.number, t.branch, t.branch, t.number)
+		if err := gc.CreateComment(org, repo, issue.Number, comment); err != nil {
+			errs = append(errs, fmt.Errorf("failed to ping on %s/%s#%d: %w", org, repo, issue.Number, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// searchQuery returns the query for the open tracking issues of orgOrRepo
+// that have been inactive for at least the ping interval.
+func searchQuery(bt plugins.BackportTracker, orgOrRepo string, now time.Time) string {
+	return strings.Join([]string{
+		"is:open",
+		"is:issue",
+		plugins.SearchQualifier(orgOrRepo),
+		fmt.Sprintf("label:%q", labels.BackportTracking),
+		"updated:<" + now.Add(-bt.PingIntervalDuration).UTC().Format(time.RFC3339),
+	}, " ")
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backporttracker
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func trackingIssue(number int, state string, branch string, updated time.Time) *github.Issue {
+	return &github.Issue{
+		ID:        number,
+		Number:    number,
+		State:     state,
+		Title:     "Backport #5 to " + branch,
+		Body:      "body\n\n" + tracker{org: "org", repo: "repo", number: 5, branch: branch}.marker(),
+		HTMLURL:   fmt.Sprintf("https://github.com/org/repo/issues/%d", number),
+		User:      github.User{Login: "k8s-ci-robot"},
+		Assignees: []github.User{{Login: "author"}},
+		UpdatedAt: updated,
+	}
+}
+
+func TestHandle(t *testing.T) {
+	opts := &plugins.BackportTracker{LabelPrefix: "needs-backport/"}
+
+	cases := []struct {
+		name           string
+		action         github.PullRequestEventAction
+		merged         bool
+		number         int
+		base           string
+		body           string
+		labels         []string
+		label          string
+		existing       []*github.Issue
+		expectTrackers []string
+		expectComments []string
+		expectClosed   []int
+	}{
+		{
+			name:   "unmerged PR is ignored",
+			action: github.PullRequestActionClosed,
+			number: 5,
+			labels: []string{"needs-backport/release-1.20"},
+		},
+		{
+			name:           "merged PR gets a tracker per backport label",
+			action:         github.PullRequestActionClosed,
+			merged:         true,
+			number:         5,
+			labels:         []string{"needs-backport/release-1.20", "needs-backport/release-1.19", "lgtm"},
+			expectTrackers: []string{"Backport #5 to release-1.19", "Backport #5 to release-1.20"},
+		},
+		{
+			name:           "existing trackers are not duplicated",
+			action:         github.PullRequestActionClosed,
+			merged:         true,
+			number:         5,
+			labels:         []string{"needs-backport/release-1.20", "needs-backport/release-1.19"},
+			existing:       []*github.Issue{trackingIssue(1, "open", "release-1.20", time.Time{})},
+			expectTrackers: []string{"Backport #5 to release-1.19", "Backport #5 to release-1.20"},
+		},
+		{
+			name:           "label added after the merge opens a tracker",
+			action:         github.PullRequestActionLabeled,
+			merged:         true,
+			number:         5,
+			labels:         []string{"needs-backport/release-1.20"},
+			label:          "needs-backport/release-1.20",
+			expectTrackers: []string{"Backport #5 to release-1.20"},
+		},
+		{
+			name:   "other label added after the merge is ignored",
+			action: github.PullRequestActionLabeled,
+			merged: true,
+			number: 5,
+			labels: []string{"lgtm"},
+			label:  "lgtm",
+		},
+		{
+			name:           "merged cherry-pick closes the tracker",
+			action:         github.PullRequestActionClosed,
+			merged:         true,
+			number:         7,
+			base:           "release-1.20",
+			body:           "This is an automated cherry-pick of #5\n\n/assign author",
+			existing:       []*github.Issue{trackingIssue(1, "open", "release-1.20", time.Time{}), trackingIssue(2, "open", "release-1.19", time.Time{})},
+			expectTrackers: []string{"Backport #5 to release-1.19", "Backport #5 to release-1.20"},
+			expectComments: []string{"org/repo#1:#5 was backported to `release-1.20` in #7."},
+			expectClosed:   []int{1},
+		},
+		{
+			name:           "merged cherry-pick to another branch leaves the tracker open",
+			action:         github.PullRequestActionClosed,
+			merged:         true,
+			number:         7,
+			base:           "release-1.18",
+			body:           "Cherry pick of #5 on release-1.18.",
+			existing:       []*github.Issue{trackingIssue(1, "open", "release-1.20", time.Time{})},
+			expectTrackers: []string{"Backport #5 to release-1.20"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.Issues = map[int]*github.Issue{}
+			for _, issue := range tc.existing {
+				fc.Issues[issue.Number] = issue
+				fc.IssueID = issue.Number
+			}
+			base := tc.base
+			if base == "" {
+				base = "master"
+			}
+			pre := github.PullRequestEvent{
+				Action: tc.action,
+				Repo:   github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+				Label:  github.Label{Name: tc.label},
+				PullRequest: github.PullRequest{
+					Number:  tc.number,
+					Merged:  tc.merged,
+					Body:    tc.body,
+					Base:    github.PullRequestBranch{Ref: base},
+					User:    github.User{Login: "author"},
+					HTMLURL: "https://github.com/org/repo/pull/5",
+				},
+			}
+			for _, l := range tc.labels {
+				pre.PullRequest.Labels = append(pre.PullRequest.Labels, github.Label{Name: l})
+			}
+			if err := handle(logrus.WithField("plugin", PluginName), fc, opts, pre); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var trackers []string
+			var closed []int
+			for number, issue := range fc.Issues {
+				trackers = append(trackers, issue.Title)
+				if issue.State == "closed" {
+					closed = append(closed, number)
+				}
+			}
+			sort.Strings(trackers)
+			if diff := cmp.Diff(tc.expectTrackers, trackers); diff != "" {
+				t.Errorf("trackers differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectComments, fc.IssueCommentsAdded); diff != "" {
+				t.Errorf("comments differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectClosed, closed); diff != "" {
+				t.Errorf("closed issues differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	cases := []struct {
+		name          string
+		enabledFor    string
+		state         string
+		inactive      time.Duration
+		expectComment bool
+	}{
+		{
+			name:     "recently active tracker is left alone",
+			inactive: 3 * day,
+		},
+		{
+			name:          "inactive tracker pings the assignees",
+			inactive:      8 * day,
+			expectComment: true,
+		},
+		{
+			name:     "closed tracker is left alone",
+			state:    "closed",
+			inactive: 8 * day,
+		},
+		{
+			name:       "tracker is left alone if the plugin is not enabled",
+			enabledFor: "org/other-repo",
+			inactive:   8 * day,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := tc.state
+			if state == "" {
+				state = "open"
+			}
+			fc := fakegithub.NewFakeClient()
+			fc.Issues = map[int]*github.Issue{1: trackingIssue(1, state, "release-1.20", now.Add(-tc.inactive))}
+			enabledFor := tc.enabledFor
+			if enabledFor == "" {
+				enabledFor = "org/repo"
+			}
+			pc := &plugins.Configuration{
+				Plugins: plugins.Plugins{enabledFor: {Plugins: []string{PluginName}}},
+				BackportTracker: []plugins.BackportTracker{{
+					Repos:                []string{"org"},
+					LabelPrefix:          "needs-backport/",
+					PingIntervalDuration: 7 * day,
+				}},
+			}
+			if err := ping(logrus.WithField("plugin", PluginName), fc, pc, now); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var expected []string
+			if tc.expectComment {
+				expected = []string{"org/repo#1:@author: #5 has still not been cherry-picked to `release-1.20`. Please open a cherry-pick, e.g. by commenting `/cherrypick release-1.20` on #5."}
+			}
+			if diff := cmp.Diff(expected, fc.IssueCommentsAdded); diff != "" {
+				t.Errorf("comments differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	bt := plugins.BackportTracker{PingIntervalDuration: 168 * time.Hour}
+	expected := `is:open is:issue repo:org/repo label:"tracking/backport" updated:<2021-02-22T00:00:00Z`
+	if got := searchQuery(bt, "org/repo", now); got != expected {
+		t.Errorf("expected query %q, got %q", expected, got)
+	}
+}
+
+func TestParseMarker(t *testing.T) {
+	expected := tracker{org: "org", repo: "repo", number: 5, branch: "release-1.20"}
+	got, ok := parseMarker("some text\n\n" + expected.marker())
+	if !ok {
+		t.Fatal("expected the marker to be found")
+	}
+	if got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if _, ok := parseMarker("no marker here"); ok {
+		t.Error("expected no marker to be found")
+	}
+}
@@ -19,6 +19,7 @@ package blunderbuss
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"regexp"
 
 	githubql "github.com/shurcooL/githubv4"
@@ -125,6 +126,7 @@ type githubClient interface {
 	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
 	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
 	Query(context.Context, interface{}, map[string]interface{}) error
+	FindIssues(query, sort string, asc bool) ([]github.Issue, error)
 }
 
 type repoownersClient interface {
@@ -159,6 +161,8 @@ func handlePullRequest(ghc githubClient, roc repoownersClient, log *logrus.Entry
 		config.MaxReviewerCount,
 		config.ExcludeApprovers,
 		config.UseStatusAvailability,
+		config.UseReviewLoad,
+		config.MaxConcurrentReviews,
 		repo,
 		pr,
 	)
@@ -201,12 +205,14 @@ func handleGenericComment(ghc githubClient, roc repoownersClient, log *logrus.En
 		config.MaxReviewerCount,
 		config.ExcludeApprovers,
 		config.UseStatusAvailability,
+		config.UseReviewLoad,
+		config.MaxConcurrentReviews,
 		repo,
 		pr,
 	)
 }
 
-func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, reviewerCount *int, maxReviewers int, excludeApprovers bool, useStatusAvailability bool, repo *github.Repo, pr *github.PullRequest) error {
+func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, reviewerCount *int, maxReviewers int, excludeApprovers bool, useStatusAvailability bool, useReviewLoad bool, maxConcurrentReviews int, repo *github.Repo, pr *github.PullRequest) error {
 	oc, err := roc.LoadRepoOwners(repo.Owner.Login, repo.Name, pr.Base.Ref)
 	if err != nil {
 		return fmt.Errorf("error loading RepoOwners: %w", err)
@@ -217,10 +223,15 @@ func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, reviewerC
 		return fmt.Errorf("error getting PR changes: %w", err)
 	}
 
+	var load *reviewLoad
+	if useReviewLoad {
+		load = newReviewLoad(ghc, maxConcurrentReviews)
+	}
+
 	var reviewers []string
 	var requiredReviewers []string
 	if reviewerCount != nil {
-		reviewers, requiredReviewers, err = getReviewers(oc, ghc, log, pr.User.Login, changes, *reviewerCount, useStatusAvailability)
+		reviewers, requiredReviewers, err = getReviewers(oc, ghc, log, pr.User.Login, changes, *reviewerCount, useStatusAvailability, load)
 		if err != nil {
 			return err
 		}
@@ -231,7 +242,7 @@ func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, reviewerC
 				// and approvers and the search might stop too early if it finds
 				// duplicates.
 				frc := fallbackReviewersClient{ownersClient: oc}
-				approvers, _, err := getReviewers(frc, ghc, log, pr.User.Login, changes, *reviewerCount, useStatusAvailability)
+				approvers, _, err := getReviewers(frc, ghc, log, pr.User.Login, changes, *reviewerCount, useStatusAvailability, load)
 				if err != nil {
 					return err
 				}
@@ -267,7 +278,7 @@ func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, reviewerC
 	return nil
 }
 
-func getReviewers(rc reviewersClient, ghc githubClient, log *logrus.Entry, author string, files []github.PullRequestChange, minReviewers int, useStatusAvailability bool) ([]string, []string, error) {
+func getReviewers(rc reviewersClient, ghc githubClient, log *logrus.Entry, author string, files []github.PullRequestChange, minReviewers int, useStatusAvailability bool, load *reviewLoad) ([]string, []string, error) {
 	authorSet := sets.NewString(github.NormLogin(author))
 	reviewers := layeredsets.NewString()
 	requiredReviewers := sets.NewString()
@@ -293,14 +304,14 @@ func getReviewers(rc reviewersClient, ghc githubClient, log *logrus.Entry, autho
 			continue
 		}
 		leafReviewers = leafReviewers.Union(fileUnusedLeafs)
-		if r := findReviewer(ghc, log, useStatusAvailability, &busyReviewers, &fileUnusedLeafs); r != "" {
+		if r := findReviewer(ghc, log, useStatusAvailability, load, &busyReviewers, &fileUnusedLeafs); r != "" {
 			reviewers.Insert(0, r)
 		}
 	}
 	// now ensure that we request review from at least minReviewers reviewers. Favor leaf reviewers.
 	unusedLeafs := leafReviewers.Difference(reviewers.Set())
 	for reviewers.Len() < minReviewers && unusedLeafs.Len() > 0 {
-		if r := findReviewer(ghc, log, useStatusAvailability, &busyReviewers, &unusedLeafs); r != "" {
+		if r := findReviewer(ghc, log, useStatusAvailability, load, &busyReviewers, &unusedLeafs); r != "" {
 			reviewers.Insert(1, r)
 		}
 	}
@@ -310,7 +321,7 @@ func getReviewers(rc reviewersClient, ghc githubClient, log *logrus.Entry, autho
 		}
 		fileReviewers := rc.Reviewers(file.Filename).Difference(authorSet)
 		for reviewers.Len() < minReviewers && fileReviewers.Len() > 0 {
-			if r := findReviewer(ghc, log, useStatusAvailability, &busyReviewers, &fileReviewers); r != "" {
+			if r := findReviewer(ghc, log, useStatusAvailability, load, &busyReviewers, &fileReviewers); r != "" {
 				reviewers.Insert(2, r)
 			}
 		}
@@ -319,10 +330,10 @@ func getReviewers(rc reviewersClient, ghc githubClient, log *logrus.Entry, autho
 }
 
 // findReviewer finds a reviewer from a set, potentially using status
-// availability.
-func findReviewer(ghc githubClient, log *logrus.Entry, useStatusAvailability bool, busyReviewers *sets.String, targetSet *layeredsets.String) string {
-	// if we don't care about status availability, just pop a target from the set
-	if !useStatusAvailability {
+// availability and review load.
+func findReviewer(ghc githubClient, log *logrus.Entry, useStatusAvailability bool, load *reviewLoad, busyReviewers *sets.String, targetSet *layeredsets.String) string {
+	// if we don't care about status availability or review load, just pop a target from the set
+	if !useStatusAvailability && load == nil {
 		return targetSet.PopRandom()
 	}
 
@@ -332,11 +343,24 @@ func findReviewer(ghc githubClient, log *logrus.Entry, useStatusAvailability boo
 			// if there are no candidates left, then break
 			break
 		}
-		candidate := targetSet.PopRandom()
+		var candidate string
+		if load != nil {
+			candidate = load.popLeastLoaded(log, targetSet)
+		} else {
+			candidate = targetSet.PopRandom()
+		}
 		if busyReviewers.Has(candidate) {
 			// we've already verified this reviewer is busy
 			continue
 		}
+		if load != nil && load.isOverloaded(candidate) {
+			log.WithField("user", candidate).Debug("User has too many pending reviews")
+			busyReviewers.Insert(candidate)
+			continue
+		}
+		if !useStatusAvailability {
+			return candidate
+		}
 		busy, err := isUserBusy(ghc, candidate)
 		if err != nil {
 			log.WithField("user", candidate).WithError(err).Error("Error checking user availability")
@@ -369,3 +393,79 @@ func isUserBusy(ghc githubClient, user string) (bool, error) {
 	err := ghc.Query(ctx, &query, vars)
 	return bool(query.User.Status.IndicatesLimitedAvailability), err
 }
+
+// maxReviewLoadLookups bounds the review loads looked up for a PR, as GitHub
+// only allows 30 search API requests per minute.
+const maxReviewLoadLookups = 10
+
+// reviewLoad looks up and caches the number of open PRs on which each user
+// has a pending review request.
+type reviewLoad struct {
+	ghc     githubClient
+	max     int
+	lookups int
+	pending map[string]int
+}
+
+func newReviewLoad(ghc githubClient, maxConcurrentReviews int) *reviewLoad {
+	return &reviewLoad{ghc: ghc, max: maxConcurrentReviews, pending: map[string]int{}}
+}
+
+// count returns the number of open PRs awaiting a review from the user, and
+// whether it is known. The load is unknown if looking it up failed, which is
+// retried for the next candidate list, or once all lookups are used up.
+func (rl *reviewLoad) count(log *logrus.Entry, user string) (int, bool) {
+	if n, ok := rl.pending[user]; ok {
+		return n, true
+	}
+	if rl.lookups >= maxReviewLoadLookups {
+		return 0, false
+	}
+	rl.lookups++
+	issues, err := rl.ghc.FindIssues(fmt.Sprintf("is:pr is:open review-requested:%s", user), "", false)
+	if err != nil {
+		log.WithField("user", user).WithError(err).Error("Error checking user review load")
+		return 0, false
+	}
+	rl.pending[user] = len(issues)
+	return len(issues), true
+}
+
+// isOverloaded returns whether the user is known to have at least the
+// maximum number of pending reviews.
+func (rl *reviewLoad) isOverloaded(user string) bool {
+	n, ok := rl.pending[user]
+	return rl.max > 0 && ok && n >= rl.max
+}
+
+// popLeastLoaded pops the candidate with the fewest pending reviews from
+// the first non-empty layer of the set, breaking ties randomly. Candidates
+// whose load is unknown are only picked if no load in the layer is known.
+func (rl *reviewLoad) popLeastLoaded(log *logrus.Entry, targetSet *layeredsets.String) string {
+	for _, layer := range *targetSet {
+		if layer.Len() == 0 {
+			continue
+		}
+		var leastLoaded, unknown []string
+		least := -1
+		for _, candidate := range layer.List() {
+			n, known := rl.count(log, candidate)
+			switch {
+			case !known:
+				unknown = append(unknown, candidate)
+			case least == -1 || n < least:
+				least = n
+				leastLoaded = []string{candidate}
+			case n == least:
+				leastLoaded = append(leastLoaded, candidate)
+			}
+		}
+		if len(leastLoaded) == 0 {
+			leastLoaded = unknown
+		}
+		sel := leastLoaded[rand.Intn(len(leastLoaded))]
+		targetSet.Delete(sel)
+		return sel
+	}
+	return ""
+}
@@ -19,6 +19,7 @@ package blunderbuss
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"reflect"
@@ -44,6 +45,10 @@ type fakeGitHubClient struct {
 	pr        *github.PullRequest
 	changes   []github.PullRequestChange
 	requested []string
+	// pendingReviews is the number of open PRs awaiting a review from each user
+	pendingReviews map[string]int
+	// reviewLoadLookups counts the searches for the review load of each user
+	reviewLoadLookups map[string]int
 }
 
 func newFakeGitHubClient(pr *github.PullRequest, filesChanged []string) *fakeGitHubClient {
@@ -97,6 +102,20 @@ func (c *fakeGitHubClient) Query(ctx context.Context, q interface{}, vars map[st
 	return nil
 }
 
+func (c *fakeGitHubClient) FindIssues(query, sort string, asc bool) ([]github.Issue, error) {
+	user := strings.TrimPrefix(query, "is:pr is:open review-requested:")
+	if user == query {
+		return nil, errors.New("unexpected query")
+	}
+	if c.reviewLoadLookups != nil {
+		c.reviewLoadLookups[user]++
+	}
+	if c.pendingReviews[user] < 0 {
+		return nil, errors.New("injected search error")
+	}
+	return make([]github.Issue, c.pendingReviews[user]), nil
+}
+
 type fakeRepoownersClient struct {
 	foc *fakeOwnersClient
 }
@@ -347,7 +366,7 @@ func TestHandleWithExcludeApproversOnlyReviewers(t *testing.T) {
 
 		if err := handle(
 			fghc, froc, logrus.WithField("plugin", PluginName),
-			&tc.reviewerCount, tc.maxReviewerCount, true, false, &repo, &pr,
+			&tc.reviewerCount, tc.maxReviewerCount, true, false, false, 0, &repo, &pr,
 		); err != nil {
 			t.Errorf("[%s] unexpected error from handle: %v", tc.name, err)
 			continue
@@ -389,7 +408,7 @@ func TestHandleWithoutExcludeApproversNoReviewers(t *testing.T) {
 
 		if err := handle(
 			fghc, froc, logrus.WithField("plugin", PluginName),
-			&tc.reviewerCount, tc.maxReviewerCount, false, false, &repo, &pr,
+			&tc.reviewerCount, tc.maxReviewerCount, false, false, false, 0, &repo, &pr,
 		); err != nil {
 			t.Errorf("[%s] unexpected error from handle: %v", tc.name, err)
 			continue
@@ -534,7 +553,7 @@ func TestHandleWithoutExcludeApproversMixed(t *testing.T) {
 		fghc := newFakeGitHubClient(&pr, tc.filesChanged)
 		if err := handle(
 			fghc, froc, logrus.WithField("plugin", PluginName),
-			&tc.reviewerCount, tc.maxReviewerCount, false, false, &repo, &pr,
+			&tc.reviewerCount, tc.maxReviewerCount, false, false, false, 0, &repo, &pr,
 		); err != nil {
 			t.Errorf("[%s] unexpected error from handle: %v", tc.name, err)
 			continue
@@ -861,7 +880,7 @@ func TestPopActiveReviewer(t *testing.T) {
 		fghc := newFakeGitHubClient(&pr, tc.filesChanged)
 		if err := handle(
 			fghc, froc, logrus.WithField("plugin", PluginName),
-			&tc.reviewerCount, tc.maxReviewerCount, false, true, &repo, &pr,
+			&tc.reviewerCount, tc.maxReviewerCount, false, true, false, 0, &repo, &pr,
 		); err != nil {
 			t.Errorf("[%s] unexpected error from handle: %v", tc.name, err)
 			continue
@@ -881,3 +900,126 @@ func TestPopActiveReviewer(t *testing.T) {
 		}
 	}
 }
+
+// TestReviewLoad checks that the reviewers with the fewest pending reviews
+// are favored, and that reviewers with too many are never requested.
+func TestReviewLoad(t *testing.T) {
+	froc := &fakeRepoownersClient{
+		foc: &fakeOwnersClient{
+			owners: map[string]string{
+				"a.go": "1",
+				"b.go": "2",
+			},
+			reviewers: map[string]layeredsets.String{
+				"a.go": layeredsets.NewString("alice", "anne", "art"),
+				"b.go": layeredsets.NewString("bob", "bill"),
+			},
+			leafReviewers: map[string]sets.String{
+				"a.go": sets.NewString("alice", "anne", "art"),
+				"b.go": sets.NewString("bob", "bill"),
+			},
+		},
+	}
+	pendingReviews := map[string]int{
+		"alice": 3,
+		"anne":  1,
+		"art":   2,
+		"bob":   5,
+		"bill":  6,
+	}
+
+	var testcases = []struct {
+		name                 string
+		filesChanged         []string
+		reviewerCount        int
+		maxConcurrentReviews int
+		expectedRequested    []string
+	}{
+		{
+			name:              "request one reviewer, get the least loaded one",
+			filesChanged:      []string{"a.go"},
+			reviewerCount:     1,
+			expectedRequested: []string{"anne"},
+		},
+		{
+			name:              "request two reviewers, get the two least loaded ones",
+			filesChanged:      []string{"a.go"},
+			reviewerCount:     2,
+			expectedRequested: []string{"anne", "art"},
+		},
+		{
+			name:              "one reviewer per OWNERS file is the least loaded one",
+			filesChanged:      []string{"a.go", "b.go"},
+			reviewerCount:     2,
+			expectedRequested: []string{"anne", "bob"},
+		},
+		{
+			name:                 "reviewers with too many pending reviews are skipped",
+			filesChanged:         []string{"a.go", "b.go"},
+			reviewerCount:        5,
+			maxConcurrentReviews: 3,
+			expectedRequested:    []string{"anne", "art"},
+		},
+	}
+	for _, tc := range testcases {
+		pr := github.PullRequest{Number: 5, User: github.User{Login: "author"}}
+		repo := github.Repo{Owner: github.User{Login: "org"}, Name: "repo"}
+		fghc := newFakeGitHubClient(&pr, tc.filesChanged)
+		fghc.pendingReviews = pendingReviews
+		if err := handle(
+			fghc, froc, logrus.WithField("plugin", PluginName),
+			&tc.reviewerCount, 0, true, false, true, tc.maxConcurrentReviews, &repo, &pr,
+		); err != nil {
+			t.Errorf("[%s] unexpected error from handle: %v", tc.name, err)
+			continue
+		}
+
+		sort.Strings(fghc.requested)
+		sort.Strings(tc.expectedRequested)
+		if !reflect.DeepEqual(fghc.requested, tc.expectedRequested) {
+			t.Errorf("[%s] expected the requested reviewers to be %q, but got %q.", tc.name, tc.expectedRequested, fghc.requested)
+		}
+	}
+}
+
+// TestReviewLoadLookups checks that review load lookups are bounded and that
+// failed lookups are not cached.
+func TestReviewLoadLookups(t *testing.T) {
+	fghc := newFakeGitHubClient(&github.PullRequest{}, nil)
+	fghc.pendingReviews = map[string]int{"flaky": -1, "alice": 2}
+	fghc.reviewLoadLookups = map[string]int{}
+	load := newReviewLoad(fghc, 2)
+	log := logrus.WithField("plugin", PluginName)
+
+	for i := 0; i < 2; i++ {
+		if n, known := load.count(log, "alice"); n != 2 || !known {
+			t.Errorf("expected a known load of 2 for alice, got %d (known: %t)", n, known)
+		}
+		if _, known := load.count(log, "flaky"); known {
+			t.Error("expected the load of flaky to be unknown")
+		}
+	}
+	if lookups := fghc.reviewLoadLookups["alice"]; lookups != 1 {
+		t.Errorf("expected the load of alice to be looked up once, got %d", lookups)
+	}
+	if lookups := fghc.reviewLoadLookups["flaky"]; lookups != 2 {
+		t.Errorf("expected the failed lookup for flaky to be retried, got %d lookups", lookups)
+	}
+	if !load.isOverloaded("alice") {
+		t.Error("expected alice to be overloaded at the maximum number of pending reviews")
+	}
+	if load.isOverloaded("flaky") {
+		t.Error("expected flaky with an unknown load not to be overloaded")
+	}
+
+	for i := 0; i < maxReviewLoadLookups; i++ {
+		load.count(log, fmt.Sprintf("user-%d", i))
+	}
+	var total int
+	for _, lookups := range fghc.reviewLoadLookups {
+		total += lookups
+	}
+	if total != maxReviewLoadLookups {
+		t.Errorf("expected %d lookups in total, got %d", maxReviewLoadLookups, total)
+	}
+}
@@ -0,0 +1,345 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/pluginhelp"
+)
+
+var (
+	pluginCommands = map[string][]Command{}
+
+	commandRe      = regexp.MustCompile(`(?m)^/([\w-]+)([ \t]+[^\r\n]*)?\r?$`)
+	commandNameRe  = regexp.MustCompile(`^[\w-]+$`)
+	commandAliasRe = regexp.MustCompile(`(?m)^/([\w-]+)`)
+	// HelpCommandRe matches the /help command listing the commands of a repo.
+	// On issues, /help asks for help so the commands are only listed with
+	// /help commands.
+	HelpCommandRe = regexp.MustCompile(`(?mi)^/help(\s+commands)?\s*$`)
+)
+
+// CommandHandler handles an invocation of a command in a comment.
+type CommandHandler func(Agent, github.GenericCommentEvent, CommandInvocation) error
+
+// Command declares a comment command of a plugin, like /hold. The plugins
+// framework matches commands in comments, validates their arguments and
+// generates their help.
+type Command struct {
+	// Name is the name of the command, /Name invokes it.
+	Name string
+	// Aliases are other names invoking the command.
+	Aliases []string
+	// Args are the positional arguments of the command.
+	Args []CommandArg
+	// Flags are the named arguments of the command, given as --name,
+	// --name=value or --name value.
+	Flags []CommandFlag
+
+	// Description, WhoCanUse, Examples and Featured are the help of the
+	// command, see pluginhelp.Command.
+	Description string
+	WhoCanUse   string
+	Examples    []string
+	Featured    bool
+
+	// Handler handles the invocations of the command in the new comments of
+	// the repos its plugin is enabled for. Invalid invocations are answered
+	// with the usage of the command. Commands without a handler are only
+	// declared, their plugin matches them with ParseCommands.
+	Handler CommandHandler
+}
+
+// CommandArg is a positional argument of a command.
+type CommandArg struct {
+	// Name is the name of the argument in the usage of the command.
+	Name string
+	// Optional arguments may be omitted, they must follow the required ones.
+	Optional bool
+	// Variadic arguments take all the remaining words, they must be last.
+	Variadic bool
+	// Values are the values the argument may take, any if empty. They are
+	// matched case insensitively.
+	Values []string
+}
+
+// CommandFlag is a named argument of a command.
+type CommandFlag struct {
+	// Name is the name of the flag, without the leading --.
+	Name string
+	// Value is the name of the value of the flag in the usage of the command.
+	// Flags without a value are booleans.
+	Value string
+	// Description describes the flag in the help of the command.
+	Description string
+}
+
+// CommandInvocation is an invocation of a command in a comment.
+type CommandInvocation struct {
+	// Command is the invoked command.
+	Command *Command
+	// Name is the name the command was invoked with, its name or an alias.
+	Name string
+	// Args are the arguments, by name. Variadic arguments are joined with
+	// spaces.
+	Args map[string]string
+	// Flags are the flags, by name. Boolean flags are "true".
+	Flags map[string]string
+}
+
+// Arg returns the argument of the name, "" if it was omitted.
+func (i CommandInvocation) Arg(name string) string {
+	return i.Args[name]
+}
+
+// Flag returns the flag of the name, "" if it was omitted.
+func (i CommandInvocation) Flag(name string) string {
+	return i.Flags[name]
+}
+
+// CommandError is an invalid invocation of a command.
+type CommandError struct {
+	Command *Command
+	Message string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s. Usage: %s", e.Message, e.Command.Usage())
+}
+
+// names returns the names invoking the command.
+func (c *Command) names() []string {
+	return append([]string{c.Name}, c.Aliases...)
+}
+
+// Usage returns the usage of the command, like /hold [cancel].
+func (c *Command) Usage() string {
+	parts := []string{"/" + strings.Join(c.names(), "|/")}
+	for _, arg := range c.Args {
+		name := "<" + arg.Name + ">"
+		if len(arg.Values) > 0 {
+			name = strings.Join(arg.Values, "|")
+			if !arg.Optional {
+				name = "<" + name + ">"
+			}
+		}
+		if arg.Variadic {
+			name += "..."
+		}
+		if arg.Optional {
+			name = "[" + name + "]"
+		}
+		parts = append(parts, name)
+	}
+	for _, flag := range c.Flags {
+		if flag.Value == "" {
+			parts = append(parts, "[--"+flag.Name+"]")
+		} else {
+			parts = append(parts, "[--"+flag.Name+" <"+flag.Value+">]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Help returns the help of the command.
+func (c *Command) Help() pluginhelp.Command {
+	description := c.Description
+	for _, flag := range c.Flags {
+		description += fmt.Sprintf("\n`--%s`: %s", flag.Name, flag.Description)
+	}
+	return pluginhelp.Command{
+		Usage:       c.Usage(),
+		Featured:    c.Featured,
+		Description: description,
+		Examples:    c.Examples,
+		WhoCanUse:   c.WhoCanUse,
+	}
+}
+
+func (c *Command) flag(name string) *CommandFlag {
+	for i := range c.Flags {
+		if c.Flags[i].Name == name {
+			return &c.Flags[i]
+		}
+	}
+	return nil
+}
+
+// parse parses the words following the command.
+func (c *Command) parse(name string, words []string) (CommandInvocation, error) {
+	invocation := CommandInvocation{Command: c, Name: name, Args: map[string]string{}, Flags: map[string]string{}}
+	var positional []string
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if !strings.HasPrefix(word, "--") || word == "--" {
+			positional = append(positional, word)
+			continue
+		}
+		flagName, value := strings.TrimPrefix(word, "--"), ""
+		hasValue := false
+		if idx := strings.Index(flagName, "="); idx >= 0 {
+			flagName, value, hasValue = flagName[:idx], flagName[idx+1:], true
+		}
+		flag := c.flag(flagName)
+		if flag == nil {
+			return invocation, &CommandError{Command: c, Message: fmt.Sprintf("Unknown flag --%s", flagName)}
+		}
+		switch {
+		case flag.Value == "" && hasValue:
+			return invocation, &CommandError{Command: c, Message: fmt.Sprintf("Flag --%s doesn't take a value", flagName)}
+		case flag.Value == "":
+			value = "true"
+		case !hasValue && i+1 < len(words):
+			i++
+			value = words[i]
+		case !hasValue:
+			return invocation, &CommandError{Command: c, Message: fmt.Sprintf("Flag --%s requires a value", flagName)}
+		}
+		invocation.Flags[flagName] = value
+	}
+
+	for i, arg := range c.Args {
+		if i >= len(positional) {
+			if !arg.Optional {
+				return invocation, &CommandError{Command: c, Message: fmt.Sprintf("Missing argument <%s>", arg.Name)}
+			}
+			break
+		}
+		value := positional[i]
+		if arg.Variadic {
+			value = strings.Join(positional[i:], " ")
+		} else if len(arg.Values) > 0 {
+			valid := false
+			for _, v := range arg.Values {
+				if strings.EqualFold(v, value) {
+					value, valid = v, true
+					break
+				}
+			}
+			if !valid {
+				return invocation, &CommandError{Command: c, Message: fmt.Sprintf("Invalid argument <%s>: %q is not one of %s", arg.Name, value, strings.Join(arg.Values, ", "))}
+			}
+		}
+		invocation.Args[arg.Name] = value
+	}
+	if len(positional) > len(c.Args) && (len(c.Args) == 0 || !c.Args[len(c.Args)-1].Variadic) {
+		return invocation, &CommandError{Command: c, Message: fmt.Sprintf("Unexpected argument %q", positional[len(c.Args)])}
+	}
+	return invocation, nil
+}
+
+// ParseCommands returns the invocations of the commands in the lines of the
+// body starting with a slash, in order, and the errors of their invalid
+// invocations. Command names are matched case insensitively.
+func ParseCommands(body string, commands []Command) ([]CommandInvocation, []error) {
+	var invocations []CommandInvocation
+	var errs []error
+	for _, match := range commandRe.FindAllStringSubmatch(body, -1) {
+		name := strings.ToLower(match[1])
+		for i := range commands {
+			c := &commands[i]
+			found := false
+			for _, n := range c.names() {
+				found = found || strings.ToLower(n) == name
+			}
+			if !found {
+				continue
+			}
+			invocation, err := c.parse(name, strings.Fields(match[2]))
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				invocations = append(invocations, invocation)
+			}
+			break
+		}
+	}
+	return invocations, errs
+}
+
+// ExpandCommandAliases rewrites the aliases of commands at the start of the
+// lines of the body into the commands they invoke, see
+// Configuration.CommandAliasesFor. The aliases are matched case
+// insensitively.
+func ExpandCommandAliases(body string, aliases map[string]string) string {
+	if len(aliases) == 0 {
+		return body
+	}
+	return commandAliasRe.ReplaceAllStringFunc(body, func(match string) string {
+		if command, ok := aliases[strings.ToLower(match[1:])]; ok {
+			return "/" + command
+		}
+		return match
+	})
+}
+
+// RegisterCommands declares the comment commands of a plugin. Hook calls the
+// handlers of the commands invoked in the comments of the repos the plugin is
+// enabled for and lists the commands with /help. The help of the commands is
+// part of the help of the plugin.
+func RegisterCommands(name string, commands ...Command) {
+	pluginCommands[name] = append(pluginCommands[name], commands...)
+	if _, ok := pluginHelp[name]; !ok {
+		pluginHelp[name] = func(*Configuration, []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+			return &pluginhelp.PluginHelp{}, nil
+		}
+	}
+}
+
+// CommandsForPlugin returns the commands registered by the plugin.
+func CommandsForPlugin(name string) []Command {
+	return pluginCommands[name]
+}
+
+// Commands returns the commands of the plugins enabled for the repo, by plugin.
+func (pa *ConfigAgent) Commands(owner, repo string) map[string][]Command {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+
+	commands := map[string][]Command{}
+	for _, p := range pa.getPlugins(owner, repo) {
+		if cs, ok := pluginCommands[p]; ok {
+			commands[p] = cs
+		}
+	}
+	return commands
+}
+
+// FormatCommandsHelp formats the commands of plugins, by plugin, as the answer
+// to the /help command.
+func FormatCommandsHelp(commands map[string][]Command) string {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString("These commands are available in this repository:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n**%s**\n", name)
+		for _, c := range commands[name] {
+			fmt.Fprintf(&b, "- `%s`: %s\n", c.Usage(), c.Description)
+		}
+	}
+	return b.String()
+}
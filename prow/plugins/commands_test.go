@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+var testCommands = []Command{
+	{
+		Name:    "hold",
+		Aliases: []string{"wait"},
+		Args:    []CommandArg{{Name: "cancel", Optional: true, Values: []string{"cancel"}}},
+	},
+	{
+		Name:  "assign",
+		Args:  []CommandArg{{Name: "users", Variadic: true}},
+		Flags: []CommandFlag{{Name: "team", Value: "name"}, {Name: "notify"}},
+	},
+}
+
+func TestParseCommands(t *testing.T) {
+	testCases := []struct {
+		name        string
+		body        string
+		expected    []CommandInvocation
+		expectedErr []string
+	}{
+		{
+			name: "no command",
+			body: "hold on, /hold is not a command here",
+		},
+		{
+			name: "command without arguments",
+			body: "/hold",
+			expected: []CommandInvocation{
+				{Name: "hold", Args: map[string]string{}, Flags: map[string]string{}},
+			},
+		},
+		{
+			name: "alias with an argument of another case",
+			body: "LGTM\r\n/WAIT Cancel\r\n",
+			expected: []CommandInvocation{
+				{Name: "wait", Args: map[string]string{"cancel": "cancel"}, Flags: map[string]string{}},
+			},
+		},
+		{
+			name: "variadic argument and flags",
+			body: "/assign --team=sig-testing alice  bob --notify\n/unknown",
+			expected: []CommandInvocation{
+				{Name: "assign", Args: map[string]string{"users": "alice bob"}, Flags: map[string]string{"team": "sig-testing", "notify": "true"}},
+			},
+		},
+		{
+			name: "flag value as the next word",
+			body: "/assign --team sig-testing alice",
+			expected: []CommandInvocation{
+				{Name: "assign", Args: map[string]string{"users": "alice"}, Flags: map[string]string{"team": "sig-testing"}},
+			},
+		},
+		{
+			name: "invalid invocations",
+			body: "/hold forever\n/assign\n/assign --team\n/assign --notify=yes alice\n/assign --force alice\n/hold cancel now",
+			expectedErr: []string{
+				`Invalid argument <cancel>: "forever" is not one of cancel. Usage: /hold|/wait [cancel]`,
+				"Missing argument <users>. Usage: /assign <users>... [--team <name>] [--notify]",
+				"Flag --team requires a value. Usage: /assign <users>... [--team <name>] [--notify]",
+				"Flag --notify doesn't take a value. Usage: /assign <users>... [--team <name>] [--notify]",
+				"Unknown flag --force. Usage: /assign <users>... [--team <name>] [--notify]",
+				`Unexpected argument "now". Usage: /hold|/wait [cancel]`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			invocations, errs := ParseCommands(tc.body, testCommands)
+			if diff := cmp.Diff(tc.expected, invocations, cmpopts.IgnoreFields(CommandInvocation{}, "Command")); diff != "" {
+				t.Errorf("invocations differ from expected: %s", diff)
+			}
+			var actualErr []string
+			for _, err := range errs {
+				actualErr = append(actualErr, err.Error())
+			}
+			if diff := cmp.Diff(tc.expectedErr, actualErr); diff != "" {
+				t.Errorf("errors differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestFormatCommandsHelp(t *testing.T) {
+	testCommands[0].Description = "Holds the PR."
+	testCommands[1].Description = "Assigns users."
+	defer func() {
+		testCommands[0].Description, testCommands[1].Description = "", ""
+	}()
+	expected := "These commands are available in this repository:\n" +
+		"\n**assign**\n" +
+		"- `/assign <users>... [--team <name>] [--notify]`: Assigns users.\n" +
+		"\n**hold**\n" +
+		"- `/hold|/wait [cancel]`: Holds the PR.\n"
+	actual := FormatCommandsHelp(map[string][]Command{"hold": testCommands[:1], "assign": testCommands[1:]})
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("help differs from expected: %s", diff)
+	}
+}
+
+func TestExpandCommandAliases(t *testing.T) {
+	aliases := map[string]string{"ship-it": "lgtm", "freeze": "lifecycle frozen"}
+	testCases := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "alias is expanded",
+			body:     "/ship-it",
+			expected: "/lgtm",
+		},
+		{
+			name:     "arguments are kept and aliases are case insensitive",
+			body:     "Looks good.\n/Ship-It no-issue\r\n/FREEZE",
+			expected: "Looks good.\n/lgtm no-issue\r\n/lifecycle frozen",
+		},
+		{
+			name:     "aliases only match whole commands at the start of lines",
+			body:     "/ship-items\nplease /ship-it\n/ship",
+			expected: "/ship-items\nplease /ship-it\n/ship",
+		},
+		{
+			name:     "other commands are kept",
+			body:     "/hold",
+			expected: "/hold",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := ExpandCommandAliases(tc.body, aliases); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
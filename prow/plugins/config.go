@@ -20,11 +20,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"net/url"
 	"path"
 	"reflect"
 	"regexp"
 	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	"sigs.k8s.io/yaml"
@@ -36,6 +39,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"k8s.io/test-infra/prow/bugzilla"
+	"k8s.io/test-infra/prow/gitattributes"
 	"k8s.io/test-infra/prow/kube"
 	"k8s.io/test-infra/prow/labels"
 	"k8s.io/test-infra/prow/logrusutil"
@@ -43,7 +47,9 @@ import (
 )
 
 const (
-	defaultBlunderbussReviewerCount = 2
+	defaultBlunderbussReviewerCount  = 2
+	defaultAssignMaxTeamReviewers    = 5
+	defaultAssignRoundRobinConfigMap = "assign-round-robin"
 )
 
 // Configuration is the top-level serialization target for plugin Configuration.
@@ -59,11 +65,39 @@ type Configuration struct {
 	// external plugins.
 	ExternalPlugins map[string][]ExternalPlugin `json:"external_plugins,omitempty"`
 
+	// ExternalPluginHealth configures the health checks of the external
+	// plugins by hook.
+	ExternalPluginHealth ExternalPluginHealth `json:"external_plugin_health,omitempty"`
+
 	// Owners contains configuration related to handling OWNERS files.
 	Owners Owners `json:"owners,omitempty"`
 
+	// DryRun configures plugins to only report the changes they would make
+	// on GitHub instead of making them.
+	DryRun DryRun `json:"dry_run,omitempty"`
+
+	// Concurrency bounds the concurrent executions of the plugins by hook.
+	Concurrency Concurrency `json:"concurrency,omitempty"`
+
+	// FeatureFlags roll out plugins and plugin behaviors to a part of the
+	// repos, by flag name. A flag named after a plugin gates the plugin: it
+	// only runs on the repos, among those it is enabled for, that the flag
+	// is enabled for.
+	FeatureFlags map[string]FeatureFlag `json:"feature_flags,omitempty"`
+
+	// CommandAliases maps aliases, like ship-it, to the commands they invoke,
+	// like lgtm, by org or org/repo. Hook rewrites the aliases at the start
+	// of the lines of comments before dispatching them, the arguments
+	// following an alias are kept. An alias may invoke a command with
+	// arguments, like "lifecycle frozen". The aliases of an org/repo extend
+	// and override those of its org.
+	CommandAliases map[string]map[string]string `json:"command_aliases,omitempty"`
+
 	// Built-in plugins specific configuration.
+	APIReview            []APIReview                  `json:"api_review,omitempty"`
 	Approve              []Approve                    `json:"approve,omitempty"`
+	Assign               Assign                       `json:"assign,omitempty"`
+	BackportTracker      []BackportTracker            `json:"backport_tracker,omitempty"`
 	Blockades            []Blockade                   `json:"blockades,omitempty"`
 	Blunderbuss          Blunderbuss                  `json:"blunderbuss,omitempty"`
 	Bugzilla             Bugzilla                     `json:"bugzilla,omitempty"`
@@ -72,6 +106,8 @@ type Configuration struct {
 	CherryPickUnapproved CherryPickUnapproved         `json:"cherry_pick_unapproved,omitempty"`
 	ConfigUpdater        ConfigUpdater                `json:"config_updater,omitempty"`
 	Dco                  map[string]*Dco              `json:"dco,omitempty"`
+	DcoCla               map[string]*DcoCla           `json:"dco_cla,omitempty"`
+	Dedupe               []Dedupe                     `json:"dedupe,omitempty"`
 	Golint               Golint                       `json:"golint,omitempty"`
 	Goose                Goose                        `json:"goose,omitempty"`
 	Heart                Heart                        `json:"heart,omitempty"`
@@ -82,13 +118,18 @@ type Configuration struct {
 	RepoMilestone        map[string]Milestone         `json:"repo_milestone,omitempty"`
 	Project              ProjectConfig                `json:"project_config,omitempty"`
 	ProjectManager       ProjectManager               `json:"project_manager,omitempty"`
+	ReleaseNote          []ReleaseNote                `json:"release_note,omitempty"`
 	RequireMatchingLabel []RequireMatchingLabel       `json:"require_matching_label,omitempty"`
+	RequiredReviewers    []RequiredReviewers          `json:"required_reviewers,omitempty"`
 	Retitle              Retitle                      `json:"retitle,omitempty"`
+	SemanticTitle        []SemanticTitle              `json:"semantic_title,omitempty"`
 	Slack                Slack                        `json:"slack,omitempty"`
 	SigMention           SigMention                   `json:"sigmention,omitempty"`
 	Size                 Size                         `json:"size,omitempty"`
+	Stale                []Stale                      `json:"stale,omitempty"`
 	Triggers             []Trigger                    `json:"triggers,omitempty"`
 	Welcome              []Welcome                    `json:"welcome,omitempty"`
+	Wip                  []Wip                        `json:"wip,omitempty"`
 	Override             Override                     `json:"override,omitempty"`
 	Help                 Help                         `json:"help,omitempty"`
 }
@@ -140,6 +181,58 @@ type ExternalPlugin struct {
 	// server to the external plugin. If no events are specified,
 	// everything is sent.
 	Events []string `json:"events,omitempty"`
+	// HealthEndpoint is where hook checks the health of the plugin with GET
+	// requests, the plugin is healthy when it answers with a 2xx status. If
+	// empty, hook sends the requests to the Endpoint and the plugin is
+	// healthy unless it doesn't answer or answers with a 5xx status.
+	HealthEndpoint string `json:"health_endpoint,omitempty"`
+}
+
+// ExternalPluginHealth configures the health checks of the external plugins.
+// A plugin failing UnhealthyThreshold checks in a row is demoted: hook stops
+// forwarding events to it until one of its checks succeeds again.
+type ExternalPluginHealth struct {
+	// Enabled turns the health checks on. They are off by default, all the
+	// external plugins then receive their events.
+	Enabled bool `json:"enabled,omitempty"`
+	// Interval is how often the external plugins are checked. Defaults to "1m".
+	Interval         string        `json:"interval,omitempty"`
+	IntervalDuration time.Duration `json:"-"`
+	// Timeout is how long a check waits for the answer of a plugin.
+	// Defaults to "5s".
+	Timeout         string        `json:"timeout,omitempty"`
+	TimeoutDuration time.Duration `json:"-"`
+	// UnhealthyThreshold is the number of checks in a row a plugin must fail
+	// to be demoted. Defaults to 3.
+	UnhealthyThreshold int `json:"unhealthy_threshold,omitempty"`
+}
+
+// APIReview specifies the configuration of the api-review plugin for a set of
+// repos. PRs changing API files are labeled api-review and a review is
+// requested from the API review team. They are blocked from merging with
+// the do-not-merge/needs-api-review label until a member of the team approves.
+type APIReview struct {
+	// Repos is either of the form org/repos or just org.
+	Repos []string `json:"repos,omitempty"`
+	// Paths are the gitattributes-style globs of the API files, e.g.
+	// "api/**", "*.proto" or "openapi/**".
+	Paths []string `json:"paths,omitempty"`
+	// Team is the slug of the GitHub team, in the org of the repo, that
+	// reviews API changes.
+	Team string `json:"team,omitempty"`
+}
+
+// Assign defines configuration for the assign plugin.
+type Assign struct {
+	// MaxTeamReviewers is the maximum number of members of a team reviews
+	// are requested from when the team is cc'ed, e.g. with "/cc @org/team".
+	// Defaults to 5.
+	MaxTeamReviewers int `json:"max_team_reviewers,omitempty"`
+	// RoundRobinConfigMap is the name of the ConfigMap in the ProwJob
+	// namespace that keeps, for each team, which of its members is assigned
+	// next when the team is assigned, e.g. with "/assign @org/team".
+	// Defaults to "assign-round-robin".
+	RoundRobinConfigMap string `json:"round_robin_configmap,omitempty"`
 }
 
 // Blunderbuss defines configuration for the blunderbuss plugin.
@@ -163,6 +256,15 @@ type Blunderbuss struct {
 	// IgnoreDrafts instructs the plugin to ignore assigning reviewers
 	// to the PR that is in Draft state. Default it's false.
 	IgnoreDrafts bool `json:"ignore_drafts,omitempty"`
+	// UseReviewLoad controls whether blunderbuss will favor the candidate
+	// reviewers with the fewest open PRs awaiting their review. This will use
+	// one GitHub search API request per candidate reviewer, for at most 10
+	// candidates per PR.
+	UseReviewLoad bool `json:"use_review_load,omitempty"`
+	// MaxConcurrentReviews is the number of open PRs awaiting their review
+	// at which reviewers are no longer requested. Only used if UseReviewLoad
+	// is true. Defaults to 0 meaning no limit.
+	MaxConcurrentReviews int `json:"max_concurrent_reviews,omitempty"`
 }
 
 // Owners contains configuration related to handling OWNERS files.
@@ -199,6 +301,17 @@ type Owners struct {
 	// Filenames allows configuring repos to use a separate set of filenames for
 	// any plugin that interacts with these files. Keys are in "org/repo" format.
 	Filenames map[string]ownersconfig.Filenames `json:"filenames,omitempty"`
+
+	// OwnersAliasesRepo maps orgs to the repo, in "org/repo" format, whose
+	// OWNERS_ALIASES file holds the aliases shared by all the repos of the org.
+	// The verify-owners plugin resolves aliases from that file in addition to
+	// the repo's own OWNERS_ALIASES file.
+	OwnersAliasesRepo map[string]string `json:"owners_aliases_repo,omitempty"`
+
+	// DefaultOwners maps orgs, or repos in "org/repo" format, to the owners of
+	// the repos that have neither OWNERS files nor a CODEOWNERS file, so that
+	// the approve, lgtm and blunderbuss plugins work for newly onboarded repos.
+	DefaultOwners map[string]ownersconfig.DefaultOwners `json:"default_owners,omitempty"`
 }
 
 // OwnersFilenames determines which filenames to use for OWNERS and OWNERS_ALIASES for a repo.
@@ -238,12 +351,226 @@ func (c *Configuration) SkipCollaborators(org, repo string) bool {
 	return false
 }
 
+// OwnersAliasesRepoFor returns the repo holding the aliases shared by the repos
+// of the org, in "org/repo" format, or an empty string if there is none.
+func (c *Configuration) OwnersAliasesRepoFor(org string) string {
+	return c.Owners.OwnersAliasesRepo[org]
+}
+
+// DefaultOwnersFor returns the owners of the repo if it has no OWNERS files,
+// or nil if none are configured for it.
+func (c *Configuration) DefaultOwnersFor(org, repo string) *ownersconfig.DefaultOwners {
+	if owners, configured := c.Owners.DefaultOwners[fmt.Sprintf("%s/%s", org, repo)]; configured {
+		return &owners
+	}
+	if owners, configured := c.Owners.DefaultOwners[org]; configured {
+		return &owners
+	}
+	return nil
+}
+
+// DryRun holds the configuration for running plugins without mutating GitHub.
+// Plugins running in dry-run mode still read from GitHub but only log the
+// labels, comments and other changes they would have made.
+type DryRun struct {
+	// All runs every plugin in dry-run mode.
+	All bool `json:"all,omitempty"`
+	// Plugins is a list of plugin names to run in dry-run mode.
+	Plugins []string `json:"plugins,omitempty"`
+}
+
+// DryRunFor returns whether the given plugin should run in dry-run mode.
+func (c *Configuration) DryRunFor(plugin string) bool {
+	if c.DryRun.All {
+		return true
+	}
+	for _, p := range c.DryRun.Plugins {
+		if p == plugin {
+			return true
+		}
+	}
+	return false
+}
+
+// Concurrency bounds the concurrent executions of the plugins by hook, so that
+// a slow plugin can't starve the others. The dispatches of events to external
+// plugins are bounded the same way, by the name of the external plugin. The
+// events beyond the limits wait for the running handlers to return.
+type Concurrency struct {
+	// DefaultPerPlugin is the maximum number of concurrent executions of each
+	// plugin. Defaults to 0, which doesn't bound them.
+	DefaultPerPlugin int `json:"default_per_plugin,omitempty"`
+	// PerPlugin overrides DefaultPerPlugin for the plugins, by name.
+	PerPlugin map[string]int `json:"per_plugin,omitempty"`
+	// PerOrg is the maximum number of concurrent executions of the plugins
+	// for the events of an org. Defaults to 0, which doesn't bound them.
+	PerOrg int `json:"per_org,omitempty"`
+}
+
+// PluginLimit returns the maximum number of concurrent executions of the
+// plugin, 0 if they are not bounded.
+func (c *Concurrency) PluginLimit(plugin string) int {
+	if limit, ok := c.PerPlugin[plugin]; ok {
+		return limit
+	}
+	return c.DefaultPerPlugin
+}
+
+func validateConcurrency(c Concurrency) error {
+	if c.DefaultPerPlugin < 0 {
+		return fmt.Errorf("concurrency.default_per_plugin must not be negative, got %d", c.DefaultPerPlugin)
+	}
+	if c.PerOrg < 0 {
+		return fmt.Errorf("concurrency.per_org must not be negative, got %d", c.PerOrg)
+	}
+	for plugin, limit := range c.PerPlugin {
+		if limit < 0 {
+			return fmt.Errorf("concurrency.per_plugin[%s] must not be negative, got %d", plugin, limit)
+		}
+	}
+	return nil
+}
+
+// FeatureFlag enables a plugin or a plugin behavior for a part of the repos.
+// Repos are picked by a hash of the flag name and of the repo, so that raising
+// the percentage of a flag only enables it for more repos.
+type FeatureFlag struct {
+	// Percentage is the percentage of the repos, between 0 and 100, the flag
+	// is enabled for.
+	Percentage int `json:"percentage,omitempty"`
+	// Canaries are the orgs or org/repos the flag is always enabled for.
+	Canaries []string `json:"canaries,omitempty"`
+	// Excluded are the orgs or org/repos the flag is never enabled for. They
+	// take precedence over Canaries.
+	Excluded []string `json:"excluded,omitempty"`
+}
+
+// Enabled returns whether the flag of the name is enabled for the repo.
+func (f FeatureFlag) Enabled(name, org, repo string) bool {
+	fullName := org + "/" + repo
+	for _, excluded := range f.Excluded {
+		if excluded == org || excluded == fullName {
+			return false
+		}
+	}
+	for _, canary := range f.Canaries {
+		if canary == org || canary == fullName {
+			return true
+		}
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name + "\x00" + fullName))
+	return int(h.Sum32()%100) < f.Percentage
+}
+
+// FeatureEnabled returns whether the feature flag of the name is enabled for
+// the repo. Flags that are not configured are disabled, so that behaviors are
+// only rolled out once their flag is configured.
+func (c *Configuration) FeatureEnabled(name, org, repo string) bool {
+	flag, ok := c.FeatureFlags[name]
+	return ok && flag.Enabled(name, org, repo)
+}
+
+// CommandAliasesFor returns the command aliases of the repo, by lower case
+// alias.
+func (c *Configuration) CommandAliasesFor(org, repo string) map[string]string {
+	aliases := map[string]string{}
+	for _, orgRepo := range []string{org, org + "/" + repo} {
+		for alias, command := range c.CommandAliases[orgRepo] {
+			aliases[strings.ToLower(alias)] = command
+		}
+	}
+	return aliases
+}
+
+func validateCommandAliases(aliases map[string]map[string]string) error {
+	for orgRepo, repoAliases := range aliases {
+		for alias, command := range repoAliases {
+			if !commandNameRe.MatchString(alias) {
+				return fmt.Errorf("command_aliases[%s] has an invalid alias %q, aliases are made of letters, digits, _ and -", orgRepo, alias)
+			}
+			name := strings.Fields(command)
+			if len(name) == 0 || !commandNameRe.MatchString(name[0]) {
+				return fmt.Errorf("command_aliases[%s][%s] has an invalid command %q, commands are given without their leading /", orgRepo, alias, command)
+			}
+			if strings.EqualFold(name[0], alias) {
+				return fmt.Errorf("command_aliases[%s][%s] aliases itself", orgRepo, alias)
+			}
+		}
+	}
+	return nil
+}
+
+func validateFeatureFlags(flags map[string]FeatureFlag) error {
+	for name, flag := range flags {
+		if flag.Percentage < 0 || flag.Percentage > 100 {
+			return fmt.Errorf("feature_flags[%s].percentage must be between 0 and 100, got %d", name, flag.Percentage)
+		}
+		for _, orgRepo := range append(append([]string{}, flag.Canaries...), flag.Excluded...) {
+			if parts := strings.Split(orgRepo, "/"); len(parts) > 2 || parts[0] == "" || (len(parts) == 2 && parts[1] == "") {
+				return fmt.Errorf("feature_flags[%s] has an invalid org or org/repo %q", name, orgRepo)
+			}
+		}
+	}
+	return nil
+}
+
 // Retitle specifies configuration for the retitle plugin.
 type Retitle struct {
 	// AllowClosedIssues allows retitling closed/merged issues and PRs.
 	AllowClosedIssues bool `json:"allow_closed_issues,omitempty"`
 }
 
+// ReleaseNote is the config for linting the release notes of the
+// release-note plugin. PRs whose release note breaks one of the rules get a
+// comment listing the problems and, when they can be fixed automatically,
+// a suggested release note.
+type ReleaseNote struct {
+	// Repos is either of the form org/repos or just org.
+	Repos []string `json:"repos,omitempty"`
+	// MaxLength is the maximum number of characters of a release note.
+	// Longer release notes are reported. Zero means no limit.
+	MaxLength int `json:"max_length,omitempty"`
+	// ImperativeMood requires release notes to start with a verb in the
+	// imperative mood, e.g. "Add" rather than "Added" or "Adds".
+	ImperativeMood bool `json:"imperative_mood,omitempty"`
+	// ForbiddenPhrases are phrases that must not appear in release notes.
+	ForbiddenPhrases []ForbiddenPhrase `json:"forbidden_phrases,omitempty"`
+	// Files are glob patterns, like CHANGELOG/unreleased/*.md, of the files
+	// holding release notes, one per line. The lines PRs add to them are
+	// linted as well, in review comments suggesting the fixed lines.
+	Files []string `json:"files,omitempty"`
+}
+
+// ForbiddenPhrase is a phrase that must not appear in release notes.
+type ForbiddenPhrase struct {
+	// Phrase is matched case-insensitively on word boundaries.
+	Phrase string         `json:"phrase"`
+	Re     *regexp.Regexp `json:"-"`
+	// Replacement, if set, is suggested instead of the phrase.
+	Replacement string `json:"replacement,omitempty"`
+	// Reason explains why the phrase is forbidden.
+	Reason string `json:"reason,omitempty"`
+}
+
+// SemanticTitle is the config for the semantic-title plugin. PRs whose
+// title does not follow the configured convention are labeled with
+// do-not-merge/invalid-title until the title is fixed.
+type SemanticTitle struct {
+	// Repos is either of the form org/repos or just org.
+	Repos []string `json:"repos,omitempty"`
+	// Regexp is a regular expression PR titles must match, e.g.
+	// '^\[[A-Z]+-[0-9]+\] '.
+	Regexp string         `json:"regexp,omitempty"`
+	Re     *regexp.Regexp `json:"-"`
+	// ConventionalCommits requires PR titles to follow the conventional
+	// commits grammar, e.g. "feat(api)!: drop the v1 endpoints".
+	ConventionalCommits bool `json:"conventional_commits,omitempty"`
+	// Types are the allowed conventional commit types. Defaults to build,
+	// chore, ci, docs, feat, fix, perf, refactor, revert, style and test.
+	Types []string `json:"types,omitempty"`
+}
+
 // SigMention specifies configuration for the sigmention plugin.
 type SigMention struct {
 	// Regexp parses comments and should return matches to team mentions.
@@ -273,6 +600,93 @@ type Size struct {
 	L   int `json:"l"`
 	Xl  int `json:"xl"`
 	Xxl int `json:"xxl"`
+
+	// ExcludedPaths are globs, in the .gitattributes pattern format, of the
+	// files whose changes do not count towards the size of PRs, e.g. vendored
+	// code, generated protobufs or lockfiles. The key defines to which repos
+	// this applies and can be `*` for global, an org or a repo in org/repo
+	// notation. Files listed in .generated_files or marked as
+	// linguist-generated in .gitattributes are always excluded.
+	ExcludedPaths map[string][]string `json:"excluded_paths,omitempty"`
+	// CommentBreakdown enables a comment on PRs changing excluded files,
+	// showing the effective line count next to the raw line count.
+	CommentBreakdown bool `json:"comment_breakdown,omitempty"`
+}
+
+// ExcludedPathsFor returns the globs of the files excluded from the size
+// of PRs in the given repo.
+func (s Size) ExcludedPathsFor(org, repo string) []string {
+	var paths []string
+	for _, orgRepoKey := range []string{"*", org, org + "/" + repo} {
+		paths = append(paths, s.ExcludedPaths[orgRepoKey]...)
+	}
+	return paths
+}
+
+// Stale specifies the configuration of the stale plugin for a set of repos.
+// Issues and PRs that stay inactive are marked with the lifecycle/stale label,
+// then with the lifecycle/rotten label, and are finally closed. Any activity,
+// e.g. a /remove-lifecycle command, restarts the inactivity window.
+type Stale struct {
+	// Repos is either of the form org/repos or just org.
+	Repos []string `json:"repos,omitempty"`
+	// PRs is a bool indicating if this config applies to PRs.
+	PRs bool `json:"prs,omitempty"`
+	// Issues is a bool indicating if this config applies to issues.
+	Issues bool `json:"issues,omitempty"`
+	// StaleAfter is how long issues and PRs must be inactive before they are
+	// marked as stale. Defaults to "2160h" (90 days).
+	StaleAfter string `json:"stale_after,omitempty"`
+	// RottenAfter is how long stale issues and PRs must remain inactive before
+	// they are marked as rotten. Defaults to "720h" (30 days).
+	RottenAfter string `json:"rotten_after,omitempty"`
+	// CloseAfter is how long rotten issues and PRs must remain inactive before
+	// they are closed. Defaults to "720h" (30 days).
+	CloseAfter string `json:"close_after,omitempty"`
+	// ExemptLabels are labels that exempt issues and PRs from the lifecycle,
+	// in addition to lifecycle/frozen.
+	ExemptLabels []string `json:"exempt_labels,omitempty"`
+	// StaleComment is the Go template of the comment posted when marking an
+	// issue or PR as stale. Templates are executed with a struct whose Kind
+	// field is either "issue" or "PR".
+	StaleComment string `json:"stale_comment,omitempty"`
+	// RottenComment is the Go template of the comment posted when marking an
+	// issue or PR as rotten.
+	RottenComment string `json:"rotten_comment,omitempty"`
+	// CloseComment is the Go template of the comment posted when closing an
+	// issue or PR.
+	CloseComment string `json:"close_comment,omitempty"`
+
+	StaleAfterDuration  time.Duration `json:"-"`
+	RottenAfterDuration time.Duration `json:"-"`
+	CloseAfterDuration  time.Duration `json:"-"`
+}
+
+// BackportTracker specifies the configuration of the backport-tracker plugin
+// for a set of repos. When a PR carrying backport labels merges, a tracking
+// issue is opened for every target branch. The issue author is pinged
+// periodically until the cherry-pick merges, which closes the issue.
+type BackportTracker struct {
+	// Repos is either of the form org/repos or just org.
+	Repos []string `json:"repos,omitempty"`
+	// LabelPrefix is the prefix of the labels requesting a backport, followed
+	// by the target branch. Defaults to "needs-backport/", so that the
+	// "needs-backport/release-1.20" label requests a backport to release-1.20.
+	LabelPrefix string `json:"label_prefix,omitempty"`
+	// PingInterval is how long a tracking issue must be inactive before the
+	// author of the PR is pinged again. Defaults to "168h" (one week).
+	PingInterval string `json:"ping_interval,omitempty"`
+
+	PingIntervalDuration time.Duration `json:"-"`
+}
+
+func (b *BackportTracker) setDefaults() {
+	if b.LabelPrefix == "" {
+		b.LabelPrefix = "needs-backport/"
+	}
+	if b.PingInterval == "" {
+		b.PingInterval = "168h"
+	}
 }
 
 // Blockade specifies a configuration for a single blockade.
@@ -321,10 +735,23 @@ type Approve struct {
 	// PrProcessLink is the link to the help page which explains the code review process.
 	// The default value is "https://git.k8s.io/community/contributors/guide/owners.md#the-code-review-process".
 	PrProcessLink string `json:"pr_process_link,omitempty"`
+	// ApprovalsRequiredByDepth is the number of distinct approvers needed for the
+	// files covered by an OWNERS file, indexed by the depth of the OWNERS file
+	// in the repo: the first entry applies to the root OWNERS file, the second
+	// to OWNERS files in top-level directories, and so on. OWNERS files deeper
+	// than the list need a single approval. E.g. [2] requires two approvers for
+	// files owned by the root OWNERS file only.
+	ApprovalsRequiredByDepth []int `json:"approvals_required_by_depth,omitempty"`
+	// ReportCheckRun makes the approve plugin publish an "approve" check run on
+	// the head commit of PRs, listing the OWNERS files that still need approval
+	// and the approvers that can provide it. This requires GitHub App auth.
+	ReportCheckRun bool `json:"report_check_run,omitempty"`
 }
 
 var (
 	warnDependentBugTargetRelease time.Time
+
+	wordCharRe = regexp.MustCompile(`\w`)
 )
 
 func (a Approve) HasSelfApproval() bool {
@@ -350,7 +777,8 @@ type Lgtm struct {
 	// acts as adding or removing the lgtm label
 	ReviewActsAsLgtm bool `json:"review_acts_as_lgtm,omitempty"`
 	// StoreTreeHash indicates if tree_hash should be stored inside a comment to detect
-	// squashed commits before removing lgtm labels
+	// squashed commits before removing lgtm labels, and to restore lgtm labels when
+	// a previously LGTM'd tree is pushed again
 	StoreTreeHash bool `json:"store_tree_hash,omitempty"`
 	// WARNING: This disables the security mechanism that prevents a malicious member (or
 	// compromised GitHub account) from merging arbitrary code. Use with caution.
@@ -358,6 +786,23 @@ type Lgtm struct {
 	// StickyLgtmTeam specifies the GitHub team whose members are trusted with sticky LGTM,
 	// which eliminates the need to re-lgtm minor fixes/updates.
 	StickyLgtmTeam string `json:"trusted_team_for_sticky_lgtm,omitempty"`
+	// BlockedTeams is a list of GitHub team slugs whose members can never add
+	// the lgtm label, e.g. teams of bot accounts or release automation.
+	BlockedTeams []string `json:"blocked_teams,omitempty"`
+	// RequiredLgtmCount is the number of distinct collaborators that must issue
+	// /lgtm before the lgtm label is applied. Endorsements are tracked in a
+	// bot-managed comment on the PR. Values of 0 and 1 apply the label on the
+	// first /lgtm.
+	RequiredLgtmCount int `json:"required_lgtm_count,omitempty"`
+	// TreeHashExemptPaths is a list of gitattributes-style patterns, e.g. 'OWNERS'
+	// or 'docs/**'. If store_tree_hash is enabled, new changes that only touch
+	// files matching one of these patterns do not remove the lgtm label.
+	TreeHashExemptPaths []string `json:"tree_hash_exempt_paths,omitempty"`
+	// StaleAfter is the duration after which an LGTM is considered stale. If set,
+	// the lgtm label is periodically removed from PRs whose last LGTM is older
+	// than this, e.g. '168h'.
+	StaleAfter         string        `json:"stale_after,omitempty"`
+	StaleAfterDuration time.Duration `json:"-"`
 }
 
 // Jira holds the config for the jira plugin.
@@ -366,6 +811,37 @@ type Jira struct {
 	// for example including `enterprise` here would disable linking for all issues
 	// that start with `enterprise-` like `enterprise-4.` Matching is case-insenitive.
 	DisabledJiraProjects []string `json:"disabled_jira_projects,omitempty"`
+
+	// IssueKeyRegexp is the regular expression used to find Jira issue keys in
+	// comments and in PR titles and bodies, e.g. `\b(PROJ-[0-9]+)\b`. Its first
+	// capturing group must match the key. Defaults to keys of any project.
+	IssueKeyRegexp string         `json:"issue_key_regexp,omitempty"`
+	IssueKeyRe     *regexp.Regexp `json:"-"`
+
+	// RequireIssue is a list of orgs and org/repos whose PRs must reference an
+	// existing Jira issue in their title or body. PRs referencing none are
+	// labeled needs-issue and PRs only referencing issues that do not exist are
+	// labeled invalid-issue.
+	RequireIssue []string `json:"require_issue,omitempty"`
+
+	// MergedState is the state the Jira issues referenced by a PR are
+	// transitioned to when it merges, e.g. "Done". Issues are left untouched
+	// if unset.
+	MergedState string `json:"merged_state,omitempty"`
+}
+
+// RequiresIssue returns true if PRs in the repo must reference a Jira issue.
+func (j *Jira) RequiresIssue(org, repo string) bool {
+	if j == nil {
+		return false
+	}
+	fullName := fmt.Sprintf("%s/%s", org, repo)
+	for _, elem := range j.RequireIssue {
+		if elem == org || elem == fullName {
+			return true
+		}
+	}
+	return false
 }
 
 // Cat contains the configuration for the cat plugin.
@@ -391,6 +867,28 @@ type Label struct {
 	// defines to which repos this applies and can be `*` for global, an org
 	// or a repo in org/repo notation.
 	RestrictedLabels map[string][]RestrictedLabel `json:"restricted_labels,omitempty"`
+
+	// AutoCreateFrom is the location of a label_sync labels.yaml file, in the
+	// org/repo:path form, e.g. "kubernetes/test-infra:label_sync/labels.yaml".
+	// Labels requested with the label commands that do not exist in the repo
+	// but are defined in the file, by default or for the org or repo, are
+	// created with the color and description from the file instead of being
+	// rejected. It is global and applies to every repo the label plugin is
+	// enabled for.
+	AutoCreateFrom string `json:"auto_create_from,omitempty"`
+}
+
+// AutoCreateSource returns the repo and the path of the AutoCreateFrom file.
+func (l Label) AutoCreateSource() (org, repo, path string, err error) {
+	parts := strings.SplitN(l.AutoCreateFrom, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", "", fmt.Errorf("%q is not of the form org/repo:path", l.AutoCreateFrom)
+	}
+	orgRepo := strings.Split(parts[0], "/")
+	if len(orgRepo) != 2 || orgRepo[0] == "" || orgRepo[1] == "" {
+		return "", "", "", fmt.Errorf("%q is not of the form org/repo:path", l.AutoCreateFrom)
+	}
+	return orgRepo[0], orgRepo[1], parts[1], nil
 }
 
 func (l Label) RestrictedLabelsFor(org, repo string) map[string]RestrictedLabel {
@@ -454,6 +952,19 @@ type Trigger struct {
 	IgnoreOkToTest bool `json:"ignore_ok_to_test,omitempty"`
 	// TriggerGitHubWorkflows enables workflows run by github to be triggered by prow.
 	TriggerGitHubWorkflows bool `json:"trigger_github_workflows,omitempty"`
+	// BatchWindow is how long trigger waits for further `/test <job>` comments on
+	// a PR before starting the requested jobs in one pass. Jobs requested several
+	// times for the same commit are only started once and a single comment lists
+	// the jobs that were started. By default, jobs are started right away.
+	// Each hook replica batches the comments it receives on its own.
+	BatchWindow string `json:"batch_window,omitempty"`
+	// BatchWindowDuration is the parsed BatchWindow.
+	BatchWindowDuration time.Duration `json:"-"`
+	// ExternalContributorDailyJobQuota caps how many presubmit jobs each user that
+	// is not a member of the org can trigger on a PR within a day. Once the quota
+	// is reached, an org member has to trigger further jobs, e.g. with /ok-to-test.
+	// By default, there is no quota.
+	ExternalContributorDailyJobQuota int `json:"external_contributor_daily_job_quota,omitempty"`
 }
 
 // Heart contains the configuration for the heart plugin.
@@ -654,6 +1165,27 @@ type MergeWarning struct {
 	ExemptBranches map[string][]string `json:"exempt_branches,omitempty"`
 }
 
+// Wip specifies the configuration of the wip plugin for a set of repos.
+// Draft PRs and PRs whose title starts with "WIP" are always considered
+// work in progress.
+type Wip struct {
+	// Repos is either of the form org/repos or just org.
+	Repos []string `json:"repos,omitempty"`
+	// TitlePatterns are additional regular expressions matching the titles
+	// of PRs that are work in progress, e.g. `(?i)^\[draft\]`.
+	TitlePatterns []string `json:"title_patterns,omitempty"`
+	// Emojis are markers that make a PR work in progress when its title
+	// contains one of them, e.g. "🚧" or ":construction:".
+	Emojis []string `json:"emojis,omitempty"`
+	// StatusContext is the context of a commit status that is set to pending
+	// while the PR is work in progress and to success otherwise, so that
+	// branch protection can block work in progress PRs from merging. No
+	// status is set if empty.
+	StatusContext string `json:"status_context,omitempty"`
+
+	TitleRes []*regexp.Regexp `json:"-"`
+}
+
 // Welcome is config for the welcome plugin.
 type Welcome struct {
 	// Repos is either of the form org/repos or just org.
@@ -661,6 +1193,11 @@ type Welcome struct {
 	// MessageTemplate is the welcome message template to post on new-contributor PRs
 	// For the info struct see prow/plugins/welcome/welcome.go's PRInfo
 	MessageTemplate string `json:"message_template,omitempty"`
+	// TemplateFile is the path, relative to the root of the repo, of a file
+	// holding the welcome message template, e.g. ".prow/welcome.md". It is
+	// read from the base branch of the PR and takes precedence over
+	// MessageTemplate when it exists.
+	TemplateFile string `json:"template_file,omitempty"`
 }
 
 // Dco is config for the DCO (https://developercertificate.org/) checker plugin.
@@ -678,6 +1215,44 @@ type Dco struct {
 	SkipDCOCheckForCollaborators bool `json:"skip_dco_check_for_collaborators,omitempty"`
 }
 
+// DcoCla is the config for the dco-cla plugin. A commit is compliant when
+// its message is signed off or, if a CLA service is configured, when its
+// author has signed the CLA.
+type DcoCla struct {
+	// ClaURL is the URL of the CLA service. For every commit missing a DCO
+	// signoff, a GET request with the `login` and `email` query parameters of
+	// the commit author is sent to it. The service must answer with a JSON
+	// object like {"signed": true}. If empty, only DCO signoffs are accepted.
+	ClaURL string `json:"cla_url,omitempty"`
+	// CacheTTL is how long the authors that signed the CLA are cached. Authors
+	// that did not sign it are looked up again every time, and the
+	// `/check-dco-cla` command always asks the CLA service. Defaults to "1h".
+	CacheTTL         string        `json:"cache_ttl,omitempty"`
+	CacheTTLDuration time.Duration `json:"-"`
+	// YesLabel is the label applied to compliant PRs. Defaults to "cncf-cla: yes".
+	YesLabel string `json:"yes_label,omitempty"`
+	// NoLabel is the label applied to PRs with non compliant commits.
+	// Defaults to "cncf-cla: no".
+	NoLabel string `json:"no_label,omitempty"`
+}
+
+// Dedupe is the config for the dedupe plugin, which suggests likely
+// duplicates of new issues among the recent open issues of the repo.
+type Dedupe struct {
+	// Repos is either of the form org/repos or just org.
+	Repos []string `json:"repos,omitempty"`
+	// MaxAge is how recently the open issues compared to new issues must have
+	// been created. Defaults to "2160h" (90 days).
+	MaxAge         string        `json:"max_age,omitempty"`
+	MaxAgeDuration time.Duration `json:"-"`
+	// Threshold is the similarity, between 0 and 1, from which an issue is
+	// suggested as a duplicate. Defaults to 0.3.
+	Threshold float64 `json:"threshold,omitempty"`
+	// MaxSuggestions is the maximum number of duplicates suggested for an
+	// issue. Defaults to 3.
+	MaxSuggestions int `json:"max_suggestions,omitempty"`
+}
+
 // CherryPickUnapproved is the config for the cherrypick-unapproved plugin.
 type CherryPickUnapproved struct {
 	// BranchRegexp is the regular expression for branch names such that
@@ -729,6 +1304,29 @@ type RequireMatchingLabel struct {
 	GracePeriodDuration time.Duration `json:"-"`
 }
 
+// RequiredReviewers is the config for the required-reviewers plugin.
+type RequiredReviewers struct {
+	// Repos is either of the form org/repos or just org.
+	Repos []string `json:"repos,omitempty"`
+	// Rules lists the groups of reviewers that must approve PRs changing
+	// some paths. PRs touching the paths of a rule are labeled with
+	// do-not-merge/needs-required-reviewer until at least one member of
+	// the group has approved.
+	Rules []RequiredReviewersRule `json:"rules,omitempty"`
+}
+
+// RequiredReviewersRule is a group of reviewers required for a set of paths.
+type RequiredReviewersRule struct {
+	// Paths are the gitattributes-style globs of the files the rule applies to,
+	// e.g. "docs/**" or "*.proto".
+	Paths []string `json:"paths,omitempty"`
+	// Teams are the slugs of the GitHub teams, in the org of the repo, whose
+	// members are part of the group.
+	Teams []string `json:"teams,omitempty"`
+	// Users are the GitHub logins of the users that are part of the group.
+	Users []string `json:"users,omitempty"`
+}
+
 // validate checks the following properties:
 // - Org, Regexp, MissingLabel, and GracePeriod must be non-empty.
 // - Repo does not contain a '/' (should use Org+Repo).
@@ -852,6 +1450,172 @@ func (c *Configuration) LgtmFor(org, repo string) *Lgtm {
 	return &Lgtm{}
 }
 
+// RequiredReviewersFor finds the RequiredReviewers for a repo, if one exists.
+// A config can be listed for the repo itself or for the owning organization.
+func (c *Configuration) RequiredReviewersFor(org, repo string) *RequiredReviewers {
+	fullName := fmt.Sprintf("%s/%s", org, repo)
+	for _, rr := range c.RequiredReviewers {
+		if !sets.NewString(rr.Repos...).Has(fullName) {
+			continue
+		}
+		return &rr
+	}
+	// If you don't find anything, loop again looking for an org config
+	for _, rr := range c.RequiredReviewers {
+		if !sets.NewString(rr.Repos...).Has(org) {
+			continue
+		}
+		return &rr
+	}
+	return &RequiredReviewers{}
+}
+
+// SemanticTitleFor finds the SemanticTitle config for a repo, if one exists.
+// A config can be listed for the repo itself or for the owning organization.
+func (c *Configuration) SemanticTitleFor(org, repo string) *SemanticTitle {
+	fullName := fmt.Sprintf("%s/%s", org, repo)
+	for _, st := range c.SemanticTitle {
+		if !sets.NewString(st.Repos...).Has(fullName) {
+			continue
+		}
+		return &st
+	}
+	// If you don't find anything, loop again looking for an org config
+	for _, st := range c.SemanticTitle {
+		if !sets.NewString(st.Repos...).Has(org) {
+			continue
+		}
+		return &st
+	}
+	return &SemanticTitle{}
+}
+
+// APIReviewFor finds the APIReview config for a repo, if one exists.
+// A config can be listed for the repo itself or for the owning organization.
+func (c *Configuration) APIReviewFor(org, repo string) *APIReview {
+	fullName := fmt.Sprintf("%s/%s", org, repo)
+	for _, ar := range c.APIReview {
+		if !sets.NewString(ar.Repos...).Has(fullName) {
+			continue
+		}
+		return &ar
+	}
+	// If you don't find anything, loop again looking for an org config
+	for _, ar := range c.APIReview {
+		if !sets.NewString(ar.Repos...).Has(org) {
+			continue
+		}
+		return &ar
+	}
+	return nil
+}
+
+// WipFor finds the Wip config for a repo.
+// A config can be listed for the repo itself or for the owning organization.
+// An empty config is returned if none is found.
+func (c *Configuration) WipFor(org, repo string) *Wip {
+	fullName := fmt.Sprintf("%s/%s", org, repo)
+	for _, wip := range c.Wip {
+		if !sets.NewString(wip.Repos...).Has(fullName) {
+			continue
+		}
+		return &wip
+	}
+	// If you don't find anything, loop again looking for an org config
+	for _, wip := range c.Wip {
+		if !sets.NewString(wip.Repos...).Has(org) {
+			continue
+		}
+		return &wip
+	}
+	return &Wip{}
+}
+
+// ReleaseNoteFor finds the ReleaseNote config for a repo, if one exists.
+// A config can be listed for the repo itself or for the owning organization.
+func (c *Configuration) ReleaseNoteFor(org, repo string) *ReleaseNote {
+	fullName := fmt.Sprintf("%s/%s", org, repo)
+	for _, rn := range c.ReleaseNote {
+		if !sets.NewString(rn.Repos...).Has(fullName) {
+			continue
+		}
+		return &rn
+	}
+	// If you don't find anything, loop again looking for an org config
+	for _, rn := range c.ReleaseNote {
+		if !sets.NewString(rn.Repos...).Has(org) {
+			continue
+		}
+		return &rn
+	}
+	return &ReleaseNote{}
+}
+
+// DedupeFor finds the Dedupe config for a repo, if one exists.
+// A config can be listed for the repo itself or for the owning organization.
+// Repos without a config use the defaults.
+func (c *Configuration) DedupeFor(org, repo string) *Dedupe {
+	fullName := fmt.Sprintf("%s/%s", org, repo)
+	for _, d := range c.Dedupe {
+		if !sets.NewString(d.Repos...).Has(fullName) {
+			continue
+		}
+		return &d
+	}
+	// If you don't find anything, loop again looking for an org config
+	for _, d := range c.Dedupe {
+		if !sets.NewString(d.Repos...).Has(org) {
+			continue
+		}
+		return &d
+	}
+	d := Dedupe{}
+	d.setDefaults()
+	// The default max age always compiles.
+	_ = d.compileDurations()
+	return &d
+}
+
+// BackportTrackerFor finds the BackportTracker config for a repo, if one exists.
+// A config can be listed for the repo itself or for the owning organization.
+func (c *Configuration) BackportTrackerFor(org, repo string) *BackportTracker {
+	fullName := fmt.Sprintf("%s/%s", org, repo)
+	for _, bt := range c.BackportTracker {
+		if !sets.NewString(bt.Repos...).Has(fullName) {
+			continue
+		}
+		return &bt
+	}
+	// If you don't find anything, loop again looking for an org config
+	for _, bt := range c.BackportTracker {
+		if !sets.NewString(bt.Repos...).Has(org) {
+			continue
+		}
+		return &bt
+	}
+	return nil
+}
+
+// StaleFor finds the Stale config for a repo, if one exists.
+// A config can be listed for the repo itself or for the owning organization.
+func (c *Configuration) StaleFor(org, repo string) *Stale {
+	fullName := fmt.Sprintf("%s/%s", org, repo)
+	for _, stale := range c.Stale {
+		if !sets.NewString(stale.Repos...).Has(fullName) {
+			continue
+		}
+		return &stale
+	}
+	// If you don't find anything, loop again looking for an org config
+	for _, stale := range c.Stale {
+		if !sets.NewString(stale.Repos...).Has(org) {
+			continue
+		}
+		return &stale
+	}
+	return nil
+}
+
 // TriggerFor finds the Trigger for a repo, if one exists
 // a trigger can be listed for the repo itself or for the
 // owning organization
@@ -891,6 +1655,21 @@ func (c *Configuration) DcoFor(org, repo string) *Dco {
 	return &Dco{}
 }
 
+// DcoClaFor finds the DcoCla config for a repo. A config can be listed for
+// the repo itself, for the owning organization or globally with "*".
+func (c *Configuration) DcoClaFor(org, repo string) *DcoCla {
+	if c.DcoCla[fmt.Sprintf("%s/%s", org, repo)] != nil {
+		return c.DcoCla[fmt.Sprintf("%s/%s", org, repo)]
+	}
+	if c.DcoCla[org] != nil {
+		return c.DcoCla[org]
+	}
+	if c.DcoCla["*"] != nil {
+		return c.DcoCla["*"]
+	}
+	return &DcoCla{YesLabel: labels.ClaYes, NoLabel: labels.ClaNo}
+}
+
 func OldToNewPlugins(oldPlugins map[string][]string) Plugins {
 	newPlugins := make(Plugins)
 	for repo, plugins := range oldPlugins {
@@ -949,6 +1728,41 @@ func (c *Configuration) EnabledReposForPlugin(plugin string) (orgs, repos []stri
 	return
 }
 
+// PluginEnabledFor returns whether the passed plugin is enabled for the repo.
+func (c *Configuration) PluginEnabledFor(plugin, org, repo string) bool {
+	if orgPlugins := c.Plugins[org]; !sets.NewString(orgPlugins.ExcludedRepos...).Has(repo) && sets.NewString(orgPlugins.Plugins...).Has(plugin) {
+		return true
+	}
+	return sets.NewString(c.Plugins[org+"/"+repo].Plugins...).Has(plugin)
+}
+
+// EnabledReposQuery returns the GitHub search qualifiers that limit a search
+// to the orgs and repos that have enabled the passed plugin, or an empty
+// string if there are none.
+func (c *Configuration) EnabledReposQuery(plugin string) string {
+	orgs, repos, orgExceptions := c.EnabledReposForPlugin(plugin)
+	var query []string
+	for _, org := range orgs {
+		query = append(query, SearchQualifier(org))
+		for _, repo := range orgExceptions[org].List() {
+			query = append(query, "-"+SearchQualifier(repo))
+		}
+	}
+	for _, repo := range repos {
+		query = append(query, SearchQualifier(repo))
+	}
+	return strings.Join(query, " ")
+}
+
+// SearchQualifier returns the GitHub search qualifier that limits a search to
+// an org, or to a repo in org/repo notation.
+func SearchQualifier(orgOrRepo string) string {
+	if strings.Contains(orgOrRepo, "/") {
+		return "repo:" + orgOrRepo
+	}
+	return "org:" + orgOrRepo
+}
+
 // EnabledReposForExternalPlugin returns the orgs and repos that have enabled the passed
 // external plugin.
 func (c *Configuration) EnabledReposForExternalPlugin(plugin string) (orgs, repos []string) {
@@ -1005,10 +1819,25 @@ func (c *Configuration) setDefaults() {
 			c.ExternalPlugins[repo][i].Endpoint = fmt.Sprintf("http://%s", p.Name)
 		}
 	}
+	if c.ExternalPluginHealth.Interval == "" {
+		c.ExternalPluginHealth.Interval = "1m"
+	}
+	if c.ExternalPluginHealth.Timeout == "" {
+		c.ExternalPluginHealth.Timeout = "5s"
+	}
+	if c.ExternalPluginHealth.UnhealthyThreshold == 0 {
+		c.ExternalPluginHealth.UnhealthyThreshold = 3
+	}
 	if c.Blunderbuss.ReviewerCount == nil {
 		c.Blunderbuss.ReviewerCount = new(int)
 		*c.Blunderbuss.ReviewerCount = defaultBlunderbussReviewerCount
 	}
+	if c.Assign.MaxTeamReviewers == 0 {
+		c.Assign.MaxTeamReviewers = defaultAssignMaxTeamReviewers
+	}
+	if c.Assign.RoundRobinConfigMap == "" {
+		c.Assign.RoundRobinConfigMap = defaultAssignRoundRobinConfigMap
+	}
 	for i := range c.Triggers {
 		c.Triggers[i].SetDefaults()
 	}
@@ -1039,6 +1868,84 @@ func (c *Configuration) setDefaults() {
 			c.RequireMatchingLabel[i].GracePeriod = "5s"
 		}
 	}
+
+	for i := range c.Stale {
+		c.Stale[i].setDefaults()
+	}
+
+	for i := range c.BackportTracker {
+		c.BackportTracker[i].setDefaults()
+	}
+
+	for i := range c.Dedupe {
+		c.Dedupe[i].setDefaults()
+	}
+
+	for _, dc := range c.DcoCla {
+		if dc.CacheTTL == "" {
+			dc.CacheTTL = "1h"
+		}
+		if dc.YesLabel == "" {
+			dc.YesLabel = labels.ClaYes
+		}
+		if dc.NoLabel == "" {
+			dc.NoLabel = labels.ClaNo
+		}
+	}
+
+	for i := range c.SemanticTitle {
+		if c.SemanticTitle[i].ConventionalCommits && len(c.SemanticTitle[i].Types) == 0 {
+			c.SemanticTitle[i].Types = []string{"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test"}
+		}
+	}
+}
+
+func (d *Dedupe) setDefaults() {
+	if d.MaxAge == "" {
+		d.MaxAge = "2160h"
+	}
+	if d.Threshold == 0 {
+		d.Threshold = 0.3
+	}
+	if d.MaxSuggestions == 0 {
+		d.MaxSuggestions = 3
+	}
+}
+
+func (d *Dedupe) compileDurations() error {
+	dur, err := time.ParseDuration(d.MaxAge)
+	if err != nil {
+		return fmt.Errorf("failed to compile dedupe max_age duration: %q, error: %w", d.MaxAge, err)
+	}
+	d.MaxAgeDuration = dur
+	return nil
+}
+
+func (s *Stale) setDefaults() {
+	if s.StaleAfter == "" {
+		s.StaleAfter = "2160h"
+	}
+	if s.RottenAfter == "" {
+		s.RottenAfter = "720h"
+	}
+	if s.CloseAfter == "" {
+		s.CloseAfter = "720h"
+	}
+	if s.StaleComment == "" {
+		s.StaleComment = "This {{.Kind}} has been inactive for a while and is now marked as stale. " +
+			"If it stays inactive, it will be marked as rotten and then closed.\n\n" +
+			"You can mark it as fresh with `/remove-lifecycle stale`" +
+			"{{if eq .Kind \"issue\"}} or exempt it from this process with `/lifecycle frozen`{{end}}."
+	}
+	if s.RottenComment == "" {
+		s.RottenComment = "This {{.Kind}} has been inactive since it was marked as stale and is now marked as rotten. " +
+			"If it stays inactive, it will be closed.\n\n" +
+			"You can mark it as fresh with `/remove-lifecycle rotten`."
+	}
+	if s.CloseComment == "" {
+		s.CloseComment = "This {{.Kind}} has been inactive since it was marked as rotten and is now closed.\n\n" +
+			"You can reopen it with `/reopen` and mark it as fresh with `/remove-lifecycle rotten`."
+	}
 }
 
 // validatePluginsDupes will return an error if there are duplicated plugins.
@@ -1075,6 +1982,13 @@ func validateSizes(size Size) error {
 	if size.S > size.M || size.M > size.L || size.L > size.Xl || size.Xl > size.Xxl {
 		return errors.New("invalid size plugin configuration - one of the smaller sizes is bigger than a larger one")
 	}
+	for orgRepo, paths := range size.ExcludedPaths {
+		for _, path := range paths {
+			if _, err := gitattributes.ParsePattern(path); err != nil {
+				return fmt.Errorf("invalid size plugin configuration - excluded path %q for %q: %w", path, orgRepo, err)
+			}
+		}
+	}
 
 	return nil
 }
@@ -1122,6 +2036,32 @@ func validateExternalPlugins(pluginMap map[string][]ExternalPlugin) error {
 	return nil
 }
 
+func (h *ExternalPluginHealth) compileDurations() error {
+	interval, err := time.ParseDuration(h.Interval)
+	if err != nil {
+		return fmt.Errorf("failed to compile external_plugin_health interval duration: %q, error: %w", h.Interval, err)
+	}
+	timeout, err := time.ParseDuration(h.Timeout)
+	if err != nil {
+		return fmt.Errorf("failed to compile external_plugin_health timeout duration: %q, error: %w", h.Timeout, err)
+	}
+	h.IntervalDuration, h.TimeoutDuration = interval, timeout
+	return nil
+}
+
+func validateExternalPluginHealth(h ExternalPluginHealth) error {
+	if h.IntervalDuration <= 0 {
+		return errors.New("external_plugin_health.interval must be positive")
+	}
+	if h.TimeoutDuration <= 0 {
+		return errors.New("external_plugin_health.timeout must be positive")
+	}
+	if h.UnhealthyThreshold < 1 {
+		return fmt.Errorf("external_plugin_health.unhealthy_threshold must be positive, got %d", h.UnhealthyThreshold)
+	}
+	return nil
+}
+
 func validateBlunderbuss(b *Blunderbuss) error {
 	if b.ReviewerCount != nil && *b.ReviewerCount < 1 {
 		return fmt.Errorf("invalid request_count: %v (needs to be positive)", *b.ReviewerCount)
@@ -1129,6 +2069,63 @@ func validateBlunderbuss(b *Blunderbuss) error {
 	return nil
 }
 
+func validateAssign(a *Assign) error {
+	if a.MaxTeamReviewers < 0 {
+		return fmt.Errorf("invalid max_team_reviewers: %d (needs to be positive)", a.MaxTeamReviewers)
+	}
+	return nil
+}
+
+func validateApprove(approves []Approve) error {
+	for _, approve := range approves {
+		for depth, required := range approve.ApprovalsRequiredByDepth {
+			if required < 1 {
+				return fmt.Errorf("invalid approvals_required_by_depth for %v at depth %d: %d (needs to be positive)", approve.Repos, depth, required)
+			}
+		}
+	}
+	return nil
+}
+
+func validateLabel(label Label) error {
+	if label.AutoCreateFrom == "" {
+		return nil
+	}
+	if _, _, _, err := label.AutoCreateSource(); err != nil {
+		return fmt.Errorf("invalid label auto_create_from: %w", err)
+	}
+	return nil
+}
+
+func validateOwners(owners Owners) error {
+	for org, repo := range owners.OwnersAliasesRepo {
+		if parts := strings.Split(repo, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("owners_aliases_repo for org %q must be in org/repo format, got %q", org, repo)
+		}
+	}
+	return nil
+}
+
+func validateLgtm(lgtms []Lgtm) error {
+	for _, lgtm := range lgtms {
+		if lgtm.RequiredLgtmCount < 0 {
+			return fmt.Errorf("invalid required_lgtm_count for %v: %d (must not be negative)", lgtm.Repos, lgtm.RequiredLgtmCount)
+		}
+		if lgtm.StaleAfter != "" && lgtm.StaleAfterDuration <= 0 {
+			return fmt.Errorf("invalid stale_after for %v: %q (must be positive)", lgtm.Repos, lgtm.StaleAfter)
+		}
+		if len(lgtm.TreeHashExemptPaths) > 0 && !lgtm.StoreTreeHash {
+			return fmt.Errorf("tree_hash_exempt_paths for %v requires store_tree_hash to be enabled", lgtm.Repos)
+		}
+		for _, path := range lgtm.TreeHashExemptPaths {
+			if _, err := gitattributes.ParsePattern(path); err != nil {
+				return fmt.Errorf("invalid tree_hash_exempt_paths for %v: %w", lgtm.Repos, err)
+			}
+		}
+	}
+	return nil
+}
+
 // ConfigMapID is a name/namespace/cluster combination that identifies a config map
 type ConfigMapID struct {
 	Name, Namespace, Cluster string
@@ -1193,6 +2190,123 @@ func validateRequireMatchingLabel(rs []RequireMatchingLabel) error {
 	return nil
 }
 
+func validateRequiredReviewers(rrs []RequiredReviewers) error {
+	for _, rr := range rrs {
+		for i, rule := range rr.Rules {
+			if len(rule.Paths) == 0 {
+				return fmt.Errorf("required_reviewers rule #%d for %v has no paths", i, rr.Repos)
+			}
+			if len(rule.Teams) == 0 && len(rule.Users) == 0 {
+				return fmt.Errorf("required_reviewers rule #%d for %v has neither teams nor users", i, rr.Repos)
+			}
+			for _, path := range rule.Paths {
+				if _, err := gitattributes.ParsePattern(path); err != nil {
+					return fmt.Errorf("invalid paths in required_reviewers rule #%d for %v: %w", i, rr.Repos, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateAPIReview(ars []APIReview) error {
+	for _, ar := range ars {
+		if len(ar.Paths) == 0 {
+			return fmt.Errorf("api_review config for %v has no paths", ar.Repos)
+		}
+		if ar.Team == "" {
+			return fmt.Errorf("api_review config for %v has no team", ar.Repos)
+		}
+		for _, path := range ar.Paths {
+			if _, err := gitattributes.ParsePattern(path); err != nil {
+				return fmt.Errorf("invalid paths in api_review config for %v: %w", ar.Repos, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateDcoCla(dcs map[string]*DcoCla) error {
+	for key, dc := range dcs {
+		if dc.ClaURL != "" {
+			if u, err := url.Parse(dc.ClaURL); err != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("invalid dco_cla cla_url for %s: %q", key, dc.ClaURL)
+			}
+		}
+		if dc.YesLabel == dc.NoLabel {
+			return fmt.Errorf("dco_cla config for %s uses the same yes_label and no_label: %q", key, dc.YesLabel)
+		}
+	}
+	return nil
+}
+
+func validateDedupe(dedupes []Dedupe) error {
+	for _, d := range dedupes {
+		if d.Threshold < 0 || d.Threshold > 1 {
+			return fmt.Errorf("invalid dedupe config for %v: threshold must be between 0 and 1, got %v", d.Repos, d.Threshold)
+		}
+		if d.MaxSuggestions < 0 {
+			return fmt.Errorf("invalid dedupe config for %v: max_suggestions must not be negative", d.Repos)
+		}
+		if d.MaxAgeDuration <= 0 {
+			return fmt.Errorf("invalid dedupe config for %v: max_age must be positive", d.Repos)
+		}
+	}
+	return nil
+}
+
+func validateReleaseNote(rns []ReleaseNote) error {
+	for _, rn := range rns {
+		if rn.MaxLength < 0 {
+			return fmt.Errorf("release_note config for %v has a negative max_length", rn.Repos)
+		}
+		for _, fp := range rn.ForbiddenPhrases {
+			if strings.TrimSpace(fp.Phrase) == "" {
+				return fmt.Errorf("release_note config for %v has an empty forbidden phrase", rn.Repos)
+			}
+		}
+	}
+	return nil
+}
+
+func validateSemanticTitle(sts []SemanticTitle) error {
+	for _, st := range sts {
+		if st.Regexp == "" && !st.ConventionalCommits {
+			return fmt.Errorf("semantic_title config for %v has neither a regexp nor conventional_commits", st.Repos)
+		}
+		if !st.ConventionalCommits && len(st.Types) > 0 {
+			return fmt.Errorf("semantic_title config for %v sets types without conventional_commits", st.Repos)
+		}
+	}
+	return nil
+}
+
+func validateBackportTracker(trackers []BackportTracker) error {
+	for _, bt := range trackers {
+		if bt.PingIntervalDuration <= 0 {
+			return fmt.Errorf("invalid backport_tracker config for %v: ping_interval must be positive", bt.Repos)
+		}
+	}
+	return nil
+}
+
+func validateStale(stales []Stale) error {
+	for _, stale := range stales {
+		if !stale.PRs && !stale.Issues {
+			return fmt.Errorf("stale config for %v applies to neither PRs nor issues", stale.Repos)
+		}
+		if stale.StaleAfterDuration <= 0 || stale.RottenAfterDuration <= 0 || stale.CloseAfterDuration <= 0 {
+			return fmt.Errorf("invalid stale config for %v: stale_after, rotten_after and close_after must be positive", stale.Repos)
+		}
+		for name, comment := range map[string]string{"stale_comment": stale.StaleComment, "rotten_comment": stale.RottenComment, "close_comment": stale.CloseComment} {
+			if _, err := template.New(name).Parse(comment); err != nil {
+				return fmt.Errorf("invalid %s for %v: %w", name, stale.Repos, err)
+			}
+		}
+	}
+	return nil
+}
+
 func validateProjectManager(pm ProjectManager) error {
 
 	projectConfig := pm
@@ -1240,10 +2354,26 @@ func validateTrigger(triggers []Trigger) error {
 		if trigger.TrustedOrg != "" {
 			logrusutil.ThrottledWarnf(&warnTriggerTrustedOrg, 5*time.Minute, "trusted_org functionality is deprecated. Please ensure your configuration is updated before the end of December 2019.")
 		}
+		if trigger.ExternalContributorDailyJobQuota < 0 {
+			return fmt.Errorf("external_contributor_daily_job_quota for %v cannot be negative", trigger.Repos)
+		}
 	}
 	return nil
 }
 
+// forbiddenPhraseRegexp matches the phrase case-insensitively, requiring word
+// boundaries where the phrase starts or ends with a word character.
+func forbiddenPhraseRegexp(phrase string) *regexp.Regexp {
+	expr := regexp.QuoteMeta(phrase)
+	if wordCharRe.MatchString(phrase[:1]) {
+		expr = `\b` + expr
+	}
+	if wordCharRe.MatchString(phrase[len(phrase)-1:]) {
+		expr += `\b`
+	}
+	return regexp.MustCompile(`(?i)` + expr)
+}
+
 func compileRegexpsAndDurations(pc *Configuration) error {
 	cRe, err := regexp.Compile(pc.SigMention.Regexp)
 	if err != nil {
@@ -1274,6 +2404,17 @@ func compileRegexpsAndDurations(pc *Configuration) error {
 	}
 	pc.Heart.CommentRe = commentRe
 
+	for i := range pc.Wip {
+		pc.Wip[i].TitleRes = nil
+		for _, pattern := range pc.Wip[i].TitlePatterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("failed to compile wip title pattern: %q, error: %w", pattern, err)
+			}
+			pc.Wip[i].TitleRes = append(pc.Wip[i].TitleRes, re)
+		}
+	}
+
 	rs := pc.RequireMatchingLabel
 	for i := range rs {
 		re, err := regexp.Compile(rs[i].Regexp)
@@ -1289,6 +2430,109 @@ func compileRegexpsAndDurations(pc *Configuration) error {
 		}
 		rs[i].GracePeriodDuration = dur
 	}
+
+	for i := range pc.Dedupe {
+		if err := pc.Dedupe[i].compileDurations(); err != nil {
+			return err
+		}
+	}
+
+	if err := pc.ExternalPluginHealth.compileDurations(); err != nil {
+		return err
+	}
+
+	for key, dc := range pc.DcoCla {
+		dur, err := time.ParseDuration(dc.CacheTTL)
+		if err != nil {
+			return fmt.Errorf("failed to compile dco_cla cache_ttl duration for %s: %q, error: %w", key, dc.CacheTTL, err)
+		}
+		dc.CacheTTLDuration = dur
+	}
+
+	if pc.Jira != nil && pc.Jira.IssueKeyRegexp != "" {
+		re, err := regexp.Compile(pc.Jira.IssueKeyRegexp)
+		if err != nil {
+			return fmt.Errorf("failed to compile jira issue_key_regexp: %q, error: %w", pc.Jira.IssueKeyRegexp, err)
+		}
+		if re.NumSubexp() < 1 {
+			return fmt.Errorf("jira issue_key_regexp %q must have a capturing group for the issue key", pc.Jira.IssueKeyRegexp)
+		}
+		pc.Jira.IssueKeyRe = re
+	}
+
+	for i := range pc.ReleaseNote {
+		for _, pattern := range pc.ReleaseNote[i].Files {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid release_note file pattern %q: %w", pattern, err)
+			}
+		}
+		for j := range pc.ReleaseNote[i].ForbiddenPhrases {
+			phrase := strings.TrimSpace(pc.ReleaseNote[i].ForbiddenPhrases[j].Phrase)
+			if phrase == "" {
+				continue
+			}
+			pc.ReleaseNote[i].ForbiddenPhrases[j].Re = forbiddenPhraseRegexp(phrase)
+		}
+	}
+
+	for i := range pc.SemanticTitle {
+		if pc.SemanticTitle[i].Regexp == "" {
+			continue
+		}
+		re, err := regexp.Compile(pc.SemanticTitle[i].Regexp)
+		if err != nil {
+			return fmt.Errorf("failed to compile semantic_title regexp: %q, error: %w", pc.SemanticTitle[i].Regexp, err)
+		}
+		pc.SemanticTitle[i].Re = re
+	}
+
+	for i := range pc.Triggers {
+		if pc.Triggers[i].BatchWindow == "" {
+			continue
+		}
+		batchWindow, err := time.ParseDuration(pc.Triggers[i].BatchWindow)
+		if err != nil {
+			return fmt.Errorf("failed to compile trigger batch_window duration: %q, error: %w", pc.Triggers[i].BatchWindow, err)
+		}
+		pc.Triggers[i].BatchWindowDuration = batchWindow
+	}
+
+	for i := range pc.Stale {
+		for _, d := range []struct {
+			name  string
+			value string
+			dest  *time.Duration
+		}{
+			{name: "stale_after", value: pc.Stale[i].StaleAfter, dest: &pc.Stale[i].StaleAfterDuration},
+			{name: "rotten_after", value: pc.Stale[i].RottenAfter, dest: &pc.Stale[i].RottenAfterDuration},
+			{name: "close_after", value: pc.Stale[i].CloseAfter, dest: &pc.Stale[i].CloseAfterDuration},
+		} {
+			dur, err := time.ParseDuration(d.value)
+			if err != nil {
+				return fmt.Errorf("failed to compile stale %s duration: %q, error: %w", d.name, d.value, err)
+			}
+			*d.dest = dur
+		}
+	}
+
+	for i := range pc.BackportTracker {
+		dur, err := time.ParseDuration(pc.BackportTracker[i].PingInterval)
+		if err != nil {
+			return fmt.Errorf("failed to compile backport_tracker ping_interval duration: %q, error: %w", pc.BackportTracker[i].PingInterval, err)
+		}
+		pc.BackportTracker[i].PingIntervalDuration = dur
+	}
+
+	for i := range pc.Lgtm {
+		if pc.Lgtm[i].StaleAfter == "" {
+			continue
+		}
+		dur, err := time.ParseDuration(pc.Lgtm[i].StaleAfter)
+		if err != nil {
+			return fmt.Errorf("failed to compile lgtm stale_after duration: %q, error: %w", pc.Lgtm[i].StaleAfter, err)
+		}
+		pc.Lgtm[i].StaleAfterDuration = dur
+	}
 	return nil
 }
 
@@ -1316,21 +2560,72 @@ func (c *Configuration) Validate() error {
 	if err := validateBlunderbuss(&c.Blunderbuss); err != nil {
 		return err
 	}
+	if err := validateAssign(&c.Assign); err != nil {
+		return err
+	}
 	if err := validateConfigUpdater(&c.ConfigUpdater); err != nil {
 		return err
 	}
+	if err := validateApprove(c.Approve); err != nil {
+		return err
+	}
+	if err := validateLabel(c.Label); err != nil {
+		return err
+	}
+	if err := validateOwners(c.Owners); err != nil {
+		return err
+	}
+	if err := validateLgtm(c.Lgtm); err != nil {
+		return err
+	}
 	if err := validateSizes(c.Size); err != nil {
 		return err
 	}
 	if err := validateRequireMatchingLabel(c.RequireMatchingLabel); err != nil {
 		return err
 	}
+	if err := validateRequiredReviewers(c.RequiredReviewers); err != nil {
+		return err
+	}
+	if err := validateAPIReview(c.APIReview); err != nil {
+		return err
+	}
+	if err := validateDedupe(c.Dedupe); err != nil {
+		return err
+	}
+	if err := validateDcoCla(c.DcoCla); err != nil {
+		return err
+	}
+	if err := validateReleaseNote(c.ReleaseNote); err != nil {
+		return err
+	}
+	if err := validateSemanticTitle(c.SemanticTitle); err != nil {
+		return err
+	}
+	if err := validateStale(c.Stale); err != nil {
+		return err
+	}
+	if err := validateBackportTracker(c.BackportTracker); err != nil {
+		return err
+	}
 	if err := validateProjectManager(c.ProjectManager); err != nil {
 		return err
 	}
 	if err := validateTrigger(c.Triggers); err != nil {
 		return err
 	}
+	if err := validateConcurrency(c.Concurrency); err != nil {
+		return err
+	}
+	if err := validateExternalPluginHealth(c.ExternalPluginHealth); err != nil {
+		return err
+	}
+	if err := validateFeatureFlags(c.FeatureFlags); err != nil {
+		return err
+	}
+	if err := validateCommandAliases(c.CommandAliases); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -1871,6 +3166,9 @@ type Override struct {
 	// AllowedGitHubTeams is a map of orgs and/or repositories (eg "org" or "org/repo") to list of GitHub team slugs,
 	// members of which are allowed to override contexts
 	AllowedGitHubTeams map[string][]string `json:"allowed_github_teams,omitempty"`
+	// RequireReason is a map of orgs and/or repositories (eg "org" or "org/repo") to lists of
+	// protected contexts, which can only be overridden with a reason given with --reason.
+	RequireReason map[string][]string `json:"require_reason,omitempty"`
 }
 
 func (c *Configuration) mergeFrom(other *Configuration) error {
@@ -2053,7 +3351,8 @@ func (c *Configuration) HasConfigFor() (global bool, orgs sets.String, repos set
 		}
 	}
 
-	if len(c.Label.AdditionalLabels) > 0 {
+	// Missing labels are created from the same file for every repo.
+	if len(c.Label.AdditionalLabels) > 0 || c.Label.AutoCreateFrom != "" {
 		global = true
 	}
 	for key := range c.Label.RestrictedLabels {
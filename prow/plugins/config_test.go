@@ -1506,6 +1506,327 @@ orgA/repoB:
 	}
 }
 
+func TestPluginEnabledFor(t *testing.T) {
+	pluginsYaml := []byte(`
+orgA:
+ excluded_repos:
+ - repoB
+ - repoC
+ plugins:
+ - pluginCommon
+ - pluginNotForRepoB
+orgA/repoB:
+ plugins:
+ - pluginCommon
+ - pluginOnlyForRepoB
+`)
+	var p Plugins
+	if err := yaml.Unmarshal(pluginsYaml, &p); err != nil {
+		t.Fatalf("cannot unmarshal plugins config: %v", err)
+	}
+	cfg := Configuration{Plugins: p}
+	testCases := []struct {
+		name        string
+		wantEnabled sets.String
+	}{
+		{
+			name:        "pluginCommon",
+			wantEnabled: sets.NewString("orgA/repoA", "orgA/repoB"),
+		},
+		{
+			name:        "pluginNotForRepoB",
+			wantEnabled: sets.NewString("orgA/repoA"),
+		},
+		{
+			name:        "pluginOnlyForRepoB",
+			wantEnabled: sets.NewString("orgA/repoB"),
+		},
+		{
+			name:        "pluginNotEnabled",
+			wantEnabled: sets.NewString(),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			enabled := sets.NewString()
+			for _, repo := range []string{"repoA", "repoB", "repoC"} {
+				if cfg.PluginEnabledFor(tc.name, "orgA", repo) {
+					enabled.Insert("orgA/" + repo)
+				}
+			}
+			if diff := cmp.Diff(tc.wantEnabled.List(), enabled.List()); diff != "" {
+				t.Errorf("expected enabled repos differ from actual: %s", diff)
+			}
+		})
+	}
+}
+
+func TestEnabledReposQuery(t *testing.T) {
+	pluginsYaml := []byte(`
+orgA:
+ excluded_repos:
+ - repoB
+ - repoC
+ plugins:
+ - pluginCommon
+ - pluginNotForRepoB
+orgA/repoB:
+ plugins:
+ - pluginCommon
+ - pluginOnlyForRepoB
+`)
+	var p Plugins
+	if err := yaml.Unmarshal(pluginsYaml, &p); err != nil {
+		t.Fatalf("cannot unmarshal plugins config: %v", err)
+	}
+	cfg := Configuration{Plugins: p}
+	testCases := []struct {
+		name      string
+		wantQuery string
+	}{
+		{
+			name:      "pluginCommon",
+			wantQuery: "org:orgA -repo:orgA/repoC repo:orgA/repoB",
+		},
+		{
+			name:      "pluginNotForRepoB",
+			wantQuery: "org:orgA -repo:orgA/repoB -repo:orgA/repoC",
+		},
+		{
+			name:      "pluginOnlyForRepoB",
+			wantQuery: "repo:orgA/repoB",
+		},
+		{
+			name: "pluginNotEnabled",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if query := cfg.EnabledReposQuery(tc.name); query != tc.wantQuery {
+				t.Errorf("expected query %q, got %q", tc.wantQuery, query)
+			}
+		})
+	}
+}
+
+func TestDryRunFor(t *testing.T) {
+	testCases := []struct {
+		name     string
+		dryRun   DryRun
+		plugin   string
+		expected bool
+	}{
+		{
+			name:     "no dry run configured",
+			plugin:   "lgtm",
+			expected: false,
+		},
+		{
+			name:     "all plugins in dry run",
+			dryRun:   DryRun{All: true},
+			plugin:   "lgtm",
+			expected: true,
+		},
+		{
+			name:     "plugin listed for dry run",
+			dryRun:   DryRun{Plugins: []string{"approve", "lgtm"}},
+			plugin:   "lgtm",
+			expected: true,
+		},
+		{
+			name:     "plugin not listed for dry run",
+			dryRun:   DryRun{Plugins: []string{"approve"}},
+			plugin:   "lgtm",
+			expected: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Configuration{DryRun: tc.dryRun}
+			if actual := cfg.DryRunFor(tc.plugin); actual != tc.expected {
+				t.Errorf("expected dry run to be %t for plugin %q, got %t", tc.expected, tc.plugin, actual)
+			}
+		})
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	c := Concurrency{DefaultPerPlugin: 10, PerPlugin: map[string]int{"owners-label": 2, "trigger": 0}}
+	for plugin, expected := range map[string]int{"owners-label": 2, "trigger": 0, "lgtm": 10} {
+		if actual := c.PluginLimit(plugin); actual != expected {
+			t.Errorf("expected limit %d for plugin %q, got %d", expected, plugin, actual)
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		concurrency Concurrency
+		expectErr   bool
+	}{
+		{
+			name:        "limits are valid",
+			concurrency: c,
+		},
+		{
+			name:        "negative default",
+			concurrency: Concurrency{DefaultPerPlugin: -1},
+			expectErr:   true,
+		},
+		{
+			name:        "negative plugin limit",
+			concurrency: Concurrency{PerPlugin: map[string]int{"lgtm": -1}},
+			expectErr:   true,
+		},
+		{
+			name:        "negative org limit",
+			concurrency: Concurrency{PerOrg: -1},
+			expectErr:   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateConcurrency(tc.concurrency); (err != nil) != tc.expectErr {
+				t.Errorf("expected error: %t, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestFeatureFlag(t *testing.T) {
+	c := &Configuration{FeatureFlags: map[string]FeatureFlag{
+		"off":    {},
+		"on":     {Percentage: 100},
+		"canary": {Canaries: []string{"org", "other/repo"}, Excluded: []string{"org/excluded"}},
+	}}
+	testCases := []struct {
+		flag, org, repo string
+		expected        bool
+	}{
+		{flag: "unknown", org: "org", repo: "repo", expected: false},
+		{flag: "off", org: "org", repo: "repo", expected: false},
+		{flag: "on", org: "org", repo: "repo", expected: true},
+		{flag: "canary", org: "org", repo: "repo", expected: true},
+		{flag: "canary", org: "org", repo: "excluded", expected: false},
+		{flag: "canary", org: "other", repo: "repo", expected: true},
+		{flag: "canary", org: "other", repo: "else", expected: false},
+	}
+	for _, tc := range testCases {
+		if actual := c.FeatureEnabled(tc.flag, tc.org, tc.repo); actual != tc.expected {
+			t.Errorf("expected flag %s to be enabled for %s/%s: %t, got %t", tc.flag, tc.org, tc.repo, tc.expected, actual)
+		}
+	}
+
+	// Raising the percentage only enables the flag for more repos.
+	enabled := map[int]int{}
+	for _, percentage := range []int{10, 50, 90} {
+		flag := FeatureFlag{Percentage: percentage}
+		for i := 0; i < 1000; i++ {
+			repo := fmt.Sprintf("repo-%d", i)
+			if !flag.Enabled("rollout", "org", repo) {
+				continue
+			}
+			enabled[percentage]++
+			if percentage < 90 && !(FeatureFlag{Percentage: 90}).Enabled("rollout", "org", repo) {
+				t.Errorf("expected %s enabled at %d%% to stay enabled at 90%%", repo, percentage)
+			}
+		}
+	}
+	if enabled[10] >= enabled[50] || enabled[50] >= enabled[90] || enabled[50] < 400 || enabled[50] > 600 {
+		t.Errorf("expected the enabled repos to grow with the percentage, got %v out of 1000", enabled)
+	}
+
+	for name, flags := range map[string]map[string]FeatureFlag{
+		"percentage above 100": {"flag": {Percentage: 101}},
+		"empty canary":         {"flag": {Canaries: []string{""}}},
+		"invalid exclusion":    {"flag": {Excluded: []string{"org/repo/path"}}},
+	} {
+		if err := validateFeatureFlags(flags); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCommandAliases(t *testing.T) {
+	c := &Configuration{CommandAliases: map[string]map[string]string{
+		"org":       {"ship-it": "lgtm", "freeze": "hold"},
+		"org/repo":  {"Freeze": "lifecycle frozen"},
+		"other/org": {"ship-it": "approve"},
+	}}
+	expected := map[string]string{"ship-it": "lgtm", "freeze": "lifecycle frozen"}
+	if diff := cmp.Diff(expected, c.CommandAliasesFor("org", "repo")); diff != "" {
+		t.Errorf("aliases differ from expected: %s", diff)
+	}
+	if actual := c.CommandAliasesFor("else", "repo"); len(actual) != 0 {
+		t.Errorf("expected no aliases, got %v", actual)
+	}
+
+	for name, aliases := range map[string]map[string]string{
+		"slash in alias":   {"/ship-it": "lgtm"},
+		"space in alias":   {"ship it": "lgtm"},
+		"empty command":    {"ship-it": " "},
+		"slash in command": {"ship-it": "/lgtm"},
+		"aliases itself":   {"lgtm": "LGTM cancel"},
+	} {
+		if err := validateCommandAliases(map[string]map[string]string{"org": aliases}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if err := validateCommandAliases(c.CommandAliases); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestExternalPluginHealth(t *testing.T) {
+	testCases := []struct {
+		name      string
+		health    ExternalPluginHealth
+		expected  ExternalPluginHealth
+		expectErr bool
+	}{
+		{
+			name: "defaults",
+			expected: ExternalPluginHealth{
+				Interval:           "1m",
+				IntervalDuration:   time.Minute,
+				Timeout:            "5s",
+				TimeoutDuration:    5 * time.Second,
+				UnhealthyThreshold: 3,
+			},
+		},
+		{
+			name:      "invalid interval",
+			health:    ExternalPluginHealth{Interval: "often"},
+			expectErr: true,
+		},
+		{
+			name:      "negative timeout",
+			health:    ExternalPluginHealth{Timeout: "-1s"},
+			expectErr: true,
+		},
+		{
+			name:      "negative threshold",
+			health:    ExternalPluginHealth{UnhealthyThreshold: -1},
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Configuration{ExternalPluginHealth: tc.health}
+			c.setDefaults()
+			err := c.ExternalPluginHealth.compileDurations()
+			if err == nil {
+				err = validateExternalPluginHealth(c.ExternalPluginHealth)
+			}
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error: %t, got %v", tc.expectErr, err)
+			}
+			if err == nil && c.ExternalPluginHealth != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, c.ExternalPluginHealth)
+			}
+		})
+	}
+}
+
 func TestPluginsUnmarshalFailed(t *testing.T) {
 	badPluginsYaml := []byte(`
 orgA:
@@ -2113,7 +2434,7 @@ func TestHasConfigFor(t *testing.T) {
 			name: "Any config with label.restricted_labels is considered to be for the org and repos references there",
 			resultGenerator: func(fuzzedConfig *Configuration) (toCheck *Configuration, expectGlobal bool, expectOrgs sets.String, expectRepos sets.String) {
 				fuzzedConfig = &Configuration{Label: fuzzedConfig.Label}
-				if len(fuzzedConfig.Label.AdditionalLabels) > 0 {
+				if len(fuzzedConfig.Label.AdditionalLabels) > 0 || fuzzedConfig.Label.AutoCreateFrom != "" {
 					expectGlobal = true
 				}
 
@@ -2262,3 +2583,43 @@ func TestMergeFrom(t *testing.T) {
 		}
 	}
 }
+
+func TestLabelAutoCreateSource(t *testing.T) {
+	testCases := []struct {
+		name           string
+		autoCreateFrom string
+		expectedOrg    string
+		expectedRepo   string
+		expectedPath   string
+		expectedErr    bool
+	}{
+		{
+			name:           "valid location",
+			autoCreateFrom: "kubernetes/test-infra:label_sync/labels.yaml",
+			expectedOrg:    "kubernetes",
+			expectedRepo:   "test-infra",
+			expectedPath:   "label_sync/labels.yaml",
+		},
+		{
+			name:           "missing path",
+			autoCreateFrom: "kubernetes/test-infra",
+			expectedErr:    true,
+		},
+		{
+			name:           "missing repo",
+			autoCreateFrom: "kubernetes:labels.yaml",
+			expectedErr:    true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			org, repo, path, err := Label{AutoCreateFrom: tc.autoCreateFrom}.AutoCreateSource()
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error: %t, got: %v", tc.expectedErr, err)
+			}
+			if org != tc.expectedOrg || repo != tc.expectedRepo || path != tc.expectedPath {
+				t.Errorf("expected %s/%s:%s, got %s/%s:%s", tc.expectedOrg, tc.expectedRepo, tc.expectedPath, org, repo, path)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dcocla
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// claClient asks a CLA service whether commit authors signed the CLA.
+// Authors that signed the CLA are cached, so that every push to a PR does not
+// hit the service. Authors that did not are always looked up again, so that
+// they are recognized as soon as they sign it.
+type claClient struct {
+	httpClient *http.Client
+	backoff    wait.Backoff
+	now        func() time.Time
+
+	lock  sync.Mutex
+	cache map[string]claCacheEntry
+}
+
+type claCacheEntry struct {
+	expires time.Time
+}
+
+// claResponse is the answer of the CLA service.
+type claResponse struct {
+	Signed bool `json:"signed"`
+}
+
+func newCLAClient() *claClient {
+	return &claClient{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		backoff:    wait.Backoff{Duration: 250 * time.Millisecond, Factor: 2.0, Jitter: 0.1, Steps: 4},
+		now:        time.Now,
+		cache:      map[string]claCacheEntry{},
+	}
+}
+
+// signed returns whether the author with the given login and email signed
+// the CLA of the service. Requests failing with a network error or a 429 or
+// 5XX status are retried with an exponential backoff. If fresh is set, the
+// service is asked even if the author is cached.
+func (c *claClient) signed(serviceURL, login, email string, ttl time.Duration, fresh bool) (bool, error) {
+	key := strings.Join([]string{serviceURL, strings.ToLower(login), strings.ToLower(email)}, "\x00")
+	c.lock.Lock()
+	entry, cached := c.cache[key]
+	c.lock.Unlock()
+	if !fresh && cached && c.now().Before(entry.expires) {
+		return true, nil
+	}
+
+	u, err := url.Parse(serviceURL)
+	if err != nil {
+		return false, fmt.Errorf("invalid CLA service URL %q: %w", serviceURL, err)
+	}
+	query := u.Query()
+	query.Set("login", login)
+	query.Set("email", email)
+	u.RawQuery = query.Encode()
+
+	var signed bool
+	var errs []error
+	if err := wait.ExponentialBackoff(c.backoff, func() (bool, error) {
+		resp, err := c.httpClient.Get(u.String())
+		if err != nil {
+			// Store and swallow errors, if we end up timing out we will return all of them
+			errs = append(errs, err)
+			return false, nil
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+			errs = append(errs, fmt.Errorf("CLA service returned status %d", resp.StatusCode))
+			return false, nil
+		}
+		if resp.StatusCode != http.StatusOK {
+			return false, fmt.Errorf("CLA service returned status %d", resp.StatusCode)
+		}
+		var r claResponse
+		if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+			return false, fmt.Errorf("failed to decode the CLA service response: %w", err)
+		}
+		signed = r.Signed
+		return true, nil
+	}); err != nil {
+		if err != wait.ErrWaitTimeout {
+			return false, err
+		}
+		return false, utilerrors.NewAggregate(errs)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := c.now()
+	for k, e := range c.cache {
+		if !now.Before(e.expires) {
+			delete(c.cache, k)
+		}
+	}
+	if signed {
+		c.cache[key] = claCacheEntry{expires: now.Add(ttl)}
+	} else {
+		delete(c.cache, key)
+	}
+	return signed, nil
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dcocla
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func newTestCLAClient(now func() time.Time) *claClient {
+	c := newCLAClient()
+	c.backoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+	c.now = now
+	return c
+}
+
+func TestCLAClientSigned(t *testing.T) {
+	testCases := []struct {
+		name             string
+		statuses         []int
+		login            string
+		expectedSigned   bool
+		expectedErr      bool
+		expectedRequests int
+	}{
+		{
+			name:             "signed",
+			statuses:         []int{http.StatusOK},
+			login:            "alice",
+			expectedSigned:   true,
+			expectedRequests: 1,
+		},
+		{
+			name:             "not signed",
+			statuses:         []int{http.StatusOK},
+			login:            "bob",
+			expectedRequests: 1,
+		},
+		{
+			name:             "server errors are retried",
+			statuses:         []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusOK},
+			login:            "alice",
+			expectedSigned:   true,
+			expectedRequests: 3,
+		},
+		{
+			name:             "retries are bounded",
+			statuses:         []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			login:            "alice",
+			expectedErr:      true,
+			expectedRequests: 3,
+		},
+		{
+			name:             "client errors are not retried",
+			statuses:         []int{http.StatusNotFound, http.StatusOK},
+			login:            "alice",
+			expectedErr:      true,
+			expectedRequests: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tc.statuses[requests]
+				requests++
+				w.WriteHeader(status)
+				fmt.Fprintf(w, `{"signed": %t}`, r.URL.Query().Get("login") == "alice" && r.URL.Query().Get("email") == "alice@example.com")
+			}))
+			defer server.Close()
+
+			signed, err := newTestCLAClient(time.Now).signed(server.URL, tc.login, tc.login+"@example.com", time.Hour, false)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %t, got %v", tc.expectedErr, err)
+			}
+			if signed != tc.expectedSigned {
+				t.Errorf("expected signed %t, got %t", tc.expectedSigned, signed)
+			}
+			if requests != tc.expectedRequests {
+				t.Errorf("expected %d requests, got %d", tc.expectedRequests, requests)
+			}
+		})
+	}
+}
+
+func TestCLAClientCache(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"signed": true}`)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	c := newTestCLAClient(func() time.Time { return now })
+	for _, step := range []struct {
+		login            string
+		after            time.Duration
+		expectedRequests int
+	}{
+		{login: "alice", expectedRequests: 1},
+		{login: "Alice", after: 30 * time.Minute, expectedRequests: 1},
+		{login: "bob", expectedRequests: 2},
+		{login: "alice", after: 30 * time.Minute, expectedRequests: 3},
+	} {
+		now = now.Add(step.after)
+		if _, err := c.signed(server.URL, step.login, "", time.Hour, false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if requests != step.expectedRequests {
+			t.Errorf("after looking up %s, expected %d requests, got %d", step.login, step.expectedRequests, requests)
+		}
+	}
+	if len(c.cache) != 2 {
+		t.Errorf("expected expired entries to be evicted, got %d cached entries", len(c.cache))
+	}
+}
+
+func TestCLAClientCacheOnlySigned(t *testing.T) {
+	var requests int
+	signed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{"signed": %t}`, signed)
+	}))
+	defer server.Close()
+
+	c := newTestCLAClient(time.Now)
+	for _, step := range []struct {
+		name             string
+		signs            bool
+		fresh            bool
+		expectedSigned   bool
+		expectedRequests int
+	}{
+		{name: "unsigned author is looked up", expectedRequests: 1},
+		{name: "unsigned author is not cached", expectedRequests: 2},
+		{name: "author signs the CLA", signs: true, expectedSigned: true, expectedRequests: 3},
+		{name: "signed author is cached", signs: true, expectedSigned: true, expectedRequests: 3},
+		{name: "fresh lookup skips the cache", fresh: true, expectedRequests: 4},
+		{name: "author that is no longer signed is not cached", expectedRequests: 5},
+	} {
+		signed = step.signs
+		actual, err := c.signed(server.URL, "alice", "", time.Hour, step.fresh)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", step.name, err)
+		}
+		if actual != step.expectedSigned {
+			t.Errorf("%s: expected signed %t, got %t", step.name, step.expectedSigned, actual)
+		}
+		if requests != step.expectedRequests {
+			t.Errorf("%s: expected %d requests, got %d", step.name, step.expectedRequests, requests)
+		}
+	}
+}
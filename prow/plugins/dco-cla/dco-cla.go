@@ -0,0 +1,235 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dcocla implements the `dco-cla` plugin. It requires every commit
+// of a PR to either carry a DCO signoff or, when a CLA service is configured,
+// to be authored by someone who signed the CLA.
+package dcocla
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/pluginhelp"
+	"k8s.io/test-infra/prow/plugins"
+	"k8s.io/test-infra/prow/plugins/dco"
+)
+
+const (
+	// PluginName defines this plugin's registered name.
+	PluginName = "dco-cla"
+
+	contextName           = "dco-cla"
+	contextMessageFailed  = "Commits in PR are neither signed off nor covered by a CLA"
+	contextMessageSuccess = "All commits are signed off or covered by a CLA"
+
+	msgPruneMatch          = "Thanks for your pull request. Before we can look at it, every commit needs a 'DCO signoff'"
+	notCompliantMessageFmt = `Thanks for your pull request. Before we can look at it, every commit needs a 'DCO signoff'%s.
+
+:memo: **Please follow instructions in the [contributing guide](%s) to update your commits.**
+
+**The list of commits missing a DCO signoff%s**:
+
+%s
+
+<details>
+
+%s
+</details>
+`
+)
+
+var checkRe = regexp.MustCompile(`(?mi)^/check-dco-cla\s*$`)
+
+// defaultCLAClient is shared by all events so that CLA lookups are cached.
+var defaultCLAClient = newCLAClient()
+
+type githubClient interface {
+	AddLabel(org, repo string, number int, label string) error
+	RemoveLabel(org, repo string, number int, label string) error
+	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
+	CreateComment(org, repo string, number int, comment string) error
+	CreateStatus(org, repo, ref string, status github.Status) error
+	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
+	ListPRCommits(org, repo string, number int) ([]github.RepositoryCommit, error)
+}
+
+type commentPruner interface {
+	PruneComments(shouldPrune func(github.IssueComment) bool)
+}
+
+func init() {
+	plugins.RegisterPullRequestHandler(PluginName, handlePullRequestEvent, helpProvider)
+	plugins.RegisterGenericCommentHandler(PluginName, handleCommentEvent, helpProvider)
+}
+
+func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	configInfo := map[string]string{}
+	for _, repo := range enabledRepos {
+		opts := config.DcoClaFor(repo.Org, repo.Repo)
+		if opts.ClaURL != "" {
+			configInfo[repo.String()] = fmt.Sprintf("Commits must be signed off or authored by someone who signed the CLA according to %s.", opts.ClaURL)
+		} else {
+			configInfo[repo.String()] = "Commits must be signed off."
+		}
+	}
+	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
+		DcoCla: map[string]*plugins.DcoCla{
+			"org": {
+				ClaURL:   "https://cla.example.com/check",
+				CacheTTL: "1h",
+				YesLabel: "cncf-cla: yes",
+				NoLabel:  "cncf-cla: no",
+			},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", PluginName)
+	}
+	pluginHelp := &pluginhelp.PluginHelp{
+		Description: "The dco-cla plugin requires every commit of a pull request to carry a 'DCO sign off' or, if a CLA service is configured for the org or repo, to be authored by someone who signed the CLA. It maintains the '" + contextName + "' status context as well as the configured yes and no labels.",
+		Config:      configInfo,
+		Snippet:     yamlSnippet,
+	}
+	pluginHelp.AddCommand(pluginhelp.Command{
+		Usage:       "/check-dco-cla",
+		Description: "Forces rechecking of the DCO and CLA status.",
+		Featured:    true,
+		WhoCanUse:   "Anyone",
+		Examples:    []string{"/check-dco-cla"},
+	})
+	return pluginHelp, nil
+}
+
+func handlePullRequestEvent(pc plugins.Agent, pe github.PullRequestEvent) error {
+	switch pe.Action {
+	case github.PullRequestActionOpened,
+		github.PullRequestActionReopened,
+		github.PullRequestActionSynchronize:
+	default:
+		return nil
+	}
+	cp, err := pc.CommentPruner()
+	if err != nil {
+		return err
+	}
+	cfg := pc.PluginConfig.DcoClaFor(pe.Repo.Owner.Login, pe.Repo.Name)
+	shouldComment := pe.Action != github.PullRequestActionReopened
+	return handle(pc.Logger, pc.GitHubClient, defaultCLAClient, cp, cfg, pe.Repo.Owner.Login, pe.Repo.Name, pe.PullRequest, shouldComment, false)
+}
+
+func handleCommentEvent(pc plugins.Agent, ce github.GenericCommentEvent) error {
+	// Only consider open PRs and new "/check-dco-cla" comments.
+	if ce.IssueState != "open" || ce.Action != github.GenericCommentActionCreated || !ce.IsPR || !checkRe.MatchString(ce.Body) {
+		return nil
+	}
+	pr, err := pc.GitHubClient.GetPullRequest(ce.Repo.Owner.Login, ce.Repo.Name, ce.Number)
+	if err != nil {
+		return fmt.Errorf("error getting pull request for comment: %w", err)
+	}
+	cp, err := pc.CommentPruner()
+	if err != nil {
+		return err
+	}
+	cfg := pc.PluginConfig.DcoClaFor(ce.Repo.Owner.Login, ce.Repo.Name)
+	// An explicit recheck is usually asked for right after signing the CLA.
+	return handle(pc.Logger, pc.GitHubClient, defaultCLAClient, cp, cfg, ce.Repo.Owner.Login, ce.Repo.Name, *pr, true, true)
+}
+
+// 1. List the commits missing a DCO signoff.
+// 2. Ask the CLA service, if any, whether their authors signed the CLA.
+// 3. Apply the labels and the status context matching the result.
+// 4. If some commits are not compliant, comment with the list of commits.
+// If recheck is set, the CLA service is asked again about cached authors.
+func handle(log *logrus.Entry, ghc githubClient, cc *claClient, cp commentPruner, cfg *plugins.DcoCla, org, repo string, pr github.PullRequest, addComment, recheck bool) error {
+	l := log.WithField("pr", pr.Number)
+
+	commits, err := ghc.ListPRCommits(org, repo, pr.Number)
+	if err != nil {
+		return fmt.Errorf("error listing commits for pull request: %w", err)
+	}
+	var notCompliant []github.RepositoryCommit
+	for _, commit := range commits {
+		if dco.SignedOff(commit.Commit.Message) {
+			continue
+		}
+		if cfg.ClaURL != "" {
+			signed, err := cc.signed(cfg.ClaURL, commit.Author.Login, commit.Commit.Author.Email, cfg.CacheTTLDuration, recheck)
+			if err != nil {
+				// Keep the current labels and status rather than flagging commits
+				// because the CLA service is unavailable.
+				return fmt.Errorf("error checking the CLA of the author of %s: %w", commit.SHA, err)
+			}
+			if signed {
+				continue
+			}
+		}
+		notCompliant = append(notCompliant, commit)
+	}
+	l.Debugf("Commits in PR neither signed off nor covered by a CLA: %d", len(notCompliant))
+
+	issueLabels, err := ghc.GetIssueLabels(org, repo, pr.Number)
+	if err != nil {
+		return fmt.Errorf("error getting pull request labels: %w", err)
+	}
+	addLabel, removeLabel := cfg.YesLabel, cfg.NoLabel
+	status := github.Status{
+		Context:     contextName,
+		State:       github.StatusSuccess,
+		TargetURL:   fmt.Sprintf("https://github.com/%s/%s/blob/master/CONTRIBUTING.md", org, repo),
+		Description: contextMessageSuccess,
+	}
+	if len(notCompliant) > 0 {
+		addLabel, removeLabel = cfg.NoLabel, cfg.YesLabel
+		status.State = github.StatusFailure
+		status.Description = contextMessageFailed
+	}
+	if github.HasLabel(removeLabel, issueLabels) {
+		if err := ghc.RemoveLabel(org, repo, pr.Number, removeLabel); err != nil {
+			return fmt.Errorf("error removing label: %w", err)
+		}
+	}
+	if !github.HasLabel(addLabel, issueLabels) {
+		if err := ghc.AddLabel(org, repo, pr.Number, addLabel); err != nil {
+			return fmt.Errorf("error adding label: %w", err)
+		}
+	}
+	if err := ghc.CreateStatus(org, repo, pr.Head.SHA, status); err != nil {
+		return fmt.Errorf("error setting pull request status: %w", err)
+	}
+
+	if len(notCompliant) == 0 || addComment {
+		cp.PruneComments(func(comment github.IssueComment) bool {
+			return strings.Contains(comment.Body, msgPruneMatch)
+		})
+	}
+	if len(notCompliant) > 0 && addComment {
+		var orCLA, andCLA string
+		if cfg.ClaURL != "" {
+			orCLA = " or to be authored by someone who signed the CLA"
+			andCLA = " and not covered by a CLA"
+		}
+		if err := ghc.CreateComment(org, repo, pr.Number, fmt.Sprintf(notCompliantMessageFmt, orCLA, status.TargetURL, andCLA, dco.MarkdownSHAList(org, repo, notCompliant), plugins.AboutThisBot)); err != nil {
+			l.WithError(err).Warning("Could not create the non compliant commits comment.")
+		}
+	}
+	return nil
+}
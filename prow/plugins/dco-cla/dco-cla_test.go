@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dcocla
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+type fakePruner struct {
+	pruned int
+}
+
+func (fp *fakePruner) PruneComments(shouldPrune func(github.IssueComment) bool) {
+	fp.pruned++
+}
+
+func commit(sha, login, message string) github.RepositoryCommit {
+	return github.RepositoryCommit{
+		SHA:    sha,
+		Author: github.User{Login: login},
+		Commit: github.GitCommit{Message: message},
+	}
+}
+
+func TestHandle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"signed": %t}`, r.URL.Query().Get("login") == "signer")
+	}))
+	defer server.Close()
+	yes := "org/repo#1:" + labels.ClaYes
+	no := "org/repo#1:" + labels.ClaNo
+
+	testCases := []struct {
+		name            string
+		claURL          string
+		commits         []github.RepositoryCommit
+		existingLabels  []string
+		addComment      bool
+		expectedAdded   []string
+		expectedRemoved []string
+		expectedState   string
+		expectedComment string
+	}{
+		{
+			name:          "signed off commits",
+			commits:       []github.RepositoryCommit{commit("sha1", "author", "fix\n\nSigned-off-by: Author <author@example.com>")},
+			addComment:    true,
+			expectedAdded: []string{yes},
+			expectedState: github.StatusSuccess,
+		},
+		{
+			name:            "commit without signoff and no CLA service",
+			commits:         []github.RepositoryCommit{commit("sha1", "signer", "fix")},
+			existingLabels:  []string{yes},
+			addComment:      true,
+			expectedAdded:   []string{no},
+			expectedRemoved: []string{yes},
+			expectedState:   github.StatusFailure,
+			expectedComment: "- [sha1](https://github.com/org/repo/commits/sha1) fix",
+		},
+		{
+			name:            "commit without signoff by a CLA signer",
+			claURL:          server.URL,
+			commits:         []github.RepositoryCommit{commit("sha1", "signer", "fix")},
+			existingLabels:  []string{no},
+			addComment:      true,
+			expectedAdded:   []string{yes},
+			expectedRemoved: []string{no},
+			expectedState:   github.StatusSuccess,
+		},
+		{
+			name:   "commit without signoff by someone who did not sign the CLA",
+			claURL: server.URL,
+			commits: []github.RepositoryCommit{
+				commit("sha1", "signer", "fix"),
+				commit("sha2", "other", "more fixes"),
+			},
+			addComment:      true,
+			expectedAdded:   []string{no},
+			expectedState:   github.StatusFailure,
+			expectedComment: "**The list of commits missing a DCO signoff and not covered by a CLA**:\n\n- [sha2](https://github.com/org/repo/commits/sha2) more fixes\n",
+		},
+		{
+			name:           "no comment requested",
+			commits:        []github.RepositoryCommit{commit("sha1", "author", "fix")},
+			existingLabels: []string{no},
+			expectedState:  github.StatusFailure,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.CommitMap = map[string][]github.RepositoryCommit{"org/repo#1": tc.commits}
+			fc.IssueLabelsExisting = tc.existingLabels
+			fp := &fakePruner{}
+			cfg := &plugins.DcoCla{ClaURL: tc.claURL, CacheTTLDuration: time.Hour, YesLabel: labels.ClaYes, NoLabel: labels.ClaNo}
+			pr := github.PullRequest{Number: 1, Head: github.PullRequestBranch{SHA: "head"}}
+
+			if err := handle(logrus.WithField("plugin", PluginName), fc, newTestCLAClient(time.Now), fp, cfg, "org", "repo", pr, tc.addComment, false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expectedAdded, fc.IssueLabelsAdded); diff != "" {
+				t.Errorf("added labels differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedRemoved, fc.IssueLabelsRemoved); diff != "" {
+				t.Errorf("removed labels differ from expected: %s", diff)
+			}
+			if statuses := fc.CreatedStatuses["head"]; len(statuses) != 1 || statuses[0].Context != contextName || statuses[0].State != tc.expectedState {
+				t.Errorf("expected a %s %q status, got %v", contextName, tc.expectedState, statuses)
+			}
+			comments := fc.IssueComments[1]
+			switch {
+			case tc.expectedComment == "" && len(comments) > 0:
+				t.Errorf("expected no comment, got %v", comments)
+			case tc.expectedComment != "" && (len(comments) != 1 || !strings.Contains(comments[0].Body, tc.expectedComment)):
+				t.Errorf("expected a comment containing %q, got %v", tc.expectedComment, comments)
+			}
+		})
+	}
+}
+
+func TestHandleCLAServiceUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	fc := fakegithub.NewFakeClient()
+	fc.CommitMap = map[string][]github.RepositoryCommit{"org/repo#1": {commit("sha1", "signer", "fix")}}
+	cfg := &plugins.DcoCla{ClaURL: server.URL, YesLabel: labels.ClaYes, NoLabel: labels.ClaNo}
+	pr := github.PullRequest{Number: 1, Head: github.PullRequestBranch{SHA: "head"}}
+
+	if err := handle(logrus.WithField("plugin", PluginName), fc, newTestCLAClient(time.Now), &fakePruner{}, cfg, "org", "repo", pr, true, false); err == nil {
+		t.Error("expected an error when the CLA service is unavailable")
+	}
+	if len(fc.IssueLabelsAdded) > 0 || len(fc.CreatedStatuses) > 0 {
+		t.Errorf("expected no label or status change, got labels %v and statuses %v", fc.IssueLabelsAdded, fc.CreatedStatuses)
+	}
+}
@@ -151,7 +151,7 @@ func checkCommitMessages(gc gitHubClient, l *logrus.Entry, org, repo string, num
 
 	var commitsMissingDCO []github.RepositoryCommit
 	for _, commit := range allCommits {
-		if !testRe.MatchString(commit.Commit.Message) {
+		if !SignedOff(commit.Commit.Message) {
 			commitsMissingDCO = append(commitsMissingDCO, commit)
 		}
 	}
@@ -318,6 +318,11 @@ func handle(config plugins.Dco, gc gitHubClient, cp commentPruner, log *logrus.E
 	return takeAction(gc, cp, l, org, repo, pr, commitsMissingDCO, existingStatus, hasYesLabel, hasNoLabel, addComment)
 }
 
+// SignedOff returns whether the commit message contains a DCO signoff.
+func SignedOff(message string) bool {
+	return testRe.MatchString(message)
+}
+
 // MarkdownSHAList prints the list of commits in a markdown-friendly way.
 func MarkdownSHAList(org, repo string, list []github.RepositoryCommit) string {
 	lines := make([]string, len(list))
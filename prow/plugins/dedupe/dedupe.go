@@ -0,0 +1,257 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dedupe implements the `dedupe` plugin. It suggests likely
+// duplicates of new issues among the recent open issues of the repo, and
+// lets collaborators close an issue as a duplicate of another one.
+package dedupe
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/pluginhelp"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// PluginName defines this plugin's registered name.
+const PluginName = "dedupe"
+
+// shingleSize is the number of consecutive words of the shingles compared
+// between issues.
+const shingleSize = 2
+
+const suggestionsCommentBody = `This issue may be a duplicate of:
+
+%s
+
+Triagers can close this issue as a duplicate of one of them by commenting ` + "`/close duplicate #<number>`" + `.
+
+<details>
+
+%s
+</details>
+`
+
+var (
+	closeDuplicateRe = regexp.MustCompile(`(?mi)^/close duplicate #?(\d+)\s*$`)
+	wordRe           = regexp.MustCompile(`[a-z0-9]+`)
+)
+
+type githubClient interface {
+	AddLabel(org, repo string, number int, label string) error
+	CloseIssue(org, repo string, number int) error
+	CreateComment(org, repo string, number int, comment string) error
+	FindIssues(query, sort string, asc bool) ([]github.Issue, error)
+	GetIssue(org, repo string, number int) (*github.Issue, error)
+	IsCollaborator(org, repo, user string) (bool, error)
+}
+
+func init() {
+	plugins.RegisterIssueHandler(PluginName, handleIssueEvent, helpProvider)
+	plugins.RegisterGenericCommentHandler(PluginName, handleGenericComment, helpProvider)
+}
+
+func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	configInfo := map[string]string{}
+	for _, repo := range enabledRepos {
+		opts := config.DedupeFor(repo.Org, repo.Repo)
+		configInfo[repo.String()] = fmt.Sprintf("Up to %d open issues created in the last %s with a similarity of at least %.0f%% are suggested as duplicates.", opts.MaxSuggestions, opts.MaxAgeDuration, opts.Threshold*100)
+	}
+	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
+		Dedupe: []plugins.Dedupe{
+			{
+				Repos:          []string{"org/repo"},
+				MaxAge:         "720h",
+				Threshold:      0.5,
+				MaxSuggestions: 5,
+			},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", PluginName)
+	}
+	pluginHelp := &pluginhelp.PluginHelp{
+		Description: "The dedupe plugin compares the title and body of new issues with the recent open issues of the repo, and comments with the most similar ones when they are likely duplicates.",
+		Config:      configInfo,
+		Snippet:     yamlSnippet,
+	}
+	pluginHelp.AddCommand(pluginhelp.Command{
+		Usage:       "/close duplicate #<number>",
+		Description: fmt.Sprintf("Closes the issue as a duplicate of another issue and applies the '%s' label.", labels.TriageDuplicate),
+		Featured:    false,
+		WhoCanUse:   "Collaborators of the repo.",
+		Examples:    []string{"/close duplicate #1234"},
+	})
+	return pluginHelp, nil
+}
+
+func handleIssueEvent(pc plugins.Agent, ie github.IssueEvent) error {
+	return handleIssue(pc.Logger, pc.GitHubClient, pc.PluginConfig.DedupeFor(ie.Repo.Owner.Login, ie.Repo.Name), ie, time.Now())
+}
+
+func handleIssue(log *logrus.Entry, ghc githubClient, cfg *plugins.Dedupe, ie github.IssueEvent, now time.Time) error {
+	if ie.Action != github.IssueActionOpened || ie.Issue.IsPullRequest() {
+		return nil
+	}
+	org := ie.Repo.Owner.Login
+	repo := ie.Repo.Name
+
+	query := fmt.Sprintf("is:issue is:open repo:%s/%s created:>=%s", org, repo, now.Add(-cfg.MaxAgeDuration).Format("2006-01-02"))
+	candidates, err := ghc.FindIssues(query, "created", false)
+	if err != nil {
+		return fmt.Errorf("failed to search for recent issues: %w", err)
+	}
+
+	issue := newDocument(ie.Issue)
+	var suggestions []suggestion
+	for _, candidate := range candidates {
+		if candidate.Number == ie.Issue.Number || candidate.IsPullRequest() {
+			continue
+		}
+		if score := issue.similarity(newDocument(candidate)); score >= cfg.Threshold {
+			suggestions = append(suggestions, suggestion{issue: candidate, score: score})
+		}
+	}
+	if len(suggestions) == 0 {
+		return nil
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].score != suggestions[j].score {
+			return suggestions[i].score > suggestions[j].score
+		}
+		return suggestions[i].issue.Number > suggestions[j].issue.Number
+	})
+	if len(suggestions) > cfg.MaxSuggestions {
+		suggestions = suggestions[:cfg.MaxSuggestions]
+	}
+
+	var lines []string
+	for _, s := range suggestions {
+		lines = append(lines, fmt.Sprintf("- #%d: %s (%.0f%% similar)", s.issue.Number, s.issue.Title, s.score*100))
+	}
+	log.Infof("Suggesting %d possible duplicates.", len(suggestions))
+	return ghc.CreateComment(org, repo, ie.Issue.Number, fmt.Sprintf(suggestionsCommentBody, strings.Join(lines, "\n"), plugins.AboutThisBot))
+}
+
+type suggestion struct {
+	issue github.Issue
+	score float64
+}
+
+// document holds the shingles of the title and the body of an issue.
+type document struct {
+	title, body sets.String
+}
+
+func newDocument(issue github.Issue) document {
+	return document{title: shingles(issue.Title), body: shingles(issue.Body)}
+}
+
+// shingles returns the sets of consecutive words of the text, ignoring case
+// and punctuation. Texts shorter than a shingle are a single shingle.
+func shingles(text string) sets.String {
+	words := wordRe.FindAllString(strings.ToLower(text), -1)
+	result := sets.NewString()
+	if len(words) > 0 && len(words) < shingleSize {
+		result.Insert(strings.Join(words, " "))
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		result.Insert(strings.Join(words[i:i+shingleSize], " "))
+	}
+	return result
+}
+
+// similarity is the average of the Jaccard indexes of the shingles of the
+// titles and of the bodies. Bodies are ignored when both are empty.
+func (d document) similarity(other document) float64 {
+	titles := jaccard(d.title, other.title)
+	if d.body.Len() == 0 && other.body.Len() == 0 {
+		return titles
+	}
+	return (titles + jaccard(d.body, other.body)) / 2
+}
+
+func jaccard(a, b sets.String) float64 {
+	union := a.Union(b).Len()
+	if union == 0 {
+		return 0
+	}
+	return float64(a.Intersection(b).Len()) / float64(union)
+}
+
+func handleGenericComment(pc plugins.Agent, e github.GenericCommentEvent) error {
+	return handleCloseDuplicate(pc.Logger, pc.GitHubClient, &e)
+}
+
+func handleCloseDuplicate(log *logrus.Entry, ghc githubClient, e *github.GenericCommentEvent) error {
+	// Only consider new comments on open issues.
+	if e.IsPR || e.IssueState != "open" || e.Action != github.GenericCommentActionCreated {
+		return nil
+	}
+	match := closeDuplicateRe.FindStringSubmatch(e.Body)
+	if match == nil {
+		return nil
+	}
+	org := e.Repo.Owner.Login
+	repo := e.Repo.Name
+	respond := func(resp string) error {
+		return ghc.CreateComment(org, repo, e.Number, plugins.FormatResponseRaw(e.Body, e.HTMLURL, e.User.Login, resp))
+	}
+
+	isCollaborator, err := ghc.IsCollaborator(org, repo, e.User.Login)
+	if err != nil {
+		return fmt.Errorf("failed to check if %s is a collaborator of %s/%s: %w", e.User.Login, org, repo, err)
+	}
+	if !isCollaborator {
+		return respond("only collaborators can close issues as duplicates.")
+	}
+
+	original, err := strconv.Atoi(match[1])
+	if err != nil {
+		return respond(fmt.Sprintf("%q is not a valid issue number.", match[1]))
+	}
+	if original == e.Number {
+		return respond("an issue cannot be a duplicate of itself.")
+	}
+	if _, err := ghc.GetIssue(org, repo, original); err != nil {
+		log.WithError(err).Infof("Failed to get issue #%d.", original)
+		return respond(fmt.Sprintf("cannot find issue #%d.", original))
+	}
+
+	if err := ghc.AddLabel(org, repo, e.Number, labels.TriageDuplicate); err != nil {
+		log.WithError(err).Warnf("Failed to add the %s label.", labels.TriageDuplicate)
+	}
+	// GitHub marks the issue as a duplicate when the comment starts with
+	// "Duplicate of #<number>".
+	comment := fmt.Sprintf("Duplicate of #%d\n\n%s", original, plugins.FormatResponseRaw(e.Body, e.HTMLURL, e.User.Login, "Closing this issue as a duplicate."))
+	if err := ghc.CreateComment(org, repo, e.Number, comment); err != nil {
+		return err
+	}
+	log.Infof("Closing issue as a duplicate of #%d.", original)
+	return ghc.CloseIssue(org, repo, e.Number)
+}
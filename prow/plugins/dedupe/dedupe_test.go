@@ -0,0 +1,231 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dedupe
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestSimilarity(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a, b     github.Issue
+		expected float64
+	}{
+		{
+			name:     "same title, no bodies",
+			a:        github.Issue{Title: "Hook crashes on startup"},
+			b:        github.Issue{Title: "hook crashes on startup!"},
+			expected: 1,
+		},
+		{
+			name:     "different titles, no bodies",
+			a:        github.Issue{Title: "Hook crashes on startup"},
+			b:        github.Issue{Title: "Tide does not merge"},
+			expected: 0,
+		},
+		{
+			name:     "same title, different bodies",
+			a:        github.Issue{Title: "Hook crashes", Body: "panic in the server"},
+			b:        github.Issue{Title: "Hook crashes", Body: "it stopped working"},
+			expected: 0.5,
+		},
+		{
+			name:     "overlapping titles",
+			a:        github.Issue{Title: "hook crashes on startup"},
+			b:        github.Issue{Title: "hook crashes on reload"},
+			expected: 0.5,
+		},
+		{
+			name:     "one word titles",
+			a:        github.Issue{Title: "Flake"},
+			b:        github.Issue{Title: "flake"},
+			expected: 1,
+		},
+		{
+			name: "empty issues",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := newDocument(tc.a).similarity(newDocument(tc.b)); got != tc.expected {
+				t.Errorf("expected similarity %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestHandleIssue(t *testing.T) {
+	cfg := &plugins.Dedupe{MaxAgeDuration: 24 * time.Hour, Threshold: 0.3, MaxSuggestions: 2}
+	existing := map[int]*github.Issue{
+		1: {Number: 1, Title: "Hook crashes on startup", Body: "The hook server panics when it starts."},
+		2: {Number: 2, Title: "hook crashes on startup", Body: "Since the last release the hook server panics when it starts."},
+		3: {Number: 3, Title: "Hook crashes on reload", Body: "The hook server panics when the config is reloaded."},
+		4: {Number: 4, Title: "Tide does not merge", Body: "PRs stay in the pool forever."},
+		5: {Number: 5, Title: "Hook crashes on startup", PullRequest: &struct{}{}},
+	}
+
+	testCases := []struct {
+		name             string
+		action           github.IssueEventAction
+		issue            github.Issue
+		expectedComments []string
+	}{
+		{
+			name:   "duplicates are suggested by decreasing similarity",
+			action: github.IssueActionOpened,
+			issue:  github.Issue{Number: 10, Title: "Hook crashes on startup", Body: "The hook server panics when it starts."},
+			expectedComments: []string{
+				"- #1: Hook crashes on startup (100% similar)\n- #2: hook crashes on startup (80% similar)\n\nTriagers",
+			},
+		},
+		{
+			name:   "no similar issue",
+			action: github.IssueActionOpened,
+			issue:  github.Issue{Number: 10, Title: "Add a dedupe plugin", Body: "Suggest duplicates of new issues."},
+		},
+		{
+			name:   "only new issues are considered",
+			action: github.IssueActionEdited,
+			issue:  github.Issue{Number: 10, Title: "Hook crashes on startup", Body: "The hook server panics when it starts."},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.Issues = existing
+			ie := github.IssueEvent{
+				Action: tc.action,
+				Issue:  tc.issue,
+				Repo:   github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+			}
+
+			if err := handleIssue(logrus.WithField("plugin", PluginName), fc, cfg, ie, time.Now()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			comments := fc.IssueComments[10]
+			if len(comments) != len(tc.expectedComments) {
+				t.Fatalf("expected %d comments, got %v", len(tc.expectedComments), comments)
+			}
+			for i, expected := range tc.expectedComments {
+				if !strings.Contains(comments[i].Body, expected) {
+					t.Errorf("expected comment %q to contain %q", comments[i].Body, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleCloseDuplicate(t *testing.T) {
+	testCases := []struct {
+		name            string
+		body            string
+		commenter       string
+		isPR            bool
+		expectedComment string
+		expectClosed    bool
+	}{
+		{
+			name:      "not a command",
+			body:      "/close",
+			commenter: "collab",
+		},
+		{
+			name:      "ignored on PRs",
+			body:      "/close duplicate #1",
+			commenter: "collab",
+			isPR:      true,
+		},
+		{
+			name:            "non collaborator cannot close",
+			body:            "/close duplicate #1",
+			commenter:       "outsider",
+			expectedComment: "only collaborators can close issues as duplicates.",
+		},
+		{
+			name:            "duplicate of itself",
+			body:            "/close duplicate #2",
+			commenter:       "collab",
+			expectedComment: "an issue cannot be a duplicate of itself.",
+		},
+		{
+			name:            "unknown original",
+			body:            "/close duplicate #42",
+			commenter:       "collab",
+			expectedComment: "cannot find issue #42.",
+		},
+		{
+			name:            "closed as a duplicate",
+			body:            "/close duplicate #1",
+			commenter:       "Collab",
+			expectedComment: "Duplicate of #1\n\n",
+			expectClosed:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.Collaborators = []string{"collab"}
+			fc.Issues = map[int]*github.Issue{
+				1: {Number: 1, State: "open"},
+				2: {Number: 2, State: "open"},
+			}
+			e := &github.GenericCommentEvent{
+				Action:     github.GenericCommentActionCreated,
+				IsPR:       tc.isPR,
+				IssueState: "open",
+				Body:       tc.body,
+				Number:     2,
+				Repo:       github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+				User:       github.User{Login: tc.commenter},
+			}
+
+			if err := handleCloseDuplicate(logrus.WithField("plugin", PluginName), fc, e); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			comments := fc.IssueComments[2]
+			switch {
+			case tc.expectedComment == "" && len(comments) > 0:
+				t.Errorf("expected no comment, got %v", comments)
+			case tc.expectedComment != "" && (len(comments) != 1 || !strings.Contains(comments[0].Body, tc.expectedComment)):
+				t.Errorf("expected a comment containing %q, got %v", tc.expectedComment, comments)
+			}
+			if closed := fc.Issues[2].State == "closed"; closed != tc.expectClosed {
+				t.Errorf("expected closed %t, got %t", tc.expectClosed, closed)
+			}
+			var expectedLabels []string
+			if tc.expectClosed {
+				expectedLabels = []string{"org/repo#2:" + labels.TriageDuplicate}
+			}
+			if diff := cmp.Diff(expectedLabels, fc.IssueLabelsAdded); diff != "" {
+				t.Errorf("added labels differ from expected: %s", diff)
+			}
+		})
+	}
+}
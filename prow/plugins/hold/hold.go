@@ -23,9 +23,13 @@ package hold
 import (
 	"fmt"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/labels"
@@ -39,28 +43,49 @@ const (
 )
 
 var (
-	labelRe       = regexp.MustCompile(`(?mi)^/hold(\s.*)?$`)
-	labelCancelRe = regexp.MustCompile(`(?mi)^/(remove-hold|hold\s+cancel|unhold)\s*$`)
+	// durationLikeRe matches the arguments that are meant as a duration rather
+	// than as the reason of the hold.
+	durationLikeRe = regexp.MustCompile(`^[0-9]`)
+	expiryRe       = regexp.MustCompile(`<!-- hold expires at (\S+) for (\S+) -->`)
+
+	holdCommands = []plugins.Command{
+		{
+			Name: "hold",
+			// The argument is either cancel, a duration or the reason of
+			// the hold.
+			Args:        []plugins.CommandArg{{Name: "cancel|duration", Optional: true, Variadic: true}},
+			Description: "Adds or removes the `" + labels.Hold + "` Label which is used to indicate that the PR should not be automatically merged. If a duration is given, the Label is removed automatically once it has elapsed, and holding a held PR again without a duration keeps that expiry.",
+			WhoCanUse:   "Anyone can use the /hold command to add or remove the '" + labels.Hold + "' Label.",
+			Examples:    []string{"/hold", "/hold 48h", "/hold cancel"},
+			Handler:     handleCommand,
+		},
+		{
+			Name:        "unhold",
+			Aliases:     []string{"remove-hold"},
+			Description: "Removes the `" + labels.Hold + "` Label.",
+			WhoCanUse:   "Anyone can use the /unhold command to remove the '" + labels.Hold + "' Label.",
+			Examples:    []string{"/unhold", "/remove-hold"},
+			Handler:     handleCommand,
+		},
+	}
 )
 
+// expiryFormat is the hidden part of the bot comment recording when a hold expires.
+const expiryFormat = "<!-- hold expires at %s for %s -->"
+
 type hasLabelFunc func(label string, issueLabels []github.Label) bool
 
 func init() {
-	plugins.RegisterGenericCommentHandler(PluginName, handleGenericComment, helpProvider)
+	plugins.RegisterCommands(PluginName, holdCommands...)
+	plugins.RegisterPeriodicHandler(PluginName, handlePeriodic, helpProvider)
 }
 
 func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
 	// The Config field is omitted because this plugin is not configurable.
+	// The help of the commands is added by the plugins framework.
 	pluginHelp := &pluginhelp.PluginHelp{
 		Description: "The hold plugin allows anyone to add or remove the '" + labels.Hold + "' Label from a pull request in order to temporarily prevent the PR from merging without withholding approval.",
 	}
-	pluginHelp.AddCommand(pluginhelp.Command{
-		Usage:       "/[remove-][un]hold [cancel]",
-		Description: "Adds or removes the `" + labels.Hold + "` Label which is used to indicate that the PR should not be automatically merged.",
-		Featured:    false,
-		WhoCanUse:   "Anyone can use the /hold command to add or remove the '" + labels.Hold + "' Label.",
-		Examples:    []string{"/hold", "/hold cancel", "/unhold", "/remove-hold"},
-	})
 	return pluginHelp, nil
 }
 
@@ -68,33 +93,34 @@ type githubClient interface {
 	AddLabel(owner, repo string, number int, label string) error
 	RemoveLabel(owner, repo string, number int, label string) error
 	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
+	BotUserChecker() (func(candidate string) bool, error)
+	CreateComment(owner, repo string, number int, comment string) error
+	DeleteStaleComments(org, repo string, number int, comments []github.IssueComment, isStale func(github.IssueComment) bool) error
+	FindIssues(query, sort string, asc bool) ([]github.Issue, error)
+	ListIssueComments(owner, repo string, issue int) ([]github.IssueComment, error)
 }
 
-func handleGenericComment(pc plugins.Agent, e github.GenericCommentEvent) error {
+func handleCommand(pc plugins.Agent, e github.GenericCommentEvent, invocation plugins.CommandInvocation) error {
 	hasLabel := func(label string, labels []github.Label) bool {
 		return github.HasLabel(label, labels)
 	}
-	return handle(pc.GitHubClient, pc.Logger, &e, hasLabel)
+	return handle(pc.GitHubClient, pc.Logger, &e, invocation, hasLabel, time.Now())
+}
+
+func handlePeriodic(pc plugins.Agent) error {
+	return expireHolds(pc.Logger, pc.GitHubClient, pc.PluginConfig, time.Now())
 }
 
 // handle drives the pull request to the desired state. If any user adds
 // a /hold directive, we want to add a label if one does not already exist.
 // If they add /hold cancel, we want to remove the label if it exists.
-func handle(gc githubClient, log *logrus.Entry, e *github.GenericCommentEvent, f hasLabelFunc) error {
+// A /hold directive with a duration also records when the hold expires.
+func handle(gc githubClient, log *logrus.Entry, e *github.GenericCommentEvent, invocation plugins.CommandInvocation, f hasLabelFunc, now time.Time) error {
 	if !e.IsPR {
 		return nil
 	}
-	if e.Action != github.GenericCommentActionCreated {
-		return nil
-	}
-	needsLabel := false
-	if labelCancelRe.MatchString(e.Body) {
-		needsLabel = false
-	} else if labelRe.MatchString(e.Body) {
-		needsLabel = true
-	} else {
-		return nil
-	}
+	arg := invocation.Arg("cancel|duration")
+	needsLabel := invocation.Command.Name == "hold" && !strings.EqualFold(arg, "cancel")
 
 	org := e.Repo.Owner.Login
 	repo := e.Repo.Name
@@ -103,7 +129,37 @@ func handle(gc githubClient, log *logrus.Entry, e *github.GenericCommentEvent, f
 		return fmt.Errorf("failed to get the labels on %s/%s#%d: %w", org, repo, e.Number, err)
 	}
 
+	var duration time.Duration
+	if needsLabel && arg != "" && !strings.Contains(arg, " ") {
+		// Anything but a duration is the reason of an indefinite hold,
+		// unless it looks like a mistyped duration.
+		if d, err := time.ParseDuration(arg); err == nil && d > 0 {
+			duration = d
+		} else if durationLikeRe.MatchString(arg) {
+			resp := fmt.Sprintf("`%s` is not a valid hold duration, so I did not set when the hold expires. Use a positive duration like `48h` or `90m`.", arg)
+			if err := gc.CreateComment(org, repo, e.Number, plugins.FormatResponseRaw(e.Body, e.HTMLURL, e.User.Login, resp)); err != nil {
+				return err
+			}
+		}
+	}
+
 	hasLabel := f(labels.Hold, issueLabels)
+	// Cancelling the hold, placing a new one or giving a new duration replaces
+	// the recorded expiry, which a /hold on a held PR keeps.
+	keepsExpiry := hasLabel && needsLabel && duration == 0
+	if (hasLabel || needsLabel) && !keepsExpiry {
+		if err := deleteExpiryComments(gc, org, repo, e.Number); err != nil {
+			return err
+		}
+	}
+	if duration > 0 {
+		expiry := now.Add(duration).UTC()
+		log.Infof("Recording hold expiry at %s for %s/%s#%d", expiry, org, repo, e.Number)
+		resp := fmt.Sprintf("The `%s` Label will be removed at %s unless the hold is cancelled or renewed before.\n"+expiryFormat, labels.Hold, expiry.Format(time.RFC1123), expiry.Format(time.RFC3339), e.User.Login)
+		if err := gc.CreateComment(org, repo, e.Number, plugins.FormatSimpleResponse(e.User.Login, resp)); err != nil {
+			return err
+		}
+	}
 	if hasLabel && !needsLabel {
 		log.Infof("Removing %q Label for %s/%s#%d", labels.Hold, org, repo, e.Number)
 		return gc.RemoveLabel(org, repo, e.Number, labels.Hold)
@@ -113,3 +169,84 @@ func handle(gc githubClient, log *logrus.Entry, e *github.GenericCommentEvent, f
 	}
 	return nil
 }
+
+// deleteExpiryComments deletes the bot comments recording when the hold expires.
+func deleteExpiryComments(gc githubClient, org, repo string, number int) error {
+	botUserChecker, err := gc.BotUserChecker()
+	if err != nil {
+		return err
+	}
+	return gc.DeleteStaleComments(org, repo, number, nil, func(comment github.IssueComment) bool {
+		return botUserChecker(comment.User.Login) && expiryRe.MatchString(comment.Body)
+	})
+}
+
+// expireHolds removes the hold Label from the PRs whose hold has expired.
+func expireHolds(log *logrus.Entry, gc githubClient, config *plugins.Configuration, now time.Time) error {
+	enabledRepos := config.EnabledReposQuery(PluginName)
+	if enabledRepos == "" {
+		return nil
+	}
+	prs, err := gc.FindIssues(fmt.Sprintf("is:pr is:open label:%q %s", labels.Hold, enabledRepos), "", false)
+	if err != nil {
+		return fmt.Errorf("failed to search for held PRs: %w", err)
+	}
+
+	botUserChecker, err := gc.BotUserChecker()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, pr := range prs {
+		org, repo, err := github.OrgRepoFromHTMLURL(pr.HTMLURL)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := expireHold(log, gc, botUserChecker, org, repo, pr.Number, now); err != nil {
+			errs = append(errs, fmt.Errorf("failed to expire the hold of %s/%s#%d: %w", org, repo, pr.Number, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func expireHold(log *logrus.Entry, gc githubClient, botUserChecker func(string) bool, org, repo string, number int, now time.Time) error {
+	comments, err := gc.ListIssueComments(org, repo, number)
+	if err != nil {
+		return fmt.Errorf("failed to list comments: %w", err)
+	}
+	var expiry time.Time
+	var holder string
+	for _, comment := range comments {
+		if !botUserChecker(comment.User.Login) {
+			continue
+		}
+		m := expiryRe.FindStringSubmatch(comment.Body)
+		if m == nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, m[1])
+		if err != nil {
+			log.WithError(err).Warnf("Ignoring invalid hold expiry in comment %d.", comment.ID)
+			continue
+		}
+		if t.After(expiry) {
+			expiry, holder = t, m[2]
+		}
+	}
+	if expiry.IsZero() || now.Before(expiry) {
+		return nil
+	}
+
+	log.Infof("Removing expired %q Label for %s/%s#%d", labels.Hold, org, repo, number)
+	if err := gc.RemoveLabel(org, repo, number, labels.Hold); err != nil {
+		return err
+	}
+	if err := gc.DeleteStaleComments(org, repo, number, comments, func(comment github.IssueComment) bool {
+		return botUserChecker(comment.User.Login) && expiryRe.MatchString(comment.Body)
+	}); err != nil {
+		log.WithError(err).Error("Failed to delete hold expiry comments.")
+	}
+	resp := fmt.Sprintf("The hold expired at %s, so I removed the `%s` Label. Use `/hold` to hold the PR again.", expiry.Format(time.RFC1123), labels.Hold)
+	return gc.CreateComment(org, repo, number, plugins.FormatSimpleResponse(holder, resp))
+}
@@ -18,15 +18,29 @@ package hold
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
 	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/plugins"
 )
 
+// handleComment handles the hold commands in the comment like hook does.
+func handleComment(gc githubClient, log *logrus.Entry, e *github.GenericCommentEvent, f hasLabelFunc, now time.Time) error {
+	invocations, _ := plugins.ParseCommands(e.Body, holdCommands)
+	for _, invocation := range invocations {
+		if err := handle(gc, log, e, invocation, f, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func TestHandle(t *testing.T) {
 	var tests = []struct {
 		name          string
@@ -189,7 +203,7 @@ func TestHandle(t *testing.T) {
 			return tc.hasLabel
 		}
 
-		if err := handle(fc, logrus.WithField("plugin", PluginName), e, hasLabel); err != nil {
+		if err := handleComment(fc, logrus.WithField("plugin", PluginName), e, hasLabel, time.Now()); err != nil {
 			t.Errorf("For case %s, didn't expect error from hold: %v", tc.name, err)
 			continue
 		}
@@ -211,3 +225,108 @@ func TestHandle(t *testing.T) {
 		}
 	}
 }
+
+func TestHoldExpiry(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	comment := func(body string) *github.GenericCommentEvent {
+		return &github.GenericCommentEvent{
+			Action: github.GenericCommentActionCreated,
+			Body:   body,
+			Number: 1,
+			Repo:   github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+			User:   github.User{Login: "alice"},
+			IsPR:   true,
+		}
+	}
+	hasLabel := func(has bool) hasLabelFunc {
+		return func(string, []github.Label) bool { return has }
+	}
+	log := logrus.WithField("plugin", PluginName)
+	pc := &plugins.Configuration{Plugins: plugins.Plugins{"org/repo": {Plugins: []string{PluginName}}}}
+
+	fc := fakegithub.NewFakeClient()
+	fc.Issues = map[int]*github.Issue{1: {Number: 1, HTMLURL: "https://github.com/org/repo/pull/1"}}
+	if err := handleComment(fc, log, comment("/hold 48h"), hasLabel(false), now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fc.IssueLabelsAdded) != 1 {
+		t.Fatalf("expected the %q Label to be added, got %v", labels.Hold, fc.IssueLabelsAdded)
+	}
+	if len(fc.IssueComments[1]) != 1 || !strings.Contains(fc.IssueComments[1][0].Body, "<!-- hold expires at 2021-03-03T00:00:00Z for alice -->") {
+		t.Fatalf("expected a comment recording the hold expiry, got %v", fc.IssueComments[1])
+	}
+
+	// The hold is kept until it expires.
+	if err := expireHolds(log, fc, pc, now.Add(47*time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fc.IssueLabelsRemoved) != 0 {
+		t.Errorf("expected the hold to be kept, but removed %v", fc.IssueLabelsRemoved)
+	}
+	if err := expireHolds(log, fc, pc, now.Add(49*time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fc.IssueLabelsRemoved) != 1 {
+		t.Errorf("expected the expired hold to be removed, got %v", fc.IssueLabelsRemoved)
+	}
+	if len(fc.IssueComments[1]) != 1 || !strings.Contains(fc.IssueComments[1][0].Body, "@alice: The hold expired") {
+		t.Errorf("expected the expiry comment to be replaced by a notification, got %v", fc.IssueComments[1])
+	}
+
+	// A hold without a duration on a held PR keeps the recorded expiry.
+	fc = fakegithub.NewFakeClient()
+	fc.Issues = map[int]*github.Issue{1: {Number: 1, HTMLURL: "https://github.com/org/repo/pull/1"}}
+	if err := handleComment(fc, log, comment("/hold 1h"), hasLabel(false), now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := handleComment(fc, log, comment("/hold for further review"), hasLabel(true), now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fc.IssueComments[1]) != 1 {
+		t.Errorf("expected the expiry comment to be kept, got %v", fc.IssueComments[1])
+	}
+	if err := expireHolds(log, fc, pc, now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fc.IssueLabelsRemoved) != 1 {
+		t.Errorf("expected the expired hold to be removed, got %v", fc.IssueLabelsRemoved)
+	}
+
+	// Cancelling the hold clears the recorded expiry.
+	fc = fakegithub.NewFakeClient()
+	if err := handleComment(fc, log, comment("/hold 1h"), hasLabel(false), now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := handleComment(fc, log, comment("/hold cancel"), hasLabel(true), now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fc.IssueComments[1]) != 0 {
+		t.Errorf("expected the expiry comment to be deleted, got %v", fc.IssueComments[1])
+	}
+
+	// A hold on a PR that is not held clears an expiry left from a previous
+	// hold whose Label was removed by hand.
+	fc = fakegithub.NewFakeClient()
+	if err := handleComment(fc, log, comment("/hold 1h"), hasLabel(false), now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fc.IssueLabelsAdded = nil
+	if err := handleComment(fc, log, comment("/hold"), hasLabel(false), now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fc.IssueComments[1]) != 0 {
+		t.Errorf("expected the expiry comment to be deleted, got %v", fc.IssueComments[1])
+	}
+
+	// A mistyped duration holds the PR and tells the user why it won't expire.
+	fc = fakegithub.NewFakeClient()
+	if err := handleComment(fc, log, comment("/hold 2days"), hasLabel(false), now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fc.IssueLabelsAdded) != 1 {
+		t.Errorf("expected the %q Label to be added, got %v", labels.Hold, fc.IssueLabelsAdded)
+	}
+	if len(fc.IssueComments[1]) != 1 || !strings.Contains(fc.IssueComments[1][0].Body, "`2days` is not a valid hold duration") {
+		t.Errorf("expected a comment about the invalid duration, got %v", fc.IssueComments[1])
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	jiraclient "k8s.io/test-infra/prow/jira"
+	"k8s.io/test-infra/prow/labels"
 	"k8s.io/test-infra/prow/pluginhelp"
 	"k8s.io/test-infra/prow/plugins"
 )
@@ -44,8 +45,16 @@ var (
 	projectCache   = &threadsafeSet{data: sets.String{}}
 )
 
-func extractCandidatesFromText(t string) []string {
-	matches := issueNameRegex.FindAllStringSubmatch(t, -1)
+// issueKeyRegexp returns the regular expression matching the Jira issue keys.
+func issueKeyRegexp(cfg *plugins.Jira) *regexp.Regexp {
+	if cfg != nil && cfg.IssueKeyRe != nil {
+		return cfg.IssueKeyRe
+	}
+	return issueNameRegex
+}
+
+func extractCandidatesFromText(re *regexp.Regexp, t string) []string {
+	matches := re.FindAllStringSubmatch(t, -1)
 	if matches == nil {
 		return nil
 	}
@@ -61,12 +70,37 @@ func extractCandidatesFromText(t string) []string {
 
 func init() {
 	plugins.RegisterGenericCommentHandler(PluginName, handleGenericComment, helpProvider)
+	plugins.RegisterPullRequestHandler(PluginName, handlePullRequest, helpProvider)
 }
 
-func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
-	// The Config field is omitted because this plugin is not configurable.
+func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	configInfo := map[string]string{}
+	for _, repo := range enabledRepos {
+		var info []string
+		if config.Jira.RequiresIssue(repo.Org, repo.Repo) {
+			info = append(info, fmt.Sprintf("PRs must reference an existing Jira issue, otherwise they are labeled %s or %s.", labels.NeedsIssue, labels.InvalidIssue))
+		}
+		if config.Jira != nil && config.Jira.MergedState != "" {
+			info = append(info, fmt.Sprintf("Jira issues referenced by merged PRs are transitioned to %q.", config.Jira.MergedState))
+		}
+		if len(info) > 0 {
+			configInfo[repo.String()] = strings.Join(info, " ")
+		}
+	}
+	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
+		Jira: &plugins.Jira{
+			IssueKeyRegexp: `\b(PROJ-[0-9]+)\b`,
+			RequireIssue:   []string{"org/repo"},
+			MergedState:    "Done",
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", PluginName)
+	}
 	pluginHelp := &pluginhelp.PluginHelp{
-		Description: "The Jira plugin links Pull Requests and Issues to Jira issues",
+		Description: "The Jira plugin links Pull Requests and Issues to Jira issues. It can also require PRs to reference an existing Jira issue and transition the referenced issues when PRs merge.",
+		Config:      configInfo,
+		Snippet:     yamlSnippet,
 	}
 	return pluginHelp, nil
 }
@@ -75,6 +109,8 @@ type githubClient interface {
 	EditComment(org, repo string, id int, comment string) error
 	GetIssue(org, repo string, number int) (*github.Issue, error)
 	EditIssue(org, repo string, number int, issue *github.Issue) (*github.Issue, error)
+	AddLabel(org, repo string, number int, label string) error
+	RemoveLabel(org, repo string, number int, label string) error
 }
 
 func handleGenericComment(pc plugins.Agent, e github.GenericCommentEvent) error {
@@ -82,21 +118,29 @@ func handleGenericComment(pc plugins.Agent, e github.GenericCommentEvent) error
 }
 
 func handle(jc jiraclient.Client, ghc githubClient, cfg *plugins.Jira, log *logrus.Entry, e *github.GenericCommentEvent) error {
-	if projectCache.entryCount() == 0 {
-		projects, err := jc.ListProjects()
-		if err != nil {
-			return fmt.Errorf("failed to list jira projects: %w", err)
-		}
-		var projectNames []string
-		for _, project := range *projects {
-			projectNames = append(projectNames, strings.ToLower(project.Key))
-		}
-		projectCache.insert(projectNames...)
+	if err := ensureProjectCache(jc); err != nil {
+		return err
 	}
 
 	return handleWithProjectCache(jc, ghc, cfg, log, e, projectCache)
 }
 
+func ensureProjectCache(jc jiraclient.Client) error {
+	if projectCache.entryCount() != 0 {
+		return nil
+	}
+	projects, err := jc.ListProjects()
+	if err != nil {
+		return fmt.Errorf("failed to list jira projects: %w", err)
+	}
+	var projectNames []string
+	for _, project := range *projects {
+		projectNames = append(projectNames, strings.ToLower(project.Key))
+	}
+	projectCache.insert(projectNames...)
+	return nil
+}
+
 func handleWithProjectCache(jc jiraclient.Client, ghc githubClient, cfg *plugins.Jira, log *logrus.Entry, e *github.GenericCommentEvent, projectCache *threadsafeSet) error {
 	// Nothing to do on deletion
 	if e.Action == github.GenericCommentActionDeleted {
@@ -105,8 +149,9 @@ func handleWithProjectCache(jc jiraclient.Client, ghc githubClient, cfg *plugins
 
 	jc = &projectCachingJiraClient{jc, projectCache}
 
-	issueCandidateNames := extractCandidatesFromText(e.Body)
-	issueCandidateNames = append(issueCandidateNames, extractCandidatesFromText(e.IssueTitle)...)
+	re := issueKeyRegexp(cfg)
+	issueCandidateNames := extractCandidatesFromText(re, e.Body)
+	issueCandidateNames = append(issueCandidateNames, extractCandidatesFromText(re, e.IssueTitle)...)
 	issueCandidateNames = filterOutDisabledJiraProjects(issueCandidateNames, cfg)
 	if len(issueCandidateNames) == 0 {
 		return nil
@@ -147,6 +192,111 @@ func handleWithProjectCache(jc jiraclient.Client, ghc githubClient, cfg *plugins
 	return utilerrors.NewAggregate(errs)
 }
 
+func handlePullRequest(pc plugins.Agent, pre github.PullRequestEvent) error {
+	return handlePR(pc.JiraClient, pc.GitHubClient, pc.PluginConfig.Jira, pc.Logger, &pre)
+}
+
+func handlePR(jc jiraclient.Client, ghc githubClient, cfg *plugins.Jira, log *logrus.Entry, pre *github.PullRequestEvent) error {
+	if jc == nil || cfg == nil {
+		return nil
+	}
+	validate := cfg.RequiresIssue(pre.Repo.Owner.Login, pre.Repo.Name) &&
+		(pre.Action == github.PullRequestActionOpened ||
+			pre.Action == github.PullRequestActionReopened ||
+			pre.Action == github.PullRequestActionEdited)
+	transition := cfg.MergedState != "" && pre.Action == github.PullRequestActionClosed && pre.PullRequest.Merged
+	if !validate && !transition {
+		return nil
+	}
+	if err := ensureProjectCache(jc); err != nil {
+		return err
+	}
+
+	return handlePRWithProjectCache(jc, ghc, cfg, log, pre, projectCache)
+}
+
+// handlePRWithProjectCache labels PRs that do not reference an existing Jira
+// issue and transitions the referenced issues once PRs merge.
+func handlePRWithProjectCache(jc jiraclient.Client, ghc githubClient, cfg *plugins.Jira, log *logrus.Entry, pre *github.PullRequestEvent, projectCache *threadsafeSet) error {
+	jc = &projectCachingJiraClient{jc, projectCache}
+
+	re := issueKeyRegexp(cfg)
+	issueCandidateNames := extractCandidatesFromText(re, pre.PullRequest.Title)
+	issueCandidateNames = append(issueCandidateNames, extractCandidatesFromText(re, pre.PullRequest.Body)...)
+	issueCandidateNames = filterOutDisabledJiraProjects(issueCandidateNames, cfg)
+
+	var errs []error
+	referencedIssues := map[string]*jira.Issue{}
+	for _, name := range sets.NewString(issueCandidateNames...).List() {
+		issue, err := jc.GetIssue(name)
+		if err != nil {
+			if !jiraclient.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("failed to get issue %s: %w", name, err))
+			}
+			continue
+		}
+		referencedIssues[name] = issue
+	}
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+
+	if pre.Action == github.PullRequestActionClosed {
+		for name, issue := range referencedIssues {
+			if err := transitionIssue(jc, log, name, issue, cfg.MergedState); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return utilerrors.NewAggregate(errs)
+	}
+
+	var wantedLabel string
+	switch {
+	case len(referencedIssues) > 0:
+	case len(issueCandidateNames) > 0:
+		wantedLabel = labels.InvalidIssue
+	default:
+		wantedLabel = labels.NeedsIssue
+	}
+	org := pre.Repo.Owner.Login
+	repo := pre.Repo.Name
+	for _, label := range []string{labels.NeedsIssue, labels.InvalidIssue} {
+		hasLabel := github.HasLabel(label, pre.PullRequest.Labels)
+		if label == wantedLabel && !hasLabel {
+			if err := ghc.AddLabel(org, repo, pre.Number, label); err != nil {
+				errs = append(errs, fmt.Errorf("failed to add %s label: %w", label, err))
+			}
+		} else if label != wantedLabel && hasLabel {
+			if err := ghc.RemoveLabel(org, repo, pre.Number, label); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove %s label: %w", label, err))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// transitionIssue moves the issue to the given state, unless it already is in it.
+func transitionIssue(jc jiraclient.Client, log *logrus.Entry, name string, issue *jira.Issue, state string) error {
+	if issue.Fields != nil && issue.Fields.Status != nil && strings.EqualFold(issue.Fields.Status.Name, state) {
+		return nil
+	}
+	transitions, err := jc.GetTransitions(name)
+	if err != nil {
+		return fmt.Errorf("failed to get transitions of issue %s: %w", name, err)
+	}
+	for _, transition := range transitions {
+		if !strings.EqualFold(transition.To.Name, state) && !strings.EqualFold(transition.Name, state) {
+			continue
+		}
+		if err := jc.DoTransition(name, transition.ID); err != nil {
+			return fmt.Errorf("failed to transition issue %s to %s: %w", name, state, err)
+		}
+		log.WithField("Issue", name).Infof("Transitioned issue to %s.", state)
+		return nil
+	}
+	return fmt.Errorf("issue %s has no transition to %s", name, state)
+}
+
 func updateComment(e *github.GenericCommentEvent, validIssues []string, jiraBaseURL string, ghc githubClient) error {
 	withLinks := insertLinksIntoComment(e.Body, validIssues, jiraBaseURL)
 	if withLinks == e.Body {
@@ -305,11 +455,15 @@ func filterOutDisabledJiraProjects(candidateNames []string, cfg *plugins.Jira) [
 	}
 
 	var result []string
-	for _, excludedProject := range cfg.DisabledJiraProjects {
-		for _, candidate := range candidateNames {
+	for _, candidate := range candidateNames {
+		var disabled bool
+		for _, excludedProject := range cfg.DisabledJiraProjects {
 			if strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(excludedProject)) {
-				continue
+				disabled = true
+				break
 			}
+		}
+		if !disabled {
 			result = append(result, candidate)
 		}
 	}
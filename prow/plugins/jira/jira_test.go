@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
 	"testing"
 
 	"github.com/andygrunwald/go-jira"
@@ -29,6 +30,7 @@ import (
 
 	"k8s.io/test-infra/prow/github"
 	jiraclient "k8s.io/test-infra/prow/jira"
+	"k8s.io/test-infra/prow/labels"
 	"k8s.io/test-infra/prow/plugins"
 )
 
@@ -91,7 +93,7 @@ func TestRegex(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result := extractCandidatesFromText(tc.input)
+			result := extractCandidatesFromText(issueNameRegex, tc.input)
 			if diff := cmp.Diff(tc.expected, result); diff != "" {
 				t.Errorf("expected differs from actual: %s", diff)
 			}
@@ -104,6 +106,9 @@ type fakeJiraClient struct {
 	existingLinks  map[string][]jira.RemoteLink
 	newLinks       []jira.RemoteLink
 	getIssueError  error
+	transitions    []jira.Transition
+	// doneTransitions holds "issue:transitionID" strings
+	doneTransitions []string
 }
 
 func (f *fakeJiraClient) ListProjects() (*jira.ProjectList, error) {
@@ -134,6 +139,27 @@ func (f *fakeJiraClient) AddRemoteLink(id string, link *jira.RemoteLink) error {
 	return nil
 }
 
+func (f *fakeJiraClient) GetTransitions(id string) ([]jira.Transition, error) {
+	return f.transitions, nil
+}
+
+func (f *fakeJiraClient) DoTransition(id, transitionID string) error {
+	f.doneTransitions = append(f.doneTransitions, fmt.Sprintf("%s:%s", id, transitionID))
+	return nil
+}
+
+func (f *fakeJiraClient) CreateIssue(issue *jira.Issue) (*jira.Issue, error) {
+	panic("not implemented")
+}
+
+func (f *fakeJiraClient) SearchIssues(jql string) ([]jira.Issue, error) {
+	panic("not implemented")
+}
+
+func (f *fakeJiraClient) AddComment(id string, comment *jira.Comment) (*jira.Comment, error) {
+	panic("not implemented")
+}
+
 func (f *fakeJiraClient) JiraClient() *jira.Client {
 	panic("not implemented")
 }
@@ -146,6 +172,18 @@ func (f *fakeJiraClient) JiraURL() string {
 
 type fakeGitHubClient struct {
 	editedComments map[string]string
+	addedLabels    []string
+	removedLabels  []string
+}
+
+func (f *fakeGitHubClient) AddLabel(org, repo string, number int, label string) error {
+	f.addedLabels = append(f.addedLabels, label)
+	return nil
+}
+
+func (f *fakeGitHubClient) RemoveLabel(org, repo string, number int, label string) error {
+	f.removedLabels = append(f.removedLabels, label)
+	return nil
 }
 
 func (f *fakeGitHubClient) EditComment(org, repo string, id int, body string) error {
@@ -368,6 +406,139 @@ func TestHandle(t *testing.T) {
 
 }
 
+func TestHandlePR(t *testing.T) {
+	t.Parallel()
+	requireIssue := &plugins.Jira{RequireIssue: []string{"org"}, MergedState: "Done"}
+	transitions := []jira.Transition{
+		{ID: "11", Name: "Start Progress", To: jira.Status{Name: "In Progress"}},
+		{ID: "31", Name: "Resolve", To: jira.Status{Name: "Done"}},
+	}
+	testCases := []struct {
+		name                    string
+		action                  github.PullRequestEventAction
+		title                   string
+		body                    string
+		merged                  bool
+		labels                  []string
+		cfg                     *plugins.Jira
+		existingIssues          []jira.Issue
+		expectedAddedLabels     []string
+		expectedRemovedLabels   []string
+		expectedDoneTransitions []string
+	}{
+		{
+			name:   "no config, nothing to do",
+			action: github.PullRequestActionOpened,
+			title:  "Some PR",
+		},
+		{
+			name:   "issue not required for the repo",
+			action: github.PullRequestActionOpened,
+			title:  "Some PR",
+			cfg:    &plugins.Jira{RequireIssue: []string{"other-org"}},
+		},
+		{
+			name:                "no issue referenced",
+			action:              github.PullRequestActionOpened,
+			title:               "Some PR",
+			cfg:                 requireIssue,
+			expectedAddedLabels: []string{labels.NeedsIssue},
+		},
+		{
+			name:                  "only missing issue referenced",
+			action:                github.PullRequestActionEdited,
+			title:                 "ABC-404: Some PR",
+			labels:                []string{labels.NeedsIssue},
+			cfg:                   requireIssue,
+			expectedAddedLabels:   []string{labels.InvalidIssue},
+			expectedRemovedLabels: []string{labels.NeedsIssue},
+		},
+		{
+			name:                  "existing issue referenced in body",
+			action:                github.PullRequestActionEdited,
+			title:                 "Some PR",
+			body:                  "Fixes ABC-123",
+			labels:                []string{labels.InvalidIssue},
+			cfg:                   requireIssue,
+			existingIssues:        []jira.Issue{{ID: "ABC-123"}},
+			expectedRemovedLabels: []string{labels.InvalidIssue},
+		},
+		{
+			name:                "custom issue key regexp",
+			action:              github.PullRequestActionOpened,
+			title:               "ABC-123: Some PR",
+			cfg:                 &plugins.Jira{RequireIssue: []string{"org/repo"}, IssueKeyRe: regexp.MustCompile(`\b(XYZ-[0-9]+)\b`)},
+			existingIssues:      []jira.Issue{{ID: "ABC-123"}},
+			expectedAddedLabels: []string{labels.NeedsIssue},
+		},
+		{
+			name:                    "merged PR transitions the referenced issues",
+			action:                  github.PullRequestActionClosed,
+			title:                   "ABC-123: Some PR",
+			body:                    "Also fixes ABC-124",
+			merged:                  true,
+			cfg:                     requireIssue,
+			existingIssues:          []jira.Issue{{ID: "ABC-123"}, {ID: "ABC-124", Fields: &jira.IssueFields{Status: &jira.Status{Name: "Done"}}}},
+			expectedDoneTransitions: []string{"ABC-123:31"},
+		},
+		{
+			name:           "closed PR does not transition the referenced issues",
+			action:         github.PullRequestActionClosed,
+			title:          "ABC-123: Some PR",
+			cfg:            requireIssue,
+			existingIssues: []jira.Issue{{ID: "ABC-123"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			jiraClient := &fakeJiraClient{
+				existingIssues: tc.existingIssues,
+				transitions:    transitions,
+			}
+			githubClient := &fakeGitHubClient{}
+			pre := &github.PullRequestEvent{
+				Action: tc.action,
+				Number: 3,
+				Repo:   github.Repo{FullName: "org/repo", Owner: github.User{Login: "org"}, Name: "repo"},
+				PullRequest: github.PullRequest{
+					Title:  tc.title,
+					Body:   tc.body,
+					Merged: tc.merged,
+				},
+			}
+			for _, label := range tc.labels {
+				pre.PullRequest.Labels = append(pre.PullRequest.Labels, github.Label{Name: label})
+			}
+			projectCache.insert("abc")
+
+			if err := handlePR(jiraClient, githubClient, tc.cfg, logrus.NewEntry(logrus.New()), pre); err != nil {
+				t.Fatalf("handlePR failed: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expectedAddedLabels, githubClient.addedLabels); diff != "" {
+				t.Errorf("added labels differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedRemovedLabels, githubClient.removedLabels); diff != "" {
+				t.Errorf("removed labels differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedDoneTransitions, jiraClient.doneTransitions); diff != "" {
+				t.Errorf("transitions differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterOutDisabledJiraProjects(t *testing.T) {
+	candidates := []string{"ABC-1", "enterprise-4", "XYZ-2"}
+	if diff := cmp.Diff(candidates, filterOutDisabledJiraProjects(candidates, &plugins.Jira{})); diff != "" {
+		t.Errorf("candidates without disabled projects differ from expected: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"ABC-1"}, filterOutDisabledJiraProjects(candidates, &plugins.Jira{DisabledJiraProjects: []string{"Enterprise", "xyz"}})); diff != "" {
+		t.Errorf("candidates with disabled projects differ from expected: %s", diff)
+	}
+}
+
 func intPtr(i int) *int {
 	return &i
 }
@@ -22,6 +22,7 @@ import (
 	"strings"
 
 	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/test-infra/prow/config"
@@ -72,13 +73,14 @@ func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhel
 					AssignOn:     []plugins.AssignOnLabel{{Label: "other-label"}},
 				}},
 			},
+			AutoCreateFrom: "org/repo:label_sync/labels.yaml",
 		},
 	})
 	if err != nil {
 		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", PluginName)
 	}
 	pluginHelp := &pluginhelp.PluginHelp{
-		Description: "The label plugin provides commands that add or remove certain types of labels. Labels of the following types can be manipulated: 'area/*', 'committee/*', 'kind/*', 'language/*', 'priority/*', 'sig/*', 'triage/*', and 'wg/*'. More labels can be configured to be used via the /label command. Restricted labels are only able to be added by the teams and users present in their configuration, and those users can be automatically assigned when another label is added using the assign_on config.",
+		Description: "The label plugin provides commands that add or remove certain types of labels. Labels of the following types can be manipulated: 'area/*', 'committee/*', 'kind/*', 'language/*', 'priority/*', 'sig/*', 'triage/*', and 'wg/*'. Labels can be hierarchical, e.g. '/area prow/hook' applies 'area/prow/hook'. More labels can be configured to be used via the /label command. Labels missing in the repo can be created automatically from a label_sync labels.yaml file. Restricted labels are only able to be added by the teams and users present in their configuration, and those users can be automatically assigned when another label is added using the assign_on config.",
 		Config: map[string]string{
 			"": configString(labels),
 		},
@@ -89,7 +91,7 @@ func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhel
 		Description: "Applies or removes a label from one of the recognized types of labels.",
 		Featured:    false,
 		WhoCanUse:   "Anyone can trigger this command on issues and PRs. `triage/accepted` can only be added by org members. Restricted labels are only able to be added by teams and users in their configuration.",
-		Examples:    []string{"/kind bug", "/remove-area prow", "/sig testing", "/language zh", "/label foo-bar-baz"},
+		Examples:    []string{"/kind bug", "/remove-area prow", "/area prow/hook", "/sig testing", "/language zh", "/label foo-bar-baz"},
 	})
 	return pluginHelp, nil
 }
@@ -111,6 +113,50 @@ type githubClient interface {
 	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
 	TeamBySlugHasMember(org string, teamSlug string, memberLogin string) (bool, error)
 	AssignIssue(owner, repo string, number int, assignees []string) error
+	AddRepoLabel(org, repo, label, description, color string) error
+	GetFile(org, repo, filepath, commit string) ([]byte, error)
+}
+
+// labelsFile is the subset of the label_sync configuration needed to create labels.
+type labelsFile struct {
+	Default labelsFileRepo            `json:"default"`
+	Orgs    map[string]labelsFileRepo `json:"orgs,omitempty"`
+	Repos   map[string]labelsFileRepo `json:"repos,omitempty"`
+}
+
+type labelsFileRepo struct {
+	Labels []labelDefinition `json:"labels"`
+}
+
+type labelDefinition struct {
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+}
+
+// loadLabelDefinitions returns the labels defined for the repo in the
+// label_sync file configured in AutoCreateFrom, keyed by their lowercase name.
+func loadLabelDefinitions(gc githubClient, config plugins.Label, org, repo string) (map[string]labelDefinition, error) {
+	srcOrg, srcRepo, path, err := config.AutoCreateSource()
+	if err != nil {
+		return nil, err
+	}
+	content, err := gc.GetFile(srcOrg, srcRepo, path, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", config.AutoCreateFrom, err)
+	}
+	var file labelsFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", config.AutoCreateFrom, err)
+	}
+	definitions := map[string]labelDefinition{}
+	// Repo definitions take precedence over org ones, which take precedence over the default ones.
+	for _, labels := range [][]labelDefinition{file.Default.Labels, file.Orgs[org].Labels, file.Repos[org+"/"+repo].Labels} {
+		for _, label := range labels {
+			definitions[strings.ToLower(label.Name)] = label
+		}
+	}
+	return definitions, nil
 }
 
 // Get Labels from Regexp matches
@@ -193,6 +239,33 @@ func handleComment(gc githubClient, log *logrus.Entry, config plugins.Label, e *
 		return restrictedLabel || additionalLabelSet.Has(label)
 	}
 
+	// createLabel creates a label missing in the repo if it is defined in the
+	// AutoCreateFrom file, which is only loaded when needed.
+	var definitions map[string]labelDefinition
+	createLabel := func(label string) bool {
+		if config.AutoCreateFrom == "" {
+			return false
+		}
+		if definitions == nil {
+			var err error
+			if definitions, err = loadLabelDefinitions(gc, config, org, repo); err != nil {
+				log.WithError(err).Warn("Failed to load the label definitions.")
+				definitions = map[string]labelDefinition{}
+			}
+		}
+		definition, ok := definitions[label]
+		if !ok {
+			return false
+		}
+		if err := gc.AddRepoLabel(org, repo, definition.Name, definition.Description, definition.Color); err != nil {
+			log.WithError(err).WithField("label", label).Error("GitHub failed to create the label")
+			return false
+		}
+		log.WithField("label", label).Info("Created missing label.")
+		RepoLabelsExisting.Insert(label)
+		return true
+	}
+
 	// Get labels to add and labels to remove from regexp matches
 	labelsToAdd = append(getLabelsFromREMatches(labelMatches), getLabelsFromGenericMatches(customLabelMatches, labelFilter, &nonexistent)...)
 	labelsToRemove = append(getLabelsFromREMatches(removeLabelMatches), getLabelsFromGenericMatches(customRemoveLabelMatches, labelFilter, &nonexistent)...)
@@ -202,7 +275,7 @@ func handleComment(gc githubClient, log *logrus.Entry, config plugins.Label, e *
 			continue
 		}
 
-		if !RepoLabelsExisting.Has(labelToAdd) {
+		if !RepoLabelsExisting.Has(labelToAdd) && !createLabel(labelToAdd) {
 			noSuchLabelsInRepo = append(noSuchLabelsInRepo, labelToAdd)
 			continue
 		}
@@ -64,6 +64,8 @@ func TestHandleComment(t *testing.T) {
 		expectedCommentText   string
 		action                github.GenericCommentEventAction
 		teams                 map[string]map[string]fakegithub.TeamWithMembers
+		autoCreateFrom        string
+		expectedRepoLabels    []string
 	}
 	testcases := []testCase{
 		{
@@ -738,8 +740,84 @@ func TestHandleComment(t *testing.T) {
 			action:                github.GenericCommentActionCreated,
 			expectedRemovedLabels: formatWithPRInfo("restricted-label"),
 		},
+		{
+			name:              "Add hierarchical area label",
+			body:              "/area foo/bar",
+			repoLabels:        []string{"area/foo/bar"},
+			commenter:         orgMember,
+			action:            github.GenericCommentActionCreated,
+			expectedNewLabels: formatWithPRInfo("area/foo/bar"),
+		},
+		{
+			name:               "Missing label is not created without auto_create_from",
+			body:               "/area foo/bar",
+			repoLabels:         []string{},
+			commenter:          orgMember,
+			action:             github.GenericCommentActionCreated,
+			expectedNewLabels:  formatWithPRInfo(),
+			expectedBotComment: true,
+		},
+		{
+			name:               "Missing label defined in labels file is created and added",
+			body:               "/area foo/bar",
+			repoLabels:         []string{},
+			commenter:          orgMember,
+			action:             github.GenericCommentActionCreated,
+			autoCreateFrom:     "org/test-infra:label_sync/labels.yaml",
+			expectedNewLabels:  formatWithPRInfo("area/foo/bar"),
+			expectedRepoLabels: []string{"area/foo/bar"},
+		},
+		{
+			name:               "Missing label defined for the repo in labels file is created and added",
+			body:               "/kind regression",
+			repoLabels:         []string{},
+			commenter:          orgMember,
+			action:             github.GenericCommentActionCreated,
+			autoCreateFrom:     "org/test-infra:label_sync/labels.yaml",
+			expectedNewLabels:  formatWithPRInfo("kind/regression"),
+			expectedRepoLabels: []string{"kind/regression"},
+		},
+		{
+			name:                "Missing label not defined in labels file is reported",
+			body:                "/area foo/baz",
+			repoLabels:          []string{},
+			commenter:           orgMember,
+			action:              github.GenericCommentActionCreated,
+			autoCreateFrom:      "org/test-infra:label_sync/labels.yaml",
+			expectedNewLabels:   formatWithPRInfo(),
+			expectedBotComment:  true,
+			expectedCommentText: "because the repository doesn't have them",
+		},
+		{
+			name:                "Missing labels file is tolerated",
+			body:                "/area foo/bar",
+			repoLabels:          []string{},
+			commenter:           orgMember,
+			action:              github.GenericCommentActionCreated,
+			autoCreateFrom:      "org/test-infra:missing/labels.yaml",
+			expectedNewLabels:   formatWithPRInfo(),
+			expectedBotComment:  true,
+			expectedCommentText: "because the repository doesn't have them",
+		},
 	}
 
+	labelsFile := `default:
+  labels:
+  - name: area/foo/bar
+    color: 0052cc
+    description: Issues or PRs related to foo/bar
+repos:
+  org/repo:
+    labels:
+    - name: kind/regression
+      color: e11d21
+      description: Categorizes issue or PR as related to a regression
+  org/other:
+    labels:
+    - name: area/foo/baz
+      color: 0052cc
+`
+
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			sort.Strings(tc.expectedNewLabels)
@@ -751,6 +829,7 @@ func TestHandleComment(t *testing.T) {
 			fakeClient.IssueLabelsAdded = []string{}
 			fakeClient.IssueLabelsRemoved = []string{}
 			fakeClient.Teams = tc.teams
+			fakeClient.RemoteFiles = map[string]map[string]string{"label_sync/labels.yaml": {"master": labelsFile}}
 			// Add initial labels
 			for _, label := range tc.issueLabels {
 				fakeClient.AddLabel("org", "repo", 1, label)
@@ -762,7 +841,7 @@ func TestHandleComment(t *testing.T) {
 				Repo:   github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
 				User:   github.User{Login: tc.commenter},
 			}
-			err := handleComment(fakeClient, logrus.WithField("plugin", PluginName), plugins.Label{AdditionalLabels: tc.extraLabels, RestrictedLabels: tc.restrictedLabels}, e)
+			err := handleComment(fakeClient, logrus.WithField("plugin", PluginName), plugins.Label{AdditionalLabels: tc.extraLabels, RestrictedLabels: tc.restrictedLabels, AutoCreateFrom: tc.autoCreateFrom}, e)
 			if err != nil {
 				t.Fatalf("didn't expect error from handle comment test: %v", err)
 			}
@@ -783,6 +862,9 @@ func TestHandleComment(t *testing.T) {
 			if diff := cmp.Diff(tc.expectedRemovedLabels, fakeClient.IssueLabelsRemoved, cmpopts.EquateEmpty()); diff != "" {
 				t.Errorf("expected removed labels differ from actual removed labels: %s", diff)
 			}
+			if diff := cmp.Diff(append(tc.repoLabels, tc.expectedRepoLabels...), fakeClient.RepoLabelsExisting, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("expected repo labels differ from actual repo labels: %s", diff)
+			}
 			if len(fakeClient.IssueCommentsAdded) > 0 && !tc.expectedBotComment {
 				t.Errorf("unexpected bot comments: %#v", fakeClient.IssueCommentsAdded)
 			}
@@ -21,10 +21,15 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
 	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/gitattributes"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/labels"
 	"k8s.io/test-infra/prow/pkg/layeredsets"
@@ -42,16 +47,35 @@ var (
 	addLGTMLabelNotification   = "LGTM label has been added.  <details>Git tree hash: %s</details>"
 	addLGTMLabelNotificationRe = regexp.MustCompile(fmt.Sprintf(addLGTMLabelNotification, "(.*)"))
 	configInfoReviewActsAsLgtm = `Reviews of "approve" or "request changes" act as adding or removing LGTM.`
-	configInfoStoreTreeHash    = `Squashing commits does not remove LGTM.`
+	configInfoStoreTreeHash    = `Squashing commits does not remove LGTM, and pushing a previously LGTM'd tree again restores it.`
 	// LGTMLabel is the name of the lgtm label applied by the lgtm plugin
-	LGTMLabel = labels.LGTM
-	// LGTMRe is the regex that matches lgtm comments
-	LGTMRe = regexp.MustCompile(`(?mi)^/lgtm(?: no-issue)?\s*$`)
-	// LGTMCancelRe is the regex that matches lgtm cancel comments
-	LGTMCancelRe        = regexp.MustCompile(`(?mi)^/(remove-lgtm|lgtm cancel)\s*$`)
+	LGTMLabel           = labels.LGTM
 	removeLGTMLabelNoti = "New changes are detected. LGTM label has been removed."
+	// lgtmEndorsementsNotification is the marker of the bot-managed comment that
+	// tracks who has issued /lgtm when more than one LGTM is required or LGTMs
+	// are scoped to OWNERS files.
+	lgtmEndorsementsNotification = "<!-- lgtm endorsements: %s -->"
+	lgtmEndorsementsRe           = regexp.MustCompile(`<!-- lgtm endorsements: (.*) -->`)
+	blockedTeamsResponse         = "members of the %s team cannot add LGTM. Please ask a reviewer outside of this team to LGTM instead."
+	staleLGTMNotification        = "The last LGTM on this PR was given more than %s ago and is considered stale. LGTM label has been removed, please review the PR again."
 )
 
+func configInfoBlockedTeams(teams []string) string {
+	return fmt.Sprintf(`Members of the following teams cannot add LGTM: %s.`, strings.Join(teams, ", "))
+}
+
+func configInfoTreeHashExemptPaths(paths []string) string {
+	return fmt.Sprintf(`Changes that only touch files matching %s do not remove LGTM.`, strings.Join(paths, ", "))
+}
+
+func configInfoStaleAfter(staleAfter time.Duration) string {
+	return fmt.Sprintf(`The LGTM label is removed once the last LGTM is older than %s.`, staleAfter)
+}
+
+func configInfoRequiredLgtmCount(count int) string {
+	return fmt.Sprintf(`The LGTM label is only applied after %d distinct collaborators have issued /lgtm.`, count)
+}
+
 func configInfoStickyLgtmTeam(team string) string {
 	return fmt.Sprintf(`Commits from "%s" do not remove LGTM.`, team)
 }
@@ -60,12 +84,32 @@ type commentPruner interface {
 	PruneComments(shouldPrune func(github.IssueComment) bool)
 }
 
+var lgtmCommands = []plugins.Command{
+	{
+		Name:        "lgtm",
+		Args:        []plugins.CommandArg{{Name: "cancel", Optional: true, Values: []string{"cancel", "no-issue"}}},
+		Description: "Adds or removes the 'lgtm' label which is typically used to gate merging. The command may also be issued in an inline review comment thread, and reviews may act as the command.",
+		Featured:    true,
+		WhoCanUse:   "Collaborators on the repository. '/lgtm cancel' can be used additionally by the PR author.",
+		Examples:    []string{"/lgtm", "/lgtm cancel", "<a href=\"https://help.github.com/articles/about-pull-request-reviews/\">'Approve' or 'Request Changes'</a>"},
+		Handler:     handleCommand,
+	},
+	{
+		Name:        "remove-lgtm",
+		Description: "Removes the 'lgtm' label.",
+		WhoCanUse:   "Collaborators on the repository and the PR author.",
+		Examples:    []string{"/remove-lgtm"},
+		Handler:     handleCommand,
+	},
+}
+
 func init() {
-	plugins.RegisterGenericCommentHandler(PluginName, handleGenericCommentEvent, helpProvider)
+	plugins.RegisterCommands(PluginName, lgtmCommands...)
 	plugins.RegisterPullRequestHandler(PluginName, func(pc plugins.Agent, pe github.PullRequestEvent) error {
 		return handlePullRequestEvent(pc, pe)
 	}, helpProvider)
 	plugins.RegisterReviewEventHandler(PluginName, handlePullRequestReviewEvent, helpProvider)
+	plugins.RegisterPeriodicHandler(PluginName, handlePeriodic, helpProvider)
 }
 
 func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
@@ -87,6 +131,22 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 			configInfoStrings = append(configInfoStrings, "<li>"+configInfoStickyLgtmTeam(opts.StickyLgtmTeam)+"</li>")
 			isConfigured = true
 		}
+		if opts.RequiredLgtmCount > 1 {
+			configInfoStrings = append(configInfoStrings, "<li>"+configInfoRequiredLgtmCount(opts.RequiredLgtmCount)+"</li>")
+			isConfigured = true
+		}
+		if len(opts.BlockedTeams) > 0 {
+			configInfoStrings = append(configInfoStrings, "<li>"+configInfoBlockedTeams(opts.BlockedTeams)+"</li>")
+			isConfigured = true
+		}
+		if len(opts.TreeHashExemptPaths) > 0 {
+			configInfoStrings = append(configInfoStrings, "<li>"+configInfoTreeHashExemptPaths(opts.TreeHashExemptPaths)+"</li>")
+			isConfigured = true
+		}
+		if opts.StaleAfterDuration > 0 {
+			configInfoStrings = append(configInfoStrings, "<li>"+configInfoStaleAfter(opts.StaleAfterDuration)+"</li>")
+			isConfigured = true
+		}
 		configInfoStrings = append(configInfoStrings, "</ul>")
 		if isConfigured {
 			configInfo[repo.String()] = strings.Join(configInfoStrings, "\n")
@@ -105,18 +165,12 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 	if err != nil {
 		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", PluginName)
 	}
+	// The help of the commands is added by the plugins framework.
 	pluginHelp := &pluginhelp.PluginHelp{
 		Description: "The lgtm plugin manages the application and removal of the 'lgtm' (Looks Good To Me) label which is typically used to gate merging.",
 		Config:      configInfo,
 		Snippet:     yamlSnippet,
 	}
-	pluginHelp.AddCommand(pluginhelp.Command{
-		Usage:       "/[remove-]lgtm [cancel] or GitHub Review action",
-		Description: "Adds or removes the 'lgtm' label which is typically used to gate merging.",
-		Featured:    true,
-		WhoCanUse:   "Collaborators on the repository. '/lgtm cancel' can be used additionally by the PR author.",
-		Examples:    []string{"/lgtm", "/lgtm cancel", "/remove-lgtm", "<a href=\"https://help.github.com/articles/about-pull-request-reviews/\">'Approve' or 'Request Changes'</a>"},
-	})
 	return pluginHelp, nil
 }
 
@@ -125,6 +179,7 @@ type githubClient interface {
 	AddLabel(owner, repo string, number int, label string) error
 	AssignIssue(owner, repo string, number int, assignees []string) error
 	CreateComment(owner, repo string, number int, comment string) error
+	EditComment(org, repo string, ID int, comment string) error
 	RemoveLabel(owner, repo string, number int, label string) error
 	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
 	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
@@ -133,9 +188,13 @@ type githubClient interface {
 	DeleteComment(org, repo string, ID int) error
 	BotUserChecker() (func(candidate string) bool, error)
 	GetSingleCommit(org, repo, SHA string) (github.RepositoryCommit, error)
+	ListPRCommits(org, repo string, number int) ([]github.RepositoryCommit, error)
+	FindIssues(query, sort string, asc bool) ([]github.Issue, error)
+	ListIssueEvents(org, repo string, num int) ([]github.ListedIssueEvent, error)
 	IsMember(org, user string) (bool, error)
 	ListTeams(org string) ([]github.Team, error)
 	ListTeamMembers(org string, id int, role string) ([]github.TeamMember, error)
+	TeamBySlugHasMember(org string, teamSlug string, memberLogin string) (bool, error)
 	RequestReview(org, repo string, number int, logins []string) error
 }
 
@@ -147,12 +206,15 @@ type reviewCtx struct {
 	number                             int
 }
 
-func handleGenericCommentEvent(pc plugins.Agent, e github.GenericCommentEvent) error {
+// handleCommand handles the commands in top-level comments, review bodies and
+// inline review comments alike: hook coerces all of them into generic comment
+// events, so no dedicated review comment handler is registered.
+func handleCommand(pc plugins.Agent, e github.GenericCommentEvent, invocation plugins.CommandInvocation) error {
 	cp, err := pc.CommentPruner()
 	if err != nil {
 		return err
 	}
-	return handleGenericComment(pc.GitHubClient, pc.PluginConfig, pc.OwnersClient, pc.Logger, cp, e)
+	return handleInvocation(pc.GitHubClient, pc.PluginConfig, pc.OwnersClient, pc.Logger, cp, e, invocation)
 }
 
 func handlePullRequestEvent(pc plugins.Agent, pre github.PullRequestEvent) error {
@@ -177,7 +239,11 @@ func handlePullRequestReviewEvent(pc plugins.Agent, e github.ReviewEvent) error
 	return handlePullRequestReview(pc.GitHubClient, pc.PluginConfig, pc.OwnersClient, pc.Logger, cp, e)
 }
 
-func handleGenericComment(gc githubClient, config *plugins.Configuration, ownersClient repoowners.Interface, log *logrus.Entry, cp commentPruner, e github.GenericCommentEvent) error {
+func handlePeriodic(pc plugins.Agent) error {
+	return handleStale(pc.Logger, pc.GitHubClient, pc.PluginConfig, time.Now())
+}
+
+func handleInvocation(gc githubClient, config *plugins.Configuration, ownersClient repoowners.Interface, log *logrus.Entry, cp commentPruner, e github.GenericCommentEvent, invocation plugins.CommandInvocation) error {
 	rc := reviewCtx{
 		author:      e.User.Login,
 		issueAuthor: e.IssueAuthor.Login,
@@ -188,21 +254,14 @@ func handleGenericComment(gc githubClient, config *plugins.Configuration, owners
 		number:      e.Number,
 	}
 
-	// Only consider open PRs and new comments.
-	if !e.IsPR || e.IssueState != "open" || e.Action != github.GenericCommentActionCreated {
+	// Only consider open PRs, hook only handles the commands in new comments.
+	if !e.IsPR || e.IssueState != "open" {
 		return nil
 	}
 
 	// If we create an "/lgtm" comment, add lgtm if necessary.
 	// If we create a "/lgtm cancel" comment, remove lgtm if necessary.
-	wantLGTM := false
-	if LGTMRe.MatchString(rc.body) {
-		wantLGTM = true
-	} else if LGTMCancelRe.MatchString(rc.body) {
-		wantLGTM = false
-	} else {
-		return nil
-	}
+	wantLGTM := invocation.Command.Name == "lgtm" && invocation.Arg("cancel") != "cancel"
 
 	// use common handler to do the rest
 	return handle(wantLGTM, config, ownersClient, rc, gc, log, cp)
@@ -226,7 +285,7 @@ func handlePullRequestReview(gc githubClient, config *plugins.Configuration, own
 
 	// If the review event body contains an '/lgtm' or '/lgtm cancel' comment,
 	// skip handling the review event
-	if LGTMRe.MatchString(rc.body) || LGTMCancelRe.MatchString(rc.body) {
+	if invocations, _ := plugins.ParseCommands(rc.body, lgtmCommands); len(invocations) > 0 {
 		return nil
 	}
 
@@ -268,6 +327,21 @@ func handle(wantLGTM bool, config *plugins.Configuration, ownersClient repoowner
 		return gc.CreateComment(rc.repo.Owner.Login, rc.repo.Name, rc.number, plugins.FormatResponseRaw(rc.body, rc.htmlURL, rc.author, resp))
 	}
 
+	// Members of blocked teams cannot LGTM, comment and abort
+	opts := config.LgtmFor(org, repoName)
+	if wantLGTM {
+		blockedTeam, err := memberOfBlockedTeam(gc, org, author, opts.BlockedTeams)
+		if err != nil {
+			log.WithError(err).Error("Failed to check if author is a member of a blocked team.")
+			return err
+		}
+		if blockedTeam != "" {
+			resp := fmt.Sprintf(blockedTeamsResponse, blockedTeam)
+			log.Infof("Reply to /lgtm request with comment: \"%s\"", resp)
+			return gc.CreateComment(org, repoName, number, plugins.FormatResponseRaw(body, htmlURL, author, resp))
+		}
+	}
+
 	// Determine if reviewer is already assigned
 	isAssignee := false
 	for _, assignee := range assignees {
@@ -302,15 +376,20 @@ func handle(wantLGTM bool, config *plugins.Configuration, ownersClient repoowner
 		if err := gc.AssignIssue(org, repoName, number, []string{author}); err != nil {
 			log.WithError(err).Errorf("Failed to assign %s/%s#%d to %s", org, repoName, number, author)
 		}
-	} else if !isAuthor && skipCollaborators {
-		// in this case we depend on OWNERS files instead to check if the author
-		// is an approver or reviewer of the changed files
+	}
+
+	// when skipping collaborators we depend on OWNERS files instead, both to
+	// check if the author is an approver or reviewer of the changed files and
+	// to determine which of the changed files the author's LGTM covers
+	var ro repoowners.RepoOwner
+	var filenames []string
+	if !isAuthor && skipCollaborators {
 		log.Debugf("Skipping collaborator checks and loading OWNERS for %s/%s#%d", org, repoName, number)
-		ro, err := loadRepoOwners(gc, ownersClient, org, repoName, number)
+		ro, err = loadRepoOwners(gc, ownersClient, org, repoName, number)
 		if err != nil {
 			return err
 		}
-		filenames, err := getChangedFiles(gc, org, repoName, number)
+		filenames, err = getChangedFiles(gc, org, repoName, number)
 		if err != nil {
 			return err
 		}
@@ -324,6 +403,43 @@ func handle(wantLGTM bool, config *plugins.Configuration, ownersClient repoowner
 	// now we update the LGTM labels, having checked all cases where changing
 	// LGTM was not allowed for the commenter
 
+	if opts.RequiredLgtmCount > 1 || skipCollaborators {
+		// Record the endorsement and only touch the label once enough
+		// endorsements have been given and, in OWNERS mode, every changed file
+		// is covered by at least one of them. The PR author can only cancel,
+		// which withdraws every endorsement.
+		existing, endorsers, err := findEndorsements(gc, org, repoName, number)
+		if err != nil {
+			log.WithError(err).Error("Failed to find LGTM endorsements.")
+			return err
+		}
+		switch {
+		case isAuthor:
+			endorsers = sets.NewString()
+		case wantLGTM:
+			endorsers.Insert(github.NormLogin(author))
+		default:
+			endorsers.Delete(github.NormLogin(author))
+		}
+		var uncovered []string
+		if skipCollaborators && endorsers.Len() > 0 {
+			uncovered = uncoveredFiles(ro, filenames, endorsers)
+		}
+		if err := setEndorsements(gc, org, repoName, number, existing, endorsers, uncovered); err != nil {
+			log.WithError(err).Error("Failed to update LGTM endorsements.")
+			return err
+		}
+		satisfied := endorsers.Len() > 0 && endorsers.Len() >= opts.RequiredLgtmCount && len(uncovered) == 0
+		if wantLGTM && !satisfied {
+			log.Infof("LGTM endorsements are not sufficient yet (%d given, %d required, %d files uncovered), not adding the LGTM label.", endorsers.Len(), opts.RequiredLgtmCount, len(uncovered))
+			return nil
+		}
+		if !wantLGTM && satisfied {
+			log.Info("Remaining LGTM endorsements are sufficient, not removing the LGTM label.")
+			return nil
+		}
+	}
+
 	// Only add the label if it doesn't have it, and vice versa.
 	labels, err := gc.GetIssueLabels(org, repoName, number)
 	if err != nil {
@@ -332,7 +448,6 @@ func handle(wantLGTM bool, config *plugins.Configuration, ownersClient repoowner
 	hasLGTM := github.HasLabel(LGTMLabel, labels)
 
 	// remove the label if necessary, we're done after this
-	opts := config.LgtmFor(rc.repo.Owner.Login, rc.repo.Name)
 	if hasLGTM && !wantLGTM {
 		log.Info("Removing LGTM label.")
 		if err := removeLGTMAndRequestReview(gc, org, repoName, number, getLogins(assignees), opts.StoreTreeHash); err != nil {
@@ -404,6 +519,21 @@ func stickyLgtm(log *logrus.Entry, gc githubClient, _ *plugins.Configuration, lg
 	return false
 }
 
+// memberOfBlockedTeam returns the first of the blocked teams that login is a
+// member of, or an empty string if there is none.
+func memberOfBlockedTeam(gc githubClient, org, login string, blockedTeams []string) (string, error) {
+	for _, team := range blockedTeams {
+		isMember, err := gc.TeamBySlugHasMember(org, team, login)
+		if err != nil {
+			return "", err
+		}
+		if isMember {
+			return team, nil
+		}
+	}
+	return "", nil
+}
+
 func handlePullRequest(log *logrus.Entry, gc githubClient, config *plugins.Configuration, pe *github.PullRequestEvent) error {
 	if pe.PullRequest.Merged {
 		return nil
@@ -429,29 +559,33 @@ func handlePullRequest(log *logrus.Entry, gc githubClient, config *plugins.Confi
 		log.WithError(err).Error("Failed to get labels.")
 	}
 	if !github.HasLabel(LGTMLabel, labels) {
+		if opts.StoreTreeHash {
+			restored, err := restoreLGTM(log, gc, org, repo, number, pe.PullRequest.Head.SHA)
+			if err != nil {
+				log.WithError(err).Error("Failed to restore LGTM label.")
+			} else if restored {
+				return nil
+			}
+		}
+		// Partial endorsements were given for code that has since changed.
+		if opts.RequiredLgtmCount > 1 || skipCollaborators(config, org, repo) {
+			if err := resetEndorsements(gc, org, repo, number); err != nil {
+				log.WithError(err).Error("Failed to reset LGTM endorsements.")
+			}
+		}
 		return nil
 	}
 
 	if opts.StoreTreeHash {
 		// Check if we have a tree-hash comment
 		var lastLgtmTreeHash string
-		botUserChecker, err := gc.BotUserChecker()
+		treeHashes, err := lgtmTreeHashes(gc, org, repo, number)
 		if err != nil {
 			return err
 		}
-		comments, err := gc.ListIssueComments(org, repo, number)
-		if err != nil {
-			log.WithError(err).Error("Failed to get issue comments.")
-		}
-		// older comments are still present
-		// iterate backwards to find the last LGTM tree-hash
-		for i := len(comments) - 1; i >= 0; i-- {
-			comment := comments[i]
-			m := addLGTMLabelNotificationRe.FindStringSubmatch(comment.Body)
-			if botUserChecker(comment.User.Login) && m != nil && comment.UpdatedAt.Equal(comment.CreatedAt) {
-				lastLgtmTreeHash = m[1]
-				break
-			}
+		// older comments are still present, the last one is the current LGTM
+		if len(treeHashes) > 0 {
+			lastLgtmTreeHash = treeHashes[len(treeHashes)-1]
 		}
 		if lastLgtmTreeHash != "" {
 			// Get the current tree-hash
@@ -465,12 +599,26 @@ func handlePullRequest(log *logrus.Entry, gc githubClient, config *plugins.Confi
 				log.Infof("Keeping LGTM label as the tree-hash remained the same: %s", treeHash)
 				return nil
 			}
+			if len(opts.TreeHashExemptPaths) > 0 {
+				exempt, err := onlyExemptChanges(gc, org, repo, number, lastLgtmTreeHash, opts.TreeHashExemptPaths)
+				if err != nil {
+					log.WithError(err).Error("Failed to determine changes since LGTM.")
+				} else if exempt {
+					log.Infof("Keeping LGTM label as all changes since tree-hash %s match exempt paths.", lastLgtmTreeHash)
+					return nil
+				}
+			}
 		}
 	}
 
 	if err := removeLGTMAndRequestReview(gc, org, repo, number, getLogins(pe.PullRequest.Assignees), opts.StoreTreeHash); err != nil {
 		return fmt.Errorf("failed removing lgtm label: %w", err)
 	}
+	if opts.RequiredLgtmCount > 1 || skipCollaborators(config, org, repo) {
+		if err := resetEndorsements(gc, org, repo, number); err != nil {
+			log.WithError(err).Error("Failed to reset LGTM endorsements.")
+		}
+	}
 
 	// Create a comment to inform participants that LGTM label is removed due to new
 	// pull request changes.
@@ -478,6 +626,222 @@ func handlePullRequest(log *logrus.Entry, gc githubClient, config *plugins.Confi
 	return gc.CreateComment(org, repo, number, removeLGTMLabelNoti)
 }
 
+// handleStale removes the lgtm label from open PRs whose last LGTM is older
+// than the stale_after duration configured for their repo.
+func handleStale(log *logrus.Entry, gc githubClient, config *plugins.Configuration, now time.Time) error {
+	// An org and one of its repos may both be configured, so collect the PRs
+	// first and resolve the effective config for each of them afterwards.
+	prs := map[string]github.Issue{}
+	var errs []error
+	for _, lgtm := range config.Lgtm {
+		if lgtm.StaleAfterDuration == 0 {
+			continue
+		}
+		for _, orgOrRepo := range lgtm.Repos {
+			issues, err := gc.FindIssues(fmt.Sprintf("is:pr is:open label:%s %s", LGTMLabel, plugins.SearchQualifier(orgOrRepo)), "", false)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to search for PRs in %s: %w", orgOrRepo, err))
+				continue
+			}
+			for _, issue := range issues {
+				prs[issue.HTMLURL] = issue
+			}
+		}
+	}
+
+	for htmlURL, pr := range prs {
+		org, repo, err := github.OrgRepoFromHTMLURL(htmlURL)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		opts := config.LgtmFor(org, repo)
+		if !config.PluginEnabledFor(PluginName, org, repo) || opts.StaleAfterDuration == 0 {
+			continue
+		}
+		l := log.WithFields(logrus.Fields{github.OrgLogField: org, github.RepoLogField: repo, github.PrLogField: pr.Number})
+		if err := removeStaleLGTM(l, gc, config, opts, org, repo, pr, now); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove stale LGTM from %s/%s#%d: %w", org, repo, pr.Number, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// removeStaleLGTM removes the lgtm label from the PR if it was last added
+// longer than opts.StaleAfterDuration ago.
+func removeStaleLGTM(log *logrus.Entry, gc githubClient, config *plugins.Configuration, opts *plugins.Lgtm, org, repo string, pr github.Issue, now time.Time) error {
+	events, err := gc.ListIssueEvents(org, repo, pr.Number)
+	if err != nil {
+		return fmt.Errorf("failed to list issue events: %w", err)
+	}
+	var lastLGTM time.Time
+	for _, event := range events {
+		if event.Event == github.IssueActionLabeled && event.Label.Name == LGTMLabel && event.CreatedAt.After(lastLGTM) {
+			lastLGTM = event.CreatedAt
+		}
+	}
+	if lastLGTM.IsZero() || now.Sub(lastLGTM) < opts.StaleAfterDuration {
+		return nil
+	}
+
+	log.WithField("lgtm_time", lastLGTM).Info("Removing stale LGTM label.")
+	if err := removeLGTMAndRequestReview(gc, org, repo, pr.Number, getLogins(pr.Assignees), opts.StoreTreeHash); err != nil {
+		return err
+	}
+	if opts.StoreTreeHash {
+		// A stale LGTM must not be restored by pushing its tree again.
+		if err := deleteTreeHashComments(gc, org, repo, pr.Number); err != nil {
+			log.WithError(err).Error("Failed to delete LGTM tree-hash comments.")
+		}
+	}
+	if opts.RequiredLgtmCount > 1 || skipCollaborators(config, org, repo) {
+		if err := resetEndorsements(gc, org, repo, pr.Number); err != nil {
+			log.WithError(err).Error("Failed to reset LGTM endorsements.")
+		}
+	}
+	return gc.CreateComment(org, repo, pr.Number, fmt.Sprintf(staleLGTMNotification, opts.StaleAfterDuration))
+}
+
+// onlyExemptChanges determines whether every file changed by the commits
+// following the LGTM'd tree matches one of the exempt path patterns. If the
+// LGTM'd tree is no longer part of the PR history, e.g. after a force-push, the
+// changes cannot be determined and are not considered exempt.
+func onlyExemptChanges(gc githubClient, org, repo string, number int, lgtmTreeHash string, exemptPaths []string) (bool, error) {
+	var patterns []gitattributes.Pattern
+	for _, path := range exemptPaths {
+		pattern, err := gitattributes.ParsePattern(path)
+		if err != nil {
+			return false, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	matchesExempt := func(filename string) bool {
+		for _, pattern := range patterns {
+			if pattern.Match(filename) {
+				return true
+			}
+		}
+		return false
+	}
+
+	commits, err := gc.ListPRCommits(org, repo, number)
+	if err != nil {
+		return false, fmt.Errorf("failed to list commits: %w", err)
+	}
+	lgtmIndex := -1
+	for i, commit := range commits {
+		if commit.Commit.Tree.SHA == lgtmTreeHash {
+			lgtmIndex = i
+		}
+	}
+	if lgtmIndex == -1 || lgtmIndex == len(commits)-1 {
+		return false, nil
+	}
+	for _, commit := range commits[lgtmIndex+1:] {
+		full, err := gc.GetSingleCommit(org, repo, commit.SHA)
+		if err != nil {
+			return false, fmt.Errorf("failed to get commit %s: %w", commit.SHA, err)
+		}
+		for _, file := range full.Files {
+			if !matchesExempt(file.Filename) {
+				return false, nil
+			}
+			if file.PreviousFilename != "" && !matchesExempt(file.PreviousFilename) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// lgtmTreeHashes returns the tree-hashes the bot recorded when adding the
+// LGTM label, oldest first.
+func lgtmTreeHashes(gc githubClient, org, repo string, number int) ([]string, error) {
+	botUserChecker, err := gc.BotUserChecker()
+	if err != nil {
+		return nil, err
+	}
+	comments, err := gc.ListIssueComments(org, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issue comments: %w", err)
+	}
+	var treeHashes []string
+	for _, comment := range comments {
+		m := addLGTMLabelNotificationRe.FindStringSubmatch(comment.Body)
+		if botUserChecker(comment.User.Login) && m != nil && comment.UpdatedAt.Equal(comment.CreatedAt) {
+			treeHashes = append(treeHashes, m[1])
+		}
+	}
+	return treeHashes, nil
+}
+
+// deleteTreeHashComments deletes the tree-hash comments of every previous LGTM.
+func deleteTreeHashComments(gc githubClient, org, repo string, number int) error {
+	botUserChecker, err := gc.BotUserChecker()
+	if err != nil {
+		return err
+	}
+	comments, err := gc.ListIssueComments(org, repo, number)
+	if err != nil {
+		return fmt.Errorf("failed to list issue comments: %w", err)
+	}
+	var errs []error
+	for _, comment := range comments {
+		if botUserChecker(comment.User.Login) && addLGTMLabelNotificationRe.MatchString(comment.Body) {
+			if err := gc.DeleteComment(org, repo, comment.ID); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// restoreLGTM re-adds the LGTM label when the PR was synchronized back to a
+// tree that was LGTM'd before, e.g. after a rebase that was reverted. The label
+// is only restored if it was last removed by the bot, so that a human removing
+// it is not overridden.
+func restoreLGTM(log *logrus.Entry, gc githubClient, org, repo string, number int, headSHA string) (bool, error) {
+	treeHashes, err := lgtmTreeHashes(gc, org, repo, number)
+	if err != nil || len(treeHashes) == 0 {
+		return false, err
+	}
+	commit, err := gc.GetSingleCommit(org, repo, headSHA)
+	if err != nil {
+		return false, fmt.Errorf("failed to get commit %s: %w", headSHA, err)
+	}
+	treeHash := commit.Commit.Tree.SHA
+	if !sets.NewString(treeHashes...).Has(treeHash) {
+		return false, nil
+	}
+
+	events, err := gc.ListIssueEvents(org, repo, number)
+	if err != nil {
+		return false, fmt.Errorf("failed to list issue events: %w", err)
+	}
+	var lastRemoval *github.ListedIssueEvent
+	for i, event := range events {
+		if event.Event == github.IssueActionUnlabeled && event.Label.Name == LGTMLabel && (lastRemoval == nil || !event.CreatedAt.Before(lastRemoval.CreatedAt)) {
+			lastRemoval = &events[i]
+		}
+	}
+	botUserChecker, err := gc.BotUserChecker()
+	if err != nil {
+		return false, err
+	}
+	if lastRemoval == nil || !botUserChecker(lastRemoval.Actor.Login) {
+		return false, nil
+	}
+
+	log.WithField("tree", treeHash).Info("Restoring LGTM label as the tree-hash matches a previous LGTM.")
+	if err := gc.AddLabel(org, repo, number, LGTMLabel); err != nil {
+		return false, err
+	}
+	if err := gc.CreateComment(org, repo, number, fmt.Sprintf(addLGTMLabelNotification, treeHash)); err != nil {
+		log.WithError(err).Error("Failed to add comment.")
+	}
+	return true, nil
+}
+
 func removeLGTMAndRequestReview(gc githubClient, org, repo string, number int, logins []string, storeTreeHash bool) error {
 	if err := gc.RemoveLabel(org, repo, number, LGTMLabel); err != nil {
 		return fmt.Errorf("failed removing lgtm label: %w", err)
@@ -493,6 +857,80 @@ func removeLGTMAndRequestReview(gc githubClient, org, repo string, number int, l
 	return nil
 }
 
+// findEndorsements returns the bot-managed endorsements comment, if there is
+// one, and the logins it records.
+func findEndorsements(gc githubClient, org, repo string, number int) (*github.IssueComment, sets.String, error) {
+	botUserChecker, err := gc.BotUserChecker()
+	if err != nil {
+		return nil, nil, err
+	}
+	comments, err := gc.ListIssueComments(org, repo, number)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	for i := range comments {
+		if !botUserChecker(comments[i].User.Login) {
+			continue
+		}
+		if m := lgtmEndorsementsRe.FindStringSubmatch(comments[i].Body); m != nil {
+			return &comments[i], sets.NewString(strings.Fields(m[1])...), nil
+		}
+	}
+	return nil, sets.NewString(), nil
+}
+
+// setEndorsements records endorsers and the changed files that none of them
+// cover in the bot-managed endorsements comment.
+func setEndorsements(gc githubClient, org, repo string, number int, existing *github.IssueComment, endorsers sets.String, uncovered []string) error {
+	var b strings.Builder
+	if endorsers.Len() == 0 {
+		b.WriteString("No LGTMs have been given.\n")
+	} else {
+		fmt.Fprintf(&b, "LGTM has been given by: `%s`.\n", strings.Join(endorsers.List(), "`, `"))
+	}
+	if len(uncovered) > 0 {
+		b.WriteString("\nThe following files still need an LGTM from an approver or reviewer in their OWNERS files:\n")
+		for _, filename := range uncovered {
+			fmt.Fprintf(&b, "- `%s`\n", filename)
+		}
+	}
+	fmt.Fprintf(&b, lgtmEndorsementsNotification, strings.Join(endorsers.List(), " "))
+	body := b.String()
+
+	if existing == nil {
+		if endorsers.Len() == 0 {
+			return nil
+		}
+		return gc.CreateComment(org, repo, number, body)
+	}
+	if existing.Body == body {
+		return nil
+	}
+	return gc.EditComment(org, repo, existing.ID, body)
+}
+
+// resetEndorsements withdraws every endorsement recorded on the PR.
+func resetEndorsements(gc githubClient, org, repo string, number int) error {
+	existing, _, err := findEndorsements(gc, org, repo, number)
+	if err != nil {
+		return err
+	}
+	return setEndorsements(gc, org, repo, number, existing, sets.NewString(), nil)
+}
+
+// uncoveredFiles returns the filenames for which none of the endorsers is an
+// approver or reviewer in the covering OWNERS files.
+func uncoveredFiles(ro repoowners.RepoOwner, filenames []string, endorsers sets.String) []string {
+	var uncovered []string
+	for _, filename := range filenames {
+		owners := ro.Approvers(filename).Union(ro.Reviewers(filename))
+		if !owners.Set().HasAny(endorsers.UnsortedList()...) {
+			uncovered = append(uncovered, filename)
+		}
+	}
+	return uncovered
+}
+
 func getLogins(usrs []github.User) []string {
 	res := []string{}
 	for _, usr := range usrs {
@@ -533,7 +971,8 @@ func getChangedFiles(gc githubClient, org, repo string, number int) ([]string, e
 }
 
 // loadReviewers returns all reviewers and approvers from all OWNERS files that
-// cover the provided filenames.
+// cover the provided filenames. For repos without OWNERS files these come from
+// the owners listed in the repo's CODEOWNERS file.
 func loadReviewers(ro repoowners.RepoOwner, filenames []string) layeredsets.String {
 	reviewers := layeredsets.String{}
 	for _, filename := range filenames {
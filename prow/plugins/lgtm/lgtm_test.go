@@ -51,6 +51,21 @@ func (f *fakeOwnersClient) LoadRepoOwnersSha(org, repo, base, sha string, update
 
 var _ repoowners.Interface = &fakeOwnersClient{}
 
+// handleGenericComment handles the lgtm commands in new comments like hook
+// does.
+func handleGenericComment(gc githubClient, config *plugins.Configuration, ownersClient repoowners.Interface, log *logrus.Entry, cp commentPruner, e github.GenericCommentEvent) error {
+	if e.Action != github.GenericCommentActionCreated {
+		return nil
+	}
+	invocations, _ := plugins.ParseCommands(e.Body, lgtmCommands)
+	for _, invocation := range invocations {
+		if err := handleInvocation(gc, config, ownersClient, log, cp, e, invocation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (f *fakeOwnersClient) LoadRepoOwners(org, repo, base string) (repoowners.RepoOwner, error) {
 	return &fakeRepoOwners{approvers: f.approvers, reviewers: f.reviewers}, nil
 }
@@ -459,9 +474,16 @@ func TestLGTMComment(t *testing.T) {
 		} else if (tc.hasLGTM && len(fc.IssueLabelsAdded) > 1) || (!tc.hasLGTM && len(fc.IssueLabelsAdded) > 0) {
 			t.Error("should not have added LGTM.")
 		}
-		if tc.shouldComment && len(fc.IssueComments[5]) != 1 {
+		// LGTM endorsements are tracked in their own bot-managed comment
+		var comments []github.IssueComment
+		for _, comment := range fc.IssueComments[5] {
+			if !lgtmEndorsementsRe.MatchString(comment.Body) {
+				comments = append(comments, comment)
+			}
+		}
+		if tc.shouldComment && len(comments) != 1 {
 			t.Error("should have commented.")
-		} else if !tc.shouldComment && len(fc.IssueComments[5]) != 0 {
+		} else if !tc.shouldComment && len(comments) != 0 {
 			t.Error("should not have commented.")
 		}
 		if tc.shouldRequest && len(fc.ReviewersRequested) == 0 {
@@ -473,6 +495,147 @@ func TestLGTMComment(t *testing.T) {
 	}
 }
 
+func TestLGTMFromReviewComment(t *testing.T) {
+	var testcases = []struct {
+		name         string
+		body         string
+		commenter    string
+		hasLGTM      bool
+		shouldToggle bool
+	}{
+		{
+			name:         "lgtm in inline review comment by collaborator",
+			body:         "/lgtm",
+			commenter:    "collab1",
+			shouldToggle: true,
+		},
+		{
+			name:         "lgtm cancel in inline review comment by collaborator",
+			body:         "/lgtm cancel",
+			commenter:    "collab1",
+			hasLGTM:      true,
+			shouldToggle: true,
+		},
+		{
+			name:      "lgtm in inline review comment by rando",
+			body:      "/lgtm",
+			commenter: "not-in-the-org",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.PullRequests = map[int]*github.PullRequest{5: {}}
+			fc.Collaborators = []string{"collab1", "collab2"}
+			if tc.hasLGTM {
+				fc.IssueLabelsAdded = []string{"org/repo#5:" + LGTMLabel}
+			}
+			// This mirrors the generic comment event hook creates for a
+			// pull_request_review_comment event.
+			commentID := 1
+			e := github.GenericCommentEvent{
+				Action:      github.GenericCommentActionCreated,
+				IssueState:  "open",
+				IsPR:        true,
+				CommentID:   &commentID,
+				Body:        tc.body,
+				User:        github.User{Login: tc.commenter},
+				IssueAuthor: github.User{Login: "author"},
+				Number:      5,
+				Assignees:   []github.User{{Login: "collab1"}},
+				Repo:        github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+				HTMLURL:     "<url>",
+			}
+			if err := handleGenericComment(fc, &plugins.Configuration{}, &fakeOwnersClient{}, logrus.WithField("plugin", PluginName), &fakePruner{GitHubClient: fc}, e); err != nil {
+				t.Fatalf("didn't expect error from lgtmComment: %v", err)
+			}
+			var toggled bool
+			if tc.hasLGTM {
+				toggled = len(fc.IssueLabelsRemoved) > 0
+			} else {
+				toggled = len(fc.IssueLabelsAdded) > 0
+			}
+			if toggled != tc.shouldToggle {
+				t.Errorf("expected label toggled %t, got %t", tc.shouldToggle, toggled)
+			}
+		})
+	}
+}
+
+func TestLGTMBlockedTeams(t *testing.T) {
+	var testcases = []struct {
+		name          string
+		body          string
+		commenter     string
+		hasLGTM       bool
+		shouldToggle  bool
+		shouldComment bool
+	}{
+		{
+			name:          "lgtm by member of a blocked team",
+			body:          "/lgtm",
+			commenter:     "release-bot",
+			shouldComment: true,
+		},
+		{
+			name:         "lgtm by collaborator outside of blocked teams",
+			body:         "/lgtm",
+			commenter:    "collab1",
+			shouldToggle: true,
+		},
+		{
+			name:         "lgtm cancel by member of a blocked team",
+			body:         "/lgtm cancel",
+			commenter:    "release-bot",
+			hasLGTM:      true,
+			shouldToggle: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.PullRequests = map[int]*github.PullRequest{5: {}}
+			fc.Collaborators = []string{"collab1", "release-bot"}
+			fc.Teams = map[string]map[string]fakegithub.TeamWithMembers{
+				"org": {"release-automation": {Members: sets.NewString("release-bot")}},
+			}
+			if tc.hasLGTM {
+				fc.IssueLabelsAdded = []string{"org/repo#5:" + LGTMLabel}
+			}
+			e := github.GenericCommentEvent{
+				Action:      github.GenericCommentActionCreated,
+				IssueState:  "open",
+				IsPR:        true,
+				Body:        tc.body,
+				User:        github.User{Login: tc.commenter},
+				IssueAuthor: github.User{Login: "author"},
+				Number:      5,
+				Assignees:   []github.User{{Login: "collab1"}, {Login: "release-bot"}},
+				Repo:        github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+				HTMLURL:     "<url>",
+			}
+			pc := &plugins.Configuration{
+				Lgtm: []plugins.Lgtm{{Repos: []string{"org"}, BlockedTeams: []string{"release-automation"}}},
+			}
+			if err := handleGenericComment(fc, pc, &fakeOwnersClient{}, logrus.WithField("plugin", PluginName), &fakePruner{GitHubClient: fc}, e); err != nil {
+				t.Fatalf("didn't expect error from lgtmComment: %v", err)
+			}
+			var toggled bool
+			if tc.hasLGTM {
+				toggled = len(fc.IssueLabelsRemoved) > 0
+			} else {
+				toggled = len(fc.IssueLabelsAdded) > 0
+			}
+			if toggled != tc.shouldToggle {
+				t.Errorf("expected label toggled %t, got %t", tc.shouldToggle, toggled)
+			}
+			if commented := len(fc.IssueComments[5]) > 0; commented != tc.shouldComment {
+				t.Errorf("expected comment %t, got %t", tc.shouldComment, commented)
+			}
+		})
+	}
+}
+
 func TestLGTMCommentWithLGTMNoti(t *testing.T) {
 	var testcases = []struct {
 		name         string
@@ -1207,6 +1370,163 @@ func TestHandlePullRequest(t *testing.T) {
 	}
 }
 
+func TestHandlePullRequestTreeHashExemptPaths(t *testing.T) {
+	lgtmTreeSHA := "6dcb09b5b57875f334f61aebed695e2e4193db5e"
+	commitWithFiles := func(sha, tree string, files ...string) github.RepositoryCommit {
+		commit := github.RepositoryCommit{SHA: sha}
+		commit.Commit.Tree.SHA = tree
+		for _, file := range files {
+			commit.Files = append(commit.Files, github.CommitFile{Filename: file})
+		}
+		return commit
+	}
+	cases := []struct {
+		name          string
+		commits       []github.RepositoryCommit
+		expectRemoved bool
+	}{
+		{
+			name: "only exempt files changed since the LGTM",
+			commits: []github.RepositoryCommit{
+				commitWithFiles("sha1", lgtmTreeSHA, "pkg/main.go"),
+				commitWithFiles("sha2", "tree2", "docs/README.md"),
+				commitWithFiles("sha3", "tree3", "pkg/OWNERS"),
+			},
+		},
+		{
+			name: "non-exempt file changed since the LGTM",
+			commits: []github.RepositoryCommit{
+				commitWithFiles("sha1", lgtmTreeSHA, "pkg/main.go"),
+				commitWithFiles("sha2", "tree2", "docs/README.md", "pkg/main.go"),
+			},
+			expectRemoved: true,
+		},
+		{
+			name: "LGTM'd tree is no longer part of the history",
+			commits: []github.RepositoryCommit{
+				commitWithFiles("sha2", "tree2", "docs/README.md"),
+			},
+			expectRemoved: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.IssueLabelsAdded = []string{"org/repo#5:" + LGTMLabel}
+			fc.IssueComments = map[int][]github.IssueComment{
+				5: {{
+					Body: fmt.Sprintf(addLGTMLabelNotification, lgtmTreeSHA),
+					User: github.User{Login: fakegithub.Bot},
+				}},
+			}
+			fc.CommitMap = map[string][]github.RepositoryCommit{"org/repo#5": tc.commits}
+			fc.Commits = map[string]github.RepositoryCommit{}
+			for _, commit := range tc.commits {
+				fc.Commits[commit.SHA] = commit
+			}
+			head := tc.commits[len(tc.commits)-1].SHA
+			pc := &plugins.Configuration{
+				Lgtm: []plugins.Lgtm{{
+					Repos:               []string{"org/repo"},
+					StoreTreeHash:       true,
+					TreeHashExemptPaths: []string{"OWNERS", "docs/**"},
+				}},
+			}
+			event := github.PullRequestEvent{
+				Action: github.PullRequestActionSynchronize,
+				PullRequest: github.PullRequest{
+					Number: 5,
+					Base:   github.PullRequestBranch{Repo: github.Repo{Owner: github.User{Login: "org"}, Name: "repo"}},
+					Head:   github.PullRequestBranch{SHA: head},
+				},
+			}
+			if err := handlePullRequest(logrus.WithField("plugin", PluginName), fc, pc, &event); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			removed := len(fc.IssueLabelsRemoved) > 0
+			if removed != tc.expectRemoved {
+				t.Errorf("expected label removed %t, got %t", tc.expectRemoved, removed)
+			}
+		})
+	}
+}
+
+func TestHandlePullRequestRestoresLGTM(t *testing.T) {
+	lgtmTreeSHA := "6dcb09b5b57875f334f61aebed695e2e4193db5e"
+	removedBy := func(login string) []github.ListedIssueEvent {
+		return []github.ListedIssueEvent{
+			{Event: github.IssueActionLabeled, Label: github.Label{Name: LGTMLabel}, Actor: github.User{Login: "reviewer"}, CreatedAt: time.Unix(10, 0)},
+			{Event: github.IssueActionUnlabeled, Label: github.Label{Name: LGTMLabel}, Actor: github.User{Login: login}, CreatedAt: time.Unix(20, 0)},
+		}
+	}
+	cases := []struct {
+		name           string
+		headTree       string
+		events         []github.ListedIssueEvent
+		expectRestored bool
+	}{
+		{
+			name:           "tree restored after the bot removed the label",
+			headTree:       lgtmTreeSHA,
+			events:         removedBy(fakegithub.Bot),
+			expectRestored: true,
+		},
+		{
+			name:     "tree differs from every LGTM'd tree",
+			headTree: "other-tree",
+			events:   removedBy(fakegithub.Bot),
+		},
+		{
+			name:     "label was removed by a human",
+			headTree: lgtmTreeSHA,
+			events:   removedBy("reviewer"),
+		},
+		{
+			name:     "label was never removed",
+			headTree: lgtmTreeSHA,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.IssueComments = map[int][]github.IssueComment{
+				5: {{
+					Body: fmt.Sprintf(addLGTMLabelNotification, lgtmTreeSHA),
+					User: github.User{Login: fakegithub.Bot},
+				}},
+			}
+			fc.IssueEvents = map[int][]github.ListedIssueEvent{5: tc.events}
+			head := github.RepositoryCommit{SHA: "head"}
+			head.Commit.Tree.SHA = tc.headTree
+			fc.Commits = map[string]github.RepositoryCommit{"head": head}
+			pc := &plugins.Configuration{
+				Lgtm: []plugins.Lgtm{{
+					Repos:         []string{"org/repo"},
+					StoreTreeHash: true,
+				}},
+			}
+			event := github.PullRequestEvent{
+				Action: github.PullRequestActionSynchronize,
+				PullRequest: github.PullRequest{
+					Number: 5,
+					Base:   github.PullRequestBranch{Repo: github.Repo{Owner: github.User{Login: "org"}, Name: "repo"}},
+					Head:   github.PullRequestBranch{SHA: "head"},
+				},
+			}
+			if err := handlePullRequest(logrus.WithField("plugin", PluginName), fc, pc, &event); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			restored := sets.NewString(fc.IssueLabelsAdded...).Has("org/repo#5:" + LGTMLabel)
+			if restored != tc.expectRestored {
+				t.Errorf("expected label restored %t, got %t", tc.expectRestored, restored)
+			}
+			if tc.expectRestored && len(fc.IssueComments[5]) != 2 {
+				t.Errorf("expected a new tree-hash comment, got comments %v", fc.IssueComments[5])
+			}
+		})
+	}
+}
+
 func TestAddTreeHashComment(t *testing.T) {
 	cases := []struct {
 		name          string
@@ -1341,6 +1661,297 @@ func TestRemoveTreeHashComment(t *testing.T) {
 	}
 }
 
+func TestRequiredLgtmCount(t *testing.T) {
+	endorsementsComment := func(endorsers ...string) github.IssueComment {
+		return github.IssueComment{
+			ID:   1,
+			Body: fmt.Sprintf(lgtmEndorsementsNotification, strings.Join(endorsers, " ")),
+			User: github.User{Login: fakegithub.Bot},
+		}
+	}
+	cases := []struct {
+		name              string
+		body              string
+		commenter         string
+		hasLGTM           bool
+		comments          []github.IssueComment
+		expectAdded       bool
+		expectRemoved     bool
+		expectEndorsement string
+	}{
+		{
+			name:              "first lgtm only records the endorsement",
+			body:              "/lgtm",
+			commenter:         "collab1",
+			expectEndorsement: "collab1",
+		},
+		{
+			name:              "second distinct lgtm adds the label",
+			body:              "/lgtm",
+			commenter:         "collab2",
+			comments:          []github.IssueComment{endorsementsComment("collab1")},
+			expectAdded:       true,
+			expectEndorsement: "collab1 collab2",
+		},
+		{
+			name:              "repeated lgtm from the same user does not add the label",
+			body:              "/lgtm",
+			commenter:         "collab1",
+			comments:          []github.IssueComment{endorsementsComment("collab1")},
+			expectEndorsement: "collab1",
+		},
+		{
+			name:              "cancel drops below the threshold and removes the label",
+			body:              "/lgtm cancel",
+			commenter:         "collab1",
+			hasLGTM:           true,
+			comments:          []github.IssueComment{endorsementsComment("collab1", "collab2")},
+			expectRemoved:     true,
+			expectEndorsement: "collab2",
+		},
+		{
+			name:              "cancel that keeps enough endorsements keeps the label",
+			body:              "/lgtm cancel",
+			commenter:         "collab1",
+			hasLGTM:           true,
+			comments:          []github.IssueComment{endorsementsComment("assignee1", "collab1", "collab2")},
+			expectEndorsement: "assignee1 collab2",
+		},
+		{
+			name:              "cancel by the author withdraws all endorsements",
+			body:              "/lgtm cancel",
+			commenter:         "author",
+			hasLGTM:           true,
+			comments:          []github.IssueComment{endorsementsComment("collab1", "collab2")},
+			expectRemoved:     true,
+			expectEndorsement: "",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.IssueComments = map[int][]github.IssueComment{5: tc.comments}
+			fc.IssueCommentID = len(tc.comments)
+			fc.PullRequests = map[int]*github.PullRequest{5: {}}
+			fc.Collaborators = []string{"collab1", "collab2"}
+			if tc.hasLGTM {
+				fc.IssueLabelsAdded = []string{"org/repo#5:" + LGTMLabel}
+			}
+			e := github.GenericCommentEvent{
+				Action:      github.GenericCommentActionCreated,
+				IssueState:  "open",
+				IsPR:        true,
+				Body:        tc.body,
+				User:        github.User{Login: tc.commenter},
+				IssueAuthor: github.User{Login: "author"},
+				Number:      5,
+				Assignees:   []github.User{{Login: "collab1"}, {Login: "collab2"}},
+				Repo:        github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+			}
+			pc := &plugins.Configuration{
+				Lgtm: []plugins.Lgtm{{Repos: []string{"org/repo"}, RequiredLgtmCount: 2}},
+			}
+			fp := &fakePruner{GitHubClient: fc}
+			if err := handleGenericComment(fc, pc, &fakeOwnersClient{}, logrus.WithField("plugin", PluginName), fp, e); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			added := len(fc.IssueLabelsAdded) > 0 && !tc.hasLGTM
+			if added != tc.expectAdded {
+				t.Errorf("expected label added %t, got %t", tc.expectAdded, added)
+			}
+			removed := len(fc.IssueLabelsRemoved) > 0
+			if removed != tc.expectRemoved {
+				t.Errorf("expected label removed %t, got %t", tc.expectRemoved, removed)
+			}
+			var endorsements string
+			for _, comment := range fc.IssueComments[5] {
+				if m := lgtmEndorsementsRe.FindStringSubmatch(comment.Body); m != nil {
+					endorsements = m[1]
+				}
+			}
+			if endorsements != tc.expectEndorsement {
+				t.Errorf("expected endorsements %q, got %q", tc.expectEndorsement, endorsements)
+			}
+		})
+	}
+}
+
+func TestPathScopedLGTM(t *testing.T) {
+	scopedApprovers := map[string]layeredsets.String{
+		"docs/README.md": layeredsets.NewString("doc-approver"),
+		"pkg/main.go":    layeredsets.NewString("pkg-approver"),
+	}
+	scopedReviewers := map[string]layeredsets.String{
+		"docs/README.md": layeredsets.NewString("doc-reviewer"),
+		"pkg/main.go":    layeredsets.NewString("pkg-reviewer"),
+	}
+	endorsementsComment := func(endorsers ...string) github.IssueComment {
+		return github.IssueComment{
+			ID:   1,
+			Body: fmt.Sprintf(lgtmEndorsementsNotification, strings.Join(endorsers, " ")),
+			User: github.User{Login: fakegithub.Bot},
+		}
+	}
+	cases := []struct {
+		name            string
+		body            string
+		commenter       string
+		hasLGTM         bool
+		comments        []github.IssueComment
+		expectAdded     bool
+		expectRemoved   bool
+		expectUncovered []string
+	}{
+		{
+			name:            "lgtm covering only some files does not add the label",
+			body:            "/lgtm",
+			commenter:       "doc-reviewer",
+			expectUncovered: []string{"pkg/main.go"},
+		},
+		{
+			name:        "lgtm covering the remaining files adds the label",
+			body:        "/lgtm",
+			commenter:   "pkg-approver",
+			comments:    []github.IssueComment{endorsementsComment("doc-reviewer")},
+			expectAdded: true,
+		},
+		{
+			name:            "cancel leaving files uncovered removes the label",
+			body:            "/lgtm cancel",
+			commenter:       "pkg-approver",
+			hasLGTM:         true,
+			comments:        []github.IssueComment{endorsementsComment("doc-reviewer", "pkg-approver")},
+			expectRemoved:   true,
+			expectUncovered: []string{"pkg/main.go"},
+		},
+		{
+			name:      "cancel leaving all files covered keeps the label",
+			body:      "/lgtm cancel",
+			commenter: "doc-approver",
+			hasLGTM:   true,
+			comments:  []github.IssueComment{endorsementsComment("doc-approver", "doc-reviewer", "pkg-reviewer")},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.IssueComments = map[int][]github.IssueComment{5: tc.comments}
+			fc.IssueCommentID = len(tc.comments)
+			fc.PullRequests = map[int]*github.PullRequest{5: {Base: github.PullRequestBranch{Ref: "master"}}}
+			fc.PullRequestChanges = map[int][]github.PullRequestChange{
+				5: {{Filename: "docs/README.md"}, {Filename: "pkg/main.go"}},
+			}
+			if tc.hasLGTM {
+				fc.IssueLabelsAdded = []string{"org/repo#5:" + LGTMLabel}
+			}
+			e := github.GenericCommentEvent{
+				Action:      github.GenericCommentActionCreated,
+				IssueState:  "open",
+				IsPR:        true,
+				Body:        tc.body,
+				User:        github.User{Login: tc.commenter},
+				IssueAuthor: github.User{Login: "author"},
+				Number:      5,
+				Repo:        github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+			}
+			pc := &plugins.Configuration{}
+			pc.Owners.SkipCollaborators = []string{"org/repo"}
+			oc := &fakeOwnersClient{approvers: scopedApprovers, reviewers: scopedReviewers}
+			if err := handleGenericComment(fc, pc, oc, logrus.WithField("plugin", PluginName), &fakePruner{GitHubClient: fc}, e); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			added := len(fc.IssueLabelsAdded) > 0 && !tc.hasLGTM
+			if added != tc.expectAdded {
+				t.Errorf("expected label added %t, got %t", tc.expectAdded, added)
+			}
+			removed := len(fc.IssueLabelsRemoved) > 0
+			if removed != tc.expectRemoved {
+				t.Errorf("expected label removed %t, got %t", tc.expectRemoved, removed)
+			}
+			var endorsements string
+			for _, comment := range fc.IssueComments[5] {
+				if lgtmEndorsementsRe.MatchString(comment.Body) {
+					endorsements = comment.Body
+				}
+			}
+			for _, filename := range []string{"docs/README.md", "pkg/main.go"} {
+				expected := sets.NewString(tc.expectUncovered...).Has(filename)
+				if listed := strings.Contains(endorsements, "- `"+filename+"`"); listed != expected {
+					t.Errorf("expected %s to be listed as uncovered %t, got %t", filename, expected, listed)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleStale(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	labeled := func(age time.Duration) github.ListedIssueEvent {
+		return github.ListedIssueEvent{
+			Event:     github.IssueActionLabeled,
+			Label:     github.Label{Name: LGTMLabel},
+			CreatedAt: now.Add(-age),
+		}
+	}
+	cases := []struct {
+		name          string
+		enabledFor    string
+		events        []github.ListedIssueEvent
+		expectRemoved bool
+	}{
+		{
+			name:       "fresh lgtm is kept",
+			enabledFor: "org/repo",
+			events:     []github.ListedIssueEvent{labeled(time.Hour)},
+		},
+		{
+			name:          "stale lgtm is removed",
+			enabledFor:    "org/repo",
+			events:        []github.ListedIssueEvent{labeled(48 * time.Hour)},
+			expectRemoved: true,
+		},
+		{
+			name:       "only the most recent lgtm counts",
+			enabledFor: "org/repo",
+			events:     []github.ListedIssueEvent{labeled(48 * time.Hour), labeled(time.Hour)},
+		},
+		{
+			name:       "stale lgtm is kept if the plugin is not enabled",
+			enabledFor: "org/other-repo",
+			events:     []github.ListedIssueEvent{labeled(48 * time.Hour)},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.Issues = map[int]*github.Issue{
+				5: {
+					Number:  5,
+					HTMLURL: "https://github.com/org/repo/pull/5",
+					Labels:  []github.Label{{Name: LGTMLabel}},
+				},
+			}
+			fc.IssueEvents = map[int][]github.ListedIssueEvent{5: tc.events}
+			fc.IssueLabelsAdded = []string{"org/repo#5:" + LGTMLabel}
+			pc := &plugins.Configuration{
+				Plugins: plugins.Plugins{tc.enabledFor: {Plugins: []string{PluginName}}},
+				Lgtm:    []plugins.Lgtm{{Repos: []string{"org"}, StaleAfter: "24h", StaleAfterDuration: 24 * time.Hour}},
+			}
+			if err := handleStale(logrus.WithField("plugin", PluginName), fc, pc, now); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			removed := len(fc.IssueLabelsRemoved) > 0
+			if removed != tc.expectRemoved {
+				t.Errorf("expected label removed %t, got %t", tc.expectRemoved, removed)
+			}
+			commented := len(fc.IssueComments[5]) > 0
+			if commented != tc.expectRemoved {
+				t.Errorf("expected notification %t, got %t", tc.expectRemoved, commented)
+			}
+		})
+	}
+}
+
 func TestHelpProvider(t *testing.T) {
 	enabledRepos := []config.OrgRepo{
 		{Org: "org1", Repo: "repo"},
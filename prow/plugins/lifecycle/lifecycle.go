@@ -18,7 +18,6 @@ package lifecycle
 
 import (
 	"fmt"
-	"regexp"
 
 	"github.com/sirupsen/logrus"
 
@@ -31,11 +30,31 @@ import (
 
 var (
 	lifecycleLabels = []string{labels.LifecycleActive, labels.LifecycleFrozen, labels.LifecycleStale, labels.LifecycleRotten}
-	lifecycleRe     = regexp.MustCompile(`(?mi)^/(remove-)?lifecycle (active|frozen|stale|rotten)\s*$`)
+	lifecycleStates = []string{"active", "frozen", "stale", "rotten"}
+
+	lifecycleCommands = []plugins.Command{
+		{
+			Name:        "lifecycle",
+			Args:        []plugins.CommandArg{{Name: "state", Values: lifecycleStates}},
+			Description: "Flags an issue or PR as active/frozen/stale/rotten",
+			WhoCanUse:   "Anyone can trigger this command.",
+			Examples:    []string{"/lifecycle frozen"},
+			Handler:     handleCommand,
+		},
+		{
+			Name:        "remove-lifecycle",
+			Args:        []plugins.CommandArg{{Name: "state", Values: lifecycleStates}},
+			Description: "Removes the active/frozen/stale/rotten flag of an issue or PR",
+			WhoCanUse:   "Anyone can trigger this command.",
+			Examples:    []string{"/remove-lifecycle stale"},
+			Handler:     handleCommand,
+		},
+	}
 )
 
 func init() {
 	plugins.RegisterGenericCommentHandler("lifecycle", lifecycleHandleGenericComment, help)
+	plugins.RegisterCommands("lifecycle", lifecycleCommands...)
 }
 
 func help(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
@@ -56,13 +75,6 @@ func help(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhelp.Plugin
 		WhoCanUse:   "Authors and collaborators on the repository can trigger this command.",
 		Examples:    []string{"/reopen"},
 	})
-	pluginHelp.AddCommand(pluginhelp.Command{
-		Usage:       "/[remove-]lifecycle <frozen|stale|rotten>",
-		Description: "Flags an issue or PR as frozen/stale/rotten",
-		Featured:    false,
-		WhoCanUse:   "Anyone can trigger this command.",
-		Examples:    []string{"/lifecycle frozen", "/remove-lifecycle stale"},
-	})
 	return pluginHelp, nil
 }
 
@@ -79,35 +91,24 @@ func lifecycleHandleGenericComment(pc plugins.Agent, e github.GenericCommentEven
 	if err := handleReopen(gc, log, &e); err != nil {
 		return err
 	}
-	if err := handleClose(gc, log, &e); err != nil {
-		return err
-	}
-	return handle(gc, log, &e)
+	return handleClose(gc, log, &e)
 }
 
-func handle(gc lifecycleClient, log *logrus.Entry, e *github.GenericCommentEvent) error {
-	// Only consider new comments.
-	if e.Action != github.GenericCommentActionCreated {
-		return nil
-	}
-
-	for _, mat := range lifecycleRe.FindAllStringSubmatch(e.Body, -1) {
-		if err := handleOne(gc, log, e, mat); err != nil {
-			return err
-		}
-	}
-	return nil
+func handleCommand(pc plugins.Agent, e github.GenericCommentEvent, invocation plugins.CommandInvocation) error {
+	return handle(pc.GitHubClient, pc.Logger, &e, invocation)
 }
 
-func handleOne(gc lifecycleClient, log *logrus.Entry, e *github.GenericCommentEvent, mat []string) error {
+// handle handles an invocation of /lifecycle or /remove-lifecycle, which
+// are only matched in new comments.
+func handle(gc lifecycleClient, log *logrus.Entry, e *github.GenericCommentEvent, invocation plugins.CommandInvocation) error {
+	remove := invocation.Name == "remove-lifecycle"
+	state := invocation.Arg("state")
 	org := e.Repo.Owner.Login
 	repo := e.Repo.Name
 	number := e.Number
 	user := e.User.Login
 
-	remove := mat[1] != ""
-	cmd := mat[2]
-	lbl := "lifecycle/" + cmd
+	lbl := "lifecycle/" + state
 
 	// Don't allow adding lifecycle/frozen label to PRs
 	if e.IsPR && lbl == labels.LifecycleFrozen && !remove {
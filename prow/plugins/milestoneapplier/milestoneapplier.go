@@ -19,6 +19,7 @@ limitations under the License.
 package milestoneapplier
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -34,6 +35,7 @@ import (
 const pluginName = "milestoneapplier"
 
 type githubClient interface {
+	ClearMilestone(org, repo string, num int) error
 	SetMilestone(org, repo string, issueNum, milestoneNum int) error
 	ListMilestones(org, repo string) ([]github.Milestone, error)
 }
@@ -65,7 +67,7 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", pluginName)
 	}
 	return &pluginhelp.PluginHelp{
-		Description: "The milestoneapplier plugin automatically applies the configured milestone for the base branch after a PR is merged. If a PR targets a non-default branch, it also adds the milestone when the PR is opened.",
+		Description: "The milestoneapplier plugin automatically applies the configured milestone for the base branch after a PR is merged. If a PR targets a non-default branch, it also adds the milestone when the PR is opened or retargeted to that branch. When a PR is retargeted to a branch without a configured milestone, the milestone of its previous base branch is removed.",
 		Config:      configInfo,
 		Snippet:     yamlSnippet,
 	}, nil
@@ -74,35 +76,60 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 func handlePullRequest(pc plugins.Agent, pre github.PullRequestEvent) error {
 	org := pre.PullRequest.Base.Repo.Owner.Login
 	repo := pre.PullRequest.Base.Repo.Name
-	baseBranch := pre.PullRequest.Base.Ref
 
 	// if there are no branch to milestone mappings for this repo, return early
 	branchToMilestone, ok := pc.PluginConfig.MilestoneApplier[fmt.Sprintf("%s/%s", org, repo)]
 	if !ok {
 		return nil
 	}
-	// if the repo does not define milestones for this branch, return early
-	milestone, ok := branchToMilestone[baseBranch]
-	if !ok {
-		return nil
-	}
 
-	return handle(pc.GitHubClient, pc.Logger, milestone, pre)
+	return handle(pc.GitHubClient, pc.Logger, branchToMilestone, pre)
 }
 
-func handle(gc githubClient, log *logrus.Entry, configuredMilestone string, pre github.PullRequestEvent) error {
+// retargetedFrom returns the previous base branch of a PR whose base branch
+// was just changed, or an empty string.
+func retargetedFrom(pre github.PullRequestEvent) string {
+	if pre.Action != github.PullRequestActionEdited {
+		return ""
+	}
+	var changes struct {
+		Base struct {
+			Ref struct {
+				From string `json:"from"`
+			} `json:"ref"`
+		} `json:"base"`
+	}
+	if err := json.Unmarshal(pre.Changes, &changes); err != nil {
+		return ""
+	}
+	return changes.Base.Ref.From
+}
+
+func handle(gc githubClient, log *logrus.Entry, branchToMilestone plugins.BranchToMilestone, pre github.PullRequestEvent) error {
 	pr := pre.PullRequest
+	previousBase := retargetedFrom(pre)
+
+	configuredMilestone, ok := branchToMilestone[pr.Base.Ref]
+	if !ok {
+		// if the repo does not define milestones for this branch, only remove
+		// the milestone applied for the branch the PR was retargeted from
+		if previousBase != "" && pr.Milestone != nil && pr.Milestone.Title == branchToMilestone[previousBase] {
+			log.Infof("Clearing the milestone %s of %s/%s#%d retargeted from %s.", pr.Milestone.Title, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pre.Number, previousBase)
+			return gc.ClearMilestone(pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pre.Number)
+		}
+		return nil
+	}
 
 	// if the current milestone is equal to the configured milestone, return early
 	if pr.Milestone != nil && pr.Milestone.Title == configuredMilestone {
 		return nil
 	}
 
-	// if a PR targets a non-default branch, apply milestone when opened and on merge
+	// if a PR targets a non-default branch, apply milestone when opened or retargeted and on merge
 	// if a PR targets the default branch, apply the milestone only on merge
 	merged := pre.Action == github.PullRequestActionClosed && pr.Merged
 	if pr.Base.Repo.DefaultBranch != pr.Base.Ref {
-		if !merged && pre.Action != github.PullRequestActionOpened {
+		if !merged && pre.Action != github.PullRequestActionOpened && previousBase == "" {
 			return nil
 		}
 	} else if !merged {
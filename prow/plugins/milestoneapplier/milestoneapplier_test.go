@@ -17,12 +17,14 @@ limitations under the License.
 package milestoneapplier
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/sirupsen/logrus"
 
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
 )
 
 func TestMilestoneApplier(t *testing.T) {
@@ -30,6 +32,7 @@ func TestMilestoneApplier(t *testing.T) {
 	testcases := []struct {
 		name                string
 		baseBranch          string
+		previousBaseBranch  string
 		prAction            github.PullRequestEventAction
 		merged              bool
 		previousMilestone   int
@@ -128,6 +131,46 @@ func TestMilestoneApplier(t *testing.T) {
 			configuredMilestone: 1,
 			expectedMilestone:   1,
 		},
+		{
+			name:                "PR retargeted to non-default branch => add milestone",
+			baseBranch:          "release-1.0",
+			previousBaseBranch:  "master",
+			prAction:            github.PullRequestActionEdited,
+			configuredMilestone: 1,
+			expectedMilestone:   1,
+		},
+		{
+			name:                "PR retargeted to another non-default branch => replace milestone",
+			baseBranch:          "release-1.0",
+			previousBaseBranch:  "release-2.0",
+			prAction:            github.PullRequestActionEdited,
+			previousMilestone:   2,
+			configuredMilestone: 1,
+			expectedMilestone:   1,
+		},
+		{
+			name:                "PR edited without retargeting on non-default branch => do nothing",
+			baseBranch:          "release-1.0",
+			prAction:            github.PullRequestActionEdited,
+			configuredMilestone: 1,
+			expectedMilestone:   0,
+		},
+		{
+			name:               "PR retargeted to branch without milestone => clear milestone of previous branch",
+			baseBranch:         "feature",
+			previousBaseBranch: "release-2.0",
+			prAction:           github.PullRequestActionEdited,
+			previousMilestone:  2,
+			expectedMilestone:  0,
+		},
+		{
+			name:               "PR retargeted to branch without milestone => keep other milestone",
+			baseBranch:         "feature",
+			previousBaseBranch: "release-2.0",
+			prAction:           github.PullRequestActionEdited,
+			previousMilestone:  1,
+			expectedMilestone:  1,
+		},
 	}
 
 	for _, tc := range testcases {
@@ -147,9 +190,14 @@ func TestMilestoneApplier(t *testing.T) {
 			}
 
 			basicPR.Merged = tc.merged
+			milestoneTitles := map[int]string{}
+			for title, number := range milestonesMap {
+				milestoneTitles[number] = title
+			}
 			if tc.previousMilestone != 0 {
 				basicPR.Milestone = &github.Milestone{
 					Number: tc.previousMilestone,
+					Title:  milestoneTitles[tc.previousMilestone],
 				}
 			}
 
@@ -158,6 +206,21 @@ func TestMilestoneApplier(t *testing.T) {
 				Number:      basicPR.Number,
 				PullRequest: basicPR,
 			}
+			if tc.previousBaseBranch != "" {
+				var changes struct {
+					Base struct {
+						Ref struct {
+							From string `json:"from"`
+						} `json:"ref"`
+					} `json:"base"`
+				}
+				changes.Base.Ref.From = tc.previousBaseBranch
+				raw, err := json.Marshal(changes)
+				if err != nil {
+					t.Fatalf("failed to marshal changes: %v", err)
+				}
+				event.Changes = raw
+			}
 
 			fakeClient := fakegithub.NewFakeClient()
 			fakeClient.PullRequests = map[int]*github.PullRequest{
@@ -166,14 +229,12 @@ func TestMilestoneApplier(t *testing.T) {
 			fakeClient.MilestoneMap = milestonesMap
 			fakeClient.Milestone = tc.previousMilestone
 
-			var configuredMilestoneTitle string
-			for title, number := range milestonesMap {
-				if number == tc.configuredMilestone {
-					configuredMilestoneTitle = title
-				}
+			branchToMilestone := plugins.BranchToMilestone{"release-2.0": "v2.0"}
+			if tc.configuredMilestone != 0 {
+				branchToMilestone[tc.baseBranch] = milestoneTitles[tc.configuredMilestone]
 			}
 
-			if err := handle(fakeClient, logrus.WithField("plugin", pluginName), configuredMilestoneTitle, event); err != nil {
+			if err := handle(fakeClient, logrus.WithField("plugin", pluginName), branchToMilestone, event); err != nil {
 				t.Fatalf("(%s): Unexpected error from handle: %v.", tc.name, err)
 			}
 
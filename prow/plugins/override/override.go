@@ -19,13 +19,16 @@ package override
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
@@ -41,9 +44,18 @@ import (
 const pluginName = "override"
 
 var (
-	overrideRe = regexp.MustCompile(`(?mi)^/override( (.+?)\s*)?$`)
+	overrideRe     = regexp.MustCompile(`(?mi)^/override( (.+?)\s*)?$`)
+	overrideFlagRe = regexp.MustCompile(`\s+--(for|reason)(?:[\s=]+|$)`)
+	// expiryMarkerRe matches the hidden markers the audit comment records
+	// expiring overrides with. Quoted lines cannot match, so users cannot
+	// forge markers in the comment the audit comment responds to.
+	expiryMarkerRe = regexp.MustCompile(`(?m)^<!-- override-expiry: (\S+) (\S+) (\S+) (.+) -->$`)
 )
 
+// auditHeader starts the comment listing the overridden contexts. It is
+// used to search for PRs with overrides that may have expired.
+const auditHeader = "Overrode contexts on behalf of"
+
 type githubClient interface {
 	CreateComment(owner, repo string, number int, comment string) error
 	CreateStatus(org, repo, ref string, s github.Status) error
@@ -56,6 +68,16 @@ type githubClient interface {
 	ListTeamMembersBySlug(org, teamSlug, role string) ([]github.TeamMember, error)
 }
 
+// periodicClient is the client used to revert expired overrides.
+type periodicClient interface {
+	BotUserChecker() (func(candidate string) bool, error)
+	CreateComment(owner, repo string, number int, comment string) error
+	CreateStatus(org, repo, ref string, s github.Status) error
+	FindIssues(query, sort string, asc bool) ([]github.Issue, error)
+	ListIssueComments(org, repo string, number int) ([]github.IssueComment, error)
+	ListStatuses(org, repo, ref string) ([]github.Status, error)
+}
+
 type prowJobClient interface {
 	Create(context.Context, *prowapi.ProwJob, metav1.CreateOptions) (*prowapi.ProwJob, error)
 }
@@ -139,6 +161,7 @@ func (c client) LoadRepoOwners(org, repo, base string) (repoowners.RepoOwner, er
 
 func init() {
 	plugins.RegisterGenericCommentHandler(pluginName, handleGenericComment, helpProvider)
+	plugins.RegisterPeriodicHandler(pluginName, handlePeriodic, helpProvider)
 }
 
 func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
@@ -148,13 +171,16 @@ func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhel
 			AllowedGitHubTeams: map[string][]string{
 				"kubernetes/kubernetes": {"team1", "team2"},
 			},
+			RequireReason: map[string][]string{
+				"kubernetes/kubernetes": {"pull-kubernetes-e2e"},
+			},
 		},
 	})
 	if err != nil {
 		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", pluginName)
 	}
 	pluginHelp := &pluginhelp.PluginHelp{
-		Description: "The override plugin allows repo admins to force a github status context to pass",
+		Description: "The override plugin allows repo admins to force a github status context to pass. An override can be limited in time with `--for <duration>`, after which the status is reset to failure unless the context was rerun in the meantime. A reason can be given with `--reason <text>`, which is required for the contexts configured as protected. Every override is recorded in a comment listing who overrode which contexts and why.",
 		Snippet:     yamlSnippet,
 	}
 	overrideConfig := plugins.Override{}
@@ -162,11 +188,11 @@ func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhel
 		overrideConfig = config.Override
 	}
 	pluginHelp.AddCommand(pluginhelp.Command{
-		Usage:       "/override [context]",
-		Description: "Forces a github status context to green (one per line).",
+		Usage:       "/override [context] [--for <duration>] [--reason <text>]",
+		Description: "Forces a github status context to green (one per line), optionally until the duration expires.",
 		Featured:    false,
 		WhoCanUse:   whoCanUse(overrideConfig, "", ""),
-		Examples:    []string{"/override pull-repo-whatever", "/override ci/circleci", "/override deleted-job"},
+		Examples:    []string{"/override pull-repo-whatever", "/override ci/circleci", "/override deleted-job", "/override pull-repo-e2e --for 24h --reason flaky until #123 merges"},
 	})
 	return pluginHelp, nil
 }
@@ -203,7 +229,7 @@ func handleGenericComment(pc plugins.Agent, e github.GenericCommentEvent) error
 		prowJobClient: pc.ProwJobClient,
 		ownersClient:  pc.OwnersClient,
 	}
-	return handle(c, pc.Logger, &e, pc.PluginConfig.Override)
+	return handle(c, pc.Logger, &e, pc.PluginConfig.Override, time.Now())
 }
 
 func authorizedUser(gc githubClient, log *logrus.Entry, org, repo, user string) bool {
@@ -267,10 +293,76 @@ func authorizedGitHubTeamMember(gc githubClient, log *logrus.Entry, teamSlugs ma
 	return false
 }
 
+// requiresReason returns true if overriding the context requires a reason.
+// The context may also be given as the name of the job reporting to it.
+func requiresReason(options plugins.Override, org, repo, context string, presubmits []config.Presubmit) bool {
+	protected := sets.NewString(options.RequireReason[fmt.Sprintf("%s/%s", org, repo)]...)
+	protected.Insert(options.RequireReason[org]...)
+	if protected.Has(context) {
+		return true
+	}
+	for _, p := range presubmits {
+		if p.Name == context && protected.Has(p.Context) {
+			return true
+		}
+	}
+	return false
+}
+
 func description(user string) string {
 	return fmt.Sprintf("Overridden by %s", user)
 }
 
+func expiringDescription(user string, expiry time.Time) string {
+	return fmt.Sprintf("Overridden by %s until %s", user, expiry.UTC().Format(time.RFC3339))
+}
+
+// overrideRequest is a single /override command.
+type overrideRequest struct {
+	context string
+	// duration is how long the override lasts, or zero if it does not expire.
+	duration time.Duration
+	reason   string
+}
+
+// parseOverride parses the arguments of an /override command, i.e. the
+// context optionally followed by the --for and --reason flags.
+func parseOverride(arg string) (overrideRequest, error) {
+	arg = " " + arg
+	flags := overrideFlagRe.FindAllStringSubmatchIndex(arg, -1)
+	if len(flags) == 0 {
+		return overrideRequest{context: strings.TrimSpace(arg)}, nil
+	}
+	req := overrideRequest{context: strings.TrimSpace(arg[:flags[0][0]])}
+	seen := sets.NewString()
+	for i, flag := range flags {
+		name := arg[flag[2]:flag[3]]
+		end := len(arg)
+		if i+1 < len(flags) {
+			end = flags[i+1][0]
+		}
+		value := strings.Trim(strings.TrimSpace(arg[flag[1]:end]), `"'`)
+		if seen.Has(name) {
+			return req, fmt.Errorf("--%s is given more than once", name)
+		}
+		seen.Insert(name)
+		switch name {
+		case "for":
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration <= 0 {
+				return req, fmt.Errorf("--for requires a positive duration such as 24h, got %q", value)
+			}
+			req.duration = duration
+		case "reason":
+			if value == "" {
+				return req, errors.New("--reason requires a reason")
+			}
+			req.reason = value
+		}
+	}
+	return req, nil
+}
+
 func formatList(list []string) string {
 	var lines []string
 	for _, item := range list {
@@ -279,7 +371,7 @@ func formatList(list []string) string {
 	return strings.Join(lines, "\n")
 }
 
-func handle(oc overrideClient, log *logrus.Entry, e *github.GenericCommentEvent, options plugins.Override) error {
+func handle(oc overrideClient, log *logrus.Entry, e *github.GenericCommentEvent, options plugins.Override, now time.Time) error {
 
 	if !e.IsPR || e.IssueState != "open" || e.Action != github.GenericCommentActionCreated {
 		return nil
@@ -296,13 +388,24 @@ func handle(oc overrideClient, log *logrus.Entry, e *github.GenericCommentEvent,
 	user := e.User.Login
 
 	overrides := sets.NewString()
+	requests := map[string]overrideRequest{}
 	for _, m := range mat {
-		if m[1] == "" {
+		var req overrideRequest
+		if m[1] != "" {
+			var err error
+			if req, err = parseOverride(m[2]); err != nil {
+				resp := fmt.Sprintf("Cannot parse `/override %s`: %v", strings.TrimSpace(m[2]), err)
+				log.Debug(resp)
+				return oc.CreateComment(org, repo, number, plugins.FormatResponseRaw(e.Body, e.HTMLURL, user, resp))
+			}
+		}
+		if req.context == "" {
 			resp := "/override requires a failed status context to operate on, but none was given"
 			log.Debug(resp)
 			return oc.CreateComment(org, repo, number, plugins.FormatResponseRaw(e.Body, e.HTMLURL, user, resp))
 		}
-		overrides.Insert(strings.TrimSpace(m[2]))
+		overrides.Insert(req.context)
+		requests[req.context] = req
 	}
 
 	authorized := authorizedUser(oc, log, org, repo, user)
@@ -388,15 +491,38 @@ Only the following contexts were expected:
 		return oc.CreateComment(org, repo, number, plugins.FormatResponseRaw(e.Body, e.HTMLURL, user, resp))
 	}
 
+	var missingReason []string
+	for context, req := range requests {
+		if req.reason == "" && requiresReason(options, org, repo, context, presubmits) {
+			missingReason = append(missingReason, context)
+		}
+	}
+	if len(missingReason) > 0 {
+		sort.Strings(missingReason)
+		resp := fmt.Sprintf("/override requires a reason to override the following protected contexts:\n%s\n\nGive one with `--reason`, e.g. `/override %s --reason <text>`.", formatList(missingReason), missingReason[0])
+		log.Debug(resp)
+		return oc.CreateComment(org, repo, number, plugins.FormatResponseRaw(e.Body, e.HTMLURL, user, resp))
+	}
+
 	done := sets.String{}
 	contextsWithCreatedJobs := sets.String{}
+	// audit holds the details of the overrides with a reason or an expiry and
+	// markers holds the hidden markers recording the expiring overrides.
+	var audit, markers []string
 
 	defer func() {
 		if len(done) == 0 {
 			return
 		}
-		msg := fmt.Sprintf("Overrode contexts on behalf of %s: %s", user, strings.Join(done.List(), ", "))
+		msg := fmt.Sprintf("%s %s: %s", auditHeader, user, strings.Join(done.List(), ", "))
 		log.Info(msg)
+		if len(audit) > 0 {
+			sort.Strings(audit)
+			msg += "\n\n" + strings.Join(audit, "\n")
+		}
+		if len(markers) > 0 {
+			msg += "\n\n" + strings.Join(markers, "\n")
+		}
 		oc.CreateComment(org, repo, number, plugins.FormatResponseRaw(e.Body, e.HTMLURL, user, msg))
 	}()
 
@@ -406,6 +532,17 @@ Only the following contexts were expected:
 			continue
 		}
 
+		req, ok := requests[status.Context]
+		if !ok && pre != nil {
+			req = requests[pre.Name]
+		}
+		desc := description(user)
+		var expiry time.Time
+		if req.duration > 0 {
+			expiry = now.Add(req.duration).UTC()
+			desc = expiringDescription(user, expiry)
+		}
+
 		// Create the overridden prow result if necessary
 		if pre != nil {
 			baseSHA, err := baseSHAGetter()
@@ -421,7 +558,7 @@ Only the following contexts were expected:
 				StartTime:      now,
 				CompletionTime: &now,
 				State:          prowapi.SuccessState,
-				Description:    description(user),
+				Description:    desc,
 				URL:            e.HTMLURL,
 			}
 
@@ -434,13 +571,111 @@ Only the following contexts were expected:
 			contextsWithCreatedJobs.Insert(status.Context)
 		}
 		status.State = github.StatusSuccess
-		status.Description = description(user)
+		status.Description = desc
 		if err := oc.CreateStatus(org, repo, sha, status); err != nil {
 			resp := fmt.Sprintf("Cannot update PR status for context %s", status.Context)
 			log.WithError(err).Warn(resp)
 			return oc.CreateComment(org, repo, number, plugins.FormatResponseRaw(e.Body, e.HTMLURL, user, resp))
 		}
 		done.Insert(status.Context)
+
+		var details []string
+		if req.reason != "" {
+			details = append(details, "reason: "+req.reason)
+		}
+		if !expiry.IsZero() {
+			details = append(details, "expires at "+expiry.Format(time.RFC3339))
+			markers = append(markers, fmt.Sprintf("<!-- override-expiry: %s %s %s %s -->", sha, expiry.Format(time.RFC3339), user, status.Context))
+		}
+		if len(details) > 0 {
+			audit = append(audit, fmt.Sprintf(" - `%s`: %s", status.Context, strings.Join(details, ", ")))
+		}
+	}
+	return nil
+}
+
+func handlePeriodic(pc plugins.Agent) error {
+	return expireOverrides(pc.GitHubClient, pc.Logger, pc.PluginConfig, time.Now())
+}
+
+// expireOverrides resets the statuses of the expired overrides to failure,
+// unless the context was reported again since it was overridden.
+func expireOverrides(gc periodicClient, log *logrus.Entry, config *plugins.Configuration, now time.Time) error {
+	enabledRepos := config.EnabledReposQuery(pluginName)
+	if enabledRepos == "" {
+		return nil
+	}
+	issues, err := gc.FindIssues(fmt.Sprintf("is:pr is:open %s in:comments %q", enabledRepos, auditHeader), "", false)
+	if err != nil {
+		return fmt.Errorf("failed to search for overridden PRs: %w", err)
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+
+	botUserChecker, err := gc.BotUserChecker()
+	if err != nil {
+		return fmt.Errorf("failed to get the bot's name: %w", err)
+	}
+	var errs []error
+	for _, issue := range issues {
+		org, repo, err := github.OrgRepoFromHTMLURL(issue.HTMLURL)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !config.PluginEnabledFor(pluginName, org, repo) {
+			continue
+		}
+		l := log.WithFields(logrus.Fields{github.OrgLogField: org, github.RepoLogField: repo, github.PrLogField: issue.Number})
+		if err := expirePROverrides(gc, l, botUserChecker, org, repo, issue.Number, now); err != nil {
+			errs = append(errs, fmt.Errorf("failed to expire the overrides of %s/%s#%d: %w", org, repo, issue.Number, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func expirePROverrides(gc periodicClient, log *logrus.Entry, botUserChecker func(string) bool, org, repo string, number int, now time.Time) error {
+	comments, err := gc.ListIssueComments(org, repo, number)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		if !botUserChecker(comment.User.Login) {
+			continue
+		}
+		for _, m := range expiryMarkerRe.FindAllStringSubmatch(comment.Body, -1) {
+			sha, user, context := m[1], m[3], m[4]
+			expiry, err := time.Parse(time.RFC3339, m[2])
+			if err != nil || now.Before(expiry) {
+				continue
+			}
+			statuses, err := gc.ListStatuses(org, repo, sha)
+			if err != nil {
+				return err
+			}
+			// Statuses are listed from the most recent one, which is the
+			// override unless the context was reported again since.
+			for _, status := range statuses {
+				if status.Context != context {
+					continue
+				}
+				if status.State != github.StatusSuccess || status.Description != expiringDescription(user, expiry) {
+					break
+				}
+				log.WithField("context", context).Info("Reverting expired override.")
+				status.State = github.StatusFailure
+				status.Description = fmt.Sprintf("Override by %s expired", user)
+				if err := gc.CreateStatus(org, repo, sha, status); err != nil {
+					return err
+				}
+				msg := fmt.Sprintf("The override of `%s` expired, so its status was reset to failure. Rerun the job or override it again.", context)
+				if err := gc.CreateComment(org, repo, number, plugins.FormatSimpleResponse(user, msg)); err != nil {
+					return err
+				}
+				break
+			}
+		}
 	}
 	return nil
 }
@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -31,6 +32,7 @@ import (
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
 	"k8s.io/test-infra/prow/pkg/layeredsets"
 	"k8s.io/test-infra/prow/plugins"
 	"k8s.io/test-infra/prow/plugins/ownersconfig"
@@ -47,6 +49,8 @@ const (
 	adminUser   = "admin-user"
 )
 
+var fakeNow = time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+
 type fakeRepoownersClient struct {
 	foc *fakeOwnersClient
 }
@@ -758,6 +762,103 @@ func TestHandle(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "override with expiry and reason",
+			comment: "/override job --for 24h --reason \"flaky until #123 merges\"",
+			contexts: []github.Status{
+				{
+					Context:     "job",
+					Description: "failed",
+					State:       github.StatusFailure,
+				},
+			},
+			expected: []github.Status{
+				{
+					Context:     "job",
+					Description: expiringDescription(adminUser, fakeNow.Add(24*time.Hour)),
+					State:       github.StatusSuccess,
+				},
+			},
+			checkComments: []string{
+				"on behalf of " + adminUser + ": job",
+				" - `job`: reason: flaky until #123 merges, expires at 2021-03-02T00:00:00Z",
+				"\n<!-- override-expiry: deadbeef 2021-03-02T00:00:00Z admin-user job -->",
+			},
+		},
+		{
+			name:    "override of protected context without reason is rejected",
+			comment: "/override job",
+			options: plugins.Override{
+				RequireReason: map[string][]string{fakeOrg: {"job"}},
+			},
+			contexts: []github.Status{
+				{
+					Context:     "job",
+					Description: "failed",
+					State:       github.StatusFailure,
+				},
+			},
+			expected: []github.Status{
+				{
+					Context:     "job",
+					Description: "failed",
+					State:       github.StatusFailure,
+				},
+			},
+			checkComments: []string{"requires a reason to override the following protected contexts:\n - `job`"},
+		},
+		{
+			name:    "override of protected context by job name with reason works",
+			comment: "/override prow-job --reason infra outage",
+			options: plugins.Override{
+				RequireReason: map[string][]string{fmt.Sprintf("%s/%s", fakeOrg, fakeRepo): {"ci/prow/prow-job"}},
+			},
+			contexts: []github.Status{
+				{
+					Context:     "ci/prow/prow-job",
+					Description: "failed",
+					State:       github.StatusFailure,
+				},
+			},
+			presubmits: []config.Presubmit{
+				{
+					JobBase: config.JobBase{
+						Name: "prow-job",
+					},
+					Reporter: config.Reporter{
+						Context: "ci/prow/prow-job",
+					},
+				},
+			},
+			jobs: sets.NewString("ci/prow/prow-job"),
+			expected: []github.Status{
+				{
+					Context:     "ci/prow/prow-job",
+					Description: description(adminUser),
+					State:       github.StatusSuccess,
+				},
+			},
+			checkComments: []string{" - `ci/prow/prow-job`: reason: infra outage"},
+		},
+		{
+			name:    "override with invalid duration is rejected",
+			comment: "/override job --for tomorrow",
+			contexts: []github.Status{
+				{
+					Context:     "job",
+					Description: "failed",
+					State:       github.StatusFailure,
+				},
+			},
+			expected: []github.Status{
+				{
+					Context:     "job",
+					Description: "failed",
+					State:       github.StatusFailure,
+				},
+			},
+			checkComments: []string{"--for requires a positive duration"},
+		},
 		{
 			name:      "override with allow_top_level_owners works",
 			comment:   "/override job",
@@ -1017,7 +1118,7 @@ func TestHandle(t *testing.T) {
 				tc.jobs = sets.String{}
 			}
 
-			err := handle(&fc, log, &event, tc.options)
+			err := handle(&fc, log, &event, tc.options, fakeNow)
 			switch {
 			case err != nil:
 				if !tc.err {
@@ -1199,3 +1300,159 @@ func TestValidateGitHubTeamSlugs(t *testing.T) {
 		}
 	}
 }
+
+func TestParseOverride(t *testing.T) {
+	cases := []struct {
+		name     string
+		arg      string
+		expected overrideRequest
+		err      bool
+	}{
+		{
+			name:     "context only",
+			arg:      "ci/prow/job",
+			expected: overrideRequest{context: "ci/prow/job"},
+		},
+		{
+			name:     "context with spaces",
+			arg:      "some context",
+			expected: overrideRequest{context: "some context"},
+		},
+		{
+			name:     "duration and reason",
+			arg:      "job --for 24h --reason known flake",
+			expected: overrideRequest{context: "job", duration: 24 * time.Hour, reason: "known flake"},
+		},
+		{
+			name:     "quoted reason before duration",
+			arg:      `job --reason "known flake" --for=1h`,
+			expected: overrideRequest{context: "job", duration: time.Hour, reason: "known flake"},
+		},
+		{
+			name:     "no context",
+			arg:      "--for 1h",
+			expected: overrideRequest{duration: time.Hour},
+		},
+		{
+			name: "missing duration",
+			arg:  "job --for",
+			err:  true,
+		},
+		{
+			name: "negative duration",
+			arg:  "job --for -1h",
+			err:  true,
+		},
+		{
+			name: "empty reason",
+			arg:  `job --reason ""`,
+			err:  true,
+		},
+		{
+			name: "repeated flag",
+			arg:  "job --for 1h --for 2h",
+			err:  true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parseOverride(tc.arg)
+			switch {
+			case err != nil:
+				if !tc.err {
+					t.Errorf("unexpected error: %v", err)
+				}
+			case tc.err:
+				t.Error("failed to receive an error")
+			case actual != tc.expected:
+				t.Errorf("expected %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestExpireOverrides(t *testing.T) {
+	expiry := fakeNow.Add(-time.Hour)
+	marker := fmt.Sprintf("<!-- override-expiry: %s %s %s job -->", fakeSHA, expiry.Format(time.RFC3339), adminUser)
+
+	cases := []struct {
+		name           string
+		enabledFor     string
+		commenter      string
+		body           string
+		status         github.Status
+		expectedStatus github.Status
+		expectComment  bool
+	}{
+		{
+			name:           "expired override is reverted",
+			body:           "Overrode contexts on behalf of admin-user: job\n\n" + marker,
+			status:         github.Status{Context: "job", State: github.StatusSuccess, Description: expiringDescription(adminUser, expiry)},
+			expectedStatus: github.Status{Context: "job", State: github.StatusFailure, Description: "Override by admin-user expired"},
+			expectComment:  true,
+		},
+		{
+			name:           "pending override is left alone",
+			body:           strings.Replace(marker, expiry.Format(time.RFC3339), fakeNow.Add(time.Hour).Format(time.RFC3339), 1),
+			status:         github.Status{Context: "job", State: github.StatusSuccess, Description: expiringDescription(adminUser, fakeNow.Add(time.Hour))},
+			expectedStatus: github.Status{Context: "job", State: github.StatusSuccess, Description: expiringDescription(adminUser, fakeNow.Add(time.Hour))},
+		},
+		{
+			name:           "context reported again since the override is left alone",
+			body:           marker,
+			status:         github.Status{Context: "job", State: github.StatusSuccess, Description: "Job succeeded."},
+			expectedStatus: github.Status{Context: "job", State: github.StatusSuccess, Description: "Job succeeded."},
+		},
+		{
+			name:           "markers from other users are ignored",
+			commenter:      "someone",
+			body:           marker,
+			status:         github.Status{Context: "job", State: github.StatusSuccess, Description: expiringDescription(adminUser, expiry)},
+			expectedStatus: github.Status{Context: "job", State: github.StatusSuccess, Description: expiringDescription(adminUser, expiry)},
+		},
+		{
+			name:           "quoted markers are ignored",
+			body:           ">" + marker,
+			status:         github.Status{Context: "job", State: github.StatusSuccess, Description: expiringDescription(adminUser, expiry)},
+			expectedStatus: github.Status{Context: "job", State: github.StatusSuccess, Description: expiringDescription(adminUser, expiry)},
+		},
+		{
+			name:           "PRs are left alone if the plugin is not enabled",
+			enabledFor:     "other-org",
+			body:           marker,
+			status:         github.Status{Context: "job", State: github.StatusSuccess, Description: expiringDescription(adminUser, expiry)},
+			expectedStatus: github.Status{Context: "job", State: github.StatusSuccess, Description: expiringDescription(adminUser, expiry)},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			commenter := tc.commenter
+			if commenter == "" {
+				commenter = "k8s-ci-robot"
+			}
+			enabledFor := tc.enabledFor
+			if enabledFor == "" {
+				enabledFor = fakeOrg
+			}
+			fc := fakegithub.NewFakeClient()
+			fc.PullRequests = map[int]*github.PullRequest{fakePR: {
+				Number:  fakePR,
+				HTMLURL: fmt.Sprintf("https://github.com/%s/%s/pull/%d", fakeOrg, fakeRepo, fakePR),
+			}}
+			fc.IssueComments[fakePR] = []github.IssueComment{{Body: tc.body, User: github.User{Login: commenter}}}
+			fc.CreatedStatuses = map[string][]github.Status{fakeSHA: {tc.status}}
+			pc := &plugins.Configuration{
+				Plugins: plugins.Plugins{enabledFor: {Plugins: []string{pluginName}}},
+			}
+			if err := expireOverrides(fc, logrus.WithField("plugin", pluginName), pc, fakeNow); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := fc.CreatedStatuses[fakeSHA][0]; actual != tc.expectedStatus {
+				t.Errorf("expected status %+v, got %+v", tc.expectedStatus, actual)
+			}
+			if commented := len(fc.IssueCommentsAdded) > 0; commented != tc.expectComment {
+				t.Errorf("expected comment %t, got %t", tc.expectComment, commented)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ownerscache loads the OWNERS of branches as soon as they are
+// pushed, so that handlers of comments and PRs find them in the cache.
+package ownerscache
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/pluginhelp"
+	"k8s.io/test-infra/prow/plugins"
+	"k8s.io/test-infra/prow/repoowners"
+)
+
+const (
+	// PluginName defines this plugin's registered name.
+	PluginName = "owners-cache"
+)
+
+func init() {
+	plugins.RegisterPushEventHandler(PluginName, handlePush, helpProvider)
+}
+
+func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	return &pluginhelp.PluginHelp{
+			Description: "The owners-cache plugin loads the OWNERS files of a branch when it is pushed to, so that plugins handling PRs and comments don't wait for them to be loaded. If hook is started with --owners-cache-redis-address, the OWNERS are shared with all replicas of hook.",
+		},
+		nil
+}
+
+type ownersClient interface {
+	LoadRepoOwnersSha(org, repo, base, sha string, updateCache bool) (repoowners.RepoOwner, error)
+}
+
+func handlePush(pc plugins.Agent, pe github.PushEvent) error {
+	return handle(pc.OwnersClient, pc.Logger, pe)
+}
+
+func handle(oc ownersClient, log *logrus.Entry, pe github.PushEvent) error {
+	// Tags have no OWNERS that anyone asks for, and deleted branches are gone.
+	if pe.Deleted || !strings.HasPrefix(pe.Ref, "refs/heads/") {
+		return nil
+	}
+	org, repo, branch := pe.Repo.Owner.Login, pe.Repo.Name, pe.Branch()
+	if _, err := oc.LoadRepoOwnersSha(org, repo, branch, pe.After, true); err != nil {
+		return fmt.Errorf("error loading RepoOwners of %s/%s@%s: %w", org, repo, pe.After, err)
+	}
+	log.WithFields(logrus.Fields{"branch": branch, "sha": pe.After}).Debug("Loaded OWNERS of the pushed branch.")
+	return nil
+}
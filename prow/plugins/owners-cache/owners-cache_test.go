@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ownerscache
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/repoowners"
+)
+
+type fakeOwnersClient struct {
+	loaded []string
+}
+
+func (f *fakeOwnersClient) LoadRepoOwnersSha(org, repo, base, sha string, updateCache bool) (repoowners.RepoOwner, error) {
+	if updateCache {
+		f.loaded = append(f.loaded, org+"/"+repo+"@"+base+":"+sha)
+	}
+	return nil, nil
+}
+
+func TestHandle(t *testing.T) {
+	testCases := []struct {
+		name     string
+		event    github.PushEvent
+		expected []string
+	}{
+		{
+			name:     "OWNERS of a pushed branch are loaded",
+			event:    github.PushEvent{Ref: "refs/heads/main", After: "abc"},
+			expected: []string{"org/repo@main:abc"},
+		},
+		{
+			name:  "deleted branch is ignored",
+			event: github.PushEvent{Ref: "refs/heads/main", After: "0000000000000000000000000000000000000000", Deleted: true},
+		},
+		{
+			name:  "tag is ignored",
+			event: github.PushEvent{Ref: "refs/tags/v1.0.0", After: "abc"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.event.Repo = github.Repo{Owner: github.User{Login: "org"}, Name: "repo"}
+			oc := &fakeOwnersClient{}
+			if err := handle(oc, logrus.WithField("plugin", PluginName), tc.event); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(oc.loaded) != len(tc.expected) || (len(tc.expected) > 0 && oc.loaded[0] != tc.expected[0]) {
+				t.Errorf("Expected OWNERS of %v to be loaded, got %v", tc.expected, oc.loaded)
+			}
+		})
+	}
+}
@@ -30,6 +30,21 @@ const (
 
 type Resolver func(org, repo string) Filenames
 
+// DefaultOwners are the owners of a repo that has no OWNERS files at all.
+type DefaultOwners struct {
+	// Approvers are the logins of the approvers.
+	Approvers []string `json:"approvers,omitempty"`
+	// Reviewers are the logins of the reviewers.
+	Reviewers []string `json:"reviewers,omitempty"`
+	// Teams are slugs of teams of the org whose members are both approvers
+	// and reviewers.
+	Teams []string `json:"teams,omitempty"`
+}
+
+// DefaultOwnersResolver returns the DefaultOwners of a repo, or nil if it
+// has none.
+type DefaultOwnersResolver func(org, repo string) *DefaultOwners
+
 // FakeResolver fills in for tests that use a resolver but aren't testing it.
 // This should not be used in production code.
 func FakeResolver(_, _ string) Filenames {
@@ -37,7 +37,6 @@ import (
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/kubernetes"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
-	"sigs.k8s.io/yaml"
 
 	"k8s.io/test-infra/prow/bugzilla"
 	prowv1 "k8s.io/test-infra/prow/client/clientset/versioned/typed/prowjobs/v1"
@@ -52,6 +51,11 @@ import (
 	"k8s.io/test-infra/prow/version"
 )
 
+// TemplatesKey declares named partial plugin settings in plugin config files.
+// Any map of settings may list the templates it inherits under inherits and
+// override them, the way jobs inherit job templates.
+const TemplatesKey = "plugin_templates"
+
 var (
 	pluginHelp                 = map[string]HelpProvider{}
 	genericCommentHandlers     = map[string]GenericCommentHandler{}
@@ -62,6 +66,8 @@ var (
 	reviewEventHandlers        = map[string]ReviewEventHandler{}
 	reviewCommentEventHandlers = map[string]ReviewCommentEventHandler{}
 	statusEventHandlers        = map[string]StatusEventHandler{}
+	periodicHandlers           = map[string]PeriodicHandler{}
+	shutdownHandlers           = map[string]ShutdownHandler{}
 	// CommentMap is used by many plugins for printing help messages defined in
 	// config.go.
 	CommentMap, _ = genyaml.NewCommentMap(nil)
@@ -95,6 +101,12 @@ func HelpProviders() map[string]HelpProvider {
 	return pluginHelp
 }
 
+// RegisterHelpProvider registers the help of a plugin that has no event
+// handlers, only commands, see RegisterCommands.
+func RegisterHelpProvider(name string, help HelpProvider) {
+	pluginHelp[name] = help
+}
+
 // IssueHandler defines the function contract for a github.IssueEvent handler.
 type IssueHandler func(Agent, github.IssueEvent) error
 
@@ -167,6 +179,28 @@ func RegisterGenericCommentHandler(name string, fn GenericCommentHandler, help H
 	genericCommentHandlers[name] = fn
 }
 
+// PeriodicHandler defines the function contract for a handler that hook runs
+// periodically instead of in response to an event. It is run once per period
+// if the plugin is enabled for at least one org or repo, and is responsible for
+// finding the orgs and repos it applies to in the plugin configuration.
+type PeriodicHandler func(Agent) error
+
+// RegisterPeriodicHandler registers a plugin's periodic handler.
+func RegisterPeriodicHandler(name string, fn PeriodicHandler, help HelpProvider) {
+	pluginHelp[name] = help
+	periodicHandlers[name] = fn
+}
+
+// ShutdownHandler defines the function contract for a handler that hook runs
+// when it shuts down, once all event handlers have returned. Plugins that hold
+// back work, e.g. to batch it, use it to finish that work before hook exits.
+type ShutdownHandler func()
+
+// RegisterShutdownHandler registers a plugin's shutdown handler.
+func RegisterShutdownHandler(name string, fn ShutdownHandler) {
+	shutdownHandlers[name] = fn
+}
+
 type PluginGitHubClient interface {
 	github.Client
 	Query(ctx context.Context, q interface{}, vars map[string]interface{}) error
@@ -194,6 +228,10 @@ type Agent struct {
 	// PluginConfig provides plugin-specific options
 	PluginConfig *Configuration
 
+	// DryRun is set when the plugin is configured to run in dry-run mode.
+	// GitHubClient then only logs the changes it would have made.
+	DryRun bool
+
 	Logger *logrus.Entry
 
 	// may be nil if not initialized
@@ -205,7 +243,12 @@ func NewAgent(configAgent *config.Agent, pluginConfigAgent *ConfigAgent, clientA
 	logger = logger.WithField("plugin", plugin)
 	prowConfig := configAgent.Config()
 	pluginConfig := pluginConfigAgent.Config()
-	gitHubClient := &githubV4OrgAddingWrapper{org: githubOrg, Client: clientAgent.GitHubClient.WithFields(logger.Data).ForPlugin(plugin)}
+	pluginGitHubClient := clientAgent.GitHubClient.WithFields(logger.Data).ForPlugin(plugin)
+	dryRun := pluginConfig != nil && pluginConfig.DryRunFor(plugin)
+	if dryRun {
+		pluginGitHubClient = pluginGitHubClient.ForDryRun()
+	}
+	gitHubClient := &githubV4OrgAddingWrapper{org: githubOrg, Client: pluginGitHubClient}
 	return Agent{
 		GitHubClient:              gitHubClient,
 		KubernetesClient:          clientAgent.KubernetesClient,
@@ -219,6 +262,7 @@ func NewAgent(configAgent *config.Agent, pluginConfigAgent *ConfigAgent, clientA
 		Metrics:                   metrics,
 		Config:                    prowConfig,
 		PluginConfig:              pluginConfig,
+		DryRun:                    dryRun,
 		Logger:                    logger,
 	}
 }
@@ -274,11 +318,20 @@ func (pa *ConfigAgent) Load(path string, supplementalPluginConfigDirs []string,
 	if err != nil {
 		return err
 	}
-	np := &Configuration{}
-	if err := yaml.Unmarshal(b, np); err != nil {
-		return err
+	// The settings of a file may inherit the templates of any file, they are
+	// collected before the files are unmarshaled.
+	templates := config.NewTemplateResolver(TemplatesKey, "plugin template")
+	raw, err := templates.Collect(path, b)
+	if err != nil {
+		return fmt.Errorf("failed to read the plugin templates of %s: %w", path, err)
 	}
 
+	type supplementalConfig struct {
+		path string
+		data []byte
+		raw  map[string]interface{}
+	}
+	var supplementalConfigs []supplementalConfig
 	var errs []error
 	for _, supplementalPluginConfigDir := range supplementalPluginConfigDirs {
 		if supplementalPluginConfigFileSuffix == "" {
@@ -310,15 +363,12 @@ func (pa *ConfigAgent) Load(path string, supplementalPluginConfigDirs []string,
 				return nil
 			}
 
-			cfg := &Configuration{}
-			if err := yaml.Unmarshal(data, cfg); err != nil {
-				errs = append(errs, fmt.Errorf("failed to unmarshal %s: %w", path, err))
+			raw, err := templates.Collect(path, data)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to read the plugin templates of %s: %w", path, err))
 				return nil
 			}
-
-			if err := np.mergeFrom(cfg); err != nil {
-				errs = append(errs, fmt.Errorf("failed to merge config from %s into main config: %w", path, err))
-			}
+			supplementalConfigs = append(supplementalConfigs, supplementalConfig{path: path, data: data, raw: raw})
 
 			return nil
 
@@ -330,6 +380,25 @@ func (pa *ConfigAgent) Load(path string, supplementalPluginConfigDirs []string,
 		return err
 	}
 
+	np := &Configuration{}
+	if err := templates.Unmarshal(b, raw, np); err != nil {
+		return err
+	}
+	for _, supplemental := range supplementalConfigs {
+		cfg := &Configuration{}
+		if err := templates.Unmarshal(supplemental.data, supplemental.raw, cfg); err != nil {
+			errs = append(errs, fmt.Errorf("failed to unmarshal %s: %w", supplemental.path, err))
+			continue
+		}
+
+		if err := np.mergeFrom(cfg); err != nil {
+			errs = append(errs, fmt.Errorf("failed to merge config from %s into main config: %w", supplemental.path, err))
+		}
+	}
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return err
+	}
+
 	if err := np.Validate(); err != nil {
 		return err
 	}
@@ -502,6 +571,35 @@ func (pa *ConfigAgent) PushEventHandlers(owner, repo string) map[string]PushEven
 	return hs
 }
 
+// PeriodicHandlers returns a map of plugin names to periodic handlers for all
+// plugins that are enabled on at least one org or repo.
+func (pa *ConfigAgent) PeriodicHandlers() map[string]PeriodicHandler {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+
+	hs := map[string]PeriodicHandler{}
+	for _, orgPlugins := range pa.configuration.Plugins {
+		for _, p := range orgPlugins.Plugins {
+			if h, ok := periodicHandlers[p]; ok {
+				hs[p] = h
+			}
+		}
+	}
+
+	return hs
+}
+
+// ShutdownHandlers returns a map of plugin names to shutdown handlers for all
+// registered plugins, as plugins may hold back work from before they were
+// disabled.
+func (pa *ConfigAgent) ShutdownHandlers() map[string]ShutdownHandler {
+	hs := map[string]ShutdownHandler{}
+	for p, h := range shutdownHandlers {
+		hs[p] = h
+	}
+	return hs
+}
+
 // getPlugins returns a list of plugins that are enabled on a given (org, repository).
 func (pa *ConfigAgent) getPlugins(owner, repo string) []string {
 	var plugins []string
@@ -512,7 +610,15 @@ func (pa *ConfigAgent) getPlugins(owner, repo string) []string {
 	}
 	plugins = append(plugins, pa.configuration.Plugins[fullName].Plugins...)
 
-	return plugins
+	var enabled []string
+	for _, plugin := range plugins {
+		// Plugins are only gated by the flags named after them.
+		if _, gated := pa.configuration.FeatureFlags[plugin]; gated && !pa.configuration.FeatureEnabled(plugin, owner, repo) {
+			continue
+		}
+		enabled = append(enabled, plugin)
+	}
+	return enabled
 }
 
 // EventsForPlugin returns the registered events for the passed plugin.
@@ -541,6 +647,11 @@ func EventsForPlugin(name string) []string {
 	}
 	if _, ok := genericCommentHandlers[name]; ok {
 		events = append(events, "GenericCommentEvent (any event for user text)")
+	} else if _, ok := pluginCommands[name]; ok {
+		events = append(events, "GenericCommentEvent (any event for user text)")
+	}
+	if _, ok := periodicHandlers[name]; ok {
+		events = append(events, "periodic")
 	}
 	return events
 }
@@ -21,6 +21,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -163,6 +164,7 @@ func TestGetPlugins(t *testing.T) {
 	var testcases = []struct {
 		name            string
 		pluginMap       Plugins // this is read from the plugins.yaml file typically.
+		featureFlags    map[string]FeatureFlag
 		owner           string
 		repo            string
 		expectedPlugins []string
@@ -216,9 +218,22 @@ func TestGetPlugins(t *testing.T) {
 			repo:            "repo",
 			expectedPlugins: []string{"plugin3"},
 		},
+		{
+			name: "Plugins gated by a feature flag should only be returned for the repos it is enabled for",
+			pluginMap: Plugins{
+				"org1": {Plugins: []string{"plugin1", "plugin2", "plugin3"}},
+			},
+			featureFlags: map[string]FeatureFlag{
+				"plugin1": {Canaries: []string{"org1/repo"}},
+				"plugin2": {Percentage: 100, Excluded: []string{"org1/repo"}},
+			},
+			owner:           "org1",
+			repo:            "repo",
+			expectedPlugins: []string{"plugin1", "plugin3"},
+		},
 	}
 	for _, tc := range testcases {
-		pa := ConfigAgent{configuration: &Configuration{Plugins: tc.pluginMap}}
+		pa := ConfigAgent{configuration: &Configuration{Plugins: tc.pluginMap, FeatureFlags: tc.featureFlags}}
 
 		plugins := pa.getPlugins(tc.owner, tc.repo)
 		if diff := cmp.Diff(plugins, tc.expectedPlugins); diff != "" {
@@ -233,6 +248,7 @@ func TestLoad(t *testing.T) {
 	defaultedConfig := func(m ...func(*Configuration)) *Configuration {
 		cfg := &Configuration{
 			Owners:      Owners{LabelsDenyList: []string{"approved", "lgtm"}},
+			Assign:      Assign{MaxTeamReviewers: 5, RoundRobinConfigMap: "assign-round-robin"},
 			Blunderbuss: Blunderbuss{ReviewerCount: func() *int { i := 2; return &i }()},
 			CherryPickUnapproved: CherryPickUnapproved{
 				BranchRegexp: "^release-.*$",
@@ -252,6 +268,13 @@ func TestLoad(t *testing.T) {
 			Help: Help{
 				HelpGuidelinesURL: "https://git.k8s.io/community/contributors/guide/help-wanted.md",
 			},
+			ExternalPluginHealth: ExternalPluginHealth{
+				Interval:           "1m",
+				IntervalDuration:   time.Minute,
+				Timeout:            "5s",
+				TimeoutDuration:    5 * time.Second,
+				UnhealthyThreshold: 3,
+			},
 		}
 		for _, modify := range m {
 			modify(cfg)
@@ -315,6 +338,45 @@ plugins:
 				c.Plugins = Plugins{"org/repo": {Plugins: []string{"wip"}}}
 			}),
 		},
+		{
+			name: "Supplemental configs inherit plugin templates",
+			config: `
+plugin_templates:
+  base:
+    plugins:
+    - wip
+    - hold
+  strict-lgtm:
+    review_acts_as_lgtm: true
+    store_tree_hash: true
+plugins:
+  org:
+    inherits: [base]
+lgtm:
+- repos: [org]
+  inherits: [strict-lgtm]`,
+			supplementalConfigs: map[string]string{
+				"some-path-extra_config.yaml": `
+plugins:
+  other/repo:
+    inherits: [base]
+lgtm:
+- repos: [other/repo]
+  inherits: [strict-lgtm]
+  store_tree_hash: null`,
+			},
+			supplementalPluginConfigFileSuffix: "extra_config.yaml",
+			expected: defaultedConfig(func(c *Configuration) {
+				c.Plugins = Plugins{
+					"org":        {Plugins: []string{"wip", "hold"}},
+					"other/repo": {Plugins: []string{"wip", "hold"}},
+				}
+				c.Lgtm = []Lgtm{
+					{Repos: []string{"org"}, ReviewActsAsLgtm: true, StoreTreeHash: true},
+					{Repos: []string{"other/repo"}, ReviewActsAsLgtm: true},
+				}
+			}),
+		},
 	}
 
 	for _, tc := range testCases {
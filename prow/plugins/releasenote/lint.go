@@ -0,0 +1,304 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package releasenote
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const (
+	lintHeader      = "The release note of this PR does not follow the release note guidelines:"
+	lintFilesHeader = "The release notes added by this PR do not follow the release note guidelines, see the review comments for the problems and their fixes."
+)
+
+var (
+	firstWordRe = regexp.MustCompile(`^(\W*)([A-Za-z]+)`)
+
+	// imperativeForms maps common non-imperative verb forms that release
+	// notes start with to their imperative form.
+	imperativeForms = map[string]string{}
+)
+
+func init() {
+	for _, forms := range [][]string{
+		{"add", "added", "adds", "adding"},
+		{"allow", "allowed", "allows", "allowing"},
+		{"bump", "bumped", "bumps", "bumping"},
+		{"change", "changed", "changes", "changing"},
+		{"deprecate", "deprecated", "deprecates", "deprecating"},
+		{"disable", "disabled", "disables", "disabling"},
+		{"drop", "dropped", "drops", "dropping"},
+		{"enable", "enabled", "enables", "enabling"},
+		{"ensure", "ensured", "ensures", "ensuring"},
+		{"expose", "exposed", "exposes", "exposing"},
+		{"fix", "fixed", "fixes", "fixing"},
+		{"implement", "implemented", "implements", "implementing"},
+		{"improve", "improved", "improves", "improving"},
+		{"increase", "increased", "increases", "increasing"},
+		{"introduce", "introduced", "introduces", "introducing"},
+		{"make", "made", "makes", "making"},
+		{"move", "moved", "moves", "moving"},
+		{"reduce", "reduced", "reduces", "reducing"},
+		{"refactor", "refactored", "refactors", "refactoring"},
+		{"remove", "removed", "removes", "removing"},
+		{"rename", "renamed", "renames", "renaming"},
+		{"replace", "replaced", "replaces", "replacing"},
+		{"revert", "reverted", "reverts", "reverting"},
+		{"support", "supported", "supports", "supporting"},
+		{"update", "updated", "updates", "updating"},
+		{"upgrade", "upgraded", "upgrades", "upgrading"},
+		{"use", "used", "uses", "using"},
+	} {
+		for _, form := range forms[1:] {
+			imperativeForms[form] = forms[0]
+		}
+	}
+}
+
+// hasLintRules returns true if the config enables any release note rule.
+func hasLintRules(cfg *plugins.ReleaseNote) bool {
+	return cfg != nil && (cfg.MaxLength > 0 || cfg.ImperativeMood || len(cfg.ForbiddenPhrases) > 0)
+}
+
+// lintReleaseNote checks the release note against the configured rules. It
+// returns the problems found along with a suggested release note fixing the
+// ones that can be fixed automatically, or an empty string if there is none.
+func lintReleaseNote(note string, cfg *plugins.ReleaseNote) ([]string, string) {
+	var problems []string
+	suggestion := note
+
+	if cfg.MaxLength > 0 {
+		if length := len([]rune(note)); length > cfg.MaxLength {
+			problems = append(problems, fmt.Sprintf("The release note is %d characters long, the maximum is %d", length, cfg.MaxLength))
+		}
+	}
+
+	if cfg.ImperativeMood {
+		if match := firstWordRe.FindStringSubmatch(note); match != nil {
+			if imperative, ok := imperativeForms[strings.ToLower(match[2])]; ok {
+				imperative = matchCase(imperative, match[2])
+				problems = append(problems, fmt.Sprintf("Use the imperative mood: %q rather than %q", imperative, match[2]))
+				suggestion = match[1] + imperative + suggestion[len(match[0]):]
+			}
+		}
+	}
+
+	for _, fp := range cfg.ForbiddenPhrases {
+		if fp.Re == nil || !fp.Re.MatchString(note) {
+			continue
+		}
+		problem := fmt.Sprintf("Avoid %q", fp.Phrase)
+		if fp.Replacement != "" {
+			problem += fmt.Sprintf(", use %q instead", fp.Replacement)
+			suggestion = fp.Re.ReplaceAllLiteralString(suggestion, fp.Replacement)
+		}
+		if fp.Reason != "" {
+			problem += ": " + fp.Reason
+		}
+		problems = append(problems, problem)
+	}
+
+	if suggestion == note {
+		suggestion = ""
+	}
+	return problems, suggestion
+}
+
+// matchCase returns word with the same capitalization as reference.
+func matchCase(word, reference string) string {
+	switch {
+	case reference == strings.ToUpper(reference):
+		return strings.ToUpper(word)
+	case reference[:1] == strings.ToUpper(reference[:1]):
+		return strings.ToUpper(word[:1]) + word[1:]
+	default:
+		return word
+	}
+}
+
+func lintComment(problems []string, suggestion string) string {
+	var b strings.Builder
+	b.WriteString(lintHeader + "\n\n")
+	for _, problem := range problems {
+		fmt.Fprintf(&b, "- %s\n", problem)
+	}
+	if suggestion != "" {
+		b.WriteString("\nSuggested release note:\n\n```release-note\n" + suggestion + "\n```\n\n")
+		b.WriteString("To accept it, replace the `release-note` block in the PR description with the one above, or have an org member comment `/release-note-edit` followed by it.")
+	}
+	return b.String()
+}
+
+// lintPR comments on the PR with the problems found in its release note and
+// deletes the previous comments once they are outdated.
+func lintPR(gc githubClient, log *logrus.Entry, pr *github.PullRequestEvent, cfg *plugins.ReleaseNote) error {
+	if !hasLintRules(cfg) || pr.PullRequest.Merged {
+		return nil
+	}
+	if pr.Action != github.PullRequestActionOpened && pr.Action != github.PullRequestActionEdited {
+		return nil
+	}
+	org := pr.Repo.Owner.Login
+	repo := pr.Repo.Name
+
+	var comment string
+	if note := getReleaseNote(pr.PullRequest.Body); note != "" && !noneRe.MatchString(note) {
+		if problems, suggestion := lintReleaseNote(note, cfg); len(problems) > 0 {
+			comment = plugins.FormatSimpleResponse(pr.PullRequest.User.Login, lintComment(problems, suggestion))
+		}
+	}
+
+	botUserChecker, err := gc.BotUserChecker()
+	if err != nil {
+		return err
+	}
+	comments, err := gc.ListIssueComments(org, repo, pr.Number)
+	if err != nil {
+		return fmt.Errorf("failed to list comments on %s/%s#%d. err: %w", org, repo, pr.Number, err)
+	}
+	isLintComment := func(c github.IssueComment) bool {
+		return botUserChecker(c.User.Login) && strings.Contains(c.Body, lintHeader)
+	}
+	for _, c := range comments {
+		if comment != "" && isLintComment(c) && c.Body == comment {
+			// The problems were already reported.
+			return nil
+		}
+	}
+	if err := gc.DeleteStaleComments(org, repo, pr.Number, comments, isLintComment); err != nil {
+		return err
+	}
+	if comment == "" {
+		return nil
+	}
+	log.Info("Commenting on release note problems.")
+	return gc.CreateComment(org, repo, pr.Number, comment)
+}
+
+// lintFiles reviews the lines the PR adds to the release note files, each
+// line being a release note. Review comments suggest the fixed lines, so that
+// authors can apply the fixes from the review. The problems already reported
+// on a line are not reported again.
+func lintFiles(gc githubClient, log *logrus.Entry, pr *github.PullRequestEvent, cfg *plugins.ReleaseNote) error {
+	if !hasLintRules(cfg) || len(cfg.Files) == 0 || pr.PullRequest.Merged {
+		return nil
+	}
+	if pr.Action != github.PullRequestActionOpened && pr.Action != github.PullRequestActionSynchronize {
+		return nil
+	}
+	org := pr.Repo.Owner.Login
+	repo := pr.Repo.Name
+
+	changes, err := gc.GetPullRequestChanges(org, repo, pr.Number)
+	if err != nil {
+		return fmt.Errorf("failed to get the changes of %s/%s#%d: %w", org, repo, pr.Number, err)
+	}
+	var comments []github.DraftReviewComment
+	for _, change := range changes {
+		if change.Status != github.PullRequestFileRemoved && isReleaseNoteFile(change.Filename, cfg.Files) {
+			comments = append(comments, lintPatch(change, cfg)...)
+		}
+	}
+	if len(comments) == 0 {
+		return nil
+	}
+
+	botUserChecker, err := gc.BotUserChecker()
+	if err != nil {
+		return err
+	}
+	reviewComments, err := gc.ListPullRequestComments(org, repo, pr.Number)
+	if err != nil {
+		return fmt.Errorf("failed to list review comments on %s/%s#%d: %w", org, repo, pr.Number, err)
+	}
+	reported := sets.NewString()
+	for _, c := range reviewComments {
+		// Comments on lines that changed since are outdated, their position is nil.
+		if botUserChecker(c.User.Login) && c.Position != nil {
+			reported.Insert(c.Path + "\x00" + c.Body)
+		}
+	}
+	var newComments []github.DraftReviewComment
+	for _, c := range comments {
+		if !reported.Has(c.Path + "\x00" + c.Body) {
+			newComments = append(newComments, c)
+		}
+	}
+	if len(newComments) == 0 {
+		return nil
+	}
+	log.Infof("Reviewing %d release notes.", len(newComments))
+	return gc.CreateReview(org, repo, pr.Number, github.DraftReview{
+		Body:      plugins.FormatSimpleResponse(pr.PullRequest.User.Login, lintFilesHeader),
+		Action:    github.Comment,
+		CommitSHA: pr.PullRequest.Head.SHA,
+		Comments:  newComments,
+	})
+}
+
+func isReleaseNoteFile(filename string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if match, _ := path.Match(pattern, filename); match {
+			return true
+		}
+	}
+	return false
+}
+
+// lintPatch lints the lines added by the patch, skipping blank lines and
+// markdown headings. The review comments are positioned in the patch.
+func lintPatch(change github.PullRequestChange, cfg *plugins.ReleaseNote) []github.DraftReviewComment {
+	var comments []github.DraftReviewComment
+	// The position of a line is its number of lines below the first hunk header.
+	for position, line := range strings.Split(change.Patch, "\n") {
+		if !strings.HasPrefix(line, "+") {
+			continue
+		}
+		note := strings.TrimSpace(line[1:])
+		if note == "" || strings.HasPrefix(note, "#") {
+			continue
+		}
+		problems, suggestion := lintReleaseNote(note, cfg)
+		if len(problems) == 0 {
+			continue
+		}
+		var b strings.Builder
+		for _, problem := range problems {
+			fmt.Fprintf(&b, "- %s\n", problem)
+		}
+		if suggestion != "" {
+			// Keep the indentation of the line.
+			fixed := strings.Replace(line[1:], note, suggestion, 1)
+			b.WriteString("\n```suggestion\n" + fixed + "\n```\n")
+		}
+		comments = append(comments, github.DraftReviewComment{
+			Path:     change.Filename,
+			Position: position,
+			Body:     strings.TrimSuffix(b.String(), "\n"),
+		})
+	}
+	return comments
+}
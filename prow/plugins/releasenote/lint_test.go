@@ -0,0 +1,295 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package releasenote
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	utilpointer "k8s.io/utils/pointer"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+var testLintConfig = &plugins.ReleaseNote{
+	MaxLength:      60,
+	ImperativeMood: true,
+	ForbiddenPhrases: []plugins.ForbiddenPhrase{
+		{Phrase: "k8s", Re: regexp.MustCompile(`(?i)\bk8s\b`), Replacement: "Kubernetes"},
+		{Phrase: "simply", Re: regexp.MustCompile(`(?i)\bsimply\b`), Reason: "what is simple for you might not be for the reader"},
+	},
+}
+
+func TestLintReleaseNote(t *testing.T) {
+	testCases := []struct {
+		name               string
+		note               string
+		expectedProblems   []string
+		expectedSuggestion string
+	}{
+		{
+			name: "valid release note",
+			note: "Add the --foo flag to kubectl.",
+		},
+		{
+			name:               "past tense is fixed",
+			note:               "Added the --foo flag to kubectl.",
+			expectedProblems:   []string{`Use the imperative mood: "Add" rather than "Added"`},
+			expectedSuggestion: "Add the --foo flag to kubectl.",
+		},
+		{
+			name:               "third person in upper case is fixed",
+			note:               "* FIXES a panic.",
+			expectedProblems:   []string{`Use the imperative mood: "FIX" rather than "FIXES"`},
+			expectedSuggestion: "* FIX a panic.",
+		},
+		{
+			name:               "forbidden phrase with a replacement is fixed",
+			note:               "Add k8s 1.22 support, K8S rocks.",
+			expectedProblems:   []string{`Avoid "k8s", use "Kubernetes" instead`},
+			expectedSuggestion: "Add Kubernetes 1.22 support, Kubernetes rocks.",
+		},
+		{
+			name:             "forbidden phrase inside a word is ignored, without a replacement it is only reported",
+			note:             "Simply add the k8sfoo binary.",
+			expectedProblems: []string{`Avoid "simply": what is simple for you might not be for the reader`},
+		},
+		{
+			name:             "too long release note is reported",
+			note:             "Add " + strings.Repeat("a", 60),
+			expectedProblems: []string{"The release note is 64 characters long, the maximum is 60"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, suggestion := lintReleaseNote(tc.note, testLintConfig)
+			if diff := cmp.Diff(tc.expectedProblems, problems); diff != "" {
+				t.Errorf("problems differ from expected: %s", diff)
+			}
+			if suggestion != tc.expectedSuggestion {
+				t.Errorf("expected suggestion %q, got %q", tc.expectedSuggestion, suggestion)
+			}
+		})
+	}
+}
+
+func TestLintPR(t *testing.T) {
+	invalidBody := "```release-note\nAdded k8s support.\n```"
+	invalidComment := plugins.FormatSimpleResponse("author", lintComment([]string{
+		`Use the imperative mood: "Add" rather than "Added"`,
+		`Avoid "k8s", use "Kubernetes" instead`,
+	}, "Add Kubernetes support."))
+
+	testCases := []struct {
+		name             string
+		action           github.PullRequestEventAction
+		body             string
+		cfg              *plugins.ReleaseNote
+		existingComments []github.IssueComment
+		expectedComments []string
+		expectedDeleted  []string
+	}{
+		{
+			name:   "no rules configured",
+			action: github.PullRequestActionOpened,
+			body:   invalidBody,
+			cfg:    &plugins.ReleaseNote{},
+		},
+		{
+			name:   "labeled events are ignored",
+			action: github.PullRequestActionLabeled,
+			body:   invalidBody,
+			cfg:    testLintConfig,
+		},
+		{
+			name:   "valid release note",
+			action: github.PullRequestActionOpened,
+			body:   "```release-note\nAdd Kubernetes support.\n```",
+			cfg:    testLintConfig,
+		},
+		{
+			name:   "none release note is not linted",
+			action: github.PullRequestActionOpened,
+			body:   "```release-note\nNONE\n```",
+			cfg:    testLintConfig,
+		},
+		{
+			name:             "invalid release note is reported with a suggestion",
+			action:           github.PullRequestActionOpened,
+			body:             invalidBody,
+			cfg:              testLintConfig,
+			expectedComments: []string{"org/repo#1:" + invalidComment},
+		},
+		{
+			name:             "problems already reported are not reported again",
+			action:           github.PullRequestActionEdited,
+			body:             invalidBody,
+			cfg:              testLintConfig,
+			existingComments: []github.IssueComment{{ID: 1, Body: invalidComment, User: github.User{Login: "k8s-ci-robot"}}},
+		},
+		{
+			name:             "outdated report is replaced",
+			action:           github.PullRequestActionEdited,
+			body:             invalidBody,
+			cfg:              testLintConfig,
+			existingComments: []github.IssueComment{{ID: 1, Body: lintHeader + "\n\n- old problem", User: github.User{Login: "k8s-ci-robot"}}},
+			expectedComments: []string{"org/repo#1:" + invalidComment},
+			expectedDeleted:  []string{"org/repo#1"},
+		},
+		{
+			name:             "report is deleted once the release note is fixed",
+			action:           github.PullRequestActionEdited,
+			body:             "```release-note\nAdd Kubernetes support.\n```",
+			cfg:              testLintConfig,
+			existingComments: []github.IssueComment{{ID: 1, Body: invalidComment, User: github.User{Login: "k8s-ci-robot"}}},
+			expectedDeleted:  []string{"org/repo#1"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.IssueComments = map[int][]github.IssueComment{1: tc.existingComments}
+			fc.IssueCommentID = len(tc.existingComments)
+			pr := &github.PullRequestEvent{
+				Action: tc.action,
+				Number: 1,
+				Repo:   github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+				PullRequest: github.PullRequest{
+					Body: tc.body,
+					User: github.User{Login: "author"},
+				},
+			}
+			if err := lintPR(fc, logrus.WithField("plugin", PluginName), pr, tc.cfg); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expectedComments, fc.IssueCommentsAdded); diff != "" {
+				t.Errorf("comments differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedDeleted, fc.IssueCommentsDeleted); diff != "" {
+				t.Errorf("deleted comments differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+// reviewingClient records the reviews it creates.
+type reviewingClient struct {
+	*fakegithub.FakeClient
+	reviews []github.DraftReview
+}
+
+func (c *reviewingClient) CreateReview(org, repo string, number int, r github.DraftReview) error {
+	c.reviews = append(c.reviews, r)
+	return nil
+}
+
+func TestLintFiles(t *testing.T) {
+	cfg := *testLintConfig
+	cfg.Files = []string{"CHANGELOG/unreleased/*.md"}
+	patch := "@@ -1,2 +1,6 @@\n ## Features\n+## Fixed\n+  - Fixed a panic in k8s.\n+- Add Kubernetes support.\n+\n+- Simply remove the flag."
+	expectedComments := []github.DraftReviewComment{
+		{
+			Path:     "CHANGELOG/unreleased/pr.md",
+			Position: 3,
+			Body:     "- Use the imperative mood: \"Fix\" rather than \"Fixed\"\n- Avoid \"k8s\", use \"Kubernetes\" instead\n\n```suggestion\n  - Fix a panic in Kubernetes.\n```",
+		},
+		{
+			Path:     "CHANGELOG/unreleased/pr.md",
+			Position: 6,
+			Body:     "- Avoid \"simply\": what is simple for you might not be for the reader",
+		},
+	}
+	testCases := []struct {
+		name             string
+		action           github.PullRequestEventAction
+		cfg              *plugins.ReleaseNote
+		changes          []github.PullRequestChange
+		existingComments []github.ReviewComment
+		expectedComments []github.DraftReviewComment
+	}{
+		{
+			name:    "files are not linted without file patterns",
+			action:  github.PullRequestActionOpened,
+			cfg:     testLintConfig,
+			changes: []github.PullRequestChange{{Filename: "CHANGELOG/unreleased/pr.md", Patch: patch}},
+		},
+		{
+			name:    "files are not linted on edits",
+			action:  github.PullRequestActionEdited,
+			cfg:     &cfg,
+			changes: []github.PullRequestChange{{Filename: "CHANGELOG/unreleased/pr.md", Patch: patch}},
+		},
+		{
+			name:    "other files are not linted",
+			action:  github.PullRequestActionOpened,
+			cfg:     &cfg,
+			changes: []github.PullRequestChange{{Filename: "CHANGELOG/v1.0.md", Patch: patch}},
+		},
+		{
+			name:             "added lines are reviewed with suggestions",
+			action:           github.PullRequestActionOpened,
+			cfg:              &cfg,
+			changes:          []github.PullRequestChange{{Filename: "CHANGELOG/unreleased/pr.md", Patch: patch}},
+			expectedComments: expectedComments,
+		},
+		{
+			name:    "problems already reported are not reported again",
+			action:  github.PullRequestActionSynchronize,
+			cfg:     &cfg,
+			changes: []github.PullRequestChange{{Filename: "CHANGELOG/unreleased/pr.md", Patch: patch}},
+			existingComments: []github.ReviewComment{
+				{Path: expectedComments[0].Path, Body: expectedComments[0].Body, Position: utilpointer.IntPtr(3), User: github.User{Login: "k8s-ci-robot"}},
+				{Path: expectedComments[1].Path, Body: expectedComments[1].Body, User: github.User{Login: "k8s-ci-robot"}},
+			},
+			expectedComments: expectedComments[1:],
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := &reviewingClient{FakeClient: fakegithub.NewFakeClient()}
+			fc.PullRequestChanges = map[int][]github.PullRequestChange{1: tc.changes}
+			fc.PullRequestComments = map[int][]github.ReviewComment{1: tc.existingComments}
+			pr := &github.PullRequestEvent{
+				Action: tc.action,
+				Number: 1,
+				Repo:   github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+				PullRequest: github.PullRequest{
+					User: github.User{Login: "author"},
+					Head: github.PullRequestBranch{SHA: "sha"},
+				},
+			}
+			if err := lintFiles(fc, logrus.WithField("plugin", PluginName), pr, tc.cfg); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var comments []github.DraftReviewComment
+			for _, review := range fc.reviews {
+				if review.CommitSHA != "sha" {
+					t.Errorf("expected the review of commit sha, got %q", review.CommitSHA)
+				}
+				comments = append(comments, review.Comments...)
+			}
+			if diff := cmp.Diff(tc.expectedComments, comments); diff != "" {
+				t.Errorf("review comments differ from expected: %s", diff)
+			}
+		})
+	}
+}
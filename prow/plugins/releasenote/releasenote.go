@@ -72,14 +72,58 @@ func init() {
 	plugins.RegisterPullRequestHandler(PluginName, handlePullRequest, helpProvider)
 }
 
-func helpProvider(_ *plugins.Configuration, _ []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	configInfo := map[string]string{}
+	for _, repo := range enabledRepos {
+		cfg := config.ReleaseNoteFor(repo.Org, repo.Repo)
+		if !hasLintRules(cfg) {
+			continue
+		}
+		var rules []string
+		if cfg.MaxLength > 0 {
+			rules = append(rules, fmt.Sprintf("be at most %d characters long", cfg.MaxLength))
+		}
+		if cfg.ImperativeMood {
+			rules = append(rules, "start with a verb in the imperative mood")
+		}
+		for _, fp := range cfg.ForbiddenPhrases {
+			rules = append(rules, fmt.Sprintf("not contain %q", fp.Phrase))
+		}
+		configInfo[repo.String()] = "Release notes must " + strings.Join(rules, ", ") + "."
+		if len(cfg.Files) > 0 {
+			configInfo[repo.String()] += fmt.Sprintf(" Release notes are also read from the lines added to %s.", strings.Join(cfg.Files, ", "))
+		}
+	}
+	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
+		ReleaseNote: []plugins.ReleaseNote{
+			{
+				Repos:          []string{"org/repo"},
+				MaxLength:      200,
+				ImperativeMood: true,
+				Files:          []string{"CHANGELOG/unreleased/*.md"},
+				ForbiddenPhrases: []plugins.ForbiddenPhrase{
+					{
+						Phrase:      "k8s",
+						Replacement: "Kubernetes",
+						Reason:      "release notes spell out project names",
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", PluginName)
+	}
 	pluginHelp := &pluginhelp.PluginHelp{
+		Config:  configInfo,
+		Snippet: yamlSnippet,
 		Description: `The releasenote plugin implements a release note process that uses a markdown 'release-note' code block to associate a release note with a pull request. Until the 'release-note' block in the pull request body is populated the PR will be assigned the '` + labels.ReleaseNoteLabelNeeded + `' label.
 <br>There are three valid types of release notes that can replace this label:
 <ol><li>PRs with a normal release note in the 'release-note' block are given the label '` + labels.ReleaseNote + `'.</li>
 <li>PRs that have a release note of 'none' in the block are given the label '` + labels.ReleaseNoteNone + `' to indicate that the PR does not warrant a release note.</li>
 <li>PRs that contain 'action required' in their 'release-note' block are given the label '` + labels.ReleaseNoteActionRequired + `' to indicate that the PR introduces potentially breaking changes that necessitate user action before upgrading to the release.</li></ol>
-` + "To use the plugin, in the pull request body text:\n\n```release-note\n<release note content>\n```",
+` + "To use the plugin, in the pull request body text:\n\n```release-note\n<release note content>\n```" + `
+<br>Release notes can also be linted against per-repo rules on their length, mood and wording. The problems found are reported in a comment along with a suggested release note when they can be fixed automatically. The lines PRs add to the configured release note files are reviewed, with suggested changes fixing them.`,
 	}
 	// NOTE: the other two commands re deprecated, so we're not documenting them
 	pluginHelp.AddCommand(pluginhelp.Command{
@@ -107,6 +151,9 @@ type githubClient interface {
 	DeleteStaleComments(org, repo string, number int, comments []github.IssueComment, isStale func(github.IssueComment) bool) error
 	BotUserChecker() (func(candidate string) bool, error)
 	EditIssue(org, repo string, number int, issue *github.Issue) (*github.Issue, error)
+	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
+	ListPullRequestComments(org, repo string, number int) ([]github.ReviewComment, error)
+	CreateReview(org, repo string, number int, r github.DraftReview) error
 }
 
 func handleIssueComment(pc plugins.Agent, ic github.IssueCommentEvent) error {
@@ -215,6 +262,13 @@ func removeOtherLabels(remover func(string) error, label string, labelSet []stri
 }
 
 func handlePullRequest(pc plugins.Agent, pr github.PullRequestEvent) error {
+	cfg := pc.PluginConfig.ReleaseNoteFor(pr.Repo.Owner.Login, pr.Repo.Name)
+	if err := lintPR(pc.GitHubClient, pc.Logger, &pr, cfg); err != nil {
+		pc.Logger.WithError(err).Warn("Failed to lint the release note.")
+	}
+	if err := lintFiles(pc.GitHubClient, pc.Logger, &pr, cfg); err != nil {
+		pc.Logger.WithError(err).Warn("Failed to lint the release note files.")
+	}
 	return handlePR(pc.GitHubClient, pc.Logger, &pr)
 }
 
@@ -0,0 +1,242 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package requiredreviewers implements the `required-reviewers` plugin.
+// It labels PRs with `do-not-merge/needs-required-reviewer` until a member
+// of each group of reviewers configured for the changed paths has approved.
+package requiredreviewers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/gitattributes"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/pluginhelp"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// PluginName defines this plugin's registered name.
+const PluginName = "required-reviewers"
+
+var handlePRActions = map[github.PullRequestEventAction]bool{
+	github.PullRequestActionOpened:         true,
+	github.PullRequestActionReopened:       true,
+	github.PullRequestActionSynchronize:    true,
+	github.PullRequestActionReadyForReview: true,
+}
+
+type githubClient interface {
+	AddLabel(org, repo string, number int, label string) error
+	RemoveLabel(org, repo string, number int, label string) error
+	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
+	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
+	ListReviews(org, repo string, number int) ([]github.Review, error)
+	TeamBySlugHasMember(org string, teamSlug string, memberLogin string) (bool, error)
+}
+
+func init() {
+	plugins.RegisterPullRequestHandler(PluginName, handlePullRequestEvent, helpProvider)
+	plugins.RegisterReviewEventHandler(PluginName, handleReviewEvent, helpProvider)
+}
+
+func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	configInfo := map[string]string{}
+	for _, repo := range enabledRepos {
+		var rules []string
+		for _, rule := range config.RequiredReviewersFor(repo.Org, repo.Repo).Rules {
+			rules = append(rules, fmt.Sprintf("Changes to %s need an approval from one of %s.", strings.Join(rule.Paths, ", "), strings.Join(groupMembers(rule), ", ")))
+		}
+		if len(rules) > 0 {
+			configInfo[repo.String()] = strings.Join(rules, "<br>")
+		}
+	}
+	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
+		RequiredReviewers: []plugins.RequiredReviewers{
+			{
+				Repos: []string{"org/repo"},
+				Rules: []plugins.RequiredReviewersRule{
+					{
+						Paths: []string{"api/**", "*.proto"},
+						Teams: []string{"api-reviewers"},
+						Users: []string{"alice"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", PluginName)
+	}
+	// Only the 'Description' and 'Config' fields are necessary because this plugin does not react
+	// to any commands.
+	return &pluginhelp.PluginHelp{
+		Description: fmt.Sprintf("The required-reviewers plugin applies the '%s' label to PRs that change paths for which a group of reviewers is configured, until at least one member of each such group has approved the PR with a GitHub review. The label is removed once every group has approved.", labels.NeedsRequiredReviewer),
+		Config:      configInfo,
+		Snippet:     yamlSnippet,
+	}, nil
+}
+
+// groupMembers describes the members of the group of a rule.
+func groupMembers(rule plugins.RequiredReviewersRule) []string {
+	var members []string
+	for _, team := range rule.Teams {
+		members = append(members, "team "+team)
+	}
+	return append(members, rule.Users...)
+}
+
+func handlePullRequestEvent(pc plugins.Agent, pre github.PullRequestEvent) error {
+	if !handlePRActions[pre.Action] {
+		return nil
+	}
+	return handle(pc.Logger, pc.GitHubClient, pc.PluginConfig.RequiredReviewersFor(pre.Repo.Owner.Login, pre.Repo.Name), pre.Repo.Owner.Login, pre.Repo.Name, pre.Number)
+}
+
+func handleReviewEvent(pc plugins.Agent, re github.ReviewEvent) error {
+	if re.Action != github.ReviewActionSubmitted && re.Action != github.ReviewActionDismissed {
+		return nil
+	}
+	if re.PullRequest.State != "open" {
+		return nil
+	}
+	return handle(pc.Logger, pc.GitHubClient, pc.PluginConfig.RequiredReviewersFor(re.Repo.Owner.Login, re.Repo.Name), re.Repo.Owner.Login, re.Repo.Name, re.PullRequest.Number)
+}
+
+func handle(log *logrus.Entry, ghc githubClient, cfg *plugins.RequiredReviewers, org, repo string, number int) error {
+	if len(cfg.Rules) == 0 {
+		return nil
+	}
+
+	changes, err := ghc.GetPullRequestChanges(org, repo, number)
+	if err != nil {
+		return fmt.Errorf("failed to get PR changes: %w", err)
+	}
+	rules := matchingRules(log, cfg.Rules, changes)
+
+	var missing []plugins.RequiredReviewersRule
+	if len(rules) > 0 {
+		reviews, err := ghc.ListReviews(org, repo, number)
+		if err != nil {
+			return fmt.Errorf("failed to list reviews: %w", err)
+		}
+		approvers := CurrentApprovers(reviews)
+		for _, rule := range rules {
+			approved, err := groupApproved(ghc, org, rule, approvers)
+			if err != nil {
+				return err
+			}
+			if !approved {
+				missing = append(missing, rule)
+			}
+		}
+	}
+
+	issueLabels, err := ghc.GetIssueLabels(org, repo, number)
+	if err != nil {
+		return fmt.Errorf("failed to get labels: %w", err)
+	}
+	hasLabel := github.HasLabel(labels.NeedsRequiredReviewer, issueLabels)
+
+	if len(missing) > 0 && !hasLabel {
+		for _, rule := range missing {
+			log.Infof("Missing an approval from one of %s.", strings.Join(groupMembers(rule), ", "))
+		}
+		return ghc.AddLabel(org, repo, number, labels.NeedsRequiredReviewer)
+	}
+	if len(missing) == 0 && hasLabel {
+		return ghc.RemoveLabel(org, repo, number, labels.NeedsRequiredReviewer)
+	}
+	return nil
+}
+
+// matchingRules returns the rules with a path matching at least one of the
+// changed files.
+func matchingRules(log *logrus.Entry, rules []plugins.RequiredReviewersRule, changes []github.PullRequestChange) []plugins.RequiredReviewersRule {
+	var matching []plugins.RequiredReviewersRule
+	for _, rule := range rules {
+		var patterns []gitattributes.Pattern
+		for _, path := range rule.Paths {
+			pattern, err := gitattributes.ParsePattern(path)
+			if err != nil {
+				log.WithError(err).Warnf("Ignoring invalid path %q.", path)
+				continue
+			}
+			patterns = append(patterns, pattern)
+		}
+	changes:
+		for _, change := range changes {
+			for _, pattern := range patterns {
+				if pattern.Match(change.Filename) {
+					matching = append(matching, rule)
+					break changes
+				}
+			}
+		}
+	}
+	return matching
+}
+
+// CurrentApprovers returns the normalized logins of the users whose latest
+// review approves the PR. Comment-only reviews do not change a previous state.
+func CurrentApprovers(reviews []github.Review) sets.String {
+	sort.SliceStable(reviews, func(i, j int) bool {
+		return reviews[i].SubmittedAt.Before(reviews[j].SubmittedAt)
+	})
+	states := map[string]github.ReviewState{}
+	for _, review := range reviews {
+		state := github.ReviewState(strings.ToUpper(string(review.State)))
+		if state == github.ReviewStateCommented || state == github.ReviewStatePending {
+			continue
+		}
+		states[github.NormLogin(review.User.Login)] = state
+	}
+	approvers := sets.NewString()
+	for login, state := range states {
+		if state == github.ReviewStateApproved {
+			approvers.Insert(login)
+		}
+	}
+	return approvers
+}
+
+// groupApproved returns whether one of the approvers belongs to the group of the rule.
+func groupApproved(ghc githubClient, org string, rule plugins.RequiredReviewersRule, approvers sets.String) (bool, error) {
+	for _, user := range rule.Users {
+		if approvers.Has(github.NormLogin(user)) {
+			return true, nil
+		}
+	}
+	for _, team := range rule.Teams {
+		for _, approver := range approvers.List() {
+			member, err := ghc.TeamBySlugHasMember(org, team, approver)
+			if err != nil {
+				return false, fmt.Errorf("failed to check if %s is a member of team %s: %w", approver, team, err)
+			}
+			if member {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
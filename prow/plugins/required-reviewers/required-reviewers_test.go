@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package requiredreviewers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestHandle(t *testing.T) {
+	cfg := &plugins.RequiredReviewers{
+		Repos: []string{"org/repo"},
+		Rules: []plugins.RequiredReviewersRule{
+			{
+				Paths: []string{"api/**"},
+				Teams: []string{"api-reviewers"},
+			},
+			{
+				Paths: []string{"*.proto"},
+				Users: []string{"Alice"},
+			},
+		},
+	}
+	now := time.Now()
+	review := func(user string, state github.ReviewState, ago time.Duration) github.Review {
+		return github.Review{User: github.User{Login: user}, State: state, SubmittedAt: now.Add(-ago)}
+	}
+	label := "org/repo#1:" + labels.NeedsRequiredReviewer
+
+	testCases := []struct {
+		name            string
+		files           []string
+		reviews         []github.Review
+		hasLabel        bool
+		expectedAdded   []string
+		expectedRemoved []string
+	}{
+		{
+			name:  "no matching rule",
+			files: []string{"docs/README.md"},
+		},
+		{
+			name:            "no matching rule removes the label",
+			files:           []string{"docs/README.md"},
+			hasLabel:        true,
+			expectedRemoved: []string{label},
+		},
+		{
+			name:          "matching rule without approval adds the label",
+			files:         []string{"api/types.go"},
+			reviews:       []github.Review{review("bob", github.ReviewStateApproved, time.Hour)},
+			expectedAdded: []string{label},
+		},
+		{
+			name:    "matching rule approved by a team member",
+			files:   []string{"api/types.go"},
+			reviews: []github.Review{review("Carl", github.ReviewStateApproved, time.Hour)},
+		},
+		{
+			name:            "approval by a team member removes the label",
+			files:           []string{"api/types.go"},
+			reviews:         []github.Review{review("carl", github.ReviewStateApproved, time.Hour)},
+			hasLabel:        true,
+			expectedRemoved: []string{label},
+		},
+		{
+			name:  "every matching rule needs an approval",
+			files: []string{"api/types.go", "api/v1/types.proto"},
+			reviews: []github.Review{
+				review("carl", github.ReviewStateApproved, time.Hour),
+			},
+			expectedAdded: []string{label},
+		},
+		{
+			name:  "every matching rule is approved",
+			files: []string{"api/types.go", "api/v1/types.proto"},
+			reviews: []github.Review{
+				review("carl", github.ReviewStateApproved, time.Hour),
+				review("alice", github.ReviewStateApproved, time.Minute),
+			},
+		},
+		{
+			name:  "approval superseded by a request for changes",
+			files: []string{"types.proto"},
+			reviews: []github.Review{
+				review("alice", github.ReviewStateChangesRequested, time.Minute),
+				review("alice", github.ReviewStateApproved, time.Hour),
+			},
+			expectedAdded: []string{label},
+		},
+		{
+			name:  "comment does not supersede an approval",
+			files: []string{"types.proto"},
+			reviews: []github.Review{
+				review("alice", github.ReviewStateApproved, time.Hour),
+				review("alice", github.ReviewStateCommented, time.Minute),
+			},
+		},
+		{
+			name:     "label is kept while an approval is missing",
+			files:    []string{"types.proto"},
+			hasLabel: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			var changes []github.PullRequestChange
+			for _, file := range tc.files {
+				changes = append(changes, github.PullRequestChange{Filename: file})
+			}
+			fc.PullRequestChanges = map[int][]github.PullRequestChange{1: changes}
+			fc.Reviews = map[int][]github.Review{1: tc.reviews}
+			fc.Teams = map[string]map[string]fakegithub.TeamWithMembers{
+				"org": {"api-reviewers": {Members: sets.NewString("carl")}},
+			}
+			if tc.hasLabel {
+				fc.IssueLabelsExisting = []string{label}
+			}
+
+			if err := handle(logrus.WithField("plugin", PluginName), fc, cfg, "org", "repo", 1); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expectedAdded, fc.IssueLabelsAdded); diff != "" {
+				t.Errorf("added labels differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedRemoved, fc.IssueLabelsRemoved); diff != "" {
+				t.Errorf("removed labels differ from expected: %s", diff)
+			}
+		})
+	}
+}
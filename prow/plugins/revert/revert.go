@@ -0,0 +1,283 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package revert contains a plugin which opens a PR reverting a merged PR,
+// or one of its commits, when an org member comments `/revert` on it.
+package revert
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/git/v2"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/pluginhelp"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// PluginName defines this plugin's registered name.
+const PluginName = "revert"
+
+var (
+	revertRe  = regexp.MustCompile(`(?mi)^/revert(?:\s+([0-9a-f]{7,40}))?\s*$`)
+	approveRe = regexp.MustCompile(`(?mi)^/approve\s*$`)
+)
+
+// revertBranchFmt is the name of the branch pushed to the bot's fork for a revert.
+const revertBranchFmt = "revert-%d-%s"
+
+type githubClient interface {
+	AssignIssue(org, repo string, number int, logins []string) error
+	BotUser() (*github.UserData, error)
+	CreateComment(org, repo string, number int, comment string) error
+	CreatePullRequest(org, repo, title, body, head, base string, canModify bool) (int, error)
+	EnsureFork(forkingUser, org, repo string) (string, error)
+	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
+	GetSingleCommit(org, repo, SHA string) (github.RepositoryCommit, error)
+	IsMember(org, user string) (bool, error)
+	ListIssueComments(org, repo string, number int) ([]github.IssueComment, error)
+	ListPRCommits(org, repo string, number int) ([]github.RepositoryCommit, error)
+	ListReviews(org, repo string, number int) ([]github.Review, error)
+}
+
+func init() {
+	plugins.RegisterGenericCommentHandler(PluginName, handleGenericComment, helpProvider)
+}
+
+func helpProvider(_ *plugins.Configuration, _ []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	// The Config field is omitted because this plugin is not configurable.
+	pluginHelp := &pluginhelp.PluginHelp{
+		Description: "The revert plugin opens a PR reverting a merged PR. The revert PR is assigned to the author and the approvers of the original PR, and both PRs are linked to each other.",
+	}
+	pluginHelp.AddCommand(pluginhelp.Command{
+		Usage:       "/revert [sha]",
+		Description: "Opens a PR reverting the merged PR, or all of its commits if it was rebase merged. If the SHA of one of the commits of the PR is given, only that commit is reverted.",
+		Featured:    false,
+		WhoCanUse:   "Members of the organization for the repo.",
+		Examples:    []string{"/revert", "/revert 5f3c8a1"},
+	})
+	return pluginHelp, nil
+}
+
+func handleGenericComment(pc plugins.Agent, e github.GenericCommentEvent) error {
+	return handle(pc.Logger, pc.GitHubClient, pc.GitClient, &e)
+}
+
+func handle(log *logrus.Entry, ghc githubClient, gc git.ClientFactory, e *github.GenericCommentEvent) error {
+	if !e.IsPR || e.Action != github.GenericCommentActionCreated {
+		return nil
+	}
+	match := revertRe.FindStringSubmatch(e.Body)
+	if match == nil {
+		return nil
+	}
+
+	org := e.Repo.Owner.Login
+	repo := e.Repo.Name
+	respond := func(resp string) error {
+		return ghc.CreateComment(org, repo, e.Number, plugins.FormatResponseRaw(e.Body, e.HTMLURL, e.User.Login, resp))
+	}
+
+	member, err := ghc.IsMember(org, e.User.Login)
+	if err != nil {
+		return fmt.Errorf("failed to check if %s is a member of %s: %w", e.User.Login, org, err)
+	}
+	if !member {
+		return respond(fmt.Sprintf("only [%s](https://github.com/orgs/%s/people) org members may request reverts.", org, org))
+	}
+
+	pr, err := ghc.GetPullRequest(org, repo, e.Number)
+	if err != nil {
+		return fmt.Errorf("failed to get pull request %s/%s#%d: %w", org, repo, e.Number, err)
+	}
+	if !pr.Merged || pr.MergeSHA == nil {
+		return respond("cannot revert a PR that has not been merged.")
+	}
+
+	commits, err := ghc.ListPRCommits(org, repo, e.Number)
+	if err != nil {
+		return fmt.Errorf("failed to list the commits of %s/%s#%d: %w", org, repo, e.Number, err)
+	}
+	// targets are the commits to revert, newest first.
+	targets := []string{*pr.MergeSHA}
+	if sha := match[1]; sha != "" {
+		targets = nil
+		for _, commit := range commits {
+			if strings.HasPrefix(commit.SHA, strings.ToLower(sha)) {
+				targets = []string{commit.SHA}
+				break
+			}
+		}
+		if len(targets) == 0 {
+			return respond(fmt.Sprintf("%s is not a commit of this PR.", sha))
+		}
+	} else {
+		rebased, err := rebasedCommits(ghc, org, repo, *pr.MergeSHA, commits)
+		if errors.Is(err, errRebasedCommitsNotFound) {
+			return respond(fmt.Sprintf("this PR was rebase merged, but not all of its commits could be found on branch %q. Name the commit to revert with `/revert <sha>`.", pr.Base.Ref))
+		}
+		if err != nil {
+			return err
+		}
+		if len(rebased) > 0 {
+			targets = rebased
+		}
+	}
+	target := targets[0]
+
+	botUser, err := ghc.BotUser()
+	if err != nil {
+		return fmt.Errorf("failed to get the bot user: %w", err)
+	}
+	forkName, err := ghc.EnsureFork(botUser.Login, org, repo)
+	if err != nil {
+		return fmt.Errorf("failed to ensure the fork of %s/%s exists: %w", org, repo, err)
+	}
+
+	r, err := gc.ClientFor(org, repo)
+	if err != nil {
+		return fmt.Errorf("failed to get git client for %s/%s: %w", org, repo, err)
+	}
+	defer func() {
+		if err := r.Clean(); err != nil {
+			log.WithError(err).Error("Error cleaning up repo.")
+		}
+	}()
+	if err := r.Checkout(pr.Base.Ref); err != nil {
+		return fmt.Errorf("failed to checkout %s: %w", pr.Base.Ref, err)
+	}
+	if err := r.Config("user.name", botUser.Login); err != nil {
+		return fmt.Errorf("failed to configure git user: %w", err)
+	}
+	email := botUser.Email
+	if email == "" {
+		email = fmt.Sprintf("%s@users.noreply.github.com", botUser.Login)
+	}
+	if err := r.Config("user.email", email); err != nil {
+		return fmt.Errorf("failed to configure git email: %w", err)
+	}
+	branch := fmt.Sprintf(revertBranchFmt, e.Number, target[:7])
+	if err := r.CheckoutNewBranch(branch); err != nil {
+		return fmt.Errorf("failed to checkout %s: %w", branch, err)
+	}
+	for _, sha := range targets {
+		if err := r.Revert(sha); err != nil {
+			log.WithError(err).Warnf("Failed to revert %s.", sha)
+			return respond(fmt.Sprintf("%s cannot be reverted on top of branch %q, it needs to be reverted manually:\n```\n%v\n```", sha, pr.Base.Ref, err))
+		}
+	}
+	if err := r.PushToNamedFork(forkName, branch, true); err != nil {
+		return fmt.Errorf("failed to push %s: %w", branch, err)
+	}
+
+	title := fmt.Sprintf("Revert %q", pr.Title)
+	body := fmt.Sprintf("This reverts #%d, as requested by @%s.", e.Number, e.User.Login)
+	if match[1] != "" {
+		title = fmt.Sprintf("Revert %s from %q", target[:7], pr.Title)
+		body = fmt.Sprintf("This reverts commit %s of #%d, as requested by @%s.", target, e.Number, e.User.Login)
+	}
+	head := fmt.Sprintf("%s:%s", botUser.Login, branch)
+	revertNum, err := ghc.CreatePullRequest(org, repo, title, body, head, pr.Base.Ref, true)
+	if err != nil {
+		return fmt.Errorf("failed to create the revert pull request: %w", err)
+	}
+	log.Infof("Created revert PR %s/%s#%d.", org, repo, revertNum)
+
+	assignees, err := revertAssignees(ghc, org, repo, pr, botUser.Login)
+	if err != nil {
+		log.WithError(err).Warn("Failed to determine the approvers of the reverted PR.")
+	}
+	if len(assignees) > 0 {
+		// Not every approver can be assigned, e.g. when they are no longer collaborators.
+		if err := ghc.AssignIssue(org, repo, revertNum, assignees); err != nil {
+			log.WithError(err).Warnf("Failed to assign %s to the revert PR.", strings.Join(assignees, ", "))
+		}
+	}
+	return respond(fmt.Sprintf("revert PR created: #%d", revertNum))
+}
+
+// errRebasedCommitsNotFound is returned when a PR was rebase merged but its
+// commits cannot all be found on the base branch.
+var errRebasedCommitsNotFound = errors.New("rebased commits not found")
+
+// rebasedCommits returns the commits that rebase merging the PR added to the
+// base branch, newest first, starting at the merge SHA and following the first
+// parents. Rebased commits keep the messages of the commits of the PR, which
+// tells them apart from a merge or squash commit. It returns no commits if the
+// PR was not rebase merged or only has a single commit.
+func rebasedCommits(ghc githubClient, org, repo, mergeSHA string, prCommits []github.RepositoryCommit) ([]string, error) {
+	if len(prCommits) < 2 {
+		return nil, nil
+	}
+	var rebased []string
+	sha := mergeSHA
+	for i := len(prCommits) - 1; i >= 0; i-- {
+		commit, err := ghc.GetSingleCommit(org, repo, sha)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit %s: %w", sha, err)
+		}
+		if len(commit.Parents) != 1 || commit.Commit.Message != prCommits[i].Commit.Message {
+			if len(rebased) == 0 {
+				// The merge SHA is a merge or squash commit.
+				return nil, nil
+			}
+			return nil, errRebasedCommitsNotFound
+		}
+		rebased = append(rebased, sha)
+		sha = commit.Parents[0].SHA
+	}
+	return rebased, nil
+}
+
+// revertAssignees returns the author of the PR followed by the users who
+// approved it, either with a GitHub review or with the /approve command.
+func revertAssignees(ghc githubClient, org, repo string, pr *github.PullRequest, botLogin string) ([]string, error) {
+	assignees := []string{pr.User.Login}
+	seen := sets.NewString(github.NormLogin(pr.User.Login), github.NormLogin(botLogin))
+	add := func(login string) {
+		if !seen.Has(github.NormLogin(login)) {
+			seen.Insert(github.NormLogin(login))
+			assignees = append(assignees, login)
+		}
+	}
+
+	reviews, err := ghc.ListReviews(org, repo, pr.Number)
+	if err != nil {
+		return assignees, err
+	}
+	for _, review := range reviews {
+		if review.State == github.ReviewStateApproved {
+			add(review.User.Login)
+		}
+	}
+	comments, err := ghc.ListIssueComments(org, repo, pr.Number)
+	if err != nil {
+		return assignees, err
+	}
+	for _, comment := range comments {
+		if approveRe.MatchString(comment.Body) {
+			add(comment.User.Login)
+		}
+	}
+	return assignees, nil
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/git/localgit"
+	"k8s.io/test-infra/prow/git/v2"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+var defaultBranch = localgit.DefaultBranch("")
+
+type fakeClient struct {
+	*fakegithub.FakeClient
+	createdPRs []string
+}
+
+func (f *fakeClient) EnsureFork(forkingUser, org, repo string) (string, error) {
+	return repo, nil
+}
+
+func (f *fakeClient) CreatePullRequest(org, repo, title, body, head, base string, canModify bool) (int, error) {
+	f.createdPRs = append(f.createdPRs, fmt.Sprintf("title=%q body=%q head=%s base=%s", title, body, head, base))
+	return f.FakeClient.CreatePullRequest(org, repo, title, body, head, base, canModify)
+}
+
+// pushRecorder records the files present in the working tree of each pushed branch.
+type pushRecorder struct {
+	git.ClientFactory
+	pushes map[string][]string
+}
+
+func (p *pushRecorder) ClientFor(org, repo string) (git.RepoClient, error) {
+	r, err := p.ClientFactory.ClientFor(org, repo)
+	return &pushRecordingRepo{RepoClient: r, recorder: p}, err
+}
+
+type pushRecordingRepo struct {
+	git.RepoClient
+	recorder *pushRecorder
+}
+
+func (r *pushRecordingRepo) PushToNamedFork(forkName, branch string, force bool) error {
+	var files []string
+	for _, file := range []string{"a.txt", "b.txt"} {
+		if _, err := os.Stat(filepath.Join(r.Directory(), file)); err == nil {
+			files = append(files, file)
+		}
+	}
+	r.recorder.pushes[forkName+":"+branch] = files
+	return nil
+}
+
+func TestHandle(t *testing.T) {
+	testHandle(localgit.New, t)
+}
+
+func TestHandleV2(t *testing.T) {
+	testHandle(localgit.NewV2, t)
+}
+
+func testHandle(clients localgit.Clients, t *testing.T) {
+	lg, c, err := clients()
+	if err != nil {
+		t.Fatalf("Making localgit: %v", err)
+	}
+	defer func() {
+		if err := lg.Clean(); err != nil {
+			t.Errorf("Cleaning up localgit: %v", err)
+		}
+		if err := c.Clean(); err != nil {
+			t.Errorf("Cleaning up client: %v", err)
+		}
+	}()
+	if err := lg.MakeFakeRepo("org", "repo"); err != nil {
+		t.Fatalf("Making fake repo: %v", err)
+	}
+	var shas []string
+	for _, file := range []string{"a.txt", "b.txt"} {
+		if err := lg.AddCommit("org", "repo", map[string][]byte{file: []byte(file)}); err != nil {
+			t.Fatalf("Adding commit: %v", err)
+		}
+		sha, err := lg.RevParse("org", "repo", "HEAD")
+		if err != nil {
+			t.Fatalf("Getting commit: %v", err)
+		}
+		shas = append(shas, sha)
+	}
+
+	testCases := []struct {
+		name              string
+		body              string
+		commenter         string
+		unmerged          bool
+		rebased           map[string]github.RepositoryCommit
+		expectedPRs       []string
+		expectedPushes    map[string][]string
+		expectedAssignees []string
+		expectedComment   string
+	}{
+		{
+			name:            "not a revert command",
+			body:            "/revert-this",
+			commenter:       "member",
+			expectedPushes:  map[string][]string{},
+			expectedComment: "",
+		},
+		{
+			name:            "non member cannot revert",
+			body:            "/revert",
+			commenter:       "outsider",
+			expectedPushes:  map[string][]string{},
+			expectedComment: "only [org](https://github.com/orgs/org/people) org members may request reverts.",
+		},
+		{
+			name:            "unmerged PR cannot be reverted",
+			body:            "/revert",
+			commenter:       "member",
+			unmerged:        true,
+			expectedPushes:  map[string][]string{},
+			expectedComment: "cannot revert a PR that has not been merged.",
+		},
+		{
+			name:      "revert the merge",
+			body:      "/revert",
+			commenter: "member",
+			expectedPRs: []string{
+				fmt.Sprintf(`title="Revert \"Add b\"" body="This reverts #1, as requested by @member." head=k8s-ci-robot:revert-1-%s base=%s`, shas[1][:7], defaultBranch),
+			},
+			expectedPushes: map[string][]string{
+				"repo:revert-1-" + shas[1][:7]: {"a.txt"},
+			},
+			expectedAssignees: []string{"org/repo#0:author", "org/repo#0:alice", "org/repo#0:bob"},
+			expectedComment:   "revert PR created: #0",
+		},
+		{
+			name:      "revert a single commit",
+			body:      "/revert " + shas[0][:7],
+			commenter: "member",
+			expectedPRs: []string{
+				fmt.Sprintf(`title="Revert %s from \"Add b\"" body="This reverts commit %s of #1, as requested by @member." head=k8s-ci-robot:revert-1-%s base=%s`, shas[0][:7], shas[0], shas[0][:7], defaultBranch),
+			},
+			expectedPushes: map[string][]string{
+				"repo:revert-1-" + shas[0][:7]: {"b.txt"},
+			},
+			expectedAssignees: []string{"org/repo#0:author", "org/repo#0:alice", "org/repo#0:bob"},
+			expectedComment:   "revert PR created: #0",
+		},
+		{
+			name:      "revert all the commits of a rebase merged PR",
+			body:      "/revert",
+			commenter: "member",
+			rebased: map[string]github.RepositoryCommit{
+				shas[1]: {SHA: shas[1], Commit: github.GitCommit{Message: "Add b"}, Parents: []github.GitCommit{{SHA: shas[0]}}},
+				shas[0]: {SHA: shas[0], Commit: github.GitCommit{Message: "Add a"}, Parents: []github.GitCommit{{SHA: "base"}}},
+			},
+			expectedPRs: []string{
+				fmt.Sprintf(`title="Revert \"Add b\"" body="This reverts #1, as requested by @member." head=k8s-ci-robot:revert-1-%s base=%s`, shas[1][:7], defaultBranch),
+			},
+			expectedPushes: map[string][]string{
+				"repo:revert-1-" + shas[1][:7]: nil,
+			},
+			expectedAssignees: []string{"org/repo#0:author", "org/repo#0:alice", "org/repo#0:bob"},
+			expectedComment:   "revert PR created: #0",
+		},
+		{
+			name:      "rebase merged PR whose commits cannot all be found",
+			body:      "/revert",
+			commenter: "member",
+			rebased: map[string]github.RepositoryCommit{
+				shas[1]: {SHA: shas[1], Commit: github.GitCommit{Message: "Add b"}, Parents: []github.GitCommit{{SHA: shas[0]}}},
+				shas[0]: {SHA: shas[0], Commit: github.GitCommit{Message: "Add something else"}, Parents: []github.GitCommit{{SHA: "base"}}},
+			},
+			expectedPushes:  map[string][]string{},
+			expectedComment: "Name the commit to revert with `/revert <sha>`.",
+		},
+		{
+			name:            "unknown commit",
+			body:            "/revert abcdef0",
+			commenter:       "member",
+			expectedPushes:  map[string][]string{},
+			expectedComment: "abcdef0 is not a commit of this PR.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.OrgMembers = map[string][]string{"org": {"member"}}
+			fc.PullRequests = map[int]*github.PullRequest{
+				1: {
+					Number:   1,
+					Title:    "Add b",
+					User:     github.User{Login: "author"},
+					Base:     github.PullRequestBranch{Ref: defaultBranch},
+					Merged:   !tc.unmerged,
+					MergeSHA: &shas[1],
+				},
+			}
+			fc.CommitMap = map[string][]github.RepositoryCommit{
+				"org/repo#1": {{SHA: shas[0]}, {SHA: shas[1]}},
+			}
+			if tc.rebased != nil {
+				fc.Commits = tc.rebased
+				fc.CommitMap["org/repo#1"] = []github.RepositoryCommit{
+					{SHA: "pre-rebase-a", Commit: github.GitCommit{Message: "Add a"}},
+					{SHA: "pre-rebase-b", Commit: github.GitCommit{Message: "Add b"}},
+				}
+			}
+			fc.Reviews = map[int][]github.Review{
+				1: {
+					{User: github.User{Login: "alice"}, State: github.ReviewStateApproved},
+					{User: github.User{Login: "carol"}, State: github.ReviewStateCommented},
+				},
+			}
+			fc.IssueComments = map[int][]github.IssueComment{
+				1: {
+					{User: github.User{Login: "bob"}, Body: "/approve"},
+					{User: github.User{Login: "Alice"}, Body: "/approve"},
+					{User: github.User{Login: "dave"}, Body: "/approve cancel"},
+				},
+			}
+			ghc := &fakeClient{FakeClient: fc}
+			gc := &pushRecorder{ClientFactory: c, pushes: map[string][]string{}}
+			e := &github.GenericCommentEvent{
+				Action: github.GenericCommentActionCreated,
+				IsPR:   true,
+				Body:   tc.body,
+				Number: 1,
+				Repo:   github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+				User:   github.User{Login: tc.commenter},
+			}
+
+			if err := handle(logrus.WithField("plugin", PluginName), ghc, gc, e); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expectedPRs, ghc.createdPRs); diff != "" {
+				t.Errorf("created PRs differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedPushes, gc.pushes); diff != "" {
+				t.Errorf("pushes differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedAssignees, fc.AssigneesAdded); diff != "" {
+				t.Errorf("assignees differ from expected: %s", diff)
+			}
+			var comments []string
+			for _, comment := range fc.IssueComments[1] {
+				if comment.User.Login == "k8s-ci-robot" {
+					comments = append(comments, comment.Body)
+				}
+			}
+			switch {
+			case tc.expectedComment == "" && len(comments) > 0:
+				t.Errorf("expected no comment, got %v", comments)
+			case tc.expectedComment != "" && (len(comments) != 1 || !strings.Contains(comments[0], tc.expectedComment)):
+				t.Errorf("expected a comment containing %q, got %v", tc.expectedComment, comments)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package semantictitle implements the `semantic-title` plugin.
+// It labels PRs with `do-not-merge/invalid-title` while their title does not
+// match the configured regexp or the conventional commits grammar.
+package semantictitle
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/pluginhelp"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// PluginName defines this plugin's registered name.
+const PluginName = "semantic-title"
+
+const (
+	invalidTitleCommentBody = `The title of this PR does not follow the title conventions of this repository. It must:
+
+%s
+
+You can edit the title by writing **/retitle <new-title>** in a comment.
+
+<details>
+
+%s
+</details>
+`
+	invalidTitleCommentPruneBody = "does not follow the title conventions of this repository"
+)
+
+var handlePRActions = map[github.PullRequestEventAction]bool{
+	github.PullRequestActionOpened:   true,
+	github.PullRequestActionReopened: true,
+	github.PullRequestActionEdited:   true,
+}
+
+type githubClient interface {
+	AddLabel(org, repo string, number int, label string) error
+	RemoveLabel(org, repo string, number int, label string) error
+	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
+	CreateComment(org, repo string, number int, comment string) error
+}
+
+type commentPruner interface {
+	PruneComments(shouldPrune func(github.IssueComment) bool)
+}
+
+func init() {
+	plugins.RegisterPullRequestHandler(PluginName, handlePullRequestEvent, helpProvider)
+}
+
+func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	configInfo := map[string]string{}
+	for _, repo := range enabledRepos {
+		if requirements := titleRequirements(config.SemanticTitleFor(repo.Org, repo.Repo)); len(requirements) > 0 {
+			configInfo[repo.String()] = "PR titles must " + strings.Join(requirements, " and ") + "."
+		}
+	}
+	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
+		SemanticTitle: []plugins.SemanticTitle{
+			{
+				Repos:               []string{"org/repo"},
+				Regexp:              `^\S.{0,71}$`,
+				ConventionalCommits: true,
+				Types:               []string{"feat", "fix", "docs"},
+			},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", PluginName)
+	}
+	// Only the 'Description' and 'Config' fields are necessary because this plugin does not react
+	// to any commands.
+	return &pluginhelp.PluginHelp{
+		Description: fmt.Sprintf("The semantic-title plugin applies the '%s' label to PRs whose title does not match the configured regexp or the conventional commits grammar, and explains the expected format in a comment. The label is removed once the title is fixed. Tide queries should list the label in their missingLabels so that PRs with an invalid title cannot merge.", labels.InvalidTitle),
+		Config:      configInfo,
+		Snippet:     yamlSnippet,
+	}, nil
+}
+
+func handlePullRequestEvent(pc plugins.Agent, pre github.PullRequestEvent) error {
+	if !handlePRActions[pre.Action] {
+		return nil
+	}
+	cfg := pc.PluginConfig.SemanticTitleFor(pre.Repo.Owner.Login, pre.Repo.Name)
+	if cfg.Re == nil && !cfg.ConventionalCommits {
+		return nil
+	}
+	cp, err := pc.CommentPruner()
+	if err != nil {
+		return err
+	}
+	return handle(pc.Logger, pc.GitHubClient, cp, cfg, pre)
+}
+
+func handle(log *logrus.Entry, ghc githubClient, cp commentPruner, cfg *plugins.SemanticTitle, pre github.PullRequestEvent) error {
+	var (
+		org    = pre.Repo.Owner.Login
+		repo   = pre.Repo.Name
+		number = pre.Number
+	)
+
+	issueLabels, err := ghc.GetIssueLabels(org, repo, number)
+	if err != nil {
+		return fmt.Errorf("failed to get labels: %w", err)
+	}
+	hasLabel := github.HasLabel(labels.InvalidTitle, issueLabels)
+
+	violations := validateTitle(cfg, pre.PullRequest.Title)
+	if len(violations) == 0 {
+		if hasLabel {
+			if err := ghc.RemoveLabel(org, repo, number, labels.InvalidTitle); err != nil {
+				return fmt.Errorf("failed to remove the %s label: %w", labels.InvalidTitle, err)
+			}
+			cp.PruneComments(func(comment github.IssueComment) bool {
+				return strings.Contains(comment.Body, invalidTitleCommentPruneBody)
+			})
+		}
+		return nil
+	}
+
+	// The explanation only depends on the config, so it is only posted
+	// when the label is first applied.
+	if hasLabel {
+		return nil
+	}
+	log.Infof("Title %q is invalid.", pre.PullRequest.Title)
+	if err := ghc.AddLabel(org, repo, number, labels.InvalidTitle); err != nil {
+		return fmt.Errorf("failed to add the %s label: %w", labels.InvalidTitle, err)
+	}
+	cp.PruneComments(func(comment github.IssueComment) bool {
+		return strings.Contains(comment.Body, invalidTitleCommentPruneBody)
+	})
+	var list []string
+	for _, violation := range violations {
+		list = append(list, "- "+violation)
+	}
+	return ghc.CreateComment(org, repo, number, fmt.Sprintf(invalidTitleCommentBody, strings.Join(list, "\n"), plugins.AboutThisBot))
+}
+
+// validateTitle returns the requirements of the config the title does not meet.
+func validateTitle(cfg *plugins.SemanticTitle, title string) []string {
+	var violations []string
+	if cfg.Re != nil && !cfg.Re.MatchString(title) {
+		violations = append(violations, regexpRequirement(cfg))
+	}
+	if cfg.ConventionalCommits && !conventionalCommitRe(cfg.Types).MatchString(title) {
+		violations = append(violations, conventionalCommitRequirement(cfg))
+	}
+	return violations
+}
+
+// titleRequirements describes every requirement of the config.
+func titleRequirements(cfg *plugins.SemanticTitle) []string {
+	var requirements []string
+	if cfg.Regexp != "" {
+		requirements = append(requirements, regexpRequirement(cfg))
+	}
+	if cfg.ConventionalCommits {
+		requirements = append(requirements, conventionalCommitRequirement(cfg))
+	}
+	return requirements
+}
+
+func regexpRequirement(cfg *plugins.SemanticTitle) string {
+	return fmt.Sprintf("match the regular expression `%s`", cfg.Regexp)
+}
+
+func conventionalCommitRequirement(cfg *plugins.SemanticTitle) string {
+	return fmt.Sprintf("follow the [Conventional Commits](https://www.conventionalcommits.org) format `<type>[(<scope>)][!]: <description>`, where the type is one of %s", strings.Join(cfg.Types, ", "))
+}
+
+// conventionalCommitRe matches the header of a conventional commit with one
+// of the given types, e.g. "feat(api)!: drop the v1 endpoints".
+func conventionalCommitRe(types []string) *regexp.Regexp {
+	var quoted []string
+	for _, t := range types {
+		quoted = append(quoted, regexp.QuoteMeta(t))
+	}
+	return regexp.MustCompile(`^(?:` + strings.Join(quoted, "|") + `)(?:\([\w./, -]+\))?!?: \S`)
+}
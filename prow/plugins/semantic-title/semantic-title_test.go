@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package semantictitle
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+type fakePruner struct {
+	pruned int
+}
+
+func (fp *fakePruner) PruneComments(shouldPrune func(github.IssueComment) bool) {
+	fp.pruned++
+}
+
+func TestValidateTitle(t *testing.T) {
+	conventional := &plugins.SemanticTitle{ConventionalCommits: true, Types: []string{"feat", "fix"}}
+	both := &plugins.SemanticTitle{Re: regexp.MustCompile(`^.{0,20}$`), Regexp: `^.{0,20}$`, ConventionalCommits: true, Types: []string{"feat", "fix"}}
+
+	testCases := []struct {
+		name       string
+		cfg        *plugins.SemanticTitle
+		title      string
+		violations int
+	}{
+		{name: "conventional title", cfg: conventional, title: "feat: add a plugin"},
+		{name: "conventional title with scope", cfg: conventional, title: "fix(hook): handle nil events"},
+		{name: "breaking change", cfg: conventional, title: "feat(api)!: drop the v1 endpoints"},
+		{name: "unknown type", cfg: conventional, title: "chore: bump deps", violations: 1},
+		{name: "missing description", cfg: conventional, title: "feat: ", violations: 1},
+		{name: "missing type", cfg: conventional, title: "Add a plugin", violations: 1},
+		{name: "type prefix is not enough", cfg: conventional, title: "feature: add a plugin", violations: 1},
+		{name: "both requirements met", cfg: both, title: "fix: a typo"},
+		{name: "regexp not matched", cfg: both, title: "fix: a typo in the documentation", violations: 1},
+		{name: "no requirement met", cfg: both, title: "Fix a typo in the documentation", violations: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if violations := validateTitle(tc.cfg, tc.title); len(violations) != tc.violations {
+				t.Errorf("expected %d violations, got %v", tc.violations, violations)
+			}
+		})
+	}
+}
+
+func TestHandle(t *testing.T) {
+	cfg := &plugins.SemanticTitle{ConventionalCommits: true, Types: []string{"feat", "fix"}}
+	label := "org/repo#1:" + labels.InvalidTitle
+
+	testCases := []struct {
+		name            string
+		title           string
+		hasLabel        bool
+		expectedAdded   []string
+		expectedRemoved []string
+		expectComment   bool
+		expectPrune     bool
+	}{
+		{
+			name:  "valid title",
+			title: "feat: add a plugin",
+		},
+		{
+			name:          "invalid title adds the label and a comment",
+			title:         "Add a plugin",
+			expectedAdded: []string{label},
+			expectComment: true,
+			expectPrune:   true,
+		},
+		{
+			name:     "invalid title keeps the label without commenting again",
+			title:    "Add another plugin",
+			hasLabel: true,
+		},
+		{
+			name:            "fixed title removes the label and the comment",
+			title:           "fix: add a plugin",
+			hasLabel:        true,
+			expectedRemoved: []string{label},
+			expectPrune:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			if tc.hasLabel {
+				fc.IssueLabelsExisting = []string{label}
+			}
+			fp := &fakePruner{}
+			pre := github.PullRequestEvent{
+				Action:      github.PullRequestActionEdited,
+				Number:      1,
+				Repo:        github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+				PullRequest: github.PullRequest{Title: tc.title},
+			}
+
+			if err := handle(logrus.WithField("plugin", PluginName), fc, fp, cfg, pre); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expectedAdded, fc.IssueLabelsAdded); diff != "" {
+				t.Errorf("added labels differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedRemoved, fc.IssueLabelsRemoved); diff != "" {
+				t.Errorf("removed labels differ from expected: %s", diff)
+			}
+			comments := fc.IssueComments[1]
+			switch {
+			case !tc.expectComment && len(comments) > 0:
+				t.Errorf("expected no comment, got %v", comments)
+			case tc.expectComment && (len(comments) != 1 || !strings.Contains(comments[0].Body, "`<type>[(<scope>)][!]: <description>`, where the type is one of feat, fix")):
+				t.Errorf("expected a comment explaining the conventional commits format, got %v", comments)
+			}
+			if pruned := fp.pruned > 0; pruned != tc.expectPrune {
+				t.Errorf("expected pruning %t, got %t", tc.expectPrune, pruned)
+			}
+		})
+	}
+}
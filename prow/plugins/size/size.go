@@ -58,13 +58,18 @@ func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhel
 			L:   100,
 			Xl:  500,
 			Xxl: 1000,
+			ExcludedPaths: map[string][]string{
+				"*":        {"vendor/**", "*.pb.go"},
+				"org/repo": {"package-lock.json"},
+			},
+			CommentBreakdown: true,
 		},
 	})
 	if err != nil {
 		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", pluginName)
 	}
 	return &pluginhelp.PluginHelp{
-			Description: "The size plugin manages the 'size/*' labels, maintaining the appropriate label on each pull request as it is updated. Generated files identified by the config file '.generated_files' at the repo root are ignored. Labels are applied based on the total number of lines of changes (additions and deletions).",
+			Description: "The size plugin manages the 'size/*' labels, maintaining the appropriate label on each pull request as it is updated. Generated files identified by the config file '.generated_files' at the repo root, files marked as 'linguist-generated' in '.gitattributes' and files matching the configured excluded paths are ignored. Labels are applied based on the total number of lines of changes (additions and deletions).",
 			Config: map[string]string{
 				"": fmt.Sprintf(`The plugin has the following thresholds:<ul>
 <li>size/XS:  0-%d</li>
@@ -91,8 +96,15 @@ type githubClient interface {
 	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
 	GetFile(org, repo, filepath, commit string) ([]byte, error)
 	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
+	BotUserChecker() (func(candidate string) bool, error)
+	CreateComment(owner, repo string, number int, comment string) error
+	DeleteStaleComments(org, repo string, number int, comments []github.IssueComment, isStale func(github.IssueComment) bool) error
+	ListIssueComments(owner, repo string, issue int) ([]github.IssueComment, error)
 }
 
+// breakdownMarker identifies the comments showing the breakdown of the size of a PR.
+const breakdownMarker = "<!-- size breakdown -->"
+
 func handlePR(gc githubClient, sizes plugins.Size, le *logrus.Entry, pe github.PullRequestEvent) error {
 	if !isPRChanged(pe) {
 		return nil
@@ -126,10 +138,13 @@ func handlePR(gc githubClient, sizes plugins.Size, le *logrus.Entry, pe github.P
 		return fmt.Errorf("can not get PR changes for size plugin: %w", err)
 	}
 
-	var count int
+	excluded := excludedPatterns(sizes.ExcludedPathsFor(owner, repo), le)
+
+	var count, rawCount int
 	for _, change := range changes {
-		// Skip generated and linguist-generated files.
-		if gf.Match(change.Filename) || ga.IsLinguistGenerated(change.Filename) {
+		rawCount += change.Additions + change.Deletions
+		// Skip generated, linguist-generated and excluded files.
+		if gf.Match(change.Filename) || ga.IsLinguistGenerated(change.Filename) || matchesAny(excluded, change.Filename) {
 			continue
 		}
 
@@ -142,6 +157,11 @@ func handlePR(gc githubClient, sizes plugins.Size, le *logrus.Entry, pe github.P
 	}
 
 	newLabel := bucket(count, sizes).label()
+	if sizes.CommentBreakdown {
+		if err := updateBreakdownComment(gc, owner, repo, num, count, rawCount, newLabel); err != nil {
+			le.WithError(err).Warn("error while updating the size breakdown comment")
+		}
+	}
 	var hasLabel bool
 
 	for _, label := range labels {
@@ -168,6 +188,68 @@ func handlePR(gc githubClient, sizes plugins.Size, le *logrus.Entry, pe github.P
 	return nil
 }
 
+// excludedPatterns parses the excluded path globs, skipping invalid ones.
+func excludedPatterns(paths []string, le *logrus.Entry) []gitattributes.Pattern {
+	var patterns []gitattributes.Pattern
+	for _, path := range paths {
+		pattern, err := gitattributes.ParsePattern(path)
+		if err != nil {
+			le.WithError(err).Warnf("ignoring invalid excluded path %q", path)
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+func matchesAny(patterns []gitattributes.Pattern, path string) bool {
+	for _, pattern := range patterns {
+		if pattern.Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateBreakdownComment makes sure the PR has a single comment showing the
+// effective and raw line counts when they differ, and none otherwise.
+func updateBreakdownComment(gc githubClient, owner, repo string, num, count, rawCount int, label string) error {
+	var body string
+	if count != rawCount {
+		body = fmt.Sprintf("This PR changes %d lines, %d of which are in generated, vendored or otherwise excluded files. Only the remaining %d lines count towards its size, labeled `%s`.\n%s", rawCount, rawCount-count, count, label, breakdownMarker)
+	}
+
+	botUserChecker, err := gc.BotUserChecker()
+	if err != nil {
+		return err
+	}
+	comments, err := gc.ListIssueComments(owner, repo, num)
+	if err != nil {
+		return err
+	}
+	isBreakdown := func(comment github.IssueComment) bool {
+		return botUserChecker(comment.User.Login) && strings.Contains(comment.Body, breakdownMarker)
+	}
+	var existing []github.IssueComment
+	for _, comment := range comments {
+		if isBreakdown(comment) {
+			existing = append(existing, comment)
+		}
+	}
+	if len(existing) == 1 && existing[0].Body == body {
+		return nil
+	}
+	if len(existing) > 0 {
+		if err := gc.DeleteStaleComments(owner, repo, num, comments, isBreakdown); err != nil {
+			return err
+		}
+	}
+	if body == "" {
+		return nil
+	}
+	return gc.CreateComment(owner, repo, num, body)
+}
+
 // One of a set of discrete buckets.
 type size int
 
@@ -17,6 +17,7 @@ limitations under the License.
 package size
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -31,6 +32,7 @@ type ghc struct {
 	labels    map[github.Label]bool
 	files     map[string][]byte
 	prChanges []github.PullRequestChange
+	comments  []github.IssueComment
 
 	addLabelErr, removeLabelErr, getIssueLabelsErr,
 	getFileErr, getPullRequestChangesErr error
@@ -76,6 +78,35 @@ func (c *ghc) GetPullRequestChanges(_, _ string, _ int) ([]github.PullRequestCha
 	return c.prChanges, c.getPullRequestChangesErr
 }
 
+const botName = "k8s-ci-robot"
+
+func (c *ghc) BotUserChecker() (func(candidate string) bool, error) {
+	return func(candidate string) bool { return candidate == botName }, nil
+}
+
+func (c *ghc) CreateComment(_, _ string, _ int, comment string) error {
+	c.T.Logf("CreateComment: %s", comment)
+	c.comments = append(c.comments, github.IssueComment{User: github.User{Login: botName}, Body: comment})
+	return nil
+}
+
+func (c *ghc) DeleteStaleComments(_, _ string, _ int, comments []github.IssueComment, isStale func(github.IssueComment) bool) error {
+	c.T.Log("DeleteStaleComments")
+	var kept []github.IssueComment
+	for _, comment := range c.comments {
+		if !isStale(comment) {
+			kept = append(kept, comment)
+		}
+	}
+	c.comments = kept
+	return nil
+}
+
+func (c *ghc) ListIssueComments(_, _ string, _ int) ([]github.IssueComment, error) {
+	c.T.Log("ListIssueComments")
+	return c.comments, nil
+}
+
 func TestSizesOrDefault(t *testing.T) {
 	for _, c := range []struct {
 		input    plugins.Size
@@ -106,7 +137,7 @@ func TestSizesOrDefault(t *testing.T) {
 			expected: defaultSizes,
 		},
 	} {
-		if c.expected != sizesOrDefault(c.input) {
+		if !reflect.DeepEqual(c.expected, sizesOrDefault(c.input)) {
 			t.Fatalf("Unexpected sizes from sizesOrDefault - expected %+v but got %+v", c.expected, sizesOrDefault(c.input))
 		}
 	}
@@ -609,6 +640,72 @@ func TestHandlePR(t *testing.T) {
 				Xxl: 4,
 			},
 		},
+		{
+			name: "excluded paths are not counted",
+			client: &ghc{
+				labels:     map[github.Label]bool{},
+				getFileErr: &github.FileNotFound{},
+				prChanges: []github.PullRequestChange{
+					{
+						SHA:       "abcd",
+						Filename:  "foobar",
+						Additions: 10,
+						Deletions: 10,
+						Changes:   20,
+					},
+					{
+						SHA:       "abcd",
+						Filename:  "vendor/github.com/foo/bar.go",
+						Additions: 500,
+						Changes:   500,
+					},
+					{
+						SHA:       "abcd",
+						Filename:  "api/types.pb.go",
+						Additions: 200,
+						Changes:   200,
+					},
+					{
+						SHA:       "abcd",
+						Filename:  "package-lock.json",
+						Additions: 300,
+						Changes:   300,
+					},
+				},
+			},
+			event: github.PullRequestEvent{
+				Action: github.PullRequestActionOpened,
+				Number: 101,
+				PullRequest: github.PullRequest{
+					Number: 101,
+					Base: github.PullRequestBranch{
+						SHA: "abcd",
+						Repo: github.Repo{
+							Owner: github.User{
+								Login: "kubernetes",
+							},
+							Name: "kubernetes",
+						},
+					},
+				},
+			},
+			finalLabels: []github.Label{
+				{Name: "size/S"},
+			},
+			sizes: plugins.Size{
+				S:   10,
+				M:   30,
+				L:   100,
+				Xl:  500,
+				Xxl: 1000,
+				ExcludedPaths: map[string][]string{
+					"*":                     {"vendor/**"},
+					"kubernetes":            {"*.pb.go"},
+					"kubernetes/kubernetes": {"package-lock.json"},
+					"kubernetes/other":      {"foobar"},
+				},
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -648,6 +745,106 @@ func TestHandlePR(t *testing.T) {
 	}
 }
 
+func TestBreakdownComment(t *testing.T) {
+	sizes := defaultSizes
+	sizes.ExcludedPaths = map[string][]string{"*": {"vendor/**"}}
+	sizes.CommentBreakdown = true
+	event := github.PullRequestEvent{
+		Action: github.PullRequestActionSynchronize,
+		Number: 101,
+		PullRequest: github.PullRequest{
+			Number: 101,
+			Base: github.PullRequestBranch{
+				SHA: "abcd",
+				Repo: github.Repo{
+					Owner: github.User{Login: "kubernetes"},
+					Name:  "kubernetes",
+				},
+			},
+		},
+	}
+	breakdown := "This PR changes 120 lines, 100 of which are in generated, vendored or otherwise excluded files. Only the remaining 20 lines count towards its size, labeled `size/S`.\n" + breakdownMarker
+
+	cases := []struct {
+		name             string
+		prChanges        []github.PullRequestChange
+		comments         []github.IssueComment
+		expectedComments []github.IssueComment
+	}{
+		{
+			name: "no excluded files, no comment",
+			prChanges: []github.PullRequestChange{
+				{Filename: "foobar", Additions: 20},
+			},
+		},
+		{
+			name: "excluded files are broken down",
+			prChanges: []github.PullRequestChange{
+				{Filename: "foobar", Additions: 20},
+				{Filename: "vendor/foo.go", Additions: 100},
+			},
+			expectedComments: []github.IssueComment{
+				{User: github.User{Login: botName}, Body: breakdown},
+			},
+		},
+		{
+			name: "up to date breakdown is kept",
+			prChanges: []github.PullRequestChange{
+				{Filename: "foobar", Additions: 20},
+				{Filename: "vendor/foo.go", Additions: 100},
+			},
+			comments: []github.IssueComment{
+				{User: github.User{Login: "someone"}, Body: "lgtm"},
+				{User: github.User{Login: botName}, Body: breakdown},
+			},
+			expectedComments: []github.IssueComment{
+				{User: github.User{Login: "someone"}, Body: "lgtm"},
+				{User: github.User{Login: botName}, Body: breakdown},
+			},
+		},
+		{
+			name: "outdated breakdown is replaced",
+			prChanges: []github.PullRequestChange{
+				{Filename: "foobar", Additions: 20},
+				{Filename: "vendor/foo.go", Additions: 100},
+			},
+			comments: []github.IssueComment{
+				{User: github.User{Login: botName}, Body: "This PR changes 30 lines.\n" + breakdownMarker},
+			},
+			expectedComments: []github.IssueComment{
+				{User: github.User{Login: botName}, Body: breakdown},
+			},
+		},
+		{
+			name: "breakdown is removed once no excluded file is changed",
+			prChanges: []github.PullRequestChange{
+				{Filename: "foobar", Additions: 20},
+			},
+			comments: []github.IssueComment{
+				{User: github.User{Login: botName}, Body: breakdown},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := &ghc{
+				T:          t,
+				labels:     map[github.Label]bool{},
+				getFileErr: &github.FileNotFound{},
+				prChanges:  c.prChanges,
+				comments:   c.comments,
+			}
+			if err := handlePR(client, sizes, logrus.NewEntry(logrus.New()), event); err != nil {
+				t.Fatalf("handlePR error: %v", err)
+			}
+			if !reflect.DeepEqual(client.comments, c.expectedComments) {
+				t.Errorf("expected comments %v, got %v", c.expectedComments, client.comments)
+			}
+		})
+	}
+}
+
 func TestHelpProvider(t *testing.T) {
 	enabledRepos := []config.OrgRepo{
 		{Org: "org1", Repo: "repo"},
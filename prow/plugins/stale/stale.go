@@ -0,0 +1,227 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package stale implements the `stale` plugin. It periodically marks
+// inactive issues and PRs as stale, then as rotten, and finally closes them.
+package stale
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/pluginhelp"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// PluginName defines this plugin's registered name.
+const PluginName = "stale"
+
+type githubClient interface {
+	AddLabel(org, repo string, number int, label string) error
+	RemoveLabel(org, repo string, number int, label string) error
+	CreateComment(org, repo string, number int, comment string) error
+	CloseIssue(org, repo string, number int) error
+	ClosePR(org, repo string, number int) error
+	FindIssues(query, sort string, asc bool) ([]github.Issue, error)
+}
+
+func init() {
+	plugins.RegisterPeriodicHandler(PluginName, handlePeriodic, helpProvider)
+}
+
+func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	configInfo := map[string]string{}
+	for _, repo := range enabledRepos {
+		opts := config.StaleFor(repo.Org, repo.Repo)
+		if opts == nil {
+			continue
+		}
+		var kinds []string
+		if opts.Issues {
+			kinds = append(kinds, "issues")
+		}
+		if opts.PRs {
+			kinds = append(kinds, "PRs")
+		}
+		configInfo[repo.String()] = fmt.Sprintf("Inactive %s are marked as stale after %s, as rotten after %s more and closed after %s more.", strings.Join(kinds, " and "), opts.StaleAfterDuration, opts.RottenAfterDuration, opts.CloseAfterDuration)
+	}
+	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
+		Stale: []plugins.Stale{
+			{
+				Repos:        []string{"org/repo"},
+				PRs:          true,
+				Issues:       true,
+				StaleAfter:   "2160h",
+				RottenAfter:  "720h",
+				CloseAfter:   "720h",
+				ExemptLabels: []string{"priority/critical-urgent"},
+			},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", PluginName)
+	}
+	// Only the 'Description' and 'Config' fields are necessary because this plugin does not react
+	// to any commands.
+	return &pluginhelp.PluginHelp{
+		Description: fmt.Sprintf("The stale plugin periodically applies the '%s' label to issues and PRs that have been inactive for a while, then replaces it with the '%s' label if they stay inactive, and finally closes them. A comment is posted at every step. Any activity, such as commenting '/remove-lifecycle stale', restarts the inactivity window. Issues and PRs labeled '%s' are exempt.", labels.LifecycleStale, labels.LifecycleRotten, labels.LifecycleFrozen),
+		Config:      configInfo,
+		Snippet:     yamlSnippet,
+	}, nil
+}
+
+func handlePeriodic(pc plugins.Agent) error {
+	return handle(pc.Logger, pc.GitHubClient, pc.PluginConfig, time.Now())
+}
+
+func handle(log *logrus.Entry, gc githubClient, config *plugins.Configuration, now time.Time) error {
+	// An org and one of its repos may both be configured, so collect the
+	// issues first and resolve the effective config for each of them afterwards.
+	issues := map[string]github.Issue{}
+	var errs []error
+	for _, stale := range config.Stale {
+		for _, orgOrRepo := range stale.Repos {
+			found, err := gc.FindIssues(searchQuery(stale, orgOrRepo, now), "", false)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to search for inactive issues in %s: %w", orgOrRepo, err))
+				continue
+			}
+			for _, issue := range found {
+				issues[issue.HTMLURL] = issue
+			}
+		}
+	}
+
+	for htmlURL, issue := range issues {
+		org, repo, err := github.OrgRepoFromHTMLURL(htmlURL)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		opts := config.StaleFor(org, repo)
+		if !config.PluginEnabledFor(PluginName, org, repo) || opts == nil {
+			continue
+		}
+		l := log.WithFields(logrus.Fields{github.OrgLogField: org, github.RepoLogField: repo, github.PrLogField: issue.Number})
+		if err := advanceLifecycle(l, gc, opts, org, repo, issue, now); err != nil {
+			errs = append(errs, fmt.Errorf("failed to update the lifecycle of %s/%s#%d: %w", org, repo, issue.Number, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// searchQuery returns the query for the open issues and PRs of orgOrRepo
+// that have been inactive for at least the shortest window of the config.
+func searchQuery(stale plugins.Stale, orgOrRepo string, now time.Time) string {
+	query := []string{"is:open", plugins.SearchQualifier(orgOrRepo)}
+	switch {
+	case stale.PRs && !stale.Issues:
+		query = append(query, "is:pr")
+	case stale.Issues && !stale.PRs:
+		query = append(query, "is:issue")
+	}
+	for _, label := range append([]string{labels.LifecycleFrozen}, stale.ExemptLabels...) {
+		query = append(query, fmt.Sprintf("-label:%q", label))
+	}
+	shortest := stale.StaleAfterDuration
+	for _, d := range []time.Duration{stale.RottenAfterDuration, stale.CloseAfterDuration} {
+		if d < shortest {
+			shortest = d
+		}
+	}
+	query = append(query, "updated:<"+now.Add(-shortest).UTC().Format(time.RFC3339))
+	return strings.Join(query, " ")
+}
+
+// advanceLifecycle moves the issue to the next lifecycle step if it has been
+// inactive for long enough. Every step updates the issue, so the inactivity
+// window of the next step starts when the previous one is applied.
+func advanceLifecycle(log *logrus.Entry, gc githubClient, opts *plugins.Stale, org, repo string, issue github.Issue, now time.Time) error {
+	kind := "issue"
+	if issue.IsPullRequest() {
+		kind = "PR"
+		if !opts.PRs {
+			return nil
+		}
+	} else if !opts.Issues {
+		return nil
+	}
+	for _, label := range append([]string{labels.LifecycleFrozen}, opts.ExemptLabels...) {
+		if issue.HasLabel(label) {
+			return nil
+		}
+	}
+	inactive := now.Sub(issue.UpdatedAt)
+
+	switch {
+	case issue.HasLabel(labels.LifecycleRotten):
+		if inactive < opts.CloseAfterDuration {
+			return nil
+		}
+		log.WithField("inactive", inactive).Infof("Closing rotten %s.", kind)
+		if err := comment(gc, opts.CloseComment, kind, org, repo, issue); err != nil {
+			return err
+		}
+		if issue.IsPullRequest() {
+			return gc.ClosePR(org, repo, issue.Number)
+		}
+		return gc.CloseIssue(org, repo, issue.Number)
+	case issue.HasLabel(labels.LifecycleStale):
+		if inactive < opts.RottenAfterDuration {
+			return nil
+		}
+		log.WithField("inactive", inactive).Infof("Marking stale %s as rotten.", kind)
+		if err := comment(gc, opts.RottenComment, kind, org, repo, issue); err != nil {
+			return err
+		}
+		if err := gc.AddLabel(org, repo, issue.Number, labels.LifecycleRotten); err != nil {
+			return err
+		}
+		return gc.RemoveLabel(org, repo, issue.Number, labels.LifecycleStale)
+	default:
+		if inactive < opts.StaleAfterDuration {
+			return nil
+		}
+		log.WithField("inactive", inactive).Infof("Marking %s as stale.", kind)
+		if err := comment(gc, opts.StaleComment, kind, org, repo, issue); err != nil {
+			return err
+		}
+		return gc.AddLabel(org, repo, issue.Number, labels.LifecycleStale)
+	}
+}
+
+// comment posts the rendered template on the issue.
+func comment(gc githubClient, tmpl, kind, org, repo string, issue github.Issue) error {
+	t, err := template.New("comment").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse comment template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, struct{ Kind string }{Kind: kind}); err != nil {
+		return fmt.Errorf("failed to execute comment template: %w", err)
+	}
+	return gc.CreateComment(org, repo, issue.Number, plugins.FormatSimpleResponse(issue.User.Login, buf.String()))
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+type fakeClient struct {
+	*fakegithub.FakeClient
+	closedPRs []int
+}
+
+func (f *fakeClient) ClosePR(org, repo string, number int) error {
+	f.closedPRs = append(f.closedPRs, number)
+	return nil
+}
+
+func TestHandle(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	label := func(l string) string {
+		return "org/repo#5:" + l
+	}
+
+	cases := []struct {
+		name             string
+		enabledFor       string
+		pr               bool
+		labels           []string
+		inactive         time.Duration
+		expectAdded      []string
+		expectRemoved    []string
+		expectComment    bool
+		expectClosed     bool
+		expectedClosedPR bool
+	}{
+		{
+			name:     "active issue is left alone",
+			inactive: day,
+		},
+		{
+			name:          "inactive issue is marked as stale",
+			inactive:      11 * day,
+			expectAdded:   []string{label(labels.LifecycleStale)},
+			expectComment: true,
+		},
+		{
+			name:     "recently marked stale issue is left alone",
+			labels:   []string{labels.LifecycleStale},
+			inactive: 4 * day,
+		},
+		{
+			name:          "inactive stale issue is marked as rotten",
+			labels:        []string{labels.LifecycleStale},
+			inactive:      6 * day,
+			expectAdded:   []string{label(labels.LifecycleRotten)},
+			expectRemoved: []string{label(labels.LifecycleStale)},
+			expectComment: true,
+		},
+		{
+			name:          "inactive rotten issue is closed",
+			labels:        []string{labels.LifecycleRotten},
+			inactive:      3 * day,
+			expectComment: true,
+			expectClosed:  true,
+		},
+		{
+			name:             "inactive rotten PR is closed",
+			pr:               true,
+			labels:           []string{labels.LifecycleRotten},
+			inactive:         3 * day,
+			expectComment:    true,
+			expectedClosedPR: true,
+		},
+		{
+			name:     "frozen issue is exempt",
+			labels:   []string{labels.LifecycleFrozen},
+			inactive: 11 * day,
+		},
+		{
+			name:     "issue with an exempt label is exempt",
+			labels:   []string{"priority/critical-urgent"},
+			inactive: 11 * day,
+		},
+		{
+			name:       "issue is left alone if the plugin is not enabled",
+			enabledFor: "org/other-repo",
+			inactive:   11 * day,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			issue := &github.Issue{
+				Number:    5,
+				State:     "open",
+				HTMLURL:   "https://github.com/org/repo/issues/5",
+				User:      github.User{Login: "author"},
+				UpdatedAt: now.Add(-tc.inactive),
+			}
+			if tc.pr {
+				issue.PullRequest = &struct{}{}
+			}
+			for _, l := range tc.labels {
+				issue.Labels = append(issue.Labels, github.Label{Name: l})
+			}
+			fc := &fakeClient{FakeClient: fakegithub.NewFakeClient()}
+			fc.Issues = map[int]*github.Issue{5: issue}
+			enabledFor := tc.enabledFor
+			if enabledFor == "" {
+				enabledFor = "org/repo"
+			}
+			pc := &plugins.Configuration{
+				Plugins: plugins.Plugins{enabledFor: {Plugins: []string{PluginName}}},
+				Stale: []plugins.Stale{{
+					Repos:         []string{"org"},
+					PRs:           true,
+					Issues:        true,
+					ExemptLabels:  []string{"priority/critical-urgent"},
+					StaleComment:  "stale {{.Kind}}",
+					RottenComment: "rotten {{.Kind}}",
+					CloseComment:  "closed {{.Kind}}",
+
+					StaleAfterDuration:  10 * day,
+					RottenAfterDuration: 5 * day,
+					CloseAfterDuration:  2 * day,
+				}},
+			}
+			if err := handle(logrus.WithField("plugin", PluginName), fc, pc, now); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expectAdded, fc.IssueLabelsAdded); diff != "" {
+				t.Errorf("added labels differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectRemoved, fc.IssueLabelsRemoved); diff != "" {
+				t.Errorf("removed labels differ from expected: %s", diff)
+			}
+			if commented := len(fc.IssueComments[5]) > 0; commented != tc.expectComment {
+				t.Errorf("expected comment %t, got %t", tc.expectComment, commented)
+			}
+			if closed := issue.State == "closed"; closed != tc.expectClosed {
+				t.Errorf("expected issue closed %t, got %t", tc.expectClosed, closed)
+			}
+			if closedPR := len(fc.closedPRs) > 0; closedPR != tc.expectedClosedPR {
+				t.Errorf("expected PR closed %t, got %t", tc.expectedClosedPR, closedPR)
+			}
+		})
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	stale := plugins.Stale{
+		PRs:                 true,
+		ExemptLabels:        []string{"priority/critical-urgent"},
+		StaleAfterDuration:  240 * time.Hour,
+		RottenAfterDuration: 120 * time.Hour,
+		CloseAfterDuration:  48 * time.Hour,
+	}
+	expected := `is:open repo:org/repo is:pr -label:"lifecycle/frozen" -label:"priority/critical-urgent" updated:<2021-02-27T00:00:00Z`
+	if got := searchQuery(stale, "org/repo", now); got != expected {
+		t.Errorf("expected query %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,320 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+const (
+	// transactionAttempts is the number of times a mutation of a
+	// transaction, or its rollback, is attempted.
+	transactionAttempts = 3
+	// transactionBackoff is the wait before the second attempt, it doubles
+	// with every attempt.
+	transactionBackoff = 250 * time.Millisecond
+)
+
+type transactionClient interface {
+	AddLabel(org, repo string, number int, label string) error
+	RemoveLabel(org, repo string, number int, label string) error
+	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
+	AssignIssue(org, repo string, number int, logins []string) error
+	UnassignIssue(org, repo string, number int, logins []string) error
+	GetIssue(org, repo string, number int) (*github.Issue, error)
+	RequestReview(org, repo string, number int, logins []string) error
+	UnrequestReview(org, repo string, number int, logins []string) error
+	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
+	CreateComment(org, repo string, number int, comment string) error
+}
+
+// Mutation is a change of a transaction.
+type Mutation struct {
+	// Description describes the change in errors and logs, like
+	// "add label lgtm".
+	Description string
+	// Apply makes the change.
+	Apply func() error
+	// Rollback undoes the change, nil if it can't be undone.
+	Rollback func() error
+}
+
+// Transaction batches changes of an issue or a PR, like adding a label,
+// assigning users and commenting, so that either all or none of them are
+// applied. The changes are applied in order when the transaction is
+// committed, and the applied ones are rolled back in reverse order when one
+// fails. Comments can't be rolled back and are made last.
+type Transaction struct {
+	client transactionClient
+	logger *logrus.Entry
+	org    string
+	repo   string
+	number int
+
+	mutations []func(*transactionState) *Mutation
+	comments  []string
+	// sleep is replaced in tests.
+	sleep func(time.Duration)
+}
+
+// transactionState is the state of the issue or PR when the transaction is
+// committed, fetched once for the changes that need it so that changes that
+// are no-ops are skipped and rollbacks don't undo more than the transaction
+// did.
+type transactionState struct {
+	t         *Transaction
+	labels    []github.Label
+	issue     *github.Issue
+	pr        *github.PullRequest
+	fetchErrs []error
+}
+
+// NewTransaction returns a transaction changing the issue or PR with the
+// GitHub client of the agent.
+func (a *Agent) NewTransaction(org, repo string, number int) *Transaction {
+	return newTransaction(a.GitHubClient, a.Logger, org, repo, number)
+}
+
+func newTransaction(client transactionClient, logger *logrus.Entry, org, repo string, number int) *Transaction {
+	return &Transaction{
+		client: client,
+		logger: logger.WithFields(logrus.Fields{"org": org, "repo": repo, "number": number}),
+		org:    org,
+		repo:   repo,
+		number: number,
+		sleep:  time.Sleep,
+	}
+}
+
+// Do adds a custom change to the transaction.
+func (t *Transaction) Do(m Mutation) *Transaction {
+	t.mutations = append(t.mutations, func(*transactionState) *Mutation { return &m })
+	return t
+}
+
+// AddLabel adds a label, unless the issue or PR already has it.
+func (t *Transaction) AddLabel(label string) *Transaction {
+	t.mutations = append(t.mutations, func(s *transactionState) *Mutation {
+		if labels := s.getLabels(); labels != nil && github.HasLabel(label, labels) {
+			return nil
+		}
+		return &Mutation{
+			Description: "add label " + label,
+			Apply:       func() error { return t.client.AddLabel(t.org, t.repo, t.number, label) },
+			Rollback:    func() error { return t.client.RemoveLabel(t.org, t.repo, t.number, label) },
+		}
+	})
+	return t
+}
+
+// RemoveLabel removes a label, if the issue or PR has it.
+func (t *Transaction) RemoveLabel(label string) *Transaction {
+	t.mutations = append(t.mutations, func(s *transactionState) *Mutation {
+		if labels := s.getLabels(); labels != nil && !github.HasLabel(label, labels) {
+			return nil
+		}
+		return &Mutation{
+			Description: "remove label " + label,
+			Apply:       func() error { return t.client.RemoveLabel(t.org, t.repo, t.number, label) },
+			Rollback:    func() error { return t.client.AddLabel(t.org, t.repo, t.number, label) },
+		}
+	})
+	return t
+}
+
+// Assign assigns the users that are not assigned yet.
+func (t *Transaction) Assign(logins ...string) *Transaction {
+	t.mutations = append(t.mutations, func(s *transactionState) *Mutation {
+		var toAssign []string
+		for _, login := range logins {
+			if issue := s.getIssue(); issue == nil || !issue.IsAssignee(login) {
+				toAssign = append(toAssign, login)
+			}
+		}
+		if len(toAssign) == 0 {
+			return nil
+		}
+		return &Mutation{
+			Description: fmt.Sprintf("assign %v", toAssign),
+			Apply:       func() error { return t.client.AssignIssue(t.org, t.repo, t.number, toAssign) },
+			Rollback:    func() error { return t.client.UnassignIssue(t.org, t.repo, t.number, toAssign) },
+		}
+	})
+	return t
+}
+
+// Unassign unassigns the users that are assigned.
+func (t *Transaction) Unassign(logins ...string) *Transaction {
+	t.mutations = append(t.mutations, func(s *transactionState) *Mutation {
+		var toUnassign []string
+		for _, login := range logins {
+			if issue := s.getIssue(); issue == nil || issue.IsAssignee(login) {
+				toUnassign = append(toUnassign, login)
+			}
+		}
+		if len(toUnassign) == 0 {
+			return nil
+		}
+		return &Mutation{
+			Description: fmt.Sprintf("unassign %v", toUnassign),
+			Apply:       func() error { return t.client.UnassignIssue(t.org, t.repo, t.number, toUnassign) },
+			Rollback:    func() error { return t.client.AssignIssue(t.org, t.repo, t.number, toUnassign) },
+		}
+	})
+	return t
+}
+
+// RequestReview requests the review of the users whose review is not
+// requested yet.
+func (t *Transaction) RequestReview(logins ...string) *Transaction {
+	t.mutations = append(t.mutations, func(s *transactionState) *Mutation {
+		var toRequest []string
+		for _, login := range logins {
+			if pr := s.getPullRequest(); pr == nil || !reviewRequested(pr, login) {
+				toRequest = append(toRequest, login)
+			}
+		}
+		if len(toRequest) == 0 {
+			return nil
+		}
+		return &Mutation{
+			Description: fmt.Sprintf("request the review of %v", toRequest),
+			Apply:       func() error { return t.client.RequestReview(t.org, t.repo, t.number, toRequest) },
+			Rollback:    func() error { return t.client.UnrequestReview(t.org, t.repo, t.number, toRequest) },
+		}
+	})
+	return t
+}
+
+func reviewRequested(pr *github.PullRequest, login string) bool {
+	for _, reviewer := range pr.RequestedReviewers {
+		if github.NormLogin(reviewer.Login) == github.NormLogin(login) {
+			return true
+		}
+	}
+	return false
+}
+
+// Comment comments on the issue or PR once all the other changes are applied.
+func (t *Transaction) Comment(comment string) *Transaction {
+	t.comments = append(t.comments, comment)
+	return t
+}
+
+func (s *transactionState) getLabels() []github.Label {
+	if s.labels == nil {
+		labels, err := s.t.client.GetIssueLabels(s.t.org, s.t.repo, s.t.number)
+		if err != nil {
+			s.fetchErrs = append(s.fetchErrs, fmt.Errorf("failed to get the labels: %w", err))
+			return nil
+		}
+		s.labels = append([]github.Label{}, labels...)
+	}
+	return s.labels
+}
+
+func (s *transactionState) getIssue() *github.Issue {
+	if s.issue == nil {
+		issue, err := s.t.client.GetIssue(s.t.org, s.t.repo, s.t.number)
+		if err != nil {
+			s.fetchErrs = append(s.fetchErrs, fmt.Errorf("failed to get the issue: %w", err))
+			return nil
+		}
+		s.issue = issue
+	}
+	return s.issue
+}
+
+func (s *transactionState) getPullRequest() *github.PullRequest {
+	if s.pr == nil {
+		pr, err := s.t.client.GetPullRequest(s.t.org, s.t.repo, s.t.number)
+		if err != nil {
+			s.fetchErrs = append(s.fetchErrs, fmt.Errorf("failed to get the pull request: %w", err))
+			return nil
+		}
+		s.pr = pr
+	}
+	return s.pr
+}
+
+// attempt calls f until it succeeds, at most transactionAttempts times.
+func (t *Transaction) attempt(f func() error) error {
+	var err error
+	backoff := transactionBackoff
+	for i := 0; i < transactionAttempts; i++ {
+		if i > 0 {
+			t.sleep(backoff)
+			backoff *= 2
+		}
+		if err = f(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Commit applies the changes of the transaction. If a change fails, the
+// applied ones are rolled back and the error of the change is returned, along
+// with the errors of the rollbacks that failed.
+func (t *Transaction) Commit() error {
+	state := &transactionState{t: t}
+	var mutations []*Mutation
+	for _, mutation := range t.mutations {
+		if m := mutation(state); m != nil {
+			mutations = append(mutations, m)
+		}
+	}
+	if len(state.fetchErrs) > 0 {
+		return fmt.Errorf("failed to get the state of %s/%s#%d: %w", t.org, t.repo, t.number, utilerrors.NewAggregate(state.fetchErrs))
+	}
+	for _, comment := range t.comments {
+		comment := comment
+		mutations = append(mutations, &Mutation{
+			Description: "comment",
+			Apply:       func() error { return t.client.CreateComment(t.org, t.repo, t.number, comment) },
+		})
+	}
+
+	for i, m := range mutations {
+		err := t.attempt(m.Apply)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("failed to %s on %s/%s#%d: %w", m.Description, t.org, t.repo, t.number, err)
+		errs := []error{err}
+		for j := i - 1; j >= 0; j-- {
+			applied := mutations[j]
+			if applied.Rollback == nil {
+				errs = append(errs, fmt.Errorf("can't roll back %s", applied.Description))
+				continue
+			}
+			if err := t.attempt(applied.Rollback); err != nil {
+				errs = append(errs, fmt.Errorf("failed to roll back %s: %w", applied.Description, err))
+				continue
+			}
+			t.logger.Infof("Rolled back %s.", applied.Description)
+		}
+		return utilerrors.NewAggregate(errs)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+// fakeTransactionClient records the calls changing the issue and fails the
+// ones listed in failures as many times as given.
+type fakeTransactionClient struct {
+	labels    []string
+	assignees []string
+	reviewers []string
+	calls     []string
+	failures  map[string]int
+}
+
+func (f *fakeTransactionClient) call(call string) error {
+	if f.failures[call] > 0 {
+		f.failures[call]--
+		return errors.New("injected failure")
+	}
+	f.calls = append(f.calls, call)
+	return nil
+}
+
+func (f *fakeTransactionClient) AddLabel(_, _ string, _ int, label string) error {
+	return f.call("AddLabel " + label)
+}
+
+func (f *fakeTransactionClient) RemoveLabel(_, _ string, _ int, label string) error {
+	return f.call("RemoveLabel " + label)
+}
+
+func (f *fakeTransactionClient) GetIssueLabels(_, _ string, _ int) ([]github.Label, error) {
+	var labels []github.Label
+	for _, label := range f.labels {
+		labels = append(labels, github.Label{Name: label})
+	}
+	return labels, nil
+}
+
+func (f *fakeTransactionClient) AssignIssue(_, _ string, _ int, logins []string) error {
+	return f.call(fmt.Sprintf("AssignIssue %v", logins))
+}
+
+func (f *fakeTransactionClient) UnassignIssue(_, _ string, _ int, logins []string) error {
+	return f.call(fmt.Sprintf("UnassignIssue %v", logins))
+}
+
+func (f *fakeTransactionClient) GetIssue(_, _ string, _ int) (*github.Issue, error) {
+	issue := &github.Issue{}
+	for _, login := range f.assignees {
+		issue.Assignees = append(issue.Assignees, github.User{Login: login})
+	}
+	return issue, nil
+}
+
+func (f *fakeTransactionClient) RequestReview(_, _ string, _ int, logins []string) error {
+	return f.call(fmt.Sprintf("RequestReview %v", logins))
+}
+
+func (f *fakeTransactionClient) UnrequestReview(_, _ string, _ int, logins []string) error {
+	return f.call(fmt.Sprintf("UnrequestReview %v", logins))
+}
+
+func (f *fakeTransactionClient) GetPullRequest(_, _ string, _ int) (*github.PullRequest, error) {
+	pr := &github.PullRequest{}
+	for _, login := range f.reviewers {
+		pr.RequestedReviewers = append(pr.RequestedReviewers, github.User{Login: login})
+	}
+	return pr, nil
+}
+
+func (f *fakeTransactionClient) CreateComment(_, _ string, _ int, comment string) error {
+	return f.call("CreateComment " + comment)
+}
+
+func TestTransaction(t *testing.T) {
+	testCases := []struct {
+		name      string
+		labels    []string
+		assignees []string
+		reviewers []string
+		failures  map[string]int
+		build     func(*Transaction)
+
+		expectedCalls []string
+		expectedErr   bool
+	}{
+		{
+			name: "all changes are applied in order, comments last",
+			build: func(tx *Transaction) {
+				tx.Comment("done").AddLabel("lgtm").Assign("alice").RequestReview("bob")
+			},
+			expectedCalls: []string{"AddLabel lgtm", "AssignIssue [alice]", "RequestReview [bob]", "CreateComment done"},
+		},
+		{
+			name:      "changes that are no-ops are skipped",
+			labels:    []string{"lgtm"},
+			assignees: []string{"alice"},
+			reviewers: []string{"bob"},
+			build: func(tx *Transaction) {
+				tx.AddLabel("lgtm").RemoveLabel("approved").Assign("Alice", "carol").Unassign("dave").RequestReview("bob")
+			},
+			expectedCalls: []string{"AssignIssue [carol]"},
+		},
+		{
+			name:     "transient failures are retried",
+			failures: map[string]int{"AssignIssue [alice]": 2},
+			build: func(tx *Transaction) {
+				tx.AddLabel("lgtm").Assign("alice")
+			},
+			expectedCalls: []string{"AddLabel lgtm", "AssignIssue [alice]"},
+		},
+		{
+			name:     "applied changes are rolled back in reverse order on failure",
+			labels:   []string{"needs-rebase"},
+			failures: map[string]int{"RequestReview [bob]": transactionAttempts},
+			build: func(tx *Transaction) {
+				tx.AddLabel("lgtm").RemoveLabel("needs-rebase").Assign("alice").RequestReview("bob").Comment("done")
+			},
+			expectedCalls: []string{
+				"AddLabel lgtm", "RemoveLabel needs-rebase", "AssignIssue [alice]",
+				"UnassignIssue [alice]", "AddLabel needs-rebase", "RemoveLabel lgtm",
+			},
+			expectedErr: true,
+		},
+		{
+			name:     "failed rollbacks don't stop the other rollbacks",
+			failures: map[string]int{"AssignIssue [alice]": transactionAttempts, "RemoveLabel lgtm": transactionAttempts},
+			build: func(tx *Transaction) {
+				tx.AddLabel("lgtm").AddLabel("approved").Assign("alice")
+			},
+			expectedCalls: []string{"AddLabel lgtm", "AddLabel approved", "RemoveLabel approved"},
+			expectedErr:   true,
+		},
+		{
+			name:     "custom changes are rolled back",
+			failures: map[string]int{"AddLabel lgtm": transactionAttempts},
+			build: func(tx *Transaction) {
+				tx.Do(Mutation{
+					Description: "custom",
+					Apply:       func() error { return tx.client.(*fakeTransactionClient).call("Apply") },
+					Rollback:    func() error { return tx.client.(*fakeTransactionClient).call("Rollback") },
+				}).AddLabel("lgtm")
+			},
+			expectedCalls: []string{"Apply", "Rollback"},
+			expectedErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeTransactionClient{labels: tc.labels, assignees: tc.assignees, reviewers: tc.reviewers, failures: tc.failures}
+			tx := newTransaction(client, logrus.WithField("test", tc.name), "org", "repo", 1)
+			tx.sleep = func(time.Duration) {}
+			tc.build(tx)
+
+			err := tx.Commit()
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error: %t, got: %v", tc.expectedErr, err)
+			}
+			if diff := cmp.Diff(tc.expectedCalls, client.calls); diff != "" {
+				t.Errorf("calls differ from expected (-expected +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// batcher collects the presubmits requested for a PR with `/test <job>`
+// comments, so that the comments posted within a batch window only result in
+// a single pass creating the jobs.
+//
+// Batches are only held in the memory of the hook replica that received the
+// comments: comments delivered to different replicas are batched separately,
+// and pending batches are run when hook shuts down.
+var batcher = newTestBatcher(time.AfterFunc)
+
+type testBatcher struct {
+	lock    sync.Mutex
+	batches map[string]*testBatch
+	// pending tracks the batches that have not finished running.
+	pending sync.WaitGroup
+	// afterFunc schedules the run of a batch, it is time.AfterFunc outside of tests.
+	afterFunc func(time.Duration, func()) *time.Timer
+}
+
+// testBatch holds the presubmits requested for a commit of a PR.
+type testBatch struct {
+	client     Client
+	trigger    plugins.Trigger
+	pr         *github.PullRequest
+	baseSHA    string
+	eventGUID  string
+	window     time.Duration
+	jobs       []config.Presubmit
+	jobNames   sets.String
+	requesters sets.String
+	// requestedBy is the first user that requested each job.
+	requestedBy map[string]string
+	timer       *time.Timer
+}
+
+func newTestBatcher(afterFunc func(time.Duration, func()) *time.Timer) *testBatcher {
+	return &testBatcher{batches: map[string]*testBatch{}, afterFunc: afterFunc}
+}
+
+// add adds the requested presubmits to the batch of the PR's head commit. The
+// first request for a commit opens its batch, which runs once the batch window
+// of the trigger config has passed.
+func (b *testBatcher) add(c Client, trigger plugins.Trigger, pr *github.PullRequest, baseSHA string, requestedJobs []config.Presubmit, eventGUID, requester string) {
+	window := trigger.BatchWindowDuration
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	key := fmt.Sprintf("%s/%s#%d@%s", pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number, pr.Head.SHA)
+	batch, ok := b.batches[key]
+	if !ok {
+		batch = &testBatch{
+			client:      c,
+			trigger:     trigger,
+			pr:          pr,
+			baseSHA:     baseSHA,
+			eventGUID:   eventGUID,
+			window:      window,
+			jobNames:    sets.NewString(),
+			requesters:  sets.NewString(),
+			requestedBy: map[string]string{},
+		}
+		b.batches[key] = batch
+		b.pending.Add(1)
+		batch.timer = b.afterFunc(window, func() { b.run(key) })
+	}
+	batch.requesters.Insert(requester)
+	for _, job := range requestedJobs {
+		if batch.jobNames.Has(job.Name) {
+			continue
+		}
+		batch.jobNames.Insert(job.Name)
+		batch.jobs = append(batch.jobs, job)
+		batch.requestedBy[job.Name] = requester
+	}
+	c.Logger.WithField("batch", key).Infof("Batched %d requested jobs, starting them in %s.", len(requestedJobs), window)
+}
+
+// run runs a batch once its window has passed, unless it was flushed already.
+func (b *testBatcher) run(key string) {
+	b.lock.Lock()
+	batch := b.batches[key]
+	delete(b.batches, key)
+	b.lock.Unlock()
+	if batch == nil {
+		return
+	}
+	defer b.pending.Done()
+	batch.run(key)
+}
+
+// flush runs all pending batches right away and waits until every batch,
+// including those whose window has just passed, has finished running.
+func (b *testBatcher) flush() {
+	b.lock.Lock()
+	batches := b.batches
+	b.batches = map[string]*testBatch{}
+	b.lock.Unlock()
+	for key, batch := range batches {
+		if batch.timer != nil {
+			batch.timer.Stop()
+		}
+		batch.run(key)
+		b.pending.Done()
+	}
+	b.pending.Wait()
+}
+
+// run creates the jobs of a batch and comments with the list of jobs.
+func (batch *testBatch) run(key string) {
+	log := batch.client.Logger.WithField("batch", key)
+
+	toRun, running, err := withoutRunningJobs(batch.client, batch.pr, batch.jobs)
+	if err != nil {
+		log.WithError(err).Warn("Failed to list running jobs, starting all the requested jobs.")
+		toRun = batch.jobs
+	}
+	byRequester := map[string][]config.Presubmit{}
+	for _, job := range toRun {
+		byRequester[batch.requestedBy[job.Name]] = append(byRequester[batch.requestedBy[job.Name]], job)
+	}
+	var started, overQuota []config.Presubmit
+	for requester, jobs := range byRequester {
+		// The quota was checked for each comment on its own, so check it again
+		// for all the jobs the requester asked for within the window.
+		exceeded, err := exceedsExternalContributorQuota(batch.client, batch.trigger, batch.pr, requester, len(jobs))
+		if err != nil {
+			log.WithError(err).WithField("requester", requester).Error("Failed to check the external contributor quota, not starting the batched jobs.")
+			continue
+		}
+		if exceeded {
+			overQuota = append(overQuota, jobs...)
+			continue
+		}
+		if err := runRequested(batch.client, batch.pr, batch.baseSHA, jobs, batch.eventGUID, nil, requester); err != nil {
+			log.WithError(err).Error("Failed to start the batched jobs.")
+		}
+		started = append(started, jobs...)
+	}
+
+	pr := batch.pr
+	resp := batchMessage(batch.trigger, pr.Head.SHA, batch.window, started, running, overQuota)
+	if err := batch.client.GitHubClient.CreateComment(pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number, plugins.FormatSimpleResponse(strings.Join(batch.requesters.List(), ", @"), resp)); err != nil {
+		log.WithError(err).Error("Failed to comment with the batched jobs.")
+	}
+}
+
+// withoutRunningJobs splits the presubmits between those to run and those that
+// are already running against the head commit of the PR.
+func withoutRunningJobs(c Client, pr *github.PullRequest, presubmits []config.Presubmit) ([]config.Presubmit, []config.Presubmit, error) {
+	selector, err := labelSelectorForPR(pr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to construct label selector: %w", err)
+	}
+	jobs, err := c.ProwJobClient.List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list prowjobs for pr: %w", err)
+	}
+	runningNames := sets.NewString()
+	for _, job := range jobs.Items {
+		if job.Complete() || job.Spec.Refs == nil || len(job.Spec.Refs.Pulls) == 0 || job.Spec.Refs.Pulls[0].SHA != pr.Head.SHA {
+			continue
+		}
+		runningNames.Insert(job.Spec.Job)
+	}
+
+	var toRun, running []config.Presubmit
+	for _, presubmit := range presubmits {
+		if runningNames.Has(presubmit.Name) {
+			running = append(running, presubmit)
+		} else {
+			toRun = append(toRun, presubmit)
+		}
+	}
+	return toRun, running, nil
+}
+
+func batchMessage(trigger plugins.Trigger, sha string, window time.Duration, started, running, overQuota []config.Presubmit) string {
+	listBuilder := func(presubmits []config.Presubmit) string {
+		names := sets.NewString()
+		for _, presubmit := range presubmits {
+			names.Insert(presubmit.Name)
+		}
+		var list strings.Builder
+		for _, name := range names.List() {
+			list.WriteString(fmt.Sprintf("\n* `%s`", name))
+		}
+		return list.String()
+	}
+
+	var resp string
+	if len(started) > 0 {
+		resp += fmt.Sprintf("The following jobs requested within %s were started for %s:%s\n\n", window, sha, listBuilder(started))
+	}
+	if len(running) > 0 {
+		resp += fmt.Sprintf("The following jobs were not started again because they are already running for %s:%s\n\n", sha, listBuilder(running))
+	}
+	if len(overQuota) > 0 {
+		resp += fmt.Sprintf("The following jobs were not started for %s:%s\n\n%s\n\n", sha, listBuilder(overQuota), quotaExceededMessage(trigger))
+	}
+	return resp
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/client/clientset/versioned/fake"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/pjutil"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestTestBatcher(t *testing.T) {
+	pr := &github.PullRequest{
+		Number: 1,
+		Head:   github.PullRequestBranch{SHA: "cafe"},
+		Base: github.PullRequestBranch{
+			Ref: "master",
+			Repo: github.Repo{
+				Owner: github.User{Login: "org"},
+				Name:  "repo",
+			},
+		},
+	}
+	presubmit := func(name string) config.Presubmit {
+		return config.Presubmit{JobBase: config.JobBase{Name: name}, Reporter: config.Reporter{Context: "pull-" + name}}
+	}
+
+	running := pjutil.NewPresubmit(*pr, "base", presubmit("jab"), "guid", nil)
+	fakeProwJobClient := fake.NewSimpleClientset(&running)
+	fc := fakegithub.NewFakeClient()
+	c := Client{
+		GitHubClient:  fc,
+		ProwJobClient: fakeProwJobClient.ProwV1().ProwJobs(""),
+		Logger:        logrus.WithField("plugin", PluginName),
+	}
+
+	var scheduled []func()
+	b := newTestBatcher(func(d time.Duration, f func()) *time.Timer {
+		if d != time.Minute {
+			t.Errorf("expected the batch to run after %s, got %s", time.Minute, d)
+		}
+		scheduled = append(scheduled, f)
+		return nil
+	})
+	b.add(c, plugins.Trigger{BatchWindowDuration: time.Minute}, pr, "base", []config.Presubmit{presubmit("job"), presubmit("jib")}, "guid-1", "alice")
+	b.add(c, plugins.Trigger{BatchWindowDuration: time.Minute}, pr, "base", []config.Presubmit{presubmit("job"), presubmit("jab")}, "guid-2", "bob")
+	if len(scheduled) != 1 {
+		t.Fatalf("expected a single batch to be scheduled, got %d", len(scheduled))
+	}
+	scheduled[0]()
+
+	jobs, err := fakeProwJobClient.ProwV1().ProwJobs("").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list prowjobs: %v", err)
+	}
+	started := map[string]int{}
+	for _, job := range jobs.Items {
+		started[job.Spec.Job]++
+	}
+	for name, expected := range map[string]int{"job": 1, "jib": 1, "jab": 1} {
+		if started[name] != expected {
+			t.Errorf("expected %d prowjobs for %s, got %d", expected, name, started[name])
+		}
+	}
+
+	comments := fc.IssueComments[1]
+	if len(comments) != 1 {
+		t.Fatalf("expected a single comment, got %d", len(comments))
+	}
+	for _, expected := range []string{
+		"@alice, @bob",
+		"were started for cafe:\n* `jib`\n* `job`\n\n",
+		"already running for cafe:\n* `jab`\n\n",
+	} {
+		if !strings.Contains(comments[0].Body, expected) {
+			t.Errorf("expected the comment to contain %q, got %q", expected, comments[0].Body)
+		}
+	}
+
+	if len(b.batches) != 0 {
+		t.Errorf("expected no batch to be left, got %v", sets.StringKeySet(b.batches).List())
+	}
+}
+
+func TestTestBatcherFlush(t *testing.T) {
+	pr := &github.PullRequest{
+		Number: 1,
+		Head:   github.PullRequestBranch{SHA: "cafe"},
+		Base: github.PullRequestBranch{
+			Ref: "master",
+			Repo: github.Repo{
+				Owner: github.User{Login: "org"},
+				Name:  "repo",
+			},
+		},
+	}
+	fakeProwJobClient := fake.NewSimpleClientset()
+	fc := fakegithub.NewFakeClient()
+	c := Client{
+		GitHubClient:  fc,
+		ProwJobClient: fakeProwJobClient.ProwV1().ProwJobs(""),
+		Logger:        logrus.WithField("plugin", PluginName),
+	}
+
+	var scheduled []func()
+	b := newTestBatcher(func(d time.Duration, f func()) *time.Timer {
+		scheduled = append(scheduled, f)
+		return nil
+	})
+	b.add(c, plugins.Trigger{BatchWindowDuration: time.Minute}, pr, "base", []config.Presubmit{{JobBase: config.JobBase{Name: "job"}, Reporter: config.Reporter{Context: "pull-job"}}}, "guid", "alice")
+	b.flush()
+
+	jobs, err := fakeProwJobClient.ProwV1().ProwJobs("").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list prowjobs: %v", err)
+	}
+	if len(jobs.Items) != 1 {
+		t.Errorf("expected the flushed batch to start 1 prowjob, got %d", len(jobs.Items))
+	}
+	if len(b.batches) != 0 {
+		t.Errorf("expected no batch to be left, got %v", sets.StringKeySet(b.batches).List())
+	}
+
+	// The flushed batch is not run again once its window has passed.
+	scheduled[0]()
+	if comments := fc.IssueComments[1]; len(comments) != 1 {
+		t.Errorf("expected a single comment, got %d", len(comments))
+	}
+}
+
+// TestTestBatcherExternalContributorQuota ensures that the jobs an external
+// contributor requests in several comments within the batch window count
+// towards their quota together.
+func TestTestBatcherExternalContributorQuota(t *testing.T) {
+	pr := &github.PullRequest{
+		Number: 1,
+		Head:   github.PullRequestBranch{SHA: "cafe"},
+		Base: github.PullRequestBranch{
+			Ref: "master",
+			Repo: github.Repo{
+				Owner: github.User{Login: "org"},
+				Name:  "repo",
+			},
+		},
+	}
+	presubmit := func(name string) config.Presubmit {
+		return config.Presubmit{JobBase: config.JobBase{Name: name}, Reporter: config.Reporter{Context: "pull-" + name}}
+	}
+
+	fakeProwJobClient := fake.NewSimpleClientset()
+	fc := fakegithub.NewFakeClient()
+	fc.OrgMembers = map[string][]string{"org": {"member"}}
+	c := Client{
+		GitHubClient:  fc,
+		ProwJobClient: fakeProwJobClient.ProwV1().ProwJobs(""),
+		Logger:        logrus.WithField("plugin", PluginName),
+	}
+	trigger := plugins.Trigger{BatchWindowDuration: time.Minute, ExternalContributorDailyJobQuota: 2}
+
+	var scheduled []func()
+	b := newTestBatcher(func(d time.Duration, f func()) *time.Timer {
+		scheduled = append(scheduled, f)
+		return nil
+	})
+	// Each comment stays within the quota on its own.
+	b.add(c, trigger, pr, "base", []config.Presubmit{presubmit("job"), presubmit("jib")}, "guid-1", "external")
+	b.add(c, trigger, pr, "base", []config.Presubmit{presubmit("jab")}, "guid-2", "external")
+	b.add(c, trigger, pr, "base", []config.Presubmit{presubmit("jub")}, "guid-3", "member")
+	if len(scheduled) != 1 {
+		t.Fatalf("expected a single batch to be scheduled, got %d", len(scheduled))
+	}
+	scheduled[0]()
+
+	jobs, err := fakeProwJobClient.ProwV1().ProwJobs("").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list prowjobs: %v", err)
+	}
+	started := sets.NewString()
+	for _, job := range jobs.Items {
+		started.Insert(job.Spec.Job)
+	}
+	if expected := sets.NewString("jub"); !started.Equal(expected) {
+		t.Errorf("expected only %v to be started, got %v", expected.List(), started.List())
+	}
+
+	comments := fc.IssueComments[1]
+	if len(comments) != 1 {
+		t.Fatalf("expected a single comment, got %d", len(comments))
+	}
+	for _, expected := range []string{
+		"were started for cafe:\n* `jub`\n\n",
+		"were not started for cafe:\n* `jab`\n* `jib`\n* `job`\n\n",
+		quotaExceededMessage(trigger),
+	} {
+		if !strings.Contains(comments[0].Body, expected) {
+			t.Errorf("expected the comment to contain %q, got %q", expected, comments[0].Body)
+		}
+	}
+}
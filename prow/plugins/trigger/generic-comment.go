@@ -133,6 +133,18 @@ func handleGenericComment(c Client, trigger plugins.Trigger, gc github.GenericCo
 	if needsHelp, note := pjutil.ShouldRespondWithHelp(gc.Body, len(toTest)); needsHelp {
 		return addHelpComment(c.GitHubClient, gc.Body, org, repo, pr.Base.Ref, pr.Number, presubmits, gc.HTMLURL, commentAuthor, note, c.Logger)
 	}
+	if exceeded, err := exceedsExternalContributorQuota(c, trigger, pr, commentAuthor, len(toTest)); err != nil {
+		return err
+	} else if exceeded {
+		resp := quotaExceededMessage(trigger)
+		c.Logger.Infof("Commenting \"%s\".", resp)
+		return c.GitHubClient.CreateComment(org, repo, number, plugins.FormatResponseRaw(gc.Body, gc.HTMLURL, gc.User.Login, resp))
+	}
+	// `/test <job>` comments posted within the batch window are run together
+	if trigger.BatchWindowDuration > 0 && isJobTestCommand(gc.Body) {
+		batcher.add(c, trigger, pr, baseSHA, toTest, gc.GUID, commentAuthor)
+		return nil
+	}
 	// we want to be able to track re-tests separately from the general body of tests
 	additionalLabels := map[string]string{}
 	if pjutil.RetestRe.MatchString(gc.Body) || pjutil.RetestRequiredRe.MatchString(gc.Body) {
@@ -167,7 +179,17 @@ func handleGenericComment(c Client, trigger plugins.Trigger, gc github.GenericCo
 			}
 		}
 	}
-	return RunRequestedWithLabels(c, pr, baseSHA, toTest, gc.GUID, additionalLabels)
+	return runRequested(c, pr, baseSHA, toTest, gc.GUID, additionalLabels, commentAuthor)
+}
+
+// isJobTestCommand determines whether the comment only requests specific jobs,
+// e.g. `/test foo`, as opposed to `/retest`, `/test all` or `/ok-to-test`.
+func isJobTestCommand(body string) bool {
+	return pjutil.TestWithAnyTargetRe.MatchString(body) &&
+		!pjutil.TestAllRe.MatchString(body) &&
+		!pjutil.RetestRe.MatchString(body) &&
+		!pjutil.RetestRequiredRe.MatchString(body) &&
+		!pjutil.OkToTestRe.MatchString(body)
 }
 
 func HonorOkToTest(trigger plugins.Trigger) bool {
@@ -232,11 +254,19 @@ func getContexts(combinedStatus *github.CombinedStatus) (sets.String, sets.Strin
 
 func addHelpComment(githubClient githubClient, body, org, repo, branch string, number int, presubmits []config.Presubmit, HTMLURL, user, note string, logger *logrus.Entry) error {
 	changes := config.NewGitHubDeferredChangedFilesProvider(githubClient, org, repo, number)
-	testAllNames, optionalJobsCommands, requiredJobsCommands, err := pjutil.AvailablePresubmits(changes, org, repo, branch, presubmits, logger)
-	if err != nil {
-		return err
+	var resp string
+	if pjutil.TestHelpRe.MatchString(body) {
+		alwaysRun, runIfChanged, optional, err := pjutil.ApplicablePresubmits(changes, branch, presubmits)
+		if err != nil {
+			return err
+		}
+		resp = pjutil.JobListMessage(org, repo, branch, alwaysRun, runIfChanged, optional)
+	} else {
+		testAllNames, optionalJobsCommands, requiredJobsCommands, err := pjutil.AvailablePresubmits(changes, org, repo, branch, presubmits, logger)
+		if err != nil {
+			return err
+		}
+		resp = pjutil.HelpMessage(org, repo, branch, note, testAllNames, optionalJobsCommands, requiredJobsCommands)
 	}
-
-	resp := pjutil.HelpMessage(org, repo, branch, note, testAllNames, optionalJobsCommands, requiredJobsCommands)
 	return githubClient.CreateComment(org, repo, number, plugins.FormatResponseRaw(body, HTMLURL, user, resp))
 }
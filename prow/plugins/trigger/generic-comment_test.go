@@ -67,6 +67,8 @@ type testcase struct {
 func TestHandleGenericComment(t *testing.T) {
 	helpComment := "The following commands are available to trigger required jobs:\n* `/test jib`\n* `/test job`\n\n"
 	helpTestAllWithJobsComment := fmt.Sprintf("Use `/test all` to run the following jobs that were automatically triggered:%s\n\n", "\n* `job`")
+	listTestAllComment := "Use `/test all` to run all jobs that always run or run because of the files changed."
+	listOptionalComment := "The following optional jobs only run when requested:\n* `jib`: `/test jib`\n\n"
 	var testcases = []testcase{
 		{
 			name: "Not a PR.",
@@ -994,7 +996,54 @@ func TestHandleGenericComment(t *testing.T) {
 					},
 				},
 			},
-			AddedComment: helpComment + "Use `/test all` to run all jobs.",
+			AddedComment: "The following jobs always run:\n* `jib`: `/test jib`\n* `job`: `/test job`\n\n" + listTestAllComment,
+		},
+		{
+			name:   `help command "/test list" lists available presubmits`,
+			Author: "trusted-member",
+			Body:   "/test list",
+			State:  "open",
+			IsPR:   true,
+			AddedComment: "The following jobs always run:\n* `job`: `/test job`\n\n" +
+				listOptionalComment + listTestAllComment,
+		},
+		{
+			name:   `help command "/test ?" lists run_if_changed presubmits matching the changes`,
+			Author: "trusted-member",
+			Body:   "/test ?",
+			State:  "open",
+			IsPR:   true,
+			Presubmits: map[string][]config.Presubmit{
+				"org/repo": {
+					{
+						JobBase: config.JobBase{
+							Name: "job",
+						},
+						RegexpChangeMatcher: config.RegexpChangeMatcher{
+							RunIfChanged: "CHANGED",
+						},
+						Reporter: config.Reporter{
+							Context: "pull-job",
+						},
+						Trigger:      `(?m)^/test (?:.*? )?job(?: .*?)?$`,
+						RerunCommand: `/test job`,
+					},
+					{
+						JobBase: config.JobBase{
+							Name: "jib",
+						},
+						RegexpChangeMatcher: config.RegexpChangeMatcher{
+							RunIfChanged: "UNCHANGED",
+						},
+						Reporter: config.Reporter{
+							Context: "pull-jib",
+						},
+						Trigger:      `(?m)^/test (?:.*? )?jib(?: .*?)?$`,
+						RerunCommand: `/test jib`,
+					},
+				},
+			},
+			AddedComment: "@trusted-member: The following jobs run because of the files changed:\n* `job`: `/test job`\n\n" + listTestAllComment,
 		},
 		{
 			name:   `help command "/test ?" uses unique RerunCommand field of presubmits`,
@@ -1038,9 +1087,10 @@ func TestHandleGenericComment(t *testing.T) {
 					},
 				},
 			},
-			AddedComment: "@trusted-member: The following commands are available to trigger required jobs:\n" +
-				"* `/command_foo`\n* `/rerun_command`\n\n" +
-				"Use `/test all` to run all jobs.",
+			AddedComment: "@trusted-member: The following jobs always run:\n" +
+				"* `jib`: `/command_foo`\n* `jub`: `/rerun_command`\n\n" +
+				"The following optional jobs only run when requested:\n* `jab`: `/rerun_command`\n\n" +
+				listTestAllComment,
 		},
 		{
 			name:         "/test with no target results in a help message",
@@ -1084,7 +1134,7 @@ func TestHandleGenericComment(t *testing.T) {
 			AddedComment: pjutil.TargetNotFoundNote + helpComment + helpTestAllWithJobsComment,
 		},
 		{
-			name:   "help comment should list only eligible jobs under '/test all'",
+			name:   "help comment should list only eligible jobs as always run",
 			Author: "trusted-member",
 			Body:   "/test ?",
 			State:  "open",
@@ -1114,7 +1164,7 @@ func TestHandleGenericComment(t *testing.T) {
 					},
 				},
 			},
-			AddedComment: helpComment + helpTestAllWithJobsComment,
+			AddedComment: "The following jobs always run:\n* `job`: `/test job`\n\n" + listOptionalComment + listTestAllComment,
 		},
 		{
 			name:   "when no jobs can be run with /test all, respond accordingly",
@@ -1196,10 +1246,10 @@ func TestHandleGenericComment(t *testing.T) {
 					},
 				},
 			},
-			AddedComment: helpComment + helpTestAllWithJobsComment,
+			AddedComment: "The following jobs always run:\n* `job`: `/test job`\n\n" + listOptionalComment + listTestAllComment,
 		},
 		{
-			name:   `help command "/test ?" differs between optional and required jobs`,
+			name:   `help command "/test ?" lists optional jobs that always run with the other jobs that always run`,
 			Author: "trusted-member",
 			Body:   "/test ?",
 			State:  "open",
@@ -1242,9 +1292,7 @@ func TestHandleGenericComment(t *testing.T) {
 					},
 				},
 			},
-			AddedComment: helpComment +
-				"The following commands are available to trigger optional jobs:\n* `/test jub`\n\n" +
-				"Use `/test all` to run all jobs.",
+			AddedComment: "The following jobs always run:\n* `jib`: `/test jib`\n* `job`: `/test job`\n* `jub`: `/test jub`\n\n" + listTestAllComment,
 		},
 	}
 	for _, tc := range testcases {
@@ -84,7 +84,7 @@ func handlePR(c Client, trigger plugins.Trigger, pr github.PullRequestEvent) err
 				return draftMsg(c.GitHubClient, pr.PullRequest)
 			}
 			c.Logger.Info("Starting all jobs for new PR.")
-			return buildAllButDrafts(c, &pr.PullRequest, pr.GUID, baseSHA, presubmits)
+			return buildAllButDrafts(c, &pr.PullRequest, pr.GUID, baseSHA, presubmits, pr.Sender.Login)
 		}
 		c.Logger.Infof("Welcome message to PR author %q.", author)
 		if err := welcomeMsg(c.GitHubClient, trigger, pr.PullRequest); err != nil {
@@ -128,7 +128,7 @@ func handlePR(c Client, trigger plugins.Trigger, pr github.PullRequestEvent) err
 				return fmt.Errorf("could not validate PR: %s", err)
 			} else if !trusted {
 				c.Logger.Info("Starting all jobs for untrusted PR with LGTM.")
-				return buildAllButDrafts(c, &pr.PullRequest, pr.GUID, baseSHA, presubmits)
+				return buildAllButDrafts(c, &pr.PullRequest, pr.GUID, baseSHA, presubmits, pr.Sender.Login)
 			}
 		}
 		if pr.Label.Name == labels.OkToTest {
@@ -143,7 +143,7 @@ func handlePR(c Client, trigger plugins.Trigger, pr github.PullRequestEvent) err
 				c.Logger.Debug("Label added by the bot, skipping.")
 				return nil
 			}
-			return buildAllButDrafts(c, &pr.PullRequest, pr.GUID, baseSHA, presubmits)
+			return buildAllButDrafts(c, &pr.PullRequest, pr.GUID, baseSHA, presubmits, pr.Sender.Login)
 		}
 	case github.PullRequestActionClosed:
 		if err := abortAllJobs(c, &pr.PullRequest); err != nil {
@@ -236,8 +236,19 @@ func buildAllIfTrusted(c Client, trigger plugins.Trigger, pr github.PullRequestE
 				return err
 			}
 		}
+		if trigger.ExternalContributorDailyJobQuota > 0 && !pr.PullRequest.Draft {
+			toTest, err := presubmitsToBuild(c, &pr.PullRequest, presubmits)
+			if err != nil {
+				return err
+			}
+			if exceeded, err := exceedsExternalContributorQuota(c, trigger, &pr.PullRequest, pr.Sender.Login, len(toTest)); err != nil {
+				return err
+			} else if exceeded {
+				return c.GitHubClient.CreateComment(org, repo, num, plugins.FormatSimpleResponse(pr.Sender.Login, quotaExceededMessage(trigger)))
+			}
+		}
 		c.Logger.Info("Starting all jobs for updated PR.")
-		return buildAllButDrafts(c, &pr.PullRequest, pr.GUID, baseSHA, presubmits)
+		return buildAllButDrafts(c, &pr.PullRequest, pr.GUID, baseSHA, presubmits, pr.Sender.Login)
 	}
 	return nil
 }
@@ -339,21 +350,26 @@ func TrustedPullRequest(tprc trustedPullRequestClient, trigger plugins.Trigger,
 }
 
 // buildAllButDrafts ensures that all builds that should run and will be required are built, but skips draft PRs
-func buildAllButDrafts(c Client, pr *github.PullRequest, eventGUID string, baseSHA string, presubmits []config.Presubmit) error {
+func buildAllButDrafts(c Client, pr *github.PullRequest, eventGUID string, baseSHA string, presubmits []config.Presubmit, triggeredBy string) error {
 	if pr.Draft {
 		c.Logger.Info("Skipping all jobs for draft PR.")
 		return nil
 	}
-	return buildAll(c, pr, eventGUID, baseSHA, presubmits)
+	return buildAll(c, pr, eventGUID, baseSHA, presubmits, triggeredBy)
 }
 
 // buildAll ensures that all builds that should run and will be required are built
-func buildAll(c Client, pr *github.PullRequest, eventGUID string, baseSHA string, presubmits []config.Presubmit) error {
-	org, repo, number, branch := pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number, pr.Base.Ref
-	changes := config.NewGitHubDeferredChangedFilesProvider(c.GitHubClient, org, repo, number)
-	toTest, err := pjutil.FilterPresubmits(pjutil.NewTestAllFilter(), changes, branch, presubmits, c.Logger)
+func buildAll(c Client, pr *github.PullRequest, eventGUID string, baseSHA string, presubmits []config.Presubmit, triggeredBy string) error {
+	toTest, err := presubmitsToBuild(c, pr, presubmits)
 	if err != nil {
 		return err
 	}
-	return RunRequested(c, pr, baseSHA, toTest, eventGUID)
+	return runRequested(c, pr, baseSHA, toTest, eventGUID, nil, triggeredBy)
+}
+
+// presubmitsToBuild returns the presubmits that buildAll starts for the PR.
+func presubmitsToBuild(c Client, pr *github.PullRequest, presubmits []config.Presubmit) ([]config.Presubmit, error) {
+	org, repo, number, branch := pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number, pr.Base.Ref
+	changes := config.NewGitHubDeferredChangedFilesProvider(c.GitHubClient, org, repo, number)
+	return pjutil.FilterPresubmits(pjutil.NewTestAllFilter(), changes, branch, presubmits, c.Logger)
 }
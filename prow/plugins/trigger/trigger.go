@@ -34,6 +34,7 @@ import (
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/git/v2"
 	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/kube"
 	"k8s.io/test-infra/prow/pjutil"
 	"k8s.io/test-infra/prow/pluginhelp"
 	"k8s.io/test-infra/prow/plugins"
@@ -75,6 +76,7 @@ func init() {
 	plugins.RegisterGenericCommentHandler(PluginName, handleGenericCommentEvent, helpProvider)
 	plugins.RegisterPullRequestHandler(PluginName, handlePullRequest, helpProvider)
 	plugins.RegisterPushEventHandler(PluginName, handlePush, helpProvider)
+	plugins.RegisterShutdownHandler(PluginName, batcher.flush)
 }
 
 func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
@@ -86,6 +88,9 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 			org = trigger.TrustedOrg
 		}
 		configInfo[repo.String()] = fmt.Sprintf("The trusted GitHub organization for this repository is %q.", org)
+		if trigger.BatchWindowDuration > 0 {
+			configInfo[repo.String()] += fmt.Sprintf(" Jobs requested with '/test <job>' within %s are started together.", trigger.BatchWindowDuration)
+		}
 	}
 	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
 		Triggers: []plugins.Trigger{
@@ -132,11 +137,11 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 		Examples:    []string{"/retest"},
 	})
 	pluginHelp.AddCommand(pluginhelp.Command{
-		Usage:       "/test ?",
-		Description: "List available test job(s) for a trusted PR.",
+		Usage:       "/test [?|list]",
+		Description: "List the test job(s) that apply to the changes and base branch of a trusted PR, grouped into jobs that always run, jobs that run because of the files changed and optional jobs, with the command that triggers each of them.",
 		Featured:    true,
 		WhoCanUse:   "Anyone can trigger this command on a trusted PR.",
-		Examples:    []string{"/test ?"},
+		Examples:    []string{"/test ?", "/test list"},
 	})
 	return pluginHelp, nil
 }
@@ -306,19 +311,24 @@ func validateContextOverlap(toRun, toSkip []config.Presubmit) error {
 
 // RunRequested executes the config.Presubmits that are requested
 func RunRequested(c Client, pr *github.PullRequest, baseSHA string, requestedJobs []config.Presubmit, eventGUID string) error {
-	return runRequested(c, pr, baseSHA, requestedJobs, eventGUID, nil)
+	return runRequested(c, pr, baseSHA, requestedJobs, eventGUID, nil, "")
 }
 
 // RunRequestedWithLabels executes the config.Presubmits that are requested with the additional labels
 func RunRequestedWithLabels(c Client, pr *github.PullRequest, baseSHA string, requestedJobs []config.Presubmit, eventGUID string, labels map[string]string) error {
-	return runRequested(c, pr, baseSHA, requestedJobs, eventGUID, labels)
+	return runRequested(c, pr, baseSHA, requestedJobs, eventGUID, labels, "")
 }
 
-func runRequested(c Client, pr *github.PullRequest, baseSHA string, requestedJobs []config.Presubmit, eventGUID string, labels map[string]string, millisecondOverride ...time.Duration) error {
+// runRequested executes the config.Presubmits that are requested, recording
+// the user that triggered them if known.
+func runRequested(c Client, pr *github.PullRequest, baseSHA string, requestedJobs []config.Presubmit, eventGUID string, labels map[string]string, triggeredBy string, millisecondOverride ...time.Duration) error {
 	var errors []error
 	for _, job := range requestedJobs {
 		c.Logger.Infof("Starting %s build.", job.Name)
 		pj := pjutil.NewPresubmit(*pr, baseSHA, job, eventGUID, labels)
+		if triggeredBy != "" {
+			pj.Annotations[kube.TriggeredByAnnotation] = triggeredBy
+		}
 		c.Logger.WithFields(pjutil.ProwJobFields(&pj)).Info("Creating a new prowjob.")
 		if err := createWithRetry(context.TODO(), c.ProwJobClient, &pj, millisecondOverride...); err != nil {
 			c.Logger.WithError(err).Error("Failed to create prowjob.")
@@ -328,6 +338,52 @@ func runRequested(c Client, pr *github.PullRequest, baseSHA string, requestedJob
 	return utilerrors.NewAggregate(errors)
 }
 
+// exceedsExternalContributorQuota determines whether starting the given number
+// of jobs on the PR would exceed the daily quota of jobs that users that are not
+// members of the org can trigger. Only the jobs the user triggered count towards
+// their quota, and jobs triggered by org members are never limited.
+func exceedsExternalContributorQuota(c Client, trigger plugins.Trigger, pr *github.PullRequest, user string, jobs int) (bool, error) {
+	if trigger.ExternalContributorDailyJobQuota == 0 || jobs == 0 {
+		return false, nil
+	}
+	org := pr.Base.Repo.Owner.Login
+	for _, trustedOrg := range sets.NewString(org, trigger.TrustedOrg).Delete("").List() {
+		member, err := c.GitHubClient.IsMember(trustedOrg, user)
+		if err != nil {
+			return false, fmt.Errorf("error in IsMember(%s): %w", trustedOrg, err)
+		}
+		if member {
+			return false, nil
+		}
+	}
+
+	selector, err := labelSelectorForPR(pr)
+	if err != nil {
+		return false, fmt.Errorf("failed to construct label selector: %w", err)
+	}
+	existing, err := c.ProwJobClient.List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return false, fmt.Errorf("failed to list prowjobs for pr: %w", err)
+	}
+	since := time.Now().Add(-24 * time.Hour)
+	var started int
+	for _, job := range existing.Items {
+		if job.Status.StartTime.After(since) && github.NormLogin(job.Annotations[kube.TriggeredByAnnotation]) == github.NormLogin(user) {
+			started++
+		}
+	}
+	if started+jobs > trigger.ExternalContributorDailyJobQuota {
+		c.Logger.Infof("Starting %d jobs would exceed the daily quota of %d jobs for external contributors, %d were already started.", jobs, trigger.ExternalContributorDailyJobQuota, started)
+		return true, nil
+	}
+	return false, nil
+}
+
+// quotaExceededMessage explains that an org member has to trigger the jobs.
+func quotaExceededMessage(trigger plugins.Trigger) string {
+	return fmt.Sprintf("Cannot start the requested jobs: users that are not members of the org can trigger at most %d jobs per day on this PR. An org member can start them with `/ok-to-test`.", trigger.ExternalContributorDailyJobQuota)
+}
+
 func getPresubmits(log *logrus.Entry, gc git.ClientFactory, cfg *config.Config, orgRepo string, baseSHAGetter, headSHAGetter config.RefGetter) []config.Presubmit {
 	presubmits, err := cfg.GetPresubmits(gc, orgRepo, baseSHAGetter, headSHAGetter)
 	if err != nil {
@@ -35,6 +35,8 @@ import (
 	"k8s.io/test-infra/prow/git/v2"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/pjutil"
 	"k8s.io/test-infra/prow/plugins"
 	utilpointer "k8s.io/utils/pointer"
 )
@@ -181,7 +183,7 @@ func TestRunRequested(t *testing.T) {
 				Logger:        logrus.WithField("testcase", testCase.name),
 			}
 
-			err := runRequested(client, pr, fakegithub.TestRef, testCase.requestedJobs, "event-guid", nil, time.Nanosecond)
+			err := runRequested(client, pr, fakegithub.TestRef, testCase.requestedJobs, "event-guid", nil, "", time.Nanosecond)
 			if err == nil && testCase.expectedErr {
 				t.Error("failed to receive an error")
 			}
@@ -608,3 +610,93 @@ func TestCreateWithRetry(t *testing.T) {
 		})
 	}
 }
+
+func TestExceedsExternalContributorQuota(t *testing.T) {
+	pr := &github.PullRequest{
+		Number: 1,
+		Base: github.PullRequestBranch{
+			Ref: "master",
+			Repo: github.Repo{
+				Owner: github.User{Login: "org"},
+				Name:  "repo",
+			},
+		},
+	}
+	startedAgo := func(ago time.Duration, triggeredBy string) runtime.Object {
+		pj := pjutil.NewPresubmit(*pr, "base", config.Presubmit{JobBase: config.JobBase{Name: "job"}}, "guid", nil)
+		pj.Name = ago.String()
+		pj.Annotations[kube.TriggeredByAnnotation] = triggeredBy
+		pj.Status.StartTime = metav1.NewTime(time.Now().Add(-ago))
+		return &pj
+	}
+
+	testCases := []struct {
+		name     string
+		quota    int
+		user     string
+		existing []runtime.Object
+		jobs     int
+		expected bool
+	}{
+		{
+			name:     "no quota",
+			user:     "external",
+			existing: []runtime.Object{startedAgo(time.Hour, "External"), startedAgo(2*time.Hour, "External")},
+			jobs:     1,
+		},
+		{
+			name:     "within quota",
+			quota:    3,
+			user:     "external",
+			existing: []runtime.Object{startedAgo(time.Hour, "External")},
+			jobs:     2,
+		},
+		{
+			name:     "exceeding quota",
+			quota:    3,
+			user:     "external",
+			existing: []runtime.Object{startedAgo(time.Hour, "External"), startedAgo(2*time.Hour, "External")},
+			jobs:     2,
+			expected: true,
+		},
+		{
+			name:     "jobs started more than a day ago do not count",
+			quota:    3,
+			user:     "external",
+			existing: []runtime.Object{startedAgo(25*time.Hour, "External"), startedAgo(26*time.Hour, "External")},
+			jobs:     2,
+		},
+		{
+			name:     "jobs triggered by other users do not count",
+			quota:    3,
+			user:     "external",
+			existing: []runtime.Object{startedAgo(time.Hour, "member"), startedAgo(2*time.Hour, "")},
+			jobs:     2,
+		},
+		{
+			name:     "org members are not limited",
+			quota:    3,
+			user:     "member",
+			existing: []runtime.Object{startedAgo(time.Hour, "member"), startedAgo(2*time.Hour, "member")},
+			jobs:     2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			fc.OrgMembers = map[string][]string{"org": {"member"}}
+			c := Client{
+				GitHubClient:  fc,
+				ProwJobClient: fake.NewSimpleClientset(tc.existing...).ProwV1().ProwJobs(""),
+				Logger:        logrus.WithField("plugin", PluginName),
+			}
+			exceeded, err := exceedsExternalContributorQuota(c, plugins.Trigger{ExternalContributorDailyJobQuota: tc.quota}, pr, tc.user, tc.jobs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if exceeded != tc.expected {
+				t.Errorf("expected quota exceeded %t, got %t", tc.expected, exceeded)
+			}
+		})
+	}
+}
@@ -66,7 +66,7 @@ func init() {
 
 func helpProvider(c *plugins.Configuration, orgRepo []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
 	pluginHelp := &pluginhelp.PluginHelp{
-		Description: fmt.Sprintf("The verify-owners plugin validates %s and %s files (by default) and ensures that they always contain collaborators of the org, if they are modified in a PR. On validation failure it automatically adds the '%s' label to the PR, and a review comment on the incriminating file(s). Per-repo configuration for filenames is possible.", ownersconfig.DefaultOwnersFile, ownersconfig.DefaultOwnersAliasesFile, labels.InvalidOwners),
+		Description: fmt.Sprintf("The verify-owners plugin validates %s and %s files (by default) and ensures that they always contain collaborators of the org, if they are modified in a PR. On validation failure it automatically adds the '%s' label to the PR, and a review comment on the incriminating file(s). Per-repo configuration for filenames is possible, as is resolving aliases shared by the repos of an org from a central repo.", ownersconfig.DefaultOwnersFile, ownersconfig.DefaultOwnersAliasesFile, labels.InvalidOwners),
 		Config:      map[string]string{},
 	}
 	defaultFilenames := c.OwnersFilenames("", "")
@@ -83,9 +83,15 @@ func helpProvider(c *plugins.Configuration, orgRepo []config.OrgRepo) (*pluginhe
 	pluginHelp.Config["default"] = descriptionFor(defaultFilenames)
 	for _, item := range orgRepo {
 		filenames := c.OwnersFilenames(item.Org, item.Repo)
-		if !reflect.DeepEqual(filenames, defaultFilenames) {
-			pluginHelp.Config[item.String()] = descriptionFor(filenames)
+		aliasesRepo := c.OwnersAliasesRepoFor(item.Org)
+		if reflect.DeepEqual(filenames, defaultFilenames) && aliasesRepo == "" {
+			continue
+		}
+		description := descriptionFor(filenames)
+		if aliasesRepo != "" {
+			description = fmt.Sprintf("%s Aliases shared by the repos of the org are resolved from %s.", description, aliasesRepo)
 		}
+		pluginHelp.Config[item.String()] = description
 	}
 	pluginHelp.AddCommand(pluginhelp.Command{
 		Usage:       "/verify-owners",
@@ -117,6 +123,8 @@ type githubClient interface {
 	RemoveLabel(owner, repo string, number int, label string) error
 	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
 	BotUserChecker() (func(candidate string) bool, error)
+	GetFile(org, repo, filepath, commit string) ([]byte, error)
+	UserExists(login string) (bool, error)
 }
 
 type commentPruner interface {
@@ -155,7 +163,7 @@ func handlePullRequest(pc plugins.Agent, pre github.PullRequestEvent) error {
 		number:       pre.Number,
 	}
 
-	return handle(pc.GitHubClient, pc.GitClient, pc.OwnersClient, pc.Logger, &pre.PullRequest, prInfo, pc.PluginConfig.Owners.LabelsDenyList, pc.PluginConfig.TriggerFor(pre.Repo.Owner.Login, pre.Repo.Name), skipTrustedUserCheck, cp, pc.PluginConfig.OwnersFilenames)
+	return handle(pc.GitHubClient, pc.GitClient, pc.OwnersClient, pc.Logger, &pre.PullRequest, prInfo, pc.PluginConfig.Owners.LabelsDenyList, pc.PluginConfig.TriggerFor(pre.Repo.Owner.Login, pre.Repo.Name), skipTrustedUserCheck, cp, pc.PluginConfig.OwnersFilenames, pc.PluginConfig.OwnersAliasesRepoFor(pre.Repo.Owner.Login))
 }
 
 func handleGenericCommentEvent(pc plugins.Agent, e github.GenericCommentEvent) error {
@@ -172,10 +180,10 @@ func handleGenericCommentEvent(pc plugins.Agent, e github.GenericCommentEvent) e
 		}
 	}
 
-	return handleGenericComment(pc.GitHubClient, pc.GitClient, pc.OwnersClient, pc.Logger, &e, pc.PluginConfig.Owners.LabelsDenyList, pc.PluginConfig.TriggerFor(e.Repo.Owner.Login, e.Repo.Name), skipTrustedUserCheck, cp, pc.PluginConfig.OwnersFilenames)
+	return handleGenericComment(pc.GitHubClient, pc.GitClient, pc.OwnersClient, pc.Logger, &e, pc.PluginConfig.Owners.LabelsDenyList, pc.PluginConfig.TriggerFor(e.Repo.Owner.Login, e.Repo.Name), skipTrustedUserCheck, cp, pc.PluginConfig.OwnersFilenames, pc.PluginConfig.OwnersAliasesRepoFor(e.Repo.Owner.Login))
 }
 
-func handleGenericComment(ghc githubClient, gc git.ClientFactory, roc repoownersClient, log *logrus.Entry, ce *github.GenericCommentEvent, bannedLabels []string, triggerConfig plugins.Trigger, skipTrustedUserCheck bool, cp commentPruner, resolver ownersconfig.Resolver) error {
+func handleGenericComment(ghc githubClient, gc git.ClientFactory, roc repoownersClient, log *logrus.Entry, ce *github.GenericCommentEvent, bannedLabels []string, triggerConfig plugins.Trigger, skipTrustedUserCheck bool, cp commentPruner, resolver ownersconfig.Resolver, aliasesRepo string) error {
 	// Only consider open PRs and new comments.
 	if ce.IssueState != "open" || !ce.IsPR || ce.Action != github.GenericCommentActionCreated {
 		return nil
@@ -197,7 +205,7 @@ func handleGenericComment(ghc githubClient, gc git.ClientFactory, roc repoowners
 		return err
 	}
 
-	return handle(ghc, gc, roc, log, pr, prInfo, bannedLabels, triggerConfig, skipTrustedUserCheck, cp, resolver)
+	return handle(ghc, gc, roc, log, pr, prInfo, bannedLabels, triggerConfig, skipTrustedUserCheck, cp, resolver, aliasesRepo)
 }
 
 type messageWithLine struct {
@@ -205,7 +213,7 @@ type messageWithLine struct {
 	message string
 }
 
-func handle(ghc githubClient, gc git.ClientFactory, roc repoownersClient, log *logrus.Entry, pr *github.PullRequest, info info, bannedLabels []string, triggerConfig plugins.Trigger, skipTrustedUserCheck bool, cp commentPruner, resolver ownersconfig.Resolver) error {
+func handle(ghc githubClient, gc git.ClientFactory, roc repoownersClient, log *logrus.Entry, pr *github.PullRequest, info info, bannedLabels []string, triggerConfig plugins.Trigger, skipTrustedUserCheck bool, cp commentPruner, resolver ownersconfig.Resolver, aliasesRepo string) error {
 	org := info.org
 	repo := info.repo
 	number := info.number
@@ -276,6 +284,20 @@ func handle(ghc githubClient, gc git.ClientFactory, roc repoownersClient, log *l
 		return err
 	}
 
+	// Aliases shared by the repos of the org can be used as well, unless the
+	// repo defines an alias with the same name.
+	centralAliases, centralAliasesFile := loadCentralAliases(ghc, log, aliasesRepo, resolver)
+	if ownerAliasesModified {
+		if msg := aliasConflicts(repoAliases, centralAliases, filenames.OwnersAliases, centralAliasesFile); msg != "" {
+			wrongOwnersFiles[filenames.OwnersAliases] = messageWithLine{1, msg}
+		}
+	}
+	for alias, members := range centralAliases {
+		if _, ok := repoAliases[alias]; !ok {
+			repoAliases[alias] = members
+		}
+	}
+
 	// Check if OWNERS files have the correct config and if they do,
 	// check if all newly added owners are trusted users.
 	oc, err := roc.LoadRepoOwners(org, repo, pr.Base.Ref)
@@ -290,6 +312,14 @@ func handle(ghc githubClient, gc git.ClientFactory, roc repoownersClient, log *l
 			wrongOwnersFiles[c.Filename] = *msg
 			continue
 		}
+		msg, err = unknownAliases(ghc, c, owners, repoAliases, filenames.OwnersAliases, centralAliasesFile)
+		if err != nil {
+			return err
+		}
+		if msg != nil {
+			wrongOwnersFiles[c.Filename] = *msg
+			continue
+		}
 
 		if !skipTrustedUserCheck {
 			nonTrustedUsers, err = nonTrustedUsersInOwners(ghc, log, triggerConfig, org, repo, c.Patch, c.Filename, owners, nonTrustedUsers, trustedUsers, repoAliases)
@@ -345,7 +375,11 @@ func handle(ghc githubClient, gc git.ClientFactory, roc repoownersClient, log *l
 		cp.PruneComments(func(comment github.IssueComment) bool {
 			return strings.Contains(comment.Body, fmt.Sprintf(untrustedResponseFormat, filenames.Owners, triggerConfig.JoinOrgURL, org))
 		})
-		if err := ghc.CreateComment(org, repo, number, markdownFriendlyComment(org, triggerConfig.JoinOrgURL, nonTrustedUsers, filenames)); err != nil {
+		comment := markdownFriendlyComment(org, triggerConfig.JoinOrgURL, nonTrustedUsers, filenames)
+		if centralAliasesFile != "" {
+			comment += fmt.Sprintf("\n\nUsers above that are meant to be aliases are defined in neither %s nor %s.", filenames.OwnersAliases, centralAliasesFile)
+		}
+		if err := ghc.CreateComment(org, repo, number, comment); err != nil {
 			log.WithError(err).Errorf("Could not create comment for listing non-collaborators in %s files", filenames.Owners)
 		}
 	}
@@ -406,6 +440,25 @@ func parseOwnersFile(oc ownersClient, path string, c github.PullRequestChange, l
 			approvers = append(approvers, config.Approvers...)
 			labels = append(labels, config.Labels...)
 		}
+		for _, rule := range full.Rules {
+			if len(rule.Paths) == 0 {
+				return &messageWithLine{
+					lineNumber,
+					"Rules must have at least one path.",
+				}, nil
+			}
+			for _, rulePath := range rule.Paths {
+				if err := repoowners.ValidateRulePath(rulePath); err != nil {
+					return &messageWithLine{
+						lineNumber,
+						fmt.Sprintf("Rule paths must stay in the directory of the OWNERS file: %v.", err),
+					}, nil
+				}
+			}
+			reviewers = append(reviewers, rule.Reviewers...)
+			approvers = append(approvers, rule.Approvers...)
+			labels = append(labels, rule.Labels...)
+		}
 	} else {
 		// it's a SimpleConfig
 		reviewers = simple.Config.Reviewers
@@ -444,6 +497,107 @@ func markdownFriendlyComment(org, joinOrgURL string, nonTrustedUsers map[string]
 	return strings.Join(commentLines, "\n")
 }
 
+// loadCentralAliases returns the aliases defined in the aliases file of the
+// repo holding the aliases shared by the repos of the org, along with the
+// location of that file. Both are empty if there is no such repo.
+func loadCentralAliases(ghc githubClient, log *logrus.Entry, aliasesRepo string, resolver ownersconfig.Resolver) (repoowners.RepoAliases, string) {
+	if aliasesRepo == "" {
+		return nil, ""
+	}
+	parts := strings.SplitN(aliasesRepo, "/", 2)
+	if len(parts) != 2 {
+		log.Warnf("Invalid aliases repo %q, expected org/repo.", aliasesRepo)
+		return nil, ""
+	}
+	filename := resolver(parts[0], parts[1]).OwnersAliases
+	location := fmt.Sprintf("%s/%s", aliasesRepo, filename)
+	b, err := ghc.GetFile(parts[0], parts[1], filename, "")
+	if err != nil {
+		log.WithError(err).Warnf("Failed to get %s.", location)
+		return nil, location
+	}
+	aliases, err := repoowners.ParseAliasesConfig(b)
+	if err != nil {
+		log.WithError(err).Warnf("Failed to parse %s.", location)
+		return nil, location
+	}
+	return aliases, location
+}
+
+// aliasConflicts describes the aliases that are defined with different members
+// in the repo's aliases file and in the central one, with a diff of their
+// members. It returns an empty string if there is no conflict.
+func aliasConflicts(repoAliases, centralAliases repoowners.RepoAliases, filename, centralFile string) string {
+	var conflicts []string
+	for _, alias := range sets.StringKeySet(repoAliases).Intersection(sets.StringKeySet(centralAliases)).List() {
+		members, centralMembers := repoAliases[alias], centralAliases[alias]
+		if members.Equal(centralMembers) {
+			continue
+		}
+		lines := []string{fmt.Sprintf("Alias %s is defined with different members in %s and %s:", alias, centralFile, filename), "```diff"}
+		for _, member := range centralMembers.Difference(members).List() {
+			lines = append(lines, "- "+member)
+		}
+		for _, member := range members.Difference(centralMembers).List() {
+			lines = append(lines, "+ "+member)
+		}
+		lines = append(lines, "```")
+		conflicts = append(conflicts, strings.Join(lines, "\n"))
+	}
+	return strings.Join(conflicts, "\n\n")
+}
+
+// unknownAliases reports the owners added to the OWNERS file that are neither
+// aliases nor GitHub users: they can only be meant to be aliases, but are
+// defined in neither the aliases file of the repo nor the central one. The
+// message is bound to the first line adding one of them.
+func unknownAliases(ghc githubClient, c github.PullRequestChange, owners []string, aliases repoowners.RepoAliases, aliasesFile, centralAliasesFile string) (*messageWithLine, error) {
+	lines := strings.Split(c.Patch, "\n")
+	var unknown []string
+	position := 0
+	for _, owner := range sets.NewString(owners...).List() {
+		if _, ok := aliases[owner]; ok {
+			continue
+		}
+		addedRe := regexp.MustCompile(fmt.Sprintf(`(?i)^\+\s*-\s*['"]?%s['"]?\s*(#.*)?$`, regexp.QuoteMeta(owner)))
+		line := -1
+		for i, l := range lines {
+			if addedRe.MatchString(l) {
+				line = i
+				break
+			}
+		}
+		if line < 0 {
+			continue
+		}
+		exists, err := ghc.UserExists(owner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check whether %s is a GitHub user: %w", owner, err)
+		}
+		if exists {
+			continue
+		}
+		unknown = append(unknown, owner)
+		if position == 0 || line < position {
+			position = line
+		}
+	}
+	if len(unknown) == 0 {
+		return nil, nil
+	}
+	if position == 0 {
+		position = 1
+	}
+	definedIn := fmt.Sprintf("not defined in %s", aliasesFile)
+	if centralAliasesFile != "" {
+		definedIn = fmt.Sprintf("defined in neither %s nor %s", aliasesFile, centralAliasesFile)
+	}
+	return &messageWithLine{
+		position,
+		fmt.Sprintf("Unknown aliases, %s and not GitHub users either: %s.", definedIn, strings.Join(unknown, ", ")),
+	}, nil
+}
+
 func nonTrustedUsersInOwnersAliases(ghc githubClient, log *logrus.Entry, triggerConfig plugins.Trigger, org, repo, dir, patch string, ownerAliasesModified, skipTrustedUserCheck bool, filenames ownersconfig.Filenames) (map[string]nonTrustedReasons, sets.String, repoowners.RepoAliases, error) {
 	repoAliases := make(repoowners.RepoAliases)
 	// nonTrustedUsers is a map of non-trusted users to the reasons they were not trusted
@@ -129,6 +129,40 @@ labels:
     - bob
     labels:
     - label1
+`),
+	"validRules": []byte(`version: 2
+rules:
+- paths:
+  - "**"
+  approvers:
+  - jdoe
+- paths:
+  - pkg/api/
+  - "docs/*.md"
+  reviewers:
+  - alice
+  no_parent_owners: true
+`),
+	"invalidLabelsRules": []byte(`version: 2
+rules:
+- paths:
+  - "**"
+  approvers:
+  - jdoe
+  labels:
+  - lgtm
+`),
+	"noPathsRules": []byte(`version: 2
+rules:
+- approvers:
+  - jdoe
+`),
+	"parentPathRules": []byte(`version: 2
+rules:
+- paths:
+  - "../../**"
+  approvers:
+  - jdoe
 `),
 	"referencesToBeAddedAlias": []byte(`approvers:
 - not-yet-existing-alias
@@ -367,6 +401,24 @@ func testHandle(clients localgit.Clients, t *testing.T) {
 			ownersFile:   "validFilters",
 			shouldLabel:  false,
 		},
+		{
+			name:         "good OWNERS file with rules",
+			filesChanged: []string{"OWNERS", "b.go"},
+			ownersFile:   "validRules",
+			shouldLabel:  false,
+		},
+		{
+			name:         "OWNERS file with a rule without paths",
+			filesChanged: []string{"OWNERS", "b.go"},
+			ownersFile:   "noPathsRules",
+			shouldLabel:  true,
+		},
+		{
+			name:         "OWNERS file with a rule outside of its directory",
+			filesChanged: []string{"OWNERS", "b.go"},
+			ownersFile:   "parentPathRules",
+			shouldLabel:  true,
+		},
 		{
 			name:         "invalid syntax OWNERS file",
 			filesChanged: []string{"OWNERS", "b.go"},
@@ -391,6 +443,12 @@ func testHandle(clients localgit.Clients, t *testing.T) {
 			ownersFile:   "invalidLabelsFilters",
 			shouldLabel:  true,
 		},
+		{
+			name:         "forbidden labels in OWNERS file with rules",
+			filesChanged: []string{"OWNERS", "b.go"},
+			ownersFile:   "invalidLabelsRules",
+			shouldLabel:  true,
+		},
 		{
 			name:         "empty approvers in OWNERS file",
 			filesChanged: []string{"OWNERS", "b.go"},
@@ -530,7 +588,7 @@ func testHandle(clients localgit.Clients, t *testing.T) {
 				number:       pr,
 			}
 
-			if err := handle(fghc, c, makeFakeRepoOwnersClient(), logrus.WithField("plugin", PluginName), &pre.PullRequest, prInfo, []string{labels.Approved, labels.LGTM}, plugins.Trigger{}, false, &fakePruner{}, ownersconfig.FakeResolver); err != nil {
+			if err := handle(fghc, c, makeFakeRepoOwnersClient(), logrus.WithField("plugin", PluginName), &pre.PullRequest, prInfo, []string{labels.Approved, labels.LGTM}, plugins.Trigger{}, false, &fakePruner{}, ownersconfig.FakeResolver, ""); err != nil {
 				t.Fatalf("Handle PR: %v", err)
 			}
 			if !test.shouldLabel && IssueLabelsContain(fghc.IssueLabelsAdded, labels.InvalidOwners) {
@@ -698,7 +756,7 @@ func TestHelpProvider(t *testing.T) {
 			config:       &plugins.Configuration{},
 			enabledRepos: enabledRepos,
 			expected: &pluginhelp.PluginHelp{
-				Description: "The verify-owners plugin validates OWNERS and OWNERS_ALIASES files (by default) and ensures that they always contain collaborators of the org, if they are modified in a PR. On validation failure it automatically adds the 'do-not-merge/invalid-owners-file' label to the PR, and a review comment on the incriminating file(s). Per-repo configuration for filenames is possible.",
+				Description: "The verify-owners plugin validates OWNERS and OWNERS_ALIASES files (by default) and ensures that they always contain collaborators of the org, if they are modified in a PR. On validation failure it automatically adds the 'do-not-merge/invalid-owners-file' label to the PR, and a review comment on the incriminating file(s). Per-repo configuration for filenames is possible, as is resolving aliases shared by the repos of an org from a central repo.",
 				Config: map[string]string{
 					"default": "OWNERS and OWNERS_ALIASES files are validated.",
 				},
@@ -719,7 +777,7 @@ func TestHelpProvider(t *testing.T) {
 			},
 			enabledRepos: enabledRepos,
 			expected: &pluginhelp.PluginHelp{
-				Description: "The verify-owners plugin validates OWNERS and OWNERS_ALIASES files (by default) and ensures that they always contain collaborators of the org, if they are modified in a PR. On validation failure it automatically adds the 'do-not-merge/invalid-owners-file' label to the PR, and a review comment on the incriminating file(s). Per-repo configuration for filenames is possible.",
+				Description: "The verify-owners plugin validates OWNERS and OWNERS_ALIASES files (by default) and ensures that they always contain collaborators of the org, if they are modified in a PR. On validation failure it automatically adds the 'do-not-merge/invalid-owners-file' label to the PR, and a review comment on the incriminating file(s). Per-repo configuration for filenames is possible, as is resolving aliases shared by the repos of an org from a central repo.",
 				Config: map[string]string{
 					"default": "OWNERS and OWNERS_ALIASES files are validated. The verify-owners plugin will complain if OWNERS files contain any of the following banned labels: label1, label2.",
 				},
@@ -731,6 +789,28 @@ func TestHelpProvider(t *testing.T) {
 				}},
 			},
 		},
+		{
+			name: "OwnersAliasesRepo specified",
+			config: &plugins.Configuration{
+				Owners: plugins.Owners{
+					OwnersAliasesRepo: map[string]string{"org1": "org1/aliases"},
+				},
+			},
+			enabledRepos: enabledRepos,
+			expected: &pluginhelp.PluginHelp{
+				Description: "The verify-owners plugin validates OWNERS and OWNERS_ALIASES files (by default) and ensures that they always contain collaborators of the org, if they are modified in a PR. On validation failure it automatically adds the 'do-not-merge/invalid-owners-file' label to the PR, and a review comment on the incriminating file(s). Per-repo configuration for filenames is possible, as is resolving aliases shared by the repos of an org from a central repo.",
+				Config: map[string]string{
+					"default":   "OWNERS and OWNERS_ALIASES files are validated.",
+					"org1/repo": "OWNERS and OWNERS_ALIASES files are validated. Aliases shared by the repos of the org are resolved from org1/aliases.",
+				},
+				Commands: []pluginhelp.Command{{
+					Usage:       "/verify-owners",
+					Description: "do-not-merge/invalid-owners-file",
+					Examples:    []string{"/verify-owners"},
+					WhoCanUse:   "Anyone",
+				}},
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -887,6 +967,8 @@ func testNonCollaborators(clients localgit.Clients, t *testing.T) {
 		ownersAliasesPatch   string
 		includeVendorOwners  bool
 		skipTrustedUserCheck bool
+		aliasesRepo          string
+		centralAliasesFile   string
 		shouldLabel          bool
 		shouldComment        bool
 		commentShouldContain string
@@ -1001,6 +1083,46 @@ func testNonCollaborators(clients localgit.Clients, t *testing.T) {
 			shouldLabel:        false,
 			shouldComment:      false,
 		},
+		{
+			name:                 "alias missing from OWNERS_ALIASES file",
+			filesChanged:         []string{"OWNERS"},
+			ownersFile:           "collaboratorsWithAliases",
+			ownersPatch:          "collaboratorsWithAliases",
+			shouldLabel:          true,
+			shouldComment:        true,
+			commentShouldContain: nonTrustedNotMemberNotCollaborator,
+		},
+		{
+			name:               "alias defined in central aliases repo",
+			filesChanged:       []string{"OWNERS"},
+			ownersFile:         "collaboratorsWithAliases",
+			ownersPatch:        "collaboratorsWithAliases",
+			aliasesRepo:        "org/aliases",
+			centralAliasesFile: "collaborators",
+			shouldLabel:        false,
+			shouldComment:      false,
+		},
+		{
+			name:                 "alias missing from both OWNERS_ALIASES files",
+			filesChanged:         []string{"OWNERS"},
+			ownersFile:           "collaboratorsWithAliases",
+			ownersPatch:          "collaboratorsWithAliases",
+			aliasesRepo:          "org/aliases",
+			shouldLabel:          true,
+			shouldComment:        true,
+			commentShouldContain: "defined in neither OWNERS_ALIASES nor org/aliases/OWNERS_ALIASES",
+		},
+		{
+			name:               "alias defined with different members in central aliases repo",
+			filesChanged:       []string{"OWNERS_ALIASES"},
+			ownersFile:         "collaboratorsWithAliases",
+			ownersAliasesFile:  "collaborators",
+			ownersAliasesPatch: "collaboratorAdditions",
+			aliasesRepo:        "org/aliases",
+			centralAliasesFile: "nonCollaborators",
+			shouldLabel:        true,
+			shouldComment:      false,
+		},
 		{
 			name:          "non-collaborators additions in OWNERS file in vendor subdir",
 			filesChanged:  []string{"vendor/k8s.io/client-go/OWNERS"},
@@ -1093,6 +1215,9 @@ func testNonCollaborators(clients localgit.Clients, t *testing.T) {
 			}
 			fghc := newFakeGitHubClient(emptyPatch(test.filesChanged), nil, pr)
 			fghc.PullRequestChanges[pr] = changes
+			if test.centralAliasesFile != "" {
+				fghc.RemoteFiles = map[string]map[string]string{"OWNERS_ALIASES": {"master": string(ownersAliases[test.centralAliasesFile])}}
+			}
 
 			fghc.PullRequests = map[int]*github.PullRequest{}
 			fghc.PullRequests[pr] = &github.PullRequest{
@@ -1119,7 +1244,7 @@ func testNonCollaborators(clients localgit.Clients, t *testing.T) {
 				number:       pr,
 			}
 
-			if err := handle(fghc, c, froc, logrus.WithField("plugin", PluginName), &pre.PullRequest, prInfo, []string{labels.Approved, labels.LGTM}, plugins.Trigger{}, test.skipTrustedUserCheck, &fakePruner{}, ownersconfig.FakeResolver); err != nil {
+			if err := handle(fghc, c, froc, logrus.WithField("plugin", PluginName), &pre.PullRequest, prInfo, []string{labels.Approved, labels.LGTM}, plugins.Trigger{}, test.skipTrustedUserCheck, &fakePruner{}, ownersconfig.FakeResolver, test.aliasesRepo); err != nil {
 				t.Fatalf("Handle PR: %v", err)
 			}
 			if !test.shouldLabel && IssueLabelsContain(fghc.IssueLabelsAdded, labels.InvalidOwners) {
@@ -1294,7 +1419,7 @@ func testHandleGenericComment(clients localgit.Clients, t *testing.T) {
 				},
 			}
 
-			if err := handleGenericComment(fghc, c, makeFakeRepoOwnersClient(), logrus.WithField("plugin", PluginName), &test.commentEvent, []string{labels.Approved, labels.LGTM}, plugins.Trigger{}, false, &fakePruner{}, ownersconfig.FakeResolver); err != nil {
+			if err := handleGenericComment(fghc, c, makeFakeRepoOwnersClient(), logrus.WithField("plugin", PluginName), &test.commentEvent, []string{labels.Approved, labels.LGTM}, plugins.Trigger{}, false, &fakePruner{}, ownersconfig.FakeResolver, ""); err != nil {
 				t.Fatalf("Handle PR: %v", err)
 			}
 			if !test.shouldLabel && IssueLabelsContain(fghc.IssueLabelsAdded, labels.InvalidOwners) {
@@ -1427,7 +1552,7 @@ func testOwnersRemoval(clients localgit.Clients, t *testing.T) {
 
 			froc := makeFakeRepoOwnersClient()
 
-			if err := handle(fghc, c, froc, logrus.WithField("plugin", PluginName), &pre.PullRequest, prInfo, []string{labels.Approved, labels.LGTM}, plugins.Trigger{}, false, &fakePruner{}, ownersconfig.FakeResolver); err != nil {
+			if err := handle(fghc, c, froc, logrus.WithField("plugin", PluginName), &pre.PullRequest, prInfo, []string{labels.Approved, labels.LGTM}, plugins.Trigger{}, false, &fakePruner{}, ownersconfig.FakeResolver, ""); err != nil {
 				t.Fatalf("Handle PR: %v", err)
 			}
 			if test.shouldRemoveLabel && !IssueLabelsContain(fghc.IssueLabelsRemoved, labels.InvalidOwners) {
@@ -1447,3 +1572,72 @@ func TestOwnersRemoval(t *testing.T) {
 func TestOwnersRemovalV2(t *testing.T) {
 	testOwnersRemoval(localgit.NewV2, t)
 }
+
+func TestAliasConflicts(t *testing.T) {
+	repoAliases := repoowners.RepoAliases{
+		"foo-reviewers": sets.NewString("alice", "bob"),
+		"bar-reviewers": sets.NewString("alice"),
+		"baz-reviewers": sets.NewString("zee"),
+	}
+	centralAliases := repoowners.RepoAliases{
+		"foo-reviewers": sets.NewString("alice", "phippy"),
+		"bar-reviewers": sets.NewString("alice"),
+		"qux-reviewers": sets.NewString("zee"),
+	}
+	expected := "Alias foo-reviewers is defined with different members in org/aliases/OWNERS_ALIASES and OWNERS_ALIASES:\n```diff\n- phippy\n+ bob\n```"
+	if actual := aliasConflicts(repoAliases, centralAliases, "OWNERS_ALIASES", "org/aliases/OWNERS_ALIASES"); actual != expected {
+		t.Errorf("expected conflicts:\n%s\ngot:\n%s", expected, actual)
+	}
+	if actual := aliasConflicts(repoAliases, nil, "OWNERS_ALIASES", ""); actual != "" {
+		t.Errorf("expected no conflict without central aliases, got:\n%s", actual)
+	}
+}
+
+func TestUnknownAliases(t *testing.T) {
+	aliases := repoowners.RepoAliases{"foo-reviewers": sets.NewString("alice")}
+	testCases := []struct {
+		name               string
+		patch              string
+		owners             []string
+		centralAliasesFile string
+		expected           *messageWithLine
+	}{
+		{
+			name:   "added aliases and users are known",
+			patch:  "@@ -1,2 +1,4 @@\n approvers:\n+- foo-reviewers\n+- alice",
+			owners: []string{"foo-reviewers", "alice"},
+		},
+		{
+			name:   "owners that were not added are not checked",
+			patch:  "@@ -1,2 +1,3 @@\n approvers:\n - bar-reviewers\n+- alice",
+			owners: []string{"bar-reviewers", "alice"},
+		},
+		{
+			name:     "added unknown aliases are reported on the first line adding one",
+			patch:    "@@ -1,2 +1,5 @@\n approvers:\n+- alice\n+- Qux-Reviewers\n+- bar-reviewers # the bar team",
+			owners:   []string{"alice", "bar-reviewers", "qux-reviewers"},
+			expected: &messageWithLine{3, "Unknown aliases, not defined in OWNERS_ALIASES and not GitHub users either: bar-reviewers, qux-reviewers."},
+		},
+		{
+			name:               "central aliases file is mentioned",
+			patch:              "@@ -1,2 +1,3 @@\n approvers:\n+- bar-reviewers",
+			owners:             []string{"bar-reviewers"},
+			centralAliasesFile: "org/aliases/OWNERS_ALIASES",
+			expected:           &messageWithLine{2, "Unknown aliases, defined in neither OWNERS_ALIASES nor org/aliases/OWNERS_ALIASES and not GitHub users either: bar-reviewers."},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fghc := fakegithub.NewFakeClient()
+			fghc.NonexistentUsers = []string{"bar-reviewers", "qux-reviewers"}
+			change := github.PullRequestChange{Filename: "OWNERS", Patch: tc.patch}
+			actual, err := unknownAliases(fghc, change, tc.owners, aliases, "OWNERS_ALIASES", tc.centralAliasesFile)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, actual, cmp.AllowUnexported(messageWithLine{})); diff != "" {
+				t.Errorf("unknown aliases differ from expected: %s", diff)
+			}
+		})
+	}
+}
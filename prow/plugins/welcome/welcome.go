@@ -19,8 +19,10 @@ package welcome
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
@@ -30,6 +32,7 @@ import (
 	"k8s.io/test-infra/prow/pluginhelp"
 	"k8s.io/test-infra/prow/plugins"
 	"k8s.io/test-infra/prow/plugins/trigger"
+	"k8s.io/test-infra/prow/repoowners"
 )
 
 const (
@@ -43,6 +46,13 @@ type PRInfo struct {
 	Repo        string
 	AuthorLogin string
 	AuthorName  string
+	Number      int
+	Title       string
+	// ChangedAreas are the top-level directories changed by the PR.
+	ChangedAreas []string
+	// SIGs are the names of the sig/* labels that the OWNERS files apply to
+	// the changed files, without the "sig/" prefix.
+	SIGs []string
 }
 
 func init() {
@@ -54,6 +64,9 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 	for _, repo := range enabledRepos {
 		messageTemplate := welcomeMessageForRepo(config, repo.Org, repo.Repo)
 		welcomeConfig[repo.String()] = fmt.Sprintf("The welcome plugin is configured to post using following welcome template: %s.", messageTemplate)
+		if templateFile := optionsForRepo(config, repo.Org, repo.Repo).TemplateFile; templateFile != "" {
+			welcomeConfig[repo.String()] = fmt.Sprintf("The welcome plugin is configured to post using the welcome template in %s, or the following one if the file does not exist: %s.", templateFile, messageTemplate)
+		}
 	}
 
 	// The {WhoCanUse, Usage, Examples} fields are omitted because this plugin is not triggered with commands.
@@ -65,6 +78,7 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 					"org/repo2",
 				},
 				MessageTemplate: "Welcome @{{.AuthorLogin}}!",
+				TemplateFile:    ".prow/welcome.md",
 			},
 		},
 	})
@@ -72,7 +86,7 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", pluginName)
 	}
 	return &pluginhelp.PluginHelp{
-			Description: "The welcome plugin posts a welcoming message when it detects a user's first contribution to a repo.",
+			Description: "The welcome plugin posts a welcoming message when it detects a user's first contribution to a repo. The message is rendered from a template configured for the repo or stored in the repo itself, which can refer to the author, the areas changed by the PR and the SIGs owning them.",
 			Config:      welcomeConfig,
 			Snippet:     yamlSnippet,
 		},
@@ -85,26 +99,35 @@ type githubClient interface {
 	IsCollaborator(org, repo, user string) (bool, error)
 	IsMember(org, user string) (bool, error)
 	BotUserChecker() (func(candidate string) bool, error)
+	GetFile(org, repo, filepath, commit string) ([]byte, error)
+	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
+}
+
+type ownersClient interface {
+	LoadRepoOwners(org, repo, base string) (repoowners.RepoOwner, error)
 }
 
 type client struct {
 	GitHubClient githubClient
+	OwnersClient ownersClient
 	Logger       *logrus.Entry
 }
 
 func getClient(pc plugins.Agent) client {
 	return client{
 		GitHubClient: pc.GitHubClient,
+		OwnersClient: pc.OwnersClient,
 		Logger:       pc.Logger,
 	}
 }
 
 func handlePullRequest(pc plugins.Agent, pre github.PullRequestEvent) error {
 	t := pc.PluginConfig.TriggerFor(pre.PullRequest.Base.Repo.Owner.Login, pre.PullRequest.Base.Repo.Name)
-	return handlePR(getClient(pc), t, pre, welcomeMessageForRepo(pc.PluginConfig, pre.Repo.Owner.Login, pre.Repo.Name))
+	templateFile := optionsForRepo(pc.PluginConfig, pre.Repo.Owner.Login, pre.Repo.Name).TemplateFile
+	return handlePR(getClient(pc), t, pre, welcomeMessageForRepo(pc.PluginConfig, pre.Repo.Owner.Login, pre.Repo.Name), templateFile)
 }
 
-func handlePR(c client, t plugins.Trigger, pre github.PullRequestEvent, welcomeTemplate string) error {
+func handlePR(c client, t plugins.Trigger, pre github.PullRequestEvent, welcomeTemplate, templateFile string) error {
 	// Only consider newly opened PRs
 	if pre.Action != github.PullRequestActionOpened {
 		return nil
@@ -136,18 +159,30 @@ func handlePR(c client, t plugins.Trigger, pre github.PullRequestEvent, welcomeT
 
 	// if there are no results, this is the first! post the welcome comment
 	if len(issues) == 0 || len(issues) == 1 && issues[0].Number == pre.Number {
+		if templateFile != "" {
+			welcomeTemplate = c.templateFromRepo(org, repo, pre.PullRequest.Base.Ref, templateFile, welcomeTemplate)
+		}
+
 		// load the template, and run it over the PR info
 		parsedTemplate, err := template.New("welcome").Parse(welcomeTemplate)
 		if err != nil {
 			return err
 		}
-		var msgBuffer bytes.Buffer
-		err = parsedTemplate.Execute(&msgBuffer, PRInfo{
+		info := PRInfo{
 			Org:         org,
 			Repo:        repo,
 			AuthorLogin: user,
 			AuthorName:  pre.PullRequest.User.Name,
-		})
+			Number:      pre.PullRequest.Number,
+			Title:       pre.PullRequest.Title,
+		}
+		// Listing the changes and loading the OWNERS files is only worth it
+		// when the template uses them.
+		if strings.Contains(welcomeTemplate, ".ChangedAreas") || strings.Contains(welcomeTemplate, ".SIGs") {
+			info.ChangedAreas, info.SIGs = c.changedAreasAndSIGs(org, repo, pre.PullRequest.Base.Ref, pre.PullRequest.Number)
+		}
+		var msgBuffer bytes.Buffer
+		err = parsedTemplate.Execute(&msgBuffer, info)
 		if err != nil {
 			return err
 		}
@@ -159,6 +194,53 @@ func handlePR(c client, t plugins.Trigger, pre github.PullRequestEvent, welcomeT
 	return nil
 }
 
+// templateFromRepo returns the content of the template file on the base
+// branch, or the fallback template if it cannot be read.
+func (c client) templateFromRepo(org, repo, base, templateFile, fallback string) string {
+	content, err := c.GitHubClient.GetFile(org, repo, templateFile, base)
+	if err != nil {
+		var notFound *github.FileNotFound
+		if !errors.As(err, &notFound) {
+			c.Logger.WithError(err).Warnf("Failed to get the welcome template %s, using the configured one.", templateFile)
+		}
+		return fallback
+	}
+	return string(content)
+}
+
+// changedAreasAndSIGs returns the top-level directories changed by the PR and
+// the SIGs owning the changed files according to the OWNERS files. Failures
+// are logged and leave the corresponding list empty, as they should not
+// prevent welcoming the author.
+func (c client) changedAreasAndSIGs(org, repo, base string, number int) ([]string, []string) {
+	changes, err := c.GitHubClient.GetPullRequestChanges(org, repo, number)
+	if err != nil {
+		c.Logger.WithError(err).Warn("Failed to get the PR changes.")
+		return nil, nil
+	}
+	areas := sets.NewString()
+	for _, change := range changes {
+		if i := strings.Index(change.Filename, "/"); i > 0 {
+			areas.Insert(change.Filename[:i])
+		}
+	}
+
+	owners, err := c.OwnersClient.LoadRepoOwners(org, repo, base)
+	if err != nil {
+		c.Logger.WithError(err).Warn("Failed to load the OWNERS files.")
+		return areas.List(), nil
+	}
+	sigs := sets.NewString()
+	for _, change := range changes {
+		for _, label := range owners.FindLabelsForFile(change.Filename).List() {
+			if strings.HasPrefix(label, "sig/") {
+				sigs.Insert(strings.TrimPrefix(label, "sig/"))
+			}
+		}
+	}
+	return areas.List(), sigs.List()
+}
+
 func welcomeMessageForRepo(config *plugins.Configuration, org, repo string) string {
 	opts := optionsForRepo(config, org, repo)
 	if opts.MessageTemplate != "" {
@@ -31,6 +31,7 @@ import (
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/plugins"
+	"k8s.io/test-infra/prow/repoowners"
 )
 
 const (
@@ -46,6 +47,12 @@ type fakeClient struct {
 
 	// collaborators is a list of collaborators names.
 	collaborators []string
+
+	// files maps "path@ref" to the content of files in the repo.
+	files map[string]string
+
+	// changes are the files changed by every PR.
+	changes []github.PullRequestChange
 }
 
 func newFakeClient() *fakeClient {
@@ -60,6 +67,20 @@ func (fc *fakeClient) BotUserChecker() (func(candidate string) bool, error) {
 	return func(_ string) bool { return false }, nil
 }
 
+// GetFile returns the content of a file recorded in the client.
+func (fc *fakeClient) GetFile(org, repo, filepath, commit string) ([]byte, error) {
+	content, ok := fc.files[filepath+"@"+commit]
+	if !ok {
+		return nil, &github.FileNotFound{}
+	}
+	return []byte(content), nil
+}
+
+// GetPullRequestChanges returns the changes recorded in the client.
+func (fc *fakeClient) GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error) {
+	return fc.changes, nil
+}
+
 // CreateComment adds and tracks a comment in the client
 func (fc *fakeClient) CreateComment(owner, repo string, number int, comment string) error {
 	fc.commentsAdded[number] = append(fc.commentsAdded[number], comment)
@@ -312,7 +333,7 @@ func TestHandlePR(t *testing.T) {
 		}
 
 		// try handling it
-		if err := handlePR(c, tr, event, testWelcomeTemplate); err != nil {
+		if err := handlePR(c, tr, event, testWelcomeTemplate, ""); err != nil {
 			t.Fatalf("did not expect error handling PR for case '%s': %v", tc.name, err)
 		}
 
@@ -329,6 +350,98 @@ func TestHandlePR(t *testing.T) {
 	}
 }
 
+type fakeOwnersClient struct {
+	labels map[string]sets.String
+}
+
+func (foc *fakeOwnersClient) LoadRepoOwners(org, repo, base string) (repoowners.RepoOwner, error) {
+	return &fakeRepoOwners{labels: foc.labels}, nil
+}
+
+type fakeRepoOwners struct {
+	repoowners.RepoOwner
+	labels map[string]sets.String
+}
+
+func (fro *fakeRepoOwners) FindLabelsForFile(path string) sets.String {
+	return fro.labels[path]
+}
+
+func TestHandlePRTemplateFile(t *testing.T) {
+	newContributor := github.User{
+		Login: "newContributor",
+		Type:  github.UserTypeUser,
+	}
+	foc := &fakeOwnersClient{
+		labels: map[string]sets.String{
+			"prow/hook/server.go":    sets.NewString("sig/testing", "area/prow"),
+			"config/jobs/job.yaml":   sets.NewString("sig/testing"),
+			"label_sync/labels.yaml": sets.NewString("sig/contributor-experience"),
+		},
+	}
+
+	testCases := []struct {
+		name            string
+		files           map[string]string
+		templateFile    string
+		expectedComment string
+	}{
+		{
+			name:            "no template file configured",
+			files:           map[string]string{".prow/welcome.md@main": "from the repo"},
+			expectedComment: "from the config",
+		},
+		{
+			name:            "template file is missing",
+			files:           map[string]string{".prow/welcome.md@other": "from the repo"},
+			templateFile:    ".prow/welcome.md",
+			expectedComment: "from the config",
+		},
+		{
+			name:            "template file from the base branch",
+			files:           map[string]string{".prow/welcome.md@main": "Welcome @{{.AuthorLogin}} to #{{.Number}}!"},
+			templateFile:    ".prow/welcome.md",
+			expectedComment: "Welcome @newContributor to #50!",
+		},
+		{
+			name: "template file with changed areas and SIGs",
+			files: map[string]string{
+				".prow/welcome.md@main": "Areas:{{range .ChangedAreas}} {{.}}{{end}}\nSIGs:{{range .SIGs}} sig-{{.}}{{end}}",
+			},
+			templateFile:    ".prow/welcome.md",
+			expectedComment: "Areas: config label_sync prow\nSIGs: sig-contributor-experience sig-testing",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := newFakeClient()
+			fc.files = tc.files
+			fc.changes = []github.PullRequestChange{
+				{Filename: "prow/hook/server.go"},
+				{Filename: "config/jobs/job.yaml"},
+				{Filename: "label_sync/labels.yaml"},
+				{Filename: "README.md"},
+			}
+			c := client{
+				GitHubClient: fc,
+				OwnersClient: foc,
+				Logger:       logrus.WithField("plugin", pluginName),
+			}
+			event := makeFakePullRequestEvent("kubernetes", "test-infra", newContributor, 50, github.PullRequestActionOpened)
+			event.PullRequest.Number = 50
+			event.PullRequest.Base.Ref = "main"
+
+			if err := handlePR(c, plugins.Trigger{TrustedOrg: "kubernetes"}, event, "from the config", tc.templateFile); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if comments := fc.commentsAdded[50]; len(comments) != 1 || comments[0] != tc.expectedComment {
+				t.Errorf("expected comment %q, got %q", tc.expectedComment, comments)
+			}
+		})
+	}
+}
+
 func TestWelcomeConfig(t *testing.T) {
 	var (
 		orgMessage  = "defined message for an org"
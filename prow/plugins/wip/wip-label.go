@@ -18,12 +18,14 @@ limitations under the License.
 // a prefix to their pull request title to the same effect. The submit-
 // queue will not merge pull requests with the work-in-progress label.
 // The label will be removed when the title changes to no longer begin
-// with the prefix.
+// with the prefix. Repos can configure additional title patterns and
+// emojis, and a commit status reflecting the work-in-progress state.
 package wip
 
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
@@ -47,6 +49,7 @@ type event struct {
 	org      string
 	repo     string
 	number   int
+	sha      string
 	title    string
 	draft    bool
 	hasLabel bool
@@ -56,10 +59,42 @@ func init() {
 	plugins.RegisterPullRequestHandler(PluginName, handlePullRequest, helpProvider)
 }
 
-func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
-	// Only the Description field is specified because this plugin is not triggered with commands and is not configurable.
+func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	configInfo := map[string]string{}
+	for _, repo := range enabledRepos {
+		opts := config.WipFor(repo.Org, repo.Repo)
+		var info []string
+		if len(opts.TitlePatterns) > 0 {
+			info = append(info, fmt.Sprintf("PRs whose title matches one of %s are work in progress.", strings.Join(opts.TitlePatterns, ", ")))
+		}
+		if len(opts.Emojis) > 0 {
+			info = append(info, fmt.Sprintf("PRs whose title contains one of %s are work in progress.", strings.Join(opts.Emojis, " ")))
+		}
+		if opts.StatusContext != "" {
+			info = append(info, fmt.Sprintf("The '%s' status is pending while the PR is work in progress.", opts.StatusContext))
+		}
+		if len(info) > 0 {
+			configInfo[repo.String()] = strings.Join(info, " ")
+		}
+	}
+	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
+		Wip: []plugins.Wip{
+			{
+				Repos:         []string{"org/repo"},
+				TitlePatterns: []string{`(?i)^\[draft\]`},
+				Emojis:        []string{"🚧", ":construction:"},
+				StatusContext: "wip",
+			},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", PluginName)
+	}
+	// Only the 'Description', 'Config' and 'Snippet' fields are specified because this plugin is not triggered with commands.
 	return &pluginhelp.PluginHelp{
-			Description: "The wip (Work In Progress) plugin applies the '" + labels.WorkInProgress + "' Label to pull requests whose title starts with 'WIP' or are in the 'draft' stage, and removes it from pull requests when they remove the title prefix or become ready for review. The '" + labels.WorkInProgress + "' Label is typically used to block a pull request from merging while it is still in progress.",
+			Description: "The wip (Work In Progress) plugin applies the '" + labels.WorkInProgress + "' Label to pull requests whose title starts with 'WIP' or are in the 'draft' stage, and removes it from pull requests when they remove the title prefix or become ready for review. The '" + labels.WorkInProgress + "' Label is typically used to block a pull request from merging while it is still in progress. Repos can configure additional title patterns and emojis marking pull requests as work in progress, as well as a commit status that is pending while a pull request is in progress, so that branch protection can block it from merging without Tide.",
+			Config:      configInfo,
+			Snippet:     yamlSnippet,
 		},
 		nil
 }
@@ -69,26 +104,31 @@ type githubClient interface {
 	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
 	AddLabel(owner, repo string, number int, label string) error
 	RemoveLabel(owner, repo string, number int, label string) error
+	CreateStatus(org, repo, ref string, s github.Status) error
 }
 
 func handlePullRequest(pc plugins.Agent, pe github.PullRequestEvent) error {
-	// These are the only actions indicating the PR title may have changed.
-	if pe.Action != github.PullRequestActionOpened &&
-		pe.Action != github.PullRequestActionReopened &&
-		pe.Action != github.PullRequestActionEdited &&
-		pe.Action != github.PullRequestActionReadyForReview &&
-		pe.Action != github.PullRequestActionConvertedToDraft {
-		return nil
-	}
-
 	var (
 		org    = pe.PullRequest.Base.Repo.Owner.Login
 		repo   = pe.PullRequest.Base.Repo.Name
 		number = pe.PullRequest.Number
+		sha    = pe.PullRequest.Head.SHA
 		title  = pe.PullRequest.Title
 		draft  = pe.PullRequest.Draft
+		cfg    = pc.PluginConfig.WipFor(org, repo)
 	)
 
+	// These are the only actions indicating the PR title may have changed.
+	// New commits need the status too.
+	if pe.Action != github.PullRequestActionOpened &&
+		pe.Action != github.PullRequestActionReopened &&
+		pe.Action != github.PullRequestActionEdited &&
+		pe.Action != github.PullRequestActionReadyForReview &&
+		pe.Action != github.PullRequestActionConvertedToDraft &&
+		!(pe.Action == github.PullRequestActionSynchronize && cfg.StatusContext != "") {
+		return nil
+	}
+
 	currentLabels, err := pc.GitHubClient.GetIssueLabels(org, repo, number)
 	if err != nil {
 		return fmt.Errorf("could not get labels for PR %s/%s:%d in WIP plugin: %w", org, repo, number, err)
@@ -103,19 +143,55 @@ func handlePullRequest(pc plugins.Agent, pe github.PullRequestEvent) error {
 		org:      org,
 		repo:     repo,
 		number:   number,
+		sha:      sha,
 		title:    title,
 		draft:    draft,
 		hasLabel: hasLabel,
 	}
-	return handle(pc.GitHubClient, pc.Logger, e)
+	return handle(pc.GitHubClient, pc.Logger, e, cfg)
+}
+
+// isWIP returns true if the PR is a draft or if its title marks it as work
+// in progress.
+func isWIP(e *event, cfg *plugins.Wip) bool {
+	if e.draft || titleRegex.MatchString(e.title) {
+		return true
+	}
+	for _, re := range cfg.TitleRes {
+		if re.MatchString(e.title) {
+			return true
+		}
+	}
+	for _, emoji := range cfg.Emojis {
+		if strings.Contains(e.title, emoji) {
+			return true
+		}
+	}
+	return false
 }
 
 // handle interacts with GitHub to drive the pull request to the
 // proper state by adding and removing comments and labels. If a
 // PR has a WIP prefix, it needs an explanatory comment and label.
 // Otherwise, neither should be present.
-func handle(gc githubClient, le *logrus.Entry, e *event) error {
-	needsLabel := e.draft || titleRegex.MatchString(e.title)
+func handle(gc githubClient, le *logrus.Entry, e *event, cfg *plugins.Wip) error {
+	needsLabel := isWIP(e, cfg)
+
+	if cfg.StatusContext != "" {
+		status := github.Status{
+			Context:     cfg.StatusContext,
+			State:       github.StatusSuccess,
+			Description: "Ready for review.",
+		}
+		if needsLabel {
+			status.State = github.StatusPending
+			status.Description = "Work in progress."
+		}
+		if err := gc.CreateStatus(e.org, e.repo, e.sha, status); err != nil {
+			le.Warnf("error while setting status %q: %v", cfg.StatusContext, err)
+			return err
+		}
+	}
 
 	if needsLabel && !e.hasLabel {
 		if err := gc.AddLabel(e.org, e.repo, e.number, labels.WorkInProgress); err != nil {
@@ -18,6 +18,7 @@ package wip
 
 import (
 	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -25,6 +26,7 @@ import (
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
 	"k8s.io/test-infra/prow/labels"
+	"k8s.io/test-infra/prow/plugins"
 )
 
 func TestWipLabel(t *testing.T) {
@@ -104,7 +106,7 @@ func TestWipLabel(t *testing.T) {
 			hasLabel: tc.hasLabel,
 		}
 
-		if err := handle(fc, logrus.WithField("plugin", PluginName), e); err != nil {
+		if err := handle(fc, logrus.WithField("plugin", PluginName), e, &plugins.Wip{}); err != nil {
 			t.Errorf("For case %s, didn't expect error from wip: %v", tc.name, err)
 			continue
 		}
@@ -127,6 +129,88 @@ func TestWipLabel(t *testing.T) {
 	}
 }
 
+func TestWipConfig(t *testing.T) {
+	cfg := &plugins.Wip{
+		TitleRes:      []*regexp.Regexp{regexp.MustCompile(`(?i)^\[draft\]`)},
+		Emojis:        []string{"🚧", ":construction:"},
+		StatusContext: "wip",
+	}
+
+	var testcases = []struct {
+		name          string
+		title         string
+		draft         bool
+		hasLabel      bool
+		shouldLabel   bool
+		shouldUnlabel bool
+		expected      github.Status
+	}{
+		{
+			name:     "regular PR gets a success status",
+			title:    "title",
+			expected: github.Status{Context: "wip", State: github.StatusSuccess, Description: "Ready for review."},
+		},
+		{
+			name:        "title matching a pattern needs label and pending status",
+			title:       "[Draft] title",
+			shouldLabel: true,
+			expected:    github.Status{Context: "wip", State: github.StatusPending, Description: "Work in progress."},
+		},
+		{
+			name:        "title with an emoji needs label and pending status",
+			title:       "title 🚧",
+			shouldLabel: true,
+			expected:    github.Status{Context: "wip", State: github.StatusPending, Description: "Work in progress."},
+		},
+		{
+			name:        "title with an emoji shortcode needs label and pending status",
+			title:       ":construction: title",
+			shouldLabel: true,
+			expected:    github.Status{Context: "wip", State: github.StatusPending, Description: "Work in progress."},
+		},
+		{
+			name:        "draft PR needs label and pending status",
+			title:       "title",
+			draft:       true,
+			shouldLabel: true,
+			expected:    github.Status{Context: "wip", State: github.StatusPending, Description: "Work in progress."},
+		},
+		{
+			name:          "marker removed from title clears label and status",
+			title:         "title",
+			hasLabel:      true,
+			shouldUnlabel: true,
+			expected:      github.Status{Context: "wip", State: github.StatusSuccess, Description: "Ready for review."},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fakegithub.NewFakeClient()
+			e := &event{
+				org:      "org",
+				repo:     "repo",
+				number:   5,
+				sha:      "sha",
+				title:    tc.title,
+				draft:    tc.draft,
+				hasLabel: tc.hasLabel,
+			}
+			if err := handle(fc, logrus.WithField("plugin", PluginName), e, cfg); err != nil {
+				t.Fatalf("didn't expect error from wip: %v", err)
+			}
+			if labeled := len(fc.IssueLabelsAdded) > 0; labeled != tc.shouldLabel {
+				t.Errorf("expected labeled %t, got added labels %v", tc.shouldLabel, fc.IssueLabelsAdded)
+			}
+			if unlabeled := len(fc.IssueLabelsRemoved) > 0; unlabeled != tc.shouldUnlabel {
+				t.Errorf("expected unlabeled %t, got removed labels %v", tc.shouldUnlabel, fc.IssueLabelsRemoved)
+			}
+			if statuses := fc.CreatedStatuses["sha"]; len(statuses) != 1 || statuses[0] != tc.expected {
+				t.Errorf("expected status %+v, got %+v", tc.expected, statuses)
+			}
+		})
+	}
+}
+
 func TestHasWipPrefix(t *testing.T) {
 	var tests = []struct {
 		title    string
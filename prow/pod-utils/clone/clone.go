@@ -19,9 +19,12 @@ package clone
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"net/url"
+	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +34,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config/secret"
+	"k8s.io/test-infra/prow/git"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/logrusutil"
 )
@@ -41,7 +45,9 @@ type runnable interface {
 
 // Run clones the refs under the prescribed directory and optionally
 // configures the git username and email in the repository as well.
-func Run(refs prowapi.Refs, dir, gitUserName, gitUserEmail, cookiePath string, env []string, userGenerator github.UserGenerator, tokenGenerator github.TokenGenerator) Record {
+// If mirrorDir holds a bare mirror of the repository, objects are borrowed
+// from it while fetching and copied into the clone afterwards.
+func Run(refs prowapi.Refs, dir, gitUserName, gitUserEmail, cookiePath, mirrorDir string, env []string, userGenerator github.UserGenerator, tokenGenerator github.TokenGenerator) Record {
 	record := Record{Refs: refs}
 
 	var (
@@ -99,6 +105,7 @@ func Run(refs prowapi.Refs, dir, gitUserName, gitUserEmail, cookiePath string, e
 	}
 
 	g := gitCtxForRefs(refs, dir, env, user, token)
+	g.mirrorObjects = mirrorObjectsForRefs(mirrorDir, refs)
 	if err := runCommands(g.commandsForBaseRef(refs, gitUserName, gitUserEmail, cookiePath)); err != nil {
 		return record
 	}
@@ -110,6 +117,17 @@ func Run(refs prowapi.Refs, dir, gitUserName, gitUserEmail, cookiePath string, e
 	if err := runCommands(g.commandsForPullRefs(refs, timestamp)); err != nil {
 		return record
 	}
+	if err := runCommands(g.commandsForDissociate()); err != nil {
+		return record
+	}
+	if err := runCommands(g.commandsForSubmodules(refs)); err != nil {
+		record.SubmodulesFailed = true
+		return record
+	}
+	if err := runCommands(g.commandsForLFS(refs)); err != nil {
+		record.LFSFailed = true
+		return record
+	}
 
 	finalSHA, err := g.gitRevParse()
 	if err != nil {
@@ -150,6 +168,22 @@ type gitCtx struct {
 	cloneDir      string
 	env           []string
 	repositoryURI string
+	// mirrorObjects is the object directory of a mirror of the repository
+	// that objects are borrowed from, if any.
+	mirrorObjects string
+}
+
+// mirrorObjectsForRefs returns the object directory of the mirror of the
+// refs under mirrorDir, or "" if there is none.
+func mirrorObjectsForRefs(mirrorDir string, refs prowapi.Refs) string {
+	if mirrorDir == "" {
+		return ""
+	}
+	objects := filepath.Join(git.MirrorPath(mirrorDir, refs.Org, refs.Repo), "objects")
+	if info, err := os.Stat(objects); err != nil || !info.IsDir() {
+		return ""
+	}
+	return objects
 }
 
 // gitCtxForRefs creates a gitCtx based on the provide refs and baseDir.
@@ -189,6 +223,10 @@ func (g *gitCtx) gitCommand(args ...string) cloneCommand {
 	return cloneCommand{dir: g.cloneDir, env: g.env, command: "git", args: args}
 }
 
+// partialCloneFilter makes fetches of partial clones skip the contents of
+// files, git fetches them when they are checked out.
+const partialCloneFilter = "--filter=blob:none"
+
 var (
 	fetchRetries = []time.Duration{
 		100 * time.Millisecond,
@@ -217,6 +255,11 @@ func (g *gitCtx) commandsForBaseRef(refs prowapi.Refs, gitUserName, gitUserEmail
 	commands = append(commands, cloneCommand{dir: "/", env: g.env, command: "mkdir", args: []string{"-p", g.cloneDir}})
 
 	commands = append(commands, g.gitCommand("init"))
+	if g.mirrorObjects != "" {
+		// This is what `git clone --reference` does: objects the mirror has
+		// are not fetched.
+		commands = append(commands, alternatesCommand{dir: g.cloneDir, objects: g.mirrorObjects})
+	}
 	if gitUserName != "" {
 		commands = append(commands, g.gitCommand("config", "user.name", gitUserName))
 	}
@@ -227,10 +270,17 @@ func (g *gitCtx) commandsForBaseRef(refs prowapi.Refs, gitUserName, gitUserEmail
 		commands = append(commands, g.gitCommand("config", "http.cookiefile", cookiePath))
 	}
 
+	if len(refs.SparseCheckout) > 0 {
+		commands = append(commands, g.gitCommand(append([]string{"sparse-checkout", "set", "--cone"}, refs.SparseCheckout...)...))
+	}
+
 	var depthArgs []string
 	if d := refs.CloneDepth; d > 0 {
 		depthArgs = append(depthArgs, "--depth", strconv.Itoa(d))
 	}
+	if refs.PartialClone {
+		depthArgs = append(depthArgs, partialCloneFilter)
+	}
 
 	if !refs.SkipFetchHead {
 		fetchArgs := []string{g.repositoryURI, "--tags", "--prune"}
@@ -306,7 +356,7 @@ func (g *gitCtx) gitRevParse() (string, error) {
 }
 
 // commandsForPullRefs returns the list of commands needed to fetch and
-// merge any pull refs as well as submodules. These commands should be run only
+// merge any pull refs. These commands should be run only
 // after the commands provided by commandsForBaseRef have been run
 // successfully.
 // Each merge commit will be created at sequential seconds after fakeTimestamp.
@@ -323,7 +373,11 @@ func (g *gitCtx) commandsForPullRefs(refs prowapi.Refs, fakeTimestamp int) []run
 		if prRef.Ref != "" {
 			ref = prRef.Ref
 		}
-		commands = append(commands, g.gitFetch(g.repositoryURI, ref))
+		if refs.PartialClone {
+			commands = append(commands, g.gitFetch(partialCloneFilter, g.repositoryURI, ref))
+		} else {
+			commands = append(commands, g.gitFetch(g.repositoryURI, ref))
+		}
 		var prCheckout string
 		if prRef.SHA != "" {
 			prCheckout = prRef.SHA
@@ -336,12 +390,48 @@ func (g *gitCtx) commandsForPullRefs(refs prowapi.Refs, fakeTimestamp int) []run
 		commands = append(commands, gitMergeCommand)
 	}
 
+	return commands
+}
+
+// commandsForSubmodules returns the list of commands needed to initialize
+// the submodules, recursively. These commands should be run only after the
+// commands provided by commandsForPullRefs have been run successfully.
+func (g *gitCtx) commandsForSubmodules(refs prowapi.Refs) []runnable {
 	// unless the user specifically asks us not to, init submodules
-	if !refs.SkipSubmodules {
-		commands = append(commands, g.gitCommand("submodule", "update", "--init", "--recursive"))
+	if refs.SkipSubmodules {
+		return nil
+	}
+	return []runnable{g.gitCommand("submodule", "update", "--init", "--recursive")}
+}
+
+// commandsForLFS returns the list of commands needed to fetch the Git LFS
+// objects of the checked out commit and replace their pointer files with
+// them. These commands should be run only after the commands provided by
+// commandsForPullRefs have been run successfully.
+func (g *gitCtx) commandsForLFS(refs prowapi.Refs) []runnable {
+	if !refs.FetchLFS {
+		return nil
 	}
+	return []runnable{
+		g.gitCommand("lfs", "install", "--local"),
+		retryCommand{
+			runnable: g.gitCommand("lfs", "pull", g.repositoryURI),
+			retries:  fetchRetries,
+		},
+	}
+}
 
-	return commands
+// commandsForDissociate returns the commands that copy the objects borrowed
+// from the mirror into the clone, which is used where the mirror is not
+// mounted, like `git clone --dissociate` does.
+func (g *gitCtx) commandsForDissociate() []runnable {
+	if g.mirrorObjects == "" {
+		return nil
+	}
+	return []runnable{
+		g.gitCommand("repack", "-a", "-d", "-q"),
+		alternatesCommand{dir: g.cloneDir},
+	}
 }
 
 type retryCommand struct {
@@ -389,3 +479,34 @@ func (c cloneCommand) run() (string, string, error) {
 func (c cloneCommand) String() string {
 	return fmt.Sprintf("PWD=%s %s %s %s", c.dir, strings.Join(c.env, " "), c.command, strings.Join(c.args, " "))
 }
+
+// alternatesCommand makes the repository in dir borrow objects from another
+// object directory, or stops it from doing so if objects is empty.
+type alternatesCommand struct {
+	dir     string
+	objects string
+}
+
+func (c alternatesCommand) path() string {
+	return filepath.Join(c.dir, ".git", "objects", "info", "alternates")
+}
+
+func (c alternatesCommand) run() (string, string, error) {
+	if c.objects == "" {
+		if err := os.Remove(c.path()); err != nil && !os.IsNotExist(err) {
+			return c.String(), "", err
+		}
+		return c.String(), "", nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path()), 0755); err != nil {
+		return c.String(), "", err
+	}
+	return c.String(), "", ioutil.WriteFile(c.path(), []byte(c.objects+"\n"), 0644)
+}
+
+func (c alternatesCommand) String() string {
+	if c.objects == "" {
+		return fmt.Sprintf("PWD=%s rm -f .git/objects/info/alternates", c.dir)
+	}
+	return fmt.Sprintf("PWD=%s echo %s > .git/objects/info/alternates", c.dir, c.objects)
+}
@@ -28,6 +28,12 @@ func FormatRecord(record Record) string {
 	if record.Failed {
 		fmt.Fprintln(&output, "# FAILED")
 	}
+	if record.SubmodulesFailed {
+		fmt.Fprintln(&output, "# FAILED to initialize submodules")
+	}
+	if record.LFSFailed {
+		fmt.Fprintln(&output, "# FAILED to fetch Git LFS objects")
+	}
 	if record.Refs.Repo == "" {
 		fmt.Fprintf(&output, "Environment setup")
 	} else {
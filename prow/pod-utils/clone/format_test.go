@@ -111,6 +111,24 @@ func TestFormatRecord(t *testing.T) {
 			},
 			require: []string{"42", "food", "13"},
 		},
+		{
+			name: "include submodule failures",
+			r: Record{
+				Failed:           true,
+				SubmodulesFailed: true,
+			},
+			require: []string{"# FAILED", "# FAILED to initialize submodules"},
+			deny:    []string{"Git LFS"},
+		},
+		{
+			name: "include LFS failures",
+			r: Record{
+				Failed:    true,
+				LFSFailed: true,
+			},
+			require: []string{"# FAILED", "# FAILED to fetch Git LFS objects"},
+			deny:    []string{"submodules"},
+		},
 	}
 
 	for _, tc := range cases {
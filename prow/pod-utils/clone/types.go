@@ -29,6 +29,12 @@ type Record struct {
 	Commands []Command    `json:"commands,omitempty"`
 	Failed   bool         `json:"failed,omitempty"`
 
+	// SubmodulesFailed and LFSFailed tell whether initializing the
+	// submodules or fetching the Git LFS objects of the ref failed,
+	// after the ref itself was checked out.
+	SubmodulesFailed bool `json:"submodules_failed,omitempty"`
+	LFSFailed        bool `json:"lfs_failed,omitempty"`
+
 	// FinalSHA is the SHA from ultimate state of a cloned ref
 	// This is used to populate RepoCommit in started.json properly
 	FinalSHA string `json:"final_sha,omitempty"`
@@ -17,6 +17,7 @@ limitations under the License.
 package decorate
 
 import (
+	"encoding/json"
 	"fmt"
 	"path"
 	"path/filepath"
@@ -60,6 +61,15 @@ const (
 	s3CredentialsMountPath  = "/secrets/s3-storage"
 	outputMountName         = "output"
 	outputMountPath         = "/output"
+	cacheMountNamePrefix    = "cache-"
+	// untrustedCacheSuffix keys the caches of the jobs running the code of PRs
+	// apart from those of the other jobs, so that PRs can't poison the caches
+	// postsubmits and periodics read. Cache names are DNS labels, so no other
+	// cache has the name of the key.
+	untrustedCacheSuffix = ".presubmits"
+	// prerequisiteArtifactsEnv maps the names of the jobs a job ran after to
+	// the URLs of their artifacts, as JSON.
+	prerequisiteArtifactsEnv = "PREREQUISITE_ARTIFACTS"
 )
 
 // Labels returns a string slice with label consts from kube.
@@ -80,6 +90,9 @@ func VolumeMounts(dc *prowapi.DecorationConfig) sets.String {
 	for _, sshKeySecret := range dc.SSHKeySecrets {
 		ret.Insert(sshKeySecret)
 	}
+	for _, cache := range dc.Caches {
+		ret.Insert(cacheMountNamePrefix + cache.Name)
+	}
 	return ret
 }
 
@@ -205,6 +218,17 @@ func ProwJobToPodLocal(pj prowapi.ProwJob, outputDir string) (*coreapi.Pod, erro
 	if err != nil {
 		return nil, err
 	}
+	if len(pj.Status.Prerequisites) > 0 {
+		artifacts := map[string]string{}
+		for _, prerequisite := range pj.Status.Prerequisites {
+			artifacts[prerequisite.Job] = prerequisite.ArtifactsURL
+		}
+		raw, err := json.Marshal(artifacts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal the artifacts of the prerequisites: %w", err)
+		}
+		rawEnv[prerequisiteArtifactsEnv] = string(raw)
+	}
 
 	spec := pj.Spec.PodSpec.DeepCopy()
 	spec.RestartPolicy = "Never"
@@ -365,6 +389,47 @@ func sshVolume(secret string) (coreapi.Volume, coreapi.VolumeMount) {
 	return v, vm
 }
 
+// submoduleCredentialVolume converts a secret holding OAuth tokens for the
+// hosts of submodules into the corresponding volume and mount.
+//
+// This is used by CloneRefs to attach the mount to the clonerefs container.
+func submoduleCredentialVolume(secret string) (coreapi.Volume, coreapi.VolumeMount) {
+	name := strings.Join([]string{"submodule-credentials", secret}, "-")
+	return coreapi.Volume{
+		Name: name,
+		VolumeSource: coreapi.VolumeSource{
+			Secret: &coreapi.SecretVolumeSource{
+				SecretName: secret,
+			},
+		},
+	}, coreapi.VolumeMount{
+		Name:      name,
+		MountPath: path.Join("/secrets/submodules", secret),
+		ReadOnly:  true,
+	}
+}
+
+// gitMirrorVolume converts a claim of a volume holding bare mirrors of
+// repositories into the corresponding volume and read-only mount.
+//
+// This is used by CloneRefs to attach the mount to the clonerefs container.
+func gitMirrorVolume(claim string) (coreapi.Volume, coreapi.VolumeMount) {
+	name := "git-mirror"
+	return coreapi.Volume{
+		Name: name,
+		VolumeSource: coreapi.VolumeSource{
+			PersistentVolumeClaim: &coreapi.PersistentVolumeClaimVolumeSource{
+				ClaimName: claim,
+				ReadOnly:  true,
+			},
+		},
+	}, coreapi.VolumeMount{
+		Name:      name,
+		MountPath: "/git-mirror",
+		ReadOnly:  true,
+	}
+}
+
 // cookiefileVolumes converts a secret holding cookies into the corresponding volume and mount.
 //
 // Secret can be of the form secret-name/base-name or just secret-name.
@@ -424,6 +489,7 @@ func CloneRefs(pj prowapi.ProwJob, codeMount, logMount coreapi.VolumeMount) (*co
 	if len(refs) == 0 { // nothing to clone
 		return nil, nil, nil, nil
 	}
+	refs[0] = withCloneDefaults(refs[0], pj.Spec.DecorationConfig)
 	if codeMount.Name == "" || codeMount.MountPath == "" {
 		return nil, nil, nil, fmt.Errorf("codeMount must set Name and MountPath")
 	}
@@ -461,6 +527,31 @@ func CloneRefs(pj prowapi.ProwJob, codeMount, logMount coreapi.VolumeMount) (*co
 		cloneVolumes = append(cloneVolumes, keyVolume)
 	}
 
+	var submoduleCredentialFiles map[string]string
+	mountedSubmoduleCredentials := sets.NewString()
+	submoduleCredentialSecrets := pj.Spec.DecorationConfig.SubmoduleCredentialSecrets
+	for _, host := range sets.StringKeySet(submoduleCredentialSecrets).List() {
+		secret := submoduleCredentialSecrets[host]
+		volume, mount := submoduleCredentialVolume(secret.Name)
+		if !mountedSubmoduleCredentials.Has(secret.Name) {
+			mountedSubmoduleCredentials.Insert(secret.Name)
+			cloneMounts = append(cloneMounts, mount)
+			cloneVolumes = append(cloneVolumes, volume)
+		}
+		if submoduleCredentialFiles == nil {
+			submoduleCredentialFiles = map[string]string{}
+		}
+		submoduleCredentialFiles[host] = path.Join(mount.MountPath, secret.Key)
+	}
+
+	var gitMirrorDir string
+	if claim := pj.Spec.DecorationConfig.GitMirrorClaim; claim != "" {
+		volume, mount := gitMirrorVolume(claim)
+		cloneMounts = append(cloneMounts, mount)
+		cloneVolumes = append(cloneVolumes, volume)
+		gitMirrorDir = mount.MountPath
+	}
+
 	volume, mount := tmpVolume("clonerefs-tmp")
 	cloneMounts = append(cloneMounts, mount)
 	cloneVolumes = append(cloneVolumes, volume)
@@ -477,18 +568,20 @@ func CloneRefs(pj prowapi.ProwJob, codeMount, logMount coreapi.VolumeMount) (*co
 	}
 
 	env, err := cloneEnv(clonerefs.Options{
-		CookiePath:              cookiefilePath,
-		GitRefs:                 refs,
-		GitUserEmail:            clonerefs.DefaultGitUserEmail,
-		GitUserName:             clonerefs.DefaultGitUserName,
-		HostFingerprints:        pj.Spec.DecorationConfig.SSHHostFingerprints,
-		KeyFiles:                sshKeyPaths,
-		Log:                     CloneLogPath(logMount),
-		SrcRoot:                 codeMount.MountPath,
-		OauthTokenFile:          oauthMountPath,
-		GitHubAPIEndpoints:      githubAPIEndpoints,
-		GitHubAppID:             pj.Spec.DecorationConfig.GitHubAppID,
-		GitHubAppPrivateKeyFile: githubAppPrivateKeyMountPath,
+		CookiePath:               cookiefilePath,
+		GitRefs:                  refs,
+		GitUserEmail:             clonerefs.DefaultGitUserEmail,
+		GitUserName:              clonerefs.DefaultGitUserName,
+		HostFingerprints:         pj.Spec.DecorationConfig.SSHHostFingerprints,
+		KeyFiles:                 sshKeyPaths,
+		Log:                      CloneLogPath(logMount),
+		SrcRoot:                  codeMount.MountPath,
+		OauthTokenFile:           oauthMountPath,
+		GitHubAPIEndpoints:       githubAPIEndpoints,
+		GitHubAppID:              pj.Spec.DecorationConfig.GitHubAppID,
+		GitHubAppPrivateKeyFile:  githubAppPrivateKeyMountPath,
+		SubmoduleCredentialFiles: submoduleCredentialFiles,
+		GitMirrorDir:             gitMirrorDir,
 	})
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("clone env: %w", err)
@@ -539,7 +632,8 @@ func entrypointLocation(tools coreapi.VolumeMount) string {
 }
 
 // InjectEntrypoint will make the entrypoint binary in the tools volume the container's entrypoint, which will output to the log volume.
-func InjectEntrypoint(c *coreapi.Container, timeout, gracePeriod time.Duration, prefix, previousMarker string, exitZero bool, log, tools coreapi.VolumeMount) (*wrapper.Options, error) {
+// If steps are given, the entrypoint runs them instead of the command of the container.
+func InjectEntrypoint(c *coreapi.Container, timeout, gracePeriod time.Duration, steps []prowapi.Step, prefix, previousMarker string, exitZero bool, log, tools coreapi.VolumeMount) (*wrapper.Options, error) {
 	wrapperOptions := &wrapper.Options{
 		Args:          append(c.Command, c.Args...),
 		ContainerName: c.Name,
@@ -555,6 +649,7 @@ func InjectEntrypoint(c *coreapi.Container, timeout, gracePeriod time.Duration,
 		Timeout:        timeout,
 		AlwaysZero:     exitZero,
 		PreviousMarker: previousMarker,
+		Steps:          steps,
 	})
 	if err != nil {
 		return nil, err
@@ -702,6 +797,57 @@ func ToolsMountAndVolume() (coreapi.VolumeMount, coreapi.Volume) {
 		}
 }
 
+// withCloneDefaults returns the refs with the clone options of the decoration
+// config that they leave unset.
+func withCloneDefaults(refs prowapi.Refs, dc *prowapi.DecorationConfig) prowapi.Refs {
+	if refs.CloneDepth == 0 && dc.CloneDepth != nil {
+		refs.CloneDepth = *dc.CloneDepth
+	}
+	if len(refs.SparseCheckout) == 0 {
+		refs.SparseCheckout = dc.SparseCheckout
+	}
+	if !refs.PartialClone && dc.PartialClone != nil {
+		refs.PartialClone = *dc.PartialClone
+	}
+	return refs
+}
+
+// CacheMountsAndVolumes returns the volumes and mounts of the caches of a
+// decorated ProwJob. The caches are mounted from a directory of their volume
+// keyed by their name and the repo and branch of the job, or the name of the
+// job if it has no refs. Presubmits and batches get their own directory.
+func CacheMountsAndVolumes(pj prowapi.ProwJob) ([]coreapi.VolumeMount, []coreapi.Volume) {
+	refs := pj.Spec.Refs
+	if refs == nil && len(pj.Spec.ExtraRefs) > 0 {
+		refs = &pj.Spec.ExtraRefs[0]
+	}
+	var mounts []coreapi.VolumeMount
+	var volumes []coreapi.Volume
+	for _, cache := range pj.Spec.DecorationConfig.Caches {
+		key := cache.Name
+		if pj.Spec.Type == prowapi.PresubmitJob || pj.Spec.Type == prowapi.BatchJob {
+			key += untrustedCacheSuffix
+		}
+		subPath := path.Join(key, "jobs", pj.Spec.Job)
+		if refs != nil {
+			subPath = path.Join(key, refs.Org, refs.Repo, refs.BaseRef)
+		}
+		mounts = append(mounts, coreapi.VolumeMount{
+			Name:      cacheMountNamePrefix + cache.Name,
+			MountPath: cache.MountPath,
+			SubPath:   subPath,
+		})
+		volume := coreapi.Volume{Name: cacheMountNamePrefix + cache.Name}
+		if cache.CSI != nil {
+			volume.CSI = cache.CSI.DeepCopy()
+		} else {
+			volume.PersistentVolumeClaim = &coreapi.PersistentVolumeClaimVolumeSource{ClaimName: cache.PersistentVolumeClaim}
+		}
+		volumes = append(volumes, volume)
+	}
+	return mounts, volumes
+}
+
 func decorate(spec *coreapi.PodSpec, pj *prowapi.ProwJob, rawEnv map[string]string, outputDir string) error {
 	// TODO(fejta): we should pass around volume names rather than forcing particular mount paths.
 
@@ -733,6 +879,13 @@ func decorate(spec *coreapi.PodSpec, pj *prowapi.ProwJob, rawEnv map[string]stri
 
 	blobStorageVolumes, blobStorageMounts, blobStorageOptions := BlobStorageOptions(*pj.Spec.DecorationConfig, localMode)
 
+	cacheMounts, cacheVolumes := CacheMountsAndVolumes(*pj)
+	for _, cache := range pj.Spec.DecorationConfig.Caches {
+		if cache.Env != "" {
+			rawEnv[cache.Env] = cache.MountPath
+		}
+	}
+
 	cloner, refs, cloneVolumes, err := CloneRefs(*pj, codeMount, logMount)
 	if err != nil {
 		return fmt.Errorf("create clonerefs container: %w", err)
@@ -782,7 +935,7 @@ func decorate(spec *coreapi.PodSpec, pj *prowapi.ProwJob, rawEnv map[string]stri
 		if len(spec.Containers) == 1 {
 			prefix = ""
 		}
-		wrapperOptions, err := InjectEntrypoint(&spec.Containers[i], pj.Spec.DecorationConfig.Timeout.Get(), pj.Spec.DecorationConfig.GracePeriod.Get(), prefix, previous, exitZero, logMount, toolsMount)
+		wrapperOptions, err := InjectEntrypoint(&spec.Containers[i], pj.Spec.DecorationConfig.Timeout.Get(), pj.Spec.DecorationConfig.GracePeriod.Get(), pj.Spec.DecorationConfig.Steps, prefix, previous, exitZero, logMount, toolsMount)
 		if err != nil {
 			return fmt.Errorf("wrap container: %w", err)
 		}
@@ -807,6 +960,13 @@ func decorate(spec *coreapi.PodSpec, pj *prowapi.ProwJob, rawEnv map[string]stri
 		spec.Volumes = append(spec.Volumes, *outputVolume)
 	}
 
+	if len(cacheMounts) > 0 {
+		for i, container := range spec.Containers {
+			spec.Containers[i].VolumeMounts = append(container.VolumeMounts, cacheMounts...)
+		}
+		spec.Volumes = append(spec.Volumes, cacheVolumes...)
+	}
+
 	if len(refs) > 0 {
 		for i, container := range spec.Containers {
 			spec.Containers[i].WorkingDir = DetermineWorkDir(codeMount.MountPath, refs)
@@ -855,6 +1015,7 @@ func Sidecar(config *prowapi.DecorationConfig, gcsOptions gcsupload.Options, blo
 		secretVolumePaths = append(secretVolumePaths, volumeMount.MountPath)
 	}
 	gcsOptions.Items = append(gcsOptions.Items, artifactsDir(logMount))
+	gcsOptions.ArtifactRetention = config.ArtifactRetention
 	censoringOptions := &sidecar.CensoringOptions{
 		SecretDirectories: secretVolumePaths,
 	}
@@ -870,6 +1031,7 @@ func Sidecar(config *prowapi.DecorationConfig, gcsOptions gcsupload.Options, blo
 		EntryError:       requirePassingEntries,
 		IgnoreInterrupts: ignoreInterrupts,
 		CensoringOptions: censoringOptions,
+		StreamingOptions: config.StreamingOptions,
 	})
 
 	if err != nil {
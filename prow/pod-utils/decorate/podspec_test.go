@@ -474,6 +474,97 @@ func TestCloneRefs(t *testing.T) {
 				tmpVolume,
 			},
 		},
+		{
+			name: "include submodule credential secrets when set",
+			pj: prowapi.ProwJob{
+				Spec: prowapi.ProwJobSpec{
+					ExtraRefs: []prowapi.Refs{{}},
+					DecorationConfig: &prowapi.DecorationConfig{
+						UtilityImages: &prowapi.UtilityImages{},
+						SubmoduleCredentialSecrets: map[string]prowapi.OauthTokenSecret{
+							"gitlab.com":         {Name: "submodule-tokens", Key: "gitlab"},
+							"git.example.com":    {Name: "example-token", Key: "token"},
+							"gitlab.example.com": {Name: "submodule-tokens", Key: "example"},
+						},
+					},
+				},
+			},
+			expected: &coreapi.Container{
+				Name: cloneRefsName,
+				Env: envOrDie(clonerefs.Options{
+					GitRefs:            []prowapi.Refs{{}},
+					GitUserEmail:       clonerefs.DefaultGitUserEmail,
+					GitUserName:        clonerefs.DefaultGitUserName,
+					SrcRoot:            codeMount.MountPath,
+					Log:                CloneLogPath(logMount),
+					GitHubAPIEndpoints: []string{github.DefaultAPIEndpoint},
+					SubmoduleCredentialFiles: map[string]string{
+						"gitlab.com":         "/secrets/submodules/submodule-tokens/gitlab",
+						"git.example.com":    "/secrets/submodules/example-token/token",
+						"gitlab.example.com": "/secrets/submodules/submodule-tokens/example",
+					},
+				}),
+				VolumeMounts: []coreapi.VolumeMount{
+					logMount, codeMount,
+					{Name: "submodule-credentials-example-token", ReadOnly: true, MountPath: "/secrets/submodules/example-token"},
+					{Name: "submodule-credentials-submodule-tokens", ReadOnly: true, MountPath: "/secrets/submodules/submodule-tokens"},
+					tmpMount,
+				},
+			},
+			volumes: []coreapi.Volume{
+				{
+					Name: "submodule-credentials-example-token",
+					VolumeSource: coreapi.VolumeSource{
+						Secret: &coreapi.SecretVolumeSource{SecretName: "example-token"},
+					},
+				},
+				{
+					Name: "submodule-credentials-submodule-tokens",
+					VolumeSource: coreapi.VolumeSource{
+						Secret: &coreapi.SecretVolumeSource{SecretName: "submodule-tokens"},
+					},
+				},
+				tmpVolume,
+			},
+		},
+		{
+			name: "include git mirror when set",
+			pj: prowapi.ProwJob{
+				Spec: prowapi.ProwJobSpec{
+					ExtraRefs: []prowapi.Refs{{}},
+					DecorationConfig: &prowapi.DecorationConfig{
+						UtilityImages:  &prowapi.UtilityImages{},
+						GitMirrorClaim: "git-mirrors",
+					},
+				},
+			},
+			expected: &coreapi.Container{
+				Name: cloneRefsName,
+				Env: envOrDie(clonerefs.Options{
+					GitRefs:            []prowapi.Refs{{}},
+					GitUserEmail:       clonerefs.DefaultGitUserEmail,
+					GitUserName:        clonerefs.DefaultGitUserName,
+					SrcRoot:            codeMount.MountPath,
+					Log:                CloneLogPath(logMount),
+					GitHubAPIEndpoints: []string{github.DefaultAPIEndpoint},
+					GitMirrorDir:       "/git-mirror",
+				}),
+				VolumeMounts: []coreapi.VolumeMount{
+					logMount, codeMount,
+					{Name: "git-mirror", ReadOnly: true, MountPath: "/git-mirror"},
+					tmpMount,
+				},
+			},
+			volumes: []coreapi.Volume{
+				{
+					Name: "git-mirror",
+					VolumeSource: coreapi.VolumeSource{
+						PersistentVolumeClaim: &coreapi.PersistentVolumeClaimVolumeSource{ClaimName: "git-mirrors", ReadOnly: true},
+					},
+				},
+				tmpVolume,
+			},
+		},
 		{
 			name: "include custom GitHub API endpoints when set",
 			pj: prowapi.ProwJob{
@@ -1198,6 +1289,203 @@ func TestProwJobToPod_setsTerminationGracePeriodSeconds(t *testing.T) {
 	}
 }
 
+func TestProwJobToPod_setsPrerequisiteArtifacts(t *testing.T) {
+	pj := prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod"},
+		Spec: prowapi.ProwJobSpec{
+			Type:     prowapi.PostsubmitJob,
+			Job:      "deploy",
+			Refs:     &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "main", BaseSHA: "abcdef"},
+			PodSpec:  &coreapi.PodSpec{Containers: []coreapi.Container{{Image: "tester"}}},
+			RunAfter: []string{"build"},
+		},
+		Status: prowapi.ProwJobStatus{
+			BuildID: "2",
+			Prerequisites: []prowapi.ProwJobPrerequisite{{
+				Job:          "build",
+				ProwJob:      "build-pj",
+				ArtifactsURL: "gs://bucket/logs/build/1/artifacts",
+			}},
+		},
+	}
+	pod, err := ProwJobToPod(pj)
+	if err != nil {
+		t.Fatalf("failed to create pod: %v", err)
+	}
+	expected := coreapi.EnvVar{Name: prerequisiteArtifactsEnv, Value: `{"build":"gs://bucket/logs/build/1/artifacts"}`}
+	for _, env := range pod.Spec.Containers[0].Env {
+		if env.Name == expected.Name {
+			if env != expected {
+				t.Errorf("expected env %v, got %v", expected, env)
+			}
+			return
+		}
+	}
+	t.Errorf("expected env %s to be set", expected.Name)
+}
+
+func TestWithCloneDefaults(t *testing.T) {
+	depth, partial := 1, true
+	dc := &prowapi.DecorationConfig{CloneDepth: &depth, SparseCheckout: []string{"docs"}, PartialClone: &partial}
+	testCases := []struct {
+		name     string
+		refs     prowapi.Refs
+		dc       *prowapi.DecorationConfig
+		expected prowapi.Refs
+	}{
+		{
+			name:     "no clone options",
+			refs:     prowapi.Refs{Org: "org", Repo: "repo"},
+			dc:       &prowapi.DecorationConfig{},
+			expected: prowapi.Refs{Org: "org", Repo: "repo"},
+		},
+		{
+			name:     "clone options of the decoration config",
+			refs:     prowapi.Refs{Org: "org", Repo: "repo"},
+			dc:       dc,
+			expected: prowapi.Refs{Org: "org", Repo: "repo", CloneDepth: 1, SparseCheckout: []string{"docs"}, PartialClone: true},
+		},
+		{
+			name:     "clone options of the refs take precedence",
+			refs:     prowapi.Refs{Org: "org", Repo: "repo", CloneDepth: 10, SparseCheckout: []string{"hack"}},
+			dc:       dc,
+			expected: prowapi.Refs{Org: "org", Repo: "repo", CloneDepth: 10, SparseCheckout: []string{"hack"}, PartialClone: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := withCloneDefaults(tc.refs, tc.dc); !equality.Semantic.DeepEqual(tc.expected, actual) {
+				t.Errorf("unexpected refs:\n%s", diff.ObjectReflectDiff(tc.expected, actual))
+			}
+		})
+	}
+}
+
+func TestCacheMountsAndVolumes(t *testing.T) {
+	caches := []prowapi.Cache{
+		{Name: "go-mod", MountPath: "/caches/go-mod", Env: "GOMODCACHE", PersistentVolumeClaim: "caches"},
+		{Name: "bazel", MountPath: "/caches/bazel", CSI: &coreapi.CSIVolumeSource{Driver: "cache.csi.example.com"}},
+	}
+	expectedVolumes := []coreapi.Volume{
+		{
+			Name:         "cache-go-mod",
+			VolumeSource: coreapi.VolumeSource{PersistentVolumeClaim: &coreapi.PersistentVolumeClaimVolumeSource{ClaimName: "caches"}},
+		},
+		{
+			Name:         "cache-bazel",
+			VolumeSource: coreapi.VolumeSource{CSI: &coreapi.CSIVolumeSource{Driver: "cache.csi.example.com"}},
+		},
+	}
+	testCases := []struct {
+		name           string
+		spec           prowapi.ProwJobSpec
+		expectedMounts []coreapi.VolumeMount
+	}{
+		{
+			name: "caches are keyed by repo and branch",
+			spec: prowapi.ProwJobSpec{
+				Job:  "post-repo-unit",
+				Type: prowapi.PostsubmitJob,
+				Refs: &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "release-1.0"},
+			},
+			expectedMounts: []coreapi.VolumeMount{
+				{Name: "cache-go-mod", MountPath: "/caches/go-mod", SubPath: "go-mod/org/repo/release-1.0"},
+				{Name: "cache-bazel", MountPath: "/caches/bazel", SubPath: "bazel/org/repo/release-1.0"},
+			},
+		},
+		{
+			name: "caches of presubmits are kept apart",
+			spec: prowapi.ProwJobSpec{
+				Job:  "pull-repo-unit",
+				Type: prowapi.PresubmitJob,
+				Refs: &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "release-1.0", Pulls: []prowapi.Pull{{Number: 1}}},
+			},
+			expectedMounts: []coreapi.VolumeMount{
+				{Name: "cache-go-mod", MountPath: "/caches/go-mod", SubPath: "go-mod.presubmits/org/repo/release-1.0"},
+				{Name: "cache-bazel", MountPath: "/caches/bazel", SubPath: "bazel.presubmits/org/repo/release-1.0"},
+			},
+		},
+		{
+			name: "caches of batches are kept apart",
+			spec: prowapi.ProwJobSpec{
+				Job:  "pull-repo-unit",
+				Type: prowapi.BatchJob,
+				Refs: &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "main", Pulls: []prowapi.Pull{{Number: 1}, {Number: 2}}},
+			},
+			expectedMounts: []coreapi.VolumeMount{
+				{Name: "cache-go-mod", MountPath: "/caches/go-mod", SubPath: "go-mod.presubmits/org/repo/main"},
+				{Name: "cache-bazel", MountPath: "/caches/bazel", SubPath: "bazel.presubmits/org/repo/main"},
+			},
+		},
+		{
+			name: "caches of jobs with only extra refs are keyed by the first one",
+			spec: prowapi.ProwJobSpec{
+				Job:       "periodic-repo-unit",
+				ExtraRefs: []prowapi.Refs{{Org: "org", Repo: "repo", BaseRef: "main"}, {Org: "org", Repo: "other", BaseRef: "main"}},
+			},
+			expectedMounts: []coreapi.VolumeMount{
+				{Name: "cache-go-mod", MountPath: "/caches/go-mod", SubPath: "go-mod/org/repo/main"},
+				{Name: "cache-bazel", MountPath: "/caches/bazel", SubPath: "bazel/org/repo/main"},
+			},
+		},
+		{
+			name: "caches of jobs without refs are keyed by job",
+			spec: prowapi.ProwJobSpec{Job: "periodic-cleanup"},
+			expectedMounts: []coreapi.VolumeMount{
+				{Name: "cache-go-mod", MountPath: "/caches/go-mod", SubPath: "go-mod/jobs/periodic-cleanup"},
+				{Name: "cache-bazel", MountPath: "/caches/bazel", SubPath: "bazel/jobs/periodic-cleanup"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.spec.DecorationConfig = &prowapi.DecorationConfig{Caches: caches}
+			mounts, volumes := CacheMountsAndVolumes(prowapi.ProwJob{Spec: tc.spec})
+			if !equality.Semantic.DeepEqual(tc.expectedMounts, mounts) {
+				t.Errorf("unexpected mounts:\n%s", diff.ObjectReflectDiff(tc.expectedMounts, mounts))
+			}
+			if !equality.Semantic.DeepEqual(expectedVolumes, volumes) {
+				t.Errorf("unexpected volumes:\n%s", diff.ObjectReflectDiff(expectedVolumes, volumes))
+			}
+		})
+	}
+}
+
+func TestDecorateMountsCaches(t *testing.T) {
+	pj := &prowapi.ProwJob{
+		Spec: prowapi.ProwJobSpec{
+			Job:     "pull-repo-unit",
+			Refs:    &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "main"},
+			PodSpec: &coreapi.PodSpec{Containers: []coreapi.Container{{Name: "test", Command: []string{"go", "test"}}}},
+			DecorationConfig: &prowapi.DecorationConfig{
+				UtilityImages:    &prowapi.UtilityImages{},
+				GCSConfiguration: &prowapi.GCSConfiguration{Bucket: "bucket"},
+				Caches:           []prowapi.Cache{{Name: "go-mod", MountPath: "/caches/go-mod", Env: "GOMODCACHE", PersistentVolumeClaim: "caches"}},
+			},
+		},
+	}
+	if err := decorate(pj.Spec.PodSpec, pj, map[string]string{}, ""); err != nil {
+		t.Fatalf("decoration failed: %v", err)
+	}
+	test := pj.Spec.PodSpec.Containers[0]
+	var mounted bool
+	for _, mount := range test.VolumeMounts {
+		mounted = mounted || mount.Name == "cache-go-mod"
+	}
+	if !mounted {
+		t.Errorf("expected the cache to be mounted into the test container, got mounts %v", test.VolumeMounts)
+	}
+	var env bool
+	for _, envVar := range test.Env {
+		env = env || envVar == coreapi.EnvVar{Name: "GOMODCACHE", Value: "/caches/go-mod"}
+	}
+	if !env {
+		t.Errorf("expected GOMODCACHE to be set in the test container, got env %v", test.Env)
+	}
+}
+
 func TestSidecar(t *testing.T) {
 	var testCases = []struct {
 		name                                    string
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"cloud.google.com/go/storage"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/api/option"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	pkgio "k8s.io/test-infra/prow/io"
+	"k8s.io/test-infra/prow/io/providers"
+)
+
+// chunkSuffix is the suffix of the objects chunks are uploaded to before
+// they are composed with the objects they are appended to.
+const chunkSuffix = ".chunk"
+
+// ErrAppendUnsupported is returned when appending to the objects of a bucket
+// is not supported, which is the case for buckets that are not in GCS.
+var ErrAppendUnsupported = errors.New("appending is only supported for GCS buckets")
+
+// Append appends all of the data in the uploadTargets map to the objects in
+// the GCS bucket, creating the objects that do not exist. The map is keyed
+// on blob storage path under the bucket. Every chunk is uploaded next to its
+// object, then composed with it and deleted, so that only the new data is
+// uploaded.
+func Append(ctx context.Context, bucket, gcsCredentialsFile string, uploadTargets map[string]UploadFunc) error {
+	parsedBucket, err := url.Parse(bucket)
+	if err != nil {
+		return fmt.Errorf("cannot parse bucket name %s: %w", bucket, err)
+	}
+	bucketName := parsedBucket.Host
+	switch parsedBucket.Scheme {
+	case "":
+		bucketName = bucket
+	case providers.GS:
+	default:
+		return ErrAppendUnsupported
+	}
+
+	chunkTargets := make(map[string]UploadFunc, len(uploadTargets))
+	for dest, upload := range uploadTargets {
+		chunkTargets[dest+chunkSuffix] = upload
+	}
+	if err := Upload(ctx, bucket, gcsCredentialsFile, "", chunkTargets); err != nil {
+		return err
+	}
+
+	var opts []option.ClientOption
+	if gcsCredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(gcsCredentialsFile))
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("could not create GCS client: %w", err)
+	}
+	defer client.Close()
+	bkt := client.Bucket(bucketName)
+
+	var errs []error
+	for dest := range uploadTargets {
+		object, chunk := bkt.Object(dest), bkt.Object(dest+chunkSuffix)
+		sources := []*storage.ObjectHandle{object, chunk}
+		if _, err := object.Attrs(ctx); err == storage.ErrObjectNotExist {
+			sources = sources[1:]
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("could not get the attributes of %s: %w", dest, err))
+			continue
+		}
+		if _, err := object.ComposerFrom(sources...).Run(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("could not append to %s: %w", dest, err))
+			continue
+		}
+		if err := chunk.Delete(ctx); err != nil {
+			logrus.WithError(err).WithField("dest", dest+chunkSuffix).Warn("Failed to delete chunk.")
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// LocalAppend appends all of the data in the uploadTargets map to local files,
+// creating the files that do not exist. The map is keyed on file path under
+// the exportDir.
+func LocalAppend(ctx context.Context, exportDir string, uploadTargets map[string]UploadFunc) error {
+	dtw := func(dest string) dataWriter {
+		return &appendFileWriter{path: filepath.Join(exportDir, dest)}
+	}
+	return upload(dtw, uploadTargets)
+}
+
+// appendFileWriter appends to a local file, which is opened on the first
+// write.
+type appendFileWriter struct {
+	path string
+	file *os.File
+}
+
+func (w *appendFileWriter) Write(p []byte) (int, error) {
+	if w.file == nil {
+		if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+			return 0, err
+		}
+		file, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return 0, err
+		}
+		w.file = file
+	}
+	return w.file.Write(p)
+}
+
+func (w *appendFileWriter) Close() error {
+	if w.file == nil {
+		if _, err := w.Write(nil); err != nil {
+			return err
+		}
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *appendFileWriter) ApplyWriterOptions(pkgio.WriterOptions) {}
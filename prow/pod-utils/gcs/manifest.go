@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcs
+
+import (
+	"github.com/mattn/go-zglob"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+// RetentionClassMetadataKey is the key of the object metadata that holds the
+// retention class of an artifact, which lifecycle policies can act on.
+const RetentionClassMetadataKey = "retention-class"
+
+// ArtifactManifest holds artifacts-manifest.json data
+type ArtifactManifest struct {
+	// Artifacts are sorted by their path.
+	Artifacts []ArtifactManifestEntry `json:"artifacts"`
+}
+
+// ArtifactManifestEntry describes an uploaded build log or artifact.
+type ArtifactManifestEntry struct {
+	// Path is where the object is uploaded to, relative to the
+	// directory of the job in blob storage.
+	Path string `json:"path"`
+	// Size is the size of the object in bytes.
+	Size int64 `json:"size"`
+	// SHA256 is the hex encoded SHA256 checksum of the object.
+	SHA256 string `json:"sha256"`
+	// ContentType is the media type of the object.
+	ContentType string `json:"content_type,omitempty"`
+	// RetentionClass is the retention class of the artifact, if any.
+	RetentionClass string `json:"retention_class,omitempty"`
+}
+
+// Entries returns every entry of the manifest by its path.
+func (m ArtifactManifest) Entries() map[string]ArtifactManifestEntry {
+	entries := make(map[string]ArtifactManifestEntry, len(m.Artifacts))
+	for _, artifact := range m.Artifacts {
+		entries[artifact.Path] = artifact
+	}
+	return entries
+}
+
+// RetentionClass returns the class of the first retention class that matches
+// the artifact at the path relative to $ARTIFACTS, or an empty string if none
+// does.
+func RetentionClass(retention []prowapi.ArtifactRetentionClass, relPath string) string {
+	for _, class := range retention {
+		for _, glob := range class.Artifacts {
+			if found, err := zglob.Match(glob, relPath); err == nil && found {
+				return class.Class
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcs
+
+import (
+	"testing"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+func TestRetentionClass(t *testing.T) {
+	retention := []prowapi.ArtifactRetentionClass{
+		{Class: "release", Artifacts: []string{"release/**"}},
+		{Class: "short-lived", Artifacts: []string{"**/*.tar", "**/*.tar.gz"}},
+	}
+	testCases := []struct {
+		name     string
+		relPath  string
+		expected string
+	}{
+		{
+			name:     "artifact matches a class",
+			relPath:  "images/node.tar",
+			expected: "short-lived",
+		},
+		{
+			name:     "first matching class wins",
+			relPath:  "release/kubernetes.tar.gz",
+			expected: "release",
+		},
+		{
+			name:    "artifact matches no class",
+			relPath: "junit_01.xml",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := RetentionClass(retention, tc.relPath); actual != tc.expected {
+				t.Errorf("expected retention class %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
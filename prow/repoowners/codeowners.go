@@ -0,0 +1,209 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repoowners
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/pkg/layeredsets"
+)
+
+// codeOwnersLocations are the paths, relative to the repo root, at which
+// GitHub looks for a CODEOWNERS file, in order of precedence.
+var codeOwnersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeOwnersRule is a single line of a CODEOWNERS file.
+type codeOwnersRule struct {
+	pattern string
+	re      *regexp.Regexp
+	owners  sets.String
+}
+
+// codeOwners holds the rules of a CODEOWNERS file in the order they appear.
+// As in GitHub, the last rule matching a path determines its owners.
+type codeOwners []codeOwnersRule
+
+// parseCodeOwners parses the content of a CODEOWNERS file. Only user owners
+// ("@login") are kept: team and email owners cannot be resolved from the
+// repository alone and are ignored.
+// https://docs.github.com/en/repositories/managing-your-repositorys-settings-and-features/customizing-your-repository/about-code-owners
+func parseCodeOwners(b []byte) (codeOwners, error) {
+	var rules codeOwners
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(text, " #"); i >= 0 {
+			text = text[:i]
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		re, err := codeOwnersPatternToRegexp(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		owners := sets.NewString()
+		for _, owner := range fields[1:] {
+			if !strings.HasPrefix(owner, "@") || strings.Contains(owner, "/") {
+				continue
+			}
+			owners.Insert(github.NormLogin(owner))
+		}
+		rules = append(rules, codeOwnersRule{pattern: fields[0], re: re, owners: owners})
+	}
+	return rules, scanner.Err()
+}
+
+// codeOwnersPatternToRegexp converts a CODEOWNERS pattern, which follows most
+// of the .gitignore rules, into a regexp matching paths relative to the repo root.
+func codeOwnersPatternToRegexp(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "!") {
+		return nil, fmt.Errorf("negative patterns are not supported: %q", pattern)
+	}
+	p := strings.TrimSuffix(pattern, "/")
+	// A pattern containing a slash anywhere but at its end is relative
+	// to the repo root, otherwise it matches at any depth.
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case p[i] == '*':
+			expr.WriteString("[^/]*")
+		case p[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	// A matched directory owns everything below it, except that "dir/*"
+	// only matches the direct children of dir.
+	if strings.HasSuffix(p, "/*") {
+		expr.WriteString("$")
+	} else {
+		expr.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(expr.String())
+}
+
+// ruleFor returns the last rule matching the path.
+func (c codeOwners) ruleFor(path string) (codeOwnersRule, bool) {
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].re.MatchString(path) {
+			return c[i], true
+		}
+	}
+	return codeOwnersRule{}, false
+}
+
+// ownersPathFor returns the path to look the owners of the path up with, which
+// is the path itself since the rules of a CODEOWNERS file are not tied to a
+// directory, or an empty string if no rule matches.
+func (c codeOwners) ownersPathFor(path string) string {
+	if _, ok := c.ruleFor(path); ok {
+		return path
+	}
+	return ""
+}
+
+// ownersFor returns the owners of the last rule matching the path.
+func (c codeOwners) ownersFor(path string) sets.String {
+	if rule, ok := c.ruleFor(path); ok {
+		return rule.owners.Union(nil)
+	}
+	return sets.NewString()
+}
+
+// entriesForFile returns the owners of the path as a single layer.
+func (c codeOwners) entriesForFile(path string) layeredsets.String {
+	return layeredsets.NewString(c.ownersFor(path).List()...)
+}
+
+func (c codeOwners) allOwners() sets.String {
+	all := sets.NewString()
+	for _, rule := range c {
+		all = all.Union(rule.owners)
+	}
+	return all
+}
+
+func (c codeOwners) filterCollaborators(collabs sets.String) codeOwners {
+	filtered := make(codeOwners, 0, len(c))
+	for _, rule := range c {
+		rule.owners = rule.owners.Intersection(collabs)
+		filtered = append(filtered, rule)
+	}
+	return filtered
+}
+
+// loadCodeOwnersFrom loads the first CODEOWNERS file found in the repo.
+// It returns nil if the repo has no CODEOWNERS file.
+func loadCodeOwnersFrom(baseDir string, log *logrus.Entry) codeOwners {
+	for _, location := range codeOwnersLocations {
+		path := filepath.Join(baseDir, location)
+		b, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			log.WithError(err).Warnf("Failed to read CODEOWNERS file %q.", path)
+			return nil
+		}
+		rules, err := parseCodeOwners(b)
+		if err != nil {
+			log.WithError(err).Errorf("Failed to parse CODEOWNERS file %q.", path)
+			return nil
+		}
+		log.Infof("Loaded %d rules from %q.", len(rules), path)
+		return rules
+	}
+	return nil
+}
+
+// isCodeOwnersFile returns whether the path is one of the CODEOWNERS locations.
+func isCodeOwnersFile(path string) bool {
+	for _, location := range codeOwnersLocations {
+		if path == location {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repoowners
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/git/localgit"
+)
+
+const testCodeOwners = `# This is a comment.
+*       @global-owner1 @Global-Owner2
+*.js    @js-owner # trailing comment
+docs/*  docs@example.com
+apps/   @octocat
+/build/logs/ @doctocat @org/team
+/scripts/ @doctocat @octocat
+**/logs @logs-owner
+/unowned/generated
+`
+
+func TestCodeOwnersOwnersFor(t *testing.T) {
+	rules, err := parseCodeOwners([]byte(testCodeOwners))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing CODEOWNERS: %v.", err)
+	}
+	tests := map[string]sets.String{
+		"README.md":                 sets.NewString("global-owner1", "global-owner2"),
+		"src/main.js":               sets.NewString("js-owner"),
+		"docs/getting-started.md":   sets.NewString(),
+		"docs/build-app/trouble.md": sets.NewString("global-owner1", "global-owner2"),
+		"apps/web/index.html":       sets.NewString("octocat"),
+		"nested/apps/main.go":       sets.NewString("octocat"),
+		"build/logs/output.txt":     sets.NewString("logs-owner"),
+		"scripts/release.sh":        sets.NewString("doctocat", "octocat"),
+		"nested/scripts/release.sh": sets.NewString("global-owner1", "global-owner2"),
+		"deep/logs/today.txt":       sets.NewString("logs-owner"),
+		"unowned/generated/zz.go":   sets.NewString(),
+	}
+	for path, expected := range tests {
+		if got := rules.ownersFor(path); !got.Equal(expected) {
+			t.Errorf("For path %q expected owners %q, but got %q.", path, expected.List(), got.List())
+		}
+	}
+}
+
+func TestCodeOwnersFindOwnersForFile(t *testing.T) {
+	rules, err := parseCodeOwners([]byte(testCodeOwners))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing CODEOWNERS: %v.", err)
+	}
+	ro := &RepoOwners{codeOwners: rules}
+	for _, path := range []string{"README.md", "src/main.js", "apps/web/index.html", "scripts/release.sh", "build/logs/output.txt"} {
+		if got := ro.FindApproverOwnersForFile(path); got != path {
+			t.Errorf("For path %q expected approver owners %q, but got %q.", path, path, got)
+		}
+		if got := ro.FindReviewersOwnersForFile(path); got != path {
+			t.Errorf("For path %q expected reviewer owners %q, but got %q.", path, path, got)
+		}
+		if !ro.IsNoParentOwners(path) {
+			t.Errorf("Expected the rule of %q not to inherit the owners of other rules.", path)
+		}
+	}
+	if got, expected := ro.Approvers("scripts/release.sh").Set(), sets.NewString("doctocat", "octocat"); !got.Equal(expected) {
+		t.Errorf("For path %q expected approvers %q, but got %q.", "scripts/release.sh", expected.List(), got.List())
+	}
+}
+
+func TestCodeOwnersLastMatchWins(t *testing.T) {
+	// The path looks like the pattern of the first rule, but the last
+	// matching rule owns it.
+	rules, err := parseCodeOwners([]byte("*.js @js-owner\n** @everyone\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing CODEOWNERS: %v.", err)
+	}
+	if got, expected := rules.ownersFor("*.js"), sets.NewString("everyone"); !got.Equal(expected) {
+		t.Errorf("For path %q expected owners %q, but got %q.", "*.js", expected.List(), got.List())
+	}
+}
+
+func TestParseCodeOwnersNegativePattern(t *testing.T) {
+	if _, err := parseCodeOwners([]byte("!docs/ @octocat")); err == nil {
+		t.Error("Expected an error for a negative pattern, got none.")
+	}
+}
+
+func TestLoadRepoOwnersFromCodeOwners(t *testing.T) {
+	testLoadRepoOwnersFromCodeOwners(localgit.New, t)
+}
+
+func TestLoadRepoOwnersFromCodeOwnersV2(t *testing.T) {
+	testLoadRepoOwnersFromCodeOwners(localgit.NewV2, t)
+}
+
+func testLoadRepoOwnersFromCodeOwners(clients localgit.Clients, t *testing.T) {
+	tests := []struct {
+		name              string
+		files             map[string][]byte
+		expectedApprovers map[string]sets.String
+	}{
+		{
+			name: "repo with only a CODEOWNERS file uses it",
+			files: map[string][]byte{
+				".github/CODEOWNERS": []byte("* @alice\n/src/ @Bob\n"),
+				"src/main.go":        []byte("package main"),
+			},
+			expectedApprovers: map[string]sets.String{
+				"README.md":   sets.NewString("alice"),
+				"src/main.go": sets.NewString("bob"),
+			},
+		},
+		{
+			name: "OWNERS files take precedence over CODEOWNERS",
+			files: map[string][]byte{
+				".github/CODEOWNERS": []byte("* @alice\n"),
+				"OWNERS":             []byte("approvers:\n- cjwagner"),
+			},
+			expectedApprovers: map[string]sets.String{
+				"README.md": sets.NewString("cjwagner"),
+			},
+		},
+		{
+			name: ".github/CODEOWNERS takes precedence over other locations",
+			files: map[string][]byte{
+				".github/CODEOWNERS": []byte("* @alice\n"),
+				"CODEOWNERS":         []byte("* @bob\n"),
+			},
+			expectedApprovers: map[string]sets.String{
+				"README.md": sets.NewString("alice"),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, cleanup, err := getTestClient(test.files, false, true, false, false, nil, nil, nil, nil, clients)
+			if err != nil {
+				t.Fatalf("Error creating test client: %v.", err)
+			}
+			defer cleanup()
+
+			ro, err := client.LoadRepoOwners("org", "repo", defaultBranch)
+			if err != nil {
+				t.Fatalf("Unexpected error loading RepoOwners: %v.", err)
+			}
+			for path, expected := range test.expectedApprovers {
+				if got := ro.Approvers(path).Set(); !got.Equal(expected) {
+					t.Errorf("For path %q expected approvers %q, but got %q.", path, expected.List(), got.List())
+				}
+				if got := ro.Reviewers(path).Set(); len(ro.(*RepoOwners).codeOwners) > 0 && !got.Equal(expected) {
+					t.Errorf("For path %q expected reviewers %q, but got %q.", path, expected.List(), got.List())
+				}
+			}
+		})
+	}
+}
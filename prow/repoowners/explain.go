@@ -0,0 +1,213 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repoowners
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/plugins/ownersconfig"
+)
+
+// The roles that OWNERS files grant.
+const (
+	RoleApprovers         = "approvers"
+	RoleReviewers         = "reviewers"
+	RoleRequiredReviewers = "required_reviewers"
+)
+
+// OwnersContribution is what a single OWNERS file contributes to the owners
+// of a path in one role.
+type OwnersContribution struct {
+	// File is the OWNERS file, or the markdown file with an OWNERS header,
+	// relative to the root of the repo. It is empty for default owners.
+	File string `json:"file"`
+	// Filter is the filter or the path of the rule of the OWNERS file that
+	// matched the path. It is empty if the owners apply to all files.
+	Filter string `json:"filter,omitempty"`
+	// Role is one of RoleApprovers, RoleReviewers or RoleRequiredReviewers.
+	Role string `json:"role"`
+	// Leaf is set if this is the OWNERS file closest to the path that has
+	// owners in the role. Otherwise the owners are inherited from a parent
+	// directory.
+	Leaf bool `json:"leaf"`
+	// Logins are the owners, with aliases expanded.
+	Logins []string `json:"logins"`
+	// NotCollaborators are the owners listed in the file that were dropped
+	// because they are not collaborators of the repo.
+	NotCollaborators []string `json:"not_collaborators,omitempty"`
+}
+
+// OwnersExplanation holds the effective owners of a path and explains which
+// OWNERS files they come from.
+type OwnersExplanation struct {
+	Path              string   `json:"path"`
+	Approvers         []string `json:"approvers"`
+	LeafApprovers     []string `json:"leaf_approvers"`
+	Reviewers         []string `json:"reviewers"`
+	LeafReviewers     []string `json:"leaf_reviewers"`
+	RequiredReviewers []string `json:"required_reviewers"`
+	// Contributions are ordered from the OWNERS file closest to the path to
+	// the one at the root of the repo.
+	Contributions []OwnersContribution `json:"contributions"`
+	// NoParentOwners is the directory whose OWNERS file stops inheriting
+	// owners from parent directories, if any.
+	NoParentOwners string `json:"no_parent_owners,omitempty"`
+	// CodeOwners is set if the owners come from a CODEOWNERS file because
+	// the repo has no OWNERS files.
+	CodeOwners bool `json:"code_owners,omitempty"`
+	// DefaultOwners is set if the owners are the default owners configured
+	// for the repo because it has neither OWNERS files nor a CODEOWNERS file.
+	DefaultOwners bool `json:"default_owners,omitempty"`
+}
+
+// Explain returns the effective approvers and reviewers of the path, which
+// OWNERS files contributed them and whether they were inherited, to debug
+// why someone can or can't approve a file.
+func (o *RepoOwners) Explain(path string) OwnersExplanation {
+	explanation := OwnersExplanation{
+		Path:              path,
+		Approvers:         o.Approvers(path).Set().List(),
+		LeafApprovers:     o.LeafApprovers(path).List(),
+		Reviewers:         o.Reviewers(path).Set().List(),
+		LeafReviewers:     o.LeafReviewers(path).List(),
+		RequiredReviewers: o.RequiredReviewers(path).List(),
+		Contributions:     []OwnersContribution{},
+		DefaultOwners:     o.defaultOwners,
+	}
+
+	if o.codeOwners != nil {
+		explanation.CodeOwners = true
+		rule, ok := o.codeOwners.ruleFor(path)
+		if !ok {
+			return explanation
+		}
+		var notCollaborators []string
+		if o.unfiltered != nil {
+			if unfiltered, ok := o.unfiltered.codeOwners.ruleFor(path); ok {
+				notCollaborators = unfiltered.owners.Difference(rule.owners).List()
+			}
+		}
+		// CODEOWNERS grants both roles, and only the last matching rule counts.
+		for _, role := range []string{RoleApprovers, RoleReviewers} {
+			explanation.Contributions = append(explanation.Contributions, OwnersContribution{
+				File:             "CODEOWNERS",
+				Filter:           rule.pattern,
+				Role:             role,
+				Leaf:             true,
+				Logins:           rule.owners.List(),
+				NotCollaborators: notCollaborators,
+			})
+		}
+		return explanation
+	}
+
+	roles := []struct {
+		name       string
+		people     map[string]map[*regexp.Regexp]sets.String
+		unfiltered map[string]map[*regexp.Regexp]sets.String
+	}{
+		{name: RoleApprovers, people: o.approvers},
+		{name: RoleReviewers, people: o.reviewers},
+		{name: RoleRequiredReviewers, people: o.requiredReviewers},
+	}
+	if o.unfiltered != nil {
+		roles[0].unfiltered = o.unfiltered.approvers
+		roles[1].unfiltered = o.unfiltered.reviewers
+	}
+	leafFound := sets.NewString()
+
+	// This walks the tree like entriesForFile does.
+	d := path
+	if !o.enableMDYAML || !strings.HasSuffix(path, ".md") {
+		d = canonicalize(d)
+	}
+	for {
+		relative, err := filepath.Rel(d, path)
+		if err != nil {
+			o.log.WithError(err).WithField("path", path).Errorf("Unable to find relative path between %q and path.", d)
+			return explanation
+		}
+		for _, role := range roles {
+			var contributions []OwnersContribution
+			for re, logins := range role.people[d] {
+				if re != nil && !re.MatchString(relative) {
+					continue
+				}
+				contribution := OwnersContribution{File: o.ownersFile(d), Role: role.name, Logins: logins.List()}
+				if source, ok := o.ruleSources[re]; ok {
+					contribution.File, contribution.Filter = source.file, source.pattern
+				} else if re != nil {
+					contribution.Filter = re.String()
+				}
+				if unfiltered, ok := role.unfiltered[d][re]; ok {
+					contribution.NotCollaborators = unfiltered.Difference(logins).List()
+				}
+				if len(contribution.Logins) == 0 && len(contribution.NotCollaborators) == 0 {
+					continue
+				}
+				contributions = append(contributions, contribution)
+			}
+			sort.Slice(contributions, func(i, j int) bool {
+				return contributions[i].Filter < contributions[j].Filter
+			})
+
+			isLeaf := false
+			if !leafFound.Has(role.name) {
+				for _, contribution := range contributions {
+					isLeaf = isLeaf || len(contribution.Logins) > 0
+				}
+			}
+			if isLeaf {
+				leafFound.Insert(role.name)
+			}
+			for _, contribution := range contributions {
+				contribution.Leaf = isLeaf
+				explanation.Contributions = append(explanation.Contributions, contribution)
+			}
+		}
+		if d == baseDirConvention {
+			break
+		}
+		if o.stopsInheritance(d, relative) {
+			explanation.NoParentOwners = d
+			break
+		}
+		d = canonicalize(filepath.Dir(d))
+	}
+	return explanation
+}
+
+// ownersFile returns the file that holds the owners of the directory, or the
+// markdown file itself if it has an OWNERS header.
+func (o *RepoOwners) ownersFile(dir string) string {
+	if o.defaultOwners {
+		return ""
+	}
+	if o.enableMDYAML && strings.HasSuffix(dir, ".md") {
+		return dir
+	}
+	filename := o.filenames.Owners
+	if filename == "" {
+		filename = ownersconfig.DefaultOwnersFile
+	}
+	return filepath.Join(dir, filename)
+}
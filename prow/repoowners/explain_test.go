@@ -0,0 +1,155 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repoowners
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+func TestExplain(t *testing.T) {
+	goFiles := regexp.MustCompile(`\.go$`)
+	ro := &RepoOwners{
+		approvers: map[string]map[*regexp.Regexp]sets.String{
+			baseDir: regexpAll("alice", "bob"),
+			leafDir: {
+				nil:     sets.NewString("carl"),
+				goFiles: sets.NewString("dave"),
+			},
+			noParentsDir: regexpAll("mml"),
+		},
+		reviewers: map[string]map[*regexp.Regexp]sets.String{
+			baseDir: regexpAll("erin"),
+		},
+		requiredReviewers: map[string]map[*regexp.Regexp]sets.String{},
+		options: map[string]dirOptions{
+			noParentsDir: {NoParentOwners: true},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		owners   *RepoOwners
+		path     string
+		expected OwnersExplanation
+	}{
+		{
+			name:   "owners of the leaf directory and inherited ones",
+			owners: ro,
+			path:   "a/b/c/main.go",
+			expected: OwnersExplanation{
+				Path:              "a/b/c/main.go",
+				Approvers:         []string{"alice", "bob", "carl", "dave"},
+				LeafApprovers:     []string{"carl", "dave"},
+				Reviewers:         []string{"erin"},
+				LeafReviewers:     []string{"erin"},
+				RequiredReviewers: []string{},
+				Contributions: []OwnersContribution{
+					{File: "a/b/c/OWNERS", Role: RoleApprovers, Leaf: true, Logins: []string{"carl"}},
+					{File: "a/b/c/OWNERS", Filter: `\.go$`, Role: RoleApprovers, Leaf: true, Logins: []string{"dave"}},
+					{File: "OWNERS", Role: RoleApprovers, Logins: []string{"alice", "bob"}},
+					{File: "OWNERS", Role: RoleReviewers, Leaf: true, Logins: []string{"erin"}},
+				},
+			},
+		},
+		{
+			name:   "filters that don't match are left out",
+			owners: ro,
+			path:   "a/b/c/README",
+			expected: OwnersExplanation{
+				Path:              "a/b/c/README",
+				Approvers:         []string{"alice", "bob", "carl"},
+				LeafApprovers:     []string{"carl"},
+				Reviewers:         []string{"erin"},
+				LeafReviewers:     []string{"erin"},
+				RequiredReviewers: []string{},
+				Contributions: []OwnersContribution{
+					{File: "a/b/c/OWNERS", Role: RoleApprovers, Leaf: true, Logins: []string{"carl"}},
+					{File: "OWNERS", Role: RoleApprovers, Logins: []string{"alice", "bob"}},
+					{File: "OWNERS", Role: RoleReviewers, Leaf: true, Logins: []string{"erin"}},
+				},
+			},
+		},
+		{
+			name:   "no_parent_owners stops inheritance",
+			owners: ro,
+			path:   "d/main.go",
+			expected: OwnersExplanation{
+				Path:              "d/main.go",
+				Approvers:         []string{"mml"},
+				LeafApprovers:     []string{"mml"},
+				Reviewers:         []string{},
+				LeafReviewers:     []string{},
+				RequiredReviewers: []string{},
+				Contributions: []OwnersContribution{
+					{File: "d/OWNERS", Role: RoleApprovers, Leaf: true, Logins: []string{"mml"}},
+				},
+				NoParentOwners: "d",
+			},
+		},
+		{
+			name:   "owners that are not collaborators are explained",
+			owners: ro.filterCollaborators([]github.User{{Login: "alice"}, {Login: "dave"}, {Login: "erin"}}),
+			path:   "a/b/c/README",
+			expected: OwnersExplanation{
+				Path:              "a/b/c/README",
+				Approvers:         []string{"alice"},
+				LeafApprovers:     []string{"alice"},
+				Reviewers:         []string{"erin"},
+				LeafReviewers:     []string{"erin"},
+				RequiredReviewers: []string{},
+				Contributions: []OwnersContribution{
+					{File: "a/b/c/OWNERS", Role: RoleApprovers, Logins: []string{}, NotCollaborators: []string{"carl"}},
+					{File: "OWNERS", Role: RoleApprovers, Leaf: true, Logins: []string{"alice"}, NotCollaborators: []string{"bob"}},
+					{File: "OWNERS", Role: RoleReviewers, Leaf: true, Logins: []string{"erin"}, NotCollaborators: []string{}},
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, tc.owners.Explain(tc.path)); diff != "" {
+				t.Errorf("unexpected explanation (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExplainCodeOwners(t *testing.T) {
+	rules, err := parseCodeOwners([]byte("* @alice\n/docs/ @bob @carl\n"))
+	if err != nil {
+		t.Fatalf("failed to parse CODEOWNERS: %v", err)
+	}
+	ro := (&RepoOwners{codeOwners: rules}).filterCollaborators([]github.User{{Login: "alice"}, {Login: "bob"}})
+
+	explanation := ro.Explain("docs/index.md")
+	expected := []OwnersContribution{
+		{File: "CODEOWNERS", Filter: "/docs/", Role: RoleApprovers, Leaf: true, Logins: []string{"bob"}, NotCollaborators: []string{"carl"}},
+		{File: "CODEOWNERS", Filter: "/docs/", Role: RoleReviewers, Leaf: true, Logins: []string{"bob"}, NotCollaborators: []string{"carl"}},
+	}
+	if !explanation.CodeOwners {
+		t.Error("expected the owners to come from CODEOWNERS")
+	}
+	if diff := cmp.Diff(expected, explanation.Contributions); diff != "" {
+		t.Errorf("unexpected contributions (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,278 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repoowners
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/plugins/ownersconfig"
+)
+
+// PersistentCache stores loaded OWNERS outside of the process, so that all
+// replicas of a component share them and they survive restarts. Entries are
+// keyed by the hash of the git tree they were loaded from, which never
+// changes, so they don't need to be invalidated.
+type PersistentCache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// redisKeyPrefix is the prefix of all keys repoowners stores in Redis.
+const redisKeyPrefix = "repoowners:"
+
+// redisCache is a PersistentCache that stores OWNERS in Redis.
+type redisCache struct {
+	getConn func() redis.Conn
+	// ttl is how long entries are kept. They are only read while the tree
+	// is the head of a branch or of a PR, so they can expire.
+	ttl time.Duration
+}
+
+// NewRedisCache returns a PersistentCache that stores OWNERS in the Redis
+// server at the address for ttl.
+func NewRedisCache(address string, ttl time.Duration) PersistentCache {
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", address)
+		},
+		MaxIdle:     10,
+		IdleTimeout: 5 * time.Minute,
+	}
+	return &redisCache{getConn: pool.Get, ttl: ttl}
+}
+
+func (c *redisCache) Get(key string) ([]byte, bool) {
+	conn := c.getConn()
+	defer conn.Close()
+	value, err := redis.Bytes(conn.Do("GET", redisKeyPrefix+key))
+	if err != nil {
+		if err != redis.ErrNil {
+			logrus.WithError(err).WithField("cache-key", key).Warn("Failed to get OWNERS from Redis.")
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+func (c *redisCache) Set(key string, value []byte) {
+	conn := c.getConn()
+	defer conn.Close()
+	if _, err := conn.Do("SET", redisKeyPrefix+key, value, "EX", int(c.ttl.Seconds())); err != nil {
+		logrus.WithError(err).WithField("cache-key", key).Warn("Failed to store OWNERS in Redis.")
+	}
+}
+
+// persistentKey returns the key of the OWNERS of a tree of a repo. It
+// includes everything else that loading them depends on.
+func persistentKey(org, repo, tree string, mdYaml bool, filenames ownersconfig.Filenames, ignoreDirPatterns []string) string {
+	settings, _ := json.Marshal(struct {
+		MDYAML            bool
+		Filenames         ownersconfig.Filenames
+		IgnoreDirPatterns []string
+	}{mdYaml, filenames, ignoreDirPatterns})
+	hash := sha256.Sum256(settings)
+	return fmt.Sprintf("%s/%s:%s:%s", org, repo, tree, hex.EncodeToString(hash[:8]))
+}
+
+// persistedOwners is how a cacheEntry is stored in a PersistentCache.
+type persistedOwners struct {
+	SHA     string              `json:"sha"`
+	Aliases map[string][]string `json:"aliases,omitempty"`
+	// Filters are the filters the owners of directories are stored with,
+	// which all entries refer to by index, as rules are told apart by
+	// filter.
+	Filters           []persistedFilter     `json:"filters,omitempty"`
+	Approvers         []persistedEntry      `json:"approvers,omitempty"`
+	Reviewers         []persistedEntry      `json:"reviewers,omitempty"`
+	RequiredReviewers []persistedEntry      `json:"required_reviewers,omitempty"`
+	Labels            []persistedEntry      `json:"labels,omitempty"`
+	Options           map[string]dirOptions `json:"options,omitempty"`
+	NoParentOwners    map[string][]int      `json:"no_parent_owners,omitempty"`
+	CodeOwners        []persistedCodeOwners `json:"code_owners,omitempty"`
+}
+
+type persistedFilter struct {
+	Pattern     string `json:"pattern"`
+	RuleFile    string `json:"rule_file,omitempty"`
+	RulePattern string `json:"rule_pattern,omitempty"`
+}
+
+type persistedEntry struct {
+	Dir string `json:"dir"`
+	// Filter is the index of the filter in Filters, or -1 for all files.
+	Filter int      `json:"filter"`
+	Logins []string `json:"logins"`
+}
+
+type persistedCodeOwners struct {
+	Pattern string   `json:"pattern"`
+	Owners  []string `json:"owners"`
+}
+
+// persistEntry serializes a fully loaded cacheEntry.
+func persistEntry(entry cacheEntry) ([]byte, error) {
+	o := entry.owners
+	p := persistedOwners{
+		SHA:            entry.sha,
+		Aliases:        map[string][]string{},
+		Options:        o.options,
+		NoParentOwners: map[string][]int{},
+	}
+	for alias, logins := range entry.aliases {
+		p.Aliases[alias] = logins.List()
+	}
+
+	filters := map[*regexp.Regexp]int{}
+	filterIndex := func(re *regexp.Regexp) int {
+		if re == nil {
+			return -1
+		}
+		if i, ok := filters[re]; ok {
+			return i
+		}
+		filter := persistedFilter{Pattern: re.String()}
+		if source, ok := o.ruleSources[re]; ok {
+			filter.RuleFile, filter.RulePattern = source.file, source.pattern
+		}
+		filters[re] = len(p.Filters)
+		p.Filters = append(p.Filters, filter)
+		return filters[re]
+	}
+	persistPeople := func(people map[string]map[*regexp.Regexp]sets.String) []persistedEntry {
+		var entries []persistedEntry
+		for dir, byFilter := range people {
+			for re, logins := range byFilter {
+				entries = append(entries, persistedEntry{Dir: dir, Filter: filterIndex(re), Logins: logins.List()})
+			}
+		}
+		return entries
+	}
+	p.Approvers = persistPeople(o.approvers)
+	p.Reviewers = persistPeople(o.reviewers)
+	p.RequiredReviewers = persistPeople(o.requiredReviewers)
+	p.Labels = persistPeople(o.labels)
+	for dir, res := range o.noParentOwners {
+		for _, re := range res {
+			p.NoParentOwners[dir] = append(p.NoParentOwners[dir], filterIndex(re))
+		}
+	}
+	for _, rule := range o.codeOwners {
+		p.CodeOwners = append(p.CodeOwners, persistedCodeOwners{Pattern: rule.pattern, Owners: rule.owners.List()})
+	}
+	return json.Marshal(p)
+}
+
+// restoreEntry deserializes a cacheEntry stored by persistEntry.
+func restoreEntry(b []byte, mdYaml bool, dirIgnorelist []*regexp.Regexp, filenames ownersconfig.Filenames, log *logrus.Entry) (cacheEntry, error) {
+	var p persistedOwners
+	if err := json.Unmarshal(b, &p); err != nil {
+		return cacheEntry{}, err
+	}
+	aliases := RepoAliases{}
+	for alias, logins := range p.Aliases {
+		aliases[alias] = sets.NewString(logins...)
+	}
+	o := &RepoOwners{
+		RepoAliases:  aliases,
+		enableMDYAML: mdYaml,
+		filenames:    filenames,
+		log:          log,
+
+		options:        p.Options,
+		ruleSources:    make(map[*regexp.Regexp]ruleSource),
+		noParentOwners: make(map[string][]*regexp.Regexp),
+
+		dirDenylist: dirIgnorelist,
+	}
+	if o.options == nil {
+		o.options = make(map[string]dirOptions)
+	}
+
+	filters := make([]*regexp.Regexp, len(p.Filters))
+	for i, filter := range p.Filters {
+		re, err := regexp.Compile(filter.Pattern)
+		if err != nil {
+			return cacheEntry{}, fmt.Errorf("invalid filter %q: %w", filter.Pattern, err)
+		}
+		filters[i] = re
+		if filter.RuleFile != "" {
+			o.ruleSources[re] = ruleSource{file: filter.RuleFile, pattern: filter.RulePattern}
+		}
+	}
+	filterAt := func(i int) (*regexp.Regexp, error) {
+		if i == -1 {
+			return nil, nil
+		}
+		if i < 0 || i >= len(filters) {
+			return nil, fmt.Errorf("invalid filter index %d", i)
+		}
+		return filters[i], nil
+	}
+	restorePeople := func(entries []persistedEntry) (map[string]map[*regexp.Regexp]sets.String, error) {
+		people := make(map[string]map[*regexp.Regexp]sets.String)
+		for _, entry := range entries {
+			re, err := filterAt(entry.Filter)
+			if err != nil {
+				return nil, err
+			}
+			if people[entry.Dir] == nil {
+				people[entry.Dir] = make(map[*regexp.Regexp]sets.String)
+			}
+			people[entry.Dir][re] = sets.NewString(entry.Logins...)
+		}
+		return people, nil
+	}
+	var err error
+	if o.approvers, err = restorePeople(p.Approvers); err != nil {
+		return cacheEntry{}, err
+	}
+	if o.reviewers, err = restorePeople(p.Reviewers); err != nil {
+		return cacheEntry{}, err
+	}
+	if o.requiredReviewers, err = restorePeople(p.RequiredReviewers); err != nil {
+		return cacheEntry{}, err
+	}
+	if o.labels, err = restorePeople(p.Labels); err != nil {
+		return cacheEntry{}, err
+	}
+	for dir, indices := range p.NoParentOwners {
+		for _, i := range indices {
+			re, err := filterAt(i)
+			if err != nil {
+				return cacheEntry{}, err
+			}
+			o.noParentOwners[dir] = append(o.noParentOwners[dir], re)
+		}
+	}
+	for _, rule := range p.CodeOwners {
+		re, err := codeOwnersPatternToRegexp(rule.Pattern)
+		if err != nil {
+			return cacheEntry{}, err
+		}
+		o.codeOwners = append(o.codeOwners, codeOwnersRule{pattern: rule.Pattern, re: re, owners: sets.NewString(rule.Owners...)})
+	}
+	return cacheEntry{sha: p.SHA, aliases: aliases, owners: o}, nil
+}
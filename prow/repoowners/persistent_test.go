@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repoowners
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/git/localgit"
+	"k8s.io/test-infra/prow/git/v2"
+)
+
+type fakePersistentCache struct {
+	lock sync.Mutex
+	data map[string][]byte
+}
+
+func (f *fakePersistentCache) Get(key string) ([]byte, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	value, ok := f.data[key]
+	return value, ok
+}
+
+func (f *fakePersistentCache) Set(key string, value []byte) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.data[key] = value
+}
+
+// unclonableFactory fails the test's OWNERS walk if it is reached.
+type unclonableFactory struct {
+	git.ClientFactory
+}
+
+func (unclonableFactory) ClientFor(org, repo string) (git.RepoClient, error) {
+	return nil, errors.New("expected OWNERS to come from the persistent cache")
+}
+
+func persistentTestFiles() map[string][]byte {
+	files := map[string][]byte{
+		"mono/OWNERS": []byte(`version: 2
+rules:
+- paths:
+  - svc/a/
+  approvers:
+  - alice
+- paths:
+  - "svc/b/**/*.proto"
+  approvers:
+  - bob
+  no_parent_owners: true`),
+	}
+	for path, content := range testFiles {
+		files[path] = content
+	}
+	return files
+}
+
+func TestPersistEntry(t *testing.T) {
+	client, cleanup, err := getTestClient(persistentTestFiles(), true, true, true, false, nil, nil, nil, nil, localgit.New)
+	if err != nil {
+		t.Fatalf("Error creating test client: %v.", err)
+	}
+	defer cleanup()
+
+	r, err := client.LoadRepoOwners("org", "repo", defaultBranch)
+	if err != nil {
+		t.Fatalf("Unexpected error loading RepoOwners: %v.", err)
+	}
+	loaded := r.(*RepoOwners)
+	b, err := persistEntry(cacheEntry{sha: "sha", aliases: loaded.RepoAliases, owners: loaded})
+	if err != nil {
+		t.Fatalf("Unexpected error persisting RepoOwners: %v.", err)
+	}
+	entry, err := restoreEntry(b, true, loaded.dirDenylist, loaded.filenames, logrus.WithField("test", t.Name()))
+	if err != nil {
+		t.Fatalf("Unexpected error restoring RepoOwners: %v.", err)
+	}
+	if entry.sha != "sha" || !entry.fullyLoaded() {
+		t.Fatalf("Expected a fully loaded entry for sha, got %+v.", entry)
+	}
+
+	restored := entry.owners
+	for _, path := range []string{
+		"foo",
+		"src/dir/subdir/file.go",
+		"src/dir/conformance/file.go",
+		"docs/file.md",
+		"mono/svc/a/main.go",
+		"mono/svc/b/api/types.proto",
+		"mono/svc/b/main.go",
+	} {
+		if diff := cmp.Diff(loaded.Explain(path), restored.Explain(path)); diff != "" {
+			t.Errorf("Restored owners of %q differ (-loaded +restored):\n%s", path, diff)
+		}
+		if expected, got := loaded.FindLabelsForFile(path), restored.FindLabelsForFile(path); !expected.Equal(got) {
+			t.Errorf("For file %q expected labels %q, but got %q.", path, expected.List(), got.List())
+		}
+	}
+	if !restored.IsNoParentOwners("src/dir/conformance") {
+		t.Error("Expected no_parent_owners to be restored.")
+	}
+	if expected, got := loaded.ExpandAlias("best-approvers"), restored.ExpandAlias("best-approvers"); !expected.Equal(got) {
+		t.Errorf("Expected alias to expand to %q, but got %q.", expected.List(), got.List())
+	}
+}
+
+func TestPersistentCacheIsShared(t *testing.T) {
+	client, cleanup, err := getTestClient(persistentTestFiles(), false, true, false, false, nil, nil, nil, nil, localgit.New)
+	if err != nil {
+		t.Fatalf("Error creating test client: %v.", err)
+	}
+	defer cleanup()
+	persistent := &fakePersistentCache{data: map[string][]byte{}}
+	client.UsePersistentCache(persistent)
+
+	if _, err := client.LoadRepoOwners("org", "repo", defaultBranch); err != nil {
+		t.Fatalf("Unexpected error loading RepoOwners: %v.", err)
+	}
+	if len(persistent.data) != 1 {
+		t.Fatalf("Expected the OWNERS to be stored in the persistent cache, got %d entries.", len(persistent.data))
+	}
+
+	// Another replica can't walk the repo and has nothing in memory.
+	replica := &Client{
+		logger: client.logger,
+		ghc:    client.ghc,
+		delegate: &delegate{
+			git:               unclonableFactory{},
+			cache:             newCache(),
+			mdYAMLEnabled:     client.mdYAMLEnabled,
+			skipCollaborators: client.skipCollaborators,
+			ownersDirDenylist: client.ownersDirDenylist,
+			filenames:         client.filenames,
+		},
+	}
+	replica.UsePersistentCache(persistent)
+	r, err := replica.LoadRepoOwners("org", "repo", defaultBranch)
+	if err != nil {
+		t.Fatalf("Unexpected error loading RepoOwners: %v.", err)
+	}
+	if got := r.Approvers("mono/svc/b/api/types.proto").Set(); !got.Equal(sets.NewString("bob")) {
+		t.Errorf("Expected approvers %q, but got %q.", []string{"bob"}, got.List())
+	}
+	if got := r.LeafApprovers("src/dir/subdir/file.go"); !got.Equal(sets.NewString("alice", "bob")) {
+		t.Errorf("Expected leaf approvers %q, but got %q.", []string{"alice", "bob"}, got.List())
+	}
+}
@@ -75,11 +75,37 @@ func (s *SimpleConfig) Empty() bool {
 type FullConfig struct {
 	Options dirOptions        `json:"options,omitempty"`
 	Filters map[string]Config `json:"filters,omitempty"`
+
+	// Version is 2 for OWNERS files that hold Rules.
+	Version int `json:"version,omitempty"`
+	// Rules apply specific Config to the files matching their globs, which
+	// may reach into subdirectories, so that a single OWNERS file can stand
+	// in for the OWNERS files of many directories. Only used if Version is 2.
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+// ownersV2 is the version of OWNERS files that may hold rules.
+const ownersV2 = 2
+
+// Rule applies Config to the files matching any of its Paths.
+type Rule struct {
+	// Paths are globs relative to the directory of the OWNERS file that
+	// follow the .gitignore syntax, like CODEOWNERS, e.g. "pkg/api/",
+	// "cmd/**/*.go" or "*.md". The matching files are owned as if an OWNERS
+	// file in the deepest directory before the first wildcard held Config,
+	// so they inherit the owners of its parent directories.
+	Paths []string `json:"paths"`
+	// NoParentOwners stops the matching files from inheriting the owners of
+	// parent directories.
+	NoParentOwners bool `json:"no_parent_owners,omitempty"`
+	Config         `json:",inline"`
 }
 
 type githubClient interface {
 	ListCollaborators(org, repo string) ([]github.User, error)
 	GetRef(org, repo, ref string) (string, error)
+	GetSingleCommit(org, repo, SHA string) (github.RepositoryCommit, error)
+	ListTeamMembersBySlug(org, teamSlug, role string) ([]github.TeamMember, error)
 }
 
 func newCache() *cache {
@@ -127,10 +153,23 @@ func (c *cache) setEntry(key string, data cacheEntry) {
 	c.dataLock.Unlock()
 }
 
+// defaultTeamMembersTTL is how long the members of the teams of the default
+// owners are cached for.
+const defaultTeamMembersTTL = 10 * time.Minute
+
 type cacheEntry struct {
 	sha     string
 	aliases RepoAliases
 	owners  *RepoOwners
+
+	// defaultTeamMembers are the members of the teams of the default owners
+	// of the repo by team slug, they are not persisted.
+	defaultTeamMembers map[string]cachedTeamMembers
+}
+
+type cachedTeamMembers struct {
+	logins  sets.String
+	expires time.Time
 }
 
 func (entry cacheEntry) matchesMDYAML(mdYAML bool) bool {
@@ -171,6 +210,10 @@ type delegate struct {
 	filenames         ownersconfig.Resolver
 
 	cache *cache
+	// persistent is shared by all replicas, if set.
+	persistent PersistentCache
+	// defaultOwners are the owners of repos without OWNERS files, if set.
+	defaultOwners ownersconfig.DefaultOwnersResolver
 }
 
 // WithFields clones the client, keeping the underlying delegate the same but adding
@@ -235,6 +278,20 @@ func NewClient(
 	}
 }
 
+// UsePersistentCache makes the client share the OWNERS it loads with other
+// clients through the cache, and look them up there before loading them. It
+// must be called before the client is used.
+func (c *Client) UsePersistentCache(cache PersistentCache) {
+	c.persistent = cache
+}
+
+// UseDefaultOwners makes the client fall back to the owners the resolver
+// returns for repos that have neither OWNERS files nor a CODEOWNERS file. It
+// must be called before the client is used.
+func (c *Client) UseDefaultOwners(resolver ownersconfig.DefaultOwnersResolver) {
+	c.defaultOwners = resolver
+}
+
 // RepoAliases defines groups of people to be used in OWNERS files
 type RepoAliases map[string]sets.String
 
@@ -269,6 +326,25 @@ type RepoOwners struct {
 	labels            map[string]map[*regexp.Regexp]sets.String
 	options           map[string]dirOptions
 
+	// codeOwners is only set for repos that have no OWNERS files but do
+	// have a CODEOWNERS file, and then supersedes approvers and reviewers.
+	codeOwners codeOwners
+
+	// unfiltered is set if these owners were filtered down to the
+	// collaborators of the repo, and holds them as they were before.
+	unfiltered *RepoOwners
+	// defaultOwners is set if the repo has no OWNERS files, and the owners
+	// of its root directory are the configured default owners.
+	defaultOwners bool
+
+	// ruleSources are the rules of version 2 OWNERS files that the filters
+	// of the owner maps come from.
+	ruleSources map[*regexp.Regexp]ruleSource
+	// noParentOwners holds the filters of the rules of a directory that stop
+	// inheriting the owners of parent directories. A nil filter matches all
+	// files.
+	noParentOwners map[string][]*regexp.Regexp
+
 	baseDir      string
 	enableMDYAML bool
 	dirDenylist  []*regexp.Regexp
@@ -312,15 +388,23 @@ func (c *Client) LoadRepoOwnersSha(org, repo, base, sha string, updateCache bool
 		return nil, err
 	}
 
+	owners := entry.owners
+	// The default owners are applied to cached entries too, as they are
+	// configured outside of the repo.
+	if owners.empty() && c.defaultOwners != nil {
+		if defaults := c.defaultOwners(org, repo); defaults != nil {
+			owners = c.withDefaultOwners(org, fullName, owners, defaults, log)
+		}
+	}
+
 	start := time.Now()
 	if c.skipCollaborators(org, repo) {
 		log.WithField("duration", time.Since(start).String()).Debugf("Completed c.skipCollaborators(%s, %s)", org, repo)
 		log.Debugf("Skipping collaborator checks for %s/%s", org, repo)
-		return entry.owners, nil
+		return owners, nil
 	}
 	log.WithField("duration", time.Since(start).String()).Debugf("Completed c.skipCollaborators(%s, %s)", org, repo)
 
-	var owners *RepoOwners
 	// Filter collaborators. We must filter the RepoOwners struct even if it came from the cache
 	// because the list of collaborators could have changed without the git SHA changing.
 	start = time.Now()
@@ -328,10 +412,9 @@ func (c *Client) LoadRepoOwnersSha(org, repo, base, sha string, updateCache bool
 	log.WithField("duration", time.Since(start).String()).Debugf("Completed ghc.ListCollaborators(%s, %s)", org, repo)
 	if err != nil {
 		log.WithError(err).Errorf("Failed to list collaborators while loading RepoOwners. Skipping collaborator filtering.")
-		owners = entry.owners
 	} else {
 		start = time.Now()
-		owners = entry.owners.filterCollaborators(collaborators)
+		owners = owners.filterCollaborators(collaborators)
 		log.WithField("duration", time.Since(start).String()).Debugf("Completed owners.filterCollaborators(collaborators)")
 	}
 	return owners, nil
@@ -347,7 +430,35 @@ func (c *Client) cacheEntryFor(org, repo, base, cloneRef, fullName, sha string,
 	defer entryLock.Unlock()
 	filenames := c.filenames(org, repo)
 	if !ok || entry.sha != sha || entry.owners == nil || !entry.matchesMDYAML(mdYaml) {
+		ignoreDirPatterns, dirIgnorelist := c.ignoredDirs(org, repo, log)
+
+		// Another replica may have loaded the OWNERS of this tree already.
 		start := time.Now()
+		var key string
+		if c.persistent != nil {
+			commit, err := c.ghc.GetSingleCommit(org, repo, sha)
+			if err != nil {
+				log.WithError(err).Warn("Failed to get the tree of the commit, not using the persistent OWNERS cache.")
+			} else {
+				key = persistentKey(org, repo, commit.Commit.Tree.SHA, mdYaml, filenames, ignoreDirPatterns)
+			}
+		}
+		if key != "" {
+			if b, found := c.persistent.Get(key); found {
+				restored, err := restoreEntry(b, mdYaml, dirIgnorelist, filenames, log)
+				if err == nil {
+					log.WithField("duration", time.Since(start).String()).Debug("Restored RepoOwners from the persistent cache.")
+					restored.sha = sha
+					if setEntry {
+						c.cache.setEntry(fullName, restored)
+					}
+					return restored, nil
+				}
+				log.WithError(err).Warn("Failed to restore RepoOwners from the persistent cache.")
+			}
+		}
+
+		start = time.Now()
 		gitRepo, err := c.git.ClientFor(org, repo)
 		if err != nil {
 			return cacheEntry{}, fmt.Errorf("failed to clone %s: %w", cloneRef, err)
@@ -369,7 +480,8 @@ func (c *Client) cacheEntryFor(org, repo, base, cloneRef, fullName, sha string,
 			for _, change := range changes {
 				if mdYaml && strings.HasSuffix(change, ".md") ||
 					strings.HasSuffix(change, filenames.OwnersAliases) ||
-					strings.HasSuffix(change, filenames.Owners) {
+					strings.HasSuffix(change, filenames.Owners) ||
+					isCodeOwnersFile(change) {
 					reusable = false
 					log.WithField("duration", time.Since(start).String()).Debugf("Completed owners change verification loop")
 					break
@@ -393,19 +505,6 @@ func (c *Client) cacheEntryFor(org, repo, base, cloneRef, fullName, sha string,
 			}
 			log.WithField("duration", time.Since(start).String()).Debugf("Completed loadAliasesFrom(%s, log)", gitRepo.Directory())
 
-			start = time.Now()
-			ignoreDirPatterns := c.ownersDirDenylist().ListIgnoredDirs(org, repo)
-			var dirIgnorelist []*regexp.Regexp
-			for _, pattern := range ignoreDirPatterns {
-				re, err := regexp.Compile(pattern)
-				if err != nil {
-					log.WithError(err).Errorf("Invalid OWNERS dir denylist regexp %q.", pattern)
-					continue
-				}
-				dirIgnorelist = append(dirIgnorelist, re)
-			}
-			log.WithField("duration", time.Since(start).String()).Debugf("Completed dirIgnorelist loading")
-
 			start = time.Now()
 			entry.owners, err = loadOwnersFrom(gitRepo.Directory(), mdYaml, entry.aliases, dirIgnorelist, filenames, log)
 			if err != nil {
@@ -417,10 +516,95 @@ func (c *Client) cacheEntryFor(org, repo, base, cloneRef, fullName, sha string,
 				c.cache.setEntry(fullName, entry)
 			}
 		}
+		if key != "" {
+			if b, err := persistEntry(entry); err != nil {
+				log.WithError(err).Warn("Failed to serialize RepoOwners for the persistent cache.")
+			} else {
+				c.persistent.Set(key, b)
+			}
+		}
 	}
 	return entry, nil
 }
 
+// withDefaultOwners returns the owners with the default owners as the owners
+// of the root directory of the repo.
+func (c *Client) withDefaultOwners(org, fullName string, o *RepoOwners, defaults *ownersconfig.DefaultOwners, log *logrus.Entry) *RepoOwners {
+	approvers := o.ExpandAliases(NormLogins(defaults.Approvers))
+	reviewers := o.ExpandAliases(NormLogins(defaults.Reviewers))
+	for _, team := range defaults.Teams {
+		members, err := c.defaultTeamMembers(org, fullName, team)
+		if err != nil {
+			log.WithError(err).Warnf("Failed to list the members of the default owners team %q.", team)
+			continue
+		}
+		approvers.Insert(members.UnsortedList()...)
+		reviewers.Insert(members.UnsortedList()...)
+	}
+
+	result := *o
+	result.defaultOwners = true
+	result.codeOwners = nil
+	result.approvers = make(map[string]map[*regexp.Regexp]sets.String)
+	result.reviewers = make(map[string]map[*regexp.Regexp]sets.String)
+	if approvers.Len() > 0 {
+		result.approvers[baseDirConvention] = map[*regexp.Regexp]sets.String{nil: approvers}
+	}
+	if reviewers.Len() > 0 {
+		result.reviewers[baseDirConvention] = map[*regexp.Regexp]sets.String{nil: reviewers}
+	}
+	return &result
+}
+
+// defaultTeamMembers returns the normalized logins of the members of the team
+// of the org. They are cached with the entry of the repo for
+// defaultTeamMembersTTL, as loading the owners of a repo without OWNERS files
+// would list them every time otherwise.
+func (c *Client) defaultTeamMembers(org, fullName, team string) (sets.String, error) {
+	entry, ok, entryLock := c.cache.getEntry(fullName)
+	defer entryLock.Unlock()
+	if cached, hit := entry.defaultTeamMembers[team]; hit && time.Now().Before(cached.expires) {
+		return cached.logins, nil
+	}
+
+	members, err := c.ghc.ListTeamMembersBySlug(org, team, github.RoleAll)
+	if err != nil {
+		return nil, err
+	}
+	logins := sets.NewString()
+	for _, member := range members {
+		logins.Insert(github.NormLogin(member.Login))
+	}
+	if ok {
+		cached := make(map[string]cachedTeamMembers, len(entry.defaultTeamMembers)+1)
+		for slug, members := range entry.defaultTeamMembers {
+			cached[slug] = members
+		}
+		cached[team] = cachedTeamMembers{logins: logins, expires: time.Now().Add(defaultTeamMembersTTL)}
+		entry.defaultTeamMembers = cached
+		c.cache.setEntry(fullName, entry)
+	}
+	return logins, nil
+}
+
+// ignoredDirs returns the patterns of the directories whose OWNERS files are
+// ignored in the repo, and their regexps.
+func (c *Client) ignoredDirs(org, repo string, log *logrus.Entry) ([]string, []*regexp.Regexp) {
+	start := time.Now()
+	ignoreDirPatterns := c.ownersDirDenylist().ListIgnoredDirs(org, repo)
+	var dirIgnorelist []*regexp.Regexp
+	for _, pattern := range ignoreDirPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.WithError(err).Errorf("Invalid OWNERS dir denylist regexp %q.", pattern)
+			continue
+		}
+		dirIgnorelist = append(dirIgnorelist, re)
+	}
+	log.WithField("duration", time.Since(start).String()).Debugf("Completed dirIgnorelist loading")
+	return ignoreDirPatterns, dirIgnorelist
+}
+
 // ExpandAlias returns members of an alias
 func (a RepoAliases) ExpandAlias(alias string) sets.String {
 	if a == nil {
@@ -490,11 +674,21 @@ func loadOwnersFrom(baseDir string, mdYaml bool, aliases RepoAliases, dirIgnorel
 		requiredReviewers: make(map[string]map[*regexp.Regexp]sets.String),
 		labels:            make(map[string]map[*regexp.Regexp]sets.String),
 		options:           make(map[string]dirOptions),
+		ruleSources:       make(map[*regexp.Regexp]ruleSource),
+		noParentOwners:    make(map[string][]*regexp.Regexp),
 
 		dirDenylist: dirIgnorelist,
 	}
 
-	return o, filepath.Walk(o.baseDir, o.walkFunc)
+	if err := filepath.Walk(o.baseDir, o.walkFunc); err != nil {
+		return o, err
+	}
+	// Repos that only maintain a CODEOWNERS file get their approvers and
+	// reviewers from there.
+	if len(o.approvers) == 0 && len(o.reviewers) == 0 {
+		o.codeOwners = loadCodeOwnersFrom(o.baseDir, log)
+	}
+	return o, nil
 }
 
 // by default, github's api doesn't root the project directory at "/" and instead uses the empty string for the base dir
@@ -552,6 +746,15 @@ func (o *RepoOwners) walkFunc(path string, info os.FileInfo, err error) error {
 		return nil
 	}
 
+	full, err := o.ParseFullConfig(path)
+	if err == filepath.SkipDir {
+		return err
+	}
+	if err == nil && full.Version == ownersV2 {
+		o.applyFullConfigToPath(relPathDir, filepath.Join(relPathDir, filename), full, log)
+		return nil
+	}
+
 	simple, err := o.ParseSimpleConfig(path)
 	if err == filepath.SkipDir {
 		return err
@@ -565,17 +768,7 @@ func (o *RepoOwners) walkFunc(path string, info os.FileInfo, err error) error {
 			log.WithError(err).Debugf("Failed to unmarshal %s into either Simple or FullConfig.", path)
 		} else {
 			// it's a FullConfig
-			for pattern, config := range c.Filters {
-				var re *regexp.Regexp
-				if pattern != ".*" {
-					if re, err = regexp.Compile(pattern); err != nil {
-						log.WithError(err).Debugf("Invalid regexp %q.", pattern)
-						continue
-					}
-				}
-				o.applyConfigToPath(relPathDir, re, &config)
-			}
-			o.applyOptionsToPath(relPathDir, c.Options)
+			o.applyFullConfigToPath(relPathDir, filepath.Join(relPathDir, filename), c, log)
 		}
 	} else {
 		// it's a SimpleConfig
@@ -701,6 +894,98 @@ func NormLogins(logins []string) sets.String {
 
 var defaultDirOptions = dirOptions{}
 
+// ruleSource is the rule of an OWNERS file that a filter comes from.
+type ruleSource struct {
+	file    string
+	pattern string
+}
+
+func (o *RepoOwners) applyFullConfigToPath(path, file string, full FullConfig, log *logrus.Entry) {
+	for pattern, config := range full.Filters {
+		var re *regexp.Regexp
+		if pattern != ".*" {
+			var err error
+			if re, err = regexp.Compile(pattern); err != nil {
+				log.WithError(err).Debugf("Invalid regexp %q.", pattern)
+				continue
+			}
+		}
+		o.applyConfigToPath(path, re, &config)
+	}
+	o.applyOptionsToPath(path, full.Options)
+	if full.Version != ownersV2 {
+		return
+	}
+	for _, rule := range full.Rules {
+		for _, pattern := range rule.Paths {
+			target, re, err := ruleTarget(path, pattern)
+			if err != nil {
+				log.WithError(err).Debugf("Invalid rule path %q.", pattern)
+				continue
+			}
+			if rule.NoParentOwners {
+				o.noParentOwners[target] = append(o.noParentOwners[target], re)
+			}
+			// Every rule gets its own filter, even if it applies to all files
+			// below the target, so that its owners are kept apart from those
+			// of the OWNERS file of the target.
+			if re == nil {
+				re = regexp.MustCompile("")
+			}
+			o.ruleSources[re] = ruleSource{file: file, pattern: pattern}
+			o.applyConfigToPath(target, re, &rule.Config)
+		}
+	}
+}
+
+// ValidateRulePath checks that a rule path of an OWNERS file stays in the
+// directory of the file. The owners of the file must not be able to grant
+// themselves approval of the files of its parent or sibling directories.
+func ValidateRulePath(pattern string) error {
+	for _, component := range strings.Split(pattern, "/") {
+		if component == ".." {
+			return fmt.Errorf("rule path %q must not contain \"..\"", pattern)
+		}
+	}
+	return nil
+}
+
+// ruleTarget returns the path whose owners the files matching the rule path
+// get and the filter that selects them below it, or nil if the rule applies
+// to all of them. The target is the deepest path the rule path names before
+// its first wildcard, e.g. "pkg/api" for "pkg/api/**/*.go".
+func ruleTarget(dir, pattern string) (string, *regexp.Regexp, error) {
+	if err := ValidateRulePath(pattern); err != nil {
+		return "", nil, err
+	}
+	trimmed := strings.Trim(pattern, "/")
+	// Like in .gitignore, a pattern without a slash but at its end matches
+	// at any depth.
+	if !strings.Contains(trimmed, "/") && !strings.HasPrefix(pattern, "/") && strings.ContainsAny(trimmed, "*?") {
+		if trimmed == "**" {
+			return dir, nil, nil
+		}
+		re, err := codeOwnersPatternToRegexp(pattern)
+		return dir, re, err
+	}
+
+	components := strings.Split(trimmed, "/")
+	literal := 0
+	for literal < len(components) && !strings.ContainsAny(components[literal], "*?") {
+		literal++
+	}
+	target := canonicalize(filepath.Join(append([]string{dir}, components[:literal]...)...))
+	if dir != baseDirConvention && target != dir && !strings.HasPrefix(target, dir+"/") {
+		return "", nil, fmt.Errorf("rule path %q is not below %q", pattern, dir)
+	}
+	rest := strings.Join(components[literal:], "/")
+	if rest == "" || rest == "**" {
+		return target, nil, nil
+	}
+	re, err := codeOwnersPatternToRegexp("/" + rest)
+	return target, re, err
+}
+
 func (o *RepoOwners) applyConfigToPath(path string, re *regexp.Regexp, config *Config) {
 	if len(config.Approvers) > 0 {
 		if o.approvers[path] == nil {
@@ -752,8 +1037,12 @@ func (o *RepoOwners) filterCollaborators(toKeep []github.User) *RepoOwners {
 	}
 
 	result := *o
+	result.unfiltered = o
 	result.approvers = filter(o.approvers)
 	result.reviewers = filter(o.reviewers)
+	if o.codeOwners != nil {
+		result.codeOwners = o.codeOwners.filterCollaborators(collabs)
+	}
 	return &result
 }
 
@@ -781,14 +1070,22 @@ func findOwnersForFile(log *logrus.Entry, path string, ownerMap map[string]map[*
 }
 
 // FindApproverOwnersForFile returns the directory containing the OWNERS file furthest down the tree for a specified file
-// that contains an approvers section
+// that contains an approvers section. For repos using a CODEOWNERS file, the file itself is returned instead if a
+// rule matches it.
 func (o *RepoOwners) FindApproverOwnersForFile(path string) string {
+	if o.codeOwners != nil {
+		return o.codeOwners.ownersPathFor(path)
+	}
 	return findOwnersForFile(o.log, path, o.approvers)
 }
 
 // FindReviewersOwnersForFile returns the OWNERS file path furthest down the tree for a specified file
-// that contains a reviewers section
+// that contains a reviewers section. For repos using a CODEOWNERS file, the file itself is returned instead if a
+// rule matches it.
 func (o *RepoOwners) FindReviewersOwnersForFile(path string) string {
+	if o.codeOwners != nil {
+		return o.codeOwners.ownersPathFor(path)
+	}
 	return findOwnersForFile(o.log, path, o.reviewers)
 }
 
@@ -798,9 +1095,41 @@ func (o *RepoOwners) FindLabelsForFile(path string) sets.String {
 	return o.entriesForFile(path, o.labels, false).Set()
 }
 
+// empty returns whether the repo has neither OWNERS files nor a CODEOWNERS
+// file that grant anyone approval or review.
+func (o *RepoOwners) empty() bool {
+	return len(o.approvers) == 0 && len(o.reviewers) == 0 && len(o.codeOwners) == 0
+}
+
 // IsNoParentOwners checks if an OWNERS file path refers to an OWNERS file with NoParentOwners enabled.
+// CODEOWNERS rules never inherit the owners of other rules.
 func (o *RepoOwners) IsNoParentOwners(path string) bool {
-	return o.options[path].NoParentOwners
+	if o.codeOwners != nil {
+		return true
+	}
+	if o.options[path].NoParentOwners {
+		return true
+	}
+	for _, re := range o.noParentOwners[path] {
+		if re == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// stopsInheritance returns whether the owners of the file at the relative
+// path below dir don't include the owners of the parent directories of dir.
+func (o *RepoOwners) stopsInheritance(dir, relative string) bool {
+	if o.options[dir].NoParentOwners {
+		return true
+	}
+	for _, re := range o.noParentOwners[dir] {
+		if re == nil || re.MatchString(relative) {
+			return true
+		}
+	}
+	return false
 }
 
 func (o *RepoOwners) IsAutoApproveUnownedSubfolders(ownersFilePath string) bool {
@@ -837,7 +1166,7 @@ func (o *RepoOwners) entriesForFile(path string, people map[string]map[*regexp.R
 		if d == baseDirConvention {
 			break
 		}
-		if o.options[d].NoParentOwners {
+		if o.stopsInheritance(d, relative) {
 			break
 		}
 		d = filepath.Dir(d)
@@ -851,6 +1180,9 @@ func (o *RepoOwners) entriesForFile(path string, people map[string]map[*regexp.R
 // requested file. If pkg/OWNERS has user1 and pkg/util/OWNERS has user2 this
 // will only return user2 for the path pkg/util/sets/file.go
 func (o *RepoOwners) LeafApprovers(path string) sets.String {
+	if o.codeOwners != nil {
+		return o.codeOwners.ownersFor(path)
+	}
 	return o.entriesForFile(path, o.approvers, true).Set()
 }
 
@@ -859,6 +1191,9 @@ func (o *RepoOwners) LeafApprovers(path string) sets.String {
 // If pkg/OWNERS has user1 and pkg/util/OWNERS has user2 this
 // will return both user1 and user2 for the path pkg/util/sets/file.go
 func (o *RepoOwners) Approvers(path string) layeredsets.String {
+	if o.codeOwners != nil {
+		return o.codeOwners.entriesForFile(path)
+	}
 	return o.entriesForFile(path, o.approvers, false)
 }
 
@@ -866,6 +1201,9 @@ func (o *RepoOwners) Approvers(path string) layeredsets.String {
 // requested file. If pkg/OWNERS has user1 and pkg/util/OWNERS has user2 this
 // will only return user2 for the path pkg/util/sets/file.go
 func (o *RepoOwners) LeafReviewers(path string) sets.String {
+	if o.codeOwners != nil {
+		return o.codeOwners.ownersFor(path)
+	}
 	return o.entriesForFile(path, o.reviewers, true).Set()
 }
 
@@ -874,6 +1212,9 @@ func (o *RepoOwners) LeafReviewers(path string) sets.String {
 // If pkg/OWNERS has user1 and pkg/util/OWNERS has user2 this
 // will return both user1 and user2 for the path pkg/util/sets/file.go
 func (o *RepoOwners) Reviewers(path string) layeredsets.String {
+	if o.codeOwners != nil {
+		return o.codeOwners.entriesForFile(path)
+	}
 	return o.entriesForFile(path, o.reviewers, false)
 }
 
@@ -886,11 +1227,17 @@ func (o *RepoOwners) RequiredReviewers(path string) sets.String {
 }
 
 func (o *RepoOwners) TopLevelApprovers() sets.String {
+	if o.codeOwners != nil {
+		return o.codeOwners.ownersFor(baseDirConvention)
+	}
 	return o.entriesForFile(".", o.approvers, true).Set()
 }
 
 func (o *RepoOwners) AllOwners() sets.String {
 	allOwners := sets.NewString()
+	if o.codeOwners != nil {
+		allOwners = allOwners.Union(o.codeOwners.allOwners())
+	}
 	for _, pv := range o.approvers {
 		for _, rv := range pv {
 			allOwners = allOwners.Union(rv)
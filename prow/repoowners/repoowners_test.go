@@ -25,6 +25,7 @@ import (
 	"regexp"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -128,6 +129,7 @@ type cacheOptions struct {
 
 type fakeGitHubClient struct {
 	Collaborators []string
+	Teams         map[string][]string
 	ref           string
 }
 
@@ -143,6 +145,18 @@ func (f *fakeGitHubClient) GetRef(org, repo, ref string) (string, error) {
 	return f.ref, nil
 }
 
+func (f *fakeGitHubClient) ListTeamMembersBySlug(org, teamSlug, role string) ([]github.TeamMember, error) {
+	var members []github.TeamMember
+	for _, login := range f.Teams[teamSlug] {
+		members = append(members, github.TeamMember{Login: login})
+	}
+	return members, nil
+}
+
+func (f *fakeGitHubClient) GetSingleCommit(org, repo, SHA string) (github.RepositoryCommit, error) {
+	return github.RepositoryCommit{SHA: SHA, Commit: github.GitCommit{Tree: github.Tree{SHA: "tree-of-" + SHA}}}, nil
+}
+
 func getTestClient(
 	files map[string][]byte,
 	enableMdYaml,
@@ -453,6 +467,285 @@ func testOwnersRegexpFiltering(clients localgit.Clients, t *testing.T) {
 	}
 }
 
+func TestOwnersRules(t *testing.T) {
+	testOwnersRules(localgit.New, t)
+}
+
+func TestOwnersRulesV2(t *testing.T) {
+	testOwnersRules(localgit.NewV2, t)
+}
+
+func testOwnersRules(clients localgit.Clients, t *testing.T) {
+	files := map[string][]byte{
+		"OWNERS": []byte(`approvers:
+- root`),
+		"mono/OWNERS": []byte(`version: 2
+rules:
+- paths:
+  - "**"
+  approvers:
+  - mono-lead
+- paths:
+  - svc/a/
+  approvers:
+  - alice
+  required_reviewers:
+  - sec
+- paths:
+  - "svc/b/**/*.proto"
+  approvers:
+  - bob
+  no_parent_owners: true
+- paths:
+  - "*.md"
+  reviewers:
+  - docs`),
+		"mono/svc/a/OWNERS": []byte(`approvers:
+- carl`),
+	}
+	tests := []struct {
+		path                      string
+		expectedApprovers         sets.String
+		expectedLeafApprovers     sets.String
+		expectedReviewers         sets.String
+		expectedRequiredReviewers sets.String
+	}{
+		{
+			path:                      "mono/svc/a/main.go",
+			expectedApprovers:         sets.NewString("root", "mono-lead", "alice", "carl"),
+			expectedLeafApprovers:     sets.NewString("alice", "carl"),
+			expectedReviewers:         sets.NewString(),
+			expectedRequiredReviewers: sets.NewString("sec"),
+		},
+		{
+			path:                      "mono/svc/b/api/v1/types.proto",
+			expectedApprovers:         sets.NewString("bob"),
+			expectedLeafApprovers:     sets.NewString("bob"),
+			expectedReviewers:         sets.NewString(),
+			expectedRequiredReviewers: sets.NewString(),
+		},
+		{
+			path:                      "mono/svc/b/main.go",
+			expectedApprovers:         sets.NewString("root", "mono-lead"),
+			expectedLeafApprovers:     sets.NewString("mono-lead"),
+			expectedReviewers:         sets.NewString(),
+			expectedRequiredReviewers: sets.NewString(),
+		},
+		{
+			path:                      "mono/svc/c/README.md",
+			expectedApprovers:         sets.NewString("root", "mono-lead"),
+			expectedLeafApprovers:     sets.NewString("mono-lead"),
+			expectedReviewers:         sets.NewString("docs"),
+			expectedRequiredReviewers: sets.NewString(),
+		},
+	}
+
+	client, cleanup, err := getTestClient(files, false, true, false, false, nil, nil, nil, nil, clients)
+	if err != nil {
+		t.Fatalf("Error creating test client: %v.", err)
+	}
+	defer cleanup()
+
+	r, err := client.LoadRepoOwners("org", "repo", defaultBranch)
+	if err != nil {
+		t.Fatalf("Unexpected error loading RepoOwners: %v.", err)
+	}
+	ro := r.(*RepoOwners)
+	for _, test := range tests {
+		if got := ro.Approvers(test.path).Set(); !got.Equal(test.expectedApprovers) {
+			t.Errorf("For file %q expected approvers %q, but got %q.", test.path, test.expectedApprovers.List(), got.List())
+		}
+		if got := ro.LeafApprovers(test.path); !got.Equal(test.expectedLeafApprovers) {
+			t.Errorf("For file %q expected leaf approvers %q, but got %q.", test.path, test.expectedLeafApprovers.List(), got.List())
+		}
+		if got := ro.Reviewers(test.path).Set(); !got.Equal(test.expectedReviewers) {
+			t.Errorf("For file %q expected reviewers %q, but got %q.", test.path, test.expectedReviewers.List(), got.List())
+		}
+		if got := ro.RequiredReviewers(test.path); !got.Equal(test.expectedRequiredReviewers) {
+			t.Errorf("For file %q expected required reviewers %q, but got %q.", test.path, test.expectedRequiredReviewers.List(), got.List())
+		}
+	}
+}
+
+func TestRuleTarget(t *testing.T) {
+	tests := []struct {
+		pattern        string
+		expectedTarget string
+		expectedRegexp string
+	}{
+		{pattern: "**", expectedTarget: "mono"},
+		{pattern: "svc/a/", expectedTarget: "mono/svc/a"},
+		{pattern: "/svc/a/main.go", expectedTarget: "mono/svc/a/main.go"},
+		{pattern: "svc/b/**/*.proto", expectedTarget: "mono/svc/b", expectedRegexp: `^(?:.*/)?[^/]*\.proto(?:/.*)?$`},
+		{pattern: "svc/*/api/", expectedTarget: "mono/svc", expectedRegexp: `^[^/]*/api(?:/.*)?$`},
+		{pattern: "*.md", expectedTarget: "mono", expectedRegexp: `^(?:.*/)?[^/]*\.md(?:/.*)?$`},
+	}
+	for _, test := range tests {
+		target, re, err := ruleTarget("mono", test.pattern)
+		if err != nil {
+			t.Errorf("For pattern %q unexpected error: %v", test.pattern, err)
+			continue
+		}
+		if target != test.expectedTarget {
+			t.Errorf("For pattern %q expected target %q, but got %q.", test.pattern, test.expectedTarget, target)
+		}
+		var got string
+		if re != nil {
+			got = re.String()
+		}
+		if got != test.expectedRegexp {
+			t.Errorf("For pattern %q expected regexp %q, but got %q.", test.pattern, test.expectedRegexp, got)
+		}
+	}
+}
+
+func TestRuleTargetOutsideOfDir(t *testing.T) {
+	for _, pattern := range []string{"../**", "../../**", "svc/../../other/", "/../main.go", "..", "svc/**/../x"} {
+		if target, _, err := ruleTarget("mono/sig-foo", pattern); err == nil {
+			t.Errorf("For pattern %q expected an error, but got target %q.", pattern, target)
+		}
+	}
+}
+
+func TestRulesOutsideOfDirGrantNothing(t *testing.T) {
+	testRulesOutsideOfDirGrantNothing(localgit.New, t)
+}
+
+func TestRulesOutsideOfDirGrantNothingV2(t *testing.T) {
+	testRulesOutsideOfDirGrantNothing(localgit.NewV2, t)
+}
+
+func testRulesOutsideOfDirGrantNothing(clients localgit.Clients, t *testing.T) {
+	files := map[string][]byte{
+		"OWNERS": []byte(`approvers:
+- root`),
+		"sig-foo/OWNERS": []byte(`version: 2
+rules:
+- paths:
+  - "../../**"
+  - "../sig-bar/"
+  approvers:
+  - mallory
+`),
+		"sig-bar/OWNERS": []byte(`approvers:
+- bar`),
+	}
+	client, cleanup, err := getTestClient(files, false, true, false, false, nil, nil, nil, nil, clients)
+	if err != nil {
+		t.Fatalf("Error creating test client: %v.", err)
+	}
+	defer cleanup()
+
+	ro, err := client.LoadRepoOwners("org", "repo", defaultBranch)
+	if err != nil {
+		t.Fatalf("Unexpected error loading RepoOwners: %v.", err)
+	}
+	for _, path := range []string{"main.go", "sig-bar/bar.go"} {
+		if ro.Approvers(path).Has("mallory") {
+			t.Errorf("Expected mallory not to be an approver of %q, got %v.", path, ro.Approvers(path).Set().List())
+		}
+	}
+}
+
+func TestDefaultOwners(t *testing.T) {
+	defaults := &ownersconfig.DefaultOwners{
+		Approvers: []string{"Alice"},
+		Reviewers: []string{"carl"},
+		Teams:     []string{"maintainers"},
+	}
+	testCases := []struct {
+		name              string
+		files             map[string][]byte
+		expectedApprovers sets.String
+		expectedReviewers sets.String
+		expectDefaults    bool
+	}{
+		{
+			name:              "repo without OWNERS files gets the default owners",
+			files:             map[string][]byte{"src/main.go": []byte("package main")},
+			expectedApprovers: sets.NewString("alice", "bob"),
+			expectedReviewers: sets.NewString("carl", "bob"),
+			expectDefaults:    true,
+		},
+		{
+			name:              "repo with OWNERS files keeps its owners",
+			files:             map[string][]byte{"OWNERS": []byte("approvers:\n- cjwagner")},
+			expectedApprovers: sets.NewString("cjwagner"),
+			expectedReviewers: sets.NewString(),
+		},
+		{
+			name:              "repo with a CODEOWNERS file keeps its owners",
+			files:             map[string][]byte{"CODEOWNERS": []byte("* @mml")},
+			expectedApprovers: sets.NewString("mml"),
+			expectedReviewers: sets.NewString("mml"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, cleanup, err := getTestClient(tc.files, false, false, false, false, nil, nil, nil, nil, localgit.New)
+			if err != nil {
+				t.Fatalf("Error creating test client: %v.", err)
+			}
+			defer cleanup()
+			// eve is not a collaborator of the repo.
+			client.ghc.(*fakeGitHubClient).Teams = map[string][]string{"maintainers": {"bob", "eve"}}
+			client.UseDefaultOwners(func(org, repo string) *ownersconfig.DefaultOwners {
+				return defaults
+			})
+
+			r, err := client.LoadRepoOwners("org", "repo", defaultBranch)
+			if err != nil {
+				t.Fatalf("Unexpected error loading RepoOwners: %v.", err)
+			}
+			if got := r.Approvers("src/main.go").Set(); !got.Equal(tc.expectedApprovers) {
+				t.Errorf("Expected approvers %q, but got %q.", tc.expectedApprovers.List(), got.List())
+			}
+			if got := r.Reviewers("src/main.go").Set(); !got.Equal(tc.expectedReviewers) {
+				t.Errorf("Expected reviewers %q, but got %q.", tc.expectedReviewers.List(), got.List())
+			}
+			if got := r.(*RepoOwners).Explain("src/main.go").DefaultOwners; got != tc.expectDefaults {
+				t.Errorf("Expected the explanation to report default owners to be %t, but got %t.", tc.expectDefaults, got)
+			}
+		})
+	}
+}
+
+func TestDefaultOwnersTeamMembersAreCached(t *testing.T) {
+	client, cleanup, err := getTestClient(map[string][]byte{"src/main.go": []byte("package main")}, false, false, false, false, nil, nil, nil, nil, localgit.New)
+	if err != nil {
+		t.Fatalf("Error creating test client: %v.", err)
+	}
+	defer cleanup()
+	ghc := client.ghc.(*fakeGitHubClient)
+	ghc.Teams = map[string][]string{"maintainers": {"bob"}}
+	client.UseDefaultOwners(func(org, repo string) *ownersconfig.DefaultOwners {
+		return &ownersconfig.DefaultOwners{Teams: []string{"maintainers"}}
+	})
+	expectApprovers := func(expected ...string) {
+		t.Helper()
+		r, err := client.LoadRepoOwners("org", "repo", defaultBranch)
+		if err != nil {
+			t.Fatalf("Unexpected error loading RepoOwners: %v.", err)
+		}
+		if got := r.Approvers("src/main.go").Set(); !got.Equal(sets.NewString(expected...)) {
+			t.Errorf("Expected approvers %q, but got %q.", expected, got.List())
+		}
+	}
+
+	expectApprovers("bob")
+	// The members are cached with the repo until they expire.
+	ghc.Teams = map[string][]string{"maintainers": {"cjwagner"}}
+	expectApprovers("bob")
+
+	key := "org/repo:" + defaultBranch
+	entry := client.cache.data[key]
+	cached := entry.defaultTeamMembers["maintainers"]
+	cached.expires = time.Now().Add(-time.Second)
+	entry.defaultTeamMembers["maintainers"] = cached
+	client.cache.setEntry(key, entry)
+	expectApprovers("cjwagner")
+}
+
 func strP(str string) *string {
 	return &str
 }
@@ -68,25 +68,14 @@ func (o Options) censor() error {
 		errLock.Unlock()
 	}()
 
-	secrets, err := loadSecrets(o.CensoringOptions.SecretDirectories, o.CensoringOptions.IniFilenames)
+	censorer, bufferSize, err := o.censorer()
 	if err != nil {
 		// TODO(petr-muller): This return makes the censoring mechanism fragile, single failure in `loadSecrets`
 		// will prevent us from censoring all other secrets that were successfully loaded. Alternatively,
 		// we could be more strict and just bail out at our callsite in run.go:preUpload() instead of just
 		// emitting a warning there. But failing fast combined with just warning about the failure is not
 		// a sound approach for a secret-censoring mechanism.
-		return fmt.Errorf("could not load secrets: %w", err)
-	}
-	logrus.WithField("secrets", len(secrets)).Debug("Loaded secrets to censor.")
-	censorer := secretutil.NewCensorer()
-	censorer.RefreshBytes(secrets...)
-
-	bufferSize := defaultBufferSize
-	if o.CensoringOptions.CensoringBufferSize != nil {
-		bufferSize = *o.CensoringOptions.CensoringBufferSize
-	}
-	if largest := censorer.LargestSecret(); 2*largest > bufferSize {
-		bufferSize = 2 * largest
+		return err
 	}
 	logrus.WithField("buffer_size", bufferSize).Debug("Determined censoring buffer size.")
 	censorFile := fileCensorer(sem, errors, censorer, bufferSize)
@@ -156,6 +145,27 @@ func (o Options) censor() error {
 	return kerrors.NewAggregate(errs)
 }
 
+// censorer loads the secrets and returns a censorer for them, along with the
+// size of the buffer that files should be censored with.
+func (o Options) censorer() (secretutil.Censorer, int, error) {
+	secrets, err := loadSecrets(o.CensoringOptions.SecretDirectories, o.CensoringOptions.IniFilenames)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not load secrets: %w", err)
+	}
+	logrus.WithField("secrets", len(secrets)).Debug("Loaded secrets to censor.")
+	censorer := secretutil.NewCensorer()
+	censorer.RefreshBytes(secrets...)
+
+	bufferSize := defaultBufferSize
+	if o.CensoringOptions.CensoringBufferSize != nil {
+		bufferSize = *o.CensoringOptions.CensoringBufferSize
+	}
+	if largest := censorer.LargestSecret(); 2*largest > bufferSize {
+		bufferSize = 2 * largest
+	}
+	return censorer, bufferSize, nil
+}
+
 func shouldCensor(options CensoringOptions, path string) (bool, error) {
 	for _, glob := range options.ExcludeDirectories {
 		found, err := zglob.Match(glob, path)
@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+)
+
+// artifactManifest describes the build logs and the artifacts the job
+// uploads. The paths of the entries are the same the upload uses, so the
+// manifest must be built after the files are censored.
+func (o Options) artifactManifest() gcs.ArtifactManifest {
+	entries := map[string]gcs.ArtifactManifestEntry{}
+	add := func(file, destination, retentionClass string) {
+		if _, exists := entries[destination]; exists {
+			return
+		}
+		entry, err := manifestEntry(file)
+		if err != nil {
+			logrus.WithError(err).WithField("path", file).Warn("Failed to add file to the artifact manifest.")
+			return
+		}
+		entry.Path = destination
+		entry.RetentionClass = retentionClass
+		entries[destination] = entry
+	}
+
+	wrappers := o.entries()
+	for _, opt := range wrappers {
+		buildLog := "build-log.txt"
+		if len(wrappers) > 1 {
+			buildLog = fmt.Sprintf("%s-build-log.txt", opt.ContainerName)
+		}
+		add(opt.ProcessLog, buildLog, "")
+	}
+
+	for _, item := range o.GcsOptions.Items {
+		info, err := os.Stat(item)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			destination, _ := gcs.WriterOptionsFromFileName(info.Name())
+			add(item, destination, "")
+			continue
+		}
+		if err := filepath.Walk(item, func(absPath string, info os.FileInfo, err error) error {
+			if info == nil || info.IsDir() {
+				return nil
+			}
+			relPath, err := filepath.Rel(item, absPath)
+			if err != nil {
+				return nil
+			}
+			// This mirrors how gcsupload names the objects of artifacts.
+			dir, filename := path.Split(path.Join(filepath.Base(item), filepath.ToSlash(relPath)))
+			filename, _ = gcs.WriterOptionsFromFileName(filename)
+			destination := strings.ReplaceAll(path.Join(dir, filename), "#", "%23")
+			add(absPath, destination, gcs.RetentionClass(o.GcsOptions.ArtifactRetention, relPath))
+			return nil
+		}); err != nil {
+			logrus.WithError(err).WithField("item", item).Warn("Could not walk item to build the artifact manifest.")
+		}
+	}
+
+	manifest := gcs.ArtifactManifest{Artifacts: make([]gcs.ArtifactManifestEntry, 0, len(entries))}
+	for _, entry := range entries {
+		manifest.Artifacts = append(manifest.Artifacts, entry)
+	}
+	sort.Slice(manifest.Artifacts, func(i, j int) bool {
+		return manifest.Artifacts[i].Path < manifest.Artifacts[j].Path
+	})
+	return manifest
+}
+
+// manifestEntry determines the size, checksum and content type of the file.
+func manifestEntry(file string) (gcs.ArtifactManifestEntry, error) {
+	input, err := os.Open(file)
+	if err != nil {
+		return gcs.ArtifactManifestEntry{}, fmt.Errorf("could not open file: %w", err)
+	}
+	defer input.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, input)
+	if err != nil {
+		return gcs.ArtifactManifestEntry{}, fmt.Errorf("could not read file: %w", err)
+	}
+
+	_, writerOptions := gcs.WriterOptionsFromFileName(filepath.Base(file))
+	var contentType string
+	if writerOptions.ContentType != nil {
+		contentType = *writerOptions.ContentType
+	} else if contentType, err = determineContentType(file); err != nil {
+		return gcs.ArtifactManifestEntry{}, err
+	}
+	return gcs.ArtifactManifestEntry{
+		Size:        size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		ContentType: contentType,
+	}, nil
+}
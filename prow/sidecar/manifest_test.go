@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/gcsupload"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/pod-utils/wrapper"
+)
+
+func TestArtifactManifest(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "manifest")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	write := func(file, content string) {
+		if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+			t.Fatalf("could not create directory for %s: %v", file, err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write %s: %v", file, err)
+		}
+	}
+	checksum := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+	artifactDir := path.Join(tmpDir, "artifacts")
+	processLog := path.Join(tmpDir, "process-log.txt")
+	write(processLog, "build log\n")
+	write(path.Join(artifactDir, "junit.xml"), "<testsuites/>")
+	write(path.Join(artifactDir, "images", "diff.png"), "image")
+	write(path.Join(artifactDir, "logs", "kubelet#1.txt"), "kubelet log\n")
+
+	options := Options{
+		GcsOptions: &gcsupload.Options{
+			Items: []string{artifactDir},
+			ArtifactRetention: []prowv1.ArtifactRetentionClass{
+				{Class: "short-lived", Artifacts: []string{"images/**"}},
+			},
+		},
+		Entries: []wrapper.Options{{ProcessLog: processLog}},
+	}
+	expected := gcs.ArtifactManifest{Artifacts: []gcs.ArtifactManifestEntry{
+		{
+			Path:           "artifacts/images/diff.png",
+			Size:           5,
+			SHA256:         checksum("image"),
+			ContentType:    "image/png",
+			RetentionClass: "short-lived",
+		},
+		{
+			Path:        "artifacts/junit.xml",
+			Size:        13,
+			SHA256:      checksum("<testsuites/>"),
+			ContentType: "text/xml; charset=utf-8",
+		},
+		{
+			Path:        "artifacts/logs/kubelet%231.txt",
+			Size:        12,
+			SHA256:      checksum("kubelet log\n"),
+			ContentType: "text/plain; charset=utf-8",
+		},
+		{
+			Path:        "build-log.txt",
+			Size:        10,
+			SHA256:      checksum("build log\n"),
+			ContentType: "text/plain; charset=utf-8",
+		},
+	}}
+	if diff := cmp.Diff(expected, options.artifactManifest()); diff != "" {
+		t.Errorf("artifact manifest differs from expected: %s", diff)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"flag"
 	"fmt"
 
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/gcsupload"
 	"k8s.io/test-infra/prow/pod-utils/wrapper"
 )
@@ -84,6 +85,10 @@ type Options struct {
 	// CensoringOptions are options that pertain to censoring output before upload.
 	CensoringOptions *CensoringOptions `json:"censoring_options,omitempty"`
 
+	// StreamingOptions are options that pertain to uploading the build logs and
+	// selected artifacts while the job runs.
+	StreamingOptions *prowv1.StreamingOptions `json:"streaming_options,omitempty"`
+
 	// SecretDirectories is deprecated, use censoring_options.secret_directories instead.
 	SecretDirectories []string `json:"secret_directories,omitempty"`
 	// CensoringConcurrency is deprecated, use censoring_options.censoring_concurrency instead.
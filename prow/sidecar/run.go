@@ -86,6 +86,20 @@ func (o Options) Run(ctx context.Context) (int, error) {
 
 	ctx, cancel := context.WithCancel(ctx)
 
+	// Streaming is stopped and waited for before the final and the
+	// best-effort uploads so that it does not overwrite the complete logs and
+	// artifacts with snapshots.
+	streamCtx, stopStreaming := context.WithCancel(ctx)
+	streamed := make(chan struct{})
+	if o.StreamingOptions != nil {
+		go func() {
+			defer close(streamed)
+			o.stream(streamCtx, spec, entries)
+		}()
+	} else {
+		close(streamed)
+	}
+
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
 	go func() {
@@ -102,6 +116,9 @@ func (o Options) Run(ctx context.Context) (int, error) {
 				// data into GCS than attempt to cancel these uploads and get none.
 				logrus.Errorf("Received an interrupt: %s, cancelling...", s)
 
+				stopStreaming()
+				<-streamed
+
 				// perform pre upload tasks
 				o.preUpload()
 
@@ -123,6 +140,8 @@ func (o Options) Run(ctx context.Context) (int, error) {
 	passed, aborted, failures := wait(ctx, entries)
 
 	cancel()
+	stopStreaming()
+	<-streamed
 	// If we are being asked to terminate by the kubelet but we have
 	// seen the test process exit cleanly, we need a chance to upload
 	// artifacts to GCS. The only valid way for this program to exit
@@ -248,6 +267,13 @@ func (o Options) doUpload(ctx context.Context, spec *downwardapi.JobSpec, passed
 		uploadTargets[prowv1.FinishedStatusFile] = gcs.DataUpload(bytes.NewBuffer(finishedData))
 	}
 
+	manifestData, err := json.Marshal(o.artifactManifest())
+	if err != nil {
+		logrus.WithError(err).Warn("Could not marshal artifact manifest")
+	} else {
+		uploadTargets[prowv1.ArtifactManifestFile] = gcs.DataUpload(bytes.NewBuffer(manifestData))
+	}
+
 	if err := o.GcsOptions.Run(ctx, spec, uploadTargets); err != nil {
 		return fmt.Errorf("failed to upload to GCS: %w", err)
 	}
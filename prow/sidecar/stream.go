@@ -0,0 +1,310 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-zglob"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/pod-utils/wrapper"
+	"k8s.io/test-infra/prow/secretutil"
+)
+
+// defaultStreamingInterval is how often the build logs and artifacts are
+// uploaded while the job runs if the streaming options do not set it.
+const defaultStreamingInterval = 30 * time.Second
+
+// streamer uploads the build logs and the selected artifacts of a job while
+// it runs. Only the bytes written since the last upload are appended to the
+// objects where the bucket supports it, like GCS does by composing objects.
+// Elsewhere every upload replaces the objects with snapshots of everything
+// written so far.
+type streamer struct {
+	options Options
+	spec    *downwardapi.JobSpec
+	entries []wrapper.Options
+
+	// censorer censors the snapshots, it is nil if censoring is disabled.
+	censorer   secretutil.Censorer
+	bufferSize int
+	// largestSecret is the size of the largest secret to censor. The last
+	// bytes of censored files are held back by as much, as they may be the
+	// start of a secret.
+	largestSecret int64
+
+	// uploaded holds how many bytes of every file were uploaded.
+	uploaded map[string]int64
+	// replace is set once appending turned out to be unsupported.
+	replace bool
+}
+
+// stream uploads the build logs and the selected artifacts whenever they
+// changed, until the context is cancelled.
+func (o Options) stream(ctx context.Context, spec *downwardapi.JobSpec, entries []wrapper.Options) {
+	s := &streamer{options: o, spec: spec, entries: entries, uploaded: map[string]int64{}}
+	if o.CensoringOptions != nil {
+		censorer, bufferSize, err := o.censorer()
+		if err != nil {
+			// We must not upload anything we could not censor.
+			logrus.WithError(err).Error("Failed to load secrets, not streaming logs and artifacts.")
+			return
+		}
+		s.censorer, s.bufferSize = censorer, bufferSize
+		if sized, ok := censorer.(interface{ LargestSecret() int }); ok {
+			s.largestSecret = int64(sized.LargestSecret())
+		}
+	}
+
+	interval := o.StreamingOptions.Interval.Get()
+	if interval <= 0 {
+		interval = defaultStreamingInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.sync(ctx); err != nil && ctx.Err() == nil {
+			logrus.WithError(err).Warn("Failed to stream logs and artifacts.")
+		}
+	}
+}
+
+// sync uploads the bytes of the files written since they were last
+// uploaded. Files are replaced with snapshots when they are first uploaded,
+// when they shrank or when appending is not supported.
+func (s *streamer) sync(ctx context.Context) error {
+	appendTargets := map[string]gcs.UploadFunc{}
+	replaceTargets := map[string]gcs.UploadFunc{}
+	appended := map[string]int64{}
+	replaced := map[string]int64{}
+	var snapshots []string
+	defer func() {
+		for _, snapshot := range snapshots {
+			if err := os.Remove(snapshot); err != nil {
+				logrus.WithError(err).WithField("snapshot", snapshot).Warn("Failed to remove snapshot.")
+			}
+		}
+	}()
+
+	for destination, file := range s.files() {
+		info, err := os.Stat(file.path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logrus.WithError(err).WithField("path", file.path).Warn("Failed to stat file to stream.")
+			}
+			continue
+		}
+		end := info.Size()
+		if file.censor {
+			end -= s.largestSecret
+		}
+		uploaded, ok := s.uploaded[file.path]
+		if end <= 0 || end == uploaded {
+			continue
+		}
+		from := uploaded
+		if !ok || end < uploaded || s.replace {
+			from = 0
+		}
+		snapshot, err := s.snapshot(file.path, from, end, file.censor)
+		if err != nil {
+			logrus.WithError(err).WithField("path", file.path).Warn("Failed to snapshot file to stream.")
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+		if from == 0 {
+			replaceTargets[destination] = gcs.FileUpload(snapshot)
+			replaced[file.path] = end
+		} else {
+			appendTargets[destination] = gcs.FileUpload(snapshot)
+			appended[file.path] = end
+		}
+	}
+
+	if len(appendTargets) > 0 {
+		switch err := s.options.GcsOptions.AppendFiles(ctx, s.spec, appendTargets); {
+		case errors.Is(err, gcs.ErrAppendUnsupported):
+			// The next sync uploads snapshots of the files instead.
+			logrus.Info("Appending is not supported for the bucket, uploading snapshots from now on.")
+			s.replace = true
+		case err != nil:
+			return err
+		default:
+			for file, end := range appended {
+				s.uploaded[file] = end
+			}
+		}
+	}
+	if len(replaceTargets) > 0 {
+		if err := s.options.GcsOptions.UploadFiles(ctx, s.spec, replaceTargets); err != nil {
+			return err
+		}
+		for file, end := range replaced {
+			s.uploaded[file] = end
+		}
+	}
+	return nil
+}
+
+// streamedFile is a file that is streamed while the job runs.
+type streamedFile struct {
+	path string
+	// censor is true if the file must be censored before it is uploaded.
+	censor bool
+}
+
+// files returns the files to stream by their destination relative to the
+// directory of the job in blob storage, which is the same the final upload
+// uses.
+func (s *streamer) files() map[string]streamedFile {
+	files := map[string]streamedFile{}
+	for _, opt := range s.entries {
+		buildLog := "build-log.txt"
+		if len(s.entries) > 1 {
+			buildLog = fmt.Sprintf("%s-build-log.txt", opt.ContainerName)
+		}
+		files[buildLog] = streamedFile{path: opt.ProcessLog, censor: s.censorer != nil}
+	}
+
+	for _, item := range s.options.GcsOptions.Items {
+		if err := filepath.Walk(item, func(absPath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info.IsDir() || info.Mode()&os.ModeSymlink == os.ModeSymlink {
+				return nil
+			}
+			relPath, err := filepath.Rel(item, absPath)
+			if err != nil {
+				return nil
+			}
+			if !s.selected(relPath) {
+				return nil
+			}
+			censorFile := s.censorer != nil
+			if censorFile {
+				if censorFile, err = shouldCensor(*s.options.CensoringOptions, relPath); err != nil {
+					logrus.WithError(err).WithField("path", absPath).Warn("Could not determine if the artifact should be censored, not streaming it.")
+					return nil
+				}
+			}
+			if censorFile {
+				// Archives are only censored once the job finished.
+				contentType, err := determineContentType(absPath)
+				if err != nil || contentType == "application/x-gzip" || contentType == "application/zip" {
+					return nil
+				}
+			}
+			destination := strings.ReplaceAll(path.Join(filepath.Base(item), filepath.ToSlash(relPath)), "#", "%23")
+			files[destination] = streamedFile{path: absPath, censor: censorFile}
+			return nil
+		}); err != nil {
+			logrus.WithError(err).WithField("item", item).Warn("Could not walk item to stream artifacts.")
+		}
+	}
+	return files
+}
+
+// selected returns true if the artifact at the path, relative to the
+// artifact directory, matches one of the artifacts to stream.
+func (s *streamer) selected(relPath string) bool {
+	for _, glob := range s.options.StreamingOptions.Artifacts {
+		if found, err := zglob.Match(glob, relPath); err == nil && found {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot copies the bytes of the file from the offset up to the end to a
+// temporary file and returns the path of the copy. Censored bytes are censored
+// along with the largest secret's worth of bytes around them, so that secrets
+// spanning the offsets are censored too.
+func (s *streamer) snapshot(file string, from, end int64, censorData bool) (string, error) {
+	input, err := os.Open(file)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %w", err)
+	}
+	defer input.Close()
+	output, err := ioutil.TempFile("", "sidecar-stream")
+	if err != nil {
+		return "", fmt.Errorf("could not create snapshot: %w", err)
+	}
+
+	if censorData {
+		err = s.censorSection(input, output, from, end)
+	} else {
+		_, err = io.Copy(output, io.NewSectionReader(input, from, end-from))
+		if closeErr := output.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		os.Remove(output.Name())
+		return "", fmt.Errorf("could not write snapshot: %w", err)
+	}
+	return output.Name(), nil
+}
+
+// censorSection writes the censored bytes of the input from the offset up to
+// the end to the output, which it closes.
+func (s *streamer) censorSection(input *os.File, output *os.File, from, end int64) error {
+	windowStart := from - s.largestSecret
+	if windowStart < 0 {
+		windowStart = 0
+	}
+	window, err := ioutil.TempFile("", "sidecar-stream-window")
+	if err != nil {
+		output.Close()
+		return err
+	}
+	defer os.Remove(window.Name())
+	section := ioutil.NopCloser(io.NewSectionReader(input, windowStart, end+s.largestSecret-windowStart))
+	if err := censor(section, window, s.censorer, s.bufferSize); err != nil {
+		output.Close()
+		return err
+	}
+
+	censored, err := os.Open(window.Name())
+	if err != nil {
+		output.Close()
+		return err
+	}
+	defer censored.Close()
+	_, err = io.Copy(output, io.NewSectionReader(censored, from-windowStart, end-from))
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/gcsupload"
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+	"k8s.io/test-infra/prow/pod-utils/wrapper"
+	"k8s.io/test-infra/prow/secretutil"
+)
+
+func TestStreamerSync(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "stream")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	artifactDir := path.Join(tmpDir, "artifacts")
+	outputDir := path.Join(tmpDir, "output")
+	processLog := path.Join(tmpDir, "process-log.txt")
+	write := func(file, content string) {
+		if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+			t.Fatalf("could not create directory for %s: %v", file, err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write %s: %v", file, err)
+		}
+	}
+	read := func(file string) string {
+		data, err := ioutil.ReadFile(path.Join(outputDir, file))
+		if err != nil {
+			t.Fatalf("could not read uploaded %s: %v", file, err)
+		}
+		return string(data)
+	}
+	write(processLog, "starting with secret\n")
+	write(path.Join(artifactDir, "progress", "status.txt"), "50% done\n")
+	write(path.Join(artifactDir, "junit.xml"), "<testsuites/>")
+
+	censorer := secretutil.NewCensorer()
+	censorer.RefreshBytes([]byte("secret"))
+	s := &streamer{
+		options: Options{
+			GcsOptions: &gcsupload.Options{
+				Items: []string{artifactDir},
+				GCSConfiguration: &prowv1.GCSConfiguration{
+					PathStrategy:   prowv1.PathStrategyExplicit,
+					LocalOutputDir: outputDir,
+				},
+			},
+			CensoringOptions: &CensoringOptions{ExcludeDirectories: []string{"progress/**"}},
+			StreamingOptions: &prowv1.StreamingOptions{Artifacts: []string{"progress/**"}},
+		},
+		spec:          &downwardapi.JobSpec{Job: "job", Type: prowv1.PeriodicJob, BuildID: "1"},
+		entries:       []wrapper.Options{{ProcessLog: processLog}},
+		censorer:      censorer,
+		bufferSize:    defaultBufferSize,
+		largestSecret: int64(censorer.LargestSecret()),
+		uploaded:      map[string]int64{},
+	}
+
+	// The last bytes of censored files are held back by the size of the
+	// largest secret, the base64 encoding of "secret", as they may be the
+	// start of a secret.
+	if err := s.sync(context.Background()); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if actual, expected := read("build-log.txt"), "starting with"; actual != expected {
+		t.Errorf("expected the streamed log to be %q, got %q", expected, actual)
+	}
+	if actual, expected := read("artifacts/progress/status.txt"), "50% done\n"; actual != expected {
+		t.Errorf("expected the streamed artifact to be %q, got %q", expected, actual)
+	}
+	if _, err := os.Stat(path.Join(outputDir, "artifacts", "junit.xml")); !os.IsNotExist(err) {
+		t.Errorf("expected artifacts that are not selected not to be streamed, got error %v", err)
+	}
+
+	// Only files that changed are uploaded again, and only the bytes that
+	// were written since.
+	write(path.Join(outputDir, "artifacts", "progress", "status.txt"), "overwritten")
+	write(processLog, "starting with secret\nstill running\n")
+	if err := s.sync(context.Background()); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if actual, expected := read("build-log.txt"), "starting with XXXXXX\nstill "; actual != expected {
+		t.Errorf("expected the streamed log to be %q, got %q", expected, actual)
+	}
+	if actual, expected := read("artifacts/progress/status.txt"), "overwritten"; actual != expected {
+		t.Errorf("expected the unchanged artifact not to be streamed again, got %q", actual)
+	}
+
+	write(path.Join(outputDir, "build-log.txt"), "appended to: ")
+	write(processLog, "starting with secret\nstill running\ndone\n")
+	if err := s.sync(context.Background()); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if actual, expected := read("build-log.txt"), "appended to: runni"; actual != expected {
+		t.Errorf("expected the new bytes of the log to be appended, got %q", actual)
+	}
+
+	// Files that shrank are uploaded again.
+	write(processLog, "restarted\n")
+	if err := s.sync(context.Background()); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if actual, expected := read("build-log.txt"), "re"; actual != expected {
+		t.Errorf("expected the streamed log to be %q, got %q", expected, actual)
+	}
+}
@@ -111,10 +111,10 @@ func (sl *Client) urlValues() *url.Values {
 	return &uv
 }
 
-func (sl *Client) postMessage(url string, uv *url.Values) error {
+func (sl *Client) postMessage(url string, uv *url.Values) (string, error) {
 	resp, err := http.PostForm(url, *uv)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
 
@@ -122,32 +122,45 @@ func (sl *Client) postMessage(url string, uv *url.Values) error {
 	apiResponse := struct {
 		Ok    bool   `json:"ok"`
 		Error string `json:"error"`
+		TS    string `json:"ts"`
 	}{}
 
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		return fmt.Errorf("API returned invalid JSON (%q): %w", string(body), err)
+		return "", fmt.Errorf("API returned invalid JSON (%q): %w", string(body), err)
 	}
 
 	if resp.StatusCode != 200 || !apiResponse.Ok {
-		return fmt.Errorf("request failed: %s", apiResponse.Error)
+		return "", fmt.Errorf("request failed: %s", apiResponse.Error)
 	}
 
-	return nil
+	return apiResponse.TS, nil
 }
 
 // WriteMessage adds text to channel
 func (sl *Client) WriteMessage(text, channel string) error {
-	sl.log("WriteMessage", text, channel)
+	_, err := sl.WriteThreadMessage(text, channel, "")
+	return err
+}
+
+// WriteThreadMessage adds text to the thread of the message with the timestamp
+// threadTS in channel. If threadTS is empty, the text is added to the channel.
+// It returns the timestamp of the new message.
+func (sl *Client) WriteThreadMessage(text, channel, threadTS string) (string, error) {
+	sl.log("WriteThreadMessage", text, channel, threadTS)
 	if sl.fake {
-		return nil
+		return "", nil
 	}
 
 	var uv = sl.urlValues()
 	uv.Add("channel", channel)
 	uv.Add("text", text)
+	if threadTS != "" {
+		uv.Add("thread_ts", threadTS)
+	}
 
-	if err := sl.postMessage(chatPostMessage, uv); err != nil {
-		return fmt.Errorf("failed to post message to #%s: %w", channel, err)
+	ts, err := sl.postMessage(chatPostMessage, uv)
+	if err != nil {
+		return "", fmt.Errorf("failed to post message to #%s: %w", channel, err)
 	}
-	return nil
+	return ts, nil
 }
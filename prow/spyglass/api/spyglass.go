@@ -40,6 +40,15 @@ type Lens interface {
 	Callback(artifacts []Artifact, resourceRoot string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string
 }
 
+// PreviousRunLens is a Lens that compares the artifacts of a job run with those of the previous run
+// of the job, e.g. of the same presubmit for the same PR.
+type PreviousRunLens interface {
+	Lens
+	// PreviousRunBody is called instead of Body with the artifacts of the previous run that match
+	// the files of the lens. They are empty if there is no previous run.
+	PreviousRunBody(artifacts, previousArtifacts []Artifact, resourceRoot string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string
+}
+
 // Artifact represents some output of a prow job
 type Artifact interface {
 	// ReadAt reads len(p) bytes of the artifact at offset off. (unsupported on some compressed files)
@@ -91,4 +100,15 @@ type LensRequest struct {
 	// LensIndex is the index by which the lens config can be found
 	// TODO: Replace with something proper or avoid needing this
 	LensIndex int `json:"index"`
+	// LensName is the name of the lens the request is for. Lens servers
+	// serving several lenses over gRPC use it to pick the lens.
+	LensName string `json:"lensName,omitempty"`
+}
+
+// LensResponse is the response of a lens to a LensRequest made over gRPC.
+type LensResponse struct {
+	// ContentType is the content type of the body, if any.
+	ContentType string `json:"contentType,omitempty"`
+	// Body is the content rendered by the lens.
+	Body string `json:"body"`
 }
@@ -350,6 +350,91 @@ function clipLine(lineEl: HTMLDivElement) {
   lineEl.insertAdjacentElement("afterbegin", pin);
 }
 
+function escapeHTML(text: string): string {
+  return text.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;');
+}
+
+// followLog appends the lines written to the log of a running job as Deck
+// sends them over a websocket. Deck resumes the stream itself while it can,
+// and closes the websocket normally once the whole log was sent, i.e. the job
+// has finished. On any other close, the log is followed again from the offset
+// received.
+function followLog(container: HTMLElement): void {
+  const artifact = container.id.replace(/-content$/, '');
+  const status = document.getElementById(`${artifact}-follow-status`);
+  const setStatus = (text: string) => {
+    if (status) {
+      status.textContent = text;
+    }
+  };
+  let offset = Number(container.dataset.followOffset);
+  let lineNumber = Number(container.dataset.followLine);
+  const group = document.createElement('div');
+  group.className = 'shown';
+  container.appendChild(group);
+
+  // The incomplete last line, which is written once its newline is received.
+  let partial = '';
+  const appendLines = (text: string) => {
+    const lines = (partial + text).split('\n');
+    partial = lines.pop()!;
+    if (lines.length === 0) {
+      return;
+    }
+    for (const line of lines) {
+      offset += new TextEncoder().encode(line).length + 1;
+      const row = document.createElement('div');
+      row.id = `${artifact}:${lineNumber}`;
+      row.innerHTML = `<div class="linenum"><a data-artifact="${escapeHTML(artifact)}" data-line-number="${lineNumber}">${lineNumber}</a></div>` +
+        `<div class="linetext"><span>${ansiToHTML(escapeHTML(line))}</span></div>`;
+      group.appendChild(row);
+      lineNumber++;
+    }
+    fixLinks(group);
+    spyglass.contentUpdated();
+  };
+
+  const scheme = location.protocol === 'https:' ? 'wss:' : 'ws:';
+  const initialRetryDelay = 1000;
+  const maxRetryDelay = 30 * 1000;
+  let retryDelay = initialRetryDelay;
+  let failures = 0;
+  const connect = () => {
+    // Lines are only counted once complete, so the partial line is received
+    // again.
+    partial = '';
+    const decoder = new TextDecoder();
+    setStatus('Following the log...');
+    const socket = new WebSocket(`${scheme}//${location.host}${container.dataset.followLink}&follow=true&offset=${offset}`);
+    socket.binaryType = 'arraybuffer';
+    socket.onmessage = (event: MessageEvent) => {
+      retryDelay = initialRetryDelay;
+      failures = 0;
+      appendLines(decoder.decode(new Uint8Array(event.data as ArrayBuffer), {stream: true}));
+    };
+    socket.onclose = (event: CloseEvent) => {
+      if (event.code === 1000) {
+        // The log is complete, so is its last line.
+        appendLines(decoder.decode());
+        if (partial !== '') {
+          appendLines('\n');
+        }
+        setStatus('The job has finished, reload the page for its artifacts.');
+        return;
+      }
+      console.log(`Error following the log: ${event.code} ${event.reason}`);
+      if (++failures >= 10) {
+        setStatus('Failed to follow the log, reload the page to retry.');
+        return;
+      }
+      setStatus(`Reconnecting in ${retryDelay / 1000}s...`);
+      setTimeout(connect, retryDelay);
+      retryDelay = Math.min(retryDelay * 2, maxRetryDelay);
+    };
+  };
+  connect();
+}
+
 window.addEventListener('hashchange', () => handleHash());
 
 window.addEventListener('load', () => {
@@ -372,5 +457,9 @@ window.addEventListener('load', () => {
   }
   fixLinks(document.documentElement);
 
+  for (const container of Array.from(document.querySelectorAll<HTMLElement>('.loglines[data-follow-link]'))) {
+    followLog(container);
+  }
+
   handleHash();
 });
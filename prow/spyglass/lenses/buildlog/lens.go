@@ -140,6 +140,11 @@ type LogArtifactView struct {
 	ViewAll      bool
 	ShowRawLog   bool
 	CanSave      bool
+	// Follow is set for the logs of running jobs, which are followed from
+	// FollowOffset on, numbering their lines from FollowLine.
+	Follow       bool
+	FollowOffset int64
+	FollowLine   int
 }
 
 // buildLogsView holds each log file view
@@ -196,6 +201,16 @@ func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string,
 			logrus.WithError(err).Info("Error reading log.")
 			continue
 		}
+		if isPodLog(av.ArtifactLink) {
+			// The last line may be incomplete, it is shown once the log is followed.
+			last := len(lines) - 1
+			for _, line := range lines[:last] {
+				av.FollowOffset += int64(len(line)) + 1
+			}
+			av.Follow = true
+			av.FollowLine = last + 1
+			lines = lines[:last]
+		}
 		artifact := av.ArtifactName
 		meta, _ := a.Metadata()
 		start, end := -1, -1
@@ -225,6 +240,12 @@ func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string,
 	return executeTemplate(resourceDir, "body", buildLogsView)
 }
 
+// isPodLog returns whether the link is to the log of the pod of a running job,
+// which is served by Deck.
+func isPodLog(link string) bool {
+	return strings.HasPrefix(link, "/log?")
+}
+
 func canSave(link string) bool {
 	return strings.Contains(link, pkgio.GSAnonHost) || strings.Contains(link, pkgio.GSCookieHost)
 }
@@ -320,6 +320,39 @@ func TestBody(t *testing.T) {
 				},
 			})),
 		},
+		{
+			name: "pod log of running job is followed",
+			artifact: &fake.Artifact{
+				Path:    "foo",
+				Content: []byte("hello\nwor"),
+				Link:    pstr("/log?container=test&id=1&job=job"),
+			},
+			want: render(func() LogArtifactView {
+				v := view("foo", "/log?container=test&id=1&job=job", []LineGroup{
+					{
+						Start:        1,
+						End:          1,
+						ArtifactName: pstr("foo"),
+						LogLines: []LogLine{
+							{
+								ArtifactName: pstr("foo"),
+								Number:       1,
+								Length:       6,
+								SubLines: []SubLine{
+									{
+										Text: "hello",
+									},
+								},
+							},
+						},
+					},
+				})
+				v.Follow = true
+				v.FollowOffset = 6
+				v.FollowLine = 2
+				return v
+			}()),
+		},
 		{
 			name: "cookie savable",
 			artifact: &fake.Artifact{
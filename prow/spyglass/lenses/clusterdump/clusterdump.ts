@@ -0,0 +1,32 @@
+const severityRanks: {[severity: string]: number} = {info: 0, warning: 1, error: 2};
+
+function applyFilters(): void {
+  const namespace = (document.getElementById('namespace-filter') as HTMLSelectElement).value;
+  const minRank = severityRanks[(document.getElementById('severity-filter') as HTMLSelectElement).value];
+
+  for (const elem of Array.from(document.querySelectorAll<HTMLElement>('[data-severity]'))) {
+    const rank = severityRanks[elem.dataset.severity!];
+    elem.classList.toggle('filtered', rank < minRank);
+  }
+  for (const elem of Array.from(document.querySelectorAll<HTMLElement>('[data-namespace]'))) {
+    const hidden = namespace !== '' && elem.dataset.namespace !== namespace;
+    elem.classList.toggle('filtered', hidden || severityRanks[elem.dataset.severity!] < minRank);
+    if (namespace !== '' && !hidden) {
+      (elem as HTMLDetailsElement).open = true;
+    }
+  }
+  // Nodes don't belong to a namespace.
+  const nodes = document.querySelector<HTMLElement>('[data-section="nodes"]');
+  if (nodes) {
+    nodes.classList.toggle('filtered', namespace !== '');
+  }
+  spyglass.contentUpdated();
+}
+
+window.addEventListener('DOMContentLoaded', () => {
+  document.getElementById('namespace-filter')!.addEventListener('change', applyFilters);
+  document.getElementById('severity-filter')!.addEventListener('change', applyFilters);
+  for (const details of Array.from(document.querySelectorAll('details'))) {
+    details.addEventListener('toggle', () => spyglass.contentUpdated());
+  }
+});
@@ -0,0 +1,368 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clusterdump provides a Spyglass lens rendering the cluster dumps of
+// Kubernetes e2e runs.
+package clusterdump
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass/api"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "clusterdump"
+	title    = "Cluster Dump"
+	priority = 15
+
+	nodesFile  = "nodes.json"
+	podsFile   = "pods.json"
+	eventsFile = "events.json"
+)
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Lens is the implementation of a Spyglass lens rendering the layout written by
+// `kubectl cluster-info dump --output-directory` next to the node logs of
+// kubetest, e.g.:
+// * artifacts/cluster-info/nodes.json
+// * artifacts/cluster-info/<namespace>/{events,pods,...}.json
+// * artifacts/cluster-info/<namespace>/<pod>/logs.txt
+// * artifacts/<node>/kubelet.log
+type Lens struct{}
+
+// The severities of the objects of the dump, which can be filtered by.
+const (
+	severityInfo    = "info"
+	severityWarning = "warning"
+	severityError   = "error"
+)
+
+var severityRanks = map[string]int{severityInfo: 0, severityWarning: 1, severityError: 2}
+
+// maxSeverity returns the most severe of the severities.
+func maxSeverity(severities ...string) string {
+	max := severityInfo
+	for _, s := range severities {
+		if severityRanks[s] > severityRanks[max] {
+			max = s
+		}
+	}
+	return max
+}
+
+type file struct {
+	Name string
+	Link string
+}
+
+type node struct {
+	Name     string
+	Status   string
+	Severity string
+	Files    []file
+}
+
+type pod struct {
+	Name     string
+	Phase    string
+	Restarts int32
+	Severity string
+	Files    []file
+}
+
+type event struct {
+	Type     string
+	Reason   string
+	Object   string
+	Message  string
+	Count    int32
+	Severity string
+}
+
+type namespace struct {
+	Name     string
+	Severity string
+	Pods     []pod
+	Events   []event
+	Files    []file
+}
+
+type clusterDump struct {
+	Nodes      []node
+	Namespaces []namespace
+	// Errors are the artifacts that couldn't be parsed.
+	Errors []string
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []api.Artifact, resourceDir string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING HEADER: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "header", nil); err != nil {
+		return fmt.Sprintf("<!-- FAILED EXECUTING HEADER TEMPLATE: %v -->", err)
+	}
+	return buf.String()
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	return ""
+}
+
+// Body renders the <body> with the tree of the nodes and namespaces.
+func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	dump := parseDump(artifacts)
+
+	dumpTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+		return fmt.Sprintf("Failed to load template file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := dumpTemplate.ExecuteTemplate(&buf, "body", dump); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+
+	return buf.String()
+}
+
+func readJSON(artifact api.Artifact, v interface{}) error {
+	contents, err := artifact.ReadAll()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(contents, v)
+}
+
+// parseDump builds the tree of the dump. Its root is the directory of the
+// nodes.json artifact, node logs are the artifacts in directories named after
+// the nodes.
+func parseDump(artifacts []api.Artifact) clusterDump {
+	var dump clusterDump
+	root := ""
+	for _, artifact := range artifacts {
+		if path.Base(artifact.JobPath()) == nodesFile {
+			root = path.Dir(artifact.JobPath()) + "/"
+			var nodes v1.NodeList
+			if err := readJSON(artifact, &nodes); err != nil {
+				logrus.WithError(err).WithField("artifact", artifact.JobPath()).Info("Failed to parse nodes.")
+				dump.Errors = append(dump.Errors, artifact.JobPath())
+			}
+			for _, n := range nodes.Items {
+				dump.Nodes = append(dump.Nodes, parseNode(n))
+			}
+			break
+		}
+	}
+
+	nodeIndexes := map[string]int{}
+	for i, n := range dump.Nodes {
+		nodeIndexes[n.Name] = i
+	}
+	namespaces := map[string]*namespace{}
+	getNamespace := func(name string) *namespace {
+		if _, ok := namespaces[name]; !ok {
+			namespaces[name] = &namespace{Name: name}
+		}
+		return namespaces[name]
+	}
+	pods := map[string]map[string]*pod{}
+	getPod := func(ns, name string) *pod {
+		if pods[ns] == nil {
+			pods[ns] = map[string]*pod{}
+		}
+		if _, ok := pods[ns][name]; !ok {
+			pods[ns][name] = &pod{Name: name, Severity: severityInfo}
+		}
+		return pods[ns][name]
+	}
+
+	for _, artifact := range artifacts {
+		jobPath := artifact.JobPath()
+		f := file{Name: path.Base(jobPath), Link: artifact.CanonicalLink()}
+		if root == "" || !strings.HasPrefix(jobPath, root) {
+			if i, ok := nodeIndexes[path.Base(path.Dir(jobPath))]; ok {
+				dump.Nodes[i].Files = append(dump.Nodes[i].Files, f)
+			}
+			continue
+		}
+
+		parts := strings.Split(strings.TrimPrefix(jobPath, root), "/")
+		switch {
+		case len(parts) == 2 && parts[1] == podsFile:
+			var list v1.PodList
+			if err := readJSON(artifact, &list); err != nil {
+				logrus.WithError(err).WithField("artifact", jobPath).Info("Failed to parse pods.")
+				dump.Errors = append(dump.Errors, jobPath)
+				continue
+			}
+			for _, p := range list.Items {
+				parsePod(getPod(parts[0], p.Name), p)
+			}
+			getNamespace(parts[0]).Files = append(getNamespace(parts[0]).Files, f)
+		case len(parts) == 2 && parts[1] == eventsFile:
+			var list v1.EventList
+			if err := readJSON(artifact, &list); err != nil {
+				logrus.WithError(err).WithField("artifact", jobPath).Info("Failed to parse events.")
+				dump.Errors = append(dump.Errors, jobPath)
+				continue
+			}
+			ns := getNamespace(parts[0])
+			for _, e := range list.Items {
+				ns.Events = append(ns.Events, parseEvent(e))
+			}
+			ns.Files = append(ns.Files, f)
+		case len(parts) == 2:
+			getNamespace(parts[0]).Files = append(getNamespace(parts[0]).Files, f)
+		case len(parts) > 2:
+			p := getPod(parts[0], parts[1])
+			p.Files = append(p.Files, f)
+		}
+	}
+
+	for name, nsPods := range pods {
+		ns := getNamespace(name)
+		for _, p := range nsPods {
+			sortFiles(p.Files)
+			ns.Pods = append(ns.Pods, *p)
+		}
+	}
+	for _, ns := range namespaces {
+		ns.Severity = severityInfo
+		for _, p := range ns.Pods {
+			ns.Severity = maxSeverity(ns.Severity, p.Severity)
+		}
+		for _, e := range ns.Events {
+			ns.Severity = maxSeverity(ns.Severity, e.Severity)
+		}
+		sort.Slice(ns.Pods, func(i, j int) bool { return ns.Pods[i].Name < ns.Pods[j].Name })
+		sortFiles(ns.Files)
+		// Warnings first, then the most recent events as dumped.
+		sort.SliceStable(ns.Events, func(i, j int) bool {
+			return severityRanks[ns.Events[i].Severity] > severityRanks[ns.Events[j].Severity]
+		})
+		dump.Namespaces = append(dump.Namespaces, *ns)
+	}
+	sort.Slice(dump.Namespaces, func(i, j int) bool { return dump.Namespaces[i].Name < dump.Namespaces[j].Name })
+	sort.Slice(dump.Nodes, func(i, j int) bool { return dump.Nodes[i].Name < dump.Nodes[j].Name })
+	for _, n := range dump.Nodes {
+		sortFiles(n.Files)
+	}
+	return dump
+}
+
+func sortFiles(files []file) {
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+}
+
+func parseNode(n v1.Node) node {
+	ready := false
+	var problems []string
+	for _, c := range n.Status.Conditions {
+		if c.Type == v1.NodeReady {
+			ready = c.Status == v1.ConditionTrue
+		} else if c.Status == v1.ConditionTrue {
+			// The other conditions, e.g. MemoryPressure, are problems when true.
+			problems = append(problems, string(c.Type))
+		}
+	}
+	result := node{Name: n.Name, Status: "Ready", Severity: severityInfo}
+	if !ready {
+		result.Status, result.Severity = "NotReady", severityError
+	} else if len(problems) > 0 {
+		result.Severity = severityWarning
+	}
+	if len(problems) > 0 {
+		result.Status += ", " + strings.Join(problems, ", ")
+	}
+	return result
+}
+
+// failedWaitingReasons are the reasons of waiting containers which won't start
+// without intervention.
+var failedWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"CreateContainerConfigError": true,
+}
+
+func parsePod(result *pod, p v1.Pod) {
+	result.Phase = string(p.Status.Phase)
+	result.Severity = severityInfo
+	if p.Status.Phase == v1.PodPending {
+		result.Severity = severityWarning
+	}
+	if p.Status.Phase == v1.PodFailed {
+		result.Severity = severityError
+	}
+	for _, c := range append(p.Status.InitContainerStatuses, p.Status.ContainerStatuses...) {
+		result.Restarts += c.RestartCount
+		if c.RestartCount > 0 {
+			result.Severity = maxSeverity(result.Severity, severityWarning)
+		}
+		if c.State.Waiting != nil && failedWaitingReasons[c.State.Waiting.Reason] {
+			result.Severity = severityError
+		}
+		if c.State.Terminated != nil && c.State.Terminated.ExitCode != 0 && p.Status.Phase != v1.PodSucceeded {
+			result.Severity = severityError
+		}
+	}
+}
+
+func parseEvent(e v1.Event) event {
+	result := event{
+		Type:     e.Type,
+		Reason:   e.Reason,
+		Object:   e.InvolvedObject.Kind + "/" + e.InvolvedObject.Name,
+		Message:  e.Message,
+		Count:    e.Count,
+		Severity: severityInfo,
+	}
+	if e.Type == v1.EventTypeWarning {
+		result.Severity = severityWarning
+	}
+	return result
+}
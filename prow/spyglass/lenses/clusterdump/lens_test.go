@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterdump
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/spyglass/api"
+	"k8s.io/test-infra/prow/spyglass/lenses/fake"
+)
+
+func artifact(path, content string) api.Artifact {
+	link := "link/" + path
+	return &fake.Artifact{Path: path, Content: []byte(content), Link: &link}
+}
+
+func TestParseDump(t *testing.T) {
+	artifacts := []api.Artifact{
+		artifact("artifacts/cluster-info/nodes.json", `{"items": [
+			{"metadata": {"name": "node-b"}, "status": {"conditions": [{"type": "Ready", "status": "False"}]}},
+			{"metadata": {"name": "node-a"}, "status": {"conditions": [{"type": "Ready", "status": "True"}, {"type": "MemoryPressure", "status": "True"}]}}
+		]}`),
+		artifact("artifacts/node-a/kubelet.log", ""),
+		artifact("artifacts/junit_01.xml", ""),
+		artifact("artifacts/cluster-info/kube-system/pods.json", `{"items": [
+			{"metadata": {"name": "dns"}, "status": {"phase": "Running", "containerStatuses": [{"restartCount": 2, "state": {"running": {}}}]}},
+			{"metadata": {"name": "proxy"}, "status": {"phase": "Running"}}
+		]}`),
+		artifact("artifacts/cluster-info/kube-system/events.json", `{"items": [
+			{"type": "Normal", "reason": "Pulled", "involvedObject": {"kind": "Pod", "name": "proxy"}, "message": "pulled", "count": 1},
+			{"type": "Warning", "reason": "BackOff", "involvedObject": {"kind": "Pod", "name": "dns"}, "message": "back-off", "count": 3}
+		]}`),
+		artifact("artifacts/cluster-info/kube-system/dns/logs.txt", ""),
+		artifact("artifacts/cluster-info/e2e-test/pods.json", `{"items": [
+			{"metadata": {"name": "crashing"}, "status": {"phase": "Running", "containerStatuses": [{"state": {"waiting": {"reason": "CrashLoopBackOff"}}}]}}
+		]}`),
+		artifact("artifacts/cluster-info/broken/events.json", `not json`),
+	}
+
+	expected := clusterDump{
+		Nodes: []node{
+			{Name: "node-a", Status: "Ready, MemoryPressure", Severity: severityWarning, Files: []file{{Name: "kubelet.log", Link: "link/artifacts/node-a/kubelet.log"}}},
+			{Name: "node-b", Status: "NotReady", Severity: severityError},
+		},
+		Namespaces: []namespace{
+			{
+				Name:     "e2e-test",
+				Severity: severityError,
+				Pods:     []pod{{Name: "crashing", Phase: "Running", Severity: severityError}},
+				Files:    []file{{Name: "pods.json", Link: "link/artifacts/cluster-info/e2e-test/pods.json"}},
+			},
+			{
+				Name:     "kube-system",
+				Severity: severityWarning,
+				Pods: []pod{
+					{Name: "dns", Phase: "Running", Restarts: 2, Severity: severityWarning, Files: []file{{Name: "logs.txt", Link: "link/artifacts/cluster-info/kube-system/dns/logs.txt"}}},
+					{Name: "proxy", Phase: "Running", Severity: severityInfo},
+				},
+				Events: []event{
+					{Type: "Warning", Reason: "BackOff", Object: "Pod/dns", Message: "back-off", Count: 3, Severity: severityWarning},
+					{Type: "Normal", Reason: "Pulled", Object: "Pod/proxy", Message: "pulled", Count: 1, Severity: severityInfo},
+				},
+				Files: []file{
+					{Name: "events.json", Link: "link/artifacts/cluster-info/kube-system/events.json"},
+					{Name: "pods.json", Link: "link/artifacts/cluster-info/kube-system/pods.json"},
+				},
+			},
+		},
+		Errors: []string{"artifacts/cluster-info/broken/events.json"},
+	}
+	if diff := cmp.Diff(expected, parseDump(artifacts)); diff != "" {
+		t.Errorf("dump differs from expected: %s", diff)
+	}
+}
@@ -17,7 +17,10 @@ limitations under the License.
 package common
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -34,6 +37,7 @@ import (
 	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/io/providers"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
 	"k8s.io/test-infra/prow/spyglass/api"
 )
 
@@ -109,46 +113,120 @@ func newLensHandler(lens api.Lens, opts lensHandlerOpts) http.HandlerFunc {
 			return
 		}
 
-		artifacts, err := FetchArtifacts(r.Context(), opts.PJFetcher, opts.ConfigGetter, opts.StorageArtifactFetcher, opts.PodLogArtifactFetcher, request.ArtifactSource, "", opts.ConfigGetter().Deck.Spyglass.SizeLimit, request.Artifacts)
-		if err != nil || len(artifacts) == 0 {
-			statusCode := http.StatusInternalServerError
-			if len(artifacts) == 0 {
-				statusCode = http.StatusNotFound
-				err = errors.New("no artifacts found")
-			}
-
-			writeHTTPError(w, fmt.Errorf("failed to retrieve expected artifacts: %w", err), statusCode)
+		response, statusCode, err := renderLens(r.Context(), lens, opts, request)
+		if err != nil {
+			writeHTTPError(w, err, statusCode)
 			return
 		}
+		if response.ContentType != "" {
+			w.Header().Set("Content-Type", response.ContentType)
+		}
+		if _, err := w.Write([]byte(response.Body)); err != nil {
+			logrus.WithError(err).Error("Failed to write response")
+		}
+	}
+}
+
+// renderLens renders the lens for a request. If it fails, it returns the HTTP
+// status code to answer the request with.
+func renderLens(ctx context.Context, lens api.Lens, opts lensHandlerOpts, request *api.LensRequest) (*api.LensResponse, int, error) {
+	artifacts, err := FetchArtifacts(ctx, opts.PJFetcher, opts.ConfigGetter, opts.StorageArtifactFetcher, opts.PodLogArtifactFetcher, request.ArtifactSource, "", opts.ConfigGetter().Deck.Spyglass.SizeLimit, request.Artifacts)
+	if err != nil || len(artifacts) == 0 {
+		statusCode := http.StatusInternalServerError
+		if len(artifacts) == 0 {
+			statusCode = http.StatusNotFound
+			err = errors.New("no artifacts found")
+		}
+		return nil, statusCode, fmt.Errorf("failed to retrieve expected artifacts: %w", err)
+	}
+
+	switch request.Action {
+	case api.RequestActionInitial:
+		var buf bytes.Buffer
+		if err := lensTemplate.Execute(&buf, struct {
+			Title   string
+			BaseURL string
+			Head    template.HTML
+			Body    template.HTML
+		}{
+			opts.LensTitle,
+			request.ResourceRoot,
+			template.HTML(lens.Header(artifacts, opts.LensResourcesDir, opts.ConfigGetter().Deck.Spyglass.Lenses[request.LensIndex].Lens.Config, opts.ConfigGetter().Deck.Spyglass)),
+			template.HTML(renderBody(ctx, lens, opts, request, artifacts, "")),
+		}); err != nil {
+			return nil, http.StatusInternalServerError, fmt.Errorf("failed to render lens: %w", err)
+		}
+		return &api.LensResponse{ContentType: "text/html; encoding=utf-8", Body: buf.String()}, http.StatusOK, nil
+
+	case api.RequestActionRerender:
+		return &api.LensResponse{ContentType: "text/html; encoding=utf-8", Body: renderBody(ctx, lens, opts, request, artifacts, request.Data)}, http.StatusOK, nil
+
+	case api.RequestActionCallBack:
+		return &api.LensResponse{Body: lens.Callback(artifacts, opts.LensResourcesDir, request.Data, opts.ConfigGetter().Deck.Spyglass.Lenses[request.LensIndex].Lens.Config, opts.ConfigGetter().Deck.Spyglass)}, http.StatusOK, nil
+
+	default:
+		// This is a bit weird as we proxy this and the request we are complaining about was issued by Deck, not by the original client that sees this error
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid action %q", request.Action)
+	}
+}
+
+// renderBody renders the body of the lens, passing it the artifacts of the previous run of the job
+// if it compares them.
+func renderBody(ctx context.Context, lens api.Lens, opts lensHandlerOpts, request *api.LensRequest, artifacts []api.Artifact, data string) string {
+	lensConfig := opts.ConfigGetter().Deck.Spyglass.Lenses[request.LensIndex]
+	previousRunLens, ok := lens.(api.PreviousRunLens)
+	if !ok {
+		return lens.Body(artifacts, opts.LensResourcesDir, data, lensConfig.Lens.Config, opts.ConfigGetter().Deck.Spyglass)
+	}
+	previousArtifacts, err := fetchPreviousRunArtifacts(ctx, opts, request.ArtifactSource, append(lensConfig.RequiredFiles, lensConfig.OptionalFiles...))
+	if err != nil {
+		logrus.WithError(err).WithField("src", request.ArtifactSource).Warn("Failed to fetch artifacts of previous run")
+	}
+	return previousRunLens.PreviousRunBody(artifacts, previousArtifacts, opts.LensResourcesDir, data, lensConfig.Lens.Config, opts.ConfigGetter().Deck.Spyglass)
+}
+
+// PreviousRunFetcher knows how to find the previous run of a job
+type PreviousRunFetcher interface {
+	PreviousRun(ctx context.Context, key string) (string, []string, error)
+}
 
-		switch request.Action {
-		case api.RequestActionInitial:
-			w.Header().Set("Content-Type", "text/html; encoding=utf-8")
-			lensTemplate.Execute(w, struct {
-				Title   string
-				BaseURL string
-				Head    template.HTML
-				Body    template.HTML
-			}{
-				opts.LensTitle,
-				request.ResourceRoot,
-				template.HTML(lens.Header(artifacts, opts.LensResourcesDir, opts.ConfigGetter().Deck.Spyglass.Lenses[request.LensIndex].Lens.Config, opts.ConfigGetter().Deck.Spyglass)),
-				template.HTML(lens.Body(artifacts, opts.LensResourcesDir, "", opts.ConfigGetter().Deck.Spyglass.Lenses[request.LensIndex].Lens.Config, opts.ConfigGetter().Deck.Spyglass)),
-			})
-
-		case api.RequestActionRerender:
-			w.Header().Set("Content-Type", "text/html; encoding=utf-8")
-			w.Write([]byte(lens.Body(artifacts, opts.LensResourcesDir, request.Data, opts.ConfigGetter().Deck.Spyglass.Lenses[request.LensIndex].Lens.Config, opts.ConfigGetter().Deck.Spyglass)))
-
-		case api.RequestActionCallBack:
-			w.Write([]byte(lens.Callback(artifacts, opts.LensResourcesDir, request.Data, opts.ConfigGetter().Deck.Spyglass.Lenses[request.LensIndex].Lens.Config, opts.ConfigGetter().Deck.Spyglass)))
-
-		default:
-			w.WriteHeader(http.StatusBadRequest)
-			// This is a bit weird as we proxy this and the request we are complaining about was issued by Deck, not by the original client that sees this error
-			w.Write([]byte(fmt.Sprintf("Invalid action %q", request.Action)))
+// fetchPreviousRunArtifacts fetches the artifacts of the run preceding the one of src that match
+// any of the files regexes. It returns no artifacts if there is no previous run.
+func fetchPreviousRunArtifacts(ctx context.Context, opts lensHandlerOpts, src string, files []string) ([]api.Artifact, error) {
+	fetcher, ok := opts.StorageArtifactFetcher.(PreviousRunFetcher)
+	if !ok {
+		return nil, nil
+	}
+	key, err := storageKey(opts.PJFetcher, opts.ConfigGetter, src)
+	if err != nil {
+		return nil, err
+	}
+	previousKey, names, err := fetcher.PreviousRun(ctx, key)
+	if err != nil || previousKey == "" {
+		return nil, err
+	}
+
+	spyglassConfig := opts.ConfigGetter().Deck.Spyglass
+	var arts []api.Artifact
+	for _, name := range names {
+		var matches bool
+		for _, re := range files {
+			if spyglassConfig.RegexCache[re].MatchString(name) {
+				matches = true
+				break
+			}
 		}
+		if !matches {
+			continue
+		}
+		art, err := opts.StorageArtifactFetcher.Artifact(ctx, previousKey, name, spyglassConfig.SizeLimit)
+		if err != nil {
+			logrus.WithError(err).WithField("artifact", name).Debug("Failed to fetch artifact of previous run")
+			continue
+		}
+		arts = append(arts, art)
 	}
+	return arts, nil
 }
 
 func writeHTTPError(w http.ResponseWriter, err error, statusCode int) {
@@ -182,34 +260,31 @@ func FetchArtifacts(
 ) ([]api.Artifact, error) {
 	artStart := time.Now()
 	arts := []api.Artifact{}
-	keyType, key, err := splitSrc(src)
+	gcsKey, err := storageKey(pjFetcher, cfg, src)
 	if err != nil {
-		return arts, fmt.Errorf("error parsing src: %w", err)
-	}
-	gcsKey := ""
-	switch keyType {
-	case api.ProwKeyType:
-		storageProvider, key, err := ProwToGCS(pjFetcher, cfg, key)
-		if err != nil {
-			logrus.Warningln(err)
-		}
-		gcsKey = fmt.Sprintf("%s://%s", storageProvider, strings.TrimSuffix(key, "/"))
-	default:
-		if keyType == api.GCSKeyType {
-			keyType = providers.GS
-		}
-		gcsKey = fmt.Sprintf("%s://%s", keyType, strings.TrimSuffix(key, "/"))
+		return arts, err
 	}
 
 	logsNeeded := []string{}
+	var manifest map[string]gcs.ArtifactManifestEntry
+	if len(artifactNames) > 0 {
+		manifest = artifactManifestEntries(ctx, storageArtifactFetcher, gcsKey, sizeLimit)
+	}
 
 	for _, name := range artifactNames {
 		art, err := storageArtifactFetcher.Artifact(ctx, gcsKey, name, sizeLimit)
+		var size int64
 		if err == nil {
 			// Actually try making a request, because calling StorageArtifactFetcher.artifact does no I/O.
 			// (these files are being explicitly requested and so will presumably soon be accessed, so
 			// the extra network I/O should not be too problematic).
-			_, err = art.Size()
+			size, err = art.Size()
+		}
+		if entry, listed := manifest[name]; err == nil && listed {
+			// The upload of the artifact did not complete, or it was overwritten since.
+			if err = verifyArtifact(art, size, sizeLimit, entry); err != nil {
+				logrus.WithError(err).WithField("artifact", name).Warn("Artifact failed the integrity check")
+			}
 		}
 		if err != nil {
 			if buildLogRegex.MatchString(name) {
@@ -236,6 +311,70 @@ func FetchArtifacts(
 	return arts, nil
 }
 
+// verifyArtifact checks the artifact of the given size against its entry in
+// the artifact manifest. The checksum is only verified for artifacts within
+// the size limit, larger ones cannot be read whole by lenses anyway.
+func verifyArtifact(art api.Artifact, size, sizeLimit int64, entry gcs.ArtifactManifestEntry) error {
+	if size != entry.Size {
+		return fmt.Errorf("artifact has %d bytes but the artifact manifest lists %d", size, entry.Size)
+	}
+	if entry.SHA256 == "" || size > sizeLimit {
+		return nil
+	}
+	content, err := art.ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to read artifact to verify its checksum: %w", err)
+	}
+	sum := sha256.Sum256(content)
+	if checksum := hex.EncodeToString(sum[:]); checksum != entry.SHA256 {
+		return fmt.Errorf("artifact has the SHA256 checksum %s but the artifact manifest lists %s", checksum, entry.SHA256)
+	}
+	return nil
+}
+
+// artifactManifestEntries returns the entries of the artifacts listed in the
+// artifact manifest the sidecar uploaded by their path, or nil if there is
+// none.
+func artifactManifestEntries(ctx context.Context, storageArtifactFetcher ArtifactFetcher, gcsKey string, sizeLimit int64) map[string]gcs.ArtifactManifestEntry {
+	art, err := storageArtifactFetcher.Artifact(ctx, gcsKey, prowv1.ArtifactManifestFile, sizeLimit)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to fetch artifact manifest")
+		return nil
+	}
+	data, err := art.ReadAll()
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to read artifact manifest")
+		return nil
+	}
+	var manifest gcs.ArtifactManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		logrus.WithError(err).Warn("Failed to unmarshal artifact manifest")
+		return nil
+	}
+	return manifest.Entries()
+}
+
+// storageKey returns the storage key of the artifacts of src.
+func storageKey(pjFetcher ProwJobFetcher, cfg config.Getter, src string) (string, error) {
+	keyType, key, err := splitSrc(src)
+	if err != nil {
+		return "", fmt.Errorf("error parsing src: %w", err)
+	}
+	switch keyType {
+	case api.ProwKeyType:
+		storageProvider, key, err := ProwToGCS(pjFetcher, cfg, key)
+		if err != nil {
+			logrus.Warningln(err)
+		}
+		return fmt.Sprintf("%s://%s", storageProvider, strings.TrimSuffix(key, "/")), nil
+	default:
+		if keyType == api.GCSKeyType {
+			keyType = providers.GS
+		}
+		return fmt.Sprintf("%s://%s", keyType, strings.TrimSuffix(key, "/")), nil
+	}
+}
+
 // ProwJobFetcher knows how to get a ProwJob
 type ProwJobFetcher interface {
 	GetProwJob(job string, id string) (prowv1.ProwJob, error)
@@ -17,11 +17,16 @@ limitations under the License.
 package common
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"testing"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/io/providers"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
 )
 
 // fakeProwJobFetcher is used to fetch ProwJobs in tests
@@ -199,3 +204,76 @@ func TestProwToGCS(t *testing.T) {
 		})
 	}
 }
+
+func TestFetchArtifactsVerifiesManifest(t *testing.T) {
+	checksum := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+	testCases := []struct {
+		name      string
+		manifest  *gcs.ArtifactManifest
+		sizeLimit int64
+		expected  []string
+	}{
+		{
+			name:      "artifacts are fetched without a manifest",
+			sizeLimit: 100,
+			expected:  []string{"junit.xml"},
+		},
+		{
+			name: "artifact matching the manifest is fetched",
+			manifest: &gcs.ArtifactManifest{Artifacts: []gcs.ArtifactManifestEntry{
+				{Path: "junit.xml", Size: 5, SHA256: checksum("hello")},
+			}},
+			sizeLimit: 100,
+			expected:  []string{"junit.xml"},
+		},
+		{
+			name: "artifact of another size is dropped",
+			manifest: &gcs.ArtifactManifest{Artifacts: []gcs.ArtifactManifestEntry{
+				{Path: "junit.xml", Size: 4, SHA256: checksum("hello")},
+			}},
+			sizeLimit: 100,
+		},
+		{
+			name: "artifact with another checksum is dropped",
+			manifest: &gcs.ArtifactManifest{Artifacts: []gcs.ArtifactManifestEntry{
+				{Path: "junit.xml", Size: 5, SHA256: checksum("world")},
+			}},
+			sizeLimit: 100,
+		},
+		{
+			name: "checksum of artifact over the size limit is not verified",
+			manifest: &gcs.ArtifactManifest{Artifacts: []gcs.ArtifactManifestEntry{
+				{Path: "junit.xml", Size: 5, SHA256: checksum("world")},
+			}},
+			sizeLimit: 4,
+			expected:  []string{"junit.xml"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fetcher := fakeArtifactFetcher{"gs://bucket/logs/job/1/junit.xml": "hello"}
+			if tc.manifest != nil {
+				manifest, err := json.Marshal(tc.manifest)
+				if err != nil {
+					t.Fatalf("failed to marshal manifest: %v", err)
+				}
+				fetcher["gs://bucket/logs/job/1/"+prowapi.ArtifactManifestFile] = string(manifest)
+			}
+			arts, err := FetchArtifacts(context.Background(), nil, nil, fetcher, fetcher, "gs/bucket/logs/job/1", "", tc.sizeLimit, []string{"junit.xml"})
+			if err != nil {
+				t.Fatalf("failed to fetch artifacts: %v", err)
+			}
+			var names []string
+			for _, art := range arts {
+				names = append(names, art.JobPath())
+			}
+			if len(names) != len(tc.expected) || (len(names) > 0 && names[0] != tc.expected[0]) {
+				t.Errorf("expected artifacts %v, got %v", tc.expected, names)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass/api"
+)
+
+// Remote lenses can be served over gRPC, with a single unary Render method
+// that takes an api.LensRequest and returns an api.LensResponse. Messages are
+// encoded as JSON, so lens backends do not need generated protobuf code: any
+// gRPC implementation that supports custom codecs can serve a lens.
+const (
+	// LensServiceName is the name of the gRPC service serving lenses.
+	LensServiceName = "spyglass.lens.v1.Lens"
+	// LensRenderMethod is the full name of the gRPC method rendering a lens.
+	LensRenderMethod = "/" + LensServiceName + "/Render"
+
+	// GRPCScheme is the scheme of remote lens endpoints served over plaintext gRPC.
+	GRPCScheme = "grpc"
+	// GRPCSScheme is the scheme of remote lens endpoints served over gRPC with TLS.
+	GRPCSScheme = "grpcs"
+)
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec encodes gRPC messages as JSON. It is used with the "json" content
+// subtype, so requests are sent with the application/grpc+json content type.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+// lensService is the server API of the gRPC lens service.
+type lensService interface {
+	Render(context.Context, *api.LensRequest) (*api.LensResponse, error)
+}
+
+var lensServiceDesc = grpc.ServiceDesc{
+	ServiceName: LensServiceName,
+	HandlerType: (*lensService)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Render",
+			Handler:    renderHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+func renderHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	request := &api.LensRequest{}
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(lensService).Render(ctx, request)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: LensRenderMethod}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(lensService).Render(ctx, req.(*api.LensRequest))
+	}
+	return interceptor(ctx, request, info, handler)
+}
+
+// grpcLensServer serves lenses over gRPC, picking the lens by the name set in
+// the request.
+type grpcLensServer struct {
+	lenses map[string]api.Lens
+	opts   map[string]lensHandlerOpts
+}
+
+func (s *grpcLensServer) Render(ctx context.Context, request *api.LensRequest) (*api.LensResponse, error) {
+	lens, ok := s.lenses[request.LensName]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no lens named %q", request.LensName)
+	}
+	response, statusCode, err := renderLens(ctx, lens, s.opts[request.LensName], request)
+	if err != nil {
+		logrus.WithError(err).WithField("lens", request.LensName).Debug("Failed to process request")
+		return nil, status.Error(grpcCode(statusCode), err.Error())
+	}
+	return response, nil
+}
+
+// NewGRPCLensServer returns a gRPC server serving the lenses. The caller is
+// responsible for serving it on a listener.
+func NewGRPCLensServer(
+	pjFetcher ProwJobFetcher,
+	storageArtifactFetcher ArtifactFetcher,
+	podLogArtifactFetcher ArtifactFetcher,
+	cfg config.Getter,
+	lenses []LensWithConfiguration,
+	serverOpts ...grpc.ServerOption,
+) (*grpc.Server, error) {
+	service := &grpcLensServer{lenses: map[string]api.Lens{}, opts: map[string]lensHandlerOpts{}}
+	for _, lens := range lenses {
+		if _, seen := service.lenses[lens.Config.LensName]; seen {
+			return nil, fmt.Errorf("duplicate lens named %q", lens.Config.LensName)
+		}
+
+		logrus.WithField("Lens", lens.Config.LensName).Info("Adding gRPC handler for lens")
+		service.lenses[lens.Config.LensName] = lens.Lens
+		service.opts[lens.Config.LensName] = lensHandlerOpts{
+			PJFetcher:              pjFetcher,
+			StorageArtifactFetcher: storageArtifactFetcher,
+			PodLogArtifactFetcher:  podLogArtifactFetcher,
+			ConfigGetter:           cfg,
+			LensOpt:                lens.Config,
+		}
+	}
+
+	server := grpc.NewServer(serverOpts...)
+	server.RegisterService(&lensServiceDesc, service)
+	return server, nil
+}
+
+func grpcCode(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	default:
+		return codes.Internal
+	}
+}
+
+// HTTPStatusForGRPCError returns the HTTP status code to answer a request
+// with when calling a lens over gRPC failed.
+func HTTPStatusForGRPCError(err error) int {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// IsGRPCEndpoint returns whether a remote lens endpoint is served over gRPC.
+func IsGRPCEndpoint(endpoint *url.URL) bool {
+	return endpoint != nil && (endpoint.Scheme == GRPCScheme || endpoint.Scheme == GRPCSScheme)
+}
+
+// GRPCLensClient calls remote lenses served over gRPC. It keeps a connection
+// open to each endpoint it called.
+type GRPCLensClient struct {
+	lock  sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// NewGRPCLensClient returns a client for remote lenses served over gRPC.
+func NewGRPCLensClient() *GRPCLensClient {
+	return &GRPCLensClient{conns: map[string]*grpc.ClientConn{}}
+}
+
+// Render calls the lens served at the endpoint.
+func (c *GRPCLensClient) Render(ctx context.Context, endpoint *url.URL, request *api.LensRequest) (*api.LensResponse, error) {
+	conn, err := c.conn(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	response := &api.LensResponse{}
+	if err := conn.Invoke(ctx, LensRenderMethod, request, response, grpc.CallContentSubtype(jsonCodec{}.Name())); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *GRPCLensClient) conn(endpoint *url.URL) (*grpc.ClientConn, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := endpoint.Scheme + "://" + endpoint.Host
+	if conn, ok := c.conns[key]; ok {
+		return conn, nil
+	}
+
+	var opts []grpc.DialOption
+	switch endpoint.Scheme {
+	case GRPCScheme:
+		opts = append(opts, grpc.WithInsecure())
+	case GRPCSScheme:
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})))
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q for gRPC lens endpoint %q", endpoint.Scheme, endpoint)
+	}
+	conn, err := grpc.Dial(endpoint.Host, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial lens endpoint %q: %w", endpoint, err)
+	}
+	c.conns[key] = conn
+	return conn, nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass/api"
+)
+
+type fakeArtifact struct {
+	api.Artifact
+	path    string
+	content string
+}
+
+func (a *fakeArtifact) JobPath() string {
+	return a.path
+}
+
+func (a *fakeArtifact) Size() (int64, error) {
+	return int64(len(a.content)), nil
+}
+
+func (a *fakeArtifact) ReadAll() ([]byte, error) {
+	return []byte(a.content), nil
+}
+
+type fakeArtifactFetcher map[string]string
+
+func (f fakeArtifactFetcher) Artifact(_ context.Context, key string, artifactName string, _ int64) (api.Artifact, error) {
+	content, ok := f[key+"/"+artifactName]
+	if !ok {
+		return nil, errors.New("no such artifact")
+	}
+	return &fakeArtifact{path: artifactName, content: content}, nil
+}
+
+type fakeLens struct{}
+
+func (fakeLens) Header(artifacts []api.Artifact, resourceDir string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	return ""
+}
+
+func (fakeLens) Body(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	content, _ := artifacts[0].ReadAll()
+	return fmt.Sprintf("body of %s: %s (%s)", artifacts[0].JobPath(), content, data)
+}
+
+func (fakeLens) Callback(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	return "callback: " + data
+}
+
+func TestGRPCLens(t *testing.T) {
+	cfg := &config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{Spyglass: config.Spyglass{
+		Lenses: []config.LensFileConfig{{Lens: config.LensConfig{Name: "fake"}}},
+	}}}}
+	fetcher := fakeArtifactFetcher{"gs://bucket/logs/job/1/build-log.txt": "hello"}
+	server, err := NewGRPCLensServer(nil, fetcher, fetcher, func() *config.Config { return cfg }, []LensWithConfiguration{{
+		Config: LensOpt{LensName: "fake", LensTitle: "Fake"},
+		Lens:   fakeLens{},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create gRPC lens server: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.Serve(listener)
+	defer server.Stop()
+
+	endpoint := &url.URL{Scheme: GRPCScheme, Host: listener.Addr().String()}
+	if !IsGRPCEndpoint(endpoint) {
+		t.Fatalf("expected %s to be a gRPC endpoint", endpoint)
+	}
+	client := NewGRPCLensClient()
+
+	testCases := []struct {
+		name             string
+		request          api.LensRequest
+		expectedResponse api.LensResponse
+		expectedCode     codes.Code
+	}{
+		{
+			name: "rerender",
+			request: api.LensRequest{
+				Action:         api.RequestActionRerender,
+				Data:           "data",
+				Artifacts:      []string{"build-log.txt"},
+				ArtifactSource: "gs/bucket/logs/job/1",
+				LensName:       "fake",
+			},
+			expectedResponse: api.LensResponse{ContentType: "text/html; encoding=utf-8", Body: "body of build-log.txt: hello (data)"},
+		},
+		{
+			name: "callback",
+			request: api.LensRequest{
+				Action:         api.RequestActionCallBack,
+				Data:           "data",
+				Artifacts:      []string{"build-log.txt"},
+				ArtifactSource: "gs/bucket/logs/job/1",
+				LensName:       "fake",
+			},
+			expectedResponse: api.LensResponse{Body: "callback: data"},
+		},
+		{
+			name: "invalid action",
+			request: api.LensRequest{
+				Action:         "invalid",
+				Artifacts:      []string{"build-log.txt"},
+				ArtifactSource: "gs/bucket/logs/job/1",
+				LensName:       "fake",
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "no artifacts",
+			request: api.LensRequest{
+				Action:         api.RequestActionRerender,
+				Artifacts:      []string{"missing.txt"},
+				ArtifactSource: "gs/bucket/logs/job/1",
+				LensName:       "fake",
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name: "unknown lens",
+			request: api.LensRequest{
+				Action:         api.RequestActionRerender,
+				Artifacts:      []string{"build-log.txt"},
+				ArtifactSource: "gs/bucket/logs/job/1",
+				LensName:       "unknown",
+			},
+			expectedCode: codes.NotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := client.Render(context.Background(), endpoint, &tc.request)
+			if code := status.Code(err); code != tc.expectedCode {
+				t.Fatalf("expected code %s, got %s (error: %v)", tc.expectedCode, code, err)
+			}
+			if err != nil {
+				return
+			}
+			if *response != tc.expectedResponse {
+				t.Errorf("expected response %+v, got %+v", tc.expectedResponse, *response)
+			}
+		})
+	}
+}
@@ -137,6 +137,12 @@ func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string,
 	return buf.String()
 }
 
+// Results returns the results of the tests in the junit artifacts, grouped by
+// status.
+func Results(artifacts []api.Artifact) JVD {
+	return Lens{}.getJvd(artifacts)
+}
+
 func (lens Lens) getJvd(artifacts []api.Artifact) JVD {
 	type testResults struct {
 		// Group results based on their full path name
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package junitdiff provides a Spyglass lens comparing the junit results of a
+// job run with those of the previous run of the job.
+package junitdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass/api"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/junit"
+)
+
+const (
+	name     = "junitdiff"
+	title    = "JUnit Diff"
+	priority = 6
+)
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Lens is the implementation of a Spyglass lens rendering the difference of
+// the junit results of a job run and of the previous run.
+type Lens struct{}
+
+// Diff holds the tests whose results changed since the previous run and the
+// tests that failed in both runs.
+type Diff struct {
+	HasPreviousRun bool
+	NewlyFailing   []junit.TestResult
+	NewlyPassing   []junit.TestResult
+	StillFailing   []junit.TestResult
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []api.Artifact, resourceDir string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING HEADER: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "header", nil); err != nil {
+		return fmt.Sprintf("<!-- FAILED EXECUTING HEADER TEMPLATE: %v -->", err)
+	}
+	return buf.String()
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	return ""
+}
+
+// Body renders the <body> as if there was no previous run, it is only called
+// by lens servers that can't fetch previous runs.
+func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	return lens.PreviousRunBody(artifacts, nil, resourceDir, data, config, spyglassConfig)
+}
+
+// PreviousRunBody renders the <body> with the difference of the junit results
+// of the artifacts and of the previous artifacts.
+func (lens Lens) PreviousRunBody(artifacts, previousArtifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	diff := getDiff(artifacts, previousArtifacts)
+
+	diffTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+		return fmt.Sprintf("Failed to load template file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := diffTemplate.ExecuteTemplate(&buf, "body", diff); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+
+	return buf.String()
+}
+
+func testKey(test junit.TestResult) string {
+	first := test.Junit[0]
+	return first.ClassName + ": " + first.Name
+}
+
+// getDiff compares the results of the tests of both runs. Flaky tests count as
+// passed, tests that didn't run in the previous run as passed before.
+func getDiff(artifacts, previousArtifacts []api.Artifact) Diff {
+	diff := Diff{HasPreviousRun: len(previousArtifacts) > 0}
+	current := junit.Results(artifacts)
+	previous := junit.Results(previousArtifacts)
+
+	previousFailed := map[string]bool{}
+	for _, test := range previous.Failed {
+		previousFailed[testKey(test)] = true
+	}
+	currentFailed := map[string]bool{}
+	for _, test := range current.Failed {
+		key := testKey(test)
+		currentFailed[key] = true
+		if previousFailed[key] {
+			diff.StillFailing = append(diff.StillFailing, test)
+		} else {
+			diff.NewlyFailing = append(diff.NewlyFailing, test)
+		}
+	}
+	for _, test := range append(current.Passed, current.Flaky...) {
+		// The same test may be in several junit files.
+		key := testKey(test)
+		if previousFailed[key] && !currentFailed[key] {
+			diff.NewlyPassing = append(diff.NewlyPassing, test)
+		}
+	}
+	return diff
+}
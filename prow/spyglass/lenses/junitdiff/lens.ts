@@ -0,0 +1,42 @@
+function addSectionExpanders(): void {
+  const expanders = document.querySelectorAll<HTMLTableRowElement>('tr.section-expander');
+  for (const expander of Array.from(expanders)) {
+    expander.onclick = () => {
+      const tbody = expander.parentElement!.nextElementSibling!;
+      const icon = expander.querySelector('i')!;
+      if (tbody.classList.contains('hidden-tests')) {
+        tbody.classList.remove('hidden-tests');
+        icon.innerText = 'expand_less';
+      } else {
+        tbody.classList.add('hidden-tests');
+        icon.innerText = 'expand_more';
+      }
+      spyglass.contentUpdated();
+    };
+  }
+}
+
+function addTestExpanders(): void {
+  const rows = document.querySelectorAll<HTMLTableRowElement>('.failure-name');
+  for (const row of Array.from(rows)) {
+    row.onclick = () => {
+      const sibling = row.nextElementSibling!;
+      const icon = row.querySelector('i')!;
+      if (sibling.classList.contains('hidden')) {
+        sibling.classList.remove('hidden');
+        icon.innerText = 'expand_less';
+      } else {
+        sibling.classList.add('hidden');
+        icon.innerText = 'expand_more';
+      }
+      spyglass.contentUpdated();
+    };
+  }
+}
+
+function loaded(): void {
+  addTestExpanders();
+  addSectionExpanders();
+}
+
+window.addEventListener('DOMContentLoaded', loaded);
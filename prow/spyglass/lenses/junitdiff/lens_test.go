@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junitdiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/spyglass/api"
+	"k8s.io/test-infra/prow/spyglass/lenses/fake"
+	"k8s.io/test-infra/prow/spyglass/lenses/junit"
+)
+
+// junitArtifact returns a junit artifact with a passing test for each name
+// prefixed with "+" and a failing test for each name prefixed with "-".
+func junitArtifact(tests ...string) api.Artifact {
+	var cases []string
+	for _, test := range tests {
+		if strings.HasPrefix(test, "-") {
+			cases = append(cases, fmt.Sprintf(`<testcase classname="suite" name="%s"><failure>failed</failure></testcase>`, test[1:]))
+		} else {
+			cases = append(cases, fmt.Sprintf(`<testcase classname="suite" name="%s"></testcase>`, test[1:]))
+		}
+	}
+	return &fake.Artifact{
+		Path:    "artifacts/junit.xml",
+		Content: []byte("<testsuites><testsuite>" + strings.Join(cases, "") + "</testsuite></testsuites>"),
+	}
+}
+
+func testNames(results []junit.TestResult) []string {
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Junit[0].Name)
+	}
+	return names
+}
+
+func TestGetDiff(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		artifacts            []api.Artifact
+		previousArtifacts    []api.Artifact
+		expectedPreviousRun  bool
+		expectedNewlyFailing []string
+		expectedNewlyPassing []string
+		expectedStillFailing []string
+	}{
+		{
+			name:                 "no previous run",
+			artifacts:            []api.Artifact{junitArtifact("+a", "-b")},
+			expectedNewlyFailing: []string{"b"},
+		},
+		{
+			name:                 "results changed",
+			artifacts:            []api.Artifact{junitArtifact("+a", "-b", "-c", "+d")},
+			previousArtifacts:    []api.Artifact{junitArtifact("-a", "+b", "-c", "+d")},
+			expectedPreviousRun:  true,
+			expectedNewlyFailing: []string{"b"},
+			expectedNewlyPassing: []string{"a"},
+			expectedStillFailing: []string{"c"},
+		},
+		{
+			name:                 "new failing test",
+			artifacts:            []api.Artifact{junitArtifact("+a", "-b")},
+			previousArtifacts:    []api.Artifact{junitArtifact("+a")},
+			expectedPreviousRun:  true,
+			expectedNewlyFailing: []string{"b"},
+		},
+		{
+			name:                 "results in several files",
+			artifacts:            []api.Artifact{junitArtifact("+a"), junitArtifact("-b")},
+			previousArtifacts:    []api.Artifact{junitArtifact("-a", "+b")},
+			expectedPreviousRun:  true,
+			expectedNewlyFailing: []string{"b"},
+			expectedNewlyPassing: []string{"a"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := getDiff(tc.artifacts, tc.previousArtifacts)
+			if diff.HasPreviousRun != tc.expectedPreviousRun {
+				t.Errorf("expected previous run %t, got %t", tc.expectedPreviousRun, diff.HasPreviousRun)
+			}
+			if d := cmp.Diff(tc.expectedNewlyFailing, testNames(diff.NewlyFailing)); d != "" {
+				t.Errorf("newly failing tests differ from expected: %s", d)
+			}
+			if d := cmp.Diff(tc.expectedNewlyPassing, testNames(diff.NewlyPassing)); d != "" {
+				t.Errorf("newly passing tests differ from expected: %s", d)
+			}
+			if d := cmp.Diff(tc.expectedStillFailing, testNames(diff.StillFailing)); d != "" {
+				t.Errorf("still failing tests differ from expected: %s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package search provides a Spyglass lens searching the artifacts of a job run
+// for a regex.
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass/api"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "search"
+	title    = "Search"
+	priority = 25
+
+	// pageSize is the number of matches returned per page.
+	pageSize = 50
+	// maxMatches is the number of matches after which the search stops.
+	maxMatches = 5000
+	// maxLineLength is the length matching lines are truncated to.
+	maxLineLength = 1000
+	buildLogName  = "build-log.txt"
+)
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Lens is the implementation of a Spyglass lens searching the artifacts.
+type Lens struct{}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []api.Artifact, resourceDir string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	return renderTemplate(resourceDir, "header", nil)
+}
+
+// Body renders the search form, the searching happens in callbacks.
+func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	return renderTemplate(resourceDir, "body", len(artifacts))
+}
+
+func renderTemplate(resourceDir, block string, params interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("Failed to load template file: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, block, params); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
+
+type searchRequest struct {
+	Regex string `json:"regex"`
+	Page  int    `json:"page"`
+}
+
+// SubLine is a part of a matching line, the parts matching the regex are
+// highlighted.
+type SubLine struct {
+	Highlighted bool   `json:"highlighted"`
+	Text        string `json:"text"`
+}
+
+type match struct {
+	Artifact string    `json:"artifact"`
+	Link     string    `json:"link"`
+	Line     int       `json:"line"`
+	SubLines []SubLine `json:"subLines"`
+}
+
+type searchResponse struct {
+	Error   string  `json:"error,omitempty"`
+	Matches []match `json:"matches"`
+	Total   int     `json:"total"`
+	// Truncated is set when the search stopped after maxMatches matches.
+	Truncated bool `json:"truncated"`
+	Page      int  `json:"page"`
+	Pages     int  `json:"pages"`
+	// Skipped are the artifacts that couldn't be searched, e.g. because they
+	// exceed the size limit.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// Callback searches the artifacts for the regex of the request and returns the
+// requested page of the matches. The artifacts are searched again for every
+// page, the lens keeps no state.
+func (lens Lens) Callback(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	var request searchRequest
+	var response searchResponse
+	if err := json.Unmarshal([]byte(data), &request); err != nil {
+		response.Error = fmt.Sprintf("invalid request: %v", err)
+	} else if request.Regex == "" {
+		response.Error = "no regex to search for"
+	} else if re, err := regexp.Compile(request.Regex); err != nil {
+		response.Error = fmt.Sprintf("invalid regex: %v", err)
+	} else {
+		response = search(artifacts, re, request.Page)
+	}
+	b, err := json.Marshal(response)
+	if err != nil {
+		logrus.WithError(err).Error("Error marshaling search results.")
+		return `{"error": "failed to marshal search results"}`
+	}
+	return string(b)
+}
+
+// search searches the artifacts line by line, the build log first and the
+// other artifacts by name.
+func search(artifacts []api.Artifact, re *regexp.Regexp, page int) searchResponse {
+	sorted := make([]api.Artifact, len(artifacts))
+	copy(sorted, artifacts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].JobPath(), sorted[j].JobPath()
+		if (a == buildLogName) != (b == buildLogName) {
+			return a == buildLogName
+		}
+		return a < b
+	})
+
+	response := searchResponse{Matches: []match{}}
+	var matches []match
+	for _, artifact := range sorted {
+		if len(matches) >= maxMatches {
+			response.Truncated = true
+			break
+		}
+		contents, err := artifact.ReadAll()
+		if err != nil {
+			logrus.WithError(err).WithField("artifact", artifact.JobPath()).Debug("Failed to read artifact for search.")
+			response.Skipped = append(response.Skipped, artifact.JobPath())
+			continue
+		}
+		for i, line := range strings.Split(string(contents), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			if len(matches) >= maxMatches {
+				response.Truncated = true
+				break
+			}
+			matches = append(matches, match{
+				Artifact: artifact.JobPath(),
+				Link:     artifact.CanonicalLink(),
+				Line:     i + 1,
+				SubLines: highlight(line, re),
+			})
+		}
+	}
+
+	response.Total = len(matches)
+	response.Pages = (len(matches) + pageSize - 1) / pageSize
+	if page < 0 {
+		page = 0
+	}
+	response.Page = page
+	if start := page * pageSize; start < len(matches) {
+		end := start + pageSize
+		if end > len(matches) {
+			end = len(matches)
+		}
+		response.Matches = matches[start:end]
+	}
+	return response
+}
+
+// highlight splits the line into the parts matching the regex and the others,
+// after truncating it to maxLineLength.
+func highlight(line string, re *regexp.Regexp) []SubLine {
+	if len(line) > maxLineLength {
+		line = line[:maxLineLength] + "..."
+	}
+	var subLines []SubLine
+	last := 0
+	for _, loc := range re.FindAllStringIndex(line, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		if loc[0] > last {
+			subLines = append(subLines, SubLine{Text: line[last:loc[0]]})
+		}
+		subLines = append(subLines, SubLine{Highlighted: true, Text: line[loc[0]:loc[1]]})
+		last = loc[1]
+	}
+	if last < len(line) {
+		subLines = append(subLines, SubLine{Text: line[last:]})
+	}
+	return subLines
+}
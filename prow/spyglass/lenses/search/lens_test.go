@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass/api"
+	"k8s.io/test-infra/prow/spyglass/lenses/fake"
+)
+
+func TestHighlight(t *testing.T) {
+	testCases := []struct {
+		name     string
+		line     string
+		regex    string
+		expected []SubLine
+	}{
+		{
+			name:  "match in the middle",
+			line:  "some error here",
+			regex: "error",
+			expected: []SubLine{
+				{Text: "some "},
+				{Highlighted: true, Text: "error"},
+				{Text: " here"},
+			},
+		},
+		{
+			name:  "several matches",
+			line:  "error: another error",
+			regex: "error",
+			expected: []SubLine{
+				{Highlighted: true, Text: "error"},
+				{Text: ": another "},
+				{Highlighted: true, Text: "error"},
+			},
+		},
+		{
+			name:     "empty matches are ignored",
+			line:     "abc",
+			regex:    "x*",
+			expected: []SubLine{{Text: "abc"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, highlight(tc.line, regexp.MustCompile(tc.regex))); diff != "" {
+				t.Errorf("sublines differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestCallback(t *testing.T) {
+	var manyErrors []string
+	for i := 0; i < pageSize+1; i++ {
+		manyErrors = append(manyErrors, fmt.Sprintf("error %d", i))
+	}
+	artifacts := []api.Artifact{
+		&fake.Artifact{Path: "artifacts/junit.xml", Content: []byte("<failure>error in test</failure>")},
+		&fake.Artifact{Path: "build-log.txt", Content: []byte("starting\nerror: build failed\ndone")},
+		&fake.Artifact{Path: "artifacts/many.log", Content: []byte(strings.Join(manyErrors, "\n"))},
+	}
+
+	testCases := []struct {
+		name          string
+		data          string
+		expectedError bool
+		expectedTotal int
+		expectedPages int
+		expectedFirst string
+		expectedLine  int
+		expectedCount int
+	}{
+		{
+			name:          "first page starts with the build log",
+			data:          `{"regex": "error", "page": 0}`,
+			expectedTotal: pageSize + 3,
+			expectedPages: 2,
+			expectedFirst: "build-log.txt",
+			expectedLine:  2,
+			expectedCount: pageSize,
+		},
+		{
+			name:          "last page",
+			data:          `{"regex": "error", "page": 1}`,
+			expectedTotal: pageSize + 3,
+			expectedPages: 2,
+			expectedFirst: "artifacts/many.log",
+			expectedLine:  pageSize - 1,
+			expectedCount: 3,
+		},
+		{
+			name:          "no match",
+			data:          `{"regex": "panic"}`,
+			expectedCount: 0,
+		},
+		{
+			name:          "invalid regex",
+			data:          `{"regex": "("}`,
+			expectedError: true,
+		},
+		{
+			name:          "empty regex",
+			data:          `{"regex": ""}`,
+			expectedError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var response searchResponse
+			if err := json.Unmarshal([]byte(Lens{}.Callback(artifacts, "", tc.data, nil, config.Spyglass{})), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if tc.expectedError != (response.Error != "") {
+				t.Fatalf("expected error: %t, got: %q", tc.expectedError, response.Error)
+			}
+			if response.Total != tc.expectedTotal || response.Pages != tc.expectedPages {
+				t.Errorf("expected %d matches in %d pages, got %d in %d", tc.expectedTotal, tc.expectedPages, response.Total, response.Pages)
+			}
+			if len(response.Matches) != tc.expectedCount {
+				t.Fatalf("expected %d matches on the page, got %d", tc.expectedCount, len(response.Matches))
+			}
+			if tc.expectedCount > 0 {
+				first := response.Matches[0]
+				if first.Artifact != tc.expectedFirst || first.Line != tc.expectedLine {
+					t.Errorf("expected first match at %s:%d, got %s:%d", tc.expectedFirst, tc.expectedLine, first.Artifact, first.Line)
+				}
+			}
+		})
+	}
+}
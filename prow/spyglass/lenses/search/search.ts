@@ -0,0 +1,111 @@
+interface SubLine {
+  highlighted: boolean;
+  text: string;
+}
+
+interface Match {
+  artifact: string;
+  link: string;
+  line: number;
+  subLines: SubLine[];
+}
+
+interface SearchResponse {
+  error?: string;
+  matches: Match[];
+  total: number;
+  truncated: boolean;
+  page: number;
+  pages: number;
+  skipped?: string[];
+}
+
+let currentRegex = '';
+
+function statusText(response: SearchResponse): string {
+  if (response.total === 0) {
+    return 'No matches.';
+  }
+  let text = `${response.total}${response.truncated ? '+' : ''} matches.`;
+  if (response.skipped && response.skipped.length > 0) {
+    text += ` Skipped ${response.skipped.length} artifacts that couldn't be read: ${response.skipped.join(', ')}.`;
+  }
+  return text;
+}
+
+function renderMatch(match: Match): HTMLTableRowElement {
+  const row = document.createElement('tr');
+  const location = document.createElement('td');
+  location.className = 'mdl-data-table__cell--non-numeric location';
+  const link = document.createElement('a');
+  link.href = match.link;
+  link.target = '_blank';
+  link.textContent = `${match.artifact}:${match.line}`;
+  location.appendChild(link);
+  row.appendChild(location);
+
+  // The text is only ever set as textContent, never as HTML.
+  const text = document.createElement('td');
+  text.className = 'mdl-data-table__cell--non-numeric text';
+  for (const subLine of match.subLines) {
+    const span = document.createElement('span');
+    if (subLine.highlighted) {
+      span.className = 'highlighted';
+    }
+    span.textContent = subLine.text;
+    text.appendChild(span);
+  }
+  row.appendChild(text);
+  return row;
+}
+
+async function search(page: number): Promise<void> {
+  const status = document.getElementById('search-status')!;
+  const results = document.getElementById('search-results')!;
+  const pagination = document.getElementById('search-pagination')!;
+  status.textContent = 'Searching...';
+  spyglass.contentUpdated();
+
+  let response: SearchResponse;
+  try {
+    response = JSON.parse(await spyglass.request(JSON.stringify({regex: currentRegex, page})));
+  } catch (e) {
+    status.textContent = `Search failed: ${e}`;
+    spyglass.contentUpdated();
+    return;
+  }
+  if (response.error) {
+    status.textContent = response.error;
+    results.classList.add('hidden');
+    pagination.classList.add('hidden');
+    spyglass.contentUpdated();
+    return;
+  }
+
+  status.textContent = statusText(response);
+  const tbody = results.querySelector('tbody')!;
+  tbody.innerHTML = '';
+  for (const match of response.matches) {
+    tbody.appendChild(renderMatch(match));
+  }
+  results.classList.toggle('hidden', response.matches.length === 0);
+
+  pagination.classList.toggle('hidden', response.pages <= 1);
+  document.getElementById('search-page')!.textContent = `Page ${response.page + 1} of ${response.pages}`;
+  const previous = document.getElementById('search-previous') as HTMLButtonElement;
+  previous.disabled = response.page === 0;
+  previous.onclick = () => search(response.page - 1);
+  const next = document.getElementById('search-next') as HTMLButtonElement;
+  next.disabled = response.page >= response.pages - 1;
+  next.onclick = () => search(response.page + 1);
+  spyglass.contentUpdated();
+}
+
+window.addEventListener('DOMContentLoaded', () => {
+  const form = document.getElementById('search-form') as HTMLFormElement;
+  form.onsubmit = (e) => {
+    e.preventDefault();
+    currentRegex = (document.getElementById('search-regex') as HTMLInputElement).value;
+    search(0);
+  };
+});
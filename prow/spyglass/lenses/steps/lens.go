@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package steps provides a viewer for the steps entrypoint ran for Spyglass
+package steps
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/entrypoint"
+	"k8s.io/test-infra/prow/spyglass/api"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "steps"
+	title    = "Steps"
+	priority = 5
+)
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Lens is the implementation of a steps-rendering Spyglass lens.
+type Lens struct{}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []api.Artifact, resourceDir string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING HEADER: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "header", nil); err != nil {
+		return fmt.Sprintf("<!-- FAILED EXECUTING HEADER TEMPLATE: %v -->", err)
+	}
+	return buf.String()
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	return ""
+}
+
+// Body renders the steps the job ran.
+func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	if len(artifacts) == 0 {
+		logrus.Error("steps Body() called with no artifacts, which should never happen.")
+		return "Why am I here? There is no steps file."
+	}
+
+	content, err := artifacts[0].ReadAll()
+	if err != nil {
+		logrus.WithError(err).Warn("Couldn't read a steps file that should exist.")
+		return fmt.Sprintf("Failed to read the steps file: %v", err)
+	}
+	steps, err := stepViews(content)
+	if err != nil {
+		logrus.WithError(err).Info("Error unmarshalling steps")
+		return fmt.Sprintf("Couldn't unmarshal %s: %v", entrypoint.StepsFile, err)
+	}
+
+	stepsTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		logrus.WithError(err).Error("Error loading template.")
+		return fmt.Sprintf("Failed to load template file: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := stepsTemplate.ExecuteTemplate(&buf, "body", struct{ Steps []stepView }{Steps: steps}); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
+
+// stepView is a step as the template renders it.
+type stepView struct {
+	entrypoint.StepResult
+	// Status is one of passed, failed, allowed-failure or skipped.
+	Status string
+	// Result describes the status.
+	Result   string
+	Duration time.Duration
+}
+
+// stepViews parses steps.json.
+func stepViews(content []byte) ([]stepView, error) {
+	var steps entrypoint.Steps
+	if err := json.Unmarshal(content, &steps); err != nil {
+		return nil, err
+	}
+	views := make([]stepView, 0, len(steps.Steps))
+	for _, step := range steps.Steps {
+		view := stepView{
+			StepResult: step,
+			Duration:   (time.Duration(step.DurationSeconds * float64(time.Second))).Round(time.Second),
+		}
+		switch {
+		case step.Skipped:
+			view.Status, view.Result = "skipped", "Skipped"
+		case step.ExitCode == 0:
+			view.Status, view.Result = "passed", "Passed"
+		case step.AllowFailure:
+			view.Status, view.Result = "allowed-failure", "Failed (allowed)"
+		default:
+			view.Status, view.Result = "failed", "Failed"
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
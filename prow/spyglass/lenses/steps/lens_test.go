@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package steps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/entrypoint"
+)
+
+func TestStepViews(t *testing.T) {
+	content := []byte(`{"steps":[
+		{"name":"build","exit_code":0,"duration_seconds":61.4},
+		{"name":"lint","parallel_group":"check","allow_failure":true,"exit_code":1,"error":"wrapped process failed: exit status 1","duration_seconds":3},
+		{"name":"test","parallel_group":"check","exit_code":2,"error":"wrapped process failed: exit status 2","duration_seconds":10},
+		{"name":"publish","skipped":true,"exit_code":0}
+	]}`)
+	expected := []stepView{
+		{
+			StepResult: entrypoint.StepResult{Name: "build", DurationSeconds: 61.4},
+			Status:     "passed",
+			Result:     "Passed",
+			Duration:   61 * time.Second,
+		},
+		{
+			StepResult: entrypoint.StepResult{Name: "lint", ParallelGroup: "check", AllowFailure: true, ExitCode: 1, Error: "wrapped process failed: exit status 1", DurationSeconds: 3},
+			Status:     "allowed-failure",
+			Result:     "Failed (allowed)",
+			Duration:   3 * time.Second,
+		},
+		{
+			StepResult: entrypoint.StepResult{Name: "test", ParallelGroup: "check", ExitCode: 2, Error: "wrapped process failed: exit status 2", DurationSeconds: 10},
+			Status:     "failed",
+			Result:     "Failed",
+			Duration:   10 * time.Second,
+		},
+		{
+			StepResult: entrypoint.StepResult{Name: "publish", Skipped: true},
+			Status:     "skipped",
+			Result:     "Skipped",
+		},
+	}
+
+	actual, err := stepViews(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("steps differ from expected: %s", diff)
+	}
+}
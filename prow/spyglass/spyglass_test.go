@@ -146,6 +146,31 @@ test/e2e/e2e.go:137 BeforeSuite on Node 1 failed test/e2e/e2e.go:137
 						  },
 						},`),
 		},
+		{
+			BucketName: "test-bucket",
+			Name:       "logs/example-ci-run/401/junit_01.xml",
+			Content:    []byte(`<testsuite tests="1" failures="0"><testcase name="BeforeSuite" classname="Kubernetes e2e suite"/></testsuite>`),
+		},
+		{
+			BucketName: "test-bucket",
+			Name:       "logs/example-ci-run/400/build-log.txt",
+			Content:    []byte("an older log"),
+		},
+		{
+			BucketName: "test-bucket",
+			Name:       "logs/example-ci-run/405/artifacts-manifest.json",
+			Content:    []byte(`{"artifacts":[{"path":"started.json","size":2},{"path":"junit_01.xml","size":100}]}`),
+		},
+		{
+			BucketName: "test-bucket",
+			Name:       "logs/example-ci-run/405/started.json",
+			Content:    []byte(`{}`),
+		},
+		{
+			BucketName: "test-bucket",
+			Name:       "logs/example-ci-run/405/junit_01.xml",
+			Content:    []byte(`<testsuite/>`),
+		},
 		{
 			BucketName: "test-bucket",
 			Name:       "logs/symlink-party/123.txt",
@@ -1348,6 +1373,23 @@ func TestFetchArtifactsPodLog(t *testing.T) {
 	}
 }
 
+func TestFetchArtifactsIntegrity(t *testing.T) {
+	cfg := createConfigGetter("test-bucket")
+	sg := New(context.Background(), fakeJa, cfg, io.NewGCSOpener(fakeGCSServer.Client()), false)
+
+	result, err := sg.FetchArtifacts(context.Background(), "gs/test-bucket/logs/example-ci-run/405", "", 500e6, []string{"started.json", "junit_01.xml"})
+	if err != nil {
+		t.Fatalf("Unexpected error fetching artifacts: %v", err)
+	}
+	var names []string
+	for _, art := range result {
+		names = append(names, art.JobPath())
+	}
+	if expected := []string{"started.json"}; !reflect.DeepEqual(expected, names) {
+		t.Errorf("Expected artifacts %v, got %v: artifacts that do not match the artifact manifest must be skipped", expected, names)
+	}
+}
+
 func TestKeyToJob(t *testing.T) {
 	testCases := []struct {
 		name      string
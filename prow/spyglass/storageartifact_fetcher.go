@@ -24,6 +24,7 @@ import (
 	"math/rand"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -167,6 +168,56 @@ func (af *StorageArtifactFetcher) artifacts(ctx context.Context, key string) ([]
 	return artifacts, nil
 }
 
+// PreviousRun returns the key and the artifacts of the run that precedes the run with the given
+// key, that is the run with the next lower build ID in the same directory. For presubmits this is
+// the previous run of the job for the same PR. It returns an empty key if there is no previous run.
+func (af *StorageArtifactFetcher) PreviousRun(ctx context.Context, key string) (string, []string, error) {
+	src, err := af.newStorageJobSource(key)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get GCS job source from %s: %w", key, err)
+	}
+	buildID, err := strconv.ParseUint(src.buildID, 10, 64)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid build ID %q: %w", src.buildID, err)
+	}
+
+	runsPrefix := path.Dir(strings.TrimSuffix(src.jobPrefix, "/")) + "/"
+	it, err := af.opener.Iterator(ctx, fmt.Sprintf("%s%s/%s", src.linkPrefix, src.bucket, runsPrefix), "/")
+	if err != nil {
+		return "", nil, err
+	}
+	var previousID uint64
+	var previousRun string
+	for {
+		attrs, err := it.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		if !attrs.IsDir {
+			continue
+		}
+		run := path.Base(attrs.Name)
+		id, err := strconv.ParseUint(run, 10, 64)
+		if err != nil || id >= buildID || (previousRun != "" && id <= previousID) {
+			continue
+		}
+		previousID, previousRun = id, run
+	}
+	if previousRun == "" {
+		return "", nil, nil
+	}
+
+	previousKey := fmt.Sprintf("%s%s/%s%s", src.linkPrefix, src.bucket, runsPrefix, previousRun)
+	artifacts, err := af.artifacts(ctx, previousKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return previousKey, artifacts, nil
+}
+
 func (af *StorageArtifactFetcher) signURL(ctx context.Context, key string) (string, error) {
 	return af.opener.SignedURL(ctx, key, pkgio.SignedURLOptions{
 		UseGSCookieAuth: af.useCookieAuth,
@@ -236,6 +236,55 @@ func TestArtifacts_ListGCS(t *testing.T) {
 }
 
 // Tests getting handles to objects associated with the current job in GCS
+func TestPreviousRun(t *testing.T) {
+	cfg := createConfigGetter("test-bucket")
+	fakeGCSClient := fakeGCSServer.Client()
+	testAf := NewStorageArtifactFetcher(io.NewGCSOpener(fakeGCSClient), cfg, false)
+	testCases := []struct {
+		name              string
+		key               string
+		expectedKey       string
+		expectedArtifacts []string
+		expectErr         bool
+	}{
+		{
+			name:              "previous run of latest run",
+			key:               "gs://test-bucket/logs/example-ci-run/403",
+			expectedKey:       "gs://test-bucket/logs/example-ci-run/401",
+			expectedArtifacts: []string{"junit_01.xml"},
+		},
+		{
+			name:              "previous run of older run",
+			key:               "gs://test-bucket/logs/example-ci-run/401",
+			expectedKey:       "gs://test-bucket/logs/example-ci-run/400",
+			expectedArtifacts: []string{"build-log.txt"},
+		},
+		{
+			name: "no previous run",
+			key:  "gs://test-bucket/logs/example-ci-run/400",
+		},
+		{
+			name:      "invalid build ID",
+			key:       "gs://test-bucket/logs/example-ci-run/latest",
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, artifacts, err := testAf.PreviousRun(context.Background(), tc.key)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got: %v", tc.expectErr, err)
+			}
+			if key != tc.expectedKey {
+				t.Errorf("expected previous run %q, got %q", tc.expectedKey, key)
+			}
+			if diff := cmp.Diff(tc.expectedArtifacts, artifacts); diff != "" {
+				t.Errorf("artifacts differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
 func TestFetchArtifacts_GCS(t *testing.T) {
 	cfg := createConfigGetter("test-bucket")
 	fakeGCSClient := fakeGCSServer.Client()
@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"fmt"
+
+	githubql "github.com/shurcooL/githubv4"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+// enableAutoMergeMutation is a GraphQL mutation struct compatible with shurcooL/githubql's client.
+//
+// See https://docs.github.com/en/graphql/reference/mutations#enablepullrequestautomerge
+type enableAutoMergeMutation struct {
+	EnablePullRequestAutoMerge struct {
+		ClientMutationID githubql.String
+	} `graphql:"enablePullRequestAutoMerge(input: $input)"`
+}
+
+var autoMergeMethods = map[github.PullRequestMergeType]githubql.PullRequestMergeMethod{
+	github.MergeMerge:  githubql.PullRequestMergeMethodMerge,
+	github.MergeSquash: githubql.PullRequestMergeMethodSquash,
+	github.MergeRebase: githubql.PullRequestMergeMethodRebase,
+}
+
+// mergeableStateClean is the mergeable state of PRs that can be merged right
+// away, on which GitHub refuses to enable auto-merge.
+const mergeableStateClean = "clean"
+
+// enableAutoMerge enables the auto-merge of GitHub on the PR, so that GitHub
+// merges it once the protection of its base branch allows it. GitHub refuses
+// to enable auto-merge on PRs that can already be merged, those are merged
+// directly.
+func (c *Controller) enableAutoMerge(org, repo string, pr PullRequest, details github.MergeDetails) error {
+	method, ok := autoMergeMethods[github.PullRequestMergeType(details.MergeMethod)]
+	if !ok {
+		return fmt.Errorf("unsupported merge method %q", details.MergeMethod)
+	}
+	input := githubql.EnablePullRequestAutoMergeInput{
+		PullRequestID: pr.ID,
+		MergeMethod:   &method,
+	}
+	if details.CommitTitle != "" {
+		input.CommitHeadline = githubql.NewString(githubql.String(details.CommitTitle))
+	}
+	if details.CommitMessage != "" {
+		input.CommitBody = githubql.NewString(githubql.String(details.CommitMessage))
+	}
+	if err := c.ghc.MutateWithGitHubAppsSupport(c.ctx, &enableAutoMergeMutation{}, input, nil, org); err != nil {
+		// The GraphQL client doesn't expose the type of the error, ask GitHub
+		// whether the PR can be merged right away instead.
+		ghPR, getErr := c.ghc.GetPullRequest(org, repo, int(pr.Number))
+		if getErr != nil {
+			return fmt.Errorf("failed to enable auto-merge: %w, and to get the PR: %v", err, getErr)
+		}
+		if ghPR.MergeableState == mergeableStateClean {
+			return c.ghc.Merge(org, repo, int(pr.Number), details)
+		}
+		return fmt.Errorf("failed to enable auto-merge: %w", err)
+	}
+	return nil
+}
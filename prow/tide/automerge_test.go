@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+)
+
+func TestEnableAutoMerge(t *testing.T) {
+	testCases := []struct {
+		name               string
+		method             github.PullRequestMergeType
+		autoMergeErr       error
+		mergeableState     string
+		expectedErr        bool
+		expectedAutoMerged []githubql.ID
+		expectedMerged     int
+	}{
+		{
+			name:               "squash",
+			method:             github.MergeSquash,
+			expectedAutoMerged: []githubql.ID{"PR_1"},
+		},
+		{
+			name:               "rebase",
+			method:             github.MergeRebase,
+			expectedAutoMerged: []githubql.ID{"PR_1"},
+		},
+		{
+			name:        "unsupported method",
+			method:      "ifsuccess",
+			expectedErr: true,
+		},
+		{
+			name:           "PR that can be merged right away is merged",
+			method:         github.MergeSquash,
+			autoMergeErr:   errors.New("Pull request is in clean status"),
+			mergeableState: "clean",
+			expectedMerged: 1,
+		},
+		{
+			name:           "failure to enable auto-merge on a PR that can't be merged yet is returned",
+			method:         github.MergeSquash,
+			autoMergeErr:   errors.New("Pull request is in clean status"),
+			mergeableState: "blocked",
+			expectedErr:    true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ghc := &fgc{autoMergeErr: tc.autoMergeErr, mergeableStates: map[int]string{1: tc.mergeableState}}
+			c := &Controller{ctx: context.Background(), ghc: ghc}
+			pr := PullRequest{ID: "PR_1", Number: 1}
+			err := c.enableAutoMerge("org", "repo", pr, github.MergeDetails{MergeMethod: string(tc.method), CommitTitle: "title"})
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("expected error: %t, got: %v", tc.expectedErr, err)
+			}
+			if !reflect.DeepEqual(ghc.autoMerged, tc.expectedAutoMerged) {
+				t.Errorf("expected auto-merge to be enabled on %v, got %v", tc.expectedAutoMerged, ghc.autoMerged)
+			}
+			if ghc.merged != tc.expectedMerged {
+				t.Errorf("expected %d PRs to be merged, got %d", tc.expectedMerged, ghc.merged)
+			}
+		})
+	}
+}
+
+func TestMergePRsSkipsPRsWithAutoMerge(t *testing.T) {
+	ca := &config.Agent{}
+	cfg := &config.Config{}
+	cfg.Tide.AutoMergeMap = map[string]bool{"o/r": true}
+	ca.Set(cfg)
+
+	ghc := &fgc{}
+	c, err := newSyncController(
+		context.Background(),
+		logrus.WithField("controller", "tide"),
+		ghc,
+		newFakeManager(),
+		ca.Config,
+		nil,
+		&statusController{},
+		nil,
+		nil,
+		false,
+	)
+	if err != nil {
+		t.Fatalf("failed to construct sync controller: %v", err)
+	}
+
+	var prs []PullRequest
+	for i := 1; i <= 2; i++ {
+		var pr PullRequest
+		pr.ID = githubql.ID(fmt.Sprintf("PR_%d", i))
+		pr.Number = githubql.Int(i)
+		pr.Repository.NameWithOwner = "o/r"
+		prs = append(prs, pr)
+	}
+	prs[0].AutoMergeRequest = &struct{ EnabledAt githubql.DateTime }{}
+
+	sp := subpool{log: logrus.WithField("component", "tide"), org: "o", repo: "r", branch: "master"}
+	if err := c.mergePRs(sp, prs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []githubql.ID{"PR_2"}; !reflect.DeepEqual(ghc.autoMerged, expected) {
+		t.Errorf("expected auto-merge to be enabled on %v, got %v", expected, ghc.autoMerged)
+	}
+}
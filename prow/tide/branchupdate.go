@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"fmt"
+	"sync"
+)
+
+// branchUpdates counts how many times Tide updated the branch of PRs that were
+// behind their base branch. Counts expire if the PR is not in the pool during
+// a sync loop.
+type branchUpdates struct {
+	sync.Mutex
+	counts     map[string]int
+	nextCounts map[string]int
+}
+
+func newBranchUpdates() *branchUpdates {
+	return &branchUpdates{
+		counts:     make(map[string]int),
+		nextCounts: make(map[string]int),
+	}
+}
+
+// keep records that the PR is in the pool and returns its count.
+func (b *branchUpdates) keep(pr *PullRequest) int {
+	b.Lock()
+	defer b.Unlock()
+	key := prKey(pr)
+	count, ok := b.nextCounts[key]
+	if !ok {
+		count = b.counts[key]
+	}
+	b.nextCounts[key] = count
+	return count
+}
+
+func (b *branchUpdates) inc(pr *PullRequest) {
+	b.Lock()
+	defer b.Unlock()
+	key := prKey(pr)
+	count, ok := b.nextCounts[key]
+	if !ok {
+		count = b.counts[key]
+	}
+	b.nextCounts[key] = count + 1
+}
+
+// prune forgets the PRs that were not in the pool since the last prune.
+func (b *branchUpdates) prune() {
+	b.Lock()
+	defer b.Unlock()
+	b.counts = b.nextCounts
+	b.nextCounts = make(map[string]int)
+}
+
+// isBehindBase returns whether the branch of the PR is missing commits of the
+// base branch of the subpool, according to GitHub's comparison of its head with
+// the head of the base branch.
+func (c *Controller) isBehindBase(sp subpool, pr *PullRequest) (bool, error) {
+	comparison, err := c.ghc.CompareCommits(sp.org, sp.repo, sp.sha, string(pr.HeadRefOID))
+	if err != nil {
+		return false, fmt.Errorf("failed to compare PR %d with its base branch: %w", pr.Number, err)
+	}
+	return comparison.BehindBy > 0, nil
+}
+
+// upToDateCandidates returns the PRs that can be merged in a repo requiring
+// PRs to be up to date with their base branch: the PRs that are up to date and
+// those whose branch can still be updated. The numbers of the candidates that
+// are behind their base branch are returned as well.
+func (c *Controller) upToDateCandidates(sp subpool, prs []PullRequest) ([]PullRequest, map[int]bool) {
+	maxUpdates := c.config().Tide.MaxBranchUpdates
+	var candidates []PullRequest
+	behind := map[int]bool{}
+	for _, pr := range prs {
+		isBehind, err := c.isBehindBase(sp, &pr)
+		if err != nil {
+			sp.log.WithFields(pr.logFields()).WithError(err).Warn("Not merging the PR, it isn't known whether it is up to date with its base branch.")
+			continue
+		}
+		if isBehind {
+			if c.branchUpdates.keep(&pr) >= maxUpdates {
+				sp.log.WithFields(pr.logFields()).Warnf("PR is behind its base branch and its branch was already updated %d times, not merging it.", maxUpdates)
+				continue
+			}
+			behind[int(pr.Number)] = true
+		}
+		candidates = append(candidates, pr)
+	}
+	return candidates, behind
+}
+
+// updateBranch merges the base branch into the branch of the PR. This retests
+// the PR, which is merged once its tests passed again.
+func (c *Controller) updateBranch(sp subpool, pr PullRequest) error {
+	head := string(pr.HeadRefOID)
+	if err := c.ghc.UpdatePullRequestBranch(sp.org, sp.repo, int(pr.Number), &head); err != nil {
+		return fmt.Errorf("failed to update the branch of PR %d: %w", pr.Number, err)
+	}
+	c.branchUpdates.inc(&pr)
+	return nil
+}
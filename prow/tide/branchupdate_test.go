@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+)
+
+func TestTakeActionRequireUpToDate(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+
+	testCases := []struct {
+		name            string
+		requireUpToDate bool
+		mergeQueue      bool
+		behind          bool
+		compareErr      error
+		previousUpdates int
+
+		expectedAction  Action
+		expectedUpdates []int
+		expectedMerged  int
+		expectedCount   int
+	}{
+		{
+			name:            "up to date PR is merged",
+			requireUpToDate: true,
+			expectedAction:  Merge,
+			expectedMerged:  1,
+		},
+		{
+			name:            "branch of PR behind its base is updated",
+			requireUpToDate: true,
+			behind:          true,
+			expectedAction:  UpdateBranch,
+			expectedUpdates: []int{1},
+			expectedCount:   1,
+		},
+		{
+			name:            "PR whose branch was updated too often is not merged",
+			requireUpToDate: true,
+			behind:          true,
+			previousUpdates: 3,
+			expectedAction:  Wait,
+			expectedCount:   3,
+		},
+		{
+			name:            "PR that can't be compared with its base is not merged",
+			requireUpToDate: true,
+			compareErr:      errors.New("injected error"),
+			expectedAction:  Wait,
+		},
+		{
+			name:           "PR behind its base is merged if not required to be up to date",
+			behind:         true,
+			expectedAction: Merge,
+			expectedMerged: 1,
+		},
+		{
+			name:            "up to date PR at the head of the merge queue is merged",
+			requireUpToDate: true,
+			mergeQueue:      true,
+			expectedAction:  Merge,
+			expectedMerged:  1,
+		},
+		{
+			name:            "branch of PR at the head of the merge queue behind its base is updated",
+			requireUpToDate: true,
+			mergeQueue:      true,
+			behind:          true,
+			expectedAction:  UpdateBranch,
+			expectedUpdates: []int{1},
+			expectedCount:   1,
+		},
+		{
+			name:            "PR at the head of the merge queue whose branch was updated too often is skipped",
+			requireUpToDate: true,
+			mergeQueue:      true,
+			behind:          true,
+			previousUpdates: 3,
+			expectedAction:  Wait,
+			expectedCount:   3,
+		},
+		{
+			name:           "PR at the head of the merge queue behind its base is merged if not required to be up to date",
+			mergeQueue:     true,
+			behind:         true,
+			expectedAction: Merge,
+			expectedMerged: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ca := &config.Agent{}
+			cfg := &config.Config{}
+			cfg.Tide.RequireUpToDateMap = map[string]bool{"o/r": tc.requireUpToDate}
+			cfg.Tide.MaxBranchUpdates = 3
+			ca.Set(cfg)
+
+			fgc := &fgc{compareErr: tc.compareErr}
+			if tc.behind {
+				fgc.comparisons = map[string]*github.CommitComparison{"master...sha-1": {Status: "diverged", AheadBy: 1, BehindBy: 2}}
+			}
+			c, err := newSyncController(
+				context.Background(),
+				logrus.WithField("controller", "tide"),
+				fgc,
+				newFakeManager(),
+				ca.Config,
+				nil,
+				&statusController{},
+				nil,
+				nil,
+				false,
+			)
+			if err != nil {
+				t.Fatalf("failed to construct sync controller: %v", err)
+			}
+
+			var pr PullRequest
+			pr.Number = 1
+			pr.HeadRefOID = "sha-1"
+			pr.Repository.NameWithOwner = "o/r"
+			pr.Commits.Nodes = []struct{ Commit Commit }{{Commit: Commit{OID: pr.HeadRefOID}}}
+			for i := 0; i < tc.previousUpdates; i++ {
+				c.branchUpdates.inc(&pr)
+			}
+
+			sp := subpool{
+				log:    logrus.WithField("component", "tide"),
+				org:    "o",
+				repo:   "r",
+				branch: "master",
+				sha:    "master",
+				prs:    []PullRequest{pr},
+				cc:     map[int]contextChecker{1: &config.TideContextPolicy{}},
+			}
+			var act Action
+			if tc.mergeQueue {
+				act, _, _, _, err = c.takeQueueAction(sp, []PullRequest{pr}, nil, nil)
+			} else {
+				act, _, err = c.takeAction(sp, nil, []PullRequest{pr}, nil, nil, nil, nil)
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if act != tc.expectedAction {
+				t.Errorf("expected action %v, got %v", tc.expectedAction, act)
+			}
+			if diff := cmp.Diff(tc.expectedUpdates, fgc.branchUpdates); diff != "" {
+				t.Errorf("branch updates differ from expected: %s", diff)
+			}
+			if fgc.merged != tc.expectedMerged {
+				t.Errorf("expected %d merges, got %d", tc.expectedMerged, fgc.merged)
+			}
+			if n := c.branchUpdates.keep(&pr); n != tc.expectedCount {
+				t.Errorf("expected %d recorded branch updates, got %d", tc.expectedCount, n)
+			}
+		})
+	}
+}
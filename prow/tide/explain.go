@@ -0,0 +1,176 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"sort"
+	"time"
+
+	githubql "github.com/shurcooL/githubv4"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/tide/blockers"
+)
+
+// PullRequestBlockers explains what keeps a PR that Tide found with its queries
+// from merging.
+type PullRequestBlockers struct {
+	Number int
+	Title  string
+
+	// Why the PR cannot be merged at all, e.g. because of a merge conflict.
+	Unmergeable string
+	// The labels the PR lacks or must not have to meet the query it is closest
+	// to meeting, the milestone it must be in, and whether it lacks an
+	// approving review. These can differ from the queries when the PR changed
+	// after GitHub indexed it.
+	MissingLabels    []string
+	UnwantedLabels   []string
+	MissingMilestone string
+	ReviewRequired   bool
+	// The required contexts that failed, are pending or were not reported.
+	FailedContexts  []string
+	PendingContexts []string
+	MissingContexts []string
+	// Why merging is frozen for the PR, and the issues blocking merges to its
+	// branch.
+	MergeFreeze    string
+	BlockingIssues []int
+}
+
+func (b PullRequestBlockers) blocked() bool {
+	return b.Unmergeable != "" ||
+		len(b.MissingLabels) > 0 ||
+		len(b.UnwantedLabels) > 0 ||
+		b.MissingMilestone != "" ||
+		b.ReviewRequired ||
+		len(b.FailedContexts) > 0 ||
+		len(b.PendingContexts) > 0 ||
+		len(b.MissingContexts) > 0 ||
+		b.MergeFreeze != "" ||
+		len(b.BlockingIssues) > 0
+}
+
+// labelDiff returns the labels of the query the PR lacks and the labels the
+// query forbids that the PR has.
+func labelDiff(pr *PullRequest, q *config.TideQuery) (missing, present []string) {
+	for _, l1 := range q.Labels {
+		var found bool
+		for _, l2 := range pr.Labels.Nodes {
+			if string(l2.Name) == l1 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, l1)
+		}
+	}
+	for _, l1 := range q.MissingLabels {
+		for _, l2 := range pr.Labels.Nodes {
+			if string(l2.Name) == l1 {
+				present = append(present, l1)
+				break
+			}
+		}
+	}
+	return missing, present
+}
+
+// explainSubpool explains what keeps each PR of the subpool from merging,
+// except for the blocker issues, see poolBlockers. It must be called before the
+// subpool is filtered.
+func (c *Controller) explainSubpool(mergeAllowed func(*PullRequest) (string, error), sp *subpool) []PullRequestBlockers {
+	queries := c.config().Tide.Queries.QueryMap().ForRepo(config.OrgRepo{Org: sp.org, Repo: sp.repo})
+	freezes := mergeFreezes(&c.config().Tide, sp.org, sp.repo, sp.branch, time.Now())
+	explained := make([]PullRequestBlockers, 0, len(sp.prs))
+	for i := range sp.prs {
+		pr := &sp.prs[i]
+		log := sp.log.WithFields(pr.logFields())
+		cc := sp.cc[int(pr.Number)]
+		b := PullRequestBlockers{Number: int(pr.Number), Title: string(pr.Title)}
+
+		if reason, err := mergeAllowed(pr); err != nil {
+			log.WithError(err).Error("Error checking PR mergeability.")
+		} else {
+			b.Unmergeable = reason
+		}
+
+		// Compare the PR to the query it is closest to meeting, unless it
+		// meets one.
+		var closest *config.TideQuery
+		minDiff := -1
+		for j := range queries {
+			_, diff := requirementDiff(pr, &queries[j], cc)
+			if minDiff == -1 || diff < minDiff {
+				minDiff = diff
+				closest = &queries[j]
+			}
+		}
+		if closest != nil && minDiff > 0 {
+			b.MissingLabels, b.UnwantedLabels = labelDiff(pr, closest)
+			if closest.Milestone != "" && (pr.Milestone == nil || string(pr.Milestone.Title) != closest.Milestone) {
+				b.MissingMilestone = closest.Milestone
+			}
+			b.ReviewRequired = closest.ReviewApprovedRequired && pr.ReviewDecision != githubql.PullRequestReviewDecisionApproved
+			sort.Strings(b.MissingLabels)
+			sort.Strings(b.UnwantedLabels)
+		}
+
+		if contexts, err := headContexts(log, c.ghc, pr); err != nil {
+			log.WithError(err).Error("Getting head contexts.")
+		} else {
+			for _, ctx := range unsuccessfulContexts(contexts, cc, log) {
+				switch ctx.State {
+				case githubql.StatusStateExpected:
+					b.MissingContexts = append(b.MissingContexts, string(ctx.Context))
+				case githubql.StatusStatePending:
+					b.PendingContexts = append(b.PendingContexts, string(ctx.Context))
+				default:
+					b.FailedContexts = append(b.FailedContexts, string(ctx.Context))
+				}
+			}
+		}
+		sort.Strings(b.MissingContexts)
+		sort.Strings(b.PendingContexts)
+		sort.Strings(b.FailedContexts)
+
+		b.MergeFreeze = frozenReason(freezes, pr)
+
+		explained = append(explained, b)
+	}
+	return explained
+}
+
+// poolBlockers adds the issues blocking merges to the branch of the pool to the
+// explanations of its PRs and returns those of the PRs that are blocked.
+func poolBlockers(explained []PullRequestBlockers, blocks []blockers.Blocker) []PullRequestBlockers {
+	var issues []int
+	for _, block := range blocks {
+		issues = append(issues, block.Number)
+	}
+	sort.Ints(issues)
+
+	var blocked []PullRequestBlockers
+	for _, b := range explained {
+		b.BlockingIssues = issues
+		if b.blocked() {
+			blocked = append(blocked, b)
+		}
+	}
+	return blocked
+}
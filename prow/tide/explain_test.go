@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/tide/blockers"
+)
+
+func TestExplainSubpool(t *testing.T) {
+	cfg := &config.Config{
+		ProwConfig: config.ProwConfig{
+			Tide: config.Tide{
+				Queries: []config.TideQuery{{
+					Repos:                  []string{"org/repo"},
+					Labels:                 []string{"lgtm", "approved"},
+					MissingLabels:          []string{"do-not-merge/hold"},
+					Milestone:              "v1.0",
+					ReviewApprovedRequired: true,
+				}},
+			},
+		},
+	}
+	c := &Controller{
+		config: func() *config.Config { return cfg },
+		ghc:    &fgc{},
+		logger: logrus.WithField("component", "tide"),
+	}
+
+	approved := testPRWithLabels("org", "repo", "master", 1, githubql.MergeableStateMergeable, []string{"lgtm", "approved"})
+	approved.ReviewDecision = githubql.PullRequestReviewDecisionApproved
+
+	conflicting := testPRWithLabels("org", "repo", "master", 2, githubql.MergeableStateConflicting, []string{"lgtm", "approved"})
+	conflicting.ReviewDecision = githubql.PullRequestReviewDecisionApproved
+
+	held := testPRWithLabels("org", "repo", "master", 3, githubql.MergeableStateMergeable, []string{"lgtm", "do-not-merge/hold"})
+
+	failing := testPRWithLabels("org", "repo", "master", 4, githubql.MergeableStateMergeable, []string{"lgtm", "approved"})
+	failing.ReviewDecision = githubql.PullRequestReviewDecisionApproved
+	failing.Commits.Nodes[0].Commit.Status.Contexts = append(failing.Commits.Nodes[0].Commit.Status.Contexts,
+		Context{Context: "unit", State: githubql.StatusStateFailure},
+		Context{Context: "e2e", State: githubql.StatusStatePending},
+	)
+
+	cc := &config.TideContextPolicy{RequiredContexts: []string{"context", "unit", "e2e", "lint"}}
+	sp := &subpool{
+		log:    logrus.WithField("component", "tide"),
+		org:    "org",
+		repo:   "repo",
+		branch: "master",
+		prs:    []PullRequest{approved, conflicting, held, failing},
+		cc:     map[int]contextChecker{1: cc, 2: cc, 3: cc, 4: cc},
+	}
+	for i := range sp.prs {
+		if int(sp.prs[i].Number) != 3 {
+			sp.prs[i].Milestone = &struct{ Title githubql.String }{Title: "v1.0"}
+		}
+		if int(sp.prs[i].Number) == 4 {
+			continue
+		}
+		sp.prs[i].Commits.Nodes[0].Commit.Status.Contexts = append(sp.prs[i].Commits.Nodes[0].Commit.Status.Contexts,
+			Context{Context: "unit", State: githubql.StatusStateSuccess},
+			Context{Context: "e2e", State: githubql.StatusStateSuccess},
+			Context{Context: "lint", State: githubql.StatusStateSuccess},
+		)
+	}
+	mergeAllowed := func(pr *PullRequest) (string, error) {
+		if pr.Mergeable == githubql.MergeableStateConflicting {
+			return "PR has a merge conflict.", nil
+		}
+		return "", nil
+	}
+
+	expected := []PullRequestBlockers{
+		{Number: 1},
+		{Number: 2, Unmergeable: "PR has a merge conflict."},
+		{Number: 3, MissingLabels: []string{"approved"}, UnwantedLabels: []string{"do-not-merge/hold"}, MissingMilestone: "v1.0", ReviewRequired: true},
+		{Number: 4, FailedContexts: []string{"unit"}, PendingContexts: []string{"e2e"}, MissingContexts: []string{"lint"}},
+	}
+	if diff := cmp.Diff(expected, c.explainSubpool(mergeAllowed, sp)); diff != "" {
+		t.Errorf("explanations differ from expected: %s", diff)
+	}
+}
+
+func TestPoolBlockers(t *testing.T) {
+	explained := []PullRequestBlockers{
+		{Number: 1},
+		{Number: 2, Unmergeable: "PR has a merge conflict."},
+	}
+
+	expected := []PullRequestBlockers{{Number: 2, Unmergeable: "PR has a merge conflict."}}
+	if diff := cmp.Diff(expected, poolBlockers(explained, nil)); diff != "" {
+		t.Errorf("blocked PRs differ from expected: %s", diff)
+	}
+
+	blocks := []blockers.Blocker{{Number: 12}, {Number: 10}}
+	expected = []PullRequestBlockers{
+		{Number: 1, BlockingIssues: []int{10, 12}},
+		{Number: 2, Unmergeable: "PR has a merge conflict.", BlockingIssues: []int{10, 12}},
+	}
+	if diff := cmp.Diff(expected, poolBlockers(explained, blocks)); diff != "" {
+		t.Errorf("blocked PRs differ from expected: %s", diff)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"strings"
+	"time"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+// mergeFreeze is a merge calendar that does not allow merging to a branch.
+type mergeFreeze struct {
+	reason        string
+	overrideLabel string
+}
+
+// mergeFreezes returns the merge calendars that do not allow merging to the
+// branch at the given time.
+func mergeFreezes(tide *config.Tide, org, repo, branch string, now time.Time) []mergeFreeze {
+	var freezes []mergeFreeze
+	for _, calendar := range tide.MergeCalendarsFor(config.OrgRepo{Org: org, Repo: repo}, branch) {
+		if frozen, reason := calendar.Frozen(now); frozen {
+			freezes = append(freezes, mergeFreeze{reason: reason, overrideLabel: calendar.OverrideLabel})
+		}
+	}
+	return freezes
+}
+
+// freezeReasons returns the reasons of the freezes, or the empty string if
+// there are none.
+func freezeReasons(freezes []mergeFreeze) string {
+	var reasons []string
+	for _, freeze := range freezes {
+		reasons = append(reasons, freeze.reason)
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// frozenReason returns why the PR cannot be merged because of the freezes, or
+// the empty string if it has the override labels of all of them.
+func frozenReason(freezes []mergeFreeze, pr *PullRequest) string {
+	var blocking []mergeFreeze
+	for _, freeze := range freezes {
+		if freeze.overrideLabel != "" && hasAllLabels(*pr, []string{freeze.overrideLabel}) {
+			continue
+		}
+		blocking = append(blocking, freeze)
+	}
+	return freezeReasons(blocking)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"testing"
+	"time"
+
+	githubql "github.com/shurcooL/githubv4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+func TestFrozenReason(t *testing.T) {
+	now := time.Now()
+	freeze := func(reason string) []config.TideMergeFreeze {
+		return []config.TideMergeFreeze{{
+			Start:  metav1.NewTime(now.Add(-time.Hour)),
+			End:    metav1.NewTime(now.Add(time.Hour)),
+			Reason: reason,
+		}}
+	}
+	tide := &config.Tide{
+		MergeCalendars: []config.TideMergeCalendar{
+			{Orgs: []string{"org"}, Freezes: freeze("v1.2 release"), OverrideLabel: "merge-freeze-exception"},
+			{Repos: []string{"org/repo"}, Branches: []string{"release"}, Freezes: freeze("")},
+			{Repos: []string{"org/other"}, Freezes: freeze("maintenance")},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		branch   string
+		labels   []string
+		expected string
+	}{
+		{
+			name:     "frozen branch",
+			branch:   "master",
+			expected: "merge freeze (v1.2 release)",
+		},
+		{
+			name:   "override label lifts the freeze",
+			branch: "master",
+			labels: []string{"lgtm", "merge-freeze-exception"},
+		},
+		{
+			name:     "all freezes are reported",
+			branch:   "release",
+			expected: "merge freeze (v1.2 release), merge freeze",
+		},
+		{
+			name:     "freezes without override label are not lifted",
+			branch:   "release",
+			labels:   []string{"merge-freeze-exception"},
+			expected: "merge freeze",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var pr PullRequest
+			for _, label := range tc.labels {
+				pr.Labels.Nodes = append(pr.Labels.Nodes, struct{ Name githubql.String }{Name: githubql.String(label)})
+			}
+			freezes := mergeFreezes(tide, "org", "repo", tc.branch, now)
+			if got := frozenReason(freezes, &pr); got != tc.expected {
+				t.Errorf("expected reason %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,473 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/gitlab"
+)
+
+// gitlabProvider lets Tide merge the merge requests of the orgs hosted on
+// GitLab. It searches their merge requests and implements the githubClient
+// methods for them, translating from and to the GitHub types Tide uses.
+type gitlabProvider struct {
+	glc    gitlab.Client
+	config config.Getter
+	logger *logrus.Entry
+}
+
+func newGitLabProvider(glc gitlab.Client, cfg config.Getter, logger *logrus.Entry) *gitlabProvider {
+	return &gitlabProvider{glc: glc, config: cfg, logger: logger.WithField("provider", "gitlab")}
+}
+
+var errNoGitLabClient = errors.New("GitLab orgs are configured but Tide has no GitLab client, see --gitlab-endpoint")
+
+// providerClient sends the requests for orgs hosted on GitLab to the GitLab
+// provider and all other requests to GitHub.
+type providerClient struct {
+	githubClient
+	gitlab *gitlabProvider
+}
+
+func (p *providerClient) isGitLab(org string) bool {
+	return p.gitlab.config().Tide.GitLab.IsGitLabOrg(org)
+}
+
+func (p *providerClient) CreateStatus(org, repo, ref string, s github.Status) error {
+	if p.isGitLab(org) {
+		return p.gitlab.CreateStatus(org, repo, ref, s)
+	}
+	return p.githubClient.CreateStatus(org, repo, ref, s)
+}
+
+func (p *providerClient) GetCombinedStatus(org, repo, ref string) (*github.CombinedStatus, error) {
+	if p.isGitLab(org) {
+		return p.gitlab.GetCombinedStatus(org, repo, ref)
+	}
+	return p.githubClient.GetCombinedStatus(org, repo, ref)
+}
+
+func (p *providerClient) ListCheckRuns(org, repo, ref string) (*github.CheckRunList, error) {
+	if p.isGitLab(org) {
+		return &github.CheckRunList{}, nil
+	}
+	return p.githubClient.ListCheckRuns(org, repo, ref)
+}
+
+func (p *providerClient) GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error) {
+	if p.isGitLab(org) {
+		return p.gitlab.GetPullRequestChanges(org, repo, number)
+	}
+	return p.githubClient.GetPullRequestChanges(org, repo, number)
+}
+
+func (p *providerClient) GetRef(org, repo, ref string) (string, error) {
+	if p.isGitLab(org) {
+		return p.gitlab.GetRef(org, repo, ref)
+	}
+	return p.githubClient.GetRef(org, repo, ref)
+}
+
+func (p *providerClient) CompareCommits(org, repo, base, head string) (*github.CommitComparison, error) {
+	if p.isGitLab(org) {
+		return p.gitlab.CompareCommits(org, repo, base, head)
+	}
+	return p.githubClient.CompareCommits(org, repo, base, head)
+}
+
+func (p *providerClient) GetRepo(owner, name string) (github.FullRepo, error) {
+	if p.isGitLab(owner) {
+		return p.gitlab.GetRepo(owner, name)
+	}
+	return p.githubClient.GetRepo(owner, name)
+}
+
+func (p *providerClient) Merge(org, repo string, number int, details github.MergeDetails) error {
+	if p.isGitLab(org) {
+		return p.gitlab.Merge(org, repo, number, details)
+	}
+	return p.githubClient.Merge(org, repo, number, details)
+}
+
+func (p *providerClient) UpdatePullRequestBranch(org, repo string, number int, expectedHeadSha *string) error {
+	if p.isGitLab(org) {
+		return p.gitlab.UpdatePullRequestBranch(org, repo, number, expectedHeadSha)
+	}
+	return p.githubClient.UpdatePullRequestBranch(org, repo, number, expectedHeadSha)
+}
+
+func (g *gitlabProvider) CreateStatus(org, repo, ref string, s github.Status) error {
+	state := gitlab.StatusPending
+	switch s.State {
+	case github.StatusSuccess:
+		state = gitlab.StatusSuccess
+	case github.StatusFailure, github.StatusError:
+		state = gitlab.StatusFailed
+	}
+	return g.glc.SetCommitStatus(org+"/"+repo, ref, gitlab.CommitStatus{
+		Name:        s.Context,
+		Status:      state,
+		Description: s.Description,
+		TargetURL:   s.TargetURL,
+	})
+}
+
+// githubState returns the GitHub status state of a GitLab commit status.
+// Skipped jobs do not block merging, unfinished ones are pending.
+func githubState(status string) string {
+	switch status {
+	case gitlab.StatusSuccess, gitlab.StatusSkipped:
+		return github.StatusSuccess
+	case gitlab.StatusFailed, gitlab.StatusCanceled:
+		return github.StatusFailure
+	default:
+		return github.StatusPending
+	}
+}
+
+func (g *gitlabProvider) GetCombinedStatus(org, repo, ref string) (*github.CombinedStatus, error) {
+	statuses, err := g.glc.ListCommitStatuses(org+"/"+repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	combined := &github.CombinedStatus{SHA: ref}
+	for _, status := range statuses {
+		combined.Statuses = append(combined.Statuses, github.Status{
+			Context:     status.Name,
+			State:       githubState(status.Status),
+			Description: status.Description,
+			TargetURL:   status.TargetURL,
+		})
+	}
+	return combined, nil
+}
+
+func (g *gitlabProvider) GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error) {
+	changes, err := g.glc.GetMergeRequestChanges(org+"/"+repo, number)
+	if err != nil {
+		return nil, err
+	}
+	var prChanges []github.PullRequestChange
+	for _, change := range changes {
+		prChange := github.PullRequestChange{Filename: change.NewPath, Status: string(github.PullRequestFileModified)}
+		switch {
+		case change.NewFile:
+			prChange.Status = github.PullRequestFileAdded
+		case change.DeletedFile:
+			prChange.Status = github.PullRequestFileRemoved
+		case change.RenamedFile:
+			prChange.Status = github.PullRequestFileRenamed
+			prChange.PreviousFilename = change.OldPath
+		}
+		prChanges = append(prChanges, prChange)
+	}
+	return prChanges, nil
+}
+
+func (g *gitlabProvider) GetRef(org, repo, ref string) (string, error) {
+	branch, err := g.glc.GetBranch(org+"/"+repo, strings.TrimPrefix(ref, "heads/"))
+	if err != nil {
+		return "", err
+	}
+	return branch.Commit.ID, nil
+}
+
+// CompareCommits only tells how many commits of the base are missing from the
+// head, as GitLab compares commits in one direction.
+func (g *gitlabProvider) CompareCommits(org, repo, base, head string) (*github.CommitComparison, error) {
+	comparison, err := g.glc.Compare(org+"/"+repo, head, base)
+	if err != nil {
+		return nil, err
+	}
+	return &github.CommitComparison{BehindBy: len(comparison.Commits)}, nil
+}
+
+// GetRepo returns the merge methods allowed in the project. GitLab cannot
+// rebase a single merge request on merge, the project merge method applies to
+// merge commits instead.
+func (g *gitlabProvider) GetRepo(owner, name string) (github.FullRepo, error) {
+	project, err := g.glc.GetProject(owner + "/" + name)
+	if err != nil {
+		return github.FullRepo{}, err
+	}
+	return github.FullRepo{
+		Repo: github.Repo{
+			Owner:    github.User{Login: owner},
+			Name:     name,
+			FullName: project.PathWithNamespace,
+		},
+		AllowMergeCommit: true,
+		AllowSquashMerge: project.SquashOption != "never",
+	}, nil
+}
+
+func (g *gitlabProvider) Merge(org, repo string, number int, details github.MergeDetails) error {
+	project := org + "/" + repo
+	var err error
+	if g.config().Tide.GitLab.MergeTrain(config.OrgRepo{Org: org, Repo: repo}) {
+		err = g.glc.AddToMergeTrain(project, number, details.SHA)
+	} else {
+		opts := gitlab.AcceptMergeRequestOptions{
+			SHA:    details.SHA,
+			Squash: details.MergeMethod == string(github.MergeSquash),
+		}
+		if details.CommitTitle != "" {
+			message := details.CommitTitle
+			if details.CommitMessage != "" {
+				message += "\n\n" + details.CommitMessage
+			}
+			if opts.Squash {
+				opts.SquashCommitMessage = message
+			} else {
+				opts.MergeCommitMessage = message
+			}
+		}
+		err = g.glc.AcceptMergeRequest(project, number, opts)
+	}
+	var reqErr *gitlab.RequestError
+	if !errors.As(err, &reqErr) {
+		return err
+	}
+	// Translate the errors so that Tide handles them like the GitHub ones.
+	switch reqErr.StatusCode {
+	case http.StatusConflict:
+		return github.ModifiedHeadError(reqErr.Message)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return github.UnauthorizedToPushError(reqErr.Message)
+	case http.StatusMethodNotAllowed, http.StatusNotAcceptable, http.StatusUnprocessableEntity:
+		return github.UnmergablePRError(reqErr.Message)
+	}
+	return err
+}
+
+// UpdatePullRequestBranch rebases the merge request on its target branch.
+func (g *gitlabProvider) UpdatePullRequestBranch(org, repo string, number int, _ *string) error {
+	return g.glc.RebaseMergeRequest(org+"/"+repo, number)
+}
+
+// splitQuery splits a query into the queries for the orgs and repos hosted on
+// GitHub and GitLab. The GitLab query is only returned if it applies to an org
+// or repo, the GitHub query unless all of them are hosted on GitLab.
+func splitQuery(q config.TideQuery, gl *config.TideGitLab) (githubQuery, gitlabQuery *config.TideQuery) {
+	gh, glq := q, q
+	gh.Orgs, gh.Repos, glq.Orgs, glq.Repos = nil, nil, nil, nil
+	for _, org := range q.Orgs {
+		if gl.IsGitLabOrg(org) {
+			glq.Orgs = append(glq.Orgs, org)
+		} else {
+			gh.Orgs = append(gh.Orgs, org)
+		}
+	}
+	for _, repo := range q.Repos {
+		if gl.IsGitLabOrg(strings.Split(repo, "/")[0]) {
+			glq.Repos = append(glq.Repos, repo)
+		} else {
+			gh.Repos = append(gh.Repos, repo)
+		}
+	}
+	if len(glq.Orgs) > 0 || len(glq.Repos) > 0 {
+		gitlabQuery = &glq
+	}
+	if len(gh.Orgs) > 0 || len(gh.Repos) > 0 || gitlabQuery == nil {
+		githubQuery = &gh
+	}
+	return githubQuery, gitlabQuery
+}
+
+// splitOrgExceptionsAndRepos returns the output of OrgExceptionsAndRepos for the
+// orgs hosted on GitHub and GitLab.
+func splitOrgExceptionsAndRepos(queries config.TideQueries, gl *config.TideGitLab) (githubOrgExceptions map[string]sets.String, githubRepos sets.String, gitlabOrgExceptions map[string]sets.String, gitlabRepos sets.String) {
+	orgExceptions, repos := queries.OrgExceptionsAndRepos()
+	githubOrgExceptions, gitlabOrgExceptions = map[string]sets.String{}, map[string]sets.String{}
+	for org, excepted := range orgExceptions {
+		if gl.IsGitLabOrg(org) {
+			gitlabOrgExceptions[org] = excepted
+		} else {
+			githubOrgExceptions[org] = excepted
+		}
+	}
+	githubRepos, gitlabRepos = sets.NewString(), sets.NewString()
+	for _, repo := range repos.List() {
+		if gl.IsGitLabOrg(strings.Split(repo, "/")[0]) {
+			gitlabRepos.Insert(repo)
+		} else {
+			githubRepos.Insert(repo)
+		}
+	}
+	return githubOrgExceptions, githubRepos, gitlabOrgExceptions, gitlabRepos
+}
+
+// search returns the open merge requests matching the query. Failing to list
+// the merge requests of a group or project, or to convert a merge request, does
+// not fail the whole search: the errors are logged and returned along with the
+// merge requests that could be fetched.
+func (g *gitlabProvider) search(q config.TideQuery) ([]PullRequest, error) {
+	if g == nil {
+		return nil, errNoGitLabClient
+	}
+	opts := gitlab.ListMergeRequestsOptions{
+		Labels:         q.Labels,
+		NotLabels:      q.MissingLabels,
+		Milestone:      q.Milestone,
+		AuthorUsername: q.Author,
+	}
+	excluded := sets.NewString(q.ExcludedRepos...)
+	var errs []error
+	var mrs []gitlab.MergeRequest
+	for _, org := range q.Orgs {
+		orgMRs, err := g.glc.ListGroupMergeRequests(org, opts)
+		if err != nil {
+			g.logger.WithError(err).WithField("group", org).Warn("Failed to list merge requests.")
+			errs = append(errs, fmt.Errorf("failed to list merge requests of group %s: %w", org, err))
+			continue
+		}
+		mrs = append(mrs, orgMRs...)
+	}
+	for _, repo := range q.Repos {
+		repoMRs, err := g.glc.ListProjectMergeRequests(repo, opts)
+		if err != nil {
+			g.logger.WithError(err).WithField("project", repo).Warn("Failed to list merge requests.")
+			errs = append(errs, fmt.Errorf("failed to list merge requests of project %s: %w", repo, err))
+			continue
+		}
+		mrs = append(mrs, repoMRs...)
+	}
+
+	included := sets.NewString(q.IncludedBranches...)
+	excludedBranches := sets.NewString(q.ExcludedBranches...)
+	var prs []PullRequest
+	for _, mr := range mrs {
+		if project, _ := mr.Project(); excluded.Has(project) {
+			continue
+		}
+		if (included.Len() > 0 && !included.Has(mr.TargetBranch)) || excludedBranches.Has(mr.TargetBranch) {
+			continue
+		}
+		pr, err := g.pullRequest(mr)
+		if err != nil {
+			g.logger.WithError(err).WithField("merge_request", mr.References.Full).Warn("Failed to convert merge request.")
+			errs = append(errs, err)
+			continue
+		}
+		if q.ReviewApprovedRequired && pr.ReviewDecision != githubql.PullRequestReviewDecisionApproved {
+			continue
+		}
+		prs = append(prs, pr)
+	}
+	g.logger.WithField("query", q.Query()).WithField("result_count", len(prs)).Debug("Searched for merge requests.")
+	return prs, utilerrors.NewAggregate(errs)
+}
+
+// openMergeRequests returns all open merge requests of the orgs, except for
+// the repos excluded from them, and of the repos.
+func (g *gitlabProvider) openMergeRequests(orgExceptions map[string]sets.String, repos sets.String) ([]PullRequest, error) {
+	var prs []PullRequest
+	var errs []error
+	for org, excepted := range orgExceptions {
+		orgPRs, err := g.search(config.TideQuery{Orgs: []string{org}, ExcludedRepos: excepted.List()})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		prs = append(prs, orgPRs...)
+	}
+	if repos.Len() > 0 {
+		repoPRs, err := g.search(config.TideQuery{Repos: repos.List()})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		prs = append(prs, repoPRs...)
+	}
+	return prs, utilerrors.NewAggregate(errs)
+}
+
+// pullRequest converts a merge request to the PullRequest Tide uses. Its
+// pipeline jobs and external statuses become the contexts of its head commit
+// and its approval state becomes its review decision.
+func (g *gitlabProvider) pullRequest(mr gitlab.MergeRequest) (PullRequest, error) {
+	var pr PullRequest
+	project, err := mr.Project()
+	if err != nil {
+		return pr, err
+	}
+	parts := strings.SplitN(project, "/", 2)
+	if len(parts) != 2 || strings.Contains(parts[1], "/") {
+		return pr, fmt.Errorf("merge request %s is in a subgroup, which is not supported", mr.References.Full)
+	}
+
+	pr.Number = githubql.Int(mr.IID)
+	pr.Author.Login = githubql.String(mr.Author.Username)
+	pr.BaseRef.Name = githubql.String(mr.TargetBranch)
+	pr.BaseRef.Prefix = "refs/heads/"
+	pr.HeadRefName = githubql.String(mr.SourceBranch)
+	pr.HeadRefOID = githubql.String(mr.SHA)
+	pr.CanBeRebased = true
+	switch {
+	case mr.HasConflicts || mr.MergeStatus == gitlab.MergeStatusCannotBeMerged:
+		pr.Mergeable = githubql.MergeableStateConflicting
+	case mr.MergeStatus == gitlab.MergeStatusCanBeMerged:
+		pr.Mergeable = githubql.MergeableStateMergeable
+	default:
+		pr.Mergeable = githubql.MergeableStateUnknown
+	}
+	pr.Repository.Name = githubql.String(parts[1])
+	pr.Repository.NameWithOwner = githubql.String(project)
+	pr.Repository.Owner.Login = githubql.String(parts[0])
+	for _, label := range mr.Labels {
+		pr.Labels.Nodes = append(pr.Labels.Nodes, struct{ Name githubql.String }{Name: githubql.String(label)})
+	}
+	if mr.Milestone != nil {
+		pr.Milestone = &struct{ Title githubql.String }{Title: githubql.String(mr.Milestone.Title)}
+	}
+	pr.Title = githubql.String(mr.Title)
+	pr.Body = githubql.String(mr.Description)
+	pr.UpdatedAt = githubql.DateTime{Time: mr.UpdatedAt}
+
+	approvals, err := g.glc.GetMergeRequestApprovals(project, mr.IID)
+	if err != nil {
+		return pr, fmt.Errorf("failed to get the approvals of merge request %s: %w", mr.References.Full, err)
+	}
+	pr.ReviewDecision = githubql.PullRequestReviewDecisionReviewRequired
+	if approvals.Approved {
+		pr.ReviewDecision = githubql.PullRequestReviewDecisionApproved
+	}
+
+	statuses, err := g.glc.ListCommitStatuses(project, mr.SHA)
+	if err != nil {
+		return pr, fmt.Errorf("failed to list the statuses of merge request %s: %w", mr.References.Full, err)
+	}
+	commit := Commit{OID: pr.HeadRefOID}
+	for _, status := range statuses {
+		commit.Status.Contexts = append(commit.Status.Contexts, Context{
+			Context:     githubql.String(status.Name),
+			Description: githubql.String(status.Description),
+			State:       githubql.StatusState(strings.ToUpper(githubState(status.Status))),
+		})
+	}
+	pr.Commits.Nodes = []struct{ Commit Commit }{{Commit: commit}}
+	return pr, nil
+}
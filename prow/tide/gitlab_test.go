@@ -0,0 +1,313 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/gitlab"
+)
+
+type fakeGitLabClient struct {
+	gitlab.Client
+
+	mrs       map[string][]gitlab.MergeRequest
+	listErrs  map[string]error
+	approved  map[int]bool
+	statuses  map[string][]gitlab.CommitStatus
+	mergeErr  error
+	merged    []int
+	trained   []int
+	listOpts  []gitlab.ListMergeRequestsOptions
+	squashed  []int
+	rebased   []int
+	setStatus []gitlab.CommitStatus
+}
+
+func (f *fakeGitLabClient) ListGroupMergeRequests(group string, opts gitlab.ListMergeRequestsOptions) ([]gitlab.MergeRequest, error) {
+	f.listOpts = append(f.listOpts, opts)
+	if err := f.listErrs[group]; err != nil {
+		return nil, err
+	}
+	return f.mrs[group], nil
+}
+
+func (f *fakeGitLabClient) ListProjectMergeRequests(project string, opts gitlab.ListMergeRequestsOptions) ([]gitlab.MergeRequest, error) {
+	f.listOpts = append(f.listOpts, opts)
+	if err := f.listErrs[project]; err != nil {
+		return nil, err
+	}
+	return f.mrs[project], nil
+}
+
+func (f *fakeGitLabClient) GetMergeRequestApprovals(project string, iid int) (*gitlab.MergeRequestApprovals, error) {
+	return &gitlab.MergeRequestApprovals{Approved: f.approved[iid]}, nil
+}
+
+func (f *fakeGitLabClient) ListCommitStatuses(project, sha string) ([]gitlab.CommitStatus, error) {
+	return f.statuses[sha], nil
+}
+
+func (f *fakeGitLabClient) SetCommitStatus(project, sha string, status gitlab.CommitStatus) error {
+	f.setStatus = append(f.setStatus, status)
+	return nil
+}
+
+func (f *fakeGitLabClient) AcceptMergeRequest(project string, iid int, opts gitlab.AcceptMergeRequestOptions) error {
+	if f.mergeErr != nil {
+		return f.mergeErr
+	}
+	f.merged = append(f.merged, iid)
+	if opts.Squash {
+		f.squashed = append(f.squashed, iid)
+	}
+	return nil
+}
+
+func (f *fakeGitLabClient) AddToMergeTrain(project string, iid int, sha string) error {
+	f.trained = append(f.trained, iid)
+	return nil
+}
+
+func (f *fakeGitLabClient) RebaseMergeRequest(project string, iid int) error {
+	f.rebased = append(f.rebased, iid)
+	return nil
+}
+
+func newTestGitLabProvider(glc gitlab.Client, tide config.Tide) *gitlabProvider {
+	cfg := &config.Config{ProwConfig: config.ProwConfig{Tide: tide}}
+	return newGitLabProvider(glc, func() *config.Config { return cfg }, logrus.WithField("component", "tide"))
+}
+
+func TestSplitQuery(t *testing.T) {
+	gl := &config.TideGitLab{Orgs: []string{"gl"}}
+	testCases := []struct {
+		name           string
+		query          config.TideQuery
+		expectedGitHub *config.TideQuery
+		expectedGitLab *config.TideQuery
+	}{
+		{
+			name:           "GitHub only",
+			query:          config.TideQuery{Orgs: []string{"gh"}, Labels: []string{"lgtm"}},
+			expectedGitHub: &config.TideQuery{Orgs: []string{"gh"}, Labels: []string{"lgtm"}},
+		},
+		{
+			name:           "GitLab only",
+			query:          config.TideQuery{Repos: []string{"gl/repo"}, Labels: []string{"lgtm"}},
+			expectedGitLab: &config.TideQuery{Repos: []string{"gl/repo"}, Labels: []string{"lgtm"}},
+		},
+		{
+			name:           "mixed",
+			query:          config.TideQuery{Orgs: []string{"gh", "gl"}, Repos: []string{"other/repo"}},
+			expectedGitHub: &config.TideQuery{Orgs: []string{"gh"}, Repos: []string{"other/repo"}},
+			expectedGitLab: &config.TideQuery{Orgs: []string{"gl"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gh, glq := splitQuery(tc.query, gl)
+			if diff := cmp.Diff(tc.expectedGitHub, gh); diff != "" {
+				t.Errorf("GitHub query differs from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedGitLab, glq); diff != "" {
+				t.Errorf("GitLab query differs from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestGitLabSearch(t *testing.T) {
+	glc := &fakeGitLabClient{
+		mrs: map[string][]gitlab.MergeRequest{
+			"gl": {
+				{IID: 1, SHA: "sha-1", TargetBranch: "master", Labels: []string{"lgtm"}, MergeStatus: gitlab.MergeStatusCanBeMerged, References: gitlab.References{Full: "gl/repo!1"}},
+				{IID: 2, SHA: "sha-2", TargetBranch: "release", References: gitlab.References{Full: "gl/repo!2"}},
+				{IID: 3, SHA: "sha-3", TargetBranch: "master", References: gitlab.References{Full: "gl/excluded!3"}},
+				{IID: 4, SHA: "sha-4", TargetBranch: "master", References: gitlab.References{Full: "gl/repo!4"}},
+			},
+		},
+		approved: map[int]bool{1: true, 2: true, 3: true},
+		statuses: map[string][]gitlab.CommitStatus{
+			"sha-1": {{Name: "unit", Status: gitlab.StatusSuccess}, {Name: "e2e", Status: gitlab.StatusRunning}},
+		},
+	}
+	p := newTestGitLabProvider(glc, config.Tide{})
+
+	prs, err := p.search(config.TideQuery{
+		Orgs:                   []string{"gl"},
+		ExcludedRepos:          []string{"gl/excluded"},
+		Labels:                 []string{"lgtm"},
+		MissingLabels:          []string{"hold"},
+		IncludedBranches:       []string{"master"},
+		ReviewApprovedRequired: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]gitlab.ListMergeRequestsOptions{{Labels: []string{"lgtm"}, NotLabels: []string{"hold"}}}, glc.listOpts); diff != "" {
+		t.Errorf("list options differ from expected: %s", diff)
+	}
+	if diff := cmp.Diff([]int{1}, prNumbers(prs)); diff != "" {
+		t.Fatalf("merge requests differ from expected: %s", diff)
+	}
+
+	var expected PullRequest
+	expected.Number = 1
+	expected.BaseRef.Name = "master"
+	expected.BaseRef.Prefix = "refs/heads/"
+	expected.HeadRefOID = "sha-1"
+	expected.Mergeable = githubql.MergeableStateMergeable
+	expected.CanBeRebased = true
+	expected.Repository.Name = "repo"
+	expected.Repository.NameWithOwner = "gl/repo"
+	expected.Repository.Owner.Login = "gl"
+	expected.ReviewDecision = githubql.PullRequestReviewDecisionApproved
+	expected.Labels.Nodes = []struct{ Name githubql.String }{{Name: "lgtm"}}
+	expected.Commits.Nodes = []struct{ Commit Commit }{{Commit: Commit{
+		OID: "sha-1",
+		Status: CommitStatus{Contexts: []Context{
+			{Context: "unit", State: githubql.StatusStateSuccess},
+			{Context: "e2e", State: githubql.StatusStatePending},
+		}},
+	}}}
+	if diff := cmp.Diff(expected, prs[0]); diff != "" {
+		t.Errorf("pull request differs from expected: %s", diff)
+	}
+}
+
+func TestGitLabSearchPartialResults(t *testing.T) {
+	glc := &fakeGitLabClient{
+		mrs: map[string][]gitlab.MergeRequest{
+			"gl": {
+				{IID: 1, SHA: "sha-1", TargetBranch: "master", References: gitlab.References{Full: "gl/repo!1"}},
+				{IID: 2, SHA: "sha-2", TargetBranch: "master", References: gitlab.References{Full: "gl/sub/repo!2"}},
+			},
+			"gl/other": {
+				{IID: 3, SHA: "sha-3", TargetBranch: "master", References: gitlab.References{Full: "gl/other!3"}},
+			},
+		},
+		listErrs: map[string]error{
+			"broken":         errors.New("injected error"),
+			"gl/unreachable": errors.New("injected error"),
+		},
+	}
+	p := newTestGitLabProvider(glc, config.Tide{})
+
+	prs, err := p.search(config.TideQuery{
+		Orgs:  []string{"gl", "broken"},
+		Repos: []string{"gl/other", "gl/unreachable"},
+	})
+	if err == nil {
+		t.Error("expected an error, got none")
+	}
+	if diff := cmp.Diff([]int{1, 3}, prNumbers(prs)); diff != "" {
+		t.Errorf("merge requests differ from expected: %s", diff)
+	}
+}
+
+func TestGitLabMerge(t *testing.T) {
+	testCases := []struct {
+		name        string
+		mergeTrains map[string]bool
+		method      github.PullRequestMergeType
+		mergeErr    error
+
+		expectedMerged   []int
+		expectedSquashed []int
+		expectedTrained  []int
+		expectedErr      error
+	}{
+		{
+			name:           "merge",
+			method:         github.MergeMerge,
+			expectedMerged: []int{1},
+		},
+		{
+			name:             "squash",
+			method:           github.MergeSquash,
+			expectedMerged:   []int{1},
+			expectedSquashed: []int{1},
+		},
+		{
+			name:            "merge train",
+			mergeTrains:     map[string]bool{"gl": true},
+			method:          github.MergeMerge,
+			expectedTrained: []int{1},
+		},
+		{
+			name:        "changed head is translated",
+			method:      github.MergeMerge,
+			mergeErr:    &gitlab.RequestError{StatusCode: http.StatusConflict, Message: "SHA does not match"},
+			expectedErr: github.ModifiedHeadError("SHA does not match"),
+		},
+		{
+			name:        "unmergeable merge request is translated",
+			method:      github.MergeMerge,
+			mergeErr:    &gitlab.RequestError{StatusCode: http.StatusMethodNotAllowed, Message: "Method Not Allowed"},
+			expectedErr: github.UnmergablePRError("Method Not Allowed"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			glc := &fakeGitLabClient{mergeErr: tc.mergeErr}
+			p := newTestGitLabProvider(glc, config.Tide{GitLab: config.TideGitLab{Orgs: []string{"gl"}, MergeTrainsMap: tc.mergeTrains}})
+			err := p.Merge("gl", "repo", 1, github.MergeDetails{SHA: "sha-1", MergeMethod: string(tc.method)})
+			if err != tc.expectedErr {
+				t.Errorf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if diff := cmp.Diff(tc.expectedMerged, glc.merged); diff != "" {
+				t.Errorf("merged merge requests differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedSquashed, glc.squashed); diff != "" {
+				t.Errorf("squashed merge requests differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedTrained, glc.trained); diff != "" {
+				t.Errorf("merge requests added to merge trains differ from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestProviderClient(t *testing.T) {
+	glc := &fakeGitLabClient{}
+	ghc := &fgc{}
+	pc := &providerClient{
+		githubClient: ghc,
+		gitlab:       newTestGitLabProvider(glc, config.Tide{GitLab: config.TideGitLab{Orgs: []string{"gl"}}}),
+	}
+	if err := pc.CreateStatus("gl", "repo", "sha", github.Status{Context: "tide", State: github.StatusFailure}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := pc.CreateStatus("gh", "repo", "sha", github.Status{Context: "tide", State: github.StatusSuccess}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]gitlab.CommitStatus{{Name: "tide", Status: gitlab.StatusFailed}}, glc.setStatus); diff != "" {
+		t.Errorf("GitLab statuses differ from expected: %s", diff)
+	}
+	if _, ok := ghc.statuses["gh/repo/sha"]; !ok || len(ghc.statuses) != 1 {
+		t.Errorf("expected only the GitHub status to be set on GitHub, got %v", ghc.statuses)
+	}
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"time"
+
+	githubql "github.com/shurcooL/githubv4"
+)
+
+// isWithinGracePeriod returns whether the context is pending and was created
+// less than the grace period ago, so that Tide can assume that it will pass.
+func isWithinGracePeriod(ctx Context, gracePeriod time.Duration, now time.Time) bool {
+	if gracePeriod <= 0 || ctx.State != githubql.StatusStatePending || ctx.CreatedAt.IsZero() {
+		return false
+	}
+	return now.Sub(ctx.CreatedAt.Time) < gracePeriod
+}
+
+// optimisticPRs returns the numbers of the PRs that are only in the pool
+// because their pending contexts are within the grace period. They must not
+// be merged.
+func (sp subpool) optimisticPRs(prs []PullRequest) []int {
+	var numbers []int
+	for _, pr := range prs {
+		if sp.optimistic.Has(int(pr.Number)) {
+			numbers = append(numbers, int(pr.Number))
+		}
+	}
+	return numbers
+}
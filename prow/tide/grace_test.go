@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+func TestIsWithinGracePeriod(t *testing.T) {
+	now := time.Now()
+	createdAt := func(ago time.Duration) githubql.DateTime {
+		return githubql.DateTime{Time: now.Add(-ago)}
+	}
+	testCases := []struct {
+		name        string
+		ctx         Context
+		gracePeriod time.Duration
+		expected    bool
+	}{
+		{
+			name:        "recent pending context",
+			ctx:         Context{State: githubql.StatusStatePending, CreatedAt: createdAt(time.Minute)},
+			gracePeriod: time.Hour,
+			expected:    true,
+		},
+		{
+			name:        "old pending context",
+			ctx:         Context{State: githubql.StatusStatePending, CreatedAt: createdAt(2 * time.Hour)},
+			gracePeriod: time.Hour,
+		},
+		{
+			name:        "recent failed context",
+			ctx:         Context{State: githubql.StatusStateFailure, CreatedAt: createdAt(time.Minute)},
+			gracePeriod: time.Hour,
+		},
+		{
+			name:        "unknown creation time",
+			ctx:         Context{State: githubql.StatusStatePending},
+			gracePeriod: time.Hour,
+		},
+		{
+			name: "no grace period",
+			ctx:  Context{State: githubql.StatusStatePending, CreatedAt: createdAt(time.Minute)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isWithinGracePeriod(tc.ctx, tc.gracePeriod, now); got != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFilterSubpoolPendingGracePeriod(t *testing.T) {
+	now := time.Now()
+	gracePRWithContext := func(number int, ctx Context) PullRequest {
+		pr := PullRequest{Number: githubql.Int(number)}
+		pr.Commits.Nodes = []struct{ Commit Commit }{{
+			Commit{Status: struct{ Contexts []Context }{Contexts: []Context{ctx}}},
+		}}
+		return pr
+	}
+	cc := &config.TideContextPolicy{RequiredContexts: []string{"external"}}
+	sp := &subpool{
+		org:                "org",
+		repo:               "repo",
+		branch:             "branch",
+		cc:                 map[int]contextChecker{1: cc, 2: cc, 3: cc},
+		log:                logrus.WithField("component", "tide"),
+		pendingGracePeriod: time.Hour,
+		prs: []PullRequest{
+			gracePRWithContext(1, Context{Context: "external", State: githubql.StatusStateSuccess}),
+			gracePRWithContext(2, Context{Context: "external", State: githubql.StatusStatePending, CreatedAt: githubql.DateTime{Time: now.Add(-time.Minute)}}),
+			gracePRWithContext(3, Context{Context: "external", State: githubql.StatusStatePending, CreatedAt: githubql.DateTime{Time: now.Add(-2 * time.Hour)}}),
+		},
+	}
+	mergeAllowed := func(*PullRequest) (string, error) { return "", nil }
+
+	filtered := filterSubpool(nil, mergeAllowed, sp)
+	if diff := cmp.Diff([]int{1, 2}, prNumbers(filtered.prs)); diff != "" {
+		t.Errorf("filtered PRs differ from expected: %s", diff)
+	}
+	if diff := cmp.Diff([]int{2}, filtered.optimisticPRs(filtered.prs)); diff != "" {
+		t.Errorf("optimistic PRs differ from expected: %s", diff)
+	}
+}
+
+func TestTakeActionOptimisticBatch(t *testing.T) {
+	sp := subpool{
+		org:        "org",
+		repo:       "repo",
+		branch:     "branch",
+		log:        logrus.WithField("component", "tide"),
+		optimistic: sets.NewInt(2),
+	}
+	batch := []PullRequest{{Number: 1}, {Number: 2}}
+	c := &Controller{}
+	act, targets, err := c.takeAction(sp, nil, nil, nil, nil, batch, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if act != Wait || len(targets) != 0 {
+		t.Errorf("expected to wait instead of merging the batch, got %s of %v", act, prNumbers(targets))
+	}
+}
@@ -45,8 +45,9 @@ type History struct {
 	sync.Mutex
 	logSizeLimit int
 
-	opener opener
-	path   string
+	// store persists the history, nil if it is only kept in memory.
+	store Store
+	path  string
 }
 
 // opener has methods to read and write paths
@@ -55,10 +56,10 @@ type opener interface {
 	Writer(ctx context.Context, path string, opts ...io.WriterOptions) (io.WriteCloser, error)
 }
 
-func readHistory(maxRecordsPerKey int, opener opener, path string) (map[string]*recordLog, error) {
+func readRecords(opener opener, path string) (map[string][]*Record, error) {
 	reader, err := opener.Reader(context.Background(), path)
 	if io.IsNotExist(err) { // No history exists yet. This is not an error.
-		return map[string]*recordLog{}, nil
+		return map[string][]*Record{}, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("open: %w", err)
@@ -72,6 +73,14 @@ func readHistory(maxRecordsPerKey int, opener opener, path string) (map[string]*
 	if err := json.Unmarshal(raw, &recordsByPool); err != nil {
 		return nil, fmt.Errorf("unmarshal: %w", err)
 	}
+	return recordsByPool, nil
+}
+
+func readHistory(maxRecordsPerKey int, store Store) (map[string]*recordLog, error) {
+	recordsByPool, err := store.Query(Query{Limit: maxRecordsPerKey})
+	if err != nil {
+		return nil, err
+	}
 
 	// Load records into a new recordLog map.
 	logsByPool := make(map[string]*recordLog, len(recordsByPool))
@@ -123,19 +132,26 @@ type Record struct {
 }
 
 // New creates a new History struct with the specificed recordLog size limit.
+// The history is persisted to the path if it is set: a postgres:// URI stores
+// every record in a Postgres database and a sqlite:// URI, like
+// sqlite:///var/lib/tide/history.db, in a SQLite database file, any other path stores the most recent
+// records of each pool in a GCS, S3 or local file.
 func New(maxRecordsPerKey int, opener io.Opener, path string) (*History, error) {
 	hist := &History{
 		logs:         map[string]*recordLog{},
 		logSizeLimit: maxRecordsPerKey,
-		opener:       opener,
 		path:         path,
 	}
 
 	if path != "" {
-		// Load existing history from GCS.
+		// Load existing history from the store.
 		var err error
+		hist.store, err = newStore(opener, path)
+		if err != nil {
+			return nil, err
+		}
 		start := time.Now()
-		hist.logs, err = readHistory(maxRecordsPerKey, hist.opener, hist.path)
+		hist.logs, err = readHistory(maxRecordsPerKey, hist.store)
 		if err != nil {
 			return nil, err
 		}
@@ -152,17 +168,20 @@ func New(maxRecordsPerKey int, opener io.Opener, path string) (*History, error)
 func (h *History) Record(poolKey, action, baseSHA, err string, targets []prowapi.Pull, tenantIDs []string) {
 	t := now()
 	sort.Sort(ByNum(targets))
-	h.addRecord(
-		poolKey,
-		&Record{
-			Time:      t,
-			Action:    action,
-			BaseSHA:   baseSHA,
-			Target:    targets,
-			Err:       err,
-			TenantIDs: tenantIDs,
-		},
-	)
+	rec := &Record{
+		Time:      t,
+		Action:    action,
+		BaseSHA:   baseSHA,
+		Target:    targets,
+		Err:       err,
+		TenantIDs: tenantIDs,
+	}
+	h.addRecord(poolKey, rec)
+	if h.store != nil {
+		if err := h.store.Add(poolKey, rec); err != nil {
+			logrus.WithError(err).WithField("pool", poolKey).Error("Error storing action record.")
+		}
+	}
 }
 
 func (h *History) addRecord(poolKey string, rec *Record) {
@@ -175,8 +194,23 @@ func (h *History) addRecord(poolKey string, rec *Record) {
 }
 
 // ServeHTTP serves a JSON mapping from pool key -> sorted records for the pool.
+// The records are those kept in memory, unless the request has query parameters
+// selecting records, see parseQuery.
 func (h *History) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	b, err := json.Marshal(h.AllRecords())
+	records := h.AllRecords()
+	if params := r.URL.Query(); len(params) > 0 {
+		q, err := parseQuery(params)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if records, err = h.Query(q); err != nil {
+			logrus.WithError(err).Error("Querying history.")
+			http.Error(w, "failed to query history", http.StatusInternalServerError)
+			return
+		}
+	}
+	b, err := json.Marshal(records)
 	if err != nil {
 		logrus.WithError(err).Error("Encoding JSON history.")
 		b = []byte("{}")
@@ -188,23 +222,32 @@ func (h *History) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // Flush writes the action history to persistent storage if configured to do so.
 func (h *History) Flush() {
-	if h.path == "" {
+	if h.store == nil {
 		return
 	}
 	records := h.AllRecords()
 	start := time.Now()
-	err := writeHistory(h.opener, h.path, records)
+	err := h.store.Flush(records)
 	log := logrus.WithFields(logrus.Fields{
 		"duration": time.Since(start).String(),
 		"path":     h.path,
 	})
 	if err != nil {
-		log.WithError(err).Error("Error flushing action history to GCS.")
+		log.WithError(err).Error("Error flushing action history.")
 	} else {
 		log.Debugf("Successfully flushed action history for %d pools.", len(h.logs))
 	}
 }
 
+// Query returns the records matching the query from the store, or from memory
+// if the history is not persisted.
+func (h *History) Query(q Query) (map[string][]*Record, error) {
+	if h.store == nil {
+		return q.filter(h.AllRecords()), nil
+	}
+	return h.store.Query(q)
+}
+
 // AllRecords generates a map from pool key -> sorted records for the pool.
 func (h *History) AllRecords() map[string][]*Record {
 	h.Lock()
@@ -262,7 +262,7 @@ func TestReadHistory(t *testing.T) {
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 			obj := &testOpener{content: tc.raw, dne: tc.dne}
-			hist, err := readHistory(tc.maxRecsPerPool, obj, fakePath)
+			hist, err := readHistory(tc.maxRecsPerPool, &objectStore{opener: obj, path: fakePath})
 			if err != nil {
 				t.Fatalf("Unexpected error reading history: %v.", err)
 			}
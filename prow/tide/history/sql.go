@@ -0,0 +1,191 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	// Registers the postgres driver.
+	_ "github.com/lib/pq"
+	// Registers the sqlite3 driver.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	sqlTimeout = 30 * time.Second
+
+	createTableStatement = `CREATE TABLE IF NOT EXISTS tide_history (
+	pool TEXT NOT NULL,
+	time %s NOT NULL,
+	action TEXT NOT NULL,
+	base_sha TEXT NOT NULL,
+	target TEXT NOT NULL,
+	err TEXT NOT NULL,
+	tenant_ids TEXT NOT NULL
+)`
+	createIndexStatement = `CREATE INDEX IF NOT EXISTS tide_history_pool_time ON tide_history (pool, time)`
+	insertStatement      = `INSERT INTO tide_history (pool, time, action, base_sha, target, err, tenant_ids) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	recordColumns        = `pool, time, action, base_sha, target, err, tenant_ids`
+
+	// sqliteTimeFormat has a fixed width, so that the times of SQLite, which
+	// are text, are compared and sorted like times.
+	sqliteTimeFormat = "2006-01-02 15:04:05.000000000-07:00"
+)
+
+// sqlDialect is what differs between the SQL databases of the history.
+type sqlDialect struct {
+	driver string
+	// timeType is the column type of the times of the records.
+	timeType string
+	// timeArg converts times to arguments of statements.
+	timeArg func(time.Time) interface{}
+	// maxOpenConns limits the connections to the database, 0 is unlimited.
+	maxOpenConns int
+}
+
+var (
+	postgresDialect = sqlDialect{
+		driver:   "postgres",
+		timeType: "TIMESTAMP WITH TIME ZONE",
+		timeArg:  func(t time.Time) interface{} { return t },
+	}
+	// sqliteDialect stores times as UTC text, which the driver parses back
+	// into times for TIMESTAMP columns. A single connection avoids failing
+	// writes to a locked database file.
+	sqliteDialect = sqlDialect{
+		driver:       "sqlite3",
+		timeType:     "TIMESTAMP",
+		timeArg:      func(t time.Time) interface{} { return t.UTC().Format(sqliteTimeFormat) },
+		maxOpenConns: 1,
+	}
+)
+
+// sqlStore stores every record in a SQL database. It needs no flushing.
+type sqlStore struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+func newSQLStore(dialect sqlDialect, dataSource string) (*sqlStore, error) {
+	db, err := sql.Open(dialect.driver, dataSource)
+	if err != nil {
+		return nil, fmt.Errorf("open %s database: %w", dialect.driver, err)
+	}
+	db.SetMaxOpenConns(dialect.maxOpenConns)
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+	for _, statement := range []string{fmt.Sprintf(createTableStatement, dialect.timeType), createIndexStatement} {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("create history table: %w", err)
+		}
+	}
+	return &sqlStore{db: db, dialect: dialect}, nil
+}
+
+func (s *sqlStore) Add(poolKey string, rec *Record) error {
+	target, err := json.Marshal(rec.Target)
+	if err != nil {
+		return fmt.Errorf("marshal target: %w", err)
+	}
+	tenantIDs, err := json.Marshal(rec.TenantIDs)
+	if err != nil {
+		return fmt.Errorf("marshal tenant IDs: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, insertStatement, poolKey, s.dialect.timeArg(rec.Time), rec.Action, rec.BaseSHA, string(target), rec.Err, string(tenantIDs)); err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+	return nil
+}
+
+func (s *sqlStore) Flush(map[string][]*Record) error {
+	// Records are written as they are added.
+	return nil
+}
+
+func (s *sqlStore) Query(q Query) (map[string][]*Record, error) {
+	statement, args := s.dialect.selectStatement(q)
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer rows.Close()
+
+	res := make(map[string][]*Record)
+	for rows.Next() {
+		var poolKey, target, tenantIDs string
+		rec := &Record{}
+		if err := rows.Scan(&poolKey, &rec.Time, &rec.Action, &rec.BaseSHA, &target, &rec.Err, &tenantIDs); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		if err := json.Unmarshal([]byte(target), &rec.Target); err != nil {
+			return nil, fmt.Errorf("unmarshal target: %w", err)
+		}
+		if err := json.Unmarshal([]byte(tenantIDs), &rec.TenantIDs); err != nil {
+			return nil, fmt.Errorf("unmarshal tenant IDs: %w", err)
+		}
+		res[poolKey] = append(res[poolKey], rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	return res, nil
+}
+
+// selectStatement returns the statement selecting the records matching the
+// query, sorted by pool and newest first, and its arguments.
+func (d sqlDialect) selectStatement(q Query) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if q.PoolKey != "" {
+		where("pool = $%d", q.PoolKey)
+	}
+	if q.Action != "" {
+		where("action = $%d", q.Action)
+	}
+	if !q.Since.IsZero() {
+		where("time >= $%d", d.timeArg(q.Since))
+	}
+	if !q.Until.IsZero() {
+		where("time < $%d", d.timeArg(q.Until))
+	}
+	var whereClause string
+	if len(conditions) > 0 {
+		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	if q.Limit <= 0 {
+		return "SELECT " + recordColumns + " FROM tide_history" + whereClause + " ORDER BY pool, time DESC", args
+	}
+	// Number the records of each pool to keep the newest of them.
+	args = append(args, q.Limit)
+	return fmt.Sprintf("SELECT %s FROM (SELECT %s, ROW_NUMBER() OVER (PARTITION BY pool ORDER BY time DESC) AS n FROM tide_history%s) AS records WHERE n <= $%d ORDER BY pool, time DESC",
+		recordColumns, recordColumns, whereClause, len(args)), args
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store persists the action history of Tide.
+type Store interface {
+	// Add stores the record of an action taken for a pool.
+	Add(poolKey string, rec *Record) error
+	// Flush stores the records kept in memory, newest first.
+	Flush(records map[string][]*Record) error
+	// Query returns the stored records matching the query, newest first.
+	Query(q Query) (map[string][]*Record, error)
+}
+
+func newStore(opener opener, path string) (Store, error) {
+	if strings.HasPrefix(path, "postgres://") || strings.HasPrefix(path, "postgresql://") {
+		return newSQLStore(postgresDialect, path)
+	}
+	if strings.HasPrefix(path, "sqlite://") {
+		return newSQLStore(sqliteDialect, strings.TrimPrefix(path, "sqlite://"))
+	}
+	return &objectStore{opener: opener, path: path}, nil
+}
+
+// Query selects records of the history. Its zero value selects all records.
+type Query struct {
+	// PoolKey selects the records of a single pool.
+	PoolKey string
+	// Action selects the records of an action, e.g. MERGE.
+	Action string
+	// Since and Until select the records of the actions taken at or after
+	// Since and before Until.
+	Since time.Time
+	Until time.Time
+	// Limit is the maximum number of records per pool, the newest are kept.
+	Limit int
+}
+
+// parseQuery parses the query parameters of a history request: pool, action,
+// since and until as RFC 3339 times, and limit.
+func parseQuery(params url.Values) (Query, error) {
+	q := Query{
+		PoolKey: params.Get("pool"),
+		Action:  params.Get("action"),
+	}
+	var err error
+	if since := params.Get("since"); since != "" {
+		if q.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return Query{}, fmt.Errorf("invalid since: %w", err)
+		}
+	}
+	if until := params.Get("until"); until != "" {
+		if q.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return Query{}, fmt.Errorf("invalid until: %w", err)
+		}
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 0 {
+			return Query{}, fmt.Errorf("invalid limit %q, must be a non-negative integer", limit)
+		}
+	}
+	return q, nil
+}
+
+func (q Query) matches(poolKey string, rec *Record) bool {
+	if q.PoolKey != "" && poolKey != q.PoolKey {
+		return false
+	}
+	if q.Action != "" && rec.Action != q.Action {
+		return false
+	}
+	if !q.Since.IsZero() && rec.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !rec.Time.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// filter returns the records matching the query. The records of each pool must
+// be sorted newest first.
+func (q Query) filter(records map[string][]*Record) map[string][]*Record {
+	res := make(map[string][]*Record)
+	for poolKey, recs := range records {
+		var matching []*Record
+		for _, rec := range recs {
+			if q.Limit > 0 && len(matching) == q.Limit {
+				break
+			}
+			if q.matches(poolKey, rec) {
+				matching = append(matching, rec)
+			}
+		}
+		if len(matching) > 0 {
+			res[poolKey] = matching
+		}
+	}
+	return res
+}
+
+// objectStore stores the most recent records of each pool in a single GCS, S3
+// or local file, which is overwritten when the history is flushed.
+type objectStore struct {
+	opener opener
+	path   string
+}
+
+func (s *objectStore) Add(string, *Record) error {
+	// Records are written when the history is flushed.
+	return nil
+}
+
+func (s *objectStore) Flush(records map[string][]*Record) error {
+	return writeHistory(s.opener, s.path, records)
+}
+
+func (s *objectStore) Query(q Query) (map[string][]*Record, error) {
+	records, err := readRecords(s.opener, s.path)
+	if err != nil {
+		return nil, err
+	}
+	return q.filter(records), nil
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+func TestParseQuery(t *testing.T) {
+	since := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	tcs := []struct {
+		name        string
+		params      url.Values
+		expected    Query
+		expectedErr bool
+	}{
+		{
+			name:     "no parameters",
+			params:   url.Values{},
+			expected: Query{},
+		},
+		{
+			name: "all parameters",
+			params: url.Values{
+				"pool":   {"o/r:b"},
+				"action": {"MERGE"},
+				"since":  {"2021-10-01T00:00:00Z"},
+				"until":  {"2021-10-02T00:00:00Z"},
+				"limit":  {"10"},
+			},
+			expected: Query{PoolKey: "o/r:b", Action: "MERGE", Since: since, Until: since.Add(24 * time.Hour), Limit: 10},
+		},
+		{
+			name:        "invalid time",
+			params:      url.Values{"since": {"yesterday"}},
+			expectedErr: true,
+		},
+		{
+			name:        "negative limit",
+			params:      url.Values{"limit": {"-1"}},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := parseQuery(tc.params)
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("Expected error: %t, got: %v.", tc.expectedErr, err)
+			}
+			if !reflect.DeepEqual(q, tc.expected) {
+				t.Errorf("Expected query %+v, but got %+v.", tc.expected, q)
+			}
+		})
+	}
+}
+
+func TestQueryFilter(t *testing.T) {
+	start := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return start.Add(time.Duration(hours) * time.Hour) }
+	records := map[string][]*Record{
+		"o/r:a": {
+			{Time: at(3), Action: "MERGE"},
+			{Time: at(2), Action: "TRIGGER"},
+			{Time: at(1), Action: "MERGE"},
+		},
+		"o/r:b": {
+			{Time: at(2), Action: "TRIGGER_BATCH"},
+		},
+	}
+
+	tcs := []struct {
+		name     string
+		query    Query
+		expected map[string][]*Record
+	}{
+		{
+			name:     "all records",
+			query:    Query{},
+			expected: records,
+		},
+		{
+			name:     "pool",
+			query:    Query{PoolKey: "o/r:b"},
+			expected: map[string][]*Record{"o/r:b": records["o/r:b"]},
+		},
+		{
+			name:     "action",
+			query:    Query{Action: "MERGE"},
+			expected: map[string][]*Record{"o/r:a": {records["o/r:a"][0], records["o/r:a"][2]}},
+		},
+		{
+			name:  "time range",
+			query: Query{Since: at(2), Until: at(3)},
+			expected: map[string][]*Record{
+				"o/r:a": {records["o/r:a"][1]},
+				"o/r:b": records["o/r:b"],
+			},
+		},
+		{
+			name:  "limit keeps the newest records",
+			query: Query{Limit: 2},
+			expected: map[string][]*Record{
+				"o/r:a": records["o/r:a"][:2],
+				"o/r:b": records["o/r:b"],
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.query.filter(records); !reflect.DeepEqual(got, tc.expected) {
+				es, _ := json.Marshal(tc.expected)
+				gs, _ := json.Marshal(got)
+				t.Errorf("Expected records \n%s, but got \n%s.", es, gs)
+			}
+		})
+	}
+}
+
+func TestSelectStatement(t *testing.T) {
+	since := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	tcs := []struct {
+		name              string
+		dialect           *sqlDialect
+		query             Query
+		expectedStatement string
+		expectedArgs      []interface{}
+	}{
+		{
+			name:              "all records",
+			query:             Query{},
+			expectedStatement: "SELECT pool, time, action, base_sha, target, err, tenant_ids FROM tide_history ORDER BY pool, time DESC",
+		},
+		{
+			name:              "pool and time",
+			query:             Query{PoolKey: "o/r:b", Since: since},
+			expectedStatement: "SELECT pool, time, action, base_sha, target, err, tenant_ids FROM tide_history WHERE pool = $1 AND time >= $2 ORDER BY pool, time DESC",
+			expectedArgs:      []interface{}{"o/r:b", since},
+		},
+		{
+			name:              "action with limit",
+			query:             Query{Action: "MERGE", Limit: 5},
+			expectedStatement: "SELECT pool, time, action, base_sha, target, err, tenant_ids FROM (SELECT pool, time, action, base_sha, target, err, tenant_ids, ROW_NUMBER() OVER (PARTITION BY pool ORDER BY time DESC) AS n FROM tide_history WHERE action = $1) AS records WHERE n <= $2 ORDER BY pool, time DESC",
+			expectedArgs:      []interface{}{"MERGE", 5},
+		},
+		{
+			name:              "SQLite times are UTC text",
+			dialect:           &sqliteDialect,
+			query:             Query{Since: since.In(time.FixedZone("CEST", 2*60*60))},
+			expectedStatement: "SELECT pool, time, action, base_sha, target, err, tenant_ids FROM tide_history WHERE time >= $1 ORDER BY pool, time DESC",
+			expectedArgs:      []interface{}{"2021-10-01 00:00:00.000000000+00:00"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			dialect := postgresDialect
+			if tc.dialect != nil {
+				dialect = *tc.dialect
+			}
+			statement, args := dialect.selectStatement(tc.query)
+			if statement != tc.expectedStatement {
+				t.Errorf("Expected statement:\n%s\nbut got:\n%s", tc.expectedStatement, statement)
+			}
+			if !reflect.DeepEqual(args, tc.expectedArgs) {
+				t.Errorf("Expected arguments %v, but got %v.", tc.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestSQLiteStore(t *testing.T) {
+	store, err := newStore(nil, "sqlite://"+filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	start := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i, rec := range []*Record{
+		{Time: start, Action: "TRIGGER", BaseSHA: "sha", TenantIDs: []string{"tenant"}},
+		{Time: start.Add(time.Second), Action: "MERGE", BaseSHA: "sha", Target: []prowapi.Pull{{Number: 1, SHA: "head"}}},
+		{Time: start.Add(1500 * time.Millisecond), Action: "MERGE", BaseSHA: "sha", Err: "failed"},
+	} {
+		poolKey := "o/r:a"
+		if i == 0 {
+			poolKey = "o/r:b"
+		}
+		if err := store.Add(poolKey, rec); err != nil {
+			t.Fatalf("Failed to add record: %v", err)
+		}
+	}
+
+	tcs := []struct {
+		name            string
+		query           Query
+		expectedActions map[string][]string
+		expectedTimes   []time.Time
+	}{
+		{
+			name:            "all records newest first",
+			expectedActions: map[string][]string{"o/r:a": {"MERGE", "MERGE"}, "o/r:b": {"TRIGGER"}},
+		},
+		{
+			name:            "records since a time",
+			query:           Query{Since: start.Add(time.Second)},
+			expectedActions: map[string][]string{"o/r:a": {"MERGE", "MERGE"}},
+		},
+		{
+			name:            "newest record of each pool",
+			query:           Query{Limit: 1},
+			expectedActions: map[string][]string{"o/r:a": {"MERGE"}, "o/r:b": {"TRIGGER"}},
+			expectedTimes:   []time.Time{start.Add(1500 * time.Millisecond)},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			records, err := store.Query(tc.query)
+			if err != nil {
+				t.Fatalf("Failed to query: %v", err)
+			}
+			actions := map[string][]string{}
+			for poolKey, recs := range records {
+				for _, rec := range recs {
+					actions[poolKey] = append(actions[poolKey], rec.Action)
+				}
+			}
+			if !reflect.DeepEqual(actions, tc.expectedActions) {
+				t.Errorf("Expected actions %v, but got %v.", tc.expectedActions, actions)
+			}
+			for i, expected := range tc.expectedTimes {
+				if got := records["o/r:a"][i].Time; !got.Equal(expected) {
+					t.Errorf("Expected time %v, but got %v.", expected, got)
+				}
+			}
+		})
+	}
+
+	records, err := store.Query(Query{PoolKey: "o/r:a", Action: "MERGE", Limit: 2})
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if recs := records["o/r:a"]; len(recs) != 2 || recs[0].Err != "failed" || !reflect.DeepEqual(recs[1].Target, []prowapi.Pull{{Number: 1, SHA: "head"}}) {
+		t.Errorf("Expected the stored merges, but got %+v.", recs)
+	}
+}
+
+func TestServeHTTPQuery(t *testing.T) {
+	hist, err := New(10, nil, "")
+	if err != nil {
+		t.Fatalf("Failed to create history client: %v", err)
+	}
+	hist.Record("o/r:a", "MERGE", "sha", "", nil, nil)
+	hist.Record("o/r:a", "TRIGGER", "sha", "", nil, nil)
+	hist.Record("o/r:b", "MERGE", "sha", "", nil, nil)
+
+	tcs := []struct {
+		name           string
+		query          string
+		expectedCode   int
+		expectedCounts map[string]int
+	}{
+		{
+			name:           "all records",
+			expectedCode:   http.StatusOK,
+			expectedCounts: map[string]int{"o/r:a": 2, "o/r:b": 1},
+		},
+		{
+			name:           "merges of a pool",
+			query:          "?pool=o/r:a&action=MERGE",
+			expectedCode:   http.StatusOK,
+			expectedCounts: map[string]int{"o/r:a": 1},
+		},
+		{
+			name:         "invalid query",
+			query:        "?limit=many",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			hist.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/history"+tc.query, nil))
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Expected status code %d, but got %d.", tc.expectedCode, rr.Code)
+			}
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+			var records map[string][]*Record
+			if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil {
+				t.Fatalf("Failed to unmarshal records: %v.", err)
+			}
+			counts := map[string]int{}
+			for poolKey, recs := range records {
+				counts[poolKey] = len(recs)
+			}
+			if !reflect.DeepEqual(counts, tc.expectedCounts) {
+				t.Errorf("Expected record counts %v, but got %v.", tc.expectedCounts, counts)
+			}
+		})
+	}
+}
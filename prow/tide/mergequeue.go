@@ -0,0 +1,279 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"sync"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+// missingState is the state of a merge queue entry whose speculative tests
+// were not triggered yet.
+const missingState simpleState = "missing"
+
+// evictedPRs remembers the PRs evicted from merge queues. A PR stays evicted
+// until its head changes. Entries expire if they are not used during a sync loop.
+type evictedPRs struct {
+	sync.Mutex
+	heads     map[string]string
+	nextHeads map[string]string
+}
+
+func newEvictedPRs() *evictedPRs {
+	return &evictedPRs{
+		heads:     make(map[string]string),
+		nextHeads: make(map[string]string),
+	}
+}
+
+func (e *evictedPRs) evict(pr *PullRequest) {
+	e.Lock()
+	defer e.Unlock()
+	e.nextHeads[prKey(pr)] = string(pr.HeadRefOID)
+}
+
+func (e *evictedPRs) isEvicted(pr *PullRequest) bool {
+	e.Lock()
+	defer e.Unlock()
+	key := prKey(pr)
+	head, ok := e.nextHeads[key]
+	if !ok {
+		head, ok = e.heads[key]
+	}
+	if !ok || head != string(pr.HeadRefOID) {
+		return false
+	}
+	e.nextHeads[key] = head
+	return true
+}
+
+// prune forgets the evictions that were not used since the last prune.
+func (e *evictedPRs) prune() {
+	e.Lock()
+	defer e.Unlock()
+	e.heads = e.nextHeads
+	e.nextHeads = make(map[string]string)
+}
+
+// mergeQueue returns the PRs of the subpool that are in its merge queue, highest
+// priority then oldest PR first. PRs enter the queue once all their required contexts are passing,
+// or pending on jobs triggered by Tide, unless they were evicted from it.
+func (c *Controller) mergeQueue(sp subpool) []PullRequest {
+	prs := append([]PullRequest(nil), sp.prs...)
+	sortByPriority(prs, c.config().Tide.Priority)
+
+	var queue []PullRequest
+	for _, pr := range prs {
+		if c.evictions.isEvicted(&pr) {
+			sp.log.WithFields(pr.logFields()).Debug("PR was evicted from the merge queue.")
+			continue
+		}
+		if c.isRetestEligible(sp.log, &pr, sp.cc[int(pr.Number)]) {
+			queue = append(queue, pr)
+		}
+	}
+	return queue
+}
+
+// queueDepth returns how many PRs of the merge queue are speculatively tested
+// at once. It is limited by the batch size limit of the repo. Only the head of
+// the queue is tested in repos requiring PRs to be up to date with their base
+// branch, as the PRs behind it are no longer once it merged.
+func (c *Controller) queueDepth(sp subpool, queueLen int) int {
+	limit := c.config().Tide.BatchSizeLimit(config.OrgRepo{Org: sp.org, Repo: sp.repo})
+	if limit < 0 || c.config().Tide.RequireUpToDate(config.OrgRepo{Org: sp.org, Repo: sp.repo}) {
+		limit = 1
+	}
+	if limit > 0 && limit < queueLen {
+		return limit
+	}
+	return queueLen
+}
+
+// queueStates returns the state of the speculative tests of every entry of
+// the merge queue. The entry of a PR tests it rebased on the PRs ahead of it,
+// so the first entry is the presubmit of the head of the queue and the others
+// are batches containing all PRs up to theirs.
+func (c *Controller) queueStates(sp subpool, queue []PullRequest, successes, pendings []PullRequest) ([]simpleState, [][]config.Presubmit, error) {
+	states := make([]simpleState, len(queue))
+	presubmits := make([][]config.Presubmit, len(queue))
+	for i := range queue {
+		if i == 0 {
+			states[i] = missingState
+			for _, pr := range successes {
+				if pr.Number == queue[0].Number {
+					states[i] = successState
+				}
+			}
+			for _, pr := range pendings {
+				if pr.Number == queue[0].Number {
+					states[i] = pendingState
+				}
+			}
+			continue
+		}
+
+		prefix := queue[:i+1]
+		required, err := c.presubmitsForBatch(prefix, sp.org, sp.repo, sp.sha, sp.branch)
+		if err != nil {
+			return nil, nil, err
+		}
+		presubmits[i] = required
+
+		jobStates := make(map[string]simpleState)
+		for _, pj := range sp.pjs {
+			if pj.Spec.Type != prowapi.BatchJob || !refsMatchPrefix(pj.Spec.Refs, prefix) {
+				continue
+			}
+			jobStates[pj.Spec.Context] = getBetterSimpleState(jobStates[pj.Spec.Context], toSimpleState(pj.Status.State))
+		}
+		// The state of the entry is the worst state of its required presubmits,
+		// a missing one only counts if none of them failed.
+		state := successState
+		for _, ps := range required {
+			s, ok := jobStates[ps.Context]
+			switch {
+			case ok && s == failureState:
+				state = failureState
+			case !ok && state != failureState:
+				state = missingState
+			case ok && s == pendingState && state == successState:
+				state = pendingState
+			}
+		}
+		states[i] = state
+	}
+	return states, presubmits, nil
+}
+
+// refsMatchPrefix returns whether the refs test exactly the given PRs, in order.
+func refsMatchPrefix(refs *prowapi.Refs, prefix []PullRequest) bool {
+	if refs == nil || len(refs.Pulls) != len(prefix) {
+		return false
+	}
+	for i, pull := range refs.Pulls {
+		if pull.Number != int(prefix[i].Number) || pull.SHA != string(prefix[i].HeadRefOID) {
+			return false
+		}
+	}
+	return true
+}
+
+// takeQueueAction is the takeAction of subpools using a merge queue. It evicts
+// the PRs whose speculative tests failed while those of the PR ahead of them
+// passed, merges the longest prefix of the queue that passed once no longer
+// prefix is pending and otherwise triggers the missing speculative tests.
+// It returns the queue and the PRs evicted during this sync as well.
+func (c *Controller) takeQueueAction(sp subpool, successes, pendings []PullRequest, missingSerialTests map[int][]config.Presubmit) (act Action, targets, queue, evicted []PullRequest, err error) {
+	queue = c.mergeQueue(sp)
+	var behind map[int]bool
+	if c.config().Tide.RequireUpToDate(config.OrgRepo{Org: sp.org, Repo: sp.repo}) {
+		// Skip the PRs at the head of the queue that can't be brought up to date.
+		for len(queue) > 0 {
+			var candidates []PullRequest
+			if candidates, behind = c.upToDateCandidates(sp, queue[:1]); len(candidates) > 0 {
+				break
+			}
+			queue = queue[1:]
+		}
+	}
+	var tested []PullRequest
+	var states []simpleState
+	var presubmits [][]config.Presubmit
+	for {
+		tested = queue[:c.queueDepth(sp, len(queue))]
+		states, presubmits, err = c.queueStates(sp, tested, successes, pendings)
+		if err != nil {
+			return Wait, nil, queue, evicted, err
+		}
+		evictedIndex := -1
+		for i, state := range states {
+			if state != failureState {
+				continue
+			}
+			// The entries behind a failing one contain its changes, they cannot
+			// pass. If the entry ahead of it passed, its PR is the culprit.
+			if i > 0 && states[i-1] == successState {
+				evictedIndex = i
+			}
+			states = states[:i]
+			break
+		}
+		if evictedIndex < 0 {
+			break
+		}
+		pr := queue[evictedIndex]
+		sp.log.WithFields(pr.logFields()).Info("Evicting PR from the merge queue, its speculative tests failed.")
+		c.evictions.evict(&pr)
+		evicted = append(evicted, pr)
+		queue = append(queue[:evictedIndex:evictedIndex], queue[evictedIndex+1:]...)
+	}
+
+	// Merging invalidates the speculative tests of the entries behind the
+	// merged ones, so wait for the pending ones to finish first.
+	mergeable := -1
+	for i, state := range states {
+		if state == successState {
+			mergeable = i
+		} else if state == pendingState {
+			mergeable = -1
+		}
+	}
+	if mergeable >= 0 {
+		if optimistic := sp.optimisticPRs(tested[:mergeable+1]); len(optimistic) > 0 {
+			sp.log.WithField("prs", optimistic).Info("Not merging the queue, its PRs have pending contexts within the grace period.")
+			mergeable = -1
+		}
+	}
+	if mergeable == 0 && behind[int(tested[0].Number)] {
+		sp.log.WithFields(tested[0].logFields()).Info("Updating the branch of the PR at the head of the merge queue, it is behind its base branch.")
+		return UpdateBranch, tested[:1], queue, evicted, c.updateBranch(sp, tested[0])
+	}
+	if mergeable == 0 {
+		return Merge, tested[:1], queue, evicted, c.mergePRs(sp, tested[:1])
+	}
+	if mergeable > 0 {
+		return MergeBatch, tested[:mergeable+1], queue, evicted, c.mergePRs(sp, tested[:mergeable+1])
+	}
+
+	for i, state := range states {
+		if state != missingState {
+			continue
+		}
+		prefix := tested[:i+1]
+		required := presubmits[i]
+		if i == 0 {
+			required = missingSerialTests[int(tested[0].Number)]
+		}
+		// The tests of the head of the queue may only be missing for
+		// another base, e.g. while its tests on the current one are
+		// pending, then there is nothing to trigger.
+		if len(required) == 0 {
+			continue
+		}
+		if err := c.trigger(sp, required, prefix); err != nil {
+			return TriggerQueue, targets, queue, evicted, err
+		}
+		targets = prefix
+	}
+	if len(targets) > 0 {
+		return TriggerQueue, targets, queue, evicted, nil
+	}
+	return Wait, nil, queue, evicted, nil
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+func TestTakeQueueAction(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+
+	presubmit := config.Presubmit{
+		JobBase:   config.JobBase{Name: "foo"},
+		Reporter:  config.Reporter{Context: "foo"},
+		AlwaysRun: true,
+	}
+	batchJob := func(state prowapi.ProwJobState, numbers ...int) prowapi.ProwJob {
+		refs := &prowapi.Refs{Org: "o", Repo: "r", BaseRef: "master", BaseSHA: "master"}
+		for _, n := range numbers {
+			refs.Pulls = append(refs.Pulls, prowapi.Pull{Number: n, SHA: fmt.Sprintf("sha-%d", n)})
+		}
+		return prowapi.ProwJob{
+			Spec:   prowapi.ProwJobSpec{Type: prowapi.BatchJob, Job: "foo", Context: "foo", Refs: refs},
+			Status: prowapi.ProwJobStatus{State: state},
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		prs            []int
+		successes      []int
+		pendings       []int
+		pjs            []prowapi.ProwJob
+		evicted        []int
+		batchSizeLimit int
+		// noSerialTests are the PRs that miss no serial tests.
+		noSerialTests []int
+
+		expectedAction    Action
+		expectedTargets   []int
+		expectedQueue     []int
+		expectedEvicted   []int
+		expectedTriggered int
+		expectedMerged    int
+	}{
+		{
+			name:              "untested queue triggers every entry",
+			expectedAction:    TriggerQueue,
+			expectedTargets:   []int{1, 2, 3},
+			expectedQueue:     []int{1, 2, 3},
+			expectedTriggered: 3,
+		},
+		{
+			name:              "queue depth is limited by the batch size limit",
+			batchSizeLimit:    2,
+			expectedAction:    TriggerQueue,
+			expectedTargets:   []int{1, 2},
+			expectedQueue:     []int{1, 2, 3},
+			expectedTriggered: 2,
+		},
+		{
+			name:            "passing head is merged",
+			successes:       []int{1},
+			expectedAction:  Merge,
+			expectedTargets: []int{1},
+			expectedQueue:   []int{1, 2, 3},
+			expectedMerged:  1,
+		},
+		{
+			name:              "merge waits for pending entries",
+			successes:         []int{1},
+			pjs:               []prowapi.ProwJob{batchJob(prowapi.PendingState, 1, 2)},
+			expectedAction:    TriggerQueue,
+			expectedTargets:   []int{1, 2, 3},
+			expectedQueue:     []int{1, 2, 3},
+			expectedTriggered: 1,
+		},
+		{
+			name:     "longest passing entry is merged",
+			pendings: []int{1},
+			pjs: []prowapi.ProwJob{
+				batchJob(prowapi.SuccessState, 1, 2),
+				batchJob(prowapi.SuccessState, 1, 2, 3),
+			},
+			expectedAction:  MergeBatch,
+			expectedTargets: []int{1, 2, 3},
+			expectedQueue:   []int{1, 2, 3},
+			expectedMerged:  3,
+		},
+		{
+			name:      "failing entry behind a passing one is evicted",
+			successes: []int{1},
+			pjs: []prowapi.ProwJob{
+				batchJob(prowapi.FailureState, 1, 2),
+				batchJob(prowapi.FailureState, 1, 2, 3),
+			},
+			expectedAction:  Merge,
+			expectedTargets: []int{1},
+			expectedQueue:   []int{1, 3},
+			expectedEvicted: []int{2},
+			expectedMerged:  1,
+		},
+		{
+			name:     "entries behind the evicted PR stay valid",
+			prs:      []int{1, 2, 3, 4},
+			pendings: []int{1},
+			pjs: []prowapi.ProwJob{
+				batchJob(prowapi.SuccessState, 1, 2),
+				batchJob(prowapi.FailureState, 1, 2, 3),
+				batchJob(prowapi.PendingState, 1, 2, 4),
+			},
+			expectedAction:  Wait,
+			expectedQueue:   []int{1, 2, 4},
+			expectedEvicted: []int{3},
+		},
+		{
+			name:     "failing entry behind a pending one is not evicted",
+			pendings: []int{1},
+			pjs: []prowapi.ProwJob{
+				batchJob(prowapi.FailureState, 1, 2),
+				batchJob(prowapi.PendingState, 1, 2, 3),
+			},
+			expectedAction: Wait,
+			expectedQueue:  []int{1, 2, 3},
+		},
+		{
+			name:              "head without missing serial tests is not triggered",
+			noSerialTests:     []int{1},
+			expectedAction:    TriggerQueue,
+			expectedTargets:   []int{1, 2, 3},
+			expectedQueue:     []int{1, 2, 3},
+			expectedTriggered: 2,
+		},
+		{
+			name:           "queue of one without missing serial tests waits",
+			prs:            []int{1},
+			noSerialTests:  []int{1},
+			expectedAction: Wait,
+			expectedQueue:  []int{1},
+		},
+		{
+			name:              "evicted PR is left out of the queue",
+			evicted:           []int{2},
+			expectedAction:    TriggerQueue,
+			expectedTargets:   []int{1, 3},
+			expectedQueue:     []int{1, 3},
+			expectedTriggered: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ca := &config.Agent{}
+			cfg := &config.Config{ProwConfig: config.ProwConfig{ProwJobNamespace: "pj-ns"}}
+			cfg.Tide.BatchSizeLimitMap = map[string]int{"*": tc.batchSizeLimit}
+			if err := cfg.SetPresubmits(map[string][]config.Presubmit{"o/r": {presubmit}}); err != nil {
+				t.Fatalf("failed to set presubmits: %v", err)
+			}
+			ca.Set(cfg)
+
+			fgc := &fgc{}
+			c, err := newSyncController(
+				context.Background(),
+				logrus.WithField("controller", "tide"),
+				fgc,
+				newFakeManager(),
+				ca.Config,
+				nil,
+				&statusController{},
+				nil,
+				nil,
+				false,
+			)
+			if err != nil {
+				t.Fatalf("failed to construct sync controller: %v", err)
+			}
+			c.changedFiles = &changedFilesAgent{
+				ghc:             fgc,
+				nextChangeCache: make(map[changeCacheKey][]string),
+			}
+
+			sp := subpool{
+				log:        logrus.WithField("component", "tide"),
+				org:        "o",
+				repo:       "r",
+				branch:     "master",
+				sha:        "master",
+				pjs:        tc.pjs,
+				cc:         map[int]contextChecker{},
+				presubmits: map[int][]config.Presubmit{},
+			}
+			numbers := tc.prs
+			if numbers == nil {
+				numbers = []int{1, 2, 3}
+			}
+			prs := map[int]PullRequest{}
+			for _, i := range numbers {
+				var pr PullRequest
+				pr.Number = githubql.Int(i)
+				pr.HeadRefOID = githubql.String(fmt.Sprintf("sha-%d", i))
+				pr.Repository.NameWithOwner = "o/r"
+				pr.Commits.Nodes = []struct{ Commit Commit }{{Commit: Commit{OID: pr.HeadRefOID}}}
+				sp.prs = append(sp.prs, pr)
+				sp.cc[i] = &config.TideContextPolicy{}
+				sp.presubmits[i] = []config.Presubmit{presubmit}
+				prs[i] = pr
+			}
+			for _, n := range tc.evicted {
+				pr := prs[n]
+				c.evictions.evict(&pr)
+			}
+			pick := func(numbers []int) []PullRequest {
+				var res []PullRequest
+				for _, n := range numbers {
+					res = append(res, prs[n])
+				}
+				return res
+			}
+			missingSerialTests := map[int][]config.Presubmit{}
+			for n := range prs {
+				missingSerialTests[n] = []config.Presubmit{presubmit}
+			}
+			for _, n := range tc.noSerialTests {
+				delete(missingSerialTests, n)
+			}
+
+			act, targets, queue, evicted, err := c.takeQueueAction(sp, pick(tc.successes), pick(tc.pendings), missingSerialTests)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if act != tc.expectedAction {
+				t.Errorf("expected action %v, got %v", tc.expectedAction, act)
+			}
+			if diff := cmp.Diff(tc.expectedTargets, prNumbers(targets)); diff != "" {
+				t.Errorf("targets differ from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedQueue, prNumbers(queue)); diff != "" {
+				t.Errorf("queue differs from expected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedEvicted, prNumbers(evicted)); diff != "" {
+				t.Errorf("evicted PRs differ from expected: %s", diff)
+			}
+			if fgc.merged != tc.expectedMerged {
+				t.Errorf("expected %d merges, got %d", tc.expectedMerged, fgc.merged)
+			}
+			pjs := &prowapi.ProwJobList{}
+			if err := c.prowJobClient.List(context.Background(), pjs); err != nil {
+				t.Fatalf("failed to list ProwJobs: %v", err)
+			}
+			if n := len(pjs.Items); n != tc.expectedTriggered {
+				t.Errorf("expected %d triggered jobs, got %d", tc.expectedTriggered, n)
+			}
+		})
+	}
+}
+
+func TestEvictedPRs(t *testing.T) {
+	var pr PullRequest
+	pr.Number = 1
+	pr.HeadRefOID = "sha-1"
+	pr.Repository.NameWithOwner = "o/r"
+
+	e := newEvictedPRs()
+	e.evict(&pr)
+	if !e.isEvicted(&pr) {
+		t.Error("expected the PR to be evicted")
+	}
+	e.prune()
+	if !e.isEvicted(&pr) {
+		t.Error("expected the eviction to survive a prune while it is used")
+	}
+	e.prune()
+	e.prune()
+	if e.isEvicted(&pr) {
+		t.Error("expected the unused eviction to expire")
+	}
+
+	e.evict(&pr)
+	pr.HeadRefOID = "sha-2"
+	if e.isEvicted(&pr) {
+		t.Error("expected the PR to be back in the queue after a push")
+	}
+}
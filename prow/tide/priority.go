@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+// noPriorityClass is the priority class of PRs that match none of the priorities.
+const noPriorityClass = "none"
+
+// priorityIndex returns the index of the first priority the PR matches, or
+// len(priorities) if it matches none of them.
+func priorityIndex(pr PullRequest, priorities []config.TidePriority) int {
+	for i, p := range priorities {
+		if hasAllLabels(pr, p.Labels) {
+			return i
+		}
+	}
+	return len(priorities)
+}
+
+// priorityClass returns the name of the first priority the PR matches.
+func priorityClass(pr PullRequest, priorities []config.TidePriority) string {
+	if i := priorityIndex(pr, priorities); i < len(priorities) {
+		return priorities[i].ClassName()
+	}
+	return noPriorityClass
+}
+
+// sortByPriority sorts the PRs by priority, then oldest PR first.
+func sortByPriority(prs []PullRequest, priorities []config.TidePriority) {
+	sort.SliceStable(prs, func(i, j int) bool {
+		pi, pj := priorityIndex(prs[i], priorities), priorityIndex(prs[j], priorities)
+		if pi != pj {
+			return pi < pj
+		}
+		return prs[i].Number < prs[j].Number
+	})
+}
+
+// poolEntries remembers when PRs entered the merge pool, to measure how long
+// they waited before being merged. Entries expire if they are not used during
+// a sync loop.
+type poolEntries struct {
+	sync.Mutex
+	entered     map[string]time.Time
+	nextEntered map[string]time.Time
+}
+
+func newPoolEntries() *poolEntries {
+	return &poolEntries{
+		entered:     make(map[string]time.Time),
+		nextEntered: make(map[string]time.Time),
+	}
+}
+
+// enter records that the PR is in the pool and returns when it entered it.
+func (p *poolEntries) enter(pr *PullRequest, now time.Time) time.Time {
+	p.Lock()
+	defer p.Unlock()
+	key := prKey(pr)
+	entered, ok := p.nextEntered[key]
+	if !ok {
+		entered, ok = p.entered[key]
+	}
+	if !ok {
+		entered = now
+	}
+	p.nextEntered[key] = entered
+	return entered
+}
+
+// prune forgets the PRs that were not in the pool since the last prune.
+func (p *poolEntries) prune() {
+	p.Lock()
+	defer p.Unlock()
+	p.entered = p.nextEntered
+	p.nextEntered = make(map[string]time.Time)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	githubql "github.com/shurcooL/githubv4"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+func priorityTestPR(number int, labels ...string) PullRequest {
+	var pr PullRequest
+	pr.Number = githubql.Int(number)
+	pr.Repository.NameWithOwner = "o/r"
+	for _, label := range labels {
+		pr.Labels.Nodes = append(pr.Labels.Nodes, struct{ Name githubql.String }{Name: githubql.String(label)})
+	}
+	return pr
+}
+
+func TestSortByPriority(t *testing.T) {
+	priorities := []config.TidePriority{
+		{Labels: []string{"kind/revert"}},
+		{Labels: []string{"priority/critical-urgent"}},
+	}
+	prs := []PullRequest{
+		priorityTestPR(4),
+		priorityTestPR(5, "priority/critical-urgent"),
+		priorityTestPR(1),
+		priorityTestPR(6, "kind/revert"),
+		priorityTestPR(3, "priority/critical-urgent"),
+	}
+	sortByPriority(prs, priorities)
+	if diff := cmp.Diff([]int{6, 3, 5, 1, 4}, prNumbers(prs)); diff != "" {
+		t.Errorf("order differs from expected: %s", diff)
+	}
+}
+
+func TestPriorityClass(t *testing.T) {
+	priorities := []config.TidePriority{
+		{Name: "revert", Labels: []string{"kind/revert"}},
+		{Labels: []string{"priority/critical-urgent", "lgtm"}},
+	}
+	testCases := []struct {
+		name     string
+		labels   []string
+		expected string
+	}{
+		{
+			name:     "named priority",
+			labels:   []string{"kind/revert", "priority/critical-urgent", "lgtm"},
+			expected: "revert",
+		},
+		{
+			name:     "unnamed priority defaults to its labels",
+			labels:   []string{"lgtm", "priority/critical-urgent"},
+			expected: "priority/critical-urgent,lgtm",
+		},
+		{
+			name:     "PR must have all labels of a priority",
+			labels:   []string{"priority/critical-urgent"},
+			expected: noPriorityClass,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := priorityClass(priorityTestPR(1, tc.labels...), priorities); actual != tc.expected {
+				t.Errorf("expected priority class %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPoolEntries(t *testing.T) {
+	pr := priorityTestPR(1)
+	start := time.Now()
+
+	p := newPoolEntries()
+	if entered := p.enter(&pr, start); !entered.Equal(start) {
+		t.Errorf("expected the PR to enter the pool at %v, got %v", start, entered)
+	}
+	p.prune()
+	if entered := p.enter(&pr, start.Add(time.Minute)); !entered.Equal(start) {
+		t.Errorf("expected the PR to stay in the pool since %v, got %v", start, entered)
+	}
+	p.prune()
+	p.prune()
+	later := start.Add(time.Hour)
+	if entered := p.enter(&pr, later); !entered.Equal(later) {
+		t.Errorf("expected the PR to re-enter the pool at %v, got %v", later, entered)
+	}
+}
@@ -71,6 +71,9 @@ type statusController struct {
 
 	mergeChecker *mergeChecker
 
+	// gitlab searches the merge requests of the orgs hosted on GitLab.
+	gitlab *gitlabProvider
+
 	// newPoolPending is a size 1 chan that signals that the main Tide loop has
 	// updated the 'poolPRs' field with a freshly updated pool.
 	newPoolPending chan bool
@@ -179,19 +182,7 @@ func requirementDiff(pr *PullRequest, q *config.TideQuery, cc contextChecker) (s
 	}
 
 	// Weight incorrect labels and statues with low (normal) diff values.
-	var missingLabels []string
-	for _, l1 := range q.Labels {
-		var found bool
-		for _, l2 := range pr.Labels.Nodes {
-			if string(l2.Name) == l1 {
-				found = true
-				break
-			}
-		}
-		if !found {
-			missingLabels = append(missingLabels, l1)
-		}
-	}
+	missingLabels, presentLabels := labelDiff(pr, q)
 	diff += len(missingLabels)
 	if desc == "" && len(missingLabels) > 0 {
 		sort.Strings(missingLabels)
@@ -203,15 +194,6 @@ func requirementDiff(pr *PullRequest, q *config.TideQuery, cc contextChecker) (s
 		}
 	}
 
-	var presentLabels []string
-	for _, l1 := range q.MissingLabels {
-		for _, l2 := range pr.Labels.Nodes {
-			if string(l2.Name) == l1 {
-				presentLabels = append(presentLabels, l1)
-				break
-			}
-		}
-	}
 	diff += len(presentLabels)
 	if desc == "" && len(presentLabels) > 0 {
 		sort.Strings(presentLabels)
@@ -268,6 +250,11 @@ func (sc *statusController) expectedStatus(log *logrus.Entry, queryMap *config.Q
 		return github.StatusError, fmt.Sprintf(statusNotInPool, " "+reason), nil
 	}
 
+	freezes := mergeFreezes(&sc.config().Tide, repo.Org, repo.Repo, string(pr.BaseRef.Name), time.Now())
+	if reason := frozenReason(freezes, pr); reason != "" {
+		return github.StatusPending, fmt.Sprintf(statusNotInPool, " Blocked: "+reason+"."), nil
+	}
+
 	cc, err := ccg()
 	if err != nil {
 		return "", "", fmt.Errorf("failed to set up context register: %w", err)
@@ -593,7 +580,7 @@ func (sc *statusController) search() []PullRequest {
 		return nil
 	}
 
-	orgExceptions, repos := rawQueries.OrgExceptionsAndRepos()
+	orgExceptions, repos, gitlabOrgExceptions, gitlabRepos := splitOrgExceptionsAndRepos(rawQueries, &sc.config().Tide.GitLab)
 	orgs := sets.StringKeySet(orgExceptions)
 	queries := openPRsQueries(orgs.List(), repos.List(), orgExceptions)
 	if !sc.usesGitHubAppsAuth {
@@ -613,6 +600,20 @@ func (sc *statusController) search() []PullRequest {
 	var lock sync.Mutex
 	var wg sync.WaitGroup
 
+	if len(gitlabOrgExceptions) > 0 || gitlabRepos.Len() > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := sc.gitlab.openMergeRequests(gitlabOrgExceptions, gitlabRepos)
+			lock.Lock()
+			defer lock.Unlock()
+			prs = append(prs, result...)
+			errs = append(errs, err)
+		}()
+	}
+	if len(orgExceptions) == 0 && repos.Len() == 0 {
+		queries = nil
+	}
 	for org, query := range queries {
 		org, query := org, query
 		wg.Add(1)
@@ -42,6 +42,7 @@ import (
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/git/v2"
 	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/gitlab"
 	"k8s.io/test-infra/prow/io"
 	"k8s.io/test-infra/prow/kube"
 	"k8s.io/test-infra/prow/pjutil"
@@ -57,11 +58,15 @@ type githubClient interface {
 	CreateStatus(string, string, string, github.Status) error
 	GetCombinedStatus(org, repo, ref string) (*github.CombinedStatus, error)
 	ListCheckRuns(org, repo, ref string) (*github.CheckRunList, error)
+	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
 	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
 	GetRef(string, string, string) (string, error)
+	CompareCommits(org, repo, base, head string) (*github.CommitComparison, error)
 	GetRepo(owner, name string) (github.FullRepo, error)
 	Merge(string, string, int, github.MergeDetails) error
+	UpdatePullRequestBranch(org, repo string, number int, expectedHeadSha *string) error
 	QueryWithGitHubAppsSupport(ctx context.Context, q interface{}, vars map[string]interface{}, org string) error
+	MutateWithGitHubAppsSupport(ctx context.Context, m interface{}, input githubql.Input, vars map[string]interface{}, org string) error
 }
 
 type contextChecker interface {
@@ -93,6 +98,18 @@ type Controller struct {
 
 	mergeChecker *mergeChecker
 
+	// evictions remembers the PRs evicted from merge queues.
+	evictions *evictedPRs
+
+	// poolEntries remembers when PRs entered the pool.
+	poolEntries *poolEntries
+
+	// branchUpdates counts the branch updates of PRs behind their base branch.
+	branchUpdates *branchUpdates
+
+	// gitlab searches the merge requests of the orgs hosted on GitLab.
+	gitlab *gitlabProvider
+
 	History *history.History
 }
 
@@ -108,6 +125,9 @@ const (
 	Merge        Action = "MERGE"
 	MergeBatch   Action = "MERGE_BATCH"
 	PoolBlocked  Action = "BLOCKED"
+	PoolFrozen   Action = "FROZEN"
+	TriggerQueue Action = "TRIGGER_QUEUE"
+	UpdateBranch Action = "UPDATE_BRANCH"
 )
 
 // recordableActions is the subset of actions that we keep historical record of.
@@ -117,6 +137,8 @@ var recordableActions = map[Action]bool{
 	TriggerBatch: true,
 	Merge:        true,
 	MergeBatch:   true,
+	TriggerQueue: true,
+	UpdateBranch: true,
 }
 
 // Pool represents information about a tide pool. There is one for every
@@ -136,6 +158,18 @@ type Pool struct {
 	// Empty if there is no pending batch.
 	BatchPending []PullRequest
 
+	// The merge queue, head first, and the PRs evicted from it during the last
+	// sync. Empty if the pool does not use a merge queue.
+	QueuedPRs  []PullRequest
+	EvictedPRs []PullRequest
+
+	// Why merging is frozen by the merge calendars, empty if it is not.
+	MergeFreeze string
+
+	// What keeps the PRs Tide found for the pool from merging, including
+	// those that were filtered out of the pool.
+	BlockedPRs []PullRequestBlockers
+
 	// Which action did we last take, and to what target(s), if any.
 	Action   Action
 	Target   []PullRequest
@@ -156,6 +190,9 @@ var (
 		poolErrors   *prometheus.CounterVec
 		queryResults *prometheus.CounterVec
 
+		// Per pool and priority class
+		queueWaitTime *prometheus.HistogramVec
+
 		// Singleton
 		syncDuration         prometheus.Gauge
 		statusUpdateDuration prometheus.Gauge
@@ -208,6 +245,17 @@ var (
 			"result",
 		}),
 
+		queueWaitTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "tidequeuewaittime",
+			Help:    "Histogram of the seconds merged PRs spent in the pool, by priority class.",
+			Buckets: []float64{60, 300, 900, 1800, 3600, 7200, 14400, 28800, 86400, 259200},
+		}, []string{
+			"org",
+			"repo",
+			"branch",
+			"priority",
+		}),
+
 		// Use the sync heartbeat counter to monitor for liveness. Use the duration
 		// gauges for precise sync duration graphs since the prometheus scrape
 		// period is likely much larger than the loop periods.
@@ -238,6 +286,7 @@ func init() {
 	prometheus.MustRegister(tideMetrics.syncHeartbeat)
 	prometheus.MustRegister(tideMetrics.poolErrors)
 	prometheus.MustRegister(tideMetrics.queryResults)
+	prometheus.MustRegister(tideMetrics.queueWaitTime)
 }
 
 type manager interface {
@@ -245,8 +294,9 @@ type manager interface {
 	GetFieldIndexer() ctrlruntimeclient.FieldIndexer
 }
 
-// NewController makes a Controller out of the given clients.
-func NewController(ghcSync, ghcStatus github.Client, mgr manager, cfg config.Getter, gc git.ClientFactory, maxRecordsPerPool int, opener io.Opener, historyURI, statusURI string, logger *logrus.Entry, usesGitHubAppsAuth bool) (*Controller, error) {
+// NewController makes a Controller out of the given clients. The GitLab client
+// is optional and only needed if orgs hosted on GitLab are configured.
+func NewController(ghcSync, ghcStatus github.Client, glc gitlab.Client, mgr manager, cfg config.Getter, gc git.ClientFactory, maxRecordsPerPool int, opener io.Opener, historyURI, statusURI string, logger *logrus.Entry, usesGitHubAppsAuth bool) (*Controller, error) {
 	if logger == nil {
 		logger = logrus.NewEntry(logrus.StandardLogger())
 	}
@@ -254,16 +304,30 @@ func NewController(ghcSync, ghcStatus github.Client, mgr manager, cfg config.Get
 	if err != nil {
 		return nil, fmt.Errorf("error initializing history client from %q: %w", historyURI, err)
 	}
-	mergeChecker := newMergeChecker(cfg, ghcSync)
+
+	var syncClient, statusClient githubClient = ghcSync, ghcStatus
+	var provider *gitlabProvider
+	if glc != nil {
+		provider = newGitLabProvider(glc, cfg, logger)
+		syncClient = &providerClient{githubClient: ghcSync, gitlab: provider}
+		statusClient = &providerClient{githubClient: ghcStatus, gitlab: provider}
+	}
+	mergeChecker := newMergeChecker(cfg, syncClient)
 
 	ctx := context.Background()
-	sc, err := newStatusController(ctx, logger, ghcStatus, mgr, gc, cfg, opener, statusURI, mergeChecker, usesGitHubAppsAuth)
+	sc, err := newStatusController(ctx, logger, statusClient, mgr, gc, cfg, opener, statusURI, mergeChecker, usesGitHubAppsAuth)
 	if err != nil {
 		return nil, err
 	}
+	sc.gitlab = provider
 	go sc.run()
 
-	return newSyncController(ctx, logger, ghcSync, mgr, cfg, gc, sc, hist, mergeChecker, usesGitHubAppsAuth)
+	c, err := newSyncController(ctx, logger, syncClient, mgr, cfg, gc, sc, hist, mergeChecker, usesGitHubAppsAuth)
+	if err != nil {
+		return nil, err
+	}
+	c.gitlab = provider
+	return c, nil
 }
 
 func newStatusController(ctx context.Context, logger *logrus.Entry, ghc githubClient, mgr manager, gc git.ClientFactory, cfg config.Getter, opener io.Opener, statusURI string, mergeChecker *mergeChecker, usesGitHubAppsAuth bool) (*statusController, error) {
@@ -327,8 +391,11 @@ func newSyncController(
 			ghc:             ghcSync,
 			nextChangeCache: make(map[changeCacheKey][]string),
 		},
-		mergeChecker: mergeChecker,
-		History:      hist,
+		mergeChecker:  mergeChecker,
+		evictions:     newEvictedPRs(),
+		poolEntries:   newPoolEntries(),
+		branchUpdates: newBranchUpdates(),
+		History:       hist,
 	}, nil
 }
 
@@ -372,6 +439,9 @@ func (c *Controller) Sync() error {
 		tideMetrics.syncHeartbeat.WithLabelValues("sync").Inc()
 	}()
 	defer c.changedFiles.prune()
+	defer c.evictions.prune()
+	defer c.poolEntries.prune()
+	defer c.branchUpdates.prune()
 	c.config().BranchProtectionWarnings(c.logger, c.config().PresubmitsStatic)
 
 	c.logger.Debug("Building tide pool.")
@@ -405,7 +475,17 @@ func (c *Controller) Sync() error {
 	if err != nil {
 		return err
 	}
+	for _, sp := range rawPools {
+		for i := range sp.prs {
+			c.branchUpdates.keep(&sp.prs[i])
+		}
+	}
 	filteredPools := c.filterSubpools(c.mergeChecker.isAllowed, rawPools)
+	for _, sp := range filteredPools {
+		for i := range sp.prs {
+			c.poolEntries.enter(&sp.prs[i], start)
+		}
+	}
 
 	// Notify statusController about the new pool.
 	c.sc.Lock()
@@ -440,6 +520,24 @@ func (c *Controller) Sync() error {
 	for pool := range poolChan {
 		pools = append(pools, pool)
 	}
+	// Serve why the PRs of the subpools that became empty cannot merge.
+	for key, sp := range rawPools {
+		if _, ok := filteredPools[key]; ok {
+			continue
+		}
+		applicable := blocks.GetApplicable(sp.org, sp.repo, sp.branch)
+		if blocked := poolBlockers(sp.blocked, applicable); len(blocked) > 0 {
+			pools = append(pools, Pool{
+				Org:        sp.org,
+				Repo:       sp.repo,
+				Branch:     sp.branch,
+				BlockedPRs: blocked,
+				Action:     Wait,
+				Blockers:   applicable,
+				TenantIDs:  sp.TenantIDs(),
+			})
+		}
+	}
 	sortPools(pools)
 	c.m.Lock()
 	c.pools = pools
@@ -454,14 +552,50 @@ func (c *Controller) query() (map[string]PullRequest, error) {
 	wg := sync.WaitGroup{}
 	prs := make(map[string]PullRequest)
 	var errs []error
+	collect := func(i int, org, q string, results []PullRequest, err error) {
+		resultString := "success"
+		if err != nil {
+			resultString = "error"
+		}
+		tideMetrics.queryResults.WithLabelValues(strconv.Itoa(i), org, resultString).Inc()
+
+		lock.Lock()
+		defer lock.Unlock()
+		if err != nil && len(results) == 0 {
+			c.logger.WithField("query", q).WithError(err).Warn("Failed to execute query.")
+			errs = append(errs, fmt.Errorf("query %d, err: %w", i, err))
+			return
+		}
+		if err != nil {
+			c.logger.WithError(err).WithField("query", q).Warning("found partial results")
+		}
+
+		for _, pr := range results {
+			prs[prKey(&pr)] = pr
+		}
+	}
+	gitLab := c.config().Tide.GitLab
 	for i, query := range c.config().Tide.Queries {
+		githubQuery, gitlabQuery := splitQuery(query, &gitLab)
+		if gitlabQuery != nil {
+			i, gitlabQuery := i, gitlabQuery
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results, err := c.gitlab.search(*gitlabQuery)
+				collect(i, "gitlab", gitlabQuery.Query(), results, err)
+			}()
+		}
+		if githubQuery == nil {
+			continue
+		}
 
 		// Use org-sharded queries only when GitHub apps auth is in use
 		var queries map[string]string
 		if c.usesGitHubAppsAuth {
-			queries = query.OrgQueries()
+			queries = githubQuery.OrgQueries()
 		} else {
-			queries = map[string]string{"": query.Query()}
+			queries = map[string]string{"": githubQuery.Query()}
 		}
 
 		for org, q := range queries {
@@ -470,27 +604,7 @@ func (c *Controller) query() (map[string]PullRequest, error) {
 			go func() {
 				defer wg.Done()
 				results, err := search(c.ghc.QueryWithGitHubAppsSupport, c.logger, q, time.Time{}, time.Now(), org)
-
-				resultString := "success"
-				if err != nil {
-					resultString = "error"
-				}
-				tideMetrics.queryResults.WithLabelValues(strconv.Itoa(i), org, resultString).Inc()
-
-				lock.Lock()
-				defer lock.Unlock()
-				if err != nil && len(results) == 0 {
-					c.logger.WithField("query", q).WithError(err).Warn("Failed to execute query.")
-					errs = append(errs, fmt.Errorf("query %d, err: %w", i, err))
-					return
-				}
-				if err != nil {
-					c.logger.WithError(err).WithField("query", q).Warning("found partial results")
-				}
-
-				for _, pr := range results {
-					prs[prKey(&pr)] = pr
-				}
+				collect(i, org, q, results, err)
 			}()
 		}
 	}
@@ -551,6 +665,7 @@ func (c *Controller) filterSubpools(mergeAllowed func(*PullRequest) (string, err
 				sp.log.WithError(err).Error("Error initializing subpool.")
 				return
 			}
+			sp.blocked = c.explainSubpool(mergeAllowed, sp)
 			key := poolKey(sp.org, sp.repo, sp.branch)
 			if spFiltered := filterSubpool(c.ghc, mergeAllowed, sp); spFiltered != nil {
 				sp.log.WithField("key", key).WithField("pool", spFiltered).Debug("filtered sub-pool")
@@ -573,6 +688,7 @@ func (c *Controller) initSubpoolData(sp *subpool) error {
 		return fmt.Errorf("error determining required presubmit prowjobs: %w", err)
 	}
 	sp.cc = make(map[int]contextChecker, len(sp.prs))
+	sp.pendingGracePeriod = c.config().Tide.PendingContextGracePeriod(config.OrgRepo{Org: sp.org, Repo: sp.repo})
 	for _, pr := range sp.prs {
 		sp.cc[int(pr.Number)], err = c.config().GetTideContextPolicy(c.gc, sp.org, sp.repo, sp.branch, refGetterFactory(string(sp.sha)), string(pr.HeadRefOID))
 		if err != nil {
@@ -608,7 +724,8 @@ func filterSubpool(ghc githubClient, mergeAllowed func(*PullRequest) (string, er
 //   ProwJob. (This ensures that the 'tide' context indicates that the pending
 //   status is preventing merge. Required ProwJob statuses are allowed to be
 //   'pending' because this prevents kicking PRs from the pool when Tide is
-//   retesting them.)
+//   retesting them.) Contexts created within the pending grace period of the
+//   subpool are allowed too, the PR is recorded as optimistic then.
 func filterPR(ghc githubClient, mergeAllowed func(*PullRequest) (string, error), sp *subpool, pr *PullRequest) bool {
 	log := sp.log.WithFields(pr.logFields())
 	// Skip PRs that are known to be unmergeable.
@@ -635,17 +752,31 @@ func filterPR(ghc githubClient, mergeAllowed func(*PullRequest) (string, error),
 		}
 		return false
 	}
+	var optimistic bool
+	now := time.Now()
 	for _, ctx := range unsuccessfulContexts(contexts, sp.cc[int(pr.Number)], log) {
 		if ctx.State != githubql.StatusStatePending {
 			log.WithField("context", ctx.Context).Debug("filtering out PR as unsuccessful context is not pending")
 			return true
 		}
-		if !presubmitsHaveContext(string(ctx.Context)) {
-			log.WithField("context", ctx.Context).Debug("filtering out PR as unsuccessful context is not Prow-controlled")
-			return true
+		if presubmitsHaveContext(string(ctx.Context)) {
+			continue
+		}
+		if isWithinGracePeriod(ctx, sp.pendingGracePeriod, now) {
+			log.WithField("context", ctx.Context).Debug("keeping PR as pending context is within the grace period")
+			optimistic = true
+			continue
 		}
+		log.WithField("context", ctx.Context).Debug("filtering out PR as unsuccessful context is not Prow-controlled")
+		return true
 	}
 
+	if optimistic {
+		if sp.optimistic == nil {
+			sp.optimistic = sets.NewInt()
+		}
+		sp.optimistic.Insert(int(pr.Number))
+	}
 	return false
 }
 
@@ -722,10 +853,13 @@ func (m *mergeChecker) isAllowed(pr *PullRequest) (string, error) {
 		// This should be impossible.
 		return "", fmt.Errorf("Programmer error! Failed to determine a merge method: %w", err)
 	}
+	orgRepo := config.OrgRepo{Org: string(pr.Repository.Owner.Login), Repo: string(pr.Repository.Name)}
+	if override, _ := prMergeMethodOverride(m.config().Tide, pr); override != "" && !m.config().Tide.MergeMethodOverrideAllowed(orgRepo, override) {
+		return fmt.Sprintf("Merge type %q override disallowed by Tide config", override), nil
+	}
 	if mergeMethod == github.MergeRebase && !pr.CanBeRebased {
 		return "PR can't be rebased", nil
 	}
-	orgRepo := config.OrgRepo{Org: string(pr.Repository.Owner.Login), Repo: string(pr.Repository.Name)}
 	repoMethods, err := m.repoMethods(orgRepo)
 	if err != nil {
 		return "", fmt.Errorf("error getting repo data: %w", err)
@@ -1117,9 +1251,13 @@ func (c *Controller) pickBatch(sp subpool, cc map[int]contextChecker, newBatchFu
 		sp.log.Debug("Batch merges disabled by configuration in this repo.")
 		return nil, nil, nil
 	}
+	if c.config().Tide.RequireUpToDate(config.OrgRepo{Org: sp.org, Repo: sp.repo}) {
+		sp.log.Debug("Batch merges disabled as this repo requires PRs to be up to date with their base branch.")
+		return nil, nil, nil
+	}
 
-	// we must choose the oldest PRs for the batch
-	sort.Slice(sp.prs, func(i, j int) bool { return sp.prs[i].Number < sp.prs[j].Number })
+	// we must choose the highest priority, then oldest PRs for the batch
+	sortByPriority(sp.prs, c.config().Tide.Priority)
 
 	var candidates []PullRequest
 	for _, pr := range sp.prs {
@@ -1176,6 +1314,9 @@ func (c *Controller) isRetestEligible(log *logrus.Entry, candidate *PullRequest,
 		return false
 	}
 
+	gracePeriod := c.config().Tide.PendingContextGracePeriod(config.OrgRepo{Org: string(candidate.Repository.Owner.Login), Repo: string(candidate.Repository.Name)})
+	now := time.Now()
+
 	for _, headContext := range candidateHeadContexts {
 		if headContext.Context == statusContext || cc.IsOptional(string(headContext.Context)) || headContext.State == githubql.StatusStateSuccess {
 			continue
@@ -1183,6 +1324,9 @@ func (c *Controller) isRetestEligible(log *logrus.Entry, candidate *PullRequest,
 		if headContext.State != githubql.StatusStatePending {
 			return false
 		}
+		if isWithinGracePeriod(headContext, gracePeriod, now) {
+			continue
+		}
 
 		pjLabels := createdByTideLabels()
 		pjLabels[kube.ProwJobTypeLabel] = string(prowapi.PresubmitJob)
@@ -1251,8 +1395,21 @@ func (c *Controller) prepareMergeDetails(commitTemplates config.TideMergeCommitT
 }
 
 func prMergeMethod(c config.Tide, pr *PullRequest) (github.PullRequestMergeType, error) {
+	method, err := prMergeMethodOverride(c, pr)
+	if err != nil {
+		return "", err
+	}
+	if method != "" {
+		return method, nil
+	}
 	repo := config.OrgRepo{Org: string(pr.Repository.Owner.Login), Repo: string(pr.Repository.Name)}
-	method := c.MergeMethod(repo)
+	return c.MergeMethod(repo), nil
+}
+
+// prMergeMethodOverride returns the merge method selected by the labels of the
+// PR, or the empty string if none of them selects one.
+func prMergeMethodOverride(c config.Tide, pr *PullRequest) (github.PullRequestMergeType, error) {
+	var method github.PullRequestMergeType
 	squashLabel := c.SquashLabel
 	rebaseLabel := c.RebaseLabel
 	mergeLabel := c.MergeLabel
@@ -1292,9 +1449,16 @@ func (c *Controller) mergePRs(sp subpool, prs []PullRequest) error {
 	var errs []error
 	log := sp.log.WithField("merge-targets", prNumbers(prs))
 	tideConfig := c.config().Tide
+	autoMerge := tideConfig.AutoMerge(config.OrgRepo{Org: sp.org, Repo: sp.repo}) && !tideConfig.GitLab.IsGitLabOrg(sp.org)
 
 	for i, pr := range prs {
 		log := log.WithFields(pr.logFields())
+		if autoMerge && pr.AutoMergeRequest != nil {
+			// GitHub merges the PR once it can, enabling auto-merge
+			// again would fail.
+			log.Debug("Auto-merge is already enabled.")
+			continue
+		}
 		mergeMethod, err := prMergeMethod(tideConfig, &pr)
 		if err != nil {
 			log.WithError(err).Error("Failed to determine merge method.")
@@ -1316,14 +1480,23 @@ func (c *Controller) mergePRs(sp subpool, prs []PullRequest) error {
 		commitTemplates := tideConfig.MergeCommitTemplate(config.OrgRepo{Org: sp.org, Repo: sp.repo})
 		keepTrying, err := tryMerge(func() error {
 			ghMergeDetails := c.prepareMergeDetails(commitTemplates, pr, mergeMethod)
+			if autoMerge {
+				return c.enableAutoMerge(sp.org, sp.repo, pr, ghMergeDetails)
+			}
 			return c.ghc.Merge(sp.org, sp.repo, int(pr.Number), ghMergeDetails)
 		})
 		if err != nil {
 			// These are user errors, shouldn't be printed as tide errors
 			log.WithError(err).Debug("Merge failed.")
 		} else {
-			log.Info("Merged.")
+			if autoMerge {
+				log.Info("Enabled auto-merge.")
+			} else {
+				log.Info("Merged.")
+			}
 			merged = append(merged, int(pr.Number))
+			entered := c.poolEntries.enter(&pr, time.Now())
+			tideMetrics.queueWaitTime.WithLabelValues(sp.org, sp.repo, sp.branch, priorityClass(pr, tideConfig.Priority)).Observe(time.Since(entered).Seconds())
 		}
 		if !keepTrying {
 			break
@@ -1522,12 +1695,24 @@ func (c *Controller) nonFailedBatchForJobAndRefsExists(jobName string, refs *pro
 func (c *Controller) takeAction(sp subpool, batchPending, successes, pendings, missings, batchMerges []PullRequest, missingSerialTests map[int][]config.Presubmit) (Action, []PullRequest, error) {
 	// Merge the batch!
 	if len(batchMerges) > 0 {
+		if optimistic := sp.optimisticPRs(batchMerges); len(optimistic) > 0 {
+			sp.log.WithField("prs", optimistic).Info("Not merging the batch, its PRs have pending contexts within the grace period.")
+			return Wait, nil, nil
+		}
 		return MergeBatch, batchMerges, c.mergePRs(sp, batchMerges)
 	}
 	// Do not merge PRs while waiting for a batch to complete. We don't want to
 	// invalidate the old batch result.
 	if len(successes) > 0 && len(batchPending) == 0 {
+		var behind map[int]bool
+		if c.config().Tide.RequireUpToDate(config.OrgRepo{Org: sp.org, Repo: sp.repo}) {
+			successes, behind = c.upToDateCandidates(sp, successes)
+		}
 		if ok, pr := pickHighestPriorityPR(sp.log, successes, sp.cc, c.isPassingTests, c.config().Tide.Priority); ok {
+			if behind[int(pr.Number)] {
+				sp.log.WithFields(pr.logFields()).Info("Updating the branch of the PR, it is behind its base branch.")
+				return UpdateBranch, []PullRequest{pr}, c.updateBranch(sp, pr)
+			}
 			return Merge, []PullRequest{pr}, c.mergePRs(sp, []PullRequest{pr})
 		}
 	}
@@ -1725,6 +1910,22 @@ func (c *Controller) presubmitsForBatch(prs []PullRequest, org, repo, baseSHA, b
 }
 
 func (c *Controller) syncSubpool(sp subpool, blocks []blockers.Blocker) (Pool, error) {
+	pooled := len(sp.prs)
+	freezes := mergeFreezes(&c.config().Tide, sp.org, sp.repo, sp.branch, time.Now())
+	if len(freezes) > 0 {
+		// Only the PRs overriding the freezes can be tested and merged.
+		var overriding []PullRequest
+		for _, pr := range sp.prs {
+			if frozenReason(freezes, &pr) == "" {
+				overriding = append(overriding, pr)
+			}
+		}
+		sp.log.WithFields(logrus.Fields{
+			"freeze":         freezeReasons(freezes),
+			"prs-overriding": prNumbers(overriding),
+		}).Info("Merging is frozen.")
+		sp.prs = overriding
+	}
 	sp.log.WithField("num_prs", len(sp.prs)).WithField("num_prowjobs", len(sp.pjs)).Info("Syncing subpool")
 	successes, pendings, missings, missingSerialTests := accumulate(sp.presubmits, sp.prs, sp.pjs, sp.log, sp.sha, c.ghc)
 	batchMerge, batchPending := c.accumulateBatch(sp)
@@ -1738,33 +1939,37 @@ func (c *Controller) syncSubpool(sp subpool, blocks []blockers.Blocker) (Pool, e
 
 	tenantIDs := sp.TenantIDs()
 	var act Action
-	var targets []PullRequest
+	var targets, queued, evicted []PullRequest
 	var err error
 	var errorString string
 	if len(blocks) > 0 {
 		act = PoolBlocked
+	} else if len(freezes) > 0 && len(sp.prs) == 0 {
+		act = PoolFrozen
+	} else if c.config().Tide.MergeQueue(config.OrgRepo{Org: sp.org, Repo: sp.repo}) {
+		act, targets, queued, evicted, err = c.takeQueueAction(sp, successes, pendings, missingSerialTests)
 	} else {
 		act, targets, err = c.takeAction(sp, batchPending, successes, pendings, missings, batchMerge, missingSerialTests)
-		if err != nil {
-			errorString = err.Error()
-		}
-		if recordableActions[act] {
-			c.History.Record(
-				poolKey(sp.org, sp.repo, sp.branch),
-				string(act),
-				sp.sha,
-				errorString,
-				prMeta(targets...),
-				tenantIDs,
-			)
-		}
+	}
+	if err != nil {
+		errorString = err.Error()
+	}
+	if recordableActions[act] {
+		c.History.Record(
+			poolKey(sp.org, sp.repo, sp.branch),
+			string(act),
+			sp.sha,
+			errorString,
+			prMeta(targets...),
+			tenantIDs,
+		)
 	}
 
 	sp.log.WithFields(logrus.Fields{
 		"action":  string(act),
 		"targets": prNumbers(targets),
 	}).Info("Subpool synced.")
-	tideMetrics.pooledPRs.WithLabelValues(sp.org, sp.repo, sp.branch).Set(float64(len(sp.prs)))
+	tideMetrics.pooledPRs.WithLabelValues(sp.org, sp.repo, sp.branch).Set(float64(pooled))
 	tideMetrics.updateTime.WithLabelValues(sp.org, sp.repo, sp.branch).Set(float64(time.Now().Unix()))
 	return Pool{
 			Org:    sp.org,
@@ -1777,6 +1982,13 @@ func (c *Controller) syncSubpool(sp subpool, blocks []blockers.Blocker) (Pool, e
 
 			BatchPending: batchPending,
 
+			QueuedPRs:  queued,
+			EvictedPRs: evicted,
+
+			MergeFreeze: freezeReasons(freezes),
+
+			BlockedPRs: poolBlockers(sp.blocked, blocks),
+
 			Action:   act,
 			Target:   targets,
 			Blockers: blocks,
@@ -1839,6 +2051,16 @@ type subpool struct {
 	// presubmit contains all required presubmits for each PR
 	// in this subpool
 	presubmits map[int][]config.Presubmit
+
+	// pendingGracePeriod is for how long pending contexts are assumed to
+	// pass, optimistic contains the PRs that are only in the subpool because
+	// of it.
+	pendingGracePeriod time.Duration
+	optimistic         sets.Int
+
+	// blocked explains what keeps each PR of the subpool from merging
+	// before the subpool was filtered.
+	blocked []PullRequestBlockers
 }
 
 func (sp subpool) TenantIDs() []string {
@@ -1906,6 +2128,7 @@ func (c *Controller) dividePool(pool map[string]PullRequest) (map[string]*subpoo
 
 // PullRequest holds graphql data about a PR, including its commits and their contexts.
 type PullRequest struct {
+	ID     githubql.ID
 	Number githubql.Int
 	Author struct {
 		Login githubql.String
@@ -1947,6 +2170,10 @@ type PullRequest struct {
 	Body      githubql.String
 	Title     githubql.String
 	UpdatedAt githubql.DateTime
+	// AutoMergeRequest is set once auto-merge is enabled on the PR.
+	AutoMergeRequest *struct {
+		EnabledAt githubql.DateTime
+	} `graphql:"autoMergeRequest"`
 }
 
 type CommitNode struct {
@@ -1980,6 +2207,7 @@ type CheckRun struct {
 	Name       githubql.String
 	Conclusion githubql.String
 	Status     githubql.String
+	StartedAt  githubql.DateTime `graphql:"startedAt"`
 }
 
 // Context holds graphql response data for github contexts.
@@ -1987,6 +2215,7 @@ type Context struct {
 	Context     githubql.String
 	Description githubql.String
 	State       githubql.StatusState
+	CreatedAt   githubql.DateTime `graphql:"createdAt"`
 }
 
 type PRNode struct {
@@ -2180,29 +2409,20 @@ func checkRunNodesToContexts(log *logrus.Entry, nodes []CheckRunNode) []Context
 	return result
 }
 
-type descriptionAndState struct {
-	description githubql.String
-	state       githubql.StatusState
-}
-
 // deduplicateContexts deduplicates contexts, returning the best result for
 // contexts that have multiple entries
 func deduplicateContexts(contexts []Context) []Context {
-	result := map[githubql.String]descriptionAndState{}
+	result := map[githubql.String]Context{}
 	for _, context := range contexts {
 		previousResult, found := result[context.Context]
-		if !found {
-			result[context.Context] = descriptionAndState{description: context.Description, state: context.State}
-			continue
-		}
-		if isStateBetter(previousResult.state, context.State) {
-			result[context.Context] = descriptionAndState{description: context.Description, state: context.State}
+		if !found || isStateBetter(previousResult.State, context.State) {
+			result[context.Context] = context
 		}
 	}
 
 	var resultSlice []Context
-	for name, descriptionAndState := range result {
-		resultSlice = append(resultSlice, Context{Context: name, Description: descriptionAndState.description, State: descriptionAndState.state})
+	for _, context := range result {
+		resultSlice = append(resultSlice, context)
 	}
 
 	return resultSlice
@@ -2231,7 +2451,8 @@ const (
 // ref: https://developer.github.com/v3/checks/runs/#parameters
 func checkRunToContext(checkRun CheckRun) Context {
 	context := Context{
-		Context: checkRun.Name,
+		Context:   checkRun.Name,
+		CreatedAt: checkRun.StartedAt,
 	}
 	if checkRun.Status != checkRunStatusCompleted {
 		context.State = githubql.StatusStatePending
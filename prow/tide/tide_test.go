@@ -701,6 +701,13 @@ type fgc struct {
 	mergeErrs  map[int]error
 	queryCalls int
 
+	branchUpdates   []int
+	comparisons     map[string]*github.CommitComparison
+	compareErr      error
+	autoMerged      []githubql.ID
+	autoMergeErr    error
+	mergeableStates map[int]string
+
 	expectedSHA          string
 	skipExpectedShaCheck bool
 	combinedStatus       map[string]string
@@ -725,6 +732,29 @@ func (f *fgc) GetRef(o, r, ref string) (string, error) {
 	return f.refs[o+"/"+r+" "+ref], f.err
 }
 
+func (f *fgc) CompareCommits(org, repo, base, head string) (*github.CommitComparison, error) {
+	if f.compareErr != nil {
+		return nil, f.compareErr
+	}
+	if comparison, ok := f.comparisons[base+"..."+head]; ok {
+		return comparison, nil
+	}
+	return &github.CommitComparison{Status: "identical"}, nil
+}
+
+func (f *fgc) MutateWithGitHubAppsSupport(ctx context.Context, m interface{}, input githubql.Input, vars map[string]interface{}, org string) error {
+	if _, ok := m.(*enableAutoMergeMutation); !ok {
+		return errors.New("unexpected mutation type")
+	}
+	if f.autoMergeErr != nil {
+		return f.autoMergeErr
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.autoMerged = append(f.autoMerged, input.(githubql.EnablePullRequestAutoMergeInput).PullRequestID)
+	return nil
+}
+
 func (f *fgc) QueryWithGitHubAppsSupport(ctx context.Context, q interface{}, vars map[string]interface{}, org string) error {
 	sq, ok := q.(*searchQuery)
 	if !ok {
@@ -754,6 +784,13 @@ func (f *fgc) Merge(org, repo string, number int, details github.MergeDetails) e
 	return nil
 }
 
+func (f *fgc) UpdatePullRequestBranch(org, repo string, number int, expectedHeadSha *string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.branchUpdates = append(f.branchUpdates, number)
+	return nil
+}
+
 func (f *fgc) CreateStatus(org, repo, ref string, s github.Status) error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
@@ -793,6 +830,10 @@ func (f *fgc) ListCheckRuns(org, repo, ref string) (*github.CheckRunList, error)
 	return &github.CheckRunList{}, nil
 }
 
+func (f *fgc) GetPullRequest(org, repo string, number int) (*github.PullRequest, error) {
+	return &github.PullRequest{Number: number, MergeableState: f.mergeableStates[number]}, nil
+}
+
 func (f *fgc) GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error) {
 	if number != 100 {
 		return nil, nil
@@ -1169,6 +1210,9 @@ func TestMergeMethodCheckerAndPRMergeMethod(t *testing.T) {
 			"o/configured-squash-allow-rebase": github.MergeSquash, // GH client allows merge, squash, rebase
 			"o/configure-re-base":              github.MergeRebase, // GH client allows merge
 		},
+		MergeMethodOverrides: map[string][]github.PullRequestMergeType{
+			"o/restricted-squash-rebase": {github.MergeSquash}, // GH client allows merge, squash, rebase
+		},
 	}
 	cfg := func() *config.Config { return &config.Config{ProwConfig: config.ProwConfig{Tide: tideConfig}} }
 	mmc := newMergeChecker(cfg, &fgc{})
@@ -1242,6 +1286,19 @@ func TestMergeMethodCheckerAndPRMergeMethod(t *testing.T) {
 			expectErr:         false,
 			expectConflictErr: true,
 		},
+		{
+			name:           "PR label override allowed by tide config",
+			repo:           "restricted-squash-rebase",
+			labels:         []string{"tide/squash"},
+			expectedMethod: github.MergeSquash,
+		},
+		{
+			name:              "PR label override disallowed by tide config",
+			repo:              "restricted-squash-rebase",
+			labels:            []string{"tide/rebase"},
+			expectedMethod:    github.MergeRebase,
+			expectConflictErr: true,
+		},
 		{
 			name:              "default method conflicts with squash only GH settings",
 			repo:              "squash-nomerge",
@@ -2194,9 +2251,16 @@ func TestSync(t *testing.T) {
 			}},
 		},
 		{
-			name:          "1 unmergeable PR",
-			prs:           []PullRequest{unmergeableA},
-			expectedPools: []Pool{},
+			name: "1 unmergeable PR",
+			prs:  []PullRequest{unmergeableA},
+			expectedPools: []Pool{{
+				Org:        "org",
+				Repo:       "repo",
+				Branch:     "A",
+				BlockedPRs: []PullRequestBlockers{{Number: 6, Unmergeable: "PR has a merge conflict."}},
+				Action:     Wait,
+				TenantIDs:  []string{},
+			}},
 		},
 		{
 			name: "1 unknown PR",
@@ -2222,6 +2286,13 @@ func TestSync(t *testing.T) {
 				Action:     Merge,
 				Target:     []PullRequest{mergeableA},
 				TenantIDs:  []string{},
+			}, {
+				Org:        "org",
+				Repo:       "repo",
+				Branch:     "B",
+				BlockedPRs: []PullRequestBlockers{{Number: 7, Unmergeable: "PR has a merge conflict."}},
+				Action:     Wait,
+				TenantIDs:  []string{},
 			}},
 		},
 		{
@@ -2232,6 +2303,7 @@ func TestSync(t *testing.T) {
 				Repo:       "repo",
 				Branch:     "A",
 				SuccessPRs: []PullRequest{mergeableA},
+				BlockedPRs: []PullRequestBlockers{{Number: 6, Unmergeable: "PR has a merge conflict."}},
 				Action:     Merge,
 				Target:     []PullRequest{mergeableA},
 				TenantIDs:  []string{},
@@ -2301,8 +2373,11 @@ func TestSync(t *testing.T) {
 				ghc:             fgc,
 				nextChangeCache: make(map[changeCacheKey][]string),
 			},
-			mergeChecker: mergeChecker,
-			History:      hist,
+			mergeChecker:  mergeChecker,
+			evictions:     newEvictedPRs(),
+			poolEntries:   newPoolEntries(),
+			branchUpdates: newBranchUpdates(),
+			History:       hist,
 		}
 
 		if err := c.Sync(); err != nil {